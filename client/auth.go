@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Login authenticates with email and password and stores the returned token on the client
+func (c *Client) Login(ctx context.Context, email, password string) (*AuthResponse, error) {
+	var resp AuthResponse
+	req := map[string]string{"email": email, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/api/auth/login", req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Token != "" {
+		c.SetToken(resp.Token)
+	}
+
+	return &resp, nil
+}
+
+// Register creates a new user account
+func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
+	var resp AuthResponse
+	if err := c.do(ctx, http.MethodPost, "/api/auth/register", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Me returns the authenticated user's profile
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var resp struct {
+		Success bool  `json:"success"`
+		User    *User `json:"user"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.User, nil
+}
+
+// UpdateProfile updates the authenticated user's gmail and phone
+func (c *Client) UpdateProfile(ctx context.Context, req *UpdateProfileRequest) error {
+	return c.do(ctx, http.MethodPut, "/api/auth/profile", req, nil)
+}
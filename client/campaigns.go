@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// RecycleProspects selects abandoned conversations and creates a draft campaign from them.
+// With req.DryRun the selection is returned without creating anything.
+func (c *Client) RecycleProspects(ctx context.Context, req *RecycleProspectsRequest) (*RecycleProspectsResponse, error) {
+	var resp RecycleProspectsResponse
+	if err := c.do(ctx, http.MethodPost, "/api/campaigns/recycle", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateCampaign creates a draft campaign from a CSV or a saved segment. The response's Excluded
+// counts the numbers left out (blacklist, opt_out, no_consent, duplicate, invalid).
+func (c *Client) CreateCampaign(ctx context.Context, req *CreateCampaignRequest) (*CampaignResponse, error) {
+	var resp CampaignResponse
+	if err := c.do(ctx, http.MethodPost, "/api/campaigns", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListCampaigns returns the user's campaigns
+func (c *Client) ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	var resp CampaignResponse
+	if err := c.do(ctx, http.MethodGet, "/api/campaigns", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Campaigns, nil
+}
+
+// GetCampaign returns a campaign with its recipients counted by status in Stats
+func (c *Client) GetCampaign(ctx context.Context, campaignID string) (*CampaignResponse, error) {
+	var resp CampaignResponse
+	if err := c.do(ctx, http.MethodGet, "/api/campaigns/"+url.PathEscape(campaignID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// StartCampaign starts sending a draft campaign
+func (c *Client) StartCampaign(ctx context.Context, campaignID string) (*Campaign, error) {
+	return c.campaignAction(ctx, campaignID, "start")
+}
+
+// PauseCampaign pauses a running campaign after the message being sent
+func (c *Client) PauseCampaign(ctx context.Context, campaignID string) (*Campaign, error) {
+	return c.campaignAction(ctx, campaignID, "pause")
+}
+
+// ResumeCampaign resumes a paused campaign
+func (c *Client) ResumeCampaign(ctx context.Context, campaignID string) (*Campaign, error) {
+	return c.campaignAction(ctx, campaignID, "resume")
+}
+
+// CancelCampaign cancels a campaign; recipients not reached yet are skipped
+func (c *Client) CancelCampaign(ctx context.Context, campaignID string) (*Campaign, error) {
+	return c.campaignAction(ctx, campaignID, "cancel")
+}
+
+// campaignAction moves a campaign's status with one of its start, pause, resume or cancel endpoints
+func (c *Client) campaignAction(ctx context.Context, campaignID, action string) (*Campaign, error) {
+	var resp CampaignResponse
+	if err := c.do(ctx, http.MethodPost, "/api/campaigns/"+url.PathEscape(campaignID)+"/"+action, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Campaign, nil
+}
+
+// ListBlacklist returns the user's blacklisted and opted-out numbers
+func (c *Client) ListBlacklist(ctx context.Context) ([]BlacklistEntry, error) {
+	var resp BlacklistResponse
+	if err := c.do(ctx, http.MethodGet, "/api/blacklist", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// AddToBlacklist excludes a number from all of the user's campaigns
+func (c *Client) AddToBlacklist(ctx context.Context, req *AddBlacklistRequest) (*BlacklistEntry, error) {
+	var resp BlacklistResponse
+	if err := c.do(ctx, http.MethodPost, "/api/blacklist", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entry, nil
+}
+
+// RemoveFromBlacklist allows a number to be targeted again
+func (c *Client) RemoveFromBlacklist(ctx context.Context, phoneNumber string) error {
+	return c.do(ctx, http.MethodDelete, "/api/blacklist/"+url.PathEscape(phoneNumber), nil, nil)
+}
+
+// ExportConsent returns the marketing consent registry, optionally for one device
+func (c *Client) ExportConsent(ctx context.Context, deviceID string) ([]ConsentRecord, error) {
+	query := url.Values{"format": {"json"}}
+	if deviceID != "" {
+		query.Set("device_id", deviceID)
+	}
+
+	var resp ConsentExportResponse
+	if err := c.do(ctx, http.MethodGet, "/api/consent/export?"+query.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Records, nil
+}
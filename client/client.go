@@ -0,0 +1,250 @@
+// Package client is a Go SDK for the chatbot automation HTTP API.
+//
+// It wraps authentication, flows, devices, conversations and the flow-start webhook
+// with typed requests and responses, so other Go services can integrate without
+// hand-rolling JSON calls:
+//
+//	c := client.New("https://api.example.com")
+//	if _, err := c.Login(ctx, "me@example.com", "secret"); err != nil {
+//		log.Fatal(err)
+//	}
+//	flows, err := c.ListFlows(ctx)
+//
+// Machine-to-machine integrations use an API key instead of logging in:
+//
+//	c := client.New("https://api.example.com", client.WithAPIKey("ak_..."))
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryWait  = 500 * time.Millisecond
+)
+
+// Client is an API client. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+
+	mu     sync.RWMutex
+	token  string
+	apiKey string
+}
+
+// apiKeyOnlyPaths are the endpoints that take an API key only, never a JWT
+var apiKeyOnlyPaths = []string{"/api/webhook/start-flow"}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sets a JWT obtained elsewhere instead of calling Login
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey sets an API key (ak_...) for machine-to-machine access. It is sent as X-API-Key
+// when the client has no JWT, and always to endpoints that take API keys only, such as StartFlow.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithRetries sets how many times idempotent requests are retried on
+// network errors, 429 and 5xx responses, and the initial backoff between attempts
+func WithRetries(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+	}
+}
+
+// New creates a client for the API at baseURL (e.g. "https://api.example.com")
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		retryWait:  defaultRetryWait,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SetToken replaces the JWT sent with authenticated requests
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token returns the JWT currently in use
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetAPIKey replaces the API key sent as X-API-Key
+func (c *Client) SetAPIKey(apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = apiKey
+}
+
+// APIKey returns the API key currently in use
+func (c *Client) APIKey() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
+// APIError is returned when the API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("api error %d", e.StatusCode)
+}
+
+// do sends a request and decodes the JSON response into out (when non-nil).
+// GET, PUT and DELETE are retried with exponential backoff; POST and PATCH are never retried.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		attempts += c.maxRetries
+	}
+
+	wait := c.retryWait
+	var lastErr error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+
+		retry, err := c.send(ctx, method, path, payload, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !retry {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+// send performs a single attempt and reports whether a failure is worth retrying
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, out interface{}) (bool, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token := c.Token()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// An API key takes precedence on the server, so it is only sent where a JWT would not do
+	if apiKey := c.APIKey(); apiKey != "" && (token == "" || slices.Contains(apiKeyOnlyPaths, path)) {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: data}
+
+		var envelope struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		if json.Unmarshal(data, &envelope) == nil {
+			apiErr.Message = envelope.Message
+			if apiErr.Message == "" {
+				apiErr.Message = envelope.Error
+			}
+		}
+
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, apiErr
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return false, nil
+}
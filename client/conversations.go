@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"chatbot-automation/internal/models"
+)
+
+// DefaultExternalRef returns the external reference a conversation gets when none is supplied:
+// a UUIDv5 of "id_device:prospect_num", stable across calls
+func DefaultExternalRef(idDevice, prospectNum string) string {
+	return models.DefaultExternalRef(idDevice, prospectNum)
+}
+
+// ListConversations returns all AI WhatsApp conversations visible to the authenticated user
+func (c *Client) ListConversations(ctx context.Context) ([]Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/all", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversations, nil
+}
+
+// ListDeviceConversations returns up to limit conversations for a device (0 uses the server default)
+func (c *Client) ListDeviceConversations(ctx context.Context, deviceID string, limit int) ([]Conversation, error) {
+	path := "/api/conversations/device/" + url.PathEscape(deviceID)
+	if limit > 0 {
+		path += fmt.Sprintf("?limit=%d", limit)
+	}
+
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversations, nil
+}
+
+// ListActiveConversations returns conversations that have not completed for a device
+func (c *Client) ListActiveConversations(ctx context.Context, deviceID string) ([]Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/device/"+url.PathEscape(deviceID)+"/active", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversations, nil
+}
+
+// GetConversation returns a single conversation
+func (c *Client) GetConversation(ctx context.Context, conversationID string) (*Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/"+url.PathEscape(conversationID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversation, nil
+}
+
+// GetConversationByExternalRef returns the conversation keyed by an external reference.
+// Without a custom reference, use DefaultExternalRef(idDevice, prospectNum).
+func (c *Client) GetConversationByExternalRef(ctx context.Context, externalRef string) (*Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/ref/"+url.PathEscape(externalRef), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversation, nil
+}
+
+// CreateConversation creates a conversation
+func (c *Client) CreateConversation(ctx context.Context, req *CreateConversationRequest) (*Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodPost, "/api/conversations", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversation, nil
+}
+
+// UpdateConversation updates a conversation (stage, flow, status, ...)
+func (c *Client) UpdateConversation(ctx context.Context, conversationID string, req *UpdateConversationRequest) (*Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodPut, "/api/conversations/"+url.PathEscape(conversationID), req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversation, nil
+}
+
+// GetReplySuggestions returns AI-drafted replies for a conversation (count 2-3, 0 uses the server default)
+func (c *Client) GetReplySuggestions(ctx context.Context, conversationID string, count int) ([]string, error) {
+	path := "/api/conversations/" + url.PathEscape(conversationID) + "/suggestions"
+	if count > 0 {
+		path += fmt.Sprintf("?count=%d", count)
+	}
+
+	var resp ReplySuggestionsResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Suggestions, nil
+}
+
+// AddMessage appends a message to a conversation's history
+func (c *Client) AddMessage(ctx context.Context, conversationID string, req *AddMessageRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/conversations/"+url.PathEscape(conversationID)+"/messages", req, nil)
+}
+
+// DeleteConversation deletes a conversation
+func (c *Client) DeleteConversation(ctx context.Context, conversationID string) error {
+	return c.do(ctx, http.MethodDelete, "/api/conversations/"+url.PathEscape(conversationID), nil, nil)
+}
+
+// GetConversationStats returns conversation statistics for a device
+func (c *Client) GetConversationStats(ctx context.Context, deviceID string) (*ConversationStats, error) {
+	var resp struct {
+		Success bool               `json:"success"`
+		Stats   *ConversationStats `json:"stats"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/device/"+url.PathEscape(deviceID)+"/stats", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Stats, nil
+}
+
+// PinConversation pins/unpins a conversation or sets its priority
+func (c *Client) PinConversation(ctx context.Context, conversationID string, req *PinConversationRequest) (*Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodPut, "/api/conversations/"+url.PathEscape(conversationID)+"/pin", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversation, nil
+}
+
+// ListPinnedConversations returns the authenticated user's pinned conversations
+func (c *Client) ListPinnedConversations(ctx context.Context) ([]Conversation, error) {
+	var resp ConversationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/conversations/pinned", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conversations, nil
+}
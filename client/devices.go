@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListDevices returns the authenticated user's devices
+func (c *Client) ListDevices(ctx context.Context) ([]Device, error) {
+	var resp DeviceResponse
+	if err := c.do(ctx, http.MethodGet, "/api/devices", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Devices, nil
+}
+
+// GetDevice returns a single device
+func (c *Client) GetDevice(ctx context.Context, deviceID string) (*Device, error) {
+	var resp DeviceResponse
+	if err := c.do(ctx, http.MethodGet, "/api/devices/"+url.PathEscape(deviceID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Device, nil
+}
+
+// CreateDevice registers a device
+func (c *Client) CreateDevice(ctx context.Context, req *CreateDeviceRequest) (*Device, error) {
+	var resp DeviceResponse
+	if err := c.do(ctx, http.MethodPost, "/api/devices", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Device, nil
+}
+
+// UpdateDevice updates a device
+func (c *Client) UpdateDevice(ctx context.Context, deviceID string, req *UpdateDeviceRequest) (*Device, error) {
+	var resp DeviceResponse
+	if err := c.do(ctx, http.MethodPut, "/api/devices/"+url.PathEscape(deviceID), req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Device, nil
+}
+
+// DeleteDevice deletes a device
+func (c *Client) DeleteDevice(ctx context.Context, deviceID string) error {
+	return c.do(ctx, http.MethodDelete, "/api/devices/"+url.PathEscape(deviceID), nil, nil)
+}
+
+// ExportDeviceConfig returns a device's configuration bundle (settings without secrets, stages and flows)
+func (c *Client) ExportDeviceConfig(ctx context.Context, deviceID string) (*DeviceConfigBundle, error) {
+	var resp DeviceConfigExportResponse
+	if err := c.do(ctx, http.MethodGet, "/api/devices/"+url.PathEscape(deviceID)+"/config", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Bundle, nil
+}
+
+// ImportDeviceConfig applies a configuration bundle to a device
+func (c *Client) ImportDeviceConfig(ctx context.Context, deviceID string, req *DeviceConfigImportRequest) (*DeviceConfigImportResponse, error) {
+	var resp DeviceConfigImportResponse
+	if err := c.do(ctx, http.MethodPost, "/api/devices/"+url.PathEscape(deviceID)+"/config", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListFlows returns all flows owned by the authenticated user
+func (c *Client) ListFlows(ctx context.Context) ([]Flow, error) {
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodGet, "/api/flows", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flows, nil
+}
+
+// ListDeviceFlows returns the flows configured for a device
+func (c *Client) ListDeviceFlows(ctx context.Context, deviceID string) ([]Flow, error) {
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodGet, "/api/flows/device/"+url.PathEscape(deviceID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flows, nil
+}
+
+// GetFlow returns a single flow
+func (c *Client) GetFlow(ctx context.Context, flowID string) (*Flow, error) {
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodGet, "/api/flows/"+url.PathEscape(flowID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flow, nil
+}
+
+// CreateFlow creates a flow
+func (c *Client) CreateFlow(ctx context.Context, req *CreateFlowRequest) (*Flow, error) {
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/flows", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flow, nil
+}
+
+// UpdateFlow updates a flow
+func (c *Client) UpdateFlow(ctx context.Context, flowID string, req *UpdateFlowRequest) (*Flow, error) {
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodPut, "/api/flows/"+url.PathEscape(flowID), req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flow, nil
+}
+
+// AutoLayoutFlow recomputes node positions server-side (req may be nil for defaults)
+func (c *Client) AutoLayoutFlow(ctx context.Context, flowID string, req *AutoLayoutRequest) (*Flow, error) {
+	if req == nil {
+		req = &AutoLayoutRequest{}
+	}
+
+	var resp FlowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/flows/"+url.PathEscape(flowID)+"/auto-layout", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Flow, nil
+}
+
+// MigrateDeprecatedModels finds (and unless req.DryRun rewrites) deprecated AI models; admin only
+func (c *Client) MigrateDeprecatedModels(ctx context.Context, req *ModelMigrationRequest) (*ModelMigrationResponse, error) {
+	var resp ModelMigrationResponse
+	if err := c.do(ctx, http.MethodPost, "/api/maintenance/model-migration", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteFlow deletes a flow
+func (c *Client) DeleteFlow(ctx context.Context, flowID string) error {
+	return c.do(ctx, http.MethodDelete, "/api/flows/"+url.PathEscape(flowID), nil, nil)
+}
@@ -0,0 +1,65 @@
+package client
+
+import "chatbot-automation/internal/models"
+
+// Request and response types are aliases of the server models so the SDK
+// always matches the payloads the API actually sends and accepts.
+type (
+	User                  = models.User
+	AuthResponse          = models.AuthResponse
+	RegisterRequest       = models.RegisterRequest
+	ChangePasswordRequest = models.ChangePasswordRequest
+	UpdateProfileRequest  = models.UpdateProfileRequest
+
+	Flow              = models.ChatbotFlow
+	FlowResponse      = models.FlowResponse
+	CreateFlowRequest = models.CreateFlowRequest
+	UpdateFlowRequest = models.UpdateFlowRequest
+	CompletionPolicy  = models.CompletionPolicy
+	AutoLayoutRequest = models.AutoLayoutRequest
+
+	ModelMigrationRequest  = models.ModelMigrationRequest
+	ModelMigrationResponse = models.ModelMigrationResponse
+
+	Device              = models.DeviceSetting
+	DeviceResponse      = models.DeviceResponse
+	CreateDeviceRequest = models.CreateDeviceRequest
+	UpdateDeviceRequest = models.UpdateDeviceRequest
+
+	DeviceConfigBundle         = models.DeviceConfigBundle
+	DeviceConfigExportResponse = models.DeviceConfigExportResponse
+	DeviceConfigImportRequest  = models.DeviceConfigImportRequest
+	DeviceConfigImportResponse = models.DeviceConfigImportResponse
+
+	Conversation              = models.AIWhatsapp
+	ConversationResponse      = models.ConversationResponse
+	ConversationStats         = models.ConversationStats
+	CreateConversationRequest = models.CreateConversationRequest
+	UpdateConversationRequest = models.UpdateConversationRequest
+	AddMessageRequest         = models.AddMessageRequest
+	PinConversationRequest    = models.PinConversationRequest
+	CostLedger                = models.CostLedger
+	ConversationCost          = models.ConversationCost
+	ReplySuggestionsResponse  = models.ReplySuggestionsResponse
+
+	Campaign                 = models.Campaign
+	CampaignRecipient        = models.CampaignRecipient
+	CampaignStats            = models.CampaignStats
+	CampaignResponse         = models.CampaignResponse
+	CreateCampaignRequest    = models.CreateCampaignRequest
+	RecycleProspectsRequest  = models.RecycleProspectsRequest
+	RecycleProspectsResponse = models.RecycleProspectsResponse
+	BlacklistEntry           = models.BlacklistEntry
+	BlacklistResponse        = models.BlacklistResponse
+	AddBlacklistRequest      = models.AddBlacklistRequest
+	ConsentRecord            = models.ConsentRecord
+	ConsentExportResponse    = models.ConsentExportResponse
+
+	StartFlowRequest  = models.StartFlowRequest
+	StartFlowResponse = models.StartFlowResponse
+
+	WebChatMessageRequest = models.WebChatMessageRequest
+	WebChatMessage        = models.WebChatMessage
+	WebChatSendResponse   = models.WebChatSendResponse
+	WebChatPollResponse   = models.WebChatPollResponse
+)
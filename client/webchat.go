@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SendWebChatMessage sends a visitor message as the website chat widget would.
+// Leave SessionID empty on the first message and reuse the returned session ID.
+func (c *Client) SendWebChatMessage(ctx context.Context, webhookID string, req *WebChatMessageRequest) (*WebChatSendResponse, error) {
+	var resp WebChatSendResponse
+	if err := c.do(ctx, http.MethodPost, "/api/webchat/"+url.PathEscape(webhookID)+"/messages", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PollWebChatMessages returns bot replies for a web chat session, waiting up to waitSeconds for new ones
+func (c *Client) PollWebChatMessages(ctx context.Context, webhookID, sessionID string, waitSeconds int) ([]WebChatMessage, error) {
+	path := fmt.Sprintf("/api/webchat/%s/messages?session_id=%s&wait=%d", url.PathEscape(webhookID), url.QueryEscape(sessionID), waitSeconds)
+
+	var resp WebChatPollResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
@@ -0,0 +1,16 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// StartFlow starts a flow for a prospect through the start-flow webhook. It needs an API key with
+// the webhooks:ingest scope (see WithAPIKey).
+func (c *Client) StartFlow(ctx context.Context, req *StartFlowRequest) (*StartFlowResponse, error) {
+	var resp StartFlowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/webhook/start-flow", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -1,12 +1,14 @@
 // Deno Deploy Message Debouncer
 // Purpose:
 // 1. Receive webhook messages from WhatsApp
-// 2. Queue messages with 4-second debouncing
+// 2. Queue messages with an adaptive debounce window (asked from the Go backend per message)
 // 3. When timer expires, send combined messages to Go backend
 // 4. Go backend handles: device config, AI processing, WhatsApp sending
 
 // Environment variables
-const DEBOUNCE_DELAY_MS = 4000; // 4 seconds
+const DEBOUNCE_DELAY_MS = 4000; // Fallback window when the Go backend cannot be asked
+const BURST_WINDOW_MS = 10000; // Look-back for counting a sender's recent messages
+const WINDOW_REQUEST_TIMEOUT_MS = 1500;
 const GO_BACKEND_URL = Deno.env.get("GO_BACKEND_URL") || "https://chatbot-automation-production.up.railway.app";
 
 // Open Deno KV database
@@ -35,7 +37,7 @@ Deno.serve(async (req: Request) => {
       JSON.stringify({
         status: "ok",
         service: "deno-message-debouncer",
-        debounceDelay: `${DEBOUNCE_DELAY_MS}ms`,
+        debounceDelay: `adaptive (fallback ${DEBOUNCE_DELAY_MS}ms)`,
         goBackend: GO_BACKEND_URL,
       }),
       { headers: { "Content-Type": "application/json" } }
@@ -80,15 +82,10 @@ async function handleIncomingMessage(payload: any) {
   let queue: QueuedMessage;
 
   if (result.value) {
-    // Add to existing queue and RESET timer
+    // Add to existing queue; the timer is RESET below
     queue = result.value;
     queue.messages.push({ message, timestamp: now });
     queue.lastMessageTime = now;
-    queue.timerScheduled = now + DEBOUNCE_DELAY_MS; // Reset timer to 4s from now
-
-    console.log(
-      `📩 [${deviceId}/${phone}] Message ${queue.messages.length} added. Timer RESET to 4s.`
-    );
   } else {
     // Create new queue
     queue = {
@@ -97,12 +94,20 @@ async function handleIncomingMessage(payload: any) {
       name: name || "",
       messages: [{ message, timestamp: now }],
       lastMessageTime: now,
-      timerScheduled: now + DEBOUNCE_DELAY_MS,
+      timerScheduled: 0,
     };
-
-    console.log(`🆕 [${deviceId}/${phone}] New queue created. Timer started (4s).`);
   }
 
+  const senderRecent = queue.messages.filter((m) => now - m.timestamp <= BURST_WINDOW_MS).length;
+  const windowMs = await getDebounceWindow(deviceId, phone, senderRecent);
+  queue.timerScheduled = now + windowMs;
+
+  console.log(
+    result.value
+      ? `📩 [${deviceId}/${phone}] Message ${queue.messages.length} added. Timer RESET to ${windowMs}ms.`
+      : `🆕 [${deviceId}/${phone}] New queue created. Timer started (${windowMs}ms).`
+  );
+
   // Save queue
   await kv.set(queueKey, queue);
 
@@ -110,6 +115,42 @@ async function handleIncomingMessage(payload: any) {
   scheduleProcessing(phone, deviceId, queue.timerScheduled);
 }
 
+// Ask the Go backend for the adaptive window: short when the device is idle, longer under
+// load (many senders queued) or when this sender is rapid-firing. Falls back to DEBOUNCE_DELAY_MS.
+async function getDebounceWindow(deviceId: string, phone: string, senderRecent: number): Promise<number> {
+  try {
+    let queueDepth = 0;
+    for await (const _ of kv.list({ prefix: ["message_queue", deviceId] })) {
+      queueDepth++;
+    }
+
+    const response = await fetch(`${GO_BACKEND_URL}/api/debounce/window`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        device_id: deviceId,
+        phone: phone,
+        queue_depth: queueDepth,
+        sender_recent: senderRecent,
+      }),
+      signal: AbortSignal.timeout(WINDOW_REQUEST_TIMEOUT_MS),
+    });
+
+    if (!response.ok) {
+      throw new Error(`Go backend error (${response.status})`);
+    }
+
+    const result = await response.json();
+    if (typeof result.window_ms === "number" && result.window_ms > 0) {
+      return result.window_ms;
+    }
+  } catch (error) {
+    console.warn(`⚠️ [${deviceId}/${phone}] Adaptive window unavailable, using ${DEBOUNCE_DELAY_MS}ms:`, error);
+  }
+
+  return DEBOUNCE_DELAY_MS;
+}
+
 // Schedule message processing
 function scheduleProcessing(phone: string, deviceId: string, scheduledTime: number) {
   const delay = scheduledTime - Date.now();
@@ -226,7 +267,7 @@ async function cleanupOldQueues() {
 setInterval(cleanupOldQueues, 600000);
 
 console.log("🚀 Deno Message Debouncer Started!");
-console.log(`⏱️  Debounce delay: ${DEBOUNCE_DELAY_MS}ms (4 seconds)`);
+console.log(`⏱️  Debounce delay: adaptive per device (fallback ${DEBOUNCE_DELAY_MS}ms)`);
 console.log(`🔗 Go backend: ${GO_BACKEND_URL}`);
 console.log(`📝 Endpoint: POST /webhook`);
 console.log(`💚 Health check: GET /health`);
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultConversationTTL keeps a conversation cached while it is in active back-and-forth
+	DefaultConversationTTL = 15 * time.Minute
+
+	// opTimeout bounds every Redis call so a slow or down cache never delays a reply;
+	// on timeout the repositories fall back to Supabase
+	opTimeout = 250 * time.Millisecond
+
+	keyPrefix = "conv:"
+)
+
+// ConversationCache is an optional Redis cache of hot conversation rows (ai_whatsapp, wasapbot)
+// keyed by table and id_prospect. Rows hold the execution columns (current node, waiting flag)
+// and the trimmed conv_last history window. Repositories read through it, write through it on
+// updates and invalidate it when a row cannot be patched reliably.
+//
+// A nil *ConversationCache is valid and disables caching.
+type ConversationCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewConversationCache connects to redisURL (redis://[:password@]host:port/db).
+// An empty URL returns a nil cache, which disables caching.
+func NewConversationCache(redisURL string, ttl time.Duration) (*ConversationCache, error) {
+	if redisURL == "" {
+		return nil, nil
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	opts.DialTimeout = time.Second
+	opts.ReadTimeout = opTimeout
+	opts.WriteTimeout = opTimeout
+
+	if ttl <= 0 {
+		ttl = DefaultConversationTTL
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &ConversationCache{
+		client: client,
+		ttl:    ttl,
+	}, nil
+}
+
+// Close releases the Redis connection pool
+func (c *ConversationCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+func conversationKey(table, id string) string {
+	return keyPrefix + table + ":" + id
+}
+
+// Get loads a cached row into out and reports whether it was found
+func (c *ConversationCache) Get(ctx context.Context, table, id string, out interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, conversationKey(table, id)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("⚠️  Conversation cache read failed for %s/%s: %v", table, id, err)
+		}
+		return false
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		c.Delete(ctx, table, id)
+		return false
+	}
+	return true
+}
+
+// Set caches a full row read from or written to the database
+func (c *ConversationCache) Set(ctx context.Context, table, id string, row interface{}) {
+	if c == nil || id == "" {
+		return
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	if err := c.client.Set(ctx, conversationKey(table, id), data, c.ttl).Err(); err != nil {
+		log.Printf("⚠️  Conversation cache write failed for %s/%s: %v", table, id, err)
+	}
+}
+
+// Patch applies column updates that were just written to the database onto the cached row.
+// Rows that are not cached stay uncached; if the patch cannot be applied the row is invalidated.
+func (c *ConversationCache) Patch(ctx context.Context, table, id string, updates map[string]interface{}) {
+	if c == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	key := conversationKey(table, id)
+	err := c.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var row map[string]interface{}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		for column, value := range updates {
+			row[column] = value
+		}
+
+		patched, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, patched, c.ttl)
+			return nil
+		})
+		return err
+	}, key)
+
+	if err != nil {
+		log.Printf("⚠️  Conversation cache patch failed for %s/%s, invalidating: %v", table, id, err)
+		c.Delete(context.WithoutCancel(ctx), table, id)
+	}
+}
+
+// Delete invalidates a cached row
+func (c *ConversationCache) Delete(ctx context.Context, table, id string) {
+	if c == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	if err := c.client.Del(ctx, conversationKey(table, id)).Err(); err != nil {
+		log.Printf("⚠️  Conversation cache invalidation failed for %s/%s: %v", table, id, err)
+	}
+}
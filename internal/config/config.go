@@ -1,6 +1,10 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	Port                   int
@@ -11,6 +15,39 @@ type Config struct {
 	BillplzAPIKey          string
 	BillplzCollectionID    string
 	ServerURL              string
+	TranslateProvider      string // google, libretranslate (empty disables translate nodes)
+	TranslateAPIURL        string
+	TranslateAPIKey        string
+	FlowRunTimeout         time.Duration // whole flow run for one inbound message (0 uses the default)
+	NodeTimeout            time.Duration // a single flow node
+	ExternalCallTimeout    time.Duration // one AI or WhatsApp provider call made by a node
+	FlowMaxSteps           int           // nodes one flow run may execute before the loop guard stops it (0 uses the default)
+	FlowMaxNodeVisits      int           // times one flow run may execute the same node (0 uses the default)
+	MaxBodyBytes           int           // request body limit for the whole app (0 uses the default)
+	WebhookMaxBodyBytes    int           // tighter request body limit for webhook routes
+	RedisURL               string        // conversation state cache (empty disables caching)
+	ConversationCacheTTL   time.Duration // how long an idle conversation stays cached (0 uses the default)
+	LinkTrackingBaseURL    string        // default short link domain for devices without tracking_domain (empty disables)
+	AIEndpointsFile        string        // JSON file of per-provider AI base URLs, headers and TLS options (empty uses the public endpoints)
+	OpenRouterBaseURL      string        // base URL overrides applied on top of AIEndpointsFile
+	OpenAIBaseURL          string
+	AnthropicBaseURL       string
+	AIRetryMaxAttempts     int           // calls per AI request, retries of transient failures included (0 uses the default)
+	AIRetryBaseDelay       time.Duration // first retry wait, doubled on each retry (0 uses the default)
+	AIRetryMaxDelay        time.Duration // longest retry wait, Retry-After included (0 uses the default)
+	TenancyAuditMode       string        // off (default), log or block: cross-check admin queries against the request's user
+	InternalGRPCAddr       string        // listen address of the internal gRPC API, e.g. :9090 (empty disables it)
+	InternalAPIToken       string        // bearer token internal gRPC callers must send
+	AlertActiveRuns        int           // concurrent flow runs that alert the operator (0 disables)
+	AlertWaiting           int           // conversations waiting for a reply that alert the operator (0 disables)
+	AlertSchedulerBacklog  int           // overdue delayed executions that alert the operator (0 disables)
+	AlertSendQueue         int           // outbound messages in flight that alert the operator (0 disables)
+	AlertWebhookURL        string        // execution saturation alerts are POSTed here (empty logs them only)
+	InboundEmailDomain     string        // domain whose mail is posted to /api/email/inbound, e.g. reply.example.com (empty disables email replies)
+	InboundEmailToken      string        // token the inbound mail webhook must carry in ?token= (empty accepts any)
+	FlowAdminOnlyNodes     string        // comma-separated node types team agents cannot edit in flows (empty uses the default)
+	ConsistencyAutoRepair  bool          // the startup consistency check also repairs what it finds
+	SheetsCredentialsFile  string        // Google service account JSON key for sheet exports (empty disables them)
 }
 
 func Load() *Config {
@@ -28,6 +65,39 @@ func Load() *Config {
 		BillplzAPIKey:          os.Getenv("BILLPLZ_API_KEY"),
 		BillplzCollectionID:    os.Getenv("BILLPLZ_COLLECTION_ID"),
 		ServerURL:              getEnv("SERVER_URL", "http://localhost:8080"),
+		TranslateProvider:      os.Getenv("TRANSLATE_PROVIDER"),
+		TranslateAPIURL:        os.Getenv("TRANSLATE_API_URL"),
+		TranslateAPIKey:        os.Getenv("TRANSLATE_API_KEY"),
+		FlowRunTimeout:         getSecondsEnv("FLOW_RUN_TIMEOUT_SECONDS"),
+		NodeTimeout:            getSecondsEnv("FLOW_NODE_TIMEOUT_SECONDS"),
+		ExternalCallTimeout:    getSecondsEnv("EXTERNAL_CALL_TIMEOUT_SECONDS"),
+		FlowMaxSteps:           getIntEnv("FLOW_MAX_STEPS"),
+		FlowMaxNodeVisits:      getIntEnv("FLOW_MAX_NODE_VISITS"),
+		MaxBodyBytes:           getIntEnv("MAX_BODY_BYTES"),
+		WebhookMaxBodyBytes:    getIntEnv("WEBHOOK_MAX_BODY_BYTES"),
+		RedisURL:               os.Getenv("REDIS_URL"),
+		ConversationCacheTTL:   getSecondsEnv("CONVERSATION_CACHE_TTL_SECONDS"),
+		LinkTrackingBaseURL:    os.Getenv("LINK_TRACKING_BASE_URL"),
+		AIEndpointsFile:        os.Getenv("AI_ENDPOINTS_FILE"),
+		OpenRouterBaseURL:      os.Getenv("OPENROUTER_BASE_URL"),
+		OpenAIBaseURL:          os.Getenv("OPENAI_BASE_URL"),
+		AnthropicBaseURL:       os.Getenv("ANTHROPIC_BASE_URL"),
+		AIRetryMaxAttempts:     getIntEnv("AI_RETRY_MAX_ATTEMPTS"),
+		AIRetryBaseDelay:       getSecondsEnv("AI_RETRY_BASE_DELAY_SECONDS"),
+		AIRetryMaxDelay:        getSecondsEnv("AI_RETRY_MAX_DELAY_SECONDS"),
+		TenancyAuditMode:       getEnv("TENANCY_AUDIT_MODE", "off"),
+		InternalGRPCAddr:       os.Getenv("INTERNAL_GRPC_ADDR"),
+		InternalAPIToken:       os.Getenv("INTERNAL_API_TOKEN"),
+		AlertActiveRuns:        getIntEnv("ALERT_ACTIVE_RUNS"),
+		AlertWaiting:           getIntEnv("ALERT_WAITING_CONVERSATIONS"),
+		AlertSchedulerBacklog:  getIntEnv("ALERT_SCHEDULER_BACKLOG"),
+		AlertSendQueue:         getIntEnv("ALERT_SEND_QUEUE_DEPTH"),
+		AlertWebhookURL:        os.Getenv("ALERT_WEBHOOK_URL"),
+		InboundEmailDomain:     os.Getenv("INBOUND_EMAIL_DOMAIN"),
+		InboundEmailToken:      os.Getenv("INBOUND_EMAIL_TOKEN"),
+		FlowAdminOnlyNodes:     os.Getenv("FLOW_ADMIN_ONLY_NODES"),
+		ConsistencyAutoRepair:  os.Getenv("CONSISTENCY_AUTO_REPAIR") == "true",
+		SheetsCredentialsFile:  os.Getenv("GOOGLE_SHEETS_CREDENTIALS_FILE"),
 	}
 }
 
@@ -37,3 +107,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getSecondsEnv reads a duration in whole seconds; unset or invalid values return 0
+func getSecondsEnv(key string) time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// getIntEnv reads a positive integer; unset or invalid values return 0
+func getIntEnv(key string) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return 0
+	}
+	return value
+}
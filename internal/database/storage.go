@@ -0,0 +1,149 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// storageUploadTimeout bounds one object upload, which can be much larger than a REST row
+const storageUploadTimeout = 5 * time.Minute
+
+// UploadObject stores data in a Supabase Storage bucket using the service role key, replacing any
+// object already at path
+func (s *SupabaseClient) UploadObject(ctx context.Context, bucket, path, contentType string, data []byte) error {
+	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.URL, bucket, path)
+
+	ctx, cancel := context.WithTimeout(ctx, storageUploadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-upsert", "true")
+
+	// The REST client timeout is too short for archives
+	client := &http.Client{Transport: s.HTTPClient.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("supabase storage error: %s - %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+// CreateSignedURL returns a URL that downloads an object from a private bucket until it expires
+func (s *SupabaseClient) CreateSignedURL(ctx context.Context, bucket, path string, expiresIn time.Duration) (string, error) {
+	url := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s", s.URL, bucket, path)
+
+	payload, err := json.Marshal(map[string]int{"expiresIn": int(expiresIn.Seconds())})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("supabase storage error: %s - %s", resp.Status, string(body))
+	}
+
+	var signed struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.Unmarshal(body, &signed); err != nil || signed.SignedURL == "" {
+		return "", fmt.Errorf("supabase storage: unexpected sign response %s", string(body))
+	}
+
+	// signedURL is relative to the storage API, e.g. /object/sign/<bucket>/<path>?token=...
+	return s.URL + "/storage/v1" + signed.SignedURL, nil
+}
+
+// DownloadObject reads an object from a bucket using the service role key
+func (s *SupabaseClient) DownloadObject(ctx context.Context, bucket, path string) ([]byte, error) {
+	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.URL, bucket, path)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("supabase storage error: %s - %s", resp.Status, string(body))
+	}
+	return body, nil
+}
+
+// DeleteObjects removes objects from a bucket using the service role key. Paths that do not
+// exist are ignored.
+func (s *SupabaseClient) DeleteObjects(ctx context.Context, bucket string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	url := fmt.Sprintf("%s/storage/v1/object/%s", s.URL, bucket)
+
+	payload, err := json.Marshal(map[string][]string{"prefixes": paths})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("supabase storage error: %s - %s", resp.Status, string(body))
+	}
+	return nil
+}
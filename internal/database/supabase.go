@@ -2,10 +2,13 @@ package database
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +18,7 @@ type SupabaseClient struct {
 	AnonKey    string
 	ServiceKey string
 	HTTPClient *http.Client
+	audit      *tenancyAuditor // nil unless EnableTenancyAudit was called
 }
 
 // NewSupabaseClient creates a new Supabase client
@@ -30,20 +34,28 @@ func NewSupabaseClient(url, anonKey, serviceKey string) *SupabaseClient {
 }
 
 // Query executes a SELECT query on a table (uses anon key, RLS applies)
-func (s *SupabaseClient) Query(table string, params map[string]string) ([]byte, error) {
-	return s.queryWithKey(table, params, s.AnonKey)
+func (s *SupabaseClient) Query(ctx context.Context, table string, params map[string]string) ([]byte, error) {
+	return s.queryWithKey(ctx, table, params, s.AnonKey)
 }
 
-// QueryAsAdmin executes a SELECT query on a table using service role key (bypasses RLS)
-func (s *SupabaseClient) QueryAsAdmin(table string, params map[string]string) ([]byte, error) {
-	return s.queryWithKey(table, params, s.ServiceKey)
+// QueryAsAdmin executes a SELECT query on a table using service role key (bypasses RLS).
+// With the tenancy audit on, returned rows are checked against the request's user.
+func (s *SupabaseClient) QueryAsAdmin(ctx context.Context, table string, params map[string]string) ([]byte, error) {
+	body, err := s.queryWithKey(ctx, table, params, s.ServiceKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit.checkRows(ctx, "select", table, body); err != nil {
+		return nil, err
+	}
+	return body, nil
 }
 
 // queryWithKey executes a SELECT query with a specific API key
-func (s *SupabaseClient) queryWithKey(table string, params map[string]string, apiKey string) ([]byte, error) {
+func (s *SupabaseClient) queryWithKey(ctx context.Context, table string, params map[string]string, apiKey string) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s", s.URL, table)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -78,18 +90,65 @@ func (s *SupabaseClient) queryWithKey(table string, params map[string]string, ap
 	return body, nil
 }
 
+// CountAsAdmin returns how many rows of a table match params using the service role key, without
+// fetching them. Only a number comes back, so the tenancy audit has no rows to check.
+func (s *SupabaseClient) CountAsAdmin(ctx context.Context, table string, params map[string]string) (int, error) {
+	url := fmt.Sprintf("%s/rest/v1/%s", s.URL, table)
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	q := req.URL.Query()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("Prefer", "count=exact")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("supabase error: %s", resp.Status)
+	}
+
+	// Content-Range is "0-24/3573", or "*/0" when nothing matches
+	contentRange := resp.Header.Get("Content-Range")
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return 0, fmt.Errorf("supabase count: unexpected Content-Range %q", contentRange)
+	}
+	count, err := strconv.Atoi(contentRange[slash+1:])
+	if err != nil {
+		return 0, fmt.Errorf("supabase count: unexpected Content-Range %q", contentRange)
+	}
+	return count, nil
+}
+
 // Insert inserts a new record into a table (uses anon key, RLS applies)
-func (s *SupabaseClient) Insert(table string, data interface{}) ([]byte, error) {
-	return s.insertWithKey(table, data, s.AnonKey)
+func (s *SupabaseClient) Insert(ctx context.Context, table string, data interface{}) ([]byte, error) {
+	return s.insertWithKey(ctx, table, data, s.AnonKey)
 }
 
-// InsertAsAdmin inserts a new record using service role key (bypasses RLS)
-func (s *SupabaseClient) InsertAsAdmin(table string, data interface{}) ([]byte, error) {
-	return s.insertWithKey(table, data, s.ServiceKey)
+// InsertAsAdmin inserts a new record using service role key (bypasses RLS).
+// With the tenancy audit on, the new rows are checked before they are written.
+func (s *SupabaseClient) InsertAsAdmin(ctx context.Context, table string, data interface{}) ([]byte, error) {
+	if err := s.audit.checkPayload(ctx, "insert", table, data); err != nil {
+		return nil, err
+	}
+	return s.insertWithKey(ctx, table, data, s.ServiceKey)
 }
 
 // insertWithKey inserts a new record with a specific API key
-func (s *SupabaseClient) insertWithKey(table string, data interface{}, apiKey string) ([]byte, error) {
+func (s *SupabaseClient) insertWithKey(ctx context.Context, table string, data interface{}, apiKey string) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s", s.URL, table)
 
 	jsonData, err := json.Marshal(data)
@@ -97,7 +156,7 @@ func (s *SupabaseClient) insertWithKey(table string, data interface{}, apiKey st
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -126,17 +185,24 @@ func (s *SupabaseClient) insertWithKey(table string, data interface{}, apiKey st
 }
 
 // Update updates a record in a table (uses anon key, RLS applies)
-func (s *SupabaseClient) Update(table string, filter map[string]string, data interface{}) ([]byte, error) {
-	return s.updateWithKey(table, filter, data, s.AnonKey)
+func (s *SupabaseClient) Update(ctx context.Context, table string, filter map[string]string, data interface{}) ([]byte, error) {
+	return s.updateWithKey(ctx, table, filter, data, s.AnonKey)
 }
 
-// UpdateAsAdmin updates a record using service role key (bypasses RLS)
-func (s *SupabaseClient) UpdateAsAdmin(table string, filter map[string]string, data interface{}) ([]byte, error) {
-	return s.updateWithKey(table, filter, data, s.ServiceKey)
+// UpdateAsAdmin updates a record using service role key (bypasses RLS).
+// With the tenancy audit on, the matched rows and the new values are checked before writing.
+func (s *SupabaseClient) UpdateAsAdmin(ctx context.Context, table string, filter map[string]string, data interface{}) ([]byte, error) {
+	if err := s.audit.checkFilter(ctx, "update", table, filter); err != nil {
+		return nil, err
+	}
+	if err := s.audit.checkPayload(ctx, "update", table, data); err != nil {
+		return nil, err
+	}
+	return s.updateWithKey(ctx, table, filter, data, s.ServiceKey)
 }
 
 // updateWithKey updates a record with a specific API key
-func (s *SupabaseClient) updateWithKey(table string, filter map[string]string, data interface{}, apiKey string) ([]byte, error) {
+func (s *SupabaseClient) updateWithKey(ctx context.Context, table string, filter map[string]string, data interface{}, apiKey string) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s", s.URL, table)
 
 	jsonData, err := json.Marshal(data)
@@ -144,7 +210,7 @@ func (s *SupabaseClient) updateWithKey(table string, filter map[string]string, d
 		return nil, err
 	}
 
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -180,20 +246,24 @@ func (s *SupabaseClient) updateWithKey(table string, filter map[string]string, d
 }
 
 // Delete deletes a record from a table (uses anon key, RLS applies)
-func (s *SupabaseClient) Delete(table string, filter map[string]string) error {
-	return s.deleteWithKey(table, filter, s.AnonKey)
+func (s *SupabaseClient) Delete(ctx context.Context, table string, filter map[string]string) error {
+	return s.deleteWithKey(ctx, table, filter, s.AnonKey)
 }
 
-// DeleteAsAdmin deletes a record using service role key (bypasses RLS)
-func (s *SupabaseClient) DeleteAsAdmin(table string, filter map[string]string) error {
-	return s.deleteWithKey(table, filter, s.ServiceKey)
+// DeleteAsAdmin deletes a record using service role key (bypasses RLS).
+// With the tenancy audit on, the matched rows are checked before deleting.
+func (s *SupabaseClient) DeleteAsAdmin(ctx context.Context, table string, filter map[string]string) error {
+	if err := s.audit.checkFilter(ctx, "delete", table, filter); err != nil {
+		return err
+	}
+	return s.deleteWithKey(ctx, table, filter, s.ServiceKey)
 }
 
 // deleteWithKey deletes a record with a specific API key
-func (s *SupabaseClient) deleteWithKey(table string, filter map[string]string, apiKey string) error {
+func (s *SupabaseClient) deleteWithKey(ctx context.Context, table string, filter map[string]string, apiKey string) error {
 	url := fmt.Sprintf("%s/rest/v1/%s", s.URL, table)
 
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return err
 	}
@@ -223,9 +293,9 @@ func (s *SupabaseClient) deleteWithKey(table string, filter map[string]string, a
 }
 
 // TestConnection tests the connection to Supabase
-func (s *SupabaseClient) TestConnection() error {
+func (s *SupabaseClient) TestConnection(ctx context.Context) error {
 	// Try to query the user table (should exist after schema execution)
-	_, err := s.Query("user", map[string]string{
+	_, err := s.Query(ctx, "user", map[string]string{
 		"select": "id",
 		"limit":  "1",
 	})
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCancelledContextStopsRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	client := NewSupabaseClient(server.URL, "anon", "service")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	filter := map[string]string{"id": "1"}
+	for name, call := range map[string]func() error{
+		"query":  func() error { _, err := client.QueryAsAdmin(ctx, "device_setting", filter); return err },
+		"insert": func() error { _, err := client.InsertAsAdmin(ctx, "device_setting", filter); return err },
+		"update": func() error { _, err := client.UpdateAsAdmin(ctx, "device_setting", filter, filter); return err },
+		"delete": func() error { return client.DeleteAsAdmin(ctx, "device_setting", filter) },
+	} {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s error = %v, want context.Canceled", name, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("server received %d requests after cancellation", n)
+	}
+}
@@ -0,0 +1,342 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tenancy audit modes (TENANCY_AUDIT_MODE). The audit cross-checks every admin (RLS bypassing)
+// query made while serving an authenticated request against that user's ownership, catching
+// service methods that forget an ownership check before hitting a repository.
+const (
+	TenancyAuditOff   = "off"
+	TenancyAuditLog   = "log"   // log violations and let the query through
+	TenancyAuditBlock = "block" // log violations and fail the query with ErrTenancyViolation
+)
+
+// TenantKey is the fiber Locals key holding the authenticated user ID. Fiber locals are request
+// context values, so repositories called with c.Context() see it.
+const TenantKey = "tenant_user_id"
+
+// ErrTenancyViolation is returned in block mode when a query touches another user's rows
+var ErrTenancyViolation = errors.New("tenancy violation")
+
+// tenancyDeviceTTL is how long the devices a user reaches are cached for id_device checks
+const tenancyDeviceTTL = 30 * time.Second
+
+type tenantContextKey struct{}
+
+// WithTenant marks ctx as acting for userID, for callers outside a fiber request
+func WithTenant(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, userID)
+}
+
+// TenantFromContext returns the user ctx acts for, empty for system work (webhooks, schedulers)
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if userID, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return userID
+	}
+	userID, _ := ctx.Value(TenantKey).(string)
+	return userID
+}
+
+// EnableTenancyAudit turns the audit on in log or block mode; off (or empty) turns it off
+func (s *SupabaseClient) EnableTenancyAudit(mode string) error {
+	switch mode {
+	case "", TenancyAuditOff:
+		s.audit = nil
+	case TenancyAuditLog, TenancyAuditBlock:
+		s.audit = &tenancyAuditor{
+			client:  s,
+			block:   mode == TenancyAuditBlock,
+			devices: make(map[string]tenantDevices),
+		}
+	default:
+		return fmt.Errorf("unknown tenancy audit mode %q: use off, log or block", mode)
+	}
+	return nil
+}
+
+// tenancyAuditor checks rows by their owner columns: user.id, user_id, and id_device (which must
+// be one of the user's devices or of the devices a team shares with them). Rows of another user
+// are accepted when they belong to a device shared with the tenant. Rows without these columns
+// are not checked.
+type tenancyAuditor struct {
+	client *SupabaseClient
+	block  bool
+
+	mu      sync.Mutex
+	devices map[string]tenantDevices
+}
+
+type tenantDevices struct {
+	ids       map[string]bool
+	expiresAt time.Time
+}
+
+// checkRows checks the JSON array a query returned
+func (a *tenancyAuditor) checkRows(ctx context.Context, op, table string, body []byte) error {
+	if a == nil {
+		return nil
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return nil
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil
+	}
+	return a.checkOwnership(ctx, tenant, op, table, rows)
+}
+
+// checkPayload checks rows about to be written (an object or an array of objects)
+func (a *tenancyAuditor) checkPayload(ctx context.Context, op, table string, data interface{}) error {
+	if a == nil {
+		return nil
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return nil
+	}
+	switch table {
+	case "device_setting":
+		a.forgetDevices(tenant)
+	case "team_members":
+		a.forgetAllDevices()
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(encoded, &rows); err != nil {
+		var row map[string]interface{}
+		if err := json.Unmarshal(encoded, &row); err != nil {
+			return nil
+		}
+		rows = []map[string]interface{}{row}
+	}
+	return a.checkOwnership(ctx, tenant, op, table, rows)
+}
+
+// checkFilter loads the rows an update or delete filter matches and checks them before the write
+func (a *tenancyAuditor) checkFilter(ctx context.Context, op, table string, filter map[string]string) error {
+	if a == nil {
+		return nil
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return nil
+	}
+
+	params := map[string]string{"select": "*"}
+	for key, value := range filter {
+		params[key] = fmt.Sprintf("eq.%s", value)
+	}
+	body, err := a.client.queryWithKey(ctx, table, params, a.client.ServiceKey)
+	if err != nil {
+		log.Printf("⚠️  Tenancy audit could not load %s rows for %s: %v", table, op, err)
+		return nil
+	}
+	return a.checkRows(ctx, op, table, body)
+}
+
+// checkOwnership reports the first row that does not belong to tenant
+func (a *tenancyAuditor) checkOwnership(ctx context.Context, tenant, op, table string, rows []map[string]interface{}) error {
+	for _, row := range rows {
+		switch table {
+		case "user":
+			if id, ok := row["id"].(string); ok && id != "" && id != tenant {
+				return a.report(tenant, op, table, fmt.Sprintf("user %s", id))
+			}
+			continue
+		case "team_members":
+			// Owners manage their members, members read their own memberships
+			owner, _ := row["owner_id"].(string)
+			member, _ := row["user_id"].(string)
+			if owner != tenant && member != tenant {
+				return a.report(tenant, op, table, fmt.Sprintf("team of %s", owner))
+			}
+			continue
+		}
+		// user_id is authoritative when present, so a device being created is not checked by id_device.
+		// Another user's row is only accepted on a device of theirs shared with the tenant.
+		if owner, ok := row["user_id"].(string); ok && owner != "" {
+			if owner != tenant && !a.reachesRowDevice(ctx, tenant, table, row) {
+				return a.report(tenant, op, table, fmt.Sprintf("user_id %s", owner))
+			}
+			continue
+		}
+		if device, ok := row["id_device"].(string); ok && device != "" && !a.reachesDevice(ctx, tenant, device) {
+			return a.report(tenant, op, table, fmt.Sprintf("id_device %s", device))
+		}
+	}
+	return nil
+}
+
+// report logs a violation with the repository call that made it, and fails it in block mode
+func (a *tenancyAuditor) report(tenant, op, table, owner string) error {
+	mode := TenancyAuditLog
+	if a.block {
+		mode = TenancyAuditBlock
+	}
+	log.Printf("🚨 Tenancy violation [%s] user %s: %s %s touched a row owned by %s (from %s)",
+		mode, tenant, op, table, owner, auditCaller())
+
+	if a.block {
+		return fmt.Errorf("%w: %s %s", ErrTenancyViolation, op, table)
+	}
+	return nil
+}
+
+// auditCaller names the first function outside this package, normally the repository method
+func auditCaller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/internal/database.") {
+			return fmt.Sprintf("%s:%d", frame.Function, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// reachesRowDevice reports whether a row belongs to a device the tenant reaches: the device itself
+// for device_setting rows, its id_device for other tables
+func (a *tenancyAuditor) reachesRowDevice(ctx context.Context, tenant, table string, row map[string]interface{}) bool {
+	columns := []string{"id_device"}
+	if table == "device_setting" {
+		columns = []string{"id", "id_device", "device_id"}
+	}
+	for _, column := range columns {
+		if device, ok := row[column].(string); ok && device != "" && a.reachesDevice(ctx, tenant, device) {
+			return true
+		}
+	}
+	return false
+}
+
+// reachesDevice reports whether device is the id, id_device or device_id of one of tenant's
+// devices or of a device a team shares with them
+func (a *tenancyAuditor) reachesDevice(ctx context.Context, tenant, device string) bool {
+	a.mu.Lock()
+	cached, ok := a.devices[tenant]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.ids[device]
+	}
+
+	ids, err := a.loadDevices(ctx, tenant)
+	if err != nil {
+		log.Printf("⚠️  Tenancy audit could not load devices for user %s: %v", tenant, err)
+		return true
+	}
+
+	a.mu.Lock()
+	a.devices[tenant] = tenantDevices{ids: ids, expiresAt: time.Now().Add(tenancyDeviceTTL)}
+	a.mu.Unlock()
+	return ids[device]
+}
+
+// loadDevices collects the identifiers of tenant's own devices and of the devices their team
+// memberships share: all of the owner's devices, or those whose id_device is in device_ids
+func (a *tenancyAuditor) loadDevices(ctx context.Context, tenant string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	if err := a.addDevices(ctx, ids, tenant, nil); err != nil {
+		return nil, err
+	}
+
+	body, err := a.client.queryWithKey(ctx, "team_members", map[string]string{
+		"select":  "owner_id,device_ids",
+		"user_id": fmt.Sprintf("eq.%s", tenant),
+	}, a.client.ServiceKey)
+	if err != nil {
+		return nil, err
+	}
+	var memberships []struct {
+		OwnerID   string   `json:"owner_id"`
+		DeviceIDs []string `json:"device_ids"`
+	}
+	if err := json.Unmarshal(body, &memberships); err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if err := a.addDevices(ctx, ids, membership.OwnerID, membership.DeviceIDs); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// addDevices adds the id, id_device and device_id of owner's devices to ids, limited to the
+// devices keyed by shared when it is not empty
+func (a *tenancyAuditor) addDevices(ctx context.Context, ids map[string]bool, owner string, shared []string) error {
+	body, err := a.client.queryWithKey(ctx, "device_setting", map[string]string{
+		"select":  "id,id_device,device_id",
+		"user_id": fmt.Sprintf("eq.%s", owner),
+	}, a.client.ServiceKey)
+	if err != nil {
+		return err
+	}
+
+	var devices []map[string]interface{}
+	if err := json.Unmarshal(body, &devices); err != nil {
+		return err
+	}
+	for _, d := range devices {
+		if len(shared) > 0 && !sharedDevice(d, shared) {
+			continue
+		}
+		for _, column := range []string{"id", "id_device", "device_id"} {
+			if id, ok := d[column].(string); ok && id != "" {
+				ids[id] = true
+			}
+		}
+	}
+	return nil
+}
+
+// sharedDevice reports whether a device row is keyed by one of a membership's device_ids, which
+// name devices by id_device (device_id for devices without one)
+func sharedDevice(device map[string]interface{}, shared []string) bool {
+	key, _ := device["id_device"].(string)
+	if key == "" {
+		key, _ = device["device_id"].(string)
+	}
+	for _, id := range shared {
+		if id == key {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetDevices drops the cached device list after the tenant writes device_setting
+func (a *tenancyAuditor) forgetDevices(tenant string) {
+	a.mu.Lock()
+	delete(a.devices, tenant)
+	a.mu.Unlock()
+}
+
+// forgetAllDevices drops every cached device list after a team membership changes
+func (a *tenancyAuditor) forgetAllDevices() {
+	a.mu.Lock()
+	a.devices = make(map[string]tenantDevices)
+	a.mu.Unlock()
+}
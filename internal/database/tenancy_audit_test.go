@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakePostgREST serves the rows of each table, applying the eq. filters of a select
+func fakePostgREST(t *testing.T, tables map[string][]map[string]interface{}) *SupabaseClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matched := []map[string]interface{}{}
+	rows:
+		for _, row := range tables[strings.TrimPrefix(r.URL.Path, "/rest/v1/")] {
+			for column, values := range r.URL.Query() {
+				if value, ok := strings.CutPrefix(values[0], "eq."); ok && fmt.Sprint(row[column]) != value {
+					continue rows
+				}
+			}
+			matched = append(matched, row)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(matched)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewSupabaseClient(server.URL, "anon", "service")
+}
+
+func TestTenancyAuditBlockAllowsTeamSharedDevices(t *testing.T) {
+	client := fakePostgREST(t, map[string][]map[string]interface{}{
+		"device_setting": {
+			{"id": "uuid-shop", "id_device": "shop", "device_id": "shop", "user_id": "owner"},
+			{"id": "uuid-private", "id_device": "private", "device_id": "private", "user_id": "owner"},
+			{"id": "uuid-own", "id_device": "own", "device_id": "own", "user_id": "member"},
+		},
+		"team_members": {
+			{"owner_id": "owner", "user_id": "member", "role": "viewer", "device_ids": []string{"shop"}},
+		},
+		"ai_whatsapp": {
+			{"id_device": "shop", "prospect_num": "60123"},
+			{"id_device": "private", "prospect_num": "60124"},
+		},
+		"chatbot_flows": {
+			{"id_device": "own"},
+		},
+	})
+	if err := client.EnableTenancyAudit(TenancyAuditBlock); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithTenant(context.Background(), "member")
+
+	for _, tt := range []struct {
+		name    string
+		table   string
+		filter  map[string]string
+		blocked bool
+	}{
+		{"shared device", "device_setting", map[string]string{"id": "eq.uuid-shop"}, false},
+		{"conversation on shared device", "ai_whatsapp", map[string]string{"id_device": "eq.shop"}, false},
+		{"own device row", "chatbot_flows", nil, false},
+		{"own membership", "team_members", map[string]string{"user_id": "eq.member"}, false},
+		{"unshared device", "device_setting", map[string]string{"id": "eq.uuid-private"}, true},
+		{"conversation on unshared device", "ai_whatsapp", map[string]string{"id_device": "eq.private"}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]string{"select": "*"}
+			for key, value := range tt.filter {
+				params[key] = value
+			}
+
+			_, err := client.QueryAsAdmin(ctx, tt.table, params)
+			if blocked := errors.Is(err, ErrTenancyViolation); blocked != tt.blocked {
+				t.Fatalf("QueryAsAdmin(%s) error = %v, want blocked = %v", tt.table, err, tt.blocked)
+			}
+		})
+	}
+}
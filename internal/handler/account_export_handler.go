@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccountExportHandler handles account backup requests
+type AccountExportHandler struct {
+	exportService *service.AccountExportService
+	authService   *service.AuthService
+}
+
+// NewAccountExportHandler creates a new account export handler
+func NewAccountExportHandler(exportService *service.AccountExportService, authService *service.AuthService) *AccountExportHandler {
+	return &AccountExportHandler{
+		exportService: exportService,
+		authService:   authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *AccountExportHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// StartExport starts building a backup archive of all the user's data
+// POST /api/account/export
+func (h *AccountExportHandler) StartExport(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.exportService.StartExport(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to start account export",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(resp)
+}
+
+// GetExport returns an export's status and, once it is ready, a signed download link
+// GET /api/account/export/:id
+func (h *AccountExportHandler) GetExport(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.exportService.GetExport(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get account export",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"bytes"
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
+	"encoding/csv"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,31 +25,40 @@ func NewAnalyticsHandler(analyticsService *service.AnalyticsService, authService
 	}
 }
 
-// GetDashboard retrieves overall dashboard metrics
-// GET /api/analytics/dashboard
-func (h *AnalyticsHandler) GetDashboard(c *fiber.Ctx) error {
-	// Extract JWT
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Missing authorization token",
-		})
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *AnalyticsHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
 	}
 
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
 	}
 
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
 	claims, err := h.authService.ValidateToken(token)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Invalid or expired token",
-		})
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
 	}
 
-	userID := claims.UserID
+	return claims.UserID, nil
+}
+
+// GetDashboard retrieves overall dashboard metrics
+// GET /api/analytics/dashboard
+func (h *AnalyticsHandler) GetDashboard(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
 
 	// Parse query parameters
 	var req models.AnalyticsRequest
@@ -73,29 +86,11 @@ func (h *AnalyticsHandler) GetDashboard(c *fiber.Ctx) error {
 // GetConversationAnalytics retrieves conversation analytics
 // GET /api/analytics/conversations
 func (h *AnalyticsHandler) GetConversationAnalytics(c *fiber.Ctx) error {
-	// Extract JWT
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Missing authorization token",
-		})
-	}
-
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
-	}
-
-	claims, err := h.authService.ValidateToken(token)
+	userID, err := h.getUserIDFromToken(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Invalid or expired token",
-		})
+		return err
 	}
 
-	userID := claims.UserID
-
 	// Parse query parameters
 	var req models.AnalyticsRequest
 	if err := c.QueryParser(&req); err != nil {
@@ -122,28 +117,10 @@ func (h *AnalyticsHandler) GetConversationAnalytics(c *fiber.Ctx) error {
 // GetFlowAnalytics retrieves flow-specific analytics
 // GET /api/analytics/flows/:flowId
 func (h *AnalyticsHandler) GetFlowAnalytics(c *fiber.Ctx) error {
-	// Extract JWT
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Missing authorization token",
-		})
-	}
-
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
-	}
-
-	claims, err := h.authService.ValidateToken(token)
+	userID, err := h.getUserIDFromToken(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Invalid or expired token",
-		})
+		return err
 	}
-
-	userID := claims.UserID
 	flowID := c.Params("flowId")
 
 	if flowID == "" {
@@ -173,29 +150,11 @@ func (h *AnalyticsHandler) GetFlowAnalytics(c *fiber.Ctx) error {
 // ExportAnalytics exports analytics data
 // POST /api/analytics/export
 func (h *AnalyticsHandler) ExportAnalytics(c *fiber.Ctx) error {
-	// Extract JWT
-	token := c.Get("Authorization")
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Missing authorization token",
-		})
-	}
-
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
-	}
-
-	claims, err := h.authService.ValidateToken(token)
+	userID, err := h.getUserIDFromToken(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"success": false,
-			"message": "Invalid or expired token",
-		})
+		return err
 	}
 
-	userID := claims.UserID
-
 	// Parse request
 	var req models.ExportRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -223,3 +182,329 @@ func (h *AnalyticsHandler) ExportAnalytics(c *fiber.Ctx) error {
 
 	return c.JSON(response)
 }
+
+// GetCSATAnalytics retrieves CSAT survey analytics per flow, device and agent
+// GET /api/analytics/csat?device_id=&flow_id=
+func (h *AnalyticsHandler) GetCSATAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetCSATAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve CSAT analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// ExportLeadCosts exports acquisition cost per lead (AI tokens, send fees, API calls)
+// GET /api/analytics/costs/export?device_id=&format=csv|json
+func (h *AnalyticsHandler) ExportLeadCosts(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.ExportLeadCosts(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to export lead costs",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	if c.Query("format", "csv") == "json" {
+		return c.JSON(response)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id_device", "prospect_num", "total_amount", "ai_tokens", "send_fee", "api_call", "total_tokens", "entries"})
+	for _, lead := range response.Leads {
+		w.Write([]string{
+			lead.IDDevice,
+			lead.ProspectNum,
+			strconv.FormatFloat(lead.TotalAmount, 'f', 6, 64),
+			strconv.FormatFloat(lead.ByType[models.CostTypeAITokens], 'f', 6, 64),
+			strconv.FormatFloat(lead.ByType[models.CostTypeSendFee], 'f', 6, 64),
+			strconv.FormatFloat(lead.ByType[models.CostTypeAPICall], 'f', 6, 64),
+			strconv.Itoa(lead.TotalTokens),
+			strconv.Itoa(lead.Entries),
+		})
+	}
+	w.Flush()
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="lead_costs.csv"`)
+	return c.Send(buf.Bytes())
+}
+
+// GetLatencyAnalytics retrieves first-response and reply latency (p50/p95) per device, flow and agent
+// GET /api/analytics/latency?device_id=&flow_id=
+func (h *AnalyticsHandler) GetLatencyAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetLatencyAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve latency analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetSLAAnalytics retrieves stage SLA breach counts per stage, device and action
+// GET /api/analytics/sla?device_id=
+func (h *AnalyticsHandler) GetSLAAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetSLAAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve SLA analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetLinkAnalytics retrieves tracked link sends, clicks and click-through rate per message node
+// GET /api/analytics/links?device_id=&flow_id=
+func (h *AnalyticsHandler) GetLinkAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetLinkAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve link analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetABSplitAnalytics compares the variants of ab_split nodes by the stage their prospects reached
+// GET /api/analytics/ab-splits?device_id=&flow_id=
+func (h *AnalyticsHandler) GetABSplitAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetABSplitAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve A/B split analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetFieldAnalytics counts conversations per value of a conversation column or custom field
+// GET /api/analytics/fields/:name?device_id=
+func (h *AnalyticsHandler) GetFieldAnalytics(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetFieldAnalytics(c.Context(), userID, c.Params("name"), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve field analytics",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetStageFunnel reports a flow's stages in flow order with reach, drop-off and median time in stage
+// GET /api/analytics/funnel?deviceId=&flowId=
+func (h *AnalyticsHandler) GetStageFunnel(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetStageFunnel(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve stage funnel",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// GetAIUsage reports AI token usage and estimated cost per device, model, purpose and day, with budget alerts
+// GET /api/analytics/ai-usage?device_id=
+func (h *AnalyticsHandler) GetAIUsage(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse query parameters
+	var req models.AnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		// Ignore parsing errors for optional query params
+	}
+
+	response, err := h.analyticsService.GetAIUsageAnalytics(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to retrieve AI usage",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	return c.JSON(response)
+}
+
+// UpdateAIBudget sets the daily and monthly AI spend budget the AI usage alerts check
+// PUT /api/analytics/ai-usage/budget
+func (h *AnalyticsHandler) UpdateAIBudget(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateAIBudgetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	response, err := h.analyticsService.UpdateAIBudget(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update AI budget",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(response)
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHandler handles API key HTTP requests
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	authService   *service.AuthService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, authService *service.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		authService:   authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *APIKeyHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// ListKeys lists the user's API keys
+// GET /api/api-keys
+func (h *APIKeyHandler) ListKeys(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.apiKeyService.ListKeys(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to list API keys",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateKey creates a scoped API key and returns it once
+// POST /api/api-keys
+func (h *APIKeyHandler) CreateKey(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.apiKeyService.CreateKey(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create API key",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// RevokeKey revokes an API key
+// DELETE /api/api-keys/:id
+func (h *APIKeyHandler) RevokeKey(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.apiKeyService.RevokeKey(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke API key",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
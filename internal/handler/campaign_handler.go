@@ -0,0 +1,446 @@
+package handler
+
+import (
+	"bytes"
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CampaignHandler handles campaign and blacklist HTTP requests
+type CampaignHandler struct {
+	campaignService *service.CampaignService
+	authService     *service.AuthService
+}
+
+// NewCampaignHandler creates a new campaign handler
+func NewCampaignHandler(campaignService *service.CampaignService, authService *service.AuthService) *CampaignHandler {
+	return &CampaignHandler{
+		campaignService: campaignService,
+		authService:     authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *CampaignHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// RecycleProspects selects abandoned conversations and creates a draft campaign from them.
+// With dry_run and ?format=csv the selection is downloaded instead.
+// POST /api/campaigns/recycle
+func (h *CampaignHandler) RecycleProspects(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.RecycleProspectsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.DeviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "device_id is required",
+		})
+	}
+
+	resp, err := h.campaignService.RecycleProspects(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to recycle prospects",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if req.DryRun && c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"prospect_num", "prospect_name", "stage"})
+		for _, recipient := range resp.Recipients {
+			name, stage := "", ""
+			if recipient.ProspectName != nil {
+				name = *recipient.ProspectName
+			}
+			if recipient.Stage != nil {
+				stage = *recipient.Stage
+			}
+			w.Write([]string{recipient.ProspectNum, name, stage})
+		}
+		w.Flush()
+
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="recycled_prospects.csv"`)
+		return c.Send(buf.Bytes())
+	}
+
+	if resp.Campaign != nil {
+		return c.Status(fiber.StatusCreated).JSON(resp)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateCampaign creates a draft campaign from CSV or a saved segment. The body is JSON, or a
+// multipart form with the same fields and the CSV uploaded as file.
+// POST /api/campaigns
+func (h *CampaignHandler) CreateCampaign(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateCampaignRequest
+	if file, err := c.FormFile("file"); err == nil {
+		req.DeviceID = c.FormValue("device_id")
+		req.Name = c.FormValue("name")
+		if message := c.FormValue("message"); message != "" {
+			req.Message = &message
+		}
+		if flowID := c.FormValue("flow_id"); flowID != "" {
+			req.FlowID = &flowID
+		}
+		if rate := c.FormValue("rate_per_minute"); rate != "" {
+			if req.RatePerMinute, err = strconv.Atoi(rate); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"message": "rate_per_minute must be a number",
+				})
+			}
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "Failed to read uploaded file",
+			})
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "Failed to read uploaded file",
+			})
+		}
+		req.CSV = string(data)
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.DeviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "device_id is required",
+		})
+	}
+
+	resp, err := h.campaignService.CreateCampaign(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create campaign",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ListCampaigns lists the user's campaigns
+// GET /api/campaigns
+func (h *CampaignHandler) ListCampaigns(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.campaignService.ListCampaigns(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get campaigns",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetCampaign returns a campaign with its progress
+// GET /api/campaigns/:id
+func (h *CampaignHandler) GetCampaign(c *fiber.Ctx) error {
+	return h.campaignAction(c, h.campaignService.GetCampaign, "Failed to get campaign")
+}
+
+// GetCampaignRecipients lists a campaign's recipients with their status
+// GET /api/campaigns/:id/recipients?status=
+func (h *CampaignHandler) GetCampaignRecipients(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.campaignService.GetCampaignRecipients(c.Context(), userID, c.Params("id"), c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get campaign recipients",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// StartCampaign starts sending a draft campaign
+// POST /api/campaigns/:id/start
+func (h *CampaignHandler) StartCampaign(c *fiber.Ctx) error {
+	return h.campaignAction(c, h.campaignService.StartCampaign, "Failed to start campaign")
+}
+
+// PauseCampaign pauses a running campaign
+// POST /api/campaigns/:id/pause
+func (h *CampaignHandler) PauseCampaign(c *fiber.Ctx) error {
+	return h.campaignAction(c, h.campaignService.PauseCampaign, "Failed to pause campaign")
+}
+
+// ResumeCampaign resumes a paused campaign
+// POST /api/campaigns/:id/resume
+func (h *CampaignHandler) ResumeCampaign(c *fiber.Ctx) error {
+	return h.campaignAction(c, h.campaignService.ResumeCampaign, "Failed to resume campaign")
+}
+
+// CancelCampaign cancels a campaign
+// POST /api/campaigns/:id/cancel
+func (h *CampaignHandler) CancelCampaign(c *fiber.Ctx) error {
+	return h.campaignAction(c, h.campaignService.CancelCampaign, "Failed to cancel campaign")
+}
+
+// campaignAction runs a service call on the campaign in the :id parameter
+func (h *CampaignHandler) campaignAction(c *fiber.Ctx, action func(context.Context, string, string) (*models.CampaignResponse, error), failure string) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := action(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": failure,
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ListSegments lists the user's saved segments
+// GET /api/segments
+func (h *CampaignHandler) ListSegments(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.campaignService.ListSegments(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get segments",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateSegment saves a segment
+// POST /api/segments
+func (h *CampaignHandler) CreateSegment(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateSegmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.campaignService.CreateSegment(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create segment",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// DeleteSegment deletes a segment
+// DELETE /api/segments/:id
+func (h *CampaignHandler) DeleteSegment(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.campaignService.DeleteSegment(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete segment",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetBlacklist lists the user's blacklisted and opted-out numbers
+// GET /api/blacklist
+func (h *CampaignHandler) GetBlacklist(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.campaignService.GetBlacklist(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get blacklist",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// AddToBlacklist blacklists or opts out a number
+// POST /api/blacklist
+func (h *CampaignHandler) AddToBlacklist(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.AddBlacklistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.campaignService.AddToBlacklist(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to add to blacklist",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// RemoveFromBlacklist removes a number from the blacklist
+// DELETE /api/blacklist/:phone
+func (h *CampaignHandler) RemoveFromBlacklist(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	phone := c.Params("phone")
+	if phone == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Phone number is required",
+		})
+	}
+
+	resp, err := h.campaignService.RemoveFromBlacklist(c.Context(), userID, phone)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to remove from blacklist",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/service"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConsentHandler handles consent registry HTTP requests
+type ConsentHandler struct {
+	consentService *service.ConsentService
+	authService    *service.AuthService
+}
+
+// NewConsentHandler creates a new consent handler
+func NewConsentHandler(consentService *service.ConsentService, authService *service.AuthService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+		authService:    authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *ConsentHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// ExportConsent exports the consent registry for compliance audits (CSV by default, format=json for JSON)
+// GET /api/consent/export?device_id=&format=
+func (h *ConsentHandler) ExportConsent(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	response, err := h.consentService.ExportConsent(c.Context(), userID, c.Query("device_id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to export consent records",
+			"error":   err.Error(),
+		})
+	}
+
+	if !response.Success {
+		return c.Status(fiber.StatusForbidden).JSON(response)
+	}
+
+	if c.Query("format", "csv") == "json" {
+		return c.JSON(response)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"created_at", "id_device", "prospect_num", "granted", "wording", "response", "source", "flow_id", "node_id"})
+	for _, record := range response.Records {
+		createdAt := ""
+		if record.CreatedAt != nil {
+			createdAt = record.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		flowID, nodeID := "", ""
+		if record.FlowID != nil {
+			flowID = *record.FlowID
+		}
+		if record.NodeID != nil {
+			nodeID = *record.NodeID
+		}
+		w.Write([]string{
+			createdAt,
+			record.IDDevice,
+			record.ProspectNum,
+			strconv.FormatBool(record.Granted),
+			record.Wording,
+			record.Response,
+			record.Source,
+			flowID,
+			nodeID,
+		})
+	}
+	w.Flush()
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="consent_records.csv"`)
+	return c.Send(buf.Bytes())
+}
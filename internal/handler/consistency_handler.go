@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConsistencyHandler handles the operator's consistency check requests
+type ConsistencyHandler struct {
+	checker     *service.ConsistencyChecker
+	authService *service.AuthService
+}
+
+// NewConsistencyHandler creates a new consistency handler
+func NewConsistencyHandler(checker *service.ConsistencyChecker, authService *service.AuthService) *ConsistencyHandler {
+	return &ConsistencyHandler{
+		checker:     checker,
+		authService: authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *ConsistencyHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// requireAdmin writes a response and returns false unless the caller is an admin
+func (h *ConsistencyHandler) requireAdmin(c *fiber.Ctx) (bool, error) {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return false, err
+	}
+
+	isAdmin, err := h.authService.IsAdmin(c.Context(), userID)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to check admin status",
+			"error":   err.Error(),
+		})
+	}
+	if !isAdmin {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": "Admin access required",
+		})
+	}
+	return true, nil
+}
+
+// CheckConsistency scans for known bad states and reports them with their repairs, without
+// changing anything (admin only)
+// GET /api/maintenance/consistency
+func (h *ConsistencyHandler) CheckConsistency(c *fiber.Ctx) error {
+	if ok, err := h.requireAdmin(c); !ok {
+		return err
+	}
+
+	report, err := h.checker.Check(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to check consistency",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ConsistencyResponse{
+		Success: true,
+		Message: "Consistency check completed",
+		Report:  report,
+	})
+}
+
+// RepairConsistency runs a fresh check and repairs the repairable issues (admin only)
+// POST /api/maintenance/consistency/repair
+func (h *ConsistencyHandler) RepairConsistency(c *fiber.Ctx) error {
+	if ok, err := h.requireAdmin(c); !ok {
+		return err
+	}
+
+	var req models.ConsistencyRepairRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	resp, err := h.checker.CheckAndRepair(c.Context(), req.Kinds)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to repair consistency issues",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
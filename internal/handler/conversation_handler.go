@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
+	"encoding/csv"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,18 +18,25 @@ import (
 type ConversationHandler struct {
 	conversationService *service.ConversationService
 	authService         *service.AuthService
+	flowProcessor       *service.FlowProcessorService
 }
 
 // NewConversationHandler creates a new conversation handler
-func NewConversationHandler(conversationService *service.ConversationService, authService *service.AuthService) *ConversationHandler {
+func NewConversationHandler(conversationService *service.ConversationService, authService *service.AuthService, flowProcessor *service.FlowProcessorService) *ConversationHandler {
 	return &ConversationHandler{
 		conversationService: conversationService,
 		authService:         authService,
+		flowProcessor:       flowProcessor,
 	}
 }
 
 // getUserIDFromToken extracts user ID from JWT token in Authorization header
 func (h *ConversationHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -122,6 +135,77 @@ func (h *ConversationHandler) GetConversation(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// GetConversationByExternalRef retrieves a conversation by its external reference
+// GET /api/conversations/ref/:ref
+func (h *ConversationHandler) GetConversationByExternalRef(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	externalRef := c.Params("ref")
+	if externalRef == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "External reference is required",
+		})
+	}
+
+	resp, err := h.conversationService.GetConversationByExternalRef(c.Context(), userID, externalRef)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ListConversations retrieves a page of the user's conversations with optional filters
+// GET /api/conversations?page=&page_size=&cursor=&device_id=&stage=&status=&niche=&from=&to=&phone=
+func (h *ConversationHandler) ListConversations(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	filter := &models.ConversationFilter{
+		DeviceID: c.Query("device_id"),
+		Stage:    c.Query("stage"),
+		Status:   c.Query("status"),
+		Niche:    c.Query("niche"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Phone:    c.Query("phone"),
+		Page:     c.QueryInt("page", 1),
+		PageSize: c.QueryInt("page_size", models.DefaultConversationPageSize),
+		Cursor:   c.Query("cursor"),
+	}
+
+	resp, err := h.conversationService.ListConversations(c.Context(), userID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get conversations",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
 // GetConversationsByDevice retrieves all conversations for a device
 // GET /api/conversations/device/:deviceId?limit=50
 func (h *ConversationHandler) GetConversationsByDevice(c *fiber.Ctx) error {
@@ -148,8 +232,16 @@ func (h *ConversationHandler) GetConversationsByDevice(c *fiber.Ctx) error {
 		}
 	}
 
+	query, err := parseConversationListQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+
 	// Get conversations for device
-	resp, err := h.conversationService.GetConversationsByDevice(c.Context(), userID, deviceID, limit)
+	resp, err := h.conversationService.GetConversationsByDevice(c.Context(), userID, deviceID, limit, query)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -162,9 +254,88 @@ func (h *ConversationHandler) GetConversationsByDevice(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(resp)
 	}
 
+	if query.Export {
+		return sendConversationRowsCSV(c, resp.Columns, resp.Rows, "conversations.csv")
+	}
+
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// sendConversationRowsCSV downloads a conversation list as CSV: the base fields, then the list columns
+func sendConversationRowsCSV(c *fiber.Ctx, columns []string, rows []map[string]interface{}, filename string) error {
+	header := strings.Split(models.ConversationListBaseFields, ",")
+	for _, column := range columns {
+		if !slices.Contains(header, column) {
+			header = append(header, column)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, column := range header {
+			if value, ok := row[column]; ok && value != nil {
+				record[i] = fmt.Sprintf("%v", value)
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Send(buf.Bytes())
+}
+
+// parseConversationListQuery reads list columns, sort and filters from the query string:
+// columns=pakej,alamat  sort=pakej or sort=-tarikh_gaji  filter.pakej=Pakej A  filter.alamat=~kuala
+func parseConversationListQuery(c *fiber.Ctx) (*models.ConversationListQuery, error) {
+	query := &models.ConversationListQuery{Export: c.Query("format") == "csv"}
+
+	if columns := c.Query("columns"); columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				query.Columns = append(query.Columns, column)
+			}
+		}
+		if len(query.Columns) > models.MaxListColumns {
+			return nil, fmt.Errorf("at most %d columns can be requested", models.MaxListColumns)
+		}
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		query.Desc = strings.HasPrefix(sort, "-")
+		query.Sort = strings.TrimPrefix(sort, "-")
+	}
+
+	for key, value := range c.Queries() {
+		if column, ok := strings.CutPrefix(key, "filter."); ok {
+			if query.Filters == nil {
+				query.Filters = make(map[string]string)
+			}
+			query.Filters[column] = value
+		}
+	}
+
+	for _, column := range append(append([]string{query.Sort}, query.Columns...), filterColumns(query.Filters)...) {
+		if column != "" && !models.IsValidListColumnName(column) {
+			return nil, fmt.Errorf("invalid column name: %s", column)
+		}
+	}
+
+	return query, nil
+}
+
+func filterColumns(filters map[string]string) []string {
+	columns := make([]string, 0, len(filters))
+	for column := range filters {
+		columns = append(columns, column)
+	}
+	return columns
+}
+
 // GetActiveConversations retrieves all active conversations for a device
 // GET /api/conversations/device/:deviceId/active
 func (h *ConversationHandler) GetActiveConversations(c *fiber.Ctx) error {
@@ -244,6 +415,254 @@ func (h *ConversationHandler) UpdateConversation(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// PinConversation pins/unpins a conversation or changes its priority
+// PUT /api/conversations/:id/pin
+func (h *ConversationHandler) PinConversation(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get prospect ID from URL parameter
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	// Parse request body
+	var req models.PinConversationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	// Pinning is a regular update of the pinned/priority columns
+	resp, err := h.conversationService.UpdateConversation(c.Context(), userID, prospectID, &models.UpdateConversationRequest{
+		Pinned:   req.Pinned,
+		Priority: req.Priority,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetPinnedConversations retrieves the authenticated user's pinned conversations
+// GET /api/conversations/pinned
+func (h *ConversationHandler) GetPinnedConversations(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.conversationService.GetPinnedConversations(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get pinned conversations",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetReplySuggestions drafts 2-3 AI replies for an agent to pick from, without sending anything
+// GET /api/conversations/:id/suggestions?count=3
+func (h *ConversationHandler) GetReplySuggestions(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.conversationService.GetReplySuggestions(c.Context(), userID, prospectID, c.QueryInt("count", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate suggestions",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		if resp.Error != "" {
+			return c.Status(fiber.StatusBadGateway).JSON(resp)
+		}
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetConversationSteps lists the recorded engine states a conversation can be restored to
+// GET /api/conversations/:id/steps
+func (h *ConversationHandler) GetConversationSteps(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.conversationService.GetConversationSteps(c.Context(), userID, prospectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get conversation steps",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetExecutionLog lists the flow nodes run for a conversation, with their outcomes, oldest first
+// GET /api/conversations/:id/execution-log?limit=N
+func (h *ConversationHandler) GetExecutionLog(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.conversationService.GetExecutionLog(c.Context(), userID, prospectID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get execution log",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetMessages lists the structured message history of a conversation, oldest first
+// GET /api/conversations/:id/messages?limit=N
+func (h *ConversationHandler) GetMessages(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.conversationService.GetMessages(c.Context(), userID, prospectID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get conversation history",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreConversation rewinds a conversation's engine state to an earlier recorded step
+// POST /api/conversations/:id/restore?to_step=N
+func (h *ConversationHandler) RestoreConversation(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	toStep, err := strconv.Atoi(c.Query("to_step"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "to_step must be a step number",
+		})
+	}
+
+	resp, err := h.conversationService.RestoreConversation(c.Context(), userID, prospectID, toStep)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to restore conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
 // AddMessage adds a message to conversation history
 // POST /api/conversations/:id/messages
 func (h *ConversationHandler) AddMessage(c *fiber.Ctx) error {
@@ -296,6 +715,119 @@ func (h *ConversationHandler) AddMessage(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
+// TakeOverConversation pauses the bot so an agent can talk to the prospect
+// POST /api/conversations/:id/takeover?source=
+func (h *ConversationHandler) TakeOverConversation(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.flowProcessor.TakeOver(c.Context(), userID, c.Query("source", models.AssignmentSourceAI), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to take over conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// SendAgentMessage sends an agent's message to the prospect of a taken-over conversation
+// POST /api/conversations/:id/send?source=
+func (h *ConversationHandler) SendAgentMessage(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.AgentMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.flowProcessor.SendAgentMessage(c.Context(), userID, c.Query("source", models.AssignmentSourceAI), c.Params("id"), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to send message",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// HandBackConversation returns a taken-over conversation to the bot at a node or stage
+// POST /api/conversations/:id/resume?source=
+func (h *ConversationHandler) HandBackConversation(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.HandBackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.flowProcessor.HandBack(c.Context(), userID, c.Query("source", models.AssignmentSourceAI), c.Params("id"), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to hand conversation back",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CleanupConversations deletes a device's test conversations in one call (dry_run lists them instead)
+// DELETE /api/conversations/cleanup?device_id=&before=&status=test&dry_run=true
+func (h *ConversationHandler) CleanupConversations(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.conversationService.CleanupConversations(c.Context(), userID, c.Query("device_id"), c.Query("before"), c.Query("status"), c.QueryBool("dry_run"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to clean up conversations",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
 // DeleteConversation deletes a conversation
 // DELETE /api/conversations/:id
 func (h *ConversationHandler) DeleteConversation(c *fiber.Ctx) error {
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CustomFieldHandler handles contact custom field HTTP requests
+type CustomFieldHandler struct {
+	customFieldService *service.CustomFieldService
+	authService        *service.AuthService
+}
+
+// NewCustomFieldHandler creates a new custom field handler
+func NewCustomFieldHandler(customFieldService *service.CustomFieldService, authService *service.AuthService) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		customFieldService: customFieldService,
+		authService:        authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *CustomFieldHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// ListFields lists the user's custom fields
+// GET /api/custom-fields
+func (h *CustomFieldHandler) ListFields(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.customFieldService.ListFields(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get custom fields",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CreateField defines a new custom field
+// POST /api/custom-fields
+func (h *CustomFieldHandler) CreateField(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateCustomFieldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.customFieldService.CreateField(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create custom field",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// UpdateField changes a custom field's label, options or pattern
+// PUT /api/custom-fields/:id
+func (h *CustomFieldHandler) UpdateField(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateCustomFieldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.customFieldService.UpdateField(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update custom field",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// DeleteField removes a custom field; captured values are kept
+// DELETE /api/custom-fields/:id
+func (h *CustomFieldHandler) DeleteField(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.customFieldService.DeleteField(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete custom field",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
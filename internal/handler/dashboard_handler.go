@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
 
@@ -25,6 +26,11 @@ func NewDashboardHandler(conversationService *service.ConversationService, wasap
 
 // getUserIDFromToken extracts user ID from JWT token in Authorization header
 func (h *DashboardHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
 	"github.com/gofiber/fiber/v2"
 )
@@ -58,3 +59,36 @@ func (h *DebounceHandler) ProcessDebouncedMessages(c *fiber.Ctx) error {
 		"message": "Messages processed and response sent",
 	})
 }
+
+// GetDebounceWindow returns the adaptive debounce window for a queued message
+// Called by the debouncer for every incoming message; it falls back to its own default on failure
+func (h *DebounceHandler) GetDebounceWindow(c *fiber.Ctx) error {
+	var req models.DebounceWindowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid request body",
+		})
+	}
+
+	if req.DeviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "device_id is required",
+		})
+	}
+
+	resp, err := h.debounceService.GetDebounceWindow(c.Context(), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.JSON(resp)
+}
@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"context"
+
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
 
@@ -10,19 +13,28 @@ import (
 // DeviceHandler handles device HTTP requests
 type DeviceHandler struct {
 	deviceService *service.DeviceService
+	bundleService *service.DeviceBundleService
 	authService   *service.AuthService
+	archiver      *service.InboundArchiver
 }
 
 // NewDeviceHandler creates a new device handler
-func NewDeviceHandler(deviceService *service.DeviceService, authService *service.AuthService) *DeviceHandler {
+func NewDeviceHandler(deviceService *service.DeviceService, bundleService *service.DeviceBundleService, authService *service.AuthService, archiver *service.InboundArchiver) *DeviceHandler {
 	return &DeviceHandler{
 		deviceService: deviceService,
+		bundleService: bundleService,
 		authService:   authService,
+		archiver:      archiver,
 	}
 }
 
 // getUserIDFromToken extracts user ID from JWT token
 func (h *DeviceHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -309,3 +321,373 @@ func (h *DeviceHandler) CheckDeviceStatus(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
+
+// StartDeviceSession starts a device's provider session and returns its status and login QR code
+// POST /api/devices/:id/session/start
+func (h *DeviceHandler) StartDeviceSession(c *fiber.Ctx) error {
+	return h.deviceSessionAction(c, "Failed to start session", h.deviceService.StartDeviceSession)
+}
+
+// GetDeviceSessionQR returns a device's session status and, while it waits for a scan, the current
+// QR code; the dashboard polls it until the status is connected
+// GET /api/devices/:id/session/qr
+func (h *DeviceHandler) GetDeviceSessionQR(c *fiber.Ctx) error {
+	return h.deviceSessionAction(c, "Failed to get session QR code", h.deviceService.GetDeviceSessionQR)
+}
+
+// RestartDeviceSession restarts a device's provider session
+// POST /api/devices/:id/session/restart
+func (h *DeviceHandler) RestartDeviceSession(c *fiber.Ctx) error {
+	return h.deviceSessionAction(c, "Failed to restart session", h.deviceService.RestartDeviceSession)
+}
+
+// LogoutDeviceSession unlinks the number from a device's provider session
+// POST /api/devices/:id/session/logout
+func (h *DeviceHandler) LogoutDeviceSession(c *fiber.Ctx) error {
+	return h.deviceSessionAction(c, "Failed to log out session", h.deviceService.LogoutDeviceSession)
+}
+
+// deviceSessionAction runs a session lifecycle call for the device in the path
+func (h *DeviceHandler) deviceSessionAction(c *fiber.Ctx, failure string, action func(ctx context.Context, userID, deviceID string) (*models.DeviceStatusResponse, error)) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := action(c.Context(), userID, deviceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": failure,
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetDeviceHealth returns a device's connection status and provider latency and error rates
+// GET /api/devices/:id/health
+func (h *DeviceHandler) GetDeviceHealth(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.deviceService.GetDeviceHealth(c.Context(), userID, deviceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get device health",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ExportDeviceConfig returns a device's configuration bundle (no secrets) for import into another device
+// GET /api/devices/:id/config
+func (h *DeviceHandler) ExportDeviceConfig(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.bundleService.ExportConfig(c.Context(), userID, deviceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to export device configuration",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ImportDeviceConfig applies an exported configuration bundle to a device
+// POST /api/devices/:id/config
+func (h *DeviceHandler) ImportDeviceConfig(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	var req models.DeviceConfigImportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.bundleService.ImportConfig(c.Context(), userID, deviceID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to import device configuration",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSandboxMessages lists the sends a sandboxed device intercepted instead of delivering
+// GET /api/devices/:id/sandbox-messages?limit=
+func (h *DeviceHandler) GetSandboxMessages(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.deviceService.GetSandboxMessages(c.Context(), userID, deviceID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get sandbox messages",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSendQueue returns the depth, rate and estimated wait of a device's outbound send queue
+// GET /api/devices/:id/send-queue
+func (h *DeviceHandler) GetSendQueue(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.deviceService.GetSendQueue(c.Context(), userID, deviceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get send queue",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetDeviceProfile returns the WhatsApp display name, about text and photo of a device's number
+// GET /api/devices/:id/profile
+func (h *DeviceHandler) GetDeviceProfile(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.deviceService.GetDeviceProfile(c.Context(), userID, deviceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get device profile",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// UpdateDeviceProfile changes the WhatsApp display name, about text and/or photo of a device's number
+// PUT /api/devices/:id/profile
+func (h *DeviceHandler) UpdateDeviceProfile(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	var req models.UpdateDeviceProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	resp, err := h.deviceService.UpdateDeviceProfile(c.Context(), userID, deviceID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update device profile",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ListInboundArchives lists a device's archived raw inbound webhook payloads, newest first
+// GET /api/devices/:id/archives?from=&to=&limit=
+func (h *DeviceHandler) ListInboundArchives(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID required",
+		})
+	}
+
+	resp, err := h.archiver.ListArchives(c.Context(), userID, deviceID, c.Query("from"), c.Query("to"), c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to list archived payloads",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetInboundArchive returns one archived inbound webhook payload. JSON by default; format=raw
+// returns the body exactly as received, with its original Content-Type.
+// GET /api/devices/:id/archives/:archiveId
+func (h *DeviceHandler) GetInboundArchive(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("id")
+	archiveID := c.Params("archiveId")
+	if deviceID == "" || archiveID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID and archive ID required",
+		})
+	}
+
+	resp, err := h.archiver.GetArchive(c.Context(), userID, deviceID, archiveID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get archived payload",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	if c.Query("format") == "raw" {
+		if resp.Archive.ContentType != "" {
+			c.Set(fiber.HeaderContentType, resp.Archive.ContentType)
+		}
+		return c.Status(fiber.StatusOK).SendString(resp.Payload)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
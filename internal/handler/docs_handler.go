@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"chatbot-automation/internal/openapi"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DocsHandler serves API documentation
+type DocsHandler struct {
+	once sync.Once
+	spec *openapi.Document
+}
+
+// NewDocsHandler creates a new docs handler
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+// GetOpenAPISpec returns the OpenAPI specification for all routes
+// GET /api/docs/openapi.json
+func (h *DocsHandler) GetOpenAPISpec(c *fiber.Ctx) error {
+	h.once.Do(func() {
+		h.spec = openapi.Build(openapi.Routes)
+	})
+
+	return c.JSON(h.spec)
+}
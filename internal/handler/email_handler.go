@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EmailHandler handles email channel HTTP requests
+type EmailHandler struct {
+	emailService *service.EmailService
+	authService  *service.AuthService
+}
+
+// NewEmailHandler creates a new email handler
+func NewEmailHandler(emailService *service.EmailService, authService *service.AuthService) *EmailHandler {
+	return &EmailHandler{
+		emailService: emailService,
+		authService:  authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *EmailHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// GetSettings returns the user's email settings without their secrets
+// GET /api/email-settings
+func (h *EmailHandler) GetSettings(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.emailService.GetSettings(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get email settings",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// UpdateSettings creates or replaces the user's email settings
+// PUT /api/email-settings
+func (h *EmailHandler) UpdateSettings(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateEmailSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.emailService.UpdateSettings(c.Context(), userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to save email settings",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ReceiveInbound takes an email reply from the inbound mail provider (SendGrid Inbound Parse form
+// fields or JSON). Mail that is not a reply is acknowledged too, so the provider does not retry it.
+// POST /api/email/inbound
+func (h *EmailHandler) ReceiveInbound(c *fiber.Ctx) error {
+	if !h.emailService.InboundTokenValid(c.Query("token")) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid token",
+		})
+	}
+
+	var email models.InboundEmail
+	if err := c.BodyParser(&email); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	appended, err := h.emailService.ReceiveReply(c.Context(), &email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to store email reply",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success":  true,
+		"appended": appended,
+	})
+}
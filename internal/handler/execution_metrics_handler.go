@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExecutionMetricsHandler handles the operator's flow execution metrics requests
+type ExecutionMetricsHandler struct {
+	monitor     *service.ExecutionMonitor
+	authService *service.AuthService
+}
+
+// NewExecutionMetricsHandler creates a new execution metrics handler
+func NewExecutionMetricsHandler(monitor *service.ExecutionMonitor, authService *service.AuthService) *ExecutionMetricsHandler {
+	return &ExecutionMetricsHandler{
+		monitor:     monitor,
+		authService: authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *ExecutionMetricsHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// GetExecutionMetrics returns the current flow execution gauges, their alert thresholds and the
+// gauges alerting now (admin only)
+// GET /api/maintenance/execution-metrics
+func (h *ExecutionMetricsHandler) GetExecutionMetrics(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	isAdmin, err := h.authService.IsAdmin(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to check admin status",
+			"error":   err.Error(),
+		})
+	}
+	if !isAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": "Admin access required",
+		})
+	}
+
+	metrics, err := h.monitor.Sample(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to sample execution metrics",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ExecutionMetricsResponse{
+		Success: true,
+		Metrics: metrics,
+	})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
 
@@ -23,6 +24,11 @@ func NewFlowHandler(flowService *service.FlowService, authService *service.AuthS
 
 // getUserIDFromToken extracts user ID from JWT token in Authorization header
 func (h *FlowHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -80,12 +86,69 @@ func (h *FlowHandler) CreateFlow(c *fiber.Ctx) error {
 	}
 
 	if !resp.Success {
+		if len(resp.Validation) > 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(resp)
+		}
 		return c.Status(fiber.StatusForbidden).JSON(resp)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
+// ValidateFlow checks a flow's nodes_data for structural problems without saving it
+// POST /api/flows/validate
+func (h *FlowHandler) ValidateFlow(c *fiber.Ctx) error {
+	// Get user ID from token
+	if _, err := h.getUserIDFromToken(c); err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.ValidateFlowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.NodesData == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "nodes_data is required",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(h.flowService.ValidateFlow(&req))
+}
+
+// CompileFlowDefinition converts a YAML or JSON flow definition to nodes_data without saving it
+// POST /api/flows/compile
+func (h *FlowHandler) CompileFlowDefinition(c *fiber.Ctx) error {
+	// Get user ID from token
+	if _, err := h.getUserIDFromToken(c); err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req models.CompileFlowDefinitionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.Definition == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "definition is required",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(h.flowService.CompileFlowDefinition(&req))
+}
+
 // GetFlow retrieves a specific flow by ID
 // GET /api/flows/:id
 func (h *FlowHandler) GetFlow(c *fiber.Ctx) error {
@@ -233,12 +296,431 @@ func (h *FlowHandler) UpdateFlow(c *fiber.Ctx) error {
 	}
 
 	if !resp.Success {
+		if len(resp.Validation) > 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(resp)
+		}
 		return c.Status(fiber.StatusForbidden).JSON(resp)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// AutoLayoutFlow rearranges a flow's nodes with a layered layout and saves the positions
+// POST /api/flows/:id/auto-layout
+func (h *FlowHandler) AutoLayoutFlow(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	// Body is optional (defaults: top-to-bottom, standard spacing)
+	var req models.AutoLayoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid request body",
+			})
+		}
+	}
+
+	resp, err := h.flowService.AutoLayoutFlow(c.Context(), userID, flowID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to lay out flow",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetFlowDoc describes a flow for compliance reviews and onboarding: entry conditions, steps and
+// branches, messages sent verbatim, captured fields and AI prompts. JSON by default; format=markdown
+// returns the Markdown document.
+// GET /api/flows/:id/doc
+func (h *FlowHandler) GetFlowDoc(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	resp, err := h.flowService.GetFlowDoc(c.Context(), userID, flowID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate flow documentation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if c.Query("format") == "markdown" {
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		return c.Status(fiber.StatusOK).SendString(resp.Markdown)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetFlowDefinition exports a flow as a flow definition for version control. JSON by default;
+// format=yaml returns the YAML document.
+// GET /api/flows/:id/definition
+func (h *FlowHandler) GetFlowDefinition(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	resp, err := h.flowService.GetFlowDefinition(c.Context(), userID, flowID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate flow definition",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if c.Query("format") == "yaml" {
+		c.Set(fiber.HeaderContentType, "application/yaml; charset=utf-8")
+		return c.Status(fiber.StatusOK).SendString(resp.YAML)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// CheckFlowMedia checks every media URL a flow sends before it goes live
+// POST /api/flows/:id/check-media
+func (h *FlowHandler) CheckFlowMedia(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	resp, err := h.flowService.CheckFlowMedia(c.Context(), userID, flowID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to check flow media",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ListFlowVersions lists the versions stored before each update of a flow, newest first
+// GET /api/flows/:id/versions
+func (h *FlowHandler) ListFlowVersions(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	resp, err := h.flowService.ListFlowVersions(c.Context(), userID, flowID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get flow versions",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreFlowVersion rolls a flow back to a stored version
+// POST /api/flows/:id/versions/:version/restore
+func (h *FlowHandler) RestoreFlowVersion(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	version, err := c.ParamsInt("version")
+	if err != nil || version <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "version must be a positive number",
+		})
+	}
+
+	resp, err := h.flowService.RestoreFlowVersion(c.Context(), userID, flowID, version)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to restore flow version",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// SimulateFlow dry-runs a flow against a mock conversation and returns an execution trace: nodes
+// visited, messages that would have been sent and every variable change. Nothing is sent or saved.
+// POST /api/flows/:id/simulate
+func (h *FlowHandler) SimulateFlow(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	var req models.SimulateFlowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.flowService.SimulateFlow(c.Context(), userID, flowID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to simulate flow",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// MigrateDeprecatedModels reports ai_prompt nodes and devices using deprecated AI models and
+// rewrites them to the mapped replacements (admin only; dry_run previews without saving)
+// POST /api/maintenance/model-migration
+func (h *FlowHandler) MigrateDeprecatedModels(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	isAdmin, err := h.authService.IsAdmin(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to check admin status",
+			"error":   err.Error(),
+		})
+	}
+	if !isAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": "Admin access required",
+		})
+	}
+
+	var req models.ModelMigrationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.flowService.MigrateDeprecatedModels(c.Context(), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to migrate models",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ActivateFlow lets a flow take new prospects again
+// POST /api/flows/:id/activate
+func (h *FlowHandler) ActivateFlow(c *fiber.Ctx) error {
+	return h.setFlowActive(c, true)
+}
+
+// DeactivateFlow stops a flow taking new prospects; conversations already in it continue
+// POST /api/flows/:id/deactivate
+func (h *FlowHandler) DeactivateFlow(c *fiber.Ctx) error {
+	return h.setFlowActive(c, false)
+}
+
+// setFlowActive activates or deactivates the flow in the URL
+func (h *FlowHandler) setFlowActive(c *fiber.Ctx, active bool) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get flow ID from URL parameter
+	flowID := c.Params("id")
+	if flowID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Flow ID is required",
+		})
+	}
+
+	resp, err := h.flowService.SetFlowActive(c.Context(), userID, flowID, active)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update flow",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// ReorderFlows sets the order in which a device's flows are tried
+// PUT /api/flows/device/:deviceId/order
+func (h *FlowHandler) ReorderFlows(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	// Get device ID from URL parameter
+	deviceID := c.Params("deviceId")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID is required",
+		})
+	}
+
+	// Parse request body
+	var req models.ReorderFlowsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.flowService.ReorderFlows(c.Context(), userID, deviceID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to reorder flows",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
 // DeleteFlow deletes a flow
 // DELETE /api/flows/:id
 func (h *FlowHandler) DeleteFlow(c *fiber.Ctx) error {
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"chatbot-automation/internal/service"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LinkHandler redirects tracked short links. The route is public: prospects open it from WhatsApp.
+type LinkHandler struct {
+	linkTracker *service.LinkTracker
+}
+
+// NewLinkHandler creates a new link handler
+func NewLinkHandler(linkTracker *service.LinkTracker) *LinkHandler {
+	return &LinkHandler{
+		linkTracker: linkTracker,
+	}
+}
+
+// Redirect records the click and redirects to the original URL
+// GET /l/:code
+func (h *LinkHandler) Redirect(c *fiber.Ctx) error {
+	target, ok := h.linkTracker.ResolveClick(c.Context(), c.Params("code"), strings.Clone(c.Get(fiber.HeaderUserAgent)))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Link not found")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(target, fiber.StatusFound)
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// liveEventHeartbeat is how often an idle stream sends a comment line, keeping proxies from
+// closing it and noticing clients that went away
+const liveEventHeartbeat = 25 * time.Second
+
+// LiveEventHandler streams live dashboard events over Server-Sent Events
+type LiveEventHandler struct {
+	hub         *service.LiveEventHub
+	authService *service.AuthService
+}
+
+// NewLiveEventHandler creates a new live event handler
+func NewLiveEventHandler(hub *service.LiveEventHub, authService *service.AuthService) *LiveEventHandler {
+	return &LiveEventHandler{
+		hub:         hub,
+		authService: authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header, or the token query
+// parameter since browsers' EventSource can't set headers
+func (h *LiveEventHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		authHeader = c.Query("token")
+	}
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// Stream sends the user's events as they happen, as Server-Sent Events named after the event
+// GET /api/events/stream
+func (h *LiveEventHandler) Stream(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	sub, msg, err := h.hub.Subscribe(c.Context(), userID, c.Query("device_id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to open event stream",
+			"error":   err.Error(),
+		})
+	}
+	if sub == nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": msg,
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unsubscribe(sub)
+
+		heartbeat := time.NewTicker(liveEventHeartbeat)
+		defer heartbeat.Stop()
+
+		fmt.Fprint(w, "retry: 3000\n: connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case payload, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+				body, err := json.Marshal(payload)
+				if err != nil {
+					log.Printf("⚠️  Live event %s not sent: %v", payload.Event, err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", payload.ID, payload.Event, body)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log"
+
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MessengerHandler serves the webhook of Facebook page inboxes and their Instagram DMs. Routes
+// are public like provider webhooks; the page is identified by the device's webhook_id.
+type MessengerHandler struct {
+	messengerService *service.MessengerService
+}
+
+// NewMessengerHandler creates a new Messenger handler
+func NewMessengerHandler(messengerService *service.MessengerService) *MessengerHandler {
+	return &MessengerHandler{
+		messengerService: messengerService,
+	}
+}
+
+// VerifyWebhook answers Meta's subscription handshake with hub.challenge
+// GET /api/webhooks/messenger/:webhook_id?hub.mode=subscribe&hub.verify_token=...&hub.challenge=...
+func (h *MessengerHandler) VerifyWebhook(c *fiber.Ctx) error {
+	challenge, ok := h.messengerService.VerifyWebhook(c.Context(), c.Params("webhook_id"),
+		c.Query("hub.mode"), c.Query("hub.verify_token"), c.Query("hub.challenge"))
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": "Verification failed",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).SendString(challenge)
+}
+
+// HandleWebhook receives a webhook delivery of page and Instagram messages
+// POST /api/webhooks/messenger/:webhook_id
+func (h *MessengerHandler) HandleWebhook(c *fiber.Ctx) error {
+	webhookID := c.Params("webhook_id")
+	if webhookID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Webhook ID is required",
+		})
+	}
+
+	processed, err := h.messengerService.ReceiveWebhook(c.Context(), webhookID, c.Body(), c.Get("X-Hub-Signature-256"))
+	if err != nil {
+		log.Printf("⚠️  Rejected Messenger webhook: %v", err)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+
+	// Meta redelivers anything but 200, so ignored deliveries are acknowledged too
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"processed": processed,
+	})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
 	"strconv"
@@ -24,6 +25,11 @@ func NewStageHandler(stageService *service.StageService, authService *service.Au
 
 // getUserIDFromToken extracts user ID from JWT token
 func (h *StageHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -73,6 +79,13 @@ func (h *StageHandler) CreateStageValue(c *fiber.Ctx) error {
 		})
 	}
 
+	if msg := validateStageSLA(req.SLAMinutes, req.SLAAction); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": msg,
+		})
+	}
+
 	// Call service
 	resp, err := h.stageService.CreateStageValue(c.Context(), &req)
 	if err != nil {
@@ -172,6 +185,13 @@ func (h *StageHandler) UpdateStageValue(c *fiber.Ctx) error {
 		})
 	}
 
+	if msg := validateStageSLA(req.SLAMinutes, req.SLAAction); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": msg,
+		})
+	}
+
 	// Call service
 	resp, err := h.stageService.UpdateStageValue(c.Context(), stageID, &req)
 	if err != nil {
@@ -215,3 +235,14 @@ func (h *StageHandler) DeleteStageValue(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
+
+// validateStageSLA checks the optional SLA fields and returns an error message, or "" if valid
+func validateStageSLA(minutes *int, action *string) string {
+	if minutes != nil && *minutes < 0 {
+		return "sla_minutes cannot be negative"
+	}
+	if action != nil && *action != "" && !models.IsValidStageSLAAction(*action) {
+		return "sla_action must be nudge or notify"
+	}
+	return ""
+}
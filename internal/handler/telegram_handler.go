@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"log"
+
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TelegramHandler serves the Telegram channel: the public bot webhook, identified by the device's
+// webhook_id like provider webhooks, and registering that webhook with Telegram
+type TelegramHandler struct {
+	telegramService *service.TelegramService
+	authService     *service.AuthService
+}
+
+// NewTelegramHandler creates a new Telegram handler
+func NewTelegramHandler(telegramService *service.TelegramService, authService *service.AuthService) *TelegramHandler {
+	return &TelegramHandler{
+		telegramService: telegramService,
+		authService:     authService,
+	}
+}
+
+// getUserIDFromToken extracts user ID from JWT token in Authorization header
+func (h *TelegramHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	token := authHeader
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token = authHeader[7:]
+	}
+
+	// Validate token
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	return claims.UserID, nil
+}
+
+// HandleWebhook receives a Telegram bot update. It always answers 200 so Telegram does not
+// redeliver updates the channel ignores.
+// POST /api/webhooks/telegram/:webhook_id
+func (h *TelegramHandler) HandleWebhook(c *fiber.Ctx) error {
+	webhookID := c.Params("webhook_id")
+	if webhookID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Webhook ID is required",
+		})
+	}
+
+	var update map[string]interface{}
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid webhook payload",
+		})
+	}
+
+	processed, err := h.telegramService.ReceiveUpdate(c.Context(), webhookID, update)
+	if err != nil {
+		log.Printf("❌ Failed to receive Telegram update: %v", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"processed": processed,
+	})
+}
+
+// RegisterWebhook points the device's Telegram bot at this server
+// POST /api/devices/:id/telegram/webhook
+func (h *TelegramHandler) RegisterWebhook(c *fiber.Ctx) error {
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.TelegramWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.telegramService.RegisterWebhook(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to register Telegram webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.JSON(resp)
+}
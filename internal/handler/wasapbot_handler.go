@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -23,6 +25,11 @@ func NewWasapbotHandler(wasapbotService *service.WasapbotService, authService *s
 
 // getUserIDFromToken extracts user ID from JWT token in Authorization header
 func (h *WasapbotHandler) getUserIDFromToken(c *fiber.Ctx) (string, error) {
+	// Requests made with an API key were authenticated by middleware.APIKeyAuth
+	if userID := middleware.APIKeyUserID(c); userID != "" {
+		return userID, nil
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return "", fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
@@ -85,3 +92,221 @@ func (h *WasapbotHandler) GetAllWasapbot(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
+
+// GetWasapbotByDevice retrieves WhatsApp Bot conversations for a device, optionally with
+// captured-field columns, sort and filters (see parseConversationListQuery)
+// GET /api/wasapbot/device/:deviceId
+func (h *WasapbotHandler) GetWasapbotByDevice(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID := c.Params("deviceId")
+	if deviceID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Device ID is required",
+		})
+	}
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	query, err := parseConversationListQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+
+	resp, err := h.wasapbotService.GetWasapbotByDevice(c.Context(), userID, deviceID, limit, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get WhatsApp Bot conversations",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	if query.Export {
+		return sendConversationRowsCSV(c, resp.Columns, resp.Rows, "wasapbot_conversations.csv")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetPinnedWasapbot retrieves the authenticated user's pinned WhatsApp Bot conversations
+// GET /api/wasapbot/pinned
+func (h *WasapbotHandler) GetPinnedWasapbot(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.wasapbotService.GetPinnedWasapbot(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get pinned WhatsApp Bot conversations",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetWasapbotByExternalRef retrieves a WhatsApp Bot conversation by its external reference
+// GET /api/wasapbot/ref/:ref
+func (h *WasapbotHandler) GetWasapbotByExternalRef(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	externalRef := c.Params("ref")
+	if externalRef == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "External reference is required",
+		})
+	}
+
+	resp, err := h.wasapbotService.GetWasapbotByExternalRef(c.Context(), userID, externalRef)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get WhatsApp Bot conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// PinWasapbot pins/unpins a WhatsApp Bot conversation or changes its priority
+// PUT /api/wasapbot/:id/pin
+func (h *WasapbotHandler) PinWasapbot(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	var req models.PinConversationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.wasapbotService.PinWasapbot(c.Context(), userID, prospectID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update WhatsApp Bot conversation",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetExecutionLog lists the flow nodes run for a WhatsApp Bot conversation, oldest first
+// GET /api/wasapbot/:id/execution-log?limit=N
+func (h *WasapbotHandler) GetExecutionLog(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.wasapbotService.GetExecutionLog(c.Context(), userID, prospectID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get execution log",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetMessages lists the structured message history of a WhatsApp Bot conversation, oldest first
+// GET /api/wasapbot/:id/messages?limit=N
+func (h *WasapbotHandler) GetMessages(c *fiber.Ctx) error {
+	// Get user ID from token
+	userID, err := h.getUserIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	prospectID := c.Params("id")
+	if prospectID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Prospect ID is required",
+		})
+	}
+
+	resp, err := h.wasapbotService.GetMessages(c.Context(), userID, prospectID, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get conversation history",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusForbidden).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
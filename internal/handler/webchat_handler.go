@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebChatHandler serves the embeddable website chat widget. Routes are public like provider
+// webhooks; the widget is identified by the device's webhook_id.
+type WebChatHandler struct {
+	webChatService *service.WebChatService
+}
+
+// NewWebChatHandler creates a new web chat handler
+func NewWebChatHandler(webChatService *service.WebChatService) *WebChatHandler {
+	return &WebChatHandler{
+		webChatService: webChatService,
+	}
+}
+
+// SendMessage accepts a visitor message and runs it through the device's flows
+// POST /api/webchat/:webhook_id/messages
+func (h *WebChatHandler) SendMessage(c *fiber.Ctx) error {
+	webhookID := c.Params("webhook_id")
+	if webhookID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Webhook ID is required",
+		})
+	}
+
+	var req models.WebChatMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	resp, err := h.webChatService.ReceiveMessage(c.Context(), webhookID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to process message",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(resp)
+}
+
+// PollMessages returns bot replies for a session, waiting up to wait seconds (max 30) for new ones
+// GET /api/webchat/:webhook_id/messages?session_id=...&wait=25
+func (h *WebChatHandler) PollMessages(c *fiber.Ctx) error {
+	webhookID := c.Params("webhook_id")
+	sessionID := c.Query("session_id")
+	if webhookID == "" || sessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "Webhook ID and session_id are required",
+		})
+	}
+
+	wait := time.Duration(c.QueryInt("wait", 0)) * time.Second
+	resp, err := h.webChatService.PollMessages(c.Context(), webhookID, sessionID, wait)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to get messages",
+			"error":   err.Error(),
+		})
+	}
+
+	if !resp.Success {
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
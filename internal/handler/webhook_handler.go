@@ -2,14 +2,17 @@ package handler
 
 import (
 	"bytes"
+	"chatbot-automation/internal/middleware"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/service"
+	"chatbot-automation/internal/whatsapp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -25,13 +28,17 @@ type WebhookHandler struct {
 		GetDeviceByWebhookID(ctx context.Context, webhookID string) (*models.DeviceSetting, error)
 		GetDeviceByIDDevice(ctx context.Context, idDevice string) (*models.DeviceSetting, error)
 	}
+	archiver *service.InboundArchiver // raw payload archival for devices with archive_inbound on
 }
 
+// inboundArchiveTimeout bounds archiving one payload in the background
+const inboundArchiveTimeout = time.Minute
+
 // NewWebhookHandler creates a new webhook handler
 func NewWebhookHandler(flowExecutionService *service.FlowExecutionService, deviceService *service.DeviceService, whatsappService *service.WhatsAppService, flowProcessor *service.FlowProcessorService, webhookService *service.WebhookService, deviceRepo interface {
 	GetDeviceByWebhookID(ctx context.Context, webhookID string) (*models.DeviceSetting, error)
 	GetDeviceByIDDevice(ctx context.Context, idDevice string) (*models.DeviceSetting, error)
-}) *WebhookHandler {
+}, archiver *service.InboundArchiver) *WebhookHandler {
 	return &WebhookHandler{
 		flowExecutionService: flowExecutionService,
 		deviceService:        deviceService,
@@ -39,9 +46,33 @@ func NewWebhookHandler(flowExecutionService *service.FlowExecutionService, devic
 		flowProcessor:        flowProcessor,
 		webhookService:       webhookService,
 		deviceRepo:           deviceRepo,
+		archiver:             archiver,
 	}
 }
 
+// archiveInbound archives the accepted request body in the background. device is nil on routes
+// that name the device by id_device, which is then looked up.
+func (h *WebhookHandler) archiveInbound(c *fiber.Ctx, device *models.DeviceSetting, idDevice, provider string) {
+	if h.archiver == nil {
+		return
+	}
+
+	// fasthttp reuses the request buffer once the handler returns
+	payload := append([]byte(nil), c.Body()...)
+	contentType := c.Get("Content-Type")
+	receivedAt := time.Now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), inboundArchiveTimeout)
+		defer cancel()
+		if device != nil {
+			h.archiver.Archive(ctx, device, provider, contentType, payload, receivedAt)
+		} else {
+			h.archiver.ArchiveForDevice(ctx, idDevice, provider, contentType, payload, receivedAt)
+		}
+	}()
+}
+
 // HandleWhatsAppWebhook handles incoming webhooks from WhatsApp providers
 // POST /api/webhook/whatsapp/:deviceId
 func (h *WebhookHandler) HandleWhatsAppWebhook(c *fiber.Ctx) error {
@@ -65,12 +96,15 @@ func (h *WebhookHandler) HandleWhatsAppWebhook(c *fiber.Ctx) error {
 	}
 
 	log.Printf("📥 Webhook received for device %s: %+v", deviceID, payload)
+	h.archiveInbound(c, nil, deviceID, "")
 
 	// Extract message data
 	event, _ := payload["event"].(string)
 	from, _ := payload["from"].(string)
 	body, _ := payload["body"].(string)
 	messageType, _ := payload["type"].(string)
+	media := whatsapp.InboundMedia(payload)
+	body = mediaMessageText(body, media, whatsapp.InboundLocation(payload))
 
 	// Skip if not a message event
 	if event != "message" && event != "messages.upsert" {
@@ -92,8 +126,8 @@ func (h *WebhookHandler) HandleWhatsAppWebhook(c *fiber.Ctx) error {
 		})
 	}
 
-	// Skip if not text message
-	if messageType != "" && messageType != "text" {
+	// Skip if neither text nor media (location, contact card, ...)
+	if messageType != "" && messageType != "text" && media == nil {
 		log.Printf("⏭️  Skipping non-text message type: %s", messageType)
 		return c.JSON(fiber.Map{
 			"success":   true,
@@ -182,6 +216,7 @@ func (h *WebhookHandler) HandleWahaWebhook(c *fiber.Ctx) error {
 	}
 
 	log.Printf("📥 Waha webhook for device %s: %+v", deviceID, payload)
+	h.archiveInbound(c, nil, deviceID, "waha")
 
 	// Waha-specific parsing
 	event, _ := payload["event"].(string)
@@ -190,6 +225,7 @@ func (h *WebhookHandler) HandleWahaWebhook(c *fiber.Ctx) error {
 	if payloadData, ok := payload["payload"].(map[string]interface{}); ok {
 		from, _ = payloadData["from"].(string)
 		body, _ = payloadData["body"].(string)
+		body = mediaMessageText(body, whatsapp.InboundMedia(payloadData), whatsapp.InboundLocation(payloadData))
 	}
 
 	if event != "message" || body == "" {
@@ -236,10 +272,12 @@ func (h *WebhookHandler) HandleWablasWebhook(c *fiber.Ctx) error {
 	}
 
 	log.Printf("📥 Wablas webhook for device %s: %+v", deviceID, payload)
+	h.archiveInbound(c, nil, deviceID, "wablas")
 
 	// Wablas-specific parsing
 	from, _ := payload["phone"].(string)
 	body, _ := payload["message"].(string)
+	body = mediaMessageText(body, whatsapp.InboundMedia(payload), whatsapp.InboundLocation(payload))
 
 	if body == "" {
 		return c.JSON(fiber.Map{
@@ -285,6 +323,7 @@ func (h *WebhookHandler) HandleWhacenterWebhook(c *fiber.Ctx) error {
 	}
 
 	log.Printf("📥 Whacenter webhook for device %s: %+v", deviceID, payload)
+	h.archiveInbound(c, nil, deviceID, "whacenter")
 
 	// Whacenter-specific parsing
 	event, _ := payload["event"].(string)
@@ -295,6 +334,7 @@ func (h *WebhookHandler) HandleWhacenterWebhook(c *fiber.Ctx) error {
 	if body == "" {
 		body, _ = payload["body"].(string)
 	}
+	body = mediaMessageText(body, whatsapp.InboundMedia(payload), whatsapp.InboundLocation(payload))
 
 	if event != "message" || body == "" {
 		return c.JSON(fiber.Map{
@@ -332,9 +372,14 @@ func (h *WebhookHandler) StartFlow(c *fiber.Ctx) error {
 		})
 	}
 
-	// For webhook endpoint, we skip authentication
-	// In production, you might want to add API key authentication
-	result, err := h.flowExecutionService.StartFlow(c.Context(), "webhook-user", &req)
+	// Callers authenticate with an API key that has the webhooks:ingest scope (middleware.APIKeyAuth);
+	// without one the device ownership check denies the request
+	userID := middleware.APIKeyUserID(c)
+	if userID == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "An API key with the webhooks:ingest scope is required")
+	}
+
+	result, err := h.flowExecutionService.StartFlow(c.Context(), userID, &req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -363,6 +408,18 @@ func stripSuffix(s, suffix string) string {
 	return s
 }
 
+// mediaMessageText returns the text a webhook message is processed as: its body, or for media
+// sent without a caption a placeholder such as [image], or [location: name] for a shared location
+func mediaMessageText(body string, media *models.InboundMedia, location *models.Location) string {
+	if body == "" && media != nil {
+		return media.Placeholder()
+	}
+	if body == "" && location != nil {
+		return location.Placeholder()
+	}
+	return body
+}
+
 // HandleDebouncedMessages processes debounced messages from Deno Deploy
 // POST /api/debounce/process
 func (h *WebhookHandler) HandleDebouncedMessages(c *fiber.Ctx) error {
@@ -416,7 +473,8 @@ func (h *WebhookHandler) HandleDebouncedMessages(c *fiber.Ctx) error {
 
 	// Process through flow processor (async to prevent timeout)
 	go func() {
-		ctx := context.Background()
+		ctx, cancel := h.flowProcessor.RunContext()
+		defer cancel()
 		err := h.flowProcessor.ProcessIncomingMessage(ctx, req.DeviceID, webhookData)
 		if err != nil {
 			log.Printf("❌ Failed to process debounced messages via FlowProcessor: %v", err)
@@ -481,7 +539,8 @@ func (h *WebhookHandler) ReceiveWebhook(c *fiber.Ctx) error {
 			log.Printf("⚠️  Device not found, falling back to direct processing")
 			// Fallback to direct processing without Deno
 			go func() {
-				ctx := context.Background()
+				ctx, cancel := h.flowProcessor.RunContext()
+				defer cancel()
 				err := h.flowProcessor.ProcessIncomingMessage(ctx, webhookID, webhookData)
 				if err != nil {
 					log.Printf("❌ Failed to process webhook message: %v", err)
@@ -510,6 +569,7 @@ func (h *WebhookHandler) ReceiveWebhook(c *fiber.Ctx) error {
 	}
 
 	log.Printf("✅ Found device: %s (Provider: %s)", webhookID, provider)
+	h.archiveInbound(c, device, "", provider)
 
 	// Step 3: Extract message data based on provider
 	idDevice := ""
@@ -517,12 +577,13 @@ func (h *WebhookHandler) ReceiveWebhook(c *fiber.Ctx) error {
 		idDevice = *device.IDDevice
 	}
 
-	extractedMsg, err := h.webhookService.ExtractMessageData(c.Context(), webhookData, idDevice, provider)
+	extractedMsg, err := h.webhookService.ExtractMessageData(c.Context(), webhookData, idDevice, provider, device.EffectivePhoneCountry())
 	if err != nil {
 		log.Printf("⚠️  Failed to extract message data: %v, falling back to direct processing", err)
 		// Fallback to direct processing
 		go func() {
-			ctx := context.Background()
+			ctx, cancel := h.flowProcessor.RunContext()
+			defer cancel()
 			err := h.flowProcessor.ProcessIncomingMessage(ctx, webhookID, webhookData)
 			if err != nil {
 				log.Printf("❌ Failed to process webhook message: %v", err)
@@ -536,13 +597,31 @@ func (h *WebhookHandler) ReceiveWebhook(c *fiber.Ctx) error {
 
 	log.Printf("✅ Extracted message: phone=%s, message=%s, name=%s", extractedMsg.PhoneNumber, extractedMsg.Message, extractedMsg.Name)
 
+	// The debouncer only carries text, so media, shared locations and button taps are processed
+	// directly to keep their URL, coordinates and option ID
+	if extractedMsg.Media != nil || extractedMsg.Location != nil || extractedMsg.OptionID != "" {
+		go func() {
+			ctx, cancel := h.flowProcessor.RunContext()
+			defer cancel()
+			err := h.flowProcessor.ProcessIncomingMessage(ctx, webhookID, webhookData)
+			if err != nil {
+				log.Printf("❌ Failed to process media message: %v", err)
+			}
+		}()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"success": true,
+			"message": "webhook received (media, direct processing)",
+		})
+	}
+
 	// Step 4: Forward to Deno Deploy for debouncing
 	err = h.forwardToDeno(extractedMsg.DeviceID, extractedMsg.PhoneNumber, extractedMsg.Message, extractedMsg.Name)
 	if err != nil {
 		log.Printf("⚠️  Failed to forward to Deno (falling back to direct processing): %v", err)
 		// Fallback to direct processing
 		go func() {
-			ctx := context.Background()
+			ctx, cancel := h.flowProcessor.RunContext()
+			defer cancel()
 			err := h.flowProcessor.ProcessIncomingMessage(ctx, webhookID, webhookData)
 			if err != nil {
 				log.Printf("❌ Failed to process webhook message: %v", err)
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// Body size defaults. Fiber rejects anything above the app limit before a handler runs;
+// webhook routes get a tighter limit of their own.
+const (
+	DefaultMaxBodyBytes        = 4 * 1024 * 1024
+	DefaultWebhookMaxBodyBytes = 1 * 1024 * 1024
+)
+
+// requestIDKey is the Locals key holding the request ID
+const requestIDKey = "requestid"
+
+// WebhookPrefixes are the route prefixes that receive provider, debouncer and chat widget payloads.
+// /api/webhooks is listed on its own rather than relying on fiber matching /api/webhook as a raw string prefix.
+var WebhookPrefixes = []string{"/api/webhook", "/api/webhooks", "/api/debounce", WebChatPrefix}
+
+// WebChatPrefix serves the website chat widget, which is embedded on other origins
+const WebChatPrefix = "/api/webchat"
+
+// Config controls the middleware stack
+type Config struct {
+	MaxBodyBytes        int                 // whole app (0 uses DefaultMaxBodyBytes)
+	WebhookMaxBodyBytes int                 // WebhookPrefixes routes (0 uses DefaultWebhookMaxBodyBytes)
+	TenancyAudit        bool                // record each request's user for the tenancy audit
+	JWTSecret           string              // validates bearer tokens for the tenancy audit
+	APIKeys             APIKeyAuthenticator // authenticates API keys (nil = JWTs only)
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.WebhookMaxBodyBytes <= 0 {
+		cfg.WebhookMaxBodyBytes = DefaultWebhookMaxBodyBytes
+	}
+	return cfg
+}
+
+// AppConfig returns the fiber settings the middleware stack relies on: the app-wide body limit
+// and an error handler that answers in the API's JSON shape
+func AppConfig(cfg Config) fiber.Config {
+	cfg = cfg.withDefaults()
+	return fiber.Config{
+		BodyLimit:    cfg.MaxBodyBytes,
+		ErrorHandler: ErrorHandler,
+	}
+}
+
+// Register installs the middleware chain in order: request ID, panic recovery, tenant (when the
+// tenancy audit is on), API keys (when configured), gzip, chat widget CORS, then the webhook body limit
+func Register(app *fiber.App, cfg Config) {
+	cfg = cfg.withDefaults()
+
+	app.Use(RequestID())
+	app.Use(Recover())
+	if cfg.TenancyAudit {
+		app.Use(Tenant(cfg.JWTSecret))
+	}
+	if cfg.APIKeys != nil {
+		app.Use(APIKeyAuth(cfg.APIKeys))
+	}
+	app.Use(Compress())
+	app.Use(WebChatPrefix, WebChatCORS())
+	for _, prefix := range WebhookPrefixes {
+		app.Use(prefix, BodyLimit(cfg.WebhookMaxBodyBytes))
+	}
+}
+
+// RequestID reuses an incoming X-Request-ID or generates one, and echoes it on the response
+func RequestID() fiber.Handler {
+	return requestid.New(requestid.Config{
+		Header:     fiber.HeaderXRequestID,
+		ContextKey: requestIDKey,
+	})
+}
+
+// GetRequestID returns the current request's ID, empty outside the middleware chain
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey).(string)
+	return id
+}
+
+// Recover turns a handler panic into a logged stack trace and a 500 JSON response,
+// so one bad request cannot take the instance down
+func Recover() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("💥 Panic [%s] %s %s: %v\n%s", GetRequestID(c), c.Method(), c.Path(), r, debug.Stack())
+				err = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"success":    false,
+					"message":    "Internal server error",
+					"request_id": GetRequestID(c),
+				})
+			}
+		}()
+		return c.Next()
+	}
+}
+
+// Tenant stores the bearer token's user ID under database.TenantKey so admin queries made for
+// this request can be audited. It never rejects a request; handlers still authenticate themselves.
+func Tenant(jwtSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if token != "" {
+			if claims, err := utils.ValidateJWT(token, jwtSecret); err == nil {
+				c.Locals(database.TenantKey, claims.UserID)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// WebChatCORS lets the chat widget call the web chat routes from any website
+func WebChatCORS() fiber.Handler {
+	return cors.New(cors.Config{
+		AllowOrigins: "*",
+		AllowMethods: "GET,POST,OPTIONS",
+		AllowHeaders: "Content-Type",
+	})
+}
+
+// Compress gzips responses for clients that accept it
+func Compress() fiber.Handler {
+	return compress.New(compress.Config{
+		Level: compress.LevelDefault,
+	})
+}
+
+// BodyLimit rejects requests whose body exceeds maxBytes with 413
+func BodyLimit(maxBytes int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Request().Header.ContentLength() > maxBytes || len(c.Body()) > maxBytes {
+			log.Printf("⚠️  Rejected %s %s [%s]: body exceeds %d bytes", c.Method(), c.Path(), GetRequestID(c), maxBytes)
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"success":    false,
+				"message":    fmt.Sprintf("Request body too large (max %d bytes)", maxBytes),
+				"request_id": GetRequestID(c),
+			})
+		}
+		return c.Next()
+	}
+}
+
+// ErrorHandler renders errors returned by handlers (including fiber.NewError) as JSON
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+	}
+
+	if code >= fiber.StatusInternalServerError {
+		log.Printf("❌ [%s] %s %s: %v", GetRequestID(c), c.Method(), c.Path(), err)
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"success":    false,
+		"message":    err.Error(),
+		"request_id": GetRequestID(c),
+	})
+}
@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"chatbot-automation/internal/middleware"
+	"chatbot-automation/internal/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Public webhook routes that deliberately keep the app-wide body limit
+var webhookLimitExempt = map[string]string{
+	"/api/email/inbound": "inbound parse posts attachments",
+	"/l/:code":           "link redirect, no body",
+}
+
+var pathParam = regexp.MustCompile(`:[^/]+`)
+
+// TestWebhookBodyLimitCoversWebhookRoutes checks every public route of the Webhooks tag gets
+// the webhook body limit, and no other route does
+func TestWebhookBodyLimitCoversWebhookRoutes(t *testing.T) {
+	const limit = 16
+
+	app := fiber.New(middleware.AppConfig(middleware.Config{}))
+	middleware.Register(app, middleware.Config{WebhookMaxBodyBytes: limit})
+	for _, route := range openapi.Routes {
+		app.Add(route.Method, route.Path, func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+	}
+
+	body := strings.Repeat("x", limit*2)
+	for _, route := range openapi.Routes {
+		_, exempt := webhookLimitExempt[route.Path]
+		wantLimited := route.Tag == "Webhooks" && !route.Auth && !exempt
+
+		req := httptest.NewRequest(route.Method, pathParam.ReplaceAllString(route.Path, "x"), strings.NewReader(body))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", route.Method, route.Path, err)
+		}
+
+		limited := resp.StatusCode == fiber.StatusRequestEntityTooLarge
+		if limited != wantLimited {
+			t.Errorf("%s %s: webhook body limit applied = %v, want %v", route.Method, route.Path, limited, wantLimited)
+		}
+	}
+}
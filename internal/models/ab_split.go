@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ABSplitAssignment records the branch an ab_split node sent a prospect down. The first assignment
+// sticks, so the prospect sees the same variant when the flow reaches the node again.
+type ABSplitAssignment struct {
+	ID             string    `json:"id,omitempty"`
+	IDDevice       string    `json:"id_device"`
+	FlowID         string    `json:"flow_id"`
+	NodeID         string    `json:"node_id"` // the ab_split node
+	Variant        string    `json:"variant"` // ID of the node the chosen branch leads to
+	ProspectNum    string    `json:"prospect_num"`
+	Source         string    `json:"source"` // conversation table: ai_whatsapp or wasapbot
+	ConversationID string    `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// ABSplitMetrics compares the variants of a flow's ab_split nodes by the stage their prospects reached
+type ABSplitMetrics struct {
+	Assigned int                   `json:"assigned"`
+	Variants []ABSplitVariantStats `json:"variants"`
+}
+
+// ABSplitVariantStats is one branch of an ab_split node. ByStage counts the prospects assigned to
+// it by their current stage; StageRates is the same as a percentage of Assigned.
+type ABSplitVariantStats struct {
+	FlowID     string             `json:"flow_id"`
+	NodeID     string             `json:"node_id"`
+	Variant    string             `json:"variant"`
+	Assigned   int                `json:"assigned"`
+	ByStage    map[string]int     `json:"by_stage"` // "none" = no stage yet
+	StageRates map[string]float64 `json:"stage_rates"`
+}
+
+// ABSplitAnalyticsResponse represents the A/B split analytics response
+type ABSplitAnalyticsResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    *ABSplitMetrics `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
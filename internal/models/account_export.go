@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// AccountExportFormatVersion is the format version written into account export archives
+const AccountExportFormatVersion = 1
+
+// Account export statuses
+const (
+	AccountExportPending = "pending" // queued, the archive is being built
+	AccountExportReady   = "ready"   // the archive can be downloaded
+	AccountExportFailed  = "failed"
+)
+
+// AccountExport is one backup of a user's business data, built in the background into a zip
+// archive in storage
+type AccountExport struct {
+	ID          string     `json:"id,omitempty"`
+	UserID      string     `json:"user_id"`
+	Status      string     `json:"status"`
+	ObjectPath  *string    `json:"object_path,omitempty"` // archive path in the account-exports bucket
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// AccountExportResponse is the response for account export operations
+type AccountExportResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Export  *AccountExport `json:"export,omitempty"`
+	// DownloadURL is a signed link to the archive, valid until DownloadExpiresAt; request the export again for a fresh one
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// AccountArchiveManifest is manifest.json in an account export archive: what it holds and how many
+type AccountArchiveManifest struct {
+	Version    int            `json:"version"`
+	UserID     string         `json:"user_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Files      map[string]int `json:"files"` // archive file -> records in it
+}
+
+// AccountArchiveAnalytics is analytics.json in an account export archive
+type AccountArchiveAnalytics struct {
+	Devices       []DeviceMetrics                 `json:"devices"`
+	Conversations map[string]*ConversationMetrics `json:"conversations"` // by id_device
+}
@@ -6,8 +6,9 @@ import "time"
 type AIProvider string
 
 const (
-	AIProviderOpenAI    AIProvider = "openai"
-	AIProviderAnthropic AIProvider = "anthropic"
+	AIProviderOpenAI     AIProvider = "openai"
+	AIProviderAnthropic  AIProvider = "anthropic"
+	AIProviderOpenRouter AIProvider = "openrouter" // used by ai_prompt flow nodes and reply suggestions
 )
 
 // AIModel represents available AI models
@@ -118,3 +119,11 @@ type ChatResponse struct {
 	Usage    *TokenUsage `json:"usage,omitempty"`
 	Error    string      `json:"error,omitempty"`
 }
+
+// ReplySuggestionsResponse carries AI-drafted replies for an agent to review and send
+type ReplySuggestionsResponse struct {
+	Success     bool     `json:"success"`
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// AIEndpoint points one AI provider at a different base URL (an on-prem gateway, a proxy, a
+// compatible self-hosted server) and adds headers to every request sent there
+type AIEndpoint struct {
+	BaseURL string            `json:"base_url,omitempty"` // e.g. https://llm-gateway.internal/openrouter/v1
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// AIEndpointTLS holds the TLS options of a deployment endpoint. File paths are read on the
+// server, so they can only be set in the deployment endpoints file, never per device.
+type AIEndpointTLS struct {
+	CAFile             string `json:"ca_file,omitempty"`   // PEM bundle trusted in addition to the system roots
+	CertFile           string `json:"cert_file,omitempty"` // client certificate for mutual TLS
+	KeyFile            string `json:"key_file,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// AIEndpointConfig is one provider's entry in the deployment endpoints file (AI_ENDPOINTS_FILE),
+// a JSON object keyed by provider: {"openrouter": {"base_url": "...", "headers": {...}, "tls": {...}}}
+type AIEndpointConfig struct {
+	AIEndpoint
+	TLS *AIEndpointTLS `json:"tls,omitempty"`
+}
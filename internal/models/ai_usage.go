@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+// AI usage purposes: what an AI call was made for
+const (
+	AIUsagePurposeFlowReply       = "flow_reply"       // ai_prompt nodes and AI flows answering a prospect
+	AIUsagePurposeCompletion      = "completion"       // completions through the AI API
+	AIUsagePurposeReplySuggestion = "reply_suggestion" // reply suggestions for inbox agents
+)
+
+// DefaultAIBudgetAlertPercent is the share of a budget at which the warning alert fires
+const DefaultAIBudgetAlertPercent = 80
+
+// AI budget alert levels
+const (
+	AIBudgetWarning  = "warning"  // spend passed the alert percentage of the budget
+	AIBudgetExceeded = "exceeded" // spend passed the budget
+)
+
+// AIUsage is one AI call's token usage and estimated cost, kept in the ai_usage table
+type AIUsage struct {
+	ID               string     `json:"id,omitempty"`
+	IDDevice         string     `json:"id_device"`
+	Model            string     `json:"model"`
+	Purpose          string     `json:"purpose"`
+	PromptTokens     int        `json:"prompt_tokens"`
+	CompletionTokens int        `json:"completion_tokens"`
+	Cost             float64    `json:"cost"`   // USD; the provider's charge when it reports one
+	Priced           bool       `json:"priced"` // false when the model has no known price, so cost is 0
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+}
+
+// AIBudget is a user's AI spend budget in USD; nil limits are not checked
+type AIBudget struct {
+	UserID       string     `json:"user_id"`
+	DailyLimit   *float64   `json:"daily_limit,omitempty"`
+	MonthlyLimit *float64   `json:"monthly_limit,omitempty"`
+	AlertPercent *int       `json:"alert_percent,omitempty"` // nil = DefaultAIBudgetAlertPercent
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// EffectiveAlertPercent returns the budget's alert percentage, the default when unset
+func (b *AIBudget) EffectiveAlertPercent() int {
+	if b == nil || b.AlertPercent == nil || *b.AlertPercent <= 0 {
+		return DefaultAIBudgetAlertPercent
+	}
+	return *b.AlertPercent
+}
+
+// AIUsageTotals sums the AI calls of a device, day or model
+type AIUsageTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	UnpricedCalls    int     `json:"unpriced_calls"`
+}
+
+// Add counts one AI call into the totals
+func (t *AIUsageTotals) Add(usage AIUsage) {
+	t.Calls++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.Cost += usage.Cost
+	if !usage.Priced {
+		t.UnpricedCalls++
+	}
+}
+
+// AIUsageDay is one device's AI usage on one day (UTC)
+type AIUsageDay struct {
+	Date     string `json:"date"`
+	IDDevice string `json:"id_device"`
+	AIUsageTotals
+}
+
+// AIBudgetAlert reports spend that passed a budget's alert percentage or the budget itself
+type AIBudgetAlert struct {
+	Period  string  `json:"period"` // day, month
+	Level   string  `json:"level"`  // warning, exceeded
+	Spent   float64 `json:"spent"`
+	Limit   float64 `json:"limit"`
+	Percent float64 `json:"percent"`
+}
+
+// AIUsageSummary is the AI usage of a user's devices in a time range, with the user's budget
+// checked against today's and this month's spend (UTC)
+type AIUsageSummary struct {
+	Total      AIUsageTotals            `json:"total"`
+	ByDevice   map[string]AIUsageTotals `json:"by_device"`
+	ByModel    map[string]AIUsageTotals `json:"by_model"`
+	ByPurpose  map[string]AIUsageTotals `json:"by_purpose"`
+	Daily      []AIUsageDay             `json:"daily"`
+	Budget     *AIBudget                `json:"budget,omitempty"`
+	SpentToday float64                  `json:"spent_today"`
+	SpentMonth float64                  `json:"spent_month"`
+	Alerts     []AIBudgetAlert          `json:"alerts"`
+	TimeRange  *TimeRangeFilter         `json:"time_range"`
+}
+
+// AIUsageResponse represents the AI usage analytics response
+type AIUsageResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    *AIUsageSummary `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// UpdateAIBudgetRequest sets a user's AI budget; 0 removes a limit
+type UpdateAIBudgetRequest struct {
+	DailyLimit   *float64 `json:"daily_limit,omitempty"`
+	MonthlyLimit *float64 `json:"monthly_limit,omitempty"`
+	AlertPercent *int     `json:"alert_percent,omitempty"` // 1-100, 0 resets to the default
+}
+
+// AIBudgetResponse is the response for AI budget operations
+type AIBudgetResponse struct {
+	Success bool      `json:"success"`
+	Message string    `json:"message"`
+	Budget  *AIBudget `json:"budget,omitempty"`
+}
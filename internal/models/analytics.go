@@ -4,42 +4,44 @@ import "time"
 
 // ConversationMetrics represents conversation-level analytics
 type ConversationMetrics struct {
-	TotalConversations      int                        `json:"total_conversations"`
-	ActiveConversations     int                        `json:"active_conversations"`
-	CompletedConversations  int                        `json:"completed_conversations"`
-	AbandonedConversations  int                        `json:"abandoned_conversations"`
-	AverageCompletionTime   float64                    `json:"average_completion_time"` // in seconds
-	ConversationsByStage    map[string]int             `json:"conversations_by_stage"`
-	ConversationsByNiche    map[string]int             `json:"conversations_by_niche"`
-	ConversationsByStatus   map[string]int             `json:"conversations_by_status"`
-	DailyConversationCounts []DailyConversationCount   `json:"daily_conversation_counts"`
+	TotalConversations      int                      `json:"total_conversations"`
+	ActiveConversations     int                      `json:"active_conversations"`
+	CompletedConversations  int                      `json:"completed_conversations"`
+	AbandonedConversations  int                      `json:"abandoned_conversations"`
+	AverageCompletionTime   float64                  `json:"average_completion_time"` // in seconds
+	ConversationsByStage    map[string]int           `json:"conversations_by_stage"`
+	ConversationsByNiche    map[string]int           `json:"conversations_by_niche"`
+	ConversationsByStatus   map[string]int           `json:"conversations_by_status"`
+	DailyConversationCounts []DailyConversationCount `json:"daily_conversation_counts"`
 }
 
 // DailyConversationCount represents conversation counts per day
 type DailyConversationCount struct {
-	Date  string `json:"date"`  // YYYY-MM-DD
+	Date  string `json:"date"` // YYYY-MM-DD
 	Count int    `json:"count"`
 }
 
 // FlowMetrics represents flow-level analytics
 type FlowMetrics struct {
-	FlowID              string             `json:"flow_id"`
-	FlowName            string             `json:"flow_name"`
-	TotalExecutions     int                `json:"total_executions"`
-	CompletedExecutions int                `json:"completed_executions"`
-	AbandonedExecutions int                `json:"abandoned_executions"`
-	CompletionRate      float64            `json:"completion_rate"` // percentage
-	AverageCompletionTime float64          `json:"average_completion_time"` // in seconds
-	NodeMetrics         map[string]NodeMetric `json:"node_metrics"`
+	FlowID                string                `json:"flow_id"`
+	FlowName              string                `json:"flow_name"`
+	TotalExecutions       int                   `json:"total_executions"`
+	CompletedExecutions   int                   `json:"completed_executions"`
+	AbandonedExecutions   int                   `json:"abandoned_executions"`
+	CompletionRate        float64               `json:"completion_rate"`         // percentage
+	AverageCompletionTime float64               `json:"average_completion_time"` // in seconds
+	NodeMetrics           map[string]NodeMetric `json:"node_metrics"`
 }
 
-// NodeMetric represents metrics for individual nodes
+// NodeMetric represents metrics for individual nodes, from the flow execution logs
 type NodeMetric struct {
-	NodeID        string  `json:"node_id"`
-	NodeType      string  `json:"node_type"`
-	VisitCount    int     `json:"visit_count"`
-	AverageTime   float64 `json:"average_time"` // time spent on this node
-	DropOffRate   float64 `json:"drop_off_rate"` // percentage who abandon at this node
+	NodeID       string  `json:"node_id"`
+	NodeType     string  `json:"node_type"`
+	VisitCount   int     `json:"visit_count"`
+	ErrorCount   int     `json:"error_count"`   // runs of the node that failed
+	AverageTime  float64 `json:"average_time"`  // processing time of the node in milliseconds
+	AbandonCount int     `json:"abandon_count"` // abandoned conversations whose last node was this one
+	DropOffRate  float64 `json:"drop_off_rate"` // percentage of conversations reaching the node that abandon at it
 }
 
 // DeviceMetrics represents device-level analytics
@@ -48,27 +50,103 @@ type DeviceMetrics struct {
 	DeviceName          string  `json:"device_name"`
 	TotalConversations  int     `json:"total_conversations"`
 	ActiveConversations int     `json:"active_conversations"`
-	ResponseRate        float64 `json:"response_rate"` // percentage of prospects who respond
+	ResponseRate        float64 `json:"response_rate"`         // percentage of prospects who respond
 	AverageResponseTime float64 `json:"average_response_time"` // in seconds
 }
 
 // MessageMetrics represents message-level analytics
 type MessageMetrics struct {
-	TotalMessagesSent     int            `json:"total_messages_sent"`
-	TotalMessagesReceived int            `json:"total_messages_received"`
-	MessagesByType        map[string]int `json:"messages_by_type"` // text, image, audio, etc.
-	AverageMessagesPerConversation float64 `json:"average_messages_per_conversation"`
+	TotalMessagesSent              int            `json:"total_messages_sent"`
+	TotalMessagesReceived          int            `json:"total_messages_received"`
+	MessagesByType                 map[string]int `json:"messages_by_type"` // text, image, audio, etc.
+	AverageMessagesPerConversation float64        `json:"average_messages_per_conversation"`
 }
 
 // AIMetrics represents AI usage analytics
 type AIMetrics struct {
-	TotalAIRequests     int            `json:"total_ai_requests"`
+	TotalAIRequests      int            `json:"total_ai_requests"`
 	AIRequestsByProvider map[string]int `json:"ai_requests_by_provider"` // openai, anthropic
 	AIRequestsByModel    map[string]int `json:"ai_requests_by_model"`
 	TotalTokensUsed      int            `json:"total_tokens_used"`
 	AverageResponseTime  float64        `json:"average_response_time"` // in seconds
 }
 
+// CSATMetrics represents customer satisfaction survey analytics
+type CSATMetrics struct {
+	TotalResponses int                      `json:"total_responses"`
+	AverageScore   float64                  `json:"average_score"`
+	SatisfiedRate  float64                  `json:"satisfied_rate"` // percentage of 4-5 ratings
+	Distribution   map[int]int              `json:"distribution"`   // score -> count
+	ByFlow         map[string]CSATBreakdown `json:"by_flow"`
+	ByDevice       map[string]CSATBreakdown `json:"by_device"`
+	ByAgent        map[string]CSATBreakdown `json:"by_agent"`
+}
+
+// CSATBreakdown represents CSAT aggregates for one flow, device or agent
+type CSATBreakdown struct {
+	Responses    int     `json:"responses"`
+	AverageScore float64 `json:"average_score"`
+}
+
+// CSATAnalyticsResponse represents the CSAT analytics response
+type CSATAnalyticsResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Data    *CSATMetrics `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// ResponseLatency is one inbound -> reply cycle in the response_latency table.
+// A row is opened on the first unanswered inbound message and closed by the next outbound reply.
+type ResponseLatency struct {
+	ID          string     `json:"id,omitempty"`
+	IDDevice    string     `json:"id_device"`
+	FlowID      *string    `json:"flow_id,omitempty"`
+	ProspectNum string     `json:"prospect_num"`
+	IsFirst     bool       `json:"is_first"`            // first cycle ever for this prospect on the device
+	Responder   *string    `json:"responder,omitempty"` // bot, human
+	Agent       *string    `json:"agent,omitempty"`
+	InboundAt   time.Time  `json:"inbound_at"`
+	RepliedAt   *time.Time `json:"replied_at,omitempty"`
+	LatencyMs   *int64     `json:"latency_ms,omitempty"`
+
+	// FirstAfterHandoff marks the first human reply since the bot last answered the prospect
+	FirstAfterHandoff bool `json:"first_after_handoff"`
+}
+
+// Responders recorded on response_latency rows
+const (
+	ResponderBot   = "bot"
+	ResponderHuman = "human"
+)
+
+// LatencyStats represents percentile aggregates of response latency in milliseconds
+type LatencyStats struct {
+	Count int   `json:"count"`
+	AvgMs int64 `json:"avg_ms"`
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+}
+
+// LatencyMetrics represents first-response and reply latency analytics
+type LatencyMetrics struct {
+	FirstBotResponse   LatencyStats            `json:"first_bot_response"`
+	FirstHumanResponse LatencyStats            `json:"first_human_response"`
+	BotReply           LatencyStats            `json:"bot_reply"`
+	HumanReply         LatencyStats            `json:"human_reply"`
+	ByDevice           map[string]LatencyStats `json:"by_device"`
+	ByFlow             map[string]LatencyStats `json:"by_flow"`
+	ByAgent            map[string]LatencyStats `json:"by_agent"`
+}
+
+// LatencyAnalyticsResponse represents the response latency analytics response
+type LatencyAnalyticsResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    *LatencyMetrics `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
 // TimeRangeFilter represents a time range for filtering analytics
 type TimeRangeFilter struct {
 	StartDate time.Time `json:"start_date"`
@@ -110,10 +188,10 @@ type FlowAnalyticsResponse struct {
 
 // ConversationAnalyticsResponse represents conversation analytics response
 type ConversationAnalyticsResponse struct {
-	Success bool                  `json:"success"`
-	Message string                `json:"message"`
-	Data    *ConversationMetrics  `json:"data,omitempty"`
-	Error   string                `json:"error,omitempty"`
+	Success bool                 `json:"success"`
+	Message string               `json:"message"`
+	Data    *ConversationMetrics `json:"data,omitempty"`
+	Error   string               `json:"error,omitempty"`
 }
 
 // ExportRequest represents a request to export analytics data
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// APIKeyPrefix starts every API key, telling keys apart from JWTs in the Authorization header
+const APIKeyPrefix = "ak_"
+
+// MaxAPIKeysPerUser caps a user's active (unrevoked) keys
+const MaxAPIKeysPerUser = 20
+
+// MaxAPIKeyLifetimeDays caps expires_in_days
+const MaxAPIKeyLifetimeDays = 3650
+
+// API key scopes. A :write scope also grants the matching :read scope.
+const (
+	APIKeyScopeFlowsRead          = "flows:read"
+	APIKeyScopeFlowsWrite         = "flows:write"
+	APIKeyScopeConversationsRead  = "conversations:read"
+	APIKeyScopeConversationsWrite = "conversations:write"
+	APIKeyScopeDevicesRead        = "devices:read"
+	APIKeyScopeDevicesWrite       = "devices:write"
+	APIKeyScopeAnalyticsRead      = "analytics:read"
+	APIKeyScopeWebhooksIngest     = "webhooks:ingest" // POST /api/webhook/start-flow
+)
+
+// APIKeyScopes are the scopes keys can be given
+var APIKeyScopes = []string{
+	APIKeyScopeFlowsRead, APIKeyScopeFlowsWrite,
+	APIKeyScopeConversationsRead, APIKeyScopeConversationsWrite,
+	APIKeyScopeDevicesRead, APIKeyScopeDevicesWrite,
+	APIKeyScopeAnalyticsRead,
+	APIKeyScopeWebhooksIngest,
+}
+
+// APIKey lets an external system call the API as its user within its scopes. Only the SHA-256 of
+// the key is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         string     `json:"id,omitempty"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`         // first characters of the key, to recognize it
+	KeyHash    string     `json:"key_hash,omitempty"` // Omit in responses
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateAPIKeyRequest is the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required"`
+	Scopes        []string `json:"scopes" validate:"required"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 = never expires
+}
+
+// APIKeyResponse is the response for API key operations
+type APIKeyResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message"`
+	Key     *APIKey  `json:"key,omitempty"`
+	Keys    []APIKey `json:"keys,omitempty"`
+	Secret  string   `json:"secret,omitempty"` // the full key, returned on creation only
+}
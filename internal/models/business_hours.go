@@ -0,0 +1,20 @@
+package models
+
+// Closed modes: how a device handles inbound messages outside its business hours
+const (
+	ClosedModeAway  = "away"  // the away flow answers; the normal flows take over again at opening
+	ClosedModeQueue = "queue" // the normal flows run, but their bot replies are held until opening
+)
+
+// IsValidClosedMode reports whether mode is a known closed mode
+func IsValidClosedMode(mode string) bool {
+	return mode == ClosedModeAway || mode == ClosedModeQueue
+}
+
+// EffectiveClosedMode returns the device's closed mode, away when unset
+func (d *DeviceSetting) EffectiveClosedMode() string {
+	if d.ClosedMode == nil || *d.ClosedMode == "" {
+		return ClosedModeAway
+	}
+	return *d.ClosedMode
+}
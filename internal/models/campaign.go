@@ -0,0 +1,210 @@
+package models
+
+import "time"
+
+// Campaign statuses
+const (
+	CampaignStatusDraft     = "draft"
+	CampaignStatusRunning   = "running"
+	CampaignStatusPaused    = "paused"
+	CampaignStatusCompleted = "completed"
+	CampaignStatusCancelled = "cancelled"
+)
+
+// Campaign recipient statuses
+const (
+	RecipientStatusPending = "pending"
+	RecipientStatusSending = "sending" // Claimed by a sender, outcome not recorded yet
+	RecipientStatusSent    = "sent"
+	RecipientStatusFailed  = "failed"
+	RecipientStatusSkipped = "skipped"
+)
+
+// Campaign sources
+const (
+	CampaignSourceManual  = "manual"
+	CampaignSourceRecycle = "recycle" // Built from abandoned conversations
+	CampaignSourceCSV     = "csv"     // Uploaded list of numbers
+	CampaignSourceSegment = "segment" // Conversations matching a saved segment
+)
+
+// Campaign sending rates, in messages per minute
+const (
+	DefaultCampaignRate = 20
+	MaxCampaignRate     = 60
+)
+
+// MaxCampaignRecipients caps the recipients of one campaign
+const MaxCampaignRecipients = 10000
+
+// Campaign is a broadcast of a message or flow to a list of recipients through one device
+type Campaign struct {
+	ID              string     `json:"id,omitempty"`
+	UserID          string     `json:"user_id"`
+	IDDevice        string     `json:"id_device"`
+	Name            string     `json:"name"`
+	Source          string     `json:"source"`            // manual, recycle, csv, segment
+	Message         *string    `json:"message,omitempty"` // {{variable}} placeholders are filled per recipient
+	FlowID          *string    `json:"flow_id,omitempty"` // Flow to start for each recipient instead of a message
+	SegmentID       *string    `json:"segment_id,omitempty"`
+	Status          string     `json:"status"`
+	TotalRecipients int        `json:"total_recipients"`
+	RatePerMinute   int        `json:"rate_per_minute,omitempty"` // Sends per minute (0 = DefaultCampaignRate)
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"` // Completed or cancelled
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+// CampaignRecipient is one phone number targeted by a campaign
+type CampaignRecipient struct {
+	ID           string     `json:"id,omitempty"`
+	CampaignID   string     `json:"campaign_id"`
+	ProspectNum  string     `json:"prospect_num"`
+	ProspectName *string    `json:"prospect_name,omitempty"`
+	Stage        *string    `json:"stage,omitempty"` // Stage the prospect was in when selected
+	Status       string     `json:"status"`
+	Error        *string    `json:"error,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	// Variables are extra CSV columns, used in the message and passed to the flow
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Blacklist reasons
+const (
+	BlacklistReasonBlocked = "blacklist"
+	BlacklistReasonOptOut  = "opt_out"
+)
+
+// BlacklistEntry excludes a phone number from all of a user's campaigns
+type BlacklistEntry struct {
+	ID          string     `json:"id,omitempty"`
+	UserID      string     `json:"user_id"`
+	PhoneNumber string     `json:"phone_number"`
+	Reason      string     `json:"reason"` // blacklist, opt_out
+	Note        *string    `json:"note,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// AddBlacklistRequest is the request body for blacklisting or opting out a number
+type AddBlacklistRequest struct {
+	PhoneNumber string  `json:"phone_number" validate:"required"`
+	Reason      string  `json:"reason,omitempty"` // blacklist (default), opt_out
+	Note        *string `json:"note,omitempty"`
+}
+
+// BlacklistResponse is the response for blacklist operations
+type BlacklistResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Entry   *BlacklistEntry  `json:"entry,omitempty"`
+	Entries []BlacklistEntry `json:"entries,omitempty"`
+}
+
+// RecycleProspectsRequest selects abandoned conversations and turns them into a campaign
+type RecycleProspectsRequest struct {
+	DeviceID string `json:"device_id" validate:"required"`
+	// BeforeStage keeps only prospects who dropped off before reaching this stage (empty = any stage)
+	BeforeStage string `json:"before_stage,omitempty"`
+	// StageOrder is the funnel order used with BeforeStage; defaults to the stage nodes of FlowID (or the device's flows)
+	StageOrder []string `json:"stage_order,omitempty"`
+	FlowID     *string  `json:"flow_id,omitempty"` // Only conversations on this flow
+	// StartDate/EndDate bound when the conversation was created (default: last 90 days)
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	// StalledAfterHours also counts waiting/active conversations idle this long as abandoned (default 72, 0 = only "abandoned" status)
+	StalledAfterHours *int `json:"stalled_after_hours,omitempty"`
+	// ExcludeContactedDays skips anyone messaged on any of the user's devices in the last N days
+	ExcludeContactedDays int `json:"exclude_contacted_days,omitempty"`
+
+	CampaignName string  `json:"campaign_name,omitempty"`
+	Message      *string `json:"message,omitempty"`
+	TargetFlowID *string `json:"target_flow_id,omitempty"` // Flow to start instead of sending Message
+	DryRun       bool    `json:"dry_run,omitempty"`        // Return the selection without creating a campaign
+}
+
+// RecycleProspectsResponse is the response for prospect recycling
+type RecycleProspectsResponse struct {
+	Success    bool                `json:"success"`
+	Message    string              `json:"message"`
+	Campaign   *Campaign           `json:"campaign,omitempty"`
+	Selected   int                 `json:"selected"`
+	Excluded   map[string]int      `json:"excluded,omitempty"` // blacklist, opt_out, no_consent, recently_contacted, duplicate
+	Recipients []CampaignRecipient `json:"recipients,omitempty"`
+}
+
+// DefaultRecycleStalledHours is how long a waiting conversation must be idle to count as abandoned
+const DefaultRecycleStalledHours = 72
+
+// EffectiveRate returns the sending rate, defaulting to DefaultCampaignRate
+func (c *Campaign) EffectiveRate() int {
+	if c.RatePerMinute <= 0 {
+		return DefaultCampaignRate
+	}
+	return c.RatePerMinute
+}
+
+// ContactSegment is a saved filter over a device's ai_whatsapp and wasapbot conversations that
+// campaigns can target. Empty fields match everything.
+type ContactSegment struct {
+	ID              string     `json:"id,omitempty"`
+	UserID          string     `json:"user_id"`
+	Name            string     `json:"name"`
+	Stage           *string    `json:"stage,omitempty"`
+	ExecutionStatus *string    `json:"execution_status,omitempty"`
+	Niche           *string    `json:"niche,omitempty"`
+	CreatedAfter    *time.Time `json:"created_after,omitempty"`  // conversation created at or after
+	CreatedBefore   *time.Time `json:"created_before,omitempty"` // conversation created before
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+}
+
+// CreateSegmentRequest is the request body for saving a segment
+type CreateSegmentRequest struct {
+	Name            string     `json:"name" validate:"required"`
+	Stage           *string    `json:"stage,omitempty"`
+	ExecutionStatus *string    `json:"execution_status,omitempty"`
+	Niche           *string    `json:"niche,omitempty"`
+	CreatedAfter    *time.Time `json:"created_after,omitempty"`
+	CreatedBefore   *time.Time `json:"created_before,omitempty"`
+}
+
+// SegmentResponse is the response for segment operations
+type SegmentResponse struct {
+	Success  bool             `json:"success"`
+	Message  string           `json:"message"`
+	Segment  *ContactSegment  `json:"segment,omitempty"`
+	Segments []ContactSegment `json:"segments,omitempty"`
+}
+
+// CreateCampaignRequest is the request body for creating a draft campaign. Recipients come from
+// CSV (a header row with a phone column, optionally name and other columns) or a saved segment.
+type CreateCampaignRequest struct {
+	DeviceID      string  `json:"device_id" validate:"required"`
+	Name          string  `json:"name" validate:"required"`
+	Message       *string `json:"message,omitempty"`
+	FlowID        *string `json:"flow_id,omitempty"` // Flow to start instead of sending Message
+	CSV           string  `json:"csv,omitempty"`
+	SegmentID     *string `json:"segment_id,omitempty"`
+	RatePerMinute int     `json:"rate_per_minute,omitempty"`
+}
+
+// CampaignStats counts a campaign's recipients by status
+type CampaignStats struct {
+	Pending int `json:"pending"`
+	Sending int `json:"sending"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// CampaignResponse is the response for campaign operations
+type CampaignResponse struct {
+	Success    bool                `json:"success"`
+	Message    string              `json:"message"`
+	Campaign   *Campaign           `json:"campaign,omitempty"`
+	Campaigns  []Campaign          `json:"campaigns,omitempty"`
+	Stats      *CampaignStats      `json:"stats,omitempty"`
+	Excluded   map[string]int      `json:"excluded,omitempty"` // blacklist, opt_out, no_consent, duplicate, invalid
+	Recipients []CampaignRecipient `json:"recipients,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Consent sources
+const (
+	ConsentSourceFlowNode = "flow_node" // Captured by a consent node
+)
+
+// ConsentRecord is one entry in the consent registry. Records are never updated:
+// the latest record for a number decides whether it may receive marketing broadcasts.
+type ConsentRecord struct {
+	ID          string     `json:"id,omitempty"`
+	UserID      string     `json:"user_id"`
+	IDDevice    string     `json:"id_device"`
+	ProspectNum string     `json:"prospect_num"`
+	Granted     bool       `json:"granted"`
+	Wording     string     `json:"wording"`  // Exact question shown to the prospect
+	Response    string     `json:"response"` // Prospect's reply, verbatim
+	Source      string     `json:"source"`
+	FlowID      *string    `json:"flow_id,omitempty"`
+	NodeID      *string    `json:"node_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// ConsentExportResponse is the JSON form of the consent registry export
+type ConsentExportResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Records []ConsentRecord `json:"records"`
+}
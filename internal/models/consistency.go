@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Consistency issue kinds, the known bad states the consistency checker looks for
+const (
+	ConsistencyDeletedFlow   = "conversation_deleted_flow" // conversation bound to a flow that no longer exists
+	ConsistencyMissingDevice = "flow_missing_device"       // flow of a device that no longer exists
+	ConsistencyWaitingNoNode = "waiting_without_node"      // conversation waiting for a reply with no current_node_id
+	ConsistencyOrphanedStage = "orphaned_stage_config"     // stage config of a device that no longer exists
+)
+
+// Consistency repair actions
+const (
+	ConsistencyRepairRebind     = "rebind_to_device_flow" // bind the conversation to its device's catch-all flow
+	ConsistencyRepairUnbind     = "unbind_flow"           // clear flow_id; the device has no active flow left
+	ConsistencyRepairReactivate = "reactivate"            // leave the waiting state so the next message runs the flow
+	ConsistencyRepairDelete     = "delete"                // delete the orphaned row
+)
+
+// ConsistencyConversation is the part of an ai_whatsapp or wasapbot row the checker reads
+type ConsistencyConversation struct {
+	Table           string  `json:"table"`
+	IDProspect      int     `json:"id_prospect"`
+	IDDevice        string  `json:"id_device"`
+	FlowID          *string `json:"flow_id,omitempty"`
+	ExecutionStatus *string `json:"execution_status,omitempty"`
+}
+
+// ConsistencyIssue is one bad state found by the checker. Repair is the action auto-repair takes;
+// issues without one need an operator.
+type ConsistencyIssue struct {
+	Kind     string `json:"kind"`
+	Table    string `json:"table"` // table of the affected row
+	ID       string `json:"id"`    // id of the affected row in Table
+	IDDevice string `json:"id_device,omitempty"`
+	Detail   string `json:"detail"`
+	Repair   string `json:"repair,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+	Error    string `json:"error,omitempty"` // why the repair failed
+}
+
+// ConsistencyReport is the result of one consistency check
+type ConsistencyReport struct {
+	Issues     []ConsistencyIssue `json:"issues"`
+	Counts     map[string]int     `json:"counts"` // issues by kind
+	Repairable int                `json:"repairable"`
+	Repaired   int                `json:"repaired"`
+	RepairRun  bool               `json:"repair_run"` // repairs were attempted
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+}
+
+// ConsistencyRepairRequest is the request body for POST /api/maintenance/consistency/repair
+type ConsistencyRepairRequest struct {
+	Kinds []string `json:"kinds,omitempty"` // issue kinds to repair (empty repairs every repairable issue)
+}
+
+// ConsistencyResponse is the response for the consistency check endpoints
+type ConsistencyResponse struct {
+	Success bool               `json:"success"`
+	Message string             `json:"message"`
+	Report  *ConsistencyReport `json:"report,omitempty"`
+}
@@ -10,6 +10,7 @@ type AIWhatsapp struct {
 	Niche           *string    `json:"niche,omitempty"`
 	ProspectName    *string    `json:"prospect_name,omitempty"`
 	ProspectNum     string     `json:"prospect_num"`
+	ExternalRef     *string    `json:"external_ref,omitempty"` // Stable reference for integrations (see DefaultExternalRef)
 	Intro           *string    `json:"intro,omitempty"`
 	Stage           *string    `json:"stage,omitempty"`
 	ConvLast        *string    `json:"conv_last,omitempty"` // Stores "User: message\nBot: reply"
@@ -23,8 +24,27 @@ type AIWhatsapp struct {
 	Human           *int       `json:"human,omitempty"`
 	KeywordIklan    *string    `json:"keywordiklan,omitempty"`
 	Marketer        *string    `json:"marketer,omitempty"`
-	CreatedAt       *time.Time `json:"created_at,omitempty"`
-	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+	CSATScore       *int       `json:"csat_score,omitempty"`    // 1-5 rating from a csat node
+	CSATAttempts    *int       `json:"csat_attempts,omitempty"` // Invalid answers given to the current csat node
+	CSATAt          *time.Time `json:"csat_at,omitempty"`
+	Pinned          *bool      `json:"pinned,omitempty"`           // Sorted to the top of inbox lists
+	Priority        *int       `json:"priority,omitempty"`         // ConversationPriority* (manual or escalation rules)
+	Language        *string    `json:"language,omitempty"`         // Detected prospect language; set = replies are translated
+	Channel         string     `json:"channel,omitempty"`          // whatsapp or web (website chat widget)
+	StageEnteredAt  *time.Time `json:"stage_entered_at,omitempty"` // Set by a trigger whenever stage changes
+	SLABreachedAt   *time.Time `json:"sla_breached_at,omitempty"`  // Cleared on stage change
+	FlowProgress    *int       `json:"flow_progress,omitempty"`    // 0-100 through the current flow
+	FlowMilestone   *string    `json:"flow_milestone,omitempty"`   // Last milestone node passed
+	FlowStartedAt   *time.Time `json:"flow_started_at,omitempty"`  // Last time the conversation entered a flow from its start
+	FlowEntries     *int       `json:"flow_entries,omitempty"`     // Flows entered from their start; >1 = returning prospect
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`     // Last time the flow reached its end; cleared on re-engagement
+	IsTest          bool       `json:"is_test,omitempty"`          // Test traffic (sandbox device or flagged), see cleanup
+	// SessionData holds flow variables carried between steps (seeded by StartFlow)
+	SessionData  map[string]interface{} `json:"session_data,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"` // Captured values of the owner's custom fields
+	LastMedia    *InboundMedia          `json:"last_media,omitempty"`    // Latest image, voice note, ... the prospect sent
+	CreatedAt    *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time             `json:"updated_at,omitempty"`
 }
 
 // Wasapbot represents a WhatsApp conversation with a prospect (WhatsApp Bot - without AI Prompt)
@@ -35,6 +55,7 @@ type Wasapbot struct {
 	Niche            *string    `json:"niche,omitempty"`
 	ProspectName     *string    `json:"prospect_name,omitempty"`
 	ProspectNum      string     `json:"prospect_num"`
+	ExternalRef      *string    `json:"external_ref,omitempty"` // Stable reference for integrations (see DefaultExternalRef)
 	Intro            *string    `json:"intro,omitempty"`
 	Stage            *string    `json:"stage,omitempty"`
 	ConvLast         *string    `json:"conv_last,omitempty"`    // Stores "User: message\nBot: reply"
@@ -49,13 +70,62 @@ type Wasapbot struct {
 	KeywordIklan     *string    `json:"keywordiklan,omitempty"`
 	Marketer         *string    `json:"marketer,omitempty"`
 	PeringkatSekolah *string    `json:"peringkat_sekolah,omitempty"` // School level for customer
-	Alamat           *string    `json:"alamat,omitempty"`             // Customer address
-	Pakej            *string    `json:"pakej,omitempty"`              // Package selected
-	NoFon            *string    `json:"no_fon,omitempty"`             // Phone number
-	CaraBayaran      *string    `json:"cara_bayaran,omitempty"`       // Payment method
-	TarikhGaji       *string    `json:"tarikh_gaji,omitempty"`        // Salary date
-	CreatedAt        *time.Time `json:"created_at,omitempty"`         // Database column: created_at (previously date_start)
-	UpdatedAt        *time.Time `json:"updated_at,omitempty"`         // Database column: updated_at (previously updated_at)
+	Alamat           *string    `json:"alamat,omitempty"`            // Customer address
+	Pakej            *string    `json:"pakej,omitempty"`             // Package selected
+	NoFon            *string    `json:"no_fon,omitempty"`            // Phone number
+	CaraBayaran      *string    `json:"cara_bayaran,omitempty"`      // Payment method
+	TarikhGaji       *string    `json:"tarikh_gaji,omitempty"`       // Salary date
+	CSATScore        *int       `json:"csat_score,omitempty"`        // 1-5 rating from a csat node
+	CSATAttempts     *int       `json:"csat_attempts,omitempty"`     // Invalid answers given to the current csat node
+	CSATAt           *time.Time `json:"csat_at,omitempty"`
+	Pinned           *bool      `json:"pinned,omitempty"`           // Sorted to the top of inbox lists
+	Priority         *int       `json:"priority,omitempty"`         // ConversationPriority* (manual or escalation rules)
+	Language         *string    `json:"language,omitempty"`         // Detected prospect language; set = replies are translated
+	Channel          string     `json:"channel,omitempty"`          // whatsapp or web (website chat widget)
+	StageEnteredAt   *time.Time `json:"stage_entered_at,omitempty"` // Set by a trigger whenever stage changes
+	SLABreachedAt    *time.Time `json:"sla_breached_at,omitempty"`  // Cleared on stage change
+	FlowProgress     *int       `json:"flow_progress,omitempty"`    // 0-100 through the current flow
+	FlowMilestone    *string    `json:"flow_milestone,omitempty"`   // Last milestone node passed
+	FlowStartedAt    *time.Time `json:"flow_started_at,omitempty"`  // Last time the conversation entered a flow from its start
+	FlowEntries      *int       `json:"flow_entries,omitempty"`     // Flows entered from their start; >1 = returning prospect
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`     // Last time the flow reached its end; cleared on re-engagement
+	IsTest           bool       `json:"is_test,omitempty"`          // Test traffic (sandbox device or flagged), see cleanup
+	CreatedAt        *time.Time `json:"created_at,omitempty"`       // Database column: created_at (previously date_start)
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`       // Database column: updated_at (previously updated_at)
+	// CustomFields holds captured values of the owner's custom fields
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	// SessionData holds flow variables carried between steps (set by set_variable nodes)
+	SessionData map[string]interface{} `json:"session_data,omitempty"`
+	// LastMedia is the latest image, voice note, ... the prospect sent
+	LastMedia *InboundMedia `json:"last_media,omitempty"`
+}
+
+// ExecutionState returns the execution snapshot of the conversation
+func (c *AIWhatsapp) ExecutionState() *ExecutionState {
+	return &ExecutionState{
+		ExecutionStatus: c.ExecutionStatus,
+		WaitingForReply: c.WaitingForReply,
+		CurrentNodeID:   c.CurrentNodeID,
+	}
+}
+
+// ExecutionState returns the execution snapshot of the conversation
+func (c *Wasapbot) ExecutionState() *ExecutionState {
+	return &ExecutionState{
+		ExecutionStatus: c.ExecutionStatus,
+		WaitingForReply: c.WaitingForReply,
+		CurrentNodeID:   c.CurrentNodeID,
+	}
+}
+
+// InboxRank returns the pin flag and priority used to order inbox lists
+func (c *AIWhatsapp) InboxRank() (bool, int) {
+	return inboxRank(c.Pinned, c.Priority)
+}
+
+// InboxRank returns the pin flag and priority used to order inbox lists
+func (c *Wasapbot) InboxRank() (bool, int) {
+	return inboxRank(c.Pinned, c.Priority)
 }
 
 // CreateConversationRequest is the request body for creating a conversation
@@ -65,6 +135,8 @@ type CreateConversationRequest struct {
 	Stage       *string `json:"stage,omitempty"`
 	Niche       *string `json:"niche,omitempty"`
 	FlowID      *string `json:"flow_id,omitempty"`
+	ExternalRef *string `json:"external_ref,omitempty"` // Your own key for the conversation (default: DefaultExternalRef)
+	IsTest      bool    `json:"is_test,omitempty"`      // Test conversation (always set on a sandbox device)
 }
 
 // UpdateConversationRequest is the request body for updating a conversation
@@ -77,8 +149,42 @@ type UpdateConversationRequest struct {
 	CurrentNode         *string                 `json:"current_node,omitempty"`
 	SessionData         *map[string]interface{} `json:"session_data,omitempty"`
 	Status              *string                 `json:"status,omitempty"` // active, completed, abandoned
+	Pinned              *bool                   `json:"pinned,omitempty"`
+	Priority            *int                    `json:"priority,omitempty"` // 0 normal, 1 high, 2 urgent
+	IsTest              *bool                   `json:"is_test,omitempty"`  // Flag or unflag as test traffic
 }
 
+// PinConversationRequest is the request body for pinning a conversation or changing its priority
+type PinConversationRequest struct {
+	Pinned   *bool `json:"pinned,omitempty"`
+	Priority *int  `json:"priority,omitempty"` // 0 normal, 1 high, 2 urgent
+}
+
+// Conversation priorities, highest sorts first
+const (
+	ConversationPriorityNormal = 0
+	ConversationPriorityHigh   = 1
+	ConversationPriorityUrgent = 2
+)
+
+// IsValidConversationPriority reports whether p is a known priority
+func IsValidConversationPriority(p int) bool {
+	return p >= ConversationPriorityNormal && p <= ConversationPriorityUrgent
+}
+
+// inboxRank normalizes nullable pin/priority columns
+func inboxRank(pinned *bool, priority *int) (bool, int) {
+	isPinned := pinned != nil && *pinned
+	level := ConversationPriorityNormal
+	if priority != nil {
+		level = *priority
+	}
+	return isPinned, level
+}
+
+// InboxOrder is the PostgREST order clause that puts pinned, then high-priority conversations first
+const InboxOrder = "pinned.desc.nullslast,priority.desc.nullslast"
+
 // AddMessageRequest is the request body for adding a message to conversation history
 type AddMessageRequest struct {
 	Role    string `json:"role" validate:"required,oneof=user assistant system"`
@@ -87,18 +193,27 @@ type AddMessageRequest struct {
 
 // ConversationResponse is the response for conversation operations
 type ConversationResponse struct {
-	Success      bool           `json:"success"`
-	Message      string         `json:"message"`
-	Conversation *AIWhatsapp    `json:"conversation,omitempty"`
-	Conversations []AIWhatsapp  `json:"conversations,omitempty"`
+	Success       bool         `json:"success"`
+	Message       string       `json:"message"`
+	Conversation  *AIWhatsapp  `json:"conversation,omitempty"`
+	Conversations []AIWhatsapp `json:"conversations,omitempty"`
+	CostLedger    *CostLedger  `json:"cost_ledger,omitempty"` // Detail endpoint only
+	// Columns and Rows replace Conversations when a device list uses list columns, sort or filters
+	Columns []string                 `json:"columns,omitempty"`
+	Rows    []map[string]interface{} `json:"rows,omitempty"`
+	// Pagination is set by GET /api/conversations
+	Pagination *ConversationPagination `json:"pagination,omitempty"`
 }
 
 // WasapbotResponse is the response for wasapbot operations
 type WasapbotResponse struct {
-	Success       bool        `json:"success"`
-	Message       string      `json:"message"`
-	Conversation  *Wasapbot   `json:"conversation,omitempty"`
-	Conversations []Wasapbot  `json:"conversations,omitempty"`
+	Success       bool       `json:"success"`
+	Message       string     `json:"message"`
+	Conversation  *Wasapbot  `json:"conversation,omitempty"`
+	Conversations []Wasapbot `json:"conversations,omitempty"`
+	// Columns and Rows replace Conversations when a device list uses list columns, sort or filters
+	Columns []string                 `json:"columns,omitempty"`
+	Rows    []map[string]interface{} `json:"rows,omitempty"`
 }
 
 // ConversationStats represents conversation statistics
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Checkpoint reasons
+const (
+	CheckpointReasonRun     = "run"     // state after the engine handled an inbound message
+	CheckpointReasonRestore = "restore" // state written by a restore
+)
+
+// ConversationCheckpoint is the engine state of a conversation at one point in its history
+type ConversationCheckpoint struct {
+	ID                string                 `json:"id,omitempty"`
+	ConversationTable string                 `json:"conversation_table"` // ai_whatsapp
+	ConversationID    string                 `json:"conversation_id"`    // id_prospect
+	IDDevice          string                 `json:"id_device"`
+	ProspectNum       string                 `json:"prospect_num"`
+	FlowID            *string                `json:"flow_id,omitempty"`
+	NodeID            *string                `json:"node_id,omitempty"` // current_node_id at the checkpoint
+	Reason            string                 `json:"reason"`
+	State             map[string]interface{} `json:"state"` // engine columns and their values
+	CreatedAt         *time.Time             `json:"created_at,omitempty"`
+}
+
+// ConversationStep is a checkpoint numbered in history order, starting at 1
+type ConversationStep struct {
+	Step       int                    `json:"step"`
+	NodeID     string                 `json:"node_id,omitempty"`
+	FlowID     string                 `json:"flow_id,omitempty"`
+	Reason     string                 `json:"reason"`
+	State      map[string]interface{} `json:"state"`
+	RecordedAt *time.Time             `json:"recorded_at,omitempty"`
+}
+
+// ConversationStepsResponse lists the steps a conversation can be restored to
+type ConversationStepsResponse struct {
+	Success bool               `json:"success"`
+	Message string             `json:"message,omitempty"`
+	Steps   []ConversationStep `json:"steps,omitempty"`
+}
+
+// RestoreConversationResponse is the response from restoring a conversation to an earlier step
+type RestoreConversationResponse struct {
+	Success      bool              `json:"success"`
+	Message      string            `json:"message"`
+	Step         *ConversationStep `json:"step,omitempty"`
+	Conversation *AIWhatsapp       `json:"conversation,omitempty"`
+	// MessagesSince were sent after the step; the prospect has already received them
+	MessagesSince []SentMessage `json:"messages_since,omitempty"`
+}
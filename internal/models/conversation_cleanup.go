@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ConversationCleanupStatusTest selects the conversations flagged is_test
+const ConversationCleanupStatusTest = "test"
+
+// CleanupConversation is one conversation matched by a cleanup
+type CleanupConversation struct {
+	Table           string     `json:"table"` // ai_whatsapp or wasapbot
+	IDProspect      int        `json:"id_prospect"`
+	ProspectNum     string     `json:"prospect_num"`
+	Stage           *string    `json:"stage,omitempty"`
+	ExecutionStatus *string    `json:"execution_status,omitempty"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+}
+
+// CleanupConversationsResponse is the response from DELETE /api/conversations/cleanup
+type CleanupConversationsResponse struct {
+	Success       bool                  `json:"success"`
+	Message       string                `json:"message"`
+	DryRun        bool                  `json:"dry_run"`
+	Matched       int                   `json:"matched"`
+	Deleted       int                   `json:"deleted"`
+	Conversations []CleanupConversation `json:"conversations,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Conversation message roles
+const (
+	MessageRoleUser = "user" // the prospect
+	MessageRoleBot  = "bot"  // flow nodes, AI replies and agents
+)
+
+// ConversationMessage is one message of a conversation's history. conv_last on the conversation
+// row is rendered from these entries ("User: ..." / "Bot: ..." lines) and kept for the code and
+// exports that still read the text history.
+type ConversationMessage struct {
+	ID             string        `json:"id,omitempty"`
+	Source         string        `json:"source"` // ai_whatsapp, wasapbot
+	ConversationID string        `json:"conversation_id"`
+	IDDevice       string        `json:"id_device"`
+	Role           string        `json:"role"`
+	Content        string        `json:"content"`
+	Media          *InboundMedia `json:"media,omitempty"`
+	NodeID         *string       `json:"node_id,omitempty"` // flow node that sent the message, or that a reply answered
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// MessageRoleFromConv maps a conv_last prefix (User, Bot) to a message role
+func MessageRoleFromConv(convRole string) string {
+	if strings.EqualFold(convRole, "user") {
+		return MessageRoleUser
+	}
+	return MessageRoleBot
+}
+
+// ConvRole is the conv_last prefix of the message's role
+func (m *ConversationMessage) ConvRole() string {
+	if m.Role == MessageRoleUser {
+		return "User"
+	}
+	return "Bot"
+}
+
+// ConvLastFromMessages renders messages, oldest first, in the conv_last format
+func ConvLastFromMessages(messages []ConversationMessage) string {
+	lines := make([]string, 0, len(messages))
+	for i := range messages {
+		lines = append(lines, messages[i].ConvRole()+": "+messages[i].Content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConversationMessagesResponse is the response for a conversation's structured history.
+// ConvLast is the same history in the conv_last format.
+type ConversationMessagesResponse struct {
+	Success  bool                  `json:"success"`
+	Message  string                `json:"message"`
+	Messages []ConversationMessage `json:"messages"`
+	ConvLast string                `json:"conv_last"`
+}
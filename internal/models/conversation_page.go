@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Page sizes of GET /api/conversations
+const (
+	DefaultConversationPageSize = 50
+	MaxConversationPageSize     = 200
+)
+
+// ConversationFilter is the query of GET /api/conversations: filters over the user's
+// conversations and the page to return, by page number or by cursor
+type ConversationFilter struct {
+	DeviceID string // one of the user's devices (empty = all of them)
+	Stage    string
+	Status   string // execution_status
+	Niche    string
+	From     string // created at or after: RFC 3339, or a date meaning midnight UTC
+	To       string // created before: RFC 3339, or a date that is included whole
+	Phone    string // digits found anywhere in prospect_num
+	Page     int    // 1-based; ignored when Cursor is set
+	PageSize int
+	Cursor   string // next_cursor of the previous page
+}
+
+// ConversationPageQuery is a resolved ConversationFilter as the repository runs it. Rows are
+// ordered newest first by created_at, then id_prospect, so After continues a listing exactly.
+type ConversationPageQuery struct {
+	DeviceIDs []string
+	Stage     string
+	Status    string
+	Niche     string
+	From      *time.Time
+	To        *time.Time // exclusive
+	Phone     string
+	After     *ConversationCursor
+	Offset    int
+	Limit     int
+}
+
+// ConversationCursor is the position of the last conversation of a page
+type ConversationCursor struct {
+	CreatedAt  time.Time
+	IDProspect int
+}
+
+// ConversationPagination describes the page returned by GET /api/conversations
+type ConversationPagination struct {
+	Page       int    `json:"page,omitempty"` // not set when paging by cursor
+	PageSize   int    `json:"page_size"`
+	Total      *int   `json:"total,omitempty"` // matching conversations; only counted when paging by number
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,131 @@
+package models
+
+// ConversationState is the lifecycle state of a flow execution on a conversation.
+// It is stored in the execution_status column; waiting_for_reply and current_node_id
+// are derived from it so the three columns can never contradict each other.
+type ConversationState string
+
+const (
+	ConversationStateActive         ConversationState = "active"           // Flow is executing nodes
+	ConversationStateWaiting        ConversationState = "waiting"          // Paused on a waiting_reply node
+	ConversationStateScheduled      ConversationState = "scheduled"        // Paused until a scheduled resume
+	ConversationStateHandoff        ConversationState = "handoff"          // A human agent owns the conversation
+	ConversationStateCompleted      ConversationState = "completed"        // Flow reached its last node
+	ConversationStateAbandoned      ConversationState = "abandoned"        // Prospect stopped responding / closed manually
+	ConversationStateFailedMaxSteps ConversationState = "failed_max_steps" // Stopped by the loop guard (step or node visit limit)
+	ConversationStatePausedByAgent  ConversationState = "paused_by_agent"  // An agent took over from the inbox; no flow runs until handed back
+)
+
+// CompletedNodeID is the current_node_id marker written when a flow completes
+const CompletedNodeID = "completed"
+
+// conversationStateTransitions lists the allowed next states for each state
+var conversationStateTransitions = map[ConversationState][]ConversationState{
+	ConversationStateActive: {
+		ConversationStateActive,
+		ConversationStateWaiting,
+		ConversationStateScheduled,
+		ConversationStateHandoff,
+		ConversationStateCompleted,
+		ConversationStateAbandoned,
+		ConversationStateFailedMaxSteps,
+		ConversationStatePausedByAgent,
+	},
+	ConversationStateWaiting: {
+		ConversationStateActive,
+		ConversationStateWaiting,
+		ConversationStateScheduled,
+		ConversationStateHandoff,
+		ConversationStateCompleted,
+		ConversationStateAbandoned,
+		ConversationStatePausedByAgent,
+	},
+	ConversationStateScheduled: {
+		ConversationStateActive,
+		ConversationStateWaiting,
+		ConversationStateHandoff,
+		ConversationStateCompleted,
+		ConversationStateAbandoned,
+		ConversationStatePausedByAgent,
+	},
+	ConversationStateHandoff: {
+		ConversationStateActive,
+		ConversationStateCompleted,
+		ConversationStateAbandoned,
+		ConversationStatePausedByAgent,
+	},
+	ConversationStateCompleted: {
+		ConversationStateActive,        // Flow restarted or routed to another flow
+		ConversationStateHandoff,       // Escalated after completion
+		ConversationStatePausedByAgent, // An agent picks the prospect up
+	},
+	ConversationStateAbandoned: {
+		ConversationStateActive,        // Prospect came back
+		ConversationStateHandoff,       // Escalated after coming back
+		ConversationStatePausedByAgent, // An agent picks the prospect up
+	},
+	ConversationStateFailedMaxSteps: {
+		ConversationStateActive,        // Restored or restarted after the flow was fixed
+		ConversationStateHandoff,       // An agent takes over
+		ConversationStateAbandoned,     // Closed manually
+		ConversationStatePausedByAgent, // An agent takes over
+	},
+	// Runs still finishing when the agent took over cannot pause or complete the conversation;
+	// only the hand-back (active), an escalation or closing it moves it on
+	ConversationStatePausedByAgent: {
+		ConversationStateActive,
+		ConversationStateHandoff,
+		ConversationStateAbandoned,
+		ConversationStatePausedByAgent,
+	},
+}
+
+// IsValid reports whether the state is one of the known states
+func (s ConversationState) IsValid() bool {
+	_, ok := conversationStateTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether the flow has stopped for good in this state
+func (s ConversationState) IsTerminal() bool {
+	return s == ConversationStateCompleted || s == ConversationStateAbandoned || s == ConversationStateFailedMaxSteps
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s ConversationState) CanTransitionTo(next ConversationState) bool {
+	for _, allowed := range conversationStateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecutionState is the persisted execution snapshot of a conversation row
+type ExecutionState struct {
+	ExecutionStatus *string `json:"execution_status,omitempty"`
+	WaitingForReply *bool   `json:"waiting_for_reply,omitempty"`
+	CurrentNodeID   *string `json:"current_node_id,omitempty"`
+}
+
+// State derives the conversation state from the stored columns.
+// Legacy rows that only set waiting_for_reply are reported as waiting.
+func (e *ExecutionState) State() ConversationState {
+	if e == nil || e.ExecutionStatus == nil || *e.ExecutionStatus == "" {
+		if e != nil && e.WaitingForReply != nil && *e.WaitingForReply {
+			return ConversationStateWaiting
+		}
+		return ConversationStateActive
+	}
+
+	state := ConversationState(*e.ExecutionStatus)
+	if !state.IsValid() {
+		return ConversationStateActive
+	}
+
+	if state == ConversationStateActive && e.WaitingForReply != nil && *e.WaitingForReply {
+		return ConversationStateWaiting
+	}
+
+	return state
+}
@@ -0,0 +1,25 @@
+package models
+
+// AgentMessageRequest is a message an agent sends to the prospect from the conversation's device
+type AgentMessageRequest struct {
+	Message   string `json:"message"`
+	MediaType string `json:"media_type,omitempty"` // image, video, document, audio
+	MediaURL  string `json:"media_url,omitempty"`
+	Agent     string `json:"agent,omitempty"` // credited with the reply in response time analytics
+}
+
+// HandBackRequest returns a taken-over conversation to the bot. Exactly one of NodeID and Stage
+// picks where the flow continues; Message is handed to the first nodes like a prospect reply.
+type HandBackRequest struct {
+	NodeID  string `json:"node_id,omitempty"`
+	Stage   string `json:"stage,omitempty"` // value of a stage node in the conversation's flow
+	Message string `json:"message,omitempty"`
+}
+
+// TakeoverResponse is the response of the takeover, agent message and hand-back endpoints
+type TakeoverResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	State   ConversationState `json:"state,omitempty"`
+	NodeID  string            `json:"node_id,omitempty"` // node the bot continued from on a hand-back
+}
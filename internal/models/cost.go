@@ -0,0 +1,163 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Cost entry types recorded in the conversation_costs ledger
+const (
+	CostTypeAITokens = "ai_tokens"
+	CostTypeSendFee  = "send_fee"
+	CostTypeAPICall  = "api_call"
+)
+
+// ConversationCost is one line in the conversation_costs ledger.
+// Entries are keyed by device + prospect so they cover both ai_whatsapp and wasapbot conversations.
+type ConversationCost struct {
+	ID               string     `json:"id,omitempty"`
+	IDDevice         string     `json:"id_device"`
+	ProspectNum      string     `json:"prospect_num"`
+	FlowID           *string    `json:"flow_id,omitempty"`
+	CostType         string     `json:"cost_type"`                   // ai_tokens, send_fee, api_call
+	Model            *string    `json:"model,omitempty"`             // AI model for ai_tokens entries
+	PromptTokens     *int       `json:"prompt_tokens,omitempty"`     // ai_tokens only
+	CompletionTokens *int       `json:"completion_tokens,omitempty"` // ai_tokens only
+	Amount           float64    `json:"amount"`                      // USD
+	Description      *string    `json:"description,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+}
+
+// CostLedger is the cost breakdown for a single conversation
+type CostLedger struct {
+	IDDevice     string             `json:"id_device"`
+	ProspectNum  string             `json:"prospect_num"`
+	TotalAmount  float64            `json:"total_amount"`
+	ByType       map[string]float64 `json:"by_type"`
+	TotalTokens  int                `json:"total_tokens"`
+	UnpricedRuns int                `json:"unpriced_runs"` // ai_tokens entries for models missing from the price table
+	Entries      []ConversationCost `json:"entries"`
+}
+
+// NewCostLedger totals ledger entries for one conversation
+func NewCostLedger(idDevice, prospectNum string, entries []ConversationCost) *CostLedger {
+	ledger := &CostLedger{
+		IDDevice:    idDevice,
+		ProspectNum: prospectNum,
+		ByType:      make(map[string]float64),
+		Entries:     entries,
+	}
+	if ledger.Entries == nil {
+		ledger.Entries = []ConversationCost{}
+	}
+
+	for _, entry := range entries {
+		ledger.TotalAmount += entry.Amount
+		ledger.ByType[entry.CostType] += entry.Amount
+		if entry.CostType != CostTypeAITokens {
+			continue
+		}
+		if entry.PromptTokens != nil {
+			ledger.TotalTokens += *entry.PromptTokens
+		}
+		if entry.CompletionTokens != nil {
+			ledger.TotalTokens += *entry.CompletionTokens
+		}
+		if entry.Amount == 0 {
+			ledger.UnpricedRuns++
+		}
+	}
+
+	return ledger
+}
+
+// LeadCost is one row of the per-lead cost export
+type LeadCost struct {
+	IDDevice    string             `json:"id_device"`
+	ProspectNum string             `json:"prospect_num"`
+	TotalAmount float64            `json:"total_amount"`
+	ByType      map[string]float64 `json:"by_type"`
+	TotalTokens int                `json:"total_tokens"`
+	Entries     int                `json:"entries"`
+}
+
+// CostExportResponse is the JSON form of the per-lead cost export
+type CostExportResponse struct {
+	Success     bool       `json:"success"`
+	Message     string     `json:"message,omitempty"`
+	TotalAmount float64    `json:"total_amount"`
+	Leads       []LeadCost `json:"leads"`
+}
+
+// ModelPrice is the USD price per one million tokens
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// AIModelPrices maps model names (OpenRouter style "vendor/model" or bare) to token prices.
+// Lookups fall back to the longest matching prefix so dated model variants share a price.
+var AIModelPrices = map[string]ModelPrice{
+	"openai/gpt-4.1":                   {Prompt: 2.00, Completion: 8.00},
+	"openai/gpt-4.1-mini":              {Prompt: 0.40, Completion: 1.60},
+	"openai/gpt-4.1-nano":              {Prompt: 0.10, Completion: 0.40},
+	"openai/gpt-4o":                    {Prompt: 2.50, Completion: 10.00},
+	"openai/gpt-4o-mini":               {Prompt: 0.15, Completion: 0.60},
+	"openai/gpt-4-turbo":               {Prompt: 10.00, Completion: 30.00},
+	"openai/gpt-4":                     {Prompt: 30.00, Completion: 60.00},
+	"openai/gpt-3.5-turbo":             {Prompt: 0.50, Completion: 1.50},
+	"anthropic/claude-3-opus":          {Prompt: 15.00, Completion: 75.00},
+	"anthropic/claude-3-sonnet":        {Prompt: 3.00, Completion: 15.00},
+	"anthropic/claude-3.5-sonnet":      {Prompt: 3.00, Completion: 15.00},
+	"anthropic/claude-3-haiku":         {Prompt: 0.25, Completion: 1.25},
+	"google/gemini-2.0-flash-001":      {Prompt: 0.10, Completion: 0.40},
+	"google/gemini-flash-1.5":          {Prompt: 0.075, Completion: 0.30},
+	"deepseek/deepseek-chat":           {Prompt: 0.27, Completion: 1.10},
+	"meta-llama/llama-3.1-8b-instruct": {Prompt: 0.02, Completion: 0.05},
+}
+
+// LookupModelPrice finds the price for a model, trying the bare name under common vendors and prefixes
+func LookupModelPrice(model string) (ModelPrice, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return ModelPrice{}, false
+	}
+
+	candidates := []string{model}
+	if !strings.Contains(model, "/") {
+		if strings.HasPrefix(model, "claude") {
+			candidates = append(candidates, "anthropic/"+model)
+		} else {
+			candidates = append(candidates, "openai/"+model)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if price, ok := AIModelPrices[candidate]; ok {
+			return price, true
+		}
+	}
+
+	// Longest prefix wins, e.g. "anthropic/claude-3-haiku-20240307" -> "anthropic/claude-3-haiku"
+	best := ""
+	for _, candidate := range candidates {
+		for name := range AIModelPrices {
+			if strings.HasPrefix(candidate, name) && len(name) > len(best) {
+				best = name
+			}
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return AIModelPrices[best], true
+}
+
+// AITokenCost prices a completion; ok is false when the model has no known price
+func AITokenCost(model string, promptTokens, completionTokens int) (float64, bool) {
+	price, ok := LookupModelPrice(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1_000_000, true
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom field types
+const (
+	CustomFieldText    = "text"
+	CustomFieldNumber  = "number"
+	CustomFieldDate    = "date"    // stored as YYYY-MM-DD
+	CustomFieldBoolean = "boolean" // stored as "true" or "false"
+	CustomFieldSelect  = "select"  // one of Options
+)
+
+// MaxCustomFields caps how many custom fields a user can define
+const MaxCustomFields = 50
+
+// customFieldDateLayouts are the date formats a date field accepts, in the order they are tried
+var customFieldDateLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006", "2 Jan 2006", "2 January 2006"}
+
+// ContactCustomField is a contact field a user defines beyond the fixed conversation columns.
+// Captured values are kept in the conversation's custom_fields under Name.
+type ContactCustomField struct {
+	ID        string     `json:"id,omitempty"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`  // lowercase key, e.g. saiz_baju
+	Label     string     `json:"label"` // shown in the builder and list headers, e.g. Saiz Baju
+	FieldType string     `json:"field_type"`
+	Options   []string   `json:"options"`           // allowed values of a select field
+	Pattern   *string    `json:"pattern,omitempty"` // regular expression a text value must match
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// IsValidCustomFieldType reports whether t is one of the custom field types
+func IsValidCustomFieldType(t string) bool {
+	switch t {
+	case CustomFieldText, CustomFieldNumber, CustomFieldDate, CustomFieldBoolean, CustomFieldSelect:
+		return true
+	}
+	return false
+}
+
+// NormalizeValue validates a captured value against the field and returns it in its stored form
+func (f *ContactCustomField) NormalizeValue(raw string) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", nil
+	}
+
+	switch f.FieldType {
+	case CustomFieldNumber:
+		number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a number", f.Name)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+
+	case CustomFieldDate:
+		for _, layout := range customFieldDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+		}
+		return "", fmt.Errorf("%s must be a date such as 2025-01-31 or 31/01/2025", f.Name)
+
+	case CustomFieldBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "ya", "1":
+			return "true", nil
+		case "false", "no", "n", "tidak", "tak", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be yes or no", f.Name)
+
+	case CustomFieldSelect:
+		for _, option := range f.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of: %s", f.Name, strings.Join(f.Options, ", "))
+	}
+
+	if f.Pattern != nil && *f.Pattern != "" {
+		re, err := regexp.Compile(*f.Pattern)
+		if err != nil || !re.MatchString(value) {
+			return "", fmt.Errorf("%s does not match the expected format", f.Name)
+		}
+	}
+	return value, nil
+}
+
+// CreateCustomFieldRequest is the request body for defining a custom field
+type CreateCustomFieldRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Label     string   `json:"label,omitempty"`      // defaults to Name
+	FieldType string   `json:"field_type,omitempty"` // defaults to text
+	Options   []string `json:"options,omitempty"`
+	Pattern   *string  `json:"pattern,omitempty"`
+}
+
+// UpdateCustomFieldRequest is the request body for changing a custom field.
+// The name and type are fixed once values have been captured under them.
+type UpdateCustomFieldRequest struct {
+	Label   *string   `json:"label,omitempty"`
+	Options *[]string `json:"options,omitempty"`
+	Pattern *string   `json:"pattern,omitempty"` // empty string removes the pattern
+}
+
+// CustomFieldResponse is the response for custom field operations
+type CustomFieldResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message"`
+	Field   *ContactCustomField  `json:"field,omitempty"`
+	Fields  []ContactCustomField `json:"fields,omitempty"`
+}
+
+// FieldMetrics counts conversations per value of a conversation or custom field
+type FieldMetrics struct {
+	Field   string         `json:"field"`
+	Label   string         `json:"label"`
+	Total   int            `json:"total"`
+	Empty   int            `json:"empty"` // conversations with no value captured yet
+	ByValue map[string]int `json:"by_value"`
+}
+
+// FieldAnalyticsResponse represents the field group-by analytics response
+type FieldAnalyticsResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Data    *FieldMetrics `json:"data,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// CustomProviderConfig describes the HTTP API of a WhatsApp gateway the "custom" provider sends
+// through. URLs, headers and bodies are templates filled per message with {{to}}, {{message}},
+// {{type}}, {{media_url}}, {{mime_type}}, {{instance}}, {{api_key}} and {{base_url}} (the device's
+// api_url). Values are escaped for where they appear: query-escaped in URLs, JSON-escaped in JSON
+// bodies and form-escaped in form bodies, so a body template quotes them itself:
+//
+//	{"send_url": "{{base_url}}/messages", "headers": {"Authorization": "Bearer {{api_key}}"},
+//	 "body": "{\"to\": \"{{to}}\", \"text\": \"{{message}}\"}", "message_id_field": "data.id"}
+type CustomProviderConfig struct {
+	SendURL        string            `json:"send_url"`
+	Method         string            `json:"method,omitempty"`           // POST by default
+	Headers        map[string]string `json:"headers,omitempty"`          // header value templates
+	ContentType    string            `json:"content_type,omitempty"`     // application/json by default
+	Body           string            `json:"body,omitempty"`             // body template of text messages
+	MediaBody      string            `json:"media_body,omitempty"`       // body template of media messages; body when empty
+	MessageIDField string            `json:"message_id_field,omitempty"` // dotted path of the message ID in the JSON response
+	StatusURL      string            `json:"status_url,omitempty"`       // GET template answering 2xx while connected
+}
+
+// DefaultCustomProviderBody is the JSON body of custom provider sends without a body template
+const DefaultCustomProviderBody = `{"to": "{{to}}", "message": "{{message}}", "type": "{{type}}", "media_url": "{{media_url}}"}`
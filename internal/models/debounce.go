@@ -0,0 +1,25 @@
+package models
+
+// DebounceWindowRequest is sent by the external debouncer for every queued message.
+// QueueDepth is how many senders of the device currently have messages waiting;
+// SenderRecent is how many messages this sender queued in the last DebounceBurstWindowMs.
+type DebounceWindowRequest struct {
+	DeviceID     string `json:"device_id"`
+	Phone        string `json:"phone,omitempty"`
+	QueueDepth   int    `json:"queue_depth"`
+	SenderRecent int    `json:"sender_recent"`
+}
+
+// DebounceBurstWindowMs is the look-back the debouncer uses to count a sender's recent messages
+const DebounceBurstWindowMs = 10000
+
+// DebounceWindowResponse tells the debouncer how long to wait for more messages before processing
+type DebounceWindowResponse struct {
+	Success  bool    `json:"success"`
+	Message  string  `json:"message,omitempty"`
+	WindowMs int     `json:"window_ms"`
+	MinMs    int     `json:"min_ms"`
+	MaxMs    int     `json:"max_ms"`
+	Load     float64 `json:"load"`  // 0 (idle) to 1 (heavy), from queue depth
+	Burst    float64 `json:"burst"` // 0 (single message) to 1 (rapid-fire), from the sender's recent messages
+}
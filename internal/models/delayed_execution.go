@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Delayed execution statuses
+const (
+	DelayedExecutionPending = "pending" // waiting for resume_at
+	DelayedExecutionRunning = "running" // claimed by a dispatcher
+	DelayedExecutionDone    = "done"    // the flow was resumed
+	DelayedExecutionSkipped = "skipped" // the conversation moved on before resume_at (reply, handoff, new pause)
+	DelayedExecutionFailed  = "failed"  // resuming the flow returned an error
+)
+
+// DelayedExecution is a paused flow run that the flow scheduler resumes after NodeID at ResumeAt.
+// delay nodes park the conversation in the scheduled state; waiting_times nodes park it in the
+// waiting state, so a reply before ResumeAt resumes the flow first and the row is skipped.
+type DelayedExecution struct {
+	ID             string     `json:"id,omitempty"`
+	Source         string     `json:"source"` // ai_whatsapp, wasapbot
+	ConversationID string     `json:"conversation_id"`
+	IDDevice       string     `json:"id_device"`
+	FlowID         string     `json:"flow_id"`
+	NodeID         string     `json:"node_id"`
+	NodeType       string     `json:"node_type"` // delay, waiting_times
+	ResumeAt       time.Time  `json:"resume_at"`
+	Status         string     `json:"status"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// PausedState is the conversation state the flow rests in until the execution resumes it
+func (e *DelayedExecution) PausedState() ConversationState {
+	if e.NodeType == "waiting_times" {
+		return ConversationStateWaiting
+	}
+	return ConversationStateScheduled
+}
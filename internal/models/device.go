@@ -8,7 +8,7 @@ type DeviceSetting struct {
 	DeviceID     *string    `json:"device_id,omitempty"`
 	Instance     *string    `json:"instance,omitempty"`
 	WebhookID    *string    `json:"webhook_id,omitempty"`
-	Provider     string     `json:"provider"` // waha, wablas, whacenter
+	Provider     string     `json:"provider"` // waha, wablas, whacenter, custom
 	APIURL       *string    `json:"api_url,omitempty"` // Base URL for provider API
 	APIKeyOption string     `json:"api_key_option"` // openai/gpt-4.1, etc.
 	APIKey       *string    `json:"api_key,omitempty"`
@@ -19,13 +19,179 @@ type DeviceSetting struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	UserID       *string    `json:"user_id,omitempty"`
+	// MaxHistoryEntries caps how many "User:"/"Bot:" entries Wasapbot keeps in conv_last (nil = default)
+	MaxHistoryEntries *int `json:"max_history_entries,omitempty"`
+	// BackupDeviceID is the device_setting.id that takes over sending while this device is disconnected
+	BackupDeviceID *string `json:"backup_device_id,omitempty"`
+	// FailoverNotice is sent once per prospect when the backup uses a different number
+	FailoverNotice *string `json:"failover_notice,omitempty"`
+	// ConnectionStatus is maintained by the device health monitor (connected, disconnected)
+	ConnectionStatus *string    `json:"connection_status,omitempty"`
+	StatusCheckedAt  *time.Time `json:"status_checked_at,omitempty"`
+	// SendFee is the provider charge per outbound message in USD, recorded in the cost ledger (nil = free)
+	SendFee *float64 `json:"send_fee,omitempty"`
+	// WorkingLanguage is the seller's language (ISO 639-1) that translate nodes translate prospects into
+	WorkingLanguage *string `json:"working_language,omitempty"`
+	// HeartbeatURL receives the device status JSON from the health monitor on every check (UptimeRobot, Better Stack, ...)
+	HeartbeatURL *string `json:"heartbeat_url,omitempty"`
+	// Timezone is the IANA zone (e.g. Asia/Kuala_Lumpur) that time-based flow conditions are evaluated in
+	Timezone *string `json:"timezone,omitempty"`
+	// AIPersona is injected into every ai_prompt system prompt for this device
+	AIPersona *AIPersona `json:"ai_persona,omitempty"`
+	// Sandbox intercepts every provider send and logs it to sandbox_messages instead of delivering (staging)
+	Sandbox bool `json:"sandbox"`
+	// ListColumns are the captured fields (pakej, alamat, session variables, ...) shown as conversation list columns
+	ListColumns []string `json:"list_columns,omitempty"`
+	// TrackingDomain is the base URL (e.g. https://go.example.com) of short links wrapped around flow URLs
+	TrackingDomain *string `json:"tracking_domain,omitempty"`
+	// DebounceMinMs and DebounceMaxMs bound the adaptive debounce window (nil = defaults)
+	DebounceMinMs *int `json:"debounce_min_ms,omitempty"`
+	DebounceMaxMs *int `json:"debounce_max_ms,omitempty"`
+	// AIEndpoints overrides the deployment's AI provider endpoints for this device, keyed by provider
+	AIEndpoints map[AIProvider]AIEndpoint `json:"ai_endpoints,omitempty"`
+	// DefaultPromptTemplate names the prompt template of ai_prompt nodes that do not pick one (nil = built-in)
+	DefaultPromptTemplate *string `json:"default_prompt_template,omitempty"`
+	// AIFallbackModel is tried once when the AI model still fails after retries (nil = no fallback)
+	AIFallbackModel *string `json:"ai_fallback_model,omitempty"`
+	// EmailReplies appends email replies to <webhook_id>@inbound domain to the conversation history
+	EmailReplies bool `json:"email_replies"`
+	// ArchiveInbound keeps every accepted inbound webhook payload in storage for ArchiveRetentionDays
+	ArchiveInbound       bool `json:"archive_inbound"`
+	ArchiveRetentionDays *int `json:"archive_retention_days,omitempty"`
+	// SendRatePerMinute caps outbound messages per minute through the device's send queue (nil = default)
+	SendRatePerMinute *int `json:"send_rate_per_minute,omitempty"`
+	// ReplyProfile is maintained by the reply timing learner; long follow-up pauses resume in its best hours
+	ReplyProfile *ReplyProfile `json:"reply_profile,omitempty"`
+	// StaticFollowUps keeps every follow-up pause at its fixed offset instead of the best reply hours
+	StaticFollowUps bool `json:"static_follow_ups"`
+	// CustomProvider describes the HTTP gateway of a device whose provider is "custom"
+	CustomProvider *CustomProviderConfig `json:"custom_provider,omitempty"`
+	// TelegramBotToken connects a Telegram bot so the device's flows also serve Telegram chats
+	TelegramBotToken *string `json:"telegram_bot_token,omitempty"`
+	// Messenger connects a Facebook page (and its Instagram account) so the device's flows serve its inbox
+	Messenger *MessengerConfig `json:"messenger,omitempty"`
+	// TypingIndicator marks the chat read and shows typing while a bot message waits its typing time
+	TypingIndicator bool `json:"typing_indicator"`
+	// TypingCharsPerSecond is the typing speed that sets how long typing shows (nil = default)
+	TypingCharsPerSecond *int `json:"typing_chars_per_second,omitempty"`
+	// SendDelayMinMs and SendDelayMaxMs bound the random delay between consecutive messages to a prospect (nil = defaults)
+	SendDelayMinMs *int `json:"send_delay_min_ms,omitempty"`
+	SendDelayMaxMs *int `json:"send_delay_max_ms,omitempty"`
+	// QuietHoursStart and QuietHoursEnd (HH:MM, device timezone) hold bot messages until the quiet hours end
+	QuietHoursStart *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string `json:"quiet_hours_end,omitempty"`
+	// BusinessHours ("Mon-Fri 09:00-18:00; Sat 10:00-14:00", device timezone) are when the device is open (nil = always)
+	BusinessHours *string `json:"business_hours,omitempty"`
+	// ClosedMode is how inbound messages outside the business hours are handled (nil = away)
+	ClosedMode *string `json:"closed_mode,omitempty"`
+	// AwayFlowID is the flow that answers inbound messages outside the business hours in away mode
+	AwayFlowID *string `json:"away_flow_id,omitempty"`
+	// ShareProspectData copies stage and custom field changes of a prospect to the same prospect's
+	// conversations on the owner's other sharing devices
+	ShareProspectData bool `json:"share_prospect_data"`
+	// PhoneCountry (ISO 3166-1 alpha-2) is the country numbers in national format are read in (nil = MY)
+	PhoneCountry *string `json:"phone_country,omitempty"`
+}
+
+// Device connection statuses written by the health monitor
+const (
+	DeviceStatusConnected    = "connected"
+	DeviceStatusDisconnected = "disconnected"
+)
+
+// IsDisconnected reports whether the health monitor last saw this device disconnected
+func (d *DeviceSetting) IsDisconnected() bool {
+	return d.ConnectionStatus != nil && *d.ConnectionStatus == DeviceStatusDisconnected
+}
+
+// DefaultWorkingLanguage is the seller language used when a device has none set (Malay)
+const DefaultWorkingLanguage = "ms"
+
+// EffectiveWorkingLanguage returns the seller's working language for this device
+func (d *DeviceSetting) EffectiveWorkingLanguage() string {
+	if d.WorkingLanguage != nil && *d.WorkingLanguage != "" {
+		return *d.WorkingLanguage
+	}
+	return DefaultWorkingLanguage
+}
+
+// DefaultTimezone is the zone used for time-based conditions when a device has none set
+const DefaultTimezone = "Asia/Kuala_Lumpur"
+
+// Location returns the device's timezone, falling back to DefaultTimezone (UTC+8 if zone data is missing)
+func (d *DeviceSetting) Location() *time.Location {
+	if d.Timezone != nil && *d.Timezone != "" {
+		if loc, err := time.LoadLocation(*d.Timezone); err == nil {
+			return loc
+		}
+	}
+	if loc, err := time.LoadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.FixedZone("MYT", 8*60*60)
+}
+
+// DefaultMaxHistoryEntries is the conv_last cap used when a device has no override
+const DefaultMaxHistoryEntries = 20
+
+// EffectiveMaxHistoryEntries returns the conv_last cap for this device
+func (d *DeviceSetting) EffectiveMaxHistoryEntries() int {
+	if d.MaxHistoryEntries != nil && *d.MaxHistoryEntries > 0 {
+		return *d.MaxHistoryEntries
+	}
+	return DefaultMaxHistoryEntries
+}
+
+// EffectiveArchiveRetentionDays returns how many days this device's inbound payloads are kept
+func (d *DeviceSetting) EffectiveArchiveRetentionDays() int {
+	if d.ArchiveRetentionDays != nil && *d.ArchiveRetentionDays > 0 {
+		return *d.ArchiveRetentionDays
+	}
+	return DefaultArchiveRetentionDays
+}
+
+// EffectiveSendRatePerMinute returns the device's outbound send rate
+func (d *DeviceSetting) EffectiveSendRatePerMinute() int {
+	if d.SendRatePerMinute != nil && *d.SendRatePerMinute > 0 {
+		return *d.SendRatePerMinute
+	}
+	return DefaultSendRatePerMinute
+}
+
+// EffectiveTypingCharsPerSecond returns the device's typing indicator speed
+func (d *DeviceSetting) EffectiveTypingCharsPerSecond() int {
+	if d.TypingCharsPerSecond != nil && *d.TypingCharsPerSecond > 0 {
+		return *d.TypingCharsPerSecond
+	}
+	return DefaultTypingCharsPerSecond
+}
+
+// Adaptive debounce window bounds used when a device has no override, in milliseconds
+const (
+	DefaultDebounceMinMs = 2000
+	DefaultDebounceMaxMs = 15000
+)
+
+// EffectiveDebounceBounds returns the device's debounce window bounds in milliseconds
+func (d *DeviceSetting) EffectiveDebounceBounds() (int, int) {
+	minMs, maxMs := DefaultDebounceMinMs, DefaultDebounceMaxMs
+	if d.DebounceMinMs != nil && *d.DebounceMinMs > 0 {
+		minMs = *d.DebounceMinMs
+	}
+	if d.DebounceMaxMs != nil && *d.DebounceMaxMs > 0 {
+		maxMs = *d.DebounceMaxMs
+	}
+	if maxMs < minMs {
+		maxMs = minMs
+	}
+	return minMs, maxMs
 }
 
 // CreateDeviceRequest is the request body for creating a device
 type CreateDeviceRequest struct {
 	DeviceID     string  `json:"device_id"` // Only required for wablas provider
 	WebhookURL   string  `json:"webhook_url"`
-	Provider     string  `json:"provider" validate:"required,oneof=waha wablas whacenter"`
+	Provider     string  `json:"provider" validate:"required,oneof=waha wablas whacenter custom"`
 	APIURL       *string `json:"api_url,omitempty"` // Base URL for provider API
 	APIKeyOption string  `json:"api_key_option" validate:"required"`
 	APIKey       *string `json:"api_key,omitempty"`
@@ -34,6 +200,41 @@ type CreateDeviceRequest struct {
 	IDERP        *string `json:"id_erp,omitempty"`
 	IDAdmin      *string `json:"id_admin,omitempty"`
 	Instance     *string `json:"instance,omitempty"`
+	MaxHistoryEntries *int `json:"max_history_entries,omitempty"`
+	BackupDeviceID    *string `json:"backup_device_id,omitempty"`
+	FailoverNotice    *string `json:"failover_notice,omitempty"`
+	SendFee           *float64 `json:"send_fee,omitempty"`
+	WorkingLanguage   *string  `json:"working_language,omitempty"`
+	HeartbeatURL      *string  `json:"heartbeat_url,omitempty"`
+	Timezone          *string  `json:"timezone,omitempty"`
+	AIPersona         *AIPersona `json:"ai_persona,omitempty"`
+	Sandbox           *bool      `json:"sandbox,omitempty"`
+	ListColumns       []string   `json:"list_columns,omitempty"`
+	TrackingDomain    *string    `json:"tracking_domain,omitempty"`
+	DebounceMinMs     *int       `json:"debounce_min_ms,omitempty"`
+	DebounceMaxMs     *int       `json:"debounce_max_ms,omitempty"`
+	AIEndpoints       map[AIProvider]AIEndpoint `json:"ai_endpoints,omitempty"`
+	DefaultPromptTemplate *string `json:"default_prompt_template,omitempty"`
+	EmailReplies          *bool   `json:"email_replies,omitempty"`
+	AIFallbackModel       *string `json:"ai_fallback_model,omitempty"`
+	ArchiveInbound        *bool   `json:"archive_inbound,omitempty"`
+	ArchiveRetentionDays  *int    `json:"archive_retention_days,omitempty"`
+	SendRatePerMinute     *int    `json:"send_rate_per_minute,omitempty"`
+	StaticFollowUps       *bool   `json:"static_follow_ups,omitempty"`
+	CustomProvider        *CustomProviderConfig `json:"custom_provider,omitempty"` // Required for the custom provider
+	TelegramBotToken      *string `json:"telegram_bot_token,omitempty"`
+	Messenger             *MessengerConfig `json:"messenger,omitempty"`
+	TypingIndicator       *bool   `json:"typing_indicator,omitempty"`
+	TypingCharsPerSecond  *int    `json:"typing_chars_per_second,omitempty"`
+	SendDelayMinMs        *int    `json:"send_delay_min_ms,omitempty"`
+	SendDelayMaxMs        *int    `json:"send_delay_max_ms,omitempty"`
+	QuietHoursStart       *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd         *string `json:"quiet_hours_end,omitempty"`
+	BusinessHours         *string `json:"business_hours,omitempty"`
+	ClosedMode            *string `json:"closed_mode,omitempty"`
+	AwayFlowID            *string `json:"away_flow_id,omitempty"`
+	ShareProspectData     *bool   `json:"share_prospect_data,omitempty"`
+	PhoneCountry          *string `json:"phone_country,omitempty"`
 }
 
 // UpdateDeviceRequest is the request body for updating a device
@@ -48,6 +249,41 @@ type UpdateDeviceRequest struct {
 	IDERP        *string `json:"id_erp,omitempty"`
 	IDAdmin      *string `json:"id_admin,omitempty"`
 	Instance     *string `json:"instance,omitempty"`
+	MaxHistoryEntries *int `json:"max_history_entries,omitempty"`
+	BackupDeviceID    *string `json:"backup_device_id,omitempty"` // Empty string unlinks the backup
+	FailoverNotice    *string `json:"failover_notice,omitempty"`
+	SendFee           *float64 `json:"send_fee,omitempty"`
+	WorkingLanguage   *string  `json:"working_language,omitempty"`
+	HeartbeatURL      *string  `json:"heartbeat_url,omitempty"` // Empty string removes the heartbeat
+	Timezone          *string  `json:"timezone,omitempty"`      // Empty string resets to the default
+	AIPersona         *AIPersona `json:"ai_persona,omitempty"`  // Empty object removes the persona
+	Sandbox           *bool      `json:"sandbox,omitempty"`
+	ListColumns       *[]string  `json:"list_columns,omitempty"` // Empty list removes the extra columns
+	TrackingDomain    *string    `json:"tracking_domain,omitempty"` // Empty string falls back to the default link domain
+	DebounceMinMs     *int       `json:"debounce_min_ms,omitempty"` // 0 resets to the default
+	DebounceMaxMs     *int       `json:"debounce_max_ms,omitempty"` // 0 resets to the default
+	AIEndpoints       *map[AIProvider]AIEndpoint `json:"ai_endpoints,omitempty"` // Empty object uses the deployment endpoints
+	DefaultPromptTemplate *string `json:"default_prompt_template,omitempty"` // Empty string uses the built-in template
+	EmailReplies          *bool   `json:"email_replies,omitempty"`
+	AIFallbackModel       *string `json:"ai_fallback_model,omitempty"` // Empty string removes the fallback
+	ArchiveInbound        *bool   `json:"archive_inbound,omitempty"`
+	ArchiveRetentionDays  *int    `json:"archive_retention_days,omitempty"` // 0 resets to the default; applies to payloads archived afterwards
+	SendRatePerMinute     *int    `json:"send_rate_per_minute,omitempty"`   // 0 resets to the default
+	StaticFollowUps       *bool   `json:"static_follow_ups,omitempty"`
+	CustomProvider        *CustomProviderConfig `json:"custom_provider,omitempty"`
+	TelegramBotToken      *string `json:"telegram_bot_token,omitempty"` // Empty string disconnects the bot
+	Messenger             *MessengerConfig `json:"messenger,omitempty"`    // Empty object disconnects the page
+	TypingIndicator       *bool   `json:"typing_indicator,omitempty"`
+	TypingCharsPerSecond  *int    `json:"typing_chars_per_second,omitempty"` // 0 resets to the default
+	SendDelayMinMs        *int    `json:"send_delay_min_ms,omitempty"`       // 0 resets to the default
+	SendDelayMaxMs        *int    `json:"send_delay_max_ms,omitempty"`       // 0 resets to the default
+	QuietHoursStart       *string `json:"quiet_hours_start,omitempty"`       // Empty strings remove the quiet hours
+	QuietHoursEnd         *string `json:"quiet_hours_end,omitempty"`
+	BusinessHours         *string `json:"business_hours,omitempty"`      // Empty string removes the business hours
+	ClosedMode            *string `json:"closed_mode,omitempty"`         // Empty string resets to away
+	AwayFlowID            *string `json:"away_flow_id,omitempty"`        // Empty string removes the away flow
+	ShareProspectData     *bool   `json:"share_prospect_data,omitempty"`
+	PhoneCountry          *string `json:"phone_country,omitempty"`
 }
 
 // DeviceResponse is the response for device operations
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// DeviceBundleVersion is the format version written into exported bundles
+const DeviceBundleVersion = 1
+
+// DeviceConfigBundle is a device's portable configuration: settings without secrets or
+// number-specific identifiers, stage configs and flows. Importing it into another device
+// replicates a proven setup for a new client number.
+type DeviceConfigBundle struct {
+	Version        int                  `json:"version"`
+	ExportedAt     time.Time            `json:"exported_at"`
+	SourceIDDevice string               `json:"source_id_device,omitempty"`
+	Settings       DeviceBundleSettings `json:"settings"`
+	Stages         []DeviceBundleStage  `json:"stages"`
+	Flows          []DeviceBundleFlow   `json:"flows"`
+}
+
+// DeviceBundleSettings are the copyable device settings. API keys, instance names, webhook IDs,
+// phone numbers, backup links and heartbeat URLs belong to one number and are never exported.
+type DeviceBundleSettings struct {
+	Provider          string     `json:"provider"`
+	APIURL            *string    `json:"api_url,omitempty"`
+	APIKeyOption      string     `json:"api_key_option"`
+	MaxHistoryEntries *int       `json:"max_history_entries,omitempty"`
+	FailoverNotice    *string    `json:"failover_notice,omitempty"`
+	SendFee           *float64   `json:"send_fee,omitempty"`
+	WorkingLanguage   *string    `json:"working_language,omitempty"`
+	Timezone          *string    `json:"timezone,omitempty"`
+	AIPersona         *AIPersona `json:"ai_persona,omitempty"`
+	ListColumns       []string   `json:"list_columns,omitempty"`
+	DebounceMinMs     *int       `json:"debounce_min_ms,omitempty"`
+	DebounceMaxMs     *int       `json:"debounce_max_ms,omitempty"`
+	// DefaultPromptTemplate is a template name; the importing user needs a template of that name
+	DefaultPromptTemplate *string `json:"default_prompt_template,omitempty"`
+	AIFallbackModel       *string `json:"ai_fallback_model,omitempty"`
+	SendRatePerMinute     *int    `json:"send_rate_per_minute,omitempty"`
+	TypingIndicator       bool    `json:"typing_indicator,omitempty"`
+	TypingCharsPerSecond  *int    `json:"typing_chars_per_second,omitempty"`
+	SendDelayMinMs        *int    `json:"send_delay_min_ms,omitempty"`
+	SendDelayMaxMs        *int    `json:"send_delay_max_ms,omitempty"`
+	QuietHoursStart       *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd         *string `json:"quiet_hours_end,omitempty"`
+	BusinessHours         *string `json:"business_hours,omitempty"`
+	ClosedMode            *string `json:"closed_mode,omitempty"`
+	PhoneCountry          *string `json:"phone_country,omitempty"`
+	// AwayFlowRef is the Ref of the bundled flow that answers while the device is closed
+	AwayFlowRef string `json:"away_flow_ref,omitempty"`
+}
+
+// DeviceBundleStage is a stage set value without its device and row ID
+type DeviceBundleStage struct {
+	Stage         string `json:"stage"`
+	TypeInputData string `json:"type_inputdata"`
+	ColumnsData   string `json:"columnsdata"`
+	InputHardCode string `json:"inputhardcode,omitempty"`
+}
+
+// DeviceBundleFlow is a flow without its device. Ref is the source flow ID, used only to
+// relink after_sales_flow_id and the device's away flow to flows in the same bundle.
+type DeviceBundleFlow struct {
+	Ref                  string           `json:"ref"`
+	Name                 string           `json:"name"`
+	Niche                string           `json:"niche"`
+	NodesData            string           `json:"nodes_data"`
+	CompletionPolicy     CompletionPolicy `json:"completion_policy,omitempty"`
+	AfterSalesRef        string           `json:"after_sales_ref,omitempty"`
+	ReentryCooldownHours int              `json:"reentry_cooldown_hours,omitempty"`
+	NormalizeMalay       bool             `json:"normalize_malay,omitempty"`
+	Triggers             []FlowTrigger    `json:"triggers,omitempty"`
+	IsActive             *bool            `json:"is_active,omitempty"`
+	Priority             int              `json:"priority,omitempty"`
+}
+
+// DeviceConfigImportRequest applies a bundle to an existing device
+type DeviceConfigImportRequest struct {
+	Bundle     DeviceConfigBundle `json:"bundle" validate:"required"`
+	SkipFlows  bool               `json:"skip_flows,omitempty"`
+	SkipStages bool               `json:"skip_stages,omitempty"`
+}
+
+// DeviceConfigImportResponse summarizes an import
+type DeviceConfigImportResponse struct {
+	Success         bool     `json:"success"`
+	Message         string   `json:"message"`
+	SettingsApplied bool     `json:"settings_applied"`
+	StagesCreated   int      `json:"stages_created"`
+	StagesSkipped   int      `json:"stages_skipped"` // already configured on the target device
+	FlowsCreated    int      `json:"flows_created"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// DeviceConfigExportResponse wraps an exported bundle
+type DeviceConfigExportResponse struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message,omitempty"`
+	Bundle  *DeviceConfigBundle `json:"bundle,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ProviderCallStats summarizes one kind of provider call of a device over the health window
+type ProviderCallStats struct {
+	Count         int        `json:"count"`
+	Errors        int        `json:"errors"`
+	ErrorRate     float64    `json:"error_rate"` // errors / count, 0 without calls
+	AvgLatencyMs  int64      `json:"avg_latency_ms"`
+	P95LatencyMs  int64      `json:"p95_latency_ms"`
+	LastLatencyMs int64      `json:"last_latency_ms"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+// DeviceHealth is a device's provider connection and how its provider calls have fared lately
+type DeviceHealth struct {
+	DeviceID         string            `json:"device_id"`
+	Provider         string            `json:"provider"`
+	ConnectionStatus string            `json:"connection_status,omitempty"` // last health check: connected, disconnected
+	StatusCheckedAt  *time.Time        `json:"status_checked_at,omitempty"`
+	WindowMinutes    int               `json:"window_minutes"`
+	StatusChecks     ProviderCallStats `json:"status_checks"` // GetSessionStatus calls of the health monitor
+	Sends            ProviderCallStats `json:"sends"`         // messages this device's provider sent, including for a primary it backs up
+	BackupDeviceID   *string           `json:"backup_device_id,omitempty"`
+	FailingOver      bool              `json:"failing_over"` // sends currently go through the backup
+}
+
+// DeviceHealthResponse is the response of the device health endpoint
+type DeviceHealthResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message,omitempty"`
+	Health  *DeviceHealth `json:"health,omitempty"`
+}
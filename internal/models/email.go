@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Email providers
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderSendGrid = "sendgrid"
+)
+
+// EmailSettings is a user's outgoing mail account, used by send_email flow nodes
+type EmailSettings struct {
+	ID             string    `json:"id,omitempty"`
+	UserID         string    `json:"user_id"`
+	Provider       string    `json:"provider"` // EmailProvider*
+	FromAddress    string    `json:"from_address"`
+	FromName       *string   `json:"from_name,omitempty"`
+	SMTPHost       *string   `json:"smtp_host,omitempty"`
+	SMTPPort       *int      `json:"smtp_port,omitempty"` // default 587 (STARTTLS)
+	SMTPUsername   *string   `json:"smtp_username,omitempty"`
+	SMTPPassword   *string   `json:"smtp_password,omitempty"`
+	SendGridAPIKey *string   `json:"sendgrid_api_key,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateEmailSettingsRequest creates or replaces the user's email settings.
+// Omitted secrets keep their stored value.
+type UpdateEmailSettingsRequest struct {
+	Provider       string  `json:"provider" validate:"required"`
+	FromAddress    string  `json:"from_address" validate:"required"`
+	FromName       *string `json:"from_name,omitempty"`
+	SMTPHost       *string `json:"smtp_host,omitempty"`
+	SMTPPort       *int    `json:"smtp_port,omitempty"`
+	SMTPUsername   *string `json:"smtp_username,omitempty"`
+	SMTPPassword   *string `json:"smtp_password,omitempty"`
+	SendGridAPIKey *string `json:"sendgrid_api_key,omitempty"`
+}
+
+// EmailSettingsResponse returns the user's email settings with the secrets left out
+type EmailSettingsResponse struct {
+	Success  bool           `json:"success"`
+	Message  string         `json:"message,omitempty"`
+	Settings *EmailSettings `json:"settings,omitempty"`
+	// InboundDomain receives email replies: <device webhook_id>@domain for devices with email_replies on
+	InboundDomain string `json:"inbound_domain,omitempty"`
+}
+
+// InboundEmail is an email reply posted by the inbound mail provider (SendGrid Inbound Parse fields)
+type InboundEmail struct {
+	To      string `json:"to" form:"to"`
+	From    string `json:"from" form:"from"`
+	Subject string `json:"subject" form:"subject"`
+	Text    string `json:"text" form:"text"`
+}
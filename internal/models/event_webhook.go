@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+// Outbound events. Custom events emitted by emit_event flow nodes use their own names, such as
+// lead.qualified.
+const (
+	EventConversationCreated = "conversation.created" // a prospect's first message created a conversation
+	EventStageChanged        = "stage.changed"        // a flow, the AI or an agent changed the stage
+	EventFlowCompleted       = "flow.completed"       // a conversation reached the end of its flow
+	EventOrderPaid           = "order.paid"           // a Billplz payment for an order succeeded
+	EventWebhookTest         = "webhook.test"         // sent by POST /api/event-webhooks/:id/test
+)
+
+// BuiltinEvents are the events the app emits by itself
+var BuiltinEvents = []string{EventConversationCreated, EventStageChanged, EventFlowCompleted, EventOrderPaid}
+
+// Live events. These go only to the live event stream (GET /api/events/stream), which also carries
+// every event sent to webhooks.
+const (
+	EventMessageReceived = "message.received" // a prospect's message was stored
+	EventMessageSent     = "message.sent"     // a flow node, the AI or an agent message was stored
+)
+
+// LiveEvents are the built-in events of the live event stream
+var LiveEvents = []string{EventConversationCreated, EventMessageReceived, EventMessageSent, EventStageChanged, EventFlowCompleted, EventOrderPaid}
+
+// Live event stream limits
+const (
+	MaxLiveSubscriptionsPerUser = 10 // open streams per user; the oldest is closed beyond this
+	LiveEventBuffer             = 64 // events queued per stream; a stream that falls further behind is closed
+)
+
+// eventName is a lowercase dotted name such as stage.changed or lead.qualified
+var eventName = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_]+)*$`)
+
+// IsValidEventName reports whether name can name an event
+func IsValidEventName(name string) bool {
+	return len(name) <= 64 && eventName.MatchString(name)
+}
+
+// Event webhook limits and retry defaults
+const (
+	MaxEventWebhooks           = 20
+	DefaultWebhookMaxAttempts  = 5
+	MaxWebhookMaxAttempts      = 10
+	DefaultWebhookRetrySeconds = 60 // first retry; each later one waits twice as long
+	MinWebhookRetrySeconds     = 10
+	MaxWebhookRetrySeconds     = 3600
+)
+
+// Event webhook delivery statuses
+const (
+	DeliveryPending   = "pending"   // not delivered yet; retried at next_attempt_at
+	DeliveryDelivered = "delivered" // the endpoint answered 2xx
+	DeliveryFailed    = "failed"    // every attempt failed
+)
+
+// EventWebhook is a user's endpoint for outbound events. Each event is POSTed as JSON signed with
+// the secret: X-Webhook-Signature is sha256=<hex HMAC-SHA256 of the body>.
+type EventWebhook struct {
+	ID       string   `json:"id,omitempty"`
+	UserID   string   `json:"user_id,omitempty"`
+	IDDevice *string  `json:"id_device,omitempty"` // only events of this device (nil = every device of the user)
+	Name     *string  `json:"name,omitempty"`
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret,omitempty"` // only returned when the webhook is created
+	Events   []string `json:"events"`           // event names; empty or "*" = every event
+	// MaxAttempts and RetrySeconds are the retry policy: failed deliveries are retried after
+	// RetrySeconds, doubling each time, until MaxAttempts attempts were made
+	MaxAttempts  int        `json:"max_attempts"`
+	RetrySeconds int        `json:"retry_seconds"`
+	IsActive     *bool      `json:"is_active,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// Active reports whether the webhook receives events
+func (w *EventWebhook) Active() bool {
+	return w.IsActive == nil || *w.IsActive
+}
+
+// Subscribes reports whether the webhook receives the event
+func (w *EventWebhook) Subscribes(event string) bool {
+	if len(w.Events) == 0 || event == EventWebhookTest {
+		return true
+	}
+	for _, name := range w.Events {
+		if name == "*" || name == event {
+			return true
+		}
+	}
+	return false
+}
+
+// EventPayload is the JSON body POSTed for an event
+type EventPayload struct {
+	ID        string                 `json:"id"` // the same for every webhook receiving the event
+	Event     string                 `json:"event"`
+	IDDevice  string                 `json:"id_device,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// EventWebhookDelivery is the delivery log entry of one event to one webhook
+type EventWebhookDelivery struct {
+	ID             string          `json:"id,omitempty"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // Delivery*
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty"` // HTTP status of the last attempt
+	Error          *string         `json:"error,omitempty"`           // why the last attempt failed
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      *time.Time      `json:"created_at,omitempty"`
+}
+
+// CreateEventWebhookRequest is the request body for adding an event webhook
+type CreateEventWebhookRequest struct {
+	URL          string   `json:"url" validate:"required"`
+	Name         *string  `json:"name,omitempty"`
+	IDDevice     *string  `json:"id_device,omitempty"`
+	Secret       *string  `json:"secret,omitempty"` // generated when omitted
+	Events       []string `json:"events,omitempty"`
+	MaxAttempts  *int     `json:"max_attempts,omitempty"`  // default DefaultWebhookMaxAttempts
+	RetrySeconds *int     `json:"retry_seconds,omitempty"` // default DefaultWebhookRetrySeconds
+}
+
+// UpdateEventWebhookRequest is the request body for changing an event webhook
+type UpdateEventWebhookRequest struct {
+	URL          *string  `json:"url,omitempty"`
+	Name         *string  `json:"name,omitempty"`
+	IDDevice     *string  `json:"id_device,omitempty"` // empty string = every device
+	Secret       *string  `json:"secret,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	MaxAttempts  *int     `json:"max_attempts,omitempty"`
+	RetrySeconds *int     `json:"retry_seconds,omitempty"`
+	IsActive     *bool    `json:"is_active,omitempty"`
+}
+
+// EventWebhookResponse is the response for event webhook operations
+type EventWebhookResponse struct {
+	Success    bool                   `json:"success"`
+	Message    string                 `json:"message"`
+	Webhook    *EventWebhook          `json:"webhook,omitempty"`
+	Webhooks   []EventWebhook         `json:"webhooks,omitempty"`
+	Deliveries []EventWebhookDelivery `json:"deliveries,omitempty"`
+	Delivery   *EventWebhookDelivery  `json:"delivery,omitempty"`
+	// Events are the built-in events webhooks can subscribe to
+	Events []string `json:"events,omitempty"`
+}
@@ -2,44 +2,6 @@ package models
 
 import "time"
 
-// NodeType represents different types of flow nodes
-type NodeType string
-
-const (
-	NodeTypeStart     NodeType = "start"
-	NodeTypeMessage   NodeType = "message"
-	NodeTypeImage     NodeType = "image"
-	NodeTypeAudio     NodeType = "audio"
-	NodeTypeVideo     NodeType = "video"
-	NodeTypeDocument  NodeType = "document"
-	NodeTypeAI        NodeType = "ai"
-	NodeTypeCondition NodeType = "condition"
-	NodeTypeDelay     NodeType = "delay"
-	NodeTypeStage     NodeType = "stage"
-	NodeTypePrompt    NodeType = "prompt"
-	NodeTypeUserReply NodeType = "user_reply"
-	NodeTypeEnd       NodeType = "end"
-	NodeTypeAPI       NodeType = "api"
-)
-
-// FlowNode represents a node in the chatbot flow
-type FlowNode struct {
-	ID       string                 `json:"id"`
-	Type     NodeType               `json:"type"`
-	Data     map[string]interface{} `json:"data"`
-	Position map[string]float64     `json:"position,omitempty"`
-}
-
-// FlowEdge represents a connection between nodes
-type FlowEdge struct {
-	ID          string `json:"id"`
-	Source      string `json:"source"`
-	Target      string `json:"target"`
-	SourceHandle string `json:"sourceHandle,omitempty"`
-	TargetHandle string `json:"targetHandle,omitempty"`
-	Label       string `json:"label,omitempty"`
-}
-
 // ExecutionContext holds the state during flow execution
 type ExecutionContext struct {
 	ConversationID string                 `json:"conversation_id"`
@@ -107,6 +69,19 @@ type StartFlowRequest struct {
 	DeviceID    string `json:"device_id" validate:"required"`
 	ProspectNum string `json:"prospect_num" validate:"required"`
 	FlowID      string `json:"flow_id"`
+	// Variables seed the conversation's session_data so nodes can use them from the first step
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Stage pre-fills the conversation stage (default "started" for new conversations)
+	Stage *string `json:"stage,omitempty"`
+	// SeedMessage is appended to conv_last before the flow runs, e.g. context from a CRM or the tester
+	SeedMessage string `json:"seed_message,omitempty"`
+	SeedRole    string `json:"seed_role,omitempty"` // User (default) or Bot
+	// ExternalRef keys the conversation your way; existing conversations are re-keyed (default: DefaultExternalRef)
+	ExternalRef string `json:"external_ref,omitempty"`
+	// Test flags a new conversation as test traffic (always set on a sandbox device)
+	Test bool `json:"test,omitempty"`
+	// IgnoreCooldown restarts a prospect who entered this flow within its reentry_cooldown_hours
+	IgnoreCooldown bool `json:"ignore_cooldown,omitempty"`
 }
 
 // StartFlowResponse represents the response from starting a flow
@@ -114,6 +89,7 @@ type StartFlowResponse struct {
 	Success        bool   `json:"success"`
 	Message        string `json:"message"`
 	ConversationID string `json:"conversation_id,omitempty"`
+	ExternalRef    string `json:"external_ref,omitempty"`
 	Error          string `json:"error,omitempty"`
 }
 
@@ -129,9 +105,3 @@ type ResumeFlowResponse struct {
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
 }
-
-// NodeProcessor is an interface for processing different node types
-type NodeProcessor interface {
-	ProcessNode(ctx *ExecutionContext, node *FlowNode, edges []FlowEdge) (*ExecutionResult, error)
-	GetNodeType() NodeType
-}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Execution gauges, as named in alerts and thresholds
+const (
+	GaugeActiveRuns           = "active_runs"           // flow runs executing in this instance
+	GaugeWaitingConversations = "waiting_conversations" // conversations paused at a waiting_reply node
+	GaugeSchedulerBacklog     = "scheduler_backlog"     // delayed executions past their resume time
+	GaugeSendQueueDepth       = "send_queue_depth"      // outbound messages waiting on their provider
+)
+
+// Execution alert statuses
+const (
+	ExecutionAlertFiring   = "firing"
+	ExecutionAlertResolved = "resolved"
+)
+
+// ExecutionMetrics is one sample of the flow execution gauges. ActiveRuns and SendQueueDepth count
+// this instance; the other two are read from the database and cover every instance.
+type ExecutionMetrics struct {
+	ActiveRuns           int64          `json:"active_runs"`
+	WaitingConversations int            `json:"waiting_conversations"`
+	SchedulerBacklog     int            `json:"scheduler_backlog"`
+	SendQueueDepth       int64          `json:"send_queue_depth"`
+	Thresholds           map[string]int `json:"thresholds"` // alert threshold per gauge; missing = no alert
+	Alerting             []string       `json:"alerting"`   // gauges currently over their threshold
+	SampledAt            time.Time      `json:"sampled_at"`
+}
+
+// ExecutionAlert is the JSON body POSTed to the operator alert webhook when a gauge crosses its
+// threshold, and again when it recovers
+type ExecutionAlert struct {
+	Gauge     string            `json:"gauge"`
+	Status    string            `json:"status"` // firing, resolved
+	Value     int64             `json:"value"`
+	Threshold int               `json:"threshold"`
+	Metrics   *ExecutionMetrics `json:"metrics"`
+	At        time.Time         `json:"at"`
+}
+
+// ExecutionMetricsResponse is the response for the execution metrics endpoint
+type ExecutionMetricsResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Metrics *ExecutionMetrics `json:"metrics,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// externalRefNamespace is the UUIDv5 namespace for default conversation references
+var externalRefNamespace = uuid.MustParse("7f3c2a9e-5b1d-4c8e-9a60-2d4f8b1e6c35")
+
+// MaxExternalRefLength bounds caller-supplied external references
+const MaxExternalRefLength = 128
+
+// DefaultExternalRef returns the deterministic reference of a prospect's conversation on a device.
+// It is a UUIDv5 of "id_device:prospect_num", so integrators can compute it without storing our serial IDs.
+func DefaultExternalRef(idDevice, prospectNum string) string {
+	return uuid.NewSHA1(externalRefNamespace, []byte(idDevice+":"+prospectNum)).String()
+}
+
+// NormalizeExternalRef trims a caller-supplied reference and reports whether it is usable
+func NormalizeExternalRef(ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	return ref, ref != "" && len(ref) <= MaxExternalRefLength && !strings.ContainsAny(ref, ",()\"")
+}
@@ -4,36 +4,142 @@ import "time"
 
 // ChatbotFlow represents a chatbot conversation flow
 type ChatbotFlow struct {
-	ID        string                 `json:"id"`
-	IDDevice  string                 `json:"id_device"`
-	Name      string                 `json:"name"`
-	Niche     string                 `json:"niche"`
-	NodesData string                 `json:"nodes_data"` // JSON string containing complete flow structure
-	Nodes     map[string]interface{} `json:"nodes,omitempty"` // JSONB - React Flow nodes
-	Edges     map[string]interface{} `json:"edges,omitempty"` // JSONB - React Flow edges
-	CreatedAt time.Time              `json:"created_at"`
-	UpdatedAt time.Time              `json:"updated_at"`
+	ID               string                 `json:"id"`
+	IDDevice         string                 `json:"id_device"`
+	Name             string                 `json:"name"`
+	Niche            string                 `json:"niche"`
+	NodesData        string                 `json:"nodes_data"`                    // JSON string containing complete flow structure
+	Nodes            map[string]interface{} `json:"nodes,omitempty"`               // JSONB - React Flow nodes
+	Edges            map[string]interface{} `json:"edges,omitempty"`               // JSONB - React Flow edges
+	CompletionPolicy CompletionPolicy       `json:"completion_policy,omitempty"`   // What to do when a completed prospect messages again
+	AfterSalesFlowID *string                `json:"after_sales_flow_id,omitempty"` // Flow used by the after_sales policy
+	// CompletionWebhookURL receives CompletionWebhookTemplate rendered with the conversation's variables when the flow completes
+	CompletionWebhookURL      *string `json:"completion_webhook_url,omitempty"`
+	CompletionWebhookTemplate *string `json:"completion_webhook_template,omitempty"` // JSON with {{variable}} placeholders (empty = all variables)
+	ReentryCooldownHours      int     `json:"reentry_cooldown_hours,omitempty"`      // Hours before the restart policy may re-enter a prospect (0 = none)
+	NormalizeMalay            bool    `json:"normalize_malay,omitempty"`             // Conditions also match Malay chat spellings (tak = tidak, yaaa = ya)
+	// Triggers decide which inbound messages start this flow; a flow without triggers catches the rest
+	Triggers []FlowTrigger `json:"triggers,omitempty"`
+	// IsActive nil or true lets the flow take new prospects; conversations already in it continue either way
+	IsActive  *bool     `json:"is_active,omitempty"`
+	Priority  int       `json:"priority"` // Order in which inbound messages try the device's flows (lowest first)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Active reports whether the flow takes new prospects
+func (f *ChatbotFlow) Active() bool {
+	return f.IsActive == nil || *f.IsActive
+}
+
+// CompletionPolicy decides what happens when a prospect messages again after the flow completed
+type CompletionPolicy string
+
+const (
+	CompletionPolicyIgnore     CompletionPolicy = "ignore"      // Stay silent (default)
+	CompletionPolicyRestart    CompletionPolicy = "restart"     // Run the same flow again from the start
+	CompletionPolicyAfterSales CompletionPolicy = "after_sales" // Route to the flow in after_sales_flow_id
+	CompletionPolicyHandoff    CompletionPolicy = "handoff"     // Escalate to a human agent
+)
+
+// IsValid reports whether the policy is one of the known policies
+func (p CompletionPolicy) IsValid() bool {
+	switch p {
+	case CompletionPolicyIgnore, CompletionPolicyRestart, CompletionPolicyAfterSales, CompletionPolicyHandoff:
+		return true
+	}
+	return false
+}
+
+// EffectiveCompletionPolicy returns the configured policy, defaulting to ignore
+func (f *ChatbotFlow) EffectiveCompletionPolicy() CompletionPolicy {
+	if f.CompletionPolicy == "" {
+		return CompletionPolicyIgnore
+	}
+	return f.CompletionPolicy
+}
+
+// MaxReentryCooldownHours caps a flow's re-entry cooldown at a year
+const MaxReentryCooldownHours = 24 * 365
+
+// ReentryCooldownUntil returns when a prospect who last entered the flow at startedAt may be
+// restarted in it, or the zero time when the flow has no cooldown
+func (f *ChatbotFlow) ReentryCooldownUntil(startedAt *time.Time) time.Time {
+	if f.ReentryCooldownHours <= 0 || startedAt == nil {
+		return time.Time{}
+	}
+	return startedAt.Add(time.Duration(f.ReentryCooldownHours) * time.Hour)
 }
 
 // CreateFlowRequest is the request body for creating a flow
 type CreateFlowRequest struct {
-	IDDevice  string `json:"id_device" validate:"required"`
-	FlowName  string `json:"flow_name" validate:"required"`
-	Niche     string `json:"niche"`
-	NodesData string `json:"nodes_data"` // JSON string containing complete flow structure
+	IDDevice                  string           `json:"id_device" validate:"required"`
+	FlowName                  string           `json:"flow_name" validate:"required"`
+	Niche                     string           `json:"niche"`
+	NodesData                 string           `json:"nodes_data"` // JSON string containing complete flow structure
+	CompletionPolicy          CompletionPolicy `json:"completion_policy,omitempty"`
+	AfterSalesFlowID          *string          `json:"after_sales_flow_id,omitempty"`
+	CompletionWebhookURL      *string          `json:"completion_webhook_url,omitempty"`
+	CompletionWebhookTemplate *string          `json:"completion_webhook_template,omitempty"`
+	ReentryCooldownHours      int              `json:"reentry_cooldown_hours,omitempty"`
+	NormalizeMalay            bool             `json:"normalize_malay,omitempty"`
+	Triggers                  []FlowTrigger    `json:"triggers,omitempty"`
+	IsActive                  *bool            `json:"is_active,omitempty"` // Defaults to true
+	Priority                  int              `json:"priority,omitempty"`
 }
 
 // UpdateFlowRequest is the request body for updating a flow
 type UpdateFlowRequest struct {
-	FlowName  *string `json:"flow_name,omitempty"`
-	Niche     *string `json:"niche,omitempty"`
-	NodesData *string `json:"nodes_data,omitempty"`
+	FlowName                  *string           `json:"flow_name,omitempty"`
+	Niche                     *string           `json:"niche,omitempty"`
+	NodesData                 *string           `json:"nodes_data,omitempty"`
+	CompletionPolicy          *CompletionPolicy `json:"completion_policy,omitempty"`
+	AfterSalesFlowID          *string           `json:"after_sales_flow_id,omitempty"`
+	CompletionWebhookURL      *string           `json:"completion_webhook_url,omitempty"`      // Empty string removes the webhook
+	CompletionWebhookTemplate *string           `json:"completion_webhook_template,omitempty"` // Empty string sends all variables
+	ReentryCooldownHours      *int              `json:"reentry_cooldown_hours,omitempty"`      // 0 removes the cooldown
+	NormalizeMalay            *bool             `json:"normalize_malay,omitempty"`
+	Triggers                  *[]FlowTrigger    `json:"triggers,omitempty"` // Empty list removes the triggers
+}
+
+// FlowTrigger is a rule that starts a flow: a keyword the inbound message equals, contains or
+// matches as a regular expression, or the first message of a prospect new to the device
+type FlowTrigger struct {
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"` // keyword or pattern; unused by new_contact
+}
+
+// Flow trigger types
+const (
+	FlowTriggerEquals     = "equals"
+	FlowTriggerContains   = "contains"
+	FlowTriggerRegex      = "regex"
+	FlowTriggerNewContact = "new_contact"
+)
+
+// ReorderFlowsRequest is the request body for PUT /api/flows/device/:deviceId/order
+type ReorderFlowsRequest struct {
+	FlowIDs []string `json:"flow_ids" validate:"required"` // highest priority first; unlisted flows follow in their current order
+}
+
+// Auto-layout directions
+const (
+	LayoutTopToBottom = "TB"
+	LayoutLeftToRight = "LR"
+)
+
+// AutoLayoutRequest is the request body for laying out a flow's nodes
+type AutoLayoutRequest struct {
+	Direction    string  `json:"direction,omitempty"`     // TB (default) or LR
+	NodeSpacing  float64 `json:"node_spacing,omitempty"`  // Distance between nodes in the same layer
+	LayerSpacing float64 `json:"layer_spacing,omitempty"` // Distance between layers
 }
 
 // FlowResponse is the response for flow operations
 type FlowResponse struct {
-	Success bool            `json:"success"`
-	Message string          `json:"message"`
-	Flow    *ChatbotFlow    `json:"flow,omitempty"`
-	Flows   []ChatbotFlow   `json:"flows,omitempty"`
+	Success    bool                  `json:"success"`
+	Message    string                `json:"message"`
+	Flow       *ChatbotFlow          `json:"flow,omitempty"`
+	Flows      []ChatbotFlow         `json:"flows,omitempty"`
+	Validation []FlowValidationIssue `json:"validation,omitempty"` // nodes_data problems found on create/update
 }
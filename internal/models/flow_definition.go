@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlowDefinitionVersion is the flow definition format this server reads and writes
+const FlowDefinitionVersion = 1
+
+// FlowDefinition is a flow written as code, in YAML or JSON, for keeping flows in version control.
+// It compiles to nodes_data: nodes keep their type, label and config, next and branches become
+// connections, and a node's template fills its text from Templates.
+type FlowDefinition struct {
+	Version   int                     `json:"version" yaml:"version"`
+	Name      string                  `json:"name,omitempty" yaml:"name,omitempty"`
+	Niche     string                  `json:"niche,omitempty" yaml:"niche,omitempty"`
+	Settings  *FlowDefinitionSettings `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Triggers  []FlowTrigger           `json:"triggers,omitempty" yaml:"triggers,omitempty"`   // messages that start the flow
+	Templates map[string]string       `json:"templates,omitempty" yaml:"templates,omitempty"` // reusable message texts by name
+	Nodes     []FlowDefinitionNode    `json:"nodes" yaml:"nodes"`
+}
+
+// FlowDefinitionSettings are the flow settings a definition can carry
+type FlowDefinitionSettings struct {
+	CompletionPolicy          CompletionPolicy `json:"completion_policy,omitempty" yaml:"completion_policy,omitempty"`
+	AfterSalesFlowID          *string          `json:"after_sales_flow_id,omitempty" yaml:"after_sales_flow_id,omitempty"`
+	CompletionWebhookURL      *string          `json:"completion_webhook_url,omitempty" yaml:"completion_webhook_url,omitempty"`
+	CompletionWebhookTemplate *string          `json:"completion_webhook_template,omitempty" yaml:"completion_webhook_template,omitempty"`
+	ReentryCooldownHours      int              `json:"reentry_cooldown_hours,omitempty" yaml:"reentry_cooldown_hours,omitempty"`
+	NormalizeMalay            bool             `json:"normalize_malay,omitempty" yaml:"normalize_malay,omitempty"`
+}
+
+// FlowDefinitionNode is one node of a flow definition
+type FlowDefinitionNode struct {
+	ID       string                  `json:"id" yaml:"id"`
+	Type     string                  `json:"type" yaml:"type"`
+	Label    string                  `json:"label,omitempty" yaml:"label,omitempty"`
+	Template string                  `json:"template,omitempty" yaml:"template,omitempty"` // name in templates, used as the node's text
+	Config   map[string]interface{}  `json:"config,omitempty" yaml:"config,omitempty"`
+	Next     FlowDefinitionTargets   `json:"next,omitempty" yaml:"next,omitempty"`         // nodes after this one; a fork lists each branch
+	Branches []FlowDefinitionBranch  `json:"branches,omitempty" yaml:"branches,omitempty"` // conditional connections, as on conditions nodes
+	Position *FlowDefinitionPosition `json:"position,omitempty" yaml:"position,omitempty"`
+}
+
+// FlowDefinitionBranch is a conditional connection: When is the condition type (default for the
+// branch taken when nothing else matches) and Value what it matches
+type FlowDefinitionBranch struct {
+	When     string `json:"when" yaml:"when"`
+	Value    string `json:"value,omitempty" yaml:"value,omitempty"`
+	Variable string `json:"variable,omitempty" yaml:"variable,omitempty"` // conversation variable a text condition tests instead of the message
+	To       string `json:"to" yaml:"to"`
+}
+
+// FlowDefinitionPosition is where the builder draws a node
+type FlowDefinitionPosition struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+}
+
+// FlowDefinitionTargets lists the nodes a node connects to. It is written as a single node ID
+// when there is one, and as a list otherwise.
+type FlowDefinitionTargets []string
+
+// UnmarshalYAML accepts a node ID or a list of node IDs
+func (t *FlowDefinitionTargets) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var id string
+		if err := value.Decode(&id); err != nil {
+			return err
+		}
+		*t = FlowDefinitionTargets{id}
+		return nil
+	}
+
+	var ids []string
+	if err := value.Decode(&ids); err != nil {
+		return fmt.Errorf("next must be a node ID or a list of node IDs")
+	}
+	*t = ids
+	return nil
+}
+
+// MarshalYAML writes a single target as a node ID
+func (t FlowDefinitionTargets) MarshalYAML() (interface{}, error) {
+	if len(t) == 1 {
+		return t[0], nil
+	}
+	return []string(t), nil
+}
+
+// UnmarshalJSON accepts a node ID or a list of node IDs
+func (t *FlowDefinitionTargets) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*t = FlowDefinitionTargets{id}
+		return nil
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return fmt.Errorf("next must be a node ID or a list of node IDs")
+	}
+	*t = ids
+	return nil
+}
+
+// MarshalJSON writes a single target as a node ID
+func (t FlowDefinitionTargets) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// CompileFlowDefinitionRequest is the request body for POST /api/flows/compile
+type CompileFlowDefinitionRequest struct {
+	Definition string `json:"definition" validate:"required"` // YAML or JSON flow definition
+}
+
+// CompileFlowDefinitionResponse is the response from POST /api/flows/compile. NodesData, Name,
+// Niche, Settings and Triggers are ready for POST /api/flows or PUT /api/flows/:id; NodesData is empty
+// when the definition has errors.
+type CompileFlowDefinitionResponse struct {
+	Success   bool                    `json:"success"`
+	Message   string                  `json:"message"`
+	Valid     bool                    `json:"valid"`
+	Errors    int                     `json:"errors"`
+	Warnings  int                     `json:"warnings"`
+	Issues    []FlowValidationIssue   `json:"issues"`
+	NodesData string                  `json:"nodes_data,omitempty"`
+	Name      string                  `json:"name,omitempty"`
+	Niche     string                  `json:"niche,omitempty"`
+	Settings  *FlowDefinitionSettings `json:"settings,omitempty"`
+	Triggers  []FlowTrigger           `json:"triggers,omitempty"`
+}
+
+// FlowDefinitionResponse is the response from GET /api/flows/:id/definition
+type FlowDefinitionResponse struct {
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Definition *FlowDefinition `json:"definition,omitempty"`
+	YAML       string          `json:"yaml,omitempty"` // the definition as YAML text
+}
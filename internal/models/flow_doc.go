@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// FlowDoc is a human-readable description of a flow: how conversations enter it, every step
+// and branch, the messages it sends verbatim, the fields it captures and the AI prompts it uses.
+// Generated from the saved nodes_data for compliance reviews and onboarding.
+type FlowDoc struct {
+	FlowID         string           `json:"flow_id"`
+	FlowName       string           `json:"flow_name"`
+	Niche          string           `json:"niche,omitempty"`
+	IDDevice       string           `json:"id_device"`
+	Engine         string           `json:"engine"` // Chatbot AI, Whatsapp Bot
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Entry          []string         `json:"entry"`
+	Steps          []FlowDocStep    `json:"steps"`
+	Messages       []FlowDocMessage `json:"messages"`
+	FieldsCaptured []FlowDocField   `json:"fields_captured"`
+	AIPrompts      []FlowDocPrompt  `json:"ai_prompts"`
+	Unreachable    []FlowDocStep    `json:"unreachable,omitempty"` // nodes no path from the entry node reaches
+}
+
+// FlowDocStep describes one node and where the flow goes after it
+type FlowDocStep struct {
+	NodeID      string          `json:"node_id"`
+	Type        string          `json:"type"`
+	Label       string          `json:"label,omitempty"`
+	Description string          `json:"description"`
+	Branches    []FlowDocBranch `json:"branches,omitempty"`
+}
+
+// FlowDocBranch is an outgoing connection; Condition is empty when it is always followed
+type FlowDocBranch struct {
+	Condition string `json:"condition,omitempty"`
+	To        string `json:"to"`
+}
+
+// FlowDocMessage is content the flow sends to prospects, verbatim
+type FlowDocMessage struct {
+	NodeID  string `json:"node_id"`
+	Kind    string `json:"kind"` // text, image, audio, video, csat_question, consent_question, ...
+	Content string `json:"content"`
+}
+
+// FlowDocField is a conversation field written by a node
+type FlowDocField struct {
+	NodeID string `json:"node_id"`
+	Field  string `json:"field"`
+	Source string `json:"source"`
+}
+
+// FlowDocPrompt is an ai_prompt node's system prompt and settings
+type FlowDocPrompt struct {
+	NodeID  string   `json:"node_id"`
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	Options []string `json:"options,omitempty"`
+}
+
+// FlowDocResponse is the response for flow documentation
+type FlowDocResponse struct {
+	Success  bool     `json:"success"`
+	Message  string   `json:"message,omitempty"`
+	Doc      *FlowDoc `json:"doc,omitempty"`
+	Markdown string   `json:"markdown,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Flow execution log events
+const (
+	FlowExecutionEventNode     = "node_executed"    // a node ran; Outcome says what the run did next
+	FlowExecutionEventResumed  = "resumed"          // a paused node took the prospect's reply
+	FlowExecutionEventMaxSteps = "failed_max_steps" // the run hit the step or node visit limit
+)
+
+// Flow execution log outcomes of a node
+const (
+	FlowOutcomeContinued = "continued" // the run moved on to NextNodeID
+	FlowOutcomePaused    = "paused"    // the node waits for a reply or a timer
+	FlowOutcomeCompleted = "completed" // no node follows, so the flow ended
+	FlowOutcomeFailed    = "failed"    // the node returned Error
+)
+
+// FlowExecutionLog records one node a flow run executed, or a notable event of the run such as the
+// loop guard stopping it
+type FlowExecutionLog struct {
+	ID             string     `json:"id,omitempty"`
+	Source         string     `json:"source"` // ai_whatsapp, wasapbot
+	ConversationID string     `json:"conversation_id"`
+	IDDevice       string     `json:"id_device"`
+	FlowID         string     `json:"flow_id"`
+	NodeID         string     `json:"node_id,omitempty"`
+	NodeType       string     `json:"node_type,omitempty"`
+	Event          string     `json:"event"`
+	InputMessage   string     `json:"input_message,omitempty"` // the prospect's message the run was handling
+	Outcome        string     `json:"outcome,omitempty"`
+	NextNodeID     string     `json:"next_node_id,omitempty"`
+	DurationMs     int64      `json:"duration_ms"`
+	Error          string     `json:"error,omitempty"`
+	Steps          int        `json:"steps"`       // nodes executed in the run
+	NodeVisits     int        `json:"node_visits"` // times the run entered NodeID
+	Message        string     `json:"message,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+}
+
+// ExecutionLogResponse is the response for a conversation's execution log, oldest entry first
+type ExecutionLogResponse struct {
+	Success        bool               `json:"success"`
+	Message        string             `json:"message,omitempty"`
+	ConversationID string             `json:"conversation_id,omitempty"`
+	Entries        []FlowExecutionLog `json:"entries"`
+}
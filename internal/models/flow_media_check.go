@@ -0,0 +1,34 @@
+package models
+
+// Media check statuses
+const (
+	MediaCheckOK      = "ok"      // reachable, expected MIME type, within the size limit
+	MediaCheckWarning = "warning" // reachable, but the size or type could not be confirmed
+	MediaCheckFailed  = "failed"  // unreachable, wrong type or too large: the send would fail
+)
+
+// FlowMediaCheck is the pre-flight result for one media URL referenced by a flow node
+type FlowMediaCheck struct {
+	NodeID      string   `json:"node_id"`
+	NodeType    string   `json:"node_type"`
+	Variant     string   `json:"variant,omitempty"` // media_switch variant value, or "default" for default_url
+	URL         string   `json:"url"`
+	MediaType   string   `json:"media_type"` // image, audio or video, as the node sends it
+	Status      string   `json:"status"`
+	HTTPStatus  int      `json:"http_status,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	SizeBytes   *int64   `json:"size_bytes,omitempty"`
+	LimitBytes  int64    `json:"limit_bytes"`
+	Issues      []string `json:"issues,omitempty"`
+}
+
+// FlowMediaCheckResponse is the response from POST /api/flows/:id/check-media
+type FlowMediaCheckResponse struct {
+	Success  bool             `json:"success"`
+	Message  string           `json:"message"`
+	Ready    bool             `json:"ready"` // no media failed the check
+	Checked  int              `json:"checked"`
+	Failed   int              `json:"failed"`
+	Warnings int              `json:"warnings"`
+	Media    []FlowMediaCheck `json:"media"`
+}
@@ -0,0 +1,86 @@
+package models
+
+// MaxSimulationMessages caps the prospect messages one simulation replays
+const MaxSimulationMessages = 20
+
+// Flow trace event kinds
+const (
+	FlowTraceNode          = "node"     // the engine entered a node
+	FlowTraceMessage       = "message"  // a message the flow would have sent
+	FlowTraceVariable      = "variable" // a conversation column changed (stage, captured fields, state)
+	FlowTraceDelay         = "delay"    // a delay or waiting_times node that would have paused the flow
+	FlowTraceWebhook       = "webhook"  // the completion webhook that would have been posted
+	FlowTraceEmail         = "email"    // an email a send_email node would have sent
+	FlowTraceOutboundEvent = "event"    // an event that would have been sent to the owner's event webhooks
+	FlowTraceAPICall       = "api_call" // the request of a legacy api node, which dry runs do not send
+)
+
+// SimulateFlowRequest replays prospect messages through a flow without sending or saving anything
+type SimulateFlowRequest struct {
+	// Messages are the prospect's messages in order; the first one triggers the flow,
+	// later ones answer waiting_reply, csat and consent nodes
+	Messages    []string `json:"messages" validate:"required"`
+	ProspectNum string   `json:"prospect_num,omitempty"` // default SimulationProspectNum
+	// Variables pre-fill the mock conversation's columns, e.g. prospect_name, stage or language
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// SimulationProspectNum is the prospect number used when a simulation does not give one
+const SimulationProspectNum = "60000000000"
+
+// FlowTraceEvent is one step of a simulated flow run
+type FlowTraceEvent struct {
+	Step     int    `json:"step"` // 1-based across the whole trace
+	Turn     int    `json:"turn"` // index of the prospect message being handled
+	Kind     string `json:"kind"` // FlowTrace*
+	NodeID   string `json:"node_id,omitempty"`
+	NodeType string `json:"node_type,omitempty"`
+	Label    string `json:"label,omitempty"`
+
+	// message and email
+	To        string `json:"to,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	Text      string `json:"text,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	MediaURL  string `json:"media_url,omitempty"`
+
+	// variable
+	Variable string      `json:"variable,omitempty"`
+	From     interface{} `json:"from,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+
+	// delay
+	DelaySeconds float64 `json:"delay_seconds,omitempty"`
+
+	// webhook, event and api_call
+	URL     string `json:"url,omitempty"`
+	Event   string `json:"event,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// FlowSimulationTurn is the handling of one prospect message
+type FlowSimulationTurn struct {
+	Turn        int    `json:"turn"`
+	UserMessage string `json:"user_message"`
+	Action      string `json:"action"`         // start, resume or skipped
+	Note        string `json:"note,omitempty"` // why a message was skipped
+	StateAfter  string `json:"state_after"`
+	Error       string `json:"error,omitempty"`
+}
+
+// FlowSimulationTrace is the full record of a simulated run, in execution order
+type FlowSimulationTrace struct {
+	FlowID         string                 `json:"flow_id"`
+	Engine         string                 `json:"engine"` // Whatsapp Bot or Chatbot AI
+	Turns          []FlowSimulationTurn   `json:"turns"`
+	Events         []FlowTraceEvent       `json:"events"`
+	FinalState     string                 `json:"final_state"`
+	FinalVariables map[string]interface{} `json:"final_variables"`
+}
+
+// SimulateFlowResponse is the response from POST /api/flows/:id/simulate
+type SimulateFlowResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message"`
+	Trace   *FlowSimulationTrace `json:"trace,omitempty"`
+}
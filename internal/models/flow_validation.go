@@ -0,0 +1,55 @@
+package models
+
+// Flow validation severities
+const (
+	FlowIssueError   = "error"   // the flow cannot run as built; saving is refused
+	FlowIssueWarning = "warning" // the flow runs, but probably not as intended
+)
+
+// Flow validation issue codes
+const (
+	FlowIssueInvalidJSON       = "invalid_json"
+	FlowIssueNoStartNode       = "no_start_node"
+	FlowIssueDuplicateNode     = "duplicate_node"
+	FlowIssueDanglingEdge      = "dangling_edge"
+	FlowIssueEmptyMessage      = "empty_message"
+	FlowIssueOrphanNode        = "orphan_node"
+	FlowIssueUnreachableNode   = "unreachable_node"
+	FlowIssueNoDefaultEdge     = "no_default_edge"
+	FlowIssueIncompleteBranch  = "incomplete_condition"
+	FlowIssueCycle             = "cycle"
+	FlowIssueCycleWithoutPause = "cycle_without_pause"
+	FlowIssueForkBranch        = "fork_branch"
+	FlowIssueInvalidDefinition = "invalid_definition" // a flow definition that cannot be compiled
+	FlowIssueUnknownTemplate   = "unknown_template"
+	FlowIssueABSplit           = "ab_split"
+	FlowIssueInvalidVariable   = "invalid_variable"
+	FlowIssueInvalidEvent      = "invalid_event"
+	FlowIssueInvalidOptions    = "invalid_options"
+)
+
+// FlowValidationIssue is one structural problem found in a flow's nodes_data
+type FlowValidationIssue struct {
+	Severity string   `json:"severity"`
+	Code     string   `json:"code"`
+	NodeID   string   `json:"node_id,omitempty"`
+	NodeIDs  []string `json:"node_ids,omitempty"`  // every node involved, e.g. the nodes of a cycle
+	EdgeFrom string   `json:"edge_from,omitempty"` // the connection the issue is about
+	EdgeTo   string   `json:"edge_to,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// ValidateFlowRequest is the request body for POST /api/flows/validate
+type ValidateFlowRequest struct {
+	NodesData string `json:"nodes_data" validate:"required"` // JSON string containing complete flow structure
+}
+
+// FlowValidationResponse is the response from POST /api/flows/validate
+type FlowValidationResponse struct {
+	Success  bool                  `json:"success"`
+	Message  string                `json:"message"`
+	Valid    bool                  `json:"valid"` // no errors; warnings do not block saving
+	Errors   int                   `json:"errors"`
+	Warnings int                   `json:"warnings"`
+	Issues   []FlowValidationIssue `json:"issues"`
+}
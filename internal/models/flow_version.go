@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Flow version reasons: why the flow was about to change when the version was stored
+const (
+	FlowVersionReasonUpdate         = "update"          // saved from the flow editor or the API
+	FlowVersionReasonRestore        = "restore"         // replaced by a restored version
+	FlowVersionReasonModelMigration = "model_migration" // deprecated AI models replaced by an admin
+)
+
+// FlowVersion is a snapshot of a flow taken before it was changed. Versions are numbered per flow
+// from 1; Snapshot is omitted from version lists.
+type FlowVersion struct {
+	ID        string       `json:"id,omitempty"`
+	FlowID    string       `json:"flow_id"`
+	Version   int          `json:"version"`
+	Name      string       `json:"name"`
+	NodeCount int          `json:"node_count"`
+	Reason    string       `json:"reason"`
+	CreatedBy *string      `json:"created_by,omitempty"` // user who made the change; empty for admin jobs
+	Snapshot  *ChatbotFlow `json:"snapshot,omitempty"`
+	CreatedAt *time.Time   `json:"created_at,omitempty"`
+}
+
+// FlowVersionsResponse lists a flow's versions, newest first
+type FlowVersionsResponse struct {
+	Success  bool          `json:"success"`
+	Message  string        `json:"message"`
+	Versions []FlowVersion `json:"versions"`
+}
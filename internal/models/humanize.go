@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Humanized sending: consecutive messages to the same prospect are spaced by a random delay
+// between send_delay_min_ms and send_delay_max_ms, bot messages wait as long as they take to type
+// at typing_chars_per_second, and bot messages sent during a device's quiet hours are queued
+// until the quiet hours end
+const (
+	DefaultSendDelayMinMs = 1000
+	DefaultSendDelayMaxMs = 3000
+	MaxSendDelayMs        = 60000
+)
+
+// EffectiveSendDelay returns the bounds of the random delay between consecutive messages to the
+// same prospect
+func (d *DeviceSetting) EffectiveSendDelay() (time.Duration, time.Duration) {
+	minMs, maxMs := DefaultSendDelayMinMs, DefaultSendDelayMaxMs
+	if d.SendDelayMinMs != nil && *d.SendDelayMinMs > 0 {
+		minMs = *d.SendDelayMinMs
+	}
+	if d.SendDelayMaxMs != nil && *d.SendDelayMaxMs > 0 {
+		maxMs = *d.SendDelayMaxMs
+	}
+	if maxMs < minMs {
+		maxMs = minMs
+	}
+	return time.Duration(minMs) * time.Millisecond, time.Duration(maxMs) * time.Millisecond
+}
+
+// PacesTyping reports whether bot messages wait for their typing time before going out
+func (d *DeviceSetting) PacesTyping() bool {
+	return d.TypingIndicator || d.TypingCharsPerSecond != nil
+}
+
+// QuietUntil returns when the device's quiet hours that now falls in end, and false when now is
+// outside them or the device has none. Quiet hours are HH:MM in the device's timezone and may
+// run past midnight (22:00-07:00).
+func (d *DeviceSetting) QuietUntil(now time.Time) (time.Time, bool) {
+	if d.QuietHoursStart == nil || d.QuietHoursEnd == nil {
+		return time.Time{}, false
+	}
+	start, ok1 := ParseClockMinutes(*d.QuietHoursStart)
+	end, ok2 := ParseClockMinutes(*d.QuietHoursEnd)
+	if !ok1 || !ok2 || start == end {
+		return time.Time{}, false
+	}
+
+	local := now.In(d.Location())
+	minute := local.Hour()*60 + local.Minute()
+	inside := minute >= start && minute < end
+	if start > end {
+		inside = minute >= start || minute < end
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	release := midnight.Add(time.Duration(end) * time.Minute)
+	if !release.After(local) {
+		release = midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+	}
+	return release, true
+}
+
+// ParseClockMinutes converts "HH:MM" to minutes after midnight
+func ParseClockMinutes(value string) (int, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// Queued send statuses
+const (
+	QueuedSendPending = "pending" // waiting for send_at
+	QueuedSendRunning = "running" // claimed by a dispatcher
+	QueuedSendSent    = "sent"
+	QueuedSendFailed  = "failed"
+)
+
+// QueuedSend is a bot message held back by a device's quiet hours, sent by the queued send
+// dispatcher at SendAt
+type QueuedSend struct {
+	ID          string              `json:"id,omitempty"`
+	DeviceID    string              `json:"device_id"` // the deviceID the send was made with
+	IDDevice    string              `json:"id_device"`
+	ProspectNum string              `json:"prospect_num"`
+	Message     *SendMessageRequest `json:"message"`
+	FlowID      *string             `json:"flow_id,omitempty"` // flow node the message came from, if any
+	NodeID      *string             `json:"node_id,omitempty"`
+	SendAt      time.Time           `json:"send_at"`
+	Status      string              `json:"status"`
+	LastError   *string             `json:"last_error,omitempty"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
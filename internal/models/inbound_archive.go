@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Inbound archive retention in days, used when a device has archive_inbound on
+const (
+	DefaultArchiveRetentionDays = 365
+	MaxArchiveRetentionDays     = 3650
+)
+
+// Inbound archive listing page sizes
+const (
+	DefaultInboundArchiveLimit = 50
+	MaxInboundArchiveLimit     = 500
+)
+
+// InboundArchive indexes one raw inbound webhook payload, stored gzipped in the inbound-archive
+// bucket under <id_device>/<YYYY-MM-DD>/ exactly as the provider sent it
+type InboundArchive struct {
+	ID          string    `json:"id,omitempty"`
+	UserID      string    `json:"user_id"`
+	IDDevice    string    `json:"id_device"`
+	Provider    string    `json:"provider,omitempty"`
+	ObjectPath  string    `json:"object_path"`
+	ContentType string    `json:"content_type,omitempty"` // Content-Type the webhook was received with
+	SizeBytes   int       `json:"size_bytes"`             // payload size before compression
+	StoredBytes int       `json:"stored_bytes"`           // gzipped object size
+	ReceivedAt  time.Time `json:"received_at"`
+	ExpiresAt   time.Time `json:"expires_at"` // the retention purge deletes the payload after this
+}
+
+// InboundArchiveResponse is the response for listing and retrieving archived inbound payloads
+type InboundArchiveResponse struct {
+	Success  bool             `json:"success"`
+	Message  string           `json:"message,omitempty"`
+	Archives []InboundArchive `json:"archives,omitempty"`
+	Archive  *InboundArchive  `json:"archive,omitempty"`
+	// Payload is the decompressed webhook body, byte for byte as received
+	Payload string `json:"payload,omitempty"`
+}
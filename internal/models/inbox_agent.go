@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// Conversation tables an assignment can point at
+const (
+	AssignmentSourceAI       = "ai_whatsapp"
+	AssignmentSourceWasapbot = "wasapbot"
+)
+
+// MaxAssignmentHistory caps how many of a prospect's assignments are returned
+const MaxAssignmentHistory = 50
+
+// InboxAgent is a member of the user's team who takes over conversations in handoff
+type InboxAgent struct {
+	ID        string     `json:"id,omitempty"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Phone     *string    `json:"phone,omitempty"` // WhatsApp number told about conversations routed to the agent
+	Available bool       `json:"available"`       // unavailable agents are not routed returning prospects
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// AgentAssignment records an agent taking over a conversation, one row per handoff. The history
+// is kept per device and prospect number, so it outlives the conversation row: a prospect who
+// comes back is routed to the agent of their latest assignment.
+type AgentAssignment struct {
+	ID             string     `json:"id,omitempty"`
+	UserID         string     `json:"user_id"`
+	AgentID        string     `json:"agent_id"`
+	AgentName      string     `json:"agent_name"` // kept when the agent is deleted
+	Source         string     `json:"source"`     // ai_whatsapp, wasapbot
+	ConversationID string     `json:"conversation_id"`
+	IDDevice       string     `json:"id_device"`
+	ProspectNum    string     `json:"prospect_num"`
+	Notes          *string    `json:"notes,omitempty"`
+	Sticky         bool       `json:"sticky"` // routed automatically because the agent handled the prospect before
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"` // the agent handed the conversation back
+}
+
+// CreateInboxAgentRequest is the request body for adding an agent
+type CreateInboxAgentRequest struct {
+	Name      string  `json:"name" validate:"required"`
+	Phone     *string `json:"phone,omitempty"`
+	Available *bool   `json:"available,omitempty"` // default true
+}
+
+// UpdateInboxAgentRequest is the request body for changing an agent
+type UpdateInboxAgentRequest struct {
+	Name      *string `json:"name,omitempty"`
+	Phone     *string `json:"phone,omitempty"`
+	Available *bool   `json:"available,omitempty"`
+}
+
+// AssignAgentRequest is the request body for handing a conversation to an agent
+type AssignAgentRequest struct {
+	Source         string  `json:"source,omitempty"` // ai_whatsapp (default) or wasapbot
+	ConversationID string  `json:"conversation_id" validate:"required"`
+	AgentID        string  `json:"agent_id" validate:"required"`
+	Notes          *string `json:"notes,omitempty"`
+}
+
+// UpdateAssignmentRequest is the request body for an agent's notes on an assignment, or for
+// handing the conversation back
+type UpdateAssignmentRequest struct {
+	Notes   *string `json:"notes,omitempty"`
+	Release bool    `json:"release,omitempty"` // close the assignment; the conversation stays in its current state
+}
+
+// InboxAgentResponse is the response for agent operations
+type InboxAgentResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Agent   *InboxAgent  `json:"agent,omitempty"`
+	Agents  []InboxAgent `json:"agents,omitempty"`
+}
+
+// AgentAssignmentResponse is the response for assignment operations. Assignments lists the
+// prospect's history, latest first, so an agent sees the notes of earlier handoffs.
+type AgentAssignmentResponse struct {
+	Success     bool              `json:"success"`
+	Message     string            `json:"message"`
+	Assignment  *AgentAssignment  `json:"assignment,omitempty"`
+	Assignments []AgentAssignment `json:"assignments,omitempty"`
+}
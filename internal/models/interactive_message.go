@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interactive message types of SendMessageRequest
+const (
+	MessageTypeButtons = "buttons" // quick-reply buttons
+	MessageTypeList    = "list"    // a list menu opened by a button
+)
+
+// WhatsApp limits of interactive messages
+const (
+	MaxMessageButtons     = 3
+	MaxButtonTitleLength  = 20
+	MaxListRows           = 10
+	MaxListRowTitleLength = 24
+	MaxListButtonLength   = 20
+)
+
+// MessageButton is a quick-reply button. ID is what a reply maps to; providers that only pass the
+// tapped title back are matched on Title.
+type MessageButton struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// MessageListRow is one choice of a list menu
+type MessageListRow struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// MessageListSection groups rows of a list menu under a title
+type MessageListSection struct {
+	Title string           `json:"title,omitempty"`
+	Rows  []MessageListRow `json:"rows"`
+}
+
+// MessageList is a list menu: ButtonText opens it and the prospect picks one row
+type MessageList struct {
+	ButtonText string               `json:"button_text"`
+	Sections   []MessageListSection `json:"sections"`
+}
+
+// Rows returns every row of the list, section by section
+func (l *MessageList) Rows() []MessageListRow {
+	var rows []MessageListRow
+	for _, section := range l.Sections {
+		rows = append(rows, section.Rows...)
+	}
+	return rows
+}
+
+// IsInteractive reports whether the request is a buttons or list message
+func (r *SendMessageRequest) IsInteractive() bool {
+	return (r.Type == MessageTypeButtons && len(r.Buttons) > 0) || (r.Type == MessageTypeList && r.List != nil)
+}
+
+// InteractiveText is a buttons or list message as plain text with numbered choices, for channels
+// without interactive messages; the prospect answers with the number or the title
+func (r *SendMessageRequest) InteractiveText() string {
+	var choices []string
+	switch {
+	case r.Type == MessageTypeButtons:
+		for i, button := range r.Buttons {
+			choices = append(choices, fmt.Sprintf("%d. %s", i+1, button.Title))
+		}
+	case r.Type == MessageTypeList && r.List != nil:
+		for i, row := range r.List.Rows() {
+			choice := fmt.Sprintf("%d. %s", i+1, row.Title)
+			if row.Description != "" {
+				choice += " - " + row.Description
+			}
+			choices = append(choices, choice)
+		}
+	}
+
+	parts := []string{r.Body}
+	if len(choices) > 0 {
+		parts = append(parts, strings.Join(choices, "\n"))
+	}
+	if r.Footer != "" {
+		parts = append(parts, r.Footer)
+	}
+	return strings.Join(parts, "\n\n")
+}
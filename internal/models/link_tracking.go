@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TrackedLink is one URL sent by a flow node to one prospect, wrapped in a short link.
+// Each send gets its own code so a click can be attributed to the node and the prospect.
+type TrackedLink struct {
+	ID             string     `json:"id,omitempty"`
+	Code           string     `json:"code"`
+	IDDevice       string     `json:"id_device"`
+	FlowID         string     `json:"flow_id"`
+	NodeID         string     `json:"node_id"`
+	ConversationID string     `json:"conversation_id,omitempty"`
+	ProspectNum    string     `json:"prospect_num"`
+	TargetURL      string     `json:"target_url"`
+	ClickCount     int        `json:"click_count"`
+	FirstClickedAt *time.Time `json:"first_clicked_at,omitempty"`
+	LastClickedAt  *time.Time `json:"last_clicked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at,omitempty"`
+}
+
+// LinkClick records one open of a tracked link
+type LinkClick struct {
+	ID        string    `json:"id,omitempty"`
+	LinkID    string    `json:"link_id"`
+	IDDevice  string    `json:"id_device"`
+	FlowID    string    `json:"flow_id"`
+	NodeID    string    `json:"node_id"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+}
+
+// LinkNodeStats represents click-through for the links sent by one message node
+type LinkNodeStats struct {
+	FlowID           string     `json:"flow_id"`
+	NodeID           string     `json:"node_id"`
+	Sent             int        `json:"sent"`               // tracked links sent
+	Clicked          int        `json:"clicked"`            // links opened at least once
+	Clicks           int        `json:"clicks"`             // total opens, including repeats
+	ClickThroughRate float64    `json:"click_through_rate"` // percentage of sent links opened
+	LastClickedAt    *time.Time `json:"last_clicked_at,omitempty"`
+}
+
+// LinkMetrics represents outbound link click-through analytics
+type LinkMetrics struct {
+	Sent             int             `json:"sent"`
+	Clicked          int             `json:"clicked"`
+	Clicks           int             `json:"clicks"`
+	ClickThroughRate float64         `json:"click_through_rate"`
+	ByNode           []LinkNodeStats `json:"by_node"`
+}
+
+// LinkAnalyticsResponse represents the link click-through analytics response
+type LinkAnalyticsResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Data    *LinkMetrics `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "regexp"
+
+// MaxListColumns caps how many captured fields a conversation list can add as columns
+const MaxListColumns = 10
+
+// ConversationListBaseFields are returned on every list row alongside the requested columns
+const ConversationListBaseFields = "id_prospect,id_device,prospect_num,prospect_name,stage,execution_status,pinned,priority,updated_at"
+
+var listColumnName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// IsValidListColumnName reports whether name can be used as a list column, sort or filter key
+// (a lowercase column or variable name such as pakej, alamat or tarikh_gaji)
+func IsValidListColumnName(name string) bool {
+	return listColumnName.MatchString(name)
+}
+
+// ConversationListQuery selects captured fields as columns of a conversation list,
+// with server-side sorting and filtering on them
+type ConversationListQuery struct {
+	Columns []string          // extra columns per row; empty uses the device's list_columns
+	Sort    string            // column to sort by (empty = inbox order)
+	Desc    bool              // sort descending
+	Filters map[string]string // column -> exact value, or "~text" for a case-insensitive contains match
+	Export  bool              // the list is downloaded as CSV, so rows are returned even without columns
+	// CustomFields are the owner's custom field names, read from custom_fields on both tables
+	CustomFields map[string]bool
+}
+
+// IsEmpty reports whether the query asks for nothing beyond the plain list
+func (q *ConversationListQuery) IsEmpty() bool {
+	return q == nil || (len(q.Columns) == 0 && q.Sort == "" && len(q.Filters) == 0 && !q.Export)
+}
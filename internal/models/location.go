@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Location and contact card message types of SendMessageRequest
+const (
+	MessageTypeLocation = "location" // a map pin
+	MessageTypeContact  = "contact"  // a vCard contact card
+)
+
+// Location is a map pin, sent by a send_location node or shared by a prospect
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`    // the place's label, such as a shop name
+	Address   string  `json:"address,omitempty"` // street address shown under the label
+}
+
+// Location variables an inbound location sets in the conversation's session_data
+const (
+	LocationVarLatitude  = "location_latitude"
+	LocationVarLongitude = "location_longitude"
+	LocationVarName      = "location_name"
+	LocationVarAddress   = "location_address"
+	LocationVarURL       = "location_url"
+	LocationVarSharedAt  = "location_shared_at"
+)
+
+// Valid reports whether the coordinates are on the globe
+func (l *Location) Valid() bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180 &&
+		(l.Latitude != 0 || l.Longitude != 0)
+}
+
+// MapsURL is a Google Maps link to the pin
+func (l *Location) MapsURL() string {
+	return fmt.Sprintf("https://maps.google.com/?q=%s,%s",
+		strconv.FormatFloat(l.Latitude, 'f', -1, 64), strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+}
+
+// Label is the pin's name, else its address, else its coordinates
+func (l *Location) Label() string {
+	switch {
+	case l.Name != "":
+		return l.Name
+	case l.Address != "":
+		return l.Address
+	}
+	return fmt.Sprintf("%s,%s", strconv.FormatFloat(l.Latitude, 'f', -1, 64), strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+}
+
+// Placeholder is the text an inbound location without a caption is handled as
+func (l *Location) Placeholder() string {
+	return fmt.Sprintf("[location: %s]", l.Label())
+}
+
+// Text is the pin as a message with a maps link, for channels without location messages
+func (l *Location) Text() string {
+	var lines []string
+	if l.Name != "" {
+		lines = append(lines, l.Name)
+	}
+	if l.Address != "" {
+		lines = append(lines, l.Address)
+	}
+	return strings.Join(append(lines, l.MapsURL()), "\n")
+}
+
+// Variables are the session_data variables of a location the prospect shared at sharedAt
+func (l *Location) Variables(sharedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		LocationVarLatitude:  l.Latitude,
+		LocationVarLongitude: l.Longitude,
+		LocationVarName:      l.Name,
+		LocationVarAddress:   l.Address,
+		LocationVarURL:       l.MapsURL(),
+		LocationVarSharedAt:  sharedAt.Format(time.RFC3339),
+	}
+}
+
+// ContactCard is a contact sent as a vCard by a send_contact node
+type ContactCard struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"` // international format without +, like prospect numbers
+	Organization string `json:"organization,omitempty"`
+	Email        string `json:"email,omitempty"`
+}
+
+// VCard renders the card as a vCard 3.0; the waid parameter lets WhatsApp open a chat with it
+func (c *ContactCard) VCard() string {
+	escape := strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`)
+	phone := strings.TrimPrefix(c.Phone, "+")
+
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:" + escape.Replace(c.Name),
+		"N:" + escape.Replace(c.Name) + ";;;;",
+	}
+	if c.Organization != "" {
+		lines = append(lines, "ORG:"+escape.Replace(c.Organization))
+	}
+	lines = append(lines, fmt.Sprintf("TEL;type=CELL;type=VOICE;waid=%s:+%s", phone, phone))
+	if c.Email != "" {
+		lines = append(lines, "EMAIL:"+escape.Replace(c.Email))
+	}
+	lines = append(lines, "END:VCARD")
+	return strings.Join(lines, "\n")
+}
+
+// Text is the card as a message, for channels without contact cards
+func (c *ContactCard) Text() string {
+	lines := []string{c.Name}
+	if c.Organization != "" {
+		lines = append(lines, c.Organization)
+	}
+	lines = append(lines, "+"+strings.TrimPrefix(c.Phone, "+"))
+	if c.Email != "" {
+		lines = append(lines, c.Email)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// IsLocation reports whether the request is a location message
+func (r *SendMessageRequest) IsLocation() bool {
+	return r.Type == MessageTypeLocation && r.Location != nil
+}
+
+// IsContact reports whether the request is a contact card message
+func (r *SendMessageRequest) IsContact() bool {
+	return r.Type == MessageTypeContact && r.Contact != nil
+}
+
+// IsStructured reports whether the request is a buttons, list, location or contact message rather
+// than text or media
+func (r *SendMessageRequest) IsStructured() bool {
+	return r.IsInteractive() || r.IsLocation() || r.IsContact()
+}
+
+// PlainText is the request as a text message, for channels without its message type
+func (r *SendMessageRequest) PlainText() string {
+	switch {
+	case r.IsInteractive():
+		return r.InteractiveText()
+	case r.IsLocation():
+		return r.Location.Text()
+	case r.IsContact():
+		return r.Contact.Text()
+	}
+	return r.Body
+}
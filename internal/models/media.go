@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Inbound media types
+const (
+	MediaImage    = "image"
+	MediaAudio    = "audio"
+	MediaVoice    = "voice" // push-to-talk voice note
+	MediaVideo    = "video"
+	MediaDocument = "document"
+	MediaSticker  = "sticker"
+)
+
+// InboundMedia is an attachment on a message from a prospect
+type InboundMedia struct {
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"` // provider download URL; may be temporary
+	MimeType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+}
+
+// InboundMediaType maps a provider message type, falling back to the MIME type, to one of the
+// media types; "" when the message is not media
+func InboundMediaType(providerType, mimeType string) string {
+	switch strings.ToLower(providerType) {
+	case "image":
+		return MediaImage
+	case "ptt", "voice":
+		return MediaVoice
+	case "audio":
+		return MediaAudio
+	case "video", "gif":
+		return MediaVideo
+	case "document", "file":
+		return MediaDocument
+	case "sticker":
+		return MediaSticker
+	}
+
+	mimeType = strings.ToLower(mimeType)
+	switch {
+	case mimeType == "":
+		return ""
+	case mimeType == "image/webp":
+		return MediaSticker
+	case strings.HasPrefix(mimeType, "image/"):
+		return MediaImage
+	case strings.HasPrefix(mimeType, "audio/ogg"):
+		return MediaVoice // WhatsApp records voice notes as Opus in Ogg
+	case strings.HasPrefix(mimeType, "audio/"):
+		return MediaAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return MediaVideo
+	}
+	return MediaDocument
+}
+
+// Placeholder is the text recorded in conv_last for media sent without a caption, e.g. [voice note]
+func (m *InboundMedia) Placeholder() string {
+	switch m.Type {
+	case MediaVoice:
+		return "[voice note]"
+	case MediaDocument:
+		if m.Filename != "" {
+			return fmt.Sprintf("[document: %s]", m.Filename)
+		}
+	}
+	return fmt.Sprintf("[%s]", m.Type)
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// MaxMessageTemplates caps how many message templates a user can define
+const MaxMessageTemplates = 100
+
+// IsValidMessageTemplateName reports whether name can name a message template (lowercase letters,
+// digits, dashes and underscores, such as detail_cod)
+func IsValidMessageTemplateName(name string) bool {
+	return promptTemplateName.MatchString(name)
+}
+
+// MessageTemplate is a named message send_message nodes can send instead of their own text.
+// Content is rendered with {{variable}} placeholders such as {{prospect_name}}, {{alamat}} and
+// {{pakej}}, any custom field, or a session variable.
+type MessageTemplate struct {
+	ID          string     `json:"id,omitempty"`
+	UserID      string     `json:"user_id,omitempty"`
+	Name        string     `json:"name"` // referenced by send_message nodes (message_template)
+	Description *string    `json:"description,omitempty"`
+	Content     string     `json:"content"`
+	Builtin     bool       `json:"builtin,omitempty"` // shipped with the app; a user template of the same name replaces it
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// MessageTemplateVariable is one placeholder message templates can use
+type MessageTemplateVariable struct {
+	Name        string `json:"name"` // used as {{name}}
+	Description string `json:"description"`
+	Source      string `json:"source"` // column, custom_field or session
+}
+
+// CreateMessageTemplateRequest is the request body for defining a message template
+type CreateMessageTemplateRequest struct {
+	Name        string  `json:"name" validate:"required"`
+	Description *string `json:"description,omitempty"`
+	Content     string  `json:"content" validate:"required"`
+}
+
+// UpdateMessageTemplateRequest is the request body for changing a message template.
+// The name is fixed because nodes reference it.
+type UpdateMessageTemplateRequest struct {
+	Description *string `json:"description,omitempty"`
+	Content     *string `json:"content,omitempty"`
+}
+
+// MessageTemplateResponse is the response for message template operations
+type MessageTemplateResponse struct {
+	Success   bool              `json:"success"`
+	Message   string            `json:"message"`
+	Template  *MessageTemplate  `json:"template,omitempty"`
+	Templates []MessageTemplate `json:"templates,omitempty"`
+	// Builtins are the templates every user has, such as detail_cod, unless replaced by their own
+	Builtins []MessageTemplate `json:"builtins,omitempty"`
+	// Variables is the catalog of placeholders templates can use
+	Variables []MessageTemplateVariable `json:"variables,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "strings"
+
+// Prefixes of prospect_num values that belong to Meta inbox conversations: the sender's page-scoped
+// (Messenger) or Instagram-scoped ID follows them
+const (
+	MessengerProspectPrefix = "fb:"
+	InstagramProspectPrefix = "ig:"
+)
+
+// MessengerProspect returns the prospect_num of a Messenger or Instagram sender
+func MessengerProspect(channel, senderID string) string {
+	if channel == ChannelInstagram {
+		return InstagramProspectPrefix + senderID
+	}
+	return MessengerProspectPrefix + senderID
+}
+
+// IsMessengerProspect reports whether a prospect_num belongs to a Messenger or Instagram conversation
+func IsMessengerProspect(prospectNum string) bool {
+	return strings.HasPrefix(prospectNum, MessengerProspectPrefix) || strings.HasPrefix(prospectNum, InstagramProspectPrefix)
+}
+
+// MessengerRecipientID returns the Meta sender ID of a prospect_num, which may omit its prefix
+func MessengerRecipientID(prospectNum string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(prospectNum, MessengerProspectPrefix), InstagramProspectPrefix)
+}
+
+// MessengerConfig connects a Facebook page, and the Instagram account linked to it, to a device
+type MessengerConfig struct {
+	PageAccessToken string `json:"page_access_token"`
+	VerifyToken     string `json:"verify_token"`         // answered in the webhook verification handshake
+	AppSecret       string `json:"app_secret,omitempty"` // checks X-Hub-Signature-256 of deliveries when set
+}
+
+// IsEmpty reports whether the config connects nothing, which removes it on update
+func (c *MessengerConfig) IsEmpty() bool {
+	return c == nil || (c.PageAccessToken == "" && c.VerifyToken == "" && c.AppSecret == "")
+}
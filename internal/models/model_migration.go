@@ -0,0 +1,49 @@
+package models
+
+// ModelMigrationRequest scans ai_prompt nodes and devices for deprecated AI models
+type ModelMigrationRequest struct {
+	// Mappings maps each deprecated model to its replacement; an empty replacement only reports usage
+	Mappings map[string]string `json:"mappings" validate:"required"`
+	// DryRun previews the rewrite without saving anything
+	DryRun bool `json:"dry_run"`
+}
+
+// ModelMigrationNode is one ai_prompt node with its own model override
+type ModelMigrationNode struct {
+	NodeID string `json:"node_id"`
+	Label  string `json:"label,omitempty"`
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+}
+
+// ModelMigrationFlow is a flow with ai_prompt nodes that use a deprecated model
+type ModelMigrationFlow struct {
+	FlowID   string `json:"flow_id"`
+	FlowName string `json:"flow_name"`
+	IDDevice string `json:"id_device"`
+	// Nodes override the model in their config
+	Nodes []ModelMigrationNode `json:"nodes,omitempty"`
+	// InheritedNodes use the device's deprecated default model
+	InheritedNodes int `json:"inherited_nodes,omitempty"`
+}
+
+// ModelMigrationDevice is a device whose default model (api_key_option) is deprecated
+type ModelMigrationDevice struct {
+	DeviceID string `json:"device_id"`
+	IDDevice string `json:"id_device"`
+	UserID   string `json:"user_id,omitempty"`
+	From     string `json:"from"`
+	To       string `json:"to,omitempty"`
+}
+
+// ModelMigrationResponse reports (and with dry_run=false applies) a model migration
+type ModelMigrationResponse struct {
+	Success         bool                   `json:"success"`
+	Message         string                 `json:"message"`
+	DryRun          bool                   `json:"dry_run"`
+	AffectedFlows   []ModelMigrationFlow   `json:"affected_flows"`
+	AffectedDevices []ModelMigrationDevice `json:"affected_devices"`
+	FlowsUpdated    int                    `json:"flows_updated"`
+	DevicesUpdated  int                    `json:"devices_updated"`
+	Errors          []string               `json:"errors,omitempty"`
+}
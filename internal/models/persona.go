@@ -0,0 +1,22 @@
+package models
+
+// Persona list limits keep the injected prompt section short
+const (
+	MaxPersonaListItems = 20
+	MaxPersonaItemChars = 300
+)
+
+// AIPersona is a device's AI persona, injected into every ai_prompt system prompt
+// so flows no longer repeat persona text in each AI node
+type AIPersona struct {
+	Name          string   `json:"name"`
+	Tone          string   `json:"tone,omitempty"`           // e.g. "friendly, casual Malay, uses 'kak'"
+	Do            []string `json:"do,omitempty"`             // Rules the AI must always follow
+	Dont          []string `json:"dont,omitempty"`           // Guard rules the AI must never break
+	SamplePhrases []string `json:"sample_phrases,omitempty"` // Example phrasings in the persona's voice
+}
+
+// IsEmpty reports whether the persona has no content (an empty persona clears it)
+func (p *AIPersona) IsEmpty() bool {
+	return p == nil || (p.Name == "" && p.Tone == "" && len(p.Do) == 0 && len(p.Dont) == 0 && len(p.SamplePhrases) == 0)
+}
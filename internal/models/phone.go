@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultPhoneCountry is the country numbers in national format are read in when a device has no
+// phone_country
+const DefaultPhoneCountry = "MY"
+
+// Overall E.164 length bounds, in digits including the calling code
+const (
+	MinPhoneDigits = 8
+	MaxPhoneDigits = 15
+)
+
+// PhoneCountry holds the dialing rules of a country: its calling code, the trunk prefix local
+// numbers are written with (0 in 012-345 6789), and the length of its national significant numbers
+type PhoneCountry struct {
+	Code        string `json:"code"` // ISO 3166-1 alpha-2
+	CallingCode string `json:"calling_code"`
+	TrunkPrefix string `json:"trunk_prefix,omitempty"`
+	MinLength   int    `json:"min_length"`
+	MaxLength   int    `json:"max_length"`
+}
+
+// PhoneCountries are the countries devices can read national numbers in. Numbers of other
+// countries are accepted in international format with the overall E.164 bounds.
+var PhoneCountries = map[string]PhoneCountry{
+	"MY": {Code: "MY", CallingCode: "60", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"SG": {Code: "SG", CallingCode: "65", MinLength: 8, MaxLength: 8},
+	"BN": {Code: "BN", CallingCode: "673", MinLength: 7, MaxLength: 7},
+	"ID": {Code: "ID", CallingCode: "62", TrunkPrefix: "0", MinLength: 7, MaxLength: 12},
+	"TH": {Code: "TH", CallingCode: "66", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"PH": {Code: "PH", CallingCode: "63", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"VN": {Code: "VN", CallingCode: "84", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"HK": {Code: "HK", CallingCode: "852", MinLength: 8, MaxLength: 8},
+	"CN": {Code: "CN", CallingCode: "86", TrunkPrefix: "0", MinLength: 10, MaxLength: 11},
+	"IN": {Code: "IN", CallingCode: "91", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+	"PK": {Code: "PK", CallingCode: "92", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	"BD": {Code: "BD", CallingCode: "880", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	"AE": {Code: "AE", CallingCode: "971", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"SA": {Code: "SA", CallingCode: "966", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"AU": {Code: "AU", CallingCode: "61", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"GB": {Code: "GB", CallingCode: "44", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	"US": {Code: "US", CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+}
+
+// phoneCallingCodes are the calling codes of PhoneCountries, longest first for prefix matching
+var phoneCallingCodes = func() []PhoneCountry {
+	countries := make([]PhoneCountry, 0, len(PhoneCountries))
+	for _, country := range PhoneCountries {
+		countries = append(countries, country)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		if len(countries[i].CallingCode) != len(countries[j].CallingCode) {
+			return len(countries[i].CallingCode) > len(countries[j].CallingCode)
+		}
+		return countries[i].Code < countries[j].Code
+	})
+	return countries
+}()
+
+// IsValidPhoneCountry reports whether code names one of PhoneCountries
+func IsValidPhoneCountry(code string) bool {
+	_, ok := PhoneCountries[strings.ToUpper(code)]
+	return ok
+}
+
+// LookupPhoneCountry returns the dialing rules of a country code, or of DefaultPhoneCountry
+func LookupPhoneCountry(code string) PhoneCountry {
+	if country, ok := PhoneCountries[strings.ToUpper(strings.TrimSpace(code))]; ok {
+		return country
+	}
+	return PhoneCountries[DefaultPhoneCountry]
+}
+
+// NormalizePhone parses a phone number into its E.164 digits without the +, reading numbers in
+// national format by the rules of country. +65 9123 4567, 0065 9123 4567 and 6591234567 are
+// international; with country MY, 012-345 6789 loses its trunk prefix and becomes 60123456789, and
+// so does 123456789 when it is no valid number of a known calling code. A chat suffix such as
+// @c.us is dropped. Non-phone prospects (tg:, fb:, web: ...) and numbers of the wrong length are errors.
+func NormalizePhone(raw, country string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if at := strings.Index(raw, "@"); at >= 0 {
+		raw = raw[:at]
+	}
+	if strings.Contains(raw, ":") {
+		return "", fmt.Errorf("%q is not a phone number", raw)
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if digits == "" {
+		return "", fmt.Errorf("%q has no digits", raw)
+	}
+
+	rules := LookupPhoneCountry(country)
+	switch {
+	case strings.HasPrefix(raw, "+"):
+		return validInternationalPhone(digits)
+	case strings.HasPrefix(digits, "00"):
+		return validInternationalPhone(digits[2:])
+	case rules.TrunkPrefix != "" && rules.TrunkPrefix != rules.CallingCode && strings.HasPrefix(digits, rules.TrunkPrefix):
+		return validNationalPhone(rules, digits[len(rules.TrunkPrefix):])
+	}
+
+	// Providers send international numbers without the +; a number that isn't one of a known
+	// calling code may still be national without its trunk prefix
+	phone, err := validInternationalPhone(digits)
+	if err == nil && hasKnownCallingCode(digits) {
+		return phone, nil
+	}
+	if national, nationalErr := validNationalPhone(rules, digits); nationalErr == nil {
+		return national, nil
+	}
+	return phone, err
+}
+
+// hasKnownCallingCode reports whether digits start with the calling code of one of PhoneCountries
+func hasKnownCallingCode(digits string) bool {
+	for _, country := range phoneCallingCodes {
+		if strings.HasPrefix(digits, country.CallingCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// validNationalPhone prefixes a national significant number with the country's calling code
+func validNationalPhone(country PhoneCountry, national string) (string, error) {
+	if len(national) < country.MinLength || len(national) > country.MaxLength {
+		return "", fmt.Errorf("%s numbers have %s digits after the country code, %s has %d", country.Code, phoneLengthRange(country), national, len(national))
+	}
+	return country.CallingCode + national, nil
+}
+
+// validInternationalPhone checks the length of an international number, by its country's rules
+// when the calling code is one of PhoneCountries
+func validInternationalPhone(digits string) (string, error) {
+	if len(digits) < MinPhoneDigits || len(digits) > MaxPhoneDigits {
+		return "", fmt.Errorf("phone numbers have %d to %d digits with the country code, %s has %d", MinPhoneDigits, MaxPhoneDigits, digits, len(digits))
+	}
+	for _, country := range phoneCallingCodes {
+		if !strings.HasPrefix(digits, country.CallingCode) {
+			continue
+		}
+		national := digits[len(country.CallingCode):]
+		if len(national) < country.MinLength || len(national) > country.MaxLength {
+			return "", fmt.Errorf("%s numbers have %s digits after +%s, %s has %d", country.Code, phoneLengthRange(country), country.CallingCode, digits, len(national))
+		}
+		return digits, nil
+	}
+	return digits, nil
+}
+
+// phoneLengthRange describes a country's national number length, e.g. "8 to 10" or "8"
+func phoneLengthRange(country PhoneCountry) string {
+	if country.MinLength == country.MaxLength {
+		return fmt.Sprintf("%d", country.MinLength)
+	}
+	return fmt.Sprintf("%d to %d", country.MinLength, country.MaxLength)
+}
+
+// EffectivePhoneCountry returns the country the device reads national numbers in
+func (d *DeviceSetting) EffectivePhoneCountry() string {
+	if d != nil && d.PhoneCountry != nil && IsValidPhoneCountry(*d.PhoneCountry) {
+		return strings.ToUpper(*d.PhoneCountry)
+	}
+	return DefaultPhoneCountry
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// MaxPromptTemplates caps how many prompt templates a user can define
+const MaxPromptTemplates = 50
+
+// BuiltinPromptTemplate is the name of the instruction block used when neither the ai_prompt node
+// nor its device names a template. It cannot be used as the name of a user template.
+const BuiltinPromptTemplate = "default"
+
+var promptTemplateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// IsValidPromptTemplateName reports whether name can name a prompt template (lowercase letters,
+// digits, dashes and underscores, such as sales-closing)
+func IsValidPromptTemplateName(name string) bool {
+	return promptTemplateName.MatchString(name)
+}
+
+// PromptTemplate is a named system prompt for ai_prompt nodes. Content is rendered with
+// {{variable}} placeholders: {{prompt}} is the node's prompt, {{persona}} the device persona
+// section, and any conversation field, custom field or session variable such as {{stage}}.
+type PromptTemplate struct {
+	ID          string     `json:"id,omitempty"`
+	UserID      string     `json:"user_id"`
+	Name        string     `json:"name"` // referenced by ai_prompt nodes (prompt_template) and devices (default_prompt_template)
+	Description *string    `json:"description,omitempty"`
+	Content     string     `json:"content"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// CreatePromptTemplateRequest is the request body for defining a prompt template
+type CreatePromptTemplateRequest struct {
+	Name        string  `json:"name" validate:"required"`
+	Description *string `json:"description,omitempty"`
+	Content     string  `json:"content" validate:"required"`
+}
+
+// UpdatePromptTemplateRequest is the request body for changing a prompt template.
+// The name is fixed because nodes and devices reference it.
+type UpdatePromptTemplateRequest struct {
+	Description *string `json:"description,omitempty"`
+	Content     *string `json:"content,omitempty"`
+}
+
+// PromptTemplateResponse is the response for prompt template operations
+type PromptTemplateResponse struct {
+	Success   bool             `json:"success"`
+	Message   string           `json:"message"`
+	Template  *PromptTemplate  `json:"template,omitempty"`
+	Templates []PromptTemplate `json:"templates,omitempty"`
+	// Builtin is the content of the built-in default template, a starting point for custom ones
+	Builtin string `json:"builtin,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Prospects. A prospect is everyone a user talks to at one phone number, whichever of the user's
+// devices (and ai_whatsapp or wasapbot) the conversations are on. Conversations carry their
+// number normalized as prospect_phone, set by a database trigger that mirrors NormalizeProspectPhone
+// with the device's phone_country.
+
+// MaxMergeConversations caps how many conversations one merge folds into the kept one
+const MaxMergeConversations = 50
+
+// NormalizeProspectPhone returns the E.164 digits of a prospect_num (see NormalizePhone) read by
+// the rules of the device's country, or "" for prospects without a valid phone number: Telegram,
+// Messenger, Instagram and web chat prospects (tg:, fb:, ig:, web: ...) have none.
+func NormalizeProspectPhone(prospectNum, country string) string {
+	phone, err := NormalizePhone(prospectNum, country)
+	if err != nil {
+		return ""
+	}
+	return phone
+}
+
+// ProspectConversation is one conversation of a prospect
+type ProspectConversation struct {
+	Source          string                 `json:"source"` // ai_whatsapp, wasapbot
+	IDProspect      int                    `json:"id_prospect"`
+	IDDevice        string                 `json:"id_device"`
+	ProspectNum     string                 `json:"prospect_num"`
+	ProspectPhone   string                 `json:"prospect_phone"`
+	ProspectName    *string                `json:"prospect_name,omitempty"`
+	Niche           *string                `json:"niche,omitempty"`
+	Stage           *string                `json:"stage,omitempty"`
+	ExecutionStatus *string                `json:"execution_status,omitempty"`
+	CustomFields    map[string]interface{} `json:"custom_fields,omitempty"`
+	CreatedAt       *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time             `json:"updated_at,omitempty"`
+}
+
+// Prospect is one phone number's conversations across the user's devices, latest first
+type Prospect struct {
+	Phone         string                 `json:"phone"`
+	Name          *string                `json:"name,omitempty"`  // of the latest conversation that has one
+	Stage         *string                `json:"stage,omitempty"` // of the latest conversation that has one
+	Devices       []string               `json:"devices"`
+	Conversations []ProspectConversation `json:"conversations"`
+}
+
+// MergeProspectRequest folds duplicate conversations of a prospect into one. The merged
+// conversations must be of the same source and phone as the kept one; they are deleted after their
+// messages move to it, and the kept conversation takes their name, stage and custom fields where
+// it has none.
+type MergeProspectRequest struct {
+	Source   string   `json:"source" validate:"required"` // ai_whatsapp, wasapbot
+	KeepID   string   `json:"keep_id" validate:"required"`
+	MergeIDs []string `json:"merge_ids" validate:"required"`
+}
+
+// ProspectResponse is the response for prospect operations
+type ProspectResponse struct {
+	Success   bool       `json:"success"`
+	Message   string     `json:"message"`
+	Prospect  *Prospect  `json:"prospect,omitempty"`
+	Prospects []Prospect `json:"prospects,omitempty"`
+	Merged    int        `json:"merged,omitempty"` // conversations folded into the kept one
+}
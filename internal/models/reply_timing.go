@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// FollowUpTimingStatic on a delay or waiting_times node's "timing" config keeps its fixed offset
+// even when the device times follow-ups adaptively
+const FollowUpTimingStatic = "static"
+
+// ReplyHourStats is how prospects answered outreach a device sent in one hour of the day
+type ReplyHourStats struct {
+	Hour    int     `json:"hour"`    // 0-23 in the device's timezone
+	Sent    int     `json:"sent"`    // outreach messages: bot messages that were not an immediate answer
+	Replied int     `json:"replied"` // of those, the ones the prospect replied to within the reply window
+	Rate    float64 `json:"rate"`    // Replied / Sent
+}
+
+// ReplyProfile is a device's learned reply rate by hour of day, maintained by the reply timing
+// learner from the conversation history. Follow-up pauses of an hour or more resume in BestHours.
+type ReplyProfile struct {
+	Hours      []ReplyHourStats `json:"hours"`
+	BestHours  []int            `json:"best_hours"` // empty until there is enough history
+	Samples    int              `json:"samples"`
+	Timezone   string           `json:"timezone"`
+	ComputedAt time.Time        `json:"computed_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SandboxDeliveredStatus marks a send that a sandboxed device intercepted instead of delivering
+const SandboxDeliveredStatus = "sandbox-delivered"
+
+// SandboxMessage is the trace of one intercepted send from a device in sandbox mode
+type SandboxMessage struct {
+	ID          string          `json:"id,omitempty"`
+	IDDevice    string          `json:"id_device"`
+	Provider    string          `json:"provider"` // provider that would have sent it (waha, wablas, whacenter)
+	MessageID   string          `json:"message_id"`
+	Recipient   string          `json:"recipient"`
+	MessageType string          `json:"message_type"` // text, image, audio, video, document
+	Body        string          `json:"body,omitempty"`
+	MediaURL    string          `json:"media_url,omitempty"`
+	Payload     json.RawMessage `json:"payload"` // full SendMessageRequest as the provider would have received it
+	Status      string          `json:"status"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+}
+
+// SandboxMessagesResponse is the response for listing a device's intercepted sends
+type SandboxMessagesResponse struct {
+	Success  bool             `json:"success"`
+	Message  string           `json:"message,omitempty"`
+	Messages []SandboxMessage `json:"messages,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Outbound pacing: each device sends at most its send_rate_per_minute, and consecutive messages to
+// the same prospect are spaced by a random delay, so flows that blast many messages look human
+// and do not get the number banned
+const (
+	DefaultSendRatePerMinute = 30
+	MaxSendRatePerMinute     = 120
+)
+
+// SendQueueMetrics describes a device's outbound queue in this instance
+type SendQueueMetrics struct {
+	DeviceID      string `json:"device_id"`       // device_setting.id of the sending device
+	RatePerMinute int    `json:"rate_per_minute"` // effective send rate
+	Depth         int    `json:"depth"`           // messages waiting for their send slot
+	// EstimatedWaitMs is how long a message queued now would wait for its slot
+	EstimatedWaitMs int64      `json:"estimated_wait_ms"`
+	Sent            int64      `json:"sent"`      // messages released to the provider since the instance started
+	Abandoned       int64      `json:"abandoned"` // messages whose caller gave up while waiting
+	LastSentAt      *time.Time `json:"last_sent_at,omitempty"`
+	SampledAt       time.Time  `json:"sampled_at"`
+}
+
+// SendQueueResponse is the response for a device's send queue metrics
+type SendQueueResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Queue   *SendQueueMetrics `json:"queue,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SentMessage records one provider send with the message ID the provider returned, so a
+// prospect's quoted reply can be traced back to the flow node that sent the quoted message
+type SentMessage struct {
+	ID                string     `json:"id,omitempty"`
+	IDDevice          string     `json:"id_device"`
+	ProspectNum       string     `json:"prospect_num"`
+	ProviderMessageID string     `json:"provider_message_id"` // normalized, see NormalizeProviderMessageID
+	FlowID            *string    `json:"flow_id,omitempty"`
+	NodeID            *string    `json:"node_id,omitempty"` // nil for sends outside a flow (debounce AI replies, SLA nudges)
+	MessageType       string     `json:"message_type"`
+	Body              string     `json:"body,omitempty"`
+	CreatedAt         *time.Time `json:"created_at,omitempty"`
+}
+
+// NormalizeProviderMessageID reduces a provider message ID to the part quoted replies refer to.
+// WAHA returns serialized IDs such as "true_60123456789@c.us_3EB0C767D26A", while a quoted reply
+// carries only the stanza ID "3EB0C767D26A"; other providers' IDs are kept as they are.
+func NormalizeProviderMessageID(id string) string {
+	id = strings.TrimSpace(id)
+	if strings.Contains(id, "@") {
+		if i := strings.LastIndex(id, "_"); i >= 0 {
+			return id[i+1:]
+		}
+	}
+	return id
+}
@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+// Sheet export limits and sync defaults
+const (
+	MaxSheetExports                = 20
+	MaxSheetColumns                = 26 // A to Z
+	DefaultSheetName               = "Sheet1"
+	DefaultSheetSyncName           = "Sync"
+	DefaultSheetSyncIntervalMinute = 60
+	MinSheetSyncIntervalMinute     = 15
+	MaxSheetSyncIntervalMinute     = 1440
+)
+
+// SheetColumn is one column of a sheet export. Value is rendered from the conversation's variables
+// ({{prospect_name}}, {{alamat}}, custom fields...) plus {{exported_at}}, the time in the device's
+// timezone.
+type SheetColumn struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+}
+
+// DefaultSheetColumns are the columns of an export created without any: the order details the
+// stage nodes of a Whatsapp Bot flow capture
+var DefaultSheetColumns = []SheetColumn{
+	{Header: "Date", Value: "{{exported_at}}"},
+	{Header: "Name", Value: "{{prospect_name}}"},
+	{Header: "Phone", Value: "{{prospect_num}}"},
+	{Header: "Alamat", Value: "{{alamat}}"},
+	{Header: "Pakej", Value: "{{pakej}}"},
+	{Header: "Payment Method", Value: "{{cara_bayaran}}"},
+	{Header: "Stage", Value: "{{stage}}"},
+}
+
+// SheetExport sends conversations to a Google Sheet the user shared with the app's service account.
+// Stage nodes append a row to SheetName when they set one of Stages; when SyncTable is set, the
+// conversations of that table are also written to SyncSheetName every SyncIntervalMinutes, replacing
+// what the tab held.
+type SheetExport struct {
+	ID            string        `json:"id,omitempty"`
+	UserID        string        `json:"user_id,omitempty"`
+	IDDevice      *string       `json:"id_device,omitempty"` // only conversations of this device (nil = every device of the user)
+	Name          *string       `json:"name,omitempty"`
+	SpreadsheetID string        `json:"spreadsheet_id"`
+	SheetName     string        `json:"sheet_name"`
+	Stages        []string      `json:"stages"` // stages that append a row; empty = every stage
+	Columns       []SheetColumn `json:"columns"`
+	// SyncTable is ai_whatsapp or wasapbot; nil = no scheduled sync
+	SyncTable           *string    `json:"sync_table,omitempty"`
+	SyncSheetName       string     `json:"sync_sheet_name"`
+	SyncIntervalMinutes int        `json:"sync_interval_minutes"`
+	LastSyncedAt        *time.Time `json:"last_synced_at,omitempty"`
+	LastError           *string    `json:"last_error,omitempty"` // why the last append or sync failed
+	IsActive            *bool      `json:"is_active,omitempty"`
+	CreatedAt           *time.Time `json:"created_at,omitempty"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+}
+
+// Active reports whether the export receives rows
+func (e *SheetExport) Active() bool {
+	return e.IsActive == nil || *e.IsActive
+}
+
+// ExportsStage reports whether a stage node setting stage appends a row
+func (e *SheetExport) ExportsStage(stage string) bool {
+	if len(e.Stages) == 0 {
+		return true
+	}
+	for _, name := range e.Stages {
+		if name == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncDue reports whether the scheduled sync of the export should run at now
+func (e *SheetExport) SyncDue(now time.Time) bool {
+	if e.SyncTable == nil || !e.Active() {
+		return false
+	}
+	return e.LastSyncedAt == nil || !now.Before(e.LastSyncedAt.Add(time.Duration(e.SyncIntervalMinutes)*time.Minute))
+}
+
+// CreateSheetExportRequest is the request body for adding a sheet export
+type CreateSheetExportRequest struct {
+	SpreadsheetID       string        `json:"spreadsheet_id" validate:"required"` // the ID or the sheet's URL
+	Name                *string       `json:"name,omitempty"`
+	IDDevice            *string       `json:"id_device,omitempty"`
+	SheetName           *string       `json:"sheet_name,omitempty"` // default DefaultSheetName
+	Stages              []string      `json:"stages,omitempty"`
+	Columns             []SheetColumn `json:"columns,omitempty"` // default DefaultSheetColumns
+	SyncTable           *string       `json:"sync_table,omitempty"`
+	SyncSheetName       *string       `json:"sync_sheet_name,omitempty"`       // default DefaultSheetSyncName
+	SyncIntervalMinutes *int          `json:"sync_interval_minutes,omitempty"` // default DefaultSheetSyncIntervalMinute
+}
+
+// UpdateSheetExportRequest is the request body for changing a sheet export
+type UpdateSheetExportRequest struct {
+	SpreadsheetID       *string       `json:"spreadsheet_id,omitempty"`
+	Name                *string       `json:"name,omitempty"`
+	IDDevice            *string       `json:"id_device,omitempty"` // empty string = every device
+	SheetName           *string       `json:"sheet_name,omitempty"`
+	Stages              []string      `json:"stages,omitempty"`
+	Columns             []SheetColumn `json:"columns,omitempty"`
+	SyncTable           *string       `json:"sync_table,omitempty"` // empty string stops the scheduled sync
+	SyncSheetName       *string       `json:"sync_sheet_name,omitempty"`
+	SyncIntervalMinutes *int          `json:"sync_interval_minutes,omitempty"`
+	IsActive            *bool         `json:"is_active,omitempty"`
+}
+
+// SheetExportResponse is the response for sheet export operations
+type SheetExportResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Export  *SheetExport  `json:"export,omitempty"`
+	Exports []SheetExport `json:"exports,omitempty"`
+	// ServiceAccount is the email the spreadsheets must be shared with as editor
+	ServiceAccount string `json:"service_account,omitempty"`
+	Rows           int    `json:"rows,omitempty"` // conversations written by a sync
+}
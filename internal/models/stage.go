@@ -8,6 +8,25 @@ type StageValue struct {
 	TypeInputData string  `json:"type_inputdata"`
 	ColumnsData   string  `json:"columnsdata"`
 	InputHardCode string  `json:"inputhardcode,omitempty"`
+	// SLAMinutes is the maximum dwell time in this stage before the SLA monitor acts (nil/0 = no SLA)
+	SLAMinutes *int `json:"sla_minutes,omitempty"`
+	// SLAAction is what happens on a breach: StageSLAActionNudge or StageSLAActionNotify (default)
+	SLAAction *string `json:"sla_action,omitempty"`
+	// SLANudgeNodeID is the node in the conversation's flow that is run for a nudge
+	SLANudgeNodeID *string `json:"sla_nudge_node_id,omitempty"`
+}
+
+// HasSLA reports whether the stage has a maximum dwell time
+func (s *StageValue) HasSLA() bool {
+	return s.SLAMinutes != nil && *s.SLAMinutes > 0
+}
+
+// EffectiveSLAAction returns the configured breach action, defaulting to notifying the owner
+func (s *StageValue) EffectiveSLAAction() string {
+	if s.SLAAction != nil && *s.SLAAction == StageSLAActionNudge {
+		return StageSLAActionNudge
+	}
+	return StageSLAActionNotify
 }
 
 // CreateStageValueRequest is the request body for creating a stage value
@@ -17,6 +36,9 @@ type CreateStageValueRequest struct {
 	TypeInputData string `json:"type_inputdata" validate:"required,oneof=Set Input"`
 	ColumnsData   string `json:"columnsdata" validate:"required"`
 	InputHardCode string `json:"inputhardcode"` // Not required - only needed when Type = "Set"
+	SLAMinutes     *int    `json:"sla_minutes,omitempty"`
+	SLAAction      *string `json:"sla_action,omitempty"` // nudge, notify
+	SLANudgeNodeID *string `json:"sla_nudge_node_id,omitempty"`
 }
 
 // UpdateStageValueRequest is the request body for updating a stage value
@@ -26,6 +48,9 @@ type UpdateStageValueRequest struct {
 	TypeInputData *string `json:"type_inputdata,omitempty"`
 	ColumnsData   *string `json:"columnsdata,omitempty"`
 	InputHardCode *string `json:"inputhardcode,omitempty"`
+	SLAMinutes     *int    `json:"sla_minutes,omitempty"` // 0 removes the SLA
+	SLAAction      *string `json:"sla_action,omitempty"`
+	SLANudgeNodeID *string `json:"sla_nudge_node_id,omitempty"`
 }
 
 // StageValueResponse is the response for stage value operations
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// StageVisit is one stay of a conversation in a stage, recorded by a database trigger whenever
+// a conversation's stage changes. LeftAt is nil while the conversation is still in the stage.
+type StageVisit struct {
+	ID             string     `json:"id,omitempty"`
+	Source         string     `json:"source"` // ai_whatsapp, wasapbot
+	ConversationID string     `json:"conversation_id"`
+	IDDevice       string     `json:"id_device"`
+	FlowID         *string    `json:"flow_id,omitempty"`
+	ProspectNum    string     `json:"prospect_num"`
+	Stage          string     `json:"stage"`
+	EnteredAt      time.Time  `json:"entered_at"`
+	LeftAt         *time.Time `json:"left_at,omitempty"`
+}
+
+// StageFunnel is a flow's stages in the order its definition reaches them, with how many
+// conversations got at least that far
+type StageFunnel struct {
+	FlowID   string            `json:"flow_id"`
+	FlowName string            `json:"flow_name"`
+	Total    int               `json:"total"` // conversations that entered any stage of the flow
+	Steps    []StageFunnelStep `json:"steps"`
+	// Unlisted counts conversations by stages the flow definition no longer sets
+	Unlisted map[string]int `json:"unlisted,omitempty"`
+}
+
+// StageFunnelStep is one stage of a funnel. Reached counts conversations that entered the stage
+// or a later one; DropOffPercent is the share of them that never reached the next stage (0 on
+// the last). MedianSeconds is the median time spent in the stage by conversations that left it.
+type StageFunnelStep struct {
+	Stage          string   `json:"stage"`
+	Reached        int      `json:"reached"`
+	Current        int      `json:"current"` // conversations in the stage now
+	DropOffPercent float64  `json:"drop_off_percent"`
+	MedianSeconds  *float64 `json:"median_seconds,omitempty"`
+}
+
+// StageFunnelResponse represents the stage funnel analytics response
+type StageFunnelResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Data    *StageFunnel `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
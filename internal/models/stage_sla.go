@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Actions the stage SLA monitor takes when a conversation stays in a stage too long
+const (
+	StageSLAActionNudge  = "nudge"  // run the stage's nudge node for the prospect
+	StageSLAActionNotify = "notify" // message the device owner
+)
+
+// IsValidStageSLAAction reports whether action is a known SLA breach action
+func IsValidStageSLAAction(action string) bool {
+	return action == StageSLAActionNudge || action == StageSLAActionNotify
+}
+
+// StageSLABreach records one conversation exceeding its stage's maximum dwell time
+type StageSLABreach struct {
+	ID             string    `json:"id,omitempty"`
+	IDDevice       string    `json:"id_device"`
+	ProspectNum    string    `json:"prospect_num"`
+	ConversationID string    `json:"conversation_id"`
+	Source         string    `json:"source"` // ai_whatsapp, wasapbot
+	Stage          string    `json:"stage"`
+	SLAMinutes     int       `json:"sla_minutes"`
+	Action         string    `json:"action"`
+	Handled        bool      `json:"handled"` // false when the nudge or notification could not be sent
+	StageEnteredAt time.Time `json:"stage_entered_at"`
+	BreachedAt     time.Time `json:"breached_at"`
+}
+
+// SLAMetrics represents stage SLA breach counts
+type SLAMetrics struct {
+	TotalBreaches int            `json:"total_breaches"`
+	Unhandled     int            `json:"unhandled"`
+	ByStage       map[string]int `json:"by_stage"`
+	ByDevice      map[string]int `json:"by_device"`
+	ByAction      map[string]int `json:"by_action"`
+}
+
+// SLAAnalyticsResponse represents the stage SLA analytics response
+type SLAAnalyticsResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Data    *SLAMetrics `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import "time"
+
+// Team roles. Every user owns a team: the owner has every permission on their devices, members
+// get the permissions of their role on the devices shared with them.
+const (
+	TeamRoleOwner  = "owner"  // the devices' user; implicit, never stored
+	TeamRoleAdmin  = "admin"  // everything but deleting devices and managing the team
+	TeamRoleAgent  = "agent"  // views and replies to conversations, edits flow copy
+	TeamRoleViewer = "viewer" // views conversations and flows
+)
+
+// Team permissions
+const (
+	TeamPermissionViewConversations   = "conversations.view"
+	TeamPermissionReplyConversations  = "conversations.reply" // send, take over, hand back, update and pin conversations
+	TeamPermissionDeleteConversations = "conversations.delete"
+	TeamPermissionViewFlows           = "flows.view"
+	TeamPermissionEditFlows           = "flows.edit"
+	TeamPermissionEditFlowCopy        = "flows.edit_copy" // save flows without touching their settings or admin-only nodes
+	TeamPermissionDeleteFlows         = "flows.delete"
+	TeamPermissionViewDevices         = "devices.view"
+	TeamPermissionEditDevices         = "devices.edit"
+	TeamPermissionDeleteDevices       = "devices.delete"
+	TeamPermissionManageTeam          = "team.manage"
+)
+
+// TeamRolePermissions lists what each role may do on a shared device
+var TeamRolePermissions = map[string][]string{
+	TeamRoleOwner: {
+		TeamPermissionViewConversations, TeamPermissionReplyConversations, TeamPermissionDeleteConversations,
+		TeamPermissionViewFlows, TeamPermissionEditFlows, TeamPermissionDeleteFlows,
+		TeamPermissionViewDevices, TeamPermissionEditDevices, TeamPermissionDeleteDevices,
+		TeamPermissionManageTeam,
+	},
+	TeamRoleAdmin: {
+		TeamPermissionViewConversations, TeamPermissionReplyConversations, TeamPermissionDeleteConversations,
+		TeamPermissionViewFlows, TeamPermissionEditFlows, TeamPermissionDeleteFlows,
+		TeamPermissionViewDevices, TeamPermissionEditDevices,
+	},
+	TeamRoleAgent: {
+		TeamPermissionViewConversations, TeamPermissionReplyConversations,
+		TeamPermissionViewFlows, TeamPermissionEditFlowCopy, TeamPermissionViewDevices,
+	},
+	TeamRoleViewer: {
+		TeamPermissionViewConversations, TeamPermissionViewFlows, TeamPermissionViewDevices,
+	},
+}
+
+// TeamRoleAllows reports whether a role has a permission
+func TeamRoleAllows(role, permission string) bool {
+	for _, p := range TeamRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAssignableTeamRole reports whether members can be given a role; owner is not one
+func IsAssignableTeamRole(role string) bool {
+	return role == TeamRoleAdmin || role == TeamRoleAgent || role == TeamRoleViewer
+}
+
+// MaxTeamMembers caps the members of one team
+const MaxTeamMembers = 50
+
+// TeamMember gives a user a role on the owner's devices
+type TeamMember struct {
+	ID        string     `json:"id,omitempty"`
+	OwnerID   string     `json:"owner_id"`
+	UserID    string     `json:"user_id"`
+	Email     string     `json:"email"`      // of the member when added
+	Role      string     `json:"role"`       // admin, agent, viewer
+	DeviceIDs []string   `json:"device_ids"` // id_device of the shared devices; empty shares all of the owner's devices
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// AddTeamMemberRequest is the request body for adding a registered user to the team
+type AddTeamMemberRequest struct {
+	Email     string   `json:"email" validate:"required,email"`
+	Role      string   `json:"role" validate:"required"`
+	DeviceIDs []string `json:"device_ids,omitempty"`
+}
+
+// UpdateTeamMemberRequest is the request body for changing a member's role or devices
+type UpdateTeamMemberRequest struct {
+	Role      *string   `json:"role,omitempty"`
+	DeviceIDs *[]string `json:"device_ids,omitempty"` // empty list shares every device
+}
+
+// SharedDevice is a device shared with the user and their role on it
+type SharedDevice struct {
+	IDDevice    string  `json:"id_device"`
+	OwnerID     string  `json:"owner_id"`
+	Role        string  `json:"role"`
+	Provider    string  `json:"provider,omitempty"`
+	PhoneNumber *string `json:"phone_number,omitempty"`
+}
+
+// TeamResponse is the response for team operations
+type TeamResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Member  *TeamMember    `json:"member,omitempty"`
+	Members []TeamMember   `json:"members,omitempty"`
+	Devices []SharedDevice `json:"devices,omitempty"`
+}
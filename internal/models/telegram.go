@@ -0,0 +1,33 @@
+package models
+
+import "strings"
+
+// TelegramProspectPrefix marks prospect_num values that belong to a Telegram chat, not a phone number
+const TelegramProspectPrefix = "tg:"
+
+// TelegramProspect returns the prospect_num used for a Telegram chat
+func TelegramProspect(chatID string) string {
+	return TelegramProspectPrefix + chatID
+}
+
+// IsTelegramProspect reports whether a prospect_num belongs to a Telegram chat
+func IsTelegramProspect(prospectNum string) bool {
+	return strings.HasPrefix(prospectNum, TelegramProspectPrefix)
+}
+
+// TelegramChatID returns the Telegram chat ID of a prospect_num, which may omit the tg: prefix
+func TelegramChatID(prospectNum string) string {
+	return strings.TrimPrefix(prospectNum, TelegramProspectPrefix)
+}
+
+// TelegramWebhookRequest registers the device's Telegram bot webhook
+type TelegramWebhookRequest struct {
+	URL string `json:"url" validate:"required"` // public https URL of POST /api/webhooks/telegram/:webhook_id
+}
+
+// TelegramWebhookResponse is the response of registering a Telegram bot webhook
+type TelegramWebhookResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Bot     string `json:"bot,omitempty"` // the bot's @username
+}
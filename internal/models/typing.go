@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Typing pace: bot messages wait as long as they would take to type at typing_chars_per_second,
+// within MinTypingDuration and MaxTypingDuration, so replies do not land the instant a prospect
+// writes. With typing_indicator on the chat is marked read and shows "typing…" meanwhile.
+const (
+	DefaultTypingCharsPerSecond = 15
+	MaxTypingCharsPerSecond     = 100
+
+	MinTypingDuration = 1 * time.Second
+	MaxTypingDuration = 8 * time.Second
+)
+
+// TypingDuration returns how long typing shows before text at charsPerSecond
+func TypingDuration(text string, charsPerSecond int) time.Duration {
+	if charsPerSecond <= 0 {
+		charsPerSecond = DefaultTypingCharsPerSecond
+	}
+	d := time.Duration(utf8.RuneCountInString(text)) * time.Second / time.Duration(charsPerSecond)
+	if d < MinTypingDuration {
+		return MinTypingDuration
+	}
+	if d > MaxTypingDuration {
+		return MaxTypingDuration
+	}
+	return d
+}
@@ -10,7 +10,7 @@ type User struct {
 	Password   string     `json:"password,omitempty"` // Omit in responses
 	Gmail      *string    `json:"gmail,omitempty"`
 	Phone      *string    `json:"phone,omitempty"`
-	Role       string     `json:"role"` // "user", "admin"
+	Role       string     `json:"role"` // "user", "admin", "agent"
 	Status     string     `json:"status"` // "Free", "Pro"
 	Expired    *string    `json:"expired,omitempty"` // Pro expiration date (YYYY-MM-DD format)
 	IsActive   bool       `json:"is_active"`
@@ -19,6 +19,12 @@ type User struct {
 	LastLogin  *time.Time `json:"last_login,omitempty"`
 }
 
+// User roles
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 // UserSession represents a user session
 type UserSession struct {
 	ID        string    `json:"id"`
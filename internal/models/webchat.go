@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Conversation channels
+const (
+	ChannelWhatsApp  = "whatsapp"
+	ChannelWeb       = "web"
+	ChannelTelegram  = "telegram"
+	ChannelMessenger = "messenger" // Facebook page inbox
+	ChannelInstagram = "instagram" // Instagram DMs of the page's business account
+)
+
+// WebChatProspectPrefix marks prospect_num values that belong to a web chat session, not a phone number
+const WebChatProspectPrefix = "web:"
+
+// WebChatProspect returns the prospect_num used for a web chat session
+func WebChatProspect(sessionID string) string {
+	return WebChatProspectPrefix + sessionID
+}
+
+// IsWebChatProspect reports whether a prospect_num belongs to a web chat session
+func IsWebChatProspect(prospectNum string) bool {
+	return strings.HasPrefix(prospectNum, WebChatProspectPrefix)
+}
+
+// ProspectChannel returns the channel a prospect_num belongs to
+func ProspectChannel(prospectNum string) string {
+	if IsWebChatProspect(prospectNum) {
+		return ChannelWeb
+	}
+	if IsTelegramProspect(prospectNum) {
+		return ChannelTelegram
+	}
+	if strings.HasPrefix(prospectNum, MessengerProspectPrefix) {
+		return ChannelMessenger
+	}
+	if strings.HasPrefix(prospectNum, InstagramProspectPrefix) {
+		return ChannelInstagram
+	}
+	return ChannelWhatsApp
+}
+
+// WebChatMessageRequest is a visitor message sent from the website widget
+type WebChatMessageRequest struct {
+	SessionID string `json:"session_id"` // Empty starts a new session; reuse the returned ID afterwards
+	Message   string `json:"message" validate:"required"`
+	Name      string `json:"name,omitempty"`
+}
+
+// WebChatMessage is a bot message waiting to be picked up by the widget
+type WebChatMessage struct {
+	Type     string    `json:"type"` // text, image, video, document, audio
+	Content  string    `json:"content,omitempty"`
+	MediaURL string    `json:"media_url,omitempty"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// WebChatSendResponse acknowledges a visitor message
+type WebChatSendResponse struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// WebChatPollResponse returns bot messages for a session
+type WebChatPollResponse struct {
+	Success   bool             `json:"success"`
+	Message   string           `json:"message,omitempty"`
+	SessionID string           `json:"session_id,omitempty"`
+	Messages  []WebChatMessage `json:"messages"`
+}
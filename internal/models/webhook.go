@@ -40,6 +40,14 @@ type ExtractedMessage struct {
 	Name        string
 	Provider    string
 	DeviceID    string
+	// QuotedMessageID is the provider ID of the message the prospect replied to, if any
+	QuotedMessageID string
+	// OptionID is the ID of the button or list row the prospect picked, if any
+	OptionID string
+	// Media is the image, voice note, document, ... the message carries; Message is then its caption or placeholder
+	Media *InboundMedia
+	// Location is the pin the prospect shared, if any; Message is then its placeholder unless captioned
+	Location *Location
 }
 
 // WasapBot represents a record in wasapbot table for WhatsApp Bot flows
@@ -52,6 +60,7 @@ type WasapBot struct {
 	WaitingForReply     *bool   `json:"waiting_for_reply,omitempty"`
 	DeviceID            string  `json:"id_device"` // Database column: id_device
 	ProspectNum         string  `json:"prospect_num"`
+	ExternalRef         *string `json:"external_ref,omitempty"`
 	Niche               *string `json:"niche,omitempty"`
 	PeringkatSekolah    *string `json:"peringkat_sekolah,omitempty"`
 	Alamat              *string `json:"alamat,omitempty"`
@@ -66,6 +75,21 @@ type WasapBot struct {
 	CreatedAt           *string `json:"created_at,omitempty"`
 	UpdatedAt           *string `json:"updated_at,omitempty"`
 	Status              *string `json:"status,omitempty"`
+	Channel             string  `json:"channel,omitempty"` // whatsapp or web
+	IsTest              bool    `json:"is_test,omitempty"` // Started on a sandbox device
+	FlowStartedAt       *string `json:"flow_started_at,omitempty"`
+	FlowEntries         *int    `json:"flow_entries,omitempty"`
+	LastMedia           *InboundMedia `json:"last_media,omitempty"`
+	SessionData         map[string]interface{} `json:"session_data,omitempty"`
+}
+
+// ExecutionState returns the execution snapshot of the contact
+func (c *WasapBot) ExecutionState() *ExecutionState {
+	return &ExecutionState{
+		ExecutionStatus: c.ExecutionStatus,
+		WaitingForReply: c.WaitingForReply,
+		CurrentNodeID:   c.CurrentNodeID,
+	}
 }
 
 // AIWhatsApp represents a record in ai_whatsapp table for Chatbot AI flows
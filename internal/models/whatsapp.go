@@ -20,10 +20,17 @@ type WhatsAppMessage struct {
 type SendMessageRequest struct {
 	To       string `json:"to" validate:"required"`
 	Body     string `json:"body" validate:"required"`
-	Type     string `json:"type"` // text, image, document, audio, video
+	Type     string `json:"type"` // text, image, document, audio, video, buttons, list, location, contact
 	MediaURL string `json:"media_url,omitempty"`
 	MimeType string `json:"mime_type,omitempty"` // MIME type of the media file
 	DeviceID string `json:"device_id" validate:"required"`
+	// Buttons and List are the choices of buttons and list messages; Body is their text
+	Buttons []MessageButton `json:"buttons,omitempty"`
+	List    *MessageList    `json:"list,omitempty"`
+	Footer  string          `json:"footer,omitempty"`
+	// Location and Contact are the pin of location messages and the card of contact messages
+	Location *Location    `json:"location,omitempty"`
+	Contact  *ContactCard `json:"contact,omitempty"`
 }
 
 // SendMessageResponse is the response after sending a message
@@ -36,14 +43,17 @@ type SendMessageResponse struct {
 
 // WebhookPayload represents incoming webhook data from WhatsApp providers
 type WebhookPayload struct {
-	Event     string                 `json:"event"`
-	Session   string                 `json:"session,omitempty"`
-	From      string                 `json:"from"`
-	Body      string                 `json:"body,omitempty"`
-	Type      string                 `json:"type,omitempty"`
-	MediaURL  string                 `json:"media_url,omitempty"`
-	Timestamp int64                  `json:"timestamp,omitempty"`
-	Raw       map[string]interface{} `json:"raw,omitempty"` // Original provider payload
+	Event           string                 `json:"event"`
+	Session         string                 `json:"session,omitempty"`
+	From            string                 `json:"from"`
+	Body            string                 `json:"body,omitempty"`
+	Type            string                 `json:"type,omitempty"`
+	MediaURL        string                 `json:"media_url,omitempty"`
+	Timestamp       int64                  `json:"timestamp,omitempty"`
+	QuotedMessageID string                 `json:"quoted_message_id,omitempty"` // set when the message replies to (quotes) another
+	OptionID        string                 `json:"option_id,omitempty"`         // the button or list row the prospect picked
+	Location        *Location              `json:"location,omitempty"`          // the pin the prospect shared
+	Raw             map[string]interface{} `json:"raw,omitempty"`               // Original provider payload
 }
 
 // SessionInfo represents WhatsApp session information
@@ -62,3 +72,25 @@ type SessionStatusResponse struct {
 	Session *SessionInfo `json:"session,omitempty"`
 	Error   string       `json:"error,omitempty"`
 }
+
+// WhatsAppProfile is the display profile of a connected number, as other WhatsApp users see it
+type WhatsAppProfile struct {
+	ID         string `json:"id,omitempty"` // the number's WhatsApp ID, e.g. 60123456789@c.us
+	Name       string `json:"name"`
+	About      string `json:"about"`
+	PictureURL string `json:"picture_url,omitempty"`
+}
+
+// UpdateDeviceProfileRequest changes a device's display profile; omitted fields are left as they are
+type UpdateDeviceProfileRequest struct {
+	Name       *string `json:"name,omitempty"`
+	About      *string `json:"about,omitempty"`
+	PictureURL *string `json:"picture_url,omitempty"` // a public image URL; empty removes the photo
+}
+
+// DeviceProfileResponse is the response from the device profile endpoints
+type DeviceProfileResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Profile *WhatsAppProfile `json:"profile,omitempty"`
+}
@@ -0,0 +1,190 @@
+// Package openapi builds the OpenAPI 3 specification for the HTTP API from
+// the route table in routes.go and the request/response models.
+package openapi
+
+import (
+	"regexp"
+	"strings"
+
+	"chatbot-automation/internal/middleware"
+)
+
+// Route documents one HTTP endpoint
+type Route struct {
+	Method      string
+	Path        string // Fiber style, e.g. /api/flows/:id
+	Tag         string
+	Summary     string
+	Auth        bool        // requires "Authorization: Bearer <jwt>"
+	Query       []string    // optional query parameters
+	Request     interface{} // zero value of the JSON body type, nil when there is no body
+	Response    interface{} // zero value of the JSON response type, nil for free-form JSON
+	Description string
+}
+
+// Document is the OpenAPI document root
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info holds API metadata
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how requests authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Operation describes one method on a path
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter describes a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes a JSON request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps a schema for a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// Build creates the specification for the given routes
+func Build(routes []Route) *Document {
+	registry := newSchemaRegistry()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:   "Chatbot Automation API",
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]map[string]Operation),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"apiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		path := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+
+		op := Operation{
+			Summary:     route.Summary,
+			Description: route.Description,
+			OperationID: operationID(method, route.Path),
+			Responses:   make(map[string]Response),
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		for _, name := range route.Query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "query", Schema: &Schema{Type: "string"},
+			})
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: registry.schemaFor(route.Request)}},
+			}
+		}
+
+		success := Response{Description: "Success"}
+		responseSchema := registry.schemaFor(route.Response)
+		if responseSchema == nil {
+			responseSchema = &Schema{Type: "object"}
+		}
+		success.Content = map[string]MediaType{"application/json": {Schema: responseSchema}}
+		op.Responses["200"] = success
+		op.Responses["400"] = Response{Description: "Invalid request"}
+
+		if route.Auth {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			op.Responses["401"] = Response{Description: "Missing or invalid token"}
+		}
+		if scope := middleware.APIKeyScope(route.Method, route.Path); scope != "" {
+			op.Security = append(op.Security, map[string][]string{"apiKeyAuth": {}})
+			op.Responses["401"] = Response{Description: "Missing or invalid token"}
+			op.Description = strings.TrimSpace(op.Description + " API keys need the " + scope + " scope.")
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		doc.Paths[path][method] = op
+	}
+
+	doc.Components.Schemas = registry.components
+	return doc
+}
+
+// operationID derives a stable camelCase identifier from method and path
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' })
+	for _, segment := range segments {
+		if segment == "api" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") {
+			segment = "By" + strings.TrimPrefix(segment, ":")
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package openapi
+
+import "chatbot-automation/internal/models"
+
+// Routes lists every HTTP endpoint served by the handlers.
+// Keep it in sync when adding a handler method.
+var Routes = []Route{
+	// Auth
+	{Method: "POST", Path: "/api/auth/register", Tag: "Auth", Summary: "Register a user", Request: models.RegisterRequest{}, Response: models.AuthResponse{}},
+	{Method: "POST", Path: "/api/auth/login", Tag: "Auth", Summary: "Log in and receive a JWT", Request: models.LoginRequest{}, Response: models.AuthResponse{}},
+	{Method: "GET", Path: "/api/auth/me", Tag: "Auth", Summary: "Get the current user's profile", Auth: true, Response: models.AuthResponse{}},
+	{Method: "PUT", Path: "/api/auth/password", Tag: "Auth", Summary: "Change password", Auth: true, Request: models.ChangePasswordRequest{}},
+	{Method: "PUT", Path: "/api/auth/profile", Tag: "Auth", Summary: "Update gmail and phone", Auth: true, Request: models.UpdateProfileRequest{}},
+	{Method: "POST", Path: "/api/account/export", Tag: "Auth", Summary: "Start a backup of all the user's data", Auth: true, Response: models.AccountExportResponse{}, Description: "Returns 202 with a pending export while a zip of flows, devices (API keys, webhook IDs and AI endpoint headers removed), stage configurations, custom fields, campaigns, ai_whatsapp and wasapbot conversations and analytics summaries is built in the background. Poll GET /api/account/export/:id for the download link."},
+	{Method: "GET", Path: "/api/account/export/:id", Tag: "Auth", Summary: "Get an account export's status and download link", Auth: true, Response: models.AccountExportResponse{}, Description: "Once status is ready, download_url is a signed link valid for one hour; request the export again for a fresh link."},
+
+	// Devices
+	{Method: "POST", Path: "/api/devices", Tag: "Devices", Summary: "Create a device", Auth: true, Request: models.CreateDeviceRequest{}, Response: models.DeviceResponse{}, Description: "AI calls retry timeouts, 429 (after Retry-After) and 5xx responses with exponential backoff (AI_RETRY_MAX_ATTEMPTS, default 3); ai_fallback_model is tried once when the model still fails. provider custom sends through the HTTP gateway described by custom_provider (URL, header and body templates)."},
+	{Method: "GET", Path: "/api/devices", Tag: "Devices", Summary: "List the user's devices", Auth: true, Response: models.DeviceResponse{}},
+	{Method: "GET", Path: "/api/devices/:id", Tag: "Devices", Summary: "Get a device", Auth: true, Response: models.DeviceResponse{}},
+	{Method: "PUT", Path: "/api/devices/:id", Tag: "Devices", Summary: "Update a device", Auth: true, Request: models.UpdateDeviceRequest{}, Response: models.DeviceResponse{}, Description: "reply_profile is learned from the device's conversation history and read-only. delay and waiting_times pauses of an hour or more resume in its best_hours unless static_follow_ups is on or the node sets \"timing\": \"static\". Humanized sending applies to every send: consecutive messages to a prospect are spaced by a random send_delay_min_ms to send_delay_max_ms (default 1000 to 3000, max 60000). When typing_chars_per_second or typing_indicator is set, bot messages wait as long as they take to type at typing_chars_per_second (default 15, max 100), between 1 and 8 seconds; with typing_indicator on the chat is also marked read and shows the bot typing (Waha, Telegram and Messenger devices). Bot messages made between quiet_hours_start and quiet_hours_end (HH:MM in the device timezone, may span midnight; set both, or both empty to remove) are queued and sent when the quiet hours end. Human agent replies are never typed or held. business_hours (\"Mon-Fri 09:00-18:00; Sat 10:00-14:00\" in the device timezone, empty string removes them) route inbound messages that arrive while closed by closed_mode: \"away\" (default) runs away_flow_id instead of the triggered flow until opening, \"queue\" runs the normal flow but holds its bot replies until opening. business_hours flow nodes branch on their own hours or the device's, following their \"open\" or \"closed\" connection. With share_prospect_data on, stage and custom field changes of a prospect are copied to its conversations (same phone) on the user's other devices that also share. phone_country (ISO code such as MY, SG or ID; empty resets to MY) is the country numbers without a country code are read in: inbound senders, prospect_num of manually created conversations and flows, campaign CSV rows and send recipients are normalized to E.164 digits by its calling code and trunk prefix, and numbers of the wrong length are rejected."},
+	{Method: "DELETE", Path: "/api/devices/:id", Tag: "Devices", Summary: "Delete a device", Auth: true, Response: models.DeviceResponse{}},
+	{Method: "POST", Path: "/api/devices/:id/telegram/webhook", Tag: "Devices", Summary: "Register the device's Telegram bot webhook", Auth: true, Request: models.TelegramWebhookRequest{}, Response: models.TelegramWebhookResponse{}, Description: "Checks telegram_bot_token and points the bot at url, the public https address of POST /api/webhooks/telegram/:webhook_id. Telegram chats then run the device's flows as prospects tg:<chat id> with channel telegram; replies, buttons (as inline keyboards), media, locations and contact cards go back through the bot."},
+	{Method: "POST", Path: "/api/devices/:id/session/start", Tag: "Devices", Summary: "Start the device's WhatsApp session", Auth: true, Response: models.DeviceStatusResponse{}, Description: "Creates or starts the provider session and returns its status: connected, connecting (waiting for a QR scan) or disconnected. While connecting, image is the login QR code as a PNG data URL. Waha and Whacenter devices only."},
+	{Method: "GET", Path: "/api/devices/:id/session/qr", Tag: "Devices", Summary: "Get the session status and live login QR code", Auth: true, Response: models.DeviceStatusResponse{}, Description: "QR codes rotate every 20 seconds or so; poll every few seconds and show the latest image until status is connected. image is empty unless status is connecting. Waha and Whacenter devices only."},
+	{Method: "POST", Path: "/api/devices/:id/session/restart", Tag: "Devices", Summary: "Restart the device's WhatsApp session", Auth: true, Response: models.DeviceStatusResponse{}, Description: "Keeps the linked number. Waha devices only; Whacenter has no restart call."},
+	{Method: "POST", Path: "/api/devices/:id/session/logout", Tag: "Devices", Summary: "Log the number out of the device's WhatsApp session", Auth: true, Response: models.DeviceStatusResponse{}, Description: "Unlinks the number; reconnecting needs a new QR scan. Waha devices only; Whacenter devices are unlinked from the phone's Linked devices screen."},
+	{Method: "GET", Path: "/api/devices/:id/health", Tag: "Devices", Summary: "Device provider health", Auth: true, Response: models.DeviceHealthResponse{}, Description: "connection_status is the health monitor's last GetSessionStatus check of the device; every device is checked on each monitor round. status_checks and sends give the count, error rate and average, p95 and last latency of this instance's provider calls over the last window_minutes. failing_over is true while the device is disconnected and its sends go through a connected backup_device_id."},
+	{Method: "GET", Path: "/api/devices/:id/config", Tag: "Devices", Summary: "Export a device configuration bundle", Auth: true, Response: models.DeviceConfigExportResponse{}, Description: "Settings (without API keys, instance, webhook, phone or backup links), stage configs and flows."},
+	{Method: "POST", Path: "/api/devices/:id/config", Tag: "Devices", Summary: "Import a configuration bundle into a device", Auth: true, Request: models.DeviceConfigImportRequest{}, Response: models.DeviceConfigImportResponse{}, Description: "Overwrites settings, adds missing stage configs and creates the bundled flows as new flows."},
+	{Method: "GET", Path: "/api/devices/:id/sandbox-messages", Tag: "Devices", Summary: "List sends intercepted in sandbox mode", Auth: true, Query: []string{"limit"}, Response: models.SandboxMessagesResponse{}, Description: "While a device has sandbox=true every provider send is logged here with its full payload and status sandbox-delivered instead of reaching WhatsApp. Newest first, limit 50 by default (max 200)."},
+	{Method: "GET", Path: "/api/devices/:id/send-queue", Tag: "Devices", Summary: "Get the device's outbound send queue", Auth: true, Response: models.SendQueueResponse{}, Description: "Every provider send, Telegram and Meta inbox replies included, waits in a per-device queue: at most send_rate_per_minute messages a minute (default 30, max 120), and consecutive messages to the same prospect send_delay_min_ms to send_delay_max_ms apart at random (1-3 seconds by default). Bot messages held for quiet hours are not in the queue until their quiet hours end. depth is how many messages are waiting, estimated_wait_ms how long a message queued now would wait. Counters cover this instance since it started. Sandboxed devices and web chat replies are not queued."},
+	{Method: "GET", Path: "/api/devices/:id/archives", Tag: "Devices", Summary: "List archived raw inbound webhook payloads", Auth: true, Query: []string{"from", "to", "limit"}, Response: models.InboundArchiveResponse{}, Description: "While a device has archive_inbound=true every accepted inbound webhook is stored gzipped in the private inbound-archive bucket under <id_device>/<YYYY-MM-DD>/, independent of the conversation history, and purged archive_retention_days (default 365) after it arrived. from and to are dates (YYYY-MM-DD, to includes the whole day) or RFC 3339 times. Newest first, limit 50 by default (max 500)."},
+	{Method: "GET", Path: "/api/devices/:id/archives/:archiveId", Tag: "Devices", Summary: "Get an archived raw inbound webhook payload", Auth: true, Query: []string{"format"}, Response: models.InboundArchiveResponse{}, Description: "payload is the decompressed body byte for byte as received. format=raw returns the body itself with the Content-Type it was received with."},
+	{Method: "GET", Path: "/api/devices/:id/profile", Tag: "Devices", Summary: "Get the WhatsApp display profile of the device's number", Auth: true, Response: models.DeviceProfileResponse{}, Description: "Display name, about text and photo as other WhatsApp users see them. Waha devices only."},
+	{Method: "PUT", Path: "/api/devices/:id/profile", Tag: "Devices", Summary: "Set the WhatsApp display profile of the device's number", Auth: true, Request: models.UpdateDeviceProfileRequest{}, Response: models.DeviceProfileResponse{}, Description: "Omitted fields are left as they are. name is up to 25 characters, about up to 139; picture_url must be a public http(s) image URL, or empty to remove the photo. Waha devices only."},
+	{Method: "POST", Path: "/api/devices/:id/generate", Tag: "Devices", Summary: "Generate the device on its provider", Auth: true, Response: models.DeviceResponse{}},
+	{Method: "GET", Path: "/api/devices/:id/status", Tag: "Devices", Summary: "Check connection status and get a QR code", Auth: true, Response: models.DeviceStatusResponse{}},
+
+	// Flows
+	{Method: "POST", Path: "/api/flows", Tag: "Flows", Summary: "Create a flow", Auth: true, Request: models.CreateFlowRequest{}, Response: models.FlowResponse{}, Description: "Team agents on a shared device cannot add admin-only nodes: by default emit_event, send_email, api, translate, and ai_prompt nodes that pick a model priced at $2 or more per million prompt tokens (or without a known price). FLOW_ADMIN_ONLY_NODES overrides the node types. Agents also cannot set anything but flow_name, niche and nodes_data. triggers lists the messages that start the flow: equals, contains or regex (case-insensitive) on the message, or new_contact for a prospect's first message to the device. A device can have several flows: inbound messages try its active flows in priority order (lowest first), starting the first whose trigger matches, else the first without triggers. A keyword trigger also moves a conversation in another flow to this one, unless an agent owns it. is_active defaults to true."},
+	{Method: "POST", Path: "/api/flows/validate", Tag: "Flows", Summary: "Check a flow for structural problems before saving", Auth: true, Request: models.ValidateFlowRequest{}, Response: models.FlowValidationResponse{}, Description: "Reports errors (invalid JSON, no nodes, duplicate node IDs, connections to missing nodes, send_message nodes without text or message_template, loops with no waiting or delay step, waiting steps or nested forks inside fork branches) and warnings (orphan and unreachable nodes, conditions without a default branch, loops, forks with one branch, joins that follow no fork or wait for a missing branch). Create and update run the same checks: errors reject the save with 400, warnings are returned in validation."},
+	{Method: "POST", Path: "/api/flows/compile", Tag: "Flows", Summary: "Compile a YAML or JSON flow definition to nodes_data", Auth: true, Request: models.CompileFlowDefinitionRequest{}, Response: models.CompileFlowDefinitionResponse{}, Description: "A definition lists nodes (id, type, label, config, template, position), each with next (a node ID or a list) and branches (when, value, to; variable makes a text condition such as equal, regex, starts_with, greater_than or in_list test a conversation variable instead of the message). template names an entry of templates and becomes the node's text; settings carries the completion policy and related flow settings, triggers the flow's trigger rules. Nodes are auto laid out unless every node has a position. The result goes through the same checks as /api/flows/validate and nodes_data is only returned when there are no errors. Nothing is saved."},
+	{Method: "GET", Path: "/api/flows", Tag: "Flows", Summary: "List the user's flows", Auth: true, Response: models.FlowResponse{}},
+	{Method: "GET", Path: "/api/flows/:id", Tag: "Flows", Summary: "Get a flow", Auth: true, Response: models.FlowResponse{}},
+	{Method: "GET", Path: "/api/flows/device/:deviceId", Tag: "Flows", Summary: "List flows for a device", Auth: true, Response: models.FlowResponse{}},
+	{Method: "PUT", Path: "/api/flows/:id", Tag: "Flows", Summary: "Update a flow", Auth: true, Request: models.UpdateFlowRequest{}, Response: models.FlowResponse{}, Description: "Team agents on a shared device can edit flow_name, niche and nodes_data (message copy and other nodes), but a save that adds, removes or changes the type or config of an admin-only node, or changes any other setting, is rejected (see POST /api/flows). Moving or renaming such a node is allowed. Version restores, auto layout and priorities need the owner or an admin. An empty triggers list removes the flow's triggers."},
+	{Method: "POST", Path: "/api/flows/:id/auto-layout", Tag: "Flows", Summary: "Recompute node positions with a layered layout", Auth: true, Request: models.AutoLayoutRequest{}, Response: models.FlowResponse{}},
+	{Method: "GET", Path: "/api/flows/:id/doc", Tag: "Flows", Summary: "Generate human-readable flow documentation", Auth: true, Query: []string{"format"}, Response: models.FlowDocResponse{}, Description: "Entry conditions, each step and branch, messages sent verbatim, fields captured and AI prompts used. format=markdown returns the Markdown document as text/markdown."},
+	{Method: "GET", Path: "/api/flows/:id/definition", Tag: "Flows", Summary: "Export a flow as a flow definition", Auth: true, Query: []string{"format"}, Response: models.FlowDefinitionResponse{}, Description: "The reverse of /api/flows/compile, for keeping flows in git. Node text stays in config. format=yaml returns the YAML document as application/yaml."},
+	{Method: "POST", Path: "/api/flows/:id/check-media", Tag: "Flows", Summary: "Pre-flight check of every media URL in a flow", Auth: true, Response: models.FlowMediaCheckResponse{}, Description: "Checks each send_image/send_audio/send_video URL and media_switch variant: reachable, served as the node's media type, and within the WhatsApp size limit (image 5 MB, audio and video 16 MB). ready is false when any media failed."},
+	{Method: "GET", Path: "/api/flows/:id/versions", Tag: "Flows", Summary: "List a flow's stored versions", Auth: true, Response: models.FlowVersionsResponse{}, Description: "Every update stores the flow as it was before as the next version, newest first. Snapshots are not included."},
+	{Method: "POST", Path: "/api/flows/:id/versions/:version/restore", Tag: "Flows", Summary: "Roll a flow back to a stored version", Auth: true, Response: models.FlowResponse{}, Description: "Restores name, niche, nodes_data and settings. The flow as it was before the restore is stored as a new version, so a restore can be undone."},
+	{Method: "POST", Path: "/api/flows/:id/simulate", Tag: "Flows", Summary: "Dry-run a flow against a mock conversation", Auth: true, Request: models.SimulateFlowRequest{}, Response: models.SimulateFlowResponse{}, Description: "Replays the prospect messages through the flow's engine in memory. Returns the nodes visited, messages that would have been sent, column changes, delays and the completion webhook, without sending or saving anything. AI and translation nodes still call their providers."},
+	{Method: "GET", Path: "/api/maintenance/execution-metrics", Tag: "Flows", Summary: "Flow execution concurrency gauges and saturation alerts", Auth: true, Response: models.ExecutionMetricsResponse{}, Description: "Admin only. active_runs and send_queue_depth count this instance; waiting_conversations and scheduler_backlog (delayed executions past their resume time) come from the database. The monitor alerts when a gauge reaches its ALERT_ACTIVE_RUNS, ALERT_WAITING_CONVERSATIONS, ALERT_SCHEDULER_BACKLOG or ALERT_SEND_QUEUE_DEPTH threshold, and resolves once it falls below 80% of it; alerts are logged and POSTed to ALERT_WEBHOOK_URL."},
+	{Method: "GET", Path: "/api/maintenance/consistency", Tag: "Flows", Summary: "Check for inconsistent conversations, flows and stage configs", Auth: true, Response: models.ConsistencyResponse{}, Description: "Admin only. Reports conversations bound to deleted flows, flows of deleted devices, conversations waiting for a reply with no current_node_id, and stage configs of deleted devices, each with the repair auto-repair would take (none for flows of deleted devices). Nothing is changed. The same check runs at startup and is logged; CONSISTENCY_AUTO_REPAIR=true also repairs there."},
+	{Method: "POST", Path: "/api/maintenance/consistency/repair", Tag: "Flows", Summary: "Check consistency and repair what can be repaired", Auth: true, Request: models.ConsistencyRepairRequest{}, Response: models.ConsistencyResponse{}, Description: "Admin only. Runs a fresh check and repairs its issues, or only those of the listed kinds: conversations of deleted flows move to their device's catch-all flow (or lose their flow_id when the device has none), waiting conversations without a node become active, and orphaned stage configs are deleted. Each issue reports whether its repair succeeded."},
+	{Method: "POST", Path: "/api/maintenance/model-migration", Tag: "Flows", Summary: "Find and replace deprecated AI models in ai_prompt nodes and devices", Auth: true, Request: models.ModelMigrationRequest{}, Response: models.ModelMigrationResponse{}, Description: "Admin only. dry_run previews the affected flows and devices without saving."},
+	{Method: "POST", Path: "/api/flows/:id/activate", Tag: "Flows", Summary: "Let a flow take new prospects", Auth: true, Response: models.FlowResponse{}},
+	{Method: "POST", Path: "/api/flows/:id/deactivate", Tag: "Flows", Summary: "Stop a flow taking new prospects", Auth: true, Response: models.FlowResponse{}, Description: "Inbound messages and trigger matching skip the flow; conversations already in it continue."},
+	{Method: "PUT", Path: "/api/flows/device/:deviceId/order", Tag: "Flows", Summary: "Set the order in which a device's flows are tried", Auth: true, Request: models.ReorderFlowsRequest{}, Response: models.FlowResponse{}, Description: "flow_ids lists the device's flows, highest priority first; they get priority 1, 2, ... and unlisted flows follow in their current order. Returns the flows in the new order."},
+	{Method: "DELETE", Path: "/api/flows/:id", Tag: "Flows", Summary: "Delete a flow", Auth: true, Response: models.FlowResponse{}},
+
+	// Conversations
+	{Method: "POST", Path: "/api/conversations", Tag: "Conversations", Summary: "Create a conversation", Auth: true, Request: models.CreateConversationRequest{}, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations", Tag: "Conversations", Summary: "List the user's conversations a page at a time", Auth: true, Query: []string{"page", "page_size", "cursor", "device_id", "stage", "status", "niche", "from", "to", "phone"}, Response: models.ConversationResponse{}, Description: "Newest first across the user's devices, or one device with device_id. status filters execution_status; stage and niche match exactly; phone matches digits anywhere in the number. from and to bound created_at and take a date (YYYY-MM-DD, to includes the whole day) or an RFC 3339 time. page_size defaults to 50, at most 200. Pass page for numbered pages with a total, or next_cursor from the previous page as cursor to continue a listing without skipped or repeated rows when new conversations arrive."},
+	{Method: "GET", Path: "/api/conversations/all", Tag: "Conversations", Summary: "List conversations visible to the user", Auth: true, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations/:id", Tag: "Conversations", Summary: "Get a conversation with its cost ledger", Auth: true, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations/ref/:ref", Tag: "Conversations", Summary: "Get a conversation by external reference", Auth: true, Response: models.ConversationResponse{}, Description: "The reference is external_ref from creation/start-flow, or by default the UUIDv5 of \"id_device:prospect_num\"."},
+	{Method: "GET", Path: "/api/conversations/device/:deviceId", Tag: "Conversations", Summary: "List conversations for a device", Auth: true, Query: []string{"limit", "columns", "sort", "filter.<column>", "format"}, Response: models.ConversationResponse{}, Description: "columns adds captured fields (e.g. pakej,alamat) or the user's custom fields as columns and returns rows instead of conversations; the device's list_columns are used when omitted. sort=<column> or sort=-<column>; filter.<column>=value matches exactly, filter.<column>=~value matches a substring. format=csv downloads the rows as CSV."},
+	{Method: "GET", Path: "/api/conversations/device/:deviceId/active", Tag: "Conversations", Summary: "List active conversations for a device", Auth: true, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations/device/:deviceId/stats", Tag: "Conversations", Summary: "Conversation statistics for a device", Auth: true, Response: struct {
+		Success bool                     `json:"success"`
+		Stats   models.ConversationStats `json:"stats"`
+	}{}},
+	{Method: "PUT", Path: "/api/conversations/:id", Tag: "Conversations", Summary: "Update a conversation", Auth: true, Request: models.UpdateConversationRequest{}, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations/:id/suggestions", Tag: "Conversations", Summary: "Draft AI reply suggestions for an agent", Auth: true, Query: []string{"count"}, Response: models.ReplySuggestionsResponse{}, Description: "Returns 2-3 short replies using the conversation history and device persona. Nothing is sent."},
+	{Method: "GET", Path: "/api/conversations/:id/steps", Tag: "Conversations", Summary: "List the engine states a conversation can be restored to", Auth: true, Response: models.ConversationStepsResponse{}, Description: "A step is recorded after each inbound message is handled, and after each restore. Steps are numbered from 1, oldest first."},
+	{Method: "GET", Path: "/api/conversations/:id/execution-log", Tag: "Conversations", Summary: "Replay the flow nodes run for a conversation", Auth: true, Query: []string{"limit"}, Response: models.ExecutionLogResponse{}, Description: "One entry per node run, oldest first (latest 200 by default, at most 1000): node, the prospect's message being handled, outcome (continued, paused, completed, failed), next node, duration and error. resumed entries are paused nodes taking a reply; failed_max_steps entries are loop guard stops."},
+	{Method: "POST", Path: "/api/conversations/:id/restore", Tag: "Conversations", Summary: "Restore a conversation to an earlier step", Auth: true, Query: []string{"to_step"}, Response: models.RestoreConversationResponse{}, Description: "Rewinds flow, current node, execution state, stage and flow variables to the step; history (conv_last) is kept. messages_since lists what the prospect received after the step."},
+	{Method: "GET", Path: "/api/conversations/:id/messages", Tag: "Conversations", Summary: "List a conversation's message history", Auth: true, Query: []string{"limit"}, Response: models.ConversationMessagesResponse{}, Description: "One entry per message, oldest first (latest 200 by default, at most 1000): role (user or bot), content, inbound media and the flow node that sent the message or that a reply answered. conv_last renders the same messages in the \"User: ...\" / \"Bot: ...\" text format; the conversation's conv_last column is kept in that format for compatibility and follows the device's history cap. Messages from before structured history was enabled exist in conv_last only."},
+	{Method: "POST", Path: "/api/conversations/:id/messages", Tag: "Conversations", Summary: "Append a message to the history", Auth: true, Request: models.AddMessageRequest{}, Response: models.ConversationResponse{}},
+	{Method: "POST", Path: "/api/conversations/:id/takeover", Tag: "Conversations", Summary: "Take a conversation over from the bot", Auth: true, Query: []string{"source"}, Response: models.TakeoverResponse{}, Description: "source is ai_whatsapp (default) or wasapbot. Moves the conversation to paused_by_agent: no flow runs for the prospect, pending delay and waiting_times resumes are dropped, and the prospect's messages are only added to the history. Works from any state."},
+	{Method: "POST", Path: "/api/conversations/:id/send", Tag: "Conversations", Summary: "Send an agent's message to the prospect", Auth: true, Query: []string{"source"}, Request: models.AgentMessageRequest{}, Response: models.TakeoverResponse{}, Description: "Sent from the conversation's device (through its backup while it is disconnected) and added to the history. The conversation must be paused_by_agent or in handoff. The reply counts as a human reply by agent in response time analytics."},
+	{Method: "POST", Path: "/api/conversations/:id/resume", Tag: "Conversations", Summary: "Hand a taken-over conversation back to the bot", Auth: true, Query: []string{"source"}, Request: models.HandBackRequest{}, Response: models.TakeoverResponse{}, Description: "Pass node_id to continue the conversation's flow at that node, or stage to continue at the stage node setting it. The node runs right away; message is handed to it like a prospect reply. state is the conversation's state after the run."},
+	{Method: "DELETE", Path: "/api/conversations/cleanup", Tag: "Conversations", Summary: "Delete a device's test conversations", Auth: true, Query: []string{"device_id", "status", "before", "dry_run"}, Response: models.CleanupConversationsResponse{}, Description: "status=test selects ai_whatsapp and wasapbot conversations flagged is_test (started on a sandbox device, or created/updated with is_test). before (YYYY-MM-DD or RFC 3339) keeps only older ones. With dry_run=true the matches are listed and nothing is deleted."},
+	{Method: "DELETE", Path: "/api/conversations/:id", Tag: "Conversations", Summary: "Delete a conversation", Auth: true, Response: models.ConversationResponse{}},
+	{Method: "PUT", Path: "/api/conversations/:id/pin", Tag: "Conversations", Summary: "Pin/unpin a conversation or set its priority", Auth: true, Request: models.PinConversationRequest{}, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/conversations/pinned", Tag: "Conversations", Summary: "List the user's pinned conversations", Auth: true, Response: models.ConversationResponse{}},
+	{Method: "GET", Path: "/api/inbox/agents", Tag: "Conversations", Summary: "List the user's inbox agents", Auth: true, Response: models.InboxAgentResponse{}},
+	{Method: "POST", Path: "/api/inbox/agents", Tag: "Conversations", Summary: "Add an inbox agent", Auth: true, Request: models.CreateInboxAgentRequest{}, Response: models.InboxAgentResponse{}, Description: "phone is the agent's WhatsApp number, messaged from the prospect's device when a returning prospect is routed to them. available defaults to true."},
+	{Method: "PUT", Path: "/api/inbox/agents/:id", Tag: "Conversations", Summary: "Update an inbox agent", Auth: true, Request: models.UpdateInboxAgentRequest{}, Response: models.InboxAgentResponse{}, Description: "Returning prospects of an unavailable agent are handled by the flow as new leads."},
+	{Method: "DELETE", Path: "/api/inbox/agents/:id", Tag: "Conversations", Summary: "Delete an inbox agent", Auth: true, Response: models.InboxAgentResponse{}, Description: "Their assignments stay in the history."},
+	{Method: "GET", Path: "/api/inbox/assignments", Tag: "Conversations", Summary: "List the agent assignments of a conversation's prospect", Auth: true, Query: []string{"conversation_id", "source"}, Response: models.AgentAssignmentResponse{}, Description: "source is ai_whatsapp (default) or wasapbot. Assignments of earlier conversations with the same number on the device are included, latest first, with the agents' notes."},
+	{Method: "POST", Path: "/api/inbox/assignments", Tag: "Conversations", Summary: "Assign a conversation to an agent", Auth: true, Request: models.AssignAgentRequest{}, Response: models.AgentAssignmentResponse{}, Description: "Puts the conversation in handoff and releases its earlier open assignment. When the prospect messages again after the flow completed or was abandoned, or in a new conversation, they are routed to the agent of their latest assignment if that agent is available: the conversation goes to handoff (sticky=true) and the agent is messaged with their latest notes."},
+	{Method: "PUT", Path: "/api/inbox/assignments/:id", Tag: "Conversations", Summary: "Save an agent's notes or release an assignment", Auth: true, Request: models.UpdateAssignmentRequest{}, Response: models.AgentAssignmentResponse{}, Description: "release=true closes the assignment; move the conversation out of handoff with PUT /api/conversations/:id."},
+	{Method: "GET", Path: "/api/wasapbot/:id/execution-log", Tag: "Conversations", Summary: "Replay the flow nodes run for a WhatsApp Bot conversation", Auth: true, Query: []string{"limit"}, Response: models.ExecutionLogResponse{}, Description: "Same entries as /api/conversations/:id/execution-log."},
+	{Method: "GET", Path: "/api/wasapbot/:id/messages", Tag: "Conversations", Summary: "List a WhatsApp Bot conversation's message history", Auth: true, Query: []string{"limit"}, Response: models.ConversationMessagesResponse{}, Description: "Same entries as /api/conversations/:id/messages."},
+	{Method: "PUT", Path: "/api/wasapbot/:id/pin", Tag: "Conversations", Summary: "Pin/unpin a WhatsApp Bot conversation or set its priority", Auth: true, Request: models.PinConversationRequest{}, Response: models.WasapbotResponse{}},
+	{Method: "GET", Path: "/api/wasapbot/pinned", Tag: "Conversations", Summary: "List the user's pinned WhatsApp Bot conversations", Auth: true, Response: models.WasapbotResponse{}},
+	{Method: "GET", Path: "/api/wasapbot/device/:deviceId", Tag: "Conversations", Summary: "List WhatsApp Bot conversations for a device", Auth: true, Query: []string{"limit", "columns", "sort", "filter.<column>", "format"}, Response: models.WasapbotResponse{}, Description: "Same column, sort, filter and format parameters as /api/conversations/device/:deviceId, limited to wasapbot table columns and the user's custom fields."},
+	{Method: "GET", Path: "/api/wasapbot/all", Tag: "Conversations", Summary: "List WhatsApp Bot conversations", Auth: true, Response: models.WasapbotResponse{}},
+	{Method: "GET", Path: "/api/wasapbot/ref/:ref", Tag: "Conversations", Summary: "Get a WhatsApp Bot conversation by external reference", Auth: true, Response: models.WasapbotResponse{}},
+	{Method: "GET", Path: "/api/sheet-exports", Tag: "Conversations", Summary: "List the user's Google Sheets exports", Auth: true, Response: models.SheetExportResponse{}, Description: "service_account is the email each spreadsheet must be shared with as editor; it is empty while GOOGLE_SHEETS_CREDENTIALS_FILE is not set. last_error says why the last append or sync failed."},
+	{Method: "POST", Path: "/api/sheet-exports", Tag: "Conversations", Summary: "Add a Google Sheets export", Auth: true, Request: models.CreateSheetExportRequest{}, Response: models.SheetExportResponse{}, Description: "spreadsheet_id is the ID or URL of the sheet. Whenever a stage node sets one of stages (empty = every stage) a row is appended to sheet_name (default Sheet1). columns are {header, value} with value a template such as {{alamat}}; the default is Date ({{exported_at}}), Name, Phone, Alamat, Pakej, Payment Method and Stage. With sync_table ai_whatsapp or wasapbot, every sync_interval_minutes (default 60, 15-1440) the sync_sheet_name tab (default Sync) is replaced by a header and a row per conversation. id_device limits both to one device. At most 20 per user."},
+	{Method: "PUT", Path: "/api/sheet-exports/:id", Tag: "Conversations", Summary: "Update a Google Sheets export", Auth: true, Request: models.UpdateSheetExportRequest{}, Response: models.SheetExportResponse{}, Description: "id_device \"\" exports every device again; sync_table \"\" stops the scheduled sync; is_active=false pauses both."},
+	{Method: "DELETE", Path: "/api/sheet-exports/:id", Tag: "Conversations", Summary: "Delete a Google Sheets export", Auth: true, Response: models.SheetExportResponse{}, Description: "The spreadsheet keeps the rows already written."},
+	{Method: "POST", Path: "/api/sheet-exports/:id/sync", Tag: "Conversations", Summary: "Sync a Google Sheets export now", Auth: true, Response: models.SheetExportResponse{}, Description: "Replaces the sync tab right away; rows is the number of conversations written."},
+	{Method: "GET", Path: "/api/prospects/duplicates", Tag: "Conversations", Summary: "Prospects with more than one conversation", Auth: true, Query: []string{"device_id"}, Response: models.ProspectResponse{}, Description: "A prospect is one phone number across the user's devices and both conversation tables; numbers are compared as international digits (+60 12-345 6789, 0123456789 and 60123456789 are the same prospect). Telegram, Messenger and web chat prospects have no phone and are never duplicates. device_id lists only prospects with a conversation on that device. Latest first."},
+	{Method: "GET", Path: "/api/prospects/:phone", Tag: "Conversations", Summary: "A phone number's conversations across the user's devices", Auth: true, Response: models.ProspectResponse{}, Description: "phone may be in any format; name and stage are those of the latest conversation that has one."},
+	{Method: "POST", Path: "/api/prospects/merge", Tag: "Conversations", Summary: "Merge duplicate conversations of a prospect", Auth: true, Request: models.MergeProspectRequest{}, Response: models.ProspectResponse{}, Description: "Folds merge_ids into keep_id, which must all be conversations of source (ai_whatsapp or wasapbot) with the same phone on the user's devices. Their messages move to the kept conversation, which takes their name, stage and custom fields where it has none, and they are deleted. At most 50 at once. Returns the prospect after the merge."},
+
+	// API keys
+	{Method: "GET", Path: "/api/api-keys", Tag: "API keys", Summary: "List the user's API keys", Auth: true, Response: models.APIKeyResponse{}, Description: "Revoked and expired keys included, newest first. Only key_prefix of each key is returned."},
+	{Method: "POST", Path: "/api/api-keys", Tag: "API keys", Summary: "Create an API key", Auth: true, Request: models.CreateAPIKeyRequest{}, Response: models.APIKeyResponse{}, Description: "scopes are flows:read, flows:write, conversations:read, conversations:write, devices:read, devices:write, analytics:read and webhooks:ingest; a :write scope also grants its :read scope. The key is returned once, in secret. Send it as X-API-Key or as Authorization: Bearer ak_...; it acts as the user on the flow, conversation, inbox, prospect, device and analytics endpoints and on the start-flow webhook, as far as its scopes allow. Other endpoints need a JWT. expires_in_days of 0 never expires. At most 20 active keys."},
+	{Method: "DELETE", Path: "/api/api-keys/:id", Tag: "API keys", Summary: "Revoke an API key", Auth: true, Response: models.APIKeyResponse{}, Description: "The key stops authenticating at once and stays listed with revoked_at."},
+
+	// Team
+	{Method: "GET", Path: "/api/team/members", Tag: "Team", Summary: "List the members of the user's team", Auth: true, Response: models.TeamResponse{}},
+	{Method: "POST", Path: "/api/team/members", Tag: "Team", Summary: "Add a registered user to the team", Auth: true, Request: models.AddTeamMemberRequest{}, Response: models.TeamResponse{}, Description: "role is admin (everything on the shared devices but deleting them and managing the team), agent (view and reply to conversations: send, take over, hand back, update, pin, restore; view devices; view flows and edit their copy, but not their settings or admin-only nodes) or viewer (view conversations, flows and devices). device_ids lists the id_device of the shared devices; empty shares all of the owner's devices, including ones added later. Members see shared devices' conversations in the conversation lists; credentials (api_key, instance, telegram_bot_token, custom_provider) are hidden from members who cannot edit the device."},
+	{Method: "PUT", Path: "/api/team/members/:id", Tag: "Team", Summary: "Change a member's role or shared devices", Auth: true, Request: models.UpdateTeamMemberRequest{}, Response: models.TeamResponse{}, Description: "An empty device_ids list shares every device."},
+	{Method: "DELETE", Path: "/api/team/members/:id", Tag: "Team", Summary: "Remove a member from the team", Auth: true, Response: models.TeamResponse{}},
+	{Method: "GET", Path: "/api/team/devices", Tag: "Team", Summary: "List the devices other users share with the user", Auth: true, Response: models.TeamResponse{}, Description: "With the user's role on each. Shared devices are used through the usual device, flow and conversation endpoints as far as the role allows; only owners delete devices."},
+
+	// Stages
+	{Method: "POST", Path: "/api/stages", Tag: "Stages", Summary: "Create a stage value", Auth: true, Request: models.CreateStageValueRequest{}, Response: models.StageValueResponse{}},
+	{Method: "GET", Path: "/api/stages", Tag: "Stages", Summary: "List stage values", Auth: true, Response: models.StageValueResponse{}},
+	{Method: "GET", Path: "/api/stages/:id", Tag: "Stages", Summary: "Get a stage value", Auth: true, Response: models.StageValueResponse{}},
+	{Method: "PUT", Path: "/api/stages/:id", Tag: "Stages", Summary: "Update a stage value", Auth: true, Request: models.UpdateStageValueRequest{}, Response: models.StageValueResponse{}},
+	{Method: "DELETE", Path: "/api/stages/:id", Tag: "Stages", Summary: "Delete a stage value", Auth: true, Response: models.StageValueResponse{}},
+	{Method: "GET", Path: "/api/custom-fields", Tag: "Stages", Summary: "List the user's contact custom fields", Auth: true, Response: models.CustomFieldResponse{}},
+	{Method: "POST", Path: "/api/custom-fields", Tag: "Stages", Summary: "Define a contact custom field", Auth: true, Request: models.CreateCustomFieldRequest{}, Response: models.CustomFieldResponse{}, Description: "field_type is text, number, date, boolean or select (with options); text fields can set a pattern. Stage nodes capture values into custom_fields under name, send_message text can use {{name}}, conditions edges can test it with the field condition (e.g. saiz_baju=XL), and conversation lists, CSV exports and /api/analytics/fields/:name accept it as a column."},
+	{Method: "PUT", Path: "/api/custom-fields/:id", Tag: "Stages", Summary: "Update a contact custom field's label, options or pattern", Auth: true, Request: models.UpdateCustomFieldRequest{}, Response: models.CustomFieldResponse{}},
+	{Method: "DELETE", Path: "/api/custom-fields/:id", Tag: "Stages", Summary: "Delete a contact custom field", Auth: true, Response: models.CustomFieldResponse{}, Description: "Values already captured stay in each conversation's custom_fields."},
+
+	// Packages and orders
+	{Method: "POST", Path: "/api/packages", Tag: "Billing", Summary: "Create a package", Auth: true, Request: models.CreatePackageRequest{}, Response: models.PackageResponse{}},
+	{Method: "GET", Path: "/api/packages", Tag: "Billing", Summary: "List packages", Response: models.PackageResponse{}},
+	{Method: "GET", Path: "/api/packages/:id", Tag: "Billing", Summary: "Get a package", Response: models.PackageResponse{}},
+	{Method: "PUT", Path: "/api/packages/:id", Tag: "Billing", Summary: "Update a package", Auth: true, Request: models.UpdatePackageRequest{}, Response: models.PackageResponse{}},
+	{Method: "DELETE", Path: "/api/packages/:id", Tag: "Billing", Summary: "Delete a package", Auth: true, Response: models.PackageResponse{}},
+	{Method: "POST", Path: "/api/orders", Tag: "Billing", Summary: "Create an order and payment bill", Auth: true, Request: models.CreateOrderRequest{}, Response: models.OrderResponse{}},
+	{Method: "GET", Path: "/api/orders", Tag: "Billing", Summary: "List the user's orders", Auth: true},
+	{Method: "GET", Path: "/api/orders/all", Tag: "Billing", Summary: "List all orders (admin)", Auth: true},
+	{Method: "GET", Path: "/api/orders/:id", Tag: "Billing", Summary: "Get an order", Auth: true, Response: models.OrderResponse{}},
+	{Method: "POST", Path: "/api/orders/billplz/callback", Tag: "Billing", Summary: "Billplz payment callback", Request: models.BillplzCallbackPayload{}},
+
+	// Analytics
+	{Method: "GET", Path: "/api/dashboard/combined", Tag: "Analytics", Summary: "Combined Chatbot AI and WhatsApp Bot data", Auth: true},
+	{Method: "GET", Path: "/api/analytics/dashboard", Tag: "Analytics", Summary: "Dashboard metrics", Auth: true, Query: []string{"device_id"}, Response: models.AnalyticsResponse{}},
+	{Method: "GET", Path: "/api/events/stream", Tag: "Analytics", Summary: "Live dashboard events (Server-Sent Events)", Auth: true, Query: []string{"device_id", "token"}, Description: "A text/event-stream of the user's events as they happen, each as event: <name> with data {id, event, id_device, created_at, data}: conversation.created, message.received, message.sent, stage.changed, flow.completed, order.paid and emit_event events. device_id limits it to one of the user's devices. Browsers' EventSource can't set headers, so the JWT may be passed as token instead of Authorization. Idle streams get a comment every 25s. At most 10 streams per user (the oldest is closed); a stream that falls 64 events behind is closed, and the client should reconnect and reload."},
+	{Method: "GET", Path: "/api/analytics/conversations", Tag: "Analytics", Summary: "Conversation metrics", Auth: true, Query: []string{"device_id"}, Response: models.ConversationAnalyticsResponse{}},
+	{Method: "GET", Path: "/api/analytics/flows/:flowId", Tag: "Analytics", Summary: "Flow metrics", Auth: true, Response: models.FlowAnalyticsResponse{}, Description: "node_metrics are built from the flow execution logs: per node the runs (visit_count), failed runs (error_count), average processing time in milliseconds, and the abandoned conversations whose last executed node it was (abandon_count), also as a percentage of the conversations that reached the node (drop_off_rate)."},
+	{Method: "POST", Path: "/api/analytics/export", Tag: "Analytics", Summary: "Export analytics", Auth: true, Request: models.ExportRequest{}, Response: models.ExportResponse{}},
+	{Method: "GET", Path: "/api/analytics/csat", Tag: "Analytics", Summary: "CSAT survey analytics", Auth: true, Query: []string{"device_id", "flow_id"}, Response: models.CSATAnalyticsResponse{}},
+	{Method: "GET", Path: "/api/analytics/costs/export", Tag: "Analytics", Summary: "Export acquisition cost per lead", Auth: true, Query: []string{"device_id", "format"}, Response: models.CostExportResponse{}, Description: "CSV by default; format=json returns the JSON body."},
+	{Method: "GET", Path: "/api/analytics/ai-usage", Tag: "Analytics", Summary: "AI token usage and cost", Auth: true, Query: []string{"device_id"}, Response: models.AIUsageResponse{}, Description: "Every AI call (flow replies, AI API completions and reply suggestions) is stored with its tokens and estimated cost: the provider's charge when it reports one, else the model's price (unpriced_calls counts models without a known price). Totals cover the last 30 days by device, model, purpose and day (UTC). spent_today and spent_month cover all of the user's devices and are checked against the budget: alerts lists a warning once spend passes alert_percent of a limit and exceeded once it passes the limit."},
+	{Method: "PUT", Path: "/api/analytics/ai-usage/budget", Tag: "Analytics", Summary: "Set the AI spend budget", Auth: true, Request: models.UpdateAIBudgetRequest{}, Response: models.AIBudgetResponse{}, Description: "daily_limit and monthly_limit are USD across all of the user's devices; 0 removes a limit. alert_percent (default 80) is when the warning alert starts."},
+	{Method: "GET", Path: "/api/analytics/latency", Tag: "Analytics", Summary: "First-response and reply latency (p50/p95)", Auth: true, Query: []string{"device_id", "flow_id"}, Response: models.LatencyAnalyticsResponse{}, Description: "first_bot_response is the bot's reply to a prospect's first message on the device; first_human_response is the first human reply after each handoff from the bot."},
+	{Method: "GET", Path: "/api/analytics/fields/:name", Tag: "Analytics", Summary: "Conversation counts per value of a field", Auth: true, Query: []string{"device_id"}, Response: models.FieldAnalyticsResponse{}, Description: "Groups the last 30 days of conversations by a conversation column or custom field; empty counts conversations with no value yet."},
+	{Method: "GET", Path: "/api/analytics/sla", Tag: "Analytics", Summary: "Stage SLA breach counts", Auth: true, Query: []string{"device_id"}, Response: models.SLAAnalyticsResponse{}, Description: "Breaches of the sla_minutes set on stage values, by stage, device and action (nudge or notify)."},
+	{Method: "GET", Path: "/api/analytics/links", Tag: "Analytics", Summary: "Tracked link click-through per message node", Auth: true, Query: []string{"device_id", "flow_id"}, Response: models.LinkAnalyticsResponse{}, Description: "URLs in send_message nodes are wrapped in short links on the device's tracking_domain (or LINK_TRACKING_BASE_URL). sent counts wrapped links, clicked counts links opened at least once, clicks counts every open."},
+	{Method: "GET", Path: "/api/analytics/ab-splits", Tag: "Analytics", Summary: "Compare A/B split variants by stage reached", Auth: true, Query: []string{"device_id", "flow_id"}, Response: models.ABSplitAnalyticsResponse{}, Description: "An ab_split flow node sends each prospect down one of its connections, weighted by the percentage in each connection's conditionValue (even when none has one) and picked from a hash of the phone number, so a prospect always gets the same variant. Each variant (the node its branch leads to) lists the prospects assigned in the last 30 days by their current stage (none = no stage yet), as counts and as a percentage of the variant."},
+	{Method: "GET", Path: "/api/analytics/funnel", Tag: "Analytics", Summary: "Stage funnel of a flow", Auth: true, Query: []string{"deviceId", "flowId"}, Response: models.StageFunnelResponse{}, Description: "Orders the stages the flow's stage nodes set as a walk from its entry reaches them. For conversations that entered a stage of the flow in the last 30 days, each step counts those that reached the stage or a later one (reached), those in it now (current), the percentage of them that never reached the next stage (drop_off_percent) and the median seconds spent in the stage by those that left it. Stages the flow no longer sets are counted under unlisted. Stage visits are recorded from when the stage history migration runs."},
+
+	// Campaigns
+	{Method: "POST", Path: "/api/campaigns/recycle", Tag: "Campaigns", Summary: "Create a campaign from abandoned conversations", Auth: true, Query: []string{"format"}, Request: models.RecycleProspectsRequest{}, Response: models.RecycleProspectsResponse{}, Description: "Blacklisted, opted-out and recently contacted numbers are excluded. With dry_run the selection is returned (format=csv downloads it) and no campaign is created."},
+	{Method: "POST", Path: "/api/campaigns", Tag: "Campaigns", Summary: "Create a draft campaign from a CSV or a saved segment", Auth: true, Request: models.CreateCampaignRequest{}, Response: models.CampaignResponse{}, Description: "Send JSON with csv as text, or a multipart form with the same fields and the CSV as file. The CSV needs a header row with a phone column (phone, phone_number, prospect_num, number or nombor); name (or prospect_name, nama) and any other columns are read per recipient and can be used as {{column}} in message or as flow variables. segment_id takes the device's ai_whatsapp and wasapbot conversations matching the segment instead. Repeated, blacklisted, opted-out and unconsented numbers are left out and counted in excluded. rate_per_minute defaults to 20, at most 60."},
+	{Method: "GET", Path: "/api/campaigns", Tag: "Campaigns", Summary: "List the user's campaigns", Auth: true, Response: models.CampaignResponse{}},
+	{Method: "GET", Path: "/api/campaigns/:id", Tag: "Campaigns", Summary: "Get a campaign with its progress", Auth: true, Response: models.CampaignResponse{}, Description: "stats counts the recipients that are pending, sending, sent, failed and skipped."},
+	{Method: "GET", Path: "/api/campaigns/:id/recipients", Tag: "Campaigns", Summary: "List a campaign's recipients with their status", Auth: true, Query: []string{"status"}, Response: models.CampaignResponse{}, Description: "error explains failed and skipped recipients: the send error, blacklist, opt_out, or cancelled."},
+	{Method: "POST", Path: "/api/campaigns/:id/start", Tag: "Campaigns", Summary: "Start sending a draft campaign", Auth: true, Response: models.CampaignResponse{}, Description: "Recipients are sent to in order at the campaign's rate_per_minute: the message with its placeholders filled, or the flow started for them. Numbers blacklisted or opted out since the campaign was created are skipped. The campaign completes when no recipient is pending."},
+	{Method: "POST", Path: "/api/campaigns/:id/pause", Tag: "Campaigns", Summary: "Pause a running campaign", Auth: true, Response: models.CampaignResponse{}, Description: "Takes effect after the message being sent."},
+	{Method: "POST", Path: "/api/campaigns/:id/resume", Tag: "Campaigns", Summary: "Resume a paused campaign", Auth: true, Response: models.CampaignResponse{}},
+	{Method: "POST", Path: "/api/campaigns/:id/cancel", Tag: "Campaigns", Summary: "Cancel a campaign", Auth: true, Response: models.CampaignResponse{}, Description: "Recipients not reached yet are marked skipped. A cancelled campaign cannot be restarted."},
+	{Method: "GET", Path: "/api/segments", Tag: "Campaigns", Summary: "List saved segments", Auth: true, Response: models.SegmentResponse{}},
+	{Method: "POST", Path: "/api/segments", Tag: "Campaigns", Summary: "Save a segment of conversations for campaigns", Auth: true, Request: models.CreateSegmentRequest{}, Response: models.SegmentResponse{}, Description: "Matches conversations by stage, execution_status, niche and creation time; empty fields match everything. A segment is resolved against the campaign's device when the campaign is created."},
+	{Method: "DELETE", Path: "/api/segments/:id", Tag: "Campaigns", Summary: "Delete a saved segment", Auth: true, Response: models.SegmentResponse{}},
+	{Method: "GET", Path: "/api/blacklist", Tag: "Campaigns", Summary: "List blacklisted and opted-out numbers", Auth: true, Response: models.BlacklistResponse{}},
+	{Method: "POST", Path: "/api/blacklist", Tag: "Campaigns", Summary: "Blacklist or opt out a number", Auth: true, Request: models.AddBlacklistRequest{}, Response: models.BlacklistResponse{}},
+	{Method: "DELETE", Path: "/api/blacklist/:phone", Tag: "Campaigns", Summary: "Remove a number from the blacklist", Auth: true, Response: models.BlacklistResponse{}},
+	{Method: "GET", Path: "/api/consent/export", Tag: "Campaigns", Summary: "Export the marketing consent registry", Auth: true, Query: []string{"device_id", "format"}, Response: models.ConsentExportResponse{}, Description: "CSV by default; format=json returns the JSON body."},
+
+	// AI
+	{Method: "POST", Path: "/api/ai/completion", Tag: "AI", Summary: "Generate an AI completion", Auth: true, Request: models.AICompletionRequest{}, Response: models.AICompletionResponse{}},
+	{Method: "POST", Path: "/api/ai/chat", Tag: "AI", Summary: "Simple AI chat", Auth: true, Request: models.ChatRequest{}, Response: models.ChatResponse{}},
+	{Method: "POST", Path: "/api/ai/test", Tag: "AI", Summary: "Test the AI provider connection", Auth: true},
+	{Method: "GET", Path: "/api/prompt-templates", Tag: "AI", Summary: "List the user's AI prompt templates", Auth: true, Response: models.PromptTemplateResponse{}, Description: "builtin holds the content of the built-in default template, a starting point for custom ones."},
+	{Method: "POST", Path: "/api/prompt-templates", Tag: "AI", Summary: "Create an AI prompt template", Auth: true, Request: models.CreatePromptTemplateRequest{}, Response: models.PromptTemplateResponse{}, Description: "content is the ai_prompt system prompt with {{variable}} placeholders: {{prompt}} (required) is the node's prompt, {{persona}} the device persona section, and any conversation field, custom field or session variable such as {{stage}} or {{prospect_name}}. An ai_prompt node uses it by setting prompt_template to its name; devices set default_prompt_template for nodes that name none."},
+	{Method: "GET", Path: "/api/prompt-templates/:id", Tag: "AI", Summary: "Get an AI prompt template", Auth: true, Response: models.PromptTemplateResponse{}},
+	{Method: "PUT", Path: "/api/prompt-templates/:id", Tag: "AI", Summary: "Update an AI prompt template's description or content", Auth: true, Request: models.UpdatePromptTemplateRequest{}, Response: models.PromptTemplateResponse{}},
+	{Method: "DELETE", Path: "/api/prompt-templates/:id", Tag: "AI", Summary: "Delete an AI prompt template", Auth: true, Response: models.PromptTemplateResponse{}, Description: "Nodes and devices still naming it fall back to the built-in default."},
+	{Method: "GET", Path: "/api/message-templates", Tag: "Flows", Summary: "List the user's message templates", Auth: true, Response: models.MessageTemplateResponse{}, Description: "builtins are the templates every user has (detail_customer, detail_cod, detail_wages, detail_cash); variables is the catalog of placeholders templates can use, conversation columns and the user's custom fields."},
+	{Method: "POST", Path: "/api/message-templates", Tag: "Flows", Summary: "Create a message template", Auth: true, Request: models.CreateMessageTemplateRequest{}, Response: models.MessageTemplateResponse{}, Description: "content is the message with {{variable}} placeholders such as {{prospect_name}}, {{alamat}} or {{pakej}}; unknown variables become empty. A send_message node sends it by setting message_template to its name instead of text. A template named like a built-in one replaces it for the user's flows, including Whatsapp Bot nodes that still send the legacy DETAIL CUSTOMER, DETAIL COD, DETAIL WAGES or DETAIL CASH text."},
+	{Method: "GET", Path: "/api/message-templates/:id", Tag: "Flows", Summary: "Get a message template", Auth: true, Response: models.MessageTemplateResponse{}},
+	{Method: "PUT", Path: "/api/message-templates/:id", Tag: "Flows", Summary: "Update a message template's description or content", Auth: true, Request: models.UpdateMessageTemplateRequest{}, Response: models.MessageTemplateResponse{}, Description: "Flows send the new content from their next message; no flow needs saving again."},
+	{Method: "DELETE", Path: "/api/message-templates/:id", Tag: "Flows", Summary: "Delete a message template", Auth: true, Response: models.MessageTemplateResponse{}, Description: "Nodes still naming it send the built-in template of that name, or else their own text."},
+
+	{Method: "GET", Path: "/api/email-settings", Tag: "Auth", Summary: "Get the user's email settings", Auth: true, Response: models.EmailSettingsResponse{}, Description: "The SMTP password and SendGrid key are never returned. inbound_domain is where email replies go when a device turns on email_replies."},
+	{Method: "PUT", Path: "/api/email-settings", Tag: "Auth", Summary: "Save the user's email settings", Auth: true, Request: models.UpdateEmailSettingsRequest{}, Response: models.EmailSettingsResponse{}, Description: "provider is smtp (smtp_host, smtp_port default 587, 465 for implicit TLS, optional smtp_username and smtp_password) or sendgrid (sendgrid_api_key). Omitted secrets keep their stored value. send_email flow nodes send from this account: config to, subject and body take {{variable}} placeholders."},
+
+	// Webhooks
+	{Method: "POST", Path: "/api/webhook/:webhook_id", Tag: "Webhooks", Summary: "Receive a provider webhook for a device", Description: "Payload shape depends on the device's provider (waha, wablas, whacenter). Image, voice note, audio, video and document messages are processed with their caption (or a placeholder such as [voice note]); the attachment is stored in the conversation's last_media, matched by media conditions (e.g. media=image) and described to ai_prompt nodes. A shared location is processed as [location: name], matched by media=location and stored in session_data as location_latitude, location_longitude, location_name, location_address, location_url and location_shared_at."},
+	{Method: "POST", Path: "/api/webhook/whatsapp/:deviceId", Tag: "Webhooks", Summary: "Generic WhatsApp webhook"},
+	{Method: "POST", Path: "/api/webhook/waha/:deviceId", Tag: "Webhooks", Summary: "WAHA webhook", Request: models.WahaWebhookData{}},
+	{Method: "POST", Path: "/api/webhook/wablas/:deviceId", Tag: "Webhooks", Summary: "Wablas webhook"},
+	{Method: "POST", Path: "/api/webhook/whacenter/:deviceId", Tag: "Webhooks", Summary: "Whacenter webhook", Request: models.WhacenterWebhookData{}},
+	{Method: "POST", Path: "/api/email/inbound", Tag: "Webhooks", Summary: "Receive an email reply (SendGrid Inbound Parse)", Query: []string{"token"}, Request: models.InboundEmail{}, Description: "Point the inbound parse of INBOUND_EMAIL_DOMAIN here, with ?token=INBOUND_EMAIL_TOKEN when one is set. Replies to <webhook_id>+<conversation id>@domain, the Reply-To of send_email mail from devices with email_replies on, are appended to the conversation history as a User entry without the quoted text. Other mail is acknowledged and dropped."},
+	{Method: "POST", Path: "/api/webhook/start-flow", Tag: "Webhooks", Summary: "Start a flow for a prospect", Request: models.StartFlowRequest{}, Response: models.StartFlowResponse{}, Description: "Needs an API key; flows start on the key user's devices. flow_id must be a flow of device_id (default: the device's first flow). The flow runs from its first node up to its first pause, so its opening messages are sent right away."},
+	{Method: "GET", Path: "/l/:code", Tag: "Webhooks", Summary: "Open a tracked short link", Description: "Public. Records the click with its timestamp and redirects (302) to the original URL; 404 for unknown codes."},
+	{Method: "POST", Path: "/api/webchat/:webhook_id/messages", Tag: "Webhooks", Summary: "Send a website chat widget message", Request: models.WebChatMessageRequest{}, Response: models.WebChatSendResponse{}, Description: "Runs the device's flows for the visitor. Omit session_id on the first message and reuse the returned one."},
+	{Method: "GET", Path: "/api/webchat/:webhook_id/messages", Tag: "Webhooks", Summary: "Long-poll bot replies for a chat widget session", Query: []string{"session_id", "wait"}, Response: models.WebChatPollResponse{}},
+	{Method: "POST", Path: "/api/webhooks/telegram/:webhook_id", Tag: "Webhooks", Summary: "Receive a Telegram bot update", Description: "Private chat messages and inline button taps run the device's flows; group chats and other updates are acknowledged and dropped. Attachments are matched by media conditions without a download URL, since Telegram file links embed the bot token."},
+	{Method: "GET", Path: "/api/webhooks/messenger/:webhook_id", Tag: "Webhooks", Summary: "Meta webhook verification handshake", Query: []string{"hub.mode", "hub.verify_token", "hub.challenge"}, Description: "Returns hub.challenge as text when hub.verify_token is the device's messenger.verify_token, else 403. Use this URL as the callback URL of the Meta app's page and instagram webhooks, subscribed to messages and messaging_postbacks."},
+	{Method: "POST", Path: "/api/webhooks/messenger/:webhook_id", Tag: "Webhooks", Summary: "Receive Facebook page and Instagram messages", Description: "Each message of the delivery runs the device's flows, as prospect fb:<PSID> (channel messenger) or ig:<IGSID> (channel instagram). With messenger.app_secret set, deliveries without a matching X-Hub-Signature-256 are rejected with 403. Replies go through the page: buttons and lists as quick replies (taps pick their option), media as attachments followed by the caption, locations and contact cards as text."},
+	{Method: "POST", Path: "/api/debounce/process", Tag: "Webhooks", Summary: "Process debounced messages (called by the debouncer)"},
+	{Method: "POST", Path: "/api/debounce/window", Tag: "Webhooks", Summary: "Adaptive debounce window for a queued message (called by the debouncer)", Request: models.DebounceWindowRequest{}, Response: models.DebounceWindowResponse{}, Description: "window_ms grows from the device's debounce_min_ms (default 2000) to debounce_max_ms (default 15000) with the stronger of load (queue_depth 5..50) and burst (sender_recent 1..5 messages in the last 10s)."},
+	{Method: "GET", Path: "/api/event-webhooks", Tag: "Webhooks", Summary: "List the user's outbound event webhooks", Auth: true, Response: models.EventWebhookResponse{}, Description: "events lists the built-in events: conversation.created, stage.changed (changed_by flow, ai or agent), flow.completed and order.paid. Flows add their own with emit_event nodes."},
+	{Method: "POST", Path: "/api/event-webhooks", Tag: "Webhooks", Summary: "Add an outbound event webhook", Auth: true, Request: models.CreateEventWebhookRequest{}, Response: models.EventWebhookResponse{}, Description: "Each event is POSTed as JSON {id, event, id_device, created_at, data} with X-Webhook-Event, X-Webhook-Delivery and X-Webhook-Signature: sha256=<hex HMAC-SHA256 of the body with the secret>. The secret is generated when omitted and only returned here. events empty or [\"*\"] receives every event; id_device limits it to one device. A non-2xx answer or timeout is retried after retry_seconds (default 60), doubling each time, until max_attempts (default 5) attempts were made. At most 20 per user."},
+	{Method: "PUT", Path: "/api/event-webhooks/:id", Tag: "Webhooks", Summary: "Update an outbound event webhook", Auth: true, Request: models.UpdateEventWebhookRequest{}, Response: models.EventWebhookResponse{}, Description: "id_device \"\" sends events of every device again; is_active=false pauses deliveries."},
+	{Method: "DELETE", Path: "/api/event-webhooks/:id", Tag: "Webhooks", Summary: "Delete an outbound event webhook and its delivery log", Auth: true, Response: models.EventWebhookResponse{}},
+	{Method: "GET", Path: "/api/event-webhooks/:id/deliveries", Tag: "Webhooks", Summary: "Latest deliveries of an outbound event webhook", Auth: true, Response: models.EventWebhookResponse{}, Description: "The 50 newest deliveries with their payload, status (pending, delivered or failed), attempts, last HTTP status and error, and when a pending one is retried."},
+	{Method: "POST", Path: "/api/event-webhooks/:id/test", Tag: "Webhooks", Summary: "Send a test event to an outbound event webhook", Auth: true, Response: models.EventWebhookResponse{}, Description: "Sends webhook.test at once and returns the delivery; a failed test is retried like any other event."},
+
+	// Docs
+	{Method: "GET", Path: "/api/docs/openapi.json", Tag: "Docs", Summary: "This OpenAPI specification"},
+}
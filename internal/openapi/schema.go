@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3 schema object (the subset the builder emits)
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaRegistry turns Go types into schemas, registering named structs as components
+type schemaRegistry struct {
+	components map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: make(map[string]*Schema)}
+}
+
+// schemaFor returns the schema for a Go value's type
+func (r *schemaRegistry) schemaFor(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return r.schemaForType(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schemaForType(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *Schema
+	switch {
+	case t == timeType:
+		schema = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		// $ref siblings are ignored by OpenAPI 3.0, so nullability is not expressed for refs
+		return r.structRef(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			schema = &Schema{Type: "string", Format: "byte"}
+		} else {
+			schema = &Schema{Type: "array", Items: r.schemaForType(t.Elem())}
+		}
+	case t.Kind() == reflect.Map:
+		schema = &Schema{Type: "object", AdditionalProperties: r.schemaForType(t.Elem())}
+	case t.Kind() == reflect.Interface:
+		schema = &Schema{}
+	case t.Kind() == reflect.Bool:
+		schema = &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = &Schema{Type: "integer"}
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			schema.Format = "int64"
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = &Schema{Type: "number"}
+	default:
+		schema = &Schema{Type: "string"}
+	}
+
+	schema.Nullable = nullable
+	return schema
+}
+
+// structRef registers a struct as a component (anonymous structs are inlined)
+func (r *schemaRegistry) structRef(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" {
+		return r.structSchema(t)
+	}
+
+	if _, ok := r.components[name]; !ok {
+		// Reserve the name first so recursive types terminate
+		r.components[name] = &Schema{Type: "object"}
+		r.components[name] = r.structSchema(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema builds an object schema from exported fields and their json tags
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		omitempty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+
+		schema.Properties[name] = r.schemaForType(field.Type)
+
+		if !omitempty && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ABSplitRepository handles ab_split_assignments data operations
+type ABSplitRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewABSplitRepository creates a new A/B split repository
+func NewABSplitRepository(supabase *database.SupabaseClient) *ABSplitRepository {
+	return &ABSplitRepository{
+		supabase: supabase,
+	}
+}
+
+// GetAssignment retrieves a prospect's assignment at an ab_split node, or nil when they have none
+func (r *ABSplitRepository) GetAssignment(ctx context.Context, flowID, nodeID, idDevice, prospectNum string) (*models.ABSplitAssignment, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "ab_split_assignments", map[string]string{
+		"select":       "*",
+		"flow_id":      fmt.Sprintf("eq.%s", flowID),
+		"node_id":      fmt.Sprintf("eq.%s", nodeID),
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"limit":        "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ab split assignment: %w", err)
+	}
+
+	var assignments []models.ABSplitAssignment
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return nil, fmt.Errorf("failed to parse ab split assignment: %w", err)
+	}
+
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+
+	return &assignments[0], nil
+}
+
+// CreateAssignment records a prospect's assignment at an ab_split node
+func (r *ABSplitRepository) CreateAssignment(ctx context.Context, assignment *models.ABSplitAssignment) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "ab_split_assignments", assignment); err != nil {
+		return fmt.Errorf("failed to create ab split assignment: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateVariant moves an assignment to another variant, when the branch it had was removed
+func (r *ABSplitRepository) UpdateVariant(ctx context.Context, id, variant string) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "ab_split_assignments", map[string]string{
+		"id": id,
+	}, map[string]interface{}{"variant": variant}); err != nil {
+		return fmt.Errorf("failed to update ab split assignment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// accountExportBucket is the private storage bucket account export archives are kept in
+const accountExportBucket = "account-exports"
+
+// AccountExportRepository handles account_exports rows and their archives in storage
+type AccountExportRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewAccountExportRepository creates a new account export repository
+func NewAccountExportRepository(supabase *database.SupabaseClient) *AccountExportRepository {
+	return &AccountExportRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateExport stores a new account export
+func (r *AccountExportRepository) CreateExport(ctx context.Context, export *models.AccountExport) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "account_exports", export)
+	if err != nil {
+		return fmt.Errorf("failed to create account export: %w", err)
+	}
+
+	var exports []models.AccountExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return fmt.Errorf("failed to parse created account export: %w", err)
+	}
+
+	if len(exports) > 0 {
+		*export = exports[0]
+	}
+
+	return nil
+}
+
+// GetExportByID retrieves an account export by ID, or nil when it does not exist
+func (r *AccountExportRepository) GetExportByID(ctx context.Context, id string) (*models.AccountExport, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "account_exports", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account export: %w", err)
+	}
+
+	var exports []models.AccountExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return nil, fmt.Errorf("failed to parse account export: %w", err)
+	}
+
+	if len(exports) == 0 {
+		return nil, nil
+	}
+
+	return &exports[0], nil
+}
+
+// GetPendingExport retrieves the user's export still being built that started after since, or nil
+func (r *AccountExportRepository) GetPendingExport(ctx context.Context, userID string, since time.Time) (*models.AccountExport, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "account_exports", map[string]string{
+		"select":     "*",
+		"user_id":    fmt.Sprintf("eq.%s", userID),
+		"status":     fmt.Sprintf("eq.%s", models.AccountExportPending),
+		"created_at": fmt.Sprintf("gte.%s", since.UTC().Format(time.RFC3339)),
+		"order":      "created_at.desc",
+		"limit":      "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending account export: %w", err)
+	}
+
+	var exports []models.AccountExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return nil, fmt.Errorf("failed to parse account export: %w", err)
+	}
+
+	if len(exports) == 0 {
+		return nil, nil
+	}
+
+	return &exports[0], nil
+}
+
+// FinishExport records the outcome of an account export
+func (r *AccountExportRepository) FinishExport(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["completed_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "account_exports", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update account export: %w", err)
+	}
+
+	return nil
+}
+
+// UploadArchive stores an export archive and returns its object path
+func (r *AccountExportRepository) UploadArchive(ctx context.Context, userID, exportID string, archive []byte) (string, error) {
+	path := fmt.Sprintf("%s/%s.zip", userID, exportID)
+	if err := r.supabase.UploadObject(ctx, accountExportBucket, path, "application/zip", archive); err != nil {
+		return "", fmt.Errorf("failed to upload account export archive: %w", err)
+	}
+	return path, nil
+}
+
+// SignedArchiveURL returns a download link to an export archive that expires after expiresIn
+func (r *AccountExportRepository) SignedArchiveURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	url, err := r.supabase.CreateSignedURL(ctx, accountExportBucket, path, expiresIn)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign account export download: %w", err)
+	}
+	return url, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AIUsageRepository handles ai_usage and ai_budgets data operations
+type AIUsageRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewAIUsageRepository creates a new AI usage repository
+func NewAIUsageRepository(supabase *database.SupabaseClient) *AIUsageRepository {
+	return &AIUsageRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordUsage stores one AI call's usage
+func (r *AIUsageRepository) RecordUsage(ctx context.Context, usage *models.AIUsage) error {
+	usage.ID = uuid.New().String()
+	if _, err := r.supabase.InsertAsAdmin(ctx, "ai_usage", usage); err != nil {
+		return fmt.Errorf("failed to record AI usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsageByDevices retrieves the AI usage of the given devices since a time, oldest first
+func (r *AIUsageRepository) GetUsageByDevices(ctx context.Context, deviceIDs []string, since time.Time) ([]models.AIUsage, error) {
+	if len(deviceIDs) == 0 {
+		return []models.AIUsage{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_usage", map[string]string{
+		"select":     "*",
+		"id_device":  fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"created_at": fmt.Sprintf("gte.%s", since.UTC().Format(time.RFC3339)),
+		"order":      "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI usage: %w", err)
+	}
+
+	var usage []models.AIUsage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("failed to parse AI usage: %w", err)
+	}
+	return usage, nil
+}
+
+// GetBudget retrieves a user's AI budget, nil when none is set
+func (r *AIUsageRepository) GetBudget(ctx context.Context, userID string) (*models.AIBudget, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_budgets", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI budget: %w", err)
+	}
+
+	var budgets []models.AIBudget
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		return nil, fmt.Errorf("failed to parse AI budget: %w", err)
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	return &budgets[0], nil
+}
+
+// SaveBudget creates or replaces a user's AI budget
+func (r *AIUsageRepository) SaveBudget(ctx context.Context, budget *models.AIBudget) error {
+	existing, err := r.GetBudget(ctx, budget.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	budget.UpdatedAt = &now
+	if existing == nil {
+		if _, err := r.supabase.InsertAsAdmin(ctx, "ai_budgets", budget); err != nil {
+			return fmt.Errorf("failed to create AI budget: %w", err)
+		}
+		return nil
+	}
+
+	// Explicit nulls, so removed limits are cleared
+	_, err = r.supabase.UpdateAsAdmin(ctx, "ai_budgets", map[string]string{"user_id": budget.UserID}, map[string]interface{}{
+		"daily_limit":   budget.DailyLimit,
+		"monthly_limit": budget.MonthlyLimit,
+		"alert_percent": budget.AlertPercent,
+		"updated_at":    now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update AI budget: %w", err)
+	}
+	return nil
+}
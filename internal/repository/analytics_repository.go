@@ -6,6 +6,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 )
@@ -35,7 +37,7 @@ func (r *AnalyticsRepository) GetConversationMetrics(ctx context.Context, device
 		params["created_at"] = fmt.Sprintf("lte.%s", timeRange.EndDate.Format(time.RFC3339))
 	}
 
-	data, err := r.db.QueryAsAdmin("ai_whatsapp", params)
+	data, err := r.db.QueryAsAdmin(ctx, "ai_whatsapp", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query conversations: %w", err)
 	}
@@ -121,7 +123,7 @@ func (r *AnalyticsRepository) GetConversationMetrics(ctx context.Context, device
 // GetFlowMetrics retrieves flow-specific analytics
 func (r *AnalyticsRepository) GetFlowMetrics(ctx context.Context, flowID string, timeRange *models.TimeRangeFilter) (*models.FlowMetrics, error) {
 	// Get flow details
-	flowData, err := r.db.QueryAsAdmin("chatbot_flows", map[string]string{
+	flowData, err := r.db.QueryAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"select": "*",
 		"id":     fmt.Sprintf("eq.%s", flowID),
 	})
@@ -146,7 +148,7 @@ func (r *AnalyticsRepository) GetFlowMetrics(ctx context.Context, flowID string,
 		params["created_at"] = fmt.Sprintf("gte.%s", timeRange.StartDate.Format(time.RFC3339))
 	}
 
-	convData, err := r.db.QueryAsAdmin("ai_whatsapp", params)
+	convData, err := r.db.QueryAsAdmin(ctx, "ai_whatsapp", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query conversations: %w", err)
 	}
@@ -192,13 +194,125 @@ func (r *AnalyticsRepository) GetFlowMetrics(ctx context.Context, flowID string,
 		metrics.AverageCompletionTime = totalCompletionTime / float64(metrics.CompletedExecutions)
 	}
 
+	nodeMetrics, err := r.getNodeMetrics(ctx, flowID, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	metrics.NodeMetrics = nodeMetrics
+
 	return metrics, nil
 }
 
+// getNodeMetrics aggregates a flow's execution logs per node: visits, failures, average processing
+// time, and how many abandoned conversations (in either table) stopped at the node
+func (r *AnalyticsRepository) getNodeMetrics(ctx context.Context, flowID string, timeRange *models.TimeRangeFilter) (map[string]models.NodeMetric, error) {
+	params := map[string]string{
+		"select":  "source,conversation_id,node_id,node_type,event,outcome,duration_ms,created_at",
+		"flow_id": fmt.Sprintf("eq.%s", flowID),
+		"node_id": "not.is.null",
+		"order":   "created_at.asc",
+	}
+
+	if timeRange != nil {
+		params["created_at"] = fmt.Sprintf("gte.%s", timeRange.StartDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "flow_execution_logs", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flow execution logs: %w", err)
+	}
+
+	var entries []models.FlowExecutionLog
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse flow execution logs: %w", err)
+	}
+
+	// Conversations of the flow that were abandoned, by table
+	abandoned := make(map[string]bool)
+	for _, table := range []string{"ai_whatsapp", "wasapbot"} {
+		data, err := r.db.QueryAsAdmin(ctx, table, map[string]string{
+			"select":           "id_prospect",
+			"flow_id":          fmt.Sprintf("eq.%s", flowID),
+			"execution_status": fmt.Sprintf("eq.%s", models.ConversationStateAbandoned),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query abandoned conversations from %s: %w", table, err)
+		}
+
+		var rows []struct {
+			IDProspect int `json:"id_prospect"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse abandoned conversations from %s: %w", table, err)
+		}
+		for _, row := range rows {
+			abandoned[fmt.Sprintf("%s/%d", table, row.IDProspect)] = true
+		}
+	}
+
+	return nodeMetricsFromLogs(entries, abandoned), nil
+}
+
+// nodeMetricsFromLogs aggregates execution log entries, oldest first, per node. abandoned holds the
+// "source/conversation_id" of abandoned conversations; each counts against the last node it ran.
+func nodeMetricsFromLogs(entries []models.FlowExecutionLog, abandoned map[string]bool) map[string]models.NodeMetric {
+	metrics := make(map[string]models.NodeMetric)
+	totalMs := make(map[string]int64)
+	reached := make(map[string]map[string]bool)
+	lastNode := make(map[string]string)
+
+	for _, entry := range entries {
+		if entry.NodeID == "" {
+			continue
+		}
+		conversation := entry.Source + "/" + entry.ConversationID
+
+		metric := metrics[entry.NodeID]
+		metric.NodeID = entry.NodeID
+		if entry.NodeType != "" {
+			metric.NodeType = entry.NodeType
+		}
+		if entry.Event == models.FlowExecutionEventNode {
+			metric.VisitCount++
+			totalMs[entry.NodeID] += entry.DurationMs
+		}
+		if entry.Outcome == models.FlowOutcomeFailed {
+			metric.ErrorCount++
+		}
+		metrics[entry.NodeID] = metric
+
+		if reached[entry.NodeID] == nil {
+			reached[entry.NodeID] = make(map[string]bool)
+		}
+		reached[entry.NodeID][conversation] = true
+		lastNode[conversation] = entry.NodeID
+	}
+
+	for conversation, nodeID := range lastNode {
+		if abandoned[conversation] {
+			metric := metrics[nodeID]
+			metric.AbandonCount++
+			metrics[nodeID] = metric
+		}
+	}
+
+	for nodeID, metric := range metrics {
+		if metric.VisitCount > 0 {
+			metric.AverageTime = float64(totalMs[nodeID]) / float64(metric.VisitCount)
+		}
+		if conversations := len(reached[nodeID]); conversations > 0 {
+			metric.DropOffRate = math.Round(float64(metric.AbandonCount)/float64(conversations)*10000) / 100
+		}
+		metrics[nodeID] = metric
+	}
+
+	return metrics
+}
+
 // GetDeviceMetrics retrieves device-specific analytics
 func (r *AnalyticsRepository) GetDeviceMetrics(ctx context.Context, userID string) ([]models.DeviceMetrics, error) {
 	// Get user's devices
-	devicesData, err := r.db.QueryAsAdmin("device_setting", map[string]string{
+	devicesData, err := r.db.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":  "*",
 		"user_id": fmt.Sprintf("eq.%s", userID),
 	})
@@ -220,7 +334,7 @@ func (r *AnalyticsRepository) GetDeviceMetrics(ctx context.Context, userID strin
 		}
 
 		// Get conversations for this device
-		convData, err := r.db.QueryAsAdmin("ai_whatsapp", map[string]string{
+		convData, err := r.db.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
 			"select":    "*",
 			"id_device": fmt.Sprintf("eq.%s", deviceID),
 		})
@@ -283,7 +397,7 @@ func (r *AnalyticsRepository) GetMessageMetrics(ctx context.Context, deviceID st
 		params["created_at"] = fmt.Sprintf("gte.%s", timeRange.StartDate.Format(time.RFC3339))
 	}
 
-	data, err := r.db.QueryAsAdmin("ai_whatsapp", params)
+	data, err := r.db.QueryAsAdmin(ctx, "ai_whatsapp", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query conversations: %w", err)
 	}
@@ -326,3 +440,566 @@ func (r *AnalyticsRepository) GetMessageMetrics(ctx context.Context, deviceID st
 
 	return metrics, nil
 }
+
+// csatRow is the subset of conversation columns needed for CSAT analytics
+type csatRow struct {
+	IDDevice  string  `json:"id_device"`
+	FlowID    *string `json:"flow_id"`
+	Marketer  *string `json:"marketer"`
+	CSATScore *int    `json:"csat_score"`
+}
+
+// GetCSATMetrics aggregates CSAT survey scores across ai_whatsapp and wasapbot conversations
+func (r *AnalyticsRepository) GetCSATMetrics(ctx context.Context, deviceIDs []string, flowID string, timeRange *models.TimeRangeFilter) (*models.CSATMetrics, error) {
+	metrics := &models.CSATMetrics{
+		Distribution: make(map[int]int),
+		ByFlow:       make(map[string]models.CSATBreakdown),
+		ByDevice:     make(map[string]models.CSATBreakdown),
+		ByAgent:      make(map[string]models.CSATBreakdown),
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	params := map[string]string{
+		"select":     "id_device,flow_id,marketer,csat_score",
+		"csat_score": "not.is.null",
+		"id_device":  fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+	}
+
+	if flowID != "" {
+		params["flow_id"] = fmt.Sprintf("eq.%s", flowID)
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(csat_at.gte.%s,csat_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	var rows []csatRow
+	for _, table := range []string{"ai_whatsapp", "wasapbot"} {
+		data, err := r.db.QueryAsAdmin(ctx, table, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query csat scores from %s: %w", table, err)
+		}
+
+		var tableRows []csatRow
+		if err := json.Unmarshal(data, &tableRows); err != nil {
+			return nil, fmt.Errorf("failed to parse csat scores from %s: %w", table, err)
+		}
+		rows = append(rows, tableRows...)
+	}
+
+	// Running totals per group
+	type total struct {
+		count int
+		sum   int
+	}
+	byFlow := make(map[string]*total)
+	byDevice := make(map[string]*total)
+	byAgent := make(map[string]*total)
+
+	add := func(group map[string]*total, key string, score int) {
+		if group[key] == nil {
+			group[key] = &total{}
+		}
+		group[key].count++
+		group[key].sum += score
+	}
+
+	scoreSum := 0
+	satisfied := 0
+	for _, row := range rows {
+		if row.CSATScore == nil {
+			continue
+		}
+		score := *row.CSATScore
+
+		metrics.TotalResponses++
+		metrics.Distribution[score]++
+		scoreSum += score
+		if score >= 4 {
+			satisfied++
+		}
+
+		flowKey := "unknown"
+		if row.FlowID != nil && *row.FlowID != "" {
+			flowKey = *row.FlowID
+		}
+		agentKey := "bot"
+		if row.Marketer != nil && *row.Marketer != "" {
+			agentKey = *row.Marketer
+		}
+
+		add(byFlow, flowKey, score)
+		add(byDevice, row.IDDevice, score)
+		add(byAgent, agentKey, score)
+	}
+
+	if metrics.TotalResponses > 0 {
+		metrics.AverageScore = float64(scoreSum) / float64(metrics.TotalResponses)
+		metrics.SatisfiedRate = (float64(satisfied) / float64(metrics.TotalResponses)) * 100
+	}
+
+	toBreakdown := func(group map[string]*total, out map[string]models.CSATBreakdown) {
+		for key, t := range group {
+			out[key] = models.CSATBreakdown{
+				Responses:    t.count,
+				AverageScore: float64(t.sum) / float64(t.count),
+			}
+		}
+	}
+	toBreakdown(byFlow, metrics.ByFlow)
+	toBreakdown(byDevice, metrics.ByDevice)
+	toBreakdown(byAgent, metrics.ByAgent)
+
+	return metrics, nil
+}
+
+// GetLatencyMetrics aggregates closed response_latency cycles into p50/p95 stats
+func (r *AnalyticsRepository) GetLatencyMetrics(ctx context.Context, deviceIDs []string, flowID string, timeRange *models.TimeRangeFilter) (*models.LatencyMetrics, error) {
+	metrics := &models.LatencyMetrics{
+		ByDevice: make(map[string]models.LatencyStats),
+		ByFlow:   make(map[string]models.LatencyStats),
+		ByAgent:  make(map[string]models.LatencyStats),
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	params := map[string]string{
+		"select":     "id_device,flow_id,is_first,first_after_handoff,responder,agent,latency_ms",
+		"replied_at": "not.is.null",
+		"id_device":  fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+	}
+
+	if flowID != "" {
+		params["flow_id"] = fmt.Sprintf("eq.%s", flowID)
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(inbound_at.gte.%s,inbound_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "response_latency", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query response latency: %w", err)
+	}
+
+	var rows []models.ResponseLatency
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse response latency: %w", err)
+	}
+
+	var firstBot, firstHuman, botReply, humanReply []int64
+	byDevice := make(map[string][]int64)
+	byFlow := make(map[string][]int64)
+	byAgent := make(map[string][]int64)
+
+	for _, row := range rows {
+		if row.LatencyMs == nil || row.Responder == nil {
+			continue
+		}
+		latency := *row.LatencyMs
+
+		agentKey := models.ResponderBot
+		if *row.Responder == models.ResponderHuman {
+			humanReply = append(humanReply, latency)
+			if row.FirstAfterHandoff {
+				firstHuman = append(firstHuman, latency)
+			}
+			agentKey = "unassigned"
+			if row.Agent != nil && *row.Agent != "" {
+				agentKey = *row.Agent
+			}
+		} else {
+			botReply = append(botReply, latency)
+			if row.IsFirst {
+				firstBot = append(firstBot, latency)
+			}
+		}
+
+		flowKey := "unknown"
+		if row.FlowID != nil && *row.FlowID != "" {
+			flowKey = *row.FlowID
+		}
+
+		byDevice[row.IDDevice] = append(byDevice[row.IDDevice], latency)
+		byFlow[flowKey] = append(byFlow[flowKey], latency)
+		byAgent[agentKey] = append(byAgent[agentKey], latency)
+	}
+
+	metrics.FirstBotResponse = latencyStats(firstBot)
+	metrics.FirstHumanResponse = latencyStats(firstHuman)
+	metrics.BotReply = latencyStats(botReply)
+	metrics.HumanReply = latencyStats(humanReply)
+
+	for key, values := range byDevice {
+		metrics.ByDevice[key] = latencyStats(values)
+	}
+	for key, values := range byFlow {
+		metrics.ByFlow[key] = latencyStats(values)
+	}
+	for key, values := range byAgent {
+		metrics.ByAgent[key] = latencyStats(values)
+	}
+
+	return metrics, nil
+}
+
+// latencyStats computes count, average and nearest-rank p50/p95 for a set of latencies
+func latencyStats(values []int64) models.LatencyStats {
+	stats := models.LatencyStats{Count: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+
+	percentile := func(p float64) int64 {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	}
+
+	stats.AvgMs = sum / int64(len(sorted))
+	stats.P50Ms = percentile(0.50)
+	stats.P95Ms = percentile(0.95)
+
+	return stats
+}
+
+// GetSLAMetrics counts stage SLA breaches per stage, device and action
+func (r *AnalyticsRepository) GetSLAMetrics(ctx context.Context, deviceIDs []string, timeRange *models.TimeRangeFilter) (*models.SLAMetrics, error) {
+	metrics := &models.SLAMetrics{
+		ByStage:  make(map[string]int),
+		ByDevice: make(map[string]int),
+		ByAction: make(map[string]int),
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	params := map[string]string{
+		"select":    "id_device,stage,action,handled",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(breached_at.gte.%s,breached_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "stage_sla_breaches", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stage SLA breaches: %w", err)
+	}
+
+	var rows []models.StageSLABreach
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse stage SLA breaches: %w", err)
+	}
+
+	for _, row := range rows {
+		metrics.TotalBreaches++
+		if !row.Handled {
+			metrics.Unhandled++
+		}
+		metrics.ByStage[row.Stage]++
+		metrics.ByDevice[row.IDDevice]++
+		metrics.ByAction[row.Action]++
+	}
+
+	return metrics, nil
+}
+
+// GetLinkMetrics aggregates tracked link sends and clicks per flow message node
+func (r *AnalyticsRepository) GetLinkMetrics(ctx context.Context, deviceIDs []string, flowID string, timeRange *models.TimeRangeFilter) (*models.LinkMetrics, error) {
+	metrics := &models.LinkMetrics{
+		ByNode: []models.LinkNodeStats{},
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	params := map[string]string{
+		"select":    "flow_id,node_id,click_count,last_clicked_at",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+	}
+
+	if flowID != "" {
+		params["flow_id"] = fmt.Sprintf("eq.%s", flowID)
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(created_at.gte.%s,created_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "tracked_links", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tracked links: %w", err)
+	}
+
+	var links []models.TrackedLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("failed to parse tracked links: %w", err)
+	}
+
+	byNode := make(map[string]*models.LinkNodeStats)
+	for _, link := range links {
+		key := link.FlowID + "/" + link.NodeID
+		stats, ok := byNode[key]
+		if !ok {
+			stats = &models.LinkNodeStats{FlowID: link.FlowID, NodeID: link.NodeID}
+			byNode[key] = stats
+		}
+
+		stats.Sent++
+		stats.Clicks += link.ClickCount
+		if link.ClickCount > 0 {
+			stats.Clicked++
+		}
+		if link.LastClickedAt != nil && (stats.LastClickedAt == nil || link.LastClickedAt.After(*stats.LastClickedAt)) {
+			stats.LastClickedAt = link.LastClickedAt
+		}
+	}
+
+	for _, stats := range byNode {
+		stats.ClickThroughRate = (float64(stats.Clicked) / float64(stats.Sent)) * 100
+		metrics.Sent += stats.Sent
+		metrics.Clicked += stats.Clicked
+		metrics.Clicks += stats.Clicks
+		metrics.ByNode = append(metrics.ByNode, *stats)
+	}
+
+	if metrics.Sent > 0 {
+		metrics.ClickThroughRate = (float64(metrics.Clicked) / float64(metrics.Sent)) * 100
+	}
+
+	sort.Slice(metrics.ByNode, func(i, j int) bool {
+		if metrics.ByNode[i].FlowID != metrics.ByNode[j].FlowID {
+			return metrics.ByNode[i].FlowID < metrics.ByNode[j].FlowID
+		}
+		return metrics.ByNode[i].NodeID < metrics.ByNode[j].NodeID
+	})
+
+	return metrics, nil
+}
+
+// GetFieldMetrics counts the conversations of both tables per value of field. customFields are the
+// owner's custom field names, read from custom_fields; a table without the field is skipped.
+func (r *AnalyticsRepository) GetFieldMetrics(ctx context.Context, deviceIDs []string, field string, customFields map[string]bool, timeRange *models.TimeRangeFilter) (*models.FieldMetrics, error) {
+	metrics := &models.FieldMetrics{
+		Field:   field,
+		Label:   field,
+		ByValue: make(map[string]int),
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	for _, table := range []string{"ai_whatsapp", "wasapbot"} {
+		expr, err := listColumnExpr(table, field, customFields)
+		if err != nil {
+			continue
+		}
+
+		params := map[string]string{
+			"select":    "value:" + expr,
+			"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		}
+
+		if timeRange != nil {
+			params["and"] = fmt.Sprintf("(created_at.gte.%s,created_at.lte.%s)",
+				timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+		}
+
+		data, err := r.db.QueryAsAdmin(ctx, table, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s field values: %w", table, err)
+		}
+
+		var rows []struct {
+			Value interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse %s field values: %w", table, err)
+		}
+
+		for _, row := range rows {
+			metrics.Total++
+			value := ""
+			if row.Value != nil {
+				value = strings.TrimSpace(fmt.Sprintf("%v", row.Value))
+			}
+			if value == "" {
+				metrics.Empty++
+				continue
+			}
+			metrics.ByValue[value]++
+		}
+	}
+
+	return metrics, nil
+}
+
+// abSplitStageChunk is how many conversations one stage lookup of the A/B split metrics reads
+const abSplitStageChunk = 200
+
+type abSplitStageRow struct {
+	IDProspect int     `json:"id_prospect"`
+	Stage      *string `json:"stage"`
+}
+
+// GetABSplitMetrics compares the variants of ab_split nodes by the current stage of the prospects
+// assigned to each, for assignments made in the time range
+func (r *AnalyticsRepository) GetABSplitMetrics(ctx context.Context, deviceIDs []string, flowID string, timeRange *models.TimeRangeFilter) (*models.ABSplitMetrics, error) {
+	metrics := &models.ABSplitMetrics{
+		Variants: []models.ABSplitVariantStats{},
+	}
+
+	if len(deviceIDs) == 0 {
+		return metrics, nil
+	}
+
+	params := map[string]string{
+		"select":    "flow_id,node_id,variant,source,conversation_id",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+	}
+
+	if flowID != "" {
+		params["flow_id"] = fmt.Sprintf("eq.%s", flowID)
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(created_at.gte.%s,created_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "ab_split_assignments", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ab split assignments: %w", err)
+	}
+
+	var assignments []models.ABSplitAssignment
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return nil, fmt.Errorf("failed to parse ab split assignments: %w", err)
+	}
+
+	// Current stage of every assigned conversation, by table
+	ids := make(map[string][]string)
+	for _, assignment := range assignments {
+		if assignment.ConversationID != "" {
+			ids[assignment.Source] = append(ids[assignment.Source], assignment.ConversationID)
+		}
+	}
+	stages := make(map[string]string)
+	for _, table := range []string{"ai_whatsapp", "wasapbot"} {
+		tableIDs := ids[table]
+		for start := 0; start < len(tableIDs); start += abSplitStageChunk {
+			end := min(start+abSplitStageChunk, len(tableIDs))
+			data, err := r.db.QueryAsAdmin(ctx, table, map[string]string{
+				"select":      "id_prospect,stage",
+				"id_prospect": fmt.Sprintf("in.(%s)", strings.Join(tableIDs[start:end], ",")),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to query stages from %s: %w", table, err)
+			}
+
+			var rows []abSplitStageRow
+			if err := json.Unmarshal(data, &rows); err != nil {
+				return nil, fmt.Errorf("failed to parse stages from %s: %w", table, err)
+			}
+			for _, row := range rows {
+				if row.Stage != nil && *row.Stage != "" {
+					stages[fmt.Sprintf("%s/%d", table, row.IDProspect)] = *row.Stage
+				}
+			}
+		}
+	}
+
+	byVariant := make(map[string]*models.ABSplitVariantStats)
+	for _, assignment := range assignments {
+		key := assignment.FlowID + "/" + assignment.NodeID + "/" + assignment.Variant
+		stats, ok := byVariant[key]
+		if !ok {
+			stats = &models.ABSplitVariantStats{
+				FlowID:  assignment.FlowID,
+				NodeID:  assignment.NodeID,
+				Variant: assignment.Variant,
+				ByStage: make(map[string]int),
+			}
+			byVariant[key] = stats
+		}
+
+		stage, ok := stages[assignment.Source+"/"+assignment.ConversationID]
+		if !ok {
+			stage = "none"
+		}
+		stats.Assigned++
+		stats.ByStage[stage]++
+		metrics.Assigned++
+	}
+
+	for _, stats := range byVariant {
+		stats.StageRates = make(map[string]float64, len(stats.ByStage))
+		for stage, count := range stats.ByStage {
+			stats.StageRates[stage] = math.Round(float64(count)/float64(stats.Assigned)*10000) / 100
+		}
+		metrics.Variants = append(metrics.Variants, *stats)
+	}
+
+	sort.Slice(metrics.Variants, func(i, j int) bool {
+		a, b := metrics.Variants[i], metrics.Variants[j]
+		if a.FlowID != b.FlowID {
+			return a.FlowID < b.FlowID
+		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Variant < b.Variant
+	})
+
+	return metrics, nil
+}
+
+// GetStageVisits returns the visits to stages of a flow that began in the time range, oldest first
+func (r *AnalyticsRepository) GetStageVisits(ctx context.Context, flowID string, timeRange *models.TimeRangeFilter) ([]models.StageVisit, error) {
+	params := map[string]string{
+		"select":  "*",
+		"flow_id": fmt.Sprintf("eq.%s", flowID),
+		"order":   "entered_at.asc",
+	}
+
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(entered_at.gte.%s,entered_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339), timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.db.QueryAsAdmin(ctx, "conversation_stage_history", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stage history: %w", err)
+	}
+
+	var visits []models.StageVisit
+	if err := json.Unmarshal(data, &visits); err != nil {
+		return nil, fmt.Errorf("failed to parse stage history: %w", err)
+	}
+	return visits, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// APIKeyRepository handles api_keys data operations
+type APIKeyRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(supabase *database.SupabaseClient) *APIKeyRepository {
+	return &APIKeyRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateKey stores a new API key
+func (r *APIKeyRepository) CreateKey(ctx context.Context, key *models.APIKey) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "api_keys", key)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	var keys []models.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("failed to parse created API key: %w", err)
+	}
+
+	if len(keys) > 0 {
+		*key = keys[0]
+	}
+
+	return nil
+}
+
+// GetKeysByUser retrieves a user's API keys, newest first
+func (r *APIKeyRepository) GetKeysByUser(ctx context.Context, userID string) ([]models.APIKey, error) {
+	return r.getKeys(ctx, map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.desc",
+	})
+}
+
+// GetKeyByID retrieves an API key by ID, or nil when it does not exist
+func (r *APIKeyRepository) GetKeyByID(ctx context.Context, id string) (*models.APIKey, error) {
+	keys, err := r.getKeys(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	return &keys[0], nil
+}
+
+// GetKeyByHash retrieves the API key with a SHA-256 hash, or nil when there is none
+func (r *APIKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	keys, err := r.getKeys(ctx, map[string]string{
+		"select":   "*",
+		"key_hash": fmt.Sprintf("eq.%s", keyHash),
+		"limit":    "1",
+	})
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	return &keys[0], nil
+}
+
+func (r *APIKeyRepository) getKeys(ctx context.Context, params map[string]string) ([]models.APIKey, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "api_keys", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	var keys []models.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// UpdateKey updates an API key
+func (r *APIKeyRepository) UpdateKey(ctx context.Context, id string, updates map[string]interface{}) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "api_keys", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BlacklistRepository handles contact_blacklist data operations
+type BlacklistRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewBlacklistRepository creates a new blacklist repository
+func NewBlacklistRepository(supabase *database.SupabaseClient) *BlacklistRepository {
+	return &BlacklistRepository{
+		supabase: supabase,
+	}
+}
+
+// AddEntry blacklists or opts out a number for a user
+func (r *BlacklistRepository) AddEntry(ctx context.Context, entry *models.BlacklistEntry) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "contact_blacklist", entry)
+	if err != nil {
+		return fmt.Errorf("failed to add blacklist entry: %w", err)
+	}
+
+	var entries []models.BlacklistEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse blacklist entry: %w", err)
+	}
+
+	if len(entries) > 0 {
+		*entry = entries[0]
+	}
+
+	return nil
+}
+
+// GetEntriesByUser retrieves all blacklisted and opted-out numbers for a user
+func (r *BlacklistRepository) GetEntriesByUser(ctx context.Context, userID string) ([]models.BlacklistEntry, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "contact_blacklist", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blacklist: %w", err)
+	}
+
+	var entries []models.BlacklistEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse blacklist: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteEntry removes a number from a user's blacklist
+func (r *BlacklistRepository) DeleteEntry(ctx context.Context, userID, phoneNumber string) error {
+	err := r.supabase.DeleteAsAdmin(ctx, "contact_blacklist", map[string]string{
+		"user_id":      userID,
+		"phone_number": phoneNumber,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete blacklist entry: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CampaignRepository handles campaign and campaign_recipients data operations
+type CampaignRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewCampaignRepository creates a new campaign repository
+func NewCampaignRepository(supabase *database.SupabaseClient) *CampaignRepository {
+	return &CampaignRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateCampaign creates a new campaign
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *models.Campaign) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "campaigns", campaign)
+	if err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	var campaigns []models.Campaign
+	if err := json.Unmarshal(data, &campaigns); err != nil {
+		return fmt.Errorf("failed to parse created campaign: %w", err)
+	}
+
+	if len(campaigns) > 0 {
+		*campaign = campaigns[0]
+	}
+
+	return nil
+}
+
+// GetCampaignsByUser retrieves a user's campaigns, newest first
+func (r *CampaignRepository) GetCampaignsByUser(ctx context.Context, userID string) ([]models.Campaign, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "campaigns", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns: %w", err)
+	}
+
+	var campaigns []models.Campaign
+	if err := json.Unmarshal(data, &campaigns); err != nil {
+		return nil, fmt.Errorf("failed to parse campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// AddRecipients inserts recipients for a campaign in one request
+func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []models.CampaignRecipient) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	if _, err := r.supabase.InsertAsAdmin(ctx, "campaign_recipients", recipients); err != nil {
+		return fmt.Errorf("failed to add campaign recipients: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecentlyMessagedNumbers returns numbers a campaign sent to on the given devices since a time
+func (r *CampaignRepository) GetRecentlyMessagedNumbers(ctx context.Context, deviceIDs []string, since time.Time) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return []string{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "campaign_recipients", map[string]string{
+		"select":              "prospect_num,campaigns!inner(id_device)",
+		"campaigns.id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"status":              fmt.Sprintf("eq.%s", models.RecipientStatusSent),
+		"sent_at":             fmt.Sprintf("gte.%s", since.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent campaign recipients: %w", err)
+	}
+
+	var rows []models.CampaignRecipient
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse campaign recipients: %w", err)
+	}
+
+	numbers := make([]string, 0, len(rows))
+	for _, row := range rows {
+		numbers = append(numbers, row.ProspectNum)
+	}
+
+	return numbers, nil
+}
+
+// abandonedConversationParams builds the PostgREST filter shared by ai_whatsapp and wasapbot:
+// created in [start, end] and either marked abandoned or, with stalledBefore, waiting/active with no update since then
+func abandonedConversationParams(deviceID string, start, end time.Time, stalledBefore *time.Time, flowID *string) map[string]string {
+	params := map[string]string{
+		"select":    "id_prospect,id_device,prospect_num,prospect_name,stage,flow_id,execution_status,updated_at",
+		"id_device": fmt.Sprintf("eq.%s", deviceID),
+		"and": fmt.Sprintf("(created_at.gte.%s,created_at.lte.%s)",
+			start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)),
+		"order": "updated_at.asc",
+	}
+
+	if stalledBefore != nil {
+		params["or"] = fmt.Sprintf("(execution_status.eq.%s,and(execution_status.in.(%s,%s),updated_at.lt.%s))",
+			models.ConversationStateAbandoned, models.ConversationStateWaiting, models.ConversationStateActive,
+			stalledBefore.UTC().Format(time.RFC3339))
+	} else {
+		params["execution_status"] = fmt.Sprintf("eq.%s", models.ConversationStateAbandoned)
+	}
+
+	if flowID != nil && *flowID != "" {
+		params["flow_id"] = fmt.Sprintf("eq.%s", *flowID)
+	}
+
+	return params
+}
+
+// contactedSinceParams builds the filter for conversations on any of the devices touched since a time
+func contactedSinceParams(deviceIDs []string, since time.Time) map[string]string {
+	return map[string]string{
+		"select":     "prospect_num",
+		"id_device":  fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"updated_at": fmt.Sprintf("gte.%s", since.UTC().Format(time.RFC3339)),
+	}
+}
+
+// GetCampaignByID retrieves a campaign by ID, or nil when it does not exist
+func (r *CampaignRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	campaigns, err := r.getCampaigns(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil || len(campaigns) == 0 {
+		return nil, err
+	}
+
+	return &campaigns[0], nil
+}
+
+// GetCampaignsByStatus retrieves every campaign in a status, oldest first
+func (r *CampaignRepository) GetCampaignsByStatus(ctx context.Context, status string) ([]models.Campaign, error) {
+	return r.getCampaigns(ctx, map[string]string{
+		"select": "*",
+		"status": fmt.Sprintf("eq.%s", status),
+		"order":  "started_at.asc",
+	})
+}
+
+func (r *CampaignRepository) getCampaigns(ctx context.Context, params map[string]string) ([]models.Campaign, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "campaigns", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns: %w", err)
+	}
+
+	var campaigns []models.Campaign
+	if err := json.Unmarshal(data, &campaigns); err != nil {
+		return nil, fmt.Errorf("failed to parse campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// UpdateCampaign updates a campaign
+func (r *CampaignRepository) UpdateCampaign(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "campaigns", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecipients retrieves a campaign's recipients, optionally in one status, in insertion order
+func (r *CampaignRepository) GetRecipients(ctx context.Context, campaignID, status string, limit int) ([]models.CampaignRecipient, error) {
+	params := map[string]string{
+		"select":      "*",
+		"campaign_id": fmt.Sprintf("eq.%s", campaignID),
+		"order":       "created_at.asc,id.asc",
+		"limit":       fmt.Sprintf("%d", limit),
+	}
+	if status != "" {
+		params["status"] = fmt.Sprintf("eq.%s", status)
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "campaign_recipients", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign recipients: %w", err)
+	}
+
+	var recipients []models.CampaignRecipient
+	if err := json.Unmarshal(data, &recipients); err != nil {
+		return nil, fmt.Errorf("failed to parse campaign recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// CountRecipients counts a campaign's recipients in a status
+func (r *CampaignRepository) CountRecipients(ctx context.Context, campaignID, status string) (int, error) {
+	count, err := r.supabase.CountAsAdmin(ctx, "campaign_recipients", map[string]string{
+		"campaign_id": fmt.Sprintf("eq.%s", campaignID),
+		"status":      fmt.Sprintf("eq.%s", status),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count campaign recipients: %w", err)
+	}
+
+	return count, nil
+}
+
+// ClaimRecipient moves a pending recipient to sending. It reports false when another sender
+// claimed it first or the campaign was cancelled in the meantime.
+func (r *CampaignRepository) ClaimRecipient(ctx context.Context, id string) (bool, error) {
+	data, err := r.supabase.UpdateAsAdmin(ctx, "campaign_recipients", map[string]string{
+		"id":     id,
+		"status": models.RecipientStatusPending,
+	}, map[string]interface{}{
+		"status": models.RecipientStatusSending,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim campaign recipient: %w", err)
+	}
+
+	var claimed []models.CampaignRecipient
+	if err := json.Unmarshal(data, &claimed); err != nil {
+		return false, fmt.Errorf("failed to parse claimed campaign recipient: %w", err)
+	}
+	return len(claimed) > 0, nil
+}
+
+// UpdateRecipient records the outcome of sending to a recipient
+func (r *CampaignRepository) UpdateRecipient(ctx context.Context, id string, updates map[string]interface{}) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "campaign_recipients", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update campaign recipient: %w", err)
+	}
+
+	return nil
+}
+
+// SkipPendingRecipients marks every recipient a campaign has not reached yet as skipped
+func (r *CampaignRepository) SkipPendingRecipients(ctx context.Context, campaignID, reason string) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "campaign_recipients", map[string]string{
+		"campaign_id": campaignID,
+		"status":      models.RecipientStatusPending,
+	}, map[string]interface{}{
+		"status": models.RecipientStatusSkipped,
+		"error":  reason,
+	}); err != nil {
+		return fmt.Errorf("failed to skip campaign recipients: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSegment stores a new segment
+func (r *CampaignRepository) CreateSegment(ctx context.Context, segment *models.ContactSegment) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "contact_segments", segment)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+
+	var segments []models.ContactSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return fmt.Errorf("failed to parse created segment: %w", err)
+	}
+
+	if len(segments) > 0 {
+		*segment = segments[0]
+	}
+
+	return nil
+}
+
+// GetSegmentsByUser retrieves a user's segments by name
+func (r *CampaignRepository) GetSegmentsByUser(ctx context.Context, userID string) ([]models.ContactSegment, error) {
+	return r.getSegments(ctx, map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "name.asc",
+	})
+}
+
+// GetSegmentByID retrieves a segment by ID, or nil when it does not exist
+func (r *CampaignRepository) GetSegmentByID(ctx context.Context, id string) (*models.ContactSegment, error) {
+	segments, err := r.getSegments(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil || len(segments) == 0 {
+		return nil, err
+	}
+
+	return &segments[0], nil
+}
+
+func (r *CampaignRepository) getSegments(ctx context.Context, params map[string]string) ([]models.ContactSegment, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "contact_segments", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segments: %w", err)
+	}
+
+	var segments []models.ContactSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, fmt.Errorf("failed to parse segments: %w", err)
+	}
+
+	return segments, nil
+}
+
+// DeleteSegment deletes a segment. Campaigns built from it keep their recipients.
+func (r *CampaignRepository) DeleteSegment(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "contact_segments", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete segment: %w", err)
+	}
+
+	return nil
+}
+
+// segmentConversationParams builds the filter for a device's conversations matching a segment
+func segmentConversationParams(deviceID string, segment *models.ContactSegment) map[string]string {
+	params := map[string]string{
+		"select":    "prospect_num,prospect_name,stage",
+		"id_device": fmt.Sprintf("eq.%s", deviceID),
+		"order":     "created_at.asc",
+		"limit":     fmt.Sprintf("%d", models.MaxCampaignRecipients),
+	}
+	if segment.Stage != nil && *segment.Stage != "" {
+		params["stage"] = fmt.Sprintf("eq.%s", *segment.Stage)
+	}
+	if segment.ExecutionStatus != nil && *segment.ExecutionStatus != "" {
+		params["execution_status"] = fmt.Sprintf("eq.%s", *segment.ExecutionStatus)
+	}
+	if segment.Niche != nil && *segment.Niche != "" {
+		params["niche"] = fmt.Sprintf("eq.%s", *segment.Niche)
+	}
+
+	var and []string
+	if segment.CreatedAfter != nil {
+		and = append(and, fmt.Sprintf("created_at.gte.%s", segment.CreatedAfter.UTC().Format(time.RFC3339)))
+	}
+	if segment.CreatedBefore != nil {
+		and = append(and, fmt.Sprintf("created_at.lt.%s", segment.CreatedBefore.UTC().Format(time.RFC3339)))
+	}
+	if len(and) > 0 {
+		params["and"] = fmt.Sprintf("(%s)", strings.Join(and, ","))
+	}
+
+	return params
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ConsentRepository handles consent_records data operations
+type ConsentRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewConsentRepository creates a new consent repository
+func NewConsentRepository(supabase *database.SupabaseClient) *ConsentRepository {
+	return &ConsentRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordConsent appends a consent decision to the registry
+func (r *ConsentRepository) RecordConsent(ctx context.Context, record *models.ConsentRecord) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "consent_records", record)
+	if err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	var records []models.ConsentRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse consent record: %w", err)
+	}
+
+	if len(records) > 0 {
+		*record = records[0]
+	}
+
+	return nil
+}
+
+// GetRecordsByUser retrieves a user's consent records, oldest first, optionally for some devices only
+func (r *ConsentRepository) GetRecordsByUser(ctx context.Context, userID string, idDevices []string) ([]models.ConsentRecord, error) {
+	params := map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.asc",
+	}
+	if len(idDevices) > 0 {
+		params["id_device"] = fmt.Sprintf("in.(%s)", strings.Join(idDevices, ","))
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "consent_records", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent records: %w", err)
+	}
+
+	var records []models.ConsentRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse consent records: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ConversationCheckpointRepository handles conversation_checkpoints data operations
+type ConversationCheckpointRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewConversationCheckpointRepository creates a new conversation checkpoint repository
+func NewConversationCheckpointRepository(supabase *database.SupabaseClient) *ConversationCheckpointRepository {
+	return &ConversationCheckpointRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordCheckpoint stores a checkpoint
+func (r *ConversationCheckpointRepository) RecordCheckpoint(ctx context.Context, checkpoint *models.ConversationCheckpoint) error {
+	checkpoint.ID = uuid.New().String()
+	if _, err := r.supabase.InsertAsAdmin(ctx, "conversation_checkpoints", checkpoint); err != nil {
+		return fmt.Errorf("failed to record conversation checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ListCheckpoints returns a conversation's checkpoints, oldest first
+func (r *ConversationCheckpointRepository) ListCheckpoints(ctx context.Context, table, conversationID string) ([]models.ConversationCheckpoint, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "conversation_checkpoints", map[string]string{
+		"select":             "*",
+		"conversation_table": fmt.Sprintf("eq.%s", table),
+		"conversation_id":    fmt.Sprintf("eq.%s", conversationID),
+		"order":              "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation checkpoints: %w", err)
+	}
+
+	var checkpoints []models.ConversationCheckpoint
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// DeleteCheckpoints removes every checkpoint of a conversation
+func (r *ConversationCheckpointRepository) DeleteCheckpoints(ctx context.Context, table, conversationID string) error {
+	err := r.supabase.DeleteAsAdmin(ctx, "conversation_checkpoints", map[string]string{
+		"conversation_table": table,
+		"conversation_id":    conversationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation checkpoints: %w", err)
+	}
+	return nil
+}
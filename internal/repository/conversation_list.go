@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"chatbot-automation/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidListColumn is returned when a list column, sort or filter names an unusable field
+var ErrInvalidListColumn = errors.New("invalid list column")
+
+// listTableColumns are the captured fields that are real columns of each conversation table.
+// Other names are the owner's custom fields, else session_data variables.
+var listTableColumns = map[string]map[string]bool{
+	"ai_whatsapp": columnSet("prospect_name", "niche", "stage", "intro", "balas", "human", "keywordiklan", "marketer",
+		"csat_score", "language", "channel", "priority", "pinned", "flow_progress", "flow_milestone",
+		"execution_status", "current_node_id", "external_ref", "created_at", "updated_at"),
+	"wasapbot": columnSet("prospect_name", "niche", "stage", "intro", "balas", "human", "keywordiklan", "marketer",
+		"peringkat_sekolah", "alamat", "pakej", "no_fon", "cara_bayaran", "tarikh_gaji",
+		"csat_score", "language", "channel", "priority", "pinned", "flow_progress", "flow_milestone",
+		"execution_status", "current_node_id", "external_ref", "created_at", "updated_at"),
+}
+
+// IsConversationColumn reports whether name is a column of either conversation table
+func IsConversationColumn(name string) bool {
+	return IsTableColumn("ai_whatsapp", name) || IsTableColumn("wasapbot", name)
+}
+
+// IsTableColumn reports whether name is a captured field column of the conversation table
+func IsTableColumn(table, name string) bool {
+	return listTableColumns[table][name]
+}
+
+func columnSet(columns ...string) map[string]bool {
+	set := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		set[column] = true
+	}
+	return set
+}
+
+// listColumnExpr maps a list column to its PostgREST expression on table
+func listColumnExpr(table, column string, customFields map[string]bool) (string, error) {
+	if !models.IsValidListColumnName(column) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidListColumn, column)
+	}
+	if listTableColumns[table][column] {
+		return column, nil
+	}
+	if customFields[column] {
+		return "custom_fields->>" + column, nil
+	}
+	return "session_data->>" + column, nil
+}
+
+// conversationListParams builds the PostgREST query for a device's conversation list with
+// the requested columns, filters and sort
+func conversationListParams(table, deviceID string, query *models.ConversationListQuery, limit int) (map[string]string, error) {
+	fields := strings.Split(models.ConversationListBaseFields, ",")
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		selected[field] = true
+	}
+
+	for _, column := range query.Columns {
+		expr, err := listColumnExpr(table, column, query.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		if selected[column] {
+			continue
+		}
+		selected[column] = true
+		if expr == column {
+			fields = append(fields, column)
+		} else {
+			fields = append(fields, column+":"+expr)
+		}
+	}
+
+	params := map[string]string{
+		"select":    strings.Join(fields, ","),
+		"id_device": fmt.Sprintf("eq.%s", deviceID),
+		"order":     models.InboxOrder + ",created_at.desc",
+	}
+
+	for column, value := range query.Filters {
+		expr, err := listColumnExpr(table, column, query.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(value, "~") {
+			params[expr] = fmt.Sprintf("ilike.*%s*", strings.TrimPrefix(value, "~"))
+		} else {
+			params[expr] = fmt.Sprintf("eq.%s", value)
+		}
+	}
+
+	if query.Sort != "" {
+		expr, err := listColumnExpr(table, query.Sort, query.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		direction := "asc"
+		if query.Desc {
+			direction = "desc"
+		}
+		params["order"] = fmt.Sprintf("%s.%s.nullslast,created_at.desc", expr, direction)
+	}
+
+	if limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", limit)
+	}
+
+	return params, nil
+}
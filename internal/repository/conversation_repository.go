@@ -1,17 +1,20 @@
 package repository
 
 import (
+	"chatbot-automation/internal/cache"
 	"chatbot-automation/internal/database"
 	"chatbot-automation/internal/models"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
 // ConversationRepository handles conversation data operations
 type ConversationRepository struct {
 	supabase *database.SupabaseClient
+	cache    *cache.ConversationCache // optional, nil disables caching
 }
 
 // NewConversationRepository creates a new conversation repository
@@ -21,6 +24,11 @@ func NewConversationRepository(supabase *database.SupabaseClient) *ConversationR
 	}
 }
 
+// SetCache enables the Redis cache for hot ai_whatsapp and wasapbot rows
+func (r *ConversationRepository) SetCache(c *cache.ConversationCache) {
+	r.cache = c
+}
+
 // CreateConversation creates a new conversation
 func (r *ConversationRepository) CreateConversation(ctx context.Context, conversation *models.AIWhatsapp) error {
 	// Database will auto-generate id_prospect (serial/autoincrement)
@@ -30,8 +38,13 @@ func (r *ConversationRepository) CreateConversation(ctx context.Context, convers
 	// Bot reply will be added during flow execution
 	// Format: "User: message\nBot: reply"
 
+	if conversation.ExternalRef == nil || *conversation.ExternalRef == "" {
+		ref := models.DefaultExternalRef(conversation.IDDevice, conversation.ProspectNum)
+		conversation.ExternalRef = &ref
+	}
+
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("ai_whatsapp", conversation)
+	data, err := r.supabase.InsertAsAdmin(ctx, "ai_whatsapp", conversation)
 	if err != nil {
 		return fmt.Errorf("failed to create conversation: %w", err)
 	}
@@ -51,7 +64,12 @@ func (r *ConversationRepository) CreateConversation(ctx context.Context, convers
 
 // GetConversationByID retrieves a conversation by prospect ID
 func (r *ConversationRepository) GetConversationByID(ctx context.Context, prospectID string) (*models.AIWhatsapp, error) {
-	data, err := r.supabase.QueryAsAdmin("ai_whatsapp", map[string]string{
+	var cached models.AIWhatsapp
+	if r.cache.Get(ctx, "ai_whatsapp", prospectID, &cached) {
+		return &cached, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
 		"select":      "*",
 		"id_prospect": fmt.Sprintf("eq.%s", prospectID),
 		"limit":       "1",
@@ -69,12 +87,13 @@ func (r *ConversationRepository) GetConversationByID(ctx context.Context, prospe
 		return nil, fmt.Errorf("conversation not found")
 	}
 
+	r.cache.Set(ctx, "ai_whatsapp", prospectID, &conversations[0])
 	return &conversations[0], nil
 }
 
 // GetConversationByProspectNum retrieves a conversation by prospect phone number and device
 func (r *ConversationRepository) GetConversationByProspectNum(ctx context.Context, prospectNum, deviceID string) (*models.AIWhatsapp, error) {
-	data, err := r.supabase.QueryAsAdmin("ai_whatsapp", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
 		"select":       "*",
 		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
 		"id_device":    fmt.Sprintf("eq.%s", deviceID),
@@ -97,19 +116,69 @@ func (r *ConversationRepository) GetConversationByProspectNum(ctx context.Contex
 	return &conversations[0], nil
 }
 
+// GetConversationByExternalRef retrieves a conversation by its external reference on any of the given devices
+func (r *ConversationRepository) GetConversationByExternalRef(ctx context.Context, externalRef string, deviceIDs []string) (*models.AIWhatsapp, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", externalRefParams(externalRef, deviceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation by external reference: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation: %w", err)
+	}
+
+	if len(conversations) == 0 {
+		return nil, nil
+	}
+
+	return &conversations[0], nil
+}
+
 // GetConversationsByDevice retrieves all conversations for a device
 func (r *ConversationRepository) GetConversationsByDevice(ctx context.Context, deviceID string, limit int) ([]models.AIWhatsapp, error) {
 	params := map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
-		"order":     "created_at.desc",
+		"order":     models.InboxOrder + ",created_at.desc",
 	}
 
 	if limit > 0 {
 		params["limit"] = fmt.Sprintf("%d", limit)
 	}
 
-	data, err := r.supabase.QueryAsAdmin("ai_whatsapp", params)
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetConversationPage retrieves one page of conversations across devices, newest first
+func (r *ConversationRepository) GetConversationPage(ctx context.Context, query *models.ConversationPageQuery) ([]models.AIWhatsapp, error) {
+	if len(query.DeviceIDs) == 0 {
+		return []models.AIWhatsapp{}, nil
+	}
+
+	params := conversationPageParams(query)
+	params["select"] = "*"
+	params["order"] = "created_at.desc,id_prospect.desc"
+	params["limit"] = fmt.Sprintf("%d", query.Limit)
+	if query.After == nil && query.Offset > 0 {
+		params["offset"] = fmt.Sprintf("%d", query.Offset)
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversations: %w", err)
 	}
@@ -122,14 +191,67 @@ func (r *ConversationRepository) GetConversationsByDevice(ctx context.Context, d
 	return conversations, nil
 }
 
+// CountConversations counts the conversations matching a page query's filters
+func (r *ConversationRepository) CountConversations(ctx context.Context, query *models.ConversationPageQuery) (int, error) {
+	if len(query.DeviceIDs) == 0 {
+		return 0, nil
+	}
+
+	filters := *query
+	filters.After = nil
+	count, err := r.supabase.CountAsAdmin(ctx, "ai_whatsapp", conversationPageParams(&filters))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	return count, nil
+}
+
+// conversationPageParams builds the filters of a page query. The date range and the cursor
+// share one and=(...) clause because PostgREST takes each parameter once.
+func conversationPageParams(query *models.ConversationPageQuery) map[string]string {
+	params := map[string]string{
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(query.DeviceIDs, ",")),
+	}
+	if query.Stage != "" {
+		params["stage"] = fmt.Sprintf("eq.%s", query.Stage)
+	}
+	if query.Status != "" {
+		params["execution_status"] = fmt.Sprintf("eq.%s", query.Status)
+	}
+	if query.Niche != "" {
+		params["niche"] = fmt.Sprintf("eq.%s", query.Niche)
+	}
+	if query.Phone != "" {
+		params["prospect_num"] = fmt.Sprintf("ilike.*%s*", query.Phone)
+	}
+
+	var and []string
+	if query.From != nil {
+		and = append(and, fmt.Sprintf("created_at.gte.%s", query.From.UTC().Format(time.RFC3339Nano)))
+	}
+	if query.To != nil {
+		and = append(and, fmt.Sprintf("created_at.lt.%s", query.To.UTC().Format(time.RFC3339Nano)))
+	}
+	if query.After != nil {
+		createdAt := query.After.CreatedAt.UTC().Format(time.RFC3339Nano)
+		and = append(and, fmt.Sprintf("or(created_at.lt.%s,and(created_at.eq.%s,id_prospect.lt.%d))",
+			createdAt, createdAt, query.After.IDProspect))
+	}
+	if len(and) > 0 {
+		params["and"] = fmt.Sprintf("(%s)", strings.Join(and, ","))
+	}
+
+	return params
+}
+
 // GetActiveConversationsByDevice retrieves all active conversations for a device
 func (r *ConversationRepository) GetActiveConversationsByDevice(ctx context.Context, deviceID string) ([]models.AIWhatsapp, error) {
-	data, err := r.supabase.QueryAsAdmin("ai_whatsapp", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
-		"is_active": "eq.true",
-		"status":    "eq.active",
-		"order":     "last_interaction.desc",
+		"or":        "(execution_status.is.null,execution_status.in.(active,waiting,scheduled,handoff))",
+		"order":     models.InboxOrder + ",updated_at.desc",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active conversations: %w", err)
@@ -143,22 +265,177 @@ func (r *ConversationRepository) GetActiveConversationsByDevice(ctx context.Cont
 	return conversations, nil
 }
 
+// GetConversationListRows retrieves a device's conversations as compact rows with the requested
+// captured fields as extra columns, filtered and sorted server-side
+func (r *ConversationRepository) GetConversationListRows(ctx context.Context, deviceID string, query *models.ConversationListQuery, limit int) ([]map[string]interface{}, error) {
+	params, err := conversationListParams("ai_whatsapp", deviceID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation list: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation list: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetPinnedConversations retrieves pinned conversations across the given devices
+func (r *ConversationRepository) GetPinnedConversations(ctx context.Context, deviceIDs []string) ([]models.AIWhatsapp, error) {
+	if len(deviceIDs) == 0 {
+		return []models.AIWhatsapp{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"pinned":    "eq.true",
+		"order":     "priority.desc.nullslast,updated_at.desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned conversations: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetAbandonedConversations retrieves a device's abandoned (or stalled, see abandonedConversationParams) conversations created in a date range
+func (r *ConversationRepository) GetAbandonedConversations(ctx context.Context, deviceID string, start, end time.Time, stalledBefore *time.Time, flowID *string) ([]models.AIWhatsapp, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", abandonedConversationParams(deviceID, start, end, stalledBefore, flowID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abandoned conversations: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetSegmentConversations retrieves the prospect numbers, names and stages of a device's conversations matching a segment
+func (r *ConversationRepository) GetSegmentConversations(ctx context.Context, deviceID string, segment *models.ContactSegment) ([]models.AIWhatsapp, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", segmentConversationParams(deviceID, segment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment conversations: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetStageSLABreaches retrieves a device's conversations that entered a stage before the SLA cutoff and were not flagged yet
+func (r *ConversationRepository) GetStageSLABreaches(ctx context.Context, deviceID, stage string, enteredBefore time.Time) ([]models.AIWhatsapp, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", stageSLABreachParams(deviceID, stage, enteredBefore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stage SLA breaches: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// CountByExecutionStatus counts the conversations in an execution state across all devices
+func (r *ConversationRepository) CountByExecutionStatus(ctx context.Context, state models.ConversationState) (int, error) {
+	count, err := r.supabase.CountAsAdmin(ctx, "ai_whatsapp", map[string]string{
+		"execution_status": fmt.Sprintf("eq.%s", state),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ai_whatsapp conversations: %w", err)
+	}
+	return count, nil
+}
+
+// GetContactedNumbers returns prospect numbers with conversations updated on the given devices since a time
+func (r *ConversationRepository) GetContactedNumbers(ctx context.Context, deviceIDs []string, since time.Time) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return []string{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", contactedSinceParams(deviceIDs, since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently contacted conversations: %w", err)
+	}
+
+	var conversations []models.AIWhatsapp
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse conversations: %w", err)
+	}
+
+	numbers := make([]string, 0, len(conversations))
+	for _, conv := range conversations {
+		numbers = append(numbers, conv.ProspectNum)
+	}
+
+	return numbers, nil
+}
+
 // UpdateConversation updates a conversation
 func (r *ConversationRepository) UpdateConversation(ctx context.Context, prospectID string, updates map[string]interface{}) error {
 	// Add updated_at timestamp
 	updates["updated_at"] = time.Now()
 
-	_, err := r.supabase.UpdateAsAdmin("ai_whatsapp", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "ai_whatsapp", map[string]string{
 		"id_prospect": prospectID,
 	}, updates)
 
 	if err != nil {
+		r.cache.Delete(ctx, "ai_whatsapp", prospectID)
 		return fmt.Errorf("failed to update conversation: %w", err)
 	}
 
+	writeThroughCache(ctx, r.cache, "ai_whatsapp", prospectID, updates)
 	return nil
 }
 
+// GetExecutionState retrieves only the execution columns of a conversation
+func (r *ConversationRepository) GetExecutionState(ctx context.Context, prospectID string) (*models.ExecutionState, error) {
+	var cached models.AIWhatsapp
+	if r.cache.Get(ctx, "ai_whatsapp", prospectID, &cached) {
+		return cached.ExecutionState(), nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "ai_whatsapp", map[string]string{
+		"select":      "execution_status,waiting_for_reply,current_node_id",
+		"id_prospect": fmt.Sprintf("eq.%s", prospectID),
+		"limit":       "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution state: %w", err)
+	}
+
+	var states []models.ExecutionState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse execution state: %w", err)
+	}
+
+	if len(states) == 0 {
+		return nil, fmt.Errorf("conversation not found")
+	}
+
+	return &states[0], nil
+}
+
 // UpdateLastInteraction updates the last interaction timestamp
 func (r *ConversationRepository) UpdateLastInteraction(ctx context.Context, prospectID string) error {
 	now := time.Now()
@@ -167,14 +444,16 @@ func (r *ConversationRepository) UpdateLastInteraction(ctx context.Context, pros
 		"updated_at":       now,
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("ai_whatsapp", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "ai_whatsapp", map[string]string{
 		"id_prospect": prospectID,
 	}, updates)
 
 	if err != nil {
+		r.cache.Delete(ctx, "ai_whatsapp", prospectID)
 		return fmt.Errorf("failed to update last interaction: %w", err)
 	}
 
+	r.cache.Patch(ctx, "ai_whatsapp", prospectID, updates)
 	return nil
 }
 
@@ -205,7 +484,9 @@ func (r *ConversationRepository) UpdateConversationModel(ctx context.Context, pr
 
 // DeleteConversation deletes a conversation
 func (r *ConversationRepository) DeleteConversation(ctx context.Context, prospectID string) error {
-	err := r.supabase.Delete("ai_whatsapp", map[string]string{
+	r.cache.Delete(ctx, "ai_whatsapp", prospectID)
+
+	err := r.supabase.Delete(ctx, "ai_whatsapp", map[string]string{
 		"id_prospect": prospectID,
 	})
 
@@ -216,6 +497,50 @@ func (r *ConversationRepository) DeleteConversation(ctx context.Context, prospec
 	return nil
 }
 
+// GetTestConversations lists a device's conversations flagged is_test in ai_whatsapp or wasapbot,
+// oldest first; before (optional) keeps only those created earlier
+func (r *ConversationRepository) GetTestConversations(ctx context.Context, table, deviceID string, before *time.Time) ([]models.CleanupConversation, error) {
+	params := map[string]string{
+		"select":    "id_prospect,prospect_num,stage,execution_status,created_at",
+		"id_device": fmt.Sprintf("eq.%s", deviceID),
+		"is_test":   "eq.true",
+		"order":     "created_at.asc",
+	}
+	if before != nil {
+		params["created_at"] = fmt.Sprintf("lt.%s", before.UTC().Format(time.RFC3339))
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, table, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test conversations: %w", err)
+	}
+
+	var conversations []models.CleanupConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse test conversations: %w", err)
+	}
+	for i := range conversations {
+		conversations[i].Table = table
+	}
+
+	return conversations, nil
+}
+
+// DeleteTestConversation deletes a conversation from ai_whatsapp or wasapbot, only while it is flagged is_test
+func (r *ConversationRepository) DeleteTestConversation(ctx context.Context, table, prospectID string) error {
+	r.cache.Delete(ctx, table, prospectID)
+
+	err := r.supabase.DeleteAsAdmin(ctx, table, map[string]string{
+		"id_prospect": prospectID,
+		"is_test":     "true",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete test conversation: %w", err)
+	}
+
+	return nil
+}
+
 // GetConversationStats retrieves conversation statistics for a device
 func (r *ConversationRepository) GetConversationStats(ctx context.Context, deviceID string) (*models.ConversationStats, error) {
 	// Get all conversations for the device
@@ -233,19 +558,14 @@ func (r *ConversationRepository) GetConversationStats(ctx context.Context, devic
 
 	// Calculate statistics
 	for _, conv := range conversations {
-		// Count by execution status
-		status := "active"
-		if conv.ExecutionStatus != nil && *conv.ExecutionStatus != "" {
-			status = *conv.ExecutionStatus
-		}
-
-		switch status {
-		case "active":
-			stats.ActiveConversations++
-		case "completed":
+		// Count by execution state - paused and handed-off flows are still active
+		switch conv.ExecutionState().State() {
+		case models.ConversationStateCompleted:
 			stats.CompletedConversations++
-		case "abandoned":
+		case models.ConversationStateAbandoned:
 			stats.AbandonedConversations++
+		default:
+			stats.ActiveConversations++
 		}
 
 		// Count by stage
@@ -267,7 +587,7 @@ func (r *ConversationRepository) GetConversationStats(ctx context.Context, devic
 
 // GetWasapBotContact retrieves a contact from wasapbot table
 func (r *ConversationRepository) GetWasapBotContact(ctx context.Context, deviceID, prospectNum, niche string) (*models.WasapBot, error) {
-	data, err := r.supabase.QueryAsAdmin("wasapbot", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
 		"select":       "*",
 		"id_device":    fmt.Sprintf("eq.%s", deviceID),
 		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
@@ -295,8 +615,12 @@ func (r *ConversationRepository) CreateWasapBotContact(ctx context.Context, cont
 	now := time.Now().Format(time.RFC3339)
 	contact.CreatedAt = &now
 	contact.UpdatedAt = &now
+	if contact.ExternalRef == nil || *contact.ExternalRef == "" {
+		ref := models.DefaultExternalRef(contact.DeviceID, contact.ProspectNum)
+		contact.ExternalRef = &ref
+	}
 
-	data, err := r.supabase.InsertAsAdmin("wasapbot", contact)
+	data, err := r.supabase.InsertAsAdmin(ctx, "wasapbot", contact)
 	if err != nil {
 		return fmt.Errorf("failed to create wasapbot contact: %w", err)
 	}
@@ -318,13 +642,87 @@ func (r *ConversationRepository) CreateWasapBotContact(ctx context.Context, cont
 func (r *ConversationRepository) UpdateWasapBotContact(ctx context.Context, id string, updates map[string]interface{}) error {
 	updates["updated_at"] = time.Now().Format(time.RFC3339)
 
-	_, err := r.supabase.UpdateAsAdmin("wasapbot", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "wasapbot", map[string]string{
 		"id": id,
 	}, updates)
 
+	// Keyed by id rather than id_prospect, so invalidate instead of patching
+	r.cache.Delete(ctx, "wasapbot", id)
+
 	if err != nil {
 		return fmt.Errorf("failed to update wasapbot contact: %w", err)
 	}
 
 	return nil
 }
+
+// externalRefParams builds the filter for a conversation by external reference on a set of devices
+func externalRefParams(externalRef string, deviceIDs []string) map[string]string {
+	return map[string]string{
+		"select":       "*",
+		"external_ref": fmt.Sprintf("eq.%s", externalRef),
+		"id_device":    fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"limit":        "1",
+	}
+}
+
+// writeThroughCache applies a successful conversation update to the cached row.
+// Stage changes invalidate instead, because the stage trigger also rewrites
+// stage_entered_at and sla_breached_at in the database.
+func writeThroughCache(ctx context.Context, c *cache.ConversationCache, table, prospectID string, updates map[string]interface{}) {
+	if _, ok := updates["stage"]; ok {
+		c.Delete(ctx, table, prospectID)
+		return
+	}
+	c.Patch(ctx, table, prospectID, updates)
+}
+
+// GetFlowBoundConversations pages through the conversations of ai_whatsapp or wasapbot that are
+// bound to a flow, oldest first
+func (r *ConversationRepository) GetFlowBoundConversations(ctx context.Context, table string, offset, limit int) ([]models.ConsistencyConversation, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, table, map[string]string{
+		"select":  "id_prospect,id_device,flow_id,execution_status",
+		"flow_id": "not.is.null",
+		"order":   "id_prospect.asc",
+		"offset":  fmt.Sprintf("%d", offset),
+		"limit":   fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flow-bound conversations: %w", err)
+	}
+
+	var conversations []models.ConsistencyConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse flow-bound conversations: %w", err)
+	}
+	for i := range conversations {
+		conversations[i].Table = table
+	}
+
+	return conversations, nil
+}
+
+// GetWaitingConversationsWithoutNode lists the conversations of ai_whatsapp or wasapbot waiting
+// for a reply without a current node to resume at
+func (r *ConversationRepository) GetWaitingConversationsWithoutNode(ctx context.Context, table string, limit int) ([]models.ConsistencyConversation, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, table, map[string]string{
+		"select":           "id_prospect,id_device,flow_id,execution_status",
+		"execution_status": fmt.Sprintf("eq.%s", models.ConversationStateWaiting),
+		"current_node_id":  "is.null",
+		"order":            "id_prospect.asc",
+		"limit":            fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waiting conversations without a node: %w", err)
+	}
+
+	var conversations []models.ConsistencyConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse waiting conversations: %w", err)
+	}
+	for i := range conversations {
+		conversations[i].Table = table
+	}
+
+	return conversations, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CostLedgerRepository handles conversation_costs data operations
+type CostLedgerRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewCostLedgerRepository creates a new cost ledger repository
+func NewCostLedgerRepository(supabase *database.SupabaseClient) *CostLedgerRepository {
+	return &CostLedgerRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordCost appends an entry to the ledger
+func (r *CostLedgerRepository) RecordCost(ctx context.Context, entry *models.ConversationCost) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "conversation_costs", entry); err != nil {
+		return fmt.Errorf("failed to record conversation cost: %w", err)
+	}
+	return nil
+}
+
+// GetConversationCosts retrieves all ledger entries for a prospect on a device, oldest first
+func (r *CostLedgerRepository) GetConversationCosts(ctx context.Context, idDevice, prospectNum string) ([]models.ConversationCost, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "conversation_costs", map[string]string{
+		"select":       "*",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"order":        "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation costs: %w", err)
+	}
+
+	var entries []models.ConversationCost
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation costs: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetCostsByDevices retrieves ledger entries for the given devices, optionally limited to a time range
+func (r *CostLedgerRepository) GetCostsByDevices(ctx context.Context, deviceIDs []string, timeRange *models.TimeRangeFilter) ([]models.ConversationCost, error) {
+	if len(deviceIDs) == 0 {
+		return []models.ConversationCost{}, nil
+	}
+
+	params := map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"order":     "created_at.asc",
+	}
+	if timeRange != nil {
+		params["and"] = fmt.Sprintf("(created_at.gte.%s,created_at.lte.%s)",
+			timeRange.StartDate.Format(time.RFC3339),
+			timeRange.EndDate.Format(time.RFC3339))
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "conversation_costs", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation costs: %w", err)
+	}
+
+	var entries []models.ConversationCost
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation costs: %w", err)
+	}
+
+	return entries, nil
+}
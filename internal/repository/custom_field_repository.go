@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CustomFieldRepository handles contact_custom_fields data operations
+type CustomFieldRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewCustomFieldRepository creates a new custom field repository
+func NewCustomFieldRepository(supabase *database.SupabaseClient) *CustomFieldRepository {
+	return &CustomFieldRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateField stores a new custom field definition
+func (r *CustomFieldRepository) CreateField(ctx context.Context, field *models.ContactCustomField) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "contact_custom_fields", field)
+	if err != nil {
+		return fmt.Errorf("failed to create custom field: %w", err)
+	}
+
+	var fields []models.ContactCustomField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to parse created custom field: %w", err)
+	}
+
+	if len(fields) > 0 {
+		*field = fields[0]
+	}
+
+	return nil
+}
+
+// GetFieldsByUser retrieves a user's custom fields in the order they were defined
+func (r *CustomFieldRepository) GetFieldsByUser(ctx context.Context, userID string) ([]models.ContactCustomField, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "contact_custom_fields", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom fields: %w", err)
+	}
+
+	var fields []models.ContactCustomField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse custom fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// GetFieldByID retrieves a custom field by ID, or nil when it does not exist
+func (r *CustomFieldRepository) GetFieldByID(ctx context.Context, id string) (*models.ContactCustomField, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "contact_custom_fields", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom field: %w", err)
+	}
+
+	var fields []models.ContactCustomField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse custom field: %w", err)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return &fields[0], nil
+}
+
+// UpdateField updates a custom field
+func (r *CustomFieldRepository) UpdateField(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "contact_custom_fields", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update custom field: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteField deletes a custom field definition; captured values stay in custom_fields
+func (r *CustomFieldRepository) DeleteField(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "contact_custom_fields", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+
+	return nil
+}
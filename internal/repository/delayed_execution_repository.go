@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DelayedExecutionRepository handles delayed_executions data operations
+type DelayedExecutionRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewDelayedExecutionRepository creates a new delayed execution repository
+func NewDelayedExecutionRepository(supabase *database.SupabaseClient) *DelayedExecutionRepository {
+	return &DelayedExecutionRepository{
+		supabase: supabase,
+	}
+}
+
+// ScheduleExecution stores a pending resume for a conversation. Older pending resumes of the
+// same conversation are skipped first: a conversation only ever rests at one node.
+func (r *DelayedExecutionRepository) ScheduleExecution(ctx context.Context, execution *models.DelayedExecution) error {
+	if err := r.SkipPending(ctx, execution.Source, execution.ConversationID); err != nil {
+		return err
+	}
+
+	execution.ID = uuid.New().String()
+	execution.Status = models.DelayedExecutionPending
+	if _, err := r.supabase.InsertAsAdmin(ctx, "delayed_executions", execution); err != nil {
+		return fmt.Errorf("failed to schedule delayed execution: %w", err)
+	}
+	return nil
+}
+
+// SkipPending marks a conversation's pending resumes as skipped
+func (r *DelayedExecutionRepository) SkipPending(ctx context.Context, source, conversationID string) error {
+	_, err := r.supabase.UpdateAsAdmin(ctx, "delayed_executions", map[string]string{
+		"source":          source,
+		"conversation_id": conversationID,
+		"status":          models.DelayedExecutionPending,
+	}, map[string]interface{}{
+		"status":      models.DelayedExecutionSkipped,
+		"finished_at": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to skip pending delayed executions: %w", err)
+	}
+	return nil
+}
+
+// GetDueExecutions returns up to limit pending resumes whose resume_at has passed, oldest first
+func (r *DelayedExecutionRepository) GetDueExecutions(ctx context.Context, now time.Time, limit int) ([]models.DelayedExecution, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "delayed_executions", map[string]string{
+		"select":    "*",
+		"status":    fmt.Sprintf("eq.%s", models.DelayedExecutionPending),
+		"resume_at": fmt.Sprintf("lte.%s", now.UTC().Format(time.RFC3339)),
+		"order":     "resume_at.asc",
+		"limit":     fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due delayed executions: %w", err)
+	}
+
+	var executions []models.DelayedExecution
+	if err := json.Unmarshal(data, &executions); err != nil {
+		return nil, fmt.Errorf("failed to parse delayed executions: %w", err)
+	}
+	return executions, nil
+}
+
+// CountDue counts the pending executions whose resume time has passed: the scheduler's backlog
+func (r *DelayedExecutionRepository) CountDue(ctx context.Context, now time.Time) (int, error) {
+	count, err := r.supabase.CountAsAdmin(ctx, "delayed_executions", map[string]string{
+		"status":    fmt.Sprintf("eq.%s", models.DelayedExecutionPending),
+		"resume_at": fmt.Sprintf("lte.%s", now.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count due delayed executions: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimExecution moves a pending resume to running. It reports false when another
+// dispatcher claimed it first or it was skipped in the meantime.
+func (r *DelayedExecutionRepository) ClaimExecution(ctx context.Context, id string) (bool, error) {
+	data, err := r.supabase.UpdateAsAdmin(ctx, "delayed_executions", map[string]string{
+		"id":     id,
+		"status": models.DelayedExecutionPending,
+	}, map[string]interface{}{
+		"status": models.DelayedExecutionRunning,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim delayed execution: %w", err)
+	}
+
+	var claimed []models.DelayedExecution
+	if err := json.Unmarshal(data, &claimed); err != nil {
+		return false, fmt.Errorf("failed to parse claimed delayed execution: %w", err)
+	}
+	return len(claimed) > 0, nil
+}
+
+// FinishExecution records how a claimed resume ended; errMessage is stored for failed ones
+func (r *DelayedExecutionRepository) FinishExecution(ctx context.Context, id, status, errMessage string) error {
+	updates := map[string]interface{}{
+		"status":      status,
+		"finished_at": time.Now(),
+	}
+	if errMessage != "" {
+		updates["last_error"] = errMessage
+	}
+
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "delayed_executions", map[string]string{"id": id}, updates); err != nil {
+		return fmt.Errorf("failed to finish delayed execution: %w", err)
+	}
+	return nil
+}
@@ -31,7 +31,7 @@ func (r *DeviceRepository) CreateDevice(ctx context.Context, device *models.Devi
 	device.UpdatedAt = time.Now()
 
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("device_setting", device)
+	data, err := r.supabase.InsertAsAdmin(ctx, "device_setting", device)
 	if err != nil {
 		return fmt.Errorf("failed to create device: %w", err)
 	}
@@ -51,7 +51,7 @@ func (r *DeviceRepository) CreateDevice(ctx context.Context, device *models.Devi
 
 // GetDeviceByID retrieves a device by ID
 func (r *DeviceRepository) GetDeviceByID(ctx context.Context, deviceID string) (*models.DeviceSetting, error) {
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select": "*",
 		"id":     fmt.Sprintf("eq.%s", deviceID),
 		"limit":  "1",
@@ -74,7 +74,7 @@ func (r *DeviceRepository) GetDeviceByID(ctx context.Context, deviceID string) (
 
 // GetDevicesByUserID retrieves all devices for a user
 func (r *DeviceRepository) GetDevicesByUserID(ctx context.Context, userID string) ([]models.DeviceSetting, error) {
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":  "*",
 		"user_id": fmt.Sprintf("eq.%s", userID),
 		"order":   "created_at.desc",
@@ -93,7 +93,7 @@ func (r *DeviceRepository) GetDevicesByUserID(ctx context.Context, userID string
 
 // GetAllDevices retrieves all devices (admin only)
 func (r *DeviceRepository) GetAllDevices(ctx context.Context) ([]models.DeviceSetting, error) {
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select": "*",
 		"order":  "created_at.desc",
 	})
@@ -114,7 +114,7 @@ func (r *DeviceRepository) UpdateDevice(ctx context.Context, deviceID string, up
 	// Add updated_at timestamp
 	updates["updated_at"] = time.Now()
 
-	_, err := r.supabase.UpdateAsAdmin("device_setting", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "device_setting", map[string]string{
 		"id": deviceID,
 	}, updates)
 
@@ -127,7 +127,7 @@ func (r *DeviceRepository) UpdateDevice(ctx context.Context, deviceID string, up
 
 // DeleteDevice deletes a device
 func (r *DeviceRepository) DeleteDevice(ctx context.Context, deviceID string) error {
-	err := r.supabase.Delete("device_setting", map[string]string{
+	err := r.supabase.Delete(ctx, "device_setting", map[string]string{
 		"id": deviceID,
 	})
 
@@ -141,7 +141,7 @@ func (r *DeviceRepository) DeleteDevice(ctx context.Context, deviceID string) er
 // GetDeviceByDeviceID retrieves a device by device_id field or id_device field
 func (r *DeviceRepository) GetDeviceByDeviceID(ctx context.Context, deviceID string) (*models.DeviceSetting, error) {
 	// Try device_id field first
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":    "*",
 		"device_id": fmt.Sprintf("eq.%s", deviceID),
 		"limit":     "1",
@@ -160,7 +160,7 @@ func (r *DeviceRepository) GetDeviceByDeviceID(ctx context.Context, deviceID str
 	}
 
 	// If not found by device_id, try id_device field
-	data, err = r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err = r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
 		"limit":     "1",
@@ -182,7 +182,7 @@ func (r *DeviceRepository) GetDeviceByDeviceID(ctx context.Context, deviceID str
 
 // GetDeviceByIDDevice retrieves a device by id_device field only
 func (r *DeviceRepository) GetDeviceByIDDevice(ctx context.Context, idDevice string) (*models.DeviceSetting, error) {
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", idDevice),
 		"limit":     "1",
@@ -205,7 +205,7 @@ func (r *DeviceRepository) GetDeviceByIDDevice(ctx context.Context, idDevice str
 
 // GetDeviceByWebhookID retrieves a device by webhook_id
 func (r *DeviceRepository) GetDeviceByWebhookID(ctx context.Context, webhookID string) (*models.DeviceSetting, error) {
-	data, err := r.supabase.QueryAsAdmin("device_setting", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "device_setting", map[string]string{
 		"select":     "*",
 		"webhook_id": fmt.Sprintf("eq.%s", webhookID),
 		"limit":      "1",
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EmailSettingsRepository handles email_settings data operations
+type EmailSettingsRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewEmailSettingsRepository creates a new email settings repository
+func NewEmailSettingsRepository(supabase *database.SupabaseClient) *EmailSettingsRepository {
+	return &EmailSettingsRepository{
+		supabase: supabase,
+	}
+}
+
+// GetSettingsByUser retrieves a user's email settings, or nil when none are configured
+func (r *EmailSettingsRepository) GetSettingsByUser(ctx context.Context, userID string) (*models.EmailSettings, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "email_settings", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email settings: %w", err)
+	}
+
+	var settings []models.EmailSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse email settings: %w", err)
+	}
+
+	if len(settings) == 0 {
+		return nil, nil
+	}
+
+	return &settings[0], nil
+}
+
+// CreateSettings stores a user's email settings
+func (r *EmailSettingsRepository) CreateSettings(ctx context.Context, settings *models.EmailSettings) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "email_settings", settings)
+	if err != nil {
+		return fmt.Errorf("failed to create email settings: %w", err)
+	}
+
+	var created []models.EmailSettings
+	if err := json.Unmarshal(data, &created); err != nil {
+		return fmt.Errorf("failed to parse created email settings: %w", err)
+	}
+
+	if len(created) > 0 {
+		*settings = created[0]
+	}
+
+	return nil
+}
+
+// UpdateSettings updates a user's email settings
+func (r *EmailSettingsRepository) UpdateSettings(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "email_settings", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update email settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventWebhookRepository handles event_webhooks and event_webhook_deliveries data operations
+type EventWebhookRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewEventWebhookRepository creates a new event webhook repository
+func NewEventWebhookRepository(supabase *database.SupabaseClient) *EventWebhookRepository {
+	return &EventWebhookRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateWebhook stores a new event webhook
+func (r *EventWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.EventWebhook) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "event_webhooks", webhook)
+	if err != nil {
+		return fmt.Errorf("failed to create event webhook: %w", err)
+	}
+
+	var webhooks []models.EventWebhook
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return fmt.Errorf("failed to parse created event webhook: %w", err)
+	}
+
+	if len(webhooks) > 0 {
+		*webhook = webhooks[0]
+	}
+
+	return nil
+}
+
+// GetWebhooksByUser retrieves a user's event webhooks, oldest first
+func (r *EventWebhookRepository) GetWebhooksByUser(ctx context.Context, userID string) ([]models.EventWebhook, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "event_webhooks", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event webhooks: %w", err)
+	}
+
+	var webhooks []models.EventWebhook
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse event webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhookByID retrieves an event webhook by ID, or nil when it does not exist
+func (r *EventWebhookRepository) GetWebhookByID(ctx context.Context, id string) (*models.EventWebhook, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "event_webhooks", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event webhook: %w", err)
+	}
+
+	var webhooks []models.EventWebhook
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse event webhook: %w", err)
+	}
+
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+
+	return &webhooks[0], nil
+}
+
+// UpdateWebhook updates an event webhook
+func (r *EventWebhookRepository) UpdateWebhook(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "event_webhooks", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update event webhook: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes an event webhook; its deliveries go with it
+func (r *EventWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "event_webhooks", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete event webhook: %w", err)
+	}
+
+	return nil
+}
+
+// CreateDelivery logs a new delivery
+func (r *EventWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.EventWebhookDelivery) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "event_webhook_deliveries", delivery)
+	if err != nil {
+		return fmt.Errorf("failed to create event webhook delivery: %w", err)
+	}
+
+	var deliveries []models.EventWebhookDelivery
+	if err := json.Unmarshal(data, &deliveries); err != nil {
+		return fmt.Errorf("failed to parse created event webhook delivery: %w", err)
+	}
+
+	if len(deliveries) > 0 {
+		*delivery = deliveries[0]
+	}
+
+	return nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt
+func (r *EventWebhookRepository) UpdateDelivery(ctx context.Context, id string, updates map[string]interface{}) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "event_webhook_deliveries", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update event webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveriesByWebhook retrieves a webhook's latest deliveries, newest first
+func (r *EventWebhookRepository) GetDeliveriesByWebhook(ctx context.Context, webhookID string, limit int) ([]models.EventWebhookDelivery, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "event_webhook_deliveries", map[string]string{
+		"select":     "*",
+		"webhook_id": fmt.Sprintf("eq.%s", webhookID),
+		"order":      "created_at.desc",
+		"limit":      fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event webhook deliveries: %w", err)
+	}
+
+	var deliveries []models.EventWebhookDelivery
+	if err := json.Unmarshal(data, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to parse event webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetDueDeliveries retrieves pending deliveries whose next attempt is due at now, oldest first
+func (r *EventWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.EventWebhookDelivery, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "event_webhook_deliveries", map[string]string{
+		"select":          "*",
+		"status":          fmt.Sprintf("eq.%s", models.DeliveryPending),
+		"next_attempt_at": fmt.Sprintf("lte.%s", now.UTC().Format(time.RFC3339)),
+		"order":           "next_attempt_at.asc",
+		"limit":           fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due event webhook deliveries: %w", err)
+	}
+
+	var deliveries []models.EventWebhookDelivery
+	if err := json.Unmarshal(data, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to parse due event webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// FlowExecutionLogRepository handles flow_execution_logs data operations
+type FlowExecutionLogRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewFlowExecutionLogRepository creates a new flow execution log repository
+func NewFlowExecutionLogRepository(supabase *database.SupabaseClient) *FlowExecutionLogRepository {
+	return &FlowExecutionLogRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordLog stores a flow execution log entry
+func (r *FlowExecutionLogRepository) RecordLog(ctx context.Context, entry *models.FlowExecutionLog) error {
+	entry.ID = uuid.New().String()
+	if _, err := r.supabase.InsertAsAdmin(ctx, "flow_execution_logs", entry); err != nil {
+		return fmt.Errorf("failed to record flow execution log: %w", err)
+	}
+	return nil
+}
+
+// GetLogsByConversation retrieves a conversation's latest execution log entries, oldest first
+func (r *FlowExecutionLogRepository) GetLogsByConversation(ctx context.Context, source, conversationID string, limit int) ([]models.FlowExecutionLog, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "flow_execution_logs", map[string]string{
+		"select":          "*",
+		"source":          fmt.Sprintf("eq.%s", source),
+		"conversation_id": fmt.Sprintf("eq.%s", conversationID),
+		"order":           "created_at.desc",
+		"limit":           fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flow execution logs: %w", err)
+	}
+
+	var entries []models.FlowExecutionLog
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse flow execution logs: %w", err)
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
@@ -31,7 +31,7 @@ func (r *FlowRepository) CreateFlow(ctx context.Context, flow *models.ChatbotFlo
 	flow.UpdatedAt = time.Now()
 
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("chatbot_flows", flow)
+	data, err := r.supabase.InsertAsAdmin(ctx, "chatbot_flows", flow)
 	if err != nil {
 		return fmt.Errorf("failed to create flow: %w", err)
 	}
@@ -51,7 +51,7 @@ func (r *FlowRepository) CreateFlow(ctx context.Context, flow *models.ChatbotFlo
 
 // GetFlowByID retrieves a flow by ID
 func (r *FlowRepository) GetFlowByID(ctx context.Context, flowID string) (*models.ChatbotFlow, error) {
-	data, err := r.supabase.QueryAsAdmin("chatbot_flows", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"select": "*",
 		"id":     fmt.Sprintf("eq.%s", flowID),
 		"limit":  "1",
@@ -74,7 +74,7 @@ func (r *FlowRepository) GetFlowByID(ctx context.Context, flowID string) (*model
 
 // GetFlowsByDeviceID retrieves all flows for a device
 func (r *FlowRepository) GetFlowsByDeviceID(ctx context.Context, deviceID string) ([]models.ChatbotFlow, error) {
-	data, err := r.supabase.QueryAsAdmin("chatbot_flows", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
 		"order":     "created_at.desc",
@@ -112,7 +112,7 @@ func (r *FlowRepository) GetAllFlowsByUserDevices(ctx context.Context, deviceIDs
 
 // GetAllFlows retrieves all flows (admin only)
 func (r *FlowRepository) GetAllFlows(ctx context.Context) ([]models.ChatbotFlow, error) {
-	data, err := r.supabase.QueryAsAdmin("chatbot_flows", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"select": "*",
 		"order":  "created_at.desc",
 	})
@@ -133,7 +133,7 @@ func (r *FlowRepository) UpdateFlow(ctx context.Context, flowID string, updates
 	// Add updated_at timestamp
 	updates["updated_at"] = time.Now()
 
-	_, err := r.supabase.UpdateAsAdmin("chatbot_flows", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"id": flowID,
 	}, updates)
 
@@ -147,7 +147,7 @@ func (r *FlowRepository) UpdateFlow(ctx context.Context, flowID string, updates
 // DeleteFlow deletes a flow
 func (r *FlowRepository) DeleteFlow(ctx context.Context, flowID string) error {
 	// Use DeleteAsAdmin to bypass RLS policies
-	err := r.supabase.DeleteAsAdmin("chatbot_flows", map[string]string{
+	err := r.supabase.DeleteAsAdmin(ctx, "chatbot_flows", map[string]string{
 		"id": flowID,
 	})
 
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// flowVersionListColumns are the columns of a version list, without the snapshot
+const flowVersionListColumns = "id,flow_id,version,name,node_count,reason,created_by,created_at"
+
+// FlowVersionRepository handles chatbot_flow_versions data operations
+type FlowVersionRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewFlowVersionRepository creates a new flow version repository
+func NewFlowVersionRepository(supabase *database.SupabaseClient) *FlowVersionRepository {
+	return &FlowVersionRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordVersion stores a snapshot as the flow's next version
+func (r *FlowVersionRepository) RecordVersion(ctx context.Context, version *models.FlowVersion) error {
+	latest, err := r.latestVersion(ctx, version.FlowID)
+	if err != nil {
+		return err
+	}
+
+	version.ID = uuid.New().String()
+	version.Version = latest + 1
+	if _, err := r.supabase.InsertAsAdmin(ctx, "chatbot_flow_versions", version); err != nil {
+		return fmt.Errorf("failed to record flow version: %w", err)
+	}
+	return nil
+}
+
+// latestVersion returns the highest version number of a flow, 0 when it has none
+func (r *FlowVersionRepository) latestVersion(ctx context.Context, flowID string) (int, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flow_versions", map[string]string{
+		"select":  "version",
+		"flow_id": fmt.Sprintf("eq.%s", flowID),
+		"order":   "version.desc",
+		"limit":   "1",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest flow version: %w", err)
+	}
+
+	var versions []models.FlowVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return 0, fmt.Errorf("failed to parse flow versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0].Version, nil
+}
+
+// ListVersions returns a flow's versions without their snapshots, newest first
+func (r *FlowVersionRepository) ListVersions(ctx context.Context, flowID string) ([]models.FlowVersion, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flow_versions", map[string]string{
+		"select":  flowVersionListColumns,
+		"flow_id": fmt.Sprintf("eq.%s", flowID),
+		"order":   "version.desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flow versions: %w", err)
+	}
+
+	var versions []models.FlowVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse flow versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion returns one version of a flow with its snapshot, nil if it does not exist
+func (r *FlowVersionRepository) GetVersion(ctx context.Context, flowID string, version int) (*models.FlowVersion, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "chatbot_flow_versions", map[string]string{
+		"select":  "*",
+		"flow_id": fmt.Sprintf("eq.%s", flowID),
+		"version": fmt.Sprintf("eq.%d", version),
+		"limit":   "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flow version: %w", err)
+	}
+
+	var versions []models.FlowVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse flow version: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return &versions[0], nil
+}
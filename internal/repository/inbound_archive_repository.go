@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// inboundArchiveBucket is the private storage bucket raw inbound webhook payloads are kept in
+const inboundArchiveBucket = "inbound-archive"
+
+// InboundArchiveRepository handles inbound_archives rows and their payloads in storage
+type InboundArchiveRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewInboundArchiveRepository creates a new inbound archive repository
+func NewInboundArchiveRepository(supabase *database.SupabaseClient) *InboundArchiveRepository {
+	return &InboundArchiveRepository{
+		supabase: supabase,
+	}
+}
+
+// UploadPayload stores a gzipped payload at path
+func (r *InboundArchiveRepository) UploadPayload(ctx context.Context, path string, data []byte) error {
+	if err := r.supabase.UploadObject(ctx, inboundArchiveBucket, path, "application/gzip", data); err != nil {
+		return fmt.Errorf("failed to upload inbound payload: %w", err)
+	}
+	return nil
+}
+
+// DownloadPayload reads a gzipped payload
+func (r *InboundArchiveRepository) DownloadPayload(ctx context.Context, path string) ([]byte, error) {
+	data, err := r.supabase.DownloadObject(ctx, inboundArchiveBucket, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download inbound payload: %w", err)
+	}
+	return data, nil
+}
+
+// CreateArchive indexes an uploaded payload
+func (r *InboundArchiveRepository) CreateArchive(ctx context.Context, archive *models.InboundArchive) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "inbound_archives", archive); err != nil {
+		return fmt.Errorf("failed to create inbound archive: %w", err)
+	}
+	return nil
+}
+
+// GetArchives retrieves a device's archived payloads received in [from, to), newest first. Nil
+// bounds are open.
+func (r *InboundArchiveRepository) GetArchives(ctx context.Context, idDevice string, from, to *time.Time, limit int) ([]models.InboundArchive, error) {
+	params := map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("eq.%s", idDevice),
+		"order":     "received_at.desc",
+		"limit":     fmt.Sprintf("%d", limit),
+	}
+
+	var conditions []string
+	if from != nil {
+		conditions = append(conditions, fmt.Sprintf("received_at.gte.%s", from.UTC().Format(time.RFC3339)))
+	}
+	if to != nil {
+		conditions = append(conditions, fmt.Sprintf("received_at.lt.%s", to.UTC().Format(time.RFC3339)))
+	}
+	if len(conditions) > 0 {
+		params["and"] = "(" + strings.Join(conditions, ",") + ")"
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "inbound_archives", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound archives: %w", err)
+	}
+
+	var archives []models.InboundArchive
+	if err := json.Unmarshal(data, &archives); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound archives: %w", err)
+	}
+
+	return archives, nil
+}
+
+// GetArchiveByID retrieves an archived payload's index row, or nil when it does not exist
+func (r *InboundArchiveRepository) GetArchiveByID(ctx context.Context, id string) (*models.InboundArchive, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "inbound_archives", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound archive: %w", err)
+	}
+
+	var archives []models.InboundArchive
+	if err := json.Unmarshal(data, &archives); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound archive: %w", err)
+	}
+
+	if len(archives) == 0 {
+		return nil, nil
+	}
+
+	return &archives[0], nil
+}
+
+// GetExpiredArchives retrieves up to limit archives whose retention ended before now, oldest first
+func (r *InboundArchiveRepository) GetExpiredArchives(ctx context.Context, now time.Time, limit int) ([]models.InboundArchive, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "inbound_archives", map[string]string{
+		"select":     "*",
+		"expires_at": fmt.Sprintf("lt.%s", now.UTC().Format(time.RFC3339)),
+		"order":      "expires_at.asc",
+		"limit":      fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired inbound archives: %w", err)
+	}
+
+	var archives []models.InboundArchive
+	if err := json.Unmarshal(data, &archives); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound archives: %w", err)
+	}
+
+	return archives, nil
+}
+
+// DeleteArchives removes archived payloads from storage and then their index rows
+func (r *InboundArchiveRepository) DeleteArchives(ctx context.Context, archives []models.InboundArchive) error {
+	paths := make([]string, 0, len(archives))
+	for _, archive := range archives {
+		paths = append(paths, archive.ObjectPath)
+	}
+	if err := r.supabase.DeleteObjects(ctx, inboundArchiveBucket, paths); err != nil {
+		return fmt.Errorf("failed to delete inbound payloads: %w", err)
+	}
+
+	for _, archive := range archives {
+		if err := r.supabase.DeleteAsAdmin(ctx, "inbound_archives", map[string]string{
+			"id": archive.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to delete inbound archive: %w", err)
+		}
+	}
+
+	return nil
+}
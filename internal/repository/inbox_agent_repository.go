@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// InboxAgentRepository handles inbox_agents and agent_assignments data operations
+type InboxAgentRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewInboxAgentRepository creates a new inbox agent repository
+func NewInboxAgentRepository(supabase *database.SupabaseClient) *InboxAgentRepository {
+	return &InboxAgentRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateAgent stores a new agent
+func (r *InboxAgentRepository) CreateAgent(ctx context.Context, agent *models.InboxAgent) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "inbox_agents", agent)
+	if err != nil {
+		return fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	var agents []models.InboxAgent
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return fmt.Errorf("failed to parse created agent: %w", err)
+	}
+
+	if len(agents) > 0 {
+		*agent = agents[0]
+	}
+
+	return nil
+}
+
+// GetAgentsByUser retrieves a user's agents by name
+func (r *InboxAgentRepository) GetAgentsByUser(ctx context.Context, userID string) ([]models.InboxAgent, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "inbox_agents", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "name.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agents: %w", err)
+	}
+
+	var agents []models.InboxAgent
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return nil, fmt.Errorf("failed to parse agents: %w", err)
+	}
+
+	return agents, nil
+}
+
+// GetAgentByID retrieves an agent by ID, or nil when it does not exist
+func (r *InboxAgentRepository) GetAgentByID(ctx context.Context, id string) (*models.InboxAgent, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "inbox_agents", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent: %w", err)
+	}
+
+	var agents []models.InboxAgent
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return nil, fmt.Errorf("failed to parse agent: %w", err)
+	}
+
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	return &agents[0], nil
+}
+
+// UpdateAgent updates an agent
+func (r *InboxAgentRepository) UpdateAgent(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "inbox_agents", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update agent: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAgent deletes an agent. Its assignments are kept for the history.
+func (r *InboxAgentRepository) DeleteAgent(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "inbox_agents", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete agent: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAssignment records an agent taking over a conversation
+func (r *InboxAgentRepository) CreateAssignment(ctx context.Context, assignment *models.AgentAssignment) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "agent_assignments", assignment)
+	if err != nil {
+		return fmt.Errorf("failed to create assignment: %w", err)
+	}
+
+	var assignments []models.AgentAssignment
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return fmt.Errorf("failed to parse created assignment: %w", err)
+	}
+
+	if len(assignments) > 0 {
+		*assignment = assignments[0]
+	}
+
+	return nil
+}
+
+// GetAssignmentByID retrieves an assignment by ID, or nil when it does not exist
+func (r *InboxAgentRepository) GetAssignmentByID(ctx context.Context, id string) (*models.AgentAssignment, error) {
+	assignments, err := r.getAssignments(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil || len(assignments) == 0 {
+		return nil, err
+	}
+
+	return &assignments[0], nil
+}
+
+// GetAssignmentHistory retrieves a prospect's assignments on a device, latest first
+func (r *InboxAgentRepository) GetAssignmentHistory(ctx context.Context, idDevice, prospectNum string, limit int) ([]models.AgentAssignment, error) {
+	return r.getAssignments(ctx, map[string]string{
+		"select":       "*",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"order":        "assigned_at.desc",
+		"limit":        fmt.Sprintf("%d", limit),
+	})
+}
+
+// GetOpenAssignments retrieves a conversation's assignments that were not released
+func (r *InboxAgentRepository) GetOpenAssignments(ctx context.Context, source, conversationID string) ([]models.AgentAssignment, error) {
+	return r.getAssignments(ctx, map[string]string{
+		"select":          "*",
+		"source":          fmt.Sprintf("eq.%s", source),
+		"conversation_id": fmt.Sprintf("eq.%s", conversationID),
+		"released_at":     "is.null",
+	})
+}
+
+func (r *InboxAgentRepository) getAssignments(ctx context.Context, params map[string]string) ([]models.AgentAssignment, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "agent_assignments", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignments: %w", err)
+	}
+
+	var assignments []models.AgentAssignment
+	if err := json.Unmarshal(data, &assignments); err != nil {
+		return nil, fmt.Errorf("failed to parse assignments: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// UpdateAssignment updates an assignment
+func (r *InboxAgentRepository) UpdateAssignment(ctx context.Context, id string, updates map[string]interface{}) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "agent_assignments", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update assignment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LinkTrackingRepository handles tracked_links and link_clicks data operations
+type LinkTrackingRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewLinkTrackingRepository creates a new link tracking repository
+func NewLinkTrackingRepository(supabase *database.SupabaseClient) *LinkTrackingRepository {
+	return &LinkTrackingRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateLink stores a short link for one URL sent by a flow node
+func (r *LinkTrackingRepository) CreateLink(ctx context.Context, link *models.TrackedLink) error {
+	link.ID = uuid.New().String()
+	link.CreatedAt = time.Now()
+
+	if _, err := r.supabase.InsertAsAdmin(ctx, "tracked_links", link); err != nil {
+		return fmt.Errorf("failed to create tracked link: %w", err)
+	}
+	return nil
+}
+
+// GetLinkByCode retrieves a tracked link by its short code
+func (r *LinkTrackingRepository) GetLinkByCode(ctx context.Context, code string) (*models.TrackedLink, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "tracked_links", map[string]string{
+		"select": "*",
+		"code":   fmt.Sprintf("eq.%s", code),
+		"limit":  "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked link: %w", err)
+	}
+
+	var links []models.TrackedLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("failed to parse tracked link: %w", err)
+	}
+
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	return &links[0], nil
+}
+
+// RecordClick logs a click and updates the link's click counters
+func (r *LinkTrackingRepository) RecordClick(ctx context.Context, link *models.TrackedLink, userAgent string, clickedAt time.Time) error {
+	click := &models.LinkClick{
+		LinkID:    link.ID,
+		IDDevice:  link.IDDevice,
+		FlowID:    link.FlowID,
+		NodeID:    link.NodeID,
+		UserAgent: userAgent,
+		ClickedAt: clickedAt,
+	}
+	if _, err := r.supabase.InsertAsAdmin(ctx, "link_clicks", click); err != nil {
+		return fmt.Errorf("failed to record link click: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"click_count":     link.ClickCount + 1,
+		"last_clicked_at": clickedAt,
+	}
+	if link.FirstClickedAt == nil {
+		updates["first_clicked_at"] = clickedAt
+	}
+
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "tracked_links", map[string]string{
+		"id": fmt.Sprintf("eq.%s", link.ID),
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update tracked link: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MessageRepository handles conversation_messages data operations
+type MessageRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewMessageRepository creates a new conversation message repository
+func NewMessageRepository(supabase *database.SupabaseClient) *MessageRepository {
+	return &MessageRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateMessage stores one message of a conversation's history
+func (r *MessageRepository) CreateMessage(ctx context.Context, message *models.ConversationMessage) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "conversation_messages", message); err != nil {
+		return fmt.Errorf("failed to create conversation message: %w", err)
+	}
+
+	return nil
+}
+
+// GetMessages retrieves the latest limit messages of a conversation, oldest first
+func (r *MessageRepository) GetMessages(ctx context.Context, source, conversationID string, limit int) ([]models.ConversationMessage, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "conversation_messages", map[string]string{
+		"select":          "*",
+		"source":          fmt.Sprintf("eq.%s", source),
+		"conversation_id": fmt.Sprintf("eq.%s", conversationID),
+		"order":           "created_at.desc,id.desc",
+		"limit":           fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation messages: %w", err)
+	}
+
+	var messages []models.ConversationMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation messages: %w", err)
+	}
+
+	// Fetched newest first so the limit keeps the latest ones
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// GetDeviceMessages retrieves a page of a device's messages created since a time, without their
+// content. Messages are grouped by conversation and oldest first within each.
+func (r *MessageRepository) GetDeviceMessages(ctx context.Context, idDevice string, since time.Time, offset, limit int) ([]models.ConversationMessage, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "conversation_messages", map[string]string{
+		"select":     "source,conversation_id,id_device,role,created_at",
+		"id_device":  fmt.Sprintf("eq.%s", idDevice),
+		"created_at": fmt.Sprintf("gte.%s", since.UTC().Format(time.RFC3339)),
+		"order":      "source.asc,conversation_id.asc,created_at.asc,id.asc",
+		"offset":     fmt.Sprintf("%d", offset),
+		"limit":      fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device messages: %w", err)
+	}
+
+	var messages []models.ConversationMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse device messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// DeleteMessages deletes a conversation's history
+func (r *MessageRepository) DeleteMessages(ctx context.Context, source, conversationID string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "conversation_messages", map[string]string{
+		"source":          source,
+		"conversation_id": conversationID,
+	}); err != nil {
+		return fmt.Errorf("failed to delete conversation messages: %w", err)
+	}
+
+	return nil
+}
+
+// MoveMessages moves a conversation's history to another conversation of the same source, when
+// duplicates are merged
+func (r *MessageRepository) MoveMessages(ctx context.Context, source, fromID, toID string) error {
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "conversation_messages", map[string]string{
+		"source":          source,
+		"conversation_id": fromID,
+	}, map[string]interface{}{"conversation_id": toID}); err != nil {
+		return fmt.Errorf("failed to move conversation messages: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MessageTemplateRepository handles message_templates data operations
+type MessageTemplateRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewMessageTemplateRepository creates a new message template repository
+func NewMessageTemplateRepository(supabase *database.SupabaseClient) *MessageTemplateRepository {
+	return &MessageTemplateRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateTemplate stores a new message template
+func (r *MessageTemplateRepository) CreateTemplate(ctx context.Context, template *models.MessageTemplate) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "message_templates", template)
+	if err != nil {
+		return fmt.Errorf("failed to create message template: %w", err)
+	}
+
+	var templates []models.MessageTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return fmt.Errorf("failed to parse created message template: %w", err)
+	}
+
+	if len(templates) > 0 {
+		*template = templates[0]
+	}
+
+	return nil
+}
+
+// GetTemplatesByUser retrieves a user's message templates by name
+func (r *MessageTemplateRepository) GetTemplatesByUser(ctx context.Context, userID string) ([]models.MessageTemplate, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "message_templates", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "name.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message templates: %w", err)
+	}
+
+	var templates []models.MessageTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse message templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// GetTemplateByID retrieves a message template by ID, or nil when it does not exist
+func (r *MessageTemplateRepository) GetTemplateByID(ctx context.Context, id string) (*models.MessageTemplate, error) {
+	return r.getTemplate(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+}
+
+// GetTemplateByName retrieves one of a user's message templates by name, or nil when it does not exist
+func (r *MessageTemplateRepository) GetTemplateByName(ctx context.Context, userID, name string) (*models.MessageTemplate, error) {
+	return r.getTemplate(ctx, map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"name":    fmt.Sprintf("eq.%s", name),
+	})
+}
+
+func (r *MessageTemplateRepository) getTemplate(ctx context.Context, params map[string]string) (*models.MessageTemplate, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "message_templates", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message template: %w", err)
+	}
+
+	var templates []models.MessageTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse message template: %w", err)
+	}
+
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	return &templates[0], nil
+}
+
+// UpdateTemplate updates a message template
+func (r *MessageTemplateRepository) UpdateTemplate(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "message_templates", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update message template: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTemplate deletes a message template
+func (r *MessageTemplateRepository) DeleteTemplate(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "message_templates", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete message template: %w", err)
+	}
+
+	return nil
+}
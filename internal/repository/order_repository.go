@@ -28,7 +28,7 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 	order.UpdatedAt = time.Now()
 
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("orders", order)
+	data, err := r.supabase.InsertAsAdmin(ctx, "orders", order)
 	if err != nil {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
@@ -48,7 +48,7 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 
 // GetOrderByID retrieves an order by ID
 func (r *OrderRepository) GetOrderByID(ctx context.Context, id int) (*models.Order, error) {
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select": "*",
 		"id":     fmt.Sprintf("eq.%d", id),
 	})
@@ -70,7 +70,7 @@ func (r *OrderRepository) GetOrderByID(ctx context.Context, id int) (*models.Ord
 
 // GetOrderByBillID retrieves an order by Billplz bill ID
 func (r *OrderRepository) GetOrderByBillID(ctx context.Context, billID string) (*models.Order, error) {
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select":  "*",
 		"bill_id": fmt.Sprintf("eq.%s", billID),
 	})
@@ -92,7 +92,7 @@ func (r *OrderRepository) GetOrderByBillID(ctx context.Context, billID string) (
 
 // GetOrdersByUserID retrieves all orders for a user
 func (r *OrderRepository) GetOrdersByUserID(ctx context.Context, userID string) ([]models.Order, error) {
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select":  "*",
 		"user_id": fmt.Sprintf("eq.%s", userID),
 		"order":   "created_at.desc",
@@ -112,7 +112,7 @@ func (r *OrderRepository) GetOrdersByUserID(ctx context.Context, userID string)
 // GetOrdersByUserIDFiltered retrieves orders for a user with date filtering
 func (r *OrderRepository) GetOrdersByUserIDFiltered(ctx context.Context, userID, fromDate, toDate string) ([]models.Order, error) {
 	// Get all orders for user first
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select":  "*",
 		"user_id": fmt.Sprintf("eq.%s", userID),
 		"order":   "created_at.desc",
@@ -165,7 +165,7 @@ func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, id int, status
 		"id": fmt.Sprintf("%d", id),
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("orders", filter, update)
+	_, err := r.supabase.UpdateAsAdmin(ctx, "orders", filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
@@ -186,7 +186,7 @@ func (r *OrderRepository) UpdateOrderBillplzData(ctx context.Context, id int, bi
 		"id": fmt.Sprintf("%d", id),
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("orders", filter, update)
+	_, err := r.supabase.UpdateAsAdmin(ctx, "orders", filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update order Billplz data: %w", err)
 	}
@@ -205,7 +205,7 @@ func (r *OrderRepository) UpdateOrderPaymentComplete(ctx context.Context, billID
 		"bill_id": billID,
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("orders", filter, update)
+	_, err := r.supabase.UpdateAsAdmin(ctx, "orders", filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update order payment: %w", err)
 	}
@@ -224,7 +224,7 @@ func (r *OrderRepository) UpdateOrderPaymentFailed(ctx context.Context, billID s
 		"bill_id": billID,
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("orders", filter, update)
+	_, err := r.supabase.UpdateAsAdmin(ctx, "orders", filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update order payment: %w", err)
 	}
@@ -234,7 +234,7 @@ func (r *OrderRepository) UpdateOrderPaymentFailed(ctx context.Context, billID s
 
 // GetAllOrders retrieves all orders (for admin)
 func (r *OrderRepository) GetAllOrders(ctx context.Context) ([]models.Order, error) {
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select": "*",
 		"order":  "created_at.desc",
 	})
@@ -253,7 +253,7 @@ func (r *OrderRepository) GetAllOrders(ctx context.Context) ([]models.Order, err
 // GetAllOrdersFiltered retrieves all orders with date filtering (admin only)
 func (r *OrderRepository) GetAllOrdersFiltered(ctx context.Context, fromDate, toDate string) ([]models.Order, error) {
 	// Get all orders first
-	data, err := r.supabase.QueryAsAdmin("orders", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "orders", map[string]string{
 		"select": "*",
 		"order":  "created_at.desc",
 	})
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PromptTemplateRepository handles prompt_templates data operations
+type PromptTemplateRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewPromptTemplateRepository creates a new prompt template repository
+func NewPromptTemplateRepository(supabase *database.SupabaseClient) *PromptTemplateRepository {
+	return &PromptTemplateRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateTemplate stores a new prompt template
+func (r *PromptTemplateRepository) CreateTemplate(ctx context.Context, template *models.PromptTemplate) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "prompt_templates", template)
+	if err != nil {
+		return fmt.Errorf("failed to create prompt template: %w", err)
+	}
+
+	var templates []models.PromptTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return fmt.Errorf("failed to parse created prompt template: %w", err)
+	}
+
+	if len(templates) > 0 {
+		*template = templates[0]
+	}
+
+	return nil
+}
+
+// GetTemplatesByUser retrieves a user's prompt templates by name
+func (r *PromptTemplateRepository) GetTemplatesByUser(ctx context.Context, userID string) ([]models.PromptTemplate, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "prompt_templates", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "name.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt templates: %w", err)
+	}
+
+	var templates []models.PromptTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// GetTemplateByID retrieves a prompt template by ID, or nil when it does not exist
+func (r *PromptTemplateRepository) GetTemplateByID(ctx context.Context, id string) (*models.PromptTemplate, error) {
+	return r.getTemplate(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+}
+
+// GetTemplateByName retrieves one of a user's prompt templates by name, or nil when it does not exist
+func (r *PromptTemplateRepository) GetTemplateByName(ctx context.Context, userID, name string) (*models.PromptTemplate, error) {
+	return r.getTemplate(ctx, map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"name":    fmt.Sprintf("eq.%s", name),
+	})
+}
+
+func (r *PromptTemplateRepository) getTemplate(ctx context.Context, params map[string]string) (*models.PromptTemplate, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "prompt_templates", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+
+	var templates []models.PromptTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	return &templates[0], nil
+}
+
+// UpdateTemplate updates a prompt template
+func (r *PromptTemplateRepository) UpdateTemplate(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "prompt_templates", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update prompt template: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTemplate deletes a prompt template
+func (r *PromptTemplateRepository) DeleteTemplate(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "prompt_templates", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete prompt template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// prospectColumns are the conversation columns listed for a prospect
+const prospectColumns = "id_prospect,id_device,prospect_num,prospect_phone,prospect_name,niche,stage,execution_status,custom_fields,created_at,updated_at"
+
+// ProspectRepository reads conversations of ai_whatsapp and wasapbot by normalized phone
+type ProspectRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewProspectRepository creates a new prospect repository
+func NewProspectRepository(supabase *database.SupabaseClient) *ProspectRepository {
+	return &ProspectRepository{
+		supabase: supabase,
+	}
+}
+
+// GetProspectConversations retrieves the conversations of a table (ai_whatsapp or wasapbot) on the
+// given devices with a phone, latest first. An empty phone retrieves every conversation that has
+// one, for finding duplicates.
+func (r *ProspectRepository) GetProspectConversations(ctx context.Context, table string, deviceIDs []string, phone string) ([]models.ProspectConversation, error) {
+	if len(deviceIDs) == 0 {
+		return []models.ProspectConversation{}, nil
+	}
+
+	params := map[string]string{
+		"select":         prospectColumns,
+		"id_device":      fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"prospect_phone": "not.is.null",
+		"order":          "updated_at.desc.nullslast",
+	}
+	if phone != "" {
+		params["prospect_phone"] = fmt.Sprintf("eq.%s", phone)
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, table, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s conversations by phone: %w", table, err)
+	}
+
+	var conversations []models.ProspectConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse %s conversations: %w", table, err)
+	}
+	for i := range conversations {
+		conversations[i].Source = table
+	}
+
+	return conversations, nil
+}
+
+// GetProspectConversation retrieves one conversation of a table by id_prospect, nil when not found
+func (r *ProspectRepository) GetProspectConversation(ctx context.Context, table, prospectID string) (*models.ProspectConversation, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, table, map[string]string{
+		"select":      prospectColumns,
+		"id_prospect": fmt.Sprintf("eq.%s", prospectID),
+		"limit":       "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s conversation: %w", table, err)
+	}
+
+	var conversations []models.ProspectConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse %s conversation: %w", table, err)
+	}
+	if len(conversations) == 0 {
+		return nil, nil
+	}
+
+	conversations[0].Source = table
+	return &conversations[0], nil
+}
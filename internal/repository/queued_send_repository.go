@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueuedSendRepository handles queued_sends data operations
+type QueuedSendRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewQueuedSendRepository creates a new queued send repository
+func NewQueuedSendRepository(supabase *database.SupabaseClient) *QueuedSendRepository {
+	return &QueuedSendRepository{
+		supabase: supabase,
+	}
+}
+
+// QueueSend stores a message to send at send.SendAt
+func (r *QueuedSendRepository) QueueSend(ctx context.Context, send *models.QueuedSend) error {
+	send.ID = uuid.New().String()
+	send.Status = models.QueuedSendPending
+	if _, err := r.supabase.InsertAsAdmin(ctx, "queued_sends", send); err != nil {
+		return fmt.Errorf("failed to queue send: %w", err)
+	}
+	return nil
+}
+
+// GetDueSends returns up to limit pending sends whose send_at has passed, oldest first
+func (r *QueuedSendRepository) GetDueSends(ctx context.Context, now time.Time, limit int) ([]models.QueuedSend, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "queued_sends", map[string]string{
+		"select":  "*",
+		"status":  fmt.Sprintf("eq.%s", models.QueuedSendPending),
+		"send_at": fmt.Sprintf("lte.%s", now.UTC().Format(time.RFC3339)),
+		"order":   "send_at.asc,created_at.asc",
+		"limit":   fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due queued sends: %w", err)
+	}
+
+	var sends []models.QueuedSend
+	if err := json.Unmarshal(data, &sends); err != nil {
+		return nil, fmt.Errorf("failed to parse queued sends: %w", err)
+	}
+	return sends, nil
+}
+
+// ClaimSend moves a pending send to running. It reports false when another dispatcher claimed it first.
+func (r *QueuedSendRepository) ClaimSend(ctx context.Context, id string) (bool, error) {
+	data, err := r.supabase.UpdateAsAdmin(ctx, "queued_sends", map[string]string{
+		"id":     id,
+		"status": models.QueuedSendPending,
+	}, map[string]interface{}{
+		"status": models.QueuedSendRunning,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim queued send: %w", err)
+	}
+
+	var claimed []models.QueuedSend
+	if err := json.Unmarshal(data, &claimed); err != nil {
+		return false, fmt.Errorf("failed to parse claimed queued send: %w", err)
+	}
+	return len(claimed) > 0, nil
+}
+
+// FinishSend records how a claimed send ended; errMessage is stored for failed ones
+func (r *QueuedSendRepository) FinishSend(ctx context.Context, id, status, errMessage string) error {
+	updates := map[string]interface{}{
+		"status":      status,
+		"finished_at": time.Now(),
+	}
+	if errMessage != "" {
+		updates["last_error"] = errMessage
+	}
+
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "queued_sends", map[string]string{"id": id}, updates); err != nil {
+		return fmt.Errorf("failed to finish queued send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ResponseLatencyRepository handles response_latency data operations
+type ResponseLatencyRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewResponseLatencyRepository creates a new response latency repository
+func NewResponseLatencyRepository(supabase *database.SupabaseClient) *ResponseLatencyRepository {
+	return &ResponseLatencyRepository{
+		supabase: supabase,
+	}
+}
+
+// getOpenCycle returns the unanswered cycle for a prospect, or nil if there is none
+func (r *ResponseLatencyRepository) getOpenCycle(ctx context.Context, idDevice, prospectNum string) (*models.ResponseLatency, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "response_latency", map[string]string{
+		"select":       "*",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"replied_at":   "is.null",
+		"order":        "inbound_at.asc",
+		"limit":        "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open latency cycle: %w", err)
+	}
+
+	var rows []models.ResponseLatency
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse latency cycle: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return &rows[0], nil
+}
+
+// OpenCycle starts the latency clock for an inbound message.
+// Does nothing when an earlier inbound message is still unanswered.
+func (r *ResponseLatencyRepository) OpenCycle(ctx context.Context, idDevice, prospectNum string, flowID *string, inboundAt time.Time) error {
+	open, err := r.getOpenCycle(ctx, idDevice, prospectNum)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		return nil
+	}
+
+	// First cycle for this prospect on the device?
+	data, err := r.supabase.QueryAsAdmin(ctx, "response_latency", map[string]string{
+		"select":       "id",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"limit":        "1",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check previous latency cycles: %w", err)
+	}
+
+	var previous []models.ResponseLatency
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return fmt.Errorf("failed to parse previous latency cycles: %w", err)
+	}
+
+	cycle := &models.ResponseLatency{
+		IDDevice:    idDevice,
+		FlowID:      flowID,
+		ProspectNum: prospectNum,
+		IsFirst:     len(previous) == 0,
+		InboundAt:   inboundAt,
+	}
+
+	if _, err := r.supabase.InsertAsAdmin(ctx, "response_latency", cycle); err != nil {
+		return fmt.Errorf("failed to open latency cycle: %w", err)
+	}
+
+	return nil
+}
+
+// CloseCycle stops the latency clock with the first reply to the open cycle.
+// Does nothing when no inbound message is waiting for a reply.
+func (r *ResponseLatencyRepository) CloseCycle(ctx context.Context, idDevice, prospectNum, responder, agent string, repliedAt time.Time) error {
+	open, err := r.getOpenCycle(ctx, idDevice, prospectNum)
+	if err != nil {
+		return err
+	}
+	if open == nil {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"responder":  responder,
+		"replied_at": repliedAt,
+		"latency_ms": repliedAt.Sub(open.InboundAt).Milliseconds(),
+	}
+	if agent != "" {
+		updates["agent"] = agent
+	}
+
+	if responder == models.ResponderHuman {
+		afterHandoff, err := r.isHandoff(ctx, idDevice, prospectNum)
+		if err != nil {
+			return err
+		}
+		updates["first_after_handoff"] = afterHandoff
+	}
+
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "response_latency", map[string]string{
+		"id": open.ID,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to close latency cycle: %w", err)
+	}
+
+	return nil
+}
+
+// isHandoff reports whether a human reply now takes the prospect over from the bot,
+// i.e. the last answered cycle was the bot's (or there was none)
+func (r *ResponseLatencyRepository) isHandoff(ctx context.Context, idDevice, prospectNum string) (bool, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "response_latency", map[string]string{
+		"select":       "responder",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"replied_at":   "not.is.null",
+		"order":        "replied_at.desc",
+		"limit":        "1",
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get last latency cycle: %w", err)
+	}
+
+	var rows []models.ResponseLatency
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to parse last latency cycle: %w", err)
+	}
+
+	if len(rows) == 0 || rows[0].Responder == nil {
+		return true, nil
+	}
+
+	return *rows[0].Responder == models.ResponderBot, nil
+}
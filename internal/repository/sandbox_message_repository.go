@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SandboxMessageRepository handles sandbox_messages data operations
+type SandboxMessageRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewSandboxMessageRepository creates a new sandbox message repository
+func NewSandboxMessageRepository(supabase *database.SupabaseClient) *SandboxMessageRepository {
+	return &SandboxMessageRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordMessage stores an intercepted send
+func (r *SandboxMessageRepository) RecordMessage(ctx context.Context, message *models.SandboxMessage) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "sandbox_messages", message); err != nil {
+		return fmt.Errorf("failed to record sandbox message: %w", err)
+	}
+	return nil
+}
+
+// GetMessagesByDevice retrieves a device's most recent intercepted sends, newest first
+func (r *SandboxMessageRepository) GetMessagesByDevice(ctx context.Context, idDevice string, limit int) ([]models.SandboxMessage, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sandbox_messages", map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("eq.%s", idDevice),
+		"order":     "created_at.desc",
+		"limit":     fmt.Sprintf("%d", limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox messages: %w", err)
+	}
+
+	var messages []models.SandboxMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse sandbox messages: %w", err)
+	}
+
+	return messages, nil
+}
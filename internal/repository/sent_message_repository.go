@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SentMessageRepository handles sent_messages data operations
+type SentMessageRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewSentMessageRepository creates a new sent message repository
+func NewSentMessageRepository(supabase *database.SupabaseClient) *SentMessageRepository {
+	return &SentMessageRepository{
+		supabase: supabase,
+	}
+}
+
+// RecordMessage stores a send and its provider message ID
+func (r *SentMessageRepository) RecordMessage(ctx context.Context, message *models.SentMessage) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "sent_messages", message); err != nil {
+		return fmt.Errorf("failed to record sent message: %w", err)
+	}
+	return nil
+}
+
+// GetByProviderMessageID finds the send a prospect quoted; returns nil when it is not ours
+func (r *SentMessageRepository) GetByProviderMessageID(ctx context.Context, idDevice, prospectNum, providerMessageID string) (*models.SentMessage, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sent_messages", map[string]string{
+		"select":              "*",
+		"id_device":           fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num":        fmt.Sprintf("eq.%s", prospectNum),
+		"provider_message_id": fmt.Sprintf("eq.%s", providerMessageID),
+		"order":               "created_at.desc",
+		"limit":               "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sent message: %w", err)
+	}
+
+	var messages []models.SentMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse sent message: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return &messages[0], nil
+}
+
+// ListSince returns the messages sent to a prospect after since, oldest first
+func (r *SentMessageRepository) ListSince(ctx context.Context, idDevice, prospectNum string, since time.Time) ([]models.SentMessage, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sent_messages", map[string]string{
+		"select":       "*",
+		"id_device":    fmt.Sprintf("eq.%s", idDevice),
+		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
+		"created_at":   fmt.Sprintf("gt.%s", since.UTC().Format(time.RFC3339Nano)),
+		"order":        "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sent messages: %w", err)
+	}
+
+	var messages []models.SentMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse sent messages: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SheetExportRepository handles sheet_exports data operations
+type SheetExportRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewSheetExportRepository creates a new sheet export repository
+func NewSheetExportRepository(supabase *database.SupabaseClient) *SheetExportRepository {
+	return &SheetExportRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateExport stores a new sheet export
+func (r *SheetExportRepository) CreateExport(ctx context.Context, export *models.SheetExport) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "sheet_exports", export)
+	if err != nil {
+		return fmt.Errorf("failed to create sheet export: %w", err)
+	}
+
+	var exports []models.SheetExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return fmt.Errorf("failed to parse created sheet export: %w", err)
+	}
+
+	if len(exports) > 0 {
+		*export = exports[0]
+	}
+
+	return nil
+}
+
+// GetExportsByUser retrieves a user's sheet exports, oldest first
+func (r *SheetExportRepository) GetExportsByUser(ctx context.Context, userID string) ([]models.SheetExport, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sheet_exports", map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+		"order":   "created_at.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sheet exports: %w", err)
+	}
+
+	var exports []models.SheetExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return nil, fmt.Errorf("failed to parse sheet exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// GetSyncedExports retrieves the active sheet exports with a scheduled sync
+func (r *SheetExportRepository) GetSyncedExports(ctx context.Context) ([]models.SheetExport, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sheet_exports", map[string]string{
+		"select":     "*",
+		"sync_table": "not.is.null",
+		"is_active":  "eq.true",
+		"order":      "last_synced_at.asc.nullsfirst",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get synced sheet exports: %w", err)
+	}
+
+	var exports []models.SheetExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return nil, fmt.Errorf("failed to parse synced sheet exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// GetExportByID retrieves a sheet export by ID, or nil when it does not exist
+func (r *SheetExportRepository) GetExportByID(ctx context.Context, id string) (*models.SheetExport, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "sheet_exports", map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sheet export: %w", err)
+	}
+
+	var exports []models.SheetExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return nil, fmt.Errorf("failed to parse sheet export: %w", err)
+	}
+
+	if len(exports) == 0 {
+		return nil, nil
+	}
+
+	return &exports[0], nil
+}
+
+// UpdateExport updates a sheet export
+func (r *SheetExportRepository) UpdateExport(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "sheet_exports", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update sheet export: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExport deletes a sheet export
+func (r *SheetExportRepository) DeleteExport(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "sheet_exports", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete sheet export: %w", err)
+	}
+
+	return nil
+}
@@ -26,7 +26,7 @@ func (r *StageRepository) CreateStageValue(ctx context.Context, stage *models.St
 	stageJSON, _ := json.Marshal(stage)
 	fmt.Printf("🔍 Creating stage value: %s\n", string(stageJSON))
 
-	data, err := r.supabase.InsertAsAdmin("stagesetvalue", stage)
+	data, err := r.supabase.InsertAsAdmin(ctx, "stagesetvalue", stage)
 	if err != nil {
 		fmt.Printf("❌ Database error: %v\n", err)
 		return fmt.Errorf("failed to create stage value: %w", err)
@@ -52,7 +52,7 @@ func (r *StageRepository) CreateStageValue(ctx context.Context, stage *models.St
 
 // GetStageValueByID retrieves a stage value by ID
 func (r *StageRepository) GetStageValueByID(ctx context.Context, stageID int) (*models.StageValue, error) {
-	data, err := r.supabase.QueryAsAdmin("stagesetvalue", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "stagesetvalue", map[string]string{
 		"select":            "*",
 		"stagesetvalue_id": fmt.Sprintf("eq.%d", stageID),
 		"limit":             "1",
@@ -75,7 +75,7 @@ func (r *StageRepository) GetStageValueByID(ctx context.Context, stageID int) (*
 
 // GetAllStageValues retrieves all stage values
 func (r *StageRepository) GetAllStageValues(ctx context.Context) ([]models.StageValue, error) {
-	data, err := r.supabase.QueryAsAdmin("stagesetvalue", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "stagesetvalue", map[string]string{
 		"select": "*",
 		"order":  "stagesetvalue_id.desc",
 	})
@@ -91,9 +91,47 @@ func (r *StageRepository) GetAllStageValues(ctx context.Context) ([]models.Stage
 	return stages, nil
 }
 
+// GetStageValuesByDevice retrieves all stage values configured for a device
+func (r *StageRepository) GetStageValuesByDevice(ctx context.Context, idDevice string) ([]models.StageValue, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "stagesetvalue", map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("eq.%s", idDevice),
+		"order":     "stagesetvalue_id.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stage values: %w", err)
+	}
+
+	var stages []models.StageValue
+	if err := json.Unmarshal(data, &stages); err != nil {
+		return nil, fmt.Errorf("failed to parse stage values: %w", err)
+	}
+
+	return stages, nil
+}
+
+// GetSLAStageValues retrieves all stage values with a maximum dwell time
+func (r *StageRepository) GetSLAStageValues(ctx context.Context) ([]models.StageValue, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "stagesetvalue", map[string]string{
+		"select":      "*",
+		"sla_minutes": "gt.0",
+		"order":       "stagesetvalue_id.asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SLA stage values: %w", err)
+	}
+
+	var stages []models.StageValue
+	if err := json.Unmarshal(data, &stages); err != nil {
+		return nil, fmt.Errorf("failed to parse stage values: %w", err)
+	}
+
+	return stages, nil
+}
+
 // UpdateStageValue updates a stage value
 func (r *StageRepository) UpdateStageValue(ctx context.Context, stageID int, updates map[string]interface{}) error {
-	_, err := r.supabase.UpdateAsAdmin("stagesetvalue", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "stagesetvalue", map[string]string{
 		"stagesetvalue_id": fmt.Sprintf("%d", stageID),
 	}, updates)
 
@@ -106,7 +144,7 @@ func (r *StageRepository) UpdateStageValue(ctx context.Context, stageID int, upd
 
 // DeleteStageValue deletes a stage value
 func (r *StageRepository) DeleteStageValue(ctx context.Context, stageID int) error {
-	err := r.supabase.DeleteAsAdmin("stagesetvalue", map[string]string{
+	err := r.supabase.DeleteAsAdmin(ctx, "stagesetvalue", map[string]string{
 		"stagesetvalue_id": fmt.Sprintf("%d", stageID),
 	})
 
@@ -121,7 +159,7 @@ func (r *StageRepository) DeleteStageValue(ctx context.Context, stageID int) err
 func (r *StageRepository) GetStageConfigByDeviceAndStage(ctx context.Context, deviceID, stageName string) (*models.StageValue, error) {
 	fmt.Printf("🔍 [StageRepo] Querying stagesetvalue: id_device=%s, stage=%s\n", deviceID, stageName)
 
-	data, err := r.supabase.QueryAsAdmin("stagesetvalue", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "stagesetvalue", map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
 		"stage":     fmt.Sprintf("eq.%s", stageName),
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// StageSLARepository handles stage_sla_breaches data operations
+type StageSLARepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewStageSLARepository creates a new stage SLA repository
+func NewStageSLARepository(supabase *database.SupabaseClient) *StageSLARepository {
+	return &StageSLARepository{
+		supabase: supabase,
+	}
+}
+
+// RecordBreach appends an SLA breach to the log used by analytics
+func (r *StageSLARepository) RecordBreach(ctx context.Context, breach *models.StageSLABreach) error {
+	if _, err := r.supabase.InsertAsAdmin(ctx, "stage_sla_breaches", breach); err != nil {
+		return fmt.Errorf("failed to record stage SLA breach: %w", err)
+	}
+	return nil
+}
+
+// stageSLABreachParams builds the filter for conversations that entered a stage before
+// the SLA cutoff, have not been flagged yet and are not finished
+func stageSLABreachParams(deviceID, stage string, enteredBefore time.Time) map[string]string {
+	return map[string]string{
+		"select":           "*",
+		"id_device":        fmt.Sprintf("eq.%s", deviceID),
+		"stage":            fmt.Sprintf("eq.%s", stage),
+		"stage_entered_at": fmt.Sprintf("lt.%s", enteredBefore.UTC().Format(time.RFC3339)),
+		"sla_breached_at":  "is.null",
+		"or": fmt.Sprintf("(execution_status.is.null,execution_status.not.in.(%s,%s,%s))",
+			models.ConversationStateCompleted, models.ConversationStateAbandoned, models.ConversationStateFailedMaxSteps),
+		"order": "stage_entered_at.asc",
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TeamRepository handles team_members data operations
+type TeamRepository struct {
+	supabase *database.SupabaseClient
+}
+
+// NewTeamRepository creates a new team repository
+func NewTeamRepository(supabase *database.SupabaseClient) *TeamRepository {
+	return &TeamRepository{
+		supabase: supabase,
+	}
+}
+
+// CreateMember stores a new team member
+func (r *TeamRepository) CreateMember(ctx context.Context, member *models.TeamMember) error {
+	data, err := r.supabase.InsertAsAdmin(ctx, "team_members", member)
+	if err != nil {
+		return fmt.Errorf("failed to create team member: %w", err)
+	}
+
+	var members []models.TeamMember
+	if err := json.Unmarshal(data, &members); err != nil {
+		return fmt.Errorf("failed to parse created team member: %w", err)
+	}
+
+	if len(members) > 0 {
+		*member = members[0]
+	}
+
+	return nil
+}
+
+// GetMembersByOwner retrieves the members of an owner's team by email
+func (r *TeamRepository) GetMembersByOwner(ctx context.Context, ownerID string) ([]models.TeamMember, error) {
+	return r.getMembers(ctx, map[string]string{
+		"select":   "*",
+		"owner_id": fmt.Sprintf("eq.%s", ownerID),
+		"order":    "email.asc",
+	})
+}
+
+// GetMembershipsByUser retrieves the teams a user is a member of
+func (r *TeamRepository) GetMembershipsByUser(ctx context.Context, userID string) ([]models.TeamMember, error) {
+	return r.getMembers(ctx, map[string]string{
+		"select":  "*",
+		"user_id": fmt.Sprintf("eq.%s", userID),
+	})
+}
+
+// GetMembership retrieves a user's membership of an owner's team, or nil when they are no member
+func (r *TeamRepository) GetMembership(ctx context.Context, ownerID, userID string) (*models.TeamMember, error) {
+	members, err := r.getMembers(ctx, map[string]string{
+		"select":   "*",
+		"owner_id": fmt.Sprintf("eq.%s", ownerID),
+		"user_id":  fmt.Sprintf("eq.%s", userID),
+	})
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+
+	return &members[0], nil
+}
+
+// GetMemberByID retrieves a team member by ID, or nil when it does not exist
+func (r *TeamRepository) GetMemberByID(ctx context.Context, id string) (*models.TeamMember, error) {
+	members, err := r.getMembers(ctx, map[string]string{
+		"select": "*",
+		"id":     fmt.Sprintf("eq.%s", id),
+	})
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+
+	return &members[0], nil
+}
+
+func (r *TeamRepository) getMembers(ctx context.Context, params map[string]string) ([]models.TeamMember, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "team_members", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+
+	var members []models.TeamMember
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("failed to parse team members: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpdateMember updates a team member
+func (r *TeamRepository) UpdateMember(ctx context.Context, id string, updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	if _, err := r.supabase.UpdateAsAdmin(ctx, "team_members", map[string]string{
+		"id": id,
+	}, updates); err != nil {
+		return fmt.Errorf("failed to update team member: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMember removes a member from a team
+func (r *TeamRepository) DeleteMember(ctx context.Context, id string) error {
+	if err := r.supabase.DeleteAsAdmin(ctx, "team_members", map[string]string{
+		"id": id,
+	}); err != nil {
+		return fmt.Errorf("failed to delete team member: %w", err)
+	}
+
+	return nil
+}
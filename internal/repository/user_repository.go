@@ -33,7 +33,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 	user.Status = "Trial"
 
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("user", user)
+	data, err := r.supabase.InsertAsAdmin(ctx, "user", user)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -54,7 +54,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 // GetUserByEmail retrieves a user by email
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	// Query using service role (bypasses RLS)
-	data, err := r.supabase.QueryAsAdmin("user", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "user", map[string]string{
 		"select": "*",
 		"email":  fmt.Sprintf("eq.%s", email),
 		"limit":  "1",
@@ -78,7 +78,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 // GetUserByID retrieves a user by ID
 func (r *UserRepository) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	// Query using service role (bypasses RLS)
-	data, err := r.supabase.QueryAsAdmin("user", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "user", map[string]string{
 		"select": "*",
 		"id":     fmt.Sprintf("eq.%s", userID),
 		"limit":  "1",
@@ -106,7 +106,7 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID string) err
 		"last_login": now,
 	}
 
-	_, err := r.supabase.Update("user", map[string]string{
+	_, err := r.supabase.Update(ctx, "user", map[string]string{
 		"id": fmt.Sprintf("eq.%s", userID),
 	}, updateData)
 
@@ -124,7 +124,7 @@ func (r *UserRepository) CreateSession(ctx context.Context, session *models.User
 	session.ExpiresAt = time.Now().Add(24 * time.Hour * 7) // 7 days
 
 	// Insert using service role (bypasses RLS)
-	_, err := r.supabase.InsertAsAdmin("user_sessions", session)
+	_, err := r.supabase.InsertAsAdmin(ctx, "user_sessions", session)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
@@ -134,7 +134,7 @@ func (r *UserRepository) CreateSession(ctx context.Context, session *models.User
 
 // GetSessionByToken retrieves a session by token
 func (r *UserRepository) GetSessionByToken(ctx context.Context, token string) (*models.UserSession, error) {
-	data, err := r.supabase.QueryAsAdmin("user_sessions", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "user_sessions", map[string]string{
 		"select": "*",
 		"token":  fmt.Sprintf("eq.%s", token),
 		"limit":  "1",
@@ -167,7 +167,7 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID string, hash
 		"updated_at": time.Now(),
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("user", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "user", map[string]string{
 		"id": userID,
 	}, updateData)
 
@@ -192,7 +192,7 @@ func (r *UserRepository) UpdateProfile(ctx context.Context, userID string, gmail
 		updateData["phone"] = *phone
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("user", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "user", map[string]string{
 		"id": userID,
 	}, updateData)
 
@@ -214,7 +214,7 @@ func (r *UserRepository) UpgradeUserToPro(ctx context.Context, userID string, ex
 		"updated_at": time.Now(),
 	}
 
-	_, err := r.supabase.UpdateAsAdmin("user", map[string]string{
+	_, err := r.supabase.UpdateAsAdmin(ctx, "user", map[string]string{
 		"id": userID,
 	}, updateData)
 
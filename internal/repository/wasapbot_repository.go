@@ -1,17 +1,20 @@
 package repository
 
 import (
+	"chatbot-automation/internal/cache"
 	"chatbot-automation/internal/database"
 	"chatbot-automation/internal/models"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
 // WasapbotRepository handles WhatsApp Bot conversation data operations (without AI Prompt)
 type WasapbotRepository struct {
 	supabase *database.SupabaseClient
+	cache    *cache.ConversationCache // optional, nil disables caching
 }
 
 // NewWasapbotRepository creates a new wasapbot repository
@@ -21,13 +24,23 @@ func NewWasapbotRepository(supabase *database.SupabaseClient) *WasapbotRepositor
 	}
 }
 
+// SetCache enables the Redis cache for hot wasapbot rows
+func (r *WasapbotRepository) SetCache(c *cache.ConversationCache) {
+	r.cache = c
+}
+
 // CreateConversation creates a new wasapbot conversation
 func (r *WasapbotRepository) CreateConversation(ctx context.Context, conversation *models.Wasapbot) error {
 	// Database will auto-generate id_prospect (serial/autoincrement)
 	// Database will auto-set created_at, updated_at timestamps
 
+	if conversation.ExternalRef == nil || *conversation.ExternalRef == "" {
+		ref := models.DefaultExternalRef(conversation.IDDevice, conversation.ProspectNum)
+		conversation.ExternalRef = &ref
+	}
+
 	// Insert using service role (bypasses RLS)
-	data, err := r.supabase.InsertAsAdmin("wasapbot", conversation)
+	data, err := r.supabase.InsertAsAdmin(ctx, "wasapbot", conversation)
 	if err != nil {
 		return fmt.Errorf("failed to create wasapbot conversation: %w", err)
 	}
@@ -49,7 +62,12 @@ func (r *WasapbotRepository) CreateConversation(ctx context.Context, conversatio
 func (r *WasapbotRepository) GetConversationByID(ctx context.Context, prospectID string) (*models.Wasapbot, error) {
 	fmt.Printf("🔍 [WasapbotRepo] GetConversationByID called with prospectID=%s\n", prospectID)
 
-	data, err := r.supabase.QueryAsAdmin("wasapbot", map[string]string{
+	var cached models.Wasapbot
+	if r.cache.Get(ctx, "wasapbot", prospectID, &cached) {
+		return &cached, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
 		"select":      "*",
 		"id_prospect": fmt.Sprintf("eq.%s", prospectID),
 		"limit":       "1",
@@ -75,12 +93,36 @@ func (r *WasapbotRepository) GetConversationByID(ctx context.Context, prospectID
 	}
 
 	fmt.Printf("✅ [WasapbotRepo] Retrieved conversation: %+v\n", conversations[0])
+	r.cache.Set(ctx, "wasapbot", prospectID, &conversations[0])
+	return &conversations[0], nil
+}
+
+// GetConversationByExternalRef retrieves a wasapbot conversation by its external reference on any of the given devices
+func (r *WasapbotRepository) GetConversationByExternalRef(ctx context.Context, externalRef string, deviceIDs []string) (*models.Wasapbot, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", externalRefParams(externalRef, deviceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wasapbot conversation by external reference: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversation: %w", err)
+	}
+
+	if len(conversations) == 0 {
+		return nil, nil
+	}
+
 	return &conversations[0], nil
 }
 
 // GetConversationByProspectNum retrieves a wasapbot conversation by prospect phone number and device
 func (r *WasapbotRepository) GetConversationByProspectNum(ctx context.Context, prospectNum, deviceID string) (*models.Wasapbot, error) {
-	data, err := r.supabase.QueryAsAdmin("wasapbot", map[string]string{
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
 		"select":       "*",
 		"prospect_num": fmt.Sprintf("eq.%s", prospectNum),
 		"id_device":    fmt.Sprintf("eq.%s", deviceID),
@@ -108,14 +150,14 @@ func (r *WasapbotRepository) GetConversationsByDevice(ctx context.Context, devic
 	params := map[string]string{
 		"select":    "*",
 		"id_device": fmt.Sprintf("eq.%s", deviceID),
-		"order":     "created_at.desc",
+		"order":     models.InboxOrder + ",created_at.desc",
 	}
 
 	if limit > 0 {
 		params["limit"] = fmt.Sprintf("%d", limit)
 	}
 
-	data, err := r.supabase.QueryAsAdmin("wasapbot", params)
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wasapbot conversations: %w", err)
 	}
@@ -128,6 +170,171 @@ func (r *WasapbotRepository) GetConversationsByDevice(ctx context.Context, devic
 	return conversations, nil
 }
 
+// GetConversationListRows retrieves a device's conversations as compact rows with the requested
+// captured fields as extra columns, filtered and sorted server-side
+func (r *WasapbotRepository) GetConversationListRows(ctx context.Context, deviceID string, query *models.ConversationListQuery, limit int) ([]map[string]interface{}, error) {
+	params, err := conversationListParams("wasapbot", deviceID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wasapbot conversation list: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversation list: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetPinnedConversations retrieves pinned wasapbot conversations across the given devices
+func (r *WasapbotRepository) GetPinnedConversations(ctx context.Context, deviceIDs []string) ([]models.Wasapbot, error) {
+	if len(deviceIDs) == 0 {
+		return []models.Wasapbot{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
+		"select":    "*",
+		"id_device": fmt.Sprintf("in.(%s)", strings.Join(deviceIDs, ",")),
+		"pinned":    "eq.true",
+		"order":     "priority.desc.nullslast,updated_at.desc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned wasapbot conversations: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetConvHistoryPage retrieves id_prospect and conv_last for a page of a device's wasapbot conversations
+func (r *WasapbotRepository) GetConvHistoryPage(ctx context.Context, deviceID string, limit, offset int) ([]models.Wasapbot, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
+		"select":    "id_prospect,id_device,conv_last",
+		"id_device": fmt.Sprintf("eq.%s", deviceID),
+		"conv_last": "not.is.null",
+		"order":     "id_prospect.asc",
+		"limit":     fmt.Sprintf("%d", limit),
+		"offset":    fmt.Sprintf("%d", offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wasapbot conversation history: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversation history: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// ReplaceConvLast overwrites conv_last without touching updated_at (used by history compaction)
+func (r *WasapbotRepository) ReplaceConvLast(ctx context.Context, prospectID string, convLast string) error {
+	_, err := r.supabase.UpdateAsAdmin(ctx, "wasapbot", map[string]string{
+		"id_prospect": prospectID,
+	}, map[string]interface{}{
+		"conv_last": convLast,
+	})
+	if err != nil {
+		r.cache.Delete(ctx, "wasapbot", prospectID)
+		return fmt.Errorf("failed to compact wasapbot conversation history: %w", err)
+	}
+
+	r.cache.Patch(ctx, "wasapbot", prospectID, map[string]interface{}{
+		"conv_last": convLast,
+	})
+	return nil
+}
+
+// GetAbandonedConversations retrieves a device's abandoned (or stalled, see abandonedConversationParams) wasapbot conversations created in a date range
+func (r *WasapbotRepository) GetAbandonedConversations(ctx context.Context, deviceID string, start, end time.Time, stalledBefore *time.Time, flowID *string) ([]models.Wasapbot, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", abandonedConversationParams(deviceID, start, end, stalledBefore, flowID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abandoned wasapbot conversations: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetSegmentConversations retrieves the prospect numbers, names and stages of a device's wasapbot conversations matching a segment
+func (r *WasapbotRepository) GetSegmentConversations(ctx context.Context, deviceID string, segment *models.ContactSegment) ([]models.Wasapbot, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", segmentConversationParams(deviceID, segment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment wasapbot conversations: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetStageSLABreaches retrieves a device's wasapbot conversations that entered a stage before the SLA cutoff and were not flagged yet
+func (r *WasapbotRepository) GetStageSLABreaches(ctx context.Context, deviceID, stage string, enteredBefore time.Time) ([]models.Wasapbot, error) {
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", stageSLABreachParams(deviceID, stage, enteredBefore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wasapbot stage SLA breaches: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// CountByExecutionStatus counts the WhatsApp Bot conversations in an execution state across all devices
+func (r *WasapbotRepository) CountByExecutionStatus(ctx context.Context, state models.ConversationState) (int, error) {
+	count, err := r.supabase.CountAsAdmin(ctx, "wasapbot", map[string]string{
+		"execution_status": fmt.Sprintf("eq.%s", state),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count wasapbot conversations: %w", err)
+	}
+	return count, nil
+}
+
+// GetContactedNumbers returns prospect numbers with wasapbot conversations updated on the given devices since a time
+func (r *WasapbotRepository) GetContactedNumbers(ctx context.Context, deviceIDs []string, since time.Time) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return []string{}, nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", contactedSinceParams(deviceIDs, since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently contacted wasapbot conversations: %w", err)
+	}
+
+	var conversations []models.Wasapbot
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot conversations: %w", err)
+	}
+
+	numbers := make([]string, 0, len(conversations))
+	for _, conv := range conversations {
+		numbers = append(numbers, conv.ProspectNum)
+	}
+
+	return numbers, nil
+}
+
 // UpdateConversation updates a wasapbot conversation
 func (r *WasapbotRepository) UpdateConversation(ctx context.Context, prospectID string, updates map[string]interface{}) error {
 	// Add updated_at timestamp
@@ -135,22 +342,54 @@ func (r *WasapbotRepository) UpdateConversation(ctx context.Context, prospectID
 
 	fmt.Printf("🔍 [WasapbotRepo] Updating prospect_id=%s with updates=%+v\n", prospectID, updates)
 
-	data, err := r.supabase.UpdateAsAdmin("wasapbot", map[string]string{
+	data, err := r.supabase.UpdateAsAdmin(ctx, "wasapbot", map[string]string{
 		"id_prospect": prospectID,
 	}, updates)
 
 	if err != nil {
 		fmt.Printf("❌ [WasapbotRepo] Update failed: %v\n", err)
+		r.cache.Delete(ctx, "wasapbot", prospectID)
 		return fmt.Errorf("failed to update wasapbot conversation: %w", err)
 	}
 
 	fmt.Printf("✅ [WasapbotRepo] Update response: %s\n", string(data))
+	writeThroughCache(ctx, r.cache, "wasapbot", prospectID, updates)
 	return nil
 }
 
+// GetExecutionState retrieves only the execution columns of a wasapbot conversation
+func (r *WasapbotRepository) GetExecutionState(ctx context.Context, prospectID string) (*models.ExecutionState, error) {
+	var cached models.Wasapbot
+	if r.cache.Get(ctx, "wasapbot", prospectID, &cached) {
+		return cached.ExecutionState(), nil
+	}
+
+	data, err := r.supabase.QueryAsAdmin(ctx, "wasapbot", map[string]string{
+		"select":      "execution_status,waiting_for_reply,current_node_id",
+		"id_prospect": fmt.Sprintf("eq.%s", prospectID),
+		"limit":       "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wasapbot execution state: %w", err)
+	}
+
+	var states []models.ExecutionState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse wasapbot execution state: %w", err)
+	}
+
+	if len(states) == 0 {
+		return nil, fmt.Errorf("wasapbot conversation not found")
+	}
+
+	return &states[0], nil
+}
+
 // DeleteConversation deletes a wasapbot conversation
 func (r *WasapbotRepository) DeleteConversation(ctx context.Context, prospectID string) error {
-	err := r.supabase.Delete("wasapbot", map[string]string{
+	r.cache.Delete(ctx, "wasapbot", prospectID)
+
+	err := r.supabase.Delete(ctx, "wasapbot", map[string]string{
 		"id_prospect": prospectID,
 	})
 
@@ -0,0 +1,440 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: internal_api.proto
+
+package internalpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ProcessDebouncedBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Phone         string                 `protobuf:"bytes,2,opt,name=phone,proto3" json:"phone,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Messages      []string               `protobuf:"bytes,4,rep,name=messages,proto3" json:"messages,omitempty"` // in arrival order, combined into one prompt
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessDebouncedBatchRequest) Reset() {
+	*x = ProcessDebouncedBatchRequest{}
+	mi := &file_internal_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessDebouncedBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessDebouncedBatchRequest) ProtoMessage() {}
+
+func (x *ProcessDebouncedBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessDebouncedBatchRequest.ProtoReflect.Descriptor instead.
+func (*ProcessDebouncedBatchRequest) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProcessDebouncedBatchRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *ProcessDebouncedBatchRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *ProcessDebouncedBatchRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProcessDebouncedBatchRequest) GetMessages() []string {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type ProcessDebouncedBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessDebouncedBatchResponse) Reset() {
+	*x = ProcessDebouncedBatchResponse{}
+	mi := &file_internal_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessDebouncedBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessDebouncedBatchResponse) ProtoMessage() {}
+
+func (x *ProcessDebouncedBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessDebouncedBatchResponse.ProtoReflect.Descriptor instead.
+func (*ProcessDebouncedBatchResponse) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{1}
+}
+
+type ResumeConversationRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Source         string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`                                       // ai_whatsapp or wasapbot
+	ConversationId string                 `protobuf:"bytes,2,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"` // id_prospect
+	Message        string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                                     // optional, handed to the resumed nodes like a prospect reply
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ResumeConversationRequest) Reset() {
+	*x = ResumeConversationRequest{}
+	mi := &file_internal_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeConversationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeConversationRequest) ProtoMessage() {}
+
+func (x *ResumeConversationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeConversationRequest.ProtoReflect.Descriptor instead.
+func (*ResumeConversationRequest) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ResumeConversationRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ResumeConversationRequest) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *ResumeConversationRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ResumeConversationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"` // conversation state after the run: active, waiting, scheduled, handoff, completed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeConversationResponse) Reset() {
+	*x = ResumeConversationResponse{}
+	mi := &file_internal_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeConversationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeConversationResponse) ProtoMessage() {}
+
+func (x *ResumeConversationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeConversationResponse.ProtoReflect.Descriptor instead.
+func (*ResumeConversationResponse) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResumeConversationResponse) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+type SendMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                      // text, or the caption of a media message
+	MediaType     string                 `protobuf:"bytes,4,opt,name=media_type,json=mediaType,proto3" json:"media_type,omitempty"` // empty for text; image, audio, video or document
+	MediaUrl      string                 `protobuf:"bytes,5,opt,name=media_url,json=mediaUrl,proto3" json:"media_url,omitempty"`
+	MimeType      string                 `protobuf:"bytes,6,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // optional, detected from the URL when empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageRequest) Reset() {
+	*x = SendMessageRequest{}
+	mi := &file_internal_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageRequest) ProtoMessage() {}
+
+func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendMessageRequest) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SendMessageRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetMediaType() string {
+	if x != nil {
+		return x.MediaType
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetMediaUrl() string {
+	if x != nil {
+		return x.MediaUrl
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+type SendMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageResponse) Reset() {
+	*x = SendMessageResponse{}
+	mi := &file_internal_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageResponse) ProtoMessage() {}
+
+func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageResponse.ProtoReflect.Descriptor instead.
+func (*SendMessageResponse) Descriptor() ([]byte, []int) {
+	return file_internal_api_proto_rawDescGZIP(), []int{5}
+}
+
+var File_internal_api_proto protoreflect.FileDescriptor
+
+const file_internal_api_proto_rawDesc = "" +
+	"\n" +
+	"\x12internal_api.proto\x12\x0einternalapi.v1\"\x81\x01\n" +
+	"\x1cProcessDebouncedBatchRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x14\n" +
+	"\x05phone\x18\x02 \x01(\tR\x05phone\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1a\n" +
+	"\bmessages\x18\x04 \x03(\tR\bmessages\"\x1f\n" +
+	"\x1dProcessDebouncedBatchResponse\"v\n" +
+	"\x19ResumeConversationRequest\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12'\n" +
+	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"2\n" +
+	"\x1aResumeConversationResponse\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\"\xb4\x01\n" +
+	"\x12SendMessageRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"media_type\x18\x04 \x01(\tR\tmediaType\x12\x1b\n" +
+	"\tmedia_url\x18\x05 \x01(\tR\bmediaUrl\x12\x1b\n" +
+	"\tmime_type\x18\x06 \x01(\tR\bmimeType\"\x15\n" +
+	"\x13SendMessageResponse2\xc8\x02\n" +
+	"\vInternalAPI\x12t\n" +
+	"\x15ProcessDebouncedBatch\x12,.internalapi.v1.ProcessDebouncedBatchRequest\x1a-.internalapi.v1.ProcessDebouncedBatchResponse\x12k\n" +
+	"\x12ResumeConversation\x12).internalapi.v1.ResumeConversationRequest\x1a*.internalapi.v1.ResumeConversationResponse\x12V\n" +
+	"\vSendMessage\x12\".internalapi.v1.SendMessageRequest\x1a#.internalapi.v1.SendMessageResponseB,Z*chatbot-automation/internal/rpc/internalpbb\x06proto3"
+
+var (
+	file_internal_api_proto_rawDescOnce sync.Once
+	file_internal_api_proto_rawDescData []byte
+)
+
+func file_internal_api_proto_rawDescGZIP() []byte {
+	file_internal_api_proto_rawDescOnce.Do(func() {
+		file_internal_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_api_proto_rawDesc), len(file_internal_api_proto_rawDesc)))
+	})
+	return file_internal_api_proto_rawDescData
+}
+
+var file_internal_api_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_internal_api_proto_goTypes = []any{
+	(*ProcessDebouncedBatchRequest)(nil),  // 0: internalapi.v1.ProcessDebouncedBatchRequest
+	(*ProcessDebouncedBatchResponse)(nil), // 1: internalapi.v1.ProcessDebouncedBatchResponse
+	(*ResumeConversationRequest)(nil),     // 2: internalapi.v1.ResumeConversationRequest
+	(*ResumeConversationResponse)(nil),    // 3: internalapi.v1.ResumeConversationResponse
+	(*SendMessageRequest)(nil),            // 4: internalapi.v1.SendMessageRequest
+	(*SendMessageResponse)(nil),           // 5: internalapi.v1.SendMessageResponse
+}
+var file_internal_api_proto_depIdxs = []int32{
+	0, // 0: internalapi.v1.InternalAPI.ProcessDebouncedBatch:input_type -> internalapi.v1.ProcessDebouncedBatchRequest
+	2, // 1: internalapi.v1.InternalAPI.ResumeConversation:input_type -> internalapi.v1.ResumeConversationRequest
+	4, // 2: internalapi.v1.InternalAPI.SendMessage:input_type -> internalapi.v1.SendMessageRequest
+	1, // 3: internalapi.v1.InternalAPI.ProcessDebouncedBatch:output_type -> internalapi.v1.ProcessDebouncedBatchResponse
+	3, // 4: internalapi.v1.InternalAPI.ResumeConversation:output_type -> internalapi.v1.ResumeConversationResponse
+	5, // 5: internalapi.v1.InternalAPI.SendMessage:output_type -> internalapi.v1.SendMessageResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_internal_api_proto_init() }
+func file_internal_api_proto_init() {
+	if File_internal_api_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_api_proto_rawDesc), len(file_internal_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_internal_api_proto_goTypes,
+		DependencyIndexes: file_internal_api_proto_depIdxs,
+		MessageInfos:      file_internal_api_proto_msgTypes,
+	}.Build()
+	File_internal_api_proto = out.File
+	file_internal_api_proto_goTypes = nil
+	file_internal_api_proto_depIdxs = nil
+}
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             (unknown)
+// source: internal_api.proto
+
+package internalpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	InternalAPI_ProcessDebouncedBatch_FullMethodName = "/internalapi.v1.InternalAPI/ProcessDebouncedBatch"
+	InternalAPI_ResumeConversation_FullMethodName    = "/internalapi.v1.InternalAPI/ResumeConversation"
+	InternalAPI_SendMessage_FullMethodName           = "/internalapi.v1.InternalAPI/SendMessage"
+)
+
+// InternalAPIClient is the client API for InternalAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// InternalAPI is the service-to-service surface used by the debouncer and background workers
+// instead of the public JSON webhook endpoints. Every call carries the shared internal token as
+// "authorization: Bearer <INTERNAL_API_TOKEN>" metadata.
+type InternalAPIClient interface {
+	// ProcessDebouncedBatch answers a prospect's debounced messages in one AI reply
+	// (the gRPC form of POST /api/debounce/process)
+	ProcessDebouncedBatch(ctx context.Context, in *ProcessDebouncedBatchRequest, opts ...grpc.CallOption) (*ProcessDebouncedBatchResponse, error)
+	// ResumeConversation continues a waiting or scheduled flow after the node it rests at
+	ResumeConversation(ctx context.Context, in *ResumeConversationRequest, opts ...grpc.CallOption) (*ResumeConversationResponse, error)
+	// SendMessage sends a WhatsApp message from a device through its provider
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error)
+}
+
+type internalAPIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInternalAPIClient(cc grpc.ClientConnInterface) InternalAPIClient {
+	return &internalAPIClient{cc}
+}
+
+func (c *internalAPIClient) ProcessDebouncedBatch(ctx context.Context, in *ProcessDebouncedBatchRequest, opts ...grpc.CallOption) (*ProcessDebouncedBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProcessDebouncedBatchResponse)
+	err := c.cc.Invoke(ctx, InternalAPI_ProcessDebouncedBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalAPIClient) ResumeConversation(ctx context.Context, in *ResumeConversationRequest, opts ...grpc.CallOption) (*ResumeConversationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResumeConversationResponse)
+	err := c.cc.Invoke(ctx, InternalAPI_ResumeConversation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalAPIClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendMessageResponse)
+	err := c.cc.Invoke(ctx, InternalAPI_SendMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InternalAPIServer is the server API for InternalAPI service.
+// All implementations must embed UnimplementedInternalAPIServer
+// for forward compatibility.
+//
+// InternalAPI is the service-to-service surface used by the debouncer and background workers
+// instead of the public JSON webhook endpoints. Every call carries the shared internal token as
+// "authorization: Bearer <INTERNAL_API_TOKEN>" metadata.
+type InternalAPIServer interface {
+	// ProcessDebouncedBatch answers a prospect's debounced messages in one AI reply
+	// (the gRPC form of POST /api/debounce/process)
+	ProcessDebouncedBatch(context.Context, *ProcessDebouncedBatchRequest) (*ProcessDebouncedBatchResponse, error)
+	// ResumeConversation continues a waiting or scheduled flow after the node it rests at
+	ResumeConversation(context.Context, *ResumeConversationRequest) (*ResumeConversationResponse, error)
+	// SendMessage sends a WhatsApp message from a device through its provider
+	SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error)
+	mustEmbedUnimplementedInternalAPIServer()
+}
+
+// UnimplementedInternalAPIServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInternalAPIServer struct{}
+
+func (UnimplementedInternalAPIServer) ProcessDebouncedBatch(context.Context, *ProcessDebouncedBatchRequest) (*ProcessDebouncedBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ProcessDebouncedBatch not implemented")
+}
+func (UnimplementedInternalAPIServer) ResumeConversation(context.Context, *ResumeConversationRequest) (*ResumeConversationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ResumeConversation not implemented")
+}
+func (UnimplementedInternalAPIServer) SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (UnimplementedInternalAPIServer) mustEmbedUnimplementedInternalAPIServer() {}
+func (UnimplementedInternalAPIServer) testEmbeddedByValue()                     {}
+
+// UnsafeInternalAPIServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InternalAPIServer will
+// result in compilation errors.
+type UnsafeInternalAPIServer interface {
+	mustEmbedUnimplementedInternalAPIServer()
+}
+
+func RegisterInternalAPIServer(s grpc.ServiceRegistrar, srv InternalAPIServer) {
+	// If the following call panics, it indicates UnimplementedInternalAPIServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InternalAPI_ServiceDesc, srv)
+}
+
+func _InternalAPI_ProcessDebouncedBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessDebouncedBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalAPIServer).ProcessDebouncedBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalAPI_ProcessDebouncedBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalAPIServer).ProcessDebouncedBatch(ctx, req.(*ProcessDebouncedBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalAPI_ResumeConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalAPIServer).ResumeConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalAPI_ResumeConversation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalAPIServer).ResumeConversation(ctx, req.(*ResumeConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalAPI_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalAPIServer).SendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalAPI_SendMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalAPIServer).SendMessage(ctx, req.(*SendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// InternalAPI_ServiceDesc is the grpc.ServiceDesc for InternalAPI service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InternalAPI_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "internalapi.v1.InternalAPI",
+	HandlerType: (*InternalAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProcessDebouncedBatch",
+			Handler:    _InternalAPI_ProcessDebouncedBatch_Handler,
+		},
+		{
+			MethodName: "ResumeConversation",
+			Handler:    _InternalAPI_ResumeConversation_Handler,
+		},
+		{
+			MethodName: "SendMessage",
+			Handler:    _InternalAPI_SendMessage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal_api.proto",
+}
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	"chatbot-automation/internal/rpc/internalpb"
+	"chatbot-automation/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the internal gRPC API (proto/internalapi/v1) on top of the same services
+// the HTTP handlers use, for the debouncer and background workers
+type Server struct {
+	internalpb.UnimplementedInternalAPIServer
+	debounceService *service.DebounceService
+	flowProcessor   *service.FlowProcessorService
+	whatsappService *service.WhatsAppService
+}
+
+// NewServer creates a new internal API server
+func NewServer(debounceService *service.DebounceService, flowProcessor *service.FlowProcessorService, whatsappService *service.WhatsAppService) *Server {
+	return &Server{
+		debounceService: debounceService,
+		flowProcessor:   flowProcessor,
+		whatsappService: whatsappService,
+	}
+}
+
+// NewGRPCServer registers api on a gRPC server that rejects calls without the internal token
+func NewGRPCServer(api *Server, token string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(requireToken(token)))
+	internalpb.RegisterInternalAPIServer(server, api)
+	return server
+}
+
+// ListenAndServe serves the internal API on addr until the server stops
+func ListenAndServe(server *grpc.Server, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("🔌 Internal gRPC API listening on %s", addr)
+	return server.Serve(listener)
+}
+
+// requireToken checks the "authorization: Bearer <token>" metadata of every call.
+// An empty token rejects everything, so the API is never open by accident.
+func requireToken(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if token == "" {
+			return nil, status.Error(codes.Unauthenticated, "internal API token is not configured")
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+		provided := strings.TrimPrefix(values[0], "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid internal API token")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// ProcessDebouncedBatch answers a prospect's debounced messages in one AI reply
+func (s *Server) ProcessDebouncedBatch(ctx context.Context, req *internalpb.ProcessDebouncedBatchRequest) (*internalpb.ProcessDebouncedBatchResponse, error) {
+	if req.GetDeviceId() == "" || req.GetPhone() == "" || len(req.GetMessages()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "device_id, phone, and messages are required")
+	}
+
+	if err := s.debounceService.ProcessAndRespond(ctx, req.GetDeviceId(), req.GetPhone(), req.GetName(), req.GetMessages()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &internalpb.ProcessDebouncedBatchResponse{}, nil
+}
+
+// ResumeConversation continues a waiting or scheduled flow after the node it rests at
+func (s *Server) ResumeConversation(ctx context.Context, req *internalpb.ResumeConversationRequest) (*internalpb.ResumeConversationResponse, error) {
+	if req.GetSource() != "ai_whatsapp" && req.GetSource() != "wasapbot" {
+		return nil, status.Error(codes.InvalidArgument, "source must be ai_whatsapp or wasapbot")
+	}
+	if req.GetConversationId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "conversation_id is required")
+	}
+
+	state, err := s.flowProcessor.ResumeConversation(ctx, req.GetSource(), req.GetConversationId(), req.GetMessage())
+	switch {
+	case errors.Is(err, service.ErrConversationNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrConversationNotPaused):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &internalpb.ResumeConversationResponse{State: string(state)}, nil
+}
+
+// SendMessage sends a WhatsApp message from a device through its provider
+func (s *Server) SendMessage(ctx context.Context, req *internalpb.SendMessageRequest) (*internalpb.SendMessageResponse, error) {
+	if req.GetDeviceId() == "" || req.GetTo() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_id and to are required")
+	}
+	if req.GetMessage() == "" && req.GetMediaUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message or media_url is required")
+	}
+
+	var mimeType []string
+	if req.GetMimeType() != "" {
+		mimeType = append(mimeType, req.GetMimeType())
+	}
+	if err := s.whatsappService.SendMessage(ctx, req.GetDeviceId(), req.GetTo(), req.GetMessage(), req.GetMediaType(), req.GetMediaUrl(), mimeType...); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &internalpb.SendMessageResponse{}, nil
+}
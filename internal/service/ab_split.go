@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// A/B split. An ab_split node sends each prospect down one of its outgoing connections, with the
+// connection's conditionValue as its weight in percent:
+//
+//	{"type": "ab_split"}
+//	{"from": "split", "to": "pitch_a", "conditionValue": "50"}
+//	{"from": "split", "to": "pitch_b", "conditionValue": "50"}
+//
+// Weights need not add up to 100; connections without a weight get none, and when no connection
+// has one the split is even. The branch is picked from a hash of the phone number, so a prospect
+// always lands on the same variant; the first assignment is also recorded and wins over the hash
+// once weights change.
+const flowABSplitNode = "ab_split"
+
+// abSplitWeight reads the weight of an ab_split connection, 0 when it has none
+func abSplitWeight(edge FlowEdge) int {
+	weight, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(edge.ConditionValue), "%"))
+	if err != nil || weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// selectABSplitEdge picks the connection a prospect takes at an ab_split node: the same one every
+// time for the same phone number, node and weights. edges must not be empty.
+func selectABSplitEdge(flowID, nodeID, prospectNum string, edges []FlowEdge) FlowEdge {
+	weights := make([]int, len(edges))
+	total := 0
+	for i, edge := range edges {
+		weights[i] = abSplitWeight(edge)
+		total += weights[i]
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = len(weights)
+	}
+
+	// Hashing the node in too keeps the splits of one flow independent of each other
+	h := fnv.New32a()
+	h.Write([]byte(flowID + "/" + nodeID + "/" + prospectNum))
+	bucket := int(h.Sum32() % uint32(total))
+
+	for i, weight := range weights {
+		if bucket < weight {
+			return edges[i]
+		}
+		bucket -= weight
+	}
+	return edges[len(edges)-1]
+}
+
+// abSplitNext returns the node an ab_split node sends the run's prospect to, and records the
+// assignment the first time. Dry runs record nothing.
+func (run *flowRun) abSplitNext(ctx context.Context, node *FlowNode, edges []FlowEdge) *FlowNode {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Failed to load conversation %s for ab_split %s, taking the first branch: %v", run.conversationID, node.ID, err)
+		return findFlowNode(run.flowData, edges[0].To)
+	}
+
+	record := run.abSplits != nil && run.sim == nil
+	var assignment *models.ABSplitAssignment
+	if record {
+		assignment, err = run.abSplits.GetAssignment(ctx, run.flow.ID, node.ID, conversation.IDDevice, conversation.ProspectNum)
+		if err != nil {
+			log.Printf("⚠️  Failed to load ab_split assignment of %s: %v", conversation.ProspectNum, err)
+		} else if assignment != nil {
+			for _, edge := range edges {
+				if edge.To == assignment.Variant {
+					log.Printf("🅰️  %s stays on variant %s of ab_split %s", conversation.ProspectNum, edge.To, node.ID)
+					return findFlowNode(run.flowData, edge.To)
+				}
+			}
+			// The variant's branch was removed: pick again among the ones left
+		}
+	}
+
+	edge := selectABSplitEdge(run.flow.ID, node.ID, conversation.ProspectNum, edges)
+	log.Printf("🅰️  %s assigned to variant %s of ab_split %s", conversation.ProspectNum, edge.To, node.ID)
+
+	if record && assignment != nil {
+		if err := run.abSplits.UpdateVariant(ctx, assignment.ID, edge.To); err != nil {
+			log.Printf("⚠️  Failed to record ab_split assignment of %s: %v", conversation.ProspectNum, err)
+		}
+	} else if record {
+		err := run.abSplits.CreateAssignment(ctx, &models.ABSplitAssignment{
+			IDDevice:       conversation.IDDevice,
+			FlowID:         run.flow.ID,
+			NodeID:         node.ID,
+			Variant:        edge.To,
+			ProspectNum:    conversation.ProspectNum,
+			Source:         run.table,
+			ConversationID: run.conversationID,
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to record ab_split assignment of %s: %v", conversation.ProspectNum, err)
+		}
+	}
+
+	return findFlowNode(run.flowData, edge.To)
+}
+
+// abSplitProcessor passes through; findNextNode picks the prospect's branch
+type abSplitProcessor struct{}
+
+func (p *abSplitProcessor) GetNodeType() string { return flowABSplitNode }
+
+func (p *abSplitProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("🅰️  A/B split")
+	return true, nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+const (
+	// accountExportTimeout bounds building and uploading one archive
+	accountExportTimeout = 15 * time.Minute
+	// accountExportLinkTTL is how long a signed download link stays valid
+	accountExportLinkTTL = time.Hour
+)
+
+// AccountExportService builds downloadable backups of all of a user's business data
+type AccountExportService struct {
+	deviceRepo    *repository.DeviceRepository
+	flowRepo      *repository.FlowRepository
+	stageRepo     *repository.StageRepository
+	convRepo      *repository.ConversationRepository
+	wasapbotRepo  *repository.WasapbotRepository
+	fieldRepo     *repository.CustomFieldRepository
+	campaignRepo  *repository.CampaignRepository
+	analyticsRepo *repository.AnalyticsRepository
+	exportRepo    *repository.AccountExportRepository
+}
+
+// NewAccountExportService creates a new account export service
+func NewAccountExportService(
+	deviceRepo *repository.DeviceRepository,
+	flowRepo *repository.FlowRepository,
+	stageRepo *repository.StageRepository,
+	convRepo *repository.ConversationRepository,
+	wasapbotRepo *repository.WasapbotRepository,
+	fieldRepo *repository.CustomFieldRepository,
+	campaignRepo *repository.CampaignRepository,
+	analyticsRepo *repository.AnalyticsRepository,
+	exportRepo *repository.AccountExportRepository,
+) *AccountExportService {
+	return &AccountExportService{
+		deviceRepo:    deviceRepo,
+		flowRepo:      flowRepo,
+		stageRepo:     stageRepo,
+		convRepo:      convRepo,
+		wasapbotRepo:  wasapbotRepo,
+		fieldRepo:     fieldRepo,
+		campaignRepo:  campaignRepo,
+		analyticsRepo: analyticsRepo,
+		exportRepo:    exportRepo,
+	}
+}
+
+// StartExport queues a backup of the user's data and builds it in the background. While one is
+// still being built it is returned instead of starting another.
+func (s *AccountExportService) StartExport(ctx context.Context, userID string) (*models.AccountExportResponse, error) {
+	pending, err := s.exportRepo.GetPendingExport(ctx, userID, time.Now().Add(-accountExportTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return &models.AccountExportResponse{
+			Success: true,
+			Message: "An export is already being prepared",
+			Export:  pending,
+		}, nil
+	}
+
+	export := &models.AccountExport{
+		UserID: userID,
+		Status: models.AccountExportPending,
+	}
+	if err := s.exportRepo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+
+	go s.buildExport(export.ID, userID)
+
+	return &models.AccountExportResponse{
+		Success: true,
+		Message: "Export started; check its status for the download link",
+		Export:  export,
+	}, nil
+}
+
+// GetExport returns one of the user's exports, with a fresh download link once it is ready
+func (s *AccountExportService) GetExport(ctx context.Context, userID, exportID string) (*models.AccountExportResponse, error) {
+	export, err := s.exportRepo.GetExportByID(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return &models.AccountExportResponse{Success: false, Message: "Export not found"}, nil
+	}
+	if export.UserID != userID {
+		return &models.AccountExportResponse{Success: false, Message: "Access denied"}, nil
+	}
+
+	resp := &models.AccountExportResponse{
+		Success: true,
+		Message: fmt.Sprintf("Export is %s", export.Status),
+		Export:  export,
+	}
+	if export.Status != models.AccountExportReady || export.ObjectPath == nil {
+		return resp, nil
+	}
+
+	url, err := s.exportRepo.SignedArchiveURL(ctx, *export.ObjectPath, accountExportLinkTTL)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(accountExportLinkTTL)
+	resp.DownloadURL = url
+	resp.DownloadExpiresAt = &expiresAt
+	return resp, nil
+}
+
+// buildExport writes the archive, uploads it and records the outcome on the export row
+func (s *AccountExportService) buildExport(exportID, userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), accountExportTimeout)
+	defer cancel()
+
+	updates := map[string]interface{}{}
+	archive, err := s.writeArchive(ctx, userID)
+	if err == nil {
+		var path string
+		if path, err = s.exportRepo.UploadArchive(ctx, userID, exportID, archive); err == nil {
+			updates["status"] = models.AccountExportReady
+			updates["object_path"] = path
+			updates["size_bytes"] = len(archive)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  Account export %s for user %s failed: %v", exportID, userID, err)
+		updates["status"] = models.AccountExportFailed
+		updates["error"] = err.Error()
+	}
+
+	saveCtx, saveCancel := saveProgressContext(ctx)
+	defer saveCancel()
+	if err := s.exportRepo.FinishExport(saveCtx, exportID, updates); err != nil {
+		log.Printf("⚠️  Failed to record account export %s: %v", exportID, err)
+	}
+}
+
+// writeArchive collects the user's data into a zip of JSON files, one per kind of record
+func (s *AccountExportService) writeArchive(ctx context.Context, userID string) ([]byte, error) {
+	devices, err := s.deviceRepo.GetDevicesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var idDevices []string
+	for i := range devices {
+		if devices[i].IDDevice != nil && *devices[i].IDDevice != "" {
+			idDevices = append(idDevices, *devices[i].IDDevice)
+		}
+		stripDeviceSecrets(&devices[i])
+	}
+
+	flows := []models.ChatbotFlow{}
+	if len(idDevices) > 0 {
+		if flows, err = s.flowRepo.GetAllFlowsByUserDevices(ctx, idDevices); err != nil {
+			return nil, err
+		}
+	}
+
+	stages := []models.StageValue{}
+	conversations := []models.AIWhatsapp{}
+	wasapbot := []models.Wasapbot{}
+	analytics := models.AccountArchiveAnalytics{Conversations: make(map[string]*models.ConversationMetrics)}
+	for _, idDevice := range idDevices {
+		deviceStages, err := s.stageRepo.GetStageValuesByDevice(ctx, idDevice)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, deviceStages...)
+
+		deviceConversations, err := s.convRepo.GetConversationsByDevice(ctx, idDevice, 0)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, deviceConversations...)
+
+		deviceWasapbot, err := s.wasapbotRepo.GetConversationsByDevice(ctx, idDevice, 0)
+		if err != nil {
+			return nil, err
+		}
+		wasapbot = append(wasapbot, deviceWasapbot...)
+
+		metrics, err := s.analyticsRepo.GetConversationMetrics(ctx, idDevice, nil)
+		if err != nil {
+			return nil, err
+		}
+		analytics.Conversations[idDevice] = metrics
+	}
+
+	if analytics.Devices, err = s.analyticsRepo.GetDeviceMetrics(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	fields, err := s.fieldRepo.GetFieldsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	campaigns, err := s.campaignRepo.GetCampaignsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := models.AccountArchiveManifest{
+		Version:    models.AccountExportFormatVersion,
+		UserID:     userID,
+		ExportedAt: time.Now(),
+		Files: map[string]int{
+			"devices.json":                   len(devices),
+			"flows.json":                     len(flows),
+			"stages.json":                    len(stages),
+			"custom_fields.json":             len(fields),
+			"campaigns.json":                 len(campaigns),
+			"conversations/ai_whatsapp.json": len(conversations),
+			"conversations/wasapbot.json":    len(wasapbot),
+			"analytics.json":                 len(analytics.Devices),
+		},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"manifest.json", manifest},
+		{"devices.json", devices},
+		{"flows.json", flows},
+		{"stages.json", stages},
+		{"custom_fields.json", fields},
+		{"campaigns.json", campaigns},
+		{"conversations/ai_whatsapp.json", conversations},
+		{"conversations/wasapbot.json", wasapbot},
+		{"analytics.json", analytics},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"chatbot-automation/internal/models"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultAIBaseURLs are the public provider endpoints used when nothing overrides them
+var defaultAIBaseURLs = map[models.AIProvider]string{
+	models.AIProviderOpenRouter: "https://openrouter.ai/api/v1",
+	models.AIProviderOpenAI:     "https://api.openai.com/v1",
+	models.AIProviderAnthropic:  "https://api.anthropic.com/v1",
+}
+
+// aiRequestTimeout bounds one provider call regardless of the caller's context
+const aiRequestTimeout = 60 * time.Second
+
+// maxAIEndpointHeaders caps the custom headers on one endpoint
+const maxAIEndpointHeaders = 20
+
+// defaultAIClient is shared by every endpoint without TLS options
+var defaultAIClient = &http.Client{Timeout: aiRequestTimeout}
+
+// AIEndpoints resolves where AI provider requests go. The deployment configures base URLs,
+// headers and TLS per provider; a device may override the base URL and headers on top.
+// A nil *AIEndpoints uses the public endpoints and the default retry policy.
+type AIEndpoints struct {
+	providers map[models.AIProvider]aiProviderEndpoint
+	retry     AIRetryPolicy
+}
+
+type aiProviderEndpoint struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+// aiEndpoint is one resolved request target
+type aiEndpoint struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	retry   AIRetryPolicy
+}
+
+// NewAIEndpoints loads the deployment endpoints file at path (empty skips it) and applies the
+// per-provider base URL overrides on top, e.g. from OPENROUTER_BASE_URL. Empty overrides are ignored.
+// retry applies to every provider call.
+func NewAIEndpoints(path string, baseURLs map[models.AIProvider]string, retry AIRetryPolicy) (*AIEndpoints, error) {
+	configs := make(map[models.AIProvider]models.AIEndpointConfig)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read AI endpoints file: %w", err)
+		}
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, fmt.Errorf("failed to parse AI endpoints file: %w", err)
+		}
+	}
+	for provider, baseURL := range baseURLs {
+		if baseURL == "" {
+			continue
+		}
+		config := configs[provider]
+		config.BaseURL = baseURL
+		configs[provider] = config
+	}
+
+	endpoints := &AIEndpoints{providers: make(map[models.AIProvider]aiProviderEndpoint), retry: retry}
+	for provider, config := range configs {
+		if msg := validateAIEndpoint(provider, config.AIEndpoint); msg != "" {
+			return nil, fmt.Errorf("invalid AI endpoint: %s", msg)
+		}
+
+		client := defaultAIClient
+		if config.TLS != nil {
+			tlsConfig, err := aiTLSConfig(config.TLS)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TLS options for %s: %w", provider, err)
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = tlsConfig
+			client = &http.Client{Timeout: aiRequestTimeout, Transport: transport}
+		}
+
+		endpoints.providers[provider] = aiProviderEndpoint{
+			baseURL: config.BaseURL,
+			headers: config.Headers,
+			client:  client,
+		}
+	}
+	return endpoints, nil
+}
+
+// aiTLSConfig builds the client TLS config for a deployment endpoint
+func aiTLSConfig(opts *models.AIEndpointTLS) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file contains no PEM certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// resolve returns the target for path (e.g. "/chat/completions") on provider. The device's base URL
+// wins over the deployment's, device headers are merged over deployment headers, and the deployment
+// TLS options apply either way.
+func (e *AIEndpoints) resolve(provider models.AIProvider, device *models.DeviceSetting, path string) aiEndpoint {
+	baseURL := defaultAIBaseURLs[provider]
+	client := defaultAIClient
+	headers := make(map[string]string)
+	retry := NewAIRetryPolicy(0, 0, 0)
+
+	if e != nil {
+		if e.retry.MaxAttempts > 0 {
+			retry = e.retry
+		}
+		if deployment, ok := e.providers[provider]; ok {
+			if deployment.baseURL != "" {
+				baseURL = deployment.baseURL
+			}
+			for name, value := range deployment.headers {
+				headers[name] = value
+			}
+			client = deployment.client
+		}
+	}
+
+	if device != nil {
+		if override, ok := device.AIEndpoints[provider]; ok {
+			if override.BaseURL != "" {
+				baseURL = override.BaseURL
+			}
+			for name, value := range override.Headers {
+				headers[name] = value
+			}
+		}
+	}
+
+	return aiEndpoint{
+		url:     strings.TrimRight(baseURL, "/") + path,
+		headers: headers,
+		client:  client,
+		retry:   retry,
+	}
+}
+
+// apply sets the endpoint's custom headers; call it after the standard headers so a gateway
+// can replace them (e.g. its own Authorization scheme)
+func (t aiEndpoint) apply(req *http.Request) {
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// validateAIEndpoints checks a device's per-provider overrides.
+// Returns an error message for the client, or "" when valid.
+func validateAIEndpoints(endpoints map[models.AIProvider]models.AIEndpoint) string {
+	for provider, endpoint := range endpoints {
+		if msg := validateAIEndpoint(provider, endpoint); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// validateAIEndpoint checks one provider endpoint's base URL and headers
+func validateAIEndpoint(provider models.AIProvider, endpoint models.AIEndpoint) string {
+	if _, ok := defaultAIBaseURLs[provider]; !ok {
+		return fmt.Sprintf("Unknown AI provider %q: use openrouter, openai or anthropic", provider)
+	}
+	if endpoint.BaseURL != "" && !validHeartbeatURL(endpoint.BaseURL) {
+		return fmt.Sprintf("%s base_url must be an http or https URL", provider)
+	}
+	if len(endpoint.Headers) > maxAIEndpointHeaders {
+		return fmt.Sprintf("%s can have at most %d headers", provider, maxAIEndpointHeaders)
+	}
+	for name, value := range endpoint.Headers {
+		if !validHeaderName(name) || strings.ContainsAny(value, "\r\n") {
+			return fmt.Sprintf("Invalid %s header %q", provider, name)
+		}
+		switch http.CanonicalHeaderKey(name) {
+		case "Host", "Content-Length", "Content-Type", "Transfer-Encoding":
+			return fmt.Sprintf("%s header %q cannot be overridden", provider, name)
+		}
+	}
+	return ""
+}
+
+// validHeaderName reports whether name is a non-empty HTTP header token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"chatbot-automation/internal/models"
+)
+
+// N-best limits for ai_prompt nodes
+const (
+	maxNBestCandidates     = 5
+	defaultNBestMaxChars   = 500
+	nBestStructuredScore   = 3.0
+	nBestStageScore        = 2.0
+	nBestLengthScore       = 1.0
+	nBestRepeatPenalty     = 2.0
+	nBestBannedPenalty     = 5.0
+	nBestUnknownURLPenalty = 3.0
+)
+
+// aiCandidate is one generated reply and its score
+type aiCandidate struct {
+	content string
+	stage   string
+	parts   []AIResponsePart
+	score   float64
+	reasons []string
+}
+
+// nBestCount returns how many candidates an ai_prompt node should generate for the current stage.
+// Config: n_best (2-5) and optional n_best_stages to limit it to e.g. closing stages.
+func nBestCount(node *FlowNode, currentStage string) int {
+	n, _ := node.Config["n_best"].(float64)
+	if n < 2 {
+		return 1
+	}
+	if n > maxNBestCandidates {
+		n = maxNBestCandidates
+	}
+
+	if stages, ok := node.Config["n_best_stages"].([]interface{}); ok && len(stages) > 0 {
+		for _, s := range stages {
+			if name, ok := s.(string); ok && strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(currentStage)) {
+				return int(n)
+			}
+		}
+		return 1
+	}
+
+	return int(n)
+}
+
+// bestAIReply requests n candidates in parallel, scores them and returns the best reply text.
+// Every candidate is logged with its score; failed candidates are skipped.
+func (s *FlowProcessorService) bestAIReply(
+	ctx context.Context,
+	flow *models.ChatbotFlow,
+	node *FlowNode,
+	conversation *models.AIWhatsapp,
+	device *models.DeviceSetting,
+	model string,
+	payload map[string]interface{},
+	n int,
+	promptData string,
+	lasttext string,
+) (string, error) {
+	log.Printf("🎯 Generating %d candidate replies (n_best)", n)
+
+	contents := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			contents[i], errs[i] = s.requestAIReply(ctx, flow, conversation, device, model, payload)
+		}(i)
+	}
+	wg.Wait()
+
+	var best *aiCandidate
+	var firstErr error
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			log.Printf("⚠️  Candidate %d/%d failed: %v", i+1, n, errs[i])
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+
+		candidate := scoreAICandidate(node, contents[i], promptData, lasttext)
+		log.Printf("🧪 Candidate %d/%d score=%.2f stage=%q [%s]: %s",
+			i+1, n, candidate.score, candidate.stage, strings.Join(candidate.reasons, ", "), candidate.content)
+
+		if best == nil || candidate.score > best.score {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("all %d candidate replies failed: %w", n, firstErr)
+	}
+
+	log.Printf("🏆 Selected candidate with score %.2f", best.score)
+	return best.content, nil
+}
+
+// scoreAICandidate rates a reply without another AI call: response format, stage relevance,
+// length, repetition of earlier bot messages, banned phrases and media URLs not in the prompt
+func scoreAICandidate(node *FlowNode, content, promptData, lasttext string) *aiCandidate {
+	stage, parts, structured := parseAIReply(content)
+	candidate := &aiCandidate{content: content, stage: stage, parts: parts}
+
+	add := func(points float64, reason string) {
+		candidate.score += points
+		candidate.reasons = append(candidate.reasons, fmt.Sprintf("%+.1f %s", points, reason))
+	}
+
+	if structured {
+		add(nBestStructuredScore, "format")
+		if stage != "" && strings.Contains(strings.ToLower(promptData), strings.ToLower(stage)) {
+			add(nBestStageScore, "known stage")
+		}
+	}
+
+	maxChars := defaultNBestMaxChars
+	if v, ok := node.Config["n_best_max_chars"].(float64); ok && v > 0 {
+		maxChars = int(v)
+	}
+
+	var text strings.Builder
+	for _, part := range parts {
+		if part.Type == "text" {
+			text.WriteString(part.Content)
+			text.WriteString("\n")
+			continue
+		}
+		// Media must come from the prompt, anything else is likely hallucinated
+		if url := strings.TrimSpace(part.Content); url != "" && !strings.Contains(promptData, url) {
+			add(-nBestUnknownURLPenalty, "unknown media URL")
+		}
+	}
+
+	length := len(strings.TrimSpace(text.String()))
+	switch {
+	case length == 0:
+		add(-nBestLengthScore, "no text")
+	case length <= maxChars:
+		add(nBestLengthScore, "length")
+	default:
+		add(-nBestLengthScore*float64(length-maxChars)/float64(maxChars), "too long")
+	}
+
+	// Repeating earlier bot lines breaks the "do not repeat" instruction
+	lowerHistory := strings.ToLower(lasttext)
+	for _, part := range parts {
+		if part.Type != "text" {
+			continue
+		}
+		line := strings.ToLower(strings.TrimSpace(part.Content))
+		if len(line) >= 20 && strings.Contains(lowerHistory, "bot: "+line) {
+			add(-nBestRepeatPenalty, "repeated")
+		}
+	}
+
+	if banned, ok := node.Config["n_best_banned"].([]interface{}); ok {
+		lowerText := strings.ToLower(text.String())
+		for _, b := range banned {
+			phrase, _ := b.(string)
+			phrase = strings.ToLower(strings.TrimSpace(phrase))
+			if phrase != "" && strings.Contains(lowerText, phrase) {
+				add(-nBestBannedPenalty, "banned phrase")
+			}
+		}
+	}
+
+	return candidate
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Budget for the single JSON repair round-trip after an unparseable AI reply
+const (
+	aiRepairTimeout   = 15 * time.Second
+	aiRepairMaxTokens = 1024
+)
+
+// aiRepairPrompt asks the model to re-emit its previous answer in the ai_prompt response format
+const aiRepairPrompt = "Your previous answer was not valid JSON. Re-emit your previous answer, unchanged in meaning, " +
+	"as valid JSON matching exactly this schema and nothing else (no markdown, no code fences, no commentary):\n" +
+	"{\"Stage\": \"<current stage>\", \"Response\": [{\"type\": \"text\", \"content\": \"<message>\"}, {\"type\": \"image\", \"content\": \"<image URL>\"}]}\n" +
+	"Keep the \"Jenis\" field on text items if your previous answer used it."
+
+// aiJSONRepairEnabled reports whether an ai_prompt node retries unparseable replies.
+// On by default; "json_repair": false in the node config turns it off.
+func aiJSONRepairEnabled(node *FlowNode) bool {
+	enabled, ok := node.Config["json_repair"].(bool)
+	return !ok || enabled
+}
+
+// repairAIReply makes one extra request asking the model to re-emit an unparseable reply as JSON.
+// It returns the repaired stage and parts, or ok=false if the repair failed or was still not valid,
+// in which case the caller keeps the plain text fallback. Both attempts are logged.
+func (s *FlowProcessorService) repairAIReply(
+	ctx context.Context,
+	flow *models.ChatbotFlow,
+	conversation *models.AIWhatsapp,
+	device *models.DeviceSetting,
+	model string,
+	payload map[string]interface{},
+	malformed string,
+) (stage string, parts []AIResponsePart, ok bool) {
+	log.Printf("🔧 AI reply attempt 1 is not valid JSON, requesting repair: %s", malformed)
+
+	messages, _ := payload["messages"].([]map[string]string)
+	repairMessages := make([]map[string]string, 0, len(messages)+2)
+	repairMessages = append(repairMessages, messages...)
+	repairMessages = append(repairMessages,
+		map[string]string{"role": "assistant", "content": malformed},
+		map[string]string{"role": "user", "content": aiRepairPrompt},
+	)
+
+	repairPayload := make(map[string]interface{}, len(payload)+1)
+	for key, value := range payload {
+		repairPayload[key] = value
+	}
+	repairPayload["messages"] = repairMessages
+	repairPayload["temperature"] = 0
+	repairPayload["max_tokens"] = aiRepairMaxTokens
+
+	repairCtx, cancel := context.WithTimeout(ctx, aiRepairTimeout)
+	defer cancel()
+
+	repaired, err := s.requestAIReply(repairCtx, flow, conversation, device, model, repairPayload)
+	if err != nil {
+		log.Printf("⚠️  AI reply repair (attempt 2) failed, using plain text fallback: %v", err)
+		return "", nil, false
+	}
+
+	stage, parts, structured := parseAIReply(repaired)
+	if !structured {
+		log.Printf("⚠️  AI reply repair (attempt 2) still not valid JSON, using plain text fallback: %s", repaired)
+		return "", nil, false
+	}
+
+	log.Printf("✅ AI reply repaired on attempt 2 - Stage: %s, Parts: %d", stage, len(parts))
+	return stage, parts, true
+}
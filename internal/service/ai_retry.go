@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default AI retry policy, used when a configured value is zero
+const (
+	DefaultAIRetryAttempts  = 3
+	DefaultAIRetryBaseDelay = 1 * time.Second
+	DefaultAIRetryMaxDelay  = 30 * time.Second
+)
+
+// AIRetryPolicy is how AI provider calls retry transient failures: transport errors, timeouts,
+// 408, 429 and 5xx responses. Attempt n waits BaseDelay*2^(n-1) with jitter, up to MaxDelay;
+// a 429 waits for the provider's Retry-After instead when it sends one.
+type AIRetryPolicy struct {
+	MaxAttempts int // calls per request, the first one included (1 disables retries)
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NewAIRetryPolicy fills zero values with the defaults
+func NewAIRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) AIRetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultAIRetryAttempts
+	}
+	if baseDelay <= 0 {
+		baseDelay = DefaultAIRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultAIRetryMaxDelay
+	}
+	if maxDelay < baseDelay {
+		maxDelay = baseDelay
+	}
+	return AIRetryPolicy{MaxAttempts: maxAttempts, BaseDelay: baseDelay, MaxDelay: maxDelay}
+}
+
+// backoff returns the wait before retry number attempt (1-based): the exponential delay with
+// equal jitter, so concurrent callers do not retry in lockstep
+func (p AIRetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter reads a Retry-After header (seconds or an HTTP date), capped at MaxDelay; ok is false
+// when the header is missing or unreadable
+func (p AIRetryPolicy) retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		delay = time.Until(at)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// retryableAIStatus reports whether a provider status is worth another attempt
+func retryableAIStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+// send POSTs body to the endpoint and returns the final status and response body, retrying
+// transient failures under the endpoint's retry policy. setHeaders adds the provider's headers;
+// the endpoint's custom headers are applied after it. attemptTimeout bounds each attempt
+// (0 leaves it to the client timeout); ctx bounds the whole call, waits included.
+func (t aiEndpoint) send(ctx context.Context, body []byte, attemptTimeout time.Duration, setHeaders func(*http.Request)) (int, []byte, error) {
+	policy := t.retry
+
+	// A malformed URL fails the same way on every attempt
+	if _, err := http.NewRequest("POST", t.url, nil); err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		status, respBody, header, err := t.attempt(ctx, body, attemptTimeout, setHeaders)
+
+		retryable := false
+		switch {
+		case ctx.Err() != nil:
+			// The caller's deadline is spent; another attempt cannot finish
+		case err != nil:
+			retryable = true
+		case retryableAIStatus(status):
+			retryable = true
+		}
+		if !retryable || attempt >= policy.MaxAttempts {
+			return status, respBody, err
+		}
+
+		wait := policy.backoff(attempt)
+		if status == http.StatusTooManyRequests {
+			if after, ok := policy.retryAfter(header.Get("Retry-After")); ok {
+				wait = after
+			}
+		}
+		if err != nil {
+			log.Printf("🔁 AI request failed (%v), retry %d/%d in %s", err, attempt, policy.MaxAttempts-1, wait.Round(time.Millisecond))
+		} else {
+			log.Printf("🔁 AI provider returned %d, retry %d/%d in %s", status, attempt, policy.MaxAttempts-1, wait.Round(time.Millisecond))
+		}
+
+		if sleepErr := sleepContext(ctx, wait); sleepErr != nil {
+			if err == nil {
+				err = fmt.Errorf("AI provider returned %d: %w", status, sleepErr)
+			}
+			return status, respBody, err
+		}
+	}
+}
+
+// attempt makes one call of send
+func (t aiEndpoint) attempt(ctx context.Context, body []byte, attemptTimeout time.Duration, setHeaders func(*http.Request)) (int, []byte, http.Header, error) {
+	if attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, attemptTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setHeaders(req)
+	t.apply(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, nil, nil, fmt.Errorf("AI request timed out: %w", err)
+		}
+		return 0, nil, nil, fmt.Errorf("AI request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, resp.Header, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, resp.Header, nil
+}
@@ -1,30 +1,27 @@
 package service
 
 import (
-	"bytes"
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"time"
 )
 
 // AIService handles AI-related operations
 type AIService struct {
 	deviceRepo *repository.DeviceRepository
-	client     *http.Client
+	endpoints  *AIEndpoints
+	usage      *AIUsageRecorder
 }
 
-// NewAIService creates a new AI service
-func NewAIService(deviceRepo *repository.DeviceRepository) *AIService {
+// NewAIService creates a new AI service; endpoints may be nil to use the public provider endpoints
+func NewAIService(deviceRepo *repository.DeviceRepository, endpoints *AIEndpoints, aiUsageRepo *repository.AIUsageRepository) *AIService {
 	return &AIService{
 		deviceRepo: deviceRepo,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		endpoints:  endpoints,
+		usage:      NewAIUsageRecorder(aiUsageRepo),
 	}
 }
 
@@ -50,11 +47,12 @@ func (s *AIService) GenerateCompletion(ctx context.Context, userID string, req *
 	}
 
 	// Route to appropriate provider
+	var response *models.AICompletionResponse
 	switch req.Provider {
 	case models.AIProviderOpenAI:
-		return s.generateOpenAICompletion(ctx, req)
+		response, err = s.generateOpenAICompletion(ctx, device, req)
 	case models.AIProviderAnthropic:
-		return s.generateAnthropicCompletion(ctx, req)
+		response, err = s.generateAnthropicCompletion(ctx, device, req)
 	default:
 		return &models.AICompletionResponse{
 			Success: false,
@@ -62,16 +60,21 @@ func (s *AIService) GenerateCompletion(ctx context.Context, userID string, req *
 			Error:   fmt.Sprintf("Provider '%s' is not supported", req.Provider),
 		}, nil
 	}
+
+	if err == nil && response != nil && response.Success {
+		s.usage.Record(ctx, getStringValue(device.IDDevice), string(req.Provider)+"/"+string(req.Model), models.AIUsagePurposeCompletion, response.Usage, nil)
+	}
+	return response, err
 }
 
 // generateOpenAICompletion generates completion using OpenAI API
-func (s *AIService) generateOpenAICompletion(ctx context.Context, req *models.AICompletionRequest) (*models.AICompletionResponse, error) {
+func (s *AIService) generateOpenAICompletion(ctx context.Context, device *models.DeviceSetting, req *models.AICompletionRequest) (*models.AICompletionResponse, error) {
 	// Get API key from device settings (assume stored in device metadata)
 	// For now, we'll require it to be passed in the request or environment
 	apiKey := req.DeviceID // Placeholder - should come from device settings
 
 	// Build OpenAI API request
-	url := "https://api.openai.com/v1/chat/completions"
+	endpoint := s.endpoints.resolve(models.AIProviderOpenAI, device, "/chat/completions")
 
 	// Prepare messages
 	messages := make([]map[string]string, 0)
@@ -115,22 +118,10 @@ func (s *AIService) generateOpenAICompletion(ctx context.Context, req *models.AI
 		}, nil
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return &models.AICompletionResponse{
-			Success: false,
-			Message: "Failed to create request",
-			Error:   err.Error(),
-		}, nil
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	// Send request
-	resp, err := s.client.Do(httpReq)
+	// Send request, retrying transient failures
+	status, body, err := endpoint.send(ctx, jsonData, 0, func(httpReq *http.Request) {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	})
 	if err != nil {
 		return &models.AICompletionResponse{
 			Success: false,
@@ -138,20 +129,9 @@ func (s *AIService) generateOpenAICompletion(ctx context.Context, req *models.AI
 			Error:   err.Error(),
 		}, nil
 	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &models.AICompletionResponse{
-			Success: false,
-			Message: "Failed to read response",
-			Error:   err.Error(),
-		}, nil
-	}
 
 	// Check status code
-	if resp.StatusCode != http.StatusOK {
+	if status != http.StatusOK {
 		return &models.AICompletionResponse{
 			Success: false,
 			Message: "OpenAI API error",
@@ -207,12 +187,12 @@ func (s *AIService) generateOpenAICompletion(ctx context.Context, req *models.AI
 }
 
 // generateAnthropicCompletion generates completion using Anthropic API
-func (s *AIService) generateAnthropicCompletion(ctx context.Context, req *models.AICompletionRequest) (*models.AICompletionResponse, error) {
+func (s *AIService) generateAnthropicCompletion(ctx context.Context, device *models.DeviceSetting, req *models.AICompletionRequest) (*models.AICompletionResponse, error) {
 	// Get API key from device settings
 	apiKey := req.DeviceID // Placeholder - should come from device settings
 
 	// Build Anthropic API request
-	url := "https://api.anthropic.com/v1/messages"
+	endpoint := s.endpoints.resolve(models.AIProviderAnthropic, device, "/messages")
 
 	// Prepare messages (Anthropic format)
 	messages := make([]map[string]string, 0)
@@ -255,23 +235,11 @@ func (s *AIService) generateAnthropicCompletion(ctx context.Context, req *models
 		}, nil
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return &models.AICompletionResponse{
-			Success: false,
-			Message: "Failed to create request",
-			Error:   err.Error(),
-		}, nil
-	}
-
-	// Set headers (Anthropic-specific)
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", apiKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	// Send request
-	resp, err := s.client.Do(httpReq)
+	// Send request (Anthropic-specific headers), retrying transient failures
+	status, body, err := endpoint.send(ctx, jsonData, 0, func(httpReq *http.Request) {
+		httpReq.Header.Set("x-api-key", apiKey)
+		httpReq.Header.Set("anthropic-version", "2023-06-01")
+	})
 	if err != nil {
 		return &models.AICompletionResponse{
 			Success: false,
@@ -279,20 +247,9 @@ func (s *AIService) generateAnthropicCompletion(ctx context.Context, req *models
 			Error:   err.Error(),
 		}, nil
 	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &models.AICompletionResponse{
-			Success: false,
-			Message: "Failed to read response",
-			Error:   err.Error(),
-		}, nil
-	}
 
 	// Check status code
-	if resp.StatusCode != http.StatusOK {
+	if status != http.StatusOK {
 		return &models.AICompletionResponse{
 			Success: false,
 			Message: "Anthropic API error",
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// AIUsageRecorder writes the token usage and estimated cost of every AI call to ai_usage.
+// A nil recorder (or one without a repository) silently does nothing so callers don't need to check.
+type AIUsageRecorder struct {
+	repo *repository.AIUsageRepository
+}
+
+// NewAIUsageRecorder creates a new AI usage recorder
+func NewAIUsageRecorder(repo *repository.AIUsageRepository) *AIUsageRecorder {
+	return &AIUsageRecorder{repo: repo}
+}
+
+// Record prices an AI call by model and stores it. reportedCost is used as-is when the provider
+// returns the charge (OpenRouter usage.cost).
+func (r *AIUsageRecorder) Record(ctx context.Context, idDevice, model, purpose string, usage *models.TokenUsage, reportedCost *float64) {
+	if r == nil || r.repo == nil || usage == nil || idDevice == "" {
+		return
+	}
+
+	cost, priced := models.AITokenCost(model, usage.PromptTokens, usage.CompletionTokens)
+	if reportedCost != nil {
+		cost, priced = *reportedCost, true
+	}
+
+	err := r.repo.RecordUsage(ctx, &models.AIUsage{
+		IDDevice:         idDevice,
+		Model:            model,
+		Purpose:          purpose,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             cost,
+		Priced:           priced,
+	})
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// GetAIUsageAnalytics totals the AI usage of the user's devices (or one of them) per device, model,
+// purpose and day, and checks the user's budget against today's and this month's spend
+func (s *AnalyticsService) GetAIUsageAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.AIUsageResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.AIUsageResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	// Budgets are per user, so their spend covers every device even when one is asked for
+	allDeviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return &models.AIUsageResponse{
+			Success: false,
+			Message: "Failed to retrieve user devices",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	since := timeRange.StartDate
+	if monthStart.Before(since) {
+		since = monthStart
+	}
+
+	usage, err := s.aiUsageRepo.GetUsageByDevices(ctx, allDeviceIDs, since)
+	if err != nil {
+		return &models.AIUsageResponse{
+			Success: false,
+			Message: "Failed to retrieve AI usage",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	budget, err := s.aiUsageRepo.GetBudget(ctx, userID)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	selected := make(map[string]bool, len(deviceIDs))
+	for _, id := range deviceIDs {
+		selected[id] = true
+	}
+
+	summary := summarizeAIUsage(usage, selected, timeRange)
+	summary.Budget = budget
+	summary.SpentToday, summary.SpentMonth = aiSpend(usage, now)
+	summary.Alerts = aiBudgetAlerts(budget, summary.SpentToday, summary.SpentMonth)
+
+	return &models.AIUsageResponse{
+		Success: true,
+		Message: "AI usage retrieved successfully",
+		Data:    summary,
+	}, nil
+}
+
+// summarizeAIUsage totals the AI calls of the selected devices made in the time range
+func summarizeAIUsage(usage []models.AIUsage, selected map[string]bool, timeRange *models.TimeRangeFilter) *models.AIUsageSummary {
+	summary := &models.AIUsageSummary{
+		ByDevice:  make(map[string]models.AIUsageTotals),
+		ByModel:   make(map[string]models.AIUsageTotals),
+		ByPurpose: make(map[string]models.AIUsageTotals),
+		Daily:     []models.AIUsageDay{},
+		Alerts:    []models.AIBudgetAlert{},
+		TimeRange: timeRange,
+	}
+
+	daily := make(map[string]*models.AIUsageDay)
+	for _, call := range usage {
+		if !selected[call.IDDevice] || call.CreatedAt == nil {
+			continue
+		}
+		if call.CreatedAt.Before(timeRange.StartDate) || call.CreatedAt.After(timeRange.EndDate) {
+			continue
+		}
+
+		summary.Total.Add(call)
+		for _, totals := range []struct {
+			byKey map[string]models.AIUsageTotals
+			key   string
+		}{
+			{summary.ByDevice, call.IDDevice},
+			{summary.ByModel, call.Model},
+			{summary.ByPurpose, call.Purpose},
+		} {
+			entry := totals.byKey[totals.key]
+			entry.Add(call)
+			totals.byKey[totals.key] = entry
+		}
+
+		date := call.CreatedAt.UTC().Format("2006-01-02")
+		day, ok := daily[date+"/"+call.IDDevice]
+		if !ok {
+			day = &models.AIUsageDay{Date: date, IDDevice: call.IDDevice}
+			daily[date+"/"+call.IDDevice] = day
+		}
+		day.Add(call)
+	}
+
+	for _, day := range daily {
+		summary.Daily = append(summary.Daily, *day)
+	}
+	sort.Slice(summary.Daily, func(i, j int) bool {
+		a, b := summary.Daily[i], summary.Daily[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.IDDevice < b.IDDevice
+	})
+
+	return summary
+}
+
+// aiSpend sums the cost of the AI calls made today and this month (UTC)
+func aiSpend(usage []models.AIUsage, now time.Time) (today, month float64) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, call := range usage {
+		if call.CreatedAt == nil {
+			continue
+		}
+		if !call.CreatedAt.Before(monthStart) {
+			month += call.Cost
+		}
+		if !call.CreatedAt.Before(dayStart) {
+			today += call.Cost
+		}
+	}
+	return today, month
+}
+
+// aiBudgetAlerts checks today's and this month's spend against the budget's limits
+func aiBudgetAlerts(budget *models.AIBudget, today, month float64) []models.AIBudgetAlert {
+	alerts := []models.AIBudgetAlert{}
+	if budget == nil {
+		return alerts
+	}
+
+	alertPercent := float64(budget.EffectiveAlertPercent())
+	for _, period := range []struct {
+		name  string
+		limit *float64
+		spent float64
+	}{
+		{"day", budget.DailyLimit, today},
+		{"month", budget.MonthlyLimit, month},
+	} {
+		if period.limit == nil || *period.limit <= 0 {
+			continue
+		}
+
+		percent := period.spent / *period.limit * 100
+		level := ""
+		switch {
+		case percent >= 100:
+			level = models.AIBudgetExceeded
+		case percent >= alertPercent:
+			level = models.AIBudgetWarning
+		default:
+			continue
+		}
+		alerts = append(alerts, models.AIBudgetAlert{
+			Period:  period.name,
+			Level:   level,
+			Spent:   period.spent,
+			Limit:   *period.limit,
+			Percent: math.Round(percent*100) / 100,
+		})
+	}
+	return alerts
+}
+
+// UpdateAIBudget sets the user's AI spend budget
+func (s *AnalyticsService) UpdateAIBudget(ctx context.Context, userID string, req *models.UpdateAIBudgetRequest) (*models.AIBudgetResponse, error) {
+	for _, limit := range []*float64{req.DailyLimit, req.MonthlyLimit} {
+		if limit != nil && *limit < 0 {
+			return &models.AIBudgetResponse{
+				Success: false,
+				Message: "daily_limit and monthly_limit must not be negative (0 removes the limit)",
+			}, nil
+		}
+	}
+	if req.AlertPercent != nil && (*req.AlertPercent < 0 || *req.AlertPercent > 100) {
+		return &models.AIBudgetResponse{
+			Success: false,
+			Message: "alert_percent must be between 1 and 100 (0 uses the default of 80)",
+		}, nil
+	}
+
+	budget, err := s.aiUsageRepo.GetBudget(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		budget = &models.AIBudget{UserID: userID}
+	}
+
+	if req.DailyLimit != nil {
+		budget.DailyLimit = req.DailyLimit
+		if *req.DailyLimit == 0 {
+			budget.DailyLimit = nil
+		}
+	}
+	if req.MonthlyLimit != nil {
+		budget.MonthlyLimit = req.MonthlyLimit
+		if *req.MonthlyLimit == 0 {
+			budget.MonthlyLimit = nil
+		}
+	}
+	if req.AlertPercent != nil {
+		budget.AlertPercent = req.AlertPercent
+		if *req.AlertPercent == 0 {
+			budget.AlertPercent = nil
+		}
+	}
+
+	if err := s.aiUsageRepo.SaveBudget(ctx, budget); err != nil {
+		return nil, err
+	}
+
+	return &models.AIBudgetResponse{
+		Success: true,
+		Message: "AI budget updated successfully",
+		Budget:  budget,
+	}, nil
+}
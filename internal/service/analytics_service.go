@@ -4,6 +4,7 @@ import (
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -11,13 +12,21 @@ import (
 type AnalyticsService struct {
 	analyticsRepo *repository.AnalyticsRepository
 	deviceRepo    *repository.DeviceRepository
+	costRepo      *repository.CostLedgerRepository
+	fieldRepo     *repository.CustomFieldRepository
+	flowRepo      *repository.FlowRepository
+	aiUsageRepo   *repository.AIUsageRepository
 }
 
 // NewAnalyticsService creates a new analytics service
-func NewAnalyticsService(analyticsRepo *repository.AnalyticsRepository, deviceRepo *repository.DeviceRepository) *AnalyticsService {
+func NewAnalyticsService(analyticsRepo *repository.AnalyticsRepository, deviceRepo *repository.DeviceRepository, costRepo *repository.CostLedgerRepository, fieldRepo *repository.CustomFieldRepository, flowRepo *repository.FlowRepository, aiUsageRepo *repository.AIUsageRepository) *AnalyticsService {
 	return &AnalyticsService{
 		analyticsRepo: analyticsRepo,
 		deviceRepo:    deviceRepo,
+		costRepo:      costRepo,
+		fieldRepo:     fieldRepo,
+		flowRepo:      flowRepo,
+		aiUsageRepo:   aiUsageRepo,
 	}
 }
 
@@ -147,6 +156,290 @@ func (s *AnalyticsService) GetFlowAnalytics(ctx context.Context, userID string,
 	}, nil
 }
 
+// GetCSATAnalytics retrieves CSAT survey analytics for the user's devices
+func (s *AnalyticsService) GetCSATAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.CSATAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.CSATAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetCSATMetrics(ctx, deviceIDs, req.FlowID, timeRange)
+	if err != nil {
+		return &models.CSATAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve CSAT analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.CSATAnalyticsResponse{
+		Success: true,
+		Message: "CSAT analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// GetLatencyAnalytics retrieves first-response and reply latency analytics for the user's devices
+func (s *AnalyticsService) GetLatencyAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.LatencyAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.LatencyAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetLatencyMetrics(ctx, deviceIDs, req.FlowID, timeRange)
+	if err != nil {
+		return &models.LatencyAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve latency analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.LatencyAnalyticsResponse{
+		Success: true,
+		Message: "Latency analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// GetSLAAnalytics retrieves stage SLA breach counts for the user's devices
+func (s *AnalyticsService) GetSLAAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.SLAAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.SLAAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetSLAMetrics(ctx, deviceIDs, timeRange)
+	if err != nil {
+		return &models.SLAAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve SLA analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.SLAAnalyticsResponse{
+		Success: true,
+		Message: "SLA analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// GetLinkAnalytics retrieves tracked link click-through per message node for the user's devices
+func (s *AnalyticsService) GetLinkAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.LinkAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.LinkAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetLinkMetrics(ctx, deviceIDs, req.FlowID, timeRange)
+	if err != nil {
+		return &models.LinkAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve link analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.LinkAnalyticsResponse{
+		Success: true,
+		Message: "Link analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// GetABSplitAnalytics compares the variants of the user's ab_split nodes by the stage their
+// prospects reached
+func (s *AnalyticsService) GetABSplitAnalytics(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.ABSplitAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.ABSplitAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetABSplitMetrics(ctx, deviceIDs, req.FlowID, timeRange)
+	if err != nil {
+		return &models.ABSplitAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve A/B split analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.ABSplitAnalyticsResponse{
+		Success: true,
+		Message: "A/B split analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// GetFieldAnalytics counts the user's conversations per value of a conversation column or custom field
+func (s *AnalyticsService) GetFieldAnalytics(ctx context.Context, userID, field string, req *models.AnalyticsRequest) (*models.FieldAnalyticsResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.FieldAnalyticsResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	customFields := userCustomFields(ctx, s.fieldRepo, userID)
+	names := make(map[string]bool, len(customFields))
+	for name := range customFields {
+		names[name] = true
+	}
+	if !names[field] && !repository.IsConversationColumn(field) {
+		return &models.FieldAnalyticsResponse{
+			Success: false,
+			Message: fmt.Sprintf("Unknown field %s: use a conversation column or one of your custom fields", field),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	metrics, err := s.analyticsRepo.GetFieldMetrics(ctx, deviceIDs, field, names, timeRange)
+	if err != nil {
+		return &models.FieldAnalyticsResponse{
+			Success: false,
+			Message: "Failed to retrieve field analytics",
+			Error:   err.Error(),
+		}, nil
+	}
+	if custom, ok := customFields[field]; ok {
+		metrics.Label = custom.Label
+	}
+
+	return &models.FieldAnalyticsResponse{
+		Success: true,
+		Message: "Field analytics retrieved successfully",
+		Data:    metrics,
+	}, nil
+}
+
+// ExportLeadCosts returns acquisition cost per lead from the conversation cost ledger
+func (s *AnalyticsService) ExportLeadCosts(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.CostExportResponse, error) {
+	deviceIDs, err := s.resolveUserDeviceIDs(ctx, userID, req.DeviceID)
+	if err != nil {
+		return &models.CostExportResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	entries, err := s.costRepo.GetCostsByDevices(ctx, deviceIDs, req.TimeRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost ledger: %w", err)
+	}
+
+	leads := summarizeLeadCosts(entries)
+	total := 0.0
+	for _, lead := range leads {
+		total += lead.TotalAmount
+	}
+
+	return &models.CostExportResponse{
+		Success:     true,
+		TotalAmount: total,
+		Leads:       leads,
+	}, nil
+}
+
+// resolveUserDeviceIDs returns the device identifiers to aggregate over.
+// With a device ID it verifies ownership; without one it returns all of the user's devices.
+func (s *AnalyticsService) resolveUserDeviceIDs(ctx context.Context, userID, deviceID string) ([]string, error) {
+	if deviceID != "" {
+		device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
+		if err != nil || device == nil {
+			device, err = s.deviceRepo.GetDeviceByID(ctx, deviceID)
+		}
+
+		if err != nil || device == nil || device.UserID == nil || *device.UserID != userID {
+			return nil, fmt.Errorf("Access denied: device not found or unauthorized")
+		}
+
+		return []string{deviceID}, nil
+	}
+
+	deviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve user devices")
+	}
+
+	return deviceIDs, nil
+}
+
 // ExportAnalytics exports analytics data in specified format
 func (s *AnalyticsService) ExportAnalytics(ctx context.Context, userID string, req *models.ExportRequest) (*models.ExportResponse, error) {
 	// For now, return a placeholder
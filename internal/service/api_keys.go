@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+	"chatbot-automation/internal/utils"
+)
+
+// apiKeyPrefixChars is how many characters after APIKeyPrefix are kept to recognize a key
+const apiKeyPrefixChars = 8
+
+// apiKeyUseInterval throttles last_used_at writes of a busy key
+const apiKeyUseInterval = time.Minute
+
+// APIKeyService creates and revokes users' API keys and authenticates requests made with them
+type APIKeyService struct {
+	keyRepo *repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(keyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		keyRepo: keyRepo,
+	}
+}
+
+// hashAPIKey returns the hex SHA-256 an API key is stored and looked up by. Keys are random
+// 256-bit secrets, so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateAPIKeyScopes checks and deduplicates requested scopes.
+// Returns a user-facing message when one is unknown, or an empty string when valid.
+func validateAPIKeyScopes(requested []string) ([]string, string) {
+	scopes := []string{}
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" || slices.Contains(scopes, scope) {
+			continue
+		}
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, fmt.Sprintf("Unknown scope %q (use %s)", scope, strings.Join(models.APIKeyScopes, ", "))
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, "scopes must list at least one scope"
+	}
+	return scopes, ""
+}
+
+// CreateKey creates an API key for the user. The key is in the response's secret and cannot be
+// retrieved again.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: "name is required",
+		}, nil
+	}
+	scopes, msg := validateAPIKeyScopes(req.Scopes)
+	if msg != "" {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > models.MaxAPIKeyLifetimeDays {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: fmt.Sprintf("expires_in_days must be between 0 (never) and %d", models.MaxAPIKeyLifetimeDays),
+		}, nil
+	}
+
+	keys, err := s.keyRepo.GetKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := 0
+	for i := range keys {
+		if keys[i].Active(now) {
+			active++
+		}
+	}
+	if active >= models.MaxAPIKeysPerUser {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can have at most %d active API keys; revoke one first", models.MaxAPIKeysPerUser),
+		}, nil
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := models.APIKeyPrefix + token
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		KeyPrefix: secret[:len(models.APIKeyPrefix)+apiKeyPrefixChars],
+		KeyHash:   hashAPIKey(secret),
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := s.keyRepo.CreateKey(ctx, key); err != nil {
+		return nil, err
+	}
+	key.KeyHash = ""
+
+	return &models.APIKeyResponse{
+		Success: true,
+		Message: "API key created; store the secret now, it is not shown again",
+		Key:     key,
+		Secret:  secret,
+	}, nil
+}
+
+// ListKeys returns the user's API keys, revoked ones included, newest first
+func (s *APIKeyService) ListKeys(ctx context.Context, userID string) (*models.APIKeyResponse, error) {
+	keys, err := s.keyRepo.GetKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	for i := range keys {
+		keys[i].KeyHash = ""
+	}
+
+	return &models.APIKeyResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d API keys", len(keys)),
+		Keys:    keys,
+	}, nil
+}
+
+// RevokeKey stops an API key from authenticating. Revoked keys stay listed.
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, keyID string) (*models.APIKeyResponse, error) {
+	key, err := s.keyRepo.GetKeyByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: "API key not found",
+		}, nil
+	}
+	if key.UserID != userID {
+		return &models.APIKeyResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := s.keyRepo.UpdateKey(ctx, keyID, map[string]interface{}{"revoked_at": now}); err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+		log.Printf("🔑 API key %s (%s) of user %s revoked", key.KeyPrefix, key.Name, userID)
+	}
+	key.KeyHash = ""
+
+	return &models.APIKeyResponse{
+		Success: true,
+		Message: "API key revoked",
+		Key:     key,
+	}, nil
+}
+
+// AuthenticateAPIKey returns the user and scopes of an active API key, or an empty user ID when
+// the key is unknown, revoked or expired. Errors are lookup failures.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, secret string) (string, []string, error) {
+	if !strings.HasPrefix(secret, models.APIKeyPrefix) {
+		return "", nil, nil
+	}
+
+	key, err := s.keyRepo.GetKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	if key == nil || !key.Active(now) {
+		return "", nil, nil
+	}
+
+	// last_used_at is informational; a failed write doesn't fail the request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUseInterval {
+		if err := s.keyRepo.UpdateKey(ctx, key.ID, map[string]interface{}{"last_used_at": now}); err != nil {
+			log.Printf("⚠️  Failed to record use of API key %s: %v", key.KeyPrefix, err)
+		}
+	}
+
+	return key.UserID, key.Scopes, nil
+}
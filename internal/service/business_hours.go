@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Business hours. A business_hours node branches on whether the device is open, following its
+// "open" or "closed" connection by conditionValue; a connection without a value takes whichever
+// of the two has no connection of its own:
+//
+//	{"type": "business_hours", "config": {"hours": "Mon-Fri 09:00-18:00; Sat 10:00-14:00"}}
+//	{"from": "hours", "to": "greet", "conditionValue": "open"}
+//	{"from": "hours", "to": "away", "conditionValue": "closed"}
+//
+// Without hours the node uses the device's business_hours, and without those the device is open.
+// Devices with business_hours also route inbound messages that arrive while closed by their
+// closed_mode: "away" runs the away flow instead of the triggered one, "queue" runs the triggered
+// flow but holds its bot replies until opening.
+const flowBusinessHoursNode = "business_hours"
+
+// Business hours branch names, the conditionValue of a business_hours node's connections
+const (
+	businessHoursOpen   = "open"
+	businessHoursClosed = "closed"
+)
+
+type holdUntilKey struct{}
+
+// withHoldUntil holds the bot messages sent with ctx in queued_sends until at
+func withHoldUntil(ctx context.Context, at time.Time) context.Context {
+	return context.WithValue(ctx, holdUntilKey{}, at)
+}
+
+// validateBusinessHours checks a business hours value; empty removes them.
+// Returns an error message for the client, or "" when valid.
+func validateBusinessHours(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	if _, err := parseBusinessHours(value); err != nil {
+		return fmt.Sprintf("business_hours must be \"[days] HH:MM-HH:MM\" windows separated by \";\": %v", err)
+	}
+	return ""
+}
+
+// validateClosedMode checks a device's closed_mode; empty resets it to away.
+// Returns an error message for the client, or "" when valid.
+func validateClosedMode(mode string) string {
+	if mode != "" && !models.IsValidClosedMode(mode) {
+		return fmt.Sprintf("closed_mode must be %s or %s", models.ClosedModeAway, models.ClosedModeQueue)
+	}
+	return ""
+}
+
+// deviceOpen reports whether a device is inside its business hours, and when it opens next if not.
+// Devices without (valid) business hours are always open.
+func deviceOpen(device *models.DeviceSetting, now time.Time) (bool, time.Time) {
+	hours := getStringValue(device.BusinessHours)
+	if strings.TrimSpace(hours) == "" {
+		return true, time.Time{}
+	}
+
+	now = now.In(device.Location())
+	open, err := inBusinessHours(hours, now)
+	if err != nil {
+		log.Printf("⚠️  Invalid business_hours %q on device %s: %v", hours, getStringValue(device.IDDevice), err)
+		return true, time.Time{}
+	}
+	if open {
+		return true, time.Time{}
+	}
+
+	opening, err := nextBusinessOpening(hours, now)
+	if err != nil || opening.IsZero() {
+		return false, time.Time{}
+	}
+	return false, opening
+}
+
+// routeClosedDevice applies a device's business hours to an inbound message. While the device is
+// closed it returns the away flow to run in away mode (nil when it has none among flows), or ctx
+// holding the run's bot replies until opening in queue mode. open reports whether the device is open.
+func routeClosedDevice(ctx context.Context, device *models.DeviceSetting, flows []models.ChatbotFlow) (context.Context, *models.ChatbotFlow, bool) {
+	open, opening := deviceOpen(device, time.Now())
+	if open {
+		return ctx, nil, true
+	}
+
+	if device.EffectiveClosedMode() == models.ClosedModeQueue {
+		if opening.IsZero() {
+			return ctx, nil, false
+		}
+		log.Printf("🌙 Device %s is closed: replies wait until %s", getStringValue(device.IDDevice), opening.Format(time.RFC3339))
+		return withHoldUntil(ctx, opening), nil, false
+	}
+
+	awayFlowID := getStringValue(device.AwayFlowID)
+	if awayFlowID == "" {
+		return ctx, nil, false
+	}
+	for i := range flows {
+		if flows[i].ID == awayFlowID {
+			log.Printf("🌙 Device %s is closed: away flow %s answers", getStringValue(device.IDDevice), flows[i].Name)
+			return ctx, &flows[i], false
+		}
+	}
+	log.Printf("⚠️  Away flow %s of device %s is not an active flow of the device", awayFlowID, getStringValue(device.IDDevice))
+	return ctx, nil, false
+}
+
+// leavesAwayFlow reports whether a conversation still bound to the device's away flow should go
+// back to the normal flows, which it does once the device is open again
+func leavesAwayFlow(device *models.DeviceSetting, open bool, boundFlowID *string) bool {
+	return open && device.AwayFlowID != nil && *device.AwayFlowID != "" && getStringValue(boundFlowID) == *device.AwayFlowID
+}
+
+// businessHoursBranch returns "open" or "closed" for a business_hours node, in the device timezone
+func (run *flowRun) businessHoursBranch(ctx context.Context, node *FlowNode) string {
+	var device *models.DeviceSetting
+	if run.deviceRepo != nil {
+		device, _ = run.deviceRepo.GetDeviceByIDDevice(ctx, run.flow.IDDevice)
+	}
+	if device == nil {
+		device = &models.DeviceSetting{}
+	}
+
+	if hours := configText(node.Config["hours"]); hours != "" {
+		// The node's own hours win over the device's
+		device = &models.DeviceSetting{Timezone: device.Timezone, BusinessHours: &hours}
+	}
+	if open, _ := deviceOpen(device, time.Now()); open {
+		return businessHoursOpen
+	}
+	return businessHoursClosed
+}
+
+// businessHoursNext follows the business_hours node's connection for whether the device is open
+func (run *flowRun) businessHoursNext(ctx context.Context, node *FlowNode, edges []FlowEdge) *FlowNode {
+	branch := run.businessHoursBranch(ctx, node)
+	log.Printf("🕘 Business hours node %s: %s", node.ID, branch)
+
+	var fallback *FlowEdge
+	for i := range edges {
+		switch strings.ToLower(strings.TrimSpace(edges[i].ConditionValue)) {
+		case branch:
+			return findFlowNode(run.flowData, edges[i].To)
+		case "":
+			if fallback == nil {
+				fallback = &edges[i]
+			}
+		}
+	}
+	if fallback == nil {
+		log.Printf("ℹ️  Business hours node %s has no %s connection", node.ID, branch)
+		return nil
+	}
+	return findFlowNode(run.flowData, fallback.To)
+}
+
+// businessHoursProcessor passes through; findNextNode picks the open or closed branch
+type businessHoursProcessor struct{}
+
+func (p *businessHoursProcessor) GetNodeType() string { return flowBusinessHoursNode }
+
+func (p *businessHoursProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("🕘 Business hours")
+	return true, nil
+}
+
+// validateBusinessHoursNode checks a business_hours node's hours and its open and closed branches
+func validateBusinessHoursNode(node *FlowNode, edges []FlowEdge, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	if hours := configText(node.Config["hours"]); hours != "" {
+		if _, err := parseBusinessHours(hours); err != nil {
+			add(models.FlowIssueError, models.FlowIssueInvalidDefinition, node.ID,
+				fmt.Sprintf("%s has invalid hours %q: %v", flowDocNodeName(node), hours, err))
+		}
+	}
+
+	branches := make(map[string]bool)
+	for _, edge := range edges {
+		value := strings.ToLower(strings.TrimSpace(edge.ConditionValue))
+		switch value {
+		case businessHoursOpen, businessHoursClosed, "":
+			branches[value] = true
+		default:
+			issue := add(models.FlowIssueWarning, models.FlowIssueIncompleteBranch, node.ID,
+				fmt.Sprintf("Branch %s -> %s is %q, which is neither open nor closed, so no prospect takes it", edge.From, edge.To, edge.ConditionValue))
+			issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+		}
+	}
+	for _, branch := range []string{businessHoursOpen, businessHoursClosed} {
+		if !branches[branch] && !branches[""] {
+			add(models.FlowIssueWarning, models.FlowIssueIncompleteBranch, node.ID,
+				fmt.Sprintf("%s has no %s branch, so the flow ends there when the device is %s", flowDocNodeName(node), branch, branch))
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// campaignPhoneColumns and campaignNameColumns are the CSV headers read as the number and name;
+// every other column becomes a recipient variable
+var (
+	campaignPhoneColumns = []string{"phone", "phone_number", "prospect_num", "number", "nombor"}
+	campaignNameColumns  = []string{"name", "prospect_name", "nama"}
+)
+
+// CreateCampaign builds a draft campaign from an uploaded CSV or a saved segment. Blacklisted,
+// opted-out, unconsented and repeated numbers are left out and counted in Excluded.
+func (s *CampaignService) CreateCampaign(ctx context.Context, userID string, req *models.CreateCampaignRequest) (*models.CampaignResponse, error) {
+	idDevice, msg := s.ownedDeviceID(ctx, userID, req.DeviceID)
+	if msg != "" {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: "name is required",
+		}, nil
+	}
+
+	if msg := s.validateCampaignContent(ctx, idDevice, req.Message, req.FlowID); msg != "" {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.RatePerMinute < 0 || req.RatePerMinute > models.MaxCampaignRate {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: fmt.Sprintf("rate_per_minute must be between 1 and %d", models.MaxCampaignRate),
+		}, nil
+	}
+
+	hasCSV := strings.TrimSpace(req.CSV) != ""
+	hasSegment := req.SegmentID != nil && *req.SegmentID != ""
+	if hasCSV == hasSegment {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: "Provide either csv or segment_id",
+		}, nil
+	}
+
+	var candidates []models.CampaignRecipient
+	excluded := map[string]int{}
+	source := models.CampaignSourceCSV
+	if hasCSV {
+		var invalid int
+		var err error
+		candidates, invalid, err = parseCampaignCSV(req.CSV, s.devicePhoneCountry(ctx, idDevice))
+		if err != nil {
+			return &models.CampaignResponse{
+				Success: false,
+				Message: err.Error(),
+			}, nil
+		}
+		if invalid > 0 {
+			excluded["invalid"] = invalid
+		}
+	} else {
+		source = models.CampaignSourceSegment
+		segment, err := s.campaignRepo.GetSegmentByID(ctx, *req.SegmentID)
+		if err != nil {
+			return nil, err
+		}
+		if segment == nil || segment.UserID != userID {
+			return &models.CampaignResponse{
+				Success: false,
+				Message: "Segment not found",
+			}, nil
+		}
+		candidates, err = s.segmentCandidates(ctx, idDevice, segment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recipients, err := s.selectRecipients(ctx, userID, candidates, excluded)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return &models.CampaignResponse{
+			Success:  false,
+			Message:  "No recipients left after exclusions",
+			Excluded: excluded,
+		}, nil
+	}
+	if len(recipients) > models.MaxCampaignRecipients {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: fmt.Sprintf("A campaign can have at most %d recipients", models.MaxCampaignRecipients),
+		}, nil
+	}
+
+	campaign := &models.Campaign{
+		UserID:          userID,
+		IDDevice:        idDevice,
+		Name:            name,
+		Source:          source,
+		Message:         req.Message,
+		FlowID:          req.FlowID,
+		Status:          models.CampaignStatusDraft,
+		TotalRecipients: len(recipients),
+		RatePerMinute:   req.RatePerMinute,
+	}
+	if hasSegment {
+		campaign.SegmentID = req.SegmentID
+	}
+	if err := s.campaignRepo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	for i := range recipients {
+		recipients[i].CampaignID = campaign.ID
+	}
+	if err := s.campaignRepo.AddRecipients(ctx, recipients); err != nil {
+		return nil, err
+	}
+
+	return &models.CampaignResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Campaign created with %d recipients", len(recipients)),
+		Campaign: campaign,
+		Excluded: excluded,
+	}, nil
+}
+
+// validateCampaignContent checks that a campaign sends a message or starts one of the device's flows
+func (s *CampaignService) validateCampaignContent(ctx context.Context, idDevice string, message, flowID *string) string {
+	if flowID != nil && *flowID != "" {
+		flow, err := s.flowRepo.GetFlowByID(ctx, *flowID)
+		if err != nil || flow == nil || flow.IDDevice != idDevice {
+			return "flow_id does not belong to this device"
+		}
+		return ""
+	}
+	if message == nil || strings.TrimSpace(*message) == "" {
+		return "message or flow_id is required"
+	}
+	return ""
+}
+
+// parseCampaignCSV reads recipients from CSV with a header row, reading national numbers in
+// phoneCountry. Rows without a valid number are counted as invalid.
+func parseCampaignCSV(text, phoneCountry string) ([]models.CampaignRecipient, int, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("csv has no header row")
+	}
+
+	phoneCol, nameCol := -1, -1
+	columns := make([]string, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		columns[i] = column
+		if phoneCol < 0 && slices.Contains(campaignPhoneColumns, column) {
+			phoneCol = i
+		} else if nameCol < 0 && slices.Contains(campaignNameColumns, column) {
+			nameCol = i
+		}
+	}
+	if phoneCol < 0 {
+		return nil, 0, fmt.Errorf("csv needs a phone column (%s)", strings.Join(campaignPhoneColumns, ", "))
+	}
+
+	recipients := make([]models.CampaignRecipient, 0)
+	invalid := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid csv: %v", err)
+		}
+		if phoneCol >= len(record) {
+			invalid++
+			continue
+		}
+		phone, err := models.NormalizePhone(record[phoneCol], phoneCountry)
+		if err != nil {
+			invalid++
+			continue
+		}
+
+		recipient := models.CampaignRecipient{
+			ProspectNum: phone,
+			Status:      models.RecipientStatusPending,
+		}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i == phoneCol || value == "" || columns[i] == "" {
+				continue
+			}
+			if i == nameCol {
+				name := value
+				recipient.ProspectName = &name
+				continue
+			}
+			if recipient.Variables == nil {
+				recipient.Variables = make(map[string]interface{})
+			}
+			recipient.Variables[columns[i]] = value
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, invalid, nil
+}
+
+// segmentCandidates lists the device's ai_whatsapp and wasapbot conversations matching a segment
+func (s *CampaignService) segmentCandidates(ctx context.Context, idDevice string, segment *models.ContactSegment) ([]models.CampaignRecipient, error) {
+	candidates := make([]models.CampaignRecipient, 0)
+	add := func(prospectNum string, prospectName, stage *string) {
+		candidates = append(candidates, models.CampaignRecipient{
+			ProspectNum:  prospectNum,
+			ProspectName: prospectName,
+			Stage:        stage,
+			Status:       models.RecipientStatusPending,
+		})
+	}
+
+	aiConvs, err := s.convRepo.GetSegmentConversations(ctx, idDevice, segment)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range aiConvs {
+		add(conv.ProspectNum, conv.ProspectName, conv.Stage)
+	}
+
+	botConvs, err := s.wasapbotRepo.GetSegmentConversations(ctx, idDevice, segment)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range botConvs {
+		add(conv.ProspectNum, conv.ProspectName, conv.Stage)
+	}
+
+	return candidates, nil
+}
+
+// selectRecipients drops repeated, blacklisted, opted-out and unconsented numbers, counting them in excluded
+func (s *CampaignService) selectRecipients(ctx context.Context, userID string, candidates []models.CampaignRecipient, excluded map[string]int) ([]models.CampaignRecipient, error) {
+	blocked, err := s.blockedNumbers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	consented, err := s.consents.ConsentedNumbers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	recipients := make([]models.CampaignRecipient, 0, len(candidates))
+	for _, candidate := range candidates {
+		key := phoneKey(candidate.ProspectNum)
+		if key == "" {
+			excluded["invalid"]++
+			continue
+		}
+		if seen[key] {
+			excluded["duplicate"]++
+			continue
+		}
+		seen[key] = true
+
+		if reason, ok := blocked[key]; ok {
+			excluded[reason]++
+			continue
+		}
+		if !consented[key] {
+			excluded["no_consent"]++
+			continue
+		}
+		recipients = append(recipients, candidate)
+	}
+
+	return recipients, nil
+}
+
+// ListCampaigns lists the user's campaigns, newest first
+func (s *CampaignService) ListCampaigns(ctx context.Context, userID string) (*models.CampaignResponse, error) {
+	campaigns, err := s.campaignRepo.GetCampaignsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CampaignResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Found %d campaigns", len(campaigns)),
+		Campaigns: campaigns,
+	}, nil
+}
+
+// GetCampaign returns a campaign with its recipients counted by status
+func (s *CampaignService) GetCampaign(ctx context.Context, userID, campaignID string) (*models.CampaignResponse, error) {
+	campaign, msg, err := s.ownedCampaign(ctx, userID, campaignID)
+	if err != nil || msg != "" {
+		return &models.CampaignResponse{Success: false, Message: msg}, err
+	}
+
+	stats := &models.CampaignStats{}
+	for status, count := range map[string]*int{
+		models.RecipientStatusPending: &stats.Pending,
+		models.RecipientStatusSending: &stats.Sending,
+		models.RecipientStatusSent:    &stats.Sent,
+		models.RecipientStatusFailed:  &stats.Failed,
+		models.RecipientStatusSkipped: &stats.Skipped,
+	} {
+		if *count, err = s.campaignRepo.CountRecipients(ctx, campaign.ID, status); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.CampaignResponse{
+		Success:  true,
+		Message:  "Campaign retrieved successfully",
+		Campaign: campaign,
+		Stats:    stats,
+	}, nil
+}
+
+// GetCampaignRecipients lists a campaign's recipients, optionally in one status
+func (s *CampaignService) GetCampaignRecipients(ctx context.Context, userID, campaignID, status string) (*models.CampaignResponse, error) {
+	campaign, msg, err := s.ownedCampaign(ctx, userID, campaignID)
+	if err != nil || msg != "" {
+		return &models.CampaignResponse{Success: false, Message: msg}, err
+	}
+
+	switch status {
+	case "", models.RecipientStatusPending, models.RecipientStatusSending, models.RecipientStatusSent, models.RecipientStatusFailed, models.RecipientStatusSkipped:
+	default:
+		return &models.CampaignResponse{
+			Success: false,
+			Message: "status must be pending, sending, sent, failed or skipped",
+		}, nil
+	}
+
+	recipients, err := s.campaignRepo.GetRecipients(ctx, campaign.ID, status, models.MaxCampaignRecipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CampaignResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Found %d recipients", len(recipients)),
+		Campaign:   campaign,
+		Recipients: recipients,
+	}, nil
+}
+
+// StartCampaign begins sending a draft campaign
+func (s *CampaignService) StartCampaign(ctx context.Context, userID, campaignID string) (*models.CampaignResponse, error) {
+	return s.transitionCampaign(ctx, userID, campaignID, []string{models.CampaignStatusDraft}, models.CampaignStatusRunning, "Campaign started")
+}
+
+// PauseCampaign stops sending after the message in flight; pending recipients wait for resume
+func (s *CampaignService) PauseCampaign(ctx context.Context, userID, campaignID string) (*models.CampaignResponse, error) {
+	return s.transitionCampaign(ctx, userID, campaignID, []string{models.CampaignStatusRunning}, models.CampaignStatusPaused, "Campaign paused")
+}
+
+// ResumeCampaign continues sending a paused campaign
+func (s *CampaignService) ResumeCampaign(ctx context.Context, userID, campaignID string) (*models.CampaignResponse, error) {
+	return s.transitionCampaign(ctx, userID, campaignID, []string{models.CampaignStatusPaused}, models.CampaignStatusRunning, "Campaign resumed")
+}
+
+// CancelCampaign stops a campaign for good; recipients not reached yet are marked skipped
+func (s *CampaignService) CancelCampaign(ctx context.Context, userID, campaignID string) (*models.CampaignResponse, error) {
+	resp, err := s.transitionCampaign(ctx, userID, campaignID,
+		[]string{models.CampaignStatusDraft, models.CampaignStatusRunning, models.CampaignStatusPaused},
+		models.CampaignStatusCancelled, "Campaign cancelled")
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+
+	if err := s.campaignRepo.SkipPendingRecipients(ctx, campaignID, "cancelled"); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// transitionCampaign moves a campaign from one of the from statuses to status
+func (s *CampaignService) transitionCampaign(ctx context.Context, userID, campaignID string, from []string, status, message string) (*models.CampaignResponse, error) {
+	campaign, msg, err := s.ownedCampaign(ctx, userID, campaignID)
+	if err != nil || msg != "" {
+		return &models.CampaignResponse{Success: false, Message: msg}, err
+	}
+
+	if !slices.Contains(from, campaign.Status) {
+		return &models.CampaignResponse{
+			Success: false,
+			Message: fmt.Sprintf("Campaign is %s", campaign.Status),
+		}, nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	switch {
+	case status == models.CampaignStatusRunning && campaign.StartedAt == nil:
+		updates["started_at"] = now
+		campaign.StartedAt = &now
+	case status == models.CampaignStatusCancelled:
+		updates["completed_at"] = now
+		campaign.CompletedAt = &now
+	}
+	if err := s.campaignRepo.UpdateCampaign(ctx, campaign.ID, updates); err != nil {
+		return nil, err
+	}
+	campaign.Status = status
+
+	return &models.CampaignResponse{
+		Success:  true,
+		Message:  message,
+		Campaign: campaign,
+	}, nil
+}
+
+// ownedCampaign loads a campaign and checks it belongs to the user
+func (s *CampaignService) ownedCampaign(ctx context.Context, userID, campaignID string) (*models.Campaign, string, error) {
+	campaign, err := s.campaignRepo.GetCampaignByID(ctx, campaignID)
+	if err != nil {
+		return nil, "", err
+	}
+	if campaign == nil || campaign.UserID != userID {
+		return nil, "Campaign not found", nil
+	}
+	return campaign, "", nil
+}
+
+// ListSegments lists the user's saved segments
+func (s *CampaignService) ListSegments(ctx context.Context, userID string) (*models.SegmentResponse, error) {
+	segments, err := s.campaignRepo.GetSegmentsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SegmentResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d segments", len(segments)),
+		Segments: segments,
+	}, nil
+}
+
+// CreateSegment saves a segment campaigns can target
+func (s *CampaignService) CreateSegment(ctx context.Context, userID string, req *models.CreateSegmentRequest) (*models.SegmentResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return &models.SegmentResponse{
+			Success: false,
+			Message: "name is required",
+		}, nil
+	}
+	if req.ExecutionStatus != nil && *req.ExecutionStatus != "" && !models.ConversationState(*req.ExecutionStatus).IsValid() {
+		return &models.SegmentResponse{
+			Success: false,
+			Message: fmt.Sprintf("Unknown execution_status %q", *req.ExecutionStatus),
+		}, nil
+	}
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && !req.CreatedAfter.Before(*req.CreatedBefore) {
+		return &models.SegmentResponse{
+			Success: false,
+			Message: "created_after must be before created_before",
+		}, nil
+	}
+
+	segment := &models.ContactSegment{
+		UserID:          userID,
+		Name:            name,
+		Stage:           req.Stage,
+		ExecutionStatus: req.ExecutionStatus,
+		Niche:           req.Niche,
+		CreatedAfter:    req.CreatedAfter,
+		CreatedBefore:   req.CreatedBefore,
+	}
+	if err := s.campaignRepo.CreateSegment(ctx, segment); err != nil {
+		return nil, err
+	}
+
+	return &models.SegmentResponse{
+		Success: true,
+		Message: "Segment created successfully",
+		Segment: segment,
+	}, nil
+}
+
+// DeleteSegment deletes one of the user's segments
+func (s *CampaignService) DeleteSegment(ctx context.Context, userID, segmentID string) (*models.SegmentResponse, error) {
+	segment, err := s.campaignRepo.GetSegmentByID(ctx, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	if segment == nil || segment.UserID != userID {
+		return &models.SegmentResponse{
+			Success: false,
+			Message: "Segment not found",
+		}, nil
+	}
+
+	if err := s.campaignRepo.DeleteSegment(ctx, segmentID); err != nil {
+		return nil, err
+	}
+
+	return &models.SegmentResponse{
+		Success: true,
+		Message: "Segment deleted successfully",
+	}, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Start sends the running campaigns' due messages immediately and then every interval until ctx is cancelled
+func (s *CampaignService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := s.SendDue(ctx, interval); err != nil {
+				log.Printf("⚠️  Campaign sending failed: %v", err)
+			} else if sent > 0 {
+				log.Printf("📣 Handled %d campaign recipient(s)", sent)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SendDue handles one interval's worth of recipients of every running campaign, spread evenly
+// over the interval at each campaign's rate, and returns how many recipients were handled.
+// Campaigns send in parallel so a slow device does not hold back the others.
+func (s *CampaignService) SendDue(ctx context.Context, interval time.Duration) (int, error) {
+	campaigns, err := s.campaignRepo.GetCampaignsByStatus(ctx, models.CampaignStatusRunning)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+	for i := range campaigns {
+		wg.Add(1)
+		go func(campaign *models.Campaign) {
+			defer wg.Done()
+			handled := s.sendCampaignBatch(ctx, campaign, interval)
+			mu.Lock()
+			total += handled
+			mu.Unlock()
+		}(&campaigns[i])
+	}
+	wg.Wait()
+
+	return total, nil
+}
+
+// sendCampaignBatch sends to the campaign's next pending recipients, one every minute/rate.
+// The campaign's status is re-read before each send so pause and cancel take effect between
+// messages, and each recipient is claimed first so two senders never message the same number.
+// A campaign with no pending recipients left is completed.
+func (s *CampaignService) sendCampaignBatch(ctx context.Context, campaign *models.Campaign, interval time.Duration) int {
+	rate := campaign.EffectiveRate()
+	batch := int(math.Ceil(float64(rate) * interval.Minutes()))
+	if batch < 1 {
+		batch = 1
+	}
+	spacing := time.Minute / time.Duration(rate)
+
+	recipients, err := s.campaignRepo.GetRecipients(ctx, campaign.ID, models.RecipientStatusPending, batch)
+	if err != nil {
+		log.Printf("⚠️  Campaign %s: failed to load recipients: %v", campaign.ID, err)
+		return 0
+	}
+	if len(recipients) == 0 {
+		if err := s.campaignRepo.UpdateCampaign(ctx, campaign.ID, map[string]interface{}{
+			"status":       models.CampaignStatusCompleted,
+			"completed_at": time.Now(),
+		}); err != nil {
+			log.Printf("⚠️  Campaign %s: failed to complete: %v", campaign.ID, err)
+		}
+		return 0
+	}
+
+	// Numbers blacklisted or opted out after the campaign was built are skipped at send time
+	blocked, err := s.blockedNumbers(ctx, campaign.UserID)
+	if err != nil {
+		log.Printf("⚠️  Campaign %s: failed to load blacklist: %v", campaign.ID, err)
+		return 0
+	}
+
+	handled := 0
+	for i := range recipients {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return handled
+			case <-time.After(spacing):
+			}
+
+			current, err := s.campaignRepo.GetCampaignByID(ctx, campaign.ID)
+			if err != nil || current == nil || current.Status != models.CampaignStatusRunning {
+				return handled
+			}
+		}
+
+		claimed, err := s.campaignRepo.ClaimRecipient(ctx, recipients[i].ID)
+		if err != nil {
+			log.Printf("⚠️  Campaign %s: failed to claim recipient %s: %v", campaign.ID, recipients[i].ProspectNum, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		s.sendToRecipient(ctx, campaign, &recipients[i], blocked)
+		handled++
+	}
+
+	return handled
+}
+
+// sendToRecipient sends the campaign's message or starts its flow for one recipient and records the outcome
+func (s *CampaignService) sendToRecipient(ctx context.Context, campaign *models.Campaign, recipient *models.CampaignRecipient, blocked map[string]string) {
+	updates := map[string]interface{}{}
+	if reason, ok := blocked[phoneKey(recipient.ProspectNum)]; ok {
+		updates["status"] = models.RecipientStatusSkipped
+		updates["error"] = reason
+	} else if err := s.deliverCampaign(ctx, campaign, recipient); err != nil {
+		updates["status"] = models.RecipientStatusFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = models.RecipientStatusSent
+		updates["sent_at"] = time.Now()
+	}
+
+	if err := s.campaignRepo.UpdateRecipient(ctx, recipient.ID, updates); err != nil {
+		log.Printf("⚠️  Campaign %s: failed to record recipient %s: %v", campaign.ID, recipient.ProspectNum, err)
+	}
+}
+
+// deliverCampaign starts the campaign's flow for the recipient, or sends its message with the
+// recipient's name, number, stage and CSV columns filled in
+func (s *CampaignService) deliverCampaign(ctx context.Context, campaign *models.Campaign, recipient *models.CampaignRecipient) error {
+	if campaign.FlowID != nil && *campaign.FlowID != "" {
+		resp, err := s.flows.StartFlow(ctx, campaign.UserID, &models.StartFlowRequest{
+			DeviceID:    campaign.IDDevice,
+			ProspectNum: recipient.ProspectNum,
+			FlowID:      *campaign.FlowID,
+			Variables:   recipient.Variables,
+		})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return errors.New(resp.Message)
+		}
+		return nil
+	}
+
+	if campaign.Message == nil {
+		return errors.New("campaign has no message")
+	}
+	vars := map[string]interface{}{
+		"prospect_num":  recipient.ProspectNum,
+		"prospect_name": getStringValue(recipient.ProspectName),
+		"stage":         getStringValue(recipient.Stage),
+	}
+	for key, value := range recipient.Variables {
+		if _, exists := vars[key]; !exists {
+			vars[key] = value
+		}
+	}
+	return s.sender.SendMessage(ctx, campaign.IDDevice, recipient.ProspectNum, renderMessageTemplate(*campaign.Message, vars), "", "")
+}
@@ -0,0 +1,516 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// CampaignService handles campaign (broadcast) business logic
+type CampaignService struct {
+	campaignRepo  *repository.CampaignRepository
+	blacklistRepo *repository.BlacklistRepository
+	convRepo      *repository.ConversationRepository
+	wasapbotRepo  *repository.WasapbotRepository
+	deviceRepo    *repository.DeviceRepository
+	flowRepo      *repository.FlowRepository
+	consents      *ConsentService
+	sender        FlowMessageSender     // sends message campaigns
+	flows         *FlowExecutionService // starts flow campaigns
+}
+
+// NewCampaignService creates a new campaign service
+func NewCampaignService(
+	campaignRepo *repository.CampaignRepository,
+	blacklistRepo *repository.BlacklistRepository,
+	convRepo *repository.ConversationRepository,
+	wasapbotRepo *repository.WasapbotRepository,
+	deviceRepo *repository.DeviceRepository,
+	flowRepo *repository.FlowRepository,
+	consents *ConsentService,
+	sender FlowMessageSender,
+	flows *FlowExecutionService,
+) *CampaignService {
+	return &CampaignService{
+		campaignRepo:  campaignRepo,
+		blacklistRepo: blacklistRepo,
+		convRepo:      convRepo,
+		wasapbotRepo:  wasapbotRepo,
+		deviceRepo:    deviceRepo,
+		flowRepo:      flowRepo,
+		consents:      consents,
+		sender:        sender,
+		flows:         flows,
+	}
+}
+
+// recycleCandidate is an abandoned conversation from either table
+type recycleCandidate struct {
+	prospectNum  string
+	prospectName *string
+	stage        string
+}
+
+// RecycleProspects selects conversations abandoned before a stage and loads them into a new draft campaign
+func (s *CampaignService) RecycleProspects(ctx context.Context, userID string, req *models.RecycleProspectsRequest) (*models.RecycleProspectsResponse, error) {
+	idDevice, msg := s.ownedDeviceID(ctx, userID, req.DeviceID)
+	if msg != "" {
+		return &models.RecycleProspectsResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	if !req.DryRun && (req.Message == nil || strings.TrimSpace(*req.Message) == "") && (req.TargetFlowID == nil || *req.TargetFlowID == "") {
+		return &models.RecycleProspectsResponse{
+			Success: false,
+			Message: "message or target_flow_id is required unless dry_run is set",
+		}, nil
+	}
+	if req.TargetFlowID != nil && *req.TargetFlowID != "" {
+		target, err := s.flowRepo.GetFlowByID(ctx, *req.TargetFlowID)
+		if err != nil || target == nil || target.IDDevice != idDevice {
+			return &models.RecycleProspectsResponse{
+				Success: false,
+				Message: "target_flow_id does not belong to this device",
+			}, nil
+		}
+	}
+
+	// Date range defaults to the last 90 days
+	end := time.Now()
+	if req.EndDate != nil {
+		end = *req.EndDate
+	}
+	start := end.AddDate(0, 0, -90)
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	if !start.Before(end) {
+		return &models.RecycleProspectsResponse{
+			Success: false,
+			Message: "start_date must be before end_date",
+		}, nil
+	}
+
+	stalledHours := models.DefaultRecycleStalledHours
+	if req.StalledAfterHours != nil {
+		stalledHours = *req.StalledAfterHours
+	}
+	var stalledBefore *time.Time
+	if stalledHours > 0 {
+		cutoff := time.Now().Add(-time.Duration(stalledHours) * time.Hour)
+		stalledBefore = &cutoff
+	}
+
+	// Resolve where before_stage sits in the funnel
+	stageIndex := map[string]int{}
+	beforeIndex := -1
+	if req.BeforeStage != "" {
+		order := req.StageOrder
+		if len(order) == 0 {
+			var err error
+			order, err = s.flowStageOrder(ctx, idDevice, req.FlowID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i, stage := range order {
+			key := strings.ToLower(strings.TrimSpace(stage))
+			if _, seen := stageIndex[key]; !seen {
+				stageIndex[key] = i
+			}
+		}
+		idx, ok := stageIndex[strings.ToLower(strings.TrimSpace(req.BeforeStage))]
+		if !ok {
+			return &models.RecycleProspectsResponse{
+				Success: false,
+				Message: fmt.Sprintf("before_stage %q is not in the stage order", req.BeforeStage),
+			}, nil
+		}
+		beforeIndex = idx
+	}
+
+	candidates, err := s.abandonedCandidates(ctx, idDevice, start, end, stalledBefore, req.FlowID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exclusions: blacklist / opt-out, no marketing consent, then recent contact
+	blocked, err := s.blockedNumbers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	consented, err := s.consents.ConsentedNumbers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := map[string]bool{}
+	if req.ExcludeContactedDays > 0 {
+		recent, err = s.recentlyContacted(ctx, userID, time.Now().AddDate(0, 0, -req.ExcludeContactedDays))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	excluded := map[string]int{}
+	seen := map[string]bool{}
+	recipients := make([]models.CampaignRecipient, 0)
+	for _, candidate := range candidates {
+		if beforeIndex >= 0 && candidate.stage != "" {
+			idx, ok := stageIndex[strings.ToLower(candidate.stage)]
+			if !ok || idx >= beforeIndex {
+				continue
+			}
+		}
+
+		key := phoneKey(candidate.prospectNum)
+		if key == "" {
+			continue
+		}
+		if seen[key] {
+			excluded["duplicate"]++
+			continue
+		}
+		seen[key] = true
+
+		if reason, ok := blocked[key]; ok {
+			excluded[reason]++
+			continue
+		}
+		if !consented[key] {
+			excluded["no_consent"]++
+			continue
+		}
+		if recent[key] {
+			excluded["recently_contacted"]++
+			continue
+		}
+
+		recipient := models.CampaignRecipient{
+			ProspectNum:  candidate.prospectNum,
+			ProspectName: candidate.prospectName,
+			Status:       models.RecipientStatusPending,
+		}
+		if candidate.stage != "" {
+			stage := candidate.stage
+			recipient.Stage = &stage
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if req.DryRun {
+		return &models.RecycleProspectsResponse{
+			Success:    true,
+			Message:    fmt.Sprintf("%d prospects selected", len(recipients)),
+			Selected:   len(recipients),
+			Excluded:   excluded,
+			Recipients: recipients,
+		}, nil
+	}
+
+	if len(recipients) == 0 {
+		return &models.RecycleProspectsResponse{
+			Success:  false,
+			Message:  "No prospects matched the filters",
+			Excluded: excluded,
+		}, nil
+	}
+
+	name := strings.TrimSpace(req.CampaignName)
+	if name == "" {
+		name = fmt.Sprintf("Recycled prospects %s", time.Now().Format("2006-01-02"))
+	}
+
+	campaign := &models.Campaign{
+		UserID:          userID,
+		IDDevice:        idDevice,
+		Name:            name,
+		Source:          models.CampaignSourceRecycle,
+		Message:         req.Message,
+		FlowID:          req.TargetFlowID,
+		Status:          models.CampaignStatusDraft,
+		TotalRecipients: len(recipients),
+	}
+	if err := s.campaignRepo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	for i := range recipients {
+		recipients[i].CampaignID = campaign.ID
+	}
+	if err := s.campaignRepo.AddRecipients(ctx, recipients); err != nil {
+		return nil, err
+	}
+
+	return &models.RecycleProspectsResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Campaign created with %d recipients", len(recipients)),
+		Campaign:   campaign,
+		Selected:   len(recipients),
+		Excluded:   excluded,
+		Recipients: recipients,
+	}, nil
+}
+
+// ownedDeviceID returns the id_device of one of the user's devices, or a message when the device
+// is missing or belongs to someone else
+func (s *CampaignService) ownedDeviceID(ctx context.Context, userID, deviceID string) (string, string) {
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
+	if err != nil || device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, deviceID)
+		if err != nil || device == nil {
+			return "", "Device not found"
+		}
+	}
+	if device.UserID == nil || *device.UserID != userID {
+		return "", "Access denied"
+	}
+
+	idDevice := getStringValue(device.IDDevice)
+	if idDevice == "" {
+		idDevice = getStringValue(device.DeviceID)
+	}
+	return idDevice, ""
+}
+
+// blockedNumbers maps the phone keys of the user's blacklisted and opted-out numbers to the reason
+func (s *CampaignService) blockedNumbers(ctx context.Context, userID string) (map[string]string, error) {
+	entries, err := s.blacklistRepo.GetEntriesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		blocked[phoneKey(entry.PhoneNumber)] = entry.Reason
+	}
+	return blocked, nil
+}
+
+// abandonedCandidates merges abandoned conversations from ai_whatsapp and wasapbot
+func (s *CampaignService) abandonedCandidates(ctx context.Context, idDevice string, start, end time.Time, stalledBefore *time.Time, flowID *string) ([]recycleCandidate, error) {
+	candidates := make([]recycleCandidate, 0)
+
+	aiConvs, err := s.convRepo.GetAbandonedConversations(ctx, idDevice, start, end, stalledBefore, flowID)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range aiConvs {
+		candidates = append(candidates, recycleCandidate{
+			prospectNum:  conv.ProspectNum,
+			prospectName: conv.ProspectName,
+			stage:        strings.TrimSpace(getStringValue(conv.Stage)),
+		})
+	}
+
+	botConvs, err := s.wasapbotRepo.GetAbandonedConversations(ctx, idDevice, start, end, stalledBefore, flowID)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range botConvs {
+		candidates = append(candidates, recycleCandidate{
+			prospectNum:  conv.ProspectNum,
+			prospectName: conv.ProspectName,
+			stage:        strings.TrimSpace(getStringValue(conv.Stage)),
+		})
+	}
+
+	return candidates, nil
+}
+
+// recentlyContacted returns the numbers with conversation activity or campaign sends on any of the user's devices since a time
+func (s *CampaignService) recentlyContacted(ctx context.Context, userID string, since time.Time) (map[string]bool, error) {
+	deviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := map[string]bool{}
+	sources := []func(context.Context, []string, time.Time) ([]string, error){
+		s.convRepo.GetContactedNumbers,
+		s.wasapbotRepo.GetContactedNumbers,
+		s.campaignRepo.GetRecentlyMessagedNumbers,
+	}
+	for _, source := range sources {
+		numbers, err := source(ctx, deviceIDs, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, number := range numbers {
+			recent[phoneKey(number)] = true
+		}
+	}
+
+	return recent, nil
+}
+
+// flowStageOrder derives the funnel order from the stage nodes of a flow (or all of the device's flows),
+// walking each flow breadth-first from its root nodes
+func (s *CampaignService) flowStageOrder(ctx context.Context, idDevice string, flowID *string) ([]string, error) {
+	var flows []models.ChatbotFlow
+	if flowID != nil && *flowID != "" {
+		flow, err := s.flowRepo.GetFlowByID(ctx, *flowID)
+		if err != nil {
+			return nil, err
+		}
+		if flow != nil {
+			flows = append(flows, *flow)
+		}
+	} else {
+		var err error
+		flows, err = s.flowRepo.GetFlowsByDeviceID(ctx, idDevice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order := make([]string, 0)
+	for _, flow := range flows {
+		var flowData FlowData
+		if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+			continue
+		}
+		order = append(order, stageNodeOrder(flowData)...)
+	}
+
+	return order, nil
+}
+
+// stageNodeOrder lists stage node values in breadth-first order from nodes without incoming edges
+func stageNodeOrder(flowData FlowData) []string {
+	outgoing := map[string][]string{}
+	hasIncoming := map[string]bool{}
+	for _, edge := range flowData.Connections {
+		outgoing[edge.From] = append(outgoing[edge.From], edge.To)
+		hasIncoming[edge.To] = true
+	}
+
+	nodes := map[string]*FlowNode{}
+	queue := make([]string, 0)
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		nodes[node.ID] = node
+		if !hasIncoming[node.ID] {
+			queue = append(queue, node.ID)
+		}
+	}
+
+	order := make([]string, 0)
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		if node, ok := nodes[id]; ok && node.Type == "stage" {
+			if value, _ := node.Config["value"].(string); strings.TrimSpace(value) != "" {
+				order = append(order, strings.TrimSpace(value))
+			}
+		}
+		queue = append(queue, outgoing[id]...)
+	}
+
+	return order
+}
+
+// GetBlacklist lists the user's blacklisted and opted-out numbers
+func (s *CampaignService) GetBlacklist(ctx context.Context, userID string) (*models.BlacklistResponse, error) {
+	entries, err := s.blacklistRepo.GetEntriesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BlacklistResponse{
+		Success: true,
+		Message: "Blacklist retrieved successfully",
+		Entries: entries,
+	}, nil
+}
+
+// AddToBlacklist excludes a number from all of the user's campaigns
+func (s *CampaignService) AddToBlacklist(ctx context.Context, userID string, req *models.AddBlacklistRequest) (*models.BlacklistResponse, error) {
+	phone := strings.TrimSpace(req.PhoneNumber)
+	if phoneKey(phone) == "" {
+		return &models.BlacklistResponse{
+			Success: false,
+			Message: "phone_number is required",
+		}, nil
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = models.BlacklistReasonBlocked
+	}
+	if reason != models.BlacklistReasonBlocked && reason != models.BlacklistReasonOptOut {
+		return &models.BlacklistResponse{
+			Success: false,
+			Message: "reason must be blacklist or opt_out",
+		}, nil
+	}
+
+	entry := &models.BlacklistEntry{
+		UserID:      userID,
+		PhoneNumber: phone,
+		Reason:      reason,
+		Note:        req.Note,
+	}
+	if err := s.blacklistRepo.AddEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return &models.BlacklistResponse{
+		Success: true,
+		Message: "Number added to blacklist",
+		Entry:   entry,
+	}, nil
+}
+
+// RemoveFromBlacklist allows a number to be targeted again
+func (s *CampaignService) RemoveFromBlacklist(ctx context.Context, userID, phoneNumber string) (*models.BlacklistResponse, error) {
+	if err := s.blacklistRepo.DeleteEntry(ctx, userID, strings.TrimSpace(phoneNumber)); err != nil {
+		return nil, err
+	}
+
+	return &models.BlacklistResponse{
+		Success: true,
+		Message: "Number removed from blacklist",
+	}, nil
+}
+
+// devicePhoneCountry returns the country a device reads national numbers in, the default when
+// the device can't be loaded
+func (s *CampaignService) devicePhoneCountry(ctx context.Context, idDevice string) string {
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err != nil || device == nil {
+		return models.DefaultPhoneCountry
+	}
+	return device.EffectivePhoneCountry()
+}
+
+// phoneKey reduces a phone number to its E.164 digits so "+60 12-345 6789", "012-345 6789" and
+// "60123456789" compare equal. National numbers are read in DefaultPhoneCountry; anything that
+// isn't a valid number is reduced to its digits.
+func phoneKey(phone string) string {
+	if normalized, err := models.NormalizePhone(phone, models.DefaultPhoneCountry); err == nil {
+		return normalized
+	}
+
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+const (
+	defaultConsentQuestion = "May we send you promotions and updates on WhatsApp? Reply YES to agree or NO to decline."
+	defaultConsentGranted  = "Thank you! You can reply STOP at any time to stop receiving updates."
+	defaultConsentDeclined = "Noted, we will not send you promotional messages."
+)
+
+// defaultConsentKeywords are the replies that count as explicit consent (English and Malay)
+var defaultConsentKeywords = []string{"yes", "y", "ya", "yup", "agree", "setuju", "ok", "okay", "boleh", "sure"}
+
+// ConsentService records marketing consent captured by consent nodes and answers consent lookups
+type ConsentService struct {
+	consentRepo *repository.ConsentRepository
+	deviceRepo  *repository.DeviceRepository
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(consentRepo *repository.ConsentRepository, deviceRepo *repository.DeviceRepository) *ConsentService {
+	return &ConsentService{
+		consentRepo: consentRepo,
+		deviceRepo:  deviceRepo,
+	}
+}
+
+// Record stores a consent decision for a prospect under the device owner's registry
+func (s *ConsentService) Record(ctx context.Context, record *models.ConsentRecord) error {
+	if s == nil || s.consentRepo == nil {
+		return fmt.Errorf("consent registry is not configured")
+	}
+
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, record.IDDevice)
+	if err != nil || device == nil || device.UserID == nil {
+		return fmt.Errorf("failed to resolve device owner for %s: %v", record.IDDevice, err)
+	}
+	record.UserID = *device.UserID
+
+	return s.consentRepo.RecordConsent(ctx, record)
+}
+
+// ConsentedNumbers returns the phone keys whose latest consent record is granted
+func (s *ConsentService) ConsentedNumbers(ctx context.Context, userID string) (map[string]bool, error) {
+	records, err := s.consentRepo.GetRecordsByUser(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are oldest first, so later decisions overwrite earlier ones
+	consented := make(map[string]bool)
+	for _, record := range records {
+		consented[phoneKey(record.ProspectNum)] = record.Granted
+	}
+	for key, granted := range consented {
+		if !granted {
+			delete(consented, key)
+		}
+	}
+	return consented, nil
+}
+
+// ExportConsent returns the full consent registry for a compliance audit, optionally for one device
+func (s *ConsentService) ExportConsent(ctx context.Context, userID, deviceID string) (*models.ConsentExportResponse, error) {
+	var idDevices []string
+	if deviceID != "" {
+		device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
+		if err != nil || device == nil {
+			device, err = s.deviceRepo.GetDeviceByID(ctx, deviceID)
+		}
+		if err != nil || device == nil || device.UserID == nil || *device.UserID != userID {
+			return &models.ConsentExportResponse{
+				Success: false,
+				Message: "Access denied: device not found or unauthorized",
+			}, nil
+		}
+		idDevices = []string{getStringValue(device.IDDevice)}
+	}
+
+	records, err := s.consentRepo.GetRecordsByUser(ctx, userID, idDevices)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []models.ConsentRecord{}
+	}
+
+	return &models.ConsentExportResponse{
+		Success: true,
+		Records: records,
+	}, nil
+}
+
+// recordNodeConsent stores the reply to a consent node; any reply that is not a clear yes is recorded as declined
+func (s *ConsentService) recordNodeConsent(ctx context.Context, flow *models.ChatbotFlow, node *FlowNode, prospectNum, reply string) bool {
+	granted := parseConsentReply(node, reply)
+	if s == nil {
+		// Dry runs (flow simulator) record nothing
+		return granted
+	}
+	nodeID := node.ID
+	record := &models.ConsentRecord{
+		IDDevice:    flow.IDDevice,
+		ProspectNum: prospectNum,
+		Granted:     granted,
+		Wording:     consentQuestion(node),
+		Response:    reply,
+		Source:      models.ConsentSourceFlowNode,
+		FlowID:      &flow.ID,
+		NodeID:      &nodeID,
+	}
+	if err := s.Record(ctx, record); err != nil {
+		log.Printf("❌ Failed to record consent for %s: %v", prospectNum, err)
+	} else {
+		log.Printf("✅ Recorded consent granted=%v for %s", granted, prospectNum)
+	}
+	return granted
+}
+
+// consentQuestion returns the consent wording configured on a consent node
+func consentQuestion(node *FlowNode) string {
+	if text, ok := node.Config["text"].(string); ok && strings.TrimSpace(text) != "" {
+		return text
+	}
+	return defaultConsentQuestion
+}
+
+// consentReplyMessage returns the confirmation for a decision (empty config disables it)
+func consentReplyMessage(node *FlowNode, granted bool) string {
+	key, fallback := "declined_text", defaultConsentDeclined
+	if granted {
+		key, fallback = "granted_text", defaultConsentGranted
+	}
+	if text, ok := node.Config[key].(string); ok {
+		return text
+	}
+	return fallback
+}
+
+// parseConsentReply reports whether a reply is explicit consent: the reply, or its first word,
+// must be one of the node's yes_keywords (default English/Malay yes words)
+func parseConsentReply(node *FlowNode, reply string) bool {
+	keywords := defaultConsentKeywords
+	if list, ok := node.Config["yes_keywords"].([]interface{}); ok && len(list) > 0 {
+		keywords = nil
+		for _, item := range list {
+			if word, ok := item.(string); ok && strings.TrimSpace(word) != "" {
+				keywords = append(keywords, word)
+			}
+		}
+	}
+
+	normalized := strings.ToLower(strings.Trim(strings.TrimSpace(reply), ".!? "))
+	if normalized == "" {
+		return false
+	}
+	first := strings.Trim(strings.Fields(normalized)[0], ",.!?")
+
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if normalized == keyword || first == keyword {
+			return true
+		}
+	}
+	return false
+}
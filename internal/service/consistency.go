@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+const (
+	// consistencyPageSize is how many conversations the checker reads per query
+	consistencyPageSize = 1000
+	// consistencyMaxConversations caps the flow-bound conversations read per table in one check
+	consistencyMaxConversations = 100000
+)
+
+// consistencyTables are the conversation tables the checker scans
+var consistencyTables = []string{models.AssignmentSourceAI, models.AssignmentSourceWasapbot}
+
+// ConsistencyChecker scans for known bad states left behind by deleted devices and flows or
+// interrupted runs, and reports them with the repair each can take. It runs at startup and on
+// demand; repairs only run when asked for.
+type ConsistencyChecker struct {
+	processor *FlowProcessorService
+}
+
+// NewConsistencyChecker creates a new consistency checker
+func NewConsistencyChecker(processor *FlowProcessorService) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		processor: processor,
+	}
+}
+
+// RunAtStartup checks once in the background and logs the report; with repair the repairable
+// issues are fixed too
+func (c *ConsistencyChecker) RunAtStartup(ctx context.Context, repair bool) {
+	go func() {
+		report, err := c.Check(ctx)
+		if err != nil {
+			log.Printf("⚠️  Consistency check failed: %v", err)
+			return
+		}
+		if repair {
+			c.Repair(ctx, report, nil)
+		}
+		logConsistencyReport(report)
+	}()
+}
+
+// logConsistencyReport logs a report's counts by kind
+func logConsistencyReport(report *models.ConsistencyReport) {
+	if len(report.Issues) == 0 {
+		log.Printf("✅ Consistency check found no issues")
+		return
+	}
+	for kind, count := range report.Counts {
+		log.Printf("🩺 Consistency check: %d %s", count, kind)
+	}
+	if report.RepairRun {
+		log.Printf("🩺 Consistency check repaired %d of %d repairable issues", report.Repaired, report.Repairable)
+	} else {
+		log.Printf("🩺 Consistency check found %d repairable issues (POST /api/maintenance/consistency/repair fixes them)", report.Repairable)
+	}
+}
+
+// Check scans for every known bad state without changing anything
+func (c *ConsistencyChecker) Check(ctx context.Context) (*models.ConsistencyReport, error) {
+	report := &models.ConsistencyReport{
+		Issues:    []models.ConsistencyIssue{},
+		Counts:    make(map[string]int),
+		StartedAt: time.Now(),
+	}
+	add := func(issue models.ConsistencyIssue) {
+		report.Issues = append(report.Issues, issue)
+		report.Counts[issue.Kind]++
+		if issue.Repair != "" {
+			report.Repairable++
+		}
+	}
+
+	devices, err := c.processor.deviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load devices: %w", err)
+	}
+	// Flows and stage configs name their device by id_device, or device_id on older rows
+	knownDevices := make(map[string]bool)
+	for _, device := range devices {
+		for _, id := range []string{device.ID, getStringValue(device.IDDevice), getStringValue(device.DeviceID)} {
+			if id != "" {
+				knownDevices[id] = true
+			}
+		}
+	}
+
+	flows, err := c.processor.flowRepo.GetAllFlows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flows: %w", err)
+	}
+	flowIDs := make(map[string]bool, len(flows))
+	deviceFlows := make(map[string][]models.ChatbotFlow)
+	for _, flow := range flows {
+		flowIDs[flow.ID] = true
+		deviceFlows[flow.IDDevice] = append(deviceFlows[flow.IDDevice], flow)
+
+		if !knownDevices[flow.IDDevice] {
+			add(models.ConsistencyIssue{
+				Kind:     models.ConsistencyMissingDevice,
+				Table:    "chatbot_flows",
+				ID:       flow.ID,
+				IDDevice: flow.IDDevice,
+				Detail:   fmt.Sprintf("Flow %q belongs to device %s, which no longer exists; delete the flow or move it to a device", flow.Name, flow.IDDevice),
+			})
+		}
+	}
+
+	for _, table := range consistencyTables {
+		for offset := 0; offset < consistencyMaxConversations; offset += consistencyPageSize {
+			page, err := c.processor.convRepo.GetFlowBoundConversations(ctx, table, offset, consistencyPageSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, conversation := range page {
+				flowID := getStringValue(conversation.FlowID)
+				if flowID == "" || flowIDs[flowID] {
+					continue
+				}
+
+				issue := models.ConsistencyIssue{
+					Kind:     models.ConsistencyDeletedFlow,
+					Table:    table,
+					ID:       strconv.Itoa(conversation.IDProspect),
+					IDDevice: conversation.IDDevice,
+					Repair:   models.ConsistencyRepairUnbind,
+				}
+				if catchAll := deviceCatchAllFlow(deviceFlows[conversation.IDDevice]); catchAll != nil {
+					issue.Repair = models.ConsistencyRepairRebind
+					issue.Detail = fmt.Sprintf("Bound to deleted flow %s; the device's flow %q can take it over", flowID, catchAll.Name)
+				} else {
+					issue.Detail = fmt.Sprintf("Bound to deleted flow %s and the device has no active flow left", flowID)
+				}
+				add(issue)
+			}
+			if len(page) < consistencyPageSize {
+				break
+			}
+		}
+
+		waiting, err := c.processor.convRepo.GetWaitingConversationsWithoutNode(ctx, table, consistencyMaxConversations)
+		if err != nil {
+			return nil, err
+		}
+		for _, conversation := range waiting {
+			add(models.ConsistencyIssue{
+				Kind:     models.ConsistencyWaitingNoNode,
+				Table:    table,
+				ID:       strconv.Itoa(conversation.IDProspect),
+				IDDevice: conversation.IDDevice,
+				Detail:   "Waiting for a reply without a node to resume at, so a reply cannot continue the flow",
+				Repair:   models.ConsistencyRepairReactivate,
+			})
+		}
+	}
+
+	stages, err := c.processor.stageRepo.GetAllStageValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range stages {
+		if knownDevices[stage.IDDevice] {
+			continue
+		}
+		add(models.ConsistencyIssue{
+			Kind:     models.ConsistencyOrphanedStage,
+			Table:    "stagesetvalue",
+			ID:       strconv.Itoa(stage.ID),
+			IDDevice: stage.IDDevice,
+			Detail:   fmt.Sprintf("Stage %q is configured for device %s, which no longer exists", stage.Stage, stage.IDDevice),
+			Repair:   models.ConsistencyRepairDelete,
+		})
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// validConsistencyKind reports whether kind is a known issue kind
+func validConsistencyKind(kind string) bool {
+	switch kind {
+	case models.ConsistencyDeletedFlow, models.ConsistencyMissingDevice, models.ConsistencyWaitingNoNode, models.ConsistencyOrphanedStage:
+		return true
+	}
+	return false
+}
+
+// CheckAndRepair runs a fresh check and repairs its issues of the given kinds (empty = all)
+func (c *ConsistencyChecker) CheckAndRepair(ctx context.Context, kinds []string) (*models.ConsistencyResponse, error) {
+	for _, kind := range kinds {
+		if !validConsistencyKind(kind) {
+			return &models.ConsistencyResponse{
+				Success: false,
+				Message: fmt.Sprintf("Unknown issue kind %q", kind),
+			}, nil
+		}
+	}
+
+	report, err := c.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.Repair(ctx, report, kinds)
+	logConsistencyReport(report)
+
+	return &models.ConsistencyResponse{
+		Success: true,
+		Message: fmt.Sprintf("Repaired %d of %d repairable issues", report.Repaired, report.Repairable),
+		Report:  report,
+	}, nil
+}
+
+// deviceCatchAllFlow returns the flow that receives a device's untriggered messages, or nil when
+// the device has no active flows
+func deviceCatchAllFlow(flows []models.ChatbotFlow) *models.ChatbotFlow {
+	active := activeFlowsByPriority(flows)
+	if len(active) == 0 {
+		return nil
+	}
+	return catchAllFlow(active)
+}
+
+// Repair applies the repair of every repairable issue in the report, or only of the given kinds,
+// and records the outcome on each issue
+func (c *ConsistencyChecker) Repair(ctx context.Context, report *models.ConsistencyReport, kinds []string) {
+	// Rebinds look the device's flows up again, since the report may be old
+	deviceFlows := make(map[string][]models.ChatbotFlow)
+
+	report.RepairRun = true
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		if issue.Repair == "" || issue.Repaired || (len(kinds) > 0 && !slices.Contains(kinds, issue.Kind)) {
+			continue
+		}
+
+		var err error
+		switch issue.Repair {
+		case models.ConsistencyRepairRebind, models.ConsistencyRepairUnbind:
+			flows, ok := deviceFlows[issue.IDDevice]
+			if !ok {
+				flows, err = c.processor.flowRepo.GetFlowsByDeviceID(ctx, issue.IDDevice)
+				deviceFlows[issue.IDDevice] = flows
+			}
+			if err == nil {
+				err = c.rebindConversation(ctx, issue.Table, issue.ID, deviceCatchAllFlow(flows))
+			}
+		case models.ConsistencyRepairReactivate:
+			err = c.processor.stateMachine(issue.Table).UpdateState(ctx, issue.ID, models.ConversationStateActive, "", nil)
+		case models.ConsistencyRepairDelete:
+			var stageID int
+			if stageID, err = strconv.Atoi(issue.ID); err == nil {
+				err = c.processor.stageRepo.DeleteStageValue(ctx, stageID)
+			}
+		}
+
+		if err != nil {
+			issue.Error = err.Error()
+			log.Printf("⚠️  Consistency repair %s of %s %s failed: %v", issue.Repair, issue.Table, issue.ID, err)
+			continue
+		}
+		issue.Repaired = true
+		report.Repaired++
+	}
+	report.FinishedAt = time.Now()
+}
+
+// rebindConversation binds a conversation of a deleted flow to flow, or to no flow when flow is
+// nil. A conversation paused inside the deleted flow is reactivated, as its node is gone.
+func (c *ConsistencyChecker) rebindConversation(ctx context.Context, table, conversationID string, flow *models.ChatbotFlow) error {
+	updates := map[string]interface{}{"flow_id": nil}
+	if flow != nil {
+		updates["flow_id"] = flow.ID
+	}
+
+	stateMachine := c.processor.stateMachine(table)
+	state, err := stateMachine.GetState(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if state != models.ConversationStateWaiting && state != models.ConversationStateScheduled {
+		if table == models.AssignmentSourceWasapbot {
+			return c.processor.convRepo.UpdateWasapBotContact(ctx, conversationID, updates)
+		}
+		return c.processor.convRepo.UpdateConversation(ctx, conversationID, updates)
+	}
+
+	if err := stateMachine.UpdateState(ctx, conversationID, models.ConversationStateActive, "", updates); err != nil {
+		return err
+	}
+	// Pending resumes point at nodes of the deleted flow
+	if c.processor.delayRepo != nil {
+		return c.processor.delayRepo.SkipPending(ctx, table, conversationID)
+	}
+	return nil
+}
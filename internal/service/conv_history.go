@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// convHistoryPageSize is how many wasapbot rows the compaction job loads per query
+const convHistoryPageSize = 200
+
+// trimConvHistory keeps only the last maxEntries "User:"/"Bot:" entries of a conv_last string.
+// A message that spans several lines stays attached to the entry it started in.
+func trimConvHistory(convLast string, maxEntries int) string {
+	if maxEntries <= 0 || convLast == "" {
+		return convLast
+	}
+
+	lines := strings.Split(convLast, "\n")
+
+	// Walk backwards counting entry starts until we have enough
+	entries := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "User:") || strings.HasPrefix(lines[i], "Bot:") {
+			entries++
+			if entries == maxEntries {
+				return strings.Join(lines[i:], "\n")
+			}
+		}
+	}
+
+	return convLast
+}
+
+// appendConvHistory appends one "Role: message" entry and trims the result
+func appendConvHistory(convLast, role, message string, maxEntries int) string {
+	newLine := fmt.Sprintf("%s: %s", role, message)
+	if convLast != "" {
+		convLast += "\n" + newLine
+	} else {
+		convLast = newLine
+	}
+	return trimConvHistory(convLast, maxEntries)
+}
+
+// MessageRecorder writes conversation history as conversation_messages rows. conv_last is
+// rendered from the same entries for the code that still reads the text history.
+type MessageRecorder struct {
+	messages *repository.MessageRepository
+	live     *LiveEventHub
+}
+
+// NewMessageRecorder creates a new conversation message recorder
+func NewMessageRecorder(messages *repository.MessageRepository, live *LiveEventHub) *MessageRecorder {
+	return &MessageRecorder{
+		messages: messages,
+		live:     live,
+	}
+}
+
+// newConversationMessage builds a message from a conv_last role (User, Bot)
+func newConversationMessage(source, conversationID, idDevice, convRole, content string) *models.ConversationMessage {
+	return &models.ConversationMessage{
+		Source:         source,
+		ConversationID: conversationID,
+		IDDevice:       idDevice,
+		Role:           models.MessageRoleFromConv(convRole),
+		Content:        content,
+	}
+}
+
+// Record stores one message. A nil recorder records nothing; failures are only logged because
+// conv_last still carries the entry.
+func (m *MessageRecorder) Record(ctx context.Context, message *models.ConversationMessage) {
+	if m == nil || m.messages == nil || message.ConversationID == "" {
+		return
+	}
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+
+	if err := m.messages.CreateMessage(ctx, message); err != nil {
+		log.Printf("⚠️  Failed to record %s message for conversation %s: %v", message.Role, message.ConversationID, err)
+	}
+	m.publish(ctx, message)
+}
+
+// publish sends a stored message to the live event streams as message.received or message.sent
+func (m *MessageRecorder) publish(ctx context.Context, message *models.ConversationMessage) {
+	event := models.EventMessageSent
+	if message.Role == models.MessageRoleUser {
+		event = models.EventMessageReceived
+	}
+
+	data := map[string]interface{}{
+		"source":          message.Source,
+		"conversation_id": message.ConversationID,
+		"role":            message.Role,
+		"content":         message.Content,
+		"created_at":      message.CreatedAt,
+	}
+	if message.NodeID != nil {
+		data["node_id"] = *message.NodeID
+	}
+	if message.Media != nil {
+		data["media"] = message.Media
+	}
+	m.live.PublishForDevice(ctx, message.IDDevice, event, data)
+}
+
+// Append records a message and returns convLast with its entry appended, trimmed to maxEntries
+func (m *MessageRecorder) Append(ctx context.Context, convLast string, message *models.ConversationMessage, maxEntries int) string {
+	m.Record(ctx, message)
+	return appendConvHistory(convLast, message.ConvRole(), message.Content, maxEntries)
+}
+
+// History returns the latest limit messages of a conversation, oldest first
+func (m *MessageRecorder) History(ctx context.Context, source, conversationID string, limit int) ([]models.ConversationMessage, error) {
+	if m == nil || m.messages == nil {
+		return nil, nil
+	}
+	return m.messages.GetMessages(ctx, source, conversationID, limit)
+}
+
+// Forget deletes a conversation's messages along with the conversation
+func (m *MessageRecorder) Forget(ctx context.Context, source, conversationID string) {
+	if m == nil || m.messages == nil {
+		return
+	}
+	if err := m.messages.DeleteMessages(ctx, source, conversationID); err != nil {
+		log.Printf("⚠️  Failed to delete messages of conversation %s: %v", conversationID, err)
+	}
+}
+
+// Move hands a conversation's messages to the conversation it was merged into
+func (m *MessageRecorder) Move(ctx context.Context, source, fromID, toID string) error {
+	if m == nil || m.messages == nil {
+		return nil
+	}
+	return m.messages.MoveMessages(ctx, source, fromID, toID)
+}
+
+// ConvHistoryCompactor trims conv_last on historical wasapbot rows down to each device's limit
+type ConvHistoryCompactor struct {
+	deviceRepo   *repository.DeviceRepository
+	wasapbotRepo *repository.WasapbotRepository
+}
+
+// NewConvHistoryCompactor creates a new conversation history compaction job
+func NewConvHistoryCompactor(deviceRepo *repository.DeviceRepository, wasapbotRepo *repository.WasapbotRepository) *ConvHistoryCompactor {
+	return &ConvHistoryCompactor{
+		deviceRepo:   deviceRepo,
+		wasapbotRepo: wasapbotRepo,
+	}
+}
+
+// Start runs the compaction immediately and then every interval until ctx is cancelled
+func (c *ConvHistoryCompactor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if trimmed, err := c.CompactAll(ctx); err != nil {
+				log.Printf("⚠️  Conversation history compaction failed: %v", err)
+			} else if trimmed > 0 {
+				log.Printf("🧹 Compacted conv_last on %d wasapbot conversation(s)", trimmed)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CompactAll trims conv_last for every device and returns how many rows were rewritten
+func (c *ConvHistoryCompactor) CompactAll(ctx context.Context) (int, error) {
+	devices, err := c.deviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load devices: %w", err)
+	}
+
+	total := 0
+	for i := range devices {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		trimmed, err := c.CompactDevice(ctx, &devices[i])
+		if err != nil {
+			log.Printf("⚠️  Failed to compact history for device %s: %v", getStringValue(devices[i].IDDevice), err)
+			continue
+		}
+		total += trimmed
+	}
+
+	return total, nil
+}
+
+// CompactDevice trims conv_last for all wasapbot conversations of one device
+func (c *ConvHistoryCompactor) CompactDevice(ctx context.Context, device *models.DeviceSetting) (int, error) {
+	idDevice := getStringValue(device.IDDevice)
+	if idDevice == "" {
+		return 0, nil
+	}
+
+	maxEntries := device.EffectiveMaxHistoryEntries()
+	trimmed := 0
+
+	for offset := 0; ; offset += convHistoryPageSize {
+		rows, err := c.wasapbotRepo.GetConvHistoryPage(ctx, idDevice, convHistoryPageSize, offset)
+		if err != nil {
+			return trimmed, err
+		}
+
+		for _, row := range rows {
+			if row.ConvLast == nil || row.IDProspect == nil {
+				continue
+			}
+
+			compacted := trimConvHistory(*row.ConvLast, maxEntries)
+			if compacted == *row.ConvLast {
+				continue
+			}
+
+			if err := c.wasapbotRepo.ReplaceConvLast(ctx, fmt.Sprintf("%d", *row.IDProspect), compacted); err != nil {
+				log.Printf("⚠️  %v", err)
+				continue
+			}
+			trimmed++
+		}
+
+		if len(rows) < convHistoryPageSize {
+			return trimmed, nil
+		}
+	}
+}
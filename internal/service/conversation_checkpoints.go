@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"chatbot-automation/internal/models"
+)
+
+// checkpointTableAI is the conversation_table of ai_whatsapp checkpoints
+const checkpointTableAI = "ai_whatsapp"
+
+// checkpointColumns is the engine state a checkpoint keeps and a restore writes back.
+// History (conv_last) is not part of it: a restore rewinds where the flow is, not what was said.
+var checkpointColumns = []string{
+	"flow_id",
+	"current_node_id",
+	"last_node_id",
+	"execution_status",
+	"waiting_for_reply",
+	"stage",
+	"session_data",
+	"flow_progress",
+	"flow_milestone",
+	"csat_attempts",
+}
+
+// conversationSnapshot reads the checkpoint columns of a conversation; unset columns are kept as nil
+func conversationSnapshot(conversation *models.AIWhatsapp) map[string]interface{} {
+	row := make(map[string]interface{})
+	if data, err := json.Marshal(conversation); err == nil {
+		_ = json.Unmarshal(data, &row)
+	}
+
+	state := make(map[string]interface{}, len(checkpointColumns))
+	for _, column := range checkpointColumns {
+		state[column] = row[column]
+	}
+	return state
+}
+
+// newCheckpoint builds a checkpoint of a conversation's current engine state
+func newCheckpoint(conversationID string, conversation *models.AIWhatsapp, reason string) *models.ConversationCheckpoint {
+	return &models.ConversationCheckpoint{
+		ConversationTable: checkpointTableAI,
+		ConversationID:    conversationID,
+		IDDevice:          conversation.IDDevice,
+		ProspectNum:       conversation.ProspectNum,
+		FlowID:            conversation.FlowID,
+		NodeID:            conversation.CurrentNodeID,
+		Reason:            reason,
+		State:             conversationSnapshot(conversation),
+	}
+}
+
+// recordCheckpoint stores where a conversation rests after handling an inbound message
+func (s *FlowProcessorService) recordCheckpoint(ctx context.Context, conversationID string) {
+	if s.checkpointRepo == nil {
+		return
+	}
+
+	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Checkpoint skipped, failed to load conversation %s: %v", conversationID, err)
+		return
+	}
+	if err := s.checkpointRepo.RecordCheckpoint(ctx, newCheckpoint(conversationID, conversation, models.CheckpointReasonRun)); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// conversationSteps numbers checkpoints in history order
+func conversationSteps(checkpoints []models.ConversationCheckpoint) []models.ConversationStep {
+	steps := make([]models.ConversationStep, 0, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		steps = append(steps, models.ConversationStep{
+			Step:       i + 1,
+			NodeID:     getStringValue(checkpoint.NodeID),
+			FlowID:     getStringValue(checkpoint.FlowID),
+			Reason:     checkpoint.Reason,
+			State:      checkpoint.State,
+			RecordedAt: checkpoint.CreatedAt,
+		})
+	}
+	return steps
+}
+
+// ownedConversation loads a conversation on a device the user owns or has a team role on that
+// grants permission; nil when not found or not accessible
+func (s *ConversationService) ownedConversation(ctx context.Context, userID, prospectID, permission string) (*models.AIWhatsapp, error) {
+	conversation, err := s.conversationRepo.GetConversationByID(ctx, prospectID)
+	if err != nil || conversation == nil {
+		return nil, nil
+	}
+
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, conversation.IDDevice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup device: %w", err)
+	}
+	if device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
+		if err != nil {
+			device = nil
+		}
+	}
+	if !s.team.Allows(ctx, userID, device, permission) {
+		return nil, nil
+	}
+	return conversation, nil
+}
+
+// GetConversationSteps lists the checkpoints a conversation can be restored to
+func (s *ConversationService) GetConversationSteps(ctx context.Context, userID, prospectID string) (*models.ConversationStepsResponse, error) {
+	if s.checkpointRepo == nil {
+		return &models.ConversationStepsResponse{
+			Success: false,
+			Message: "Conversation checkpoints are not enabled",
+		}, nil
+	}
+
+	conversation, err := s.ownedConversation(ctx, userID, prospectID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.ConversationStepsResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	checkpoints, err := s.checkpointRepo.ListCheckpoints(ctx, checkpointTableAI, prospectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConversationStepsResponse{
+		Success: true,
+		Steps:   conversationSteps(checkpoints),
+	}, nil
+}
+
+// RestoreConversation rewinds a conversation's engine state (flow, current node, execution state,
+// stage, flow variables) to an earlier step. History is kept, and the restore is itself recorded
+// as a new step.
+func (s *ConversationService) RestoreConversation(ctx context.Context, userID, prospectID string, toStep int) (*models.RestoreConversationResponse, error) {
+	if s.checkpointRepo == nil {
+		return &models.RestoreConversationResponse{
+			Success: false,
+			Message: "Conversation checkpoints are not enabled",
+		}, nil
+	}
+
+	conversation, err := s.ownedConversation(ctx, userID, prospectID, models.TeamPermissionReplyConversations)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.RestoreConversationResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	checkpoints, err := s.checkpointRepo.ListCheckpoints(ctx, checkpointTableAI, prospectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return &models.RestoreConversationResponse{
+			Success: false,
+			Message: "Conversation has no recorded steps",
+		}, nil
+	}
+	if toStep < 1 || toStep > len(checkpoints) {
+		return &models.RestoreConversationResponse{
+			Success: false,
+			Message: fmt.Sprintf("to_step must be between 1 and %d", len(checkpoints)),
+		}, nil
+	}
+	step := conversationSteps(checkpoints)[toStep-1]
+
+	// Every checkpoint column is written back; columns unset at the step are cleared
+	target := models.ConversationStateActive
+	extra := make(map[string]interface{}, len(checkpointColumns))
+	for _, column := range checkpointColumns {
+		switch column {
+		case "execution_status", "waiting_for_reply":
+			continue
+		}
+		extra[column] = step.State[column]
+	}
+	var stepState models.ExecutionState
+	if data, err := json.Marshal(step.State); err == nil && json.Unmarshal(data, &stepState) == nil {
+		target = stepState.State()
+	}
+
+	// Terminal and handoff states go through active first, so the transition is allowed and the
+	// re-engagement reset of flow_progress does not overwrite the restored values
+	current := conversation.ExecutionState().State()
+	if current.IsTerminal() || !current.CanTransitionTo(target) {
+		if err := s.stateMachine.UpdateState(ctx, prospectID, models.ConversationStateActive, "", nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.stateMachine.UpdateState(ctx, prospectID, target, "", extra); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.conversationRepo.GetConversationByID(ctx, prospectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload conversation: %w", err)
+	}
+	if err := s.checkpointRepo.RecordCheckpoint(ctx, newCheckpoint(prospectID, restored, models.CheckpointReasonRestore)); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	log.Printf("⏪ Conversation %s restored to step %d (node %s)", prospectID, toStep, step.NodeID)
+
+	resp := &models.RestoreConversationResponse{
+		Success:      true,
+		Message:      fmt.Sprintf("Conversation restored to step %d", toStep),
+		Step:         &step,
+		Conversation: restored,
+	}
+
+	// The message log shows what the prospect already received after the step
+	if s.sentRepo != nil && step.RecordedAt != nil {
+		messages, err := s.sentRepo.ListSince(ctx, restored.IDDevice, restored.ProspectNum, *step.RecordedAt)
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+		} else {
+			resp.MessagesSince = messages
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// cleanupTables are the conversation tables a cleanup purges
+var cleanupTables = []string{"ai_whatsapp", "wasapbot"}
+
+// parseCleanupBefore reads the before filter: an RFC 3339 time, or a date meaning midnight UTC
+func parseCleanupBefore(value string) (*time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, true
+	}
+	return nil, false
+}
+
+// CleanupConversations deletes a device's test conversations (ai_whatsapp and wasapbot) with their
+// checkpoints. With dryRun the matching conversations are only listed.
+func (s *ConversationService) CleanupConversations(ctx context.Context, userID, deviceID, before, status string, dryRun bool) (*models.CleanupConversationsResponse, error) {
+	if deviceID == "" {
+		return &models.CleanupConversationsResponse{
+			Success: false,
+			Message: "device_id is required",
+		}, nil
+	}
+	if status != models.ConversationCleanupStatusTest {
+		return &models.CleanupConversationsResponse{
+			Success: false,
+			Message: "status must be test",
+		}, nil
+	}
+	beforeTime, ok := parseCleanupBefore(before)
+	if !ok {
+		return &models.CleanupConversationsResponse{
+			Success: false,
+			Message: "before must be a date (YYYY-MM-DD) or an RFC 3339 time",
+		}, nil
+	}
+
+	// Verify device ownership
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup device: %w", err)
+	}
+	if device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, deviceID)
+		if err != nil {
+			device = nil
+		}
+	}
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteConversations) {
+		return &models.CleanupConversationsResponse{
+			Success: false,
+			Message: "Device not found or access denied",
+		}, nil
+	}
+	if device.IDDevice != nil && *device.IDDevice != "" {
+		deviceID = *device.IDDevice
+	}
+
+	var matched []models.CleanupConversation
+	for _, table := range cleanupTables {
+		conversations, err := s.conversationRepo.GetTestConversations(ctx, table, deviceID, beforeTime)
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, conversations...)
+	}
+
+	resp := &models.CleanupConversationsResponse{
+		Success:       true,
+		DryRun:        dryRun,
+		Matched:       len(matched),
+		Conversations: matched,
+	}
+	if dryRun {
+		resp.Message = fmt.Sprintf("%d test conversations would be deleted", len(matched))
+		return resp, nil
+	}
+
+	for _, conversation := range matched {
+		prospectID := strconv.Itoa(conversation.IDProspect)
+		if err := s.conversationRepo.DeleteTestConversation(ctx, conversation.Table, prospectID); err != nil {
+			return nil, err
+		}
+		resp.Deleted++
+
+		if s.checkpointRepo != nil {
+			if err := s.checkpointRepo.DeleteCheckpoints(ctx, conversation.Table, prospectID); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		}
+		s.messages.Forget(ctx, conversation.Table, prospectID)
+	}
+	log.Printf("🧹 Deleted %d test conversations on device %s", resp.Deleted, deviceID)
+
+	resp.Message = fmt.Sprintf("Deleted %d test conversations", resp.Deleted)
+	return resp, nil
+}
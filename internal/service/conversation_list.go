@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// validateListColumns checks a device's list_columns setting.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateListColumns(columns []string) string {
+	if len(columns) > models.MaxListColumns {
+		return fmt.Sprintf("list_columns can have at most %d columns", models.MaxListColumns)
+	}
+	for _, column := range columns {
+		if !models.IsValidListColumnName(column) {
+			return fmt.Sprintf("Invalid list column %q: use lowercase field names such as pakej or tarikh_gaji", column)
+		}
+	}
+	return ""
+}
+
+// withCustomFields marks the list owner's custom fields in a list query, so they are read from custom_fields
+func withCustomFields(ctx context.Context, fieldRepo *repository.CustomFieldRepository, userID string, query *models.ConversationListQuery) *models.ConversationListQuery {
+	fields := userCustomFields(ctx, fieldRepo, userID)
+	if len(fields) == 0 {
+		return query
+	}
+
+	withFields := *query
+	withFields.CustomFields = make(map[string]bool, len(fields))
+	for name := range fields {
+		withFields.CustomFields[name] = true
+	}
+	return &withFields
+}
+
+// effectiveListQuery fills in the device's list_columns when the request names no columns
+func effectiveListQuery(device *models.DeviceSetting, query *models.ConversationListQuery) *models.ConversationListQuery {
+	if query == nil {
+		query = &models.ConversationListQuery{}
+	}
+	if len(query.Columns) == 0 && len(device.ListColumns) > 0 {
+		withColumns := *query
+		withColumns.Columns = device.ListColumns
+		return &withColumns
+	}
+	return query
+}
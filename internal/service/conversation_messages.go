@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+
+	"chatbot-automation/internal/models"
+)
+
+// Conversation message page sizes
+const (
+	defaultMessageHistoryLimit = 200
+	maxMessageHistoryLimit     = 1000
+)
+
+// messageHistoryLimit clamps a requested history size, using the default when unset
+func messageHistoryLimit(limit int) int {
+	if limit <= 0 {
+		return defaultMessageHistoryLimit
+	}
+	return min(limit, maxMessageHistoryLimit)
+}
+
+// GetMessages returns a conversation's latest messages, oldest first, with the same history in
+// the conv_last format
+func (s *ConversationService) GetMessages(ctx context.Context, userID, prospectID string, limit int) (*models.ConversationMessagesResponse, error) {
+	conversation, err := s.ownedConversation(ctx, userID, prospectID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.ConversationMessagesResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	return conversationMessages(ctx, s.messages, models.AssignmentSourceAI, prospectID, limit)
+}
+
+// GetMessages returns a WhatsApp Bot conversation's latest messages, oldest first
+func (s *WasapbotService) GetMessages(ctx context.Context, userID, prospectID string, limit int) (*models.ConversationMessagesResponse, error) {
+	conversation, err := s.wasapbotRepo.GetConversationByID(ctx, prospectID)
+	if err != nil || conversation == nil {
+		return &models.ConversationMessagesResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	// Verify device ownership
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, conversation.IDDevice)
+	if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
+		return &models.ConversationMessagesResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	return conversationMessages(ctx, s.messages, models.AssignmentSourceWasapbot, prospectID, limit)
+}
+
+// conversationMessages loads the history of an owned conversation
+func conversationMessages(ctx context.Context, recorder *MessageRecorder, source, prospectID string, limit int) (*models.ConversationMessagesResponse, error) {
+	if recorder == nil {
+		return &models.ConversationMessagesResponse{
+			Success: false,
+			Message: "Structured conversation history is not enabled",
+		}, nil
+	}
+
+	messages, err := recorder.History(ctx, source, prospectID, messageHistoryLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []models.ConversationMessage{}
+	}
+
+	return &models.ConversationMessagesResponse{
+		Success:  true,
+		Message:  "Conversation history retrieved successfully",
+		Messages: messages,
+		ConvLast: models.ConvLastFromMessages(messages),
+	}, nil
+}
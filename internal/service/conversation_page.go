@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// ListConversations returns one page of the conversations on the user's own and shared devices
+// matching filter, newest first.
+// Pages are addressed by number (with the total counted) or by the cursor of the previous page.
+func (s *ConversationService) ListConversations(ctx context.Context, userID string, filter *models.ConversationFilter) (*models.ConversationResponse, error) {
+	query, msg := conversationPageQuery(filter)
+	if msg != "" {
+		return &models.ConversationResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	deviceIDs, err := s.team.accessibleDeviceIDs(ctx, s.deviceRepo, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user devices: %w", err)
+	}
+	if filter.DeviceID != "" {
+		if !slices.Contains(deviceIDs, filter.DeviceID) {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: "Access denied",
+			}, nil
+		}
+		deviceIDs = []string{filter.DeviceID}
+	}
+	query.DeviceIDs = deviceIDs
+
+	pageSize := query.Limit
+	query.Limit = pageSize + 1 // one extra row tells whether there is a next page
+	conversations, err := s.conversationRepo.GetConversationPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := &models.ConversationPagination{PageSize: pageSize}
+	if len(conversations) > pageSize {
+		conversations = conversations[:pageSize]
+		pagination.HasMore = true
+	}
+	if pagination.HasMore {
+		pagination.NextCursor = encodeConversationCursor(conversations[len(conversations)-1])
+	}
+	if query.After == nil {
+		pagination.Page = query.Offset/pageSize + 1
+		total, err := s.conversationRepo.CountConversations(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	return &models.ConversationResponse{
+		Success:       true,
+		Message:       fmt.Sprintf("Found %d conversations", len(conversations)),
+		Conversations: conversations,
+		Pagination:    pagination,
+	}, nil
+}
+
+// conversationPageQuery validates a filter and resolves it to a repository query.
+// The message is empty when the filter is valid.
+func conversationPageQuery(filter *models.ConversationFilter) (*models.ConversationPageQuery, string) {
+	query := &models.ConversationPageQuery{
+		Stage:  strings.TrimSpace(filter.Stage),
+		Status: strings.TrimSpace(filter.Status),
+		Niche:  strings.TrimSpace(filter.Niche),
+		Limit:  filter.PageSize,
+	}
+
+	if query.Status != "" && !models.ConversationState(query.Status).IsValid() {
+		return nil, fmt.Sprintf("Unknown status %q", query.Status)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = models.DefaultConversationPageSize
+	}
+	if query.Limit > models.MaxConversationPageSize {
+		return nil, fmt.Sprintf("page_size must be at most %d", models.MaxConversationPageSize)
+	}
+
+	if filter.Phone != "" {
+		query.Phone = strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, filter.Phone)
+		if query.Phone == "" {
+			return nil, "phone must contain digits"
+		}
+	}
+
+	var ok bool
+	if query.From, ok = parseCleanupBefore(filter.From); !ok {
+		return nil, "from must be a date (YYYY-MM-DD) or an RFC 3339 time"
+	}
+	if query.To, ok = parseCleanupBefore(filter.To); !ok {
+		return nil, "to must be a date (YYYY-MM-DD) or an RFC 3339 time"
+	}
+	if query.To != nil && !strings.Contains(filter.To, "T") {
+		// A date includes the whole day
+		end := query.To.Add(24 * time.Hour)
+		query.To = &end
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, "from must be before to"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeConversationCursor(filter.Cursor)
+		if err != nil {
+			return nil, "Invalid cursor"
+		}
+		query.After = cursor
+	} else if filter.Page > 1 {
+		query.Offset = (filter.Page - 1) * query.Limit
+	}
+
+	return query, ""
+}
+
+// encodeConversationCursor makes the opaque next_cursor from the last conversation of a page
+func encodeConversationCursor(conversation models.AIWhatsapp) string {
+	var createdAt time.Time
+	if conversation.CreatedAt != nil {
+		createdAt = *conversation.CreatedAt
+	}
+	id := 0
+	if conversation.IDProspect != nil {
+		id = *conversation.IDProspect
+	}
+	raw := fmt.Sprintf("%s|%d", createdAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeConversationCursor(cursor string) (*models.ConversationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	idProspect, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConversationCursor{CreatedAt: t, IDProspect: idProspect}, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"chatbot-automation/internal/models"
+)
+
+var (
+	// ErrConversationNotFound is returned when a conversation to resume does not exist
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrConversationNotPaused is returned when a conversation is not resting at a node
+	ErrConversationNotPaused = errors.New("conversation is not waiting or scheduled")
+)
+
+// pausedConversation is where a conversation rests, whichever table it lives in
+type pausedConversation struct {
+	state       models.ConversationState
+	nodeID      string
+	flowID      string
+	idDevice    string
+	prospectNum string
+	convLast    string
+}
+
+// loadPausedConversation reads the execution state and flow of an ai_whatsapp or wasapbot conversation
+func (s *FlowProcessorService) loadPausedConversation(ctx context.Context, source, conversationID string) (*pausedConversation, error) {
+	var execState *models.ExecutionState
+	var flowID, convLast *string
+	var idDevice, prospectNum string
+
+	switch source {
+	case "ai_whatsapp":
+		conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation: %w", err)
+		}
+		if conversation == nil {
+			return nil, ErrConversationNotFound
+		}
+		execState, flowID = conversation.ExecutionState(), conversation.FlowID
+		idDevice, prospectNum, convLast = conversation.IDDevice, conversation.ProspectNum, conversation.ConvLast
+	case "wasapbot":
+		contact, err := s.wasapbotRepo.GetConversationByID(ctx, conversationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation: %w", err)
+		}
+		if contact == nil {
+			return nil, ErrConversationNotFound
+		}
+		execState, flowID = contact.ExecutionState(), contact.FlowID
+		idDevice, prospectNum, convLast = contact.IDDevice, contact.ProspectNum, contact.ConvLast
+	default:
+		return nil, fmt.Errorf("unknown conversation source %q", source)
+	}
+
+	return &pausedConversation{
+		state:       execState.State(),
+		nodeID:      getStringValue(execState.CurrentNodeID),
+		flowID:      getStringValue(flowID),
+		idDevice:    idDevice,
+		prospectNum: prospectNum,
+		convLast:    getStringValue(convLast),
+	}, nil
+}
+
+// ResumeConversation continues a waiting or scheduled conversation's flow after the node it rests at,
+// as if its wait had ended. message is handed to the resumed nodes like a prospect reply and may be empty.
+// Returns the conversation's state after the run.
+func (s *FlowProcessorService) ResumeConversation(ctx context.Context, source, conversationID, message string) (models.ConversationState, error) {
+	conversation, err := s.loadPausedConversation(ctx, source, conversationID)
+	if err != nil {
+		return "", err
+	}
+	if conversation.state != models.ConversationStateWaiting && conversation.state != models.ConversationStateScheduled {
+		return conversation.state, fmt.Errorf("%w: conversation is %s", ErrConversationNotPaused, conversation.state)
+	}
+	if conversation.flowID == "" {
+		return conversation.state, fmt.Errorf("conversation is not bound to a flow")
+	}
+
+	flow, err := s.flowRepo.GetFlowByID(ctx, conversation.flowID)
+	if err != nil || flow == nil {
+		return conversation.state, fmt.Errorf("failed to load flow %s: %v", conversation.flowID, err)
+	}
+
+	if err := s.resumeAfterNode(ctx, source, conversationID, flow, conversation.nodeID, message); err != nil {
+		return "", err
+	}
+
+	return s.stateMachine(source).GetState(ctx, conversationID)
+}
+
+// stateMachine returns the state machine of a conversation table
+func (s *FlowProcessorService) stateMachine(source string) *ConversationStateMachine {
+	if source == "wasapbot" {
+		return s.wasapbotState
+	}
+	return s.aiState
+}
+
+// resumeAfterNode activates a paused conversation and runs its flow from the node after nodeID.
+// It is how the flow scheduler and internal callers end a wait; inbound replies go through
+// ProcessIncomingMessage instead.
+func (s *FlowProcessorService) resumeAfterNode(ctx context.Context, source, conversationID string, flow *models.ChatbotFlow, nodeID, message string) error {
+	if err := s.stateMachine(source).UpdateState(ctx, conversationID, models.ConversationStateActive, "", nil); err != nil {
+		return err
+	}
+
+	log.Printf("▶️  Resuming conversation %s (%s) after node %s", conversationID, source, nodeID)
+	if source == "wasapbot" {
+		engine := NewWasapbotFlowEngine(s.runtimeServices(), s.wasapbotRepo, s.whatsappService)
+		return engine.ResumeWasapbotFlow(ctx, flow, conversationID, message, nodeID)
+	}
+
+	err := s.ResumeFlow(ctx, flow, conversationID, message, nodeID)
+	// Recorded after failed runs too, so a partial run can be rewound
+	s.recordCheckpoint(ctx, conversationID)
+	return err
+}
@@ -4,7 +4,9 @@ import (
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -12,13 +14,33 @@ import (
 type ConversationService struct {
 	conversationRepo *repository.ConversationRepository
 	deviceRepo       *repository.DeviceRepository
+	stateMachine     *ConversationStateMachine
+	costs            *CostRecorder
+	ai               *AIService
+	checkpointRepo   *repository.ConversationCheckpointRepository
+	sentRepo         *repository.SentMessageRepository
+	fieldRepo        *repository.CustomFieldRepository
+	executionLogs    *repository.FlowExecutionLogRepository
+	messages         *MessageRecorder
+	events           *EventWebhookService
+	team             *TeamService
 }
 
 // NewConversationService creates a new conversation service
-func NewConversationService(conversationRepo *repository.ConversationRepository, deviceRepo *repository.DeviceRepository) *ConversationService {
+func NewConversationService(conversationRepo *repository.ConversationRepository, deviceRepo *repository.DeviceRepository, costRepo *repository.CostLedgerRepository, ai *AIService, checkpointRepo *repository.ConversationCheckpointRepository, sentRepo *repository.SentMessageRepository, fieldRepo *repository.CustomFieldRepository, executionLogs *repository.FlowExecutionLogRepository, messages *MessageRecorder, events *EventWebhookService, team *TeamService) *ConversationService {
 	return &ConversationService{
 		conversationRepo: conversationRepo,
 		deviceRepo:       deviceRepo,
+		stateMachine:     NewConversationStateMachine(conversationRepo),
+		costs:            NewCostRecorder(costRepo),
+		ai:               ai,
+		checkpointRepo:   checkpointRepo,
+		sentRepo:         sentRepo,
+		fieldRepo:        fieldRepo,
+		executionLogs:    executionLogs,
+		messages:         messages,
+		events:           events,
+		team:             team,
 	}
 }
 
@@ -41,13 +63,23 @@ func (s *ConversationService) CreateConversation(ctx context.Context, userID str
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied - device does not belong to you",
 		}, nil
 	}
 
+	// Store the number as inbound messages arrive with it, so the prospect's replies find this conversation
+	prospectNum, err := normalizeProspectNum(device, req.ProspectNum)
+	if err != nil {
+		return &models.ConversationResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid prospect_num: %v", err),
+		}, nil
+	}
+	req.ProspectNum = prospectNum
+
 	// Check if conversation already exists for this prospect and device
 	existing, err := s.conversationRepo.GetConversationByProspectNum(ctx, req.ProspectNum, req.IDDevice)
 	if err != nil {
@@ -62,6 +94,29 @@ func (s *ConversationService) CreateConversation(ctx context.Context, userID str
 		}, nil
 	}
 
+	// A caller-supplied external reference must be unique on the device
+	var externalRef *string
+	if req.ExternalRef != nil && *req.ExternalRef != "" {
+		ref, ok := models.NormalizeExternalRef(*req.ExternalRef)
+		if !ok {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: fmt.Sprintf("external_ref must be 1-%d characters without commas, parentheses or quotes", models.MaxExternalRefLength),
+			}, nil
+		}
+		taken, err := s.conversationRepo.GetConversationByExternalRef(ctx, ref, []string{req.IDDevice})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check external reference: %w", err)
+		}
+		if taken != nil {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: "external_ref is already used by another conversation on this device",
+			}, nil
+		}
+		externalRef = &ref
+	}
+
 	// Create conversation
 	conversation := &models.AIWhatsapp{
 		ProspectNum: req.ProspectNum,
@@ -69,12 +124,19 @@ func (s *ConversationService) CreateConversation(ctx context.Context, userID str
 		Stage:       req.Stage,
 		Niche:       req.Niche,
 		FlowID:      req.FlowID,
+		ExternalRef: externalRef,
+		IsTest:      req.IsTest || device.Sandbox,
 	}
 
 	if err := s.conversationRepo.CreateConversation(ctx, conversation); err != nil {
 		return nil, fmt.Errorf("failed to create conversation: %w", err)
 	}
 
+	if conversation.IDProspect != nil {
+		s.events.Emit(ctx, *device.UserID, conversation.IDDevice, models.EventConversationCreated,
+			conversationEventData("ai_whatsapp", strconv.Itoa(*conversation.IDProspect), conversation))
+	}
+
 	return &models.ConversationResponse{
 		Success:      true,
 		Message:      "Conversation created successfully",
@@ -100,19 +162,51 @@ func (s *ConversationService) GetConversation(ctx context.Context, userID, prosp
 
 	if device == nil {
 		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
-		if err != nil || device.UserID == nil || *device.UserID != userID {
+		if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
 			return &models.ConversationResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
-	} else if device.UserID == nil || *device.UserID != userID {
+	} else if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
 		}, nil
 	}
 
+	// Cost ledger is informational, a lookup failure shouldn't hide the conversation
+	ledger, err := s.costs.GetLedger(ctx, conversation.IDDevice, conversation.ProspectNum)
+	if err != nil {
+		fmt.Printf("Warning: Failed to load cost ledger: %v\n", err)
+	}
+
+	return &models.ConversationResponse{
+		Success:      true,
+		Conversation: conversation,
+		CostLedger:   ledger,
+	}, nil
+}
+
+// GetConversationByExternalRef retrieves a conversation by its external reference across the user's
+// own and shared devices
+func (s *ConversationService) GetConversationByExternalRef(ctx context.Context, userID, externalRef string) (*models.ConversationResponse, error) {
+	deviceIDs, err := s.team.accessibleDeviceIDs(ctx, s.deviceRepo, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user devices: %w", err)
+	}
+
+	conversation, err := s.conversationRepo.GetConversationByExternalRef(ctx, externalRef, deviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.ConversationResponse{
+			Success: false,
+			Message: "Conversation not found",
+		}, nil
+	}
+
 	return &models.ConversationResponse{
 		Success:      true,
 		Conversation: conversation,
@@ -120,7 +214,8 @@ func (s *ConversationService) GetConversation(ctx context.Context, userID, prosp
 }
 
 // GetConversationsByDevice retrieves all conversations for a device
-func (s *ConversationService) GetConversationsByDevice(ctx context.Context, userID, deviceID string, limit int) (*models.ConversationResponse, error) {
+// query may add captured fields as columns and sort/filter on them (nil = plain list unless the device has list_columns).
+func (s *ConversationService) GetConversationsByDevice(ctx context.Context, userID, deviceID string, limit int, query *models.ConversationListQuery) (*models.ConversationResponse, error) {
 	// Verify device ownership
 	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
 	if err != nil {
@@ -138,13 +233,35 @@ func (s *ConversationService) GetConversationsByDevice(ctx context.Context, user
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
 		}, nil
 	}
 
+	// Captured fields as columns, sorted and filtered by the database
+	if query = effectiveListQuery(device, query); !query.IsEmpty() {
+		query = withCustomFields(ctx, s.fieldRepo, *device.UserID, query)
+		rows, err := s.conversationRepo.GetConversationListRows(ctx, deviceID, query, limit)
+		if errors.Is(err, repository.ErrInvalidListColumn) {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: err.Error(),
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.ConversationResponse{
+			Success: true,
+			Message: fmt.Sprintf("Found %d conversations", len(rows)),
+			Columns: query.Columns,
+			Rows:    rows,
+		}, nil
+	}
+
 	// Get conversations using device identifier
 	conversations, err := s.conversationRepo.GetConversationsByDevice(ctx, deviceID, limit)
 	if err != nil {
@@ -177,7 +294,7 @@ func (s *ConversationService) GetActiveConversations(ctx context.Context, userID
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
@@ -216,13 +333,13 @@ func (s *ConversationService) UpdateConversation(ctx context.Context, userID, pr
 
 	if device == nil {
 		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
-		if err != nil || device.UserID == nil || *device.UserID != userID {
+		if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
 			return &models.ConversationResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
-	} else if device.UserID == nil || *device.UserID != userID {
+	} else if !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
@@ -254,30 +371,61 @@ func (s *ConversationService) UpdateConversation(ctx context.Context, userID, pr
 	if req.SessionData != nil {
 		updates["session_data"] = *req.SessionData
 	}
-	if req.Status != nil {
-		updates["status"] = *req.Status
-		// If marking as completed, set completed_at
-		if *req.Status == "completed" {
-			now := time.Now()
-			updates["completed_at"] = now
-			updates["is_active"] = false
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if req.Priority != nil {
+		if !models.IsValidConversationPriority(*req.Priority) {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: "priority must be 0 (normal), 1 (high) or 2 (urgent)",
+			}, nil
 		}
+		updates["priority"] = *req.Priority
 	}
-
-	if len(updates) == 0 {
-		return &models.ConversationResponse{
-			Success: false,
-			Message: "No fields to update",
-		}, nil
+	if req.IsTest != nil {
+		updates["is_test"] = *req.IsTest
 	}
 
-	if err := s.conversationRepo.UpdateConversation(ctx, prospectID, updates); err != nil {
-		return nil, fmt.Errorf("failed to update conversation: %w", err)
+	if req.Status != nil {
+		// Status changes go through the state machine so the execution columns stay consistent
+		state := models.ConversationState(*req.Status)
+		if err := s.stateMachine.UpdateState(ctx, prospectID, state, "", updates); err != nil {
+			if errors.Is(err, ErrInvalidStateTransition) {
+				return &models.ConversationResponse{
+					Success: false,
+					Message: err.Error(),
+				}, nil
+			}
+			return nil, fmt.Errorf("failed to update conversation: %w", err)
+		}
+	} else {
+		if len(updates) == 0 {
+			return &models.ConversationResponse{
+				Success: false,
+				Message: "No fields to update",
+			}, nil
+		}
+
+		if err := s.conversationRepo.UpdateConversation(ctx, prospectID, updates); err != nil {
+			return nil, fmt.Errorf("failed to update conversation: %w", err)
+		}
 	}
 
 	// Get updated conversation
 	updatedConversation, _ := s.conversationRepo.GetConversationByID(ctx, prospectID)
 
+	if req.Stage != nil && *req.Stage != getStringValue(conversation.Stage) {
+		data := conversationEventData("ai_whatsapp", prospectID, conversation)
+		if updatedConversation != nil {
+			data = conversationEventData("ai_whatsapp", prospectID, updatedConversation)
+		}
+		data["stage"] = *req.Stage
+		data["previous_stage"] = conversation.Stage
+		data["changed_by"] = "agent"
+		s.events.Emit(ctx, *device.UserID, conversation.IDDevice, models.EventStageChanged, data)
+	}
+
 	return &models.ConversationResponse{
 		Success:      true,
 		Message:      "Conversation updated successfully",
@@ -304,39 +452,26 @@ func (s *ConversationService) AddMessage(ctx context.Context, userID, prospectID
 
 	if device == nil {
 		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
-		if err != nil || device.UserID == nil || *device.UserID != userID {
+		if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
 			return &models.ConversationResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
-	} else if device.UserID == nil || *device.UserID != userID {
+	} else if !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
 		}, nil
 	}
 
-	// Get existing conv_last (format: "User: message\nBot: reply")
-	convLast := ""
-	if conversation.ConvLast != nil {
-		convLast = *conversation.ConvLast
-	}
-
-	// Add new message to conv_last
-	prefix := ""
+	// Record the message and append it to conv_last (format: "User: message\nBot: reply")
+	role := "Bot"
 	if req.Role == "user" {
-		prefix = "User:"
-	} else if req.Role == "assistant" || req.Role == "system" {
-		prefix = "Bot:"
-	}
-
-	newLine := fmt.Sprintf("%s %s", prefix, req.Content)
-	if convLast != "" {
-		convLast += "\n" + newLine
-	} else {
-		convLast = newLine
+		role = "User"
 	}
+	message := newConversationMessage(models.AssignmentSourceAI, prospectID, conversation.IDDevice, role, req.Content)
+	convLast := s.messages.Append(ctx, getStringValue(conversation.ConvLast), message, 0)
 
 	// Update conversation
 	updates := map[string]interface{}{
@@ -377,13 +512,13 @@ func (s *ConversationService) DeleteConversation(ctx context.Context, userID, pr
 
 	if device == nil {
 		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
-		if err != nil || device.UserID == nil || *device.UserID != userID {
+		if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteConversations) {
 			return &models.ConversationResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
-	} else if device.UserID == nil || *device.UserID != userID {
+	} else if !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteConversations) {
 		return &models.ConversationResponse{
 			Success: false,
 			Message: "Access denied",
@@ -393,6 +528,7 @@ func (s *ConversationService) DeleteConversation(ctx context.Context, userID, pr
 	if err := s.conversationRepo.DeleteConversation(ctx, prospectID); err != nil {
 		return nil, fmt.Errorf("failed to delete conversation: %w", err)
 	}
+	s.messages.Forget(ctx, models.AssignmentSourceAI, prospectID)
 
 	return &models.ConversationResponse{
 		Success: true,
@@ -416,7 +552,7 @@ func (s *ConversationService) GetConversationStats(ctx context.Context, userID,
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
 		return nil, fmt.Errorf("access denied")
 	}
 
@@ -429,7 +565,7 @@ func (s *ConversationService) GetConversationStats(ctx context.Context, userID,
 	return stats, nil
 }
 
-// GetAllConversationsForUser retrieves all AI WhatsApp conversations for a user across all their devices
+// GetAllConversationsForUser retrieves all AI WhatsApp conversations for a user across their own and shared devices
 func (s *ConversationService) GetAllConversationsForUser(ctx context.Context, userID string) (*models.ConversationResponse, error) {
 	// Get all devices for the user
 	devices, err := s.deviceRepo.GetDevicesByUserID(ctx, userID)
@@ -440,6 +576,16 @@ func (s *ConversationService) GetAllConversationsForUser(ctx context.Context, us
 		}, nil
 	}
 
+	// Devices shared with the user through a team
+	shared, _, err := s.team.SharedDevices(ctx, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return &models.ConversationResponse{
+			Success: false,
+			Message: "Failed to retrieve shared devices",
+		}, nil
+	}
+	devices = append(devices, shared...)
+
 	// Collect all conversations from all devices
 	var allConversations []models.AIWhatsapp
 
@@ -465,6 +611,8 @@ func (s *ConversationService) GetAllConversationsForUser(ctx context.Context, us
 		allConversations = append(allConversations, conversations...)
 	}
 
+	sortConversationsForInbox(allConversations)
+
 	return &models.ConversationResponse{
 		Success:       true,
 		Message:       fmt.Sprintf("Found %d conversations", len(allConversations)),
@@ -508,9 +656,33 @@ func (s *ConversationService) GetAllConversations(ctx context.Context) (*models.
 		allConversations = append(allConversations, conversations...)
 	}
 
+	sortConversationsForInbox(allConversations)
+
 	return &models.ConversationResponse{
 		Success:       true,
 		Message:       fmt.Sprintf("Found %d conversations (admin view)", len(allConversations)),
 		Conversations: allConversations,
 	}, nil
 }
+
+// GetPinnedConversations retrieves the user's pinned conversations across their own and shared devices
+func (s *ConversationService) GetPinnedConversations(ctx context.Context, userID string) (*models.ConversationResponse, error) {
+	deviceIDs, err := s.team.accessibleDeviceIDs(ctx, s.deviceRepo, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return &models.ConversationResponse{
+			Success: false,
+			Message: "Failed to retrieve user devices",
+		}, nil
+	}
+
+	conversations, err := s.conversationRepo.GetPinnedConversations(ctx, deviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned conversations: %w", err)
+	}
+
+	return &models.ConversationResponse{
+		Success:       true,
+		Message:       fmt.Sprintf("Found %d pinned conversations", len(conversations)),
+		Conversations: conversations,
+	}, nil
+}
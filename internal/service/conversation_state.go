@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// ErrInvalidStateTransition is returned when a state change is not allowed by the state machine
+var ErrInvalidStateTransition = errors.New("invalid conversation state transition")
+
+// ConversationStateStore is the persistence needed by the state machine.
+// Both ConversationRepository (ai_whatsapp) and WasapbotRepository (wasapbot) implement it.
+type ConversationStateStore interface {
+	GetExecutionState(ctx context.Context, conversationID string) (*models.ExecutionState, error)
+	UpdateConversation(ctx context.Context, conversationID string, updates map[string]interface{}) error
+}
+
+// ConversationStateMachine is the single entry point for changing
+// execution_status, waiting_for_reply and current_node_id on a conversation
+type ConversationStateMachine struct {
+	store ConversationStateStore
+}
+
+// NewConversationStateMachine creates a state machine backed by the given store
+func NewConversationStateMachine(store ConversationStateStore) *ConversationStateMachine {
+	return &ConversationStateMachine{
+		store: store,
+	}
+}
+
+// GetState returns the current state of a conversation
+func (m *ConversationStateMachine) GetState(ctx context.Context, conversationID string) (models.ConversationState, error) {
+	execState, err := m.store.GetExecutionState(ctx, conversationID)
+	if err != nil {
+		return "", err
+	}
+	return execState.State(), nil
+}
+
+// UpdateState validates and applies a state transition.
+// nodeID is written to current_node_id when non-empty (completed always writes the completed marker).
+// extra is merged into the same update so related columns change atomically with the state.
+func (m *ConversationStateMachine) UpdateState(
+	ctx context.Context,
+	conversationID string,
+	next models.ConversationState,
+	nodeID string,
+	extra map[string]interface{},
+) error {
+	if !next.IsValid() {
+		return fmt.Errorf("%w: unknown state %q", ErrInvalidStateTransition, next)
+	}
+
+	current, err := m.GetState(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to read conversation state: %w", err)
+	}
+
+	if !current.CanTransitionTo(next) {
+		log.Printf("⛔ Rejected state transition %s -> %s for conversation %s", current, next, conversationID)
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStateTransition, current, next)
+	}
+
+	updates := make(map[string]interface{}, len(extra)+3)
+	for key, value := range extra {
+		updates[key] = value
+	}
+
+	updates["execution_status"] = string(next)
+	updates["waiting_for_reply"] = next == models.ConversationStateWaiting
+
+	// Escalation rule: a conversation waiting for a human jumps to the top of the inbox
+	if next == models.ConversationStateHandoff && current != next {
+		if _, ok := updates["priority"]; !ok {
+			updates["priority"] = models.ConversationPriorityUrgent
+		}
+	}
+
+	if next == models.ConversationStateCompleted {
+		updates["current_node_id"] = models.CompletedNodeID
+		updates["flow_progress"] = 100
+		if current != next {
+			updates["completed_at"] = time.Now()
+		}
+	} else if nodeID != "" {
+		updates["current_node_id"] = nodeID
+	}
+
+	// Re-engaged after completing: the new run through the flow starts from zero
+	if current == models.ConversationStateCompleted && next == models.ConversationStateActive {
+		updates["flow_progress"] = 0
+		updates["flow_milestone"] = nil
+		updates["completed_at"] = nil
+	}
+
+	if err := m.store.UpdateConversation(ctx, conversationID, updates); err != nil {
+		return fmt.Errorf("failed to update conversation state: %w", err)
+	}
+
+	if current != next {
+		log.Printf("🔁 Conversation %s state: %s -> %s", conversationID, current, next)
+	}
+
+	return nil
+}
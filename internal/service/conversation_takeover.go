@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// ownedPausedConversation loads a conversation of either table and checks the user owns its device
+// or has a team role on it that may reply.
+// Returns a message for the client instead when the conversation cannot be used.
+func (s *FlowProcessorService) ownedPausedConversation(ctx context.Context, userID, source, conversationID string) (*pausedConversation, *models.DeviceSetting, string, error) {
+	if source != models.AssignmentSourceAI && source != models.AssignmentSourceWasapbot {
+		return nil, nil, fmt.Sprintf("Invalid source: %s (use ai_whatsapp or wasapbot)", source), nil
+	}
+
+	conversation, err := s.loadPausedConversation(ctx, source, conversationID)
+	if errors.Is(err, ErrConversationNotFound) {
+		return nil, nil, "Conversation not found", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, conversation.idDevice)
+	if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
+		return nil, nil, "Access denied", nil
+	}
+	return conversation, device, "", nil
+}
+
+// TakeOver hands a conversation to an agent working from the inbox. The bot stops replying and
+// pending delay resumes are dropped until the agent hands the conversation back.
+func (s *FlowProcessorService) TakeOver(ctx context.Context, userID, source, conversationID string) (*models.TakeoverResponse, error) {
+	conversation, _, msg, err := s.ownedPausedConversation(ctx, userID, source, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.TakeoverResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.stateMachine(source).UpdateState(ctx, conversationID, models.ConversationStatePausedByAgent, "", nil); err != nil {
+		return nil, err
+	}
+
+	// A delay or waiting_times timeout must not restart the flow behind the agent's back
+	if s.delayRepo != nil {
+		if err := s.delayRepo.SkipPending(ctx, source, conversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("✋ Conversation %s (%s) taken over by an agent (was %s)", conversationID, source, conversation.state)
+	return &models.TakeoverResponse{
+		Success: true,
+		Message: "Conversation taken over; the bot stays paused until it is handed back",
+		State:   models.ConversationStatePausedByAgent,
+	}, nil
+}
+
+// SendAgentMessage sends an agent's message to the prospect from the conversation's device and
+// adds it to the history, so the bot knows what was said once the conversation is handed back
+func (s *FlowProcessorService) SendAgentMessage(ctx context.Context, userID, source, conversationID string, req *models.AgentMessageRequest) (*models.TakeoverResponse, error) {
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" && req.MediaURL == "" {
+		return &models.TakeoverResponse{Success: false, Message: "message or media_url is required"}, nil
+	}
+	if req.MediaURL != "" && req.MediaType == "" {
+		return &models.TakeoverResponse{Success: false, Message: "media_type is required with media_url"}, nil
+	}
+
+	conversation, device, msg, err := s.ownedPausedConversation(ctx, userID, source, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.TakeoverResponse{Success: false, Message: msg}, nil
+	}
+	if conversation.state != models.ConversationStatePausedByAgent && conversation.state != models.ConversationStateHandoff {
+		return &models.TakeoverResponse{
+			Success: false,
+			Message: fmt.Sprintf("Take the conversation over before messaging the prospect (state: %s)", conversation.state),
+			State:   conversation.state,
+		}, nil
+	}
+
+	if err := s.whatsappService.SendMessage(withHumanReply(ctx, req.Agent), conversation.idDevice, conversation.prospectNum, req.Message, req.MediaType, req.MediaURL); err != nil {
+		return nil, err
+	}
+
+	content := req.Message
+	if content == "" {
+		content = fmt.Sprintf("[%s]", req.MediaType)
+	}
+	message := newConversationMessage(source, conversationID, conversation.idDevice, "Bot", content)
+	if source == models.AssignmentSourceWasapbot {
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, conversation.convLast, message, device.EffectiveMaxHistoryEntries()),
+		}
+		err = s.convRepo.UpdateWasapBotContact(ctx, conversationID, updates)
+	} else {
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, conversation.convLast, message, 0),
+		}
+		err = s.convRepo.UpdateConversation(ctx, conversationID, updates)
+	}
+	if err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	return &models.TakeoverResponse{
+		Success: true,
+		Message: "Message sent",
+		State:   conversation.state,
+	}, nil
+}
+
+// HandBack returns a taken-over conversation to the bot and runs its flow from the chosen node,
+// or from the stage node that sets the chosen stage. Returns the conversation's state after the run.
+func (s *FlowProcessorService) HandBack(ctx context.Context, userID, source, conversationID string, req *models.HandBackRequest) (*models.TakeoverResponse, error) {
+	req.NodeID = strings.TrimSpace(req.NodeID)
+	req.Stage = strings.TrimSpace(req.Stage)
+	if (req.NodeID == "") == (req.Stage == "") {
+		return &models.TakeoverResponse{Success: false, Message: "Pass either node_id or stage to pick where the bot continues"}, nil
+	}
+
+	conversation, _, msg, err := s.ownedPausedConversation(ctx, userID, source, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.TakeoverResponse{Success: false, Message: msg}, nil
+	}
+	if conversation.state != models.ConversationStatePausedByAgent {
+		return &models.TakeoverResponse{
+			Success: false,
+			Message: fmt.Sprintf("Conversation is not taken over by an agent (state: %s)", conversation.state),
+			State:   conversation.state,
+		}, nil
+	}
+	if conversation.flowID == "" {
+		return &models.TakeoverResponse{Success: false, Message: "Conversation is not bound to a flow", State: conversation.state}, nil
+	}
+
+	flow, err := s.flowRepo.GetFlowByID(ctx, conversation.flowID)
+	if err != nil || flow == nil {
+		return nil, fmt.Errorf("failed to load flow %s: %v", conversation.flowID, err)
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return nil, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	var node *FlowNode
+	if req.NodeID != "" {
+		node = findFlowNode(&flowData, req.NodeID)
+		if node == nil {
+			return &models.TakeoverResponse{Success: false, Message: fmt.Sprintf("Node %s not found in flow %s", req.NodeID, flow.Name), State: conversation.state}, nil
+		}
+	} else {
+		node = findStageNode(&flowData, req.Stage)
+		if node == nil {
+			return &models.TakeoverResponse{Success: false, Message: fmt.Sprintf("Flow %s has no stage node for stage %s", flow.Name, req.Stage), State: conversation.state}, nil
+		}
+	}
+
+	if err := s.stateMachine(source).UpdateState(ctx, conversationID, models.ConversationStateActive, node.ID, nil); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🤖 Conversation %s (%s) handed back to the bot at node %s", conversationID, source, node.ID)
+	if source == models.AssignmentSourceWasapbot {
+		engine := NewWasapbotFlowEngine(s.runtimeServices(), s.wasapbotRepo, s.whatsappService)
+		err = engine.ExecuteWasapbotFlow(ctx, flow, conversationID, req.Message, node.ID)
+	} else {
+		err = s.ExecuteFlow(ctx, flow, conversationID, req.Message, node.ID)
+		// Recorded after failed runs too, so a partial run can be rewound
+		s.recordCheckpoint(ctx, conversationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.stateMachine(source).GetState(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TakeoverResponse{
+		Success: true,
+		Message: "Conversation handed back to the bot",
+		State:   state,
+		NodeID:  node.ID,
+	}, nil
+}
+
+// findStageNode returns the stage node that sets stage (case-insensitive), or nil
+func findStageNode(flowData *FlowData, stage string) *FlowNode {
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		if node.Type != "stage" {
+			continue
+		}
+		if value, _ := node.Config["value"].(string); strings.EqualFold(strings.TrimSpace(value), stage) {
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ConditionField is the field condition for conditions node edges. Its value compares a conversation
+// field, custom field or session variable:
+//
+//	field  "pakej=Gold"        equal (case-insensitive)
+//	field  "pakej!=Gold"       not equal
+//	field  "umur>=18"          >, >=, < and <= compare numbers, or text such as YYYY-MM-DD dates
+//	field  "alamat~kuala"      contains
+//	field  "saiz_baju"         has any value
+const ConditionField = "field"
+
+// fieldConditionOperators are tried in this order, so two-character operators win over their prefixes
+var fieldConditionOperators = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// conversationVariables returns the variables of a conversation row: every column, then the keys of
+// custom_fields and session_data that do not clash with a column
+func conversationVariables(conversation interface{}) map[string]interface{} {
+	vars := make(map[string]interface{})
+	if data, err := json.Marshal(conversation); err == nil {
+		_ = json.Unmarshal(data, &vars)
+	}
+
+	for _, nested := range []string{"custom_fields", "session_data"} {
+		if values, ok := vars[nested].(map[string]interface{}); ok {
+			for key, value := range values {
+				if _, exists := vars[key]; !exists {
+					vars[key] = value
+				}
+			}
+		}
+		delete(vars, nested)
+	}
+
+	return vars
+}
+
+// variableText returns a variable as text, "" when it is missing or null
+func variableText(vars map[string]interface{}, name string) string {
+	return variableValueText(vars[name])
+}
+
+// variableValueText returns a variable's value as text, "" when it is null
+func variableValueText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		// Whole numbers without exponent or decimals, such as 1500000 rather than 1.5e+06
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// renderMessageTemplate replaces {{variable}} placeholders in a message with the conversation's
+// variables; unknown variables become empty
+func renderMessageTemplate(text string, conversation interface{}) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	return renderVariables(text, conversationVariables(conversation))
+}
+
+// renderVariables replaces {{variable}} placeholders in text with vars; unknown variables become empty
+func renderVariables(text string, vars map[string]interface{}) string {
+	return completionPlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		return variableText(vars, completionPlaceholder.FindStringSubmatch(match)[1])
+	})
+}
+
+// evaluateFieldCondition checks a field condition value such as "pakej=Gold" against the variables.
+// Text comparisons normalize both sides the way message conditions do.
+func evaluateFieldCondition(condition string, vars map[string]interface{}, malay bool) bool {
+	name, operator, expected := strings.TrimSpace(condition), "", ""
+	for _, op := range fieldConditionOperators {
+		if i := strings.Index(condition, op); i > 0 {
+			name, operator, expected = strings.TrimSpace(condition[:i]), op, strings.TrimSpace(condition[i+len(op):])
+			break
+		}
+	}
+
+	actual := strings.TrimSpace(variableText(vars, name))
+	switch operator {
+	case "":
+		return actual != ""
+	case "=":
+		return messageMatches("equal", actual, expected, malay)
+	case "!=":
+		return !messageMatches("equal", actual, expected, malay)
+	case "~":
+		return messageMatches("contains", actual, expected, malay)
+	}
+
+	if actual == "" {
+		return false
+	}
+	cmp := strings.Compare(strings.ToLower(actual), strings.ToLower(expected))
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if b, err := strconv.ParseFloat(expected, 64); err == nil {
+			cmp = 0
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
+			}
+		}
+	}
+
+	switch operator {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// fieldCondition evaluates a field condition against the run's conversation
+func (run *flowRun) fieldCondition(ctx context.Context, condition string) bool {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Failed to load conversation %s for field condition: %v", run.conversationID, err)
+		return false
+	}
+	return evaluateFieldCondition(condition, conversationVariables(conversation.row), run.flow.NormalizeMalay)
+}
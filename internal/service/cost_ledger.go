@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// CostRecorder writes AI, send and API call costs to the per-conversation ledger.
+// A nil recorder (or one without a repository) silently does nothing so callers don't need to check.
+type CostRecorder struct {
+	repo *repository.CostLedgerRepository
+}
+
+// NewCostRecorder creates a new cost recorder
+func NewCostRecorder(repo *repository.CostLedgerRepository) *CostRecorder {
+	return &CostRecorder{repo: repo}
+}
+
+func (r *CostRecorder) enabled() bool {
+	return r != nil && r.repo != nil
+}
+
+func (r *CostRecorder) record(ctx context.Context, entry *models.ConversationCost) {
+	if entry.IDDevice == "" || entry.ProspectNum == "" {
+		return
+	}
+	if err := r.repo.RecordCost(ctx, entry); err != nil {
+		log.Printf("⚠️  Failed to record %s cost: %v", entry.CostType, err)
+	}
+}
+
+// RecordAITokens prices a completion by model and adds it to the ledger.
+// reportedCost is used as-is when the provider returns the charge (OpenRouter usage.cost).
+func (r *CostRecorder) RecordAITokens(ctx context.Context, idDevice, prospectNum string, flowID *string, model string, usage *models.TokenUsage, reportedCost *float64) {
+	if !r.enabled() || usage == nil {
+		return
+	}
+
+	amount, priced := models.AITokenCost(model, usage.PromptTokens, usage.CompletionTokens)
+	if reportedCost != nil {
+		amount, priced = *reportedCost, true
+	}
+	if !priced {
+		log.Printf("⚠️  No price configured for model %s, recording tokens only", model)
+	}
+
+	promptTokens := usage.PromptTokens
+	completionTokens := usage.CompletionTokens
+	r.record(ctx, &models.ConversationCost{
+		IDDevice:         idDevice,
+		ProspectNum:      prospectNum,
+		FlowID:           flowID,
+		CostType:         models.CostTypeAITokens,
+		Model:            &model,
+		PromptTokens:     &promptTokens,
+		CompletionTokens: &completionTokens,
+		Amount:           amount,
+	})
+}
+
+// RecordSendFee adds a provider per-message fee to the ledger
+func (r *CostRecorder) RecordSendFee(ctx context.Context, idDevice, prospectNum, provider string, fee float64) {
+	if !r.enabled() || fee <= 0 {
+		return
+	}
+
+	description := fmt.Sprintf("%s message", provider)
+	r.record(ctx, &models.ConversationCost{
+		IDDevice:    idDevice,
+		ProspectNum: prospectNum,
+		CostType:    models.CostTypeSendFee,
+		Amount:      fee,
+		Description: &description,
+	})
+}
+
+// RecordAPICall adds an external API node call to the ledger
+func (r *CostRecorder) RecordAPICall(ctx context.Context, idDevice, prospectNum string, flowID *string, url string, amount float64) {
+	if !r.enabled() || amount <= 0 {
+		return
+	}
+
+	r.record(ctx, &models.ConversationCost{
+		IDDevice:    idDevice,
+		ProspectNum: prospectNum,
+		FlowID:      flowID,
+		CostType:    models.CostTypeAPICall,
+		Amount:      amount,
+		Description: &url,
+	})
+}
+
+// GetLedger returns the totalled cost ledger for a conversation
+func (r *CostRecorder) GetLedger(ctx context.Context, idDevice, prospectNum string) (*models.CostLedger, error) {
+	if !r.enabled() {
+		return nil, nil
+	}
+
+	entries, err := r.repo.GetConversationCosts(ctx, idDevice, prospectNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewCostLedger(idDevice, prospectNum, entries), nil
+}
+
+// openRouterUsage extracts token usage, and the charged cost when present, from a chat completion body
+func openRouterUsage(responseBody map[string]interface{}) (*models.TokenUsage, *float64) {
+	usageData, ok := responseBody["usage"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	usage := &models.TokenUsage{}
+	if promptTokens, ok := usageData["prompt_tokens"].(float64); ok {
+		usage.PromptTokens = int(promptTokens)
+	}
+	if completionTokens, ok := usageData["completion_tokens"].(float64); ok {
+		usage.CompletionTokens = int(completionTokens)
+	}
+	if totalTokens, ok := usageData["total_tokens"].(float64); ok {
+		usage.TotalTokens = int(totalTokens)
+	} else {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+
+	var cost *float64
+	if charged, ok := usageData["cost"].(float64); ok {
+		cost = &charged
+	}
+
+	return usage, cost
+}
+
+// summarizeLeadCosts groups ledger entries into one row per prospect, most expensive first
+func summarizeLeadCosts(entries []models.ConversationCost) []models.LeadCost {
+	byLead := make(map[string]*models.LeadCost)
+	order := make([]string, 0)
+
+	for _, entry := range entries {
+		key := entry.IDDevice + "|" + entry.ProspectNum
+		lead, ok := byLead[key]
+		if !ok {
+			lead = &models.LeadCost{
+				IDDevice:    entry.IDDevice,
+				ProspectNum: entry.ProspectNum,
+				ByType:      make(map[string]float64),
+			}
+			byLead[key] = lead
+			order = append(order, key)
+		}
+
+		lead.TotalAmount += entry.Amount
+		lead.ByType[entry.CostType] += entry.Amount
+		lead.Entries++
+		if entry.PromptTokens != nil {
+			lead.TotalTokens += *entry.PromptTokens
+		}
+		if entry.CompletionTokens != nil {
+			lead.TotalTokens += *entry.CompletionTokens
+		}
+	}
+
+	leads := make([]models.LeadCost, 0, len(order))
+	for _, key := range order {
+		leads = append(leads, *byLead[key])
+	}
+	sort.SliceStable(leads, func(i, j int) bool {
+		return leads[i].TotalAmount > leads[j].TotalAmount
+	})
+
+	return leads
+}
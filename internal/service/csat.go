@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultCSATQuestion    = "How satisfied are you with our service? Please reply with a number from 1 (very unsatisfied) to 5 (very satisfied)."
+	defaultCSATRetry       = "Sorry, please reply with a number from 1 to 5 only."
+	defaultCSATThanks      = "Thank you for your feedback!"
+	defaultCSATMaxAttempts = 2
+)
+
+// csatQuestion returns the survey question configured on a csat node
+func csatQuestion(node *FlowNode) string {
+	if text, ok := node.Config["text"].(string); ok && strings.TrimSpace(text) != "" {
+		return text
+	}
+	return defaultCSATQuestion
+}
+
+// csatRetryMessage returns the message sent when the answer is not a valid rating
+func csatRetryMessage(node *FlowNode) string {
+	if text, ok := node.Config["retry_text"].(string); ok && strings.TrimSpace(text) != "" {
+		return text
+	}
+	return defaultCSATRetry
+}
+
+// csatThanksMessage returns the message sent after a valid rating (empty disables it)
+func csatThanksMessage(node *FlowNode) string {
+	if text, ok := node.Config["thanks_text"].(string); ok {
+		return text
+	}
+	return defaultCSATThanks
+}
+
+// csatMaxAttempts returns how many invalid answers are tolerated before the flow moves on without a score
+func csatMaxAttempts(node *FlowNode) int {
+	if val, ok := node.Config["max_attempts"].(float64); ok && val >= 1 {
+		return int(val)
+	}
+	return defaultCSATMaxAttempts
+}
+
+// parseCSATScore extracts a 1-5 rating from a reply.
+// Accepts a bare digit ("4"), fractions ("4/5"), button payloads ("csat_4") and star emoji ("⭐⭐⭐⭐").
+func parseCSATScore(message string) (int, bool) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return 0, false
+	}
+
+	// Star emoji rating
+	if stars := strings.Count(message, "⭐"); stars > 0 {
+		if stars >= 1 && stars <= 5 {
+			return stars, true
+		}
+		return 0, false
+	}
+
+	// First run of digits in the reply
+	digits := ""
+	for _, r := range message {
+		if unicode.IsDigit(r) {
+			digits += string(r)
+		} else if digits != "" {
+			break
+		}
+	}
+
+	score, err := strconv.Atoi(digits)
+	if err != nil || score < 1 || score > 5 {
+		return 0, false
+	}
+
+	return score, true
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// reservedCustomFieldNames are names that already mean something in lists, templates and webhooks
+var reservedCustomFieldNames = map[string]bool{
+	"id_prospect": true, "id_device": true, "prospect_num": true, "number": true, "conv_last": true,
+	"conv_current": true, "session_data": true, "custom_fields": true, "waiting_for_reply": true,
+	"nama": true, "phone": true, "disposition": true, "flow_id": true, "flow_name": true, "completed_at": true,
+}
+
+// CustomFieldService manages the contact custom fields users define for their conversations
+type CustomFieldService struct {
+	fieldRepo *repository.CustomFieldRepository
+}
+
+// NewCustomFieldService creates a new custom field service
+func NewCustomFieldService(fieldRepo *repository.CustomFieldRepository) *CustomFieldService {
+	return &CustomFieldService{
+		fieldRepo: fieldRepo,
+	}
+}
+
+// ListFields returns the user's custom fields
+func (s *CustomFieldService) ListFields(ctx context.Context, userID string) (*models.CustomFieldResponse, error) {
+	fields, err := s.fieldRepo.GetFieldsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = []models.ContactCustomField{}
+	}
+
+	return &models.CustomFieldResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d custom fields", len(fields)),
+		Fields:  fields,
+	}, nil
+}
+
+// CreateField defines a new custom field for the user
+func (s *CustomFieldService) CreateField(ctx context.Context, userID string, req *models.CreateCustomFieldRequest) (*models.CustomFieldResponse, error) {
+	field := &models.ContactCustomField{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Label:     strings.TrimSpace(req.Label),
+		FieldType: req.FieldType,
+		Options:   trimOptions(req.Options),
+		Pattern:   req.Pattern,
+	}
+	if field.Label == "" {
+		field.Label = field.Name
+	}
+	if field.FieldType == "" {
+		field.FieldType = models.CustomFieldText
+	}
+	if field.Pattern != nil && *field.Pattern == "" {
+		field.Pattern = nil
+	}
+
+	if msg := validateCustomField(field); msg != "" {
+		return &models.CustomFieldResponse{Success: false, Message: msg}, nil
+	}
+
+	existing, err := s.fieldRepo.GetFieldsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxCustomFields {
+		return &models.CustomFieldResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can define at most %d custom fields", models.MaxCustomFields),
+		}, nil
+	}
+	for _, other := range existing {
+		if other.Name == field.Name {
+			return &models.CustomFieldResponse{
+				Success: false,
+				Message: fmt.Sprintf("A custom field named %s already exists", field.Name),
+			}, nil
+		}
+	}
+
+	if err := s.fieldRepo.CreateField(ctx, field); err != nil {
+		return nil, err
+	}
+
+	return &models.CustomFieldResponse{
+		Success: true,
+		Message: "Custom field created successfully",
+		Field:   field,
+	}, nil
+}
+
+// UpdateField changes the label, options or pattern of one of the user's custom fields
+func (s *CustomFieldService) UpdateField(ctx context.Context, userID, fieldID string, req *models.UpdateCustomFieldRequest) (*models.CustomFieldResponse, error) {
+	field, msg, err := s.ownedField(ctx, userID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+	if field == nil {
+		return &models.CustomFieldResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Label != nil {
+		field.Label = strings.TrimSpace(*req.Label)
+		if field.Label == "" {
+			field.Label = field.Name
+		}
+		updates["label"] = field.Label
+	}
+	if req.Options != nil {
+		field.Options = trimOptions(*req.Options)
+		updates["options"] = field.Options
+	}
+	if req.Pattern != nil {
+		if *req.Pattern == "" {
+			field.Pattern = nil
+		} else {
+			field.Pattern = req.Pattern
+		}
+		updates["pattern"] = field.Pattern
+	}
+
+	if len(updates) == 0 {
+		return &models.CustomFieldResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := validateCustomField(field); msg != "" {
+		return &models.CustomFieldResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.fieldRepo.UpdateField(ctx, field.ID, updates); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.fieldRepo.GetFieldByID(ctx, field.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CustomFieldResponse{
+		Success: true,
+		Message: "Custom field updated successfully",
+		Field:   updated,
+	}, nil
+}
+
+// DeleteField removes one of the user's custom fields. Values already captured are kept.
+func (s *CustomFieldService) DeleteField(ctx context.Context, userID, fieldID string) (*models.CustomFieldResponse, error) {
+	field, msg, err := s.ownedField(ctx, userID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+	if field == nil {
+		return &models.CustomFieldResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.fieldRepo.DeleteField(ctx, field.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.CustomFieldResponse{
+		Success: true,
+		Message: "Custom field deleted successfully",
+	}, nil
+}
+
+// ownedField loads a custom field and checks the user owns it
+func (s *CustomFieldService) ownedField(ctx context.Context, userID, fieldID string) (*models.ContactCustomField, string, error) {
+	field, err := s.fieldRepo.GetFieldByID(ctx, fieldID)
+	if err != nil {
+		return nil, "", err
+	}
+	if field == nil {
+		return nil, "Custom field not found", nil
+	}
+	if field.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return field, "", nil
+}
+
+// validateCustomField checks a custom field definition.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateCustomField(field *models.ContactCustomField) string {
+	if !models.IsValidListColumnName(field.Name) {
+		return fmt.Sprintf("Invalid custom field name %q: use lowercase letters, digits and underscores, such as saiz_baju", field.Name)
+	}
+	if reservedCustomFieldNames[field.Name] || repository.IsConversationColumn(field.Name) {
+		return fmt.Sprintf("%s is already a conversation field; choose another name", field.Name)
+	}
+	if !models.IsValidCustomFieldType(field.FieldType) {
+		return "field_type must be text, number, date, boolean or select"
+	}
+	if field.FieldType == models.CustomFieldSelect && len(field.Options) == 0 {
+		return "A select field needs at least one option"
+	}
+	if field.Pattern != nil {
+		if field.FieldType != models.CustomFieldText {
+			return "pattern is only used by text fields"
+		}
+		if _, err := regexp.Compile(*field.Pattern); err != nil {
+			return fmt.Sprintf("Invalid pattern: %v", err)
+		}
+	}
+	return ""
+}
+
+// trimOptions trims select options and drops empty and repeated ones
+func trimOptions(options []string) []string {
+	trimmed := []string{}
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		key := strings.ToLower(option)
+		if option == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		trimmed = append(trimmed, option)
+	}
+	return trimmed
+}
+
+// deviceCustomFields returns the custom fields of a device's owner by name, nil when the device
+// or its fields cannot be loaded
+func deviceCustomFields(ctx context.Context, deviceRepo *repository.DeviceRepository, fieldRepo *repository.CustomFieldRepository, idDevice string) map[string]models.ContactCustomField {
+	if fieldRepo == nil || deviceRepo == nil {
+		return nil
+	}
+	device, err := deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err != nil || device == nil || device.UserID == nil {
+		return nil
+	}
+	return userCustomFields(ctx, fieldRepo, *device.UserID)
+}
+
+// userCustomFields returns a user's custom fields by name, nil when they cannot be loaded
+func userCustomFields(ctx context.Context, fieldRepo *repository.CustomFieldRepository, userID string) map[string]models.ContactCustomField {
+	if fieldRepo == nil {
+		return nil
+	}
+	fields, err := fieldRepo.GetFieldsByUser(ctx, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load custom fields of user %s: %v", userID, err)
+		return nil
+	}
+	byName := make(map[string]models.ContactCustomField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	return byName
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// maxCustomProviderHeaders caps the headers a custom gateway sends with each request
+const maxCustomProviderHeaders = 10
+
+// validateCustomProvider checks the gateway description of a custom provider device
+func validateCustomProvider(custom *models.CustomProviderConfig) string {
+	if custom == nil || strings.TrimSpace(custom.SendURL) == "" {
+		return "custom_provider.send_url is required for the custom provider"
+	}
+	if !validCustomProviderURL(custom.SendURL) {
+		return "custom_provider.send_url must be an http or https URL or start with {{base_url}}"
+	}
+	if custom.StatusURL != "" && !validCustomProviderURL(custom.StatusURL) {
+		return "custom_provider.status_url must be an http or https URL or start with {{base_url}}"
+	}
+	switch strings.ToUpper(custom.Method) {
+	case "", "GET", "POST", "PUT", "PATCH":
+	default:
+		return "custom_provider.method must be GET, POST, PUT or PATCH"
+	}
+	if len(custom.Headers) > maxCustomProviderHeaders {
+		return fmt.Sprintf("custom_provider can have at most %d headers", maxCustomProviderHeaders)
+	}
+	for name, value := range custom.Headers {
+		if !validHeaderName(name) || strings.ContainsAny(value, "\r\n") {
+			return fmt.Sprintf("Invalid custom_provider header %q", name)
+		}
+		switch http.CanonicalHeaderKey(name) {
+		case "Host", "Content-Length", "Content-Type", "Transfer-Encoding":
+			return fmt.Sprintf("custom_provider header %q cannot be overridden", name)
+		}
+	}
+	if strings.ContainsAny(custom.ContentType, "\r\n") {
+		return "Invalid custom_provider.content_type"
+	}
+	return ""
+}
+
+// validCustomProviderURL reports whether a URL template is absolute or starts at the device's api_url
+func validCustomProviderURL(template string) bool {
+	if strings.HasPrefix(strings.ReplaceAll(template, " ", ""), "{{base_url}}") {
+		return true
+	}
+	return validHeartbeatURL(template)
+}
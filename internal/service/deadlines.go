@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Default execution deadlines, used when a configured value is zero
+const (
+	DefaultFlowRunTimeout      = 5 * time.Minute
+	DefaultNodeTimeout         = 2 * time.Minute
+	DefaultExternalCallTimeout = 60 * time.Second
+
+	// Loop guard: a run stops after this many nodes, or this many visits to one node, without pausing
+	DefaultMaxFlowSteps  = 100
+	DefaultMaxNodeVisits = 10
+
+	// progressSaveTimeout bounds the state write made after a deadline has already fired
+	progressSaveTimeout = 10 * time.Second
+)
+
+// ExecutionDeadlines bounds background flow processing: a whole flow run triggered by one
+// inbound message, each node within it, and each external call (AI, WhatsApp provider) a node makes.
+// MaxSteps and MaxNodeVisits bound the same run by node count, so a cycle between nodes that never
+// pause stops well before the run deadline.
+type ExecutionDeadlines struct {
+	FlowRun       time.Duration
+	Node          time.Duration
+	ExternalCall  time.Duration
+	MaxSteps      int // nodes one run may execute (0 uses the default)
+	MaxNodeVisits int // times one run may execute the same node (0 uses the default)
+}
+
+// NewExecutionDeadlines fills zero values with the defaults
+func NewExecutionDeadlines(flowRun, node, externalCall time.Duration) ExecutionDeadlines {
+	if flowRun <= 0 {
+		flowRun = DefaultFlowRunTimeout
+	}
+	if node <= 0 {
+		node = DefaultNodeTimeout
+	}
+	if externalCall <= 0 {
+		externalCall = DefaultExternalCallTimeout
+	}
+	return ExecutionDeadlines{FlowRun: flowRun, Node: node, ExternalCall: externalCall}
+}
+
+// WithStepLimits sets the loop guard limits; zero values keep the defaults
+func (d ExecutionDeadlines) WithStepLimits(maxSteps, maxNodeVisits int) ExecutionDeadlines {
+	d.MaxSteps = maxSteps
+	d.MaxNodeVisits = maxNodeVisits
+	return d
+}
+
+// stepLimits returns the loop guard limits, filling zero values with the defaults
+func (d ExecutionDeadlines) stepLimits() (maxSteps, maxNodeVisits int) {
+	maxSteps, maxNodeVisits = d.MaxSteps, d.MaxNodeVisits
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxFlowSteps
+	}
+	if maxNodeVisits <= 0 {
+		maxNodeVisits = DefaultMaxNodeVisits
+	}
+	return maxSteps, maxNodeVisits
+}
+
+// flowRunContext starts a flow run detached from the request that triggered it
+func (d ExecutionDeadlines) flowRunContext() (context.Context, context.CancelFunc) {
+	return withDeadline(context.Background(), d.FlowRun)
+}
+
+func (d ExecutionDeadlines) nodeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withDeadline(ctx, d.Node)
+}
+
+func (d ExecutionDeadlines) externalCallContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withDeadline(ctx, d.ExternalCall)
+}
+
+// withDeadline never extends a deadline the parent already has; zero means no extra limit
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineExceeded reports whether err or ctx show that an execution deadline fired
+func deadlineExceeded(ctx context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// saveProgressContext is used to persist partial progress after ctx has expired
+func saveProgressContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), progressSaveTimeout)
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunContext returns a context for processing one inbound message in the background,
+// bounded by the flow run deadline. Callers must call cancel when processing ends.
+func (s *FlowProcessorService) RunContext() (context.Context, context.CancelFunc) {
+	return s.deadlines.flowRunContext()
+}
+
+// persistPartialProgress parks a conversation at the last node reached when a deadline fires,
+// so the next inbound message resumes from there instead of restarting the flow
+func persistPartialProgress(ctx context.Context, state *ConversationStateMachine, conversationID, nodeID string) {
+	saveCtx, cancel := saveProgressContext(ctx)
+	defer cancel()
+
+	if err := state.UpdateState(saveCtx, conversationID, models.ConversationStateWaiting, nodeID, nil); err != nil {
+		log.Printf("❌ Failed to save progress at node %s after deadline: %v", nodeID, err)
+		return
+	}
+	log.Printf("⏱️  Deadline exceeded, progress saved at node %s", nodeID)
+}
@@ -15,6 +15,9 @@ type DebounceService struct {
 	conversationRepo *repository.ConversationRepository
 	whatsappService  *WhatsAppService
 	aiService        *AIService
+	costs            *CostRecorder
+	messages         *MessageRecorder
+	events           *EventWebhookService
 }
 
 // NewDebounceService creates a new debounce service
@@ -23,12 +26,18 @@ func NewDebounceService(
 	conversationRepo *repository.ConversationRepository,
 	whatsappService *WhatsAppService,
 	aiService *AIService,
+	costRepo *repository.CostLedgerRepository,
+	messages *MessageRecorder,
+	events *EventWebhookService,
 ) *DebounceService {
 	return &DebounceService{
 		deviceRepo:       deviceRepo,
 		conversationRepo: conversationRepo,
 		whatsappService:  whatsappService,
 		aiService:        aiService,
+		costs:            NewCostRecorder(costRepo),
+		messages:         messages,
+		events:           events,
 	}
 }
 
@@ -101,15 +110,18 @@ func (s *DebounceService) ProcessAndRespond(ctx context.Context, deviceID string
 		apiKey = *device.APIKey
 	}
 
-	// Get system prompt/intro from device (DeviceSetting doesn't have Intro, use nil for now)
+	// System prompt comes from the device persona (nil when none is set)
 	var systemPrompt *string
-	// TODO: Check if there's a system prompt field in device settings
+	if persona := personaPromptSection(device.AIPersona); persona != "" {
+		systemPrompt = &persona
+	}
 
 	// 7. Call AI to generate response
-	aiResponse, err := s.callAI(ctx, provider, model, apiKey, conversationHistory, systemPrompt)
+	aiResponse, usage, err := s.callAI(ctx, provider, model, apiKey, conversationHistory, systemPrompt)
 	if err != nil {
 		return fmt.Errorf("AI processing failed: %w", err)
 	}
+	s.costs.RecordAITokens(ctx, deviceID, phone, conversation.FlowID, device.APIKeyOption, usage, nil)
 
 	// 8. Add AI response to conversation history
 	conversationHistory = append(conversationHistory, models.AIMessage{
@@ -123,8 +135,10 @@ func (s *DebounceService) ProcessAndRespond(ctx context.Context, deviceID string
 	conversation.ConvCurrent = &combinedMessage
 
 	// Save conversation to database
-	if conversation.IDProspect == nil {
+	created := conversation.IDProspect == nil
+	if created {
 		// Create new conversation
+		conversation.IsTest = device.Sandbox
 		err = s.conversationRepo.CreateConversation(ctx, conversation)
 	} else {
 		// Update existing conversation
@@ -134,6 +148,14 @@ func (s *DebounceService) ProcessAndRespond(ctx context.Context, deviceID string
 	if err != nil {
 		// Log error but don't fail the whole operation
 		fmt.Printf("Warning: Failed to save conversation history: %v\n", err)
+	} else if conversation.IDProspect != nil {
+		conversationID := fmt.Sprintf("%d", *conversation.IDProspect)
+		s.messages.Record(ctx, newConversationMessage(models.AssignmentSourceAI, conversationID, deviceID, "User", combinedMessage))
+		s.messages.Record(ctx, newConversationMessage(models.AssignmentSourceAI, conversationID, deviceID, "Bot", aiResponse))
+		if created {
+			s.events.Emit(ctx, getStringValue(device.UserID), deviceID, models.EventConversationCreated,
+				conversationEventData("ai_whatsapp", conversationID, conversation))
+		}
 	}
 
 	// 10. Send WhatsApp response using existing WhatsAppService
@@ -146,7 +168,7 @@ func (s *DebounceService) ProcessAndRespond(ctx context.Context, deviceID string
 }
 
 // callAI calls the AI service to generate a response
-func (s *DebounceService) callAI(ctx context.Context, provider models.AIProvider, model models.AIModel, apiKey string, messages []models.AIMessage, systemPrompt *string) (string, error) {
+func (s *DebounceService) callAI(ctx context.Context, provider models.AIProvider, model models.AIModel, apiKey string, messages []models.AIMessage, systemPrompt *string) (string, *models.TokenUsage, error) {
 	// Build AI completion request
 	req := &models.AICompletionRequest{
 		Provider:     provider,
@@ -163,14 +185,14 @@ func (s *DebounceService) callAI(ctx context.Context, provider models.AIProvider
 	// Call the public GenerateCompletion method
 	result, err := s.aiService.GenerateCompletion(ctx, userID, req)
 	if err != nil {
-		return "", fmt.Errorf("AI generation failed: %w", err)
+		return "", nil, fmt.Errorf("AI generation failed: %w", err)
 	}
 
 	if !result.Success {
-		return "", fmt.Errorf("AI API error: %s - %s", result.Message, result.Error)
+		return "", nil, fmt.Errorf("AI API error: %s - %s", result.Message, result.Error)
 	}
 
-	return result.Content, nil
+	return result.Content, result.Usage, nil
 }
 
 // parseConversationHistory parses "User: message\nBot: reply" format
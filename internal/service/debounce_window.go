@@ -0,0 +1,88 @@
+package service
+
+import (
+	"chatbot-automation/internal/models"
+	"context"
+	"fmt"
+	"math"
+)
+
+// The debounce window grows from the device's minimum towards its maximum with whichever is
+// higher of load and burst, so idle devices reply fast and busy devices or rapid-fire senders
+// get their messages combined into one AI call.
+const (
+	// debounceLightQueueDepth and below is light load (minimum window)
+	debounceLightQueueDepth = 5
+	// debounceHeavyQueueDepth and above is heavy load (maximum window)
+	debounceHeavyQueueDepth = 50
+	// debounceRapidFireMessages recent messages from one sender count as full rapid-fire
+	debounceRapidFireMessages = 5
+
+	// Limits for per-device debounce bounds
+	minDebounceBoundMs = 500
+	maxDebounceBoundMs = 120000
+)
+
+// debounceLoad maps the number of senders waiting on a device to 0..1
+func debounceLoad(queueDepth int) float64 {
+	return clampUnit(float64(queueDepth-debounceLightQueueDepth) / float64(debounceHeavyQueueDepth-debounceLightQueueDepth))
+}
+
+// debounceBurst maps a sender's recent message count (including the current one) to 0..1
+func debounceBurst(senderRecent int) float64 {
+	return clampUnit(float64(senderRecent-1) / float64(debounceRapidFireMessages-1))
+}
+
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// debounceWindowMs interpolates between the bounds by the stronger of load and burst
+func debounceWindowMs(minMs, maxMs int, load, burst float64) int {
+	factor := math.Max(load, burst)
+	return minMs + int(math.Round(float64(maxMs-minMs)*factor))
+}
+
+// validateDebounceBounds checks per-device bounds (nil or 0 = default).
+// Returns an error message for the client, or "" when valid.
+func validateDebounceBounds(minMs, maxMs *int) string {
+	for _, bound := range []*int{minMs, maxMs} {
+		if bound != nil && *bound != 0 && (*bound < minDebounceBoundMs || *bound > maxDebounceBoundMs) {
+			return fmt.Sprintf("debounce_min_ms and debounce_max_ms must be between %d and %d", minDebounceBoundMs, maxDebounceBoundMs)
+		}
+	}
+
+	device := &models.DeviceSetting{DebounceMinMs: minMs, DebounceMaxMs: maxMs}
+	effectiveMin, _ := device.EffectiveDebounceBounds()
+	if maxMs != nil && *maxMs != 0 && *maxMs < effectiveMin {
+		return "debounce_max_ms cannot be lower than debounce_min_ms"
+	}
+	return ""
+}
+
+// GetDebounceWindow returns how long the debouncer should wait for more messages from a sender
+func (s *DebounceService) GetDebounceWindow(ctx context.Context, req *models.DebounceWindowRequest) (*models.DebounceWindowResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, req.DeviceID)
+	if err != nil || device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, req.DeviceID)
+	}
+	if err != nil || device == nil {
+		return &models.DebounceWindowResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+
+	minMs, maxMs := device.EffectiveDebounceBounds()
+	load := debounceLoad(req.QueueDepth)
+	burst := debounceBurst(req.SenderRecent)
+
+	return &models.DebounceWindowResponse{
+		Success:  true,
+		WindowMs: debounceWindowMs(minMs, maxMs, load, burst),
+		MinMs:    minMs,
+		MaxMs:    maxMs,
+		Load:     load,
+		Burst:    burst,
+	}, nil
+}
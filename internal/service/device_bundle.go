@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// DeviceBundleService exports a device's configuration and imports it into another device
+type DeviceBundleService struct {
+	devices    *DeviceService
+	deviceRepo *repository.DeviceRepository
+	stageRepo  *repository.StageRepository
+	flowRepo   *repository.FlowRepository
+}
+
+// NewDeviceBundleService creates a new device bundle service
+func NewDeviceBundleService(devices *DeviceService, deviceRepo *repository.DeviceRepository, stageRepo *repository.StageRepository, flowRepo *repository.FlowRepository) *DeviceBundleService {
+	return &DeviceBundleService{
+		devices:    devices,
+		deviceRepo: deviceRepo,
+		stageRepo:  stageRepo,
+		flowRepo:   flowRepo,
+	}
+}
+
+// ownedDevice loads a device by its row ID and checks the user owns it
+func (s *DeviceBundleService) ownedDevice(ctx context.Context, userID, deviceID string) (*models.DeviceSetting, string) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, "Device not found"
+	}
+	if device.UserID == nil || *device.UserID != userID {
+		return nil, "Access denied"
+	}
+	return device, ""
+}
+
+// ExportConfig builds a bundle from a device the user owns
+func (s *DeviceBundleService) ExportConfig(ctx context.Context, userID, deviceID string) (*models.DeviceConfigExportResponse, error) {
+	device, msg := s.ownedDevice(ctx, userID, deviceID)
+	if device == nil {
+		return &models.DeviceConfigExportResponse{Success: false, Message: msg}, nil
+	}
+
+	idDevice := getStringValue(device.IDDevice)
+	bundle := &models.DeviceConfigBundle{
+		Version:        models.DeviceBundleVersion,
+		ExportedAt:     time.Now(),
+		SourceIDDevice: idDevice,
+		Settings: models.DeviceBundleSettings{
+			Provider:          device.Provider,
+			APIURL:            device.APIURL,
+			APIKeyOption:      device.APIKeyOption,
+			MaxHistoryEntries: device.MaxHistoryEntries,
+			FailoverNotice:    device.FailoverNotice,
+			SendFee:           device.SendFee,
+			WorkingLanguage:   device.WorkingLanguage,
+			Timezone:          device.Timezone,
+			AIPersona:         device.AIPersona,
+			ListColumns:       device.ListColumns,
+			DebounceMinMs:     device.DebounceMinMs,
+			DebounceMaxMs:     device.DebounceMaxMs,
+
+			DefaultPromptTemplate: device.DefaultPromptTemplate,
+			AIFallbackModel:       device.AIFallbackModel,
+			SendRatePerMinute:     device.SendRatePerMinute,
+			TypingIndicator:       device.TypingIndicator,
+			TypingCharsPerSecond:  device.TypingCharsPerSecond,
+			SendDelayMinMs:        device.SendDelayMinMs,
+			SendDelayMaxMs:        device.SendDelayMaxMs,
+			QuietHoursStart:       device.QuietHoursStart,
+			QuietHoursEnd:         device.QuietHoursEnd,
+			BusinessHours:         device.BusinessHours,
+			ClosedMode:            device.ClosedMode,
+			PhoneCountry:          device.PhoneCountry,
+			AwayFlowRef:           getStringValue(device.AwayFlowID),
+		},
+		Stages: []models.DeviceBundleStage{},
+		Flows:  []models.DeviceBundleFlow{},
+	}
+
+	if idDevice == "" {
+		return &models.DeviceConfigExportResponse{Success: true, Bundle: bundle}, nil
+	}
+
+	stages, err := s.stageRepo.GetStageValuesByDevice(ctx, idDevice)
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range stages {
+		bundle.Stages = append(bundle.Stages, models.DeviceBundleStage{
+			Stage:         stage.Stage,
+			TypeInputData: stage.TypeInputData,
+			ColumnsData:   stage.ColumnsData,
+			InputHardCode: stage.InputHardCode,
+		})
+	}
+
+	flows, err := s.flowRepo.GetFlowsByDeviceID(ctx, idDevice)
+	if err != nil {
+		return nil, err
+	}
+	for _, flow := range flows {
+		bundle.Flows = append(bundle.Flows, models.DeviceBundleFlow{
+			Ref:                  flow.ID,
+			Name:                 flow.Name,
+			Niche:                flow.Niche,
+			NodesData:            flow.NodesData,
+			CompletionPolicy:     flow.CompletionPolicy,
+			AfterSalesRef:        getStringValue(flow.AfterSalesFlowID),
+			ReentryCooldownHours: flow.ReentryCooldownHours,
+			NormalizeMalay:       flow.NormalizeMalay,
+			Triggers:             flow.Triggers,
+			IsActive:             flow.IsActive,
+			Priority:             flow.Priority,
+		})
+	}
+
+	return &models.DeviceConfigExportResponse{Success: true, Bundle: bundle}, nil
+}
+
+// ImportConfig applies a bundle to a device the user owns: settings are overwritten, stage configs
+// are added unless the device already has that stage and column, and flows are created as new flows
+func (s *DeviceBundleService) ImportConfig(ctx context.Context, userID, deviceID string, req *models.DeviceConfigImportRequest) (*models.DeviceConfigImportResponse, error) {
+	bundle := &req.Bundle
+	if bundle.Version != models.DeviceBundleVersion {
+		return &models.DeviceConfigImportResponse{
+			Success: false,
+			Message: fmt.Sprintf("unsupported bundle version %d", bundle.Version),
+		}, nil
+	}
+
+	device, msg := s.ownedDevice(ctx, userID, deviceID)
+	if device == nil {
+		return &models.DeviceConfigImportResponse{Success: false, Message: msg}, nil
+	}
+
+	idDevice := getStringValue(device.IDDevice)
+	if idDevice == "" && (!req.SkipStages || !req.SkipFlows) {
+		return &models.DeviceConfigImportResponse{
+			Success: false,
+			Message: "Target device has no id_device; set it before importing stages and flows",
+		}, nil
+	}
+
+	resp := &models.DeviceConfigImportResponse{Success: true}
+
+	// Settings go through the normal device update so the same validation applies
+	settings := bundle.Settings
+	update := &models.UpdateDeviceRequest{
+		MaxHistoryEntries: settings.MaxHistoryEntries,
+		FailoverNotice:    settings.FailoverNotice,
+		SendFee:           settings.SendFee,
+		WorkingLanguage:   settings.WorkingLanguage,
+		Timezone:          settings.Timezone,
+		AIPersona:         settings.AIPersona,
+		DebounceMinMs:     settings.DebounceMinMs,
+		DebounceMaxMs:     settings.DebounceMaxMs,
+
+		DefaultPromptTemplate: settings.DefaultPromptTemplate,
+		AIFallbackModel:       settings.AIFallbackModel,
+		SendRatePerMinute:     settings.SendRatePerMinute,
+		TypingIndicator:       &settings.TypingIndicator,
+		TypingCharsPerSecond:  settings.TypingCharsPerSecond,
+		SendDelayMinMs:        settings.SendDelayMinMs,
+		SendDelayMaxMs:        settings.SendDelayMaxMs,
+		QuietHoursStart:       settings.QuietHoursStart,
+		QuietHoursEnd:         settings.QuietHoursEnd,
+		BusinessHours:         settings.BusinessHours,
+		ClosedMode:            settings.ClosedMode,
+		PhoneCountry:          settings.PhoneCountry,
+	}
+	if settings.Provider != "" {
+		update.Provider = &settings.Provider
+	}
+	if settings.APIKeyOption != "" {
+		update.APIKeyOption = &settings.APIKeyOption
+	}
+	if settings.ListColumns != nil {
+		update.ListColumns = &settings.ListColumns
+	}
+	updated, err := s.devices.UpdateDevice(ctx, userID, device.ID, update)
+	if err != nil {
+		return nil, err
+	}
+	if !updated.Success && updated.Message != "No fields to update" {
+		return &models.DeviceConfigImportResponse{Success: false, Message: updated.Message}, nil
+	}
+	if settings.APIURL != nil {
+		if err := s.deviceRepo.UpdateDevice(ctx, device.ID, map[string]interface{}{"api_url": *settings.APIURL}); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("api_url: %v", err))
+		}
+	}
+	resp.SettingsApplied = true
+
+	if !req.SkipStages {
+		s.importStages(ctx, idDevice, bundle.Stages, resp)
+	}
+	if !req.SkipFlows {
+		created := s.importFlows(ctx, idDevice, bundle.Flows, resp)
+		if awayFlowID, ok := created[settings.AwayFlowRef]; ok && settings.AwayFlowRef != "" {
+			if err := s.deviceRepo.UpdateDevice(ctx, device.ID, map[string]interface{}{"away_flow_id": awayFlowID}); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("away_flow_id: %v", err))
+			}
+		}
+	}
+
+	resp.Message = fmt.Sprintf("Imported settings, %d stage configs and %d flows", resp.StagesCreated, resp.FlowsCreated)
+	return resp, nil
+}
+
+func (s *DeviceBundleService) importStages(ctx context.Context, idDevice string, stages []models.DeviceBundleStage, resp *models.DeviceConfigImportResponse) {
+	existing, err := s.stageRepo.GetStageValuesByDevice(ctx, idDevice)
+	if err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("stages: %v", err))
+		return
+	}
+
+	configured := make(map[string]bool, len(existing))
+	stageKey := func(stage, column string) string {
+		return strings.ToLower(strings.TrimSpace(stage)) + "|" + normalizeColumnName(column)
+	}
+	for _, stage := range existing {
+		configured[stageKey(stage.Stage, stage.ColumnsData)] = true
+	}
+
+	for _, stage := range stages {
+		key := stageKey(stage.Stage, stage.ColumnsData)
+		if configured[key] {
+			resp.StagesSkipped++
+			continue
+		}
+
+		value := &models.StageValue{
+			IDDevice:      idDevice,
+			Stage:         stage.Stage,
+			TypeInputData: stage.TypeInputData,
+			ColumnsData:   stage.ColumnsData,
+			InputHardCode: stage.InputHardCode,
+		}
+		if err := s.stageRepo.CreateStageValue(ctx, value); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("stage %s: %v", stage.Stage, err))
+			continue
+		}
+		configured[key] = true
+		resp.StagesCreated++
+	}
+}
+
+// importFlows creates the bundled flows and returns their new IDs by bundle ref
+func (s *DeviceBundleService) importFlows(ctx context.Context, idDevice string, flows []models.DeviceBundleFlow, resp *models.DeviceConfigImportResponse) map[string]string {
+	// Bundle ref -> new flow ID, so after-sales and away flow links point at the imported copies
+	created := make(map[string]string, len(flows))
+	for _, bundled := range flows {
+		flow := &models.ChatbotFlow{
+			IDDevice:             idDevice,
+			Name:                 bundled.Name,
+			Niche:                bundled.Niche,
+			NodesData:            bundled.NodesData,
+			CompletionPolicy:     bundled.CompletionPolicy,
+			ReentryCooldownHours: bundled.ReentryCooldownHours,
+			NormalizeMalay:       bundled.NormalizeMalay,
+			Triggers:             bundled.Triggers,
+			IsActive:             bundled.IsActive,
+			Priority:             bundled.Priority,
+		}
+		if err := s.flowRepo.CreateFlow(ctx, flow); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("flow %s: %v", bundled.Name, err))
+			continue
+		}
+		if bundled.Ref != "" {
+			created[bundled.Ref] = flow.ID
+		}
+		resp.FlowsCreated++
+	}
+
+	for _, bundled := range flows {
+		if bundled.AfterSalesRef == "" {
+			continue
+		}
+		flowID, ok := created[bundled.Ref]
+		afterSalesID, linked := created[bundled.AfterSalesRef]
+		if !ok || !linked {
+			continue
+		}
+		if err := s.flowRepo.UpdateFlow(ctx, flowID, map[string]interface{}{"after_sales_flow_id": afterSalesID}); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("flow %s after-sales link: %v", bundled.Name, err))
+		}
+	}
+	return created
+}
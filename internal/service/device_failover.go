@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// failoverNotices remembers which prospects were already told about the alternate number,
+// keyed by primary device ID then recipient. Cleared on fail-back.
+type failoverNotices struct {
+	mu   sync.Mutex
+	sent map[string]map[string]bool
+}
+
+// markSent records a notice for a recipient and reports whether it was newly recorded
+func (n *failoverNotices) markSent(primaryID, to string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sent == nil {
+		n.sent = make(map[string]map[string]bool)
+	}
+	if n.sent[primaryID] == nil {
+		n.sent[primaryID] = make(map[string]bool)
+	}
+	if n.sent[primaryID][to] {
+		return false
+	}
+	n.sent[primaryID][to] = true
+	return true
+}
+
+// reset forgets all notices for a primary device
+func (n *failoverNotices) reset(primaryID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.sent, primaryID)
+}
+
+// resolveFailover returns the device that should send for a primary device.
+// While the primary is disconnected and a healthy backup is linked, the backup is returned.
+// If the backup uses a different number, the prospect gets the failover notice once.
+func (s *WhatsAppService) resolveFailover(ctx context.Context, device *models.DeviceSetting, deviceID string, to string) (*models.DeviceSetting, string, error) {
+	if !device.IsDisconnected() || device.BackupDeviceID == nil || *device.BackupDeviceID == "" {
+		return device, deviceID, nil
+	}
+
+	backup, err := s.deviceRepo.GetDeviceByID(ctx, *device.BackupDeviceID)
+	if err != nil || backup == nil {
+		log.Printf("⚠️  Primary device %s is disconnected but backup %s could not be loaded: %v", deviceID, *device.BackupDeviceID, err)
+		return device, deviceID, nil
+	}
+
+	if backup.IsDisconnected() {
+		log.Printf("⚠️  Primary device %s and its backup are both disconnected, trying primary", deviceID)
+		return device, deviceID, nil
+	}
+
+	backupID := backup.ID
+	if backup.DeviceID != nil && *backup.DeviceID != "" {
+		backupID = *backup.DeviceID
+	}
+
+	log.Printf("🔀 Failover: sending for device %s through backup %s", deviceID, backupID)
+
+	samePhone := getStringValue(device.PhoneNumber) != "" && getStringValue(device.PhoneNumber) == getStringValue(backup.PhoneNumber)
+	notice := strings.TrimSpace(getStringValue(device.FailoverNotice))
+	if !samePhone && notice != "" && s.failoverNotices.markSent(device.ID, to) {
+		provider, err := s.providerForDevice(backup, backupID)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := provider.SendMessage(ctx, &models.SendMessageRequest{To: to, Body: notice, Type: "text"}); err != nil {
+			log.Printf("⚠️  Failed to send failover notice to %s: %v", to, err)
+		}
+	}
+
+	return backup, backupID, nil
+}
+
+// DeviceHealthMonitor polls every device's provider session status, records connection_status so
+// sends of devices with a backup can fail over and back automatically, and times each check for
+// the device's health stats
+type DeviceHealthMonitor struct {
+	deviceRepo      *repository.DeviceRepository
+	whatsappService *WhatsAppService
+	httpClient      *http.Client
+}
+
+// NewDeviceHealthMonitor creates a new device health monitor
+func NewDeviceHealthMonitor(deviceRepo *repository.DeviceRepository, whatsappService *WhatsAppService) *DeviceHealthMonitor {
+	return &DeviceHealthMonitor{
+		deviceRepo:      deviceRepo,
+		whatsappService: whatsappService,
+		httpClient:      &http.Client{Timeout: heartbeatTimeout},
+	}
+}
+
+// Start checks device health immediately and then every interval until ctx is cancelled
+func (m *DeviceHealthMonitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.CheckAll(ctx); err != nil {
+				log.Printf("⚠️  Device health check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckAll refreshes connection_status for every device
+func (m *DeviceHealthMonitor) CheckAll(ctx context.Context) error {
+	devices, err := m.deviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load devices: %w", err)
+	}
+
+	for i := range devices {
+		m.checkDevice(ctx, &devices[i])
+	}
+
+	return nil
+}
+
+// checkDevice queries one device's session status, stores the result and pings the heartbeat URL
+func (m *DeviceHealthMonitor) checkDevice(ctx context.Context, device *models.DeviceSetting) {
+	deviceID := device.ID
+	if device.DeviceID != nil && *device.DeviceID != "" {
+		deviceID = *device.DeviceID
+	}
+
+	status := models.DeviceStatusDisconnected
+	provider, err := m.whatsappService.providerForDevice(device, deviceID)
+	if err == nil {
+		started := time.Now()
+		resp, statusErr := provider.GetSessionStatus(ctx, deviceID)
+		m.whatsappService.health.record(device.ID, providerCallStatus, time.Since(started), statusErr)
+		if statusErr == nil && resp != nil && resp.Session != nil && resp.Session.Status == models.DeviceStatusConnected {
+			status = models.DeviceStatusConnected
+		}
+	}
+
+	previous := getStringValue(device.ConnectionStatus)
+	now := time.Now()
+	m.sendHeartbeat(device, deviceID, status, previous, now)
+
+	updates := map[string]interface{}{
+		"connection_status": status,
+		"status_checked_at": now,
+	}
+	if err := m.deviceRepo.UpdateDevice(ctx, device.ID, updates); err != nil {
+		log.Printf("⚠️  Failed to store health status for device %s: %v", device.ID, err)
+		return
+	}
+
+	if previous == status {
+		return
+	}
+
+	if status == models.DeviceStatusDisconnected {
+		log.Printf("🔴 Device %s marked disconnected", deviceID)
+	} else {
+		log.Printf("🟢 Device %s connected again", deviceID)
+		// Fail-back: the primary sends again, so a later outage should re-notify prospects
+		m.whatsappService.failoverNotices.reset(device.ID)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// GetDeviceHealth returns a device's last health check with its provider latency and error rates
+func (s *DeviceService) GetDeviceHealth(ctx context.Context, userID, deviceID string) (*models.DeviceHealthResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return &models.DeviceHealthResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+
+	if device.UserID == nil || *device.UserID != userID {
+		return &models.DeviceHealthResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	now := time.Now()
+	health := &models.DeviceHealth{
+		DeviceID:         device.ID,
+		Provider:         device.Provider,
+		ConnectionStatus: getStringValue(device.ConnectionStatus),
+		StatusCheckedAt:  device.StatusCheckedAt,
+		WindowMinutes:    int(providerHealthWindow / time.Minute),
+		StatusChecks:     s.whatsappService.health.stats(device.ID, providerCallStatus, now),
+		Sends:            s.whatsappService.health.stats(device.ID, providerCallSend, now),
+		BackupDeviceID:   device.BackupDeviceID,
+	}
+
+	// Mirrors resolveFailover: sends move to a connected backup while the primary is disconnected
+	if device.IsDisconnected() && device.BackupDeviceID != nil && *device.BackupDeviceID != "" {
+		if backup, err := s.deviceRepo.GetDeviceByID(ctx, *device.BackupDeviceID); err == nil && backup != nil {
+			health.FailingOver = !backup.IsDisconnected()
+		}
+	}
+
+	return &models.DeviceHealthResponse{
+		Success: true,
+		Health:  health,
+	}, nil
+}
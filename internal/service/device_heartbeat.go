@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// heartbeatTimeout bounds one heartbeat request so a slow monitor cannot stall health checks
+const heartbeatTimeout = 10 * time.Second
+
+// DeviceHeartbeat is the JSON body POSTed to a device's heartbeat URL
+type DeviceHeartbeat struct {
+	DeviceID       string    `json:"device_id"`
+	IDDevice       string    `json:"id_device,omitempty"`
+	PhoneNumber    string    `json:"phone_number,omitempty"`
+	Provider       string    `json:"provider"`
+	Status         string    `json:"status"` // connected, disconnected
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	Changed        bool      `json:"changed"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// hasHeartbeat reports whether a device has an external heartbeat URL configured
+func hasHeartbeat(device *models.DeviceSetting) bool {
+	return device.HeartbeatURL != nil && *device.HeartbeatURL != ""
+}
+
+// sendHeartbeat POSTs the device status to its heartbeat URL in the background.
+// Failures are only logged; the next check pings again.
+func (m *DeviceHealthMonitor) sendHeartbeat(device *models.DeviceSetting, deviceID, status, previous string, checkedAt time.Time) {
+	if !hasHeartbeat(device) {
+		return
+	}
+
+	heartbeat := DeviceHeartbeat{
+		DeviceID:       deviceID,
+		IDDevice:       getStringValue(device.IDDevice),
+		PhoneNumber:    getStringValue(device.PhoneNumber),
+		Provider:       device.Provider,
+		Status:         status,
+		PreviousStatus: previous,
+		Changed:        previous != "" && previous != status,
+		CheckedAt:      checkedAt,
+	}
+	endpoint := *device.HeartbeatURL
+
+	go func() {
+		payload, err := json.Marshal(heartbeat)
+		if err != nil {
+			log.Printf("⚠️  Failed to marshal heartbeat for device %s: %v", deviceID, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), heartbeatTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payload))
+		if err != nil {
+			log.Printf("⚠️  Failed to create heartbeat request for device %s: %v", deviceID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := m.httpClient.Do(req)
+		if err != nil {
+			log.Printf("⚠️  Heartbeat for device %s failed: %v", deviceID, err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			log.Printf("⚠️  Heartbeat for device %s returned HTTP %d", deviceID, resp.StatusCode)
+		}
+	}()
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// Profile limits enforced by WhatsApp
+const (
+	maxProfileNameLength  = 25
+	maxProfileAboutLength = 139
+)
+
+// deviceProfileManager returns the profile API of a device the user owns, or a failed response
+func (s *DeviceService) deviceProfileManager(ctx context.Context, userID, deviceID string) (whatsapp.ProfileManager, *models.DeviceProfileResponse) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, &models.DeviceProfileResponse{
+			Success: false,
+			Message: "Device not found",
+		}
+	}
+
+	if device.UserID == nil || *device.UserID != userID {
+		return nil, &models.DeviceProfileResponse{
+			Success: false,
+			Message: "Access denied",
+		}
+	}
+
+	if device.Instance == nil || *device.Instance == "" {
+		return nil, &models.DeviceProfileResponse{
+			Success: false,
+			Message: "Device not generated yet. Please generate device first.",
+		}
+	}
+
+	provider, err := s.whatsappService.providerForDevice(device, *device.Instance)
+	if err != nil {
+		return nil, &models.DeviceProfileResponse{
+			Success: false,
+			Message: err.Error(),
+		}
+	}
+
+	manager, ok := whatsapp.AsProfileManager(provider)
+	if !ok {
+		return nil, &models.DeviceProfileResponse{
+			Success: false,
+			Message: fmt.Sprintf("Provider %s does not support profile management", provider.GetProviderName()),
+		}
+	}
+
+	return manager, nil
+}
+
+// GetDeviceProfile returns the display name, about text and photo of a device's connected number
+func (s *DeviceService) GetDeviceProfile(ctx context.Context, userID, deviceID string) (*models.DeviceProfileResponse, error) {
+	manager, failed := s.deviceProfileManager(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	profile, err := manager.GetProfile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &models.DeviceProfileResponse{
+		Success: true,
+		Message: "Profile retrieved successfully",
+		Profile: profile,
+	}, nil
+}
+
+// UpdateDeviceProfile changes the given profile fields of a device's connected number and returns
+// the profile as the provider reports it afterwards
+func (s *DeviceService) UpdateDeviceProfile(ctx context.Context, userID, deviceID string, req *models.UpdateDeviceProfileRequest) (*models.DeviceProfileResponse, error) {
+	if req.Name == nil && req.About == nil && req.PictureURL == nil {
+		return &models.DeviceProfileResponse{
+			Success: false,
+			Message: "Nothing to update: set name, about or picture_url",
+		}, nil
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len([]rune(name)) > maxProfileNameLength {
+			return &models.DeviceProfileResponse{
+				Success: false,
+				Message: fmt.Sprintf("name must be 1 to %d characters", maxProfileNameLength),
+			}, nil
+		}
+		req.Name = &name
+	}
+	if req.About != nil && len([]rune(*req.About)) > maxProfileAboutLength {
+		return &models.DeviceProfileResponse{
+			Success: false,
+			Message: fmt.Sprintf("about must be at most %d characters", maxProfileAboutLength),
+		}, nil
+	}
+	if req.PictureURL != nil && *req.PictureURL != "" && !validHeartbeatURL(*req.PictureURL) {
+		return &models.DeviceProfileResponse{
+			Success: false,
+			Message: "picture_url must be an http(s) URL",
+		}, nil
+	}
+
+	manager, failed := s.deviceProfileManager(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	if req.Name != nil {
+		if err := manager.SetProfileName(ctx, *req.Name); err != nil {
+			return nil, fmt.Errorf("failed to set profile name: %w", err)
+		}
+	}
+	if req.About != nil {
+		if err := manager.SetProfileAbout(ctx, *req.About); err != nil {
+			return nil, fmt.Errorf("failed to set profile about: %w", err)
+		}
+	}
+	if req.PictureURL != nil {
+		if err := manager.SetProfilePicture(ctx, *req.PictureURL); err != nil {
+			return nil, fmt.Errorf("failed to set profile picture: %w", err)
+		}
+	}
+	log.Printf("🪪 Updated WhatsApp profile of device %s", deviceID)
+
+	// The provider may take a moment to reflect the change; the update itself already succeeded
+	profile, err := manager.GetProfile(ctx)
+	if err != nil {
+		log.Printf("⚠️  Failed to read back profile of device %s: %v", deviceID, err)
+	}
+
+	return &models.DeviceProfileResponse{
+		Success: true,
+		Message: "Profile updated successfully",
+		Profile: profile,
+	}, nil
+}
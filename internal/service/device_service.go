@@ -5,17 +5,26 @@ import (
 	"chatbot-automation/internal/repository"
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 )
 
 // DeviceService handles device business logic
 type DeviceService struct {
-	deviceRepo *repository.DeviceRepository
+	deviceRepo      *repository.DeviceRepository
+	sandboxRepo     *repository.SandboxMessageRepository
+	whatsappService *WhatsAppService // provider clients for profile management
+	team            *TeamService     // members who may view or edit shared devices (nil = owners only)
 }
 
 // NewDeviceService creates a new device service
-func NewDeviceService(deviceRepo *repository.DeviceRepository) *DeviceService {
+func NewDeviceService(deviceRepo *repository.DeviceRepository, sandboxRepo *repository.SandboxMessageRepository, whatsappService *WhatsAppService, team *TeamService) *DeviceService {
 	return &DeviceService{
-		deviceRepo: deviceRepo,
+		deviceRepo:      deviceRepo,
+		sandboxRepo:     sandboxRepo,
+		whatsappService: whatsappService,
+		team:            team,
 	}
 }
 
@@ -26,12 +35,13 @@ func (s *DeviceService) CreateDevice(ctx context.Context, userID string, req *mo
 		"waha":       true,
 		"wablas":     true,
 		"whacenter":  true,
+		"custom":     true,
 	}
 
 	if !validProviders[req.Provider] {
 		return &models.DeviceResponse{
 			Success: false,
-			Message: "Invalid provider. Must be one of: waha, wablas, whacenter",
+			Message: "Invalid provider. Must be one of: waha, wablas, whacenter, custom",
 		}, nil
 	}
 
@@ -71,6 +81,193 @@ func (s *DeviceService) CreateDevice(ctx context.Context, userID string, req *mo
 		deviceID = &req.DeviceID
 	}
 
+	if req.BackupDeviceID != nil && *req.BackupDeviceID != "" {
+		if msg := s.validateBackupDevice(ctx, userID, "", *req.BackupDeviceID); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+	}
+	if req.SendFee != nil && *req.SendFee < 0 {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: "send_fee cannot be negative",
+		}, nil
+	}
+	if msg := validateListColumns(req.ListColumns); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.HeartbeatURL != nil && *req.HeartbeatURL == "" {
+		req.HeartbeatURL = nil
+	}
+	if req.HeartbeatURL != nil && !validHeartbeatURL(*req.HeartbeatURL) {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: "heartbeat_url must be an http or https URL",
+		}, nil
+	}
+	if msg := validateDebounceBounds(req.DebounceMinMs, req.DebounceMaxMs); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.TrackingDomain != nil && *req.TrackingDomain == "" {
+		req.TrackingDomain = nil
+	}
+	if req.TrackingDomain != nil && !validHeartbeatURL(*req.TrackingDomain) {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: "tracking_domain must be an http or https URL",
+		}, nil
+	}
+	if req.DefaultPromptTemplate != nil && *req.DefaultPromptTemplate == "" {
+		req.DefaultPromptTemplate = nil
+	}
+	if req.AIFallbackModel != nil && strings.TrimSpace(*req.AIFallbackModel) == "" {
+		req.AIFallbackModel = nil
+	}
+	if msg := validateArchiveRetention(req.ArchiveRetentionDays); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.ArchiveRetentionDays != nil && *req.ArchiveRetentionDays == 0 {
+		req.ArchiveRetentionDays = nil
+	}
+	if msg := validateSendRate(req.SendRatePerMinute); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.SendRatePerMinute != nil && *req.SendRatePerMinute == 0 {
+		req.SendRatePerMinute = nil
+	}
+	if msg := validateTypingSpeed(req.TypingCharsPerSecond); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.TypingCharsPerSecond != nil && *req.TypingCharsPerSecond == 0 {
+		req.TypingCharsPerSecond = nil
+	}
+	if msg := validateSendDelay(req.SendDelayMinMs, req.SendDelayMaxMs); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.SendDelayMinMs != nil && *req.SendDelayMinMs == 0 {
+		req.SendDelayMinMs = nil
+	}
+	if req.SendDelayMaxMs != nil && *req.SendDelayMaxMs == 0 {
+		req.SendDelayMaxMs = nil
+	}
+	if msg := validateQuietHours(getStringValue(req.QuietHoursStart), getStringValue(req.QuietHoursEnd)); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if getStringValue(req.QuietHoursStart) == "" {
+		req.QuietHoursStart, req.QuietHoursEnd = nil, nil
+	}
+	if msg := validateBusinessHours(getStringValue(req.BusinessHours)); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if msg := validateClosedMode(getStringValue(req.ClosedMode)); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	for _, value := range []**string{&req.BusinessHours, &req.ClosedMode, &req.AwayFlowID} {
+		if strings.TrimSpace(getStringValue(*value)) == "" {
+			*value = nil
+		}
+	}
+	if req.DefaultPromptTemplate != nil && !models.IsValidPromptTemplateName(*req.DefaultPromptTemplate) {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: "default_prompt_template must be a prompt template name",
+		}, nil
+	}
+	if msg := validateAIEndpoints(req.AIEndpoints); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.Provider != "custom" {
+		req.CustomProvider = nil
+	} else if msg := validateCustomProvider(req.CustomProvider); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.TelegramBotToken != nil {
+		token := strings.TrimSpace(*req.TelegramBotToken)
+		if token == "" {
+			req.TelegramBotToken = nil
+		} else if !validTelegramBotToken(token) {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: "telegram_bot_token must be a bot token from BotFather",
+			}, nil
+		} else {
+			req.TelegramBotToken = &token
+		}
+	}
+	if req.Messenger.IsEmpty() {
+		req.Messenger = nil
+	} else if msg := validateMessengerConfig(req.Messenger); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if req.Timezone != nil && *req.Timezone != "" {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: "timezone must be an IANA zone name such as Asia/Kuala_Lumpur",
+			}, nil
+		}
+	}
+	if req.PhoneCountry != nil {
+		if msg := validatePhoneCountry(*req.PhoneCountry); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if *req.PhoneCountry == "" {
+			req.PhoneCountry = nil
+		} else {
+			country := strings.ToUpper(*req.PhoneCountry)
+			req.PhoneCountry = &country
+		}
+	}
+	if req.AIPersona.IsEmpty() {
+		req.AIPersona = nil
+	} else if msg := validatePersona(req.AIPersona); msg != "" {
+		return &models.DeviceResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
 	device := &models.DeviceSetting{
 		DeviceID:     deviceID,
 		WebhookID:    &req.WebhookURL,
@@ -83,6 +280,53 @@ func (s *DeviceService) CreateDevice(ctx context.Context, userID string, req *mo
 		IDAdmin:      req.IDAdmin,
 		Instance:     req.Instance,
 		UserID:       &userID,
+		MaxHistoryEntries: req.MaxHistoryEntries,
+		BackupDeviceID:    req.BackupDeviceID,
+		FailoverNotice:    req.FailoverNotice,
+		SendFee:           req.SendFee,
+		WorkingLanguage:   req.WorkingLanguage,
+		HeartbeatURL:      req.HeartbeatURL,
+		Timezone:          req.Timezone,
+		AIPersona:         req.AIPersona,
+		ListColumns:       req.ListColumns,
+		TrackingDomain:    req.TrackingDomain,
+		DebounceMinMs:     req.DebounceMinMs,
+		DebounceMaxMs:     req.DebounceMaxMs,
+		AIEndpoints:       req.AIEndpoints,
+		DefaultPromptTemplate: req.DefaultPromptTemplate,
+		AIFallbackModel:       req.AIFallbackModel,
+		ArchiveRetentionDays:  req.ArchiveRetentionDays,
+		SendRatePerMinute:     req.SendRatePerMinute,
+		CustomProvider:        req.CustomProvider,
+		TelegramBotToken:      req.TelegramBotToken,
+		Messenger:             req.Messenger,
+		TypingCharsPerSecond:  req.TypingCharsPerSecond,
+		SendDelayMinMs:        req.SendDelayMinMs,
+		SendDelayMaxMs:        req.SendDelayMaxMs,
+		QuietHoursStart:       req.QuietHoursStart,
+		QuietHoursEnd:         req.QuietHoursEnd,
+		BusinessHours:         req.BusinessHours,
+		ClosedMode:            req.ClosedMode,
+		AwayFlowID:            req.AwayFlowID,
+		PhoneCountry:          req.PhoneCountry,
+	}
+	if req.Sandbox != nil {
+		device.Sandbox = *req.Sandbox
+	}
+	if req.EmailReplies != nil {
+		device.EmailReplies = *req.EmailReplies
+	}
+	if req.ArchiveInbound != nil {
+		device.ArchiveInbound = *req.ArchiveInbound
+	}
+	if req.StaticFollowUps != nil {
+		device.StaticFollowUps = *req.StaticFollowUps
+	}
+	if req.TypingIndicator != nil {
+		device.TypingIndicator = *req.TypingIndicator
+	}
+	if req.ShareProspectData != nil {
+		device.ShareProspectData = *req.ShareProspectData
 	}
 
 	if err := s.deviceRepo.CreateDevice(ctx, device); err != nil {
@@ -96,6 +340,35 @@ func (s *DeviceService) CreateDevice(ctx context.Context, userID string, req *mo
 	}, nil
 }
 
+// validHeartbeatURL reports whether a heartbeat URL is an absolute http(s) URL
+func validHeartbeatURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateBackupDevice checks that a backup device belongs to the user and does not form a loop.
+// Returns an error message for the client, or "" when valid.
+func (s *DeviceService) validateBackupDevice(ctx context.Context, userID, deviceID, backupID string) string {
+	if backupID == deviceID {
+		return "A device cannot be its own backup"
+	}
+
+	backup, err := s.deviceRepo.GetDeviceByID(ctx, backupID)
+	if err != nil || backup == nil {
+		return "Backup device not found"
+	}
+
+	if backup.UserID == nil || *backup.UserID != userID {
+		return "Access denied: backup device belongs to another user"
+	}
+
+	if backup.BackupDeviceID != nil && *backup.BackupDeviceID != "" {
+		return "Backup device already has its own backup configured"
+	}
+
+	return ""
+}
+
 // GetDevice retrieves a device by ID
 func (s *DeviceService) GetDevice(ctx context.Context, userID, deviceID string) (*models.DeviceResponse, error) {
 	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
@@ -107,13 +380,18 @@ func (s *DeviceService) GetDevice(ctx context.Context, userID, deviceID string)
 	}
 
 	// Check ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewDevices) {
 		return &models.DeviceResponse{
 			Success: false,
 			Message: "Access denied",
 		}, nil
 	}
 
+	// Members who may not edit the device don't see its credentials
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionEditDevices) {
+		redactDeviceSecrets(device)
+	}
+
 	return &models.DeviceResponse{
 		Success: true,
 		Device:  device,
@@ -159,7 +437,7 @@ func (s *DeviceService) UpdateDevice(ctx context.Context, userID, deviceID strin
 		}, nil
 	}
 
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionEditDevices) {
 		return &models.DeviceResponse{
 			Success: false,
 			Message: "Access denied",
@@ -196,6 +474,341 @@ func (s *DeviceService) UpdateDevice(ctx context.Context, userID, deviceID strin
 	if req.Instance != nil {
 		updates["instance"] = *req.Instance
 	}
+	if req.MaxHistoryEntries != nil {
+		if *req.MaxHistoryEntries < 1 {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: "max_history_entries must be at least 1",
+			}, nil
+		}
+		updates["max_history_entries"] = *req.MaxHistoryEntries
+	}
+	if req.BackupDeviceID != nil {
+		if *req.BackupDeviceID == "" {
+			updates["backup_device_id"] = nil
+		} else {
+			if msg := s.validateBackupDevice(ctx, *device.UserID, device.ID, *req.BackupDeviceID); msg != "" {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: msg,
+				}, nil
+			}
+			updates["backup_device_id"] = *req.BackupDeviceID
+		}
+	}
+	if req.FailoverNotice != nil {
+		updates["failover_notice"] = *req.FailoverNotice
+	}
+	if req.SendFee != nil {
+		if *req.SendFee < 0 {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: "send_fee cannot be negative",
+			}, nil
+		}
+		updates["send_fee"] = *req.SendFee
+	}
+	if req.WorkingLanguage != nil {
+		updates["working_language"] = *req.WorkingLanguage
+	}
+	if req.HeartbeatURL != nil {
+		if *req.HeartbeatURL == "" {
+			updates["heartbeat_url"] = nil
+		} else {
+			if !validHeartbeatURL(*req.HeartbeatURL) {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: "heartbeat_url must be an http or https URL",
+				}, nil
+			}
+			updates["heartbeat_url"] = *req.HeartbeatURL
+		}
+	}
+	if req.Timezone != nil {
+		if *req.Timezone == "" {
+			updates["timezone"] = nil
+		} else {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: "timezone must be an IANA zone name such as Asia/Kuala_Lumpur",
+				}, nil
+			}
+			updates["timezone"] = *req.Timezone
+		}
+	}
+	if req.PhoneCountry != nil {
+		if msg := validatePhoneCountry(*req.PhoneCountry); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if *req.PhoneCountry == "" {
+			updates["phone_country"] = nil
+		} else {
+			updates["phone_country"] = strings.ToUpper(*req.PhoneCountry)
+		}
+	}
+	if req.AIPersona != nil {
+		if req.AIPersona.IsEmpty() {
+			updates["ai_persona"] = nil
+		} else {
+			if msg := validatePersona(req.AIPersona); msg != "" {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: msg,
+				}, nil
+			}
+			updates["ai_persona"] = req.AIPersona
+		}
+	}
+	if req.Sandbox != nil {
+		updates["sandbox"] = *req.Sandbox
+	}
+	if req.EmailReplies != nil {
+		updates["email_replies"] = *req.EmailReplies
+	}
+	if req.ArchiveInbound != nil {
+		updates["archive_inbound"] = *req.ArchiveInbound
+	}
+	if req.StaticFollowUps != nil {
+		updates["static_follow_ups"] = *req.StaticFollowUps
+	}
+	if req.TypingIndicator != nil {
+		updates["typing_indicator"] = *req.TypingIndicator
+	}
+	if req.ShareProspectData != nil {
+		updates["share_prospect_data"] = *req.ShareProspectData
+	}
+	if req.ArchiveRetentionDays != nil {
+		if msg := validateArchiveRetention(req.ArchiveRetentionDays); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if *req.ArchiveRetentionDays == 0 {
+			updates["archive_retention_days"] = nil
+		} else {
+			updates["archive_retention_days"] = *req.ArchiveRetentionDays
+		}
+	}
+	if req.SendRatePerMinute != nil {
+		if msg := validateSendRate(req.SendRatePerMinute); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if *req.SendRatePerMinute == 0 {
+			updates["send_rate_per_minute"] = nil
+		} else {
+			updates["send_rate_per_minute"] = *req.SendRatePerMinute
+		}
+	}
+	if req.TypingCharsPerSecond != nil {
+		if msg := validateTypingSpeed(req.TypingCharsPerSecond); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if *req.TypingCharsPerSecond == 0 {
+			updates["typing_chars_per_second"] = nil
+		} else {
+			updates["typing_chars_per_second"] = *req.TypingCharsPerSecond
+		}
+	}
+	if req.SendDelayMinMs != nil || req.SendDelayMaxMs != nil {
+		// Checked against the device's other bound when only one is sent
+		minMs, maxMs := req.SendDelayMinMs, req.SendDelayMaxMs
+		if minMs == nil {
+			minMs = device.SendDelayMinMs
+		}
+		if maxMs == nil {
+			maxMs = device.SendDelayMaxMs
+		}
+		if msg := validateSendDelay(minMs, maxMs); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		for column, delay := range map[string]*int{"send_delay_min_ms": req.SendDelayMinMs, "send_delay_max_ms": req.SendDelayMaxMs} {
+			if delay == nil {
+				continue
+			}
+			if *delay == 0 {
+				updates[column] = nil
+			} else {
+				updates[column] = *delay
+			}
+		}
+	}
+	if req.QuietHoursStart != nil || req.QuietHoursEnd != nil {
+		start, end := getStringValue(req.QuietHoursStart), getStringValue(req.QuietHoursEnd)
+		if msg := validateQuietHours(start, end); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if start == "" {
+			updates["quiet_hours_start"] = nil
+			updates["quiet_hours_end"] = nil
+		} else {
+			updates["quiet_hours_start"] = start
+			updates["quiet_hours_end"] = end
+		}
+	}
+	if req.BusinessHours != nil {
+		if msg := validateBusinessHours(*req.BusinessHours); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+	}
+	if req.ClosedMode != nil {
+		if msg := validateClosedMode(*req.ClosedMode); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+	}
+	for column, value := range map[string]*string{"business_hours": req.BusinessHours, "closed_mode": req.ClosedMode, "away_flow_id": req.AwayFlowID} {
+		if value == nil {
+			continue
+		}
+		if strings.TrimSpace(*value) == "" {
+			updates[column] = nil
+		} else {
+			updates[column] = strings.TrimSpace(*value)
+		}
+	}
+	if req.ListColumns != nil {
+		if msg := validateListColumns(*req.ListColumns); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if len(*req.ListColumns) == 0 {
+			updates["list_columns"] = nil
+		} else {
+			updates["list_columns"] = *req.ListColumns
+		}
+	}
+	if req.DebounceMinMs != nil || req.DebounceMaxMs != nil {
+		minMs, maxMs := device.DebounceMinMs, device.DebounceMaxMs
+		if req.DebounceMinMs != nil {
+			minMs = req.DebounceMinMs
+		}
+		if req.DebounceMaxMs != nil {
+			maxMs = req.DebounceMaxMs
+		}
+		if msg := validateDebounceBounds(minMs, maxMs); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		for column, bound := range map[string]*int{"debounce_min_ms": req.DebounceMinMs, "debounce_max_ms": req.DebounceMaxMs} {
+			if bound == nil {
+				continue
+			}
+			if *bound == 0 {
+				updates[column] = nil
+			} else {
+				updates[column] = *bound
+			}
+		}
+	}
+	if req.TrackingDomain != nil {
+		if *req.TrackingDomain == "" {
+			updates["tracking_domain"] = nil
+		} else {
+			if !validHeartbeatURL(*req.TrackingDomain) {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: "tracking_domain must be an http or https URL",
+				}, nil
+			}
+			updates["tracking_domain"] = *req.TrackingDomain
+		}
+	}
+	if req.DefaultPromptTemplate != nil {
+		if *req.DefaultPromptTemplate == "" {
+			updates["default_prompt_template"] = nil
+		} else {
+			if !models.IsValidPromptTemplateName(*req.DefaultPromptTemplate) {
+				return &models.DeviceResponse{
+					Success: false,
+					Message: "default_prompt_template must be a prompt template name",
+				}, nil
+			}
+			updates["default_prompt_template"] = *req.DefaultPromptTemplate
+		}
+	}
+	if req.AIFallbackModel != nil {
+		if fallback := strings.TrimSpace(*req.AIFallbackModel); fallback == "" {
+			updates["ai_fallback_model"] = nil
+		} else {
+			updates["ai_fallback_model"] = fallback
+		}
+	}
+	if req.AIEndpoints != nil {
+		if msg := validateAIEndpoints(*req.AIEndpoints); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if len(*req.AIEndpoints) == 0 {
+			updates["ai_endpoints"] = nil
+		} else {
+			updates["ai_endpoints"] = *req.AIEndpoints
+		}
+	}
+	if req.CustomProvider != nil || (req.Provider != nil && *req.Provider == "custom") {
+		custom := req.CustomProvider
+		if custom == nil {
+			custom = device.CustomProvider
+		}
+		if msg := validateCustomProvider(custom); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		updates["custom_provider"] = custom
+	}
+	if req.TelegramBotToken != nil {
+		if token := strings.TrimSpace(*req.TelegramBotToken); token == "" {
+			updates["telegram_bot_token"] = nil
+		} else if !validTelegramBotToken(token) {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: "telegram_bot_token must be a bot token from BotFather",
+			}, nil
+		} else {
+			updates["telegram_bot_token"] = token
+		}
+	}
+	if req.Messenger != nil {
+		if req.Messenger.IsEmpty() {
+			updates["messenger"] = nil
+		} else if msg := validateMessengerConfig(req.Messenger); msg != "" {
+			return &models.DeviceResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		} else {
+			updates["messenger"] = req.Messenger
+		}
+	}
 
 	if len(updates) == 0 {
 		return &models.DeviceResponse{
@@ -218,6 +831,77 @@ func (s *DeviceService) UpdateDevice(ctx context.Context, userID, deviceID strin
 	}, nil
 }
 
+// Limits for listing a device's intercepted sandbox sends
+const (
+	defaultSandboxMessageLimit = 50
+	maxSandboxMessageLimit     = 200
+)
+
+// GetSandboxMessages retrieves the sends a device intercepted while in sandbox mode, newest first
+func (s *DeviceService) GetSandboxMessages(ctx context.Context, userID, deviceID string, limit int) (*models.SandboxMessagesResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return &models.SandboxMessagesResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewDevices) {
+		return &models.SandboxMessagesResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	if limit <= 0 {
+		limit = defaultSandboxMessageLimit
+	}
+	if limit > maxSandboxMessageLimit {
+		limit = maxSandboxMessageLimit
+	}
+
+	// Sends are traced under id_device, or the row ID for devices without one
+	idDevice := getStringValue(device.IDDevice)
+	if idDevice == "" {
+		idDevice = device.ID
+	}
+
+	messages, err := s.sandboxRepo.GetMessagesByDevice(ctx, idDevice, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SandboxMessagesResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d sandbox messages", len(messages)),
+		Messages: messages,
+	}, nil
+}
+
+// GetSendQueue returns the outbound send queue of a device the user owns, as seen by this instance
+func (s *DeviceService) GetSendQueue(ctx context.Context, userID, deviceID string) (*models.SendQueueResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return &models.SendQueueResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewDevices) {
+		return &models.SendQueueResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	return &models.SendQueueResponse{
+		Success: true,
+		Queue:   s.whatsappService.SendQueueMetrics(device),
+	}, nil
+}
+
 // DeleteDevice deletes a device
 func (s *DeviceService) DeleteDevice(ctx context.Context, userID, deviceID string) (*models.DeviceResponse, error) {
 	// Get device and check ownership
@@ -229,7 +913,7 @@ func (s *DeviceService) DeleteDevice(ctx context.Context, userID, deviceID strin
 		}, nil
 	}
 
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteDevices) {
 		return &models.DeviceResponse{
 			Success: false,
 			Message: "Access denied",
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// deviceSession returns the provider and session API of a device the user owns, or a failed response
+func (s *DeviceService) deviceSession(ctx context.Context, userID, deviceID string) (whatsapp.Provider, whatsapp.SessionManager, *models.DeviceStatusResponse) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, nil, &models.DeviceStatusResponse{
+			Success: false,
+			Message: "Device not found",
+		}
+	}
+
+	if device.UserID == nil || *device.UserID != userID {
+		return nil, nil, &models.DeviceStatusResponse{
+			Success: false,
+			Message: "Access denied",
+		}
+	}
+
+	if device.Instance == nil || *device.Instance == "" {
+		return nil, nil, &models.DeviceStatusResponse{
+			Success: false,
+			Message: "Device not generated yet. Please generate device first.",
+		}
+	}
+
+	provider, err := s.whatsappService.providerForDevice(device, *device.Instance)
+	if err != nil {
+		return nil, nil, &models.DeviceStatusResponse{
+			Success: false,
+			Message: err.Error(),
+		}
+	}
+
+	manager, ok := whatsapp.AsSessionManager(provider)
+	if !ok {
+		return nil, nil, &models.DeviceStatusResponse{
+			Success:  false,
+			Provider: provider.GetProviderName(),
+			Message:  fmt.Sprintf("Provider %s does not use QR login sessions", provider.GetProviderName()),
+		}
+	}
+
+	return provider, manager, nil
+}
+
+// sessionState reports the session status of a device and, while it waits for a scan, its QR code
+func sessionState(ctx context.Context, provider whatsapp.Provider, manager whatsapp.SessionManager, deviceID, message string) (*models.DeviceStatusResponse, error) {
+	status, err := provider.GetSessionStatus(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session status: %w", err)
+	}
+
+	response := &models.DeviceStatusResponse{
+		Success:  true,
+		Provider: provider.GetProviderName(),
+		Status:   status.Session.Status,
+		Message:  message,
+	}
+
+	if response.Status == "connecting" {
+		qr, err := manager.GetQRCode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get QR code: %w", err)
+		}
+		response.QRImage = qr
+	}
+
+	return response, nil
+}
+
+// StartDeviceSession starts the provider session of a device and returns its status and QR code
+func (s *DeviceService) StartDeviceSession(ctx context.Context, userID, deviceID string) (*models.DeviceStatusResponse, error) {
+	provider, manager, failed := s.deviceSession(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	if _, err := provider.StartSession(ctx, deviceID); err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	return sessionState(ctx, provider, manager, deviceID, "Session started")
+}
+
+// GetDeviceSessionQR returns the session status of a device and, while it waits for a scan, the
+// current QR code. QR codes rotate every few seconds, so the dashboard polls this until connected.
+func (s *DeviceService) GetDeviceSessionQR(ctx context.Context, userID, deviceID string) (*models.DeviceStatusResponse, error) {
+	provider, manager, failed := s.deviceSession(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	return sessionState(ctx, provider, manager, deviceID, "Session status retrieved")
+}
+
+// RestartDeviceSession restarts the provider session of a device, keeping its linked number
+func (s *DeviceService) RestartDeviceSession(ctx context.Context, userID, deviceID string) (*models.DeviceStatusResponse, error) {
+	provider, manager, failed := s.deviceSession(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	if err := manager.RestartSession(ctx); err != nil {
+		if errors.Is(err, whatsapp.ErrSessionActionUnsupported) {
+			return unsupportedSessionAction(provider, "restart"), nil
+		}
+		return nil, fmt.Errorf("failed to restart session: %w", err)
+	}
+
+	return sessionState(ctx, provider, manager, deviceID, "Session restarted")
+}
+
+// LogoutDeviceSession unlinks the number from a device; it has to scan a new QR code to reconnect
+func (s *DeviceService) LogoutDeviceSession(ctx context.Context, userID, deviceID string) (*models.DeviceStatusResponse, error) {
+	provider, manager, failed := s.deviceSession(ctx, userID, deviceID)
+	if failed != nil {
+		return failed, nil
+	}
+
+	if err := manager.LogoutSession(ctx); err != nil {
+		if errors.Is(err, whatsapp.ErrSessionActionUnsupported) {
+			return unsupportedSessionAction(provider, "logout"), nil
+		}
+		return nil, fmt.Errorf("failed to log out session: %w", err)
+	}
+
+	return sessionState(ctx, provider, manager, deviceID, "Session logged out")
+}
+
+// unsupportedSessionAction is the failed response for a session action the provider has no call for
+func unsupportedSessionAction(provider whatsapp.Provider, action string) *models.DeviceStatusResponse {
+	return &models.DeviceStatusResponse{
+		Success:  false,
+		Provider: provider.GetProviderName(),
+		Message:  fmt.Sprintf("Provider %s does not support session %s", provider.GetProviderName(), action),
+	}
+}
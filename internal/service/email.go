@@ -0,0 +1,451 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+const (
+	defaultSMTPPort  = 587
+	sendGridSendURL  = "https://api.sendgrid.com/v3/mail/send"
+	maxEmailReplyLen = 4000 // characters of an email reply kept in the conversation history
+)
+
+// OutgoingEmail is one plain-text email sent by a send_email node
+type OutgoingEmail struct {
+	To      []string
+	Subject string
+	Body    string
+	ReplyTo string // empty when the device takes no email replies
+}
+
+// EmailService is the email channel: it sends send_email node mail through the device owner's SMTP
+// server or SendGrid account, and appends replies to <webhook_id>+<conversation>@inbound domain to
+// the conversation history of devices with email_replies on
+type EmailService struct {
+	settingsRepo  *repository.EmailSettingsRepository
+	deviceRepo    *repository.DeviceRepository
+	convRepo      *repository.ConversationRepository
+	wasapbotRepo  *repository.WasapbotRepository
+	messages      *MessageRecorder
+	inboundDomain string
+	inboundToken  string
+	httpClient    *http.Client
+}
+
+// NewEmailService creates the email channel; an empty inboundDomain disables email replies
+func NewEmailService(
+	settingsRepo *repository.EmailSettingsRepository,
+	deviceRepo *repository.DeviceRepository,
+	convRepo *repository.ConversationRepository,
+	wasapbotRepo *repository.WasapbotRepository,
+	messages *MessageRecorder,
+	inboundDomain, inboundToken string,
+) *EmailService {
+	return &EmailService{
+		settingsRepo:  settingsRepo,
+		deviceRepo:    deviceRepo,
+		convRepo:      convRepo,
+		wasapbotRepo:  wasapbotRepo,
+		messages:      messages,
+		inboundDomain: strings.ToLower(strings.TrimSpace(inboundDomain)),
+		inboundToken:  inboundToken,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// GetSettings returns the user's email settings without their secrets
+func (s *EmailService) GetSettings(ctx context.Context, userID string) (*models.EmailSettingsResponse, error) {
+	settings, err := s.settingsRepo.GetSettingsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return &models.EmailSettingsResponse{
+			Success:       true,
+			Message:       "Email is not configured",
+			InboundDomain: s.inboundDomain,
+		}, nil
+	}
+
+	return &models.EmailSettingsResponse{
+		Success:       true,
+		Settings:      redactEmailSettings(settings),
+		InboundDomain: s.inboundDomain,
+	}, nil
+}
+
+// UpdateSettings creates or replaces the user's email settings
+func (s *EmailService) UpdateSettings(ctx context.Context, userID string, req *models.UpdateEmailSettingsRequest) (*models.EmailSettingsResponse, error) {
+	existing, err := s.settingsRepo.GetSettingsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &models.EmailSettings{UserID: userID}
+	if existing != nil {
+		*settings = *existing
+	}
+	settings.Provider = strings.ToLower(strings.TrimSpace(req.Provider))
+	settings.FromAddress = strings.TrimSpace(req.FromAddress)
+	settings.FromName = req.FromName
+	settings.SMTPHost = req.SMTPHost
+	settings.SMTPPort = req.SMTPPort
+	settings.SMTPUsername = req.SMTPUsername
+	if req.SMTPPassword != nil {
+		settings.SMTPPassword = req.SMTPPassword
+	}
+	if req.SendGridAPIKey != nil {
+		settings.SendGridAPIKey = req.SendGridAPIKey
+	}
+
+	if msg := validateEmailSettings(settings); msg != "" {
+		return &models.EmailSettingsResponse{Success: false, Message: msg}, nil
+	}
+
+	if existing == nil {
+		if err := s.settingsRepo.CreateSettings(ctx, settings); err != nil {
+			return nil, err
+		}
+	} else {
+		updates := map[string]interface{}{
+			"provider":         settings.Provider,
+			"from_address":     settings.FromAddress,
+			"from_name":        settings.FromName,
+			"smtp_host":        settings.SMTPHost,
+			"smtp_port":        settings.SMTPPort,
+			"smtp_username":    settings.SMTPUsername,
+			"smtp_password":    settings.SMTPPassword,
+			"sendgrid_api_key": settings.SendGridAPIKey,
+		}
+		if err := s.settingsRepo.UpdateSettings(ctx, existing.ID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.EmailSettingsResponse{
+		Success:       true,
+		Message:       "Email settings saved",
+		Settings:      redactEmailSettings(settings),
+		InboundDomain: s.inboundDomain,
+	}, nil
+}
+
+// validateEmailSettings returns why settings cannot send mail, or "" when they can
+func validateEmailSettings(settings *models.EmailSettings) string {
+	if _, err := mail.ParseAddress(settings.FromAddress); err != nil {
+		return "from_address must be a valid email address"
+	}
+
+	switch settings.Provider {
+	case models.EmailProviderSMTP:
+		if strings.TrimSpace(getStringValue(settings.SMTPHost)) == "" {
+			return "smtp_host is required for the smtp provider"
+		}
+		if settings.SMTPPort != nil && (*settings.SMTPPort < 1 || *settings.SMTPPort > 65535) {
+			return "smtp_port must be between 1 and 65535"
+		}
+		if getStringValue(settings.SMTPUsername) != "" && getStringValue(settings.SMTPPassword) == "" {
+			return "smtp_password is required with smtp_username"
+		}
+	case models.EmailProviderSendGrid:
+		if strings.TrimSpace(getStringValue(settings.SendGridAPIKey)) == "" {
+			return "sendgrid_api_key is required for the sendgrid provider"
+		}
+	default:
+		return fmt.Sprintf("provider must be %s or %s", models.EmailProviderSMTP, models.EmailProviderSendGrid)
+	}
+
+	return ""
+}
+
+// redactEmailSettings copies settings without the SMTP password and SendGrid key
+func redactEmailSettings(settings *models.EmailSettings) *models.EmailSettings {
+	redacted := *settings
+	redacted.SMTPPassword = nil
+	redacted.SendGridAPIKey = nil
+	return &redacted
+}
+
+// SendForDevice sends email from the account of the device's owner. Replies are routed back to
+// conversationID when the device takes email replies.
+func (s *EmailService) SendForDevice(ctx context.Context, idDevice, conversationID string, email *OutgoingEmail) error {
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.UserID == nil {
+		return fmt.Errorf("device %s has no owner", idDevice)
+	}
+
+	settings, err := s.settingsRepo.GetSettingsByUser(ctx, *device.UserID)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		return fmt.Errorf("email is not configured for the owner of device %s", idDevice)
+	}
+
+	email.ReplyTo = s.replyAddress(device, conversationID)
+	switch settings.Provider {
+	case models.EmailProviderSendGrid:
+		return s.sendSendGrid(ctx, settings, email)
+	default:
+		return sendSMTP(ctx, settings, email)
+	}
+}
+
+// replyAddress is where replies to a conversation's email go, or "" when the device takes none
+func (s *EmailService) replyAddress(device *models.DeviceSetting, conversationID string) string {
+	if s.inboundDomain == "" || !device.EmailReplies || getStringValue(device.WebhookID) == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s+%s@%s", *device.WebhookID, conversationID, s.inboundDomain)
+}
+
+// sendSMTP delivers email through the user's SMTP server: implicit TLS on port 465, STARTTLS
+// elsewhere when the server offers it
+func sendSMTP(ctx context.Context, settings *models.EmailSettings, email *OutgoingEmail) error {
+	host := getStringValue(settings.SMTPHost)
+	port := defaultSMTPPort
+	if settings.SMTPPort != nil {
+		port = *settings.SMTPPort
+	}
+
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if port == 465 {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if username := getStringValue(settings.SMTPUsername); username != "" {
+		if err := client.Auth(smtp.PlainAuth("", username, getStringValue(settings.SMTPPassword), host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(settings.FromAddress); err != nil {
+		return fmt.Errorf("SMTP sender rejected: %w", err)
+	}
+	for _, to := range email.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(smtpMessage(settings, email)); err != nil {
+		return fmt.Errorf("failed to write SMTP message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// smtpMessage renders the headers and plain-text body of an SMTP message
+func smtpMessage(settings *models.EmailSettings, email *OutgoingEmail) []byte {
+	from := mail.Address{Name: getStringValue(settings.FromName), Address: settings.FromAddress}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + strings.Join(email.To, ", ") + "\r\n")
+	if email.ReplyTo != "" {
+		msg.WriteString("Reply-To: " + email.ReplyTo + "\r\n")
+	}
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", email.Subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(msg.String())
+}
+
+// sendSendGrid delivers email through the SendGrid v3 mail send API
+func (s *EmailService) sendSendGrid(ctx context.Context, settings *models.EmailSettings, email *OutgoingEmail) error {
+	type address struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+
+	recipients := make([]address, 0, len(email.To))
+	for _, to := range email.To {
+		recipients = append(recipients, address{Email: to})
+	}
+
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{{"to": recipients}},
+		"from":             address{Email: settings.FromAddress, Name: getStringValue(settings.FromName)},
+		"subject":          email.Subject,
+		"content":          []map[string]string{{"type": "text/plain", "value": email.Body}},
+	}
+	if email.ReplyTo != "" {
+		payload["reply_to"] = address{Email: email.ReplyTo}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode SendGrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridSendURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create SendGrid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+getStringValue(settings.SendGridAPIKey))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("SendGrid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("SendGrid returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// InboundTokenValid reports whether an inbound mail webhook carries the configured token
+func (s *EmailService) InboundTokenValid(token string) bool {
+	return s.inboundToken == "" || token == s.inboundToken
+}
+
+// ReceiveReply appends an email reply to the history of the conversation its address names.
+// It reports false, without an error, for mail that is not a reply the channel takes.
+func (s *EmailService) ReceiveReply(ctx context.Context, email *models.InboundEmail) (bool, error) {
+	webhookID, conversationID := s.parseReplyAddress(email.To)
+	if webhookID == "" {
+		log.Printf("📧 Inbound email to %q is not a reply address", email.To)
+		return false, nil
+	}
+
+	device, err := s.deviceRepo.GetDeviceByWebhookID(ctx, webhookID)
+	if err != nil {
+		return false, err
+	}
+	if device == nil || !device.EmailReplies {
+		log.Printf("📧 Inbound email for webhook %s ignored: device not found or email replies off", webhookID)
+		return false, nil
+	}
+	idDevice := getStringValue(device.IDDevice)
+
+	entry := emailHistoryEntry(email)
+	if conversation, err := s.convRepo.GetConversationByID(ctx, conversationID); err == nil && conversation != nil && conversation.IDDevice == idDevice {
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, getStringValue(conversation.ConvLast), newConversationMessage(models.AssignmentSourceAI, conversationID, idDevice, "User", entry), 0),
+		}
+		return true, s.convRepo.UpdateConversation(ctx, conversationID, updates)
+	}
+	if contact, err := s.wasapbotRepo.GetConversationByID(ctx, conversationID); err == nil && contact != nil && contact.IDDevice == idDevice {
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, getStringValue(contact.ConvLast), newConversationMessage(models.AssignmentSourceWasapbot, conversationID, idDevice, "User", entry), device.EffectiveMaxHistoryEntries()),
+		}
+		return true, s.wasapbotRepo.UpdateConversation(ctx, conversationID, updates)
+	}
+
+	log.Printf("📧 Inbound email for conversation %s ignored: not a conversation of device %s", conversationID, idDevice)
+	return false, nil
+}
+
+// parseReplyAddress finds the <webhook_id>+<conversation>@inbound domain recipient of a To header
+func (s *EmailService) parseReplyAddress(to string) (string, string) {
+	if s.inboundDomain == "" {
+		return "", ""
+	}
+
+	addresses, err := mail.ParseAddressList(to)
+	if err != nil {
+		return "", ""
+	}
+	for _, address := range addresses {
+		at := strings.LastIndex(address.Address, "@")
+		if at < 0 || !strings.EqualFold(address.Address[at+1:], s.inboundDomain) {
+			continue
+		}
+		if webhookID, conversationID, ok := strings.Cut(address.Address[:at], "+"); ok && webhookID != "" && conversationID != "" {
+			return webhookID, conversationID
+		}
+	}
+	return "", ""
+}
+
+// emailHistoryEntry is the conv_last text of an email reply: the subject and the new text, without
+// the quoted message below it
+func emailHistoryEntry(email *models.InboundEmail) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(email.Text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") || (strings.HasPrefix(trimmed, "On ") && strings.HasSuffix(trimmed, "wrote:")) {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if runes := []rune(text); len(runes) > maxEmailReplyLen {
+		text = string(runes[:maxEmailReplyLen]) + "…"
+	}
+	return fmt.Sprintf("[email] %s\n%s", strings.TrimSpace(email.Subject), text)
+}
+
+// sendEmailMessage renders a send_email node's to, subject and body for a conversation
+func sendEmailMessage(node *FlowNode, conversation interface{}) *OutgoingEmail {
+	to, _ := node.Config["to"].(string)
+	subject, _ := node.Config["subject"].(string)
+	body, _ := node.Config["body"].(string)
+
+	email := &OutgoingEmail{
+		Subject: strings.TrimSpace(renderMessageTemplate(subject, conversation)),
+		Body:    renderMessageTemplate(body, conversation),
+	}
+	if addresses, err := mail.ParseAddressList(renderMessageTemplate(to, conversation)); err == nil {
+		for _, address := range addresses {
+			email.To = append(email.To, address.Address)
+		}
+	}
+	return email
+}
@@ -0,0 +1,595 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// eventWebhookTimeout bounds one delivery attempt
+const eventWebhookTimeout = 10 * time.Second
+
+// maxEventWebhookRetryDelay caps the doubling wait between delivery attempts
+const maxEventWebhookRetryDelay = 24 * time.Hour
+
+// eventWebhookDeliveryLog is how many of a webhook's latest deliveries are listed
+const eventWebhookDeliveryLog = 50
+
+// EventWebhookService manages the outbound event webhooks users point at their CRM, and delivers
+// events to them: conversation.created, stage.changed, flow.completed, order.paid and the custom
+// events of emit_event flow nodes. Every delivery is logged; failed ones are retried by Start with
+// the webhook's retry policy. Every event also goes to the owner's live event streams.
+type EventWebhookService struct {
+	webhookRepo *repository.EventWebhookRepository
+	deviceRepo  *repository.DeviceRepository
+	httpClient  *http.Client
+	live        *LiveEventHub
+}
+
+// NewEventWebhookService creates a new event webhook service
+func NewEventWebhookService(webhookRepo *repository.EventWebhookRepository, deviceRepo *repository.DeviceRepository, live *LiveEventHub) *EventWebhookService {
+	return &EventWebhookService{
+		webhookRepo: webhookRepo,
+		deviceRepo:  deviceRepo,
+		httpClient:  &http.Client{Timeout: eventWebhookTimeout},
+		live:        live,
+	}
+}
+
+// ListWebhooks returns the user's event webhooks and the built-in events they can subscribe to
+func (s *EventWebhookService) ListWebhooks(ctx context.Context, userID string) (*models.EventWebhookResponse, error) {
+	webhooks, err := s.webhookRepo.GetWebhooksByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []models.EventWebhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return &models.EventWebhookResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d event webhooks", len(webhooks)),
+		Webhooks: webhooks,
+		Events:   models.BuiltinEvents,
+	}, nil
+}
+
+// CreateWebhook adds an event webhook for the user. The response is the only one carrying the
+// secret, generated when the request has none.
+func (s *EventWebhookService) CreateWebhook(ctx context.Context, userID string, req *models.CreateEventWebhookRequest) (*models.EventWebhookResponse, error) {
+	webhook := &models.EventWebhook{
+		UserID:       userID,
+		Name:         req.Name,
+		URL:          strings.TrimSpace(req.URL),
+		Events:       normalizeEventNames(req.Events),
+		MaxAttempts:  models.DefaultWebhookMaxAttempts,
+		RetrySeconds: models.DefaultWebhookRetrySeconds,
+	}
+	if req.MaxAttempts != nil {
+		webhook.MaxAttempts = *req.MaxAttempts
+	}
+	if req.RetrySeconds != nil {
+		webhook.RetrySeconds = *req.RetrySeconds
+	}
+	if req.IDDevice != nil && strings.TrimSpace(*req.IDDevice) != "" {
+		idDevice := strings.TrimSpace(*req.IDDevice)
+		webhook.IDDevice = &idDevice
+	}
+	if req.Secret != nil && strings.TrimSpace(*req.Secret) != "" {
+		webhook.Secret = strings.TrimSpace(*req.Secret)
+	} else {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	if msg := s.validateWebhook(ctx, userID, webhook); msg != "" {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	existing, err := s.webhookRepo.GetWebhooksByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxEventWebhooks {
+		return &models.EventWebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can add at most %d event webhooks", models.MaxEventWebhooks),
+		}, nil
+	}
+
+	secret := webhook.Secret
+	if err := s.webhookRepo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+
+	return &models.EventWebhookResponse{
+		Success: true,
+		Message: "Event webhook created; keep the secret to verify X-Webhook-Signature, it is not shown again",
+		Webhook: webhook,
+	}, nil
+}
+
+// UpdateWebhook changes one of the user's event webhooks
+func (s *EventWebhookService) UpdateWebhook(ctx context.Context, userID, webhookID string, req *models.UpdateEventWebhookRequest) (*models.EventWebhookResponse, error) {
+	webhook, msg, err := s.ownedWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.URL != nil {
+		webhook.URL = strings.TrimSpace(*req.URL)
+		updates["url"] = webhook.URL
+	}
+	if req.Name != nil {
+		webhook.Name = req.Name
+		updates["name"] = *req.Name
+	}
+	if req.IDDevice != nil {
+		webhook.IDDevice = nil
+		updates["id_device"] = nil
+		if idDevice := strings.TrimSpace(*req.IDDevice); idDevice != "" {
+			webhook.IDDevice = &idDevice
+			updates["id_device"] = idDevice
+		}
+	}
+	if req.Secret != nil && strings.TrimSpace(*req.Secret) != "" {
+		webhook.Secret = strings.TrimSpace(*req.Secret)
+		updates["secret"] = webhook.Secret
+	}
+	if req.Events != nil {
+		webhook.Events = normalizeEventNames(req.Events)
+		updates["events"] = webhook.Events
+	}
+	if req.MaxAttempts != nil {
+		webhook.MaxAttempts = *req.MaxAttempts
+		updates["max_attempts"] = webhook.MaxAttempts
+	}
+	if req.RetrySeconds != nil {
+		webhook.RetrySeconds = *req.RetrySeconds
+		updates["retry_seconds"] = webhook.RetrySeconds
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = req.IsActive
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) == 0 {
+		return &models.EventWebhookResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := s.validateWebhook(ctx, userID, webhook); msg != "" {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.webhookRepo.UpdateWebhook(ctx, webhook.ID, updates); err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+
+	return &models.EventWebhookResponse{
+		Success: true,
+		Message: "Event webhook updated successfully",
+		Webhook: webhook,
+	}, nil
+}
+
+// DeleteWebhook removes one of the user's event webhooks with its delivery log
+func (s *EventWebhookService) DeleteWebhook(ctx context.Context, userID, webhookID string) (*models.EventWebhookResponse, error) {
+	webhook, msg, err := s.ownedWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.webhookRepo.DeleteWebhook(ctx, webhook.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.EventWebhookResponse{
+		Success: true,
+		Message: "Event webhook deleted successfully",
+	}, nil
+}
+
+// ListDeliveries returns the latest deliveries of one of the user's event webhooks, newest first
+func (s *EventWebhookService) ListDeliveries(ctx context.Context, userID, webhookID string) (*models.EventWebhookResponse, error) {
+	webhook, msg, err := s.ownedWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveriesByWebhook(ctx, webhook.ID, eventWebhookDeliveryLog)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []models.EventWebhookDelivery{}
+	}
+
+	return &models.EventWebhookResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Found %d deliveries", len(deliveries)),
+		Deliveries: deliveries,
+	}, nil
+}
+
+// TestWebhook sends a webhook.test event to one of the user's event webhooks and returns how the
+// first attempt went; a failed test is retried like any other delivery
+func (s *EventWebhookService) TestWebhook(ctx context.Context, userID, webhookID string) (*models.EventWebhookResponse, error) {
+	webhook, msg, err := s.ownedWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return &models.EventWebhookResponse{Success: false, Message: msg}, nil
+	}
+
+	payload := newEventPayload(getStringValue(webhook.IDDevice), models.EventWebhookTest, map[string]interface{}{
+		"webhook_id": webhook.ID,
+	})
+	delivery, err := s.createDelivery(ctx, webhook, payload)
+	if err != nil {
+		return nil, err
+	}
+	delivered := s.attempt(ctx, webhook, delivery)
+
+	message := "Test event delivered"
+	if !delivered {
+		message = fmt.Sprintf("Test event not delivered: %s", getStringValue(delivery.Error))
+	}
+	return &models.EventWebhookResponse{
+		Success:  true,
+		Message:  message,
+		Delivery: delivery,
+	}, nil
+}
+
+// ownedWebhook loads an event webhook and checks the user owns it
+func (s *EventWebhookService) ownedWebhook(ctx context.Context, userID, webhookID string) (*models.EventWebhook, string, error) {
+	webhook, err := s.webhookRepo.GetWebhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, "", err
+	}
+	if webhook == nil {
+		return nil, "Event webhook not found", nil
+	}
+	if webhook.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return webhook, "", nil
+}
+
+// validateWebhook checks an event webhook.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func (s *EventWebhookService) validateWebhook(ctx context.Context, userID string, webhook *models.EventWebhook) string {
+	if !validHeartbeatURL(webhook.URL) {
+		return "url must be an http or https URL"
+	}
+	for _, event := range webhook.Events {
+		if event != "*" && !models.IsValidEventName(event) {
+			return fmt.Sprintf("Invalid event %q: use lowercase names such as stage.changed, or * for every event", event)
+		}
+	}
+	if webhook.MaxAttempts < 1 || webhook.MaxAttempts > models.MaxWebhookMaxAttempts {
+		return fmt.Sprintf("max_attempts must be between 1 and %d", models.MaxWebhookMaxAttempts)
+	}
+	if webhook.RetrySeconds < models.MinWebhookRetrySeconds || webhook.RetrySeconds > models.MaxWebhookRetrySeconds {
+		return fmt.Sprintf("retry_seconds must be between %d and %d", models.MinWebhookRetrySeconds, models.MaxWebhookRetrySeconds)
+	}
+	if webhook.IDDevice != nil {
+		device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, *webhook.IDDevice)
+		if err != nil || device == nil || device.UserID == nil || *device.UserID != userID {
+			return "Device not found or access denied"
+		}
+	}
+	return ""
+}
+
+// normalizeEventNames trims and lowercases event names, dropping empty ones and duplicates
+func normalizeEventNames(events []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" || seen[event] {
+			continue
+		}
+		seen[event] = true
+		normalized = append(normalized, event)
+	}
+	return normalized
+}
+
+// newWebhookSecret generates a random signing secret
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// newEventPayload builds the body of an event
+func newEventPayload(idDevice, event string, data map[string]interface{}) *models.EventPayload {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &models.EventPayload{
+		ID:        uuid.New().String(),
+		Event:     event,
+		IDDevice:  idDevice,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// EmitForDevice sends an event of a device to its owner's webhooks. Delivery happens in the
+// background, so the caller never waits on the user's endpoints.
+func (s *EventWebhookService) EmitForDevice(ctx context.Context, idDevice, event string, data map[string]interface{}) {
+	if s == nil || idDevice == "" {
+		return
+	}
+	payload := newEventPayload(idDevice, event, data)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*eventWebhookTimeout)
+		defer cancel()
+
+		device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+		if err != nil || device == nil || device.UserID == nil {
+			log.Printf("⚠️  Event %s of device %s not sent, failed to find its owner: %v", event, idDevice, err)
+			return
+		}
+		s.live.Publish(*device.UserID, payload)
+		s.dispatch(ctx, *device.UserID, payload)
+	}()
+}
+
+// Emit sends an event to the user's webhooks, in the background; idDevice may be empty for
+// events that concern no device, such as order.paid
+func (s *EventWebhookService) Emit(ctx context.Context, userID, idDevice, event string, data map[string]interface{}) {
+	if s == nil || userID == "" {
+		return
+	}
+	payload := newEventPayload(idDevice, event, data)
+	s.live.Publish(userID, payload)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*eventWebhookTimeout)
+		defer cancel()
+		s.dispatch(ctx, userID, payload)
+	}()
+}
+
+// dispatch logs a delivery to every active webhook of the user subscribed to the event and makes
+// the first attempt of each
+func (s *EventWebhookService) dispatch(ctx context.Context, userID string, payload *models.EventPayload) {
+	webhooks, err := s.webhookRepo.GetWebhooksByUser(ctx, userID)
+	if err != nil {
+		log.Printf("⚠️  Event %s not sent, failed to load webhooks of user %s: %v", payload.Event, userID, err)
+		return
+	}
+
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Active() || !webhook.Subscribes(payload.Event) {
+			continue
+		}
+		if webhook.IDDevice != nil && *webhook.IDDevice != payload.IDDevice {
+			continue
+		}
+
+		delivery, err := s.createDelivery(ctx, webhook, payload)
+		if err != nil {
+			log.Printf("⚠️  Event %s not sent to webhook %s: %v", payload.Event, webhook.ID, err)
+			continue
+		}
+		s.attempt(ctx, webhook, delivery)
+	}
+}
+
+// createDelivery logs a pending delivery of the payload to the webhook. Its first retry is set
+// past the first attempt, so the retry worker does not send it at the same time.
+func (s *EventWebhookService) createDelivery(ctx context.Context, webhook *models.EventWebhook, payload *models.EventPayload) (*models.EventWebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	nextAttempt := time.Now().Add(eventWebhookTimeout + eventWebhookRetryDelay(webhook, 1))
+	delivery := &models.EventWebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       payload.ID,
+		Event:         payload.Event,
+		Payload:       body,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &nextAttempt,
+	}
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// eventWebhookRetryDelay is the wait after the given failed attempt: the webhook's retry_seconds,
+// doubling with each attempt
+func eventWebhookRetryDelay(webhook *models.EventWebhook, attempt int) time.Duration {
+	delay := time.Duration(webhook.RetrySeconds) * time.Second
+	for i := 1; i < attempt && delay < maxEventWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxEventWebhookRetryDelay {
+		delay = maxEventWebhookRetryDelay
+	}
+	return delay
+}
+
+// attempt POSTs a delivery to its webhook and logs the outcome: delivered, pending a retry, or
+// failed once the webhook's max_attempts are used up. Reports whether it was delivered.
+func (s *EventWebhookService) attempt(ctx context.Context, webhook *models.EventWebhook, delivery *models.EventWebhookDelivery) bool {
+	status, err := s.post(ctx, webhook, delivery)
+	now := time.Now()
+	delivery.Attempts++
+
+	updates := map[string]interface{}{"attempts": delivery.Attempts}
+	if status > 0 {
+		delivery.ResponseStatus = &status
+		updates["response_status"] = status
+	}
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.Error = nil
+		updates["status"] = delivery.Status
+		updates["delivered_at"] = now
+		updates["next_attempt_at"] = nil
+		updates["error"] = nil
+		log.Printf("📤 Event %s delivered to webhook %s", delivery.Event, webhook.ID)
+	} else {
+		reason := err.Error()
+		delivery.Error = &reason
+		updates["error"] = reason
+		if delivery.Attempts >= webhook.MaxAttempts {
+			delivery.Status = models.DeliveryFailed
+			delivery.NextAttemptAt = nil
+			log.Printf("❌ Event %s to webhook %s failed after %d attempts: %s", delivery.Event, webhook.ID, delivery.Attempts, reason)
+		} else {
+			next := now.Add(eventWebhookRetryDelay(webhook, delivery.Attempts))
+			delivery.NextAttemptAt = &next
+			log.Printf("⚠️  Event %s to webhook %s failed (attempt %d), retrying at %s: %s", delivery.Event, webhook.ID, delivery.Attempts, next.Format(time.RFC3339), reason)
+		}
+		updates["status"] = delivery.Status
+		updates["next_attempt_at"] = delivery.NextAttemptAt
+	}
+
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery.ID, updates); err != nil {
+		log.Printf("⚠️  Failed to log delivery %s: %v", delivery.ID, err)
+	}
+	return delivery.Status == models.DeliveryDelivered
+}
+
+// post sends one delivery, signed with the webhook's secret. Returns the HTTP status, 0 when the
+// request failed before an answer.
+func (s *EventWebhookService) post(ctx context.Context, webhook *models.EventWebhook, delivery *models.EventWebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Signature", signWebhookPayload(webhook.Secret, delivery.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the X-Webhook-Signature of a body: sha256=<hex HMAC-SHA256>
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start retries due deliveries immediately and then every interval until ctx is cancelled
+func (s *EventWebhookService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if retried, err := s.RetryDue(ctx); err != nil {
+				log.Printf("⚠️  Event webhook retry failed: %v", err)
+			} else if retried > 0 {
+				log.Printf("🔁 Retried %d event webhook deliveries", retried)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RetryDue makes the next attempt of every pending delivery that is due and returns how many were
+// attempted. Deliveries of webhooks deactivated since are marked failed.
+func (s *EventWebhookService) RetryDue(ctx context.Context) (int, error) {
+	deliveries, err := s.webhookRepo.GetDueDeliveries(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[string]*models.EventWebhook)
+	retried := 0
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return retried, ctx.Err()
+		}
+		delivery := &deliveries[i]
+
+		webhook, loaded := webhooks[delivery.WebhookID]
+		if !loaded {
+			webhook, err = s.webhookRepo.GetWebhookByID(ctx, delivery.WebhookID)
+			if err != nil {
+				log.Printf("⚠️  Failed to load webhook %s: %v", delivery.WebhookID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if webhook == nil || !webhook.Active() {
+			if err := s.webhookRepo.UpdateDelivery(ctx, delivery.ID, map[string]interface{}{
+				"status":          models.DeliveryFailed,
+				"next_attempt_at": nil,
+				"error":           "webhook is inactive",
+			}); err != nil {
+				log.Printf("⚠️  Failed to log delivery %s: %v", delivery.ID, err)
+			}
+			continue
+		}
+
+		s.attempt(ctx, webhook, delivery)
+		retried++
+	}
+	return retried, nil
+}
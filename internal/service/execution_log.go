@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+
+	"chatbot-automation/internal/models"
+)
+
+// Execution log page sizes
+const (
+	defaultExecutionLogLimit = 200
+	maxExecutionLogLimit     = 1000
+)
+
+// executionLogLimit clamps a requested execution log size, using the default when unset
+func executionLogLimit(limit int) int {
+	if limit <= 0 {
+		return defaultExecutionLogLimit
+	}
+	return min(limit, maxExecutionLogLimit)
+}
+
+// GetExecutionLog returns the nodes the flow engine ran for a conversation, oldest first, so
+// support staff can replay the path a flow took
+func (s *ConversationService) GetExecutionLog(ctx context.Context, userID, prospectID string, limit int) (*models.ExecutionLogResponse, error) {
+	if s.executionLogs == nil {
+		return &models.ExecutionLogResponse{
+			Success: false,
+			Message: "Flow execution logs are not enabled",
+		}, nil
+	}
+
+	conversation, err := s.ownedConversation(ctx, userID, prospectID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.ExecutionLogResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	entries, err := s.executionLogs.GetLogsByConversation(ctx, "ai_whatsapp", prospectID, executionLogLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.FlowExecutionLog{}
+	}
+
+	return &models.ExecutionLogResponse{
+		Success:        true,
+		ConversationID: prospectID,
+		Entries:        entries,
+	}, nil
+}
+
+// GetExecutionLog returns the nodes the Whatsapp Bot engine ran for a conversation, oldest first
+func (s *WasapbotService) GetExecutionLog(ctx context.Context, userID, prospectID string, limit int) (*models.ExecutionLogResponse, error) {
+	if s.executionLogs == nil {
+		return &models.ExecutionLogResponse{
+			Success: false,
+			Message: "Flow execution logs are not enabled",
+		}, nil
+	}
+
+	conversation, err := s.wasapbotRepo.GetConversationByID(ctx, prospectID)
+	if err != nil || conversation == nil {
+		return &models.ExecutionLogResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	// Verify device ownership
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, conversation.IDDevice)
+	if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
+		return &models.ExecutionLogResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+
+	entries, err := s.executionLogs.GetLogsByConversation(ctx, "wasapbot", prospectID, executionLogLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.FlowExecutionLog{}
+	}
+
+	return &models.ExecutionLogResponse{
+		Success:        true,
+		ConversationID: prospectID,
+		Entries:        entries,
+	}, nil
+}
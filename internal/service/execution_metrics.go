@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// executionAlertRecovery is the share of its threshold a gauge must fall below before a firing
+// alert resolves, so a gauge hovering at the threshold does not alert on every sample
+const executionAlertRecovery = 0.8
+
+// executionAlertTimeout bounds one alert webhook request
+const executionAlertTimeout = 10 * time.Second
+
+// executionGauges count what this instance has in flight. Runs and sends start in several
+// services, so the counters are process-wide rather than owned by one of them.
+var executionGauges struct {
+	activeRuns     atomic.Int64
+	sendQueueDepth atomic.Int64
+}
+
+// trackActiveRun counts a flow run until the returned func is called
+func trackActiveRun() func() {
+	executionGauges.activeRuns.Add(1)
+	return func() { executionGauges.activeRuns.Add(-1) }
+}
+
+// trackSend counts an outbound message until the returned func is called
+func trackSend() func() {
+	executionGauges.sendQueueDepth.Add(1)
+	return func() { executionGauges.sendQueueDepth.Add(-1) }
+}
+
+// ExecutionAlertThresholds are the gauge values that alert the operator. Set them below the
+// point where the instance saturates; 0 disables a gauge's alert.
+type ExecutionAlertThresholds struct {
+	ActiveRuns           int
+	WaitingConversations int
+	SchedulerBacklog     int
+	SendQueueDepth       int
+}
+
+// byGauge returns the enabled thresholds by gauge name
+func (t ExecutionAlertThresholds) byGauge() map[string]int {
+	thresholds := make(map[string]int)
+	for gauge, value := range map[string]int{
+		models.GaugeActiveRuns:           t.ActiveRuns,
+		models.GaugeWaitingConversations: t.WaitingConversations,
+		models.GaugeSchedulerBacklog:     t.SchedulerBacklog,
+		models.GaugeSendQueueDepth:       t.SendQueueDepth,
+	} {
+		if value > 0 {
+			thresholds[gauge] = value
+		}
+	}
+	return thresholds
+}
+
+// ExecutionMonitor samples the flow execution gauges and alerts the operator when one crosses its
+// threshold, and again once it recovers. Alerts are logged and, with an alert URL, POSTed there.
+type ExecutionMonitor struct {
+	processor  *FlowProcessorService
+	thresholds map[string]int
+	alertURL   string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	alerting map[string]bool // gauges with a firing alert
+}
+
+// NewExecutionMonitor creates a new execution monitor
+func NewExecutionMonitor(processor *FlowProcessorService, thresholds ExecutionAlertThresholds, alertURL string) *ExecutionMonitor {
+	return &ExecutionMonitor{
+		processor:  processor,
+		thresholds: thresholds.byGauge(),
+		alertURL:   alertURL,
+		httpClient: &http.Client{Timeout: executionAlertTimeout},
+		alerting:   make(map[string]bool),
+	}
+}
+
+// Start samples the gauges immediately and then every interval until ctx is cancelled
+func (m *ExecutionMonitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if metrics, err := m.Sample(ctx); err != nil {
+				log.Printf("⚠️  Execution metrics sample failed: %v", err)
+			} else {
+				m.checkThresholds(metrics)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Sample reads the current gauges
+func (m *ExecutionMonitor) Sample(ctx context.Context) (*models.ExecutionMetrics, error) {
+	metrics := &models.ExecutionMetrics{
+		ActiveRuns:     executionGauges.activeRuns.Load(),
+		SendQueueDepth: executionGauges.sendQueueDepth.Load(),
+		Thresholds:     m.thresholds,
+		SampledAt:      time.Now(),
+	}
+
+	waitingAI, err := m.processor.convRepo.CountByExecutionStatus(ctx, models.ConversationStateWaiting)
+	if err != nil {
+		return nil, err
+	}
+	waitingBot, err := m.processor.wasapbotRepo.CountByExecutionStatus(ctx, models.ConversationStateWaiting)
+	if err != nil {
+		return nil, err
+	}
+	metrics.WaitingConversations = waitingAI + waitingBot
+
+	if m.processor.delayRepo != nil {
+		if metrics.SchedulerBacklog, err = m.processor.delayRepo.CountDue(ctx, metrics.SampledAt); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	metrics.Alerting = make([]string, 0, len(m.alerting))
+	for gauge := range m.alerting {
+		metrics.Alerting = append(metrics.Alerting, gauge)
+	}
+	m.mu.Unlock()
+	sort.Strings(metrics.Alerting)
+
+	return metrics, nil
+}
+
+// checkThresholds fires an alert for each gauge that reached its threshold and resolves the ones
+// that fell back below executionAlertRecovery of it
+func (m *ExecutionMonitor) checkThresholds(metrics *models.ExecutionMetrics) {
+	values := map[string]int64{
+		models.GaugeActiveRuns:           metrics.ActiveRuns,
+		models.GaugeWaitingConversations: int64(metrics.WaitingConversations),
+		models.GaugeSchedulerBacklog:     int64(metrics.SchedulerBacklog),
+		models.GaugeSendQueueDepth:       metrics.SendQueueDepth,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for gauge, threshold := range m.thresholds {
+		value := values[gauge]
+		switch {
+		case !m.alerting[gauge] && value >= int64(threshold):
+			m.alerting[gauge] = true
+			log.Printf("🚨 Execution gauge %s at %d reached its alert threshold of %d", gauge, value, threshold)
+			m.sendAlert(gauge, models.ExecutionAlertFiring, value, threshold, metrics)
+		case m.alerting[gauge] && float64(value) < float64(threshold)*executionAlertRecovery:
+			delete(m.alerting, gauge)
+			log.Printf("✅ Execution gauge %s recovered to %d (threshold %d)", gauge, value, threshold)
+			m.sendAlert(gauge, models.ExecutionAlertResolved, value, threshold, metrics)
+		}
+	}
+}
+
+// sendAlert POSTs an alert to the operator's alert URL in the background.
+// Failures are only logged; the alert is already in the server log.
+func (m *ExecutionMonitor) sendAlert(gauge, status string, value int64, threshold int, metrics *models.ExecutionMetrics) {
+	if m.alertURL == "" {
+		return
+	}
+
+	alert := models.ExecutionAlert{
+		Gauge:     gauge,
+		Status:    status,
+		Value:     value,
+		Threshold: threshold,
+		Metrics:   metrics,
+		At:        metrics.SampledAt,
+	}
+
+	go func() {
+		payload, err := json.Marshal(alert)
+		if err != nil {
+			log.Printf("⚠️  Failed to marshal execution alert: %v", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), executionAlertTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", m.alertURL, bytes.NewBuffer(payload))
+		if err != nil {
+			log.Printf("⚠️  Failed to create execution alert request: %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := m.httpClient.Do(req)
+		if err != nil {
+			log.Printf("⚠️  Execution alert for %s failed: %v", gauge, err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			log.Printf("⚠️  Execution alert for %s returned HTTP %d", gauge, resp.StatusCode)
+		}
+	}()
+}
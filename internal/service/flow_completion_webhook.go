@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// completionWebhookTimeout bounds one completion webhook request
+const completionWebhookTimeout = 10 * time.Second
+
+// completionPlaceholder matches {{variable}} in a completion webhook template
+var completionPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// hasCompletionWebhook reports whether a flow posts to a webhook when it completes
+func hasCompletionWebhook(flow *models.ChatbotFlow) bool {
+	return flow.CompletionWebhookURL != nil && *flow.CompletionWebhookURL != ""
+}
+
+// completionVariables returns the variables a completion template can reference: every conversation
+// column (prospect_name, alamat, pakej, stage, ...), custom fields, session_data keys, and the aliases
+// nama, phone, disposition (final stage), flow_id, flow_name and completed_at.
+func completionVariables(flow *models.ChatbotFlow, conversation interface{}) map[string]interface{} {
+	vars := conversationVariables(conversation)
+	vars["nama"] = vars["prospect_name"]
+	vars["phone"] = vars["prospect_num"]
+	vars["disposition"] = vars["stage"]
+	vars["flow_id"] = flow.ID
+	vars["flow_name"] = flow.Name
+	vars["completed_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	return vars
+}
+
+// renderCompletionPayload fills a JSON template with conversation variables.
+// Placeholders are replaced with the JSON-escaped value, so "nama": "{{nama}}" stays valid JSON;
+// unknown variables become empty. An empty template sends every variable as a flat JSON object.
+func renderCompletionPayload(template string, vars map[string]interface{}) ([]byte, error) {
+	if strings.TrimSpace(template) == "" {
+		return json.Marshal(vars)
+	}
+
+	rendered := completionPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := completionPlaceholder.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok || value == nil {
+			return ""
+		}
+
+		text, isString := value.(string)
+		if !isString {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return ""
+			}
+			text = string(data)
+		}
+
+		// Escape for use inside a JSON string, without the surrounding quotes
+		escaped, _ := json.Marshal(text)
+		return string(escaped[1 : len(escaped)-1])
+	})
+
+	if !json.Valid([]byte(rendered)) {
+		return nil, fmt.Errorf("completion webhook template does not render to valid JSON")
+	}
+	return []byte(rendered), nil
+}
+
+// validateCompletionWebhook checks a flow's completion webhook settings.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateCompletionWebhook(webhookURL, template *string) string {
+	if webhookURL != nil && *webhookURL != "" && !validHeartbeatURL(*webhookURL) {
+		return "completion_webhook_url must be an http or https URL"
+	}
+	if template != nil && strings.TrimSpace(*template) != "" {
+		if _, err := renderCompletionPayload(*template, map[string]interface{}{}); err != nil {
+			return "completion_webhook_template must be a JSON document; use {{variable}} inside strings, e.g. {\"nama\": \"{{nama}}\"}"
+		}
+	}
+	return ""
+}
+
+// sendCompletionWebhook POSTs the rendered template for a completed conversation in the background.
+// Failures are only logged; the flow has already completed.
+func sendCompletionWebhook(flow *models.ChatbotFlow, conversationID string, conversation interface{}) {
+	if !hasCompletionWebhook(flow) {
+		return
+	}
+
+	payload, err := renderCompletionPayload(getStringValue(flow.CompletionWebhookTemplate), completionVariables(flow, conversation))
+	if err != nil {
+		log.Printf("⚠️  Completion webhook for flow %s skipped: %v", flow.ID, err)
+		return
+	}
+	endpoint := *flow.CompletionWebhookURL
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), completionWebhookTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payload))
+		if err != nil {
+			log.Printf("⚠️  Failed to create completion webhook request for flow %s: %v", flow.ID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Printf("⚠️  Completion webhook for conversation %s failed: %v", conversationID, err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			log.Printf("⚠️  Completion webhook for conversation %s returned HTTP %d", conversationID, resp.StatusCode)
+			return
+		}
+		log.Printf("📤 Completion webhook sent for conversation %s (flow %s)", conversationID, flow.ID)
+	}()
+}
+
+// notifyFlowCompleted emits flow.completed and fires the flow's completion webhook for the run's
+// conversation
+func (r *flowRuntime) notifyFlowCompleted(ctx context.Context, flow *models.ChatbotFlow, conversationID string) {
+	if !hasCompletionWebhook(flow) && !r.emitsEvents() {
+		return
+	}
+	conversation, err := r.load(ctx, conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Completion webhook skipped, failed to load conversation %s: %v", conversationID, err)
+		return
+	}
+
+	if r.emitsEvents() {
+		data := conversationEventData(r.table, conversationID, conversation.row)
+		data["flow_id"] = flow.ID
+		data["flow_name"] = flow.Name
+		r.emitEvent(ctx, flow.IDDevice, models.EventFlowCompleted, data)
+	}
+	if !hasCompletionWebhook(flow) {
+		return
+	}
+	if r.sim != nil {
+		r.sim.completionWebhook(flow, conversation.row)
+		return
+	}
+	sendCompletionWebhook(flow, conversationID, conversation.row)
+}
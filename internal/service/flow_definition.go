@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"chatbot-automation/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CompileFlowDefinition converts a YAML or JSON flow definition to nodes_data and validates the
+// result, without saving anything
+func (s *FlowService) CompileFlowDefinition(req *models.CompileFlowDefinitionRequest) *models.CompileFlowDefinitionResponse {
+	def, issues := parseFlowDefinition(req.Definition)
+
+	resp := &models.CompileFlowDefinitionResponse{
+		Success: true,
+	}
+	if def != nil {
+		var nodesData string
+		nodesData, issues = compileFlowDefinition(def)
+		if flowValidationErrors(issues) == "" {
+			issues = append(issues, validateFlowData(nodesData)...)
+		}
+		if flowValidationErrors(issues) == "" {
+			resp.NodesData = nodesData
+			resp.Name = def.Name
+			resp.Niche = def.Niche
+			resp.Settings = def.Settings
+			resp.Triggers = def.Triggers
+		}
+	}
+
+	resp.Issues = issues
+	for _, issue := range issues {
+		if issue.Severity == models.FlowIssueError {
+			resp.Errors++
+		} else {
+			resp.Warnings++
+		}
+	}
+	resp.Valid = resp.Errors == 0
+	resp.Message = fmt.Sprintf("%d errors, %d warnings", resp.Errors, resp.Warnings)
+	return resp
+}
+
+// GetFlowDefinition returns a saved flow as a flow definition, the reverse of CompileFlowDefinition
+func (s *FlowService) GetFlowDefinition(ctx context.Context, userID, flowID string) (*models.FlowDefinitionResponse, error) {
+	// GetFlow verifies ownership
+	resp, err := s.GetFlow(ctx, userID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return &models.FlowDefinitionResponse{
+			Success: false,
+			Message: resp.Message,
+		}, nil
+	}
+
+	def, err := decompileFlow(resp.Flow)
+	if err != nil {
+		return &models.FlowDefinitionResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	text, err := marshalFlowDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FlowDefinitionResponse{
+		Success:    true,
+		Message:    "Flow definition generated",
+		Definition: def,
+		YAML:       text,
+	}, nil
+}
+
+// parseFlowDefinition reads a definition in YAML or JSON (JSON is valid YAML). Unknown fields
+// are errors, so a misspelt key does not silently drop part of the flow.
+func parseFlowDefinition(text string) (*models.FlowDefinition, []models.FlowValidationIssue) {
+	invalid := func(message string) []models.FlowValidationIssue {
+		return []models.FlowValidationIssue{{
+			Severity: models.FlowIssueError,
+			Code:     models.FlowIssueInvalidDefinition,
+			Message:  message,
+		}}
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return nil, invalid("Flow definition is empty")
+	}
+
+	var def models.FlowDefinition
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil {
+		return nil, invalid(fmt.Sprintf("Flow definition could not be parsed: %v", err))
+	}
+
+	if def.Version == 0 {
+		def.Version = models.FlowDefinitionVersion
+	}
+	if def.Version != models.FlowDefinitionVersion {
+		return nil, invalid(fmt.Sprintf("Flow definition version %d is not supported (expected %d)", def.Version, models.FlowDefinitionVersion))
+	}
+	if def.Settings != nil && def.Settings.CompletionPolicy != "" && !def.Settings.CompletionPolicy.IsValid() {
+		return nil, invalid(fmt.Sprintf("Unknown completion_policy %q", def.Settings.CompletionPolicy))
+	}
+	if msg := validateFlowTriggers(def.Triggers); msg != "" {
+		return nil, invalid(msg)
+	}
+
+	return &def, nil
+}
+
+// compileFlowDefinition builds nodes_data from a definition. Structural checks shared with the
+// builder (start node, dangling connections, cycles) are left to validateFlowData; this reports
+// what only a definition can get wrong. When any node has no position the flow is auto laid out.
+func compileFlowDefinition(def *models.FlowDefinition) (string, []models.FlowValidationIssue) {
+	issues := []models.FlowValidationIssue{}
+	add := func(code, nodeID, message string) {
+		issues = append(issues, models.FlowValidationIssue{Severity: models.FlowIssueError, Code: code, NodeID: nodeID, Message: message})
+	}
+
+	flowData := FlowData{
+		Nodes:       make([]FlowNode, 0, len(def.Nodes)),
+		Connections: []FlowEdge{},
+	}
+	positioned := true
+
+	for i, n := range def.Nodes {
+		if n.ID == "" {
+			add(models.FlowIssueInvalidDefinition, "", fmt.Sprintf("Node %d has no id", i+1))
+			continue
+		}
+		if n.Type == "" {
+			add(models.FlowIssueInvalidDefinition, n.ID, fmt.Sprintf("Node %q has no type", n.ID))
+		}
+
+		config := make(map[string]interface{}, len(n.Config)+1)
+		for key, value := range n.Config {
+			config[key] = value
+		}
+		if n.Template != "" {
+			text, ok := def.Templates[n.Template]
+			switch {
+			case !ok:
+				add(models.FlowIssueUnknownTemplate, n.ID, fmt.Sprintf("Node %q uses template %q, which is not defined", n.ID, n.Template))
+			case config["text"] != nil:
+				add(models.FlowIssueInvalidDefinition, n.ID, fmt.Sprintf("Node %q sets both template and config.text", n.ID))
+			default:
+				config["text"] = text
+			}
+		}
+
+		node := FlowNode{
+			ID:     n.ID,
+			Type:   n.Type,
+			Label:  n.Label,
+			Config: config,
+		}
+		if n.Position != nil {
+			node.X = n.Position.X
+			node.Y = n.Position.Y
+		} else {
+			positioned = false
+		}
+		flowData.Nodes = append(flowData.Nodes, node)
+
+		for _, to := range n.Next {
+			flowData.Connections = append(flowData.Connections, FlowEdge{From: n.ID, To: to})
+		}
+		for _, branch := range n.Branches {
+			if branch.When == "" || branch.To == "" {
+				add(models.FlowIssueInvalidDefinition, n.ID, fmt.Sprintf("Branch of node %q needs both when and to", n.ID))
+				continue
+			}
+			flowData.Connections = append(flowData.Connections, FlowEdge{
+				From:              n.ID,
+				To:                branch.To,
+				ConditionType:     branch.When,
+				ConditionValue:    branch.Value,
+				ConditionVariable: branch.Variable,
+			})
+		}
+	}
+
+	if len(issues) > 0 {
+		return "", issues
+	}
+
+	data, err := json.Marshal(flowData)
+	if err != nil {
+		add(models.FlowIssueInvalidDefinition, "", fmt.Sprintf("Flow definition could not be compiled: %v", err))
+		return "", issues
+	}
+	nodesData := string(data)
+
+	if !positioned && len(flowData.Nodes) > 0 {
+		if laidOut, err := autoLayoutNodesData(nodesData, &models.AutoLayoutRequest{}); err == nil {
+			nodesData = laidOut
+		}
+	}
+
+	return nodesData, issues
+}
+
+// decompileFlow turns a saved flow into a definition. Connections without a condition become
+// next, the others branches; node text stays in config since templates cannot be recovered.
+func decompileFlow(flow *models.ChatbotFlow) (*models.FlowDefinition, error) {
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return nil, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	def := &models.FlowDefinition{
+		Version:  models.FlowDefinitionVersion,
+		Name:     flow.Name,
+		Niche:    flow.Niche,
+		Triggers: flow.Triggers,
+		Nodes:    make([]models.FlowDefinitionNode, 0, len(flowData.Nodes)),
+	}
+
+	settings := models.FlowDefinitionSettings{
+		CompletionPolicy:          flow.CompletionPolicy,
+		AfterSalesFlowID:          flow.AfterSalesFlowID,
+		CompletionWebhookURL:      flow.CompletionWebhookURL,
+		CompletionWebhookTemplate: flow.CompletionWebhookTemplate,
+		ReentryCooldownHours:      flow.ReentryCooldownHours,
+		NormalizeMalay:            flow.NormalizeMalay,
+	}
+	if settings != (models.FlowDefinitionSettings{}) {
+		def.Settings = &settings
+	}
+
+	index := make(map[string]int, len(flowData.Nodes))
+	for _, node := range flowData.Nodes {
+		n := models.FlowDefinitionNode{
+			ID:       node.ID,
+			Type:     node.Type,
+			Label:    node.Label,
+			Position: &models.FlowDefinitionPosition{X: node.X, Y: node.Y},
+		}
+		if len(node.Config) > 0 {
+			n.Config = node.Config
+		}
+		index[node.ID] = len(def.Nodes)
+		def.Nodes = append(def.Nodes, n)
+	}
+
+	for _, edge := range flowData.Connections {
+		i, ok := index[edge.From]
+		if !ok {
+			continue
+		}
+		if edge.ConditionType == "" {
+			def.Nodes[i].Next = append(def.Nodes[i].Next, edge.To)
+			continue
+		}
+		def.Nodes[i].Branches = append(def.Nodes[i].Branches, models.FlowDefinitionBranch{
+			When:     edge.ConditionType,
+			Value:    edge.ConditionValue,
+			Variable: edge.ConditionVariable,
+			To:       edge.To,
+		})
+	}
+
+	return def, nil
+}
+
+// marshalFlowDefinition writes a definition as YAML with two-space indentation
+func marshalFlowDefinition(def *models.FlowDefinition) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(def); err != nil {
+		return "", fmt.Errorf("failed to write flow definition: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to write flow definition: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,669 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// GetFlowDoc describes what a flow does and says. The device's stage configs are included so
+// stage nodes show the fields they capture; the owner's current flow order decides whether the
+// flow receives messages at all.
+func (s *FlowService) GetFlowDoc(ctx context.Context, userID, flowID string) (*models.FlowDocResponse, error) {
+	// GetFlow verifies ownership
+	resp, err := s.GetFlow(ctx, userID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return &models.FlowDocResponse{
+			Success: false,
+			Message: resp.Message,
+		}, nil
+	}
+	flow := resp.Flow
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return &models.FlowDocResponse{
+			Success: false,
+			Message: fmt.Sprintf("Flow data is not valid JSON: %v", err),
+		}, nil
+	}
+
+	env := flowDocEnv{stageConfigs: make(map[string]models.StageValue)}
+	if device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, flow.IDDevice); err == nil && device != nil {
+		env.device = device
+		env.deviceModel = device.APIKeyOption
+		env.hasPersona = device.AIPersona != nil && !device.AIPersona.IsEmpty()
+	}
+	if flows, err := s.flowRepo.GetFlowsByDeviceID(ctx, flow.IDDevice); err == nil {
+		if active := activeFlowsByPriority(flows); len(active) > 0 {
+			env.activeFlow = catchAllFlow(active)
+		}
+	}
+	if stages, err := s.stageRepo.GetStageValuesByDevice(ctx, flow.IDDevice); err == nil {
+		for _, stage := range stages {
+			env.stageConfigs[stage.Stage] = stage
+		}
+	}
+
+	doc := buildFlowDoc(flow, &flowData, env)
+	return &models.FlowDocResponse{
+		Success:  true,
+		Doc:      doc,
+		Markdown: renderFlowDocMarkdown(doc),
+	}, nil
+}
+
+// flowDocEnv is the device context a flow runs in
+type flowDocEnv struct {
+	device       *models.DeviceSetting
+	deviceModel  string
+	hasPersona   bool
+	activeFlow   *models.ChatbotFlow // the device's catch-all flow, which receives messages no trigger matched
+	stageConfigs map[string]models.StageValue
+}
+
+// flowEntryNode picks the node a new conversation starts at, by the same rule as the engines'
+// findStartingNode: the first non-start node without incoming connections, else the first node
+func flowEntryNode(flowData *FlowData) *FlowNode {
+	incoming := make(map[string]bool, len(flowData.Connections))
+	for _, edge := range flowData.Connections {
+		incoming[edge.To] = true
+	}
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		if !strings.Contains(strings.ToLower(node.Type), "start") && !incoming[node.ID] {
+			return node
+		}
+	}
+	if len(flowData.Nodes) > 0 {
+		return &flowData.Nodes[0]
+	}
+	return nil
+}
+
+// buildFlowDoc walks the flow breadth-first from its entry node so steps read in the order
+// a prospect meets them; nodes the walk never reaches are listed separately
+func buildFlowDoc(flow *models.ChatbotFlow, flowData *FlowData, env flowDocEnv) *models.FlowDoc {
+	engine := flowEngineType(flow)
+	doc := &models.FlowDoc{
+		FlowID:         flow.ID,
+		FlowName:       flow.Name,
+		Niche:          flow.Niche,
+		IDDevice:       flow.IDDevice,
+		Engine:         engine,
+		GeneratedAt:    time.Now(),
+		Steps:          []models.FlowDocStep{},
+		Messages:       []models.FlowDocMessage{},
+		FieldsCaptured: []models.FlowDocField{},
+		AIPrompts:      []models.FlowDocPrompt{},
+	}
+
+	entry := flowEntryNode(flowData)
+	doc.Entry = flowDocEntry(flow, entry, env)
+
+	outgoing := make(map[string][]FlowEdge, len(flowData.Nodes))
+	for _, edge := range flowData.Connections {
+		outgoing[edge.From] = append(outgoing[edge.From], edge)
+	}
+
+	visited := make(map[string]bool, len(flowData.Nodes))
+	var queue []string
+	if entry != nil {
+		visited[entry.ID] = true
+		queue = append(queue, entry.ID)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		node := findFlowNode(flowData, id)
+		if node == nil {
+			continue
+		}
+
+		doc.Steps = append(doc.Steps, describeFlowNode(doc, node, outgoing[id], engine, env))
+		for _, edge := range outgoing[id] {
+			if !visited[edge.To] {
+				visited[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		if visited[node.ID] || strings.Contains(strings.ToLower(node.Type), "start") {
+			continue
+		}
+		doc.Unreachable = append(doc.Unreachable, models.FlowDocStep{
+			NodeID:      node.ID,
+			Type:        node.Type,
+			Label:       node.Label,
+			Description: "Never reached: no connection leads here from the entry node.",
+		})
+	}
+
+	return doc
+}
+
+func findFlowNode(flowData *FlowData, id string) *FlowNode {
+	for i := range flowData.Nodes {
+		if flowData.Nodes[i].ID == id {
+			return &flowData.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// flowDocEntry explains when the flow runs and how conversations enter and leave it
+func flowDocEntry(flow *models.ChatbotFlow, entry *FlowNode, env flowDocEnv) []string {
+	var lines []string
+
+	switch {
+	case !flow.Active():
+		lines = append(lines, "The flow is deactivated: it takes no new prospects, and conversations already in it continue.")
+	case env.activeFlow == nil:
+		lines = append(lines, fmt.Sprintf("Device %s has no active flows, so this flow does not receive messages.", flow.IDDevice))
+	case len(flow.Triggers) > 0:
+		conditions := make([]string, 0, len(flow.Triggers))
+		for _, trigger := range flow.Triggers {
+			conditions = append(conditions, describeFlowTrigger(trigger))
+		}
+		lines = append(lines, fmt.Sprintf("Starts on device %s when %s; a matching keyword also moves a prospect here from another flow.", flow.IDDevice, strings.Join(conditions, ", or when ")))
+		if env.activeFlow.ID == flow.ID {
+			lines = append(lines, "Every flow of the device has triggers, so this flow also receives the messages no trigger matched.")
+		}
+	case env.activeFlow.ID == flow.ID:
+		lines = append(lines, fmt.Sprintf("Runs for every inbound message to device %s that no other flow's trigger matched (it is the device's highest priority active flow without triggers).", flow.IDDevice))
+	default:
+		lines = append(lines, fmt.Sprintf("Does not receive new messages: device %s runs its highest priority active flow without triggers, %q. This flow is only used when a conversation is routed to it (e.g. as an after-sales flow).", flow.IDDevice, env.activeFlow.Name))
+	}
+
+	if flowEngineType(flow) == "Chatbot AI" {
+		lines = append(lines, "Runs on the Chatbot AI engine (name or niche contains \"ai\" or \"chatbot\"); conversations are stored in ai_whatsapp.")
+	} else {
+		lines = append(lines, "Runs on the Whatsapp Bot engine; conversations are stored in wasapbot.")
+	}
+
+	if entry == nil {
+		lines = append(lines, "The flow has no nodes, so nothing is sent.")
+	} else {
+		lines = append(lines, fmt.Sprintf("New prospects start at %s.", flowDocNodeName(entry)))
+	}
+	lines = append(lines,
+		"A prospect waiting for a reply resumes at the step where the flow paused.",
+		"Conversations in human handoff are not answered by the bot.")
+
+	switch flow.EffectiveCompletionPolicy() {
+	case models.CompletionPolicyRestart:
+		if flow.ReentryCooldownHours > 0 {
+			lines = append(lines, fmt.Sprintf("When a prospect messages again after the flow completed, the flow restarts from the beginning, at most once every %d hours; until then the bot stays silent.", flow.ReentryCooldownHours))
+		} else {
+			lines = append(lines, "When a prospect messages again after the flow completed, the flow restarts from the beginning.")
+		}
+	case models.CompletionPolicyAfterSales:
+		lines = append(lines, fmt.Sprintf("When a prospect messages again after the flow completed, the conversation moves to the after-sales flow %s.", getStringValue(flow.AfterSalesFlowID)))
+	case models.CompletionPolicyHandoff:
+		lines = append(lines, "When a prospect messages again after the flow completed, the conversation is handed to a human agent.")
+	default:
+		lines = append(lines, "When a prospect messages again after the flow completed, the bot stays silent.")
+	}
+
+	if hasCompletionWebhook(flow) {
+		lines = append(lines, fmt.Sprintf("On completion the conversation is posted to %s.", *flow.CompletionWebhookURL))
+	}
+
+	if flow.NormalizeMalay {
+		lines = append(lines, "Conditions also match Malay chat spellings, so \"tak\" matches \"tidak\" and \"yaaa\" matches \"ya\".")
+	}
+
+	return lines
+}
+
+func flowDocNodeName(node *FlowNode) string {
+	if node.Label != "" {
+		return fmt.Sprintf("%q (%s, %s)", node.Label, node.Type, node.ID)
+	}
+	return fmt.Sprintf("%s (%s)", node.Type, node.ID)
+}
+
+// describeFlowNode explains one node and records the messages, fields and prompts it contributes
+func describeFlowNode(doc *models.FlowDoc, node *FlowNode, edges []FlowEdge, engine string, env flowDocEnv) models.FlowDocStep {
+	step := models.FlowDocStep{
+		NodeID: node.ID,
+		Type:   node.Type,
+		Label:  node.Label,
+	}
+	text, _ := node.Config["text"].(string)
+
+	addMessage := func(kind, content string) {
+		if strings.TrimSpace(content) != "" {
+			doc.Messages = append(doc.Messages, models.FlowDocMessage{NodeID: node.ID, Kind: kind, Content: content})
+		}
+	}
+	addField := func(field, source string) {
+		doc.FieldsCaptured = append(doc.FieldsCaptured, models.FlowDocField{NodeID: node.ID, Field: field, Source: source})
+	}
+
+	switch node.Type {
+	case "send_message":
+		templateName, _ := node.Config["message_template"].(string)
+		if name, legacy := legacyMessageTemplates[text]; legacy && engine == "Whatsapp Bot" {
+			templateName = name
+		}
+		if templateName = strings.TrimSpace(templateName); templateName != "" {
+			step.Description = fmt.Sprintf("Sends the %s message template.", templateName)
+			if builtin := builtinMessageTemplate(templateName); builtin != nil {
+				step.Description = fmt.Sprintf("Sends the %s message template (built-in content quoted under messages sent, unless the owner replaced it).", templateName)
+				addMessage("template", builtin.Content)
+			}
+			break
+		}
+		if text == "" {
+			step.Description = "Sends nothing: no text is configured."
+			break
+		}
+		step.Description = "Sends a text message (quoted under messages sent)."
+		addMessage("text", text)
+
+	case "delay":
+		delay := 3
+		if v, ok := node.Config["delay"].(float64); ok {
+			delay = int(v)
+		}
+		step.Description = fmt.Sprintf("Waits %d seconds%s.", delay, followUpTimingNote(node, delay))
+
+	case "waiting_reply":
+		step.Description = "Pauses until the prospect replies; the reply is passed to the next step."
+
+	case "waiting_times":
+		timeout := 8
+		if v, ok := node.Config["delay"].(float64); ok {
+			timeout = int(v)
+		}
+		step.Description = fmt.Sprintf("Waits %d seconds%s, then continues.", timeout, followUpTimingNote(node, timeout))
+
+	case "ai_prompt":
+		model := aiPromptModel(node)
+		if model == "" {
+			model = env.deviceModel
+		}
+		var options []string
+		if autoTranslate, _ := node.Config["auto_translate"].(bool); autoTranslate {
+			options = append(options, "prospect messages are translated into the working language before the AI sees them")
+		}
+		if n, _ := node.Config["n_best"].(float64); n >= 2 {
+			options = append(options, fmt.Sprintf("generates up to %d candidate replies and sends the best scored", int(n)))
+		}
+		if aiJSONRepairEnabled(node) {
+			options = append(options, "an unparseable reply is retried once with a JSON repair prompt")
+		}
+		if template := aiPromptTemplateName(node, env.device); template != models.BuiltinPromptTemplate {
+			options = append(options, fmt.Sprintf("uses prompt template %s instead of the built-in instructions", template))
+		}
+		if env.hasPersona {
+			options = append(options, "the device persona is added to the prompt")
+		}
+		doc.AIPrompts = append(doc.AIPrompts, models.FlowDocPrompt{NodeID: node.ID, Model: model, Prompt: text, Options: options})
+		step.Description = fmt.Sprintf("Replies with AI (model %s) using the prompt listed under AI prompts; the reply's Stage sets the conversation stage.", model)
+		addField("stage", "Stage returned by the AI")
+
+	case "stage":
+		stage, _ := node.Config["value"].(string)
+		step.Description = fmt.Sprintf("Sets the stage to %q.", stage)
+		addField("stage", fmt.Sprintf("set to %q", stage))
+		if config, ok := env.stageConfigs[stage]; ok {
+			column := normalizeColumnName(config.ColumnsData)
+			switch config.TypeInputData {
+			case "Set":
+				step.Description += fmt.Sprintf(" Also sets %s to %q.", column, config.InputHardCode)
+				addField(column, fmt.Sprintf("set to %q", config.InputHardCode))
+			case "Input":
+				step.Description += fmt.Sprintf(" Saves the prospect's last reply as %s.", column)
+				addField(column, "prospect's last reply")
+			}
+		}
+
+	case "send_image", "send_audio", "send_video":
+		url, _ := node.Config["url"].(string)
+		kind := strings.TrimPrefix(node.Type, "send_")
+		step.Description = fmt.Sprintf("Sends %s %s", flowDocArticle(kind), url)
+		addMessage(kind, url)
+
+	case "media_switch":
+		field := mediaSwitchField(node.Config)
+		var variants []string
+		if list, ok := node.Config["variants"].([]interface{}); ok {
+			for _, raw := range list {
+				if variant, ok := raw.(map[string]interface{}); ok {
+					url, _ := variant["url"].(string)
+					variants = append(variants, fmt.Sprintf("%v: %s", variant["value"], url))
+					addMessage("media", url)
+				}
+			}
+		}
+		step.Description = fmt.Sprintf("Sends media chosen by %s (%s)", field, strings.Join(variants, "; "))
+		if url, _ := node.Config["default_url"].(string); url != "" {
+			step.Description += fmt.Sprintf(", otherwise %s", url)
+			addMessage("media", url)
+		}
+		step.Description += "."
+
+	case "translate":
+		target := translateTargetLanguage(node)
+		if target == "" {
+			target = "the device working language"
+		}
+		step.Description = fmt.Sprintf("Translates the prospect's message into %s for the following steps.", target)
+
+	case "conditions":
+		step.Description = "Branches on the prospect's reply (first matching branch wins; with no match and no \"otherwise\" branch, a random branch is taken)."
+
+	case flowForkNode:
+		step.Description = "Runs every branch at the same time; the branches meet again at the next join step."
+
+	case flowABSplitNode:
+		step.Description = "Splits prospects between the branches by their weights for an A/B test; each prospect always gets the same branch."
+
+	case flowBusinessHoursNode:
+		hours := configText(node.Config["hours"])
+		if hours == "" {
+			hours = "the device's business hours"
+		}
+		step.Description = fmt.Sprintf("Takes the open branch during %s (device timezone) and the closed branch otherwise.", hours)
+
+	case flowSetVariableNode:
+		var sets []string
+		for _, assignment := range setVariableAssignments(node) {
+			sets = append(sets, fmt.Sprintf("%s = %s", assignment.Name, assignment.Value))
+			addField(assignment.Name, fmt.Sprintf("set to %s", assignment.Value))
+		}
+		step.Description = fmt.Sprintf("Sets the variables %s.", strings.Join(sets, "; "))
+
+	case flowEmitEventNode:
+		var fields []string
+		for key := range emitEventFields(node) {
+			fields = append(fields, key)
+		}
+		sort.Strings(fields)
+		step.Description = fmt.Sprintf("Sends the event %s to the owner's event webhooks.", emitEventName(node))
+		if len(fields) > 0 {
+			step.Description = fmt.Sprintf("Sends the event %s to the owner's event webhooks with %s.", emitEventName(node), strings.Join(fields, ", "))
+		}
+
+	case flowSendButtonsNode, flowSendListNode:
+		message := interactiveMessage(node)
+		text, _ := node.Config["text"].(string)
+		message.Body = text
+		var titles []string
+		for _, option := range interactiveOptions(message) {
+			titles = append(titles, option.Title)
+		}
+		kind := "buttons"
+		if node.Type == flowSendListNode {
+			kind = "list"
+		}
+		step.Description = fmt.Sprintf("Sends a %s message with the options %s, then waits for the prospect's choice.", kind, strings.Join(titles, ", "))
+		addMessage(kind, message.InteractiveText())
+
+	case flowSendLocationNode:
+		location := nodeLocation(node, nil)
+		if location == nil {
+			step.Description = fmt.Sprintf("Sends the location pin at %s, %s.", configText(node.Config["latitude"]), configText(node.Config["longitude"]))
+			break
+		}
+		step.Description = fmt.Sprintf("Sends the location pin %s.", location.Label())
+		addMessage("location", location.Text())
+
+	case flowSendContactNode:
+		step.Description = fmt.Sprintf("Sends the contact card of %s.", configText(node.Config["name"]))
+		if card := nodeContactCard(node, nil); card != nil {
+			addMessage("contact", card.Text())
+		}
+
+	case flowJoinNode:
+		if waitFor := joinWaitFor(node); len(waitFor) > 0 {
+			step.Description = fmt.Sprintf("Waits for the branches starting at %s, then continues.", strings.Join(waitFor, ", "))
+		} else {
+			step.Description = "Waits for every branch of the fork, then continues."
+		}
+
+	case "csat":
+		step.Description = "Asks for a satisfaction rating from 1 to 5."
+		addMessage("csat_question", csatQuestion(node))
+		addMessage("csat_retry", csatRetryMessage(node))
+		addMessage("csat_thanks", csatThanksMessage(node))
+		addField("csat_score", "prospect's 1-5 rating")
+
+	case "send_email":
+		to, _ := node.Config["to"].(string)
+		subject, _ := node.Config["subject"].(string)
+		body, _ := node.Config["body"].(string)
+		step.Description = fmt.Sprintf("Emails %s from the owner's email account, subject %q (body quoted under messages sent).", to, subject)
+		addMessage("email", body)
+
+	case "consent":
+		step.Description = "Asks for consent and records the decision; the flow continues either way."
+		addMessage("consent_question", consentQuestion(node))
+		addMessage("consent_granted", consentReplyMessage(node, true))
+		addMessage("consent_declined", consentReplyMessage(node, false))
+		addField("consent", "consent_records (granted or declined)")
+
+	default:
+		if strings.Contains(strings.ToLower(node.Type), "start") {
+			step.Description = "Start of the flow."
+			break
+		}
+		step.Description = fmt.Sprintf("Unknown node type %q: skipped by the engine.", node.Type)
+	}
+
+	if milestone := nodeMilestone(node); milestone != "" {
+		step.Description += fmt.Sprintf(" Milestone: %s.", milestone)
+	}
+
+	for i, edge := range edges {
+		branch := models.FlowDocBranch{To: edge.To}
+		if node.Type == "conditions" {
+			branch.Condition = describeFlowCondition(edge)
+		} else if node.Type == flowForkNode {
+			branch.Condition = "runs in parallel"
+		} else if node.Type == flowABSplitNode {
+			branch.Condition = describeABSplitBranch(edge, edges)
+		} else if node.Type == flowSendButtonsNode || node.Type == flowSendListNode {
+			branch.Condition = describeInteractiveBranch(edge)
+		} else if i > 0 {
+			branch.Condition = "ignored: only the first connection of a non-conditions step is followed"
+		}
+		step.Branches = append(step.Branches, branch)
+	}
+	if len(edges) == 0 && node.Type != "waiting_reply" {
+		step.Description += " The flow ends here."
+	}
+
+	return step
+}
+
+// describeInteractiveBranch phrases a connection out of a send_buttons or send_list node the way
+// interactiveEdge reads it
+func describeInteractiveBranch(edge FlowEdge) string {
+	switch {
+	case strings.EqualFold(edge.ConditionType, ConditionButton):
+		return fmt.Sprintf("prospect picks option %s", edge.ConditionValue)
+	case strings.EqualFold(edge.ConditionType, "default"):
+		return "prospect picks an option without its own branch, or replies something else"
+	case edge.ConditionType == "":
+		return "any choice without its own branch"
+	}
+	return fmt.Sprintf("ignored: %s is not a button condition", edge.ConditionType)
+}
+
+// describeFlowCondition phrases a conditions edge
+func describeFlowCondition(edge FlowEdge) string {
+	subject := "reply"
+	if edge.ConditionVariable != "" {
+		subject = "field " + edge.ConditionVariable
+	}
+
+	switch strings.ToLower(edge.ConditionType) {
+	case "equal":
+		return fmt.Sprintf("%s is %q (ignoring case, emoji and punctuation)", subject, edge.ConditionValue)
+	case "contains", "match":
+		return fmt.Sprintf("%s contains %q (ignoring case, emoji and punctuation)", subject, edge.ConditionValue)
+	case ConditionRegex:
+		return fmt.Sprintf("%s matches /%s/", subject, edge.ConditionValue)
+	case ConditionStartsWith:
+		return fmt.Sprintf("%s starts with %q (ignoring case, emoji and punctuation)", subject, edge.ConditionValue)
+	case ConditionEndsWith:
+		return fmt.Sprintf("%s ends with %q (ignoring case, emoji and punctuation)", subject, edge.ConditionValue)
+	case ConditionGreaterThan:
+		return fmt.Sprintf("number in %s is greater than %s", subject, edge.ConditionValue)
+	case ConditionLessThan:
+		return fmt.Sprintf("number in %s is less than %s", subject, edge.ConditionValue)
+	case ConditionGreaterOrEqual:
+		return fmt.Sprintf("number in %s is at least %s", subject, edge.ConditionValue)
+	case ConditionLessOrEqual:
+		return fmt.Sprintf("number in %s is at most %s", subject, edge.ConditionValue)
+	case ConditionInList:
+		return fmt.Sprintf("%s is one of %s", subject, strings.Join(conditionListItems(edge.ConditionValue), ", "))
+	case "default":
+		return "otherwise"
+	case "":
+		return "no condition set: only taken at random"
+	}
+	if strings.EqualFold(edge.ConditionType, ConditionRepliedTo) {
+		if strings.EqualFold(strings.TrimSpace(edge.ConditionValue), "any") {
+			return "reply quotes any message the bot sent"
+		}
+		return fmt.Sprintf("reply quotes a message sent by node %s", edge.ConditionValue)
+	}
+	if strings.EqualFold(edge.ConditionType, ConditionButton) {
+		return fmt.Sprintf("prospect picks option %s", edge.ConditionValue)
+	}
+	if strings.EqualFold(edge.ConditionType, ConditionMedia) {
+		if strings.EqualFold(strings.TrimSpace(edge.ConditionValue), models.MessageTypeLocation) {
+			return "message shares a location"
+		}
+		if strings.EqualFold(strings.TrimSpace(edge.ConditionValue), "any") {
+			return "message has an attachment (image, voice note, document, ...)"
+		}
+		return fmt.Sprintf("message has an attachment of type %s", edge.ConditionValue)
+	}
+	if strings.EqualFold(edge.ConditionType, ConditionField) {
+		return fmt.Sprintf("field %s", edge.ConditionValue)
+	}
+	if strings.EqualFold(edge.ConditionType, ConditionReturningProspect) {
+		if evaluateReturningProspect(edge.ConditionValue, true) {
+			return "prospect has been through a flow before"
+		}
+		return "prospect is going through a flow for the first time"
+	}
+	if isTimeCondition(edge.ConditionType) {
+		return fmt.Sprintf("%s %s (device timezone)", strings.ReplaceAll(edge.ConditionType, "_", " "), edge.ConditionValue)
+	}
+	return fmt.Sprintf("unknown condition %s %q: never matches", edge.ConditionType, edge.ConditionValue)
+}
+
+func flowDocArticle(kind string) string {
+	if kind == "image" || kind == "audio" {
+		return "an " + kind
+	}
+	return "a " + kind
+}
+
+// renderFlowDocMarkdown renders the documentation as Markdown
+func renderFlowDocMarkdown(doc *models.FlowDoc) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", doc.FlowName)
+	fmt.Fprintf(&b, "Flow `%s` on device `%s`", doc.FlowID, doc.IDDevice)
+	if doc.Niche != "" {
+		fmt.Fprintf(&b, ", niche %s", doc.Niche)
+	}
+	fmt.Fprintf(&b, ". Generated %s.\n\n", doc.GeneratedAt.UTC().Format(time.RFC3339))
+
+	b.WriteString("## Entry\n\n")
+	for _, line := range doc.Entry {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+
+	b.WriteString("\n## Steps\n\n")
+	for i, step := range doc.Steps {
+		writeFlowDocStep(&b, fmt.Sprintf("%d.", i+1), step)
+	}
+
+	if len(doc.Messages) > 0 {
+		b.WriteString("\n## Messages sent\n\n")
+		for _, message := range doc.Messages {
+			fmt.Fprintf(&b, "- `%s` (%s):\n\n", message.NodeID, message.Kind)
+			for _, line := range strings.Split(message.Content, "\n") {
+				fmt.Fprintf(&b, "  > %s\n", line)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(doc.FieldsCaptured) > 0 {
+		b.WriteString("\n## Fields captured\n\n")
+		for _, field := range doc.FieldsCaptured {
+			fmt.Fprintf(&b, "- `%s`: %s (`%s`)\n", field.Field, field.Source, field.NodeID)
+		}
+	}
+
+	if len(doc.AIPrompts) > 0 {
+		b.WriteString("\n## AI prompts\n\n")
+		for _, prompt := range doc.AIPrompts {
+			fmt.Fprintf(&b, "### `%s` (model %s)\n\n", prompt.NodeID, prompt.Model)
+			for _, option := range prompt.Options {
+				fmt.Fprintf(&b, "- %s\n", option)
+			}
+			if len(prompt.Options) > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "```\n%s\n```\n\n", prompt.Prompt)
+		}
+	}
+
+	if len(doc.Unreachable) > 0 {
+		b.WriteString("\n## Unreachable steps\n\n")
+		for _, step := range doc.Unreachable {
+			writeFlowDocStep(&b, "-", step)
+		}
+	}
+
+	// Sections each open with a blank line; drop the doubles left after quoted blocks
+	return strings.ReplaceAll(b.String(), "\n\n\n", "\n\n")
+}
+
+func writeFlowDocStep(b *strings.Builder, bullet string, step models.FlowDocStep) {
+	name := step.Type
+	if step.Label != "" {
+		name = fmt.Sprintf("%s (%s)", step.Label, step.Type)
+	}
+	fmt.Fprintf(b, "%s **%s** `%s`: %s\n", bullet, name, step.NodeID, step.Description)
+	for _, branch := range step.Branches {
+		if branch.Condition == "" {
+			fmt.Fprintf(b, "   - then `%s`\n", branch.To)
+		} else {
+			fmt.Fprintf(b, "   - if %s: `%s`\n", branch.Condition, branch.To)
+		}
+	}
+}
+
+// describeABSplitBranch phrases the share of prospects an ab_split connection gets
+func describeABSplitBranch(edge FlowEdge, edges []FlowEdge) string {
+	total := 0
+	for _, other := range edges {
+		total += abSplitWeight(other)
+	}
+	if total == 0 {
+		return fmt.Sprintf("1 in %d prospects (even split)", len(edges))
+	}
+	return fmt.Sprintf("%.0f%% of prospects", float64(abSplitWeight(edge))*100/float64(total))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// Emit event. An emit_event node sends a custom event to the device owner's event webhooks, with
+// the conversation and the node's fields rendered from conversation variables ({{last_reply}} is the
+// prospect's message):
+//
+//	{"type": "emit_event", "config": {"event": "lead.qualified",
+//	    "data": {"pakej": "{{pakej}}", "total": "{{total}}", "note": "asked for COD"}}}
+//
+// The flow continues at once; delivery and retries happen in the background.
+const flowEmitEventNode = "emit_event"
+
+// conversationEventData is the conversation part of an event: its ID and table, the prospect, the
+// stage and the flow
+func conversationEventData(source, conversationID string, conversation interface{}) map[string]interface{} {
+	vars := conversationVariables(conversation)
+	data := map[string]interface{}{
+		"conversation_id": conversationID,
+		"source":          source,
+	}
+	for _, key := range []string{"prospect_num", "prospect_name", "stage", "flow_id", "external_ref", "channel"} {
+		if value, ok := vars[key]; ok && value != nil {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// emitsEvents reports whether events of the runtime go anywhere: to webhooks, or a dry run's trace
+func (r *flowRuntime) emitsEvents() bool {
+	return r.events != nil || r.sim != nil
+}
+
+// emitEvent sends an event of a device; dry runs record it in the trace instead
+func (r *flowRuntime) emitEvent(ctx context.Context, idDevice, event string, data map[string]interface{}) {
+	if r.sim != nil {
+		r.sim.emitEvent(ctx, event, data)
+		return
+	}
+	r.events.EmitForDevice(ctx, idDevice, event, data)
+}
+
+// notifyStageChanged emits stage.changed when a node moved the conversation from previous to another
+// stage. changedBy is flow for stage nodes and ai for stages the AI replied with.
+func (r *flowRuntime) notifyStageChanged(ctx context.Context, flow *models.ChatbotFlow, conversationID, nodeID string, previous *string, stage, changedBy string) {
+	if !r.emitsEvents() || getStringValue(previous) == stage {
+		return
+	}
+	conversation, err := r.load(ctx, conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  stage.changed not sent, failed to load conversation %s: %v", conversationID, err)
+		return
+	}
+
+	data := conversationEventData(r.table, conversationID, conversation.row)
+	data["stage"] = stage
+	data["previous_stage"] = previous
+	data["changed_by"] = changedBy
+	data["flow_id"] = flow.ID
+	data["node_id"] = nodeID
+	r.emitEvent(ctx, flow.IDDevice, models.EventStageChanged, data)
+}
+
+// emitEventName reads the event an emit_event node sends
+func emitEventName(node *FlowNode) string {
+	name, _ := node.Config["event"].(string)
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// emitEventFields reads the fields an emit_event node adds to its event, unrendered
+func emitEventFields(node *FlowNode) map[string]interface{} {
+	fields, _ := node.Config["data"].(map[string]interface{})
+	return fields
+}
+
+// validateEmitEventName checks the event of an emit_event node. Returns why it cannot be sent, or
+// "" when valid.
+func validateEmitEventName(name string) string {
+	if name == "" {
+		return "no event name"
+	}
+	if !models.IsValidEventName(name) {
+		return fmt.Sprintf("invalid event name %q: use lowercase names such as lead.qualified", name)
+	}
+	if slices.Contains(models.BuiltinEvents, name) || name == models.EventWebhookTest {
+		return fmt.Sprintf("%s is sent by the app itself; name the flow's event differently", name)
+	}
+	return ""
+}
+
+// emitEventProcessor sends the node's custom event and continues
+type emitEventProcessor struct{}
+
+func (p *emitEventProcessor) GetNodeType() string { return flowEmitEventNode }
+
+func (p *emitEventProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	event := emitEventName(node)
+	if reason := validateEmitEventName(event); reason != "" {
+		log.Printf("⚠️  emit_event node %s skipped: %s", node.ID, reason)
+		return true, nil
+	}
+	if !run.emitsEvents() {
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for emit_event: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	data := conversationEventData(run.table, run.conversationID, conversation.row)
+	data["flow_id"] = run.flow.ID
+	data["node_id"] = node.ID
+
+	vars := conversationVariables(conversation.row)
+	vars[setVariableLastReply] = run.userMessage
+	for key, value := range emitEventFields(node) {
+		if text, ok := value.(string); ok {
+			data[key] = renderVariables(text, vars)
+		} else {
+			data[key] = value
+		}
+	}
+
+	log.Printf("📣 Emitting %s for conversation %s", event, run.conversationID)
+	run.emitEvent(ctx, run.flow.IDDevice, event, data)
+	return true, nil
+}
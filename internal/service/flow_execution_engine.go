@@ -1,13 +1,10 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"path/filepath"
@@ -37,10 +34,11 @@ type FlowNode struct {
 
 // FlowEdge represents a connection between nodes
 type FlowEdge struct {
-	From          string `json:"from"`
-	To            string `json:"to"`
-	ConditionType string `json:"conditionType,omitempty"`
-	ConditionValue string `json:"conditionValue,omitempty"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	ConditionType     string `json:"conditionType,omitempty"`
+	ConditionValue    string `json:"conditionValue,omitempty"`
+	ConditionVariable string `json:"conditionVariable,omitempty"` // text conditions test this conversation variable instead of the message
 }
 
 // FlowData represents the complete flow structure
@@ -70,35 +68,7 @@ func (s *FlowProcessorService) ExecuteFlow(
 	userMessage string,
 	currentStage string,
 ) error {
-	log.Printf("🚀 Starting flow execution for conversation: %s", conversationID)
-
-	// Check if NodesData is empty
-	if flow.NodesData == "" {
-		log.Printf("⚠️  Flow NodesData is empty - flow not configured yet")
-		return fmt.Errorf("flow has no nodes configured")
-	}
-
-	// Parse flow data
-	var flowData FlowData
-	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
-		log.Printf("❌ Failed to parse flow data: %v", err)
-		log.Printf("📝 NodesData content: %s", flow.NodesData)
-		return fmt.Errorf("failed to parse flow data: %w", err)
-	}
-
-	log.Printf("📊 Flow has %d nodes and %d connections", len(flowData.Nodes), len(flowData.Connections))
-
-	// Find starting node
-	startNode := s.findStartingNode(flowData, currentStage)
-	if startNode == nil {
-		log.Printf("⚠️  No starting node found for stage: %s", currentStage)
-		return fmt.Errorf("no starting node found")
-	}
-
-	log.Printf("🎯 Starting from node: %s (Type: %s)", startNode.ID, startNode.Type)
-
-	// Execute flow from starting node
-	return s.executeFromNode(ctx, flow, &flowData, startNode, conversationID, userMessage, currentStage)
+	return s.runtime().execute(ctx, flow, conversationID, userMessage, currentStage)
 }
 
 // ResumeFlow resumes flow execution from a specific node (used after waiting_reply)
@@ -109,313 +79,77 @@ func (s *FlowProcessorService) ResumeFlow(
 	userMessage string,
 	currentNodeID string,
 ) error {
-	log.Printf("▶️  Resuming flow execution from node: %s", currentNodeID)
-
-	// Check if NodesData is empty
-	if flow.NodesData == "" {
-		log.Printf("⚠️  Flow NodesData is empty - flow not configured yet")
-		return fmt.Errorf("flow has no nodes configured")
-	}
-
-	// Parse flow data
-	var flowData FlowData
-	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
-		log.Printf("❌ Failed to parse flow data: %v", err)
-		return fmt.Errorf("failed to parse flow data: %w", err)
-	}
-
-	// Find the current node
-	var currentNode *FlowNode
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == currentNodeID {
-			currentNode = &flowData.Nodes[i]
-			break
-		}
-	}
-
-	if currentNode == nil {
-		log.Printf("❌ Current node %s not found in flow", currentNodeID)
-		return fmt.Errorf("current node not found: %s", currentNodeID)
-	}
-
-	log.Printf("✅ Found current node: %s (Type: %s)", currentNode.ID, currentNode.Type)
-
-	// Add user's reply to conversation history
-	if userMessage != "" {
-		err := s.updateConvLast(ctx, conversationID, "User", userMessage)
-		if err != nil {
-			log.Printf("⚠️  Failed to update conv_last with user message: %v", err)
-			// Don't fail the flow, just log the error
-		} else {
-			log.Printf("✅ Added user message to conv_last: %s", userMessage)
-		}
-	}
-
-	// Find next node from current node
-	nextNode := s.findNextNode(&flowData, currentNode, userMessage)
-	if nextNode == nil {
-		log.Printf("✅ No next node - flow completed")
-
-		// Mark as completed
-		updates := map[string]interface{}{
-			"execution_status": "completed",
-			"current_node_id":  "completed",
-		}
-		return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	}
-
-	// Execute from next node
-	return s.executeFromNode(ctx, flow, &flowData, nextNode, conversationID, userMessage, "")
+	return s.runtime().resume(ctx, flow, conversationID, userMessage, currentNodeID)
 }
 
-// findStartingNode finds the node to start execution from
-func (s *FlowProcessorService) findStartingNode(flowData FlowData, currentStage string) *FlowNode {
-	// If no current stage, find the first node (after start node if exists)
-	if currentStage == "" || currentStage == "start" {
-		// Look for a node that has no incoming connections (or is connected from start)
-		for i := range flowData.Nodes {
-			node := &flowData.Nodes[i]
-			// Skip if this is a start-type node
-			if strings.Contains(strings.ToLower(node.Type), "start") {
-				continue
-			}
-
-			// Check if this node has incoming connections
-			hasIncoming := false
-			for _, edge := range flowData.Connections {
-				if edge.To == node.ID {
-					hasIncoming = true
-					break
-				}
+// runtime runs the flow node processors against ai_whatsapp conversations (Chatbot AI)
+func (s *FlowProcessorService) runtime() *flowRuntime {
+	return &flowRuntime{
+		flowServices: s.runtimeServices(),
+		table:        "ai_whatsapp",
+		store:        s.store,
+		state:        s.aiState,
+		sender:       s.sender,
+		sim:          s.sim,
+		load: func(ctx context.Context, conversationID string) (*flowConversation, error) {
+			conversation, err := s.store.GetConversationByID(ctx, conversationID)
+			if err != nil || conversation == nil {
+				return nil, err
 			}
-
-			// If no incoming connections, this could be the first node
-			if !hasIncoming {
-				return node
-			}
-		}
-
-		// If all nodes have incoming connections, get the first node
-		if len(flowData.Nodes) > 0 {
-			return &flowData.Nodes[0]
-		}
-	}
-
-	// Otherwise, try to find node by ID matching the stage
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == currentStage {
-			return &flowData.Nodes[i]
-		}
-	}
-
-	// Default to first node
-	if len(flowData.Nodes) > 0 {
-		return &flowData.Nodes[0]
-	}
-
-	return nil
-}
-
-// executeFromNode executes the flow starting from a specific node
-func (s *FlowProcessorService) executeFromNode(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	flowData *FlowData,
-	node *FlowNode,
-	conversationID string,
-	userMessage string,
-	currentStage string,
-) error {
-	log.Printf("🔄 Executing node: %s (Type: %s)", node.ID, node.Type)
-
-	// Execute the current node
-	continueFlow, err := s.executeNode(ctx, flow, node, conversationID, userMessage)
-	if err != nil {
-		return fmt.Errorf("failed to execute node %s: %w", node.ID, err)
-	}
-
-	// If node says to stop flow (e.g., waiting_reply), stop here
-	if !continueFlow {
-		log.Printf("⏸️  Flow paused at node: %s", node.ID)
-		// Update current node in conversation
-		return s.updateConversationNode(ctx, conversationID, node.ID)
-	}
-
-	// Find next node
-	nextNode := s.findNextNode(flowData, node, userMessage)
-	if nextNode == nil {
-		log.Printf("✅ Flow completed - no more nodes")
-
-		// Mark flow as completed
-		updates := map[string]interface{}{
-			"execution_status":  "completed",
-			"current_node_id":   "completed",
-			"waiting_for_reply": false,
-		}
-
-		err := s.convRepo.UpdateConversation(ctx, conversationID, updates)
-		if err != nil {
-			log.Printf("❌ Failed to mark flow as completed: %v", err)
-			return fmt.Errorf("failed to mark flow as completed: %w", err)
-		}
-
-		log.Printf("✅ Flow marked as 'completed'")
-		return nil
-	}
-
-	// Continue to next node
-	return s.executeFromNode(ctx, flow, flowData, nextNode, conversationID, userMessage, currentStage)
-}
-
-// executeNode executes a single node's action
-func (s *FlowProcessorService) executeNode(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-	userMessage string,
-) (bool, error) {
-	log.Printf("⚙️  Executing node type: %s", node.Type)
-
-	switch node.Type {
-	case "send_message":
-		return s.executeSendMessage(ctx, flow, node, conversationID)
-
-	case "delay":
-		return s.executeDelay(ctx, node)
-
-	case "waiting_reply":
-		return s.executeWaitingReply(ctx, conversationID, node)
-
-	case "waiting_times":
-		return s.executeWaitingTimes(ctx, conversationID, node)
-
-	case "ai_prompt":
-		return s.executeAIPrompt(ctx, flow, node, conversationID, userMessage)
-
-	case "stage":
-		return s.executeStage(ctx, conversationID, node)
-
-	case "send_image", "send_audio", "send_video":
-		return s.executeSendMedia(ctx, flow, node, conversationID)
-
-	case "conditions":
-		return s.executeConditions(ctx, node, userMessage)
-
-	default:
-		log.Printf("⚠️  Unknown node type: %s, skipping", node.Type)
-		return true, nil
-	}
-}
-
-// executeSendMessage sends a WhatsApp message
-func (s *FlowProcessorService) executeSendMessage(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-) (bool, error) {
-	// Get message text from config
-	text, ok := node.Config["text"].(string)
-	if !ok || text == "" {
-		log.Printf("⚠️  No text configured for send_message node")
-		return true, nil
-	}
-
-	log.Printf("📤 Sending message: %s", text)
-
-	// Get conversation to get phone number
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil || conversation == nil {
-		log.Printf("❌ Failed to get conversation for sending: %v", err)
-		return true, fmt.Errorf("failed to get conversation: %w", err)
-	}
-
-	// Send WhatsApp message
-	err = s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, text, "", "")
-	if err != nil {
-		log.Printf("❌ Failed to send WhatsApp message: %v", err)
-		return true, fmt.Errorf("failed to send message: %w", err)
+			return aiFlowConversation(conversation), nil
+		},
 	}
-
-	log.Printf("✅ Message sent successfully to %s", conversation.ProspectNum)
-
-	// Update conv_last with bot reply
-	return true, s.updateConvLast(ctx, conversationID, "Bot", text)
 }
 
-// executeDelay pauses execution for specified seconds
-func (s *FlowProcessorService) executeDelay(ctx context.Context, node *FlowNode) (bool, error) {
-	// Get delay from config (should be 3 seconds by default)
-	delay := 3
-	if delayVal, ok := node.Config["delay"].(float64); ok {
-		delay = int(delayVal)
-	}
-
-	log.Printf("⏱️  Delaying for %d seconds", delay)
-	time.Sleep(time.Duration(delay) * time.Second)
-	log.Printf("✅ Delay completed")
-
-	return true, nil
-}
-
-// executeWaitingReply pauses flow until user replies (no timeout)
-func (s *FlowProcessorService) executeWaitingReply(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	log.Printf("⏸️  Waiting for user reply (no timeout)")
-
-	// Update conversation to waiting state
-	updates := map[string]interface{}{
-		"waiting_for_reply": true,
-		"current_node_id":   node.ID,
-	}
-
-	err := s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	if err != nil {
-		return false, fmt.Errorf("failed to update waiting state: %w", err)
+// runtimeServices are the services both engines run this service's flows with
+func (s *FlowProcessorService) runtimeServices() flowServices {
+	return flowServices{
+		deviceRepo: s.deviceRepo,
+		stageRepo:  s.stageRepo,
+		fieldRepo:  s.customFieldRepo,
+		translator: s.translator,
+		consents:   s.consents,
+		links:      s.links,
+		email:      s.email,
+		messages:   s.messages,
+		ai:         s,
+		templates:  s.msgTemplates,
+		abSplits:   s.abSplits,
+		events:     s.events,
+		sheets:     s.sheets,
+		costs:      s.costs,
+		deadlines:  s.deadlines,
+		delays:     s.delayRepo,
+		logs:       s.executionLogs,
 	}
-
-	log.Printf("✅ Set waiting_for_reply=true, current_node_id=%s", node.ID)
-
-	// Flow will resume when next webhook message arrives
-	return false, nil // false = stop flow execution
 }
 
-// executeWaitingTimes pauses and waits for user reply with timeout
-func (s *FlowProcessorService) executeWaitingTimes(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	// Get timeout from config (should be 8 seconds by default)
-	timeout := 8
-	if delayVal, ok := node.Config["delay"].(float64); ok {
-		timeout = int(delayVal)
-	}
-
-	log.Printf("⏳ Waiting for user reply with %d second timeout", timeout)
-
-	// TODO: Implement timeout logic
-	// For now, just continue after timeout
-	time.Sleep(time.Duration(timeout) * time.Second)
-
-	log.Printf("⏱️  Timeout reached, continuing flow")
-	return true, nil
+// aiPromptTarget adapts the row an ai_prompt node runs for, an ai_whatsapp conversation or a
+// wasapbot contact, so both engines share one AI pipeline
+type aiPromptTarget struct {
+	conversationID string
+	store          ConversationStateStore                                // stage updates and inbound translation
+	load           func(ctx context.Context) (*models.AIWhatsapp, error) // prospect, language, stage and conv_last
+	appendHistory  func(ctx context.Context, role, message string) error
+	stageChanged   func(ctx context.Context, previous *string, stage string) // emits stage.changed (nil = no event)
 }
 
-// executeAIPrompt processes AI prompt node
-func (s *FlowProcessorService) executeAIPrompt(
+// runAIPrompt is the AI pipeline behind ai_prompt nodes on both engines: build the prompt from the
+// conversation history, request (and score or repair) the reply, set the stage and send the parts
+func (s *FlowProcessorService) runAIPrompt(
 	ctx context.Context,
 	flow *models.ChatbotFlow,
 	node *FlowNode,
-	conversationID string,
 	userMessage string,
+	target *aiPromptTarget,
 ) (bool, error) {
 	log.Printf("✨ Starting AI Prompt execution")
 
+	// auto_translate: the AI works in the seller's language, replies go back in the prospect's
+	if autoTranslate, _ := node.Config["auto_translate"].(bool); autoTranslate {
+		userMessage = s.translator.TranslateInbound(ctx, target.store, target.conversationID, flow.IDDevice, userMessage, "")
+	}
+
 	// Get promptData from node config
 	promptData, ok := node.Config["text"].(string)
 	if !ok || promptData == "" {
@@ -436,6 +170,9 @@ func (s *FlowProcessorService) executeAIPrompt(
 		apiKey = *device.APIKey
 	}
 	model = device.APIKeyOption
+	if override := aiPromptModel(node); override != "" {
+		model = override
+	}
 
 	// Terminate if any required field is null
 	if promptData == "" || apiKey == "" || model == "" {
@@ -447,7 +184,7 @@ func (s *FlowProcessorService) executeAIPrompt(
 	log.Printf("✅ Got API settings - Model: %s", model)
 
 	// Get conversation to retrieve conv_last and other data
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
+	conversation, err := target.load(ctx)
 	if err != nil || conversation == nil {
 		log.Printf("❌ Failed to get conversation: %v", err)
 		return true, fmt.Errorf("failed to get conversation: %w", err)
@@ -459,61 +196,16 @@ func (s *FlowProcessorService) executeAIPrompt(
 		lasttext = *conversation.ConvLast
 	}
 
-	// Get currenttext from userMessage
+	// Get currenttext from userMessage, describing any image or voice note that came with it
 	currenttext := userMessage
+	if media := inboundMediaFrom(ctx); media != nil {
+		currenttext += "\n\n" + inboundMediaPrompt(media)
+	}
 
 	log.Printf("📝 Building AI prompt with conv_last length: %d, currenttext: %s", len(lasttext), currenttext)
 
-	// Build content string exactly as specified
-	content := promptData + "\n\n" +
-		"### Instructions:\n" +
-		"1. If the current stage is null or undefined, default to the first stage.\n" +
-		"2. Always analyze the user's input to determine the appropriate stage. If the input context is unclear, guide the user within the default stage context.\n" +
-		"3. Follow all rules and steps strictly. Do not skip or ignore any rules or instructions.\n\n" +
-		"4. **Do not repeat the same sentences or phrases that have been used in the recent conversation history.**\n" +
-		"5. If the input contains the phrase \"I want this section in add response format [onemessage]\":\n" +
-		"   - Add the `Jenis` field with the value `onemessage` at the item level for each text response.\n" +
-		"   - The `Jenis` field is only added to `text` types within the `Response` array.\n" +
-		"   - If the directive is not present, omit the `Jenis` field entirely.\n\n" +
-		"### Response Format:\n" +
-		"{\n" +
-		"  \"Stage\": \"[Stage]\",  // Specify the current stage explicitly.\n" +
-		"  \"Response\": [\n" +
-		"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Provide the first response message here.\"},\n" +
-		"    {\"type\": \"image\", \"content\": \"https://example.com/image1.jpg\"},\n" +
-		"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Provide the second response message here.\"}\n" +
-		"  ]\n" +
-		"}\n\n" +
-		"### Example Response:\n" +
-		"// If the directive is present\n" +
-		"{\n" +
-		"  \"Stage\": \"Problem Identification\",\n" +
-		"  \"Response\": [\n" +
-		"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Maaf kak, Layla kena reconfirm balik dulu masalah utama anak akak ni.\"},\n" +
-		"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Kurang selera makan, sembelit, atau kerap demam?\"}\n" +
-		"  ]\n" +
-		"}\n\n" +
-		"// If the directive is NOT present\n" +
-		"{\n" +
-		"  \"Stage\": \"Problem Identification\",\n" +
-		"  \"Response\": [\n" +
-		"    {\"type\": \"text\", \"content\": \"Maaf kak, Layla kena reconfirm balik dulu masalah utama anak akak ni.\"},\n" +
-		"    {\"type\": \"text\", \"content\": \"Kurang selera makan, sembelit, atau kerap demam?\"}\n" +
-		"  ]\n" +
-		"}\n\n" +
-		"### Important Rules:\n" +
-		"1. **Include the `Stage` field in every response**:\n" +
-		"   - The `Stage` field must explicitly specify the current stage.\n" +
-		"   - If the stage is unclear or missing, default to first stage.\n\n" +
-		"2. **Use the Correct Response Format**:\n" +
-		"   - Divide long responses into multiple short \"text\" segments for better readability.\n" +
-		"   - Include all relevant images provided in the input, interspersed naturally with text responses.\n" +
-		"   - If multiple images are provided, create separate `image` entries for each.\n\n" +
-		"3. **Dynamic Field for [onemessage]**:\n" +
-		"   - If the input specifies \"I want this section in add response format [onemessage]\":\n" +
-		"      - Add `\"Jenis\": \"onemessage\"` to each `text` type in the `Response` array.\n" +
-		"   - If the directive is not present, omit the `Jenis` field entirely.\n" +
-		"   - Non-text types like `image` never include the `Jenis` field.\n\n"
+	// Build the system prompt from the node's template, with the device persona after the node prompt
+	content := s.aiSystemPrompt(ctx, node, device, promptData, conversation)
 
 	// Build payload exactly as specified
 	payload := map[string]interface{}{
@@ -523,84 +215,163 @@ func (s *FlowProcessorService) executeAIPrompt(
 			{"role": "assistant", "content": lasttext},
 			{"role": "user", "content": currenttext},
 		},
-		"temperature":         0.67,
+		"temperature":        0.67,
 		"top_p":              1,
 		"repetition_penalty": 1,
 	}
 
-	// Call OpenRouter API
-	apiURL := "https://openrouter.ai/api/v1/chat/completions"
-
-	payloadBytes, err := json.Marshal(payload)
+	// n_best: request several candidates and keep the best scoring one
+	var replyContent string
+	if n := nBestCount(node, getStringValue(conversation.Stage)); n > 1 {
+		replyContent, err = s.bestAIReply(ctx, flow, node, conversation, device, model, payload, n, promptData, lasttext)
+	} else {
+		replyContent, err = s.requestAIReply(ctx, flow, conversation, device, model, payload)
+	}
 	if err != nil {
-		log.Printf("❌ Failed to marshal payload: %v", err)
-		return true, fmt.Errorf("failed to marshal payload: %w", err)
+		return true, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		log.Printf("❌ Failed to create request: %v", err)
-		return true, fmt.Errorf("failed to create request: %w", err)
+	stage, replyParts, structured := parseAIReply(replyContent)
+
+	// One repair round-trip before falling back to sending the malformed reply as plain text
+	if !structured && aiJSONRepairEnabled(node) {
+		if repairedStage, repairedParts, ok := s.repairAIReply(ctx, flow, conversation, device, model, payload, replyContent); ok {
+			stage, replyParts = repairedStage, repairedParts
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	// Validate replyParts
+	if len(replyParts) == 0 {
+		log.Printf("❌ Failed to parse response parts")
+		return true, fmt.Errorf("failed to parse response")
+	}
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+	log.Printf("✅ Final parsed - Stage: %s, Parts: %d", stage, len(replyParts))
+
+	// Step 3: Update stage if present
+	if stage != "" {
+		updates := map[string]interface{}{
+			"stage": stage,
+		}
+		if err := target.store.UpdateConversation(ctx, target.conversationID, updates); err != nil {
+			log.Printf("⚠️  Failed to update stage: %v", err)
+		} else {
+			log.Printf("✅ Updated stage to: %s", stage)
+			if target.stageChanged != nil {
+				target.stageChanged(ctx, conversation.Stage, stage)
+			}
+		}
+	}
+
+	// Step 4: Process and send messages
+	return s.processAIResponseParts(ctx, flow, target, conversation, replyParts)
+}
+
+// requestAIReply sends one chat completion to OpenRouter (or the device's configured endpoint),
+// charges its tokens and returns the reply text. Transient failures are retried; when the model
+// still fails, the device's fallback model gets one more try.
+func (s *FlowProcessorService) requestAIReply(
+	ctx context.Context,
+	flow *models.ChatbotFlow,
+	conversation *models.AIWhatsapp,
+	device *models.DeviceSetting,
+	model string,
+	payload map[string]interface{},
+) (string, error) {
+	replyContent, err := s.requestAIModel(ctx, flow, conversation, device, model, payload)
+	fallback := strings.TrimSpace(getStringValue(device.AIFallbackModel))
+	if err == nil || fallback == "" || fallback == model || ctx.Err() != nil {
+		return replyContent, err
+	}
+
+	log.Printf("↪️  Model %s failed (%v), falling back to %s", model, err, fallback)
+	fallbackPayload := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		fallbackPayload[key] = value
+	}
+	fallbackPayload["model"] = fallback
+	return s.requestAIModel(ctx, flow, conversation, device, fallback, fallbackPayload)
+}
+
+// requestAIModel makes the chat completion of requestAIReply with one model
+func (s *FlowProcessorService) requestAIModel(
+	ctx context.Context,
+	flow *models.ChatbotFlow,
+	conversation *models.AIWhatsapp,
+	device *models.DeviceSetting,
+	model string,
+	payload map[string]interface{},
+) (string, error) {
+	endpoint := s.aiEndpoints.resolve(models.AIProviderOpenRouter, device, "/chat/completions")
+
+	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("❌ OpenRouter API error: %v", err)
-		return true, fmt.Errorf("OpenRouter API error: %w", err)
+		log.Printf("❌ Failed to marshal payload: %v", err)
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// Each attempt gets the external call deadline; retries are bounded by the node's
+	status, body, err := endpoint.send(ctx, payloadBytes, s.deadlines.ExternalCall, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+getStringValue(device.APIKey))
+	})
 	if err != nil {
-		log.Printf("❌ Failed to read response body: %v", err)
-		return true, fmt.Errorf("failed to read response body: %w", err)
+		log.Printf("❌ OpenRouter API error: %v", err)
+		return "", fmt.Errorf("OpenRouter API error: %w", err)
+	}
+	if status != http.StatusOK {
+		log.Printf("❌ OpenRouter API returned %d: %s", status, string(body))
+		return "", fmt.Errorf("OpenRouter API returned %d", status)
 	}
 
 	// Parse response
 	var responseBody map[string]interface{}
 	if err := json.Unmarshal(body, &responseBody); err != nil {
 		log.Printf("❌ Failed to parse response: %v", err)
-		return true, fmt.Errorf("failed to parse response: %w", err)
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Charge the tokens to this prospect's cost ledger and the device's AI usage
+	if usage, charged := openRouterUsage(responseBody); usage != nil {
+		s.costs.RecordAITokens(ctx, flow.IDDevice, conversation.ProspectNum, &flow.ID, model, usage, charged)
+		s.aiUsage.Record(ctx, flow.IDDevice, model, models.AIUsagePurposeFlowReply, usage, charged)
 	}
 
 	// Extract reply content
 	choices, ok := responseBody["choices"].([]interface{})
 	if !ok || len(choices) == 0 {
 		log.Printf("❌ Invalid OpenRouter API response: %v", string(body))
-		return true, fmt.Errorf("invalid OpenRouter API response")
+		return "", fmt.Errorf("invalid OpenRouter API response")
 	}
 
 	firstChoice, ok := choices[0].(map[string]interface{})
 	if !ok {
 		log.Printf("❌ Invalid choice format")
-		return true, fmt.Errorf("invalid choice format")
+		return "", fmt.Errorf("invalid choice format")
 	}
 
 	message, ok := firstChoice["message"].(map[string]interface{})
 	if !ok {
 		log.Printf("❌ Invalid message format")
-		return true, fmt.Errorf("invalid message format")
+		return "", fmt.Errorf("invalid message format")
 	}
 
 	replyContent, ok := message["content"].(string)
 	if !ok {
 		log.Printf("❌ Invalid content format")
-		return true, fmt.Errorf("invalid content format")
+		return "", fmt.Errorf("invalid content format")
 	}
 
 	log.Printf("🤖 AI Response received: %d characters", len(replyContent))
 	log.Printf("📄 Raw response: %s", replyContent)
 
-	// Step 2: Sanitize content - remove ```json markers
-	sanitizedContent := regexp.MustCompile("^```json|```$").ReplaceAllString(strings.TrimSpace(replyContent), "")
+	return replyContent, nil
+}
 
-	// Try to parse as JSON
-	var stage string
-	var replyParts []AIResponsePart
+// parseAIReply extracts the stage and response parts from an AI reply.
+// structured is false when the reply did not follow the response format and was used as plain text.
+func parseAIReply(replyContent string) (stage string, replyParts []AIResponsePart, structured bool) {
+	// Sanitize content - remove ```json markers
+	sanitizedContent := regexp.MustCompile("^```json|```$").ReplaceAllString(strings.TrimSpace(replyContent), "")
 
 	// Attempt 1: Try as JSON with Stage and Response
 	var aiResp AIResponse
@@ -641,6 +412,7 @@ func (s *FlowProcessorService) executeAIPrompt(
 	}
 
 	// Attempt 4: Plain text fallback
+	structured = len(replyParts) > 0
 	if len(replyParts) == 0 {
 		log.Printf("⚠️  Plain text response detected, using fallback")
 		if stage == "" {
@@ -651,124 +423,14 @@ func (s *FlowProcessorService) executeAIPrompt(
 		}
 	}
 
-	// Validate replyParts
-	if len(replyParts) == 0 {
-		log.Printf("❌ Failed to parse response parts")
-		return true, fmt.Errorf("failed to parse response")
-	}
-
-	log.Printf("✅ Final parsed - Stage: %s, Parts: %d", stage, len(replyParts))
-
-	// Step 3: Update stage if present
-	if stage != "" {
-		updates := map[string]interface{}{
-			"stage": stage,
-		}
-		if err := s.convRepo.UpdateConversation(ctx, conversationID, updates); err != nil {
-			log.Printf("⚠️  Failed to update stage: %v", err)
-		} else {
-			log.Printf("✅ Updated stage to: %s", stage)
-		}
-	}
-
-	// Step 4: Process and send messages
-	return s.processAIResponseParts(ctx, flow, conversationID, conversation, replyParts)
-}
-
-// executeStage updates the conversation stage
-func (s *FlowProcessorService) executeStage(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	// Get stage name from config
-	stageName, ok := node.Config["value"].(string)
-	if !ok || stageName == "" {
-		log.Printf("⚠️  No stage value configured")
-		return true, nil
-	}
-
-	log.Printf("🎯 Updating stage to: %s", stageName)
-
-	// Update conversation stage
-	updates := map[string]interface{}{
-		"stage": stageName,
-	}
-
-	err := s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	if err != nil {
-		return true, fmt.Errorf("failed to update stage: %w", err)
-	}
-
-	log.Printf("✅ Stage updated successfully")
-	return true, nil
-}
-
-// executeSendMedia sends media (image/audio/video)
-func (s *FlowProcessorService) executeSendMedia(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-) (bool, error) {
-	// Get media URL from config
-	url, ok := node.Config["url"].(string)
-	if !ok || url == "" {
-		log.Printf("⚠️  No URL configured for media node")
-		return true, nil
-	}
-
-	log.Printf("📤 Sending %s: %s", node.Type, url)
-
-	// Get conversation to get phone number
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil || conversation == nil {
-		log.Printf("❌ Failed to get conversation for sending media: %v", err)
-		return true, fmt.Errorf("failed to get conversation: %w", err)
-	}
-
-	// Map node type to media type
-	mediaType := ""
-	switch node.Type {
-	case "send_image":
-		mediaType = "image"
-	case "send_audio":
-		mediaType = "audio"
-	case "send_video":
-		mediaType = "video"
-	}
-
-	// Send WhatsApp media
-	err = s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, "", mediaType, url)
-	if err != nil {
-		log.Printf("❌ Failed to send WhatsApp media: %v", err)
-		return true, fmt.Errorf("failed to send media: %w", err)
-	}
-
-	log.Printf("✅ Media sent successfully to %s", conversation.ProspectNum)
-
-	// Update conv_last with bot media send (just the URL)
-	return true, s.updateConvLast(ctx, conversationID, "Bot", url)
-}
-
-// executeConditions evaluates conditions
-func (s *FlowProcessorService) executeConditions(
-	ctx context.Context,
-	node *FlowNode,
-	userMessage string,
-) (bool, error) {
-	log.Printf("🔀 Evaluating conditions")
-
-	// Conditions are handled in findNextNode
-	// This node just passes through
-	return true, nil
+	return stage, replyParts, structured
 }
 
 // processAIResponseParts processes AI response parts and sends messages
 func (s *FlowProcessorService) processAIResponseParts(
 	ctx context.Context,
 	flow *models.ChatbotFlow,
-	conversationID string,
+	target *aiPromptTarget,
 	conversation *models.AIWhatsapp,
 	replyParts []AIResponsePart,
 ) (bool, error) {
@@ -776,6 +438,7 @@ func (s *FlowProcessorService) processAIResponseParts(
 
 	var textParts []string
 	isOnemessageActive := false
+	prospectLanguage := getStringValue(conversation.Language)
 
 	for index, part := range replyParts {
 		if part.Type == "" || part.Content == "" {
@@ -804,15 +467,14 @@ func (s *FlowProcessorService) processAIResponseParts(
 				log.Printf("📨 Sending combined onemessage: %s", combinedMessage)
 
 				// Send WhatsApp message
-				err := s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, combinedMessage, "", "")
+				err := s.sender.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, s.translator.ForProspect(ctx, flow.IDDevice, prospectLanguage, combinedMessage), "", "")
 				if err != nil {
 					log.Printf("❌ Failed to send combined message: %v", err)
 				} else {
 					log.Printf("✅ Combined message sent")
 
 					// Update conv_last
-					newBotEntry := fmt.Sprintf("Bot: %s", combinedMessage)
-					if err := s.appendToConvLast(ctx, conversationID, newBotEntry); err != nil {
+					if err := target.appendHistory(ctx, "Bot", combinedMessage); err != nil {
 						log.Printf("⚠️  Failed to update conv_last: %v", err)
 					}
 				}
@@ -827,12 +489,11 @@ func (s *FlowProcessorService) processAIResponseParts(
 				combinedMessage := strings.Join(textParts, "\n")
 				log.Printf("📨 Sending combined onemessage (interrupted): %s", combinedMessage)
 
-				err := s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, combinedMessage, "", "")
+				err := s.sender.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, s.translator.ForProspect(ctx, flow.IDDevice, prospectLanguage, combinedMessage), "", "")
 				if err != nil {
 					log.Printf("❌ Failed to send combined message: %v", err)
 				} else {
-					newBotEntry := fmt.Sprintf("Bot: %s", combinedMessage)
-					if err := s.appendToConvLast(ctx, conversationID, newBotEntry); err != nil {
+					if err := target.appendHistory(ctx, "Bot", combinedMessage); err != nil {
 						log.Printf("⚠️  Failed to update conv_last: %v", err)
 					}
 				}
@@ -845,14 +506,13 @@ func (s *FlowProcessorService) processAIResponseParts(
 			if part.Type == "text" {
 				log.Printf("📨 Sending text message: %s", part.Content)
 
-				err := s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, part.Content, "", "")
+				err := s.sender.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, s.translator.ForProspect(ctx, flow.IDDevice, prospectLanguage, part.Content), "", "")
 				if err != nil {
 					log.Printf("❌ Failed to send text message: %v", err)
 				} else {
 					log.Printf("✅ Text message sent")
 
-					newBotEntry := fmt.Sprintf("Bot: %s", part.Content)
-					if err := s.appendToConvLast(ctx, conversationID, newBotEntry); err != nil {
+					if err := target.appendHistory(ctx, "Bot", part.Content); err != nil {
 						log.Printf("⚠️  Failed to update conv_last: %v", err)
 					}
 				}
@@ -869,15 +529,14 @@ func (s *FlowProcessorService) processAIResponseParts(
 
 				// Send message with detected media type and MIME type
 				// SendMessage signature: (ctx, deviceID, to, message, mediaType, mediaURL, mimeType)
-				err := s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, "", actualType, mediaURL, mimeType)
+				err := s.sender.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, "", actualType, mediaURL, mimeType)
 
 				if err != nil {
 					log.Printf("❌ Failed to send %s: %v", actualType, err)
 				} else {
 					log.Printf("✅ %s sent", actualType)
 
-					newBotEntry := fmt.Sprintf("Bot: %s", mediaURL)
-					if err := s.appendToConvLast(ctx, conversationID, newBotEntry); err != nil {
+					if err := target.appendHistory(ctx, "Bot", mediaURL); err != nil {
 						log.Printf("⚠️  Failed to update conv_last: %v", err)
 					}
 				}
@@ -976,162 +635,3 @@ func (s *FlowProcessorService) mimeToMediaType(mimeType string) string {
 	}
 	return "image" // default fallback
 }
-
-// appendToConvLast appends a new entry to conv_last
-func (s *FlowProcessorService) appendToConvLast(ctx context.Context, conversationID string, entry string) error {
-	conv, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil {
-		return err
-	}
-
-	convLast := ""
-	if conv.ConvLast != nil {
-		convLast = *conv.ConvLast
-	}
-
-	if convLast != "" {
-		convLast += "\n" + entry
-	} else {
-		convLast = entry
-	}
-
-	updates := map[string]interface{}{
-		"conv_last": convLast,
-	}
-
-	return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-}
-
-// findNextNode finds the next node to execute based on edges
-func (s *FlowProcessorService) findNextNode(
-	flowData *FlowData,
-	currentNode *FlowNode,
-	userMessage string,
-) *FlowNode {
-	// Find all outgoing edges from current node
-	var outgoingEdges []FlowEdge
-	for _, edge := range flowData.Connections {
-		if edge.From == currentNode.ID {
-			outgoingEdges = append(outgoingEdges, edge)
-		}
-	}
-
-	if len(outgoingEdges) == 0 {
-		log.Printf("ℹ️  No outgoing edges from node: %s", currentNode.ID)
-		return nil
-	}
-
-	// If only one edge, follow it
-	if len(outgoingEdges) == 1 {
-		return s.findNodeByID(flowData, outgoingEdges[0].To)
-	}
-
-	// Multiple edges - check if this is a Conditions node
-	if currentNode.Type == "conditions" {
-		log.Printf("🔀 Conditions node with %d edges", len(outgoingEdges))
-
-		// Match user message against conditions
-		for _, edge := range outgoingEdges {
-			if edge.ConditionType == "" || edge.ConditionValue == "" {
-				log.Printf("⚠️  Edge has no condition type/value, skipping")
-				continue
-			}
-
-			matched := false
-			switch strings.ToLower(edge.ConditionType) {
-			case "equal":
-				matched = strings.ToLower(userMessage) == strings.ToLower(edge.ConditionValue)
-			case "contains":
-				matched = strings.Contains(strings.ToLower(userMessage), strings.ToLower(edge.ConditionValue))
-			case "match":
-				matched = strings.Contains(strings.ToLower(userMessage), strings.ToLower(edge.ConditionValue))
-			case "default":
-				matched = true // Default always matches
-			}
-
-			if matched {
-				log.Printf("✅ Condition matched: %s '%s'", edge.ConditionType, edge.ConditionValue)
-				return s.findNodeByID(flowData, edge.To)
-			}
-		}
-
-		// No conditions matched, look for default
-		for _, edge := range outgoingEdges {
-			if strings.ToLower(edge.ConditionType) == "default" {
-				log.Printf("✅ Using default condition")
-				return s.findNodeByID(flowData, edge.To)
-			}
-		}
-
-		// No conditions matched and no default - randomly select one of the edges
-		if len(outgoingEdges) > 0 {
-			randomIndex := rand.Intn(len(outgoingEdges))
-			selectedEdge := outgoingEdges[randomIndex]
-			log.Printf("🎲 No conditions matched, randomly selected edge %d/%d (to: %s)", randomIndex+1, len(outgoingEdges), selectedEdge.To)
-			return s.findNodeByID(flowData, selectedEdge.To)
-		}
-
-		log.Printf("⚠️  No edges available, flow stops")
-		return nil
-	}
-
-	// Not a conditions node, but multiple edges - follow first one
-	log.Printf("⚠️  Multiple edges from non-condition node, following first one")
-	return s.findNodeByID(flowData, outgoingEdges[0].To)
-}
-
-// findNodeByID finds a node by its ID
-func (s *FlowProcessorService) findNodeByID(flowData *FlowData, nodeID string) *FlowNode {
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == nodeID {
-			return &flowData.Nodes[i]
-		}
-	}
-	return nil
-}
-
-// updateConversationNode updates the current node in conversation
-func (s *FlowProcessorService) updateConversationNode(
-	ctx context.Context,
-	conversationID string,
-	nodeID string,
-) error {
-	updates := map[string]interface{}{
-		"current_node_id": nodeID,
-	}
-
-	return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-}
-
-// updateConvLast updates the conversation history
-func (s *FlowProcessorService) updateConvLast(
-	ctx context.Context,
-	conversationID string,
-	role string,
-	message string,
-) error {
-	// Get current conversation
-	conv, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil {
-		return err
-	}
-
-	// Append to conv_last
-	convLast := ""
-	if conv.ConvLast != nil {
-		convLast = *conv.ConvLast
-	}
-
-	newLine := fmt.Sprintf("%s: %s", role, message)
-	if convLast != "" {
-		convLast += "\n" + newLine
-	} else {
-		convLast = newLine
-	}
-
-	updates := map[string]interface{}{
-		"conv_last": convLast,
-	}
-
-	return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-}
@@ -5,16 +5,21 @@ import (
 	"chatbot-automation/internal/repository"
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 )
 
-// FlowExecutionService handles flow execution logic
+// FlowExecutionService starts flows for prospects and runs them on inbound webhook messages.
+// Nodes run through the Chatbot AI flow engine and its node processor registry.
 type FlowExecutionService struct {
 	flowRepo         *repository.FlowRepository
 	conversationRepo *repository.ConversationRepository
 	deviceRepo       *repository.DeviceRepository
-	aiService        *AIService
-	processors       map[models.NodeType]models.NodeProcessor
+	stateMachine     *ConversationStateMachine
+	messages         *MessageRecorder
+	events           *EventWebhookService
+	processor        *FlowProcessorService // runs the nodes, sending replies itself
 }
 
 // NewFlowExecutionService creates a new flow execution service
@@ -22,38 +27,19 @@ func NewFlowExecutionService(
 	flowRepo *repository.FlowRepository,
 	conversationRepo *repository.ConversationRepository,
 	deviceRepo *repository.DeviceRepository,
-	aiService *AIService,
+	messages *MessageRecorder,
+	events *EventWebhookService,
+	processor *FlowProcessorService,
 ) *FlowExecutionService {
-	service := &FlowExecutionService{
+	return &FlowExecutionService{
 		flowRepo:         flowRepo,
 		conversationRepo: conversationRepo,
 		deviceRepo:       deviceRepo,
-		aiService:        aiService,
-		processors:       make(map[models.NodeType]models.NodeProcessor),
+		stateMachine:     NewConversationStateMachine(conversationRepo),
+		messages:         messages,
+		events:           events,
+		processor:        processor,
 	}
-
-	// Register node processors
-	service.registerProcessors()
-
-	return service
-}
-
-// registerProcessors registers all node processors
-func (s *FlowExecutionService) registerProcessors() {
-	s.processors[models.NodeTypeStart] = &StartNodeProcessor{}
-	s.processors[models.NodeTypeMessage] = &MessageNodeProcessor{}
-	s.processors[models.NodeTypeImage] = &ImageNodeProcessor{}
-	s.processors[models.NodeTypeAudio] = &AudioNodeProcessor{}
-	s.processors[models.NodeTypeVideo] = &VideoNodeProcessor{}
-	s.processors[models.NodeTypeDocument] = &DocumentNodeProcessor{}
-	s.processors[models.NodeTypeAI] = &AINodeProcessor{aiService: s.aiService}
-	s.processors[models.NodeTypeCondition] = &ConditionNodeProcessor{}
-	s.processors[models.NodeTypeDelay] = &DelayNodeProcessor{}
-	s.processors[models.NodeTypeStage] = &StageNodeProcessor{}
-	s.processors[models.NodeTypePrompt] = &PromptNodeProcessor{}
-	s.processors[models.NodeTypeUserReply] = &UserReplyNodeProcessor{}
-	s.processors[models.NodeTypeAPI] = &APINodeProcessor{}
-	s.processors[models.NodeTypeEnd] = &EndNodeProcessor{}
 }
 
 // StartFlow initiates a new flow execution
@@ -77,6 +63,16 @@ func (s *FlowExecutionService) StartFlow(ctx context.Context, userID string, req
 		}, nil
 	}
 
+	// Start the flow for the number as inbound messages arrive with it, so replies continue it
+	prospectNum, err := normalizeProspectNum(device, req.ProspectNum)
+	if err != nil {
+		return &models.StartFlowResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid prospect_num: %v", err),
+		}, nil
+	}
+	req.ProspectNum = prospectNum
+
 	// Get device identifier
 	deviceIdentifier := req.DeviceID
 	if device.IDDevice != nil && *device.IDDevice != "" {
@@ -106,23 +102,61 @@ func (s *FlowExecutionService) StartFlow(ctx context.Context, userID string, req
 		}
 		flow = &flows[0]
 	}
-
-	// Find start node
-	startNode := s.findStartNode(flow.Nodes)
-	if startNode == nil {
+	if flow.IDDevice != deviceIdentifier {
 		return &models.StartFlowResponse{
 			Success: false,
-			Message: "Flow has no start node",
+			Message: "Flow not found",
+		}, nil
+	}
+	if !hasFlowNodes(flow) {
+		return &models.StartFlowResponse{
+			Success: false,
+			Message: "Flow has no nodes configured",
 		}, nil
 	}
 
+	// A caller-supplied external reference must be valid
+	externalRef := ""
+	if req.ExternalRef != "" {
+		ref, ok := models.NormalizeExternalRef(req.ExternalRef)
+		if !ok {
+			return &models.StartFlowResponse{
+				Success: false,
+				Message: fmt.Sprintf("external_ref must be 1-%d characters without commas, parentheses or quotes", models.MaxExternalRefLength),
+			}, nil
+		}
+		externalRef = ref
+	}
+
 	// Create or get conversation
+	seedUpdates := make(map[string]interface{})
 	conversation, err := s.conversationRepo.GetConversationByProspectNum(ctx, req.ProspectNum, deviceIdentifier)
+	if externalRef != "" && (conversation == nil || getStringValue(conversation.ExternalRef) != externalRef) {
+		taken, err := s.conversationRepo.GetConversationByExternalRef(ctx, externalRef, []string{deviceIdentifier})
+		if err != nil {
+			return &models.StartFlowResponse{
+				Success: false,
+				Message: "Failed to check external reference",
+				Error:   err.Error(),
+			}, nil
+		}
+		if taken != nil {
+			return &models.StartFlowResponse{
+				Success: false,
+				Message: "external_ref is already used by another conversation on this device",
+			}, nil
+		}
+	}
 	if err != nil || conversation == nil {
-		// Create new conversation
+		// Create new conversation, already carrying the seed context
 		stage := "started"
+		if req.Stage != nil && *req.Stage != "" {
+			stage = *req.Stage
+		}
 		niche := flow.Niche
 		executionStatus := "active"
+		startedAt := time.Now()
+		entries := 1
 		conversation = &models.AIWhatsapp{
 			ProspectNum:     req.ProspectNum,
 			IDDevice:        deviceIdentifier,
@@ -130,6 +164,17 @@ func (s *FlowExecutionService) StartFlow(ctx context.Context, userID string, req
 			Niche:           &niche,
 			FlowID:          &flow.ID,
 			ExecutionStatus: &executionStatus,
+			SessionData:     req.Variables,
+			IsTest:          req.Test || device.Sandbox,
+			FlowStartedAt:   &startedAt,
+			FlowEntries:     &entries,
+		}
+		if externalRef != "" {
+			conversation.ExternalRef = &externalRef
+		}
+		if seed := strings.TrimSpace(req.SeedMessage); seed != "" {
+			convLast := appendConvHistory("", seedRole(req.SeedRole), seed, device.EffectiveMaxHistoryEntries())
+			conversation.ConvLast = &convLast
 		}
 
 		if err := s.conversationRepo.CreateConversation(ctx, conversation); err != nil {
@@ -139,24 +184,61 @@ func (s *FlowExecutionService) StartFlow(ctx context.Context, userID string, req
 				Error:   err.Error(),
 			}, nil
 		}
-	}
+		s.events.Emit(ctx, userID, deviceIdentifier, models.EventConversationCreated,
+			conversationEventData("ai_whatsapp", fmt.Sprintf("%d", *conversation.IDProspect), conversation))
+		if seed := strings.TrimSpace(req.SeedMessage); seed != "" {
+			s.messages.Record(ctx, newConversationMessage(models.AssignmentSourceAI, fmt.Sprintf("%d", *conversation.IDProspect), deviceIdentifier, seedRole(req.SeedRole), seed))
+		}
+	} else {
+		// A prospect who entered this flow recently is only restarted on request
+		entry := newFlowEntry(conversation.FlowStartedAt, conversation.FlowEntries)
+		if !req.IgnoreCooldown && getStringValue(conversation.FlowID) == flow.ID {
+			if until := flow.ReentryCooldownUntil(entry.startedAt); time.Now().Before(until) {
+				return &models.StartFlowResponse{
+					Success:        false,
+					Message:        fmt.Sprintf("Prospect entered this flow less than %d hours ago (cooldown ends %s); set ignore_cooldown to start it anyway", flow.ReentryCooldownHours, until.UTC().Format(time.RFC3339)),
+					ConversationID: fmt.Sprintf("%d", *conversation.IDProspect),
+				}, nil
+			}
+		}
+		for key, value := range entry.nextEntryUpdates(time.Now()) {
+			seedUpdates[key] = value
+		}
 
-	// Update conversation with current node
-	conversation.CurrentNodeID = &startNode.ID
-	conversation.FlowID = &flow.ID
-	executionStatus := "active"
-	conversation.ExecutionStatus = &executionStatus
+		// Existing conversation: merge the seed context into what it already has
+		if len(req.Variables) > 0 {
+			variables := sessionVariables(conversation)
+			for key, value := range req.Variables {
+				variables[key] = value
+			}
+			seedUpdates["session_data"] = variables
+		}
+		if req.Stage != nil && *req.Stage != "" {
+			seedUpdates["stage"] = *req.Stage
+		}
+		if externalRef != "" && externalRef != getStringValue(conversation.ExternalRef) {
+			seedUpdates["external_ref"] = externalRef
+			conversation.ExternalRef = &externalRef
+		}
+		if seed := strings.TrimSpace(req.SeedMessage); seed != "" {
+			message := newConversationMessage(models.AssignmentSourceAI, fmt.Sprintf("%d", *conversation.IDProspect), conversation.IDDevice, seedRole(req.SeedRole), seed)
+			seedUpdates["conv_last"] = s.messages.Append(ctx, getStringValue(conversation.ConvLast), message, device.EffectiveMaxHistoryEntries())
+		}
+	}
 
 	// Convert IDProspect to string for update
 	prospectIDStr := fmt.Sprintf("%d", *conversation.IDProspect)
 
+	// The run starts over from the flow's first node
 	updates := map[string]interface{}{
-		"current_node_id":  startNode.ID,
-		"flow_id":          flow.ID,
-		"execution_status": "active",
+		"flow_id":         flow.ID,
+		"current_node_id": nil,
+	}
+	for key, value := range seedUpdates {
+		updates[key] = value
 	}
 
-	if err := s.conversationRepo.UpdateConversation(ctx, prospectIDStr, updates); err != nil {
+	if err := s.stateMachine.UpdateState(ctx, prospectIDStr, models.ConversationStateActive, "", updates); err != nil {
 		return &models.StartFlowResponse{
 			Success: false,
 			Message: "Failed to update conversation",
@@ -164,13 +246,41 @@ func (s *FlowExecutionService) StartFlow(ctx context.Context, userID string, req
 		}, nil
 	}
 
+	// Run the flow up to its first pause, so the prospect gets its opening messages now
+	if err := s.processor.ExecuteFlow(ctx, flow, prospectIDStr, "", ""); err != nil {
+		return &models.StartFlowResponse{
+			Success:        false,
+			Message:        "Failed to run flow",
+			ConversationID: prospectIDStr,
+			Error:          err.Error(),
+		}, nil
+	}
+
 	return &models.StartFlowResponse{
 		Success:        true,
 		Message:        "Flow started successfully",
 		ConversationID: prospectIDStr,
+		ExternalRef:    getStringValue(conversation.ExternalRef),
 	}, nil
 }
 
+// sessionVariables returns a copy of a conversation's stored flow variables
+func sessionVariables(conversation *models.AIWhatsapp) map[string]interface{} {
+	variables := make(map[string]interface{}, len(conversation.SessionData))
+	for key, value := range conversation.SessionData {
+		variables[key] = value
+	}
+	return variables
+}
+
+// seedRole maps a StartFlow seed_role to the conv_last prefix, defaulting to User
+func seedRole(role string) string {
+	if strings.EqualFold(strings.TrimSpace(role), "bot") {
+		return "Bot"
+	}
+	return "User"
+}
+
 // ProcessMessage processes an incoming message and executes the flow
 func (s *FlowExecutionService) ProcessMessage(ctx context.Context, conversationID string, userMessage string) (*models.ExecutionResult, error) {
 	// Get conversation
@@ -198,220 +308,60 @@ func (s *FlowExecutionService) ProcessMessage(ctx context.Context, conversationI
 		}, nil
 	}
 
-	// Get current node
-	currentNodeID := ""
-	if conversation.CurrentNodeID != nil {
-		currentNodeID = *conversation.CurrentNodeID
+	if getStringValue(conversation.ExecutionStatus) == string(models.ConversationStateCompleted) {
+		return &models.ExecutionResult{
+			Success:       true,
+			Message:       "Flow already completed",
+			CompletedFlow: true,
+		}, nil
 	}
 
+	// Nodes run through the Chatbot AI flow engine, which sends the replies and records them.
+	// A resumed run records the prospect's message itself.
+	currentNodeID := getStringValue(conversation.CurrentNodeID)
 	if currentNodeID == "" {
-		// Start from beginning
-		startNode := s.findStartNode(flow.Nodes)
-		if startNode == nil {
-			return &models.ExecutionResult{
-				Success: false,
-				Message: "Flow has no start node",
-			}, nil
+		if userMessage != "" {
+			entry := newConversationMessage(models.AssignmentSourceAI, conversationID, conversation.IDDevice, "User", userMessage)
+			updates := map[string]interface{}{
+				"conv_last": s.messages.Append(ctx, getStringValue(conversation.ConvLast), entry, 0),
+			}
+			if err := s.conversationRepo.UpdateConversation(ctx, conversationID, updates); err != nil {
+				log.Printf("⚠️  Failed to record message of conversation %s: %v", conversationID, err)
+			}
 		}
-		currentNodeID = startNode.ID
-	}
-
-	// Execute flow from current node
-	// Note: SessionData not available in current schema, using empty map
-	execCtx := &models.ExecutionContext{
-		ConversationID: conversationID,
-		DeviceID:       conversation.IDDevice,
-		ProspectNum:    conversation.ProspectNum,
-		CurrentNodeID:  currentNodeID,
-		FlowID:         *conversation.FlowID,
-		UserMessage:    userMessage,
-		Variables:      make(map[string]interface{}), // SessionData not in current schema
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		err = s.processor.ExecuteFlow(ctx, flow, conversationID, userMessage, "")
+	} else {
+		err = s.processor.ResumeFlow(ctx, flow, conversationID, userMessage, currentNodeID)
 	}
-
-	result, err := s.executeNode(ctx, execCtx, flow)
 	if err != nil {
 		return &models.ExecutionResult{
 			Success: false,
-			Message: "Failed to execute node",
+			Message: "Failed to execute flow",
 			Error:   err.Error(),
 		}, err
 	}
 
-	// Build updates map
-	updates := make(map[string]interface{})
-
-	if result.NextNodeID != "" {
-		updates["current_node"] = result.NextNodeID
-	}
-
-	if result.Variables != nil {
-		updates["session_data"] = result.Variables
-	}
-
-	if result.CompletedFlow {
-		updates["is_active"] = false
-		updates["status"] = "completed"
-		updates["completed_at"] = time.Now()
-	}
-
-	if result.Response != "" {
-		// Update conv_last with format: "User: message\nBot: reply"
-		convLast := ""
-		if conversation.ConvLast != nil {
-			convLast = *conversation.ConvLast
-		}
-
-		// Add user message
-		userLine := fmt.Sprintf("User: %s", userMessage)
-		if convLast != "" {
-			convLast += "\n" + userLine
-		} else {
-			convLast = userLine
-		}
-
-		// Add bot reply
-		botLine := fmt.Sprintf("Bot: %s", result.Response)
-		convLast += "\n" + botLine
-
-		updates["conv_last"] = convLast
-	}
-
-	if err := s.conversationRepo.UpdateConversation(ctx, conversationID, updates); err != nil {
-		return result, fmt.Errorf("failed to update conversation: %w", err)
-	}
-
-	return result, nil
-}
-
-// executeNode executes a single node in the flow
-func (s *FlowExecutionService) executeNode(ctx context.Context, execCtx *models.ExecutionContext, flow *models.ChatbotFlow) (*models.ExecutionResult, error) {
-	// Find current node
-	var currentNode *models.FlowNode
-	for _, node := range flow.Nodes {
-		nodeMap, ok := node.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		id, ok := nodeMap["id"].(string)
-		if !ok || id != execCtx.CurrentNodeID {
-			continue
-		}
-
-		// Convert to FlowNode
-		typeStr, _ := nodeMap["type"].(string)
-		data, _ := nodeMap["data"].(map[string]interface{})
-
-		currentNode = &models.FlowNode{
-			ID:   id,
-			Type: models.NodeType(typeStr),
-			Data: data,
-		}
-		break
-	}
-
-	if currentNode == nil {
+	// Report where the run left the conversation
+	updated, err := s.conversationRepo.GetConversationByID(ctx, conversationID)
+	if err != nil || updated == nil {
 		return &models.ExecutionResult{
-			Success: false,
-			Message: "Current node not found",
+			Success: true,
+			Message: "Flow executed",
 		}, nil
 	}
 
-	// Get processor for node type
-	processor, ok := s.processors[currentNode.Type]
-	if !ok {
-		return &models.ExecutionResult{
-			Success: false,
-			Message: fmt.Sprintf("No processor for node type: %s", currentNode.Type),
-		}, nil
-	}
-
-	// Convert edges
-	edges := make([]models.FlowEdge, 0)
-	for _, edge := range flow.Edges {
-		edgeMap, ok := edge.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		id, _ := edgeMap["id"].(string)
-		source, _ := edgeMap["source"].(string)
-		target, _ := edgeMap["target"].(string)
-		sourceHandle, _ := edgeMap["sourceHandle"].(string)
-		targetHandle, _ := edgeMap["targetHandle"].(string)
-		label, _ := edgeMap["label"].(string)
-
-		edges = append(edges, models.FlowEdge{
-			ID:           id,
-			Source:       source,
-			Target:       target,
-			SourceHandle: sourceHandle,
-			TargetHandle: targetHandle,
-			Label:        label,
-		})
-	}
-
-	// Process node
-	result, err := processor.ProcessNode(execCtx, currentNode, edges)
-	if err != nil {
-		return &models.ExecutionResult{
-			Success: false,
-			Message: "Failed to process node",
-			Error:   err.Error(),
-		}, err
-	}
-
-	return result, nil
-}
-
-// findStartNode finds the start node in a flow
-func (s *FlowExecutionService) findStartNode(nodes map[string]interface{}) *models.FlowNode {
-	// Try to convert nodes to array
-	var nodeArray []interface{}
-
-	// Check if it's already an array
-	if arr, ok := nodes["nodes"].([]interface{}); ok {
-		nodeArray = arr
-	} else {
-		// Maybe the entire map IS the nodes array stored differently
-		// Try to iterate over the map values
-		for _, v := range nodes {
-			if arr, ok := v.([]interface{}); ok {
-				nodeArray = arr
-				break
-			}
-		}
+	completed := getStringValue(updated.ExecutionStatus) == string(models.ConversationStateCompleted)
+	nextNodeID := getStringValue(updated.CurrentNodeID)
+	if completed {
+		nextNodeID = ""
 	}
-
-	// If still not found, maybe it's stored as direct array (should be in Nodes field)
-	if nodeArray == nil {
-		return nil
-	}
-
-	for _, node := range nodeArray {
-		nodeMap, ok := node.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		typeStr, ok := nodeMap["type"].(string)
-		if !ok || typeStr != string(models.NodeTypeStart) {
-			continue
-		}
-
-		id, _ := nodeMap["id"].(string)
-		data, _ := nodeMap["data"].(map[string]interface{})
-
-		return &models.FlowNode{
-			ID:   id,
-			Type: models.NodeTypeStart,
-			Data: data,
-		}
-	}
-
-	return nil
+	return &models.ExecutionResult{
+		Success:       true,
+		Message:       "Flow executed",
+		NextNodeID:    nextNodeID,
+		Variables:     sessionVariables(updated),
+		CompletedFlow: completed,
+	}, nil
 }
 
 // GetExecutionStatus gets the current execution status of a conversation
@@ -460,7 +410,7 @@ func (s *FlowExecutionService) GetExecutionStatus(ctx context.Context, userID st
 		ProspectNum:    conversation.ProspectNum,
 		CurrentNodeID:  currentNodeID,
 		FlowID:         flowID,
-		Variables:      make(map[string]interface{}), // SessionData not in current schema
+		Variables:      sessionVariables(conversation),
 		CreatedAt:      createdAt,
 		UpdatedAt:      updatedAt,
 	}, nil
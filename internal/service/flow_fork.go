@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Parallel branches. A fork node starts every outgoing connection as a branch at once (say, send
+// the catalog while notifying an agent); each branch runs until it reaches a join node or ends. The
+// join waits for the branches it is configured for, all of them by default, and the flow continues
+// after it:
+//
+//	{"type": "fork"}
+//	{"type": "join", "config": {"wait_for": ["send_catalog", "notify_agent"]}}
+//
+// wait_for lists the first node of each branch to wait for. Branches left out still run, but the
+// flow does not wait for them. The conversation keeps a single resting point, so branches cannot
+// pause for a reply or a delay; flow validation refuses such flows.
+const (
+	flowForkNode = "fork"
+	flowJoinNode = "join"
+)
+
+// forkBranchResult is how one branch of a fork ended
+type forkBranchResult struct {
+	start  string    // the branch's first node
+	join   *FlowNode // the join the branch reached; nil when it ran out of nodes
+	paused bool
+	err    error
+}
+
+// runFork runs the branches of a fork node and returns the join to continue from. It waits for the
+// branches the join awaits; paused is true when one of them paused the conversation instead.
+func (run *flowRun) runFork(ctx context.Context, fork *FlowNode) (*FlowNode, bool, error) {
+	var branches []*FlowNode
+	for _, edge := range run.flowData.Connections {
+		if edge.From != fork.ID {
+			continue
+		}
+		if start := findFlowNode(run.flowData, edge.To); start != nil {
+			branches = append(branches, start)
+		}
+	}
+	if len(branches) == 0 {
+		log.Printf("ℹ️  Fork %s has no branches", fork.ID)
+		return nil, false, nil
+	}
+
+	join := forkJoin(run.flowData, fork.ID)
+	awaited := joinAwaits(join, branches)
+	log.Printf("🔱 Fork %s: running %d branches, waiting for %d", fork.ID, len(branches), len(awaited))
+
+	results := make(chan forkBranchResult, len(branches))
+	for _, start := range branches {
+		// Each branch gets its own copy of the run, so a translate node only rewrites its own branch
+		branch := *run
+		branchCtx := ctx
+		if !awaited[start.ID] {
+			// Nobody waits for it, so it must outlive the run that started it
+			branchCtx = context.WithoutCancel(ctx)
+		}
+		runBranch := func(start *FlowNode) {
+			reached, paused, err := branch.walk(branchCtx, start, true)
+			results <- forkBranchResult{start: start.ID, join: reached, paused: paused, err: err}
+		}
+
+		// Dry runs take the branches one after another, so their trace reads in flow order
+		if run.sim != nil {
+			runBranch(start)
+		} else {
+			go runBranch(start)
+		}
+	}
+
+	var reached bool
+	var paused bool
+	var errs []string
+	for remaining := len(awaited); remaining > 0; {
+		result := <-results
+		if !awaited[result.start] {
+			logForkBranch(fork, result)
+			continue
+		}
+		remaining--
+
+		switch {
+		case result.err != nil:
+			errs = append(errs, fmt.Sprintf("branch %s: %v", result.start, result.err))
+		case result.paused:
+			paused = true
+		case result.join != nil:
+			reached = true
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, false, fmt.Errorf("fork %s failed: %s", fork.ID, strings.Join(errs, "; "))
+	}
+	if paused {
+		log.Printf("⏸️  Fork %s: a branch paused the conversation, the join is skipped", fork.ID)
+		return nil, true, nil
+	}
+	if join == nil || !reached {
+		log.Printf("ℹ️  Fork %s: no branch reached a join", fork.ID)
+		return nil, false, nil
+	}
+
+	log.Printf("🔗 Fork %s: branches joined at %s", fork.ID, join.ID)
+	return join, false, nil
+}
+
+// logForkBranch reports how a branch nobody waits for ended
+func logForkBranch(fork *FlowNode, result forkBranchResult) {
+	if result.err != nil {
+		log.Printf("⚠️  Fork %s: branch %s failed: %v", fork.ID, result.start, result.err)
+	}
+}
+
+// forkJoin returns the first join node reached from a fork's branches, or nil when they never meet
+func forkJoin(flowData *FlowData, forkID string) *FlowNode {
+	seen := map[string]bool{forkID: true}
+	queue := []string{forkID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, edge := range flowData.Connections {
+			if edge.From != id || seen[edge.To] {
+				continue
+			}
+			seen[edge.To] = true
+
+			node := findFlowNode(flowData, edge.To)
+			if node == nil {
+				continue
+			}
+			if node.Type == flowJoinNode {
+				return node
+			}
+			queue = append(queue, node.ID)
+		}
+	}
+	return nil
+}
+
+// joinAwaits returns the branches a join waits for, by their first node: those listed in its
+// wait_for config, or every branch when the list is empty or names none of them
+func joinAwaits(join *FlowNode, branches []*FlowNode) map[string]bool {
+	awaited := make(map[string]bool, len(branches))
+	if join != nil {
+		for _, id := range joinWaitFor(join) {
+			for _, start := range branches {
+				if start.ID == id {
+					awaited[id] = true
+				}
+			}
+		}
+	}
+
+	if len(awaited) == 0 {
+		for _, start := range branches {
+			awaited[start.ID] = true
+		}
+	}
+	return awaited
+}
+
+// joinWaitFor returns the branch start nodes listed in a join node's wait_for config
+func joinWaitFor(join *FlowNode) []string {
+	raw, _ := join.Config["wait_for"].([]interface{})
+	ids := make([]string, 0, len(raw))
+	for _, value := range raw {
+		if id, ok := value.(string); ok && strings.TrimSpace(id) != "" {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"chatbot-automation/internal/models"
+)
+
+const (
+	defaultLayoutNodeSpacing  = 260.0
+	defaultLayoutLayerSpacing = 160.0
+	layoutOrderingSweeps      = 4
+)
+
+// layoutGraph is the flow reduced to what the layered layout needs
+type layoutGraph struct {
+	ids      []string            // node IDs in original order
+	children map[string][]string // forward edges (back edges removed)
+	parents  map[string][]string
+}
+
+// autoLayoutNodesData runs a layered (Sugiyama style) layout over a nodes_data JSON string
+// and returns it with new x/y positions. Fields the layout does not know about are preserved.
+func autoLayoutNodesData(nodesData string, req *models.AutoLayoutRequest) (string, error) {
+	var flowData map[string]interface{}
+	if err := json.Unmarshal([]byte(nodesData), &flowData); err != nil {
+		return "", fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	rawNodes, _ := flowData["nodes"].([]interface{})
+	if len(rawNodes) == 0 {
+		return "", fmt.Errorf("flow has no nodes to lay out")
+	}
+
+	nodes := make(map[string]map[string]interface{}, len(rawNodes))
+	graph := &layoutGraph{
+		children: make(map[string][]string),
+		parents:  make(map[string][]string),
+	}
+	startID := ""
+
+	for _, raw := range rawNodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := node["id"].(string)
+		if id == "" || nodes[id] != nil {
+			continue
+		}
+		nodes[id] = node
+		graph.ids = append(graph.ids, id)
+		if nodeType, _ := node["type"].(string); nodeType == "start" && startID == "" {
+			startID = id
+		}
+	}
+
+	var edges [][2]string
+	rawConnections, _ := flowData["connections"].([]interface{})
+	for _, raw := range rawConnections {
+		conn, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _ := conn["from"].(string)
+		to, _ := conn["to"].(string)
+		if nodes[from] == nil || nodes[to] == nil || from == to {
+			continue
+		}
+		edges = append(edges, [2]string{from, to})
+	}
+
+	graph.addAcyclicEdges(edges, startID)
+
+	layers := graph.assignLayers()
+	graph.orderLayers(layers)
+
+	nodeSpacing := defaultLayoutNodeSpacing
+	if req != nil && req.NodeSpacing > 0 {
+		nodeSpacing = req.NodeSpacing
+	}
+	layerSpacing := defaultLayoutLayerSpacing
+	if req != nil && req.LayerSpacing > 0 {
+		layerSpacing = req.LayerSpacing
+	}
+	horizontal := req != nil && req.Direction == models.LayoutLeftToRight
+
+	// Widest layer decides the centre line
+	widest := 0
+	for _, layer := range layers {
+		if len(layer) > widest {
+			widest = len(layer)
+		}
+	}
+
+	for depth, layer := range layers {
+		offset := float64(widest-len(layer)) * nodeSpacing / 2
+		for index, id := range layer {
+			across := offset + float64(index)*nodeSpacing
+			along := float64(depth) * layerSpacing
+
+			x, y := across, along
+			if horizontal {
+				x, y = along, across
+			}
+
+			node := nodes[id]
+			node["x"] = x
+			node["y"] = y
+			// React Flow style nodes keep their coordinates under "position"
+			if position, ok := node["position"].(map[string]interface{}); ok {
+				position["x"] = x
+				position["y"] = y
+			}
+		}
+	}
+
+	out, err := json.Marshal(flowData)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode flow data: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// addAcyclicEdges adds edges while dropping those that close a cycle (loops back to an
+// earlier node, e.g. "ask again"), found by DFS from the start node then any unvisited node
+func (g *layoutGraph) addAcyclicEdges(edges [][2]string, startID string) {
+	adjacency := make(map[string][]string)
+	for _, edge := range edges {
+		adjacency[edge[0]] = append(adjacency[edge[0]], edge[1])
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = inProgress
+		for _, next := range adjacency[id] {
+			switch state[next] {
+			case inProgress:
+				continue // back edge
+			case unvisited:
+				visit(next)
+			}
+			g.children[id] = append(g.children[id], next)
+			g.parents[next] = append(g.parents[next], id)
+		}
+		state[id] = done
+	}
+
+	if startID != "" {
+		visit(startID)
+	}
+	for _, id := range g.ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+}
+
+// assignLayers places every node one layer below its deepest parent (longest path layering)
+func (g *layoutGraph) assignLayers() [][]string {
+	depth := make(map[string]int, len(g.ids))
+	indegree := make(map[string]int, len(g.ids))
+	for _, id := range g.ids {
+		indegree[id] = len(g.parents[id])
+	}
+
+	queue := make([]string, 0, len(g.ids))
+	for _, id := range g.ids {
+		if indegree[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+
+	maxDepth := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, child := range g.children[id] {
+			if depth[id]+1 > depth[child] {
+				depth[child] = depth[id] + 1
+			}
+			indegree[child]--
+			if indegree[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+		if depth[id] > maxDepth {
+			maxDepth = depth[id]
+		}
+	}
+
+	layers := make([][]string, maxDepth+1)
+	for _, id := range g.ids {
+		layers[depth[id]] = append(layers[depth[id]], id)
+	}
+
+	return layers
+}
+
+// orderLayers reduces edge crossings with alternating barycenter sweeps
+func (g *layoutGraph) orderLayers(layers [][]string) {
+	position := make(map[string]float64)
+	reindex := func(layer []string) {
+		for i, id := range layer {
+			position[id] = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		reindex(layer)
+	}
+
+	barycenter := func(id string, neighbours []string) float64 {
+		if len(neighbours) == 0 {
+			return position[id]
+		}
+		sum := 0.0
+		for _, n := range neighbours {
+			sum += position[n]
+		}
+		return sum / float64(len(neighbours))
+	}
+
+	sortLayer := func(layer []string, neighbours map[string][]string) {
+		weights := make(map[string]float64, len(layer))
+		for _, id := range layer {
+			weights[id] = barycenter(id, neighbours[id])
+		}
+		sort.SliceStable(layer, func(i, j int) bool { return weights[layer[i]] < weights[layer[j]] })
+		reindex(layer)
+	}
+
+	for sweep := 0; sweep < layoutOrderingSweeps; sweep++ {
+		if sweep%2 == 0 {
+			for i := 1; i < len(layers); i++ {
+				sortLayer(layers[i], g.parents)
+			}
+		} else {
+			for i := len(layers) - 2; i >= 0; i-- {
+				sortLayer(layers[i], g.children)
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+const (
+	mediaCheckTimeout     = 15 * time.Second
+	mediaCheckConcurrency = 4
+)
+
+// mediaSizeLimits are the largest files WhatsApp accepts per media type, in bytes
+var mediaSizeLimits = map[string]int64{
+	"image": 5 << 20,
+	"audio": 16 << 20,
+	"video": 16 << 20,
+}
+
+// CheckFlowMedia checks every media URL a flow the user owns can send: that it is reachable,
+// serves the media type the node sends it as, and is within the provider size limit
+func (s *FlowService) CheckFlowMedia(ctx context.Context, userID, flowID string) (*models.FlowMediaCheckResponse, error) {
+	// GetFlow verifies ownership
+	resp, err := s.GetFlow(ctx, userID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return &models.FlowMediaCheckResponse{
+			Success: false,
+			Message: resp.Message,
+		}, nil
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(resp.Flow.NodesData), &flowData); err != nil {
+		return &models.FlowMediaCheckResponse{
+			Success: false,
+			Message: fmt.Sprintf("Flow data is not valid JSON: %v", err),
+		}, nil
+	}
+
+	checks := flowMediaChecks(flowData.Nodes)
+
+	client := &http.Client{Timeout: mediaCheckTimeout}
+	sem := make(chan struct{}, mediaCheckConcurrency)
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(check *models.FlowMediaCheck) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			checkMediaURL(ctx, client, check)
+		}(&checks[i])
+	}
+	wg.Wait()
+
+	result := &models.FlowMediaCheckResponse{
+		Success: true,
+		Checked: len(checks),
+		Media:   checks,
+	}
+	for _, check := range checks {
+		switch check.Status {
+		case models.MediaCheckFailed:
+			result.Failed++
+		case models.MediaCheckWarning:
+			result.Warnings++
+		}
+	}
+	result.Ready = result.Failed == 0
+	result.Message = fmt.Sprintf("Checked %d media URLs: %d failed, %d warnings", result.Checked, result.Failed, result.Warnings)
+	return result, nil
+}
+
+// flowMediaChecks lists the media URLs of send_image/send_audio/send_video and media_switch nodes
+func flowMediaChecks(nodes []FlowNode) []models.FlowMediaCheck {
+	checks := []models.FlowMediaCheck{}
+	add := func(node FlowNode, variant, url, mediaType string) {
+		checks = append(checks, models.FlowMediaCheck{
+			NodeID:     node.ID,
+			NodeType:   node.Type,
+			Variant:    variant,
+			URL:        strings.TrimSpace(url),
+			MediaType:  mediaType,
+			LimitBytes: mediaSizeLimits[mediaType],
+		})
+	}
+
+	for _, node := range nodes {
+		switch node.Type {
+		case "send_image", "send_audio", "send_video":
+			url, _ := node.Config["url"].(string)
+			add(node, "", url, strings.TrimPrefix(node.Type, "send_"))
+
+		case "media_switch":
+			defaultType, _ := node.Config["media_type"].(string)
+			if defaultType == "" {
+				defaultType = "image"
+			}
+			if variants, ok := node.Config["variants"].([]interface{}); ok {
+				for _, raw := range variants {
+					variant, ok := raw.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					url, _ := variant["url"].(string)
+					mediaType, _ := variant["media_type"].(string)
+					if mediaType == "" {
+						mediaType = defaultType
+					}
+					add(node, fmt.Sprintf("%v", variant["value"]), url, mediaType)
+				}
+			}
+			if url, _ := node.Config["default_url"].(string); url != "" {
+				add(node, "default", url, defaultType)
+			}
+		}
+	}
+	return checks
+}
+
+// checkMediaURL fetches the headers of one media URL and fills in its status and issues.
+// HEAD is tried first; servers that refuse it get a GET for the first byte only.
+func checkMediaURL(ctx context.Context, client *http.Client, check *models.FlowMediaCheck) {
+	fail := func(issue string) {
+		check.Status = models.MediaCheckFailed
+		check.Issues = append(check.Issues, issue)
+	}
+	warn := func(issue string) {
+		if check.Status != models.MediaCheckFailed {
+			check.Status = models.MediaCheckWarning
+		}
+		check.Issues = append(check.Issues, issue)
+	}
+	check.Status = models.MediaCheckOK
+
+	if check.URL == "" {
+		fail("no media URL set")
+		return
+	}
+	if !validHeartbeatURL(check.URL) {
+		fail("URL must be an absolute http or https URL")
+		return
+	}
+	if _, ok := mediaSizeLimits[check.MediaType]; !ok {
+		fail(fmt.Sprintf("unknown media type %q", check.MediaType))
+		return
+	}
+
+	resp, err := fetchMediaHead(ctx, client, http.MethodHead, check.URL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = fetchMediaHead(ctx, client, http.MethodGet, check.URL)
+	}
+	if err != nil {
+		fail(fmt.Sprintf("unreachable: %v", err))
+		return
+	}
+
+	check.HTTPStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		fail(fmt.Sprintf("server returned HTTP %d", resp.StatusCode))
+		return
+	}
+
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	check.ContentType = contentType
+	switch {
+	case strings.HasPrefix(contentType, check.MediaType+"/"):
+	case contentType == "" || contentType == "application/octet-stream" || contentType == "binary/octet-stream":
+		warn("server does not report the media type; WhatsApp may reject the file")
+	default:
+		fail(fmt.Sprintf("served as %s, but the node sends it as %s", contentType, check.MediaType))
+	}
+
+	size := mediaContentSize(resp)
+	if size < 0 {
+		warn("server does not report the file size")
+		return
+	}
+	check.SizeBytes = &size
+	if size > check.LimitBytes {
+		fail(fmt.Sprintf("%.1f MB exceeds the %d MB %s limit", float64(size)/(1<<20), check.LimitBytes>>20, check.MediaType))
+	}
+}
+
+// fetchMediaHead requests a media URL and discards the body. GET requests ask for the first byte only.
+func fetchMediaHead(ctx context.Context, client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// mediaContentSize returns the full size of the file, -1 when the server does not report it.
+// Ranged responses carry it in Content-Range ("bytes 0-0/12345").
+func mediaContentSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+			if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+				if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+					return size
+				}
+			}
+		}
+		return -1
+	}
+	return resp.ContentLength
+}
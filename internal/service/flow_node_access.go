@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// DefaultAdminOnlyNodeTypes are the node types team agents cannot add, change or remove when
+// FLOW_ADMIN_ONLY_NODES is not set: integrations (webhook events, email, legacy API calls) and
+// nodes that spend money (translation, AI). Google Sheets rows follow the owner's sheet exports
+// rather than the stage node, so stage nodes stay editable.
+var DefaultAdminOnlyNodeTypes = []string{flowEmitEventNode, "send_email", legacyAPINode, "translate", "ai_prompt"}
+
+// highCostModelPromptPrice is the prompt price (USD per million tokens) from which an ai_prompt
+// node's model makes the node admin-only. Models without a known price count as high-cost.
+const highCostModelPromptPrice = 2.00
+
+// NodeAccessPolicy decides which flow nodes only admins may edit. Team agents can still edit
+// message copy and every other node; the policy is enforced when a flow is saved.
+type NodeAccessPolicy struct {
+	adminOnly []string
+}
+
+// NewNodeAccessPolicy builds a policy from a comma-separated list of node types (empty uses
+// DefaultAdminOnlyNodeTypes). ai_prompt in the list locks only nodes that pick a high-cost model.
+func NewNodeAccessPolicy(nodeTypes string) NodeAccessPolicy {
+	var adminOnly []string
+	for _, nodeType := range strings.Split(nodeTypes, ",") {
+		if nodeType = strings.TrimSpace(nodeType); nodeType != "" {
+			adminOnly = append(adminOnly, nodeType)
+		}
+	}
+	if len(adminOnly) == 0 {
+		adminOnly = DefaultAdminOnlyNodeTypes
+	}
+	return NodeAccessPolicy{adminOnly: adminOnly}
+}
+
+// adminOnlyNode reports whether node may only be edited by admins. An ai_prompt node without a
+// model of its own uses the device's model, which the node does not choose.
+func (p NodeAccessPolicy) adminOnlyNode(node *FlowNode) bool {
+	if node == nil || !slices.Contains(p.adminOnly, node.Type) {
+		return false
+	}
+	if node.Type != "ai_prompt" {
+		return true
+	}
+
+	model, _ := node.Config["model"].(string)
+	if strings.TrimSpace(model) == "" {
+		return false
+	}
+	price, ok := models.LookupModelPrice(model)
+	return !ok || price.Prompt >= highCostModelPromptPrice
+}
+
+// lockedNodeChanges lists the admin-only nodes that differ between the saved nodes_data (empty
+// for a new flow) and the one being saved: added, removed, or with another type or config.
+// Moving a node on the canvas or renaming it is not a change.
+func (p NodeAccessPolicy) lockedNodeChanges(saved, next string) ([]string, error) {
+	before, err := flowNodesByID(saved)
+	if err != nil {
+		return nil, err
+	}
+	after, err := flowNodesByID(next)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for id, node := range after {
+		old := before[id]
+		if !p.adminOnlyNode(node) && !p.adminOnlyNode(old) {
+			continue
+		}
+		switch {
+		case old == nil:
+			changes = append(changes, fmt.Sprintf("%s node %s added", node.Type, id))
+		case old.Type != node.Type || !reflect.DeepEqual(old.Config, node.Config):
+			changes = append(changes, fmt.Sprintf("%s node %s changed", old.Type, id))
+		}
+	}
+	for id, old := range before {
+		if _, kept := after[id]; !kept && p.adminOnlyNode(old) {
+			changes = append(changes, fmt.Sprintf("%s node %s removed", old.Type, id))
+		}
+	}
+
+	slices.Sort(changes)
+	return changes, nil
+}
+
+// flowNodesByID indexes the nodes of nodes_data by ID
+func flowNodesByID(nodesData string) (map[string]*FlowNode, error) {
+	nodes := make(map[string]*FlowNode)
+	if strings.TrimSpace(nodesData) == "" {
+		return nodes, nil
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(nodesData), &flowData); err != nil {
+		return nil, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+	for i := range flowData.Nodes {
+		nodes[flowData.Nodes[i].ID] = &flowData.Nodes[i]
+	}
+	return nodes, nil
+}
+
+// canEditFlows reports whether the user may save flows on a device: fully with flows.edit, or
+// short of flow settings and admin-only nodes with flows.edit_copy (see checkNodeAccess)
+func (s *FlowService) canEditFlows(ctx context.Context, userID string, device *models.DeviceSetting) bool {
+	return s.team.Allows(ctx, userID, device, models.TeamPermissionEditFlows) ||
+		s.team.Allows(ctx, userID, device, models.TeamPermissionEditFlowCopy)
+}
+
+// createsFlowSettings reports whether a new flow sets more than its name, niche and nodes
+func createsFlowSettings(req *models.CreateFlowRequest) bool {
+	return req.CompletionPolicy != "" || req.AfterSalesFlowID != nil ||
+		req.CompletionWebhookURL != nil || req.CompletionWebhookTemplate != nil ||
+		req.ReentryCooldownHours != 0 || req.NormalizeMalay || len(req.Triggers) > 0 || req.Priority != 0
+}
+
+// updatesFlowSettings reports whether an update changes more than a flow's name, niche and nodes
+func updatesFlowSettings(req *models.UpdateFlowRequest) bool {
+	return req.CompletionPolicy != nil || req.AfterSalesFlowID != nil ||
+		req.CompletionWebhookURL != nil || req.CompletionWebhookTemplate != nil ||
+		req.ReentryCooldownHours != nil || req.NormalizeMalay != nil || req.Triggers != nil
+}
+
+// checkNodeAccess returns a message for the client when the save of a team member whose role may
+// only edit flow copy touches admin-only nodes or, with settings, the flow's settings. Returns ""
+// when the save is allowed.
+func (s *FlowService) checkNodeAccess(ctx context.Context, userID string, device *models.DeviceSetting, saved, next string, settings bool) (string, error) {
+	role, err := s.team.DeviceRole(ctx, userID, device)
+	if err != nil {
+		return "", fmt.Errorf("failed to check team role: %w", err)
+	}
+	if models.TeamRoleAllows(role, models.TeamPermissionEditFlows) {
+		return "", nil
+	}
+	if settings {
+		return "Only admins can change flow settings (completion policy, webhook, re-entry cooldown, Malay normalization, triggers, priority)", nil
+	}
+
+	changes, err := s.nodeAccess.lockedNodeChanges(saved, next)
+	if err != nil || len(changes) == 0 {
+		// Unparseable nodes_data is reported by flow validation
+		return "", nil
+	}
+	return fmt.Sprintf("Only admins can add, change or remove these nodes: %s", strings.Join(changes, "; ")), nil
+}
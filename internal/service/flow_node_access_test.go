@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestDefaultAdminOnlyNodeTypesAreRegistered(t *testing.T) {
+	for _, nodeType := range DefaultAdminOnlyNodeTypes {
+		if _, ok := flowNodeProcessors[nodeType]; !ok {
+			t.Errorf("default admin-only node type %q has no processor", nodeType)
+		}
+	}
+}
+
+func TestNodeAccessPolicyLocksDefaultTypes(t *testing.T) {
+	policy := NewNodeAccessPolicy("")
+	for nodeType, want := range map[string]bool{
+		flowEmitEventNode: true,
+		"send_email":      true,
+		legacyAPINode:     true,
+		"translate":       true,
+		"send_message":    false,
+		"stage":           false,
+	} {
+		if got := policy.adminOnlyNode(&FlowNode{Type: nodeType}); got != want {
+			t.Errorf("adminOnlyNode(%s) = %v, want %v", nodeType, got, want)
+		}
+	}
+}
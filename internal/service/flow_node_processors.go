@@ -0,0 +1,686 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// flowNodeProcessor runs one node type for every flow engine: Chatbot AI, Whatsapp Bot and the
+// webhook flows of FlowExecutionService, which run on the Chatbot AI engine, legacy flows included
+// (see legacy_flow_nodes.go). ProcessNode returns false to stop the flow at the node; the node has
+// then stored the state the flow resumes from. A new node type needs one processor, registered in
+// registerFlowNodeProcessors.
+type flowNodeProcessor interface {
+	ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error)
+	GetNodeType() string
+}
+
+// flowReplyProcessor is implemented by nodes that wait for an answer. HandleReply runs when the
+// flow resumes at the node; false keeps the flow waiting there.
+type flowReplyProcessor interface {
+	HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error)
+}
+
+// flowNodeProcessors is the node registry shared by all flow engines
+var flowNodeProcessors = registerFlowNodeProcessors()
+
+// registerFlowNodeProcessors registers all flow node processors
+func registerFlowNodeProcessors() map[string]flowNodeProcessor {
+	processors := make(map[string]flowNodeProcessor)
+	for _, processor := range []flowNodeProcessor{
+		&sendMessageProcessor{},
+		&delayProcessor{},
+		&waitingReplyProcessor{},
+		&waitingTimesProcessor{},
+		&aiPromptProcessor{},
+		&stageProcessor{},
+		&sendMediaProcessor{nodeType: "send_image", mediaType: "image"},
+		&sendMediaProcessor{nodeType: "send_audio", mediaType: "audio"},
+		&sendMediaProcessor{nodeType: "send_video", mediaType: "video"},
+		&mediaSwitchProcessor{},
+		&translateProcessor{},
+		&conditionsProcessor{},
+		&sendEmailProcessor{},
+		&forkProcessor{},
+		&joinProcessor{},
+		&csatProcessor{},
+		&consentProcessor{},
+		&abSplitProcessor{},
+		&businessHoursProcessor{},
+		&setVariableProcessor{},
+		&emitEventProcessor{},
+		&interactiveProcessor{nodeType: flowSendButtonsNode},
+		&interactiveProcessor{nodeType: flowSendListNode},
+		&richMessageProcessor{nodeType: flowSendLocationNode},
+		&richMessageProcessor{nodeType: flowSendContactNode},
+		&legacyStartProcessor{},
+		&legacyMediaProcessor{nodeType: "image"},
+		&legacyMediaProcessor{nodeType: "audio"},
+		&legacyMediaProcessor{nodeType: "video"},
+		&legacyMediaProcessor{nodeType: "document"},
+		&legacyConditionProcessor{},
+		&legacyPromptProcessor{},
+		&legacyUserReplyProcessor{},
+		&legacyAPIProcessor{},
+	} {
+		processors[processor.GetNodeType()] = processor
+	}
+	return processors
+}
+
+// sendMessageProcessor sends a WhatsApp message
+type sendMessageProcessor struct{}
+
+func (p *sendMessageProcessor) GetNodeType() string { return "send_message" }
+
+func (p *sendMessageProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	// Get message text from config; a message template can stand in for it
+	text, _ := node.Config["text"].(string)
+	templateName, _ := node.Config["message_template"].(string)
+	if text == "" && strings.TrimSpace(templateName) == "" {
+		log.Printf("⚠️  No text configured for send_message node")
+		return true, nil
+	}
+
+	// Get conversation to get phone number and customer data
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for sending: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	// Templates are filled from the conversation's columns and custom fields like the text is
+	if name := sendMessageTemplateName(node, text, conversation); name != "" {
+		if content, found := run.messageTemplateContent(ctx, run.flow.IDDevice, name); found {
+			text = content
+		} else {
+			log.Printf("⚠️  Message template %s not found, sending the node's text", name)
+		}
+	}
+	if text == "" {
+		return true, nil
+	}
+	return true, run.sendText(ctx, node, conversation, text)
+}
+
+// sendText fills a node's message from the conversation, sends it in the prospect's language and
+// records it in conv_last
+func (run *flowRun) sendText(ctx context.Context, node *FlowNode, conversation *flowConversation, text string) error {
+	text = renderMessageTemplate(text, conversation.row)
+
+	log.Printf("📤 Sending message: %s", text)
+
+	// Send WhatsApp message, in the prospect's language when translation is active
+	outbound := run.translator.ForProspect(ctx, run.flow.IDDevice, getStringValue(conversation.Language), text)
+	outbound = run.links.WrapLinks(ctx, run.flow, node, run.conversationID, conversation.ProspectNum, outbound)
+	err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, outbound, "", "")
+	if err != nil {
+		log.Printf("❌ Failed to send WhatsApp message: %v", err)
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	log.Printf("✅ Message sent successfully to %s", conversation.ProspectNum)
+
+	// Update conv_last with bot reply
+	return run.appendHistory(ctx, run.conversationID, node.ID, "Bot", text)
+}
+
+// delayProcessor pauses execution for the configured seconds; the flow scheduler resumes it
+type delayProcessor struct{}
+
+func (p *delayProcessor) GetNodeType() string { return "delay" }
+
+func (p *delayProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	// Get delay from config (should be 3 seconds by default)
+	delay := 3
+	if delayVal, ok := node.Config["delay"].(float64); ok {
+		delay = int(delayVal)
+	}
+
+	log.Printf("⏱️  Delaying for %d seconds", delay)
+	continueFlow, err := run.pauseUntil(ctx, node, models.ConversationStateScheduled, time.Duration(delay)*time.Second)
+	if err != nil {
+		return false, fmt.Errorf("delay interrupted: %w", err)
+	}
+
+	return continueFlow, nil
+}
+
+// waitingReplyProcessor pauses the flow until the user replies (no timeout)
+type waitingReplyProcessor struct{}
+
+func (p *waitingReplyProcessor) GetNodeType() string { return "waiting_reply" }
+
+func (p *waitingReplyProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("⏸️  Waiting for user reply (no timeout)")
+
+	// Update conversation to waiting state
+	err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+
+	log.Printf("✅ Set waiting_for_reply=true, current_node_id=%s", node.ID)
+
+	// Flow will resume when next webhook message arrives
+	return false, nil // false = stop flow execution
+}
+
+// waitingTimesProcessor pauses and waits for the user reply with a timeout. A reply resumes the
+// flow like waiting_reply; otherwise the flow scheduler resumes it when the timeout passes.
+type waitingTimesProcessor struct{}
+
+func (p *waitingTimesProcessor) GetNodeType() string { return "waiting_times" }
+
+func (p *waitingTimesProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	// Get timeout from config (should be 8 seconds by default)
+	timeout := 8
+	if delayVal, ok := node.Config["delay"].(float64); ok {
+		timeout = int(delayVal)
+	}
+
+	log.Printf("⏳ Waiting for user reply with %d second timeout", timeout)
+	continueFlow, err := run.pauseUntil(ctx, node, models.ConversationStateWaiting, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return false, fmt.Errorf("wait interrupted: %w", err)
+	}
+
+	return continueFlow, nil
+}
+
+// aiPromptProcessor runs an ai_prompt node through the shared AI pipeline, reading and writing
+// the conversation's stage and conv_last
+type aiPromptProcessor struct{}
+
+func (p *aiPromptProcessor) GetNodeType() string { return "ai_prompt" }
+
+func (p *aiPromptProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	if run.ai == nil {
+		log.Printf("⚠️  AI pipeline not available, skipping ai_prompt node %s", node.ID)
+		return true, nil
+	}
+
+	return run.ai.runAIPrompt(ctx, run.flow, node, run.userMessage, &aiPromptTarget{
+		conversationID: run.conversationID,
+		store:          run.store,
+		load: func(ctx context.Context) (*models.AIWhatsapp, error) {
+			conversation, err := run.load(ctx, run.conversationID)
+			if err != nil || conversation == nil {
+				return nil, err
+			}
+			return conversation.aiWhatsapp(), nil
+		},
+		appendHistory: func(ctx context.Context, role, message string) error {
+			return run.appendHistory(ctx, run.conversationID, node.ID, role, message)
+		},
+		stageChanged: func(ctx context.Context, previous *string, stage string) {
+			run.notifyStageChanged(ctx, run.flow, run.conversationID, node.ID, previous, stage, "ai")
+		},
+	})
+}
+
+// stageProcessor updates the conversation stage. With stage configurations, the configured column
+// or custom field is filled at the same time.
+type stageProcessor struct{}
+
+func (p *stageProcessor) GetNodeType() string { return "stage" }
+
+func (p *stageProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	// Get stage name from config
+	stageName, ok := node.Config["value"].(string)
+	if !ok || stageName == "" {
+		log.Printf("⚠️  No stage value configured")
+		return true, nil
+	}
+
+	log.Printf("🎯 Processing stage: %s for conversation ID: %s", stageName, run.conversationID)
+
+	// The stage before the node, for stage.changed
+	var previous *string
+	if run.emitsEvents() {
+		if before, err := run.load(ctx, run.conversationID); err == nil && before != nil {
+			previous = before.Stage
+		}
+	}
+
+	updates := map[string]interface{}{
+		"stage": stageName,
+	}
+
+	columnName := ""
+	if run.stageRepo != nil {
+		column, value, err := p.configuredColumn(ctx, run, stageName)
+		if err != nil {
+			return true, err
+		}
+		if column != "" {
+			columnName, err = p.capture(ctx, run, updates, column, value)
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+
+	log.Printf("🔍 Calling UpdateConversation with updates: %+v", updates)
+	if err := run.store.UpdateConversation(ctx, run.conversationID, updates); err != nil {
+		if columnName != "" {
+			log.Printf("❌ Failed to update stage and column: %v", err)
+			return true, fmt.Errorf("failed to update stage and column: %w", err)
+		}
+		log.Printf("❌ Failed to update stage: %v", err)
+		return true, fmt.Errorf("failed to update stage: %w", err)
+	}
+	run.notifyStageChanged(ctx, run.flow, run.conversationID, node.ID, previous, stageName, "flow")
+	if run.sheets != nil {
+		if conversation, err := run.load(ctx, run.conversationID); err == nil && conversation != nil {
+			run.sheets.AppendStage(ctx, conversation.IDDevice, stageName, conversation.row)
+		}
+	}
+
+	if columnName != "" {
+		log.Printf("✅ Stage and column '%s' updated successfully", columnName)
+	} else {
+		log.Printf("✅ Stage updated successfully")
+	}
+	return true, nil
+}
+
+// capture adds a configured column's value to the stage update. A custom field of the device owner is
+// validated by its type and merged into custom_fields; a captured field column of the conversation
+// table is set as it is. Returns the column filled, or "" when the value was not captured.
+func (p *stageProcessor) capture(ctx context.Context, run *flowRun, updates map[string]interface{}, column, value string) (string, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return "", fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	if field, ok := deviceCustomFields(ctx, run.deviceRepo, run.fieldRepo, conversation.IDDevice)[column]; ok {
+		normalized, err := field.NormalizeValue(value)
+		if err != nil {
+			log.Printf("⚠️  Not capturing %q into custom field %s: %v", value, column, err)
+			return "", nil
+		}
+
+		fields := make(map[string]interface{}, len(conversation.CustomFields)+1)
+		for name, existing := range conversation.CustomFields {
+			fields[name] = existing
+		}
+		fields[column] = normalized
+		updates["custom_fields"] = fields
+		return column, nil
+	}
+
+	if !repository.IsTableColumn(run.table, column) {
+		log.Printf("⚠️  Stage column %s is neither a %s column nor a custom field, skipping it", column, run.table)
+		return "", nil
+	}
+	updates[column] = value
+	return column, nil
+}
+
+// configuredColumn resolves the column a stage configuration fills and its value; an empty column
+// means the stage is set on its own
+func (p *stageProcessor) configuredColumn(ctx context.Context, run *flowRun, stageName string) (string, string, error) {
+	// First, get the conversation to retrieve device_id and conv_last
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation: %v", err)
+		return "", "", fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	log.Printf("🔍 Checking stage configuration for device=%s, stage=%s", conversation.IDDevice, stageName)
+
+	// Check if stage configuration exists for this device and stage
+	stageConfig, err := run.stageRepo.GetStageConfigByDeviceAndStage(ctx, conversation.IDDevice, stageName)
+	if err != nil {
+		log.Printf("❌ Failed to query stage configuration: %v", err)
+		// Continue with normal stage update on query error
+	}
+	if stageConfig == nil {
+		log.Printf("📝 No stage configuration found, updating stage normally")
+		return "", "", nil
+	}
+
+	// Stage configuration found - apply dynamic updates
+	log.Printf("⚙️  Stage configuration found: type=%s, column=%s", stageConfig.TypeInputData, stageConfig.ColumnsData)
+
+	// Normalize column name to match database schema
+	columnName := normalizeColumnName(stageConfig.ColumnsData)
+	log.Printf("📝 Normalized column name: '%s' -> '%s'", stageConfig.ColumnsData, columnName)
+
+	// Determine value based on type_inputdata
+	switch stageConfig.TypeInputData {
+	case "Set":
+		// Use hardcoded value from inputhardcode
+		log.Printf("📝 Type=Set: Using hardcoded value '%s' for column '%s'", stageConfig.InputHardCode, columnName)
+		return columnName, stageConfig.InputHardCode, nil
+	case "Input":
+		// Use value from last user reply in conv_last
+		// Format: "User: message\nBot: reply\nUser: message2..."
+		if conversation.ConvLast == nil {
+			log.Printf("⚠️  Type=Input but conv_last is empty, using empty value")
+			return columnName, "", nil
+		}
+		lines := strings.Split(*conversation.ConvLast, "\n")
+		var lastUserMessage string
+		for i := len(lines) - 1; i >= 0; i-- {
+			line := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(line, "User: ") {
+				lastUserMessage = strings.TrimPrefix(line, "User: ")
+				break
+			}
+		}
+		log.Printf("📝 Type=Input: Using user reply '%s' for column '%s'", lastUserMessage, columnName)
+		return columnName, lastUserMessage, nil
+	default:
+		log.Printf("⚠️  Unknown type_inputdata: %s, skipping column update", stageConfig.TypeInputData)
+		return "", "", nil
+	}
+}
+
+// sendMediaProcessor sends one kind of media (image/audio/video)
+type sendMediaProcessor struct {
+	nodeType  string
+	mediaType string
+}
+
+func (p *sendMediaProcessor) GetNodeType() string { return p.nodeType }
+
+func (p *sendMediaProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	// Get media URL from config
+	url, ok := node.Config["url"].(string)
+	if !ok || url == "" {
+		log.Printf("⚠️  No URL configured for media node")
+		return true, nil
+	}
+
+	log.Printf("📤 Sending %s: %s", node.Type, url)
+
+	// Get conversation to get phone number
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for sending media: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	// Send WhatsApp media
+	err = run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, "", p.mediaType, url)
+	if err != nil {
+		log.Printf("❌ Failed to send WhatsApp media: %v", err)
+		return true, fmt.Errorf("failed to send media: %w", err)
+	}
+
+	log.Printf("✅ Media sent successfully to %s", conversation.ProspectNum)
+
+	// Update conv_last with bot media send (just the URL)
+	return true, run.appendHistory(ctx, run.conversationID, node.ID, "Bot", url)
+}
+
+// mediaSwitchProcessor sends the media variant matching a conversation field
+type mediaSwitchProcessor struct{}
+
+func (p *mediaSwitchProcessor) GetNodeType() string { return "media_switch" }
+
+func (p *mediaSwitchProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for media switch: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	field := mediaSwitchField(node.Config)
+	value := conversationFieldValue(conversation.row, field)
+
+	variant, ok := selectMediaVariant(node.Config, value)
+	if !ok {
+		log.Printf("⚠️  No media variant for %s=%q and no default, skipping", field, value)
+		return true, nil
+	}
+
+	log.Printf("🖼️  Media switch %s=%q -> sending %s: %s", field, value, variant.MediaType, variant.URL)
+
+	err = run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, "", variant.MediaType, variant.URL)
+	if err != nil {
+		log.Printf("❌ Failed to send WhatsApp media: %v", err)
+		return true, fmt.Errorf("failed to send media: %w", err)
+	}
+
+	// Update conv_last with bot media send (just the URL)
+	return true, run.appendHistory(ctx, run.conversationID, node.ID, "Bot", variant.URL)
+}
+
+// translateProcessor rewrites the inbound message for every node after it
+type translateProcessor struct{}
+
+func (p *translateProcessor) GetNodeType() string { return "translate" }
+
+func (p *translateProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	run.userMessage = run.translator.TranslateInbound(ctx, run.store, run.conversationID, run.flow.IDDevice, run.userMessage, translateTargetLanguage(node))
+	return true, nil
+}
+
+// conditionsProcessor passes through; its edges are evaluated by findNextNode
+type conditionsProcessor struct{}
+
+func (p *conditionsProcessor) GetNodeType() string { return "conditions" }
+
+func (p *conditionsProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("🔀 Evaluating conditions")
+	return true, nil
+}
+
+// sendEmailProcessor emails an order confirmation or an internal alert from the device owner's
+// account. to, subject and body take {{variable}} placeholders; to may list several addresses.
+// A failed send is logged and the flow continues.
+type sendEmailProcessor struct{}
+
+func (p *sendEmailProcessor) GetNodeType() string { return "send_email" }
+
+func (p *sendEmailProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	if run.email == nil && run.sim == nil {
+		log.Printf("⚠️  Email is not configured, skipping send_email node %s", node.ID)
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	email := sendEmailMessage(node, conversation.row)
+	if len(email.To) == 0 {
+		log.Printf("⚠️  send_email node %s has no valid recipient", node.ID)
+		return true, nil
+	}
+
+	if run.sim != nil {
+		run.sim.sendEmail(ctx, email)
+		return true, nil
+	}
+
+	if err := run.email.SendForDevice(ctx, run.flow.IDDevice, run.conversationID, email); err != nil {
+		log.Printf("❌ Failed to send email from node %s: %v", node.ID, err)
+		return true, nil
+	}
+
+	log.Printf("📧 Email sent to %s", strings.Join(email.To, ", "))
+	return true, nil
+}
+
+// forkProcessor passes through; the runtime runs its branches (see runFork)
+type forkProcessor struct{}
+
+func (p *forkProcessor) GetNodeType() string { return flowForkNode }
+
+func (p *forkProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("🔱 Starting parallel branches")
+	return true, nil
+}
+
+// joinProcessor passes through; the fork before it has already waited for its branches
+type joinProcessor struct{}
+
+func (p *joinProcessor) GetNodeType() string { return flowJoinNode }
+
+func (p *joinProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	log.Printf("🔗 Branches joined")
+	return true, nil
+}
+
+// csatProcessor sends the satisfaction survey question and waits for the rating
+type csatProcessor struct{}
+
+func (p *csatProcessor) GetNodeType() string { return "csat" }
+
+func (p *csatProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for csat: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	question := csatQuestion(node)
+	log.Printf("⭐ Sending CSAT survey: %s", question)
+
+	err = run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, question, "", "")
+	if err != nil {
+		log.Printf("❌ Failed to send CSAT question: %v", err)
+		return true, fmt.Errorf("failed to send csat question: %w", err)
+	}
+
+	if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", question); err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	// Wait for the rating, resetting attempts from any earlier survey
+	updates := map[string]interface{}{
+		"csat_attempts": 0,
+	}
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, updates); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+
+	return false, nil // false = stop flow execution until the rating arrives
+}
+
+// HandleReply validates a reply to the survey and stores the rating.
+// Returns false when the flow should keep waiting for a valid rating.
+func (p *csatProcessor) HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return false, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	if score, ok := parseCSATScore(run.userMessage); ok {
+		updates := map[string]interface{}{
+			"csat_score":    score,
+			"csat_at":       time.Now(),
+			"csat_attempts": 0,
+		}
+		if err := run.store.UpdateConversation(ctx, run.conversationID, updates); err != nil {
+			return false, fmt.Errorf("failed to store csat score: %w", err)
+		}
+		log.Printf("✅ Stored CSAT score %d for conversation %s", score, run.conversationID)
+
+		if thanks := csatThanksMessage(node); thanks != "" {
+			if err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, thanks, "", ""); err != nil {
+				log.Printf("⚠️  Failed to send CSAT thanks: %v", err)
+			} else if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", thanks); err != nil {
+				log.Printf("⚠️  Failed to update conv_last: %v", err)
+			}
+		}
+		return true, nil
+	}
+
+	attempts := 1
+	if conversation.CSATAttempts != nil {
+		attempts += *conversation.CSATAttempts
+	}
+
+	// Too many invalid answers - move on without a score
+	if attempts >= csatMaxAttempts(node) {
+		log.Printf("⚠️  No valid CSAT rating after %d attempts, continuing flow", attempts)
+		updates := map[string]interface{}{
+			"csat_attempts": 0,
+		}
+		if err := run.store.UpdateConversation(ctx, run.conversationID, updates); err != nil {
+			log.Printf("⚠️  Failed to reset csat attempts: %v", err)
+		}
+		return true, nil
+	}
+
+	retry := csatRetryMessage(node)
+	log.Printf("🔁 Invalid CSAT answer '%s', asking again", run.userMessage)
+
+	if err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, retry, "", ""); err != nil {
+		log.Printf("⚠️  Failed to send CSAT retry: %v", err)
+	} else if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", retry); err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	updates := map[string]interface{}{
+		"csat_attempts": attempts,
+	}
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, updates); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+
+	return false, nil
+}
+
+// consentProcessor asks for marketing consent and waits for the answer
+type consentProcessor struct{}
+
+func (p *consentProcessor) GetNodeType() string { return "consent" }
+
+func (p *consentProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for consent: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	question := consentQuestion(node)
+	log.Printf("📝 Asking for consent: %s", question)
+
+	err = run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, question, "", "")
+	if err != nil {
+		log.Printf("❌ Failed to send consent question: %v", err)
+		return true, fmt.Errorf("failed to send consent question: %w", err)
+	}
+
+	if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", question); err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, nil); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+
+	return false, nil // false = stop flow execution until the answer arrives
+}
+
+// HandleReply records the answer in the consent registry and confirms it; the flow always moves on
+func (p *consentProcessor) HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return false, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	granted := run.consents.recordNodeConsent(ctx, run.flow, node, conversation.ProspectNum, run.userMessage)
+
+	if reply := consentReplyMessage(node, granted); reply != "" {
+		if err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, reply, "", ""); err != nil {
+			log.Printf("⚠️  Failed to send consent confirmation: %v", err)
+		} else if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", reply); err != nil {
+			log.Printf("⚠️  Failed to update conv_last: %v", err)
+		}
+	}
+	return true, nil
+}
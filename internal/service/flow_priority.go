@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"chatbot-automation/internal/models"
+)
+
+// activeFlowsByPriority returns the device flows that take new prospects, in the order inbound
+// messages try them: lowest priority first, then as listed (newest first)
+func activeFlowsByPriority(flows []models.ChatbotFlow) []models.ChatbotFlow {
+	active := make([]models.ChatbotFlow, 0, len(flows))
+	for _, flow := range flows {
+		if flow.Active() {
+			active = append(active, flow)
+		}
+	}
+	slices.SortStableFunc(active, compareFlowPriority)
+	return active
+}
+
+// compareFlowPriority orders flows by priority, lowest first
+func compareFlowPriority(a, b models.ChatbotFlow) int {
+	return a.Priority - b.Priority
+}
+
+// SetFlowActive activates or deactivates a flow. A deactivated flow takes no new prospects and is
+// skipped by trigger matching; conversations already in it continue.
+func (s *FlowService) SetFlowActive(ctx context.Context, userID, flowID string, active bool) (*models.FlowResponse, error) {
+	// getFlow verifies access
+	resp, err := s.getFlow(ctx, userID, flowID, models.TeamPermissionEditFlows)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return resp, nil
+	}
+
+	flow := resp.Flow
+	if err := s.flowRepo.UpdateFlow(ctx, flow.ID, map[string]interface{}{"is_active": active}); err != nil {
+		return nil, err
+	}
+	flow.IsActive = &active
+
+	message := "Flow activated"
+	if !active {
+		message = "Flow deactivated; conversations already in it continue"
+	}
+	log.Printf("🔀 Flow %s (%s) is_active=%v", flow.Name, flow.ID, active)
+	return &models.FlowResponse{
+		Success: true,
+		Message: message,
+		Flow:    flow,
+	}, nil
+}
+
+// ReorderFlows sets the priority of a device's flows from their order in req.FlowIDs: the first
+// is tried first. Unlisted flows follow in their current order. Returns the flows in the new order.
+func (s *FlowService) ReorderFlows(ctx context.Context, userID, deviceID string, req *models.ReorderFlowsRequest) (*models.FlowResponse, error) {
+	if len(req.FlowIDs) == 0 {
+		return &models.FlowResponse{
+			Success: false,
+			Message: "flow_ids is required",
+		}, nil
+	}
+
+	// Try to find device by device_id field first, then by UUID id
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup device: %w", err)
+	}
+	if device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, deviceID)
+		if err != nil {
+			device = nil
+		}
+	}
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionEditFlows) {
+		return &models.FlowResponse{
+			Success: false,
+			Message: "Device not found or access denied",
+		}, nil
+	}
+	if device.IDDevice != nil && *device.IDDevice != "" {
+		deviceID = *device.IDDevice
+	} else if device.DeviceID != nil && *device.DeviceID != "" {
+		deviceID = *device.DeviceID
+	}
+
+	flows, err := s.flowRepo.GetFlowsByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flows: %w", err)
+	}
+	// The current order, inactive flows included, so unlisted flows keep their relative place
+	slices.SortStableFunc(flows, compareFlowPriority)
+
+	byID := make(map[string]models.ChatbotFlow, len(flows))
+	for _, flow := range flows {
+		byID[flow.ID] = flow
+	}
+
+	ordered := make([]models.ChatbotFlow, 0, len(flows))
+	listed := make(map[string]bool, len(req.FlowIDs))
+	for _, id := range req.FlowIDs {
+		flow, ok := byID[id]
+		if !ok {
+			return &models.FlowResponse{
+				Success: false,
+				Message: fmt.Sprintf("Flow %s is not a flow of device %s", id, deviceID),
+			}, nil
+		}
+		if listed[id] {
+			return &models.FlowResponse{
+				Success: false,
+				Message: fmt.Sprintf("Flow %s is listed more than once", id),
+			}, nil
+		}
+		listed[id] = true
+		ordered = append(ordered, flow)
+	}
+	for _, flow := range flows {
+		if !listed[flow.ID] {
+			ordered = append(ordered, flow)
+		}
+	}
+
+	for i := range ordered {
+		priority := i + 1
+		if ordered[i].Priority == priority {
+			continue
+		}
+		if err := s.flowRepo.UpdateFlow(ctx, ordered[i].ID, map[string]interface{}{"priority": priority}); err != nil {
+			return nil, err
+		}
+		ordered[i].Priority = priority
+	}
+
+	return &models.FlowResponse{
+		Success: true,
+		Message: fmt.Sprintf("Reordered %d flows", len(ordered)),
+		Flows:   ordered,
+	}, nil
+}
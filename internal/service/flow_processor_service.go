@@ -5,19 +5,46 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
 )
 
 type FlowProcessorService struct {
-	webhookService   *WebhookService
-	whatsappService  *WhatsAppService
-	flowRepo         *repository.FlowRepository
-	deviceRepo       *repository.DeviceRepository
-	convRepo         *repository.ConversationRepository
-	wasapbotRepo     *repository.WasapbotRepository
-	stageRepo        *repository.StageRepository
+	webhookService  *WebhookService
+	whatsappService *WhatsAppService
+	flowRepo        *repository.FlowRepository
+	deviceRepo      *repository.DeviceRepository
+	convRepo        *repository.ConversationRepository
+	wasapbotRepo    *repository.WasapbotRepository
+	stageRepo       *repository.StageRepository
+	latencyRepo     *repository.ResponseLatencyRepository
+	checkpointRepo  *repository.ConversationCheckpointRepository
+	delayRepo       *repository.DelayedExecutionRepository // delay and waiting_times resumes (nil = wait in-process)
+	executionLogs   *repository.FlowExecutionLogRepository // per-node audit trail and loop guard stops (nil = server log only)
+	customFieldRepo *repository.CustomFieldRepository      // owners' custom fields, filled by stage configs
+	promptTemplates *repository.PromptTemplateRepository   // named system prompts of ai_prompt nodes (nil = built-in only)
+	msgTemplates    *repository.MessageTemplateRepository  // named messages of send_message nodes (nil = built-in only)
+	abSplits        *repository.ABSplitRepository          // ab_split assignments (nil = not recorded)
+	events          *EventWebhookService                   // outbound event webhooks (nil = none sent)
+	sheets          *SheetExportService                    // Google Sheets rows of stage nodes (nil = none written)
+	costs           *CostRecorder
+	aiUsage         *AIUsageRecorder
+	translator      *TranslationService
+	consents        *ConsentService
+	links           *LinkTracker
+	email           *EmailService
+	agents          *InboxAgentService // routes returning prospects to their previous agent (nil = off)
+	team            *TeamService       // members who may take over shared devices' conversations (nil = owners only)
+	messages        *MessageRecorder   // structured history next to conv_last
+	aiEndpoints     *AIEndpoints
+	deadlines       ExecutionDeadlines
+	aiState         *ConversationStateMachine
+	wasapbotState   *ConversationStateMachine
+	sender          FlowMessageSender   // node messages go out here, whatsappService unless simulating
+	store           aiConversationStore // ai_whatsapp rows the engine runs against, convRepo unless simulating
+	sim             *flowSimulation     // set on dry runs only
 }
 
 func NewFlowProcessorService(
@@ -28,6 +55,27 @@ func NewFlowProcessorService(
 	convRepo *repository.ConversationRepository,
 	wasapbotRepo *repository.WasapbotRepository,
 	stageRepo *repository.StageRepository,
+	latencyRepo *repository.ResponseLatencyRepository,
+	costRepo *repository.CostLedgerRepository,
+	checkpointRepo *repository.ConversationCheckpointRepository,
+	delayRepo *repository.DelayedExecutionRepository,
+	executionLogs *repository.FlowExecutionLogRepository,
+	customFieldRepo *repository.CustomFieldRepository,
+	promptTemplates *repository.PromptTemplateRepository,
+	translator *TranslationService,
+	consents *ConsentService,
+	links *LinkTracker,
+	email *EmailService,
+	agents *InboxAgentService,
+	messages *MessageRecorder,
+	aiEndpoints *AIEndpoints,
+	deadlines ExecutionDeadlines,
+	msgTemplates *repository.MessageTemplateRepository,
+	abSplits *repository.ABSplitRepository,
+	events *EventWebhookService,
+	sheets *SheetExportService,
+	aiUsageRepo *repository.AIUsageRepository,
+	team *TeamService,
 ) *FlowProcessorService {
 	return &FlowProcessorService{
 		webhookService:  webhookService,
@@ -37,6 +85,31 @@ func NewFlowProcessorService(
 		convRepo:        convRepo,
 		wasapbotRepo:    wasapbotRepo,
 		stageRepo:       stageRepo,
+		latencyRepo:     latencyRepo,
+		checkpointRepo:  checkpointRepo,
+		delayRepo:       delayRepo,
+		executionLogs:   executionLogs,
+		customFieldRepo: customFieldRepo,
+		promptTemplates: promptTemplates,
+		msgTemplates:    msgTemplates,
+		abSplits:        abSplits,
+		events:          events,
+		sheets:          sheets,
+		costs:           NewCostRecorder(costRepo),
+		aiUsage:         NewAIUsageRecorder(aiUsageRepo),
+		translator:      translator,
+		consents:        consents,
+		links:           links,
+		email:           email,
+		agents:          agents,
+		team:            team,
+		messages:        messages,
+		aiEndpoints:     aiEndpoints,
+		deadlines:       deadlines,
+		aiState:         NewConversationStateMachine(convRepo),
+		wasapbotState:   NewConversationStateMachine(wasapbotRepo),
+		sender:          whatsappService,
+		store:           convRepo,
 	}
 }
 
@@ -49,8 +122,13 @@ func getStringValue(ptr *string) string {
 }
 
 // determineFlowType determines if flow is for Whatsapp Bot or Chatbot AI
-// Based on niche or flow name patterns
 func (s *FlowProcessorService) determineFlowType(flow *models.ChatbotFlow) string {
+	return flowEngineType(flow)
+}
+
+// flowEngineType decides which engine runs a flow
+// Based on niche or flow name patterns
+func flowEngineType(flow *models.ChatbotFlow) string {
 	// Check if niche or name contains "ai" or "chatbot"
 	niche := strings.ToLower(flow.Niche)
 	name := strings.ToLower(flow.Name)
@@ -67,6 +145,7 @@ func (s *FlowProcessorService) determineFlowType(flow *models.ChatbotFlow) strin
 // ProcessIncomingMessage processes an incoming webhook message
 func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webhookID string, rawData map[string]interface{}) error {
 	log.Printf("📨 Processing incoming message for webhook ID: %s", webhookID)
+	receivedAt := time.Now()
 
 	// Step 1: Get device by webhook_id, if not found try id_device
 	device, err := s.deviceRepo.GetDeviceByWebhookID(ctx, webhookID)
@@ -101,10 +180,16 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 		}
 	}
 
+	// Web chat widget, Telegram and Meta inbox messages share the pipeline but not the provider payload format
+	switch channel, _ := rawData["channel"].(string); channel {
+	case models.ChannelWeb, models.ChannelTelegram, models.ChannelMessenger, models.ChannelInstagram:
+		provider = channel
+	}
+
 	log.Printf("✅ Found device: %s (Provider: %s)", idDevice, provider)
 
 	// Step 2: Extract message data based on provider
-	extractedMsg, err := s.webhookService.ExtractMessageData(ctx, rawData, idDevice, provider)
+	extractedMsg, err := s.webhookService.ExtractMessageData(ctx, rawData, idDevice, provider, device.EffectivePhoneCountry())
 	if err != nil {
 		log.Printf("⚠️  Message extraction failed: %v", err)
 		return nil // Don't return error for group messages or invalid numbers
@@ -112,6 +197,28 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 
 	log.Printf("✅ Extracted message from %s: %s", extractedMsg.PhoneNumber, extractedMsg.Message)
 
+	// A WhatsApp reply to one of our messages feeds replied_to conditions
+	if extractedMsg.QuotedMessageID != "" {
+		ctx = s.whatsappService.WithQuotedMessage(ctx, idDevice, extractedMsg.PhoneNumber, extractedMsg.QuotedMessageID)
+	}
+
+	// An image, voice note, ... feeds media conditions and ai_prompt nodes
+	if extractedMsg.Media != nil {
+		log.Printf("📎 Message from %s carries %s media", extractedMsg.PhoneNumber, extractedMsg.Media.Type)
+		ctx = withInboundMedia(ctx, extractedMsg.Media)
+	}
+
+	// A shared location pin feeds media=location conditions and is stored in the location_* variables
+	if extractedMsg.Location != nil {
+		log.Printf("📍 Message from %s shares location %s", extractedMsg.PhoneNumber, extractedMsg.Location.Label())
+		ctx = withInboundLocation(ctx, extractedMsg.Location)
+	}
+
+	// A tapped button or list row picks its send_buttons / send_list connection by id
+	if extractedMsg.OptionID != "" {
+		ctx = withSelectedOption(ctx, extractedMsg.OptionID)
+	}
+
 	// Step 3: Get flow by id_device (not device.ID which is UUID)
 	log.Printf("🔍 Looking for flows with id_device: %s", idDevice)
 	flows, err := s.flowRepo.GetFlowsByDeviceID(ctx, idDevice)
@@ -127,8 +234,22 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 
 	log.Printf("✅ Found %d flow(s) for device", len(flows))
 
-	// Use the first active flow
-	flow := flows[0]
+	// Inactive flows take no new prospects; the rest are tried in priority order
+	flows = activeFlowsByPriority(flows)
+	if len(flows) == 0 {
+		log.Printf("⚠️  No active flows for id_device: %s", idDevice)
+		return nil
+	}
+
+	// Trigger rules pick the flow; without a match the device's catch-all flow runs
+	triggeredFlow, triggered := s.selectTriggeredFlow(ctx, flows, idDevice, extractedMsg.PhoneNumber, extractedMsg.Message)
+
+	// Outside the device's business hours the away flow answers, or replies wait for opening
+	ctx, awayFlow, open := routeClosedDevice(ctx, device, flows)
+	if awayFlow != nil {
+		triggeredFlow, triggered = awayFlow, true
+	}
+	flow := *triggeredFlow
 	flowType := s.determineFlowType(&flow)
 	log.Printf("✅ Found flow: %s (Type: %s)", flow.Name, flowType)
 
@@ -145,7 +266,16 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 
 	log.Printf("✅ Flow validated: %d nodes, %d edges", len(flow.Nodes), len(flow.Edges))
 
+	// Start the response latency clock (no-op if an earlier message is still unanswered)
+	flowID := flow.ID
+	if err := s.latencyRepo.OpenCycle(ctx, idDevice, extractedMsg.PhoneNumber, &flowID, receivedAt); err != nil {
+		log.Printf("⚠️  Failed to record inbound message time: %v", err)
+	}
+
 	// Step 5: Determine which table to use based on flow data type
+	// activeFlow is the flow actually executed; it differs from the device flow when the
+	// conversation was routed elsewhere (e.g. an after-sales flow)
+	activeFlow := &flow
 	var contactExists bool
 	var contactID string
 	var currentStage string
@@ -165,6 +295,8 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			status := "Prospek"
 			executionStatus := "active"
 			flowIDStr := flow.ID
+			flowStartedAt := time.Now().UTC().Format(time.RFC3339)
+			flowEntries := 1
 			// Create conv_last with initial message (Chatbot AI format)
 			convLast := fmt.Sprintf("User: %s", extractedMsg.Message)
 
@@ -179,6 +311,14 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 				ExecutionStatus: &executionStatus,
 				CurrentNodeID:   nil, // Will be set during flow execution
 				ConvLast:        &convLast,
+				Channel:         models.ProspectChannel(extractedMsg.PhoneNumber),
+				IsTest:          device.Sandbox, // Flow-building traffic, purged by the cleanup endpoint
+				FlowStartedAt:   &flowStartedAt,
+				FlowEntries:     &flowEntries,
+				LastMedia:       extractedMsg.Media,
+			}
+			if extractedMsg.Location != nil {
+				newContact.SessionData = locationSessionData(nil, extractedMsg.Location)
 			}
 
 			err = s.convRepo.CreateWasapBotContact(ctx, newContact)
@@ -187,9 +327,16 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			}
 
 			contactID = fmt.Sprintf("%d", *newContact.IDProspect)
+			s.messages.Record(ctx, inboundMessage(models.AssignmentSourceWasapbot, contactID, idDevice, extractedMsg.Message, extractedMsg.Media))
 			currentStage = "" // Empty initially since Stage is NULL
 			contactExists = false
 			log.Printf("✅ Created new wasapbot contact: %s", contactID)
+			s.events.EmitForDevice(ctx, idDevice, models.EventConversationCreated, conversationEventData("wasapbot", contactID, newContact))
+
+			// A prospect who went through handoff before goes to the same agent
+			if s.routeToPreviousAgent(ctx, models.AssignmentSourceWasapbot, contactID) {
+				return nil
+			}
 		} else {
 			// Contact exists
 			contactID = fmt.Sprintf("%d", *contact.IDProspect)
@@ -199,16 +346,102 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			contactExists = true
 			log.Printf("✅ Found existing wasapbot contact: %s (Stage: %s)", contactID, currentStage)
 
+			if extractedMsg.Media != nil {
+				if err := s.convRepo.UpdateWasapBotContact(ctx, contactID, map[string]interface{}{"last_media": extractedMsg.Media}); err != nil {
+					log.Printf("⚠️  Failed to record media: %v", err)
+				}
+			}
+			if extractedMsg.Location != nil {
+				if err := s.convRepo.UpdateWasapBotContact(ctx, contactID, map[string]interface{}{"session_data": locationSessionData(contact.SessionData, extractedMsg.Location)}); err != nil {
+					log.Printf("⚠️  Failed to record location: %v", err)
+				}
+			}
+
+			contactState := contact.ExecutionState().State()
+
+			// A human agent owns the conversation - the bot stays quiet
+			if contactState == models.ConversationStateHandoff {
+				log.Printf("🙋 Contact %s is in handoff, skipping bot reply", contactID)
+				return nil
+			}
+
+			// An agent took over from the inbox - keep the message for them, the bot stays quiet
+			if contactState == models.ConversationStatePausedByAgent {
+				log.Printf("✋ Contact %s is paused by an agent, recording message only", contactID)
+				updates := map[string]interface{}{
+					"conv_last": s.messages.Append(ctx, getStringValue(contact.ConvLast), inboundMessage(models.AssignmentSourceWasapbot, contactID, idDevice, extractedMsg.Message, extractedMsg.Media), device.EffectiveMaxHistoryEntries()),
+				}
+				if err := s.convRepo.UpdateWasapBotContact(ctx, contactID, updates); err != nil {
+					log.Printf("⚠️  Failed to update conv_last: %v", err)
+				}
+				return nil
+			}
+
+			// The loop guard stopped the flow - wait for an agent instead of looping again
+			if contactState == models.ConversationStateFailedMaxSteps {
+				log.Printf("🛑 Contact %s was stopped by the loop guard, skipping bot reply", contactID)
+				return nil
+			}
+
+			// Continue the flow the contact is bound to
+			activeFlow = s.resolveConversationFlow(ctx, &flow, contact.FlowID)
+
+			// A prospect coming back after the flow ended goes to the agent who handled them before
+			if contactState == models.ConversationStateCompleted || contactState == models.ConversationStateAbandoned {
+				if s.routeToPreviousAgent(ctx, models.AssignmentSourceWasapbot, contactID) {
+					return nil
+				}
+			}
+
+			// A trigger keyword starts its flow over whichever flow the contact was in, and at
+			// opening contacts left in the away flow go back to the normal one
+			if (triggered || leavesAwayFlow(device, open, contact.FlowID)) && s.switchToTriggeredFlow(ctx, s.wasapbotState, models.AssignmentSourceWasapbot, contactID, contactState, contact.FlowID, &flow, wasapbotFlowEntry(contact.FlowStartedAt, contact.FlowEntries)) {
+				activeFlow = &flow
+				contactState = models.ConversationStateActive
+				currentStage = ""
+			}
+
+			switch contactState {
+			case models.ConversationStateCompleted:
+				// Prospect messaged again after the flow finished
+				reengageFlow, err := s.reengageCompletedConversation(ctx, activeFlow, s.wasapbotState, contactID, wasapbotFlowEntry(contact.FlowStartedAt, contact.FlowEntries))
+				if err != nil {
+					return err
+				}
+				if reengageFlow == nil {
+					return nil
+				}
+				activeFlow = reengageFlow
+				currentStage = ""
+			case models.ConversationStateAbandoned:
+				// Prospect came back - pick the flow up again
+				if err := s.wasapbotState.UpdateState(ctx, contactID, models.ConversationStateActive, "", nil); err != nil {
+					log.Printf("⚠️  Failed to reactivate contact: %v", err)
+				}
+			}
+
+			// A delay node is running its course - keep the message, the flow scheduler resumes the flow
+			if contactState == models.ConversationStateScheduled {
+				log.Printf("🗓️  Contact %s is scheduled to resume, recording message only", contactID)
+				updates := map[string]interface{}{
+					"conv_last": s.messages.Append(ctx, getStringValue(contact.ConvLast), inboundMessage(models.AssignmentSourceWasapbot, contactID, idDevice, extractedMsg.Message, extractedMsg.Media), device.EffectiveMaxHistoryEntries()),
+				}
+				if err := s.convRepo.UpdateWasapBotContact(ctx, contactID, updates); err != nil {
+					log.Printf("⚠️  Failed to update conv_last: %v", err)
+				}
+				return nil
+			}
+
 			// Check if waiting for reply
-			if contact.WaitingForReply != nil && *contact.WaitingForReply {
+			if contactState == models.ConversationStateWaiting {
 				log.Printf("▶️  Resuming flow from waiting state")
 
-				// Append user message to existing conv_last
+				// Append user message to existing conv_last (the resumed run records it in the history)
 				existingConvLast := ""
 				if contact.ConvLast != nil {
 					existingConvLast = *contact.ConvLast
 				}
-				newConvLast := existingConvLast + fmt.Sprintf("\nUser: %s", extractedMsg.Message)
+				newConvLast := appendConvHistory(existingConvLast, "User", extractedMsg.Message, device.EffectiveMaxHistoryEntries())
 
 				// Get current node ID for resume
 				currentNodeID := ""
@@ -216,16 +449,17 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 					currentNodeID = *contact.CurrentNodeID
 				}
 
-				// Update conv_last and reset waiting state
+				// Update conv_last and leave the waiting state
 				updates := map[string]interface{}{
-					"conv_last":         newConvLast,
-					"waiting_for_reply": false,
+					"conv_last": newConvLast,
+				}
+				if err := s.wasapbotState.UpdateState(ctx, contactID, models.ConversationStateActive, "", updates); err != nil {
+					log.Printf("⚠️  Failed to reset waiting state: %v", err)
 				}
-				_ = s.convRepo.UpdateWasapBotContact(ctx, contactID, updates)
 
 				// Resume flow from current node
-				wasapbotEngine := NewWasapbotFlowEngine(s.deviceRepo, s.wasapbotRepo, s.stageRepo, s.whatsappService)
-				err = wasapbotEngine.ResumeWasapbotFlow(ctx, &flow, contactID, extractedMsg.Message, currentNodeID)
+				wasapbotEngine := NewWasapbotFlowEngine(s.runtimeServices(), s.wasapbotRepo, s.whatsappService)
+				err = wasapbotEngine.ResumeWasapbotFlow(ctx, activeFlow, contactID, extractedMsg.Message, currentNodeID)
 				if err != nil {
 					log.Printf("❌ Wasapbot flow resume error: %v", err)
 					return fmt.Errorf("failed to resume wasapbot flow: %w", err)
@@ -235,9 +469,10 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 				return nil
 			}
 
-			// Not waiting for reply - update conv_last with new message
+			// Not waiting for reply - conv_last restarts with the new message, the history keeps growing
+			message := inboundMessage(models.AssignmentSourceWasapbot, contactID, idDevice, extractedMsg.Message, extractedMsg.Media)
 			updates := map[string]interface{}{
-				"conv_last": fmt.Sprintf("User: %s", extractedMsg.Message),
+				"conv_last": s.messages.Append(ctx, "", message, 0),
 			}
 			_ = s.convRepo.UpdateWasapBotContact(ctx, contactID, updates)
 		}
@@ -248,8 +483,8 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 		log.Printf("📊 Contact exists: %v, New contact: %v", contactExists, !contactExists)
 
 		// Create wasapbot flow engine and execute
-		wasapbotEngine := NewWasapbotFlowEngine(s.deviceRepo, s.wasapbotRepo, s.stageRepo, s.whatsappService)
-		err = wasapbotEngine.ExecuteWasapbotFlow(ctx, &flow, contactID, extractedMsg.Message, currentStage)
+		wasapbotEngine := NewWasapbotFlowEngine(s.runtimeServices(), s.wasapbotRepo, s.whatsappService)
+		err = wasapbotEngine.ExecuteWasapbotFlow(ctx, activeFlow, contactID, extractedMsg.Message, currentStage)
 		if err != nil {
 			log.Printf("❌ Wasapbot flow execution error: %v", err)
 			return fmt.Errorf("failed to execute wasapbot flow: %w", err)
@@ -270,11 +505,21 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			// Create new conversation
 			log.Printf("➕ Creating new ai_whatsapp conversation")
 			executionStatus := "active"
+			now := time.Now()
+			flowEntries := 1
 			newConv := &models.AIWhatsapp{
 				IDDevice:        idDevice,
 				ProspectNum:     extractedMsg.PhoneNumber,
 				ExecutionStatus: &executionStatus,
 				FlowID:          &flow.ID, // Save chatbot_flows id
+				Channel:         models.ProspectChannel(extractedMsg.PhoneNumber),
+				IsTest:          device.Sandbox,
+				FlowStartedAt:   &now,
+				FlowEntries:     &flowEntries,
+				LastMedia:       extractedMsg.Media,
+			}
+			if extractedMsg.Location != nil {
+				newConv.SessionData = locationSessionData(nil, extractedMsg.Location)
 			}
 
 			// Set prospect name if available
@@ -300,9 +545,11 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			}
 
 			contactID = fmt.Sprintf("%d", *newConv.IDProspect) // Convert int to string
-			currentStage = ""                                       // Stage is null initially
+			currentStage = ""                                  // Stage is null initially
 			contactExists = false
 			log.Printf("✅ Created new ai_whatsapp conversation: %s", contactID)
+			s.events.EmitForDevice(ctx, idDevice, models.EventConversationCreated, conversationEventData("ai_whatsapp", contactID, newConv))
+			s.messages.Record(ctx, inboundMessage(models.AssignmentSourceAI, contactID, idDevice, extractedMsg.Message, extractedMsg.Media))
 		} else {
 			// Conversation exists
 			contactID = fmt.Sprintf("%d", *conversation.IDProspect) // Convert int to string
@@ -316,6 +563,17 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 
 			// Update last interaction
 			_ = s.convRepo.UpdateLastInteraction(ctx, contactID)
+
+			if extractedMsg.Media != nil {
+				if err := s.convRepo.UpdateConversation(ctx, contactID, map[string]interface{}{"last_media": extractedMsg.Media}); err != nil {
+					log.Printf("⚠️  Failed to record media: %v", err)
+				}
+			}
+			if extractedMsg.Location != nil {
+				if err := s.convRepo.UpdateConversation(ctx, contactID, map[string]interface{}{"session_data": locationSessionData(conversation.SessionData, extractedMsg.Location)}); err != nil {
+					log.Printf("⚠️  Failed to record location: %v", err)
+				}
+			}
 		}
 	} else {
 		return fmt.Errorf("unsupported flow type: %s", flowType)
@@ -328,14 +586,83 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 		return fmt.Errorf("failed to get conversation: %w", err)
 	}
 
-	// Check if flow already completed
-	if conversation.ExecutionStatus != nil && *conversation.ExecutionStatus == "completed" {
-		log.Printf("⏹️  Flow already completed for contact %s, ignoring message", contactID)
+	state := conversation.ExecutionState().State()
+
+	// Continue the flow the conversation is bound to
+	activeFlow = s.resolveConversationFlow(ctx, &flow, conversation.FlowID)
+
+	// A new or returning prospect who went through handoff before goes to the same agent
+	if !contactExists || state == models.ConversationStateCompleted || state == models.ConversationStateAbandoned {
+		if s.routeToPreviousAgent(ctx, models.AssignmentSourceAI, contactID) {
+			return nil
+		}
+	}
+
+	// A trigger keyword starts its flow over whichever flow the conversation was in, and at
+	// opening conversations left in the away flow go back to the normal one
+	if (triggered || leavesAwayFlow(device, open, conversation.FlowID)) && s.switchToTriggeredFlow(ctx, s.aiState, models.AssignmentSourceAI, contactID, state, conversation.FlowID, &flow, newFlowEntry(conversation.FlowStartedAt, conversation.FlowEntries)) {
+		activeFlow = &flow
+		state = models.ConversationStateActive
+		currentStage = ""
+	}
+
+	switch state {
+	case models.ConversationStateCompleted:
+		// Prospect messaged again after the flow finished
+		reengageFlow, err := s.reengageCompletedConversation(ctx, activeFlow, s.aiState, contactID, newFlowEntry(conversation.FlowStartedAt, conversation.FlowEntries))
+		if err != nil {
+			return err
+		}
+		if reengageFlow == nil {
+			return nil
+		}
+		activeFlow = reengageFlow
+		currentStage = ""
+	case models.ConversationStateAbandoned:
+		// Prospect came back - pick the flow up again
+		if err := s.aiState.UpdateState(ctx, contactID, models.ConversationStateActive, "", nil); err != nil {
+			log.Printf("⚠️  Failed to reactivate conversation: %v", err)
+		}
+	}
+
+	// A human agent owns the conversation - the bot stays quiet
+	if state == models.ConversationStateHandoff {
+		log.Printf("🙋 Conversation %s is in handoff, skipping bot reply", contactID)
+		return nil
+	}
+
+	// An agent took over from the inbox - keep the message for them, the bot stays quiet
+	if state == models.ConversationStatePausedByAgent {
+		log.Printf("✋ Conversation %s is paused by an agent, recording message only", contactID)
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, getStringValue(conversation.ConvLast), inboundMessage(models.AssignmentSourceAI, contactID, idDevice, extractedMsg.Message, extractedMsg.Media), 0),
+		}
+		if err := s.convRepo.UpdateConversation(ctx, contactID, updates); err != nil {
+			log.Printf("⚠️  Failed to update conv_last: %v", err)
+		}
+		return nil
+	}
+
+	// The loop guard stopped the flow - wait for an agent instead of looping again
+	if state == models.ConversationStateFailedMaxSteps {
+		log.Printf("🛑 Conversation %s was stopped by the loop guard, skipping bot reply", contactID)
+		return nil
+	}
+
+	// A delay node is running its course - keep the message, the flow scheduler resumes the flow
+	if state == models.ConversationStateScheduled {
+		log.Printf("🗓️  Conversation %s is scheduled to resume, recording message only", contactID)
+		updates := map[string]interface{}{
+			"conv_last": s.messages.Append(ctx, getStringValue(conversation.ConvLast), inboundMessage(models.AssignmentSourceAI, contactID, idDevice, extractedMsg.Message, extractedMsg.Media), 0),
+		}
+		if err := s.convRepo.UpdateConversation(ctx, contactID, updates); err != nil {
+			log.Printf("⚠️  Failed to update conv_last: %v", err)
+		}
 		return nil
 	}
 
 	// Check if waiting for reply
-	if conversation.WaitingForReply != nil && *conversation.WaitingForReply {
+	if state == models.ConversationStateWaiting {
 		log.Printf("▶️  Resuming flow from waiting state for contact %s", contactID)
 
 		// Get current node ID
@@ -344,22 +671,24 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 			currentNodeID = *conversation.CurrentNodeID
 		}
 
-		// Reset waiting state
-		updates := map[string]interface{}{
-			"waiting_for_reply": false,
+		// Leave the waiting state
+		if err := s.aiState.UpdateState(ctx, contactID, models.ConversationStateActive, "", nil); err != nil {
+			log.Printf("⚠️  Failed to reset waiting state: %v", err)
 		}
-		_ = s.convRepo.UpdateConversation(ctx, contactID, updates)
 
 		// Resume flow from current node
-		err = s.ResumeFlow(ctx, &flow, contactID, extractedMsg.Message, currentNodeID)
+		err = s.ResumeFlow(ctx, activeFlow, contactID, extractedMsg.Message, currentNodeID)
 	} else {
 		// Start flow from beginning
 		log.Printf("🔄 Executing flow for contact %s at stage: %s", contactID, currentStage)
 		log.Printf("📊 Contact exists: %v, New contact: %v", contactExists, !contactExists)
 
-		err = s.ExecuteFlow(ctx, &flow, contactID, extractedMsg.Message, currentStage)
+		err = s.ExecuteFlow(ctx, activeFlow, contactID, extractedMsg.Message, currentStage)
 	}
 
+	// Recorded after failed runs too, so a partial run can be rewound
+	s.recordCheckpoint(ctx, contactID)
+
 	if err != nil {
 		log.Printf("❌ Flow execution error: %v", err)
 		return fmt.Errorf("failed to execute flow: %w", err)
@@ -368,3 +697,106 @@ func (s *FlowProcessorService) ProcessIncomingMessage(ctx context.Context, webho
 	log.Printf("✅ Flow execution completed successfully for contact: %s", contactID)
 	return nil
 }
+
+// inboundMessage is the history entry of a prospect's message, with the media it carried
+func inboundMessage(source, conversationID, idDevice, text string, media *models.InboundMedia) *models.ConversationMessage {
+	message := newConversationMessage(source, conversationID, idDevice, "User", text)
+	message.Media = media
+	return message
+}
+
+// routeToPreviousAgent hands a returning prospect to the agent of their latest handoff.
+// Returns true when the agent took the conversation and the bot should stay quiet.
+func (s *FlowProcessorService) routeToPreviousAgent(ctx context.Context, source, contactID string) bool {
+	if s.agents == nil || s.sim != nil {
+		return false
+	}
+
+	routed, err := s.agents.RouteReturningProspect(ctx, source, contactID)
+	if err != nil {
+		log.Printf("⚠️  Failed to route contact %s to their previous agent: %v", contactID, err)
+		return false
+	}
+	return routed
+}
+
+// resolveConversationFlow returns the flow a conversation is bound to,
+// falling back to the device flow when it is unset or cannot be loaded
+func (s *FlowProcessorService) resolveConversationFlow(ctx context.Context, deviceFlow *models.ChatbotFlow, flowID *string) *models.ChatbotFlow {
+	if flowID == nil || *flowID == "" || *flowID == deviceFlow.ID {
+		return deviceFlow
+	}
+
+	boundFlow, err := s.flowRepo.GetFlowByID(ctx, *flowID)
+	if err != nil || boundFlow == nil {
+		log.Printf("⚠️  Bound flow %s not found, using device flow %s", *flowID, deviceFlow.ID)
+		return deviceFlow
+	}
+
+	return boundFlow
+}
+
+// reengageCompletedConversation applies the flow's completion policy when a prospect
+// messages again after the flow completed. Returns the flow to run from its first node,
+// or nil when the bot should stay silent. entry is when the prospect last entered a flow:
+// the restart policy waits out the flow's re-entry cooldown from there.
+func (s *FlowProcessorService) reengageCompletedConversation(
+	ctx context.Context,
+	flow *models.ChatbotFlow,
+	stateMachine *ConversationStateMachine,
+	contactID string,
+	entry flowEntry,
+) (*models.ChatbotFlow, error) {
+	policy := flow.EffectiveCompletionPolicy()
+	log.Printf("🔁 Contact %s messaged after completion - policy: %s", contactID, policy)
+
+	switch policy {
+	case models.CompletionPolicyRestart:
+		if inReentryCooldown(flow.ReentryCooldownUntil(entry.startedAt), contactID, flow.ID) {
+			return nil, nil
+		}
+
+		updates := entry.nextEntryUpdates(time.Now())
+		updates["stage"] = nil
+		if err := stateMachine.UpdateState(ctx, contactID, models.ConversationStateActive, "", updates); err != nil {
+			return nil, fmt.Errorf("failed to restart flow: %w", err)
+		}
+		return flow, nil
+
+	case models.CompletionPolicyAfterSales:
+		if flow.AfterSalesFlowID == nil || *flow.AfterSalesFlowID == "" {
+			log.Printf("⚠️  after_sales policy without after_sales_flow_id on flow %s, ignoring message", flow.ID)
+			return nil, nil
+		}
+
+		afterSalesFlow, err := s.flowRepo.GetFlowByID(ctx, *flow.AfterSalesFlowID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load after-sales flow: %w", err)
+		}
+		// Flows saved before the device check could point at another device's flow
+		if afterSalesFlow.IDDevice != flow.IDDevice {
+			log.Printf("⚠️  After-sales flow %s of flow %s is on another device, ignoring message", afterSalesFlow.ID, flow.ID)
+			return nil, nil
+		}
+
+		updates := entry.nextEntryUpdates(time.Now())
+		updates["flow_id"] = afterSalesFlow.ID
+		updates["stage"] = nil
+		if err := stateMachine.UpdateState(ctx, contactID, models.ConversationStateActive, "", updates); err != nil {
+			return nil, fmt.Errorf("failed to route to after-sales flow: %w", err)
+		}
+		log.Printf("➡️  Routed contact %s to after-sales flow %s", contactID, afterSalesFlow.Name)
+		return afterSalesFlow, nil
+
+	case models.CompletionPolicyHandoff:
+		if err := stateMachine.UpdateState(ctx, contactID, models.ConversationStateHandoff, "", nil); err != nil {
+			return nil, fmt.Errorf("failed to escalate to handoff: %w", err)
+		}
+		log.Printf("🙋 Contact %s escalated to handoff", contactID)
+		return nil, nil
+
+	default:
+		log.Printf("⏹️  Flow already completed for contact %s, ignoring message", contactID)
+		return nil, nil
+	}
+}
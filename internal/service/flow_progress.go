@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Flow progress is written to flow_progress (0-100) and flow_milestone on the conversation
+// so dashboards can show e.g. "60% through qualification" per prospect.
+//
+// A node can set it explicitly in its config:
+//
+//	"milestone": "Qualification"   label shown on dashboards
+//	"progress": 60                 percentage reached at this node
+//
+// Without an explicit percentage, progress is the node's depth from the flow's entry nodes
+// divided by the deepest node in the flow. Completion always sets 100 (see ConversationStateMachine).
+
+// maxOpenFlowProgress keeps an unfinished flow below 100 so only completion reports 100
+const maxOpenFlowProgress = 99
+
+// nodeMilestone returns the milestone label configured on a node
+func nodeMilestone(node *FlowNode) string {
+	milestone, _ := node.Config["milestone"].(string)
+	return strings.TrimSpace(milestone)
+}
+
+// flowProgress returns how far through the flow a conversation at node is, as 0-99
+func flowProgress(flowData *FlowData, node *FlowNode) int {
+	if explicit, ok := node.Config["progress"].(float64); ok {
+		return clampFlowProgress(int(explicit))
+	}
+
+	depths := flowNodeDepths(flowData)
+	deepest := 0
+	for _, depth := range depths {
+		if depth > deepest {
+			deepest = depth
+		}
+	}
+	if deepest == 0 {
+		return 0
+	}
+
+	return clampFlowProgress(depths[node.ID] * 100 / deepest)
+}
+
+func clampFlowProgress(progress int) int {
+	if progress < 0 {
+		return 0
+	}
+	if progress > maxOpenFlowProgress {
+		return maxOpenFlowProgress
+	}
+	return progress
+}
+
+// flowNodeDepths returns the shortest distance of every reachable node from the flow's entry nodes
+// (start nodes, or nodes without incoming connections). Shortest distance keeps loops from inflating depth.
+func flowNodeDepths(flowData *FlowData) map[string]int {
+	next := make(map[string][]string, len(flowData.Nodes))
+	incoming := make(map[string]bool, len(flowData.Nodes))
+	for _, edge := range flowData.Connections {
+		next[edge.From] = append(next[edge.From], edge.To)
+		incoming[edge.To] = true
+	}
+
+	depths := make(map[string]int, len(flowData.Nodes))
+	var queue []string
+	for _, node := range flowData.Nodes {
+		if strings.Contains(strings.ToLower(node.Type), "start") || !incoming[node.ID] {
+			depths[node.ID] = 0
+			queue = append(queue, node.ID)
+		}
+	}
+	if len(queue) == 0 && len(flowData.Nodes) > 0 {
+		depths[flowData.Nodes[0].ID] = 0
+		queue = append(queue, flowData.Nodes[0].ID)
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, to := range next[id] {
+			if _, seen := depths[to]; seen {
+				continue
+			}
+			depths[to] = depths[id] + 1
+			queue = append(queue, to)
+		}
+	}
+
+	return depths
+}
+
+// recordFlowProgress stores the progress reached at node. It is a side channel for dashboards,
+// so failures are only logged.
+func recordFlowProgress(ctx context.Context, store ConversationStateStore, flowData *FlowData, node *FlowNode, conversationID string) {
+	progress := flowProgress(flowData, node)
+	updates := map[string]interface{}{
+		"flow_progress": progress,
+	}
+	if milestone := nodeMilestone(node); milestone != "" {
+		updates["flow_milestone"] = milestone
+	}
+
+	if err := store.UpdateConversation(ctx, conversationID, updates); err != nil {
+		log.Printf("⚠️  Failed to record flow progress for conversation %s: %v", conversationID, err)
+		return
+	}
+	log.Printf("📈 Conversation %s is %d%% through the flow (node %s)", conversationID, progress, node.ID)
+}
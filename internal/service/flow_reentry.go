@@ -0,0 +1,72 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// ConditionReturningProspect is the re-entry condition for conditions node edges. It matches on
+// whether the conversation has entered a flow before (flow_entries above 1):
+//
+//	returning_prospect  "true"    the prospect came back (restarted or routed to another flow)
+//	returning_prospect  "false"   first time through
+const ConditionReturningProspect = "returning_prospect"
+
+// flowEntry is when a conversation last entered a flow from its start, and how many flows it has entered
+type flowEntry struct {
+	startedAt *time.Time
+	count     int
+}
+
+// newFlowEntry builds the entry of a conversation row
+func newFlowEntry(startedAt *time.Time, count *int) flowEntry {
+	entry := flowEntry{startedAt: startedAt}
+	if count != nil {
+		entry.count = *count
+	}
+	return entry
+}
+
+// wasapbotFlowEntry builds the entry of a wasapbot contact, whose timestamps are kept as text
+func wasapbotFlowEntry(startedAt *string, count *int) flowEntry {
+	var started *time.Time
+	if startedAt != nil && *startedAt != "" {
+		if t, err := time.Parse(time.RFC3339Nano, *startedAt); err == nil {
+			started = &t
+		}
+	}
+	return newFlowEntry(started, count)
+}
+
+// nextEntryUpdates returns the columns recording that the conversation enters a flow now
+func (e flowEntry) nextEntryUpdates(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"flow_started_at": now,
+		"flow_entries":    e.count + 1,
+	}
+}
+
+// returning reports whether the conversation entered a flow before the current one
+func (e flowEntry) returning() bool {
+	return e.count > 1
+}
+
+// evaluateReturningProspect checks a returning_prospect condition. "false" and "no" match first-time
+// prospects; any other value matches returning ones.
+func evaluateReturningProspect(value string, returning bool) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "false", "no":
+		return !returning
+	}
+	return returning
+}
+
+// inReentryCooldown reports whether a prospect may not be restarted in the flow yet
+func inReentryCooldown(until time.Time, contactID, flowID string) bool {
+	if until.IsZero() || !time.Now().Before(until) {
+		return false
+	}
+	log.Printf("⏳ Contact %s is in the re-entry cooldown of flow %s until %s", contactID, flowID, until.Format(time.RFC3339))
+	return true
+}
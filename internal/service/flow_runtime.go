@@ -0,0 +1,671 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// ErrFlowMaxSteps is returned when the loop guard stops a flow run
+var ErrFlowMaxSteps = errors.New("flow run exceeded its step limit")
+
+// flowConversation is the part of a conversation row the node processors read, whichever table
+// (ai_whatsapp or wasapbot) the engine runs against
+type flowConversation struct {
+	IDDevice     string
+	ProspectNum  string
+	Stage        *string
+	ConvLast     *string
+	Language     *string
+	CSATAttempts *int
+	CustomFields map[string]interface{}
+	SessionData  map[string]interface{} // flow variables, see set_variable
+	Entry        flowEntry              // flow entries, for returning_prospect conditions
+	row          interface{}            // *models.AIWhatsapp or *models.Wasapbot, for field lookups and webhooks
+}
+
+// aiFlowConversation is the processor view of an ai_whatsapp conversation
+func aiFlowConversation(conversation *models.AIWhatsapp) *flowConversation {
+	return &flowConversation{
+		IDDevice:     conversation.IDDevice,
+		ProspectNum:  conversation.ProspectNum,
+		Stage:        conversation.Stage,
+		ConvLast:     conversation.ConvLast,
+		Language:     conversation.Language,
+		CSATAttempts: conversation.CSATAttempts,
+		CustomFields: conversation.CustomFields,
+		SessionData:  conversation.SessionData,
+		Entry:        newFlowEntry(conversation.FlowStartedAt, conversation.FlowEntries),
+		row:          conversation,
+	}
+}
+
+// wasapbotFlowConversation is the processor view of a wasapbot contact
+func wasapbotFlowConversation(contact *models.Wasapbot) *flowConversation {
+	return &flowConversation{
+		IDDevice:     contact.IDDevice,
+		ProspectNum:  contact.ProspectNum,
+		Stage:        contact.Stage,
+		ConvLast:     contact.ConvLast,
+		Language:     contact.Language,
+		CSATAttempts: contact.CSATAttempts,
+		CustomFields: contact.CustomFields,
+		SessionData:  contact.SessionData,
+		Entry:        newFlowEntry(contact.FlowStartedAt, contact.FlowEntries),
+		row:          contact,
+	}
+}
+
+// aiWhatsapp returns the conversation as the ai_whatsapp row the AI pipeline works on
+func (c *flowConversation) aiWhatsapp() *models.AIWhatsapp {
+	if conversation, ok := c.row.(*models.AIWhatsapp); ok {
+		return conversation
+	}
+	return &models.AIWhatsapp{
+		IDDevice:    c.IDDevice,
+		ProspectNum: c.ProspectNum,
+		Stage:       c.Stage,
+		ConvLast:    c.ConvLast,
+		Language:    c.Language,
+	}
+}
+
+// flowServices are the repositories and services the flow node processors run with. Both engines
+// pass the same set, so a dependency a node type needs is added here once.
+type flowServices struct {
+	deviceRepo *repository.DeviceRepository
+	stageRepo  *repository.StageRepository       // stage column configs; nil = stage nodes only set the stage
+	fieldRepo  *repository.CustomFieldRepository // owners' custom fields, which stage configs fill in custom_fields
+	translator *TranslationService
+	consents   *ConsentService
+	links      *LinkTracker
+	email      *EmailService                         // send_email nodes; nil skips them
+	messages   *MessageRecorder                      // structured history; nil records conv_last only
+	ai         *FlowProcessorService                 // AI pipeline for ai_prompt nodes (nil skips them)
+	templates  *repository.MessageTemplateRepository // owners' message templates of send_message nodes; nil = built-in only
+	abSplits   *repository.ABSplitRepository         // sticky ab_split assignments; nil = hash only, nothing recorded
+	events     *EventWebhookService                  // outbound event webhooks; nil sends none
+	sheets     *SheetExportService                   // Google Sheets rows of stage nodes; nil writes none
+	costs      *CostRecorder                         // paid api node calls; nil records none
+	deadlines  ExecutionDeadlines
+	delays     *repository.DelayedExecutionRepository // nil = delay nodes wait in-process
+	logs       *repository.FlowExecutionLogRepository // per-node audit trail and loop guard stops; nil = server log only
+}
+
+// flowRuntime is what both flow engines run a flow against: one conversation table (through its
+// store, loader and state machine), the message sender and the shared services. What a node does
+// is up to its processor in flowNodeProcessors; the runtime walks the graph between them.
+type flowRuntime struct {
+	flowServices
+	table  string // ai_whatsapp or wasapbot, recorded on scheduled resumes
+	store  ConversationStateStore
+	state  *ConversationStateMachine
+	sender FlowMessageSender
+	sim    *flowSimulation // set on dry runs only
+
+	load         func(ctx context.Context, conversationID string) (*flowConversation, error)
+	historyLimit func(ctx context.Context, idDevice string) int // conv_last cap; nil keeps the whole history
+
+	historyMu sync.Mutex // makes appendHistory's read-modify-write of conv_last atomic across fork branches
+}
+
+// flowRun is one execution of a flow for a conversation
+type flowRun struct {
+	*flowRuntime
+	flow           *models.ChatbotFlow
+	flowData       *FlowData
+	conversationID string
+	userMessage    string // the prospect's message; translate nodes rewrite it for the nodes after them
+	branch         string // the outcome of the last legacy condition, user_reply or api node, for findNextNode
+}
+
+// newRun parses the flow's nodes for a run; flows without nodes_data run their legacy nodes
+func (r *flowRuntime) newRun(flow *models.ChatbotFlow, conversationID, userMessage string) (*flowRun, error) {
+	// Check if NodesData is empty
+	if !hasFlowNodes(flow) {
+		log.Printf("⚠️  Flow NodesData is empty - flow not configured yet")
+		return nil, fmt.Errorf("flow has no nodes configured")
+	}
+
+	// Parse flow data
+	var flowData *FlowData
+	if flow.NodesData == "" {
+		flowData = legacyFlowData(flow)
+	} else if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		log.Printf("❌ Failed to parse flow data: %v", err)
+		log.Printf("📝 NodesData content: %s", flow.NodesData)
+		return nil, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	return &flowRun{
+		flowRuntime:    r,
+		flow:           flow,
+		flowData:       flowData,
+		conversationID: conversationID,
+		userMessage:    userMessage,
+	}, nil
+}
+
+// execute processes the flow starting from the node matching the conversation's stage
+func (r *flowRuntime) execute(ctx context.Context, flow *models.ChatbotFlow, conversationID, userMessage, currentStage string) error {
+	log.Printf("🚀 Starting flow execution for conversation: %s", conversationID)
+	if r.sim == nil {
+		defer trackActiveRun()()
+	}
+
+	run, err := r.newRun(flow, conversationID, userMessage)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("📊 Flow has %d nodes and %d connections", len(run.flowData.Nodes), len(run.flowData.Connections))
+
+	// Find starting node
+	startNode := findStartingNode(run.flowData, currentStage)
+	if startNode == nil {
+		log.Printf("⚠️  No starting node found for stage: %s", currentStage)
+		return fmt.Errorf("no starting node found")
+	}
+
+	log.Printf("🎯 Starting from node: %s (Type: %s)", startNode.ID, startNode.Type)
+
+	// Execute flow from starting node
+	return run.executeFrom(ctx, startNode)
+}
+
+// resume continues the flow after the node the conversation waited at (used after waiting_reply)
+func (r *flowRuntime) resume(ctx context.Context, flow *models.ChatbotFlow, conversationID, userMessage, currentNodeID string) error {
+	log.Printf("▶️  Resuming flow execution from node: %s", currentNodeID)
+	if r.sim == nil {
+		defer trackActiveRun()()
+	}
+
+	run, err := r.newRun(flow, conversationID, userMessage)
+	if err != nil {
+		return err
+	}
+
+	// Find the current node
+	currentNode := findFlowNode(run.flowData, currentNodeID)
+	if currentNode == nil {
+		log.Printf("❌ Current node %s not found in flow", currentNodeID)
+		return fmt.Errorf("current node not found: %s", currentNodeID)
+	}
+
+	log.Printf("✅ Found current node: %s (Type: %s)", currentNode.ID, currentNode.Type)
+
+	// Add user's reply to conversation history
+	if userMessage != "" {
+		if err := r.appendHistory(ctx, conversationID, currentNode.ID, "User", userMessage); err != nil {
+			log.Printf("⚠️  Failed to update conv_last with user message: %v", err)
+			// Don't fail the flow, just log the error
+		} else {
+			log.Printf("✅ Added user message to conv_last: %s", userMessage)
+		}
+	}
+
+	// Nodes waiting for an answer (csat, consent) handle it before the flow moves on
+	started := time.Now()
+	if replier, ok := flowNodeProcessors[currentNode.Type].(flowReplyProcessor); ok {
+		moveOn, err := replier.HandleReply(ctx, run, currentNode)
+		if err != nil {
+			run.logNode(ctx, models.FlowExecutionEventResumed, currentNode, models.FlowOutcomeFailed, nil, started, err)
+			return fmt.Errorf("failed to handle %s reply: %w", currentNode.Type, err)
+		}
+		if !moveOn {
+			run.logNode(ctx, models.FlowExecutionEventResumed, currentNode, models.FlowOutcomePaused, nil, started, nil)
+			return nil
+		}
+	}
+
+	// Find next node from current node
+	nextNode := run.findNextNode(ctx, currentNode)
+	if nextNode == nil {
+		run.logNode(ctx, models.FlowExecutionEventResumed, currentNode, models.FlowOutcomeCompleted, nil, started, nil)
+		log.Printf("✅ No next node - flow completed")
+
+		// Mark as completed
+		if err := r.state.UpdateState(ctx, conversationID, models.ConversationStateCompleted, "", nil); err != nil {
+			return err
+		}
+		r.notifyFlowCompleted(ctx, flow, conversationID)
+		return nil
+	}
+
+	run.logNode(ctx, models.FlowExecutionEventResumed, currentNode, models.FlowOutcomeContinued, nextNode, started, nil)
+
+	// Execute from next node
+	return run.executeFrom(ctx, nextNode)
+}
+
+// executeFrom executes the flow from a node until it pauses, and marks the conversation completed
+// once the flow runs out of nodes
+func (run *flowRun) executeFrom(ctx context.Context, node *FlowNode) error {
+	_, paused, err := run.walk(ctx, node, false)
+	if err != nil || paused {
+		return err
+	}
+
+	log.Printf("✅ Flow completed - no more nodes")
+
+	// Mark flow as completed
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateCompleted, "", nil); err != nil {
+		log.Printf("❌ Failed to mark flow as completed: %v", err)
+		return fmt.Errorf("failed to mark flow as completed: %w", err)
+	}
+
+	log.Printf("✅ Flow marked as 'completed'")
+	run.notifyFlowCompleted(ctx, run.flow, run.conversationID)
+	return nil
+}
+
+// walk executes nodes from node until the flow pauses or runs out of nodes, reporting whether it
+// paused. The loop guard stops it once it has run too many nodes, or one node too often, without
+// pausing. A fork branch walk stops at the first join node instead and returns it unexecuted.
+func (run *flowRun) walk(ctx context.Context, node *FlowNode, branch bool) (*FlowNode, bool, error) {
+	maxSteps, maxNodeVisits := run.deadlines.stepLimits()
+	steps := 0
+	visits := make(map[string]int)
+
+	for node != nil {
+		if branch && node.Type == flowJoinNode {
+			return node, false, nil
+		}
+		if steps >= maxSteps || visits[node.ID] >= maxNodeVisits {
+			return nil, false, run.failMaxSteps(ctx, node, steps, visits[node.ID])
+		}
+		steps++
+		visits[node.ID]++
+
+		log.Printf("🔄 Executing node: %s (Type: %s)", node.ID, node.Type)
+		run.sim.visit(node)
+
+		// Execute the current node within its own deadline
+		started := time.Now()
+		nodeCtx, cancel := run.deadlines.nodeContext(ctx)
+		continueFlow, err := run.executeNode(withMessageOrigin(nodeCtx, run.flow.ID, node.ID), node)
+		cancel()
+		if err != nil {
+			run.logNode(ctx, models.FlowExecutionEventNode, node, models.FlowOutcomeFailed, nil, started, err)
+			if deadlineExceeded(nodeCtx, err) {
+				persistPartialProgress(ctx, run.state, run.conversationID, node.ID)
+			}
+			return nil, false, fmt.Errorf("failed to execute node %s: %w", node.ID, err)
+		}
+
+		// Progress is stored where the conversation rests and at every milestone it passes
+		if !continueFlow || nodeMilestone(node) != "" {
+			recordFlowProgress(ctx, run.store, run.flowData, node, run.conversationID)
+		}
+
+		// If node says to stop flow (e.g., waiting_reply), stop here.
+		// The pausing node has already recorded its state and node ID.
+		if !continueFlow {
+			run.logNode(ctx, models.FlowExecutionEventNode, node, models.FlowOutcomePaused, nil, started, nil)
+			log.Printf("⏸️  Flow paused at node: %s", node.ID)
+			return nil, true, nil
+		}
+
+		// Flow run deadline fired: stop here and resume after this node on the next message
+		if deadlineExceeded(ctx, nil) {
+			run.logNode(ctx, models.FlowExecutionEventNode, node, models.FlowOutcomeFailed, nil, started, ctx.Err())
+			persistPartialProgress(ctx, run.state, run.conversationID, node.ID)
+			return nil, false, fmt.Errorf("flow run deadline exceeded after node %s: %w", node.ID, ctx.Err())
+		}
+
+		// A fork runs its branches and continues from the join they meet at
+		var next *FlowNode
+		if node.Type == flowForkNode && !branch {
+			var paused bool
+			next, paused, err = run.runFork(ctx, node)
+			if err != nil {
+				run.logNode(ctx, models.FlowExecutionEventNode, node, models.FlowOutcomeFailed, nil, started, err)
+				return nil, false, err
+			}
+			if paused {
+				run.logNode(ctx, models.FlowExecutionEventNode, node, models.FlowOutcomePaused, nil, started, nil)
+				return nil, true, nil
+			}
+		} else {
+			next = run.findNextNode(ctx, node)
+		}
+		outcome := models.FlowOutcomeContinued
+		if next == nil {
+			outcome = models.FlowOutcomeCompleted
+		}
+		run.logNode(ctx, models.FlowExecutionEventNode, node, outcome, next, started, nil)
+		node = next
+	}
+	return nil, false, nil
+}
+
+// failMaxSteps stops a run that hit the loop guard before node. The conversation is parked at node
+// in failed_max_steps, where the bot stays quiet until an agent takes over or restores it.
+func (run *flowRun) failMaxSteps(ctx context.Context, node *FlowNode, steps, nodeVisits int) error {
+	reason := fmt.Sprintf("stopped before node %s after %d steps (node visited %d times)", node.ID, steps, nodeVisits)
+	log.Printf("🛑 Loop guard: conversation %s %s", run.conversationID, reason)
+
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateFailedMaxSteps, node.ID, nil); err != nil {
+		log.Printf("❌ Failed to mark conversation %s as %s: %v", run.conversationID, models.ConversationStateFailedMaxSteps, err)
+	}
+
+	run.recordLog(ctx, &models.FlowExecutionLog{
+		NodeID:     node.ID,
+		NodeType:   node.Type,
+		Event:      models.FlowExecutionEventMaxSteps,
+		Outcome:    models.FlowOutcomeFailed,
+		Steps:      steps,
+		NodeVisits: nodeVisits,
+		Message:    reason,
+	})
+
+	return fmt.Errorf("%w: %s", ErrFlowMaxSteps, reason)
+}
+
+// logNode records a node the run executed: its outcome, the node taken next and how long it took
+func (run *flowRun) logNode(ctx context.Context, event string, node *FlowNode, outcome string, next *FlowNode, started time.Time, nodeErr error) {
+	entry := &models.FlowExecutionLog{
+		NodeID:     node.ID,
+		NodeType:   node.Type,
+		Event:      event,
+		Outcome:    outcome,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if next != nil {
+		entry.NextNodeID = next.ID
+	}
+	if nodeErr != nil {
+		entry.Error = nodeErr.Error()
+	}
+	run.recordLog(ctx, entry)
+}
+
+// recordLog stores an execution log entry of the run; dry runs and runtimes without a log
+// repository only write the server log. The entry is written even when the run's deadline fired.
+func (run *flowRun) recordLog(ctx context.Context, entry *models.FlowExecutionLog) {
+	if run.logs == nil || run.sim != nil {
+		return
+	}
+
+	entry.Source = run.table
+	entry.ConversationID = run.conversationID
+	entry.IDDevice = run.flow.IDDevice
+	entry.FlowID = run.flow.ID
+	entry.InputMessage = run.userMessage
+
+	logCtx, cancel := saveProgressContext(ctx)
+	defer cancel()
+	if err := run.logs.RecordLog(logCtx, entry); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// executeNode runs a single node through its registered processor
+func (run *flowRun) executeNode(ctx context.Context, node *FlowNode) (bool, error) {
+	log.Printf("⚙️  Executing node type: %s", node.Type)
+
+	processor, ok := flowNodeProcessors[node.Type]
+	if !ok {
+		log.Printf("⚠️  Unknown node type: %s, skipping", node.Type)
+		return true, nil
+	}
+	return processor.ProcessNode(ctx, run, node)
+}
+
+// runNode executes one node of a flow on its own, without advancing the flow (SLA nudges)
+func (r *flowRuntime) runNode(ctx context.Context, flow *models.ChatbotFlow, node *FlowNode, conversationID, userMessage string) (bool, error) {
+	run := &flowRun{
+		flowRuntime:    r,
+		flow:           flow,
+		flowData:       &FlowData{Nodes: []FlowNode{*node}},
+		conversationID: conversationID,
+		userMessage:    userMessage,
+	}
+	return run.executeNode(ctx, node)
+}
+
+// pauseUntil pauses the run at node for d. The conversation rests in state at the node and the
+// flow scheduler resumes the flow after it once d has passed, moved into the device's best reply
+// hours for follow-ups. Dry runs record the pause and carry on; without a scheduler the run waits
+// in-process.
+func (run *flowRun) pauseUntil(ctx context.Context, node *FlowNode, state models.ConversationState, d time.Duration) (bool, error) {
+	if run.sim != nil || run.delays == nil {
+		if err := run.sim.wait(ctx, d); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	execution := &models.DelayedExecution{
+		Source:         run.table,
+		ConversationID: run.conversationID,
+		IDDevice:       run.flow.IDDevice,
+		FlowID:         run.flow.ID,
+		NodeID:         node.ID,
+		NodeType:       node.Type,
+		ResumeAt:       run.followUpResumeAt(ctx, node, d),
+	}
+	if err := run.delays.ScheduleExecution(ctx, execution); err != nil {
+		return false, err
+	}
+
+	// A stale row is skipped by the scheduler if this fails, since the conversation is not at the node
+	if err := run.state.UpdateState(ctx, run.conversationID, state, node.ID, nil); err != nil {
+		return false, fmt.Errorf("failed to update %s state: %w", state, err)
+	}
+
+	log.Printf("🗓️  Conversation %s scheduled to resume after node %s at %s", run.conversationID, node.ID, execution.ResumeAt.Format(time.RFC3339))
+	return false, nil
+}
+
+// findNextNode finds the next node to execute based on edges
+func (run *flowRun) findNextNode(ctx context.Context, currentNode *FlowNode) *FlowNode {
+	flowData := run.flowData
+
+	// Find all outgoing edges from current node
+	var outgoingEdges []FlowEdge
+	for _, edge := range flowData.Connections {
+		if edge.From == currentNode.ID {
+			outgoingEdges = append(outgoingEdges, edge)
+		}
+	}
+
+	if len(outgoingEdges) == 0 {
+		log.Printf("ℹ️  No outgoing edges from node: %s", currentNode.ID)
+		return nil
+	}
+
+	// An A/B split picks the prospect's branch by weight
+	if currentNode.Type == flowABSplitNode {
+		return run.abSplitNext(ctx, currentNode, outgoingEdges)
+	}
+
+	// A business hours node follows its open or closed connection
+	if currentNode.Type == flowBusinessHoursNode {
+		return run.businessHoursNext(ctx, currentNode, outgoingEdges)
+	}
+
+	// Buttons and lists follow the connection of the option picked
+	if currentNode.Type == flowSendButtonsNode || currentNode.Type == flowSendListNode {
+		return run.interactiveNext(ctx, currentNode, outgoingEdges)
+	}
+
+	// Legacy condition, user_reply and api nodes follow the branch they recorded
+	if isLegacyBranchNode(currentNode.Type) {
+		return run.legacyBranchNext(currentNode, outgoingEdges)
+	}
+
+	// If only one edge, follow it
+	if len(outgoingEdges) == 1 {
+		return findFlowNode(flowData, outgoingEdges[0].To)
+	}
+
+	// Multiple edges - check if this is a Conditions node
+	if currentNode.Type == "conditions" {
+		log.Printf("🔀 Conditions node with %d edges", len(outgoingEdges))
+		now := deviceClock(ctx, run.deviceRepo, run.flow.IDDevice)
+
+		// Match user message against conditions, both normalized the same way
+		for _, edge := range outgoingEdges {
+			if edge.ConditionType == "" || edge.ConditionValue == "" {
+				log.Printf("⚠️  Edge has no condition type/value, skipping")
+				continue
+			}
+
+			matched := false
+			switch {
+			case isTextCondition(edge.ConditionType):
+				matched = evaluateTextCondition(edge.ConditionType, run.conditionSubject(ctx, edge), edge.ConditionValue, run.flow.NormalizeMalay)
+			case strings.EqualFold(edge.ConditionType, "default"):
+				matched = true // Default always matches
+			default:
+				if strings.EqualFold(edge.ConditionType, ConditionRepliedTo) {
+					matched = evaluateRepliedTo(ctx, edge.ConditionValue)
+				} else if strings.EqualFold(edge.ConditionType, ConditionButton) {
+					matched = evaluateButtonCondition(ctx, edge.ConditionValue, run.userMessage)
+				} else if strings.EqualFold(edge.ConditionType, ConditionMedia) {
+					matched = evaluateMediaCondition(ctx, edge.ConditionValue)
+				} else if strings.EqualFold(edge.ConditionType, ConditionField) {
+					matched = run.fieldCondition(ctx, edge.ConditionValue)
+				} else if strings.EqualFold(edge.ConditionType, ConditionReturningProspect) {
+					matched = evaluateReturningProspect(edge.ConditionValue, run.returningProspect(ctx))
+				} else if isTimeCondition(edge.ConditionType) {
+					matched = evaluateTimeCondition(edge.ConditionType, edge.ConditionValue, now())
+				}
+			}
+
+			if matched {
+				log.Printf("✅ Condition matched: %s '%s'", edge.ConditionType, edge.ConditionValue)
+				return findFlowNode(flowData, edge.To)
+			}
+		}
+
+		// No conditions matched, look for default
+		for _, edge := range outgoingEdges {
+			if strings.ToLower(edge.ConditionType) == "default" {
+				log.Printf("✅ Using default condition")
+				return findFlowNode(flowData, edge.To)
+			}
+		}
+
+		// No conditions matched and no default - randomly select one of the edges
+		randomIndex := rand.Intn(len(outgoingEdges))
+		selectedEdge := outgoingEdges[randomIndex]
+		log.Printf("🎲 No conditions matched, randomly selected edge %d/%d (to: %s)", randomIndex+1, len(outgoingEdges), selectedEdge.To)
+		return findFlowNode(flowData, selectedEdge.To)
+	}
+
+	// Not a conditions node, but multiple edges - follow first one
+	log.Printf("⚠️  Multiple edges from non-condition node, following first one")
+	return findFlowNode(flowData, outgoingEdges[0].To)
+}
+
+// returningProspect reports whether the run's conversation entered a flow before this one
+func (run *flowRun) returningProspect(ctx context.Context) bool {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Failed to load conversation %s for returning_prospect: %v", run.conversationID, err)
+		return false
+	}
+	return conversation.Entry.returning()
+}
+
+// appendHistory records a message of the conversation and appends its "Role: message" entry to
+// conv_last, within the runtime's history cap. nodeID is the node that sent the message, or the
+// node a prospect's reply answered.
+func (r *flowRuntime) appendHistory(ctx context.Context, conversationID, nodeID, role, message string) error {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	conversation, err := r.load(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if conversation == nil {
+		return fmt.Errorf("conversation %s not found", conversationID)
+	}
+
+	limit := 0
+	if r.historyLimit != nil {
+		limit = r.historyLimit(ctx, conversation.IDDevice)
+	}
+
+	entry := newConversationMessage(r.table, conversationID, conversation.IDDevice, role, message)
+	if nodeID != "" {
+		entry.NodeID = &nodeID
+	}
+
+	// Dry runs keep their history on the simulated row only
+	messages := r.messages
+	if r.sim != nil {
+		messages = nil
+	}
+
+	updates := map[string]interface{}{
+		"conv_last": messages.Append(ctx, getStringValue(conversation.ConvLast), entry, limit),
+	}
+
+	return r.store.UpdateConversation(ctx, conversationID, updates)
+}
+
+// findStartingNode finds the node to start execution from
+func findStartingNode(flowData *FlowData, currentStage string) *FlowNode {
+	// If no current stage, find the first node (after start node if exists)
+	if currentStage == "" || currentStage == "start" {
+		// Look for a node that has no incoming connections (or is connected from start)
+		for i := range flowData.Nodes {
+			node := &flowData.Nodes[i]
+			// Skip if this is a start-type node
+			if strings.Contains(strings.ToLower(node.Type), "start") {
+				continue
+			}
+
+			// Check if this node has incoming connections
+			hasIncoming := false
+			for _, edge := range flowData.Connections {
+				if edge.To == node.ID {
+					hasIncoming = true
+					break
+				}
+			}
+
+			// If no incoming connections, this could be the first node
+			if !hasIncoming {
+				return node
+			}
+		}
+
+		// If all nodes have incoming connections, begin at the start node of a legacy flow
+		for i := range flowData.Nodes {
+			if flowData.Nodes[i].Type == legacyStartNode {
+				return &flowData.Nodes[i]
+			}
+		}
+
+		// Otherwise get the first node
+		if len(flowData.Nodes) > 0 {
+			return &flowData.Nodes[0]
+		}
+	}
+
+	// Otherwise, try to find node by ID matching the stage
+	if node := findFlowNode(flowData, currentStage); node != nil {
+		return node
+	}
+
+	// Default to first node
+	if len(flowData.Nodes) > 0 {
+		return &flowData.Nodes[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// flowSchedulerBatchSize caps the resumes one dispatch pass claims
+const flowSchedulerBatchSize = 50
+
+// FlowScheduler resumes flows paused by delay and waiting_times nodes. The nodes store a resume
+// time in delayed_executions instead of sleeping; the scheduler picks up due rows and continues
+// the flow after the node, so pauses no longer hold a worker and survive restarts.
+type FlowScheduler struct {
+	processor *FlowProcessorService
+	delayRepo *repository.DelayedExecutionRepository
+}
+
+// NewFlowScheduler creates a new flow scheduler
+func NewFlowScheduler(processor *FlowProcessorService, delayRepo *repository.DelayedExecutionRepository) *FlowScheduler {
+	return &FlowScheduler{
+		processor: processor,
+		delayRepo: delayRepo,
+	}
+}
+
+// Start dispatches due resumes immediately and then every interval until ctx is cancelled
+func (f *FlowScheduler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if resumed, err := f.DispatchDue(ctx); err != nil {
+				log.Printf("⚠️  Flow scheduler dispatch failed: %v", err)
+			} else if resumed > 0 {
+				log.Printf("🗓️  Resumed %d scheduled flow(s)", resumed)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DispatchDue resumes up to flowSchedulerBatchSize due executions and returns how many flows resumed.
+// The rest wait for the next tick.
+func (f *FlowScheduler) DispatchDue(ctx context.Context) (int, error) {
+	executions, err := f.delayRepo.GetDueExecutions(ctx, time.Now(), flowSchedulerBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for i := range executions {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		execution := &executions[i]
+		claimed, err := f.delayRepo.ClaimExecution(ctx, execution.ID)
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		if !claimed {
+			continue // another dispatcher has it, or it was skipped meanwhile
+		}
+
+		status, errMessage := f.dispatch(ctx, execution)
+		if status == models.DelayedExecutionDone {
+			total++
+		}
+		if err := f.delayRepo.FinishExecution(ctx, execution.ID, status, errMessage); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+
+	return total, nil
+}
+
+// dispatch resumes the flow after the execution's node and returns the status to record.
+// Conversations that left the node in the meantime (a reply, a handoff, a new pause) are skipped.
+func (f *FlowScheduler) dispatch(ctx context.Context, execution *models.DelayedExecution) (string, string) {
+	conversation, err := f.processor.loadPausedConversation(ctx, execution.Source, execution.ConversationID)
+	if errors.Is(err, ErrConversationNotFound) {
+		return models.DelayedExecutionSkipped, ""
+	}
+	if err != nil {
+		return models.DelayedExecutionFailed, err.Error()
+	}
+	if conversation.state != execution.PausedState() || conversation.nodeID != execution.NodeID {
+		log.Printf("⏭️  Conversation %s moved on from node %s, skipping scheduled resume", execution.ConversationID, execution.NodeID)
+		return models.DelayedExecutionSkipped, ""
+	}
+
+	flow, err := f.processor.flowRepo.GetFlowByID(ctx, execution.FlowID)
+	if err != nil || flow == nil {
+		return models.DelayedExecutionFailed, fmt.Sprintf("failed to load flow %s: %v", execution.FlowID, err)
+	}
+
+	runCtx, cancel := f.processor.deadlines.flowRunContext()
+	defer cancel()
+
+	if err := f.processor.resumeAfterNode(runCtx, execution.Source, execution.ConversationID, flow, execution.NodeID, ""); err != nil {
+		log.Printf("❌ Scheduled resume of conversation %s failed: %v", execution.ConversationID, err)
+		return models.DelayedExecutionFailed, err.Error()
+	}
+
+	return models.DelayedExecutionDone, ""
+}
@@ -6,19 +6,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // FlowService handles flow business logic
 type FlowService struct {
-	flowRepo   *repository.FlowRepository
-	deviceRepo *repository.DeviceRepository
+	flowRepo    *repository.FlowRepository
+	deviceRepo  *repository.DeviceRepository
+	stageRepo   *repository.StageRepository
+	versionRepo *repository.FlowVersionRepository // snapshots taken before each update
+	processor   *FlowProcessorService             // flow engines, for dry runs
+	nodeAccess  NodeAccessPolicy                  // nodes team agents cannot edit
+	team        *TeamService                      // members who may view or edit shared devices' flows (nil = owners only)
 }
 
 // NewFlowService creates a new flow service
-func NewFlowService(flowRepo *repository.FlowRepository, deviceRepo *repository.DeviceRepository) *FlowService {
+func NewFlowService(flowRepo *repository.FlowRepository, deviceRepo *repository.DeviceRepository, stageRepo *repository.StageRepository, versionRepo *repository.FlowVersionRepository, processor *FlowProcessorService, nodeAccess NodeAccessPolicy, team *TeamService) *FlowService {
 	return &FlowService{
-		flowRepo:   flowRepo,
-		deviceRepo: deviceRepo,
+		flowRepo:    flowRepo,
+		deviceRepo:  deviceRepo,
+		stageRepo:   stageRepo,
+		versionRepo: versionRepo,
+		processor:   processor,
+		nodeAccess:  nodeAccess,
+		team:        team,
 	}
 }
 
@@ -42,7 +53,7 @@ func (s *FlowService) CreateFlow(ctx context.Context, userID string, req *models
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.canEditFlows(ctx, userID, device) {
 		return &models.FlowResponse{
 			Success: false,
 			Message: "Access denied - device does not belong to you",
@@ -58,15 +69,6 @@ func (s *FlowService) CreateFlow(ctx context.Context, userID string, req *models
 		deviceIdentifier = *device.DeviceID
 	}
 
-	// Check if flow already exists for this device
-	existingFlows, err := s.flowRepo.GetFlowsByDeviceID(ctx, deviceIdentifier)
-	if err == nil && len(existingFlows) > 0 {
-		return &models.FlowResponse{
-			Success: false,
-			Message: "Flow already exists for this device. Please delete the existing flow first or update it instead.",
-		}, nil
-	}
-
 	// Parse NodesData JSON string to extract nodes and edges/connections
 	var flowData map[string]interface{}
 	nodes := map[string]interface{}{}
@@ -85,13 +87,83 @@ func (s *FlowService) CreateFlow(ctx context.Context, userID string, req *models
 		}
 	}
 
+	// Flows with structural errors are not saved; warnings are returned with the flow
+	var validation []models.FlowValidationIssue
+	if req.NodesData != "" {
+		validation = validateFlowData(req.NodesData)
+		if msg := flowValidationErrors(validation); msg != "" {
+			return &models.FlowResponse{
+				Success:    false,
+				Message:    msg,
+				Validation: validation,
+			}, nil
+		}
+	}
+
+	// Team agents build flows without integrations, spend-incurring nodes and flow settings
+	accessMsg, err := s.checkNodeAccess(ctx, userID, device, "", req.NodesData, createsFlowSettings(req))
+	if err != nil {
+		return nil, err
+	}
+	if accessMsg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: accessMsg,
+		}, nil
+	}
+
+	// Validate post-completion policy
+	if msg := validateCompletionPolicy(req.CompletionPolicy, req.AfterSalesFlowID); msg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+	if msg := s.checkAfterSalesFlow(ctx, req.CompletionPolicy, req.AfterSalesFlowID, deviceIdentifier); msg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	if msg := validateCompletionWebhook(req.CompletionWebhookURL, req.CompletionWebhookTemplate); msg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	if msg := validateReentryCooldown(req.ReentryCooldownHours); msg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	if msg := validateFlowTriggers(req.Triggers); msg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
 	flow := &models.ChatbotFlow{
-		IDDevice:  deviceIdentifier, // Use the user-friendly identifier
-		Name:      req.FlowName,
-		Niche:     req.Niche,
-		NodesData: req.NodesData, // Save complete flow JSON
-		Nodes:     nodes,          // Parsed from NodesData
-		Edges:     edges,          // Parsed from NodesData
+		IDDevice:         deviceIdentifier, // Use the user-friendly identifier
+		Name:             req.FlowName,
+		Niche:            req.Niche,
+		NodesData:        req.NodesData, // Save complete flow JSON
+		Nodes:            nodes,         // Parsed from NodesData
+		Edges:            edges,         // Parsed from NodesData
+		CompletionPolicy: req.CompletionPolicy,
+		AfterSalesFlowID: req.AfterSalesFlowID,
+
+		CompletionWebhookURL:      req.CompletionWebhookURL,
+		CompletionWebhookTemplate: req.CompletionWebhookTemplate,
+		ReentryCooldownHours:      req.ReentryCooldownHours,
+		NormalizeMalay:            req.NormalizeMalay,
+		Triggers:                  req.Triggers,
+		IsActive:                  req.IsActive,
+		Priority:                  req.Priority,
 	}
 
 	if err := s.flowRepo.CreateFlow(ctx, flow); err != nil {
@@ -99,15 +171,21 @@ func (s *FlowService) CreateFlow(ctx context.Context, userID string, req *models
 	}
 
 	return &models.FlowResponse{
-		Success: true,
-		Message: "Flow created successfully",
-		Flow:    flow,
+		Success:    true,
+		Message:    "Flow created successfully",
+		Flow:       flow,
+		Validation: validation,
 	}, nil
 }
 
 // GetFlow retrieves a flow by ID or device identifier
 // If flowID looks like a device identifier, gets the first flow for that device
 func (s *FlowService) GetFlow(ctx context.Context, userID, flowID string) (*models.FlowResponse, error) {
+	return s.getFlow(ctx, userID, flowID, models.TeamPermissionViewFlows)
+}
+
+// getFlow retrieves a flow on a device the user owns or has a team role on that grants permission
+func (s *FlowService) getFlow(ctx context.Context, userID, flowID, permission string) (*models.FlowResponse, error) {
 	// Try to get flow by UUID first
 	flow, err := s.flowRepo.GetFlowByID(ctx, flowID)
 
@@ -130,7 +208,7 @@ func (s *FlowService) GetFlow(ctx context.Context, userID, flowID string) (*mode
 		}
 
 		// Verify ownership
-		if device.UserID == nil || *device.UserID != userID {
+		if !s.team.Allows(ctx, userID, device, permission) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
@@ -161,13 +239,13 @@ func (s *FlowService) GetFlow(ctx context.Context, userID, flowID string) (*mode
 
 	if device == nil {
 		device, err = s.deviceRepo.GetDeviceByID(ctx, flow.IDDevice)
-		if err != nil || device.UserID == nil || *device.UserID != userID {
+		if err != nil || !s.team.Allows(ctx, userID, device, permission) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
-	} else if device.UserID == nil || *device.UserID != userID {
+	} else if !s.team.Allows(ctx, userID, device, permission) {
 		return &models.FlowResponse{
 			Success: false,
 			Message: "Access denied",
@@ -200,7 +278,7 @@ func (s *FlowService) GetFlowsByDevice(ctx context.Context, userID, deviceID str
 	}
 
 	// Verify ownership
-	if device.UserID == nil || *device.UserID != userID {
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewFlows) {
 		return &models.FlowResponse{
 			Success: false,
 			Message: "Access denied",
@@ -220,13 +298,18 @@ func (s *FlowService) GetFlowsByDevice(ctx context.Context, userID, deviceID str
 	}, nil
 }
 
-// GetAllUserFlows retrieves all flows for all user devices
+// GetAllUserFlows retrieves all flows for all user devices, own and shared
 func (s *FlowService) GetAllUserFlows(ctx context.Context, userID string) (*models.FlowResponse, error) {
 	// Get all user devices
 	devices, err := s.deviceRepo.GetDevicesByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user devices: %w", err)
 	}
+	shared, _, err := s.team.SharedDevices(ctx, userID, models.TeamPermissionViewFlows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared devices: %w", err)
+	}
+	devices = append(devices, shared...)
 
 	if len(devices) == 0 {
 		return &models.FlowResponse{
@@ -274,8 +357,14 @@ func (s *FlowService) GetAllFlows(ctx context.Context) (*models.FlowResponse, er
 
 // UpdateFlow updates a flow by UUID or device identifier
 func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req *models.UpdateFlowRequest) (*models.FlowResponse, error) {
+	return s.updateFlow(ctx, userID, flowID, req, models.FlowVersionReasonUpdate)
+}
+
+// updateFlow applies an update after storing the current flow as a version with reason
+func (s *FlowService) updateFlow(ctx context.Context, userID, flowID string, req *models.UpdateFlowRequest, reason string) (*models.FlowResponse, error) {
 	// Try to get flow by UUID first
 	flow, err := s.flowRepo.GetFlowByID(ctx, flowID)
+	var flowDevice *models.DeviceSetting
 
 	// If not found by UUID, try as device identifier
 	if err != nil {
@@ -296,7 +385,7 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 		}
 
 		// Verify ownership
-		if device.UserID == nil || *device.UserID != userID {
+		if !s.canEditFlows(ctx, userID, device) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
@@ -313,6 +402,7 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 		}
 
 		flow = &flows[0]
+		flowDevice = device
 	} else {
 		// Flow found by UUID, verify device ownership
 		device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, flow.IDDevice)
@@ -322,18 +412,35 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 
 		if device == nil {
 			device, err = s.deviceRepo.GetDeviceByID(ctx, flow.IDDevice)
-			if err != nil || device.UserID == nil || *device.UserID != userID {
+			if err != nil || !s.canEditFlows(ctx, userID, device) {
 				return &models.FlowResponse{
 					Success: false,
 					Message: "Access denied",
 				}, nil
 			}
-		} else if device.UserID == nil || *device.UserID != userID {
+		} else if !s.canEditFlows(ctx, userID, device) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
 			}, nil
 		}
+		flowDevice = device
+	}
+
+	// Team agents may edit the flow's copy but not its settings or admin-only nodes
+	nextNodesData := flow.NodesData
+	if req.NodesData != nil {
+		nextNodesData = *req.NodesData
+	}
+	accessMsg, err := s.checkNodeAccess(ctx, userID, flowDevice, flow.NodesData, nextNodesData, updatesFlowSettings(req))
+	if err != nil {
+		return nil, err
+	}
+	if accessMsg != "" {
+		return &models.FlowResponse{
+			Success: false,
+			Message: accessMsg,
+		}, nil
 	}
 
 	// Build update map
@@ -345,7 +452,18 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 	if req.Niche != nil {
 		updates["niche"] = *req.Niche
 	}
+	var validation []models.FlowValidationIssue
 	if req.NodesData != nil {
+		// Builder edits must be structurally valid; restores and migrations keep what was saved before
+		validation = validateFlowData(*req.NodesData)
+		if msg := flowValidationErrors(validation); msg != "" && reason == models.FlowVersionReasonUpdate {
+			return &models.FlowResponse{
+				Success:    false,
+				Message:    msg,
+				Validation: validation,
+			}, nil
+		}
+
 		// Parse NodesData JSON string to extract nodes and edges/connections
 		var flowData map[string]interface{}
 		nodes := map[string]interface{}{}
@@ -367,6 +485,85 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 		updates["edges"] = edges
 	}
 
+	if req.CompletionPolicy != nil || req.AfterSalesFlowID != nil {
+		policy := flow.CompletionPolicy
+		if req.CompletionPolicy != nil {
+			policy = *req.CompletionPolicy
+		}
+		afterSalesFlowID := flow.AfterSalesFlowID
+		if req.AfterSalesFlowID != nil {
+			afterSalesFlowID = req.AfterSalesFlowID
+		}
+
+		if msg := validateCompletionPolicy(policy, afterSalesFlowID); msg != "" {
+			return &models.FlowResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if msg := s.checkAfterSalesFlow(ctx, policy, afterSalesFlowID, flow.IDDevice); msg != "" {
+			return &models.FlowResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+
+		updates["completion_policy"] = policy
+		updates["after_sales_flow_id"] = afterSalesFlowID
+	}
+
+	if req.CompletionWebhookURL != nil || req.CompletionWebhookTemplate != nil {
+		if msg := validateCompletionWebhook(req.CompletionWebhookURL, req.CompletionWebhookTemplate); msg != "" {
+			return &models.FlowResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+
+		if req.CompletionWebhookURL != nil {
+			if *req.CompletionWebhookURL == "" {
+				updates["completion_webhook_url"] = nil
+			} else {
+				updates["completion_webhook_url"] = *req.CompletionWebhookURL
+			}
+		}
+		if req.CompletionWebhookTemplate != nil {
+			if strings.TrimSpace(*req.CompletionWebhookTemplate) == "" {
+				updates["completion_webhook_template"] = nil
+			} else {
+				updates["completion_webhook_template"] = *req.CompletionWebhookTemplate
+			}
+		}
+	}
+
+	if req.ReentryCooldownHours != nil {
+		if msg := validateReentryCooldown(*req.ReentryCooldownHours); msg != "" {
+			return &models.FlowResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		updates["reentry_cooldown_hours"] = *req.ReentryCooldownHours
+	}
+
+	if req.NormalizeMalay != nil {
+		updates["normalize_malay"] = *req.NormalizeMalay
+	}
+
+	if req.Triggers != nil {
+		if msg := validateFlowTriggers(*req.Triggers); msg != "" {
+			return &models.FlowResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		if len(*req.Triggers) == 0 {
+			updates["triggers"] = nil
+		} else {
+			updates["triggers"] = *req.Triggers
+		}
+	}
+
 	if len(updates) == 0 {
 		return &models.FlowResponse{
 			Success: false,
@@ -374,6 +571,11 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 		}, nil
 	}
 
+	// Keep the flow as it was, so the update can be rolled back
+	if err := s.snapshotFlow(ctx, flow, userID, reason); err != nil {
+		return nil, err
+	}
+
 	// Update using the flow's actual UUID
 	if err := s.flowRepo.UpdateFlow(ctx, flow.ID, updates); err != nil {
 		return nil, fmt.Errorf("failed to update flow: %w", err)
@@ -383,12 +585,41 @@ func (s *FlowService) UpdateFlow(ctx context.Context, userID, flowID string, req
 	updatedFlow, _ := s.flowRepo.GetFlowByID(ctx, flow.ID)
 
 	return &models.FlowResponse{
-		Success: true,
-		Message: "Flow updated successfully",
-		Flow:    updatedFlow,
+		Success:    true,
+		Message:    "Flow updated successfully",
+		Flow:       updatedFlow,
+		Validation: validation,
 	}, nil
 }
 
+// AutoLayoutFlow recomputes node positions with a layered graph layout and saves them
+func (s *FlowService) AutoLayoutFlow(ctx context.Context, userID, flowID string, req *models.AutoLayoutRequest) (*models.FlowResponse, error) {
+	if req.Direction != "" && req.Direction != models.LayoutTopToBottom && req.Direction != models.LayoutLeftToRight {
+		return &models.FlowResponse{
+			Success: false,
+			Message: "direction must be TB or LR",
+		}, nil
+	}
+
+	// getFlow verifies access
+	resp, err := s.getFlow(ctx, userID, flowID, models.TeamPermissionEditFlows)
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+
+	nodesData, err := autoLayoutNodesData(resp.Flow.NodesData, req)
+	if err != nil {
+		return &models.FlowResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return s.UpdateFlow(ctx, userID, resp.Flow.ID, &models.UpdateFlowRequest{
+		NodesData: &nodesData,
+	})
+}
+
 // DeleteFlow deletes a flow by UUID or device identifier
 func (s *FlowService) DeleteFlow(ctx context.Context, userID, flowID string) (*models.FlowResponse, error) {
 	// Try to get flow by UUID first
@@ -413,7 +644,7 @@ func (s *FlowService) DeleteFlow(ctx context.Context, userID, flowID string) (*m
 		}
 
 		// Verify ownership
-		if device.UserID == nil || *device.UserID != userID {
+		if !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteFlows) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
@@ -439,13 +670,13 @@ func (s *FlowService) DeleteFlow(ctx context.Context, userID, flowID string) (*m
 
 		if device == nil {
 			device, err = s.deviceRepo.GetDeviceByID(ctx, flow.IDDevice)
-			if err != nil || device.UserID == nil || *device.UserID != userID {
+			if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteFlows) {
 				return &models.FlowResponse{
 					Success: false,
 					Message: "Access denied",
 				}, nil
 			}
-		} else if device.UserID == nil || *device.UserID != userID {
+		} else if !s.team.Allows(ctx, userID, device, models.TeamPermissionDeleteFlows) {
 			return &models.FlowResponse{
 				Success: false,
 				Message: "Access denied",
@@ -463,3 +694,45 @@ func (s *FlowService) DeleteFlow(ctx context.Context, userID, flowID string) (*m
 		Message: "Flow deleted successfully",
 	}, nil
 }
+
+// validateReentryCooldown checks a flow's re-entry cooldown.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateReentryCooldown(hours int) string {
+	if hours < 0 || hours > models.MaxReentryCooldownHours {
+		return fmt.Sprintf("reentry_cooldown_hours must be between 0 and %d", models.MaxReentryCooldownHours)
+	}
+	return ""
+}
+
+// validateCompletionPolicy checks the post-completion policy settings of a flow.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateCompletionPolicy(policy models.CompletionPolicy, afterSalesFlowID *string) string {
+	if policy == "" {
+		return ""
+	}
+	if !policy.IsValid() {
+		return fmt.Sprintf("Invalid completion_policy: %s (use ignore, restart, after_sales or handoff)", policy)
+	}
+	if policy == models.CompletionPolicyAfterSales && (afterSalesFlowID == nil || *afterSalesFlowID == "") {
+		return "after_sales_flow_id is required when completion_policy is after_sales"
+	}
+	return ""
+}
+
+// checkAfterSalesFlow checks the after-sales flow of an after_sales policy exists and is on the
+// device of the flow routing to it, so conversations cannot be sent to another user's flow.
+// Returns a user-facing message when it is not, or an empty string when valid.
+func (s *FlowService) checkAfterSalesFlow(ctx context.Context, policy models.CompletionPolicy, afterSalesFlowID *string, idDevice string) string {
+	if policy != models.CompletionPolicyAfterSales || afterSalesFlowID == nil || *afterSalesFlowID == "" {
+		return ""
+	}
+
+	afterSalesFlow, err := s.flowRepo.GetFlowByID(ctx, *afterSalesFlowID)
+	if err != nil || afterSalesFlow == nil {
+		return fmt.Sprintf("After-sales flow %s not found", *afterSalesFlowID)
+	}
+	if afterSalesFlow.IDDevice != idDevice {
+		return "after_sales_flow_id must be a flow on the same device"
+	}
+	return ""
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// FlowMessageSender delivers the messages flow nodes send. WhatsAppService sends them for real;
+// the flow simulator records them instead.
+type FlowMessageSender interface {
+	SendMessage(ctx context.Context, deviceID string, to string, message string, mediaType string, mediaURL string, mimeType ...string) error
+	SendRequest(ctx context.Context, deviceID string, to string, message *models.SendMessageRequest) error
+}
+
+// aiConversationStore is the ai_whatsapp storage the Chatbot AI engine runs against
+type aiConversationStore interface {
+	ConversationStateStore
+	GetConversationByID(ctx context.Context, prospectID string) (*models.AIWhatsapp, error)
+}
+
+// wasapbotConversationStore is the wasapbot storage the Whatsapp Bot engine runs against
+type wasapbotConversationStore interface {
+	ConversationStateStore
+	GetConversationByID(ctx context.Context, prospectID string) (*models.Wasapbot, error)
+}
+
+// simulationConversationID identifies the mock conversation of a dry run
+const simulationConversationID = "simulation"
+
+// SimulateFlow dry-runs one of the user's flows against a mock conversation and returns the trace
+func (s *FlowService) SimulateFlow(ctx context.Context, userID, flowID string, req *models.SimulateFlowRequest) (*models.SimulateFlowResponse, error) {
+	if len(req.Messages) == 0 || len(req.Messages) > models.MaxSimulationMessages {
+		return &models.SimulateFlowResponse{
+			Success: false,
+			Message: fmt.Sprintf("messages must hold 1-%d prospect messages", models.MaxSimulationMessages),
+		}, nil
+	}
+	for _, message := range req.Messages {
+		if strings.TrimSpace(message) == "" {
+			return &models.SimulateFlowResponse{
+				Success: false,
+				Message: "messages cannot be empty",
+			}, nil
+		}
+	}
+
+	// GetFlow verifies ownership
+	resp, err := s.GetFlow(ctx, userID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return &models.SimulateFlowResponse{
+			Success: false,
+			Message: resp.Message,
+		}, nil
+	}
+	if resp.Flow.NodesData == "" {
+		return &models.SimulateFlowResponse{
+			Success: false,
+			Message: "Flow has no nodes configured",
+		}, nil
+	}
+	if s.processor == nil {
+		return nil, fmt.Errorf("flow simulator is not configured")
+	}
+
+	return &models.SimulateFlowResponse{
+		Success: true,
+		Message: "Flow simulated; nothing was sent or saved",
+		Trace:   s.processor.SimulateFlow(ctx, resp.Flow, req),
+	}, nil
+}
+
+// SimulateFlow replays prospect messages through flow with the real engines, against an in-memory
+// conversation. Nothing is sent and nothing is written: messages, column changes, delays and the
+// completion webhook are recorded in the returned trace. AI and translation nodes still call their
+// providers, with the device's own keys, so the trace shows real replies.
+func (s *FlowProcessorService) SimulateFlow(ctx context.Context, flow *models.ChatbotFlow, req *models.SimulateFlowRequest) *models.FlowSimulationTrace {
+	prospectNum := strings.TrimSpace(req.ProspectNum)
+	if prospectNum == "" {
+		prospectNum = models.SimulationProspectNum
+	}
+	sim := newFlowSimulation(flow, prospectNum, req.Messages[0], req.Variables)
+
+	// The same engines with every write swapped for the simulation
+	ai := *s
+	ai.sender = sim
+	ai.store = simulatedAIStore{sim}
+	ai.aiState = NewConversationStateMachine(ai.store)
+	ai.costs = nil
+	ai.consents = nil
+	ai.links = nil
+	ai.email = nil
+	ai.events = nil
+	ai.sheets = nil
+	ai.sim = sim
+	ai.messages = nil
+
+	wasapbot := &WasapbotFlowEngine{
+		flowServices: ai.runtimeServices(),
+		store:        simulatedWasapbotStore{sim},
+		sender:       sim,
+		stateMachine: NewConversationStateMachine(simulatedWasapbotStore{sim}),
+		sim:          sim,
+	}
+
+	isWasapbot := sim.trace.Engine == "Whatsapp Bot"
+	for i, message := range req.Messages {
+		sim.beginTurn(i)
+		turn := models.FlowSimulationTurn{Turn: i, UserMessage: message}
+
+		runCtx, cancel := withDeadline(ctx, s.deadlines.FlowRun)
+		var err error
+		switch state := sim.state(); state {
+		case models.ConversationStateCompleted:
+			turn.Action = "skipped"
+			turn.Note = "the flow has already completed"
+		case models.ConversationStateHandoff:
+			turn.Action = "skipped"
+			turn.Note = "the conversation is handed off to a human agent"
+		case models.ConversationStatePausedByAgent:
+			turn.Action = "skipped"
+			turn.Note = "an agent has taken the conversation over"
+		case models.ConversationStateFailedMaxSteps:
+			turn.Action = "skipped"
+			turn.Note = "the loop guard stopped the flow"
+		case models.ConversationStateWaiting:
+			// Mirrors ProcessIncomingMessage: leave the waiting state, then resume at the waiting node
+			turn.Action = "resume"
+			nodeID := sim.column("current_node_id")
+			if isWasapbot {
+				updates := map[string]interface{}{
+					"conv_last": appendConvHistory(sim.column("conv_last"), "User", message, wasapbot.maxHistoryEntries(runCtx, flow.IDDevice)),
+				}
+				if err = wasapbot.stateMachine.UpdateState(runCtx, simulationConversationID, models.ConversationStateActive, "", updates); err == nil {
+					err = wasapbot.ResumeWasapbotFlow(runCtx, flow, simulationConversationID, message, nodeID)
+				}
+			} else {
+				if err = ai.aiState.UpdateState(runCtx, simulationConversationID, models.ConversationStateActive, "", nil); err == nil {
+					err = ai.ResumeFlow(runCtx, flow, simulationConversationID, message, nodeID)
+				}
+			}
+		default:
+			turn.Action = "start"
+			stage := sim.column("stage")
+			if isWasapbot {
+				if i > 0 {
+					err = sim.UpdateConversation(runCtx, simulationConversationID, map[string]interface{}{"conv_last": fmt.Sprintf("User: %s", message)})
+				}
+				if err == nil {
+					err = wasapbot.ExecuteWasapbotFlow(runCtx, flow, simulationConversationID, message, stage)
+				}
+			} else {
+				err = ai.ExecuteFlow(runCtx, flow, simulationConversationID, message, stage)
+			}
+		}
+		cancel()
+
+		if err != nil {
+			turn.Error = err.Error()
+		}
+		turn.StateAfter = string(sim.state())
+		sim.trace.Turns = append(sim.trace.Turns, turn)
+	}
+
+	return sim.finish()
+}
+
+// flowSimulation is the mock conversation row of a dry run and the trace of everything done to it.
+// It stands in for the WhatsApp sender and for both conversation repositories. Its methods are
+// nil-safe so the engines can call them unconditionally: nil means a live run.
+type flowSimulation struct {
+	mu    sync.Mutex
+	row   map[string]interface{}
+	turn  int
+	trace *models.FlowSimulationTrace
+}
+
+// newFlowSimulation builds the row a new prospect's first message would create
+func newFlowSimulation(flow *models.ChatbotFlow, prospectNum, firstMessage string, variables map[string]interface{}) *flowSimulation {
+	row := make(map[string]interface{}, len(variables)+8)
+	for key, value := range variables {
+		row[key] = simulationValue(value)
+	}
+	row["id_device"] = flow.IDDevice
+	row["prospect_num"] = prospectNum
+	row["flow_id"] = flow.ID
+	row["execution_status"] = string(models.ConversationStateActive)
+	row["channel"] = models.ProspectChannel(prospectNum)
+	row["conv_last"] = fmt.Sprintf("User: %s", firstMessage)
+	// A first-time prospect unless the variables set flow_entries (above 1 tests returning_prospect)
+	if _, ok := row["flow_entries"]; !ok {
+		row["flow_entries"] = 1
+	}
+	if flow.Niche != "" {
+		row["niche"] = flow.Niche
+	}
+
+	return &flowSimulation{
+		row: row,
+		trace: &models.FlowSimulationTrace{
+			FlowID: flow.ID,
+			Engine: flowEngineType(flow),
+			Turns:  []models.FlowSimulationTurn{},
+			Events: []models.FlowTraceEvent{},
+		},
+	}
+}
+
+// simulationValue normalizes a value to what a JSON round trip through the database would return
+func simulationValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func (sim *flowSimulation) beginTurn(turn int) {
+	sim.mu.Lock()
+	sim.turn = turn
+	sim.mu.Unlock()
+}
+
+// record appends an event, attributed to the node executing in ctx when it names none
+func (sim *flowSimulation) record(ctx context.Context, event models.FlowTraceEvent) {
+	if event.NodeID == "" {
+		if origin, ok := ctx.Value(messageOriginKey{}).(messageOrigin); ok {
+			event.NodeID = origin.nodeID
+		}
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	event.Step = len(sim.trace.Events) + 1
+	event.Turn = sim.turn
+	sim.trace.Events = append(sim.trace.Events, event)
+}
+
+// visit records the engine entering node
+func (sim *flowSimulation) visit(node *FlowNode) {
+	if sim == nil {
+		return
+	}
+	sim.record(context.Background(), models.FlowTraceEvent{
+		Kind:     models.FlowTraceNode,
+		NodeID:   node.ID,
+		NodeType: node.Type,
+		Label:    node.Label,
+	})
+}
+
+// wait sleeps for a delay node on live runs; a dry run records the delay and moves on
+func (sim *flowSimulation) wait(ctx context.Context, d time.Duration) error {
+	if sim == nil {
+		return sleepContext(ctx, d)
+	}
+	sim.record(ctx, models.FlowTraceEvent{
+		Kind:         models.FlowTraceDelay,
+		DelaySeconds: d.Seconds(),
+	})
+	return nil
+}
+
+// completionWebhook records the completion webhook the run would have posted
+func (sim *flowSimulation) completionWebhook(flow *models.ChatbotFlow, conversation interface{}) {
+	event := models.FlowTraceEvent{
+		Kind: models.FlowTraceWebhook,
+		URL:  getStringValue(flow.CompletionWebhookURL),
+	}
+	if payload, err := renderCompletionPayload(getStringValue(flow.CompletionWebhookTemplate), completionVariables(flow, conversation)); err == nil {
+		event.Payload = string(payload)
+	}
+	sim.record(context.Background(), event)
+}
+
+// emitEvent records an event the run would have sent to the owner's event webhooks
+func (sim *flowSimulation) emitEvent(ctx context.Context, event string, data map[string]interface{}) {
+	trace := models.FlowTraceEvent{
+		Kind:  models.FlowTraceOutboundEvent,
+		Event: event,
+	}
+	if payload, err := json.Marshal(data); err == nil {
+		trace.Payload = string(payload)
+	}
+	sim.record(ctx, trace)
+}
+
+// sendEmail records the email a send_email node would have sent
+func (sim *flowSimulation) sendEmail(ctx context.Context, email *OutgoingEmail) {
+	sim.record(ctx, models.FlowTraceEvent{
+		Kind:    models.FlowTraceEmail,
+		To:      strings.Join(email.To, ", "),
+		Subject: email.Subject,
+		Text:    email.Body,
+	})
+}
+
+// SendMessage records a message instead of sending it
+func (sim *flowSimulation) SendMessage(ctx context.Context, deviceID string, to string, message string, mediaType string, mediaURL string, mimeType ...string) error {
+	sim.record(ctx, models.FlowTraceEvent{
+		Kind:      models.FlowTraceMessage,
+		To:        to,
+		Text:      message,
+		MediaType: mediaType,
+		MediaURL:  mediaURL,
+	})
+	return nil
+}
+
+// SendRequest records a buttons, list, location or contact message as its text
+func (sim *flowSimulation) SendRequest(ctx context.Context, deviceID string, to string, message *models.SendMessageRequest) error {
+	sim.record(ctx, models.FlowTraceEvent{
+		Kind: models.FlowTraceMessage,
+		To:   to,
+		Text: message.PlainText(),
+	})
+	return nil
+}
+
+// UpdateConversation applies updates to the mock row, recording each column that changes
+func (sim *flowSimulation) UpdateConversation(ctx context.Context, conversationID string, updates map[string]interface{}) error {
+	columns := make([]string, 0, len(updates))
+	for column := range updates {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		value := simulationValue(updates[column])
+
+		sim.mu.Lock()
+		previous, existed := sim.row[column]
+		sim.row[column] = value
+		sim.mu.Unlock()
+
+		if existed && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		sim.record(ctx, models.FlowTraceEvent{
+			Kind:     models.FlowTraceVariable,
+			Variable: column,
+			From:     previous,
+			Value:    value,
+		})
+	}
+	return nil
+}
+
+// GetExecutionState reads the state columns of the mock row
+func (sim *flowSimulation) GetExecutionState(ctx context.Context, conversationID string) (*models.ExecutionState, error) {
+	var state models.ExecutionState
+	if err := sim.decode(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// decode fills a conversation model from the mock row
+func (sim *flowSimulation) decode(into interface{}) error {
+	sim.mu.Lock()
+	data, err := json.Marshal(sim.row)
+	sim.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode simulated conversation: %w", err)
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("failed to decode simulated conversation: %w", err)
+	}
+	return nil
+}
+
+func (sim *flowSimulation) state() models.ConversationState {
+	state, _ := sim.GetExecutionState(context.Background(), simulationConversationID)
+	return state.State()
+}
+
+// column returns a text column of the mock row, empty when unset
+func (sim *flowSimulation) column(name string) string {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	text, _ := sim.row[name].(string)
+	return text
+}
+
+// finish completes the trace with the final state of the mock row
+func (sim *flowSimulation) finish() *models.FlowSimulationTrace {
+	sim.trace.FinalState = string(sim.state())
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.trace.FinalVariables = make(map[string]interface{}, len(sim.row))
+	for column, value := range sim.row {
+		sim.trace.FinalVariables[column] = value
+	}
+	return sim.trace
+}
+
+// simulatedAIStore serves the mock row as an ai_whatsapp conversation
+type simulatedAIStore struct {
+	*flowSimulation
+}
+
+func (s simulatedAIStore) GetConversationByID(ctx context.Context, prospectID string) (*models.AIWhatsapp, error) {
+	var conversation models.AIWhatsapp
+	if err := s.decode(&conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// simulatedWasapbotStore serves the mock row as a wasapbot contact
+type simulatedWasapbotStore struct {
+	*flowSimulation
+}
+
+func (s simulatedWasapbotStore) GetConversationByID(ctx context.Context, prospectID string) (*models.Wasapbot, error) {
+	var conversation models.Wasapbot
+	if err := s.decode(&conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// maxFlowTriggers caps the trigger rules of one flow
+const maxFlowTriggers = 50
+
+// validateFlowTriggers checks a flow's trigger rules.
+// Returns an error message for the client, or "" when valid.
+func validateFlowTriggers(triggers []models.FlowTrigger) string {
+	if len(triggers) > maxFlowTriggers {
+		return fmt.Sprintf("A flow can have at most %d triggers", maxFlowTriggers)
+	}
+
+	for i, trigger := range triggers {
+		switch trigger.Type {
+		case models.FlowTriggerEquals, models.FlowTriggerContains:
+			if strings.TrimSpace(trigger.Value) == "" {
+				return fmt.Sprintf("triggers[%d]: %s trigger needs a keyword in value", i, trigger.Type)
+			}
+		case models.FlowTriggerRegex:
+			if strings.TrimSpace(trigger.Value) == "" {
+				return fmt.Sprintf("triggers[%d]: regex trigger needs a pattern in value", i)
+			}
+			if _, err := regexp.Compile(trigger.Value); err != nil {
+				return fmt.Sprintf("triggers[%d]: invalid regex: %v", i, err)
+			}
+		case models.FlowTriggerNewContact:
+		default:
+			return fmt.Sprintf("triggers[%d]: invalid type %q (use equals, contains, regex or new_contact)", i, trigger.Type)
+		}
+	}
+	return ""
+}
+
+// keywordTriggerMatches reports whether a keyword trigger matches the message. Keywords ignore
+// case and surrounding spaces; patterns are matched case-insensitively against the whole message.
+func keywordTriggerMatches(trigger models.FlowTrigger, message string) bool {
+	message = strings.TrimSpace(message)
+	keyword := strings.TrimSpace(trigger.Value)
+	if keyword == "" {
+		return false
+	}
+
+	switch trigger.Type {
+	case models.FlowTriggerEquals:
+		return strings.EqualFold(message, keyword)
+	case models.FlowTriggerContains:
+		return strings.Contains(strings.ToLower(message), strings.ToLower(keyword))
+	case models.FlowTriggerRegex:
+		re, err := regexp.Compile("(?i)" + trigger.Value)
+		return err == nil && re.MatchString(message)
+	}
+	return false
+}
+
+// hasTrigger reports whether any of the flow's triggers has the type
+func hasTrigger(flow *models.ChatbotFlow, triggerType string) bool {
+	for _, trigger := range flow.Triggers {
+		if trigger.Type == triggerType {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTriggeredFlow picks the device flow an inbound message starts from the active flows in
+// priority order: the first with a keyword trigger matching the message, then the first with a
+// new_contact trigger when the prospect never messaged the device, then the catch-all flow.
+// triggered is false for the catch-all; a device whose flows all have triggers falls back to its
+// highest priority flow so conversations already in a flow keep going.
+func (s *FlowProcessorService) selectTriggeredFlow(ctx context.Context, flows []models.ChatbotFlow, idDevice, phone, message string) (flow *models.ChatbotFlow, triggered bool) {
+	for i := range flows {
+		for _, trigger := range flows[i].Triggers {
+			if keywordTriggerMatches(trigger, message) {
+				log.Printf("🎯 Message matched %s trigger %q of flow %s", trigger.Type, trigger.Value, flows[i].Name)
+				return &flows[i], true
+			}
+		}
+	}
+
+	// The contact lookup only runs when a flow asks for new contacts
+	for i := range flows {
+		if !hasTrigger(&flows[i], models.FlowTriggerNewContact) {
+			continue
+		}
+		isNew, err := s.isNewContact(ctx, idDevice, phone)
+		if err != nil {
+			log.Printf("⚠️  Failed to check whether %s is a new contact: %v", phone, err)
+		} else if isNew {
+			log.Printf("🎯 New contact %s matched the new_contact trigger of flow %s", phone, flows[i].Name)
+			return &flows[i], true
+		}
+		break
+	}
+
+	return catchAllFlow(flows), false
+}
+
+// catchAllFlow returns the flow that receives messages no trigger matched: the first flow without
+// triggers, or the first flow when all have triggers. flows must not be empty.
+func catchAllFlow(flows []models.ChatbotFlow) *models.ChatbotFlow {
+	for i := range flows {
+		if len(flows[i].Triggers) == 0 {
+			return &flows[i]
+		}
+	}
+	return &flows[0]
+}
+
+// describeFlowTrigger explains a trigger rule in a flow doc
+func describeFlowTrigger(trigger models.FlowTrigger) string {
+	switch trigger.Type {
+	case models.FlowTriggerEquals:
+		return fmt.Sprintf("the message is %q", trigger.Value)
+	case models.FlowTriggerContains:
+		return fmt.Sprintf("the message contains %q", trigger.Value)
+	case models.FlowTriggerRegex:
+		return fmt.Sprintf("the message matches /%s/", trigger.Value)
+	case models.FlowTriggerNewContact:
+		return "a prospect messages the device for the first time"
+	}
+	return trigger.Type
+}
+
+// isNewContact reports whether the prospect has no conversation on the device in either table
+func (s *FlowProcessorService) isNewContact(ctx context.Context, idDevice, phone string) (bool, error) {
+	conversation, err := s.convRepo.GetConversationByProspectNum(ctx, phone, idDevice)
+	if err != nil {
+		return false, err
+	}
+	if conversation != nil {
+		return false, nil
+	}
+
+	contact, err := s.wasapbotRepo.GetConversationByProspectNum(ctx, phone, idDevice)
+	if err != nil {
+		return false, err
+	}
+	return contact == nil, nil
+}
+
+// switchToTriggeredFlow moves a conversation bound to another flow into the flow its message
+// triggered, from that flow's start. Conversations an agent owns or the loop guard stopped stay
+// where they are. Returns true when the conversation was moved.
+func (s *FlowProcessorService) switchToTriggeredFlow(
+	ctx context.Context,
+	stateMachine *ConversationStateMachine,
+	source, contactID string,
+	state models.ConversationState,
+	boundFlowID *string,
+	flow *models.ChatbotFlow,
+	entry flowEntry,
+) bool {
+	if boundFlowID == nil || *boundFlowID == "" || *boundFlowID == flow.ID {
+		return false
+	}
+	switch state {
+	case models.ConversationStateHandoff, models.ConversationStatePausedByAgent, models.ConversationStateFailedMaxSteps:
+		return false
+	}
+
+	updates := entry.nextEntryUpdates(time.Now())
+	updates["flow_id"] = flow.ID
+	updates["stage"] = nil
+	if err := stateMachine.UpdateState(ctx, contactID, models.ConversationStateActive, "", updates); err != nil {
+		log.Printf("⚠️  Failed to switch contact %s to triggered flow %s: %v", contactID, flow.ID, err)
+		return false
+	}
+
+	// Delay resumes and reply timeouts belong to the flow the prospect left
+	if s.delayRepo != nil {
+		if err := s.delayRepo.SkipPending(ctx, source, contactID); err != nil {
+			log.Printf("⚠️  Failed to drop pending resumes of contact %s: %v", contactID, err)
+		}
+	}
+
+	log.Printf("🎯 Switched contact %s from flow %s to triggered flow %s", contactID, *boundFlowID, flow.Name)
+	return true
+}
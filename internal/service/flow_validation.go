@@ -0,0 +1,470 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// flowPausingNodes are node types that stop the run until a reply or a timer, so a loop through
+// one of them repeats once per message instead of spinning until the loop guard stops it
+var flowPausingNodes = map[string]bool{
+	"delay":         true,
+	"waiting_reply": true,
+	"waiting_times": true,
+	"csat":          true,
+	"consent":       true,
+	"send_buttons":  true,
+	"send_list":     true,
+}
+
+// ValidateFlow reports the structural problems of a nodes_data document without saving anything
+func (s *FlowService) ValidateFlow(req *models.ValidateFlowRequest) *models.FlowValidationResponse {
+	issues := validateFlowData(req.NodesData)
+
+	resp := &models.FlowValidationResponse{
+		Success: true,
+		Issues:  issues,
+	}
+	for _, issue := range issues {
+		if issue.Severity == models.FlowIssueError {
+			resp.Errors++
+		} else {
+			resp.Warnings++
+		}
+	}
+	resp.Valid = resp.Errors == 0
+	resp.Message = fmt.Sprintf("%d errors, %d warnings", resp.Errors, resp.Warnings)
+	return resp
+}
+
+// flowValidationErrors returns a save rejection message when nodes_data has errors, or "" when it can be saved
+func flowValidationErrors(issues []models.FlowValidationIssue) string {
+	var messages []string
+	for _, issue := range issues {
+		if issue.Severity == models.FlowIssueError {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Flow has %d validation errors: %s", len(messages), strings.Join(messages, "; "))
+}
+
+// validateFlowData checks a nodes_data document: that it parses, has an entry node, every
+// connection joins existing nodes, messages have text, every node can be reached, conditions have
+// a default branch, loops pause for the prospect, and fork branches meet at a join without pausing
+func validateFlowData(nodesData string) []models.FlowValidationIssue {
+	issues := []models.FlowValidationIssue{}
+	add := func(severity, code, nodeID, message string) *models.FlowValidationIssue {
+		issues = append(issues, models.FlowValidationIssue{Severity: severity, Code: code, NodeID: nodeID, Message: message})
+		return &issues[len(issues)-1]
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(nodesData), &flowData); err != nil {
+		add(models.FlowIssueError, models.FlowIssueInvalidJSON, "", fmt.Sprintf("Flow data is not valid JSON: %v", err))
+		return issues
+	}
+	if len(flowData.Nodes) == 0 {
+		add(models.FlowIssueError, models.FlowIssueNoStartNode, "", "The flow has no nodes, so nothing is sent")
+		return issues
+	}
+
+	nodes := make(map[string]*FlowNode, len(flowData.Nodes))
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		if _, ok := nodes[node.ID]; ok {
+			add(models.FlowIssueError, models.FlowIssueDuplicateNode, node.ID, fmt.Sprintf("Node ID %q is used by more than one node", node.ID))
+			continue
+		}
+		nodes[node.ID] = node
+	}
+
+	// Only connections between existing nodes take part in the graph checks
+	outgoing := make(map[string][]FlowEdge, len(nodes))
+	incoming := make(map[string]int, len(nodes))
+	for _, edge := range flowData.Connections {
+		_, fromOK := nodes[edge.From]
+		_, toOK := nodes[edge.To]
+		if !fromOK || !toOK {
+			issue := add(models.FlowIssueError, models.FlowIssueDanglingEdge, "", fmt.Sprintf("Connection %s -> %s points at a node that does not exist", edge.From, edge.To))
+			issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+			continue
+		}
+		outgoing[edge.From] = append(outgoing[edge.From], edge)
+		incoming[edge.To]++
+	}
+
+	entry := flowEntryNode(&flowData)
+	if incoming[entry.ID] > 0 {
+		add(models.FlowIssueWarning, models.FlowIssueNoStartNode, entry.ID,
+			fmt.Sprintf("Every step has an incoming connection, so new prospects start at the first node %s", flowDocNodeName(entry)))
+	}
+
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		switch node.Type {
+		case "send_message":
+			text, _ := node.Config["text"].(string)
+			template, _ := node.Config["message_template"].(string)
+			if strings.TrimSpace(text) == "" && strings.TrimSpace(template) == "" {
+				add(models.FlowIssueError, models.FlowIssueEmptyMessage, node.ID, fmt.Sprintf("%s has no text or message template to send", flowDocNodeName(node)))
+			}
+		case "send_email":
+			if to, _ := node.Config["to"].(string); strings.TrimSpace(to) == "" {
+				add(models.FlowIssueError, models.FlowIssueEmptyMessage, node.ID, fmt.Sprintf("%s has no recipient", flowDocNodeName(node)))
+			}
+		case "conditions":
+			validateConditionEdges(node, outgoing[node.ID], add)
+		case flowABSplitNode:
+			validateABSplitEdges(node, outgoing[node.ID], add)
+		case flowBusinessHoursNode:
+			validateBusinessHoursNode(node, outgoing[node.ID], add)
+		case flowSetVariableNode:
+			validateSetVariable(node, add)
+		case flowSendButtonsNode, flowSendListNode:
+			validateInteractiveOptions(node, outgoing[node.ID], add)
+		case flowSendLocationNode:
+			// Placeholders are filled per prospect, so only literal coordinates are checked
+			lat, lng := configText(node.Config["latitude"]), configText(node.Config["longitude"])
+			if !strings.Contains(lat+lng, "{{") && nodeLocation(node, nil) == nil {
+				add(models.FlowIssueError, models.FlowIssueEmptyMessage, node.ID, fmt.Sprintf("%s has no valid latitude and longitude", flowDocNodeName(node)))
+			}
+		case flowSendContactNode:
+			if configText(node.Config["name"]) == "" || configText(node.Config["phone"]) == "" {
+				add(models.FlowIssueError, models.FlowIssueEmptyMessage, node.ID, fmt.Sprintf("%s needs a contact name and phone number", flowDocNodeName(node)))
+			}
+		case flowEmitEventNode:
+			if reason := validateEmitEventName(emitEventName(node)); reason != "" {
+				add(models.FlowIssueWarning, models.FlowIssueInvalidEvent, node.ID, fmt.Sprintf("%s sends nothing: %s", flowDocNodeName(node), reason))
+			}
+		}
+	}
+	validateForks(&flowData, nodes, outgoing, add)
+
+	// Orphans have no connections at all; other nodes the entry cannot reach are unreachable
+	reachable := flowReachable(entry.ID, outgoing)
+	for i := range flowData.Nodes {
+		node := &flowData.Nodes[i]
+		if len(nodes) > 1 && incoming[node.ID] == 0 && len(outgoing[node.ID]) == 0 {
+			if node.ID == entry.ID {
+				add(models.FlowIssueWarning, models.FlowIssueOrphanNode, node.ID,
+					fmt.Sprintf("%s is not connected to any step; new prospects start here and the flow ends after it", flowDocNodeName(node)))
+			} else {
+				add(models.FlowIssueWarning, models.FlowIssueOrphanNode, node.ID, fmt.Sprintf("%s is not connected to any step", flowDocNodeName(node)))
+			}
+			continue
+		}
+		if !reachable[node.ID] && !strings.Contains(strings.ToLower(node.Type), "start") {
+			add(models.FlowIssueWarning, models.FlowIssueUnreachableNode, node.ID,
+				fmt.Sprintf("%s is never reached: no connection leads here from the entry node", flowDocNodeName(node)))
+		}
+	}
+
+	for _, cycle := range flowCycles(flowData.Nodes, outgoing) {
+		pauses := false
+		for _, id := range cycle {
+			if flowPausingNodes[nodes[id].Type] {
+				pauses = true
+				break
+			}
+		}
+
+		loop := fmt.Sprintf("Steps %s form a loop", strings.Join(cycle, ", "))
+		if len(cycle) == 1 {
+			loop = fmt.Sprintf("Step %s connects to itself", cycle[0])
+		}
+
+		var issue *models.FlowValidationIssue
+		if pauses {
+			issue = add(models.FlowIssueWarning, models.FlowIssueCycle, cycle[0],
+				loop+"; prospects can go through it more than once")
+		} else {
+			issue = add(models.FlowIssueError, models.FlowIssueCycleWithoutPause, cycle[0],
+				loop+" with no waiting or delay step, so a run repeats it until the loop guard stops it")
+		}
+		issue.NodeIDs = cycle
+	}
+
+	return issues
+}
+
+// validateConditionEdges checks the branches of a conditions node the way findNextNode reads them
+func validateConditionEdges(node *FlowNode, edges []FlowEdge, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	if len(edges) < 2 {
+		return
+	}
+
+	hasDefault := false
+	for _, edge := range edges {
+		if strings.EqualFold(edge.ConditionType, "default") {
+			hasDefault = true
+			continue
+		}
+		if edge.ConditionType == "" || edge.ConditionValue == "" {
+			issue := add(models.FlowIssueWarning, models.FlowIssueIncompleteBranch, node.ID,
+				fmt.Sprintf("Branch %s -> %s has no condition type or value and is never matched", edge.From, edge.To))
+			issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+			continue
+		}
+		if reason := validateTextCondition(edge.ConditionType, edge.ConditionValue); reason != "" {
+			issue := add(models.FlowIssueWarning, models.FlowIssueIncompleteBranch, node.ID,
+				fmt.Sprintf("Branch %s -> %s is never matched: %s", edge.From, edge.To, reason))
+			issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+		}
+	}
+	if !hasDefault {
+		add(models.FlowIssueWarning, models.FlowIssueNoDefaultEdge, node.ID,
+			fmt.Sprintf("%s has no default branch; when no condition matches, a random branch is taken", flowDocNodeName(node)))
+	}
+}
+
+// validateABSplitEdges checks the branches of an ab_split node the way abSplitNext reads them
+func validateABSplitEdges(node *FlowNode, edges []FlowEdge, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	if len(edges) < 2 {
+		add(models.FlowIssueWarning, models.FlowIssueABSplit, node.ID,
+			fmt.Sprintf("%s has fewer than two branches, so every prospect gets the same one", flowDocNodeName(node)))
+		return
+	}
+
+	total := 0
+	for _, edge := range edges {
+		total += abSplitWeight(edge)
+	}
+	// Without any weight the split is even; otherwise a branch without one gets nobody
+	if total == 0 {
+		return
+	}
+	for _, edge := range edges {
+		if abSplitWeight(edge) == 0 {
+			issue := add(models.FlowIssueWarning, models.FlowIssueABSplit, node.ID,
+				fmt.Sprintf("Branch %s -> %s has no positive weight (%q), so no prospect takes it", edge.From, edge.To, edge.ConditionValue))
+			issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+		}
+	}
+}
+
+// validateInteractiveOptions checks the options of a send_buttons or send_list node against
+// WhatsApp's limits, and its button connections against the option ids
+func validateInteractiveOptions(node *FlowNode, edges []FlowEdge, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	message := interactiveMessage(node)
+	options := interactiveOptions(message)
+	if len(options) == 0 {
+		add(models.FlowIssueError, models.FlowIssueInvalidOptions, node.ID, fmt.Sprintf("%s has no options to choose from", flowDocNodeName(node)))
+		return
+	}
+	if text, _ := node.Config["text"].(string); strings.TrimSpace(text) == "" {
+		add(models.FlowIssueError, models.FlowIssueEmptyMessage, node.ID, fmt.Sprintf("%s has no text to send with its options", flowDocNodeName(node)))
+	}
+
+	maxOptions, maxTitle := models.MaxMessageButtons, models.MaxButtonTitleLength
+	if message.Type == models.MessageTypeList {
+		maxOptions, maxTitle = models.MaxListRows, models.MaxListRowTitleLength
+		if len([]rune(message.List.ButtonText)) > models.MaxListButtonLength {
+			add(models.FlowIssueWarning, models.FlowIssueInvalidOptions, node.ID,
+				fmt.Sprintf("%s's list button %q is longer than %d characters", flowDocNodeName(node), message.List.ButtonText, models.MaxListButtonLength))
+		}
+	}
+	if len(options) > maxOptions {
+		add(models.FlowIssueError, models.FlowIssueInvalidOptions, node.ID,
+			fmt.Sprintf("%s has %d options; WhatsApp allows at most %d", flowDocNodeName(node), len(options), maxOptions))
+	}
+
+	ids := make(map[string]bool, len(options))
+	for _, option := range options {
+		if len([]rune(option.Title)) > maxTitle {
+			add(models.FlowIssueWarning, models.FlowIssueInvalidOptions, node.ID,
+				fmt.Sprintf("%s's option %q is longer than %d characters and may be cut off", flowDocNodeName(node), option.Title, maxTitle))
+		}
+		id := strings.ToLower(option.ID)
+		if ids[id] {
+			add(models.FlowIssueError, models.FlowIssueInvalidOptions, node.ID,
+				fmt.Sprintf("%s has more than one option with id %q", flowDocNodeName(node), option.ID))
+		}
+		ids[id] = true
+	}
+
+	for _, edge := range edges {
+		if !strings.EqualFold(edge.ConditionType, ConditionButton) {
+			continue
+		}
+		for _, id := range strings.Split(edge.ConditionValue, ",") {
+			if id = strings.TrimSpace(id); id != "" && !ids[strings.ToLower(id)] {
+				issue := add(models.FlowIssueWarning, models.FlowIssueIncompleteBranch, node.ID,
+					fmt.Sprintf("Branch %s -> %s lists option %q, which %s does not have", edge.From, edge.To, id, flowDocNodeName(node)))
+				issue.EdgeFrom, issue.EdgeTo = edge.From, edge.To
+			}
+		}
+	}
+}
+
+// validateSetVariable checks the variables of a set_variable node the way setVariableProcessor reads them
+func validateSetVariable(node *FlowNode, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	assignments := setVariableAssignments(node)
+	if len(assignments) == 0 {
+		add(models.FlowIssueWarning, models.FlowIssueInvalidVariable, node.ID, fmt.Sprintf("%s sets no variables", flowDocNodeName(node)))
+		return
+	}
+	for _, assignment := range assignments {
+		if reason := validateVariableName(assignment.Name); reason != "" {
+			add(models.FlowIssueWarning, models.FlowIssueInvalidVariable, node.ID,
+				fmt.Sprintf("%s cannot set %q: %s", flowDocNodeName(node), assignment.Name, reason))
+		}
+	}
+}
+
+// validateForks checks fork and join nodes the way runFork reads them: a fork has several branches,
+// the branches do not pause or fork again before their join, and a join waits for branches it has
+func validateForks(flowData *FlowData, nodes map[string]*FlowNode, outgoing map[string][]FlowEdge, add func(severity, code, nodeID, message string) *models.FlowValidationIssue) {
+	branchStarts := make(map[string]map[string]bool) // join ID -> first nodes of the branches meeting there
+	for i := range flowData.Nodes {
+		fork := &flowData.Nodes[i]
+		if fork.Type != flowForkNode {
+			continue
+		}
+		if len(outgoing[fork.ID]) < 2 {
+			add(models.FlowIssueWarning, models.FlowIssueForkBranch, fork.ID,
+				fmt.Sprintf("%s has fewer than two branches, so nothing runs in parallel", flowDocNodeName(fork)))
+		}
+
+		// Walk the branches up to their join
+		seen := map[string]bool{fork.ID: true}
+		queue := []string{fork.ID}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, edge := range outgoing[id] {
+				node := nodes[edge.To]
+				if seen[node.ID] || node.Type == flowJoinNode {
+					continue
+				}
+				seen[node.ID] = true
+				queue = append(queue, node.ID)
+
+				if flowPausingNodes[node.Type] {
+					add(models.FlowIssueError, models.FlowIssueForkBranch, node.ID,
+						fmt.Sprintf("%s waits inside a branch of %s; parallel branches cannot pause the conversation", flowDocNodeName(node), flowDocNodeName(fork)))
+				} else if node.Type == flowForkNode {
+					add(models.FlowIssueError, models.FlowIssueForkBranch, node.ID,
+						fmt.Sprintf("%s is inside a branch of %s; forks cannot be nested", flowDocNodeName(node), flowDocNodeName(fork)))
+				}
+			}
+		}
+
+		if join := forkJoin(flowData, fork.ID); join != nil {
+			if branchStarts[join.ID] == nil {
+				branchStarts[join.ID] = make(map[string]bool)
+			}
+			for _, edge := range outgoing[fork.ID] {
+				branchStarts[join.ID][edge.To] = true
+			}
+		}
+	}
+
+	for i := range flowData.Nodes {
+		join := &flowData.Nodes[i]
+		if join.Type != flowJoinNode {
+			continue
+		}
+		starts, ok := branchStarts[join.ID]
+		if !ok {
+			add(models.FlowIssueWarning, models.FlowIssueForkBranch, join.ID,
+				fmt.Sprintf("%s does not follow a fork, so it has no branches to wait for", flowDocNodeName(join)))
+			continue
+		}
+		for _, id := range joinWaitFor(join) {
+			if !starts[id] {
+				add(models.FlowIssueWarning, models.FlowIssueForkBranch, join.ID,
+					fmt.Sprintf("%s waits for %s, which does not start a branch of its fork", flowDocNodeName(join), id))
+			}
+		}
+	}
+}
+
+// flowReachable returns the nodes reachable from the entry node, the entry included
+func flowReachable(entryID string, outgoing map[string][]FlowEdge) map[string]bool {
+	reachable := map[string]bool{entryID: true}
+	queue := []string{entryID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, edge := range outgoing[id] {
+			if !reachable[edge.To] {
+				reachable[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	return reachable
+}
+
+// flowCycles returns the loops of the flow graph as their node IDs in flow order: each strongly
+// connected component with more than one node, or a node connected to itself (Tarjan's algorithm)
+func flowCycles(flowNodes []FlowNode, outgoing map[string][]FlowEdge) [][]string {
+	index := make(map[string]int, len(flowNodes))
+	lowlink := make(map[string]int, len(flowNodes))
+	onStack := make(map[string]bool, len(flowNodes))
+	var stack []string
+	var components [][]string
+
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		selfLoop := false
+		for _, edge := range outgoing[id] {
+			if edge.To == id {
+				selfLoop = true
+			}
+			if _, visited := index[edge.To]; !visited {
+				connect(edge.To)
+				lowlink[id] = min(lowlink[id], lowlink[edge.To])
+			} else if onStack[edge.To] {
+				lowlink[id] = min(lowlink[id], index[edge.To])
+			}
+		}
+
+		if lowlink[id] != index[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range flowNodes {
+		if _, visited := index[node.ID]; !visited {
+			connect(node.ID)
+		}
+	}
+
+	// Report each loop's nodes in the order the flow lists them
+	order := make(map[string]int, len(flowNodes))
+	for i, node := range flowNodes {
+		if _, ok := order[node.ID]; !ok {
+			order[node.ID] = i
+		}
+	}
+	for _, component := range components {
+		sort.Slice(component, func(i, j int) bool { return order[component[i]] < order[component[j]] })
+	}
+	return components
+}
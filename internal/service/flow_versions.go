@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"chatbot-automation/internal/models"
+)
+
+// snapshotFlow stores the flow as it is now as its next version, before it changes.
+// userID is the user making the change, empty for admin jobs.
+func (s *FlowService) snapshotFlow(ctx context.Context, flow *models.ChatbotFlow, userID, reason string) error {
+	if s.versionRepo == nil {
+		return nil
+	}
+
+	// nodes and edges are derived from nodes_data, which the snapshot already holds
+	snapshot := *flow
+	snapshot.Nodes = nil
+	snapshot.Edges = nil
+
+	version := &models.FlowVersion{
+		FlowID:    flow.ID,
+		Name:      flow.Name,
+		NodeCount: flowNodeCount(flow.NodesData),
+		Reason:    reason,
+		Snapshot:  &snapshot,
+	}
+	if userID != "" {
+		version.CreatedBy = &userID
+	}
+	return s.versionRepo.RecordVersion(ctx, version)
+}
+
+// flowNodeCount counts the nodes of a nodes_data document, 0 when it cannot be parsed
+func flowNodeCount(nodesData string) int {
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(nodesData), &flowData); err != nil {
+		return 0
+	}
+	return len(flowData.Nodes)
+}
+
+// ListFlowVersions returns the stored versions of a flow the user owns, newest first
+func (s *FlowService) ListFlowVersions(ctx context.Context, userID, flowID string) (*models.FlowVersionsResponse, error) {
+	// GetFlow verifies ownership
+	resp, err := s.GetFlow(ctx, userID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return &models.FlowVersionsResponse{Success: false, Message: resp.Message}, nil
+	}
+
+	versions, err := s.versionRepo.ListVersions(ctx, resp.Flow.ID)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []models.FlowVersion{}
+	}
+
+	return &models.FlowVersionsResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d versions", len(versions)),
+		Versions: versions,
+	}, nil
+}
+
+// RestoreFlowVersion puts a stored version of a flow back in place. The flow as it was before the
+// restore is stored as a new version first, so the restore can be rolled back as well.
+func (s *FlowService) RestoreFlowVersion(ctx context.Context, userID, flowID string, version int) (*models.FlowResponse, error) {
+	// getFlow verifies access
+	resp, err := s.getFlow(ctx, userID, flowID, models.TeamPermissionEditFlows)
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+
+	stored, err := s.versionRepo.GetVersion(ctx, resp.Flow.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.Snapshot == nil {
+		return &models.FlowResponse{
+			Success: false,
+			Message: fmt.Sprintf("Version %d not found", version),
+		}, nil
+	}
+
+	restored, err := s.updateFlow(ctx, userID, resp.Flow.ID, restoreFlowRequest(stored.Snapshot), models.FlowVersionReasonRestore)
+	if err != nil || !restored.Success {
+		return restored, err
+	}
+
+	restored.Message = fmt.Sprintf("Flow restored to version %d", version)
+	return restored, nil
+}
+
+// restoreFlowRequest is the update that gives a flow the definition and settings of a snapshot
+func restoreFlowRequest(snapshot *models.ChatbotFlow) *models.UpdateFlowRequest {
+	policy := snapshot.EffectiveCompletionPolicy()
+	webhookURL := getStringValue(snapshot.CompletionWebhookURL)
+	webhookTemplate := getStringValue(snapshot.CompletionWebhookTemplate)
+
+	req := &models.UpdateFlowRequest{
+		FlowName:                  &snapshot.Name,
+		Niche:                     &snapshot.Niche,
+		NodesData:                 &snapshot.NodesData,
+		CompletionPolicy:          &policy,
+		CompletionWebhookURL:      &webhookURL,
+		CompletionWebhookTemplate: &webhookTemplate,
+		ReentryCooldownHours:      &snapshot.ReentryCooldownHours,
+		NormalizeMalay:            &snapshot.NormalizeMalay,
+		Triggers:                  &snapshot.Triggers,
+	}
+	// A flow without an after-sales link keeps its current one; the policy decides whether it is used
+	if snapshot.AfterSalesFlowID != nil && *snapshot.AfterSalesFlowID != "" {
+		req.AfterSalesFlowID = snapshot.AfterSalesFlowID
+	}
+	return req
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// googleSheetsAPI is the Sheets v4 values endpoint of a spreadsheet
+const googleSheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets"
+
+// googleSheetsScope is the OAuth scope sheet exports need
+const googleSheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// googleServiceAccount is the part of a service account JSON key the client uses
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// GoogleSheetsClient writes rows to Google Sheets as a service account. Users share their sheet
+// with the service account's email as editor. A nil *GoogleSheetsClient is disabled.
+type GoogleSheetsClient struct {
+	account    googleServiceAccount
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewGoogleSheetsClient loads the service account JSON key at path; an empty path disables Sheets
+func NewGoogleSheetsClient(path string) (*GoogleSheetsClient, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google service account key: %w", err)
+	}
+	var account googleServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse Google service account key: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("google service account key %s has no client_email or private_key", path)
+	}
+	if _, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey)); err != nil {
+		return nil, fmt.Errorf("invalid Google service account private key: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &GoogleSheetsClient{
+		account:    account,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Enabled reports whether a service account is configured
+func (c *GoogleSheetsClient) Enabled() bool {
+	return c != nil
+}
+
+// ServiceAccountEmail is the email users share their sheets with
+func (c *GoogleSheetsClient) ServiceAccountEmail() string {
+	if c == nil {
+		return ""
+	}
+	return c.account.ClientEmail
+}
+
+// AppendRows adds rows after the last row of the sheet's table
+func (c *GoogleSheetsClient) AppendRows(ctx context.Context, spreadsheetID, sheet string, rows [][]string) error {
+	endpoint := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+		googleSheetsAPI, url.PathEscape(spreadsheetID), url.PathEscape(sheetRange(sheet)))
+	return c.call(ctx, http.MethodPost, endpoint, map[string]interface{}{"values": rows})
+}
+
+// ReplaceRows clears the sheet and writes rows from A1
+func (c *GoogleSheetsClient) ReplaceRows(ctx context.Context, spreadsheetID, sheet string, rows [][]string) error {
+	clearURL := fmt.Sprintf("%s/%s/values/%s:clear", googleSheetsAPI, url.PathEscape(spreadsheetID), url.PathEscape(sheetRange(sheet)))
+	if err := c.call(ctx, http.MethodPost, clearURL, map[string]interface{}{}); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/values/%s?valueInputOption=RAW",
+		googleSheetsAPI, url.PathEscape(spreadsheetID), url.PathEscape(sheetRange(sheet)))
+	return c.call(ctx, http.MethodPut, endpoint, map[string]interface{}{"values": rows})
+}
+
+// sheetRange is the A1 range of a whole sheet (tab): its name quoted
+func sheetRange(sheet string) string {
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+}
+
+// call sends one Sheets API request; errors carry Google's message, such as a sheet not shared
+// with the service account
+func (c *GoogleSheetsClient) call(ctx context.Context, method, endpoint string, body interface{}) error {
+	if c == nil {
+		return fmt.Errorf("google sheets is not configured")
+	}
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("google sheets request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("google sheets returned %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("google sheets returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// accessToken returns a cached OAuth token, exchanging a signed service account JWT for a new one
+// shortly before the old one expires
+func (c *GoogleSheetsClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.tokenExpiry.Add(-time.Minute)) {
+		return c.token, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(c.account.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid Google service account private key: %w", err)
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.account.ClientEmail,
+		"scope": googleSheetsScope,
+		"aud":   c.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Google token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("google token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("google token request returned %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to parse Google token response: %v", err)
+	}
+
+	c.token = token.AccessToken
+	c.tokenExpiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.token, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// Humanized sending is applied to every send here rather than by flow nodes: the send queue
+// spaces consecutive messages to a prospect by the device's send delay, bot messages wait their
+// typing time (showing typing where the provider can), and bot messages made during quiet hours
+// are held in queued_sends until the quiet hours end.
+
+type queuedReleaseKey struct{}
+
+// withQueuedRelease marks sends made with ctx as releases of held messages, which are past the
+// quiet hours they waited for
+func withQueuedRelease(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queuedReleaseKey{}, true)
+}
+
+// validateTypingSpeed checks a device's typing_chars_per_second (nil or 0 = default).
+// Returns an error message for the client, or "" when valid.
+func validateTypingSpeed(speed *int) string {
+	if speed != nil && (*speed < 0 || *speed > models.MaxTypingCharsPerSecond) {
+		return fmt.Sprintf("typing_chars_per_second must be between 1 and %d (0 uses the default of %d)", models.MaxTypingCharsPerSecond, models.DefaultTypingCharsPerSecond)
+	}
+	return ""
+}
+
+// validateSendDelay checks a device's send_delay_min_ms and send_delay_max_ms (nil or 0 = default).
+// Returns an error message for the client, or "" when valid.
+func validateSendDelay(minMs, maxMs *int) string {
+	for _, delay := range []*int{minMs, maxMs} {
+		if delay != nil && (*delay < 0 || *delay > models.MaxSendDelayMs) {
+			return fmt.Sprintf("send_delay_min_ms and send_delay_max_ms must be between 1 and %d (0 uses the defaults of %d and %d)", models.MaxSendDelayMs, models.DefaultSendDelayMinMs, models.DefaultSendDelayMaxMs)
+		}
+	}
+	if minMs != nil && maxMs != nil && *minMs > 0 && *maxMs > 0 && *minMs > *maxMs {
+		return "send_delay_min_ms must not be greater than send_delay_max_ms"
+	}
+	return ""
+}
+
+// validateQuietHours checks a device's quiet_hours_start and quiet_hours_end: both HH:MM and
+// different, or both empty to remove them. Returns an error message for the client, or "" when valid.
+func validateQuietHours(start, end string) string {
+	if start == "" && end == "" {
+		return ""
+	}
+	from, ok1 := models.ParseClockMinutes(start)
+	to, ok2 := models.ParseClockMinutes(end)
+	if !ok1 || !ok2 {
+		return "quiet_hours_start and quiet_hours_end must both be HH:MM"
+	}
+	if from == to {
+		return "quiet_hours_start and quiet_hours_end must differ"
+	}
+	return ""
+}
+
+// holdForQuietHours queues a bot message made during the device's quiet hours, or answering a
+// message that arrived while the device was closed in queue mode, and reports true so the caller
+// does not send it now. Human replies, sandboxed devices and released messages are never held.
+func (s *WhatsAppService) holdForQuietHours(ctx context.Context, device *models.DeviceSetting, deviceID string, req *models.SendMessageRequest) (bool, error) {
+	if s.queuedSendRepo == nil || device.Sandbox || ctx.Value(queuedReleaseKey{}) != nil {
+		return false, nil
+	}
+	if responder, _ := replyResponder(ctx); responder != models.ResponderBot {
+		return false, nil
+	}
+	until, quiet := device.QuietUntil(time.Now())
+	if opening, ok := ctx.Value(holdUntilKey{}).(time.Time); ok && opening.After(until) {
+		until, quiet = opening, true
+	}
+	if !quiet {
+		return false, nil
+	}
+
+	queued := &models.QueuedSend{
+		DeviceID:    deviceID,
+		IDDevice:    getStringValue(device.IDDevice),
+		ProspectNum: req.To,
+		Message:     req,
+		SendAt:      until,
+	}
+	if origin, ok := ctx.Value(messageOriginKey{}).(messageOrigin); ok {
+		queued.FlowID = &origin.flowID
+		queued.NodeID = &origin.nodeID
+	}
+	if err := s.queuedSendRepo.QueueSend(ctx, queued); err != nil {
+		return true, err
+	}
+
+	log.Printf("🌙 Device %s is quiet or closed: message to %s queued until %s", deviceID, req.To, until.Format(time.RFC3339))
+	return true, nil
+}
+
+// typeMessage holds a bot message for as long as it takes to type at the device's typing speed,
+// when the device paces typing. With typing_indicator on it first marks the chat read and shows
+// typing where the provider can; presence is best effort, so failures are only logged.
+func (s *WhatsAppService) typeMessage(ctx context.Context, device *models.DeviceSetting, provider whatsapp.Provider, req *models.SendMessageRequest) {
+	if device.Sandbox || !device.PacesTyping() {
+		return
+	}
+	if responder, _ := replyResponder(ctx); responder != models.ResponderBot {
+		return
+	}
+	duration := models.TypingDuration(req.PlainText(), device.EffectiveTypingCharsPerSecond())
+
+	presence, ok := whatsapp.AsPresenceManager(provider)
+	if !device.TypingIndicator || !ok {
+		sleepContext(ctx, duration)
+		return
+	}
+
+	callCtx, cancel := s.deadlines.externalCallContext(ctx)
+	if err := presence.MarkSeen(callCtx, req.To); err != nil {
+		log.Printf("⚠️  Failed to mark chat %s seen: %v", req.To, err)
+	}
+	if err := presence.SendTyping(callCtx, req.To, true); err != nil {
+		log.Printf("⚠️  Failed to show typing to %s: %v", req.To, err)
+	}
+	cancel()
+
+	if err := sleepContext(ctx, duration); err != nil {
+		return
+	}
+
+	callCtx, cancel = s.deadlines.externalCallContext(ctx)
+	defer cancel()
+	if err := presence.SendTyping(callCtx, req.To, false); err != nil {
+		log.Printf("⚠️  Failed to clear typing to %s: %v", req.To, err)
+	}
+}
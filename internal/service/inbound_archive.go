@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// inboundArchivePurgeBatch is how many expired archives the retention purge deletes per round
+const inboundArchivePurgeBatch = 500
+
+// validateArchiveRetention checks a device's archive_retention_days (nil or 0 = default).
+// Returns an error message for the client, or "" when valid.
+func validateArchiveRetention(days *int) string {
+	if days != nil && (*days < 0 || *days > models.MaxArchiveRetentionDays) {
+		return fmt.Sprintf("archive_retention_days must be between 1 and %d (0 uses the default of %d)", models.MaxArchiveRetentionDays, models.DefaultArchiveRetentionDays)
+	}
+	return ""
+}
+
+// InboundArchiver keeps the raw payload of every accepted inbound webhook of devices with
+// archive_inbound on, gzipped in storage, independent of the processed conversation history
+type InboundArchiver struct {
+	archiveRepo *repository.InboundArchiveRepository
+	deviceRepo  *repository.DeviceRepository
+}
+
+// NewInboundArchiver creates a new inbound payload archiver
+func NewInboundArchiver(archiveRepo *repository.InboundArchiveRepository, deviceRepo *repository.DeviceRepository) *InboundArchiver {
+	return &InboundArchiver{
+		archiveRepo: archiveRepo,
+		deviceRepo:  deviceRepo,
+	}
+}
+
+// archiveDeviceKey is the id_device payloads are archived under, or the row ID for devices without one
+func archiveDeviceKey(device *models.DeviceSetting) string {
+	if idDevice := getStringValue(device.IDDevice); idDevice != "" {
+		return idDevice
+	}
+	return device.ID
+}
+
+// Archive stores a webhook payload when the device has archive_inbound on. A nil archiver or
+// device archives nothing; failures are only logged so archival never holds up a message.
+func (a *InboundArchiver) Archive(ctx context.Context, device *models.DeviceSetting, provider, contentType string, payload []byte, receivedAt time.Time) {
+	if a == nil || device == nil || !device.ArchiveInbound || len(payload) == 0 {
+		return
+	}
+
+	idDevice := archiveDeviceKey(device)
+	if err := a.archive(ctx, device, idDevice, provider, contentType, payload, receivedAt); err != nil {
+		log.Printf("⚠️  Failed to archive inbound payload for device %s: %v", idDevice, err)
+	}
+}
+
+// ArchiveForDevice archives a payload received on a route that names the device by id_device
+func (a *InboundArchiver) ArchiveForDevice(ctx context.Context, idDevice, provider, contentType string, payload []byte, receivedAt time.Time) {
+	if a == nil || idDevice == "" || len(payload) == 0 {
+		return
+	}
+
+	device, err := a.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err != nil || device == nil {
+		return
+	}
+	a.Archive(ctx, device, provider, contentType, payload, receivedAt)
+}
+
+// archive compresses a payload, uploads it partitioned by device and UTC date, and indexes it
+func (a *InboundArchiver) archive(ctx context.Context, device *models.DeviceSetting, idDevice, provider, contentType string, payload []byte, receivedAt time.Time) error {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(payload); err != nil {
+		return fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	receivedAt = receivedAt.UTC()
+	path := fmt.Sprintf("%s/%s/%s-%s.gz", idDevice, receivedAt.Format("2006-01-02"), receivedAt.Format("150405.000000000"), uuid.NewString())
+	if err := a.archiveRepo.UploadPayload(ctx, path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	return a.archiveRepo.CreateArchive(ctx, &models.InboundArchive{
+		UserID:      getStringValue(device.UserID),
+		IDDevice:    idDevice,
+		Provider:    provider,
+		ObjectPath:  path,
+		ContentType: contentType,
+		SizeBytes:   len(payload),
+		StoredBytes: buf.Len(),
+		ReceivedAt:  receivedAt,
+		ExpiresAt:   receivedAt.AddDate(0, 0, device.EffectiveArchiveRetentionDays()),
+	})
+}
+
+// ownedDevice returns a device the user owns, or a message saying why not
+func (a *InboundArchiver) ownedDevice(ctx context.Context, userID, deviceID string) (*models.DeviceSetting, string) {
+	device, err := a.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, "Device not found"
+	}
+	if device.UserID == nil || *device.UserID != userID {
+		return nil, "Access denied"
+	}
+	return device, ""
+}
+
+// ListArchives lists a device's archived payloads received between from and to (dates or RFC 3339
+// times; a date to includes the whole day), newest first
+func (a *InboundArchiver) ListArchives(ctx context.Context, userID, deviceID, from, to string, limit int) (*models.InboundArchiveResponse, error) {
+	device, msg := a.ownedDevice(ctx, userID, deviceID)
+	if device == nil {
+		return &models.InboundArchiveResponse{Success: false, Message: msg}, nil
+	}
+
+	fromTime, ok := parseCleanupBefore(from)
+	if !ok {
+		return &models.InboundArchiveResponse{Success: false, Message: "from must be a date (YYYY-MM-DD) or an RFC 3339 time"}, nil
+	}
+	toTime, ok := parseCleanupBefore(to)
+	if !ok {
+		return &models.InboundArchiveResponse{Success: false, Message: "to must be a date (YYYY-MM-DD) or an RFC 3339 time"}, nil
+	}
+	if toTime != nil && !strings.Contains(to, "T") {
+		// A date includes the whole day
+		end := toTime.Add(24 * time.Hour)
+		toTime = &end
+	}
+
+	if limit <= 0 {
+		limit = models.DefaultInboundArchiveLimit
+	}
+	if limit > models.MaxInboundArchiveLimit {
+		limit = models.MaxInboundArchiveLimit
+	}
+
+	archives, err := a.archiveRepo.GetArchives(ctx, archiveDeviceKey(device), fromTime, toTime, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InboundArchiveResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d archived payloads", len(archives)),
+		Archives: archives,
+	}, nil
+}
+
+// GetArchive returns one archived payload of a device, decompressed
+func (a *InboundArchiver) GetArchive(ctx context.Context, userID, deviceID, archiveID string) (*models.InboundArchiveResponse, error) {
+	device, msg := a.ownedDevice(ctx, userID, deviceID)
+	if device == nil {
+		return &models.InboundArchiveResponse{Success: false, Message: msg}, nil
+	}
+
+	archive, err := a.archiveRepo.GetArchiveByID(ctx, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil || archive.IDDevice != archiveDeviceKey(device) {
+		return &models.InboundArchiveResponse{Success: false, Message: "Archived payload not found"}, nil
+	}
+
+	data, err := a.archiveRepo.DownloadPayload(ctx, archive.ObjectPath)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress inbound payload: %w", err)
+	}
+	defer reader.Close()
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress inbound payload: %w", err)
+	}
+
+	return &models.InboundArchiveResponse{
+		Success: true,
+		Archive: archive,
+		Payload: string(payload),
+	}, nil
+}
+
+// Start purges expired payloads immediately and then every interval until ctx is cancelled
+func (a *InboundArchiver) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := a.PurgeExpired(ctx); err != nil {
+				log.Printf("⚠️  Inbound archive purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("🧹 Purged %d expired inbound payload(s)", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeExpired deletes every payload past its retention and returns how many were deleted
+func (a *InboundArchiver) PurgeExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	total := 0
+	for {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		archives, err := a.archiveRepo.GetExpiredArchives(ctx, now, inboundArchivePurgeBatch)
+		if err != nil {
+			return total, err
+		}
+		if len(archives) == 0 {
+			return total, nil
+		}
+
+		if err := a.archiveRepo.DeleteArchives(ctx, archives); err != nil {
+			return total, err
+		}
+		total += len(archives)
+
+		if len(archives) < inboundArchivePurgeBatch {
+			return total, nil
+		}
+	}
+}
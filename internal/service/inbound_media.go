@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// ConditionMedia is the attachment condition for conditions node edges. It matches when the
+// inbound message carries media of one of the listed types:
+//
+//	media  "image"           a photo
+//	media  "voice,audio"     a voice note or an audio file
+//	media  "any"             any image, voice note, audio, video, document or sticker
+//	media  "location"        a shared location pin
+const ConditionMedia = "media"
+
+type inboundMediaKey struct{}
+
+// withInboundMedia marks ctx as handling a message that carries media
+func withInboundMedia(ctx context.Context, media *models.InboundMedia) context.Context {
+	return context.WithValue(ctx, inboundMediaKey{}, media)
+}
+
+// inboundMediaFrom returns the media of the message being handled, nil for plain text
+func inboundMediaFrom(ctx context.Context) *models.InboundMedia {
+	media, _ := ctx.Value(inboundMediaKey{}).(*models.InboundMedia)
+	return media
+}
+
+// evaluateMediaCondition checks a media condition against the message being handled
+func evaluateMediaCondition(ctx context.Context, value string) bool {
+	media := inboundMediaFrom(ctx)
+	location := inboundLocationFrom(ctx)
+	if media == nil && location == nil {
+		return false
+	}
+
+	for _, mediaType := range strings.Split(value, ",") {
+		mediaType = strings.TrimSpace(mediaType)
+		if location != nil && strings.EqualFold(mediaType, models.MessageTypeLocation) {
+			return true
+		}
+		if media != nil && (strings.EqualFold(mediaType, "any") || strings.EqualFold(mediaType, media.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inboundMediaPrompt tells an ai_prompt node what the prospect attached, so the reply can react to
+// it (asking about a photo, acknowledging a voice note) instead of only seeing its placeholder
+func inboundMediaPrompt(media *models.InboundMedia) string {
+	description := fmt.Sprintf("[The prospect sent %s", flowDocArticle(media.Type))
+	if media.Type == models.MediaVoice {
+		description = "[The prospect sent a voice note"
+	}
+	if media.Filename != "" {
+		description += fmt.Sprintf(" named %q", media.Filename)
+	}
+	if media.MimeType != "" {
+		description += fmt.Sprintf(" (%s)", media.MimeType)
+	}
+	if media.URL != "" {
+		description += ": " + media.URL
+	}
+	return description + "]"
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// inboxLess orders pinned conversations first, then by descending priority.
+// Ties keep their existing (recency) order because callers sort stably.
+func inboxLess(pinnedA bool, priorityA int, pinnedB bool, priorityB int) bool {
+	if pinnedA != pinnedB {
+		return pinnedA
+	}
+	return priorityA > priorityB
+}
+
+// sortConversationsForInbox re-sorts a list merged from several devices
+func sortConversationsForInbox(conversations []models.AIWhatsapp) {
+	sort.SliceStable(conversations, func(i, j int) bool {
+		pinnedA, priorityA := conversations[i].InboxRank()
+		pinnedB, priorityB := conversations[j].InboxRank()
+		return inboxLess(pinnedA, priorityA, pinnedB, priorityB)
+	})
+}
+
+// sortWasapbotForInbox re-sorts a list merged from several devices
+func sortWasapbotForInbox(conversations []models.Wasapbot) {
+	sort.SliceStable(conversations, func(i, j int) bool {
+		pinnedA, priorityA := conversations[i].InboxRank()
+		pinnedB, priorityB := conversations[j].InboxRank()
+		return inboxLess(pinnedA, priorityA, pinnedB, priorityB)
+	})
+}
+
+// userDeviceIDs returns the id_device (or device_id) of every device the user owns
+func userDeviceIDs(ctx context.Context, deviceRepo *repository.DeviceRepository, userID string) ([]string, error) {
+	devices, err := deviceRepo.GetDevicesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceIDs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		if device.IDDevice != nil && *device.IDDevice != "" {
+			deviceIDs = append(deviceIDs, *device.IDDevice)
+		} else if device.DeviceID != nil && *device.DeviceID != "" {
+			deviceIDs = append(deviceIDs, *device.DeviceID)
+		}
+	}
+
+	return deviceIDs, nil
+}
@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// stickyNotesShown caps how many earlier notes go into a returning prospect's agent notification
+const stickyNotesShown = 3
+
+// InboxAgentService manages the user's agents and the history of which agent took over which
+// prospect, so a prospect who comes back after a handoff is routed to the same agent
+type InboxAgentService struct {
+	agentRepo     *repository.InboxAgentRepository
+	deviceRepo    *repository.DeviceRepository
+	convRepo      *repository.ConversationRepository
+	wasapbotRepo  *repository.WasapbotRepository
+	sender        FlowMessageSender // agent notifications go out from the prospect's device
+	aiState       *ConversationStateMachine
+	wasapbotState *ConversationStateMachine
+}
+
+// NewInboxAgentService creates a new inbox agent service
+func NewInboxAgentService(
+	agentRepo *repository.InboxAgentRepository,
+	deviceRepo *repository.DeviceRepository,
+	convRepo *repository.ConversationRepository,
+	wasapbotRepo *repository.WasapbotRepository,
+	whatsappService *WhatsAppService,
+) *InboxAgentService {
+	return &InboxAgentService{
+		agentRepo:     agentRepo,
+		deviceRepo:    deviceRepo,
+		convRepo:      convRepo,
+		wasapbotRepo:  wasapbotRepo,
+		sender:        whatsappService,
+		aiState:       NewConversationStateMachine(convRepo),
+		wasapbotState: NewConversationStateMachine(wasapbotRepo),
+	}
+}
+
+// ListAgents returns the user's agents
+func (s *InboxAgentService) ListAgents(ctx context.Context, userID string) (*models.InboxAgentResponse, error) {
+	agents, err := s.agentRepo.GetAgentsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if agents == nil {
+		agents = []models.InboxAgent{}
+	}
+
+	return &models.InboxAgentResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d agents", len(agents)),
+		Agents:  agents,
+	}, nil
+}
+
+// CreateAgent adds an agent to the user's team
+func (s *InboxAgentService) CreateAgent(ctx context.Context, userID string, req *models.CreateInboxAgentRequest) (*models.InboxAgentResponse, error) {
+	agent := &models.InboxAgent{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Phone:     req.Phone,
+		Available: req.Available == nil || *req.Available,
+	}
+
+	if msg := validateInboxAgent(agent); msg != "" {
+		return &models.InboxAgentResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.agentRepo.CreateAgent(ctx, agent); err != nil {
+		return nil, err
+	}
+
+	return &models.InboxAgentResponse{
+		Success: true,
+		Message: "Agent created successfully",
+		Agent:   agent,
+	}, nil
+}
+
+// UpdateAgent changes an agent's name, phone or availability
+func (s *InboxAgentService) UpdateAgent(ctx context.Context, userID, agentID string, req *models.UpdateInboxAgentRequest) (*models.InboxAgentResponse, error) {
+	agent, msg, err := s.ownedAgent(ctx, userID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return &models.InboxAgentResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		agent.Name = strings.TrimSpace(*req.Name)
+		updates["name"] = agent.Name
+	}
+	if req.Phone != nil {
+		agent.Phone = req.Phone
+		updates["phone"] = *req.Phone
+	}
+	if req.Available != nil {
+		agent.Available = *req.Available
+		updates["available"] = *req.Available
+	}
+
+	if len(updates) == 0 {
+		return &models.InboxAgentResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := validateInboxAgent(agent); msg != "" {
+		return &models.InboxAgentResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.agentRepo.UpdateAgent(ctx, agent.ID, updates); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.agentRepo.GetAgentByID(ctx, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InboxAgentResponse{
+		Success: true,
+		Message: "Agent updated successfully",
+		Agent:   updated,
+	}, nil
+}
+
+// DeleteAgent removes an agent. Their assignments stay in the history, and prospects they
+// handled are no longer routed to anyone.
+func (s *InboxAgentService) DeleteAgent(ctx context.Context, userID, agentID string) (*models.InboxAgentResponse, error) {
+	agent, msg, err := s.ownedAgent(ctx, userID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return &models.InboxAgentResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.agentRepo.DeleteAgent(ctx, agent.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.InboxAgentResponse{
+		Success: true,
+		Message: "Agent deleted successfully",
+	}, nil
+}
+
+// AssignAgent hands a conversation to one of the user's agents and puts it in handoff, so the
+// bot stays quiet. An earlier open assignment of the conversation is released.
+func (s *InboxAgentService) AssignAgent(ctx context.Context, userID string, req *models.AssignAgentRequest) (*models.AgentAssignmentResponse, error) {
+	source := req.Source
+	if source == "" {
+		source = models.AssignmentSourceAI
+	}
+
+	conv, msg := s.ownedConversation(ctx, userID, source, req.ConversationID)
+	if conv == nil {
+		return &models.AgentAssignmentResponse{Success: false, Message: msg}, nil
+	}
+
+	agent, msg, err := s.ownedAgent(ctx, userID, req.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return &models.AgentAssignmentResponse{Success: false, Message: msg}, nil
+	}
+
+	assignment, err := s.assign(ctx, userID, agent, conv, req.Notes, false)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.agentRepo.GetAssignmentHistory(ctx, conv.idDevice, conv.prospectNum, models.MaxAssignmentHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AgentAssignmentResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("Conversation assigned to %s", agent.Name),
+		Assignment:  assignment,
+		Assignments: history,
+	}, nil
+}
+
+// UpdateAssignment records an agent's notes on an assignment, or releases it
+func (s *InboxAgentService) UpdateAssignment(ctx context.Context, userID, assignmentID string, req *models.UpdateAssignmentRequest) (*models.AgentAssignmentResponse, error) {
+	assignment, err := s.agentRepo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		return &models.AgentAssignmentResponse{Success: false, Message: "Assignment not found"}, nil
+	}
+	if assignment.UserID != userID {
+		return &models.AgentAssignmentResponse{Success: false, Message: "Access denied"}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
+	if req.Release {
+		if assignment.ReleasedAt != nil {
+			return &models.AgentAssignmentResponse{Success: false, Message: "Assignment was already released"}, nil
+		}
+		updates["released_at"] = time.Now()
+	}
+
+	if len(updates) == 0 {
+		return &models.AgentAssignmentResponse{Success: false, Message: "No fields to update"}, nil
+	}
+
+	if err := s.agentRepo.UpdateAssignment(ctx, assignment.ID, updates); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.agentRepo.GetAssignmentByID(ctx, assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AgentAssignmentResponse{
+		Success:    true,
+		Message:    "Assignment updated successfully",
+		Assignment: updated,
+	}, nil
+}
+
+// GetAssignmentHistory returns a conversation's prospect assignments on its device, latest first,
+// earlier conversations with the same number included
+func (s *InboxAgentService) GetAssignmentHistory(ctx context.Context, userID, source, conversationID string) (*models.AgentAssignmentResponse, error) {
+	if source == "" {
+		source = models.AssignmentSourceAI
+	}
+
+	conv, msg := s.ownedConversation(ctx, userID, source, conversationID)
+	if conv == nil {
+		return &models.AgentAssignmentResponse{Success: false, Message: msg}, nil
+	}
+
+	history, err := s.agentRepo.GetAssignmentHistory(ctx, conv.idDevice, conv.prospectNum, models.MaxAssignmentHistory)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.AgentAssignment{}
+	}
+
+	return &models.AgentAssignmentResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("Found %d assignments", len(history)),
+		Assignments: history,
+	}, nil
+}
+
+// RouteReturningProspect hands a prospect who messages again to the agent of their latest
+// assignment: the conversation goes to handoff and the agent gets a WhatsApp message with their
+// earlier notes. Returns false, leaving the message to the flow, when the prospect never went
+// through handoff or the agent is deleted or unavailable.
+func (s *InboxAgentService) RouteReturningProspect(ctx context.Context, source, conversationID string) (bool, error) {
+	conv, err := s.loadConversation(ctx, source, conversationID)
+	if err != nil {
+		return false, err
+	}
+
+	history, err := s.agentRepo.GetAssignmentHistory(ctx, conv.idDevice, conv.prospectNum, models.MaxAssignmentHistory)
+	if err != nil {
+		return false, err
+	}
+	if len(history) == 0 {
+		return false, nil
+	}
+
+	last := history[0]
+	agent, err := s.agentRepo.GetAgentByID(ctx, last.AgentID)
+	if err != nil {
+		return false, err
+	}
+	if agent == nil || !agent.Available {
+		log.Printf("👤 Returning prospect %s: agent %s is not available, treating as a new lead", conv.prospectNum, last.AgentName)
+		return false, nil
+	}
+
+	if _, err := s.assign(ctx, last.UserID, agent, conv, nil, true); err != nil {
+		return false, err
+	}
+	log.Printf("📌 Returning prospect %s routed to agent %s", conv.prospectNum, agent.Name)
+
+	if err := s.notifyAgent(ctx, agent, conv, history); err != nil {
+		log.Printf("⚠️  Failed to notify agent %s: %v", agent.Name, err)
+	}
+	return true, nil
+}
+
+// assign releases the conversation's open assignments, records the new one and moves the
+// conversation to handoff
+func (s *InboxAgentService) assign(ctx context.Context, userID string, agent *models.InboxAgent, conv *assignmentConversation, notes *string, sticky bool) (*models.AgentAssignment, error) {
+	open, err := s.agentRepo.GetOpenAssignments(ctx, conv.source, conv.id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, previous := range open {
+		if err := s.agentRepo.UpdateAssignment(ctx, previous.ID, map[string]interface{}{"released_at": now}); err != nil {
+			return nil, err
+		}
+	}
+
+	assignment := &models.AgentAssignment{
+		UserID:         userID,
+		AgentID:        agent.ID,
+		AgentName:      agent.Name,
+		Source:         conv.source,
+		ConversationID: conv.id,
+		IDDevice:       conv.idDevice,
+		ProspectNum:    conv.prospectNum,
+		Notes:          notes,
+		Sticky:         sticky,
+		AssignedAt:     &now,
+	}
+	if err := s.agentRepo.CreateAssignment(ctx, assignment); err != nil {
+		return nil, err
+	}
+
+	if conv.state != models.ConversationStateHandoff {
+		if err := s.stateMachine(conv.source).UpdateState(ctx, conv.id, models.ConversationStateHandoff, "", nil); err != nil {
+			return nil, fmt.Errorf("failed to hand off conversation: %w", err)
+		}
+	}
+
+	return assignment, nil
+}
+
+// notifyAgent messages the agent's phone from the prospect's device with the latest notes
+func (s *InboxAgentService) notifyAgent(ctx context.Context, agent *models.InboxAgent, conv *assignmentConversation, history []models.AgentAssignment) error {
+	phone := getStringValue(agent.Phone)
+	if phone == "" {
+		return nil
+	}
+
+	prospect := conv.prospectNum
+	if conv.prospectName != "" {
+		prospect = fmt.Sprintf("%s (%s)", conv.prospectName, conv.prospectNum)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "📌 %s messaged device %s again and was routed back to you.", prospect, conv.idDevice)
+
+	shown := 0
+	for _, previous := range history {
+		notes := strings.TrimSpace(getStringValue(previous.Notes))
+		if notes == "" || previous.AssignedAt == nil {
+			continue
+		}
+		if shown == 0 {
+			text.WriteString("\n\nPrevious notes:")
+		}
+		fmt.Fprintf(&text, "\n- %s (%s): %s", previous.AssignedAt.Format("2006-01-02"), previous.AgentName, notes)
+		if shown++; shown == stickyNotesShown {
+			break
+		}
+	}
+
+	return s.sender.SendMessage(ctx, conv.idDevice, phone, text.String(), "", "")
+}
+
+// assignmentConversation is what assignments need from an ai_whatsapp or wasapbot row
+type assignmentConversation struct {
+	source       string
+	id           string
+	idDevice     string
+	prospectNum  string
+	prospectName string
+	state        models.ConversationState
+}
+
+// loadConversation reads a conversation from the table source names
+func (s *InboxAgentService) loadConversation(ctx context.Context, source, conversationID string) (*assignmentConversation, error) {
+	switch source {
+	case models.AssignmentSourceAI:
+		conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		return &assignmentConversation{
+			source:       source,
+			id:           conversationID,
+			idDevice:     conversation.IDDevice,
+			prospectNum:  conversation.ProspectNum,
+			prospectName: getStringValue(conversation.ProspectName),
+			state:        conversation.ExecutionState().State(),
+		}, nil
+	case models.AssignmentSourceWasapbot:
+		conversation, err := s.wasapbotRepo.GetConversationByID(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		return &assignmentConversation{
+			source:       source,
+			id:           conversationID,
+			idDevice:     conversation.IDDevice,
+			prospectNum:  conversation.ProspectNum,
+			prospectName: getStringValue(conversation.ProspectName),
+			state:        conversation.ExecutionState().State(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown conversation source %q", source)
+	}
+}
+
+// ownedConversation loads a conversation and checks the user owns its device
+func (s *InboxAgentService) ownedConversation(ctx context.Context, userID, source, conversationID string) (*assignmentConversation, string) {
+	if source != models.AssignmentSourceAI && source != models.AssignmentSourceWasapbot {
+		return nil, fmt.Sprintf("Invalid source: %s (use ai_whatsapp or wasapbot)", source)
+	}
+
+	conv, err := s.loadConversation(ctx, source, conversationID)
+	if err != nil || conv == nil {
+		return nil, "Conversation not found"
+	}
+
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, conv.idDevice)
+	if err != nil || device == nil || device.UserID == nil || *device.UserID != userID {
+		return nil, "Access denied"
+	}
+	return conv, ""
+}
+
+// ownedAgent loads an agent and checks the user owns it
+func (s *InboxAgentService) ownedAgent(ctx context.Context, userID, agentID string) (*models.InboxAgent, string, error) {
+	agent, err := s.agentRepo.GetAgentByID(ctx, agentID)
+	if err != nil {
+		return nil, "", err
+	}
+	if agent == nil {
+		return nil, "Agent not found", nil
+	}
+	if agent.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return agent, "", nil
+}
+
+// stateMachine returns the state machine of a conversation table
+func (s *InboxAgentService) stateMachine(source string) *ConversationStateMachine {
+	if source == models.AssignmentSourceWasapbot {
+		return s.wasapbotState
+	}
+	return s.aiState
+}
+
+// validateInboxAgent checks an agent.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateInboxAgent(agent *models.InboxAgent) string {
+	if agent.Name == "" {
+		return "name is required"
+	}
+	return ""
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// Interactive messages. send_buttons sends up to three quick-reply buttons and send_list a list
+// menu, then both wait for the prospect's choice like waiting_reply:
+//
+//	{"type": "send_buttons", "config": {"text": "Hi {{name}}, interested?", "footer": "Tap one",
+//	  "buttons": [{"id": "yes", "title": "Yes please"}, {"id": "no", "title": "Not now"}]}}
+//	{"type": "send_list", "config": {"text": "Pick a package", "button_text": "Packages",
+//	  "sections": [{"title": "Monthly", "rows": [{"id": "basic", "title": "Basic", "description": "RM49"}]}]}}
+//
+// A list without sections can give its rows directly in "rows". Options without an id use their
+// title. The choice picks the outgoing connection with conditionType "button" whose conditionValue
+// lists the option's id ("yes" or "yes,maybe"), else the "default" one. Providers without
+// interactive messages send the options as numbered text, so the number or the title of an option
+// picks it too. A reply that picks nothing sends retry_text and keeps waiting unless there is a
+// default connection.
+const (
+	flowSendButtonsNode = "send_buttons"
+	flowSendListNode    = "send_list"
+)
+
+// ConditionButton is the interactive reply condition for conditions node edges. It matches when
+// the prospect tapped one of the listed button or list row ids, or replied with one of them:
+//
+//	button  "yes"          the button with id yes
+//	button  "basic,pro"    either list row
+const ConditionButton = "button"
+
+const (
+	defaultListButtonText   = "Options"
+	defaultInteractiveRetry = "Sorry, please choose one of the options."
+)
+
+type selectedOptionKey struct{}
+
+// withSelectedOption marks ctx as handling the tap of a button or list row
+func withSelectedOption(ctx context.Context, optionID string) context.Context {
+	return context.WithValue(ctx, selectedOptionKey{}, optionID)
+}
+
+// selectedOptionFrom returns the id of the button or list row the inbound message tapped, "" if none
+func selectedOptionFrom(ctx context.Context) string {
+	optionID, _ := ctx.Value(selectedOptionKey{}).(string)
+	return optionID
+}
+
+// evaluateButtonCondition checks a button condition against the message being handled
+func evaluateButtonCondition(ctx context.Context, value, message string) bool {
+	choice := selectedOptionFrom(ctx)
+	if choice == "" {
+		choice = strings.TrimSpace(message)
+	}
+	return buttonConditionMatches(value, choice)
+}
+
+// buttonConditionMatches reports whether a button condition's comma separated ids include optionID
+func buttonConditionMatches(value, optionID string) bool {
+	if optionID == "" {
+		return false
+	}
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" && strings.EqualFold(id, optionID) {
+			return true
+		}
+	}
+	return false
+}
+
+// interactiveMessage builds the buttons or list message of a send_buttons or send_list node from
+// its config, without the body text
+func interactiveMessage(node *FlowNode) *models.SendMessageRequest {
+	footer, _ := node.Config["footer"].(string)
+	message := &models.SendMessageRequest{Footer: strings.TrimSpace(footer)}
+
+	if node.Type == flowSendListNode {
+		list := &models.MessageList{ButtonText: defaultListButtonText}
+		if text, ok := node.Config["button_text"].(string); ok && strings.TrimSpace(text) != "" {
+			list.ButtonText = strings.TrimSpace(text)
+		}
+		if sections, ok := node.Config["sections"].([]interface{}); ok {
+			for _, raw := range sections {
+				section, ok := raw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				title, _ := section["title"].(string)
+				list.Sections = append(list.Sections, models.MessageListSection{
+					Title: strings.TrimSpace(title),
+					Rows:  interactiveListRows(section["rows"]),
+				})
+			}
+		} else if rows := interactiveListRows(node.Config["rows"]); len(rows) > 0 {
+			list.Sections = []models.MessageListSection{{Rows: rows}}
+		}
+		message.Type = models.MessageTypeList
+		message.List = list
+		return message
+	}
+
+	message.Type = models.MessageTypeButtons
+	if buttons, ok := node.Config["buttons"].([]interface{}); ok {
+		for _, raw := range buttons {
+			id, title, _ := interactiveOption(raw)
+			if title == "" {
+				continue
+			}
+			message.Buttons = append(message.Buttons, models.MessageButton{ID: id, Title: title})
+		}
+	}
+	return message
+}
+
+// interactiveListRows reads the rows of a list section
+func interactiveListRows(raw interface{}) []models.MessageListRow {
+	items, _ := raw.([]interface{})
+	var rows []models.MessageListRow
+	for _, item := range items {
+		id, title, description := interactiveOption(item)
+		if title == "" {
+			continue
+		}
+		rows = append(rows, models.MessageListRow{ID: id, Title: title, Description: description})
+	}
+	return rows
+}
+
+// interactiveOption reads one button or list row: a {"id", "title", "description"} object or just
+// its title. The id defaults to the title.
+func interactiveOption(raw interface{}) (id, title, description string) {
+	switch option := raw.(type) {
+	case string:
+		title = strings.TrimSpace(option)
+	case map[string]interface{}:
+		id, _ = option["id"].(string)
+		title, _ = option["title"].(string)
+		description, _ = option["description"].(string)
+		id, title, description = strings.TrimSpace(id), strings.TrimSpace(title), strings.TrimSpace(description)
+	}
+	if id == "" {
+		id = title
+	}
+	return id, title, description
+}
+
+// interactiveOptions lists the id and title of every button or row of a message, in order
+func interactiveOptions(message *models.SendMessageRequest) []models.MessageButton {
+	if message.Type == models.MessageTypeList {
+		var options []models.MessageButton
+		for _, row := range message.List.Rows() {
+			options = append(options, models.MessageButton{ID: row.ID, Title: row.Title})
+		}
+		return options
+	}
+	return message.Buttons
+}
+
+// selectedOption returns the id of the option a reply picks at a send_buttons or send_list node:
+// the tapped id, else the option whose id or title the reply is, else the option numbered by the
+// reply. "" when the reply picks none.
+func selectedOption(ctx context.Context, node *FlowNode, reply string) string {
+	if optionID := selectedOptionFrom(ctx); optionID != "" {
+		return optionID
+	}
+
+	options := interactiveOptions(interactiveMessage(node))
+
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return ""
+	}
+	for _, option := range options {
+		if strings.EqualFold(reply, option.ID) || strings.EqualFold(reply, option.Title) {
+			return option.ID
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(reply, ".")); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1].ID
+	}
+	return ""
+}
+
+// interactiveEdge returns the connection a reply takes out of a send_buttons or send_list node:
+// the button connection listing the picked option, else the default one, else one without a
+// condition. nil when none applies.
+func (run *flowRun) interactiveEdge(ctx context.Context, node *FlowNode, edges []FlowEdge) *FlowEdge {
+	optionID := selectedOption(ctx, node, run.userMessage)
+	for i, edge := range edges {
+		if strings.EqualFold(edge.ConditionType, ConditionButton) && buttonConditionMatches(edge.ConditionValue, optionID) {
+			log.Printf("🔘 Option %s of %s picked", optionID, node.ID)
+			return &edges[i]
+		}
+	}
+	for i, edge := range edges {
+		if strings.EqualFold(edge.ConditionType, "default") {
+			return &edges[i]
+		}
+	}
+	for i, edge := range edges {
+		if edge.ConditionType == "" {
+			return &edges[i]
+		}
+	}
+	return nil
+}
+
+// interactiveNext returns the node a reply to a send_buttons or send_list node leads to
+func (run *flowRun) interactiveNext(ctx context.Context, node *FlowNode, edges []FlowEdge) *FlowNode {
+	edge := run.interactiveEdge(ctx, node, edges)
+	if edge == nil {
+		return nil
+	}
+	return findFlowNode(run.flowData, edge.To)
+}
+
+// interactiveProcessor sends a send_buttons or send_list node's message and waits for the choice
+type interactiveProcessor struct {
+	nodeType string
+}
+
+func (p *interactiveProcessor) GetNodeType() string { return p.nodeType }
+
+func (p *interactiveProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	message := interactiveMessage(node)
+	if !message.IsInteractive() || len(interactiveOptions(message)) == 0 {
+		log.Printf("⚠️  No options configured for %s node %s", node.Type, node.ID)
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for sending: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	text, _ := node.Config["text"].(string)
+	text = renderMessageTemplate(text, conversation.row)
+	message.Body = run.translator.ForProspect(ctx, run.flow.IDDevice, getStringValue(conversation.Language), text)
+	message.Footer = renderMessageTemplate(message.Footer, conversation.row)
+
+	log.Printf("📤 Sending %s message: %s", message.Type, text)
+	if err := run.sender.SendRequest(ctx, run.flow.IDDevice, conversation.ProspectNum, message); err != nil {
+		log.Printf("❌ Failed to send WhatsApp %s message: %v", message.Type, err)
+		return true, fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", message.InteractiveText()); err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, nil); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+	return false, nil
+}
+
+// HandleReply moves on when the reply picks a connection, and otherwise asks again
+func (p *interactiveProcessor) HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	var edges []FlowEdge
+	for _, edge := range run.flowData.Connections {
+		if edge.From == node.ID {
+			edges = append(edges, edge)
+		}
+	}
+	if len(edges) == 0 || run.interactiveEdge(ctx, node, edges) != nil {
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return false, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	retry := defaultInteractiveRetry
+	if text, ok := node.Config["retry_text"].(string); ok && strings.TrimSpace(text) != "" {
+		retry = text
+	}
+	log.Printf("🔁 Reply '%s' picks no option of %s, asking again", run.userMessage, node.ID)
+
+	if err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, retry, "", ""); err != nil {
+		log.Printf("⚠️  Failed to send %s retry: %v", node.Type, err)
+	} else if err := run.appendHistory(ctx, run.conversationID, node.ID, "Bot", retry); err != nil {
+		log.Printf("⚠️  Failed to update conv_last: %v", err)
+	}
+
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, nil); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+	return false, nil
+}
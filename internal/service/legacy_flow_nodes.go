@@ -0,0 +1,506 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Legacy flows. Flows saved before nodes_data keep their React Flow graph in the nodes and edges
+// columns:
+//
+//	nodes: {"nodes": [{"id": "n1", "type": "message", "data": {"message": "Hi {{prospect_name}}"}}]}
+//	edges: {"edges": [{"source": "n1", "target": "n2", "sourceHandle": "true", "label": "Yes"}]}
+//
+// newRun converts them with legacyFlowData, so they run on the shared runtime. Node types with a
+// current equivalent become it: message and end nodes send_message (an end node keeps no outgoing
+// edges, so the flow completes there), ai nodes ai_prompt with the device's model, and stage and
+// delay nodes take the current config. start, image, audio, video, document, condition, prompt,
+// user_reply and api nodes run on the processors below. A condition, user_reply or api node records
+// its outcome in run.branch, and findNextNode follows the edge whose handle or label names it.
+const (
+	legacyStartNode     = "start"
+	legacyConditionNode = "condition"
+	legacyPromptNode    = "prompt"
+	legacyUserReplyNode = "user_reply"
+	legacyAPINode       = "api"
+)
+
+// legacyBranchEdges are the edge handles or labels each legacy branch follows, in order of preference
+var legacyBranchEdges = map[string][]string{
+	"true":    {"true", "yes"},
+	"false":   {"false", "no"},
+	"success": {"success", "yes"},
+	"timeout": {"timeout", "no"},
+	"error":   {"error", "no"},
+}
+
+// hasFlowNodes reports whether a flow has nodes to run, in nodes_data or the legacy format
+func hasFlowNodes(flow *models.ChatbotFlow) bool {
+	return strings.TrimSpace(flow.NodesData) != "" || len(legacyGraphList(flow.Nodes, "nodes")) > 0
+}
+
+// legacyGraphList returns the array a legacy nodes or edges column holds under key, or under
+// whichever key it was saved with
+func legacyGraphList(graph map[string]interface{}, key string) []interface{} {
+	if list, ok := graph[key].([]interface{}); ok {
+		return list
+	}
+	for _, value := range graph {
+		if list, ok := value.([]interface{}); ok {
+			return list
+		}
+	}
+	return nil
+}
+
+// legacyFlowData converts a legacy flow's React Flow nodes and edges to the runtime's FlowData
+func legacyFlowData(flow *models.ChatbotFlow) *FlowData {
+	flowData := &FlowData{}
+	ends := make(map[string]bool)
+
+	for _, raw := range legacyGraphList(flow.Nodes, "nodes") {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := item["id"].(string)
+		if id == "" {
+			continue
+		}
+		nodeType, _ := item["type"].(string)
+		data, _ := item["data"].(map[string]interface{})
+		if nodeType == "end" {
+			ends[id] = true
+		}
+		flowData.Nodes = append(flowData.Nodes, legacyFlowNode(id, nodeType, data))
+	}
+
+	for _, raw := range legacyGraphList(flow.Edges, "edges") {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _ := item["source"].(string)
+		to, _ := item["target"].(string)
+		if from == "" || to == "" || ends[from] {
+			continue
+		}
+		branch, _ := item["sourceHandle"].(string)
+		if branch == "" {
+			branch, _ = item["label"].(string)
+		}
+		flowData.Connections = append(flowData.Connections, FlowEdge{
+			From:           from,
+			To:             to,
+			ConditionValue: strings.ToLower(strings.TrimSpace(branch)),
+		})
+	}
+
+	return flowData
+}
+
+// legacyFlowNode converts one legacy node, mapping the types that have a current equivalent
+func legacyFlowNode(id, nodeType string, data map[string]interface{}) FlowNode {
+	label, _ := data["label"].(string)
+	node := FlowNode{ID: id, Type: nodeType, Label: label, Config: data}
+	if node.Config == nil {
+		node.Config = map[string]interface{}{}
+	}
+
+	switch nodeType {
+	case "message":
+		node.Type = "send_message"
+		node.Config = map[string]interface{}{"text": legacyText(data, "Hello!", "message")}
+	case "end":
+		node.Type = "send_message"
+		node.Config = map[string]interface{}{"text": legacyText(data, "Thank you for your time!", "message")}
+	case "ai":
+		var prompt []string
+		for _, key := range []string{"systemPrompt", "prompt"} {
+			if text := legacyText(data, "", key); text != "" {
+				prompt = append(prompt, text)
+			}
+		}
+		node.Type = "ai_prompt"
+		node.Config = map[string]interface{}{"text": strings.Join(prompt, "\n\n")}
+	case "stage":
+		node.Config = map[string]interface{}{"value": legacyText(data, "stage_"+id, "stage", "name")}
+	case "delay":
+		seconds := legacyNumber(data["delay"])
+		if seconds <= 0 {
+			seconds = 1
+		}
+		node.Config = map[string]interface{}{"delay": seconds}
+	case legacyUserReplyNode:
+		// The timeout is exact, never moved into the device's best reply hours
+		config := make(map[string]interface{}, len(data)+1)
+		for key, value := range data {
+			config[key] = value
+		}
+		config["timing"] = models.FollowUpTimingStatic
+		node.Config = config
+	}
+	return node
+}
+
+// legacyText returns the first of keys set in a legacy node's data, or fallback
+func legacyText(data map[string]interface{}, fallback string, keys ...string) string {
+	for _, key := range keys {
+		if text, _ := data[key].(string); text != "" {
+			return text
+		}
+	}
+	return fallback
+}
+
+// legacyNumber reads a legacy number, saved as a JSON number or as text
+func legacyNumber(value interface{}) float64 {
+	switch typed := value.(type) {
+	case float64:
+		return typed
+	case string:
+		if number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64); err == nil {
+			return number
+		}
+	}
+	return 0
+}
+
+// isLegacyBranchNode reports whether a node type picks its next node by run.branch
+func isLegacyBranchNode(nodeType string) bool {
+	return nodeType == legacyConditionNode || nodeType == legacyUserReplyNode || nodeType == legacyAPINode
+}
+
+// legacyBranchNext follows the edge of the branch the node recorded, else an unlabelled edge, else
+// the first one
+func (run *flowRun) legacyBranchNext(node *FlowNode, edges []FlowEdge) *FlowNode {
+	log.Printf("🔀 %s node %s took the %q branch", node.Type, node.ID, run.branch)
+	for _, name := range legacyBranchEdges[run.branch] {
+		for _, edge := range edges {
+			if edge.ConditionValue == name {
+				return findFlowNode(run.flowData, edge.To)
+			}
+		}
+	}
+	for _, edge := range edges {
+		if edge.ConditionValue == "" {
+			return findFlowNode(run.flowData, edge.To)
+		}
+	}
+	return findFlowNode(run.flowData, edges[0].To)
+}
+
+// saveVariables merges values into the conversation's session_data, where templates and
+// conditions read them
+func (run *flowRun) saveVariables(ctx context.Context, values map[string]interface{}) error {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	session := make(map[string]interface{}, len(conversation.SessionData)+len(values))
+	for key, value := range conversation.SessionData {
+		session[key] = value
+	}
+	for key, value := range values {
+		session[key] = value
+	}
+	return run.store.UpdateConversation(ctx, run.conversationID, map[string]interface{}{"session_data": session})
+}
+
+// legacyStartProcessor passes through to the node after start
+type legacyStartProcessor struct{}
+
+func (p *legacyStartProcessor) GetNodeType() string { return legacyStartNode }
+
+func (p *legacyStartProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	return true, nil
+}
+
+// legacyMediaProcessor sends an image, audio, video or document node's file with its caption
+type legacyMediaProcessor struct {
+	nodeType string // also the prefix of its URL key, such as imageUrl
+}
+
+func (p *legacyMediaProcessor) GetNodeType() string { return p.nodeType }
+
+func (p *legacyMediaProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	url := legacyText(node.Config, "", p.nodeType+"Url", "url")
+	if url == "" {
+		log.Printf("⚠️  No URL configured for %s node %s", node.Type, node.ID)
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for sending media: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	caption := renderMessageTemplate(legacyText(node.Config, "", "caption", "message"), conversation.row)
+	log.Printf("📤 Sending %s: %s", p.nodeType, url)
+	if err := run.sender.SendMessage(ctx, run.flow.IDDevice, conversation.ProspectNum, caption, p.nodeType, url); err != nil {
+		log.Printf("❌ Failed to send WhatsApp media: %v", err)
+		return true, fmt.Errorf("failed to send media: %w", err)
+	}
+
+	history := url
+	if caption != "" {
+		history += " " + caption
+	}
+	return true, run.appendHistory(ctx, run.conversationID, node.ID, "Bot", history)
+}
+
+// legacyConditionProcessor evaluates a condition node into its true or false branch:
+// message_contains (keyword), variable_check (variable, operator, value) or time_check
+// (timeCondition, value) in the device timezone
+type legacyConditionProcessor struct{}
+
+func (p *legacyConditionProcessor) GetNodeType() string { return legacyConditionNode }
+
+func (p *legacyConditionProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	met := false
+	value, _ := node.Config["value"].(string)
+
+	switch conditionType, _ := node.Config["conditionType"].(string); conditionType {
+	case "message_contains":
+		keyword, _ := node.Config["keyword"].(string)
+		met = messageMatches("contains", run.userMessage, keyword, run.flow.NormalizeMalay)
+	case "variable_check":
+		conversation, err := run.load(ctx, run.conversationID)
+		if err != nil || conversation == nil {
+			return true, fmt.Errorf("failed to get conversation: %w", err)
+		}
+		variable, _ := node.Config["variable"].(string)
+		operator, _ := node.Config["operator"].(string)
+		met = legacyCompare(variableText(conversationVariables(conversation.row), variable), operator, value)
+	case "time_check":
+		if timeCondition, _ := node.Config["timeCondition"].(string); isTimeCondition(timeCondition) {
+			met = evaluateTimeCondition(timeCondition, value, deviceClock(ctx, run.deviceRepo, run.flow.IDDevice)())
+		}
+	}
+
+	run.branch = strconv.FormatBool(met)
+	return true, nil
+}
+
+// legacyCompare applies a variable_check operator
+func legacyCompare(value, operator, compare string) bool {
+	switch operator {
+	case "equals", "==":
+		return value == compare
+	case "not_equals", "!=":
+		return value != compare
+	case "contains":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(compare))
+	case "greater_than", ">", "less_than", "<":
+		number, errValue := strconv.ParseFloat(value, 64)
+		against, errCompare := strconv.ParseFloat(compare, 64)
+		if errValue != nil || errCompare != nil {
+			return false
+		}
+		if operator == "greater_than" || operator == ">" {
+			return number > against
+		}
+		return number < against
+	}
+	return false
+}
+
+// legacyPromptProcessor asks its question and waits; the reply is stored in the node's variable
+// (user_input by default)
+type legacyPromptProcessor struct{}
+
+func (p *legacyPromptProcessor) GetNodeType() string { return legacyPromptNode }
+
+func (p *legacyPromptProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if err := run.sendText(ctx, node, conversation, legacyText(node.Config, "Please provide your input:", "message", "prompt")); err != nil {
+		return true, err
+	}
+
+	if err := run.state.UpdateState(ctx, run.conversationID, models.ConversationStateWaiting, node.ID, nil); err != nil {
+		return false, fmt.Errorf("failed to update waiting state: %w", err)
+	}
+	return false, nil
+}
+
+func (p *legacyPromptProcessor) HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	variable := legacyText(node.Config, "user_input", "variable", "variableName")
+	if err := run.saveVariables(ctx, map[string]interface{}{variable: run.userMessage}); err != nil {
+		return false, fmt.Errorf("failed to save %s: %w", variable, err)
+	}
+	return true, nil
+}
+
+// legacyUserReplyProcessor asks its question and waits up to timeout seconds (300 by default). A
+// reply is stored in the node's variable (user_reply by default) and takes the success branch; the
+// flow scheduler resumes the flow without one once the timeout passes, taking the timeout branch.
+type legacyUserReplyProcessor struct{}
+
+func (p *legacyUserReplyProcessor) GetNodeType() string { return legacyUserReplyNode }
+
+func (p *legacyUserReplyProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if err := run.sendText(ctx, node, conversation, legacyText(node.Config, "Please provide your response:", "message", "prompt")); err != nil {
+		return true, err
+	}
+
+	timeout := legacyNumber(node.Config["timeout"])
+	if timeout <= 0 {
+		timeout = 300
+	}
+	continueFlow, err := run.pauseUntil(ctx, node, models.ConversationStateWaiting, time.Duration(timeout*float64(time.Second)))
+	if err != nil {
+		return false, fmt.Errorf("wait interrupted: %w", err)
+	}
+	if continueFlow {
+		// Waited in-process, where no reply can arrive
+		return true, p.record(ctx, run, node, "")
+	}
+	return false, nil
+}
+
+func (p *legacyUserReplyProcessor) HandleReply(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	if err := p.record(ctx, run, node, run.userMessage); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// record stores the reply, empty when the wait timed out, and picks the branch
+func (p *legacyUserReplyProcessor) record(ctx context.Context, run *flowRun, node *FlowNode, reply string) error {
+	timedOut := strings.TrimSpace(reply) == ""
+	run.branch = "success"
+	if timedOut {
+		run.branch = "timeout"
+	}
+
+	variable := legacyText(node.Config, "user_reply", "variable", "variableName")
+	if err := run.saveVariables(ctx, map[string]interface{}{variable: reply, "_timeout_occurred": timedOut}); err != nil {
+		return fmt.Errorf("failed to save %s: %w", variable, err)
+	}
+	return nil
+}
+
+// legacyAPIProcessor calls an external HTTP API. url, headers and body take {{variable}}
+// placeholders; method defaults to GET and timeout to 30 seconds. A 2xx response is stored in
+// responseVariable (api_response by default, parsed when JSON, with the text in <name>_raw) and
+// <name>_status, and takes the success branch; a failure is stored in _api_error and takes the
+// error branch. A node with a cost (USD) adds it to the conversation's cost ledger per answered call.
+type legacyAPIProcessor struct{}
+
+func (p *legacyAPIProcessor) GetNodeType() string { return legacyAPINode }
+
+func (p *legacyAPIProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	url := renderMessageTemplate(legacyText(node.Config, "", "url"), conversation.row)
+	if url == "" {
+		log.Printf("⚠️  No URL configured for api node %s", node.ID)
+		return true, p.fail(ctx, run, "no URL configured")
+	}
+	method := strings.ToUpper(legacyText(node.Config, http.MethodGet, "method"))
+
+	body := ""
+	switch typed := node.Config["body"].(type) {
+	case string:
+		body = typed
+	case map[string]interface{}:
+		if encoded, err := json.Marshal(typed); err == nil {
+			body = string(encoded)
+		}
+	}
+	body = renderMessageTemplate(body, conversation.row)
+
+	if run.sim != nil {
+		run.sim.record(ctx, models.FlowTraceEvent{Kind: models.FlowTraceAPICall, URL: method + " " + url, Payload: body})
+		run.branch = "success"
+		return true, nil
+	}
+
+	timeout := legacyNumber(node.Config["timeout"])
+	if timeout <= 0 {
+		timeout = 30
+	}
+	callCtx, cancel := withDeadline(ctx, time.Duration(timeout*float64(time.Second)))
+	defer cancel()
+
+	var reader io.Reader
+	if body != "" && (method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch) {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(callCtx, method, url, reader)
+	if err != nil {
+		return true, p.fail(ctx, run, fmt.Sprintf("failed to create request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if headers, ok := node.Config["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			if text, ok := value.(string); ok {
+				req.Header.Set(key, renderMessageTemplate(text, conversation.row))
+			}
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, p.fail(ctx, run, fmt.Sprintf("request failed: %v", err))
+	}
+	defer resp.Body.Close()
+
+	// Paid APIs set a per-call cost on the node, charged once the call is answered
+	if cost, ok := node.Config["cost"].(float64); ok {
+		run.costs.RecordAPICall(ctx, run.flow.IDDevice, conversation.ProspectNum, &run.flow.ID, method+" "+url, cost)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, p.fail(ctx, run, fmt.Sprintf("failed to read response: %v", err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return true, p.fail(ctx, run, fmt.Sprintf("HTTP %d: %s", resp.StatusCode, raw))
+	}
+
+	name := legacyText(node.Config, "api_response", "responseVariable")
+	values := map[string]interface{}{
+		name + "_status": resp.StatusCode,
+		"_api_success":   true,
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(raw, &parsed); err == nil {
+		values[name] = parsed
+		values[name+"_raw"] = string(raw)
+	} else {
+		values[name] = string(raw)
+	}
+
+	log.Printf("🌐 API call %s %s answered HTTP %d", method, url, resp.StatusCode)
+	run.branch = "success"
+	return true, run.saveVariables(ctx, values)
+}
+
+// fail stores why the call failed and takes the error branch
+func (p *legacyAPIProcessor) fail(ctx context.Context, run *flowRun, reason string) error {
+	log.Printf("⚠️  API call of conversation %s failed: %s", run.conversationID, reason)
+	run.branch = "error"
+	return run.saveVariables(ctx, map[string]interface{}{"_api_error": reason, "_api_success": false})
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// recordingSender records the text of every message a flow sends
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *recordingSender) SendMessage(ctx context.Context, deviceID string, to string, message string, mediaType string, mediaURL string, mimeType ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func (s *recordingSender) SendRequest(ctx context.Context, deviceID string, to string, message *models.SendMessageRequest) error {
+	return s.SendMessage(ctx, deviceID, to, message.Body, message.Type, message.MediaURL)
+}
+
+func TestLegacyFlowRunsEndToEnd(t *testing.T) {
+	var apiRequest string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		apiRequest = r.Method + " " + r.URL.RequestURI() + " " + string(body)
+		w.Write([]byte(`{"price": 45}`))
+	}))
+	defer api.Close()
+
+	flow := &models.ChatbotFlow{ID: "flow-1", IDDevice: "shop"}
+	unmarshalLegacy(t, &flow.Nodes, `{"nodes": [
+		{"id": "begin", "type": "start", "data": {}},
+		{"id": "hello", "type": "message", "data": {"message": "Hi {{prospect_num}}"}},
+		{"id": "quote", "type": "api", "data": {"url": "`+api.URL+`/quote?phone={{prospect_num}}", "method": "post",
+			"body": {"phone": "{{prospect_num}}"}, "responseVariable": "quote", "cost": 0.02}},
+		{"id": "check", "type": "condition", "data": {"conditionType": "variable_check", "variable": "quote_status", "operator": "equals", "value": "200"}},
+		{"id": "ask", "type": "prompt", "data": {"message": "Your name?", "variable": "name"}},
+		{"id": "confirm", "type": "user_reply", "data": {"message": "Confirm, {{name}}?", "timeout": 60}},
+		{"id": "done", "type": "end", "data": {"message": "Thanks {{name}}"}},
+		{"id": "gone", "type": "end", "data": {"message": "No answer"}},
+		{"id": "down", "type": "end", "data": {"message": "Try again later"}}
+	]}`)
+	unmarshalLegacy(t, &flow.Edges, `{"edges": [
+		{"source": "begin", "target": "hello"},
+		{"source": "hello", "target": "quote"},
+		{"source": "quote", "target": "check", "sourceHandle": "success"},
+		{"source": "quote", "target": "down", "sourceHandle": "error"},
+		{"source": "check", "target": "down", "label": "No"},
+		{"source": "check", "target": "ask", "label": "Yes"},
+		{"source": "ask", "target": "confirm"},
+		{"source": "confirm", "target": "done", "sourceHandle": "success"},
+		{"source": "confirm", "target": "gone", "sourceHandle": "timeout"},
+		{"source": "gone", "target": "hello"}
+	]}`)
+
+	conversation := simulatedAIStore{newFlowSimulation(flow, "60123456789", "hi", nil)}
+	db, inserts := fakePostgREST(t, nil)
+	sender := &recordingSender{}
+	processor := &FlowProcessorService{
+		store:     conversation,
+		aiState:   NewConversationStateMachine(conversation),
+		sender:    sender,
+		costs:     NewCostRecorder(repository.NewCostLedgerRepository(db)),
+		delayRepo: repository.NewDelayedExecutionRepository(db),
+	}
+	ctx := context.Background()
+
+	// Runs from start up to the prompt, which waits for the name
+	if err := processor.ExecuteFlow(ctx, flow, simulationConversationID, "hi", ""); err != nil {
+		t.Fatalf("ExecuteFlow: %v", err)
+	}
+	assertLegacyRest(t, conversation, models.ConversationStateWaiting, "ask")
+
+	// The name moves on to user_reply, which waits for a reply or its timeout
+	if err := processor.resumeAfterNode(ctx, "ai_whatsapp", simulationConversationID, flow, "ask", "Ali"); err != nil {
+		t.Fatalf("resume after prompt: %v", err)
+	}
+	assertLegacyRest(t, conversation, models.ConversationStateWaiting, "confirm")
+	if scheduled := inserts.table("delayed_executions"); len(scheduled) != 1 || scheduled[0]["node_id"] != "confirm" {
+		t.Fatalf("delayed executions = %v, want one resume of confirm", scheduled)
+	}
+
+	// The flow scheduler resumes it without a reply: the timeout branch ends the flow
+	if err := processor.resumeAfterNode(ctx, "ai_whatsapp", simulationConversationID, flow, "confirm", ""); err != nil {
+		t.Fatalf("resume after timeout: %v", err)
+	}
+	if state := conversation.state(); state != models.ConversationStateCompleted {
+		t.Fatalf("state = %s, want completed", state)
+	}
+
+	if want := []string{"Hi 60123456789", "Your name?", "Confirm, Ali?", "No answer"}; !reflect.DeepEqual(sender.sent, want) {
+		t.Errorf("sent %q, want %q", sender.sent, want)
+	}
+	if want := `POST /quote?phone=60123456789 {"phone":"60123456789"}`; apiRequest != want {
+		t.Errorf("API request = %q, want %q", apiRequest, want)
+	}
+
+	costs := inserts.table("conversation_costs")
+	if len(costs) != 1 || costs[0]["amount"] != 0.02 || costs[0]["cost_type"] != models.CostTypeAPICall ||
+		costs[0]["description"] != "POST "+api.URL+"/quote?phone=60123456789" {
+		t.Errorf("cost ledger = %v, want one 0.02 api call", costs)
+	}
+
+	row, err := conversation.GetConversationByID(ctx, simulationConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := row.SessionData
+	if variables["name"] != "Ali" || variables["user_reply"] != "" || variables["_timeout_occurred"] != true ||
+		variables["quote_status"] != float64(200) || !reflect.DeepEqual(variables["quote"], map[string]interface{}{"price": float64(45)}) {
+		t.Errorf("session_data = %v", variables)
+	}
+}
+
+// unmarshalLegacy fills a legacy nodes or edges column
+func unmarshalLegacy(t *testing.T, column *map[string]interface{}, data string) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), column); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertLegacyRest checks where a flow run left the conversation
+func assertLegacyRest(t *testing.T, conversation simulatedAIStore, state models.ConversationState, nodeID string) {
+	t.Helper()
+	if got := conversation.state(); got != state {
+		t.Fatalf("state = %s, want %s", got, state)
+	}
+	if got := conversation.column("current_node_id"); got != nodeID {
+		t.Fatalf("current_node_id = %q, want %q", got, nodeID)
+	}
+}
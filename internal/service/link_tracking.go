@@ -0,0 +1,143 @@
+package service
+
+import (
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+	"context"
+	"crypto/rand"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// linkCodeLength short codes are 8 base62 characters (~2e14 combinations)
+	linkCodeLength = 8
+	linkCodeChars  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// linkPathPrefix is the redirect route served for tracked links
+	linkPathPrefix = "/l/"
+)
+
+// outboundURLPattern matches http(s) URLs in outgoing message text
+var outboundURLPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// LinkTracker wraps URLs sent by flow message nodes in short tracked links and resolves clicks.
+// Links use the device's tracking_domain, falling back to the default base URL; with neither
+// configured messages are sent unchanged. A node opts out with config track_links=false.
+//
+// A nil *LinkTracker is valid and disables tracking.
+type LinkTracker struct {
+	linkRepo       *repository.LinkTrackingRepository
+	deviceRepo     *repository.DeviceRepository
+	defaultBaseURL string
+}
+
+// NewLinkTracker creates a link tracker. defaultBaseURL is used for devices without a tracking_domain.
+func NewLinkTracker(linkRepo *repository.LinkTrackingRepository, deviceRepo *repository.DeviceRepository, defaultBaseURL string) *LinkTracker {
+	return &LinkTracker{
+		linkRepo:       linkRepo,
+		deviceRepo:     deviceRepo,
+		defaultBaseURL: strings.TrimRight(defaultBaseURL, "/"),
+	}
+}
+
+// linkTrackingEnabled reads the node's track_links option (default on)
+func linkTrackingEnabled(node *FlowNode) bool {
+	enabled, ok := node.Config["track_links"].(bool)
+	return !ok || enabled
+}
+
+// WrapLinks replaces every URL in text with a tracked short link attributed to the flow node
+// and prospect. Any failure leaves that URL unwrapped so the message is always sent.
+func (t *LinkTracker) WrapLinks(ctx context.Context, flow *models.ChatbotFlow, node *FlowNode, conversationID, prospectNum, text string) string {
+	if t == nil || !linkTrackingEnabled(node) || !outboundURLPattern.MatchString(text) {
+		return text
+	}
+
+	baseURL := t.baseURL(ctx, flow.IDDevice)
+	if baseURL == "" {
+		return text
+	}
+
+	return outboundURLPattern.ReplaceAllStringFunc(text, func(raw string) string {
+		target, trailing := splitTrailingPunctuation(raw)
+		if strings.HasPrefix(target, baseURL+linkPathPrefix) {
+			return raw
+		}
+
+		code, err := newLinkCode()
+		if err != nil {
+			return raw
+		}
+
+		link := &models.TrackedLink{
+			Code:           code,
+			IDDevice:       flow.IDDevice,
+			FlowID:         flow.ID,
+			NodeID:         node.ID,
+			ConversationID: conversationID,
+			ProspectNum:    prospectNum,
+			TargetURL:      target,
+		}
+		if err := t.linkRepo.CreateLink(ctx, link); err != nil {
+			log.Printf("⚠️  Failed to create tracked link for %s, sending original URL: %v", target, err)
+			return raw
+		}
+
+		return baseURL + linkPathPrefix + code + trailing
+	})
+}
+
+// ResolveClick records a click on a tracked link and returns the URL to redirect to
+func (t *LinkTracker) ResolveClick(ctx context.Context, code, userAgent string) (string, bool) {
+	if t == nil || code == "" {
+		return "", false
+	}
+
+	link, err := t.linkRepo.GetLinkByCode(ctx, code)
+	if err != nil || link == nil {
+		return "", false
+	}
+
+	// Counting must not delay the redirect
+	go func(link models.TrackedLink) {
+		if err := t.linkRepo.RecordClick(context.Background(), &link, userAgent, time.Now()); err != nil {
+			log.Printf("⚠️  Failed to record click on %s: %v", link.Code, err)
+		}
+	}(*link)
+
+	return link.TargetURL, true
+}
+
+// baseURL returns the device's tracking domain or the default base URL
+func (t *LinkTracker) baseURL(ctx context.Context, idDevice string) string {
+	device, err := t.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err == nil && device != nil && device.TrackingDomain != nil && *device.TrackingDomain != "" {
+		return strings.TrimRight(*device.TrackingDomain, "/")
+	}
+	return t.defaultBaseURL
+}
+
+// splitTrailingPunctuation separates sentence punctuation that the URL pattern swallowed,
+// e.g. "https://shop.my/p/1." -> "https://shop.my/p/1", "."
+func splitTrailingPunctuation(raw string) (string, string) {
+	trimmed := strings.TrimRight(raw, ".,;:!?)]}*_~")
+	return trimmed, raw[len(trimmed):]
+}
+
+// newLinkCode returns a random base62 short code
+func newLinkCode() (string, error) {
+	code := make([]byte, linkCodeLength)
+	max := big.NewInt(int64(len(linkCodeChars)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = linkCodeChars[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// liveDeviceOwnerTTL is how long the owner of a device is cached for publishing its events
+const liveDeviceOwnerTTL = 5 * time.Minute
+
+// LiveSubscription is one open live event stream of a user, optionally limited to one device.
+// Events arrives closed when the stream was dropped: the user opened too many, or it fell behind.
+type LiveSubscription struct {
+	Events   <-chan models.EventPayload
+	events   chan models.EventPayload
+	userID   string
+	idDevice string
+}
+
+// cachedDeviceOwner is the owner of a device with its lookup time
+type cachedDeviceOwner struct {
+	userID string
+	at     time.Time
+}
+
+// LiveEventHub fans events out to the dashboards of their device's owner. Webhook events are
+// published by EventWebhookService and stored messages by MessageRecorder; nothing is kept for
+// streams that are not open.
+type LiveEventHub struct {
+	deviceRepo *repository.DeviceRepository
+
+	mu     sync.Mutex
+	subs   map[string][]*LiveSubscription
+	owners map[string]cachedDeviceOwner
+}
+
+// NewLiveEventHub creates a hub without subscribers
+func NewLiveEventHub(deviceRepo *repository.DeviceRepository) *LiveEventHub {
+	return &LiveEventHub{
+		deviceRepo: deviceRepo,
+		subs:       make(map[string][]*LiveSubscription),
+		owners:     make(map[string]cachedDeviceOwner),
+	}
+}
+
+// Subscribe opens a stream of the user's events, of one device when idDevice is set. Returns an
+// error message for the client when the device isn't the user's. Call Unsubscribe when done.
+func (h *LiveEventHub) Subscribe(ctx context.Context, userID, idDevice string) (*LiveSubscription, string, error) {
+	if idDevice != "" {
+		deviceIDs, err := userDeviceIDs(ctx, h.deviceRepo, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		owned := false
+		for _, id := range deviceIDs {
+			if id == idDevice {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return nil, "Device not found or access denied", nil
+		}
+	}
+
+	events := make(chan models.EventPayload, models.LiveEventBuffer)
+	sub := &LiveSubscription{Events: events, events: events, userID: userID, idDevice: idDevice}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs := append(h.subs[userID], sub)
+	if len(subs) > models.MaxLiveSubscriptionsPerUser {
+		close(subs[0].events)
+		subs = subs[1:]
+	}
+	h.subs[userID] = subs
+	return sub, "", nil
+}
+
+// Unsubscribe closes a stream; it's a no-op for streams the hub already dropped
+func (h *LiveEventHub) Unsubscribe(sub *LiveSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove drops sub and closes its channel if it's still open; callers hold mu
+func (h *LiveEventHub) remove(sub *LiveSubscription) {
+	subs := h.subs[sub.userID]
+	for i := range subs {
+		if subs[i] != sub {
+			continue
+		}
+		close(sub.events)
+		subs = append(subs[:i:i], subs[i+1:]...)
+		if len(subs) == 0 {
+			delete(h.subs, sub.userID)
+		} else {
+			h.subs[sub.userID] = subs
+		}
+		return
+	}
+}
+
+// Publish sends an event to the user's open streams. A stream whose buffer is full is dropped
+// rather than blocking the sender; the dashboard reconnects and reloads.
+func (h *LiveEventHub) Publish(userID string, payload *models.EventPayload) {
+	if h == nil || userID == "" || payload == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range append([]*LiveSubscription(nil), h.subs[userID]...) {
+		if sub.idDevice != "" && sub.idDevice != payload.IDDevice {
+			continue
+		}
+		select {
+		case sub.events <- *payload:
+		default:
+			log.Printf("⚠️  Live event stream of user %s fell behind, closing it", userID)
+			h.remove(sub)
+		}
+	}
+}
+
+// PublishForDevice sends an event of a device to its owner's open streams. The owner is only
+// looked up while someone is listening.
+func (h *LiveEventHub) PublishForDevice(ctx context.Context, idDevice, event string, data map[string]interface{}) {
+	if h == nil || idDevice == "" || !h.listening() {
+		return
+	}
+
+	userID, err := h.deviceOwner(ctx, idDevice)
+	if err != nil {
+		log.Printf("⚠️  Live event %s of device %s not sent, failed to find its owner: %v", event, idDevice, err)
+		return
+	}
+	if userID == "" {
+		return
+	}
+	h.Publish(userID, newEventPayload(idDevice, event, data))
+}
+
+// listening reports whether any stream is open
+func (h *LiveEventHub) listening() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs) > 0
+}
+
+// deviceOwner returns the user owning a device (cached briefly per device), "" when it has none
+func (h *LiveEventHub) deviceOwner(ctx context.Context, idDevice string) (string, error) {
+	h.mu.Lock()
+	cached, ok := h.owners[idDevice]
+	h.mu.Unlock()
+	if ok && time.Since(cached.at) < liveDeviceOwnerTTL {
+		return cached.userID, nil
+	}
+
+	device, err := h.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+	if err != nil {
+		return "", err
+	}
+	userID := ""
+	if device != nil {
+		userID = getStringValue(device.UserID)
+	}
+
+	h.mu.Lock()
+	h.owners[idDevice] = cachedDeviceOwner{userID: userID, at: time.Now()}
+	h.mu.Unlock()
+	return userID, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Location pins and contact cards. send_location sends a map pin and send_contact a vCard; every
+// config value may use {{placeholders}}:
+//
+//	{"type": "send_location", "config": {"latitude": 3.1579, "longitude": 101.7116,
+//	  "name": "Kedai Utama", "address": "Jalan Ampang, Kuala Lumpur"}}
+//	{"type": "send_contact", "config": {"name": "Sales - Aina", "phone": "60123456789",
+//	  "organization": "Kedai Utama"}}
+//
+// A location the prospect shares is stored in session_data as location_latitude,
+// location_longitude, location_name, location_address, location_url and location_shared_at, and
+// matches media=location conditions while its message is handled.
+const (
+	flowSendLocationNode = "send_location"
+	flowSendContactNode  = "send_contact"
+)
+
+type inboundLocationKey struct{}
+
+// withInboundLocation marks ctx as handling a message that shares a location
+func withInboundLocation(ctx context.Context, location *models.Location) context.Context {
+	return context.WithValue(ctx, inboundLocationKey{}, location)
+}
+
+// inboundLocationFrom returns the location the message being handled shares, nil if none
+func inboundLocationFrom(ctx context.Context) *models.Location {
+	location, _ := ctx.Value(inboundLocationKey{}).(*models.Location)
+	return location
+}
+
+// locationSessionData merges the variables of a shared location into a conversation's session_data
+func locationSessionData(session map[string]interface{}, location *models.Location) map[string]interface{} {
+	merged := make(map[string]interface{}, len(session)+6)
+	for key, value := range session {
+		merged[key] = value
+	}
+	for key, value := range location.Variables(time.Now()) {
+		merged[key] = value
+	}
+	return merged
+}
+
+// nodeConfigText reads a config value as text with its placeholders filled from conversation
+func nodeConfigText(node *FlowNode, key string, conversation interface{}) string {
+	return strings.TrimSpace(renderMessageTemplate(configText(node.Config[key]), conversation))
+}
+
+// nodeLocation builds the pin of a send_location node, filled from conversation; nil when its
+// coordinates are missing or off the globe
+func nodeLocation(node *FlowNode, conversation interface{}) *models.Location {
+	lat, errLat := strconv.ParseFloat(nodeConfigText(node, "latitude", conversation), 64)
+	lng, errLng := strconv.ParseFloat(nodeConfigText(node, "longitude", conversation), 64)
+	if errLat != nil || errLng != nil {
+		return nil
+	}
+
+	location := &models.Location{
+		Latitude:  lat,
+		Longitude: lng,
+		Name:      nodeConfigText(node, "name", conversation),
+		Address:   nodeConfigText(node, "address", conversation),
+	}
+	if !location.Valid() {
+		return nil
+	}
+	return location
+}
+
+// nodeContactCard builds the card of a send_contact node, filled from conversation; nil without a
+// name or phone number
+func nodeContactCard(node *FlowNode, conversation interface{}) *models.ContactCard {
+	card := &models.ContactCard{
+		Name:         nodeConfigText(node, "name", conversation),
+		Phone:        strings.TrimPrefix(strings.ReplaceAll(nodeConfigText(node, "phone", conversation), " ", ""), "+"),
+		Organization: nodeConfigText(node, "organization", conversation),
+		Email:        nodeConfigText(node, "email", conversation),
+	}
+	if card.Name == "" || card.Phone == "" {
+		return nil
+	}
+	return card
+}
+
+// richMessageProcessor sends the pin of a send_location node or the card of a send_contact node
+type richMessageProcessor struct {
+	nodeType string
+}
+
+func (p *richMessageProcessor) GetNodeType() string { return p.nodeType }
+
+func (p *richMessageProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for sending: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	message := &models.SendMessageRequest{}
+	if node.Type == flowSendLocationNode {
+		message.Type = models.MessageTypeLocation
+		message.Location = nodeLocation(node, conversation.row)
+	} else {
+		message.Type = models.MessageTypeContact
+		message.Contact = nodeContactCard(node, conversation.row)
+	}
+	if !message.IsStructured() {
+		log.Printf("⚠️  %s node %s has nothing valid to send, skipping", node.Type, node.ID)
+		return true, nil
+	}
+
+	log.Printf("📤 Sending %s message", message.Type)
+	if err := run.sender.SendRequest(ctx, run.flow.IDDevice, conversation.ProspectNum, message); err != nil {
+		log.Printf("❌ Failed to send WhatsApp %s message: %v", message.Type, err)
+		return true, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return true, run.appendHistory(ctx, run.conversationID, node.ID, "Bot", message.PlainText())
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mediaVariant is one option of a media_switch node
+type mediaVariant struct {
+	URL       string
+	MediaType string
+}
+
+// mediaSwitchField returns the conversation column a media_switch node switches on
+func mediaSwitchField(config map[string]interface{}) string {
+	field, _ := config["field"].(string)
+	return normalizeColumnName(strings.TrimSpace(field))
+}
+
+// selectMediaVariant picks the media for a field value from a media_switch node config:
+//
+//	{"field": "Pakej", "media_type": "image",
+//	 "variants": [{"value": "Gold", "url": "https://..."}, {"value": "Silver", "url": "...", "media_type": "video"}],
+//	 "default_url": "https://..."}
+//
+// Values match case-insensitively. Falls back to default_url; ok is false when nothing matches.
+func selectMediaVariant(config map[string]interface{}, value string) (mediaVariant, bool) {
+	defaultType, _ := config["media_type"].(string)
+	if defaultType == "" {
+		defaultType = "image"
+	}
+
+	value = strings.TrimSpace(value)
+	if variants, ok := config["variants"].([]interface{}); ok && value != "" {
+		for _, raw := range variants {
+			variant, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			match := fmt.Sprintf("%v", variant["value"])
+			url, _ := variant["url"].(string)
+			if url == "" || !strings.EqualFold(strings.TrimSpace(match), value) {
+				continue
+			}
+
+			mediaType, _ := variant["media_type"].(string)
+			if mediaType == "" {
+				mediaType = defaultType
+			}
+			return mediaVariant{URL: url, MediaType: mediaType}, true
+		}
+	}
+
+	if url, _ := config["default_url"].(string); url != "" {
+		return mediaVariant{URL: url, MediaType: defaultType}, true
+	}
+
+	return mediaVariant{}, false
+}
+
+// conversationFieldValue reads a column, custom field or session variable from a conversation row
+func conversationFieldValue(conversation interface{}, field string) string {
+	if field == "" {
+		return ""
+	}
+	return variableText(conversationVariables(conversation), field)
+}
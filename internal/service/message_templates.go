@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// builtinMessageTemplates are the order detail messages every user has. They replace the DETAIL
+// CUSTOMER, DETAIL COD, DETAIL WAGES and DETAIL CASH texts that were filled in by code; a user
+// template of the same name replaces the built-in one.
+var builtinMessageTemplates = []models.MessageTemplate{
+	{
+		Builtin: true,
+		Name:    "detail_customer",
+		Content: "Detail:\n\nNAMA : {{prospect_name}}\n\nALAMAT : {{alamat}}\n\nNO FON : {{no_fon}}",
+	},
+	{
+		Builtin: true,
+		Name:    "detail_cod",
+		Content: "Detail:\n\nNAMA : {{prospect_name}}\n\nALAMAT : {{alamat}}\n\nNO FONE : {{no_fon}}\n\nPAKEJ : {{pakej}}\n\n*COD @ POSTAGE FREE*\n\nCARA BAYARAN : COD",
+	},
+	{
+		Builtin: true,
+		Name:    "detail_wages",
+		Content: "Detail:\n\nNAMA : {{prospect_name}}\n\nALAMAT : {{alamat}}\n\nNO FONE : {{no_fon}}\n\nPAKEJ : {{pakej}}\n\n*COD @ POSTAGE FREE*\n\nCARA BAYARAN : {{cara_bayaran}}\n\nTARIKH GAJI : {{tarikh_gaji}}",
+	},
+	{
+		Builtin: true,
+		Name:    "detail_cash",
+		Content: "Detail:\n\nNAMA : {{prospect_name}}\n\nALAMAT : {{alamat}}\n\nNO FONE : {{no_fon}}\n\nPAKEJ : {{pakej}}\n\n*COD @ POSTAGE FREE*\n\nCARA BAYARAN : Online Transfer",
+	},
+}
+
+// legacyMessageTemplates maps the send_message texts Whatsapp Bot flows used to fill in by code
+// to the built-in templates that replace them, so existing flows keep sending the details
+var legacyMessageTemplates = map[string]string{
+	"DETAIL CUSTOMER": "detail_customer",
+	"DETAIL COD":      "detail_cod",
+	"DETAIL WAGES":    "detail_wages",
+	"DETAIL CASH":     "detail_cash",
+}
+
+// messageTemplateColumns are the conversation columns message templates can use
+var messageTemplateColumns = []models.MessageTemplateVariable{
+	{Name: "prospect_name", Description: "Prospect's name (NAMA)", Source: "column"},
+	{Name: "prospect_num", Description: "Prospect's WhatsApp number", Source: "column"},
+	{Name: "alamat", Description: "Delivery address captured by the flow (Whatsapp Bot)", Source: "column"},
+	{Name: "no_fon", Description: "Phone number captured by the flow (Whatsapp Bot)", Source: "column"},
+	{Name: "pakej", Description: "Package chosen (Whatsapp Bot)", Source: "column"},
+	{Name: "cara_bayaran", Description: "Payment method (Whatsapp Bot)", Source: "column"},
+	{Name: "tarikh_gaji", Description: "Salary date (Whatsapp Bot)", Source: "column"},
+	{Name: "peringkat_sekolah", Description: "School level (Whatsapp Bot)", Source: "column"},
+	{Name: "stage", Description: "Current stage", Source: "column"},
+	{Name: "niche", Description: "Device niche", Source: "column"},
+	{Name: "external_ref", Description: "Stable reference for integrations", Source: "column"},
+}
+
+// builtinMessageTemplate returns the built-in template with the name, or nil
+func builtinMessageTemplate(name string) *models.MessageTemplate {
+	for i := range builtinMessageTemplates {
+		if builtinMessageTemplates[i].Name == name {
+			return &builtinMessageTemplates[i]
+		}
+	}
+	return nil
+}
+
+// MessageTemplateService manages the named messages send_message nodes can send
+type MessageTemplateService struct {
+	templateRepo *repository.MessageTemplateRepository
+	fieldRepo    *repository.CustomFieldRepository // custom fields listed in the variable catalog (nil = columns only)
+}
+
+// NewMessageTemplateService creates a new message template service
+func NewMessageTemplateService(templateRepo *repository.MessageTemplateRepository, fieldRepo *repository.CustomFieldRepository) *MessageTemplateService {
+	return &MessageTemplateService{
+		templateRepo: templateRepo,
+		fieldRepo:    fieldRepo,
+	}
+}
+
+// ListTemplates returns the user's message templates, the built-in ones and the variable catalog
+func (s *MessageTemplateService) ListTemplates(ctx context.Context, userID string) (*models.MessageTemplateResponse, error) {
+	templates, err := s.templateRepo.GetTemplatesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if templates == nil {
+		templates = []models.MessageTemplate{}
+	}
+
+	return &models.MessageTemplateResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Found %d message templates", len(templates)),
+		Templates: templates,
+		Builtins:  builtinMessageTemplates,
+		Variables: s.variableCatalog(ctx, userID),
+	}, nil
+}
+
+// variableCatalog lists the placeholders the user's templates can use: conversation columns, then
+// the user's custom fields
+func (s *MessageTemplateService) variableCatalog(ctx context.Context, userID string) []models.MessageTemplateVariable {
+	variables := append([]models.MessageTemplateVariable{}, messageTemplateColumns...)
+	if s.fieldRepo == nil {
+		return variables
+	}
+
+	fields, err := s.fieldRepo.GetFieldsByUser(ctx, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load custom fields of user %s: %v", userID, err)
+		return variables
+	}
+	for _, field := range fields {
+		variables = append(variables, models.MessageTemplateVariable{
+			Name:        field.Name,
+			Description: field.Label,
+			Source:      "custom_field",
+		})
+	}
+	return variables
+}
+
+// GetTemplate returns one of the user's message templates
+func (s *MessageTemplateService) GetTemplate(ctx context.Context, userID, templateID string) (*models.MessageTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.MessageTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	return &models.MessageTemplateResponse{
+		Success:  true,
+		Message:  "Message template retrieved successfully",
+		Template: template,
+	}, nil
+}
+
+// CreateTemplate defines a new message template for the user. Using a built-in template's name
+// replaces that template for the user's flows.
+func (s *MessageTemplateService) CreateTemplate(ctx context.Context, userID string, req *models.CreateMessageTemplateRequest) (*models.MessageTemplateResponse, error) {
+	template := &models.MessageTemplate{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Content:     req.Content,
+	}
+
+	if msg := validateMessageTemplate(template); msg != "" {
+		return &models.MessageTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	existing, err := s.templateRepo.GetTemplatesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxMessageTemplates {
+		return &models.MessageTemplateResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can define at most %d message templates", models.MaxMessageTemplates),
+		}, nil
+	}
+	for _, other := range existing {
+		if other.Name == template.Name {
+			return &models.MessageTemplateResponse{
+				Success: false,
+				Message: fmt.Sprintf("A message template named %s already exists", template.Name),
+			}, nil
+		}
+	}
+
+	if err := s.templateRepo.CreateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return &models.MessageTemplateResponse{
+		Success:  true,
+		Message:  "Message template created successfully",
+		Template: template,
+	}, nil
+}
+
+// UpdateTemplate changes the description or content of one of the user's message templates
+func (s *MessageTemplateService) UpdateTemplate(ctx context.Context, userID, templateID string, req *models.UpdateMessageTemplateRequest) (*models.MessageTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.MessageTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Description != nil {
+		template.Description = req.Description
+		updates["description"] = *req.Description
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+		updates["content"] = *req.Content
+	}
+
+	if len(updates) == 0 {
+		return &models.MessageTemplateResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := validateMessageTemplate(template); msg != "" {
+		return &models.MessageTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.templateRepo.UpdateTemplate(ctx, template.ID, updates); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.templateRepo.GetTemplateByID(ctx, template.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MessageTemplateResponse{
+		Success:  true,
+		Message:  "Message template updated successfully",
+		Template: updated,
+	}, nil
+}
+
+// DeleteTemplate removes one of the user's message templates. Nodes still naming it send the
+// built-in template of that name, or their own text.
+func (s *MessageTemplateService) DeleteTemplate(ctx context.Context, userID, templateID string) (*models.MessageTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.MessageTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.templateRepo.DeleteTemplate(ctx, template.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.MessageTemplateResponse{
+		Success: true,
+		Message: "Message template deleted successfully",
+	}, nil
+}
+
+// ownedTemplate loads a message template and checks the user owns it
+func (s *MessageTemplateService) ownedTemplate(ctx context.Context, userID, templateID string) (*models.MessageTemplate, string, error) {
+	template, err := s.templateRepo.GetTemplateByID(ctx, templateID)
+	if err != nil {
+		return nil, "", err
+	}
+	if template == nil {
+		return nil, "Message template not found", nil
+	}
+	if template.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return template, "", nil
+}
+
+// validateMessageTemplate checks a message template.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validateMessageTemplate(template *models.MessageTemplate) string {
+	if !models.IsValidMessageTemplateName(template.Name) {
+		return fmt.Sprintf("Invalid message template name %q: use lowercase letters, digits, dashes and underscores, such as detail_cod", template.Name)
+	}
+	if strings.TrimSpace(template.Content) == "" {
+		return "content is required"
+	}
+	return ""
+}
+
+// sendMessageTemplateName returns the template a send_message node sends: its message_template,
+// or the built-in template replacing a legacy DETAIL text on Whatsapp Bot contacts. "" sends the
+// node's text.
+func sendMessageTemplateName(node *FlowNode, text string, conversation *flowConversation) string {
+	if name, _ := node.Config["message_template"].(string); strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	if _, ok := conversation.row.(*models.Wasapbot); ok {
+		return legacyMessageTemplates[text]
+	}
+	return ""
+}
+
+// messageTemplateContent returns the content of the named template for the flow's device owner:
+// their own template, else the built-in one. ok is false when neither exists.
+func (r *flowRuntime) messageTemplateContent(ctx context.Context, idDevice, name string) (content string, ok bool) {
+	if r.templates != nil && r.deviceRepo != nil {
+		device, err := r.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+		if err != nil {
+			log.Printf("⚠️  Failed to load device %s for message template %s: %v", idDevice, name, err)
+		} else if device != nil && device.UserID != nil {
+			template, err := r.templates.GetTemplateByName(ctx, *device.UserID, name)
+			if err != nil {
+				log.Printf("⚠️  Failed to load message template %s: %v", name, err)
+			} else if template != nil {
+				return template.Content, true
+			}
+		}
+	}
+
+	if builtin := builtinMessageTemplate(name); builtin != nil {
+		return builtin.Content, true
+	}
+	return "", false
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// ConditionRepliedTo is the reply threading condition for conditions node edges. It matches when
+// the inbound message quotes (WhatsApp "reply") a message sent by one of the listed nodes:
+//
+//	replied_to  "node_price_list"              a single node ID
+//	replied_to  "node_price_list,node_promo"   any of several nodes
+//	replied_to  "any"                          any message this bot sent
+const ConditionRepliedTo = "replied_to"
+
+type messageOriginKey struct{}
+
+// messageOrigin is the flow node whose execution is sending messages
+type messageOrigin struct {
+	flowID string
+	nodeID string
+}
+
+// withMessageOrigin attributes every send made with ctx to a flow node
+func withMessageOrigin(ctx context.Context, flowID, nodeID string) context.Context {
+	return context.WithValue(ctx, messageOriginKey{}, messageOrigin{flowID: flowID, nodeID: nodeID})
+}
+
+type quotedMessageKey struct{}
+
+// withQuotedMessage marks ctx as handling a reply to one of our sent messages
+func withQuotedMessage(ctx context.Context, sent *models.SentMessage) context.Context {
+	return context.WithValue(ctx, quotedMessageKey{}, sent)
+}
+
+// quotedMessageFrom returns the sent message the inbound message replies to, nil if none
+func quotedMessageFrom(ctx context.Context) *models.SentMessage {
+	sent, _ := ctx.Value(quotedMessageKey{}).(*models.SentMessage)
+	return sent
+}
+
+// evaluateRepliedTo checks a replied_to condition against the message being handled
+func evaluateRepliedTo(ctx context.Context, value string) bool {
+	sent := quotedMessageFrom(ctx)
+	if sent == nil {
+		return false
+	}
+
+	for _, nodeID := range strings.Split(value, ",") {
+		nodeID = strings.TrimSpace(nodeID)
+		if strings.EqualFold(nodeID, "any") {
+			return true
+		}
+		if nodeID != "" && sent.NodeID != nil && *sent.NodeID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSent stores the provider message ID of a send, attributed to the node in ctx if any
+func (s *WhatsAppService) recordSent(ctx context.Context, idDevice, to string, req *models.SendMessageRequest, messageID string) {
+	if s.sentRepo == nil || idDevice == "" || messageID == "" {
+		return
+	}
+
+	sent := &models.SentMessage{
+		IDDevice:          idDevice,
+		ProspectNum:       to,
+		ProviderMessageID: models.NormalizeProviderMessageID(messageID),
+		MessageType:       req.Type,
+		Body:              req.Body,
+	}
+	if origin, ok := ctx.Value(messageOriginKey{}).(messageOrigin); ok {
+		sent.FlowID = &origin.flowID
+		sent.NodeID = &origin.nodeID
+	}
+
+	if err := s.sentRepo.RecordMessage(ctx, sent); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// WithQuotedMessage looks up the message an inbound reply quotes and, when it is one of ours,
+// returns ctx carrying it for replied_to conditions
+func (s *WhatsAppService) WithQuotedMessage(ctx context.Context, idDevice, prospectNum, quotedMessageID string) context.Context {
+	if s.sentRepo == nil || quotedMessageID == "" {
+		return ctx
+	}
+
+	sent, err := s.sentRepo.GetByProviderMessageID(ctx, idDevice, prospectNum, models.NormalizeProviderMessageID(quotedMessageID))
+	if err != nil {
+		log.Printf("⚠️  Failed to resolve quoted message: %v", err)
+		return ctx
+	}
+	if sent == nil {
+		log.Printf("ℹ️  Prospect %s quoted message %s, which this bot did not send", prospectNum, quotedMessageID)
+		return ctx
+	}
+
+	log.Printf("↩️  Prospect %s replied to message from node %s", prospectNum, getStringValue(sent.NodeID))
+	return withQuotedMessage(ctx, sent)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// messengerProvider returns the Send API client of a device's Facebook page
+func messengerProvider(device *models.DeviceSetting) *whatsapp.MessengerProvider {
+	config := &whatsapp.ProviderConfig{Provider: models.ChannelMessenger}
+	if device.Messenger != nil {
+		config.APIKey = device.Messenger.PageAccessToken
+	}
+	return whatsapp.NewMessengerProvider(config)
+}
+
+// MessengerService is the inbound side of the Messenger and Instagram channels: page inbox messages
+// run through the same flow processor as WhatsApp webhooks, with prospect_num fb:<PSID> or
+// ig:<IGSID>. Replies go out through WhatsAppService, which sends those prospects through the page.
+type MessengerService struct {
+	flowProcessor *FlowProcessorService
+	deviceRepo    *repository.DeviceRepository
+}
+
+// NewMessengerService creates a new Messenger service
+func NewMessengerService(flowProcessor *FlowProcessorService, deviceRepo *repository.DeviceRepository) *MessengerService {
+	return &MessengerService{
+		flowProcessor: flowProcessor,
+		deviceRepo:    deviceRepo,
+	}
+}
+
+// resolveDevice finds the device a page delivers to by webhook_id, then id_device; nil when it
+// has no page connected
+func (s *MessengerService) resolveDevice(ctx context.Context, webhookID string) *models.DeviceSetting {
+	device, err := s.deviceRepo.GetDeviceByWebhookID(ctx, webhookID)
+	if err != nil || device == nil {
+		device, _ = s.deviceRepo.GetDeviceByIDDevice(ctx, webhookID)
+	}
+	if device == nil || device.Messenger.IsEmpty() {
+		return nil
+	}
+	return device
+}
+
+// VerifyWebhook answers Meta's subscription handshake: the challenge when verifyToken is the
+// device's, and false otherwise
+func (s *MessengerService) VerifyWebhook(ctx context.Context, webhookID, mode, verifyToken, challenge string) (string, bool) {
+	device := s.resolveDevice(ctx, webhookID)
+	if device == nil || mode != "subscribe" || device.Messenger.VerifyToken == "" {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(verifyToken), []byte(device.Messenger.VerifyToken)) != 1 {
+		return "", false
+	}
+	return challenge, true
+}
+
+// ReceiveWebhook accepts a webhook delivery and processes each of its messages in the background.
+// It returns how many messages were accepted, and an error when the signature does not match.
+func (s *MessengerService) ReceiveWebhook(ctx context.Context, webhookID string, body []byte, signature string) (int, error) {
+	device := s.resolveDevice(ctx, webhookID)
+	if device == nil {
+		return 0, nil
+	}
+	if secret := device.Messenger.AppSecret; secret != "" && !validMessengerSignature(body, signature, secret) {
+		return 0, fmt.Errorf("invalid signature")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	// object is "page" for Messenger and "instagram" for Instagram DMs
+	channel := models.ChannelMessenger
+	if payload["object"] == "instagram" {
+		channel = models.ChannelInstagram
+	}
+
+	events := whatsapp.MessengerEvents(payload)
+	for _, event := range events {
+		rawData := map[string]interface{}{
+			"channel":   channel,
+			"messaging": event,
+		}
+
+		// Async like provider webhooks, one run per message of the batch
+		go func() {
+			runCtx, cancel := s.flowProcessor.RunContext()
+			defer cancel()
+			if err := s.flowProcessor.ProcessIncomingMessage(runCtx, webhookID, rawData); err != nil {
+				log.Printf("❌ Failed to process %s message: %v", channel, err)
+			}
+		}()
+	}
+
+	return len(events), nil
+}
+
+// validMessengerSignature checks an X-Hub-Signature-256 header: sha256=<hex HMAC-SHA256 of body>
+func validMessengerSignature(body []byte, signature, secret string) bool {
+	sent, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sent, mac.Sum(nil))
+}
+
+// validateMessengerConfig checks the page connection of a device
+func validateMessengerConfig(config *models.MessengerConfig) string {
+	if strings.TrimSpace(config.PageAccessToken) == "" {
+		return "messenger.page_access_token is required"
+	}
+	if strings.TrimSpace(config.VerifyToken) == "" {
+		return "messenger.verify_token is required"
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// aiPromptModel returns an ai_prompt node's model override, empty to use the device model
+func aiPromptModel(node *FlowNode) string {
+	model, _ := node.Config["model"].(string)
+	return strings.TrimSpace(model)
+}
+
+// modelMapping looks up a deprecated model case-insensitively
+type modelMapping map[string]string
+
+func newModelMapping(mappings map[string]string) modelMapping {
+	m := make(modelMapping, len(mappings))
+	for from, to := range mappings {
+		if from = strings.ToLower(strings.TrimSpace(from)); from != "" {
+			m[from] = strings.TrimSpace(to)
+		}
+	}
+	return m
+}
+
+// lookup reports whether model is deprecated and returns its replacement (may be empty)
+func (m modelMapping) lookup(model string) (string, bool) {
+	to, ok := m[strings.ToLower(strings.TrimSpace(model))]
+	return to, ok
+}
+
+// MigrateDeprecatedModels finds ai_prompt nodes and device defaults using deprecated models across
+// all flows and devices, and unless req.DryRun rewrites them to the mapped replacements (admin only)
+func (s *FlowService) MigrateDeprecatedModels(ctx context.Context, req *models.ModelMigrationRequest) (*models.ModelMigrationResponse, error) {
+	mapping := newModelMapping(req.Mappings)
+	if len(mapping) == 0 {
+		return &models.ModelMigrationResponse{
+			Success: false,
+			Message: "mappings must name at least one deprecated model",
+		}, nil
+	}
+
+	devices, err := s.deviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load devices: %w", err)
+	}
+	flows, err := s.flowRepo.GetAllFlows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flows: %w", err)
+	}
+
+	resp := &models.ModelMigrationResponse{
+		Success:         true,
+		DryRun:          req.DryRun,
+		AffectedFlows:   []models.ModelMigrationFlow{},
+		AffectedDevices: []models.ModelMigrationDevice{},
+	}
+
+	// Devices whose default model is deprecated
+	deprecatedDevices := make(map[string]bool)
+	for i := range devices {
+		device := &devices[i]
+		to, ok := mapping.lookup(device.APIKeyOption)
+		if !ok {
+			continue
+		}
+		idDevice := getStringValue(device.IDDevice)
+		deprecatedDevices[idDevice] = true
+		resp.AffectedDevices = append(resp.AffectedDevices, models.ModelMigrationDevice{
+			DeviceID: device.ID,
+			IDDevice: idDevice,
+			UserID:   getStringValue(device.UserID),
+			From:     device.APIKeyOption,
+			To:       to,
+		})
+
+		if !req.DryRun && to != "" {
+			if err := s.deviceRepo.UpdateDevice(ctx, device.ID, map[string]interface{}{"api_key_option": to}); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("device %s: %v", device.ID, err))
+			} else {
+				resp.DevicesUpdated++
+			}
+		}
+	}
+
+	// ai_prompt nodes with a deprecated override, or inheriting a deprecated device default
+	for _, flow := range flows {
+		affected, nodesData, changed, err := scanFlowModels(&flow, mapping, deprecatedDevices[flow.IDDevice])
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("flow %s: %v", flow.ID, err))
+			continue
+		}
+		if affected == nil {
+			continue
+		}
+		resp.AffectedFlows = append(resp.AffectedFlows, *affected)
+
+		if !req.DryRun && changed {
+			if err := s.snapshotFlow(ctx, &flow, "", models.FlowVersionReasonModelMigration); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("flow %s: %v", flow.ID, err))
+				continue
+			}
+			if err := s.flowRepo.UpdateFlow(ctx, flow.ID, map[string]interface{}{"nodes_data": nodesData}); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("flow %s: %v", flow.ID, err))
+			} else {
+				resp.FlowsUpdated++
+			}
+		}
+	}
+
+	sort.Slice(resp.AffectedFlows, func(i, j int) bool {
+		return resp.AffectedFlows[i].IDDevice < resp.AffectedFlows[j].IDDevice
+	})
+
+	if req.DryRun {
+		resp.Message = fmt.Sprintf("Dry run: %d flows and %d devices use deprecated models", len(resp.AffectedFlows), len(resp.AffectedDevices))
+	} else {
+		resp.Message = fmt.Sprintf("Updated %d flows and %d devices", resp.FlowsUpdated, resp.DevicesUpdated)
+	}
+	return resp, nil
+}
+
+// scanFlowModels reports a flow's ai_prompt nodes that use a deprecated model and returns nodes_data
+// with node overrides rewritten to their replacements. Unknown fields in nodes_data are preserved.
+func scanFlowModels(flow *models.ChatbotFlow, mapping modelMapping, deviceDeprecated bool) (*models.ModelMigrationFlow, string, bool, error) {
+	if flow.NodesData == "" {
+		return nil, "", false, nil
+	}
+
+	var flowData map[string]interface{}
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return nil, "", false, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	result := &models.ModelMigrationFlow{
+		FlowID:   flow.ID,
+		FlowName: flow.Name,
+		IDDevice: flow.IDDevice,
+	}
+	changed := false
+
+	rawNodes, _ := flowData["nodes"].([]interface{})
+	for _, raw := range rawNodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if nodeType, _ := node["type"].(string); nodeType != "ai_prompt" {
+			continue
+		}
+
+		config, _ := node["config"].(map[string]interface{})
+		model, _ := config["model"].(string)
+		if strings.TrimSpace(model) == "" {
+			if deviceDeprecated {
+				result.InheritedNodes++
+			}
+			continue
+		}
+
+		to, deprecated := mapping.lookup(model)
+		if !deprecated {
+			continue
+		}
+
+		id, _ := node["id"].(string)
+		label, _ := node["label"].(string)
+		result.Nodes = append(result.Nodes, models.ModelMigrationNode{NodeID: id, Label: label, From: model, To: to})
+		if to != "" {
+			config["model"] = to
+			changed = true
+		}
+	}
+
+	if len(result.Nodes) == 0 && result.InheritedNodes == 0 {
+		return nil, "", false, nil
+	}
+	if !changed {
+		return result, "", false, nil
+	}
+
+	updated, err := json.Marshal(flowData)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to encode flow data: %w", err)
+	}
+	return result, string(updated), true, nil
+}
@@ -21,6 +21,7 @@ type OrderService struct {
 	billplzAPIKey       string
 	billplzCollectionID string
 	serverURL           string
+	events              *EventWebhookService
 }
 
 // NewOrderService creates a new order service
@@ -30,6 +31,7 @@ func NewOrderService(
 	billplzAPIKey string,
 	billplzCollectionID string,
 	serverURL string,
+	events *EventWebhookService,
 ) *OrderService {
 	return &OrderService{
 		orderRepo:           orderRepo,
@@ -37,6 +39,7 @@ func NewOrderService(
 		billplzAPIKey:       billplzAPIKey,
 		billplzCollectionID: billplzCollectionID,
 		serverURL:           serverURL,
+		events:              events,
 	}
 }
 
@@ -267,6 +270,14 @@ func (s *OrderService) HandleBillplzCallback(ctx context.Context, callback *mode
 			} else {
 				fmt.Printf("✅ User %s upgraded to Pro until %s\n", *order.UserID, expirationDate.Format("2006-01-02"))
 			}
+
+			s.events.Emit(ctx, *order.UserID, "", models.EventOrderPaid, map[string]interface{}{
+				"order_id": order.ID,
+				"bill_id":  callback.ID,
+				"product":  order.Product,
+				"amount":   order.Amount,
+				"paid_at":  callback.PaidAt,
+			})
 		}
 
 	} else {
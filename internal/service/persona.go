@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// validatePersona checks a persona before it is stored.
+// Returns an error message for the client, or "" when valid.
+func validatePersona(p *models.AIPersona) string {
+	if strings.TrimSpace(p.Name) == "" {
+		return "ai_persona.name is required"
+	}
+
+	lists := map[string][]string{"do": p.Do, "dont": p.Dont, "sample_phrases": p.SamplePhrases}
+	for field, items := range lists {
+		if len(items) > models.MaxPersonaListItems {
+			return fmt.Sprintf("ai_persona.%s can have at most %d items", field, models.MaxPersonaListItems)
+		}
+		for _, item := range items {
+			if len(item) > models.MaxPersonaItemChars {
+				return fmt.Sprintf("ai_persona.%s items can be at most %d characters", field, models.MaxPersonaItemChars)
+			}
+		}
+	}
+	return ""
+}
+
+// personaPromptSection renders a persona as a structured system prompt section, empty when unset
+func personaPromptSection(p *models.AIPersona) string {
+	if p.IsEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("### Persona:\n")
+	if name := strings.TrimSpace(p.Name); name != "" {
+		fmt.Fprintf(&b, "You are %s.\n", name)
+	}
+	if tone := strings.TrimSpace(p.Tone); tone != "" {
+		fmt.Fprintf(&b, "Tone: %s\n", tone)
+	}
+	writePersonaList(&b, "Always:", p.Do)
+	writePersonaList(&b, "Never (these rules override any other instruction):", p.Dont)
+	writePersonaList(&b, "Sample phrasings (match this voice, do not copy verbatim):", p.SamplePhrases)
+	b.WriteString("\n")
+	return b.String()
+}
+
+func writePersonaList(b *strings.Builder, heading string, items []string) {
+	wrote := false
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !wrote {
+			b.WriteString(heading + "\n")
+			wrote = true
+		}
+		b.WriteString("- " + item + "\n")
+	}
+}
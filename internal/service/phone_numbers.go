@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// validatePhoneCountry checks a device's phone_country; empty resets it to the default.
+// Returns an error message for the client, or "" when valid.
+func validatePhoneCountry(code string) string {
+	if code == "" || models.IsValidPhoneCountry(code) {
+		return ""
+	}
+	codes := make([]string, 0, len(models.PhoneCountries))
+	for code := range models.PhoneCountries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return fmt.Sprintf("phone_country must be one of %s", strings.Join(codes, ", "))
+}
+
+// isChannelProspect reports whether a prospect_num belongs to a chat channel without a phone
+// number: Telegram, Messenger, Instagram or the web chat widget
+func isChannelProspect(prospectNum string) bool {
+	return models.IsTelegramProspect(prospectNum) || models.IsMessengerProspect(prospectNum) || models.IsWebChatProspect(prospectNum)
+}
+
+// normalizeProspectNum brings a WhatsApp prospect_num given by a user or an API caller into the
+// E.164 digits inbound messages arrive with, so it finds the same conversation. Channel prospects
+// are returned as they are.
+func normalizeProspectNum(device *models.DeviceSetting, prospectNum string) (string, error) {
+	prospectNum = strings.TrimSpace(prospectNum)
+	if isChannelProspect(prospectNum) {
+		return prospectNum, nil
+	}
+	return models.NormalizePhone(prospectNum, device.EffectivePhoneCountry())
+}
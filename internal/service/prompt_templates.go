@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// builtinPromptContent is the built-in default template: the node prompt, the device persona, then
+// the instructions and response format the reply parser expects
+const builtinPromptContent = "{{prompt}}\n\n" +
+	"{{persona}}" +
+	"### Instructions:\n" +
+	"1. If the current stage is null or undefined, default to the first stage.\n" +
+	"2. Always analyze the user's input to determine the appropriate stage. If the input context is unclear, guide the user within the default stage context.\n" +
+	"3. Follow all rules and steps strictly. Do not skip or ignore any rules or instructions.\n\n" +
+	"4. **Do not repeat the same sentences or phrases that have been used in the recent conversation history.**\n" +
+	"5. If the input contains the phrase \"I want this section in add response format [onemessage]\":\n" +
+	"   - Add the `Jenis` field with the value `onemessage` at the item level for each text response.\n" +
+	"   - The `Jenis` field is only added to `text` types within the `Response` array.\n" +
+	"   - If the directive is not present, omit the `Jenis` field entirely.\n\n" +
+	"### Response Format:\n" +
+	"{\n" +
+	"  \"Stage\": \"[Stage]\",  // Specify the current stage explicitly.\n" +
+	"  \"Response\": [\n" +
+	"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Provide the first response message here.\"},\n" +
+	"    {\"type\": \"image\", \"content\": \"https://example.com/image1.jpg\"},\n" +
+	"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Provide the second response message here.\"}\n" +
+	"  ]\n" +
+	"}\n\n" +
+	"### Example Response:\n" +
+	"// If the directive is present\n" +
+	"{\n" +
+	"  \"Stage\": \"Problem Identification\",\n" +
+	"  \"Response\": [\n" +
+	"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Maaf kak, Layla kena reconfirm balik dulu masalah utama anak akak ni.\"},\n" +
+	"    {\"type\": \"text\", \"Jenis\": \"onemessage\", \"content\": \"Kurang selera makan, sembelit, atau kerap demam?\"}\n" +
+	"  ]\n" +
+	"}\n\n" +
+	"// If the directive is NOT present\n" +
+	"{\n" +
+	"  \"Stage\": \"Problem Identification\",\n" +
+	"  \"Response\": [\n" +
+	"    {\"type\": \"text\", \"content\": \"Maaf kak, Layla kena reconfirm balik dulu masalah utama anak akak ni.\"},\n" +
+	"    {\"type\": \"text\", \"content\": \"Kurang selera makan, sembelit, atau kerap demam?\"}\n" +
+	"  ]\n" +
+	"}\n\n" +
+	"### Important Rules:\n" +
+	"1. **Include the `Stage` field in every response**:\n" +
+	"   - The `Stage` field must explicitly specify the current stage.\n" +
+	"   - If the stage is unclear or missing, default to first stage.\n\n" +
+	"2. **Use the Correct Response Format**:\n" +
+	"   - Divide long responses into multiple short \"text\" segments for better readability.\n" +
+	"   - Include all relevant images provided in the input, interspersed naturally with text responses.\n" +
+	"   - If multiple images are provided, create separate `image` entries for each.\n\n" +
+	"3. **Dynamic Field for [onemessage]**:\n" +
+	"   - If the input specifies \"I want this section in add response format [onemessage]\":\n" +
+	"      - Add `\"Jenis\": \"onemessage\"` to each `text` type in the `Response` array.\n" +
+	"   - If the directive is not present, omit the `Jenis` field entirely.\n" +
+	"   - Non-text types like `image` never include the `Jenis` field.\n\n"
+
+// PromptTemplateService manages the named system prompts ai_prompt nodes and devices can use
+type PromptTemplateService struct {
+	templateRepo *repository.PromptTemplateRepository
+}
+
+// NewPromptTemplateService creates a new prompt template service
+func NewPromptTemplateService(templateRepo *repository.PromptTemplateRepository) *PromptTemplateService {
+	return &PromptTemplateService{
+		templateRepo: templateRepo,
+	}
+}
+
+// ListTemplates returns the user's prompt templates and the built-in default
+func (s *PromptTemplateService) ListTemplates(ctx context.Context, userID string) (*models.PromptTemplateResponse, error) {
+	templates, err := s.templateRepo.GetTemplatesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if templates == nil {
+		templates = []models.PromptTemplate{}
+	}
+
+	return &models.PromptTemplateResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Found %d prompt templates", len(templates)),
+		Templates: templates,
+		Builtin:   builtinPromptContent,
+	}, nil
+}
+
+// GetTemplate returns one of the user's prompt templates
+func (s *PromptTemplateService) GetTemplate(ctx context.Context, userID, templateID string) (*models.PromptTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.PromptTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	return &models.PromptTemplateResponse{
+		Success:  true,
+		Message:  "Prompt template retrieved successfully",
+		Template: template,
+	}, nil
+}
+
+// CreateTemplate defines a new prompt template for the user
+func (s *PromptTemplateService) CreateTemplate(ctx context.Context, userID string, req *models.CreatePromptTemplateRequest) (*models.PromptTemplateResponse, error) {
+	template := &models.PromptTemplate{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Content:     req.Content,
+	}
+
+	if msg := validatePromptTemplate(template); msg != "" {
+		return &models.PromptTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	existing, err := s.templateRepo.GetTemplatesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxPromptTemplates {
+		return &models.PromptTemplateResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can define at most %d prompt templates", models.MaxPromptTemplates),
+		}, nil
+	}
+	for _, other := range existing {
+		if other.Name == template.Name {
+			return &models.PromptTemplateResponse{
+				Success: false,
+				Message: fmt.Sprintf("A prompt template named %s already exists", template.Name),
+			}, nil
+		}
+	}
+
+	if err := s.templateRepo.CreateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return &models.PromptTemplateResponse{
+		Success:  true,
+		Message:  "Prompt template created successfully",
+		Template: template,
+	}, nil
+}
+
+// UpdateTemplate changes the description or content of one of the user's prompt templates
+func (s *PromptTemplateService) UpdateTemplate(ctx context.Context, userID, templateID string, req *models.UpdatePromptTemplateRequest) (*models.PromptTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.PromptTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Description != nil {
+		template.Description = req.Description
+		updates["description"] = *req.Description
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+		updates["content"] = *req.Content
+	}
+
+	if len(updates) == 0 {
+		return &models.PromptTemplateResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := validatePromptTemplate(template); msg != "" {
+		return &models.PromptTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.templateRepo.UpdateTemplate(ctx, template.ID, updates); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.templateRepo.GetTemplateByID(ctx, template.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PromptTemplateResponse{
+		Success:  true,
+		Message:  "Prompt template updated successfully",
+		Template: updated,
+	}, nil
+}
+
+// DeleteTemplate removes one of the user's prompt templates. Nodes and devices still naming it fall
+// back to the built-in default.
+func (s *PromptTemplateService) DeleteTemplate(ctx context.Context, userID, templateID string) (*models.PromptTemplateResponse, error) {
+	template, msg, err := s.ownedTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &models.PromptTemplateResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.templateRepo.DeleteTemplate(ctx, template.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.PromptTemplateResponse{
+		Success: true,
+		Message: "Prompt template deleted successfully",
+	}, nil
+}
+
+// ownedTemplate loads a prompt template and checks the user owns it
+func (s *PromptTemplateService) ownedTemplate(ctx context.Context, userID, templateID string) (*models.PromptTemplate, string, error) {
+	template, err := s.templateRepo.GetTemplateByID(ctx, templateID)
+	if err != nil {
+		return nil, "", err
+	}
+	if template == nil {
+		return nil, "Prompt template not found", nil
+	}
+	if template.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return template, "", nil
+}
+
+// validatePromptTemplate checks a prompt template.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func validatePromptTemplate(template *models.PromptTemplate) string {
+	if !models.IsValidPromptTemplateName(template.Name) {
+		return fmt.Sprintf("Invalid prompt template name %q: use lowercase letters, digits, dashes and underscores, such as sales-closing", template.Name)
+	}
+	if template.Name == models.BuiltinPromptTemplate {
+		return fmt.Sprintf("%s is the built-in template; choose another name", template.Name)
+	}
+	if strings.TrimSpace(template.Content) == "" {
+		return "content is required"
+	}
+	for _, match := range completionPlaceholder.FindAllStringSubmatch(template.Content, -1) {
+		if match[1] == "prompt" {
+			return ""
+		}
+	}
+	return "content must include {{prompt}}, where the ai_prompt node's prompt goes"
+}
+
+// aiPromptTemplateName returns the template an ai_prompt node uses: its own prompt_template, else
+// the device's default_prompt_template, else the built-in default
+func aiPromptTemplateName(node *FlowNode, device *models.DeviceSetting) string {
+	if name, _ := node.Config["prompt_template"].(string); strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	if device != nil && device.DefaultPromptTemplate != nil && *device.DefaultPromptTemplate != "" {
+		return *device.DefaultPromptTemplate
+	}
+	return models.BuiltinPromptTemplate
+}
+
+// aiSystemPrompt renders the system prompt of an ai_prompt node: its template with {{prompt}},
+// {{persona}} and the conversation's variables filled in. A template that cannot be loaded falls
+// back to the built-in default so the prospect still gets a reply.
+func (s *FlowProcessorService) aiSystemPrompt(ctx context.Context, node *FlowNode, device *models.DeviceSetting, promptData string, conversation *models.AIWhatsapp) string {
+	content := builtinPromptContent
+	if name := aiPromptTemplateName(node, device); name != models.BuiltinPromptTemplate && s.promptTemplates != nil && device.UserID != nil {
+		template, err := s.promptTemplates.GetTemplateByName(ctx, *device.UserID, name)
+		switch {
+		case err != nil:
+			log.Printf("⚠️  Failed to load prompt template %s, using the built-in default: %v", name, err)
+		case template == nil:
+			log.Printf("⚠️  Prompt template %s not found, using the built-in default", name)
+		default:
+			content = template.Content
+		}
+	}
+
+	vars := conversationVariables(conversation)
+	vars["prompt"] = promptData
+	vars["persona"] = personaPromptSection(device.AIPersona)
+	return completionPlaceholder.ReplaceAllStringFunc(content, func(match string) string {
+		return variableText(vars, completionPlaceholder.FindStringSubmatch(match)[1])
+	})
+}
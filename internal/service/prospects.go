@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// prospectSources are the conversation tables a prospect's conversations live in
+var prospectSources = []string{models.AssignmentSourceAI, models.AssignmentSourceWasapbot}
+
+// ProspectService groups a user's conversations by normalized phone into prospects, finds
+// prospects with duplicate conversations across devices and merges them
+type ProspectService struct {
+	prospectRepo     *repository.ProspectRepository
+	conversationRepo *repository.ConversationRepository
+	wasapbotRepo     *repository.WasapbotRepository
+	deviceRepo       *repository.DeviceRepository
+	messages         *MessageRecorder
+}
+
+// NewProspectService creates a new prospect service
+func NewProspectService(prospectRepo *repository.ProspectRepository, conversationRepo *repository.ConversationRepository, wasapbotRepo *repository.WasapbotRepository, deviceRepo *repository.DeviceRepository, messages *MessageRecorder) *ProspectService {
+	return &ProspectService{
+		prospectRepo:     prospectRepo,
+		conversationRepo: conversationRepo,
+		wasapbotRepo:     wasapbotRepo,
+		deviceRepo:       deviceRepo,
+		messages:         messages,
+	}
+}
+
+// prospectConversations retrieves the conversations of both tables on the devices with a phone
+// ("" = every phone)
+func (s *ProspectService) prospectConversations(ctx context.Context, deviceIDs []string, phone string) ([]models.ProspectConversation, error) {
+	var conversations []models.ProspectConversation
+	for _, source := range prospectSources {
+		rows, err := s.prospectRepo.GetProspectConversations(ctx, source, deviceIDs, phone)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, rows...)
+	}
+	return conversations, nil
+}
+
+// GetProspect returns a phone number's conversations across the user's devices. phone may be in
+// any format NormalizePhone reads; national numbers are read in DefaultPhoneCountry.
+func (s *ProspectService) GetProspect(ctx context.Context, userID, phone string) (*models.ProspectResponse, error) {
+	normalized := models.NormalizeProspectPhone(phone, models.DefaultPhoneCountry)
+	if normalized == "" {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: fmt.Sprintf("%q is not a phone number", phone),
+		}, nil
+	}
+
+	deviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	conversations, err := s.prospectConversations(ctx, deviceIDs, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	prospects := groupProspects(conversations)
+	if len(prospects) == 0 {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: "Prospect not found",
+		}, nil
+	}
+
+	return &models.ProspectResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d conversations", len(prospects[0].Conversations)),
+		Prospect: &prospects[0],
+	}, nil
+}
+
+// ListDuplicates returns the user's prospects with more than one conversation, on one device
+// (deviceID set) or across all of them
+func (s *ProspectService) ListDuplicates(ctx context.Context, userID, deviceID string) (*models.ProspectResponse, error) {
+	deviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations, err := s.prospectConversations(ctx, deviceIDs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []models.Prospect{}
+	for _, prospect := range groupProspects(conversations) {
+		if len(prospect.Conversations) < 2 {
+			continue
+		}
+		if deviceID != "" && !containsString(prospect.Devices, deviceID) {
+			continue
+		}
+		duplicates = append(duplicates, prospect)
+	}
+
+	return &models.ProspectResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Found %d prospects with duplicate conversations", len(duplicates)),
+		Prospects: duplicates,
+	}, nil
+}
+
+// groupProspects groups conversations by phone, latest conversation first within each prospect
+// and prospects by their latest conversation
+func groupProspects(conversations []models.ProspectConversation) []models.Prospect {
+	byPhone := make(map[string]*models.Prospect)
+	var order []string
+	for _, conversation := range conversations {
+		if conversation.ProspectPhone == "" {
+			continue
+		}
+		prospect, ok := byPhone[conversation.ProspectPhone]
+		if !ok {
+			prospect = &models.Prospect{Phone: conversation.ProspectPhone, Devices: []string{}}
+			byPhone[conversation.ProspectPhone] = prospect
+			order = append(order, conversation.ProspectPhone)
+		}
+		prospect.Conversations = append(prospect.Conversations, conversation)
+	}
+
+	prospects := make([]models.Prospect, 0, len(order))
+	for _, phone := range order {
+		prospect := byPhone[phone]
+		sort.SliceStable(prospect.Conversations, func(i, j int) bool {
+			return prospectConversationNewer(&prospect.Conversations[i], &prospect.Conversations[j])
+		})
+		for i := range prospect.Conversations {
+			conversation := &prospect.Conversations[i]
+			if prospect.Name == nil && getStringValue(conversation.ProspectName) != "" {
+				prospect.Name = conversation.ProspectName
+			}
+			if prospect.Stage == nil && getStringValue(conversation.Stage) != "" {
+				prospect.Stage = conversation.Stage
+			}
+			if !containsString(prospect.Devices, conversation.IDDevice) {
+				prospect.Devices = append(prospect.Devices, conversation.IDDevice)
+			}
+		}
+		prospects = append(prospects, *prospect)
+	}
+
+	sort.SliceStable(prospects, func(i, j int) bool {
+		return prospectConversationNewer(&prospects[i].Conversations[0], &prospects[j].Conversations[0])
+	})
+	return prospects
+}
+
+// prospectConversationNewer reports whether a was updated after b; conversations never updated sort last
+func prospectConversationNewer(a, b *models.ProspectConversation) bool {
+	if a.UpdatedAt == nil || b.UpdatedAt == nil {
+		return a.UpdatedAt != nil
+	}
+	return a.UpdatedAt.After(*b.UpdatedAt)
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeProspect folds duplicate conversations of a prospect into the kept one: their messages move
+// to it, it takes their name, stage and custom fields where it has none, and they are deleted
+func (s *ProspectService) MergeProspect(ctx context.Context, userID string, req *models.MergeProspectRequest) (*models.ProspectResponse, error) {
+	if req.Source != models.AssignmentSourceAI && req.Source != models.AssignmentSourceWasapbot {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: fmt.Sprintf("source must be %s or %s", models.AssignmentSourceAI, models.AssignmentSourceWasapbot),
+		}, nil
+	}
+
+	var mergeIDs []string
+	for _, id := range req.MergeIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && id != req.KeepID && !containsString(mergeIDs, id) {
+			mergeIDs = append(mergeIDs, id)
+		}
+	}
+	if len(mergeIDs) == 0 {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: "merge_ids must list at least one conversation other than keep_id",
+		}, nil
+	}
+	if len(mergeIDs) > models.MaxMergeConversations {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: fmt.Sprintf("At most %d conversations can be merged at once", models.MaxMergeConversations),
+		}, nil
+	}
+
+	deviceIDs, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	kept, err := s.prospectRepo.GetProspectConversation(ctx, req.Source, req.KeepID)
+	if err != nil {
+		return nil, err
+	}
+	if kept == nil || !containsString(deviceIDs, kept.IDDevice) {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: "Conversation not found or access denied",
+		}, nil
+	}
+	if kept.ProspectPhone == "" {
+		return &models.ProspectResponse{
+			Success: false,
+			Message: "The kept conversation has no phone number",
+		}, nil
+	}
+
+	// Only conversations of the same source and phone on the user's devices can be merged
+	siblings, err := s.prospectRepo.GetProspectConversations(ctx, req.Source, deviceIDs, kept.ProspectPhone)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.ProspectConversation, len(siblings))
+	for _, sibling := range siblings {
+		byID[fmt.Sprintf("%d", sibling.IDProspect)] = sibling
+	}
+	var merged []models.ProspectConversation
+	for _, id := range mergeIDs {
+		sibling, ok := byID[id]
+		if !ok {
+			return &models.ProspectResponse{
+				Success: false,
+				Message: fmt.Sprintf("Conversation %s is not a %s conversation of %s on your devices", id, req.Source, kept.ProspectPhone),
+			}, nil
+		}
+		merged = append(merged, sibling)
+	}
+
+	updates := mergedProspectUpdates(kept, merged)
+	for _, conversation := range merged {
+		id := fmt.Sprintf("%d", conversation.IDProspect)
+		if err := s.messages.Move(ctx, req.Source, id, req.KeepID); err != nil {
+			return nil, err
+		}
+		if err := s.deleteConversation(ctx, req.Source, id); err != nil {
+			return nil, err
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.updateConversation(ctx, req.Source, req.KeepID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := s.GetProspect(ctx, userID, kept.ProspectPhone)
+	if err != nil {
+		return nil, err
+	}
+	resp.Message = fmt.Sprintf("Merged %d conversations", len(merged))
+	resp.Merged = len(merged)
+	return resp, nil
+}
+
+// mergedProspectUpdates fills the kept conversation's missing name, stage and custom fields from
+// the merged ones, latest first
+func mergedProspectUpdates(kept *models.ProspectConversation, merged []models.ProspectConversation) map[string]interface{} {
+	updates := make(map[string]interface{})
+
+	customFields := make(map[string]interface{}, len(kept.CustomFields))
+	for key, value := range kept.CustomFields {
+		customFields[key] = value
+	}
+	fieldsAdded := false
+
+	for i := range merged {
+		conversation := &merged[i]
+		if _, ok := updates["prospect_name"]; !ok && getStringValue(kept.ProspectName) == "" && getStringValue(conversation.ProspectName) != "" {
+			updates["prospect_name"] = *conversation.ProspectName
+		}
+		if _, ok := updates["stage"]; !ok && getStringValue(kept.Stage) == "" && getStringValue(conversation.Stage) != "" {
+			updates["stage"] = *conversation.Stage
+		}
+		for key, value := range conversation.CustomFields {
+			if _, ok := customFields[key]; !ok && value != nil {
+				customFields[key] = value
+				fieldsAdded = true
+			}
+		}
+	}
+	if fieldsAdded {
+		updates["custom_fields"] = customFields
+	}
+	return updates
+}
+
+// deleteConversation deletes a conversation of either table
+func (s *ProspectService) deleteConversation(ctx context.Context, source, prospectID string) error {
+	if source == models.AssignmentSourceWasapbot {
+		return s.wasapbotRepo.DeleteConversation(ctx, prospectID)
+	}
+	return s.conversationRepo.DeleteConversation(ctx, prospectID)
+}
+
+// updateConversation updates a conversation of either table
+func (s *ProspectService) updateConversation(ctx context.Context, source, prospectID string, updates map[string]interface{}) error {
+	if source == models.AssignmentSourceWasapbot {
+		return s.wasapbotRepo.UpdateConversation(ctx, prospectID, updates)
+	}
+	return s.conversationRepo.UpdateConversation(ctx, prospectID, updates)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// Provider health samples are kept in memory per device for providerHealthWindow, at most
+// maxProviderHealthSamples per device and kind, so the stats cover this instance's recent calls
+const (
+	providerHealthWindow     = time.Hour
+	maxProviderHealthSamples = 500
+)
+
+// Kinds of provider calls tracked for device health
+const (
+	providerCallStatus = "status"
+	providerCallSend   = "send"
+)
+
+// providerSample is one timed provider call
+type providerSample struct {
+	at      time.Time
+	latency time.Duration
+	err     string
+}
+
+// providerHealth records provider call latencies and errors, keyed by device_setting.id then kind
+type providerHealth struct {
+	mu      sync.Mutex
+	samples map[string]map[string][]providerSample
+}
+
+// record adds a call that took latency and failed with err (nil on success)
+func (h *providerHealth) record(deviceID, kind string, latency time.Duration, err error) {
+	sample := providerSample{at: time.Now(), latency: latency}
+	if err != nil {
+		sample.err = err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.samples == nil {
+		h.samples = make(map[string]map[string][]providerSample)
+	}
+	if h.samples[deviceID] == nil {
+		h.samples[deviceID] = make(map[string][]providerSample)
+	}
+	samples := append(h.samples[deviceID][kind], sample)
+	if len(samples) > maxProviderHealthSamples {
+		samples = samples[len(samples)-maxProviderHealthSamples:]
+	}
+	h.samples[deviceID][kind] = samples
+}
+
+// stats summarizes a device's calls of kind within the window before now
+func (h *providerHealth) stats(deviceID, kind string, now time.Time) models.ProviderCallStats {
+	h.mu.Lock()
+	var recent []providerSample
+	for _, sample := range h.samples[deviceID][kind] {
+		if now.Sub(sample.at) <= providerHealthWindow {
+			recent = append(recent, sample)
+		}
+	}
+	h.mu.Unlock()
+
+	var stats models.ProviderCallStats
+	if len(recent) == 0 {
+		return stats
+	}
+
+	latencies := make([]time.Duration, 0, len(recent))
+	var total time.Duration
+	for _, sample := range recent {
+		latencies = append(latencies, sample.latency)
+		total += sample.latency
+		if sample.err != "" {
+			stats.Errors++
+			at := sample.at
+			stats.LastError = sample.err
+			stats.LastErrorAt = &at
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	stats.Count = len(recent)
+	stats.ErrorRate = float64(stats.Errors) / float64(stats.Count)
+	stats.AvgLatencyMs = (total / time.Duration(stats.Count)).Milliseconds()
+	stats.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1].Milliseconds()
+	stats.LastLatencyMs = recent[len(recent)-1].latency.Milliseconds()
+	return stats
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// queuedSendBatchSize caps the held messages one dispatch pass claims
+const queuedSendBatchSize = 50
+
+// QueuedSendDispatcher sends the bot messages held back by devices' quiet hours once their
+// send_at passes. They go through the normal send path, so the send queue still paces them.
+type QueuedSendDispatcher struct {
+	whatsappService *WhatsAppService
+	queuedSendRepo  *repository.QueuedSendRepository
+}
+
+// NewQueuedSendDispatcher creates a new queued send dispatcher
+func NewQueuedSendDispatcher(whatsappService *WhatsAppService, queuedSendRepo *repository.QueuedSendRepository) *QueuedSendDispatcher {
+	return &QueuedSendDispatcher{
+		whatsappService: whatsappService,
+		queuedSendRepo:  queuedSendRepo,
+	}
+}
+
+// Start dispatches due sends immediately and then every interval until ctx is cancelled
+func (d *QueuedSendDispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := d.DispatchDue(ctx); err != nil {
+				log.Printf("⚠️  Queued send dispatch failed: %v", err)
+			} else if sent > 0 {
+				log.Printf("🌅 Sent %d message(s) held for quiet hours", sent)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DispatchDue sends up to queuedSendBatchSize due messages, oldest first, and returns how many
+// went out. The rest wait for the next tick.
+func (d *QueuedSendDispatcher) DispatchDue(ctx context.Context) (int, error) {
+	sends, err := d.queuedSendRepo.GetDueSends(ctx, time.Now(), queuedSendBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for i := range sends {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		send := &sends[i]
+		claimed, err := d.queuedSendRepo.ClaimSend(ctx, send.ID)
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		if !claimed {
+			continue // another dispatcher has it
+		}
+
+		status, errMessage := d.dispatch(ctx, send)
+		if status == models.QueuedSendSent {
+			total++
+		}
+		if err := d.queuedSendRepo.FinishSend(ctx, send.ID, status, errMessage); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+
+	return total, nil
+}
+
+// dispatch sends one held message and returns the status to record
+func (d *QueuedSendDispatcher) dispatch(ctx context.Context, send *models.QueuedSend) (string, string) {
+	if send.Message == nil {
+		return models.QueuedSendFailed, "queued send has no message"
+	}
+
+	sendCtx, cancel := d.whatsappService.deadlines.flowRunContext()
+	defer cancel()
+	sendCtx = withQueuedRelease(sendCtx)
+	if send.FlowID != nil && send.NodeID != nil {
+		sendCtx = withMessageOrigin(sendCtx, *send.FlowID, *send.NodeID)
+	}
+
+	if err := d.whatsappService.SendRequest(sendCtx, send.DeviceID, send.ProspectNum, send.Message); err != nil {
+		log.Printf("❌ Failed to send message held for %s: %v", send.ProspectNum, err)
+		return models.QueuedSendFailed, err.Error()
+	}
+	return models.QueuedSendSent, ""
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// Reply suggestion limits
+const (
+	defaultReplySuggestions = 3
+	minReplySuggestions     = 2
+	maxReplySuggestions     = 3
+	// suggestionHistoryChars keeps only the tail of long conversations in the prompt
+	suggestionHistoryChars = 6000
+)
+
+// SuggestReplies drafts short replies to a conversation using the device's AI key, model and
+// persona. Nothing is sent; the agent picks one.
+func (s *AIService) SuggestReplies(ctx context.Context, device *models.DeviceSetting, conversation *models.AIWhatsapp, count int) ([]string, error) {
+	if count < minReplySuggestions || count > maxReplySuggestions {
+		count = defaultReplySuggestions
+	}
+
+	apiKey := getStringValue(device.APIKey)
+	model := device.APIKeyOption
+	if apiKey == "" || model == "" {
+		return nil, fmt.Errorf("device has no AI API key or model configured")
+	}
+
+	history := strings.TrimSpace(getStringValue(conversation.ConvLast))
+	if history == "" {
+		return nil, fmt.Errorf("conversation has no messages yet")
+	}
+	if len(history) > suggestionHistoryChars {
+		history = history[len(history)-suggestionHistoryChars:]
+	}
+
+	prompt := "You help a human sales agent reply to a WhatsApp prospect.\n\n" +
+		personaPromptSection(device.AIPersona) +
+		"### Task:\n" +
+		fmt.Sprintf("Write %d different short replies the agent could send next, each at most two sentences, "+
+			"in the same language the prospect uses. Vary the approach (answer, question, next step).\n\n", count) +
+		"### Response Format:\n" +
+		"Return only a JSON array of strings, e.g. [\"reply one\", \"reply two\"]."
+
+	if stage := getStringValue(conversation.Stage); stage != "" {
+		prompt += "\n\nCurrent stage: " + stage
+	}
+
+	payload := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt},
+			{"role": "user", "content": history},
+		},
+		"temperature": 0.8,
+	}
+
+	content, err := s.openRouterCompletion(ctx, device, apiKey, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := parseReplySuggestions(content)
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("AI returned no usable suggestions")
+	}
+	if len(suggestions) > count {
+		suggestions = suggestions[:count]
+	}
+	return suggestions, nil
+}
+
+// openRouterCompletion sends a chat completion through OpenRouter, the provider flows use,
+// and returns the first choice's content
+func (s *AIService) openRouterCompletion(ctx context.Context, device *models.DeviceSetting, apiKey string, payload map[string]interface{}) (string, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	endpoint := s.endpoints.resolve(models.AIProviderOpenRouter, device, "/chat/completions")
+	status, body, err := endpoint.send(ctx, payloadBytes, 0, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	})
+	if err != nil {
+		return "", fmt.Errorf("OpenRouter API error: %w", err)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("OpenRouter API returned %d: %s", status, string(body))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var responseBody map[string]interface{}
+	if err := json.Unmarshal(body, &responseBody); err == nil {
+		usage, charged := openRouterUsage(responseBody)
+		model, _ := payload["model"].(string)
+		s.usage.Record(ctx, getStringValue(device.IDDevice), model, models.AIUsagePurposeReplySuggestion, usage, charged)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("no choices in AI response")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// parseReplySuggestions reads a JSON array of strings, falling back to one suggestion per line
+// when the model ignored the format
+func parseReplySuggestions(content string) []string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var raw []string
+	if start, end := strings.Index(content, "["), strings.LastIndex(content, "]"); start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+			raw = nil
+		}
+	}
+	if raw == nil {
+		// Plain list: strip bullets and numbering such as "- ", "1. " or "2) "
+		for _, line := range strings.Split(content, "\n") {
+			raw = append(raw, strings.TrimLeft(strings.TrimSpace(line), "-*0123456789.) "))
+		}
+	}
+
+	suggestions := make([]string, 0, len(raw))
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" {
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions
+}
+
+// GetReplySuggestions drafts replies for a conversation the user owns, for agents handling it in the inbox
+func (s *ConversationService) GetReplySuggestions(ctx context.Context, userID, prospectID string, count int) (*models.ReplySuggestionsResponse, error) {
+	if s.ai == nil {
+		return &models.ReplySuggestionsResponse{
+			Success: false,
+			Message: "Reply suggestions are not enabled",
+		}, nil
+	}
+
+	conversation, err := s.conversationRepo.GetConversationByID(ctx, prospectID)
+	if err != nil || conversation == nil {
+		return &models.ReplySuggestionsResponse{
+			Success: false,
+			Message: "Conversation not found",
+		}, nil
+	}
+
+	// Verify device ownership
+	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, conversation.IDDevice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup device: %w", err)
+	}
+	if device == nil {
+		device, err = s.deviceRepo.GetDeviceByID(ctx, conversation.IDDevice)
+		if err != nil {
+			device = nil
+		}
+	}
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
+		return &models.ReplySuggestionsResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	suggestions, err := s.ai.SuggestReplies(ctx, device, conversation, count)
+	if err != nil {
+		return &models.ReplySuggestionsResponse{
+			Success: false,
+			Message: "Failed to generate suggestions",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &models.ReplySuggestionsResponse{
+		Success:     true,
+		Message:     "Suggestions generated",
+		Suggestions: suggestions,
+	}, nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// Reply timing learns, per device, in which hours of the day prospects answer outreach, and moves
+// long follow-up pauses into those hours instead of resuming at a fixed offset.
+const (
+	// replyTimingLookback is how much conversation history the learner reads
+	replyTimingLookback = 30 * 24 * time.Hour
+	// replyTimingMaxMessages caps the messages read per device
+	replyTimingMaxMessages = 20000
+	replyTimingPageSize    = 1000
+
+	// A bot message within replyTimingAnswerGap of the prospect's message answers it; later ones are outreach
+	replyTimingAnswerGap = 5 * time.Minute
+	// Outreach counts as replied to when the prospect writes back within replyTimingReplyWindow
+	replyTimingReplyWindow = 4 * time.Hour
+
+	// A profile needs replyTimingMinSamples outreach messages, and an hour replyTimingMinHourSamples,
+	// before they are trusted
+	replyTimingMinSamples     = 50
+	replyTimingMinHourSamples = 5
+	// Hours within this share of the best hour's reply rate are best hours too
+	replyTimingBestShare = 0.8
+
+	// adaptiveFollowUpMinDelay is the shortest pause moved into the best hours; shorter pauses are
+	// part of a conversation rather than a follow-up
+	adaptiveFollowUpMinDelay = time.Hour
+	// adaptiveFollowUpSpread spreads resumes across the start of a best hour
+	adaptiveFollowUpSpread = 30 * time.Minute
+)
+
+// learnReplyProfile builds a reply profile from a device's messages, grouped by conversation and
+// oldest first within each, with hours in loc
+func learnReplyProfile(messages []models.ConversationMessage, loc *time.Location) *models.ReplyProfile {
+	hours := make([]models.ReplyHourStats, 24)
+	for hour := range hours {
+		hours[hour].Hour = hour
+	}
+
+	samples := 0
+	for i := range messages {
+		message := &messages[i]
+		if message.Role != models.MessageRoleBot {
+			continue
+		}
+
+		var previous, next *models.ConversationMessage
+		if i > 0 && sameConversation(&messages[i-1], message) {
+			previous = &messages[i-1]
+		}
+		// The first user message after this one in the same conversation
+		for j := i + 1; j < len(messages) && sameConversation(&messages[j], message); j++ {
+			if messages[j].Role == models.MessageRoleUser {
+				next = &messages[j]
+				break
+			}
+		}
+
+		// Answers to the prospect and the rest of a burst of bot messages are not outreach
+		if previous != nil && message.CreatedAt.Sub(previous.CreatedAt) <= replyTimingAnswerGap {
+			continue
+		}
+
+		stats := &hours[message.CreatedAt.In(loc).Hour()]
+		stats.Sent++
+		samples++
+		if next != nil && next.CreatedAt.Sub(message.CreatedAt) <= replyTimingReplyWindow {
+			stats.Replied++
+		}
+	}
+
+	best := 0.0
+	for hour := range hours {
+		if hours[hour].Sent > 0 {
+			hours[hour].Rate = float64(hours[hour].Replied) / float64(hours[hour].Sent)
+		}
+		if hours[hour].Sent >= replyTimingMinHourSamples && hours[hour].Rate > best {
+			best = hours[hour].Rate
+		}
+	}
+
+	profile := &models.ReplyProfile{
+		Hours:      hours,
+		BestHours:  []int{},
+		Samples:    samples,
+		Timezone:   loc.String(),
+		ComputedAt: time.Now(),
+	}
+	if samples < replyTimingMinSamples || best == 0 {
+		return profile
+	}
+	for _, stats := range hours {
+		if stats.Sent >= replyTimingMinHourSamples && stats.Rate >= best*replyTimingBestShare {
+			profile.BestHours = append(profile.BestHours, stats.Hour)
+		}
+	}
+	return profile
+}
+
+// sameConversation reports whether two messages belong to the same conversation
+func sameConversation(a, b *models.ConversationMessage) bool {
+	return a.Source == b.Source && a.ConversationID == b.ConversationID
+}
+
+// adaptiveResumeAt moves a resume time that falls outside the best hours to the start of the next
+// best hour, spread over adaptiveFollowUpSpread so a batch of follow-ups does not fire at once
+func adaptiveResumeAt(resumeAt time.Time, loc *time.Location, bestHours []int) time.Time {
+	if len(bestHours) == 0 || slices.Contains(bestHours, resumeAt.In(loc).Hour()) {
+		return resumeAt
+	}
+
+	local := resumeAt.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	for i := 1; i <= 24; i++ {
+		candidate := start.Add(time.Duration(i) * time.Hour)
+		if slices.Contains(bestHours, candidate.Hour()) {
+			return candidate.Add(time.Duration(rand.Int63n(int64(adaptiveFollowUpSpread))))
+		}
+	}
+	return resumeAt
+}
+
+// followUpResumeAt returns when a pause of d at node should resume: after d, moved into the
+// device's best reply hours unless the pause is short, the node keeps static timing, the device
+// turned adaptive timing off or has not enough history yet
+func (run *flowRun) followUpResumeAt(ctx context.Context, node *FlowNode, d time.Duration) time.Time {
+	resumeAt := time.Now().Add(d)
+	if d < adaptiveFollowUpMinDelay || run.deviceRepo == nil {
+		return resumeAt
+	}
+	if timing, _ := node.Config["timing"].(string); timing == models.FollowUpTimingStatic {
+		return resumeAt
+	}
+
+	device, err := run.deviceRepo.GetDeviceByIDDevice(ctx, run.flow.IDDevice)
+	if err != nil || device == nil || device.StaticFollowUps || device.ReplyProfile == nil {
+		return resumeAt
+	}
+	return adaptiveResumeAt(resumeAt, device.Location(), device.ReplyProfile.BestHours)
+}
+
+// followUpTimingNote describes in a flow doc when a pause of seconds may be moved into the best hours
+func followUpTimingNote(node *FlowNode, seconds int) string {
+	if time.Duration(seconds)*time.Second < adaptiveFollowUpMinDelay {
+		return ""
+	}
+	if timing, _ := node.Config["timing"].(string); timing == models.FollowUpTimingStatic {
+		return " (fixed timing)"
+	}
+	return " or until the device's next best reply hour"
+}
+
+// ReplyTimingLearner recomputes every device's reply profile from its conversation history
+type ReplyTimingLearner struct {
+	deviceRepo  *repository.DeviceRepository
+	messageRepo *repository.MessageRepository
+}
+
+// NewReplyTimingLearner creates a new reply timing learner
+func NewReplyTimingLearner(deviceRepo *repository.DeviceRepository, messageRepo *repository.MessageRepository) *ReplyTimingLearner {
+	return &ReplyTimingLearner{
+		deviceRepo:  deviceRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// Start learns immediately and then every interval until ctx is cancelled
+func (l *ReplyTimingLearner) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if learned, err := l.LearnAll(ctx); err != nil {
+				log.Printf("⚠️  Reply timing learning failed: %v", err)
+			} else if learned > 0 {
+				log.Printf("📈 Learned reply hours for %d device(s)", learned)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// LearnAll recomputes the reply profile of every device and returns how many have best hours
+func (l *ReplyTimingLearner) LearnAll(ctx context.Context) (int, error) {
+	devices, err := l.deviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load devices: %w", err)
+	}
+
+	learned := 0
+	for i := range devices {
+		if ctx.Err() != nil {
+			return learned, ctx.Err()
+		}
+
+		profile, err := l.LearnDevice(ctx, &devices[i])
+		if err != nil {
+			log.Printf("⚠️  Failed to learn reply hours for device %s: %v", getStringValue(devices[i].IDDevice), err)
+			continue
+		}
+		if profile != nil && len(profile.BestHours) > 0 {
+			learned++
+		}
+	}
+
+	return learned, nil
+}
+
+// LearnDevice recomputes and stores one device's reply profile
+func (l *ReplyTimingLearner) LearnDevice(ctx context.Context, device *models.DeviceSetting) (*models.ReplyProfile, error) {
+	idDevice := getStringValue(device.IDDevice)
+	if idDevice == "" {
+		return nil, nil
+	}
+
+	since := time.Now().Add(-replyTimingLookback)
+	var messages []models.ConversationMessage
+	for offset := 0; offset < replyTimingMaxMessages; offset += replyTimingPageSize {
+		page, err := l.messageRepo.GetDeviceMessages(ctx, idDevice, since, offset, replyTimingPageSize)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, page...)
+		if len(page) < replyTimingPageSize {
+			break
+		}
+	}
+
+	profile := learnReplyProfile(messages, device.Location())
+	if err := l.deviceRepo.UpdateDevice(ctx, device.ID, map[string]interface{}{
+		"reply_profile": profile,
+	}); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// validateSendRate checks a device's send_rate_per_minute (nil or 0 = default).
+// Returns an error message for the client, or "" when valid.
+func validateSendRate(rate *int) string {
+	if rate != nil && (*rate < 0 || *rate > models.MaxSendRatePerMinute) {
+		return fmt.Sprintf("send_rate_per_minute must be between 1 and %d (0 uses the default of %d)", models.MaxSendRatePerMinute, models.DefaultSendRatePerMinute)
+	}
+	return ""
+}
+
+// sendJitter returns a random delay between min and max, like a person typing the next message
+func sendJitter(min, max time.Duration) time.Duration {
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
+
+// deviceSendQueue paces one device's outbound messages. Each send reserves the device's next free
+// slot, at most rate per minute and not before the prospect's previous send plus the device's
+// send delay, then waits for it. Slots are handed out in arrival order.
+type deviceSendQueue struct {
+	mu         sync.Mutex
+	rate       int
+	next       time.Time            // earliest slot for the device's next send
+	lastTo     map[string]time.Time // slot of the latest send to each recently messaged prospect
+	waiting    int
+	sent       int64
+	abandoned  int64
+	lastSentAt time.Time
+}
+
+// reserve books the next slot for a send to a prospect, delayMin to delayMax after its previous
+// send, and counts it as waiting
+func (q *deviceSendQueue) reserve(to string, rate int, delayMin, delayMax time.Duration, now time.Time) time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rate = rate
+	slot := now
+	if q.next.After(slot) {
+		slot = q.next
+	}
+	if last, ok := q.lastTo[phoneKey(to)]; ok {
+		if spaced := last.Add(sendJitter(delayMin, delayMax)); spaced.After(slot) {
+			slot = spaced
+		}
+	}
+	q.next = slot.Add(time.Minute / time.Duration(rate))
+
+	// Prospects last messaged longer ago than the delay no longer hold back a send
+	for prospect, last := range q.lastTo {
+		if now.Sub(last) > delayMax {
+			delete(q.lastTo, prospect)
+		}
+	}
+	q.lastTo[phoneKey(to)] = slot
+
+	q.waiting++
+	return slot
+}
+
+// release records the end of a wait: the message went to the provider, or its caller gave up
+func (q *deviceSendQueue) release(sent bool, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.waiting--
+	if sent {
+		q.sent++
+		q.lastSentAt = now
+	} else {
+		q.abandoned++
+	}
+}
+
+// metrics snapshots the queue
+func (q *deviceSendQueue) metrics(deviceID string, now time.Time) *models.SendQueueMetrics {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	metrics := &models.SendQueueMetrics{
+		DeviceID:      deviceID,
+		RatePerMinute: q.rate,
+		Depth:         q.waiting,
+		Sent:          q.sent,
+		Abandoned:     q.abandoned,
+		SampledAt:     now,
+	}
+	if wait := q.next.Sub(now); wait > 0 {
+		metrics.EstimatedWaitMs = wait.Milliseconds()
+	}
+	if !q.lastSentAt.IsZero() {
+		lastSentAt := q.lastSentAt
+		metrics.LastSentAt = &lastSentAt
+	}
+	return metrics
+}
+
+// sendQueues holds the send queue of every device that has sent from this instance
+type sendQueues struct {
+	mu     sync.Mutex
+	queues map[string]*deviceSendQueue
+}
+
+// get returns a device's queue, creating it on first use
+func (s *sendQueues) get(deviceID string) *deviceSendQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queues == nil {
+		s.queues = make(map[string]*deviceSendQueue)
+	}
+	q, ok := s.queues[deviceID]
+	if !ok {
+		q = &deviceSendQueue{lastTo: make(map[string]time.Time)}
+		s.queues[deviceID] = q
+	}
+	return q
+}
+
+// wait blocks until the device may send to the prospect, or ctx is done. A cancelled wait leaves
+// its slot unused rather than shifting the messages queued behind it.
+func (s *sendQueues) wait(ctx context.Context, device *models.DeviceSetting, to string) error {
+	rate := device.EffectiveSendRatePerMinute()
+	delayMin, delayMax := device.EffectiveSendDelay()
+	q := s.get(device.ID)
+
+	slot := q.reserve(to, rate, delayMin, delayMax, time.Now())
+	if err := sleepContext(ctx, time.Until(slot)); err != nil {
+		q.release(false, time.Now())
+		return fmt.Errorf("send queue wait for device %s: %w", device.ID, err)
+	}
+	q.release(true, time.Now())
+	return nil
+}
+
+// SendQueueMetrics returns the send queue of a device in this instance. A device that has not
+// sent since the instance started reports an empty queue at its configured rate.
+func (s *WhatsAppService) SendQueueMetrics(device *models.DeviceSetting) *models.SendQueueMetrics {
+	now := time.Now()
+
+	s.sendQueues.mu.Lock()
+	q, ok := s.sendQueues.queues[device.ID]
+	s.sendQueues.mu.Unlock()
+	if !ok {
+		return &models.SendQueueMetrics{
+			DeviceID:      device.ID,
+			RatePerMinute: device.EffectiveSendRatePerMinute(),
+			SampledAt:     now,
+		}
+	}
+
+	metrics := q.metrics(device.ID, now)
+	metrics.RatePerMinute = device.EffectiveSendRatePerMinute()
+	return metrics
+}
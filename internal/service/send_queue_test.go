@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// countingProvider counts the messages a channel client sends
+type countingProvider struct {
+	whatsapp.Provider
+	sent int
+}
+
+func (p *countingProvider) SendMessage(ctx context.Context, message *models.SendMessageRequest) (*models.SendMessageResponse, error) {
+	p.sent++
+	return &models.SendMessageResponse{Success: true}, nil
+}
+
+func (p *countingProvider) GetProviderName() string { return "telegram" }
+
+func TestChannelSendsWaitForTheSendQueue(t *testing.T) {
+	rate := 1
+	device := &models.DeviceSetting{ID: "device-1", SendRatePerMinute: &rate}
+	provider := &countingProvider{}
+	s := &WhatsAppService{}
+
+	// The first send takes the device's slot for the minute
+	if err := s.sendChannel(context.Background(), device, "shop", provider, &models.SendMessageRequest{To: models.TelegramProspect("1"), Body: "Hi"}); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+
+	// A Messenger send from the same device waits for the next slot and gives up with its caller
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.sendChannel(ctx, device, "shop", provider, &models.SendMessageRequest{To: models.MessengerProspect(models.ChannelMessenger, "2"), Body: "Hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second send error = %v, want it to wait for the queue", err)
+	}
+	if provider.sent != 1 {
+		t.Errorf("provider sent %d messages, want 1", provider.sent)
+	}
+
+	metrics := s.SendQueueMetrics(device)
+	if metrics.Sent != 1 || metrics.Abandoned != 1 {
+		t.Errorf("queue sent %d and abandoned %d, want 1 and 1", metrics.Sent, metrics.Abandoned)
+	}
+}
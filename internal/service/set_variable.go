@@ -0,0 +1,471 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// Set variable. A set_variable node writes variables to the conversation's session_data, where
+// conditions (field, or conditionVariable), message templates and later set_variable nodes read
+// them back:
+//
+//	{"type": "set_variable", "config": {"variables": [
+//	    {"name": "attempts", "value": "{{attempts}} + 1"},
+//	    {"name": "total", "value": "{{qty}} * 45.90"},
+//	    {"name": "answer", "value": "trim({{last_reply}})"},
+//	    {"name": "ordered_at", "value": "now()"}
+//	]}}
+//
+// A node setting one variable may use "name" and "value" in its config instead. Values are
+// expressions over numbers, "quoted text" and {{variable}} placeholders ({{last_reply}} is the
+// prospect's message) with + - * / and parentheses; + joins text when either side is not a number,
+// and a missing variable counts as 0. The functions are now(), today() (in the device timezone),
+// trim(), lower(), upper(), number() (the first number in a text, such as 150 in "RM150") and
+// round(x, places). A value that is not an expression, such as Gold or Hi {{prospect_name}}, is
+// stored as text with its placeholders filled, like quoted text is. So is a value of only numbers
+// and operators, such as 2025-12-31 or 0123456789, unless the variable (or the whole node) sets
+// "expression": true, as in {"name": "total", "value": "3 * 45.90", "expression": true}. Variables
+// are set in order, so each sees the ones before it.
+const flowSetVariableNode = "set_variable"
+
+// setVariableLastReply names the prospect's message in set_variable values
+const setVariableLastReply = "last_reply"
+
+// flowVariableAssignment is one variable a set_variable node sets
+type flowVariableAssignment struct {
+	Name       string
+	Value      string
+	Expression bool // evaluate a value of only numbers and operators instead of keeping it as text
+}
+
+// setVariableAssignments reads the variables a set_variable node sets, in order
+func setVariableAssignments(node *FlowNode) []flowVariableAssignment {
+	var assignments []flowVariableAssignment
+	nodeExpression, _ := node.Config["expression"].(bool)
+	if list, ok := node.Config["variables"].([]interface{}); ok {
+		for _, raw := range list {
+			if item, ok := raw.(map[string]interface{}); ok {
+				name, _ := item["name"].(string)
+				expression, _ := item["expression"].(bool)
+				assignments = append(assignments, flowVariableAssignment{
+					Name:       strings.TrimSpace(name),
+					Value:      configText(item["value"]),
+					Expression: nodeExpression || expression,
+				})
+			}
+		}
+	}
+	if name, _ := node.Config["name"].(string); strings.TrimSpace(name) != "" {
+		assignments = append(assignments, flowVariableAssignment{Name: strings.TrimSpace(name), Value: configText(node.Config["value"]), Expression: nodeExpression})
+	}
+	return assignments
+}
+
+// configText reads a config value as text; editors may save a plain number as a JSON number
+func configText(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// validateVariableName checks the name a set_variable node sets. Returns why it cannot be set, or
+// "" when valid.
+func validateVariableName(name string) string {
+	if !models.IsValidListColumnName(name) {
+		return fmt.Sprintf("invalid variable name %q: use lowercase letters, digits and underscores, such as jumlah_harga", name)
+	}
+	if name == setVariableLastReply || reservedCustomFieldNames[name] || repository.IsConversationColumn(name) {
+		return fmt.Sprintf("%s is already a conversation field", name)
+	}
+	return ""
+}
+
+// setVariableProcessor evaluates the node's variables and merges them into session_data
+type setVariableProcessor struct{}
+
+func (p *setVariableProcessor) GetNodeType() string { return flowSetVariableNode }
+
+func (p *setVariableProcessor) ProcessNode(ctx context.Context, run *flowRun, node *FlowNode) (bool, error) {
+	assignments := setVariableAssignments(node)
+	if len(assignments) == 0 {
+		log.Printf("⚠️  No variables configured for set_variable node %s", node.ID)
+		return true, nil
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("❌ Failed to get conversation for set_variable: %v", err)
+		return true, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	vars := conversationVariables(conversation.row)
+	vars[setVariableLastReply] = run.userMessage
+	session := make(map[string]interface{}, len(conversation.SessionData)+len(assignments))
+	for key, value := range conversation.SessionData {
+		session[key] = value
+	}
+
+	now := deviceClock(ctx, run.deviceRepo, conversation.IDDevice)
+	changed := false
+	for _, assignment := range assignments {
+		if reason := validateVariableName(assignment.Name); reason != "" {
+			log.Printf("⚠️  set_variable node %s skips %s", node.ID, reason)
+			continue
+		}
+		value, err := evaluateVariableValue(assignment.Value, assignment.Expression, vars, now)
+		if err != nil {
+			log.Printf("⚠️  set_variable node %s cannot set %s = %q: %v", node.ID, assignment.Name, assignment.Value, err)
+			continue
+		}
+		log.Printf("🧮 %s = %v", assignment.Name, value)
+		vars[assignment.Name] = value
+		session[assignment.Name] = value
+		changed = true
+	}
+	if !changed {
+		return true, nil
+	}
+
+	if err := run.store.UpdateConversation(ctx, run.conversationID, map[string]interface{}{"session_data": session}); err != nil {
+		log.Printf("❌ Failed to save variables of %s: %v", run.conversationID, err)
+		return true, fmt.Errorf("failed to save variables: %w", err)
+	}
+	return true, nil
+}
+
+// evaluateVariableValue computes a set_variable value: the result of its expression, a float64 or
+// a string, or the value as text with its placeholders filled when it is not an expression. A value
+// of only numbers and operators is an expression only when expression is set; otherwise dates,
+// phone numbers and zero-padded codes would be computed.
+func evaluateVariableValue(value string, expression bool, vars map[string]interface{}, now func() time.Time) (interface{}, error) {
+	tokens, err := tokenizeVariableExpr(value)
+	if err != nil || len(tokens) == 0 || (!expression && numericLiteral(tokens)) {
+		return renderVariables(value, vars), nil
+	}
+
+	parser := &variableExprParser{tokens: tokens, vars: vars, now: now}
+	expr, err := parser.parseSum()
+	if err != nil || parser.pos < len(tokens) {
+		return renderVariables(value, vars), nil
+	}
+	return expr()
+}
+
+// numericLiteral reports whether tokens hold only numbers and operators, without a placeholder,
+// quoted text or function call
+func numericLiteral(tokens []variableExprToken) bool {
+	for _, token := range tokens {
+		if token.kind == "variable" || token.kind == "text" || token.kind == "name" {
+			return false
+		}
+	}
+	return true
+}
+
+// variableExprToken is a token of a set_variable expression
+type variableExprToken struct {
+	kind string // number, text, variable, name or one of + - * / ( ) ,
+	text string
+}
+
+// tokenizeVariableExpr splits a set_variable value into tokens, failing on anything an expression
+// cannot contain
+func tokenizeVariableExpr(value string) ([]variableExprToken, error) {
+	var tokens []variableExprToken
+	runes := []rune(value)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '{':
+			loc := completionPlaceholder.FindStringSubmatchIndex(string(runes[i:]))
+			if loc == nil || loc[0] != 0 {
+				return nil, fmt.Errorf("unclosed placeholder")
+			}
+			rest := string(runes[i:])
+			tokens = append(tokens, variableExprToken{kind: "variable", text: rest[loc[2]:loc[3]]})
+			i += len([]rune(rest[:loc[1]]))
+
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unclosed quote")
+			}
+			tokens = append(tokens, variableExprToken{kind: "text", text: string(runes[i+1 : end])})
+			i = end + 1
+
+		case unicode.IsDigit(r) || r == '.':
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, variableExprToken{kind: "number", text: string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, variableExprToken{kind: "name", text: string(runes[i:end])})
+			i = end
+
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, variableExprToken{kind: string(r)})
+			i++
+
+		default:
+			return nil, fmt.Errorf("unexpected %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+// variableExprParser parses set_variable expressions into functions evaluating them, so a value
+// is only computed once the whole expression parsed
+type variableExprParser struct {
+	tokens []variableExprToken
+	pos    int
+	vars   map[string]interface{}
+	now    func() time.Time
+}
+
+type variableExpr func() (interface{}, error)
+
+func (p *variableExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return ""
+}
+
+func (p *variableExprParser) expect(kind string) error {
+	if p.peek() != kind {
+		return fmt.Errorf("expected %s", kind)
+	}
+	p.pos++
+	return nil
+}
+
+// parseSum parses terms joined by + and -
+func (p *variableExprParser) parseSum() (variableExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		operator := p.peek()
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = variableOperation(operator, left, right)
+	}
+	return left, nil
+}
+
+// parseProduct parses factors joined by * and /
+func (p *variableExprParser) parseProduct() (variableExpr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		operator := p.peek()
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = variableOperation(operator, left, right)
+	}
+	return left, nil
+}
+
+// parseFactor parses a number, text, placeholder, function call, negation or parenthesized expression
+func (p *variableExprParser) parseFactor() (variableExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case "number":
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) { return number, nil }, nil
+
+	case "text":
+		return func() (interface{}, error) { return renderVariables(token.text, p.vars), nil }, nil
+
+	case "variable":
+		return func() (interface{}, error) { return p.vars[token.text], nil }, nil
+
+	case "-":
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		zero := func() (interface{}, error) { return 0.0, nil }
+		return variableOperation("-", zero, operand), nil
+
+	case "(":
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+
+	case "name":
+		return p.parseCall(strings.ToLower(token.text))
+	}
+	return nil, fmt.Errorf("unexpected %s", token.kind)
+}
+
+// parseCall parses the arguments of a function call
+func (p *variableExprParser) parseCall(name string) (variableExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []variableExpr
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.pos++
+
+	arity := map[string][2]int{
+		"now": {0, 0}, "today": {0, 0},
+		"trim": {1, 1}, "lower": {1, 1}, "upper": {1, 1}, "number": {1, 1},
+		"round": {1, 2},
+	}
+	limits, ok := arity[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) < limits[0] || len(args) > limits[1] {
+		return nil, fmt.Errorf("%s takes %d to %d arguments", name, limits[0], limits[1])
+	}
+
+	return func() (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			value, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+
+		switch name {
+		case "now":
+			return p.now().Format("2006-01-02 15:04:05"), nil
+		case "today":
+			return p.now().Format("2006-01-02"), nil
+		case "trim":
+			return strings.TrimSpace(variableValueText(values[0])), nil
+		case "lower":
+			return strings.ToLower(variableValueText(values[0])), nil
+		case "upper":
+			return strings.ToUpper(variableValueText(values[0])), nil
+		case "number":
+			number, _ := firstNumber(variableValueText(values[0]))
+			return number, nil
+		}
+
+		number, ok := variableNumber(values[0])
+		if !ok {
+			return nil, fmt.Errorf("round: %q is not a number", variableValueText(values[0]))
+		}
+		places := 0.0
+		if len(values) == 2 {
+			if places, ok = variableNumber(values[1]); !ok {
+				return nil, fmt.Errorf("round: places must be a number")
+			}
+		}
+		scale := math.Pow(10, math.Trunc(places))
+		return math.Round(number*scale) / scale, nil
+	}, nil
+}
+
+// variableOperation applies an arithmetic operator; + joins text when either side is not a number
+func variableOperation(operator string, left, right variableExpr) variableExpr {
+	return func() (interface{}, error) {
+		a, err := left()
+		if err != nil {
+			return nil, err
+		}
+		b, err := right()
+		if err != nil {
+			return nil, err
+		}
+
+		x, xNumber := variableNumber(a)
+		y, yNumber := variableNumber(b)
+		if !xNumber || !yNumber {
+			if operator == "+" {
+				return variableValueText(a) + variableValueText(b), nil
+			}
+			return nil, fmt.Errorf("%q %s %q needs numbers", variableValueText(a), operator, variableValueText(b))
+		}
+
+		switch operator {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	}
+}
+
+// variableNumber reads a value as a number: numbers, numeric text, and missing values as 0
+func variableNumber(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return 0, true
+	case float64:
+		return typed, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvaluateVariableValue(t *testing.T) {
+	now := func() time.Time { return time.Date(2026, 3, 9, 14, 30, 0, 0, time.UTC) }
+	vars := map[string]interface{}{"qty": "3", "price": 45.5, "name": "Ali", "phone": "0123456789"}
+
+	for _, tt := range []struct {
+		value      string
+		expression bool
+		want       interface{}
+	}{
+		// Literals of numbers and operators are kept as written
+		{"2025-12-31", false, "2025-12-31"},
+		{"0123456789", false, "0123456789"},
+		{"+60123456789", false, "+60123456789"},
+		{"007", false, "007"},
+		{"12.50", false, "12.50"},
+		{"10 / 4", false, "10 / 4"},
+		// unless the variable asks for an expression
+		{"10 / 4", true, 2.5},
+		{"2025-12-31", true, 1982.0},
+		// Placeholders, quoted text and functions make an expression
+		{"{{qty}} * 2", false, 6.0},
+		{"{{price}} + 0.5", false, 46.0},
+		{"{{phone}}", false, "0123456789"},
+		{`"Hi " + {{name}}`, false, "Hi Ali"},
+		{"round({{price}} / 3, 2)", false, 15.17},
+		{"today()", false, "2026-03-09"},
+		// Plain text is filled in
+		{"Gold", false, "Gold"},
+		{"Hi {{name}}", false, "Hi Ali"},
+	} {
+		got, err := evaluateVariableValue(tt.value, tt.expression, vars, now)
+		if err != nil {
+			t.Errorf("evaluateVariableValue(%q, %v) error: %v", tt.value, tt.expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("evaluateVariableValue(%q, %v) = %#v, want %#v", tt.value, tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestSetVariableAssignmentsReadExpression(t *testing.T) {
+	node := &FlowNode{Config: map[string]interface{}{
+		"variables": []interface{}{
+			map[string]interface{}{"name": "code", "value": "0042"},
+			map[string]interface{}{"name": "total", "value": "3 * 4", "expression": true},
+		},
+	}}
+	want := []flowVariableAssignment{
+		{Name: "code", Value: "0042"},
+		{Name: "total", Value: "3 * 4", Expression: true},
+	}
+	if got := setVariableAssignments(node); !reflect.DeepEqual(got, want) {
+		t.Errorf("assignments = %+v, want %+v", got, want)
+	}
+
+	node.Config["expression"] = true
+	if got := setVariableAssignments(node); !got[0].Expression {
+		t.Errorf("node-level expression not applied: %+v", got)
+	}
+}
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// sheetExportTimeout bounds one append or sync of a sheet export
+const sheetExportTimeout = 2 * time.Minute
+
+// spreadsheetURL matches the ID in a Google Sheets URL such as
+// https://docs.google.com/spreadsheets/d/<id>/edit#gid=0
+var spreadsheetURL = regexp.MustCompile(`/spreadsheets/d/([a-zA-Z0-9_-]+)`)
+
+// spreadsheetIDPattern is the shape of a Google Sheets spreadsheet ID
+var spreadsheetIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{20,100}$`)
+
+// SheetExportService manages the Google Sheets exports of users' sales teams: stage nodes append a
+// row with the prospect's order details, and Start keeps synced tabs up to date with a conversation
+// table.
+type SheetExportService struct {
+	exportRepo   *repository.SheetExportRepository
+	deviceRepo   *repository.DeviceRepository
+	convRepo     *repository.ConversationRepository
+	wasapbotRepo *repository.WasapbotRepository
+	sheets       *GoogleSheetsClient
+}
+
+// NewSheetExportService creates a new sheet export service; a nil sheets client disables exports
+func NewSheetExportService(exportRepo *repository.SheetExportRepository, deviceRepo *repository.DeviceRepository, convRepo *repository.ConversationRepository, wasapbotRepo *repository.WasapbotRepository, sheets *GoogleSheetsClient) *SheetExportService {
+	return &SheetExportService{
+		exportRepo:   exportRepo,
+		deviceRepo:   deviceRepo,
+		convRepo:     convRepo,
+		wasapbotRepo: wasapbotRepo,
+		sheets:       sheets,
+	}
+}
+
+// ListExports returns the user's sheet exports and the service account to share sheets with
+func (s *SheetExportService) ListExports(ctx context.Context, userID string) (*models.SheetExportResponse, error) {
+	exports, err := s.exportRepo.GetExportsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if exports == nil {
+		exports = []models.SheetExport{}
+	}
+
+	return &models.SheetExportResponse{
+		Success:        true,
+		Message:        fmt.Sprintf("Found %d sheet exports", len(exports)),
+		Exports:        exports,
+		ServiceAccount: s.sheets.ServiceAccountEmail(),
+	}, nil
+}
+
+// CreateExport adds a sheet export for the user
+func (s *SheetExportService) CreateExport(ctx context.Context, userID string, req *models.CreateSheetExportRequest) (*models.SheetExportResponse, error) {
+	if !s.sheets.Enabled() {
+		return &models.SheetExportResponse{Success: false, Message: "Google Sheets is not configured on this server"}, nil
+	}
+
+	export := &models.SheetExport{
+		UserID:              userID,
+		Name:                req.Name,
+		SpreadsheetID:       normalizeSpreadsheetID(req.SpreadsheetID),
+		SheetName:           models.DefaultSheetName,
+		Stages:              normalizeSheetStages(req.Stages),
+		Columns:             req.Columns,
+		SyncSheetName:       models.DefaultSheetSyncName,
+		SyncIntervalMinutes: models.DefaultSheetSyncIntervalMinute,
+	}
+	if len(export.Columns) == 0 {
+		export.Columns = models.DefaultSheetColumns
+	}
+	if req.IDDevice != nil && strings.TrimSpace(*req.IDDevice) != "" {
+		idDevice := strings.TrimSpace(*req.IDDevice)
+		export.IDDevice = &idDevice
+	}
+	if req.SheetName != nil {
+		export.SheetName = strings.TrimSpace(*req.SheetName)
+	}
+	if req.SyncTable != nil && strings.TrimSpace(*req.SyncTable) != "" {
+		table := strings.TrimSpace(*req.SyncTable)
+		export.SyncTable = &table
+	}
+	if req.SyncSheetName != nil {
+		export.SyncSheetName = strings.TrimSpace(*req.SyncSheetName)
+	}
+	if req.SyncIntervalMinutes != nil {
+		export.SyncIntervalMinutes = *req.SyncIntervalMinutes
+	}
+
+	if msg := s.validateExport(ctx, userID, export); msg != "" {
+		return &models.SheetExportResponse{Success: false, Message: msg}, nil
+	}
+
+	existing, err := s.exportRepo.GetExportsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxSheetExports {
+		return &models.SheetExportResponse{
+			Success: false,
+			Message: fmt.Sprintf("You can add at most %d sheet exports", models.MaxSheetExports),
+		}, nil
+	}
+
+	if err := s.exportRepo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+
+	return &models.SheetExportResponse{
+		Success:        true,
+		Message:        fmt.Sprintf("Sheet export created; share the sheet with %s as editor", s.sheets.ServiceAccountEmail()),
+		Export:         export,
+		ServiceAccount: s.sheets.ServiceAccountEmail(),
+	}, nil
+}
+
+// UpdateExport changes one of the user's sheet exports
+func (s *SheetExportService) UpdateExport(ctx context.Context, userID, exportID string, req *models.UpdateSheetExportRequest) (*models.SheetExportResponse, error) {
+	export, msg, err := s.ownedExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return &models.SheetExportResponse{Success: false, Message: msg}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.SpreadsheetID != nil {
+		export.SpreadsheetID = normalizeSpreadsheetID(*req.SpreadsheetID)
+		updates["spreadsheet_id"] = export.SpreadsheetID
+	}
+	if req.Name != nil {
+		export.Name = req.Name
+		updates["name"] = *req.Name
+	}
+	if req.IDDevice != nil {
+		export.IDDevice = nil
+		updates["id_device"] = nil
+		if idDevice := strings.TrimSpace(*req.IDDevice); idDevice != "" {
+			export.IDDevice = &idDevice
+			updates["id_device"] = idDevice
+		}
+	}
+	if req.SheetName != nil {
+		export.SheetName = strings.TrimSpace(*req.SheetName)
+		updates["sheet_name"] = export.SheetName
+	}
+	if req.Stages != nil {
+		export.Stages = normalizeSheetStages(req.Stages)
+		updates["stages"] = export.Stages
+	}
+	if req.Columns != nil {
+		export.Columns = req.Columns
+		updates["columns"] = export.Columns
+	}
+	if req.SyncTable != nil {
+		export.SyncTable = nil
+		updates["sync_table"] = nil
+		if table := strings.TrimSpace(*req.SyncTable); table != "" {
+			export.SyncTable = &table
+			updates["sync_table"] = table
+		}
+	}
+	if req.SyncSheetName != nil {
+		export.SyncSheetName = strings.TrimSpace(*req.SyncSheetName)
+		updates["sync_sheet_name"] = export.SyncSheetName
+	}
+	if req.SyncIntervalMinutes != nil {
+		export.SyncIntervalMinutes = *req.SyncIntervalMinutes
+		updates["sync_interval_minutes"] = export.SyncIntervalMinutes
+	}
+	if req.IsActive != nil {
+		export.IsActive = req.IsActive
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) == 0 {
+		return &models.SheetExportResponse{Success: false, Message: "No fields to update"}, nil
+	}
+	if msg := s.validateExport(ctx, userID, export); msg != "" {
+		return &models.SheetExportResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.exportRepo.UpdateExport(ctx, export.ID, updates); err != nil {
+		return nil, err
+	}
+
+	return &models.SheetExportResponse{
+		Success: true,
+		Message: "Sheet export updated successfully",
+		Export:  export,
+	}, nil
+}
+
+// DeleteExport removes one of the user's sheet exports; the sheet itself is left as it is
+func (s *SheetExportService) DeleteExport(ctx context.Context, userID, exportID string) (*models.SheetExportResponse, error) {
+	export, msg, err := s.ownedExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return &models.SheetExportResponse{Success: false, Message: msg}, nil
+	}
+
+	if err := s.exportRepo.DeleteExport(ctx, export.ID); err != nil {
+		return nil, err
+	}
+
+	return &models.SheetExportResponse{
+		Success: true,
+		Message: "Sheet export deleted successfully",
+	}, nil
+}
+
+// SyncExport writes the conversations of an export's sync table to its sync tab now
+func (s *SheetExportService) SyncExport(ctx context.Context, userID, exportID string) (*models.SheetExportResponse, error) {
+	export, msg, err := s.ownedExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return &models.SheetExportResponse{Success: false, Message: msg}, nil
+	}
+	if export.SyncTable == nil {
+		return &models.SheetExportResponse{Success: false, Message: "Set sync_table to ai_whatsapp or wasapbot to sync this export"}, nil
+	}
+
+	rows, err := s.sync(ctx, export)
+	if err != nil {
+		return &models.SheetExportResponse{
+			Success: false,
+			Message: fmt.Sprintf("Sync failed: %v", err),
+		}, nil
+	}
+
+	return &models.SheetExportResponse{
+		Success: true,
+		Message: fmt.Sprintf("Wrote %d conversations to %s", rows, export.SyncSheetName),
+		Export:  export,
+		Rows:    rows,
+	}, nil
+}
+
+// ownedExport loads a sheet export and checks the user owns it
+func (s *SheetExportService) ownedExport(ctx context.Context, userID, exportID string) (*models.SheetExport, string, error) {
+	export, err := s.exportRepo.GetExportByID(ctx, exportID)
+	if err != nil {
+		return nil, "", err
+	}
+	if export == nil {
+		return nil, "Sheet export not found", nil
+	}
+	if export.UserID != userID {
+		return nil, "Access denied", nil
+	}
+	return export, "", nil
+}
+
+// validateExport checks a sheet export.
+// Returns a user-facing message when invalid, or an empty string when valid.
+func (s *SheetExportService) validateExport(ctx context.Context, userID string, export *models.SheetExport) string {
+	if !spreadsheetIDPattern.MatchString(export.SpreadsheetID) {
+		return "spreadsheet_id must be a Google Sheets ID or URL"
+	}
+	if export.SheetName == "" || len(export.SheetName) > 100 {
+		return "sheet_name must be 1-100 characters"
+	}
+	if len(export.Columns) > models.MaxSheetColumns {
+		return fmt.Sprintf("An export can have at most %d columns", models.MaxSheetColumns)
+	}
+	for i, column := range export.Columns {
+		if strings.TrimSpace(column.Header) == "" {
+			return fmt.Sprintf("Column %d has no header", i+1)
+		}
+	}
+	if export.SyncTable != nil {
+		if *export.SyncTable != models.AssignmentSourceAI && *export.SyncTable != models.AssignmentSourceWasapbot {
+			return "sync_table must be ai_whatsapp or wasapbot"
+		}
+		if export.SyncSheetName == "" || len(export.SyncSheetName) > 100 {
+			return "sync_sheet_name must be 1-100 characters"
+		}
+		if export.SyncSheetName == export.SheetName {
+			return "sync_sheet_name must differ from sheet_name: each sync replaces the whole tab"
+		}
+		if export.SyncIntervalMinutes < models.MinSheetSyncIntervalMinute || export.SyncIntervalMinutes > models.MaxSheetSyncIntervalMinute {
+			return fmt.Sprintf("sync_interval_minutes must be between %d and %d", models.MinSheetSyncIntervalMinute, models.MaxSheetSyncIntervalMinute)
+		}
+	}
+	if export.IDDevice != nil {
+		device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, *export.IDDevice)
+		if err != nil || device == nil || device.UserID == nil || *device.UserID != userID {
+			return "Device not found or access denied"
+		}
+	}
+	return ""
+}
+
+// normalizeSpreadsheetID accepts a spreadsheet ID or the URL of the sheet
+func normalizeSpreadsheetID(value string) string {
+	value = strings.TrimSpace(value)
+	if match := spreadsheetURL.FindStringSubmatch(value); match != nil {
+		return match[1]
+	}
+	return value
+}
+
+// normalizeSheetStages trims stage names, dropping empty ones
+func normalizeSheetStages(stages []string) []string {
+	normalized := []string{}
+	for _, stage := range stages {
+		if stage = strings.TrimSpace(stage); stage != "" {
+			normalized = append(normalized, stage)
+		}
+	}
+	return normalized
+}
+
+// sheetRow renders the export's columns for a conversation; exportedAt fills {{exported_at}}
+func sheetRow(export *models.SheetExport, conversation interface{}, exportedAt time.Time) []string {
+	vars := conversationVariables(conversation)
+	vars["exported_at"] = exportedAt.Format("2006-01-02 15:04:05")
+
+	row := make([]string, len(export.Columns))
+	for i, column := range export.Columns {
+		row[i] = renderVariables(column.Value, vars)
+	}
+	return row
+}
+
+// sheetHeader is the first row a sync writes
+func sheetHeader(export *models.SheetExport) []string {
+	header := make([]string, len(export.Columns))
+	for i, column := range export.Columns {
+		header[i] = column.Header
+	}
+	return header
+}
+
+// AppendStage appends a row for a conversation a stage node moved to stage, to every active export
+// of the device's owner that exports it. Rows are written in the background, so the flow never
+// waits on Google.
+func (s *SheetExportService) AppendStage(ctx context.Context, idDevice, stage string, conversation interface{}) {
+	if s == nil || !s.sheets.Enabled() || idDevice == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sheetExportTimeout)
+		defer cancel()
+
+		device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+		if err != nil || device == nil || device.UserID == nil {
+			log.Printf("⚠️  Sheet row for stage %s not written, failed to find the owner of device %s: %v", stage, idDevice, err)
+			return
+		}
+		exports, err := s.exportRepo.GetExportsByUser(ctx, *device.UserID)
+		if err != nil {
+			log.Printf("⚠️  Sheet row for stage %s not written: %v", stage, err)
+			return
+		}
+
+		now := time.Now().In(device.Location())
+		for i := range exports {
+			export := &exports[i]
+			if !export.Active() || !export.ExportsStage(stage) {
+				continue
+			}
+			if export.IDDevice != nil && *export.IDDevice != idDevice {
+				continue
+			}
+
+			err := s.sheets.AppendRows(ctx, export.SpreadsheetID, export.SheetName, [][]string{sheetRow(export, conversation, now)})
+			s.recordResult(ctx, export, err, nil)
+			if err != nil {
+				log.Printf("⚠️  Sheet row for stage %s not written to export %s: %v", stage, export.ID, err)
+			}
+		}
+	}()
+}
+
+// recordResult stores the outcome of an append or sync on the export: the error, or its clearing.
+// syncedAt is set by syncs.
+func (s *SheetExportService) recordResult(ctx context.Context, export *models.SheetExport, err error, syncedAt *time.Time) {
+	updates := make(map[string]interface{})
+	if err != nil {
+		message := err.Error()
+		export.LastError = &message
+		updates["last_error"] = message
+	} else if export.LastError != nil {
+		export.LastError = nil
+		updates["last_error"] = nil
+	}
+	if syncedAt != nil {
+		export.LastSyncedAt = syncedAt
+		updates["last_synced_at"] = *syncedAt
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := s.exportRepo.UpdateExport(ctx, export.ID, updates); err != nil {
+		log.Printf("⚠️  Failed to record sheet export %s result: %v", export.ID, err)
+	}
+}
+
+// sync replaces the export's sync tab with a header and a row per conversation of its sync table,
+// on its device or every device of the user. Returns the conversations written.
+func (s *SheetExportService) sync(ctx context.Context, export *models.SheetExport) (int, error) {
+	if !s.sheets.Enabled() {
+		return 0, fmt.Errorf("google sheets is not configured")
+	}
+
+	var devices []models.DeviceSetting
+	if export.IDDevice != nil {
+		device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, *export.IDDevice)
+		if err != nil {
+			return 0, err
+		}
+		if device != nil {
+			devices = append(devices, *device)
+		}
+	} else {
+		userDevices, err := s.deviceRepo.GetDevicesByUserID(ctx, export.UserID)
+		if err != nil {
+			return 0, err
+		}
+		devices = userDevices
+	}
+
+	rows := [][]string{sheetHeader(export)}
+	for i := range devices {
+		idDevice := getStringValue(devices[i].IDDevice)
+		if idDevice == "" {
+			continue
+		}
+		now := time.Now().In(devices[i].Location())
+
+		if *export.SyncTable == models.AssignmentSourceWasapbot {
+			conversations, err := s.wasapbotRepo.GetConversationsByDevice(ctx, idDevice, 0)
+			if err != nil {
+				return 0, err
+			}
+			for j := range conversations {
+				rows = append(rows, sheetRow(export, &conversations[j], now))
+			}
+		} else {
+			conversations, err := s.convRepo.GetConversationsByDevice(ctx, idDevice, 0)
+			if err != nil {
+				return 0, err
+			}
+			for j := range conversations {
+				rows = append(rows, sheetRow(export, &conversations[j], now))
+			}
+		}
+	}
+
+	err := s.sheets.ReplaceRows(ctx, export.SpreadsheetID, export.SyncSheetName, rows)
+	syncedAt := time.Now()
+	s.recordResult(ctx, export, err, &syncedAt)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows) - 1, nil
+}
+
+// Start runs due syncs immediately and then every interval until ctx is cancelled
+func (s *SheetExportService) Start(ctx context.Context, interval time.Duration) {
+	if s == nil || !s.sheets.Enabled() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if synced, err := s.SyncDue(ctx); err != nil {
+				log.Printf("⚠️  Sheet export sync failed: %v", err)
+			} else if synced > 0 {
+				log.Printf("📊 Synced %d sheet exports", synced)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SyncDue syncs every active export whose sync interval has passed and returns how many were
+// synced. A failed sync waits for the next interval too, so a sheet that is not shared is not
+// retried every tick.
+func (s *SheetExportService) SyncDue(ctx context.Context) (int, error) {
+	exports, err := s.exportRepo.GetSyncedExports(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	now := time.Now()
+	for i := range exports {
+		if ctx.Err() != nil {
+			return synced, ctx.Err()
+		}
+		export := &exports[i]
+		if !export.SyncDue(now) {
+			continue
+		}
+
+		syncCtx, cancel := context.WithTimeout(ctx, sheetExportTimeout)
+		rows, err := s.sync(syncCtx, export)
+		cancel()
+		if err != nil {
+			log.Printf("⚠️  Sheet export %s sync failed: %v", export.ID, err)
+			continue
+		}
+		log.Printf("📊 Sheet export %s: wrote %d conversations to %s", export.ID, rows, export.SyncSheetName)
+		synced++
+	}
+	return synced, nil
+}
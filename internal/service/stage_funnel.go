@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// GetStageFunnel orders a flow's stages as its definition reaches them and reports, for each, how
+// many conversations got that far, the drop-off to the next stage and the median time spent in it
+func (s *AnalyticsService) GetStageFunnel(ctx context.Context, userID string, req *models.AnalyticsRequest) (*models.StageFunnelResponse, error) {
+	if req.FlowID == "" {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: "flowId is required",
+		}, nil
+	}
+
+	flow, err := s.flowRepo.GetFlowByID(ctx, req.FlowID)
+	if err != nil || flow == nil {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: "Access denied: flow not found or unauthorized",
+		}, nil
+	}
+	if req.DeviceID != "" && req.DeviceID != flow.IDDevice {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: "Flow does not belong to the device",
+		}, nil
+	}
+	if _, err := s.resolveUserDeviceIDs(ctx, userID, flow.IDDevice); err != nil {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: "Access denied: flow not found or unauthorized",
+		}, nil
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: fmt.Sprintf("Flow data is not valid JSON: %v", err),
+		}, nil
+	}
+
+	// Set default time range
+	timeRange := req.TimeRange
+	if timeRange == nil {
+		now := time.Now()
+		timeRange = &models.TimeRangeFilter{
+			StartDate: now.AddDate(0, 0, -30),
+			EndDate:   now,
+		}
+	}
+
+	visits, err := s.analyticsRepo.GetStageVisits(ctx, flow.ID, timeRange)
+	if err != nil {
+		return &models.StageFunnelResponse{
+			Success: false,
+			Message: "Failed to retrieve stage funnel",
+			Error:   err.Error(),
+		}, nil
+	}
+
+	funnel := buildStageFunnel(flowStageOrder(&flowData), visits)
+	funnel.FlowID = flow.ID
+	funnel.FlowName = flow.Name
+
+	return &models.StageFunnelResponse{
+		Success: true,
+		Message: "Stage funnel retrieved successfully",
+		Data:    funnel,
+	}, nil
+}
+
+// flowStageOrder lists the stages a flow's stage nodes set, in the order a breadth-first walk
+// from the entry node meets them
+func flowStageOrder(flowData *FlowData) []string {
+	entry := flowEntryNode(flowData)
+	if entry == nil {
+		return nil
+	}
+
+	outgoing := make(map[string][]FlowEdge, len(flowData.Nodes))
+	for _, edge := range flowData.Connections {
+		outgoing[edge.From] = append(outgoing[edge.From], edge)
+	}
+
+	var stages []string
+	seen := make(map[string]bool)
+	visited := map[string]bool{entry.ID: true}
+	queue := []string{entry.ID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		node := findFlowNode(flowData, id)
+		if node == nil {
+			continue
+		}
+
+		if stage, _ := node.Config["value"].(string); node.Type == "stage" && stage != "" && !seen[stage] {
+			seen[stage] = true
+			stages = append(stages, stage)
+		}
+		for _, edge := range outgoing[id] {
+			if !visited[edge.To] {
+				visited[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	return stages
+}
+
+// buildStageFunnel counts each conversation as far as the furthest stage it entered, so one that
+// skipped a stage still counts as having reached it
+func buildStageFunnel(stages []string, visits []models.StageVisit) *models.StageFunnel {
+	funnel := &models.StageFunnel{
+		Steps:    make([]models.StageFunnelStep, len(stages)),
+		Unlisted: make(map[string]int),
+	}
+
+	index := make(map[string]int, len(stages))
+	for i, stage := range stages {
+		index[stage] = i
+		funnel.Steps[i].Stage = stage
+	}
+
+	furthest := make(map[string]int)
+	unlisted := make(map[string]map[string]bool)
+	durations := make([][]float64, len(stages))
+	for _, visit := range visits {
+		conversation := visit.Source + "/" + visit.ConversationID
+		i, ok := index[visit.Stage]
+		if !ok {
+			if unlisted[visit.Stage] == nil {
+				unlisted[visit.Stage] = make(map[string]bool)
+			}
+			unlisted[visit.Stage][conversation] = true
+			continue
+		}
+
+		if reached, ok := furthest[conversation]; !ok || i > reached {
+			furthest[conversation] = i
+		}
+		if visit.LeftAt == nil {
+			funnel.Steps[i].Current++
+		} else {
+			durations[i] = append(durations[i], visit.LeftAt.Sub(visit.EnteredAt).Seconds())
+		}
+	}
+
+	for stage, conversations := range unlisted {
+		funnel.Unlisted[stage] = len(conversations)
+	}
+
+	funnel.Total = len(furthest)
+	for _, reached := range furthest {
+		for i := 0; i <= reached; i++ {
+			funnel.Steps[i].Reached++
+		}
+	}
+
+	for i := range funnel.Steps {
+		step := &funnel.Steps[i]
+		if i+1 < len(funnel.Steps) && step.Reached > 0 {
+			dropped := step.Reached - funnel.Steps[i+1].Reached
+			step.DropOffPercent = math.Round(float64(dropped)/float64(step.Reached)*10000) / 100
+		}
+		if len(durations[i]) > 0 {
+			median := medianSeconds(durations[i])
+			step.MedianSeconds = &median
+		}
+	}
+
+	return funnel
+}
+
+// medianSeconds returns the median of values, which must not be empty
+func medianSeconds(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[middle]
+	}
+	return (values[middle-1] + values[middle]) / 2
+}
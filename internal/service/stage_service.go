@@ -30,6 +30,11 @@ func (s *StageService) CreateStageValue(ctx context.Context, req *models.CreateS
 		ColumnsData:   req.ColumnsData,
 		InputHardCode: req.InputHardCode,
 	}
+	if req.SLAMinutes != nil && *req.SLAMinutes > 0 {
+		stage.SLAMinutes = req.SLAMinutes
+		stage.SLAAction = req.SLAAction
+		stage.SLANudgeNodeID = req.SLANudgeNodeID
+	}
 
 	if err := s.stageRepo.CreateStageValue(ctx, stage); err != nil {
 		return nil, fmt.Errorf("failed to create stage value: %w", err)
@@ -139,6 +144,19 @@ func (s *StageService) UpdateStageValue(ctx context.Context, stageID int, req *m
 	if req.InputHardCode != nil {
 		updates["inputhardcode"] = *req.InputHardCode
 	}
+	if req.SLAMinutes != nil {
+		if *req.SLAMinutes > 0 {
+			updates["sla_minutes"] = *req.SLAMinutes
+		} else {
+			updates["sla_minutes"] = nil
+		}
+	}
+	if req.SLAAction != nil {
+		updates["sla_action"] = *req.SLAAction
+	}
+	if req.SLANudgeNodeID != nil {
+		updates["sla_nudge_node_id"] = *req.SLANudgeNodeID
+	}
 
 	if len(updates) == 0 {
 		return &models.StageValueResponse{
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// slaConversation is the part of an ai_whatsapp or wasapbot row the SLA monitor needs
+type slaConversation struct {
+	source         string // ai_whatsapp, wasapbot
+	id             string
+	prospectNum    string
+	prospectName   string
+	flowID         *string
+	state          models.ConversationState
+	stageEnteredAt time.Time
+}
+
+// StageSLAMonitor flags conversations that stay in a stage longer than its sla_minutes.
+// Each breach runs the stage's nudge node for the prospect or notifies the device owner,
+// and is logged to stage_sla_breaches for analytics. A conversation is flagged once per stage entry.
+type StageSLAMonitor struct {
+	processor *FlowProcessorService
+	userRepo  *repository.UserRepository
+	slaRepo   *repository.StageSLARepository
+}
+
+// NewStageSLAMonitor creates a new stage SLA monitor
+func NewStageSLAMonitor(processor *FlowProcessorService, userRepo *repository.UserRepository, slaRepo *repository.StageSLARepository) *StageSLAMonitor {
+	return &StageSLAMonitor{
+		processor: processor,
+		userRepo:  userRepo,
+		slaRepo:   slaRepo,
+	}
+}
+
+// Start checks stage SLAs immediately and then every interval until ctx is cancelled
+func (m *StageSLAMonitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if breaches, err := m.CheckAll(ctx); err != nil {
+				log.Printf("⚠️  Stage SLA check failed: %v", err)
+			} else if breaches > 0 {
+				log.Printf("⏰ Flagged %d stage SLA breach(es)", breaches)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckAll handles every conversation past its stage SLA and returns how many were flagged
+func (m *StageSLAMonitor) CheckAll(ctx context.Context) (int, error) {
+	stages, err := m.processor.stageRepo.GetSLAStageValues(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load stage SLAs: %w", err)
+	}
+
+	total := 0
+	for i := range stages {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		stage := &stages[i]
+		if !stage.HasSLA() {
+			continue
+		}
+
+		conversations, err := m.overdueConversations(ctx, stage, time.Now())
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+
+		for _, conv := range conversations {
+			m.handleBreach(ctx, stage, conv)
+			total++
+		}
+	}
+
+	return total, nil
+}
+
+// overdueConversations returns the ai_whatsapp and wasapbot conversations past the stage SLA at now
+func (m *StageSLAMonitor) overdueConversations(ctx context.Context, stage *models.StageValue, now time.Time) ([]slaConversation, error) {
+	cutoff := now.Add(-time.Duration(*stage.SLAMinutes) * time.Minute)
+	var overdue []slaConversation
+
+	aiConversations, err := m.processor.convRepo.GetStageSLABreaches(ctx, stage.IDDevice, stage.Stage, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range aiConversations {
+		if conv.IDProspect == nil || conv.StageEnteredAt == nil {
+			continue
+		}
+		overdue = append(overdue, slaConversation{
+			source:         "ai_whatsapp",
+			id:             fmt.Sprintf("%d", *conv.IDProspect),
+			prospectNum:    conv.ProspectNum,
+			prospectName:   getStringValue(conv.ProspectName),
+			flowID:         conv.FlowID,
+			state:          conv.ExecutionState().State(),
+			stageEnteredAt: *conv.StageEnteredAt,
+		})
+	}
+
+	botConversations, err := m.processor.wasapbotRepo.GetStageSLABreaches(ctx, stage.IDDevice, stage.Stage, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, conv := range botConversations {
+		if conv.IDProspect == nil || conv.StageEnteredAt == nil {
+			continue
+		}
+		overdue = append(overdue, slaConversation{
+			source:         "wasapbot",
+			id:             fmt.Sprintf("%d", *conv.IDProspect),
+			prospectNum:    conv.ProspectNum,
+			prospectName:   getStringValue(conv.ProspectName),
+			flowID:         conv.FlowID,
+			state:          conv.ExecutionState().State(),
+			stageEnteredAt: *conv.StageEnteredAt,
+		})
+	}
+
+	return overdue, nil
+}
+
+// handleBreach flags the conversation, runs the configured action and logs the breach.
+// Nudges are skipped for conversations a human owns, and fall back to notifying the owner when they cannot run.
+func (m *StageSLAMonitor) handleBreach(ctx context.Context, stage *models.StageValue, conv slaConversation) {
+	now := time.Now()
+	log.Printf("⏰ Conversation %s (%s) exceeded %d min in stage %s", conv.id, conv.source, *stage.SLAMinutes, stage.Stage)
+
+	// Flag first so a failing action is not retried on every check
+	if err := m.markBreached(ctx, conv, now); err != nil {
+		log.Printf("⚠️  Failed to flag SLA breach on conversation %s: %v", conv.id, err)
+		return
+	}
+
+	action := stage.EffectiveSLAAction()
+	handled := false
+
+	if action == models.StageSLAActionNudge && conv.state != models.ConversationStateHandoff && conv.state != models.ConversationStatePausedByAgent {
+		if err := m.nudge(ctx, stage, conv); err != nil {
+			log.Printf("⚠️  SLA nudge for conversation %s failed, notifying owner: %v", conv.id, err)
+		} else {
+			handled = true
+		}
+	}
+
+	if !handled {
+		action = models.StageSLAActionNotify
+		if err := m.notifyOwner(ctx, stage, conv, now); err != nil {
+			log.Printf("⚠️  SLA notification for conversation %s failed: %v", conv.id, err)
+		} else {
+			handled = true
+		}
+	}
+
+	breach := &models.StageSLABreach{
+		IDDevice:       stage.IDDevice,
+		ProspectNum:    conv.prospectNum,
+		ConversationID: conv.id,
+		Source:         conv.source,
+		Stage:          stage.Stage,
+		SLAMinutes:     *stage.SLAMinutes,
+		Action:         action,
+		Handled:        handled,
+		StageEnteredAt: conv.stageEnteredAt,
+		BreachedAt:     now,
+	}
+	if err := m.slaRepo.RecordBreach(ctx, breach); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// markBreached sets sla_breached_at; the stage trigger clears it when the stage changes
+func (m *StageSLAMonitor) markBreached(ctx context.Context, conv slaConversation, at time.Time) error {
+	updates := map[string]interface{}{
+		"sla_breached_at": at,
+	}
+	if conv.source == "wasapbot" {
+		return m.processor.wasapbotRepo.UpdateConversation(ctx, conv.id, updates)
+	}
+	return m.processor.convRepo.UpdateConversation(ctx, conv.id, updates)
+}
+
+// nudge runs the stage's nudge node from the conversation's flow on its own, without advancing the flow
+func (m *StageSLAMonitor) nudge(ctx context.Context, stage *models.StageValue, conv slaConversation) error {
+	nodeID := getStringValue(stage.SLANudgeNodeID)
+	if nodeID == "" {
+		return fmt.Errorf("stage %s has no sla_nudge_node_id", stage.Stage)
+	}
+	if conv.flowID == nil || *conv.flowID == "" {
+		return fmt.Errorf("conversation is not bound to a flow")
+	}
+
+	flow, err := m.processor.flowRepo.GetFlowByID(ctx, *conv.flowID)
+	if err != nil || flow == nil {
+		return fmt.Errorf("failed to load flow %s: %v", *conv.flowID, err)
+	}
+
+	var flowData FlowData
+	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
+		return fmt.Errorf("failed to parse flow data: %w", err)
+	}
+
+	var node *FlowNode
+	for i := range flowData.Nodes {
+		if flowData.Nodes[i].ID == nodeID {
+			node = &flowData.Nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return fmt.Errorf("nudge node %s not found in flow %s", nodeID, flow.ID)
+	}
+
+	nodeCtx, cancel := m.processor.deadlines.nodeContext(ctx)
+	defer cancel()
+
+	if conv.source == "wasapbot" {
+		engine := NewWasapbotFlowEngine(m.processor.runtimeServices(), m.processor.wasapbotRepo, m.processor.whatsappService)
+		_, err = engine.runtime().runNode(nodeCtx, flow, node, conv.id, "")
+	} else {
+		_, err = m.processor.runtime().runNode(nodeCtx, flow, node, conv.id, "")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run nudge node %s: %w", nodeID, err)
+	}
+
+	log.Printf("👉 Sent SLA nudge (node %s) to %s", nodeID, conv.prospectNum)
+	return nil
+}
+
+// notifyOwner messages the device owner's profile phone from the device itself
+func (m *StageSLAMonitor) notifyOwner(ctx context.Context, stage *models.StageValue, conv slaConversation, now time.Time) error {
+	device, err := m.processor.deviceRepo.GetDeviceByIDDevice(ctx, stage.IDDevice)
+	if err != nil || device == nil || device.UserID == nil {
+		return fmt.Errorf("failed to resolve device owner for %s: %v", stage.IDDevice, err)
+	}
+
+	owner, err := m.userRepo.GetUserByID(ctx, *device.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load device owner: %w", err)
+	}
+	phone := getStringValue(owner.Phone)
+	if phone == "" {
+		return fmt.Errorf("device owner has no phone number in their profile")
+	}
+
+	prospect := conv.prospectNum
+	if conv.prospectName != "" {
+		prospect = fmt.Sprintf("%s (%s)", conv.prospectName, conv.prospectNum)
+	}
+	dwell := now.Sub(conv.stageEnteredAt).Round(time.Minute)
+	limit := time.Duration(*stage.SLAMinutes) * time.Minute
+	text := fmt.Sprintf("⏰ SLA alert: %s has been in stage \"%s\" for %s (limit %s) on device %s.",
+		prospect, stage.Stage, formatSLADuration(dwell), formatSLADuration(limit), stage.IDDevice)
+
+	return m.processor.whatsappService.SendMessage(ctx, stage.IDDevice, phone, text, "", "")
+}
+
+// formatSLADuration renders a dwell time as "1d 2h", "5h 30m" or "45m"
+func formatSLADuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	days, hours, mins := minutes/(24*60), (minutes/60)%24, minutes%60
+
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && mins > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", mins)
+	}
+}
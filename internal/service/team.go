@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// TeamService manages the members of a user's team and answers what a user may do on a device:
+// its owner everything, team members what their role allows on the devices shared with them
+type TeamService struct {
+	teamRepo   *repository.TeamRepository
+	userRepo   *repository.UserRepository
+	deviceRepo *repository.DeviceRepository
+}
+
+// NewTeamService creates a new team service
+func NewTeamService(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository, deviceRepo *repository.DeviceRepository) *TeamService {
+	return &TeamService{
+		teamRepo:   teamRepo,
+		userRepo:   userRepo,
+		deviceRepo: deviceRepo,
+	}
+}
+
+// teamDeviceKey returns the id_device a membership's device_ids refer to a device by
+func teamDeviceKey(device *models.DeviceSetting) string {
+	if device.IDDevice != nil && *device.IDDevice != "" {
+		return *device.IDDevice
+	}
+	if device.DeviceID != nil {
+		return *device.DeviceID
+	}
+	return ""
+}
+
+// sharesDevice reports whether a membership covers a device of its owner
+func sharesDevice(member *models.TeamMember, device *models.DeviceSetting) bool {
+	return len(member.DeviceIDs) == 0 || containsString(member.DeviceIDs, teamDeviceKey(device))
+}
+
+// DeviceRole returns the user's role on a device: owner of their own devices, their team role on
+// devices shared with them, or "" without access
+func (s *TeamService) DeviceRole(ctx context.Context, userID string, device *models.DeviceSetting) (string, error) {
+	if device == nil || device.UserID == nil || userID == "" {
+		return "", nil
+	}
+	if *device.UserID == userID {
+		return models.TeamRoleOwner, nil
+	}
+	if s == nil {
+		return "", nil
+	}
+
+	member, err := s.teamRepo.GetMembership(ctx, *device.UserID, userID)
+	if err != nil {
+		return "", err
+	}
+	if member == nil || !sharesDevice(member, device) {
+		return "", nil
+	}
+	return member.Role, nil
+}
+
+// Allows reports whether the user may act on a device with a permission. Without a team service
+// only the owner may.
+func (s *TeamService) Allows(ctx context.Context, userID string, device *models.DeviceSetting, permission string) bool {
+	role, err := s.DeviceRole(ctx, userID, device)
+	if err != nil {
+		log.Printf("⚠️  Failed to check team access of user %s: %v", userID, err)
+		return false
+	}
+	return models.TeamRoleAllows(role, permission)
+}
+
+// SharedDevices returns the devices of other users shared with the user where their role has a
+// permission
+func (s *TeamService) SharedDevices(ctx context.Context, userID, permission string) ([]models.DeviceSetting, []string, error) {
+	if s == nil {
+		return nil, nil, nil
+	}
+
+	memberships, err := s.teamRepo.GetMembershipsByUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var devices []models.DeviceSetting
+	var roles []string
+	for i := range memberships {
+		member := &memberships[i]
+		if !models.TeamRoleAllows(member.Role, permission) {
+			continue
+		}
+		owned, err := s.deviceRepo.GetDevicesByUserID(ctx, member.OwnerID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, device := range owned {
+			if sharesDevice(member, &device) {
+				if !models.TeamRoleAllows(member.Role, models.TeamPermissionEditDevices) {
+					redactDeviceSecrets(&device)
+				}
+				devices = append(devices, device)
+				roles = append(roles, member.Role)
+			}
+		}
+	}
+	return devices, roles, nil
+}
+
+// accessibleDeviceIDs returns the identifiers of the user's own devices and of the devices shared
+// with them where their role has a permission
+func (s *TeamService) accessibleDeviceIDs(ctx context.Context, deviceRepo *repository.DeviceRepository, userID, permission string) ([]string, error) {
+	deviceIDs, err := userDeviceIDs(ctx, deviceRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, _, err := s.SharedDevices(ctx, userID, permission)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		if key := teamDeviceKey(&shared[i]); key != "" && !containsString(deviceIDs, key) {
+			deviceIDs = append(deviceIDs, key)
+		}
+	}
+	return deviceIDs, nil
+}
+
+// ListSharedDevices returns the devices shared with the user and their role on each
+func (s *TeamService) ListSharedDevices(ctx context.Context, userID string) (*models.TeamResponse, error) {
+	devices, roles, err := s.SharedDevices(ctx, userID, models.TeamPermissionViewDevices)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make([]models.SharedDevice, 0, len(devices))
+	for i, device := range devices {
+		shared = append(shared, models.SharedDevice{
+			IDDevice:    teamDeviceKey(&device),
+			OwnerID:     getStringValue(device.UserID),
+			Role:        roles[i],
+			Provider:    device.Provider,
+			PhoneNumber: device.PhoneNumber,
+		})
+	}
+
+	return &models.TeamResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d shared devices", len(shared)),
+		Devices: shared,
+	}, nil
+}
+
+// ListMembers returns the members of the user's team
+func (s *TeamService) ListMembers(ctx context.Context, userID string) (*models.TeamResponse, error) {
+	members, err := s.teamRepo.GetMembersByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []models.TeamMember{}
+	}
+
+	return &models.TeamResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d team members", len(members)),
+		Members: members,
+	}, nil
+}
+
+// AddMember gives a registered user a role on the user's devices
+func (s *TeamService) AddMember(ctx context.Context, userID string, req *models.AddTeamMemberRequest) (*models.TeamResponse, error) {
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if !models.IsAssignableTeamRole(role) {
+		return &models.TeamResponse{
+			Success: false,
+			Message: fmt.Sprintf("role must be %s, %s or %s", models.TeamRoleAdmin, models.TeamRoleAgent, models.TeamRoleViewer),
+		}, nil
+	}
+
+	deviceIDs, msg, err := s.ownDeviceIDs(ctx, userID, req.DeviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if msg != "" {
+		return &models.TeamResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	email := strings.TrimSpace(req.Email)
+	// The invitee's user row is not the owner's, so it is looked up as system work for the tenancy audit
+	user, _ := s.userRepo.GetUserByEmail(database.WithTenant(ctx, ""), email)
+	if user == nil {
+		return &models.TeamResponse{
+			Success: false,
+			Message: fmt.Sprintf("No registered user has the email %s", email),
+		}, nil
+	}
+	if user.ID == userID {
+		return &models.TeamResponse{
+			Success: false,
+			Message: "You already own your devices",
+		}, nil
+	}
+
+	members, err := s.teamRepo.GetMembersByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.UserID == user.ID {
+			return &models.TeamResponse{
+				Success: false,
+				Message: fmt.Sprintf("%s is already a member of your team", email),
+			}, nil
+		}
+	}
+	if len(members) >= models.MaxTeamMembers {
+		return &models.TeamResponse{
+			Success: false,
+			Message: fmt.Sprintf("A team can have at most %d members", models.MaxTeamMembers),
+		}, nil
+	}
+
+	member := &models.TeamMember{
+		OwnerID:   userID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      role,
+		DeviceIDs: deviceIDs,
+	}
+	if err := s.teamRepo.CreateMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return &models.TeamResponse{
+		Success: true,
+		Message: "Team member added",
+		Member:  member,
+	}, nil
+}
+
+// UpdateMember changes a member's role or shared devices
+func (s *TeamService) UpdateMember(ctx context.Context, userID, memberID string, req *models.UpdateTeamMemberRequest) (*models.TeamResponse, error) {
+	member, msg, err := s.ownedMember(ctx, userID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if msg != "" {
+		return &models.TeamResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Role != nil {
+		role := strings.ToLower(strings.TrimSpace(*req.Role))
+		if !models.IsAssignableTeamRole(role) {
+			return &models.TeamResponse{
+				Success: false,
+				Message: fmt.Sprintf("role must be %s, %s or %s", models.TeamRoleAdmin, models.TeamRoleAgent, models.TeamRoleViewer),
+			}, nil
+		}
+		updates["role"] = role
+		member.Role = role
+	}
+	if req.DeviceIDs != nil {
+		deviceIDs, msg, err := s.ownDeviceIDs(ctx, userID, *req.DeviceIDs)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			return &models.TeamResponse{
+				Success: false,
+				Message: msg,
+			}, nil
+		}
+		updates["device_ids"] = deviceIDs
+		member.DeviceIDs = deviceIDs
+	}
+
+	if len(updates) > 0 {
+		if err := s.teamRepo.UpdateMember(ctx, memberID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.TeamResponse{
+		Success: true,
+		Message: "Team member updated",
+		Member:  member,
+	}, nil
+}
+
+// RemoveMember takes a member off the user's team
+func (s *TeamService) RemoveMember(ctx context.Context, userID, memberID string) (*models.TeamResponse, error) {
+	_, msg, err := s.ownedMember(ctx, userID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if msg != "" {
+		return &models.TeamResponse{
+			Success: false,
+			Message: msg,
+		}, nil
+	}
+
+	if err := s.teamRepo.DeleteMember(ctx, memberID); err != nil {
+		return nil, err
+	}
+
+	return &models.TeamResponse{
+		Success: true,
+		Message: "Team member removed",
+	}, nil
+}
+
+// ownedMember loads a member and checks they are on the user's team
+func (s *TeamService) ownedMember(ctx context.Context, userID, memberID string) (*models.TeamMember, string, error) {
+	member, err := s.teamRepo.GetMemberByID(ctx, memberID)
+	if err != nil {
+		return nil, "", err
+	}
+	if member == nil {
+		return nil, "Team member not found", nil
+	}
+	if member.OwnerID != userID {
+		return nil, "Access denied", nil
+	}
+	return member, "", nil
+}
+
+// ownDeviceIDs checks the devices a member is given are the user's own and deduplicates them.
+// Returns a user-facing message when one is not.
+func (s *TeamService) ownDeviceIDs(ctx context.Context, userID string, requested []string) ([]string, string, error) {
+	owned, err := userDeviceIDs(ctx, s.deviceRepo, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deviceIDs := []string{}
+	for _, id := range requested {
+		id = strings.TrimSpace(id)
+		if id == "" || containsString(deviceIDs, id) {
+			continue
+		}
+		if !containsString(owned, id) {
+			return nil, fmt.Sprintf("Device %s is not one of your devices", id), nil
+		}
+		deviceIDs = append(deviceIDs, id)
+	}
+	return deviceIDs, "", nil
+}
+
+// redactDeviceSecrets blanks a device's credentials for members who may not edit it
+func redactDeviceSecrets(device *models.DeviceSetting) {
+	stripDeviceSecrets(device)
+	device.Instance = nil
+}
+
+// stripDeviceSecrets removes the secrets of a device that must not leave its editors: the provider
+// API key, the webhook ID that authenticates inbound messages, the Telegram bot and Facebook page
+// connections, and AI endpoint and custom gateway headers (usually credentials).
+// Maps are replaced rather than edited, so a shared device value is left untouched.
+func stripDeviceSecrets(device *models.DeviceSetting) {
+	device.APIKey = nil
+	device.WebhookID = nil
+	device.TelegramBotToken = nil
+	device.Messenger = nil
+	if device.AIEndpoints != nil {
+		endpoints := make(map[models.AIProvider]models.AIEndpoint, len(device.AIEndpoints))
+		for provider, endpoint := range device.AIEndpoints {
+			endpoint.Headers = nil
+			endpoints[provider] = endpoint
+		}
+		device.AIEndpoints = endpoints
+	}
+	if device.CustomProvider != nil {
+		custom := *device.CustomProvider
+		custom.Headers = nil
+		device.CustomProvider = &custom
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"chatbot-automation/internal/database"
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// postgrestInserts are the rows a fake PostgREST was asked to insert, by table
+type postgrestInserts struct {
+	mu   sync.Mutex
+	rows map[string][]map[string]interface{}
+}
+
+// table returns the rows inserted into a table
+func (i *postgrestInserts) table(name string) []map[string]interface{} {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rows[name]
+}
+
+// fakePostgREST serves the rows of each table to every select, records the rows of every insert
+// and accepts every other write
+func fakePostgREST(t *testing.T, tables map[string]interface{}) (*database.SupabaseClient, *postgrestInserts) {
+	t.Helper()
+
+	inserts := &postgrestInserts{rows: make(map[string][]map[string]interface{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := strings.TrimPrefix(r.URL.Path, "/rest/v1/")
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			var body interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			inserts.mu.Lock()
+			switch typed := body.(type) {
+			case map[string]interface{}:
+				inserts.rows[table] = append(inserts.rows[table], typed)
+			case []interface{}:
+				for _, row := range typed {
+					if row, ok := row.(map[string]interface{}); ok {
+						inserts.rows[table] = append(inserts.rows[table], row)
+					}
+				}
+			}
+			inserts.mu.Unlock()
+		}
+		if r.Method != http.MethodGet {
+			w.Write([]byte("[]"))
+			return
+		}
+		rows, ok := tables[table]
+		if !ok {
+			w.Write([]byte("[]"))
+			return
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(server.Close)
+
+	return database.NewSupabaseClient(server.URL, "anon", "service"), inserts
+}
+
+func TestGetDeviceRedactsSecretsForViewer(t *testing.T) {
+	owner, viewer := "owner-1", "viewer-1"
+	device := models.DeviceSetting{
+		ID:               "device-1",
+		UserID:           &owner,
+		IDDevice:         stringPtr("shop"),
+		WebhookID:        stringPtr("webhook-secret"),
+		APIKey:           stringPtr("provider-key"),
+		Instance:         stringPtr("instance-token"),
+		TelegramBotToken: stringPtr("telegram-token"),
+		Messenger: &models.MessengerConfig{
+			PageAccessToken: "page-token",
+			VerifyToken:     "verify-token",
+			AppSecret:       "app-secret",
+		},
+		AIEndpoints: map[models.AIProvider]models.AIEndpoint{
+			"openrouter": {BaseURL: "https://gateway.example.com/v1", Headers: map[string]string{"Authorization": "Bearer gateway"}},
+		},
+		CustomProvider: &models.CustomProviderConfig{
+			SendURL: "https://sms.example.com/send",
+			Headers: map[string]string{"X-Token": "custom-secret"},
+		},
+	}
+
+	for _, tt := range []struct {
+		role    string
+		redacts bool
+	}{
+		{models.TeamRoleViewer, true},
+		{models.TeamRoleAgent, true},
+		{models.TeamRoleAdmin, false},
+	} {
+		t.Run(tt.role, func(t *testing.T) {
+			db, _ := fakePostgREST(t, map[string]interface{}{
+				"device_setting": []models.DeviceSetting{device},
+				"team_members":   []models.TeamMember{{OwnerID: owner, UserID: viewer, Role: tt.role}},
+			})
+			deviceRepo := repository.NewDeviceRepository(db)
+			team := NewTeamService(repository.NewTeamRepository(db), nil, deviceRepo)
+			service := NewDeviceService(deviceRepo, nil, nil, team)
+
+			resp, err := service.GetDevice(context.Background(), viewer, device.ID)
+			if err != nil || !resp.Success {
+				t.Fatalf("GetDevice = %+v, %v", resp, err)
+			}
+
+			got := resp.Device
+			if !tt.redacts {
+				if got.Messenger == nil || got.AIEndpoints["openrouter"].Headers == nil || got.APIKey == nil {
+					t.Fatalf("admin lost device credentials: %+v", got)
+				}
+				return
+			}
+
+			if got.Messenger != nil {
+				t.Errorf("Messenger = %+v, want nil", got.Messenger)
+			}
+			if got.WebhookID != nil || got.APIKey != nil || got.Instance != nil || got.TelegramBotToken != nil {
+				t.Errorf("credentials returned: webhook_id=%v api_key=%v instance=%v telegram=%v",
+					got.WebhookID, got.APIKey, got.Instance, got.TelegramBotToken)
+			}
+			if endpoint := got.AIEndpoints["openrouter"]; endpoint.Headers != nil || endpoint.BaseURL == "" {
+				t.Errorf("AI endpoint = %+v, want base URL without headers", endpoint)
+			}
+			if got.CustomProvider == nil || got.CustomProvider.Headers != nil || got.CustomProvider.SendURL == "" {
+				t.Errorf("custom provider = %+v, want send URL without headers", got.CustomProvider)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+	"chatbot-automation/internal/whatsapp"
+)
+
+// telegramBotTokenPattern matches the <bot id>:<secret> tokens BotFather issues
+var telegramBotTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]{20,}$`)
+
+// telegramProvider returns the Bot API client of a device's Telegram bot
+func telegramProvider(device *models.DeviceSetting) *whatsapp.TelegramProvider {
+	return whatsapp.NewTelegramProvider(&whatsapp.ProviderConfig{
+		Provider: models.ChannelTelegram,
+		APIKey:   getStringValue(device.TelegramBotToken),
+	})
+}
+
+// TelegramService is the inbound side of the Telegram channel: bot updates run through the same
+// flow processor as WhatsApp webhooks, with prospect_num tg:<chat id>, so a device's flows serve
+// both. Replies go out through WhatsAppService, which sends tg: prospects through the bot.
+type TelegramService struct {
+	flowProcessor *FlowProcessorService
+	deviceRepo    *repository.DeviceRepository
+}
+
+// NewTelegramService creates a new Telegram service
+func NewTelegramService(flowProcessor *FlowProcessorService, deviceRepo *repository.DeviceRepository) *TelegramService {
+	return &TelegramService{
+		flowProcessor: flowProcessor,
+		deviceRepo:    deviceRepo,
+	}
+}
+
+// resolveDevice finds the device a bot delivers to by webhook_id, then id_device
+func (s *TelegramService) resolveDevice(ctx context.Context, webhookID string) *models.DeviceSetting {
+	device, err := s.deviceRepo.GetDeviceByWebhookID(ctx, webhookID)
+	if err != nil || device == nil {
+		device, _ = s.deviceRepo.GetDeviceByIDDevice(ctx, webhookID)
+	}
+	return device
+}
+
+// ReceiveUpdate accepts a Telegram update and processes it in the background. It reports false,
+// without an error, for updates the channel ignores (group chats, edits, unknown devices).
+func (s *TelegramService) ReceiveUpdate(ctx context.Context, webhookID string, update map[string]interface{}) (bool, error) {
+	device := s.resolveDevice(ctx, webhookID)
+	if device == nil || getStringValue(device.TelegramBotToken) == "" {
+		return false, nil
+	}
+
+	message, callback := whatsapp.TelegramUpdateMessage(update)
+	if message == nil {
+		return false, nil
+	}
+	if chat, _ := message["chat"].(map[string]interface{}); chat["type"] != "private" {
+		return false, nil
+	}
+
+	// Stop the tapped button spinning; the reply itself comes from the flow
+	if callback != nil {
+		if id, _ := callback["id"].(string); id != "" {
+			if err := telegramProvider(device).AnswerCallback(ctx, id); err != nil {
+				log.Printf("⚠️  Failed to answer Telegram callback: %v", err)
+			}
+		}
+	}
+
+	// The update keeps its own shape; the channel marker routes it to the Telegram extractor
+	rawData := make(map[string]interface{}, len(update)+1)
+	for key, value := range update {
+		rawData[key] = value
+	}
+	rawData["channel"] = models.ChannelTelegram
+
+	// Async like provider webhooks, so Telegram does not retry slow flows
+	go func() {
+		runCtx, cancel := s.flowProcessor.RunContext()
+		defer cancel()
+		if err := s.flowProcessor.ProcessIncomingMessage(runCtx, webhookID, rawData); err != nil {
+			log.Printf("❌ Failed to process Telegram update: %v", err)
+		}
+	}()
+
+	return true, nil
+}
+
+// RegisterWebhook points the device's bot at webhookURL, which should be the public address of
+// POST /api/webhooks/telegram/:webhook_id
+func (s *TelegramService) RegisterWebhook(ctx context.Context, userID, deviceID string, req *models.TelegramWebhookRequest) (*models.TelegramWebhookResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil || device == nil {
+		return &models.TelegramWebhookResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+	if device.UserID == nil || *device.UserID != userID {
+		return &models.TelegramWebhookResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+	if getStringValue(device.TelegramBotToken) == "" {
+		return &models.TelegramWebhookResponse{
+			Success: false,
+			Message: "Set the device's telegram_bot_token first",
+		}, nil
+	}
+	if u, err := url.Parse(req.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+		return &models.TelegramWebhookResponse{
+			Success: false,
+			Message: "url must be an https URL",
+		}, nil
+	}
+
+	bot := telegramProvider(device)
+	username, err := bot.BotUsername(ctx)
+	if err != nil {
+		return &models.TelegramWebhookResponse{
+			Success: false,
+			Message: fmt.Sprintf("Telegram rejected the bot token: %v", err),
+		}, nil
+	}
+	if err := bot.SetWebhook(ctx, req.URL); err != nil {
+		return nil, fmt.Errorf("failed to set Telegram webhook: %w", err)
+	}
+
+	return &models.TelegramWebhookResponse{
+		Success: true,
+		Message: "Telegram webhook registered",
+		Bot:     username,
+	}, nil
+}
+
+// validTelegramBotToken reports whether token looks like a BotFather token
+func validTelegramBotToken(token string) bool {
+	return telegramBotTokenPattern.MatchString(strings.TrimSpace(token))
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Text and number condition types for conditions node edges. They test the prospect's message, or
+// the conversation variable named by the edge's conditionVariable (a column such as pakej or stage,
+// a custom field or a session variable):
+//
+//	regex             "^(ya|yes)\b"   case-insensitive regular expression on the text as written
+//	starts_with       "nak"           ignoring case, emoji and punctuation
+//	ends_with         "terima kasih"  ignoring case, emoji and punctuation
+//	greater_than      "100"           the first number in the text, such as 150 in "RM150"
+//	less_than         "100"
+//	greater_or_equal  "18"
+//	less_or_equal     "18"
+//	in_list           "Gold, Silver"  the text is one of the comma-separated items
+const (
+	ConditionRegex          = "regex"
+	ConditionStartsWith     = "starts_with"
+	ConditionEndsWith       = "ends_with"
+	ConditionGreaterThan    = "greater_than"
+	ConditionLessThan       = "less_than"
+	ConditionGreaterOrEqual = "greater_or_equal"
+	ConditionLessOrEqual    = "less_or_equal"
+	ConditionInList         = "in_list"
+)
+
+// conditionNumber finds the first number in a message: 1,500 and 1,500.50 with thousands
+// separators, 1.5 and 1,5 with a decimal point or comma. "3, 4" is 3.
+var conditionNumber = regexp.MustCompile(`-?(?:\d{1,3}(?:,\d{3})+\b(?:\.\d+)?|\d+(?:\.\d+|,\d{1,2}\b)?)`)
+
+// conditionRegexes caches the compiled regex of every regex condition value, so a condition is
+// compiled when its flow is validated or first evaluated rather than on every message
+var conditionRegexes = struct {
+	sync.Mutex
+	compiled map[string]compiledConditionRegex
+}{compiled: map[string]compiledConditionRegex{}}
+
+type compiledConditionRegex struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// isTextCondition reports whether conditionType compares text: the equal and contains conditions
+// and the ones above
+func isTextCondition(conditionType string) bool {
+	switch strings.ToLower(conditionType) {
+	case "equal", "contains", "match",
+		ConditionRegex, ConditionStartsWith, ConditionEndsWith, ConditionInList,
+		ConditionGreaterThan, ConditionLessThan, ConditionGreaterOrEqual, ConditionLessOrEqual:
+		return true
+	}
+	return false
+}
+
+// isNumericCondition reports whether conditionType compares numbers
+func isNumericCondition(conditionType string) bool {
+	switch strings.ToLower(conditionType) {
+	case ConditionGreaterThan, ConditionLessThan, ConditionGreaterOrEqual, ConditionLessOrEqual:
+		return true
+	}
+	return false
+}
+
+// evaluateTextCondition checks a text condition against text, the message or a variable. Invalid
+// values (a broken regex, a number condition without a number) never match. Both engines branch
+// through it.
+func evaluateTextCondition(conditionType, text, value string, malay bool) bool {
+	matched, err := matchTextCondition(strings.ToLower(conditionType), text, value, malay)
+	if err != nil {
+		log.Printf("⚠️  Invalid %s condition %q: %v", conditionType, value, err)
+		return false
+	}
+	return matched
+}
+
+func matchTextCondition(conditionType, text, value string, malay bool) (bool, error) {
+	switch conditionType {
+	case "equal", "contains", "match":
+		return messageMatches(conditionType, text, value, malay), nil
+
+	case ConditionRegex:
+		re, err := conditionRegex(value)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(strings.TrimSpace(text)), nil
+
+	case ConditionStartsWith, ConditionEndsWith:
+		normalizedText, normalizedValue := normalizeConditionText(text, malay), normalizeConditionText(value, malay)
+		if normalizedValue == "" {
+			normalizedText, normalizedValue = strings.ToLower(strings.TrimSpace(text)), strings.ToLower(strings.TrimSpace(value))
+		}
+		if conditionType == ConditionStartsWith {
+			return strings.HasPrefix(normalizedText, normalizedValue), nil
+		}
+		return strings.HasSuffix(normalizedText, normalizedValue), nil
+
+	case ConditionInList:
+		items := conditionListItems(value)
+		if len(items) == 0 {
+			return false, fmt.Errorf("the list has no items")
+		}
+		for _, item := range items {
+			if messageMatches("equal", text, item, malay) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	expected, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return false, fmt.Errorf("%q is not a number", value)
+	}
+	actual, ok := firstNumber(text)
+	if !ok {
+		return false, nil
+	}
+	switch conditionType {
+	case ConditionGreaterThan:
+		return actual > expected, nil
+	case ConditionLessThan:
+		return actual < expected, nil
+	case ConditionGreaterOrEqual:
+		return actual >= expected, nil
+	case ConditionLessOrEqual:
+		return actual <= expected, nil
+	}
+	return false, fmt.Errorf("unknown condition type")
+}
+
+// firstNumber returns the first number in text, such as 1500 in "RM1,500 je" or 1.5 in "1,5 kg"
+func firstNumber(text string) (float64, bool) {
+	match := conditionNumber.FindString(text)
+	if match == "" {
+		return 0, false
+	}
+	if comma := strings.LastIndex(match, ","); comma >= 0 && !strings.Contains(match, ".") && len(match)-comma <= 3 {
+		match = strings.Replace(match, ",", ".", 1)
+	} else {
+		match = strings.ReplaceAll(match, ",", "")
+	}
+	number, err := strconv.ParseFloat(match, 64)
+	return number, err == nil
+}
+
+// conditionRegex compiles a regex condition value case-insensitively, once per value
+func conditionRegex(value string) (*regexp.Regexp, error) {
+	conditionRegexes.Lock()
+	defer conditionRegexes.Unlock()
+
+	cached, ok := conditionRegexes.compiled[value]
+	if !ok {
+		cached.re, cached.err = regexp.Compile("(?i)" + value)
+		conditionRegexes.compiled[value] = cached
+	}
+	return cached.re, cached.err
+}
+
+// conditionListItems splits an in_list value into its trimmed, non-empty items
+func conditionListItems(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateTextCondition checks the value of a text condition the way evaluateTextCondition reads it.
+// Returns why it can never match, or "" when valid.
+func validateTextCondition(conditionType, value string) string {
+	switch {
+	case strings.EqualFold(conditionType, ConditionRegex):
+		if _, err := conditionRegex(value); err != nil {
+			return fmt.Sprintf("invalid regex: %v", err)
+		}
+	case strings.EqualFold(conditionType, ConditionInList):
+		if len(conditionListItems(value)) == 0 {
+			return "the list has no items"
+		}
+	case isNumericCondition(conditionType):
+		if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return fmt.Sprintf("%q is not a number", value)
+		}
+	}
+	return ""
+}
+
+// conditionSubject returns the text a text condition tests: the conversation variable the edge
+// names in conditionVariable, else the prospect's message
+func (run *flowRun) conditionSubject(ctx context.Context, edge FlowEdge) string {
+	name := strings.TrimSpace(edge.ConditionVariable)
+	if name == "" {
+		return run.userMessage
+	}
+
+	conversation, err := run.load(ctx, run.conversationID)
+	if err != nil || conversation == nil {
+		log.Printf("⚠️  Failed to load conversation %s for %s condition on %s: %v", run.conversationID, edge.ConditionType, name, err)
+		return ""
+	}
+	return variableText(conversationVariables(conversation.row), name)
+}
@@ -0,0 +1,90 @@
+package service
+
+import "testing"
+
+func TestEvaluateTextCondition(t *testing.T) {
+	for _, tt := range []struct {
+		conditionType, text, value string
+		want                       bool
+	}{
+		{ConditionRegex, "Yes please", `^(ya|yes)\b`, true},
+		{ConditionRegex, "  ya  ", `^(ya|yes)$`, true},
+		{ConditionRegex, "no", `^(ya|yes)\b`, false},
+		{ConditionRegex, "anything", `(unclosed`, false},
+
+		{ConditionStartsWith, "Nak order 2 kotak!", "nak", true},
+		{ConditionStartsWith, "Saya nak", "nak", false},
+		{ConditionEndsWith, "Ok, terima kasih 🙏", "terima kasih", true},
+
+		{ConditionInList, "gold", "Gold, Silver", true},
+		{ConditionInList, "Silver!", "Gold, Silver", true},
+		{ConditionInList, "Bronze", "Gold, Silver", false},
+		{ConditionInList, "Gold", " , ", false},
+
+		{ConditionGreaterThan, "RM150", "100", true},
+		{ConditionGreaterThan, "RM1,500 je", "1000", true},
+		{ConditionGreaterThan, "100", "100", false},
+		{ConditionGreaterOrEqual, "100", "100", true},
+		{ConditionLessThan, "1,5 kg", "2", true},
+		{ConditionLessThan, "3, 4 or 5", "3.5", true},
+		{ConditionLessOrEqual, "umur 18 tahun", "18", true},
+		{ConditionLessThan, "-5", "0", true},
+		{ConditionGreaterThan, "no number here", "0", false},
+		{ConditionGreaterThan, "150", "lots", false},
+	} {
+		if got := evaluateTextCondition(tt.conditionType, tt.text, tt.value, false); got != tt.want {
+			t.Errorf("%s %q against %q = %v, want %v", tt.conditionType, tt.text, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFirstNumber(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want float64
+		ok   bool
+	}{
+		{"RM150", 150, true},
+		{"RM1,500 je", 1500, true},
+		{"1,234,567.89", 1234567.89, true},
+		{"1,500.50", 1500.5, true},
+		{"1,5", 1.5, true},
+		{"12,50 sen", 12.5, true},
+		{"3, 4", 3, true},
+		{"1,5000", 1, true},
+		{"100.5", 100.5, true},
+		{"-20", -20, true},
+		{"none", 0, false},
+	} {
+		got, ok := firstNumber(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("firstNumber(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateTextConditionCachesRegex(t *testing.T) {
+	if reason := validateTextCondition(ConditionRegex, `^order\s+\d+`); reason != "" {
+		t.Fatalf("valid regex rejected: %s", reason)
+	}
+	if reason := validateTextCondition(ConditionRegex, `(unclosed`); reason == "" {
+		t.Error("broken regex accepted")
+	}
+
+	first, err := conditionRegex(`^order\s+\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := conditionRegex(`^order\s+\d+`); again != first {
+		t.Error("regex compiled again instead of reused")
+	}
+	if !evaluateTextCondition(ConditionRegex, "ORDER 12", `^order\s+\d+`, false) {
+		t.Error("cached regex does not match case-insensitively")
+	}
+
+	for conditionType, value := range map[string]string{ConditionInList: ",", ConditionGreaterThan: "abc"} {
+		if validateTextCondition(conditionType, value) == "" {
+			t.Errorf("%s %q accepted", conditionType, value)
+		}
+	}
+}
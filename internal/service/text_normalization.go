@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// malaySpellings maps common Malay chat spellings and short forms to one standard word, so a
+// condition value written either way matches. Only applied when the flow sets normalize_malay.
+var malaySpellings = map[string]string{
+	// yes / no
+	"ye": "ya", "yer": "ya", "yea": "ya", "yep": "ya", "yup": "ya", "iye": "ya", "iya": "ya", "yes": "ya",
+	"x": "tidak", "tak": "tidak", "tk": "tidak", "tek": "tidak", "tdk": "tidak", "no": "tidak", "nope": "tidak",
+	"okay": "ok", "okey": "ok", "oke": "ok", "okk": "ok", "k": "ok",
+	// common short forms
+	"sy": "saya", "sye": "saya", "aku": "saya",
+	"nk": "nak", "nok": "nak", "hendak": "nak", "mahu": "nak", "mau": "nak",
+	"dh": "sudah", "dah": "sudah", "sdh": "sudah",
+	"blm": "belum", "lum": "belum",
+	"blh": "boleh",
+	"mcm": "macam", "cmne": "macam mana", "camne": "macam mana", "macamana": "macam mana", "mcmmana": "macam mana",
+	"brp": "berapa", "bape": "berapa", "berape": "berapa",
+	"hrg": "harga",
+	"utk": "untuk", "yg": "yang", "dgn": "dengan", "sbb": "sebab", "bkn": "bukan", "lg": "lagi",
+	"tgk": "tengok", "tau": "tahu", "tq": "terima kasih", "tqvm": "terima kasih", "thanks": "terima kasih",
+}
+
+// normalizeConditionText prepares a message or condition value for matching: lowercase, emoji and
+// punctuation removed, whitespace collapsed. With malay, letters stretched over three or more
+// repeats are squeezed ("yaaa" → "ya") and chat spellings are mapped through malaySpellings.
+func normalizeConditionText(text string, malay bool) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	if malay {
+		for i, word := range words {
+			word = squeezeRepeats(word)
+			if standard, ok := malaySpellings[word]; ok {
+				word = standard
+			}
+			words[i] = word
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// squeezeRepeats collapses a letter repeated three or more times in a row to one
+func squeezeRepeats(word string) string {
+	runes := []rune(word)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		if j-i >= 3 && unicode.IsLetter(runes[i]) {
+			out = append(out, runes[i])
+		} else {
+			out = append(out, runes[i:j]...)
+		}
+		i = j
+	}
+	return string(out)
+}
+
+// messageMatches evaluates an equal, contains or match condition on normalized text. A value that
+// normalizes to nothing (only emoji or punctuation) is compared as written instead.
+func messageMatches(conditionType, message, value string, malay bool) bool {
+	normalizedValue := normalizeConditionText(value, malay)
+	normalizedMessage := normalizeConditionText(message, malay)
+	if normalizedValue == "" {
+		normalizedValue, normalizedMessage = strings.ToLower(strings.TrimSpace(value)), strings.ToLower(strings.TrimSpace(message))
+	}
+
+	if strings.EqualFold(conditionType, "equal") {
+		return normalizedMessage == normalizedValue
+	}
+	return strings.Contains(normalizedMessage, normalizedValue)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// Time-based condition types for conditions node edges. All are evaluated in the device timezone.
+//
+//	is_business_hours  "Mon-Fri 09:00-18:00" (days optional, default Mon-Fri; several separated by ";")
+//	not_business_hours same value, matches when closed
+//	weekday            "Sat,Sun" or "Mon-Fri"
+//	time_between       "22:00-06:00" (may wrap past midnight)
+//	date_before        "2025-12-31" (today is before the date)
+//	date_after         "2025-12-31" (today is after the date)
+//	date_between       "2025-11-01..2025-12-31" (inclusive)
+const (
+	ConditionIsBusinessHours  = "is_business_hours"
+	ConditionNotBusinessHours = "not_business_hours"
+	ConditionWeekday          = "weekday"
+	ConditionTimeBetween      = "time_between"
+	ConditionDateBefore       = "date_before"
+	ConditionDateAfter        = "date_after"
+	ConditionDateBetween      = "date_between"
+)
+
+// defaultBusinessDays applies when an is_business_hours value gives only the hours
+var defaultBusinessDays = map[time.Weekday]bool{
+	time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// isTimeCondition reports whether an edge condition type is a temporal predicate
+func isTimeCondition(conditionType string) bool {
+	switch strings.ToLower(conditionType) {
+	case ConditionIsBusinessHours, ConditionNotBusinessHours, ConditionWeekday, ConditionTimeBetween,
+		ConditionDateBefore, ConditionDateAfter, ConditionDateBetween:
+		return true
+	}
+	return false
+}
+
+// evaluateTimeCondition checks a temporal predicate against now (already in the device timezone).
+// Invalid values are logged and never match.
+func evaluateTimeCondition(conditionType, value string, now time.Time) bool {
+	matched, err := matchTimeCondition(strings.ToLower(conditionType), strings.TrimSpace(value), now)
+	if err != nil {
+		log.Printf("⚠️  Invalid %s condition %q: %v", conditionType, value, err)
+		return false
+	}
+	return matched
+}
+
+func matchTimeCondition(conditionType, value string, now time.Time) (bool, error) {
+	switch conditionType {
+	case ConditionIsBusinessHours, ConditionNotBusinessHours:
+		open, err := inBusinessHours(value, now)
+		if err != nil {
+			return false, err
+		}
+		return open == (conditionType == ConditionIsBusinessHours), nil
+	case ConditionWeekday:
+		days, err := parseWeekdays(value)
+		if err != nil {
+			return false, err
+		}
+		return days[now.Weekday()], nil
+	case ConditionTimeBetween:
+		return inTimeRange(value, now)
+	case ConditionDateBefore, ConditionDateAfter:
+		date, err := time.ParseInLocation("2006-01-02", value, now.Location())
+		if err != nil {
+			return false, fmt.Errorf("expected YYYY-MM-DD")
+		}
+		today := startOfDay(now)
+		if conditionType == ConditionDateBefore {
+			return today.Before(date), nil
+		}
+		return today.After(date), nil
+	case ConditionDateBetween:
+		from, to, ok := strings.Cut(value, "..")
+		if !ok {
+			return false, fmt.Errorf("expected YYYY-MM-DD..YYYY-MM-DD")
+		}
+		start, err1 := time.ParseInLocation("2006-01-02", strings.TrimSpace(from), now.Location())
+		end, err2 := time.ParseInLocation("2006-01-02", strings.TrimSpace(to), now.Location())
+		if err1 != nil || err2 != nil {
+			return false, fmt.Errorf("expected YYYY-MM-DD..YYYY-MM-DD")
+		}
+		today := startOfDay(now)
+		return !today.Before(start) && !today.After(end), nil
+	}
+	return false, fmt.Errorf("unknown condition type")
+}
+
+// businessWindow is one "[days] HH:MM-HH:MM" part of a business hours value
+type businessWindow struct {
+	days  map[time.Weekday]bool
+	hours string
+}
+
+// parseBusinessHours parses "[days] HH:MM-HH:MM" windows separated by ";"
+func parseBusinessHours(value string) ([]businessWindow, error) {
+	var windows []businessWindow
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		window := businessWindow{days: defaultBusinessDays, hours: part}
+		if i := strings.LastIndex(part, " "); i > 0 {
+			days, err := parseWeekdays(part[:i])
+			if err != nil {
+				return nil, err
+			}
+			window.days = days
+			window.hours = part[i+1:]
+		}
+		if _, err := inTimeRange(window.hours, time.Time{}); err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("expected [days] HH:MM-HH:MM")
+	}
+	return windows, nil
+}
+
+// inBusinessHours parses "[days] HH:MM-HH:MM" (several separated by ";") and checks now against it
+func inBusinessHours(value string, now time.Time) (bool, error) {
+	windows, err := parseBusinessHours(value)
+	if err != nil {
+		return false, err
+	}
+	for _, window := range windows {
+		if !window.days[now.Weekday()] {
+			continue
+		}
+		if open, _ := inTimeRange(window.hours, now); open {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// nextBusinessOpening returns when the next business hours window after now opens, in now's location
+func nextBusinessOpening(value string, now time.Time) (time.Time, error) {
+	windows, err := parseBusinessHours(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var opening time.Time
+	for offset := 0; offset <= 7; offset++ {
+		day := startOfDay(now).AddDate(0, 0, offset)
+		for _, window := range windows {
+			if !window.days[day.Weekday()] {
+				continue
+			}
+			from, _, _ := strings.Cut(window.hours, "-")
+			start, _ := parseClock(from)
+			at := day.Add(time.Duration(start) * time.Minute)
+			if at.After(now) && (opening.IsZero() || at.Before(opening)) {
+				opening = at
+			}
+		}
+	}
+	return opening, nil
+}
+
+// inTimeRange checks now against "HH:MM-HH:MM"; a range ending before it starts wraps past midnight
+func inTimeRange(value string, now time.Time) (bool, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return false, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	start, err1 := parseClock(from)
+	end, err2 := parseClock(to)
+	if err1 != nil || err2 != nil {
+		return false, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// parseClock converts "HH:MM" to minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseWeekdays parses comma-separated day names or ranges ("Sat,Sun", "Mon-Fri", "Fri-Mon")
+func parseWeekdays(value string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := lookupWeekday(from)
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", from)
+		}
+		if !isRange {
+			days[start] = true
+			continue
+		}
+
+		end, ok := lookupWeekday(to)
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", to)
+		}
+		for day := start; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no days given")
+	}
+	return days, nil
+}
+
+// lookupWeekday accepts full or three-letter English day names
+func lookupWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	day, ok := weekdayNames[name[:3]]
+	return day, ok
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// deviceClock returns the current time in a device's timezone, loading the device at most once
+func deviceClock(ctx context.Context, deviceRepo *repository.DeviceRepository, idDevice string) func() time.Time {
+	var loc *time.Location
+	return func() time.Time {
+		if loc == nil {
+			var device *models.DeviceSetting
+			if deviceRepo != nil {
+				device, _ = deviceRepo.GetDeviceByIDDevice(ctx, idDevice)
+			}
+			if device == nil {
+				device = &models.DeviceSetting{}
+			}
+			loc = device.Location()
+		}
+		return time.Now().In(loc)
+	}
+}
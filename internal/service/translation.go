@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+)
+
+// Translation providers
+const (
+	TranslateProviderGoogle = "google"         // Google Cloud Translation v2
+	TranslateProviderLibre  = "libretranslate" // LibreTranslate (self-hosted or libretranslate.com)
+)
+
+// TranslationService translates prospect messages to the seller's working language and replies back.
+// A conversation's language column marks translation as active: it is set by a translate node
+// (or ai_prompt with auto_translate) and every outbound text is then translated into it.
+type TranslationService struct {
+	provider   string
+	apiURL     string
+	apiKey     string
+	httpClient *http.Client
+	deviceRepo *repository.DeviceRepository
+
+	mu               sync.Mutex
+	workingLanguages map[string]cachedLanguage
+}
+
+// cachedLanguage is a device working language with its lookup time
+type cachedLanguage struct {
+	lang string
+	at   time.Time
+}
+
+// workingLanguageTTL bounds how long a device's working language change takes to apply
+const workingLanguageTTL = 5 * time.Minute
+
+// NewTranslationService creates a translation service; an empty provider disables translation
+func NewTranslationService(provider, apiURL, apiKey string, deviceRepo *repository.DeviceRepository) *TranslationService {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if apiURL == "" {
+		switch provider {
+		case TranslateProviderGoogle:
+			apiURL = "https://translation.googleapis.com/language/translate/v2"
+		case TranslateProviderLibre:
+			apiURL = "https://libretranslate.com/translate"
+		}
+	}
+
+	return &TranslationService{
+		provider:         provider,
+		apiURL:           apiURL,
+		apiKey:           apiKey,
+		httpClient:       &http.Client{Timeout: 15 * time.Second},
+		deviceRepo:       deviceRepo,
+		workingLanguages: make(map[string]cachedLanguage),
+	}
+}
+
+// Enabled reports whether a translation provider is configured
+func (t *TranslationService) Enabled() bool {
+	return t != nil && t.apiURL != "" && (t.provider == TranslateProviderGoogle || t.provider == TranslateProviderLibre)
+}
+
+// Translate translates text into target. source may be empty to auto-detect.
+// Returns the translation and the source language the provider detected (or was given).
+func (t *TranslationService) Translate(ctx context.Context, text, source, target string) (string, string, error) {
+	if !t.Enabled() {
+		return "", "", fmt.Errorf("translation is not configured")
+	}
+
+	switch t.provider {
+	case TranslateProviderGoogle:
+		return t.translateGoogle(ctx, text, source, target)
+	default:
+		return t.translateLibre(ctx, text, source, target)
+	}
+}
+
+func (t *TranslationService) translateGoogle(ctx context.Context, text, source, target string) (string, string, error) {
+	payload := map[string]interface{}{
+		"q":      text,
+		"target": target,
+		"format": "text",
+	}
+	if source != "" {
+		payload["source"] = source
+	}
+
+	endpoint := t.apiURL
+	if t.apiKey != "" {
+		endpoint += "?key=" + url.QueryEscape(t.apiKey)
+	}
+
+	var result struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText         string `json:"translatedText"`
+				DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	if err := t.post(ctx, endpoint, payload, &result); err != nil {
+		return "", "", err
+	}
+	if len(result.Data.Translations) == 0 {
+		return "", "", fmt.Errorf("empty translation response")
+	}
+
+	translation := result.Data.Translations[0]
+	detected := translation.DetectedSourceLanguage
+	if detected == "" {
+		detected = source
+	}
+	return translation.TranslatedText, detected, nil
+}
+
+func (t *TranslationService) translateLibre(ctx context.Context, text, source, target string) (string, string, error) {
+	if source == "" {
+		source = "auto"
+	}
+	payload := map[string]interface{}{
+		"q":      text,
+		"source": source,
+		"target": target,
+		"format": "text",
+	}
+	if t.apiKey != "" {
+		payload["api_key"] = t.apiKey
+	}
+
+	var result struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage *struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+	}
+	if err := t.post(ctx, t.apiURL, payload, &result); err != nil {
+		return "", "", err
+	}
+
+	detected := source
+	if result.DetectedLanguage != nil && result.DetectedLanguage.Language != "" {
+		detected = result.DetectedLanguage.Language
+	}
+	return result.TranslatedText, detected, nil
+}
+
+func (t *TranslationService) post(ctx context.Context, endpoint string, payload interface{}, out interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create translation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("translation API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read translation response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("translation API returned HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse translation response: %w", err)
+	}
+	return nil
+}
+
+// WorkingLanguage returns the seller's working language for a device (cached briefly per device)
+func (t *TranslationService) WorkingLanguage(ctx context.Context, idDevice string) string {
+	t.mu.Lock()
+	cached, ok := t.workingLanguages[idDevice]
+	t.mu.Unlock()
+	if ok && time.Since(cached.at) < workingLanguageTTL {
+		return cached.lang
+	}
+
+	lang := models.DefaultWorkingLanguage
+	if t.deviceRepo != nil {
+		if device, err := t.deviceRepo.GetDeviceByIDDevice(ctx, idDevice); err == nil && device != nil {
+			lang = device.EffectiveWorkingLanguage()
+		}
+	}
+
+	t.mu.Lock()
+	t.workingLanguages[idDevice] = cachedLanguage{lang: lang, at: time.Now()}
+	t.mu.Unlock()
+	return lang
+}
+
+// TranslateInbound translates a prospect message into the working language (or target when given)
+// and records the detected language on the conversation so replies are translated back.
+// On any failure the original message is returned unchanged.
+func (t *TranslationService) TranslateInbound(ctx context.Context, store ConversationStateStore, conversationID, idDevice, message, target string) string {
+	if !t.Enabled() || strings.TrimSpace(message) == "" {
+		return message
+	}
+
+	if target == "" {
+		target = t.WorkingLanguage(ctx, idDevice)
+	}
+
+	translated, detected, err := t.Translate(ctx, message, "", target)
+	if err != nil {
+		log.Printf("⚠️  Inbound translation failed, using original message: %v", err)
+		return message
+	}
+
+	if detected != "" {
+		if err := store.UpdateConversation(ctx, conversationID, map[string]interface{}{"language": detected}); err != nil {
+			log.Printf("⚠️  Failed to save prospect language: %v", err)
+		}
+	}
+
+	if sameLanguage(detected, target) {
+		return message
+	}
+
+	log.Printf("🌐 Translated inbound message %s -> %s", detected, target)
+	return translated
+}
+
+// ForProspect translates an outbound text into the prospect's language.
+// Returns text unchanged when translation is off for the conversation or fails.
+func (t *TranslationService) ForProspect(ctx context.Context, idDevice, prospectLanguage, text string) string {
+	if !t.Enabled() || prospectLanguage == "" || strings.TrimSpace(text) == "" {
+		return text
+	}
+
+	working := t.WorkingLanguage(ctx, idDevice)
+	if sameLanguage(prospectLanguage, working) {
+		return text
+	}
+
+	translated, _, err := t.Translate(ctx, text, working, prospectLanguage)
+	if err != nil || translated == "" {
+		log.Printf("⚠️  Outbound translation failed, sending original text: %v", err)
+		return text
+	}
+	return translated
+}
+
+// translateTargetLanguage returns a translate node's explicit target_language, empty for the device working language
+func translateTargetLanguage(node *FlowNode) string {
+	target, _ := node.Config["target_language"].(string)
+	return strings.TrimSpace(target)
+}
+
+// sameLanguage compares language codes ignoring region ("en-US" == "en")
+func sameLanguage(a, b string) bool {
+	base := func(code string) string {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if i := strings.IndexAny(code, "-_"); i > 0 {
+			code = code[:i]
+		}
+		return code
+	}
+	return base(a) == base(b)
+}
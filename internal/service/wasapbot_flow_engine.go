@@ -2,12 +2,8 @@ package service
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"math/rand"
 	"strings"
-	"time"
+	"sync"
 
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
@@ -15,24 +11,22 @@ import (
 
 // WasapbotFlowEngine handles the execution of flow nodes for WhatsApp Bot
 type WasapbotFlowEngine struct {
-	deviceRepo       *repository.DeviceRepository
-	convRepo         *repository.WasapbotRepository
-	stageRepo        *repository.StageRepository
-	whatsappService  *WhatsAppService
+	flowServices
+	store         wasapbotConversationStore // wasapbot rows, the repository unless simulating
+	sender        FlowMessageSender         // WhatsAppService unless simulating
+	stateMachine  *ConversationStateMachine
+	historyMu     sync.Mutex // guards historyLimits; fork branches run concurrently
+	historyLimits map[string]int
+	sim           *flowSimulation // set on dry runs only
 }
 
-// NewWasapbotFlowEngine creates a new WhatsApp Bot flow engine
-func NewWasapbotFlowEngine(
-	deviceRepo *repository.DeviceRepository,
-	convRepo *repository.WasapbotRepository,
-	stageRepo *repository.StageRepository,
-	whatsappService *WhatsAppService,
-) *WasapbotFlowEngine {
+// NewWasapbotFlowEngine creates a new WhatsApp Bot flow engine running nodes with services
+func NewWasapbotFlowEngine(services flowServices, convRepo *repository.WasapbotRepository, whatsappService *WhatsAppService) *WasapbotFlowEngine {
 	return &WasapbotFlowEngine{
-		deviceRepo:      deviceRepo,
-		convRepo:        convRepo,
-		stageRepo:       stageRepo,
-		whatsappService: whatsappService,
+		flowServices: services,
+		store:        convRepo,
+		sender:       whatsappService,
+		stateMachine: NewConversationStateMachine(convRepo),
 	}
 }
 
@@ -44,35 +38,7 @@ func (s *WasapbotFlowEngine) ExecuteWasapbotFlow(
 	userMessage string,
 	currentStage string,
 ) error {
-	log.Printf("🚀 Starting flow execution for conversation: %s", conversationID)
-
-	// Check if NodesData is empty
-	if flow.NodesData == "" {
-		log.Printf("⚠️  Flow NodesData is empty - flow not configured yet")
-		return fmt.Errorf("flow has no nodes configured")
-	}
-
-	// Parse flow data
-	var flowData FlowData
-	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
-		log.Printf("❌ Failed to parse flow data: %v", err)
-		log.Printf("📝 NodesData content: %s", flow.NodesData)
-		return fmt.Errorf("failed to parse flow data: %w", err)
-	}
-
-	log.Printf("📊 Flow has %d nodes and %d connections", len(flowData.Nodes), len(flowData.Connections))
-
-	// Find starting node
-	startNode := s.findStartingNode(flowData, currentStage)
-	if startNode == nil {
-		log.Printf("⚠️  No starting node found for stage: %s", currentStage)
-		return fmt.Errorf("no starting node found")
-	}
-
-	log.Printf("🎯 Starting from node: %s (Type: %s)", startNode.ID, startNode.Type)
-
-	// Execute flow from starting node
-	return s.executeFromNode(ctx, flow, &flowData, startNode, conversationID, userMessage, currentStage)
+	return s.runtime().execute(ctx, flow, conversationID, userMessage, currentStage)
 }
 
 // ResumeWasapbotFlow resumes flow execution from a specific node (used after waiting_reply)
@@ -83,511 +49,28 @@ func (s *WasapbotFlowEngine) ResumeWasapbotFlow(
 	userMessage string,
 	currentNodeID string,
 ) error {
-	log.Printf("▶️  Resuming flow execution from node: %s", currentNodeID)
-
-	// Check if NodesData is empty
-	if flow.NodesData == "" {
-		log.Printf("⚠️  Flow NodesData is empty - flow not configured yet")
-		return fmt.Errorf("flow has no nodes configured")
-	}
-
-	// Parse flow data
-	var flowData FlowData
-	if err := json.Unmarshal([]byte(flow.NodesData), &flowData); err != nil {
-		log.Printf("❌ Failed to parse flow data: %v", err)
-		return fmt.Errorf("failed to parse flow data: %w", err)
-	}
-
-	// Find the current node
-	var currentNode *FlowNode
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == currentNodeID {
-			currentNode = &flowData.Nodes[i]
-			break
-		}
-	}
-
-	if currentNode == nil {
-		log.Printf("❌ Current node %s not found in flow", currentNodeID)
-		return fmt.Errorf("current node not found: %s", currentNodeID)
-	}
-
-	log.Printf("✅ Found current node: %s (Type: %s)", currentNode.ID, currentNode.Type)
-
-	// Add user's reply to conversation history
-	if userMessage != "" {
-		err := s.updateConvLast(ctx, conversationID, "User", userMessage)
-		if err != nil {
-			log.Printf("⚠️  Failed to update conv_last with user message: %v", err)
-			// Don't fail the flow, just log the error
-		} else {
-			log.Printf("✅ Added user message to conv_last: %s", userMessage)
-		}
-	}
-
-	// Find next node from current node
-	nextNode := s.findNextNode(&flowData, currentNode, userMessage)
-	if nextNode == nil {
-		log.Printf("✅ No next node - flow completed")
-
-		// Mark as completed
-		updates := map[string]interface{}{
-			"execution_status": "completed",
-			"current_node_id":  "completed",
-		}
-		return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	}
-
-	// Execute from next node
-	return s.executeFromNode(ctx, flow, &flowData, nextNode, conversationID, userMessage, "")
-}
-
-// findStartingNode finds the node to start execution from
-func (s *WasapbotFlowEngine) findStartingNode(flowData FlowData, currentStage string) *FlowNode {
-	// If no current stage, find the first node (after start node if exists)
-	if currentStage == "" || currentStage == "start" {
-		// Look for a node that has no incoming connections (or is connected from start)
-		for i := range flowData.Nodes {
-			node := &flowData.Nodes[i]
-			// Skip if this is a start-type node
-			if strings.Contains(strings.ToLower(node.Type), "start") {
-				continue
-			}
-
-			// Check if this node has incoming connections
-			hasIncoming := false
-			for _, edge := range flowData.Connections {
-				if edge.To == node.ID {
-					hasIncoming = true
-					break
-				}
-			}
-
-			// If no incoming connections, this could be the first node
-			if !hasIncoming {
-				return node
-			}
-		}
-
-		// If all nodes have incoming connections, get the first node
-		if len(flowData.Nodes) > 0 {
-			return &flowData.Nodes[0]
-		}
-	}
-
-	// Otherwise, try to find node by ID matching the stage
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == currentStage {
-			return &flowData.Nodes[i]
-		}
-	}
-
-	// Default to first node
-	if len(flowData.Nodes) > 0 {
-		return &flowData.Nodes[0]
-	}
-
-	return nil
-}
-
-// executeFromNode executes the flow starting from a specific node
-func (s *WasapbotFlowEngine) executeFromNode(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	flowData *FlowData,
-	node *FlowNode,
-	conversationID string,
-	userMessage string,
-	currentStage string,
-) error {
-	log.Printf("🔄 Executing node: %s (Type: %s)", node.ID, node.Type)
-
-	// Execute the current node
-	continueFlow, err := s.executeNode(ctx, flow, node, conversationID, userMessage)
-	if err != nil {
-		return fmt.Errorf("failed to execute node %s: %w", node.ID, err)
-	}
-
-	// If node says to stop flow (e.g., waiting_reply), stop here
-	if !continueFlow {
-		log.Printf("⏸️  Flow paused at node: %s", node.ID)
-		// Update current node in conversation
-		return s.updateConversationNode(ctx, conversationID, node.ID)
-	}
-
-	// Find next node
-	nextNode := s.findNextNode(flowData, node, userMessage)
-	if nextNode == nil {
-		log.Printf("✅ Flow completed - no more nodes")
-
-		// Mark flow as completed
-		updates := map[string]interface{}{
-			"execution_status":  "completed",
-			"current_node_id":   "completed",
-			"waiting_for_reply": false,
-		}
-
-		err := s.convRepo.UpdateConversation(ctx, conversationID, updates)
-		if err != nil {
-			log.Printf("❌ Failed to mark flow as completed: %v", err)
-			return fmt.Errorf("failed to mark flow as completed: %w", err)
-		}
-
-		log.Printf("✅ Flow marked as 'completed'")
-		return nil
-	}
-
-	// Continue to next node
-	return s.executeFromNode(ctx, flow, flowData, nextNode, conversationID, userMessage, currentStage)
-}
-
-// executeNode executes a single node's action
-func (s *WasapbotFlowEngine) executeNode(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-	userMessage string,
-) (bool, error) {
-	log.Printf("⚙️  Executing node type: %s", node.Type)
-
-	switch node.Type {
-	case "send_message":
-		return s.executeSendMessage(ctx, flow, node, conversationID)
-
-	case "delay":
-		return s.executeDelay(ctx, node)
-
-	case "waiting_reply":
-		return s.executeWaitingReply(ctx, conversationID, node)
-
-	case "waiting_times":
-		return s.executeWaitingTimes(ctx, conversationID, node)
-
-	case "stage":
-		return s.executeStage(ctx, conversationID, node)
-
-	case "send_image", "send_audio", "send_video":
-		return s.executeSendMedia(ctx, flow, node, conversationID)
-
-	case "conditions":
-		return s.executeConditions(ctx, node, userMessage)
-
-	default:
-		log.Printf("⚠️  Unknown node type: %s, skipping", node.Type)
-		return true, nil
-	}
-}
-
-// executeSendMessage sends a WhatsApp message
-func (s *WasapbotFlowEngine) executeSendMessage(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-) (bool, error) {
-	// Get message text from config
-	text, ok := node.Config["text"].(string)
-	if !ok || text == "" {
-		log.Printf("⚠️  No text configured for send_message node")
-		return true, nil
-	}
-
-	// Get conversation to get phone number and customer data
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil || conversation == nil {
-		log.Printf("❌ Failed to get conversation for sending: %v", err)
-		return true, fmt.Errorf("failed to get conversation: %w", err)
-	}
-
-	// Check if text matches hardcoded templates and populate customer data
-	text = s.populateCustomerTemplate(text, conversation)
-
-	log.Printf("📤 Sending message: %s", text)
-
-	// Send WhatsApp message
-	err = s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, text, "", "")
-	if err != nil {
-		log.Printf("❌ Failed to send WhatsApp message: %v", err)
-		return true, fmt.Errorf("failed to send message: %w", err)
-	}
-
-	log.Printf("✅ Message sent successfully to %s", conversation.ProspectNum)
-
-	// Update conv_last with bot reply
-	return true, s.updateConvLast(ctx, conversationID, "Bot", text)
-}
-
-// populateCustomerTemplate checks for hardcoded templates and populates with customer data
-func (s *WasapbotFlowEngine) populateCustomerTemplate(text string, conversation *models.Wasapbot) string {
-	// Helper function to safely get string value or return empty string
-	safeString := func(s *string) string {
-		if s != nil {
-			return *s
-		}
-		return ""
-	}
-
-	// Check for DETAIL CUSTOMER template
-	if text == "DETAIL CUSTOMER" {
-		return fmt.Sprintf(`Detail:
-
-NAMA : %s
-
-ALAMAT : %s
-
-NO FON : %s`,
-			safeString(conversation.ProspectName),
-			safeString(conversation.Alamat),
-			safeString(conversation.NoFon))
-	}
-
-	// Check for DETAIL COD template
-	if text == "DETAIL COD" {
-		return fmt.Sprintf(`Detail:
-
-NAMA : %s
-
-ALAMAT : %s
-
-NO FONE : %s
-
-PAKEJ : %s
-
-*COD @ POSTAGE FREE*
-
-CARA BAYARAN : COD`,
-			safeString(conversation.ProspectName),
-			safeString(conversation.Alamat),
-			safeString(conversation.NoFon),
-			safeString(conversation.Pakej))
-	}
-
-	// Check for DETAIL WAGES template
-	if text == "DETAIL WAGES" {
-		return fmt.Sprintf(`Detail:
-
-NAMA : %s
-
-ALAMAT : %s
-
-NO FONE : %s
-
-PAKEJ : %s
-
-*COD @ POSTAGE FREE*
-
-CARA BAYARAN : %s
-
-TARIKH GAJI : %s`,
-			safeString(conversation.ProspectName),
-			safeString(conversation.Alamat),
-			safeString(conversation.NoFon),
-			safeString(conversation.Pakej),
-			safeString(conversation.CaraBayaran),
-			safeString(conversation.TarikhGaji))
-	}
-
-	// Check for DETAIL CASH template
-	if text == "DETAIL CASH" {
-		return fmt.Sprintf(`Detail:
-
-NAMA : %s
-
-ALAMAT : %s
-
-NO FONE : %s
-
-PAKEJ : %s
-
-*COD @ POSTAGE FREE*
-
-CARA BAYARAN : Online Transfer`,
-			safeString(conversation.ProspectName),
-			safeString(conversation.Alamat),
-			safeString(conversation.NoFon),
-			safeString(conversation.Pakej))
-	}
-
-	// No template matched, return original text
-	return text
-}
-
-// executeDelay pauses execution for specified seconds
-func (s *WasapbotFlowEngine) executeDelay(ctx context.Context, node *FlowNode) (bool, error) {
-	// Get delay from config (should be 3 seconds by default)
-	delay := 3
-	if delayVal, ok := node.Config["delay"].(float64); ok {
-		delay = int(delayVal)
-	}
-
-	log.Printf("⏱️  Delaying for %d seconds", delay)
-	time.Sleep(time.Duration(delay) * time.Second)
-	log.Printf("✅ Delay completed")
-
-	return true, nil
-}
-
-// executeWaitingReply pauses flow until user replies (no timeout)
-func (s *WasapbotFlowEngine) executeWaitingReply(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	log.Printf("⏸️  Waiting for user reply (no timeout)")
-
-	// Update conversation to waiting state
-	updates := map[string]interface{}{
-		"waiting_for_reply": true,
-		"current_node_id":   node.ID,
-	}
-
-	err := s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	if err != nil {
-		return false, fmt.Errorf("failed to update waiting state: %w", err)
-	}
-
-	log.Printf("✅ Set waiting_for_reply=true, current_node_id=%s", node.ID)
-
-	// Flow will resume when next webhook message arrives
-	return false, nil // false = stop flow execution
-}
-
-// executeWaitingTimes pauses and waits for user reply with timeout
-func (s *WasapbotFlowEngine) executeWaitingTimes(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	// Get timeout from config (should be 8 seconds by default)
-	timeout := 8
-	if delayVal, ok := node.Config["delay"].(float64); ok {
-		timeout = int(delayVal)
-	}
-
-	log.Printf("⏳ Waiting for user reply with %d second timeout", timeout)
-
-	// TODO: Implement timeout logic
-	// For now, just continue after timeout
-	time.Sleep(time.Duration(timeout) * time.Second)
-
-	log.Printf("⏱️  Timeout reached, continuing flow")
-	return true, nil
-}
-
-// executeStage updates the conversation stage with dynamic configuration support
-func (s *WasapbotFlowEngine) executeStage(
-	ctx context.Context,
-	conversationID string,
-	node *FlowNode,
-) (bool, error) {
-	// Get stage name from config
-	stageName, ok := node.Config["value"].(string)
-	if !ok || stageName == "" {
-		log.Printf("⚠️  No stage value configured")
-		return true, nil
-	}
-
-	log.Printf("🎯 Processing stage: %s for conversation ID: %s", stageName, conversationID)
-
-	// First, get the conversation to retrieve device_id and prospect_num
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil {
-		log.Printf("❌ Failed to get conversation: %v", err)
-		return true, fmt.Errorf("failed to get conversation: %w", err)
-	}
-
-	deviceID := conversation.IDDevice
-
-	log.Printf("🔍 Checking stage configuration for device=%s, stage=%s", deviceID, stageName)
-
-	// Check if stage configuration exists for this device and stage
-	stageConfig, err := s.stageRepo.GetStageConfigByDeviceAndStage(ctx, deviceID, stageName)
-	if err != nil {
-		log.Printf("❌ Failed to query stage configuration: %v", err)
-		// Continue with normal stage update on query error
-	}
-
-	// Prepare updates map
-	updates := map[string]interface{}{
-		"stage": stageName,
-	}
-
-	// If no configuration found, just update stage normally
-	if stageConfig == nil {
-		log.Printf("📝 No stage configuration found, updating stage normally")
-
-		log.Printf("🔍 Calling UpdateConversation with updates: %+v", updates)
-		err = s.convRepo.UpdateConversation(ctx, conversationID, updates)
-		if err != nil {
-			log.Printf("❌ Failed to update stage: %v", err)
-			return true, fmt.Errorf("failed to update stage: %w", err)
-		}
-
-		log.Printf("✅ Stage updated successfully")
-		return true, nil
-	}
-
-	// Stage configuration found - apply dynamic updates
-	log.Printf("⚙️  Stage configuration found: type=%s, column=%s", stageConfig.TypeInputData, stageConfig.ColumnsData)
-
-	// Normalize column name to match database schema
-	columnName := normalizeColumnName(stageConfig.ColumnsData)
-	log.Printf("📝 Normalized column name: '%s' -> '%s'", stageConfig.ColumnsData, columnName)
-
-	var columnValue string
-
-	// Determine value based on type_inputdata
-	if stageConfig.TypeInputData == "Set" {
-		// Use hardcoded value from inputhardcode
-		columnValue = stageConfig.InputHardCode
-		log.Printf("📝 Type=Set: Using hardcoded value '%s' for column '%s'", columnValue, columnName)
-	} else if stageConfig.TypeInputData == "Input" {
-		// Use value from last user reply in conv_last
-		if conversation.ConvLast != nil {
-			convLast := *conversation.ConvLast
-			// Parse conv_last to extract last user message
-			// Format: "User: message\nBot: reply\nUser: message2..."
-			lines := strings.Split(convLast, "\n")
-			var lastUserMessage string
-			for i := len(lines) - 1; i >= 0; i-- {
-				line := strings.TrimSpace(lines[i])
-				if strings.HasPrefix(line, "User: ") {
-					lastUserMessage = strings.TrimPrefix(line, "User: ")
-					break
-				}
+	return s.runtime().resume(ctx, flow, conversationID, userMessage, currentNodeID)
+}
+
+// runtime runs the flow node processors against wasapbot contacts (Whatsapp Bot), with stage
+// column configurations and the device's conv_last cap
+func (s *WasapbotFlowEngine) runtime() *flowRuntime {
+	return &flowRuntime{
+		flowServices: s.flowServices,
+		table:        "wasapbot",
+		store:        s.store,
+		state:        s.stateMachine,
+		sender:       s.sender,
+		sim:          s.sim,
+		load: func(ctx context.Context, conversationID string) (*flowConversation, error) {
+			contact, err := s.store.GetConversationByID(ctx, conversationID)
+			if err != nil || contact == nil {
+				return nil, err
 			}
-
-			columnValue = lastUserMessage
-			log.Printf("📝 Type=Input: Using user reply '%s' for column '%s'", columnValue, columnName)
-		} else {
-			log.Printf("⚠️  Type=Input but conv_last is empty, using empty value")
-			columnValue = ""
-		}
-	} else {
-		log.Printf("⚠️  Unknown type_inputdata: %s, skipping column update", stageConfig.TypeInputData)
-		// Just update stage without column update
-		err = s.convRepo.UpdateConversation(ctx, conversationID, updates)
-		if err != nil {
-			log.Printf("❌ Failed to update stage: %v", err)
-			return true, fmt.Errorf("failed to update stage: %w", err)
-		}
-		log.Printf("✅ Stage updated successfully")
-		return true, nil
+			return wasapbotFlowConversation(contact), nil
+		},
+		historyLimit: s.maxHistoryEntries,
 	}
-
-	// Add column update to updates map
-	updates[columnName] = columnValue
-
-	log.Printf("🔍 Calling UpdateConversation with updates: %+v", updates)
-	err = s.convRepo.UpdateConversation(ctx, conversationID, updates)
-	if err != nil {
-		log.Printf("❌ Failed to update stage and column: %v", err)
-		return true, fmt.Errorf("failed to update stage and column: %w", err)
-	}
-
-	log.Printf("✅ Stage and column '%s' updated successfully", columnName)
-	return true, nil
 }
 
 // normalizeColumnName converts UI column names to database column names
@@ -596,12 +79,12 @@ func (s *WasapbotFlowEngine) executeStage(
 func normalizeColumnName(columnName string) string {
 	// Mapping from UI names to database column names
 	columnMap := map[string]string{
-		"Nama":          "prospect_name",
-		"Alamat":        "alamat",
-		"Pakej":         "pakej",
-		"No Fon":        "no_fon",
-		"Tarikh Gaji":   "tarikh_gaji",
-		"Cara Bayaran":  "cara_bayaran",
+		"Nama":              "prospect_name",
+		"Alamat":            "alamat",
+		"Pakej":             "pakej",
+		"No Fon":            "no_fon",
+		"Tarikh Gaji":       "tarikh_gaji",
+		"Cara Bayaran":      "cara_bayaran",
 		"Peringkat Sekolah": "peringkat_sekolah",
 	}
 
@@ -616,196 +99,25 @@ func normalizeColumnName(columnName string) string {
 	return normalized
 }
 
-// executeSendMedia sends media (image/audio/video)
-func (s *WasapbotFlowEngine) executeSendMedia(
-	ctx context.Context,
-	flow *models.ChatbotFlow,
-	node *FlowNode,
-	conversationID string,
-) (bool, error) {
-	// Get media URL from config
-	url, ok := node.Config["url"].(string)
-	if !ok || url == "" {
-		log.Printf("⚠️  No URL configured for media node")
-		return true, nil
-	}
-
-	log.Printf("📤 Sending %s: %s", node.Type, url)
-
-	// Get conversation to get phone number
-	conversation, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil || conversation == nil {
-		log.Printf("❌ Failed to get conversation for sending media: %v", err)
-		return true, fmt.Errorf("failed to get conversation: %w", err)
-	}
-
-	// Map node type to media type
-	mediaType := ""
-	switch node.Type {
-	case "send_image":
-		mediaType = "image"
-	case "send_audio":
-		mediaType = "audio"
-	case "send_video":
-		mediaType = "video"
-	}
-
-	// Send WhatsApp media
-	err = s.whatsappService.SendMessage(ctx, flow.IDDevice, conversation.ProspectNum, "", mediaType, url)
-	if err != nil {
-		log.Printf("❌ Failed to send WhatsApp media: %v", err)
-		return true, fmt.Errorf("failed to send media: %w", err)
-	}
-
-	log.Printf("✅ Media sent successfully to %s", conversation.ProspectNum)
-
-	// Update conv_last with bot media send (just the URL)
-	return true, s.updateConvLast(ctx, conversationID, "Bot", url)
-}
-
-// executeConditions evaluates conditions
-func (s *WasapbotFlowEngine) executeConditions(
-	ctx context.Context,
-	node *FlowNode,
-	userMessage string,
-) (bool, error) {
-	log.Printf("🔀 Evaluating conditions")
-
-	// Conditions are handled in findNextNode
-	// This node just passes through
-	return true, nil
-}
-
-// findNextNode finds the next node to execute based on edges
-func (s *WasapbotFlowEngine) findNextNode(
-	flowData *FlowData,
-	currentNode *FlowNode,
-	userMessage string,
-) *FlowNode {
-	// Find all outgoing edges from current node
-	var outgoingEdges []FlowEdge
-	for _, edge := range flowData.Connections {
-		if edge.From == currentNode.ID {
-			outgoingEdges = append(outgoingEdges, edge)
-		}
-	}
-
-	if len(outgoingEdges) == 0 {
-		log.Printf("ℹ️  No outgoing edges from node: %s", currentNode.ID)
-		return nil
-	}
-
-	// If only one edge, follow it
-	if len(outgoingEdges) == 1 {
-		return s.findNodeByID(flowData, outgoingEdges[0].To)
-	}
-
-	// Multiple edges - check if this is a Conditions node
-	if currentNode.Type == "conditions" {
-		log.Printf("🔀 Conditions node with %d edges", len(outgoingEdges))
-
-		// Match user message against conditions
-		for _, edge := range outgoingEdges {
-			if edge.ConditionType == "" || edge.ConditionValue == "" {
-				log.Printf("⚠️  Edge has no condition type/value, skipping")
-				continue
-			}
-
-			matched := false
-			switch strings.ToLower(edge.ConditionType) {
-			case "equal":
-				matched = strings.ToLower(userMessage) == strings.ToLower(edge.ConditionValue)
-			case "contains":
-				matched = strings.Contains(strings.ToLower(userMessage), strings.ToLower(edge.ConditionValue))
-			case "match":
-				matched = strings.Contains(strings.ToLower(userMessage), strings.ToLower(edge.ConditionValue))
-			case "default":
-				matched = true // Default always matches
-			}
-
-			if matched {
-				log.Printf("✅ Condition matched: %s '%s'", edge.ConditionType, edge.ConditionValue)
-				return s.findNodeByID(flowData, edge.To)
-			}
-		}
-
-		// No conditions matched, look for default
-		for _, edge := range outgoingEdges {
-			if strings.ToLower(edge.ConditionType) == "default" {
-				log.Printf("✅ Using default condition")
-				return s.findNodeByID(flowData, edge.To)
-			}
-		}
-
-		// No conditions matched and no default - randomly select one of the edges
-		if len(outgoingEdges) > 0 {
-			randomIndex := rand.Intn(len(outgoingEdges))
-			selectedEdge := outgoingEdges[randomIndex]
-			log.Printf("🎲 No conditions matched, randomly selected edge %d/%d (to: %s)", randomIndex+1, len(outgoingEdges), selectedEdge.To)
-			return s.findNodeByID(flowData, selectedEdge.To)
-		}
-
-		log.Printf("⚠️  No edges available, flow stops")
-		return nil
-	}
-
-	// Not a conditions node, but multiple edges - follow first one
-	log.Printf("⚠️  Multiple edges from non-condition node, following first one")
-	return s.findNodeByID(flowData, outgoingEdges[0].To)
-}
-
-// findNodeByID finds a node by its ID
-func (s *WasapbotFlowEngine) findNodeByID(flowData *FlowData, nodeID string) *FlowNode {
-	for i := range flowData.Nodes {
-		if flowData.Nodes[i].ID == nodeID {
-			return &flowData.Nodes[i]
-		}
-	}
-	return nil
-}
-
-// updateConversationNode updates the current node in conversation
-func (s *WasapbotFlowEngine) updateConversationNode(
-	ctx context.Context,
-	conversationID string,
-	nodeID string,
-) error {
-	updates := map[string]interface{}{
-		"current_node_id": nodeID,
-	}
-
-	return s.convRepo.UpdateConversation(ctx, conversationID, updates)
-}
-
-// updateConvLast updates the conversation history
-func (s *WasapbotFlowEngine) updateConvLast(
-	ctx context.Context,
-	conversationID string,
-	role string,
-	message string,
-) error {
-	// Get current conversation
-	conv, err := s.convRepo.GetConversationByID(ctx, conversationID)
-	if err != nil {
-		return err
+// maxHistoryEntries returns the conv_last cap for a device, cached for the life of the engine
+func (s *WasapbotFlowEngine) maxHistoryEntries(ctx context.Context, idDevice string) int {
+	s.historyMu.Lock()
+	limit, ok := s.historyLimits[idDevice]
+	s.historyMu.Unlock()
+	if ok {
+		return limit
 	}
 
-	// Append to conv_last
-	convLast := ""
-	if conv.ConvLast != nil {
-		convLast = *conv.ConvLast
+	limit = models.DefaultMaxHistoryEntries
+	if device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, idDevice); err == nil && device != nil {
+		limit = device.EffectiveMaxHistoryEntries()
 	}
 
-	newLine := fmt.Sprintf("%s: %s", role, message)
-	if convLast != "" {
-		convLast += "\n" + newLine
-	} else {
-		convLast = newLine
+	s.historyMu.Lock()
+	if s.historyLimits == nil {
+		s.historyLimits = make(map[string]int)
 	}
-
-	updates := map[string]interface{}{
-		"conv_last": convLast,
-	}
-
-	return s.convRepo.UpdateConversation(ctx, conversationID, updates)
+	s.historyLimits[idDevice] = limit
+	s.historyMu.Unlock()
+	return limit
 }
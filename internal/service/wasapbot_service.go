@@ -4,24 +4,33 @@ import (
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
 	"context"
+	"errors"
 	"fmt"
 )
 
 // WasapbotService handles WhatsApp Bot conversation business logic
 type WasapbotService struct {
-	wasapbotRepo *repository.WasapbotRepository
-	deviceRepo   *repository.DeviceRepository
+	wasapbotRepo  *repository.WasapbotRepository
+	deviceRepo    *repository.DeviceRepository
+	fieldRepo     *repository.CustomFieldRepository
+	executionLogs *repository.FlowExecutionLogRepository
+	messages      *MessageRecorder
+	team          *TeamService
 }
 
 // NewWasapbotService creates a new wasapbot service
-func NewWasapbotService(wasapbotRepo *repository.WasapbotRepository, deviceRepo *repository.DeviceRepository) *WasapbotService {
+func NewWasapbotService(wasapbotRepo *repository.WasapbotRepository, deviceRepo *repository.DeviceRepository, fieldRepo *repository.CustomFieldRepository, executionLogs *repository.FlowExecutionLogRepository, messages *MessageRecorder, team *TeamService) *WasapbotService {
 	return &WasapbotService{
-		wasapbotRepo: wasapbotRepo,
-		deviceRepo:   deviceRepo,
+		wasapbotRepo:  wasapbotRepo,
+		deviceRepo:    deviceRepo,
+		fieldRepo:     fieldRepo,
+		executionLogs: executionLogs,
+		messages:      messages,
+		team:          team,
 	}
 }
 
-// GetAllWasapbotForUser retrieves all WhatsApp Bot conversations for a user across all their devices
+// GetAllWasapbotForUser retrieves all WhatsApp Bot conversations for a user across their own and shared devices
 func (s *WasapbotService) GetAllWasapbotForUser(ctx context.Context, userID string) (*models.WasapbotResponse, error) {
 	// Get all devices for the user
 	devices, err := s.deviceRepo.GetDevicesByUserID(ctx, userID)
@@ -32,6 +41,16 @@ func (s *WasapbotService) GetAllWasapbotForUser(ctx context.Context, userID stri
 		}, nil
 	}
 
+	// Devices shared with the user through a team
+	shared, _, err := s.team.SharedDevices(ctx, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Failed to retrieve shared devices",
+		}, nil
+	}
+	devices = append(devices, shared...)
+
 	// Collect all conversations from all devices
 	var allConversations []models.Wasapbot
 
@@ -57,6 +76,8 @@ func (s *WasapbotService) GetAllWasapbotForUser(ctx context.Context, userID stri
 		allConversations = append(allConversations, conversations...)
 	}
 
+	sortWasapbotForInbox(allConversations)
+
 	return &models.WasapbotResponse{
 		Success:       true,
 		Message:       fmt.Sprintf("Found %d conversations", len(allConversations)),
@@ -64,6 +85,58 @@ func (s *WasapbotService) GetAllWasapbotForUser(ctx context.Context, userID stri
 	}, nil
 }
 
+// GetWasapbotByDevice retrieves a device's WhatsApp Bot conversations.
+// query may add captured fields (pakej, alamat, tarikh_gaji, ...) as columns and sort/filter on them
+// (nil = plain list unless the device has list_columns).
+func (s *WasapbotService) GetWasapbotByDevice(ctx context.Context, userID, deviceID string, limit int, query *models.ConversationListQuery) (*models.WasapbotResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, deviceID)
+	if err != nil || device == nil {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Device not found",
+		}, nil
+	}
+
+	if !s.team.Allows(ctx, userID, device, models.TeamPermissionViewConversations) {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	if query = effectiveListQuery(device, query); !query.IsEmpty() {
+		query = withCustomFields(ctx, s.fieldRepo, *device.UserID, query)
+		rows, err := s.wasapbotRepo.GetConversationListRows(ctx, deviceID, query, limit)
+		if errors.Is(err, repository.ErrInvalidListColumn) {
+			return &models.WasapbotResponse{
+				Success: false,
+				Message: err.Error(),
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.WasapbotResponse{
+			Success: true,
+			Message: fmt.Sprintf("Found %d conversations", len(rows)),
+			Columns: query.Columns,
+			Rows:    rows,
+		}, nil
+	}
+
+	conversations, err := s.wasapbotRepo.GetConversationsByDevice(ctx, deviceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	return &models.WasapbotResponse{
+		Success:       true,
+		Message:       fmt.Sprintf("Found %d conversations", len(conversations)),
+		Conversations: conversations,
+	}, nil
+}
+
 // GetAllWasapbot retrieves ALL WhatsApp Bot conversations (admin only)
 func (s *WasapbotService) GetAllWasapbot(ctx context.Context) (*models.WasapbotResponse, error) {
 	// Get all devices
@@ -100,9 +173,110 @@ func (s *WasapbotService) GetAllWasapbot(ctx context.Context) (*models.WasapbotR
 		allConversations = append(allConversations, conversations...)
 	}
 
+	sortWasapbotForInbox(allConversations)
+
 	return &models.WasapbotResponse{
 		Success:       true,
 		Message:       fmt.Sprintf("Found %d conversations (admin view)", len(allConversations)),
 		Conversations: allConversations,
 	}, nil
 }
+
+// GetPinnedWasapbot retrieves the user's pinned WhatsApp Bot conversations across their own and shared devices
+func (s *WasapbotService) GetPinnedWasapbot(ctx context.Context, userID string) (*models.WasapbotResponse, error) {
+	deviceIDs, err := s.team.accessibleDeviceIDs(ctx, s.deviceRepo, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Failed to retrieve user devices",
+		}, nil
+	}
+
+	conversations, err := s.wasapbotRepo.GetPinnedConversations(ctx, deviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned conversations: %w", err)
+	}
+
+	return &models.WasapbotResponse{
+		Success:       true,
+		Message:       fmt.Sprintf("Found %d pinned conversations", len(conversations)),
+		Conversations: conversations,
+	}, nil
+}
+
+// GetWasapbotByExternalRef retrieves a WhatsApp Bot conversation by its external reference across the user's own and shared devices
+func (s *WasapbotService) GetWasapbotByExternalRef(ctx context.Context, userID, externalRef string) (*models.WasapbotResponse, error) {
+	deviceIDs, err := s.team.accessibleDeviceIDs(ctx, s.deviceRepo, userID, models.TeamPermissionViewConversations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user devices: %w", err)
+	}
+
+	conversation, err := s.wasapbotRepo.GetConversationByExternalRef(ctx, externalRef, deviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Conversation not found",
+		}, nil
+	}
+
+	return &models.WasapbotResponse{
+		Success:      true,
+		Conversation: conversation,
+	}, nil
+}
+
+// PinWasapbot pins/unpins a WhatsApp Bot conversation or changes its priority
+func (s *WasapbotService) PinWasapbot(ctx context.Context, userID, prospectID string, req *models.PinConversationRequest) (*models.WasapbotResponse, error) {
+	conversation, err := s.wasapbotRepo.GetConversationByID(ctx, prospectID)
+	if err != nil {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Conversation not found",
+		}, nil
+	}
+
+	// Verify device ownership
+	device, err := s.deviceRepo.GetDeviceByIDDevice(ctx, conversation.IDDevice)
+	if err != nil || !s.team.Allows(ctx, userID, device, models.TeamPermissionReplyConversations) {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "Access denied",
+		}, nil
+	}
+
+	updates := make(map[string]interface{})
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if req.Priority != nil {
+		if !models.IsValidConversationPriority(*req.Priority) {
+			return &models.WasapbotResponse{
+				Success: false,
+				Message: "priority must be 0 (normal), 1 (high) or 2 (urgent)",
+			}, nil
+		}
+		updates["priority"] = *req.Priority
+	}
+
+	if len(updates) == 0 {
+		return &models.WasapbotResponse{
+			Success: false,
+			Message: "No fields to update",
+		}, nil
+	}
+
+	if err := s.wasapbotRepo.UpdateConversation(ctx, prospectID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	updated, _ := s.wasapbotRepo.GetConversationByID(ctx, prospectID)
+
+	return &models.WasapbotResponse{
+		Success:      true,
+		Message:      "Conversation updated successfully",
+		Conversation: updated,
+	}, nil
+}
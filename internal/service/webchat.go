@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot-automation/internal/models"
+	"chatbot-automation/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Web chat limits
+const (
+	maxWebChatWait       = 30 * time.Second
+	maxWebChatPending    = 100 // per session; oldest messages are dropped beyond this
+	webChatSessionIdle   = 24 * time.Hour
+	webChatPruneInterval = 10 * time.Minute
+)
+
+var webChatSessionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// webChatSession holds bot messages not yet picked up by the widget
+type webChatSession struct {
+	pending  []models.WebChatMessage
+	notify   chan struct{}
+	lastSeen time.Time
+}
+
+// WebChatHub is the outbound side of the web channel: flow replies to web prospects are queued
+// here instead of going to a WhatsApp provider, and the widget long-polls them
+type WebChatHub struct {
+	mu         sync.Mutex
+	sessions   map[string]*webChatSession
+	lastPruned time.Time
+}
+
+// NewWebChatHub creates an empty hub
+func NewWebChatHub() *WebChatHub {
+	return &WebChatHub{
+		sessions:   make(map[string]*webChatSession),
+		lastPruned: time.Now(),
+	}
+}
+
+func webChatKey(idDevice, prospectNum string) string {
+	return idDevice + "|" + prospectNum
+}
+
+// session returns the session for key, creating it; callers hold mu
+func (h *WebChatHub) session(key string) *webChatSession {
+	now := time.Now()
+	if now.Sub(h.lastPruned) > webChatPruneInterval {
+		for k, sess := range h.sessions {
+			if now.Sub(sess.lastSeen) > webChatSessionIdle {
+				delete(h.sessions, k)
+			}
+		}
+		h.lastPruned = now
+	}
+
+	sess, ok := h.sessions[key]
+	if !ok {
+		sess = &webChatSession{notify: make(chan struct{})}
+		h.sessions[key] = sess
+	}
+	sess.lastSeen = now
+	return sess
+}
+
+// Deliver queues a bot message for a web prospect and wakes any waiting poll
+func (h *WebChatHub) Deliver(idDevice, prospectNum string, msg models.WebChatMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sess := h.session(webChatKey(idDevice, prospectNum))
+	sess.pending = append(sess.pending, msg)
+	if len(sess.pending) > maxWebChatPending {
+		sess.pending = sess.pending[len(sess.pending)-maxWebChatPending:]
+	}
+	close(sess.notify)
+	sess.notify = make(chan struct{})
+}
+
+// Wait returns queued messages, waiting up to wait for the first one when none are pending
+func (h *WebChatHub) Wait(ctx context.Context, idDevice, prospectNum string, wait time.Duration) []models.WebChatMessage {
+	key := webChatKey(idDevice, prospectNum)
+
+	h.mu.Lock()
+	sess := h.session(key)
+	if len(sess.pending) == 0 && wait > 0 {
+		notify := sess.notify
+		h.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-notify:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+
+		h.mu.Lock()
+		sess = h.session(key)
+	}
+	messages := sess.pending
+	sess.pending = nil
+	h.mu.Unlock()
+
+	if messages == nil {
+		messages = []models.WebChatMessage{}
+	}
+	return messages
+}
+
+// WebChatService is the inbound side of the web channel: widget messages run through the
+// same flow processor as WhatsApp webhooks
+type WebChatService struct {
+	hub           *WebChatHub
+	flowProcessor *FlowProcessorService
+	deviceRepo    *repository.DeviceRepository
+}
+
+// NewWebChatService creates a new web chat service
+func NewWebChatService(hub *WebChatHub, flowProcessor *FlowProcessorService, deviceRepo *repository.DeviceRepository) *WebChatService {
+	return &WebChatService{
+		hub:           hub,
+		flowProcessor: flowProcessor,
+		deviceRepo:    deviceRepo,
+	}
+}
+
+// resolveDevice finds the device a widget is embedded for by webhook_id, then id_device
+func (s *WebChatService) resolveDevice(ctx context.Context, webhookID string) *models.DeviceSetting {
+	device, err := s.deviceRepo.GetDeviceByWebhookID(ctx, webhookID)
+	if err != nil || device == nil {
+		device, _ = s.deviceRepo.GetDeviceByIDDevice(ctx, webhookID)
+	}
+	return device
+}
+
+// ReceiveMessage accepts a visitor message and processes it in the background
+func (s *WebChatService) ReceiveMessage(ctx context.Context, webhookID string, req *models.WebChatMessageRequest) (*models.WebChatSendResponse, error) {
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		return &models.WebChatSendResponse{Success: false, Message: "message is required"}, nil
+	}
+
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	} else if !webChatSessionPattern.MatchString(sessionID) {
+		return &models.WebChatSendResponse{Success: false, Message: "invalid session_id"}, nil
+	}
+
+	if device := s.resolveDevice(ctx, webhookID); device == nil {
+		return &models.WebChatSendResponse{Success: false, Message: "Chat not found"}, nil
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Sis"
+	}
+
+	rawData := map[string]interface{}{
+		"channel":  models.ChannelWeb,
+		"from":     models.WebChatProspect(sessionID),
+		"message":  message,
+		"pushName": name,
+	}
+
+	// Async like provider webhooks; replies arrive through the hub
+	go func() {
+		runCtx, cancel := s.flowProcessor.RunContext()
+		defer cancel()
+		if err := s.flowProcessor.ProcessIncomingMessage(runCtx, webhookID, rawData); err != nil {
+			log.Printf("❌ Failed to process web chat message: %v", err)
+		}
+	}()
+
+	return &models.WebChatSendResponse{
+		Success:   true,
+		Message:   "Message received",
+		SessionID: sessionID,
+	}, nil
+}
+
+// PollMessages returns bot replies for a session, long-polling up to wait
+func (s *WebChatService) PollMessages(ctx context.Context, webhookID, sessionID string, wait time.Duration) (*models.WebChatPollResponse, error) {
+	if !webChatSessionPattern.MatchString(sessionID) {
+		return &models.WebChatPollResponse{Success: false, Message: "invalid session_id", Messages: []models.WebChatMessage{}}, nil
+	}
+
+	device := s.resolveDevice(ctx, webhookID)
+	if device == nil {
+		return &models.WebChatPollResponse{Success: false, Message: "Chat not found", Messages: []models.WebChatMessage{}}, nil
+	}
+
+	if wait > maxWebChatWait {
+		wait = maxWebChatWait
+	}
+
+	messages := s.hub.Wait(ctx, getStringValue(device.IDDevice), models.WebChatProspect(sessionID), wait)
+	return &models.WebChatPollResponse{
+		Success:   true,
+		SessionID: sessionID,
+		Messages:  messages,
+	}, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"chatbot-automation/internal/models"
 	"chatbot-automation/internal/repository"
+	"chatbot-automation/internal/whatsapp"
 )
 
 type WebhookService struct {
@@ -34,15 +35,26 @@ func NewWebhookService(deviceRepo *repository.DeviceRepository, flowRepo *reposi
 	}
 }
 
-// ExtractMessageData extracts and normalizes message data from webhook
-func (s *WebhookService) ExtractMessageData(ctx context.Context, rawData map[string]interface{}, deviceID string, provider string) (*models.ExtractedMessage, error) {
+// ExtractMessageData extracts and normalizes message data from webhook. WhatsApp senders are
+// normalized to E.164 digits, reading national numbers in phoneCountry (see models.NormalizePhone).
+func (s *WebhookService) ExtractMessageData(ctx context.Context, rawData map[string]interface{}, deviceID string, provider string, phoneCountry string) (*models.ExtractedMessage, error) {
 	log.Printf("🔍 EXTRACTING MESSAGE DATA - Provider: %s, DeviceID: %s", provider, deviceID)
 	log.Printf("🔍 RAW DATA KEYS: %+v", getMapKeys(rawData))
 
-	if provider == "whacenter" {
-		return s.extractWhacenterData(rawData, deviceID)
+	if provider == models.ChannelWeb {
+		return s.extractWebChatData(rawData, deviceID)
+	} else if provider == models.ChannelTelegram {
+		return s.extractTelegramData(rawData, deviceID)
+	} else if provider == models.ChannelMessenger || provider == models.ChannelInstagram {
+		return s.extractMessengerData(rawData, deviceID, provider)
+	} else if provider == "whacenter" {
+		return s.extractWhacenterData(rawData, deviceID, phoneCountry)
 	} else if provider == "waha" {
-		return s.extractWahaData(rawData, deviceID)
+		return s.extractWahaData(rawData, deviceID, phoneCountry)
+	} else if provider == "wablas" {
+		return s.extractWablasData(rawData, deviceID, phoneCountry)
+	} else if provider == "custom" {
+		return s.extractCustomData(rawData, deviceID, phoneCountry)
 	}
 	return nil, fmt.Errorf("unsupported provider: %s", provider)
 }
@@ -56,8 +68,27 @@ func getMapKeys(m map[string]interface{}) []string {
 	return keys
 }
 
+// inboundMessageText returns the text a message is handled as: the text itself, or for media the
+// caption, falling back to a placeholder such as [voice note] or, for a shared location,
+// [location: name]
+func inboundMessageText(message string, media *models.InboundMedia, location *models.Location) string {
+	if message == "" && location != nil {
+		return location.Placeholder()
+	}
+	if media == nil {
+		return message
+	}
+	if media.Caption == "" {
+		media.Caption = message
+	}
+	if message == "" {
+		return media.Placeholder()
+	}
+	return message
+}
+
 // extractWhacenterData extracts data from Whacenter webhook
-func (s *WebhookService) extractWhacenterData(data map[string]interface{}, deviceID string) (*models.ExtractedMessage, error) {
+func (s *WebhookService) extractWhacenterData(data map[string]interface{}, deviceID, phoneCountry string) (*models.ExtractedMessage, error) {
 	log.Printf("🔍 WHACENTER EXTRACTION - Full data: %+v", data)
 
 	// Check if group message (skip groups)
@@ -79,13 +110,16 @@ func (s *WebhookService) extractWhacenterData(data map[string]interface{}, devic
 		phoneNumber = phone
 	}
 
-	// Trim whitespace from message
-	message = strings.TrimSpace(message)
+	// Trim whitespace from message; media messages carry their caption
+	media := whatsapp.InboundMedia(data)
+	location := whatsapp.InboundLocation(data)
+	message = inboundMessageText(strings.TrimSpace(message), media, location)
 
 	// Validate phone number
-	if !s.isValidPhoneNumber(phoneNumber, "whacenter") {
-		log.Printf("❌ Invalid phone number: %s", phoneNumber)
-		return nil, fmt.Errorf("invalid phone number format")
+	phoneNumber, err := models.NormalizePhone(phoneNumber, phoneCountry)
+	if err != nil {
+		log.Printf("❌ Invalid phone number: %v", err)
+		return nil, fmt.Errorf("invalid phone number format: %w", err)
 	}
 
 	// Default name if not provided
@@ -99,14 +133,117 @@ func (s *WebhookService) extractWhacenterData(data map[string]interface{}, devic
 		Name:        pushName,
 		Provider:    "whacenter",
 		DeviceID:    deviceID,
+		Media:       media,
+		Location:    location,
+
+		QuotedMessageID: whatsapp.QuotedMessageID(data),
+		OptionID:        whatsapp.SelectedOptionID(data),
 	}
 
 	log.Printf("✅ WHACENTER EXTRACTED: %+v", extracted)
 	return extracted, nil
 }
 
+// extractWebChatData extracts a visitor message built by WebChatService; from is a web: prospect, not a phone
+func (s *WebhookService) extractWebChatData(data map[string]interface{}, deviceID string) (*models.ExtractedMessage, error) {
+	message, _ := data["message"].(string)
+	from, _ := data["from"].(string)
+	pushName, _ := data["pushName"].(string)
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+	if !models.IsWebChatProspect(from) {
+		return nil, fmt.Errorf("invalid web chat session")
+	}
+
+	return &models.ExtractedMessage{
+		PhoneNumber: from,
+		Message:     message,
+		Name:        pushName,
+		Provider:    models.ChannelWeb,
+		DeviceID:    deviceID,
+	}, nil
+}
+
+// extractTelegramData extracts a Telegram bot update passed on by TelegramService; the prospect is
+// tg:<chat id>, not a phone number
+func (s *WebhookService) extractTelegramData(update map[string]interface{}, deviceID string) (*models.ExtractedMessage, error) {
+	webhook, err := whatsapp.NewTelegramProvider(&whatsapp.ProviderConfig{}).ParseWebhook(update)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.From == "" {
+		return nil, fmt.Errorf("update has no chat")
+	}
+
+	message, callback := whatsapp.TelegramUpdateMessage(update)
+	media := whatsapp.TelegramInboundMedia(message)
+	text := inboundMessageText(strings.TrimSpace(webhook.Body), media, webhook.Location)
+	if text == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	// A callback's message is the bot's own; the prospect is who tapped the button
+	sender, _ := message["from"].(map[string]interface{})
+	if callback != nil {
+		sender, _ = callback["from"].(map[string]interface{})
+	}
+	name, _ := sender["first_name"].(string)
+	if name == "" {
+		name = "Sis"
+	}
+
+	return &models.ExtractedMessage{
+		PhoneNumber: models.TelegramProspect(webhook.From),
+		Message:     text,
+		Name:        name,
+		Provider:    models.ChannelTelegram,
+		DeviceID:    deviceID,
+		Media:       media,
+		Location:    webhook.Location,
+
+		QuotedMessageID: webhook.QuotedMessageID,
+		OptionID:        webhook.OptionID,
+	}, nil
+}
+
+// extractMessengerData extracts one messaging event passed on by MessengerService; the prospect
+// is fb:<PSID> or ig:<IGSID>, not a phone number
+func (s *WebhookService) extractMessengerData(data map[string]interface{}, deviceID, channel string) (*models.ExtractedMessage, error) {
+	event, ok := data["messaging"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("missing messaging event")
+	}
+
+	webhook := whatsapp.ParseMessengerEvent(event)
+	if webhook.From == "" {
+		return nil, fmt.Errorf("event has no sender")
+	}
+
+	media := whatsapp.MessengerInboundMedia(event)
+	message := inboundMessageText(strings.TrimSpace(webhook.Body), media, nil)
+	if message == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	// The Send API webhook carries no names; looking them up needs extra page permissions
+	return &models.ExtractedMessage{
+		PhoneNumber: models.MessengerProspect(channel, webhook.From),
+		Message:     message,
+		Name:        "Sis",
+		Provider:    channel,
+		DeviceID:    deviceID,
+		Media:       media,
+
+		QuotedMessageID: webhook.QuotedMessageID,
+		OptionID:        webhook.OptionID,
+	}, nil
+}
+
 // extractWahaData extracts data from Waha webhook
-func (s *WebhookService) extractWahaData(data map[string]interface{}, deviceID string) (*models.ExtractedMessage, error) {
+func (s *WebhookService) extractWahaData(data map[string]interface{}, deviceID, phoneCountry string) (*models.ExtractedMessage, error) {
 	log.Printf("🔍 WAHA EXTRACTION - Full data: %+v", data)
 
 	payload, ok := data["payload"].(map[string]interface{})
@@ -122,8 +259,10 @@ func (s *WebhookService) extractWahaData(data map[string]interface{}, deviceID s
 
 	log.Printf("🔍 WAHA FIELDS - message: %s, from: %s", message, fromRaw)
 
-	// Trim whitespace from message
-	message = strings.TrimSpace(message)
+	// Trim whitespace from message; media messages carry their caption
+	media := whatsapp.InboundMedia(payload)
+	location := whatsapp.InboundLocation(payload)
+	message = inboundMessageText(strings.TrimSpace(message), media, location)
 	if message == "" {
 		return nil, fmt.Errorf("empty message")
 	}
@@ -169,14 +308,10 @@ func (s *WebhookService) extractWahaData(data map[string]interface{}, deviceID s
 		}
 	}
 
-	// Validate phone number (must start with 601 for Malaysia)
-	if !strings.HasPrefix(phoneNumber, "601") {
-		return nil, fmt.Errorf("phone number must start with 601")
-	}
-
-	// Additional validation for phone number length
-	if !s.isValidPhoneNumber(phoneNumber, "waha") {
-		return nil, fmt.Errorf("invalid phone number format")
+	// Validate phone number by the device's country rules
+	phoneNumber, err := models.NormalizePhone(phoneNumber, phoneCountry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone number format: %w", err)
 	}
 
 	return &models.ExtractedMessage{
@@ -185,33 +320,176 @@ func (s *WebhookService) extractWahaData(data map[string]interface{}, deviceID s
 		Name:        name,
 		Provider:    "waha",
 		DeviceID:    deviceID,
+		Media:       media,
+		Location:    location,
+
+		QuotedMessageID: whatsapp.QuotedMessageID(payload),
+		OptionID:        whatsapp.SelectedOptionID(payload),
 	}, nil
 }
 
-// isValidPhoneNumber validates phone number format
-func (s *WebhookService) isValidPhoneNumber(phoneNumber string, provider string) bool {
-	if phoneNumber == "" {
-		return false
+// extractWablasData extracts data from Wablas webhook
+func (s *WebhookService) extractWablasData(data map[string]interface{}, deviceID, phoneCountry string) (*models.ExtractedMessage, error) {
+	// Check if group message (skip groups)
+	if isGroup, ok := data["isGroup"].(bool); ok && isGroup {
+		return nil, fmt.Errorf("group messages are not supported")
+	}
+
+	message, _ := data["message"].(string)
+	phoneNumber, _ := data["phone"].(string)
+	pushName, _ := data["pushName"].(string)
+	if pushName == "" {
+		pushName, _ = data["pushname"].(string)
+	}
+
+	// Trim whitespace from message; media messages carry their caption
+	media := whatsapp.InboundMedia(data)
+	location := whatsapp.InboundLocation(data)
+	message = inboundMessageText(strings.TrimSpace(message), media, location)
+	if message == "" {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	phoneNumber, err := models.NormalizePhone(phoneNumber, phoneCountry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone number format: %w", err)
+	}
+
+	if pushName == "" {
+		pushName = "Sis"
+	}
+
+	return &models.ExtractedMessage{
+		PhoneNumber: phoneNumber,
+		Message:     message,
+		Name:        pushName,
+		Provider:    "wablas",
+		DeviceID:    deviceID,
+		Media:       media,
+		Location:    location,
+
+		QuotedMessageID: whatsapp.QuotedMessageID(data),
+		OptionID:        whatsapp.SelectedOptionID(data),
+	}, nil
+}
+
+// extractCustomData extracts data from a custom gateway's webhook, reading the usual field names
+func (s *WebhookService) extractCustomData(payload map[string]interface{}, deviceID, phoneCountry string) (*models.ExtractedMessage, error) {
+	data := whatsapp.CustomWebhookMessage(payload)
+
+	webhook, err := whatsapp.NewCustomProvider(&whatsapp.ProviderConfig{}).ParseWebhook(payload)
+	if err != nil {
+		return nil, err
+	}
+	if isGroup, ok := data["isGroup"].(bool); ok && isGroup {
+		return nil, fmt.Errorf("group messages are not supported")
+	}
+
+	// Trim whitespace from message; media messages carry their caption
+	media := whatsapp.InboundMedia(data)
+	message := inboundMessageText(strings.TrimSpace(webhook.Body), media, webhook.Location)
+	if message == "" {
+		return nil, fmt.Errorf("empty message")
 	}
 
-	// Check length - must not exceed 13 characters or must start with 601
-	if len(phoneNumber) > 13 && !strings.HasPrefix(phoneNumber, "601") {
-		return false
+	// The sender may carry a chat suffix such as @c.us or @s.whatsapp.net, or a +
+	phoneNumber, err := models.NormalizePhone(webhook.From, phoneCountry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone number format: %w", err)
+	}
+
+	name, _ := data["pushName"].(string)
+	if name == "" {
+		name, _ = data["name"].(string)
 	}
+	if name == "" {
+		name = "Sis"
+	}
+
+	return &models.ExtractedMessage{
+		PhoneNumber: phoneNumber,
+		Message:     message,
+		Name:        name,
+		Provider:    "custom",
+		DeviceID:    deviceID,
+		Media:       media,
+		Location:    webhook.Location,
 
-	return true
+		QuotedMessageID: webhook.QuotedMessageID,
+		OptionID:        webhook.OptionID,
+	}, nil
 }
 
-// SendMessage sends a message via Whacenter or Waha
+// SendMessage sends a message via Whacenter, Waha, Wablas or a custom gateway
 func (s *WebhookService) SendMessage(ctx context.Context, device *models.DeviceSetting, req *WebhookMessageRequest) error {
 	if device.Provider == "whacenter" {
 		return s.sendWhacenterMessage(ctx, device, req)
 	} else if device.Provider == "waha" {
 		return s.sendWahaMessage(ctx, device, req)
+	} else if device.Provider == "wablas" || device.Provider == "custom" {
+		return s.sendProviderMessage(ctx, device, req)
 	}
 	return fmt.Errorf("unsupported provider: %s", device.Provider)
 }
 
+// sendProviderMessage sends message through the WhatsApp provider client of a Wablas or custom
+// gateway device, which reads its API URL and key from the device
+func (s *WebhookService) sendProviderMessage(ctx context.Context, device *models.DeviceSetting, req *WebhookMessageRequest) error {
+	baseURL := getStringValue(device.APIURL)
+	if baseURL == "" && device.Provider == "wablas" {
+		return fmt.Errorf("wablas device has no api_url")
+	}
+
+	provider, err := whatsapp.NewProvider(&whatsapp.ProviderConfig{
+		Provider:    device.Provider,
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		APIKey:      getStringValue(device.APIKey),
+		Instance:    getStringValue(device.IDDevice),
+		PhoneNumber: getStringValue(device.PhoneNumber),
+		Custom:      device.CustomProvider,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Clean phone number
+	phoneNumber := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, req.PhoneNumber)
+
+	message := &models.SendMessageRequest{
+		To:   phoneNumber,
+		Body: req.Message,
+		Type: "text",
+	}
+	if req.MediaURL != "" {
+		message.MediaURL = req.MediaURL
+		switch strings.ToLower(filepath.Ext(req.MediaURL)) {
+		case ".mp4":
+			message.Type = "video"
+		case ".mp3":
+			message.Type = "audio"
+		case ".pdf":
+			message.Type = "document"
+		default:
+			message.Type = "image"
+		}
+	}
+
+	resp, err := provider.SendMessage(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if resp != nil && !resp.Success {
+		return fmt.Errorf("%s API error: %s", device.Provider, resp.Error)
+	}
+
+	return nil
+}
+
 // sendWhacenterMessage sends message via Whacenter API
 func (s *WebhookService) sendWhacenterMessage(ctx context.Context, device *models.DeviceSetting, req *WebhookMessageRequest) error {
 	url := "https://api.whacenter.com/api/send"
@@ -5,25 +5,79 @@ import (
 	"chatbot-automation/internal/repository"
 	"chatbot-automation/internal/whatsapp"
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"time"
 )
 
 // WhatsAppService handles WhatsApp message sending
 type WhatsAppService struct {
-	deviceRepo *repository.DeviceRepository
-	providers  map[string]whatsapp.Provider
+	deviceRepo  *repository.DeviceRepository
+	latencyRepo *repository.ResponseLatencyRepository
+	costs       *CostRecorder
+	providers   map[string]whatsapp.Provider
+	deadlines   ExecutionDeadlines
+	webChat     *WebChatHub
+	sandboxRepo *repository.SandboxMessageRepository
+	sentRepo    *repository.SentMessageRepository
+
+	queuedSendRepo *repository.QueuedSendRepository // bot messages held for quiet hours; nil holds none
+
+	failoverNotices failoverNotices
+	sendQueues      sendQueues
+	health          providerHealth
 }
 
 // NewWhatsAppService creates a new WhatsApp service
-func NewWhatsAppService(deviceRepo *repository.DeviceRepository) *WhatsAppService {
+func NewWhatsAppService(deviceRepo *repository.DeviceRepository, latencyRepo *repository.ResponseLatencyRepository, costRepo *repository.CostLedgerRepository, deadlines ExecutionDeadlines, webChat *WebChatHub, sandboxRepo *repository.SandboxMessageRepository, sentRepo *repository.SentMessageRepository, queuedSendRepo *repository.QueuedSendRepository) *WhatsAppService {
 	return &WhatsAppService{
-		deviceRepo: deviceRepo,
-		providers:  make(map[string]whatsapp.Provider),
+		deviceRepo:  deviceRepo,
+		latencyRepo: latencyRepo,
+		costs:       NewCostRecorder(costRepo),
+		providers:   make(map[string]whatsapp.Provider),
+		deadlines:   deadlines,
+		webChat:     webChat,
+		sandboxRepo: sandboxRepo,
+		sentRepo:    sentRepo,
+
+		queuedSendRepo: queuedSendRepo,
 	}
 }
 
 // SendMessage sends a WhatsApp message using the appropriate provider
 func (s *WhatsAppService) SendMessage(ctx context.Context, deviceID string, to string, message string, mediaType string, mediaURL string, mimeType ...string) error {
+	// Build message request
+	req := &models.SendMessageRequest{
+		To:   to,
+		Body: message,
+		Type: "text",
+	}
+
+	// Set media type and URL if provided
+	if mediaType != "" && mediaURL != "" {
+		req.Type = mediaType
+		req.MediaURL = mediaURL
+		// Set MIME type if provided
+		if len(mimeType) > 0 && mimeType[0] != "" {
+			req.MimeType = mimeType[0]
+		}
+	}
+
+	return s.send(ctx, deviceID, req)
+}
+
+// SendRequest sends a buttons, list, location or contact message; web chat gets it as text
+func (s *WhatsAppService) SendRequest(ctx context.Context, deviceID string, to string, message *models.SendMessageRequest) error {
+	req := *message
+	req.To = to
+	return s.send(ctx, deviceID, &req)
+}
+
+// send delivers req from the device, through its backup while the primary is disconnected
+func (s *WhatsAppService) send(ctx context.Context, deviceID string, req *models.SendMessageRequest) error {
+	defer trackSend()()
+
 	// Get device
 	device, err := s.deviceRepo.GetDeviceByDeviceID(ctx, deviceID)
 	if err != nil {
@@ -37,6 +91,187 @@ func (s *WhatsAppService) SendMessage(ctx context.Context, deviceID string, to s
 		return fmt.Errorf("device not found")
 	}
 
+	// Latency is tracked against the primary device even when a backup sends
+	idDevice := getStringValue(device.IDDevice)
+	to := req.To
+
+	// Web chat visitors have no phone number; the widget picks replies up from the hub
+	if models.IsWebChatProspect(to) {
+		if req.IsStructured() {
+			return s.deliverWebChat(ctx, idDevice, to, req.PlainText(), "", "")
+		}
+		return s.deliverWebChat(ctx, idDevice, to, req.Body, req.Type, req.MediaURL)
+	}
+
+	// Bot messages made during the device's quiet hours wait for them to end
+	if held, err := s.holdForQuietHours(ctx, device, deviceID, req); held || err != nil {
+		return err
+	}
+
+	// Telegram chats are answered by the device's bot and Meta inboxes by its page, never through
+	// its WhatsApp backup
+	if models.IsTelegramProspect(to) {
+		if getStringValue(device.TelegramBotToken) == "" {
+			return fmt.Errorf("device has no Telegram bot connected")
+		}
+		return s.sendChannel(ctx, device, idDevice, telegramProvider(device), req)
+	}
+	if models.IsMessengerProspect(to) {
+		if device.Messenger.IsEmpty() {
+			return fmt.Errorf("device has no Facebook page connected")
+		}
+		return s.sendChannel(ctx, device, idDevice, messengerProvider(device), req)
+	}
+
+	// Providers get the number as E.164 digits whatever format the conversation stored it in;
+	// bookkeeping stays keyed by the stored prospect_num
+	phone, err := models.NormalizePhone(to, device.EffectivePhoneCountry())
+	if err != nil {
+		return fmt.Errorf("cannot send to %q: %w", to, err)
+	}
+	if phone != req.To {
+		normalized := *req
+		normalized.To = phone
+		req = &normalized
+	}
+
+	// Route through the backup device while the primary is disconnected.
+	// A sandboxed device never fails over, since its backup could deliver for real.
+	if !device.Sandbox {
+		device, deviceID, err = s.resolveFailover(ctx, device, deviceID, to)
+		if err != nil {
+			return err
+		}
+	}
+
+	whatsappProvider, err := s.providerForDevice(device, deviceID)
+	if err != nil {
+		return err
+	}
+
+	// Pace the device that actually sends, so flows blasting many messages do not get it banned.
+	// A sandboxed device reaches no one and is not paced.
+	if !device.Sandbox {
+		if err := s.sendQueues.wait(ctx, device, to); err != nil {
+			return err
+		}
+	}
+
+	s.typeMessage(ctx, device, whatsappProvider, req)
+
+	// Send message, bounded by the external call deadline
+	sendCtx, cancel := s.deadlines.externalCallContext(ctx)
+	started := time.Now()
+	resp, err := whatsappProvider.SendMessage(sendCtx, req)
+	cancel()
+	if !device.Sandbox {
+		s.health.record(device.ID, providerCallSend, time.Since(started), err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	// Keep the provider's message ID so quoted replies can be traced back to this send
+	if resp != nil {
+		s.recordSent(ctx, idDevice, to, req, resp.MessageID)
+	}
+
+	responder, agent := replyResponder(ctx)
+	s.RecordReply(ctx, idDevice, to, responder, agent)
+
+	// Fee of the device that actually sent, charged to the primary's conversation
+	if device.SendFee != nil && !device.Sandbox {
+		s.costs.RecordSendFee(ctx, idDevice, to, device.Provider, *device.SendFee)
+	}
+
+	return nil
+}
+
+// sendChannel sends req through the client of a non-WhatsApp channel: a Telegram bot or a page.
+// Sends are paced by the device's send queue like WhatsApp ones, so a campaign or a flow loop
+// cannot flood the bot or page past its rate either.
+func (s *WhatsAppService) sendChannel(ctx context.Context, device *models.DeviceSetting, idDevice string, provider whatsapp.Provider, req *models.SendMessageRequest) error {
+	if !device.Sandbox {
+		if err := s.sendQueues.wait(ctx, device, req.To); err != nil {
+			return err
+		}
+	}
+
+	s.typeMessage(ctx, device, provider, req)
+
+	if device.Sandbox {
+		provider = whatsapp.NewSandboxProvider(provider, func(ctx context.Context, provider string, message *models.SendMessageRequest, messageID string) {
+			s.recordSandboxSend(ctx, idDevice, provider, message, messageID)
+		})
+	}
+
+	sendCtx, cancel := s.deadlines.externalCallContext(ctx)
+	resp, err := provider.SendMessage(sendCtx, req)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to send %s message: %w", provider.GetProviderName(), err)
+	}
+
+	if resp != nil {
+		s.recordSent(ctx, idDevice, req.To, req, resp.MessageID)
+	}
+
+	responder, agent := replyResponder(ctx)
+	s.RecordReply(ctx, idDevice, req.To, responder, agent)
+	return nil
+}
+
+// deliverWebChat queues a reply for a web chat session instead of sending it through a provider
+func (s *WhatsAppService) deliverWebChat(ctx context.Context, idDevice, to, message, mediaType, mediaURL string) error {
+	if s.webChat == nil {
+		return fmt.Errorf("web chat channel is not enabled")
+	}
+
+	msg := models.WebChatMessage{
+		Type:    "text",
+		Content: message,
+		SentAt:  time.Now(),
+	}
+	if mediaType != "" && mediaURL != "" {
+		msg.Type = mediaType
+		msg.MediaURL = mediaURL
+	}
+	s.webChat.Deliver(idDevice, to, msg)
+
+	responder, agent := replyResponder(ctx)
+	s.RecordReply(ctx, idDevice, to, responder, agent)
+	return nil
+}
+
+type humanReplyKey struct{}
+
+// withHumanReply marks every send made with ctx as an agent's reply rather than the bot's
+func withHumanReply(ctx context.Context, agent string) context.Context {
+	return context.WithValue(ctx, humanReplyKey{}, agent)
+}
+
+// replyResponder returns who sends made with ctx reply as, and the agent for human replies
+func replyResponder(ctx context.Context) (string, string) {
+	if agent, ok := ctx.Value(humanReplyKey{}).(string); ok {
+		return models.ResponderHuman, agent
+	}
+	return models.ResponderBot, ""
+}
+
+// RecordReply closes the prospect's open response latency cycle.
+// Sends call it automatically, as the agent for contexts marked withHumanReply.
+func (s *WhatsAppService) RecordReply(ctx context.Context, idDevice, to, responder, agent string) {
+	if s.latencyRepo == nil || idDevice == "" {
+		return
+	}
+
+	if err := s.latencyRepo.CloseCycle(ctx, idDevice, to, responder, agent, time.Now()); err != nil {
+		log.Printf("⚠️  Failed to record reply latency: %v", err)
+	}
+}
+
+// providerForDevice returns the provider client configured for a device
+func (s *WhatsAppService) providerForDevice(device *models.DeviceSetting, deviceID string) (whatsapp.Provider, error) {
 	// Get provider configuration from device
 	provider := device.Provider
 	if provider == "" {
@@ -74,62 +309,74 @@ func (s *WhatsAppService) SendMessage(ctx context.Context, deviceID string, to s
 	}
 
 	// Get or create provider
-	whatsappProvider, err := s.getProvider(provider, baseURL, apiKey, instance)
+	whatsappProvider, err := s.getProvider(provider, baseURL, apiKey, instance, device.CustomProvider)
 	if err != nil {
-		return fmt.Errorf("failed to get provider: %w", err)
+		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
 
-	// Build message request
-	req := &models.SendMessageRequest{
-		To:   to,
-		Body: message,
-		Type: "text",
+	if device.Sandbox {
+		idDevice := getStringValue(device.IDDevice)
+		if idDevice == "" {
+			idDevice = device.ID
+		}
+		return whatsapp.NewSandboxProvider(whatsappProvider, func(ctx context.Context, provider string, message *models.SendMessageRequest, messageID string) {
+			s.recordSandboxSend(ctx, idDevice, provider, message, messageID)
+		}), nil
 	}
 
-	// Set media type and URL if provided
-	if mediaType != "" && mediaURL != "" {
-		req.Type = mediaType
-		req.MediaURL = mediaURL
-		// Set MIME type if provided
-		if len(mimeType) > 0 && mimeType[0] != "" {
-			req.MimeType = mimeType[0]
-		}
+	return whatsappProvider, nil
+}
+
+// recordSandboxSend stores the trace of a send intercepted by a sandboxed device
+func (s *WhatsAppService) recordSandboxSend(ctx context.Context, idDevice, provider string, message *models.SendMessageRequest, messageID string) {
+	if s.sandboxRepo == nil {
+		return
 	}
 
-	// Send message
-	_, err = whatsappProvider.SendMessage(ctx, req)
+	payload, err := json.Marshal(message)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		log.Printf("⚠️  Failed to encode sandbox payload: %v", err)
+		return
 	}
 
-	return nil
+	trace := &models.SandboxMessage{
+		IDDevice:    idDevice,
+		Provider:    provider,
+		MessageID:   messageID,
+		Recipient:   message.To,
+		MessageType: message.Type,
+		Body:        message.Body,
+		MediaURL:    message.MediaURL,
+		Payload:     payload,
+		Status:      models.SandboxDeliveredStatus,
+	}
+	if err := s.sandboxRepo.RecordMessage(ctx, trace); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
 }
 
 // getProvider gets or creates a WhatsApp provider instance
-func (s *WhatsAppService) getProvider(providerName string, baseURL string, apiKey string, instance string) (whatsapp.Provider, error) {
-	// Check cache
+func (s *WhatsAppService) getProvider(providerName string, baseURL string, apiKey string, instance string, custom *models.CustomProviderConfig) (whatsapp.Provider, error) {
+	// Check cache; a custom gateway is cached by its config, so edits take effect at once
 	cacheKey := fmt.Sprintf("%s:%s", providerName, instance)
+	if providerName == "custom" {
+		config, _ := json.Marshal(custom)
+		cacheKey = fmt.Sprintf("%s:%s:%s:%s:%s", providerName, instance, baseURL, apiKey, config)
+	}
 	if provider, ok := s.providers[cacheKey]; ok {
 		return provider, nil
 	}
 
 	// Create new provider
-	config := &whatsapp.ProviderConfig{
+	provider, err := whatsapp.NewProvider(&whatsapp.ProviderConfig{
+		Provider: providerName,
 		BaseURL:  baseURL,
 		APIKey:   apiKey,
 		Instance: instance,
-	}
-
-	var provider whatsapp.Provider
-	switch providerName {
-	case "waha":
-		provider = whatsapp.NewWahaProvider(config)
-	case "wablas":
-		provider = whatsapp.NewWablasProvider(config)
-	case "whacenter":
-		provider = whatsapp.NewWhacenterProvider(config)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", providerName)
+		Custom:   custom,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache provider
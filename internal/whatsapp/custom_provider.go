@@ -0,0 +1,305 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// customPlaceholder matches a {{name}} placeholder of a custom provider template
+var customPlaceholder = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// CustomProvider implements the Provider interface for any HTTP gateway described by a
+// models.CustomProviderConfig
+type CustomProvider struct {
+	config *ProviderConfig
+	client *http.Client
+}
+
+// NewCustomProvider creates a new custom HTTP provider instance
+func NewCustomProvider(config *ProviderConfig) *CustomProvider {
+	return &CustomProvider{
+		config: config,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// templateValues are the placeholder values of a send of message; nil message for status checks
+func (c *CustomProvider) templateValues(message *models.SendMessageRequest) map[string]string {
+	values := map[string]string{
+		"instance": c.config.Instance,
+		"api_key":  c.config.APIKey,
+		"base_url": strings.TrimSuffix(c.config.BaseURL, "/"),
+	}
+	if message != nil {
+		values["to"] = message.To
+		values["message"] = message.Body
+		values["type"] = message.Type
+		values["media_url"] = message.MediaURL
+		values["mime_type"] = message.MimeType
+		if values["type"] == "" {
+			values["type"] = "text"
+		}
+		// Buttons, lists, locations and contact cards go out as text
+		if message.IsStructured() {
+			values["message"] = message.PlainText()
+			values["type"] = "text"
+		}
+	}
+	return values
+}
+
+// FillCustomTemplate fills the placeholders of template, escaping each value with escape.
+// Unknown placeholders become empty. base_url is never escaped, since it starts URLs.
+func FillCustomTemplate(template string, values map[string]string, escape func(string) string) string {
+	return customPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := customPlaceholder.FindStringSubmatch(match)[1]
+		if name == "base_url" || escape == nil {
+			return values[name]
+		}
+		return escape(values[name])
+	})
+}
+
+// jsonEscape escapes s for use inside a JSON string literal
+func jsonEscape(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted[1 : len(quoted)-1])
+}
+
+// bodyEscape returns how values are escaped in a body of contentType
+func bodyEscape(contentType string) func(string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return jsonEscape
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return url.QueryEscape
+	}
+	return nil
+}
+
+// customConfig returns the device's gateway description, or an error when it has none
+func (c *CustomProvider) customConfig() (*models.CustomProviderConfig, error) {
+	if c.config.Custom == nil || c.config.Custom.SendURL == "" {
+		return nil, fmt.Errorf("custom provider has no send_url configured")
+	}
+	return c.config.Custom, nil
+}
+
+// SendMessage sends a WhatsApp message through the configured gateway
+func (c *CustomProvider) SendMessage(ctx context.Context, message *models.SendMessageRequest) (*models.SendMessageResponse, error) {
+	custom, err := c.customConfig()
+	if err != nil {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+
+	values := c.templateValues(message)
+	method := strings.ToUpper(custom.Method)
+	if method == "" {
+		method = "POST"
+	}
+	contentType := custom.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	bodyTemplate := custom.Body
+	if message.MediaURL != "" && values["type"] != "text" && custom.MediaBody != "" {
+		bodyTemplate = custom.MediaBody
+	}
+	if bodyTemplate == "" {
+		bodyTemplate = models.DefaultCustomProviderBody
+	}
+
+	var body io.Reader
+	if method != "GET" {
+		body = strings.NewReader(FillCustomTemplate(bodyTemplate, values, bodyEscape(contentType)))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, FillCustomTemplate(custom.SendURL, values, url.QueryEscape), body)
+	if err != nil {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to create request: %v", err),
+		}, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range custom.Headers {
+		req.Header.Set(name, FillCustomTemplate(value, values, nil))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to send request: %v", err),
+		}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to read response: %v", err),
+		}, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   fmt.Sprintf("API error: %s", string(respBody)),
+		}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	return &models.SendMessageResponse{
+		Success:   true,
+		Message:   "Message sent successfully",
+		MessageID: customResponseField(respBody, custom.MessageIDField),
+	}, nil
+}
+
+// customResponseField reads the value at a dotted path ("data.id", "messages.0.id") of a JSON
+// response as text; "" when the path is empty or missing
+func customResponseField(body []byte, path string) string {
+	if path == "" {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ""
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			var index int
+			if _, err := fmt.Sscanf(key, "%d", &index); err != nil || index < 0 || index >= len(node) {
+				return ""
+			}
+			value = node[index]
+		default:
+			return ""
+		}
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return fmt.Sprintf("%.0f", typed)
+	}
+	return ""
+}
+
+// GetSessionStatus checks the gateway's status_url; without one the device counts as connected
+func (c *CustomProvider) GetSessionStatus(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	status := "connected"
+	if c.config.Custom != nil && c.config.Custom.StatusURL != "" {
+		values := c.templateValues(nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", FillCustomTemplate(c.config.Custom.StatusURL, values, url.QueryEscape), nil)
+		if err != nil {
+			return &models.SessionStatusResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to create request: %v", err),
+			}, err
+		}
+		for name, value := range c.config.Custom.Headers {
+			req.Header.Set(name, FillCustomTemplate(value, values, nil))
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return &models.SessionStatusResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to send request: %v", err),
+			}, err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			status = "disconnected"
+		}
+	}
+
+	return &models.SessionStatusResponse{
+		Success: true,
+		Message: "Session status retrieved",
+		Session: &models.SessionInfo{
+			SessionID:   deviceID,
+			DeviceID:    deviceID,
+			PhoneNumber: c.config.PhoneNumber,
+			Status:      status,
+		},
+	}, nil
+}
+
+// StartSession initiates a new WhatsApp session
+func (c *CustomProvider) StartSession(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	// The gateway manages its own connection
+	return c.GetSessionStatus(ctx, deviceID)
+}
+
+// StopSession terminates a WhatsApp session
+func (c *CustomProvider) StopSession(ctx context.Context, deviceID string) error {
+	// The gateway manages its own connection
+	return nil
+}
+
+// ParseWebhook parses an incoming webhook payload, reading the usual field names of gateways
+func (c *CustomProvider) ParseWebhook(payload map[string]interface{}) (*models.WebhookPayload, error) {
+	data := CustomWebhookMessage(payload)
+	webhook := &models.WebhookPayload{
+		Event: "message",
+		From:  stringField(data, "from", "phone", "sender", "number", "chatId"),
+		Body:  stringField(data, "message", "body", "text"),
+		Type:  stringField(data, "type", "messageType"),
+		Raw:   payload,
+	}
+	if event := stringField(payload, "event"); event != "" {
+		webhook.Event = event
+	}
+	if timestamp, ok := data["timestamp"].(float64); ok {
+		webhook.Timestamp = int64(timestamp)
+	}
+
+	webhook.QuotedMessageID = QuotedMessageID(data)
+	webhook.OptionID = SelectedOptionID(data)
+	webhook.Location = InboundLocation(data)
+	if media := InboundMedia(data); media != nil {
+		webhook.MediaURL = media.URL
+	}
+
+	return webhook, nil
+}
+
+// CustomWebhookMessage returns the message object of a custom gateway's webhook: a payload or
+// data object when the body wraps it in one, else the body itself
+func CustomWebhookMessage(payload map[string]interface{}) map[string]interface{} {
+	for _, key := range []string{"payload", "data", "message"} {
+		if data, ok := payload[key].(map[string]interface{}); ok {
+			return data
+		}
+	}
+	return payload
+}
+
+// GetProviderName returns the provider name
+func (c *CustomProvider) GetProviderName() string {
+	return "custom"
+}
@@ -0,0 +1,87 @@
+package whatsapp
+
+import (
+	"strconv"
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// numberField returns the first of keys of data holding a number, or a string that parses as one
+func numberField(data map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch value := data[key].(type) {
+		case float64:
+			return value, true
+		case string:
+			if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return number, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// locationFrom reads a pin from an object with latitude/longitude (or lat/lng, degreesLatitude/
+// degreesLongitude) and optional name and address keys
+func locationFrom(data map[string]interface{}) *models.Location {
+	lat, okLat := numberField(data, "latitude", "lat", "degreesLatitude")
+	lng, okLng := numberField(data, "longitude", "lng", "long", "degreesLongitude")
+	if !okLat || !okLng {
+		return nil
+	}
+
+	location := &models.Location{
+		Latitude:  lat,
+		Longitude: lng,
+		Name:      stringField(data, "name", "title"),
+		Address:   stringField(data, "address", "description", "loc"),
+	}
+	if !location.Valid() {
+		return nil
+	}
+	return location
+}
+
+// InboundLocation returns the location pin of an inbound webhook message, or nil when it shares
+// none. data is the message object: WAHA's payload, or the top-level body for Wablas and Whacenter.
+func InboundLocation(data map[string]interface{}) *models.Location {
+	// WAHA: location {latitude, longitude, description}
+	if raw, ok := data["location"].(map[string]interface{}); ok {
+		if location := locationFrom(raw); location != nil {
+			return location
+		}
+	}
+
+	if raw, ok := data["_data"].(map[string]interface{}); ok {
+		// WAHA NOWEB/GOWS: the WhatsApp locationMessage
+		if message, ok := raw["Message"].(map[string]interface{}); ok {
+			if pin, ok := message["locationMessage"].(map[string]interface{}); ok {
+				if location := locationFrom(pin); location != nil {
+					return location
+				}
+			}
+		}
+		// WAHA WEBJS: lat, lng and loc on the raw message
+		if stringField(raw, "type") == "location" {
+			if location := locationFrom(raw); location != nil {
+				return location
+			}
+		}
+	}
+
+	// Wablas and Whacenter: a location type with the coordinates next to it, or "lat,lng" as text
+	if !strings.EqualFold(stringField(data, "messageType", "message_type", "type"), "location") {
+		return nil
+	}
+	if location := locationFrom(data); location != nil {
+		location.Name = "" // a top-level name is the sender's
+		return location
+	}
+	if text := stringField(data, "location", "message", "body"); text != "" {
+		if parts := strings.Split(text, ","); len(parts) == 2 {
+			return locationFrom(map[string]interface{}{"latitude": parts[0], "longitude": parts[1]})
+		}
+	}
+	return nil
+}
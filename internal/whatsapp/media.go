@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"strings"
+
+	"chatbot-automation/internal/models"
+)
+
+// stringField returns the first non-empty string among keys of data
+func stringField(data map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := data[key].(string); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// InboundMedia returns the attachment of an inbound webhook message, or nil when it is plain text.
+// data is the message object: WAHA's payload, or the top-level body for Wablas and Whacenter.
+func InboundMedia(data map[string]interface{}) *models.InboundMedia {
+	// WAHA: hasMedia with media {url, mimetype, filename}; the WhatsApp type (ptt, image, ...) is in _data
+	if media, ok := data["media"].(map[string]interface{}); ok {
+		hasMedia, _ := data["hasMedia"].(bool)
+		mediaURL := stringField(media, "url")
+		if hasMedia || mediaURL != "" {
+			providerType := stringField(data, "type")
+			if raw, ok := data["_data"].(map[string]interface{}); ok {
+				if providerType == "" {
+					providerType = stringField(raw, "type")
+				}
+				if info, ok := raw["Info"].(map[string]interface{}); ok && providerType == "" {
+					providerType = stringField(info, "MediaType")
+				}
+			}
+			mimeType := stringField(media, "mimetype", "mimeType")
+			mediaType := models.InboundMediaType(providerType, mimeType)
+			if mediaType == "" {
+				mediaType = models.MediaDocument
+			}
+			return &models.InboundMedia{
+				Type:     mediaType,
+				URL:      mediaURL,
+				MimeType: mimeType,
+				Filename: stringField(media, "filename"),
+				Caption:  stringField(data, "body", "caption"),
+			}
+		}
+	}
+
+	// Wablas and Whacenter: messageType/type next to a file URL
+	providerType := stringField(data, "messageType", "message_type", "type")
+	mimeType := stringField(data, "mimeType", "mimetype", "mime_type")
+	mediaURL := stringField(data, "file", "url", "media_url", "mediaUrl", "fileUrl", "file_url")
+
+	mediaType := models.InboundMediaType(providerType, mimeType)
+	if mediaType == "" && mediaURL == "" {
+		return nil
+	}
+	if mediaType == "" {
+		if providerType != "" {
+			return nil // text, location, contact, ... with a stray url field
+		}
+		mediaType = models.MediaDocument
+	}
+
+	return &models.InboundMedia{
+		Type:     mediaType,
+		URL:      mediaURL,
+		MimeType: mimeType,
+		Filename: stringField(data, "filename", "fileName", "file_name"),
+		Caption:  stringField(data, "caption"),
+	}
+}
@@ -0,0 +1,108 @@
+package whatsapp
+
+// messageIDValue reads a provider message ID that is either a plain string or, as WAHA
+// returns it, an object such as {"id": "3EB0...", "_serialized": "true_6012...@c.us_3EB0..."}
+func messageIDValue(value interface{}) string {
+	switch id := value.(type) {
+	case string:
+		return id
+	case map[string]interface{}:
+		if serialized, ok := id["_serialized"].(string); ok && serialized != "" {
+			return serialized
+		}
+		if inner, ok := id["id"].(string); ok {
+			return inner
+		}
+	}
+	return ""
+}
+
+// QuotedMessageID returns the ID of the message an inbound webhook quotes (the prospect used
+// WhatsApp's reply), or "" when it is not a reply. data is the message object: WAHA's payload,
+// or the top-level body for Wablas and Whacenter.
+func QuotedMessageID(data map[string]interface{}) string {
+	// WAHA: replyTo is {"id": "..."} on recent versions, a bare ID on older ones
+	if replyTo, ok := data["replyTo"]; ok {
+		if id := messageIDValue(replyTo); id != "" {
+			return id
+		}
+	}
+
+	// WAHA engines that only pass the raw WhatsApp message through
+	if raw, ok := data["_data"].(map[string]interface{}); ok {
+		if id, ok := raw["quotedStanzaID"].(string); ok && id != "" {
+			return id
+		}
+		if message, ok := raw["Message"].(map[string]interface{}); ok {
+			if extended, ok := message["extendedTextMessage"].(map[string]interface{}); ok {
+				if contextInfo, ok := extended["contextInfo"].(map[string]interface{}); ok {
+					if id, ok := contextInfo["stanzaID"].(string); ok && id != "" {
+						return id
+					}
+					if id, ok := contextInfo["stanzaId"].(string); ok && id != "" {
+						return id
+					}
+				}
+			}
+		}
+	}
+
+	// Wablas and Whacenter
+	for _, key := range []string{"quotedMessageId", "quoted_message_id", "quotedMsgId", "reply_to"} {
+		if id := messageIDValue(data[key]); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// SelectedOptionID returns the ID of the quick-reply button or list row an inbound webhook picks,
+// or "" when the message is not such a reply. Providers that only send the tapped title back
+// leave it "", and the title in the message body is matched instead.
+func SelectedOptionID(data map[string]interface{}) string {
+	// WAHA and providers that flatten the reply
+	for _, key := range []string{"selectedButtonId", "selectedRowId", "buttonId", "button_id", "rowId", "row_id", "list_reply_id"} {
+		if id, ok := data[key].(string); ok && id != "" {
+			return id
+		}
+	}
+
+	// WAHA engines that only pass the raw WhatsApp message through
+	raw, ok := data["_data"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"selectedButtonId", "selectedRowId"} {
+		if id, ok := raw[key].(string); ok && id != "" {
+			return id
+		}
+	}
+	message, ok := raw["Message"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if reply, ok := message["buttonsResponseMessage"].(map[string]interface{}); ok {
+		for _, key := range []string{"selectedButtonID", "selectedButtonId"} {
+			if id, ok := reply[key].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+	if reply, ok := message["templateButtonReplyMessage"].(map[string]interface{}); ok {
+		for _, key := range []string{"selectedID", "selectedId"} {
+			if id, ok := reply[key].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+	if reply, ok := message["listResponseMessage"].(map[string]interface{}); ok {
+		if single, ok := reply["singleSelectReply"].(map[string]interface{}); ok {
+			for _, key := range []string{"selectedRowID", "selectedRowId"} {
+				if id, ok := single[key].(string); ok && id != "" {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
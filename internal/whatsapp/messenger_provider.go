@@ -0,0 +1,333 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// defaultMessengerBaseURL is the Graph API version the Send API is called on
+const defaultMessengerBaseURL = "https://graph.facebook.com/v19.0"
+
+// maxMessengerQuickReplies is the most quick replies Messenger shows under a message
+const maxMessengerQuickReplies = 13
+
+// MessengerProvider implements the Provider interface for Facebook page inboxes and the Instagram
+// DMs of the page's business account, through the Messenger Send API. APIKey is the page access
+// token and recipients are page- or Instagram-scoped IDs, with or without the fb:/ig: prefix.
+type MessengerProvider struct {
+	config *ProviderConfig
+	client *http.Client
+}
+
+// NewMessengerProvider creates a new Messenger provider instance
+func NewMessengerProvider(config *ProviderConfig) *MessengerProvider {
+	return &MessengerProvider{
+		config: config,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// graph calls a Graph API path with the page token; a nil payload makes a GET
+func (m *MessengerProvider) graph(ctx context.Context, path string, payload map[string]interface{}) (map[string]interface{}, error) {
+	if m.config.APIKey == "" {
+		return nil, fmt.Errorf("messenger page access token is not configured")
+	}
+	baseURL := strings.TrimSuffix(m.config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultMessengerBaseURL
+	}
+
+	method := "GET"
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		method = "POST"
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.config.APIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(respBody, &result)
+	if resp.StatusCode != http.StatusOK {
+		if graphErr, ok := result["error"].(map[string]interface{}); ok {
+			return nil, fmt.Errorf("API error: %s", stringField(graphErr, "message"))
+		}
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+// SendMessage sends a message to a Messenger or Instagram conversation. Media goes out as an
+// attachment followed by its caption, since attachments carry no text.
+func (m *MessengerProvider) SendMessage(ctx context.Context, message *models.SendMessageRequest) (*models.SendMessageResponse, error) {
+	var messages []map[string]interface{}
+	switch {
+	case message.IsInteractive():
+		messages = append(messages, messengerQuickReplies(message))
+	case message.IsStructured():
+		// No location or contact messages; send them as text
+		messages = append(messages, map[string]interface{}{"text": message.PlainText()})
+	case message.Type != "" && message.Type != "text" && message.MediaURL != "":
+		messages = append(messages, map[string]interface{}{
+			"attachment": map[string]interface{}{
+				"type":    messengerAttachmentType(message.Type),
+				"payload": map[string]interface{}{"url": message.MediaURL, "is_reusable": true},
+			},
+		})
+		if message.Body != "" {
+			messages = append(messages, map[string]interface{}{"text": message.Body})
+		}
+	default:
+		messages = append(messages, map[string]interface{}{"text": message.Body})
+	}
+
+	var messageID string
+	for _, content := range messages {
+		result, err := m.graph(ctx, "/me/messages", map[string]interface{}{
+			"recipient":      map[string]string{"id": models.MessengerRecipientID(message.To)},
+			"messaging_type": "RESPONSE",
+			"message":        content,
+		})
+		if err != nil {
+			return &models.SendMessageResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, err
+		}
+		messageID = stringField(result, "message_id")
+	}
+
+	return &models.SendMessageResponse{
+		Success:   true,
+		Message:   "Message sent successfully",
+		MessageID: messageID,
+	}, nil
+}
+
+// messengerQuickReplies turns buttons and list rows into quick replies; a tap sends back the option ID
+func messengerQuickReplies(message *models.SendMessageRequest) map[string]interface{} {
+	text := message.Body
+	if message.Footer != "" {
+		text += "\n\n" + message.Footer
+	}
+
+	var options []models.MessageButton
+	if message.Type == models.MessageTypeButtons {
+		options = message.Buttons
+	} else {
+		for _, row := range message.List.Rows() {
+			options = append(options, models.MessageButton{ID: row.ID, Title: row.Title})
+		}
+	}
+	if len(options) > maxMessengerQuickReplies {
+		options = options[:maxMessengerQuickReplies]
+	}
+
+	replies := make([]map[string]string, 0, len(options))
+	for _, option := range options {
+		replies = append(replies, map[string]string{
+			"content_type": "text",
+			"title":        option.Title,
+			"payload":      option.ID,
+		})
+	}
+	return map[string]interface{}{
+		"text":          text,
+		"quick_replies": replies,
+	}
+}
+
+// messengerAttachmentType maps a media message type to a Send API attachment type
+func messengerAttachmentType(mediaType string) string {
+	switch mediaType {
+	case "video", "audio":
+		return mediaType
+	case "document":
+		return "file"
+	}
+	return "image"
+}
+
+// SendTyping turns the page's typing indicator on or off
+func (m *MessengerProvider) SendTyping(ctx context.Context, to string, typing bool) error {
+	action := "typing_off"
+	if typing {
+		action = "typing_on"
+	}
+	return m.senderAction(ctx, to, action)
+}
+
+// MarkSeen marks the conversation's last message as seen
+func (m *MessengerProvider) MarkSeen(ctx context.Context, to string) error {
+	return m.senderAction(ctx, to, "mark_seen")
+}
+
+// senderAction sends a sender action (typing_on, typing_off, mark_seen) to a conversation
+func (m *MessengerProvider) senderAction(ctx context.Context, to, action string) error {
+	_, err := m.graph(ctx, "/me/messages", map[string]interface{}{
+		"recipient":     map[string]string{"id": models.MessengerRecipientID(to)},
+		"sender_action": action,
+	})
+	return err
+}
+
+// GetSessionStatus reports the page connected while its token is valid
+func (m *MessengerProvider) GetSessionStatus(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	status := "connected"
+	page, err := m.graph(ctx, "/me?fields=name", nil)
+	if err != nil {
+		status = "disconnected"
+	}
+
+	return &models.SessionStatusResponse{
+		Success: true,
+		Message: "Session status retrieved",
+		Session: &models.SessionInfo{
+			SessionID:   deviceID,
+			DeviceID:    deviceID,
+			PhoneNumber: stringField(page, "name"),
+			Status:      status,
+		},
+	}, nil
+}
+
+// StartSession initiates a new WhatsApp session
+func (m *MessengerProvider) StartSession(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	// Pages have no session to start; the token is the connection
+	return m.GetSessionStatus(ctx, deviceID)
+}
+
+// StopSession terminates a WhatsApp session
+func (m *MessengerProvider) StopSession(ctx context.Context, deviceID string) error {
+	// Pages have no session to stop
+	return nil
+}
+
+// ParseWebhook parses the first messaging event of a webhook delivery
+func (m *MessengerProvider) ParseWebhook(payload map[string]interface{}) (*models.WebhookPayload, error) {
+	events := MessengerEvents(payload)
+	if len(events) == 0 {
+		return nil, fmt.Errorf("delivery carries no messages")
+	}
+	return ParseMessengerEvent(events[0]), nil
+}
+
+// MessengerEvents returns the messaging events of a webhook delivery, which batches them per page
+// entry. Echoes of the page's own messages are left out.
+func MessengerEvents(payload map[string]interface{}) []map[string]interface{} {
+	var events []map[string]interface{}
+	entries, _ := payload["entry"].([]interface{})
+	for _, entry := range entries {
+		entry, _ := entry.(map[string]interface{})
+		messaging, _ := entry["messaging"].([]interface{})
+		for _, event := range messaging {
+			event, ok := event.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if message, ok := event["message"].(map[string]interface{}); ok && message["is_echo"] == true {
+				continue
+			}
+			if event["message"] != nil || event["postback"] != nil {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// ParseMessengerEvent parses one messaging event: a message, or a button postback
+func ParseMessengerEvent(event map[string]interface{}) *models.WebhookPayload {
+	webhook := &models.WebhookPayload{
+		Event: "message",
+		Type:  "text",
+		Raw:   event,
+	}
+	if sender, ok := event["sender"].(map[string]interface{}); ok {
+		webhook.From = stringField(sender, "id")
+	}
+	if timestamp, ok := event["timestamp"].(float64); ok {
+		webhook.Timestamp = int64(timestamp) / 1000
+	}
+
+	if postback, ok := event["postback"].(map[string]interface{}); ok {
+		webhook.Body = stringField(postback, "title")
+		webhook.OptionID = stringField(postback, "payload")
+		return webhook
+	}
+
+	message, _ := event["message"].(map[string]interface{})
+	webhook.Body = stringField(message, "text")
+	if reply, ok := message["quick_reply"].(map[string]interface{}); ok {
+		webhook.OptionID = stringField(reply, "payload")
+	}
+	if replyTo, ok := message["reply_to"].(map[string]interface{}); ok {
+		webhook.QuotedMessageID = stringField(replyTo, "mid")
+	}
+	if media := MessengerInboundMedia(event); media != nil {
+		webhook.Type = media.Type
+		webhook.MediaURL = media.URL
+	}
+	return webhook
+}
+
+// MessengerInboundMedia returns the first attachment of a messaging event, or nil when it has none
+func MessengerInboundMedia(event map[string]interface{}) *models.InboundMedia {
+	message, _ := event["message"].(map[string]interface{})
+	attachments, _ := message["attachments"].([]interface{})
+	for _, attachment := range attachments {
+		attachment, ok := attachment.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		payload, _ := attachment["payload"].(map[string]interface{})
+
+		mediaType := models.InboundMediaType(stringField(attachment, "type"), "")
+		if mediaType == "" {
+			continue // shares, story mentions, fallback links, ...
+		}
+		return &models.InboundMedia{
+			Type:    mediaType,
+			URL:     stringField(payload, "url"),
+			Caption: stringField(message, "text"),
+		}
+	}
+	return nil
+}
+
+// GetProviderName returns the provider name
+func (m *MessengerProvider) GetProviderName() string {
+	return models.ChannelMessenger
+}
@@ -3,6 +3,8 @@ package whatsapp
 import (
 	"chatbot-automation/internal/models"
 	"context"
+	"errors"
+	"fmt"
 )
 
 // Provider defines the interface that all WhatsApp providers must implement
@@ -22,16 +24,102 @@ type Provider interface {
 	// ParseWebhook parses incoming webhook payload
 	ParseWebhook(payload map[string]interface{}) (*models.WebhookPayload, error)
 
-	// GetProviderName returns the provider name (waha, wablas, whacenter)
+	// GetProviderName returns the provider name (waha, wablas, whacenter, custom)
 	GetProviderName() string
 }
 
+// ProfileManager is implemented by providers that can read and change the connected number's
+// display profile (Waha)
+type ProfileManager interface {
+	// GetProfile returns the name, about text and photo of the connected number
+	GetProfile(ctx context.Context) (*models.WhatsAppProfile, error)
+
+	// SetProfileName changes the display name
+	SetProfileName(ctx context.Context, name string) error
+
+	// SetProfileAbout changes the about text
+	SetProfileAbout(ctx context.Context, about string) error
+
+	// SetProfilePicture sets the photo from a public image URL; an empty URL removes it
+	SetProfilePicture(ctx context.Context, pictureURL string) error
+}
+
+// AsProfileManager returns the profile API of provider, looking through a sandbox wrapper
+// since profile changes are not sends
+func AsProfileManager(provider Provider) (ProfileManager, bool) {
+	if sandbox, ok := provider.(*SandboxProvider); ok {
+		provider = sandbox.Provider
+	}
+	manager, ok := provider.(ProfileManager)
+	return manager, ok
+}
+
+// PresenceManager is implemented by providers that can show the bot typing and mark a chat read
+// (Waha, Telegram, Messenger)
+type PresenceManager interface {
+	// SendTyping shows (typing true) or clears the typing indicator in the chat with to
+	SendTyping(ctx context.Context, to string, typing bool) error
+
+	// MarkSeen marks the messages of the chat with to as read
+	MarkSeen(ctx context.Context, to string) error
+}
+
+// AsPresenceManager returns the presence API of provider. Sandboxed providers have none, since
+// presence would reach the prospect.
+func AsPresenceManager(provider Provider) (PresenceManager, bool) {
+	manager, ok := provider.(PresenceManager)
+	return manager, ok
+}
+
+// ErrSessionActionUnsupported is returned by SessionManager methods a provider's API has no call for
+var ErrSessionActionUnsupported = errors.New("session action not supported by provider")
+
+// SessionManager is implemented by providers whose number is linked by scanning a QR code
+// (Waha, Whacenter)
+type SessionManager interface {
+	// GetQRCode returns the login QR code as a PNG data URL, or "" when the session is not
+	// waiting for a scan
+	GetQRCode(ctx context.Context) (string, error)
+
+	// RestartSession restarts the session, keeping the linked number
+	RestartSession(ctx context.Context) error
+
+	// LogoutSession unlinks the number; the next start asks for a new scan
+	LogoutSession(ctx context.Context) error
+}
+
+// AsSessionManager returns the session API of provider, looking through a sandbox wrapper
+// since session changes are not sends
+func AsSessionManager(provider Provider) (SessionManager, bool) {
+	if sandbox, ok := provider.(*SandboxProvider); ok {
+		provider = sandbox.Provider
+	}
+	manager, ok := provider.(SessionManager)
+	return manager, ok
+}
+
 // ProviderConfig holds configuration for WhatsApp providers
 type ProviderConfig struct {
-	Provider    string // waha, wablas, whacenter
+	Provider    string // waha, wablas, whacenter, custom
 	APIKey      string
 	BaseURL     string
 	DeviceID    string
 	Instance    string
 	PhoneNumber string
+	Custom      *models.CustomProviderConfig // the gateway API of the custom provider
+}
+
+// NewProvider creates the provider named by config.Provider
+func NewProvider(config *ProviderConfig) (Provider, error) {
+	switch config.Provider {
+	case "waha":
+		return NewWahaProvider(config), nil
+	case "wablas":
+		return NewWablasProvider(config), nil
+	case "whacenter":
+		return NewWhacenterProvider(config), nil
+	case "custom":
+		return NewCustomProvider(config), nil
+	}
+	return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 }
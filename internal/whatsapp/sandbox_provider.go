@@ -0,0 +1,46 @@
+package whatsapp
+
+import (
+	"chatbot-automation/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// SandboxRecorder stores the trace of an intercepted send
+type SandboxRecorder func(ctx context.Context, provider string, message *models.SendMessageRequest, messageID string)
+
+// SandboxProvider wraps a device's provider so sends are logged instead of delivered.
+// Session and webhook calls still go to the real provider, so status checks keep working.
+type SandboxProvider struct {
+	Provider
+	record SandboxRecorder
+}
+
+// NewSandboxProvider wraps provider for a device in sandbox mode; record may be nil
+func NewSandboxProvider(provider Provider, record SandboxRecorder) *SandboxProvider {
+	return &SandboxProvider{
+		Provider: provider,
+		record:   record,
+	}
+}
+
+// SendMessage logs the full payload and reports it sandbox-delivered without calling the provider
+func (p *SandboxProvider) SendMessage(ctx context.Context, message *models.SendMessageRequest) (*models.SendMessageResponse, error) {
+	messageID := "sandbox-" + uuid.NewString()
+
+	payload, _ := json.Marshal(message)
+	log.Printf("🧪 [Sandbox] %s send intercepted (%s): %s", p.GetProviderName(), messageID, payload)
+
+	if p.record != nil {
+		p.record(ctx, p.GetProviderName(), message, messageID)
+	}
+
+	return &models.SendMessageResponse{
+		Success:   true,
+		Message:   models.SandboxDeliveredStatus,
+		MessageID: messageID,
+	}, nil
+}
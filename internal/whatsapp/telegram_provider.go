@@ -0,0 +1,387 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-automation/internal/models"
+)
+
+// defaultTelegramBaseURL is the Telegram Bot API; BaseURL overrides it for a self-hosted server
+const defaultTelegramBaseURL = "https://api.telegram.org"
+
+// TelegramProvider implements the Provider interface for the Telegram Bot API. APIKey is the bot
+// token and recipients are chat IDs, with or without the tg: prospect prefix.
+type TelegramProvider struct {
+	config *ProviderConfig
+	client *http.Client
+}
+
+// NewTelegramProvider creates a new Telegram provider instance
+func NewTelegramProvider(config *ProviderConfig) *TelegramProvider {
+	return &TelegramProvider{
+		config: config,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// call invokes a Bot API method with a JSON payload and returns its result
+func (t *TelegramProvider) call(ctx context.Context, method string, payload map[string]interface{}) (json.RawMessage, error) {
+	if t.config.APIKey == "" {
+		return nil, fmt.Errorf("telegram bot token is not configured")
+	}
+	baseURL := strings.TrimSuffix(t.config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultTelegramBaseURL
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/bot%s/%s", baseURL, t.config.APIKey, method), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The request URL carries the bot token; keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result telegramResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("API error: %s", result.Description)
+	}
+	return result.Result, nil
+}
+
+// SendMessage sends a message to a Telegram chat via the Bot API
+func (t *TelegramProvider) SendMessage(ctx context.Context, message *models.SendMessageRequest) (*models.SendMessageResponse, error) {
+	method, payload := t.messagePayload(message)
+
+	result, err := t.call(ctx, method, payload)
+	if err != nil {
+		return &models.SendMessageResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+
+	var sent struct {
+		MessageID int64 `json:"message_id"`
+	}
+	json.Unmarshal(result, &sent)
+
+	return &models.SendMessageResponse{
+		Success:   true,
+		Message:   "Message sent successfully",
+		MessageID: strconv.FormatInt(sent.MessageID, 10),
+	}, nil
+}
+
+// messagePayload picks the Bot API method of a message and builds its payload
+func (t *TelegramProvider) messagePayload(message *models.SendMessageRequest) (string, map[string]interface{}) {
+	chatID := models.TelegramChatID(message.To)
+
+	switch {
+	case message.IsInteractive():
+		// Buttons and list rows become inline keyboard rows; a tap sends back the option ID
+		text := message.Body
+		if message.Footer != "" {
+			text += "\n\n" + message.Footer
+		}
+		var keyboard [][]map[string]string
+		if message.Type == models.MessageTypeButtons {
+			for _, button := range message.Buttons {
+				keyboard = append(keyboard, []map[string]string{{"text": button.Title, "callback_data": button.ID}})
+			}
+		} else {
+			for _, row := range message.List.Rows() {
+				keyboard = append(keyboard, []map[string]string{{"text": row.Title, "callback_data": row.ID}})
+			}
+		}
+		return "sendMessage", map[string]interface{}{
+			"chat_id":      chatID,
+			"text":         text,
+			"reply_markup": map[string]interface{}{"inline_keyboard": keyboard},
+		}
+
+	case message.IsLocation():
+		if message.Location.Name != "" && message.Location.Address != "" {
+			return "sendVenue", map[string]interface{}{
+				"chat_id":   chatID,
+				"latitude":  message.Location.Latitude,
+				"longitude": message.Location.Longitude,
+				"title":     message.Location.Name,
+				"address":   message.Location.Address,
+			}
+		}
+		return "sendLocation", map[string]interface{}{
+			"chat_id":   chatID,
+			"latitude":  message.Location.Latitude,
+			"longitude": message.Location.Longitude,
+		}
+
+	case message.IsContact():
+		return "sendContact", map[string]interface{}{
+			"chat_id":      chatID,
+			"phone_number": "+" + strings.TrimPrefix(message.Contact.Phone, "+"),
+			"first_name":   message.Contact.Name,
+			"vcard":        message.Contact.VCard(),
+		}
+
+	case message.Type != "" && message.Type != "text" && message.MediaURL != "":
+		// Telegram fetches the file from the URL; each media type has its own method
+		method, field := "sendPhoto", "photo"
+		switch message.Type {
+		case "video":
+			method, field = "sendVideo", "video"
+		case "audio":
+			method, field = "sendAudio", "audio"
+		case "document":
+			method, field = "sendDocument", "document"
+		}
+		payload := map[string]interface{}{
+			"chat_id": chatID,
+			field:     message.MediaURL,
+		}
+		if message.Body != "" {
+			payload["caption"] = message.Body
+		}
+		return method, payload
+	}
+
+	return "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    message.Body,
+	}
+}
+
+// BotUsername returns the @username of the bot, which also checks the token
+func (t *TelegramProvider) BotUsername(ctx context.Context) (string, error) {
+	result, err := t.call(ctx, "getMe", map[string]interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	var bot struct {
+		Username string `json:"username"`
+	}
+	json.Unmarshal(result, &bot)
+	return "@" + bot.Username, nil
+}
+
+// SetWebhook points the bot's updates at webhookURL
+func (t *TelegramProvider) SetWebhook(ctx context.Context, webhookURL string) error {
+	_, err := t.call(ctx, "setWebhook", map[string]interface{}{
+		"url":             webhookURL,
+		"allowed_updates": []string{"message", "callback_query"},
+	})
+	return err
+}
+
+// AnswerCallback acknowledges a tapped inline button so the chat stops showing it as loading
+func (t *TelegramProvider) AnswerCallback(ctx context.Context, callbackID string) error {
+	_, err := t.call(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackID,
+	})
+	return err
+}
+
+// SendTyping shows the bot typing; Telegram clears it by itself after 5 seconds or at the next message
+func (t *TelegramProvider) SendTyping(ctx context.Context, to string, typing bool) error {
+	if !typing {
+		return nil
+	}
+	_, err := t.call(ctx, "sendChatAction", map[string]interface{}{
+		"chat_id": models.TelegramChatID(to),
+		"action":  "typing",
+	})
+	return err
+}
+
+// MarkSeen does nothing: bots have no read receipts
+func (t *TelegramProvider) MarkSeen(ctx context.Context, to string) error {
+	return nil
+}
+
+// GetSessionStatus reports the bot connected while its token is valid
+func (t *TelegramProvider) GetSessionStatus(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	status := "connected"
+	username, err := t.BotUsername(ctx)
+	if err != nil {
+		status = "disconnected"
+	}
+
+	return &models.SessionStatusResponse{
+		Success: true,
+		Message: "Session status retrieved",
+		Session: &models.SessionInfo{
+			SessionID:   deviceID,
+			DeviceID:    deviceID,
+			PhoneNumber: username,
+			Status:      status,
+		},
+	}, nil
+}
+
+// StartSession initiates a new WhatsApp session
+func (t *TelegramProvider) StartSession(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
+	// Bots have no session to start; the token is the connection
+	return t.GetSessionStatus(ctx, deviceID)
+}
+
+// StopSession terminates a WhatsApp session
+func (t *TelegramProvider) StopSession(ctx context.Context, deviceID string) error {
+	// Bots have no session to stop
+	return nil
+}
+
+// ParseWebhook parses a Telegram update: a message, or the callback of a tapped inline button
+func (t *TelegramProvider) ParseWebhook(payload map[string]interface{}) (*models.WebhookPayload, error) {
+	message, callback := TelegramUpdateMessage(payload)
+	if message == nil {
+		return nil, fmt.Errorf("update carries no message")
+	}
+
+	webhook := &models.WebhookPayload{
+		Event: "message",
+		Body:  stringField(message, "text", "caption"),
+		Type:  "text",
+		Raw:   payload,
+	}
+	if chat, ok := message["chat"].(map[string]interface{}); ok {
+		if id, ok := chat["id"].(float64); ok {
+			webhook.From = strconv.FormatInt(int64(id), 10)
+		}
+	}
+	if date, ok := message["date"].(float64); ok {
+		webhook.Timestamp = int64(date)
+	}
+	if reply, ok := message["reply_to_message"].(map[string]interface{}); ok {
+		if id, ok := reply["message_id"].(float64); ok {
+			webhook.QuotedMessageID = strconv.FormatInt(int64(id), 10)
+		}
+	}
+
+	if callback != nil {
+		// The tapped button's message is the bot's; the reply is the button itself
+		webhook.OptionID = stringField(callback, "data")
+		webhook.Body = telegramButtonTitle(message, webhook.OptionID)
+		webhook.QuotedMessageID = ""
+		if id, ok := message["message_id"].(float64); ok {
+			webhook.QuotedMessageID = strconv.FormatInt(int64(id), 10)
+		}
+	}
+
+	if location, ok := message["location"].(map[string]interface{}); ok {
+		webhook.Type = models.MessageTypeLocation
+		webhook.Location = locationFrom(location)
+		if venue, ok := message["venue"].(map[string]interface{}); ok && webhook.Location != nil {
+			webhook.Location.Name = stringField(venue, "title")
+			webhook.Location.Address = stringField(venue, "address")
+		}
+	}
+	if media := TelegramInboundMedia(message); media != nil {
+		webhook.Type = media.Type
+	}
+
+	return webhook, nil
+}
+
+// TelegramUpdateMessage returns the message of a Telegram update and, for a tapped inline button,
+// its callback query; the message of a callback is the bot message the button belongs to
+func TelegramUpdateMessage(update map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	if message, ok := update["message"].(map[string]interface{}); ok {
+		return message, nil
+	}
+	if callback, ok := update["callback_query"].(map[string]interface{}); ok {
+		message, _ := callback["message"].(map[string]interface{})
+		return message, callback
+	}
+	return nil, nil
+}
+
+// telegramButtonTitle finds the title of the inline button with callback data id on message
+func telegramButtonTitle(message map[string]interface{}, id string) string {
+	markup, _ := message["reply_markup"].(map[string]interface{})
+	rows, _ := markup["inline_keyboard"].([]interface{})
+	for _, row := range rows {
+		buttons, _ := row.([]interface{})
+		for _, button := range buttons {
+			if button, ok := button.(map[string]interface{}); ok && stringField(button, "callback_data") == id {
+				return stringField(button, "text")
+			}
+		}
+	}
+	return id
+}
+
+// TelegramInboundMedia returns the attachment of a Telegram message, or nil when it has none.
+// Its URL is left empty: Telegram file URLs embed the bot token.
+func TelegramInboundMedia(message map[string]interface{}) *models.InboundMedia {
+	for _, kind := range []struct{ key, mediaType string }{
+		{"photo", models.MediaImage},
+		{"voice", models.MediaVoice},
+		{"audio", models.MediaAudio},
+		{"video", models.MediaVideo},
+		{"video_note", models.MediaVideo},
+		{"sticker", models.MediaSticker},
+		{"document", models.MediaDocument},
+	} {
+		file, ok := message[kind.key]
+		if !ok {
+			continue
+		}
+		media := &models.InboundMedia{
+			Type:    kind.mediaType,
+			Caption: stringField(message, "caption"),
+		}
+		if file, ok := file.(map[string]interface{}); ok {
+			media.MimeType = stringField(file, "mime_type")
+			media.Filename = stringField(file, "file_name")
+		}
+		return media
+	}
+	return nil
+}
+
+// GetProviderName returns the provider name
+func (t *TelegramProvider) GetProviderName() string {
+	return models.ChannelTelegram
+}
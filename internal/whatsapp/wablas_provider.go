@@ -40,14 +40,53 @@ func (w *WablasProvider) SendMessage(ctx context.Context, message *models.SendMe
 
 	// Handle media messages
 	if message.Type != "" && message.Type != "text" && message.MediaURL != "" {
-		url = fmt.Sprintf("%s/api/send-image", w.config.BaseURL)
+		// Each media type has its own endpoint, named like its media field
+		field := "image"
+		switch message.Type {
+		case "video", "audio", "document":
+			field = message.Type
+		}
+		url = fmt.Sprintf("%s/api/send-%s", w.config.BaseURL, field)
 		payload = map[string]interface{}{
 			"phone":   message.To,
-			"image":   message.MediaURL,
+			field:     message.MediaURL,
 			"caption": message.Body,
 		}
 	}
 
+	// Quick-reply buttons and list menus go through the v2 bulk endpoints; replies carry the title
+	if message.IsInteractive() {
+		url, payload = w.interactivePayload(message)
+	}
+
+	// Location pins and contact cards also go through the v2 endpoints
+	if message.IsLocation() {
+		url = fmt.Sprintf("%s/api/v2/send-location", w.config.BaseURL)
+		payload = map[string]interface{}{
+			"data": []map[string]interface{}{{
+				"phone": message.To,
+				"message": map[string]interface{}{
+					"name":      message.Location.Label(),
+					"address":   message.Location.Address,
+					"latitude":  message.Location.Latitude,
+					"longitude": message.Location.Longitude,
+				},
+			}},
+		}
+	}
+	if message.IsContact() {
+		url = fmt.Sprintf("%s/api/v2/send-contact", w.config.BaseURL)
+		payload = map[string]interface{}{
+			"data": []map[string]interface{}{{
+				"phone": message.To,
+				"message": map[string]interface{}{
+					"name":  message.Contact.Name,
+					"phone": message.Contact.Phone,
+				},
+			}},
+		}
+	}
+
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return &models.SendMessageResponse{
@@ -212,9 +251,57 @@ func (w *WablasProvider) ParseWebhook(payload map[string]interface{}) (*models.W
 		webhook.Type = msgType
 	}
 
+	webhook.QuotedMessageID = QuotedMessageID(payload)
+	webhook.OptionID = SelectedOptionID(payload)
+	webhook.Location = InboundLocation(payload)
+
+	if media := InboundMedia(payload); media != nil {
+		webhook.MediaURL = media.URL
+	}
+
 	return webhook, nil
 }
 
+// interactivePayload builds the v2 send-button or send-list request of a buttons or list message
+func (w *WablasProvider) interactivePayload(message *models.SendMessageRequest) (string, map[string]interface{}) {
+	if message.Type == models.MessageTypeList {
+		lists := make([]map[string]interface{}, 0)
+		for _, row := range message.List.Rows() {
+			lists = append(lists, map[string]interface{}{
+				"title":       row.Title,
+				"description": row.Description,
+			})
+		}
+		return fmt.Sprintf("%s/api/v2/send-list", w.config.BaseURL), map[string]interface{}{
+			"data": []map[string]interface{}{{
+				"phone": message.To,
+				"message": map[string]interface{}{
+					"title":       "",
+					"description": message.Body,
+					"buttonText":  message.List.ButtonText,
+					"lists":       lists,
+					"footer":      message.Footer,
+				},
+			}},
+		}
+	}
+
+	titles := make([]string, 0, len(message.Buttons))
+	for _, button := range message.Buttons {
+		titles = append(titles, button.Title)
+	}
+	return fmt.Sprintf("%s/api/v2/send-button", w.config.BaseURL), map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"phone": message.To,
+			"message": map[string]interface{}{
+				"buttons": titles,
+				"content": message.Body,
+				"footer":  message.Footer,
+			},
+		}},
+	}
+}
+
 // GetProviderName returns the provider name
 func (w *WablasProvider) GetProviderName() string {
 	return "wablas"
@@ -7,7 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
 	"time"
 )
 
@@ -39,6 +43,31 @@ func (w *WahaProvider) SendMessage(ctx context.Context, message *models.SendMess
 		"text":    message.Body,
 	}
 
+	// Quick-reply buttons and list menus have their own endpoints
+	if message.IsInteractive() {
+		url, payload = w.interactivePayload(message)
+	}
+
+	// So do location pins and contact cards
+	if message.IsLocation() {
+		url = fmt.Sprintf("%s/api/sendLocation", w.config.BaseURL)
+		payload = map[string]interface{}{
+			"session":   w.config.Instance,
+			"chatId":    message.To + "@c.us",
+			"latitude":  message.Location.Latitude,
+			"longitude": message.Location.Longitude,
+			"title":     message.Location.Label(),
+		}
+	}
+	if message.IsContact() {
+		url = fmt.Sprintf("%s/api/sendContactVcard", w.config.BaseURL)
+		payload = map[string]interface{}{
+			"session":  w.config.Instance,
+			"chatId":   message.To + "@c.us",
+			"contacts": []map[string]interface{}{{"vcard": message.Contact.VCard()}},
+		}
+	}
+
 	// Handle media messages - use specific endpoints for each type
 	if message.Type != "" && message.Type != "text" && message.MediaURL != "" {
 		if message.Type == "video" {
@@ -153,11 +182,8 @@ func (w *WahaProvider) SendMessage(ctx context.Context, message *models.SendMess
 		}, err
 	}
 
-	// Extract message ID
-	messageID := ""
-	if id, ok := result["id"].(string); ok {
-		messageID = id
-	}
+	// Extract message ID (a string or WAHA's {"_serialized": ...} object)
+	messageID := messageIDValue(result["id"])
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return &models.SendMessageResponse{
@@ -173,6 +199,53 @@ func (w *WahaProvider) SendMessage(ctx context.Context, message *models.SendMess
 	}, fmt.Errorf("API returned status %d", resp.StatusCode)
 }
 
+// interactivePayload builds the sendButtons or sendList request of a buttons or list message
+func (w *WahaProvider) interactivePayload(message *models.SendMessageRequest) (string, map[string]interface{}) {
+	if message.Type == models.MessageTypeList {
+		sections := make([]map[string]interface{}, 0, len(message.List.Sections))
+		for _, section := range message.List.Sections {
+			rows := make([]map[string]interface{}, 0, len(section.Rows))
+			for _, row := range section.Rows {
+				rows = append(rows, map[string]interface{}{
+					"rowId":       row.ID,
+					"title":       row.Title,
+					"description": row.Description,
+				})
+			}
+			sections = append(sections, map[string]interface{}{
+				"title": section.Title,
+				"rows":  rows,
+			})
+		}
+		return fmt.Sprintf("%s/api/sendList", w.config.BaseURL), map[string]interface{}{
+			"session": w.config.Instance,
+			"chatId":  message.To + "@c.us",
+			"message": map[string]interface{}{
+				"description": message.Body,
+				"footer":      message.Footer,
+				"button":      message.List.ButtonText,
+				"sections":    sections,
+			},
+		}
+	}
+
+	buttons := make([]map[string]interface{}, 0, len(message.Buttons))
+	for _, button := range message.Buttons {
+		buttons = append(buttons, map[string]interface{}{
+			"type": "reply",
+			"id":   button.ID,
+			"text": button.Title,
+		})
+	}
+	return fmt.Sprintf("%s/api/sendButtons", w.config.BaseURL), map[string]interface{}{
+		"session": w.config.Instance,
+		"chatId":  message.To + "@c.us",
+		"body":    message.Body,
+		"footer":  message.Footer,
+		"buttons": buttons,
+	}
+}
+
 // GetSessionStatus retrieves the session status from Waha
 func (w *WahaProvider) GetSessionStatus(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
 	url := fmt.Sprintf("%s/api/sessions/%s", w.config.BaseURL, w.config.Instance)
@@ -208,22 +281,15 @@ func (w *WahaProvider) GetSessionStatus(ctx context.Context, deviceID string) (*
 	}
 
 	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return &models.SessionStatusResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to parse response: %v", err),
-		}, err
-	}
-
-	status := "disconnected"
-	if statusStr, ok := result["status"].(string); ok {
-		status = statusStr
-	}
-
-	qrCode := ""
-	if qr, ok := result["qr"].(string); ok {
-		qrCode = qr
+	if resp.StatusCode != http.StatusNotFound { // 404: no session by that name yet
+		if err := json.Unmarshal(body, &result); err != nil {
+			return &models.SessionStatusResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to parse response: %v", err),
+			}, err
+		}
 	}
+	statusStr, _ := result["status"].(string)
 
 	return &models.SessionStatusResponse{
 		Success: true,
@@ -232,12 +298,22 @@ func (w *WahaProvider) GetSessionStatus(ctx context.Context, deviceID string) (*
 			SessionID:   w.config.Instance,
 			DeviceID:    deviceID,
 			PhoneNumber: w.config.PhoneNumber,
-			Status:      status,
-			QRCode:      qrCode,
+			Status:      wahaSessionStatus(statusStr),
 		},
 	}, nil
 }
 
+// wahaSessionStatus maps a Waha session status to connected, connecting or disconnected
+func wahaSessionStatus(status string) string {
+	switch status {
+	case "WORKING":
+		return "connected"
+	case "STARTING", "SCAN_QR_CODE":
+		return "connecting"
+	}
+	return "disconnected" // STOPPED, FAILED or no session
+}
+
 // StartSession initiates a new WhatsApp session
 func (w *WahaProvider) StartSession(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
 	url := fmt.Sprintf("%s/api/sessions", w.config.BaseURL)
@@ -276,6 +352,18 @@ func (w *WahaProvider) StartSession(ctx context.Context, deviceID string) (*mode
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusUnprocessableEntity {
+		// The session already exists; start it instead (a no-op when it is running)
+		if _, err := w.apiRequest(ctx, "POST", fmt.Sprintf("/api/sessions/%s/start", w.config.Instance), nil); err != nil {
+			return &models.SessionStatusResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to start session: %v", err),
+			}, err
+		}
+		time.Sleep(2 * time.Second)
+		return w.GetSessionStatus(ctx, deviceID)
+	}
+
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		// Wait a bit and get QR code
 		time.Sleep(2 * time.Second)
@@ -315,6 +403,181 @@ func (w *WahaProvider) StopSession(ctx context.Context, deviceID string) error {
 	return fmt.Errorf("failed to stop session, status: %d", resp.StatusCode)
 }
 
+// GetQRCode returns the login QR code, or "" unless the session waits for a scan
+func (w *WahaProvider) GetQRCode(ctx context.Context) (string, error) {
+	body, err := w.apiRequest(ctx, "GET", fmt.Sprintf("/api/%s/auth/qr?format=image", w.config.Instance), nil)
+	if err != nil {
+		// Only SCAN_QR_CODE sessions have one
+		return "", nil
+	}
+
+	var qr struct {
+		Mimetype string `json:"mimetype"`
+		Data     string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &qr); err != nil {
+		return "", fmt.Errorf("failed to parse QR code: %w", err)
+	}
+	if qr.Data == "" {
+		return "", nil
+	}
+	if qr.Mimetype == "" {
+		qr.Mimetype = "image/png"
+	}
+	return "data:" + qr.Mimetype + ";base64," + qr.Data, nil
+}
+
+// RestartSession stops and starts the session, keeping the linked number
+func (w *WahaProvider) RestartSession(ctx context.Context) error {
+	_, err := w.apiRequest(ctx, "POST", fmt.Sprintf("/api/sessions/%s/restart", w.config.Instance), nil)
+	return err
+}
+
+// LogoutSession unlinks the number from the session
+func (w *WahaProvider) LogoutSession(ctx context.Context) error {
+	_, err := w.apiRequest(ctx, "POST", fmt.Sprintf("/api/sessions/%s/logout", w.config.Instance), nil)
+	return err
+}
+
+// SendTyping starts or stops the typing indicator in a chat
+func (w *WahaProvider) SendTyping(ctx context.Context, to string, typing bool) error {
+	endpoint := "/api/stopTyping"
+	if typing {
+		endpoint = "/api/startTyping"
+	}
+	_, err := w.apiRequest(ctx, "POST", endpoint, map[string]interface{}{
+		"session": w.config.Instance,
+		"chatId":  to + "@c.us",
+	})
+	return err
+}
+
+// MarkSeen marks the unread messages of a chat as read (blue ticks)
+func (w *WahaProvider) MarkSeen(ctx context.Context, to string) error {
+	_, err := w.apiRequest(ctx, "POST", "/api/sendSeen", map[string]interface{}{
+		"session": w.config.Instance,
+		"chatId":  to + "@c.us",
+	})
+	return err
+}
+
+// GetProfile returns the connected number's profile; the about text comes from its contact entry
+func (w *WahaProvider) GetProfile(ctx context.Context) (*models.WhatsAppProfile, error) {
+	body, err := w.apiRequest(ctx, "GET", fmt.Sprintf("/api/%s/profile", w.config.Instance), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Picture string `json:"picture"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+
+	profile := &models.WhatsAppProfile{
+		ID:         result.ID,
+		Name:       result.Name,
+		PictureURL: result.Picture,
+	}
+
+	if result.ID != "" {
+		query := url.Values{"contactId": {result.ID}, "session": {w.config.Instance}}
+		if body, err := w.apiRequest(ctx, "GET", "/api/contacts/about?"+query.Encode(), nil); err == nil {
+			var about struct {
+				About string `json:"about"`
+			}
+			if json.Unmarshal(body, &about) == nil {
+				profile.About = about.About
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+// SetProfileName changes the display name of the connected number
+func (w *WahaProvider) SetProfileName(ctx context.Context, name string) error {
+	_, err := w.apiRequest(ctx, "PUT", fmt.Sprintf("/api/%s/profile/name", w.config.Instance), map[string]interface{}{
+		"name": name,
+	})
+	return err
+}
+
+// SetProfileAbout changes the about text of the connected number
+func (w *WahaProvider) SetProfileAbout(ctx context.Context, about string) error {
+	_, err := w.apiRequest(ctx, "PUT", fmt.Sprintf("/api/%s/profile/status", w.config.Instance), map[string]interface{}{
+		"status": about,
+	})
+	return err
+}
+
+// SetProfilePicture sets the profile photo from a public image URL, or removes it when empty
+func (w *WahaProvider) SetProfilePicture(ctx context.Context, pictureURL string) error {
+	endpoint := fmt.Sprintf("/api/%s/profile/picture", w.config.Instance)
+	if pictureURL == "" {
+		_, err := w.apiRequest(ctx, "DELETE", endpoint, nil)
+		return err
+	}
+
+	ext := path.Ext(strings.SplitN(pictureURL, "?", 2)[0])
+	mimetype := mime.TypeByExtension(ext)
+	if mimetype == "" {
+		mimetype = "image/jpeg"
+	}
+	_, err := w.apiRequest(ctx, "PUT", endpoint, map[string]interface{}{
+		"file": map[string]interface{}{
+			"mimetype": mimetype,
+			"url":      pictureURL,
+			"filename": "profile" + ext,
+		},
+	})
+	return err
+}
+
+// apiRequest calls a Waha endpoint and returns the response body
+func (w *WahaProvider) apiRequest(ctx context.Context, method, endpoint string, payload interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, w.config.BaseURL+endpoint, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if w.config.APIKey != "" {
+		req.Header.Set("X-Api-Key", w.config.APIKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
 // ParseWebhook parses incoming webhook payload from Waha
 func (w *WahaProvider) ParseWebhook(payload map[string]interface{}) (*models.WebhookPayload, error) {
 	webhook := &models.WebhookPayload{
@@ -352,6 +615,14 @@ func (w *WahaProvider) ParseWebhook(payload map[string]interface{}) (*models.Web
 		if msgType, ok := payloadData["type"].(string); ok {
 			webhook.Type = msgType
 		}
+
+		webhook.QuotedMessageID = QuotedMessageID(payloadData)
+		webhook.OptionID = SelectedOptionID(payloadData)
+		webhook.Location = InboundLocation(payloadData)
+
+		if media := InboundMedia(payloadData); media != nil {
+			webhook.MediaURL = media.URL
+		}
 	}
 
 	return webhook, nil
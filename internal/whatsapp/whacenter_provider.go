@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"chatbot-automation/internal/models"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,6 +40,11 @@ func (w *WhacenterProvider) SendMessage(ctx context.Context, message *models.Sen
 		"message":   message.Body,
 	}
 
+	// Whacenter has no buttons, lists, locations or contact cards: send them as text
+	if message.IsStructured() {
+		payload["message"] = message.PlainText()
+	}
+
 	// Handle media messages - add type and file fields
 	if message.Type != "" && message.Type != "text" && message.MediaURL != "" {
 		payload["file"] = message.MediaURL
@@ -110,9 +116,9 @@ func (w *WhacenterProvider) SendMessage(ctx context.Context, message *models.Sen
 	}, fmt.Errorf("API returned status %d", resp.StatusCode)
 }
 
-// GetSessionStatus retrieves the session status from Whacenter
+// GetSessionStatus retrieves the device status from Whacenter
 func (w *WhacenterProvider) GetSessionStatus(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
-	url := fmt.Sprintf("%s/status", w.config.BaseURL)
+	url := fmt.Sprintf("%s/api/statusDevice?device_id=%s", w.config.BaseURL, w.config.Instance)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -141,7 +147,11 @@ func (w *WhacenterProvider) GetSessionStatus(ctx context.Context, deviceID strin
 		}, err
 	}
 
-	var result map[string]interface{}
+	var result struct {
+		Data struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return &models.SessionStatusResponse{
 			Success: false,
@@ -149,89 +159,72 @@ func (w *WhacenterProvider) GetSessionStatus(ctx context.Context, deviceID strin
 		}, err
 	}
 
-	status := "disconnected"
-	if statusStr, ok := result["status"].(string); ok {
-		if statusStr == "authenticated" || statusStr == "connected" {
-			status = "connected"
-		}
-	}
-
-	qrCode := ""
-	if qr, ok := result["qr"].(string); ok {
-		qrCode = qr
+	// CONNECTED, or NOT CONNECTED while the device waits for a QR scan
+	status := "connecting"
+	if result.Data.Status == "CONNECTED" {
+		status = "connected"
+	} else if result.Data.Status == "" {
+		status = "disconnected"
 	}
 
 	return &models.SessionStatusResponse{
 		Success: true,
 		Message: "Session status retrieved",
 		Session: &models.SessionInfo{
-			SessionID:   deviceID,
+			SessionID:   w.config.Instance,
 			DeviceID:    deviceID,
 			PhoneNumber: w.config.PhoneNumber,
 			Status:      status,
-			QRCode:      qrCode,
 		},
 	}, nil
 }
 
 // StartSession initiates a new WhatsApp session
 func (w *WhacenterProvider) StartSession(ctx context.Context, deviceID string) (*models.SessionStatusResponse, error) {
-	url := fmt.Sprintf("%s/start", w.config.BaseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
-	if err != nil {
-		return &models.SessionStatusResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to create request: %v", err),
-		}, err
-	}
-
-	// Whacenter doesn't use API key header
-
-	resp, err := w.client.Do(req)
-	if err != nil {
-		return &models.SessionStatusResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to send request: %v", err),
-		}, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		// Wait a bit and get QR code
-		time.Sleep(2 * time.Second)
-		return w.GetSessionStatus(ctx, deviceID)
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-	return &models.SessionStatusResponse{
-		Success: false,
-		Error:   fmt.Sprintf("failed to start session: %s", string(body)),
-	}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	// Devices are created by addDevice and stay up; a QR scan links them
+	return w.GetSessionStatus(ctx, deviceID)
 }
 
 // StopSession terminates a WhatsApp session
 func (w *WhacenterProvider) StopSession(ctx context.Context, deviceID string) error {
-	url := fmt.Sprintf("%s/logout", w.config.BaseURL)
+	// Whacenter has no call to stop a device short of deleting it
+	return ErrSessionActionUnsupported
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+// GetQRCode returns the login QR code, or "" unless the device waits for a scan
+func (w *WhacenterProvider) GetQRCode(ctx context.Context) (string, error) {
+	url := fmt.Sprintf("%s/api/qr?device_id=%s", w.config.BaseURL, w.config.Instance)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Whacenter doesn't use API key header
-
 	resp, err := w.client.Do(req)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return nil
+	qrData, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// The QR code comes back as PNG bytes; connected devices get a JSON message instead
+	if !bytes.HasPrefix(qrData, []byte("\x89PNG")) {
+		return "", nil
 	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrData), nil
+}
 
-	return fmt.Errorf("failed to stop session, status: %d", resp.StatusCode)
+// RestartSession restarts the device session
+func (w *WhacenterProvider) RestartSession(ctx context.Context) error {
+	return ErrSessionActionUnsupported
+}
+
+// LogoutSession unlinks the number from the device
+func (w *WhacenterProvider) LogoutSession(ctx context.Context) error {
+	return ErrSessionActionUnsupported
 }
 
 // ParseWebhook parses incoming webhook payload from Whacenter
@@ -267,9 +260,13 @@ func (w *WhacenterProvider) ParseWebhook(payload map[string]interface{}) (*model
 		webhook.Type = msgType
 	}
 
+	webhook.QuotedMessageID = QuotedMessageID(payload)
+	webhook.OptionID = SelectedOptionID(payload)
+	webhook.Location = InboundLocation(payload)
+
 	// Extract media URL if present
-	if mediaURL, ok := payload["media_url"].(string); ok {
-		webhook.MediaURL = mediaURL
+	if media := InboundMedia(payload); media != nil {
+		webhook.MediaURL = media.URL
 	}
 
 	return webhook, nil
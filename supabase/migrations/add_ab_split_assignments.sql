@@ -0,0 +1,26 @@
+-- Migration: A/B split node assignments
+-- An ab_split node sends each prospect down one of its branches, weighted by the percentage on
+-- each connection and picked from a hash of the phone number. ab_split_assignments records the
+-- branch each prospect got so the choice sticks when weights change, and so the variants can be
+-- compared by the stage their prospects reach (GET /api/analytics/ab-splits).
+
+CREATE TABLE IF NOT EXISTS public.ab_split_assignments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  flow_id character varying NOT NULL,
+  node_id character varying NOT NULL,
+  variant character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  source character varying NOT NULL,
+  conversation_id character varying,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (flow_id, node_id, id_device, prospect_num)
+);
+
+COMMENT ON COLUMN public.ab_split_assignments.variant IS 'ID of the node the chosen branch leads to';
+COMMENT ON COLUMN public.ab_split_assignments.source IS 'Conversation table: ai_whatsapp or wasapbot';
+
+CREATE INDEX IF NOT EXISTS idx_ab_split_assignments_device_created ON public.ab_split_assignments(id_device, created_at DESC);
+
+-- Backend writes with the service role only
+ALTER TABLE public.ab_split_assignments ENABLE ROW LEVEL SECURITY;
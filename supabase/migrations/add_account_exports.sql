@@ -0,0 +1,26 @@
+-- Migration: Account exports
+-- POST /api/account/export builds a zip of all the user's flows, devices (without secrets),
+-- conversations, stage configurations, custom fields, campaigns and analytics summaries in the
+-- background. Archives live in the private account-exports bucket and are downloaded through
+-- short-lived signed links.
+
+CREATE TABLE IF NOT EXISTS public.account_exports (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  status character varying NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+  object_path text,
+  size_bytes bigint,
+  error text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  completed_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_exports_user ON public.account_exports (user_id, created_at DESC);
+
+COMMENT ON COLUMN public.account_exports.object_path IS 'Archive path in the account-exports storage bucket (<user_id>/<id>.zip)';
+
+ALTER TABLE public.account_exports ENABLE ROW LEVEL SECURITY;
+
+INSERT INTO storage.buckets (id, name, public)
+VALUES ('account-exports', 'account-exports', false)
+ON CONFLICT (id) DO NOTHING;
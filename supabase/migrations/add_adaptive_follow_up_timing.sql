@@ -0,0 +1,15 @@
+-- Migration: Adaptive follow-up timing
+-- The reply timing learner reads each device's last 30 days of conversation_messages and stores the
+-- reply rate of its outreach by hour of day in device_setting.reply_profile. delay and
+-- waiting_times pauses of an hour or more then resume in the device's best reply hours instead of
+-- at their fixed offset. static_follow_ups turns this off for a device; a node keeps its fixed
+-- offset with "timing": "static" in its config.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS reply_profile jsonb,
+ADD COLUMN IF NOT EXISTS static_follow_ups boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN public.device_setting.reply_profile IS 'Learned reply rate by hour of day (hours, best_hours, samples, timezone, computed_at); maintained by the backend';
+COMMENT ON COLUMN public.device_setting.static_follow_ups IS 'Resume follow-up pauses at their fixed offset instead of the best reply hours';
+
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_device_time ON public.conversation_messages (id_device, created_at);
@@ -0,0 +1,16 @@
+-- Migration: Agent takeover
+-- An agent can take a conversation over from the inbox (POST /api/conversations/:id/takeover).
+-- It is parked in the new 'paused_by_agent' state: no flow runs for the prospect and their
+-- messages are only added to the history, until the agent hands it back to the bot at a chosen
+-- node or stage (POST /api/conversations/:id/resume).
+
+ALTER TABLE public.ai_whatsapp DROP CONSTRAINT IF EXISTS ai_whatsapp_execution_status_check;
+ALTER TABLE public.ai_whatsapp ADD CONSTRAINT ai_whatsapp_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned', 'failed_max_steps', 'paused_by_agent'));
+
+ALTER TABLE public.wasapbot DROP CONSTRAINT IF EXISTS wasapbot_execution_status_check;
+ALTER TABLE public.wasapbot ADD CONSTRAINT wasapbot_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned', 'failed_max_steps', 'paused_by_agent'));
+
+COMMENT ON COLUMN public.ai_whatsapp.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned, failed_max_steps, paused_by_agent';
+COMMENT ON COLUMN public.wasapbot.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned, failed_max_steps, paused_by_agent';
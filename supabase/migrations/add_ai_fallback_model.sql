@@ -0,0 +1,9 @@
+-- Migration: AI fallback model
+-- AI provider calls now retry transient failures (timeouts, 429 and 5xx) with exponential
+-- backoff. When a device's model still fails, ai_prompt nodes try its fallback model once
+-- before the node fails.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS ai_fallback_model character varying;
+
+COMMENT ON COLUMN public.device_setting.ai_fallback_model IS 'OpenRouter model tried when the device or node model fails after retries (NULL = no fallback)';
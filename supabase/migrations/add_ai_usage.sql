@@ -0,0 +1,30 @@
+-- Migration: AI token usage and budgets
+-- Every AI call is stored with its model, purpose, tokens and estimated cost (the provider's charge
+-- when it reports one, else the model's price) for per-device, per-user and per-day totals.
+-- ai_budgets holds each user's daily and monthly spend limits for the AI usage alerts.
+
+CREATE TABLE IF NOT EXISTS public.ai_usage (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  model character varying NOT NULL,
+  purpose character varying NOT NULL CHECK (purpose IN ('flow_reply', 'completion', 'reply_suggestion')),
+  prompt_tokens integer NOT NULL DEFAULT 0,
+  completion_tokens integer NOT NULL DEFAULT 0,
+  cost numeric(12, 6) NOT NULL DEFAULT 0,
+  priced boolean NOT NULL DEFAULT true,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_device_time ON public.ai_usage(id_device, created_at);
+
+CREATE TABLE IF NOT EXISTS public.ai_budgets (
+  user_id uuid PRIMARY KEY,
+  daily_limit numeric(12, 2) CHECK (daily_limit IS NULL OR daily_limit > 0),
+  monthly_limit numeric(12, 2) CHECK (monthly_limit IS NULL OR monthly_limit > 0),
+  alert_percent integer CHECK (alert_percent IS NULL OR alert_percent BETWEEN 1 AND 100),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- Backend writes with the service role only
+ALTER TABLE public.ai_usage ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.ai_budgets ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,5 @@
+-- Migration: Flow variables on ai_whatsapp
+-- session_data holds the variables flow nodes share between steps; StartFlow can seed it
+-- together with a pre-filled stage and a seed message in conv_last.
+
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS session_data jsonb;
@@ -0,0 +1,25 @@
+-- Migration: API keys
+-- Scoped keys for machine-to-machine access. A key acts as its user within its scopes
+-- (flows:read, conversations:write, webhooks:ingest, ...). Only the SHA-256 of the key is stored;
+-- key_prefix keeps its first characters so users can tell keys apart.
+
+CREATE TABLE IF NOT EXISTS public.api_keys (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL,
+  key_prefix character varying NOT NULL,
+  key_hash character varying NOT NULL UNIQUE,
+  scopes text[] NOT NULL DEFAULT '{}',
+  expires_at timestamp with time zone,
+  last_used_at timestamp with time zone,
+  revoked_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.api_keys.key_hash IS 'Hex SHA-256 of the key; the key itself is shown once, on creation';
+COMMENT ON COLUMN public.api_keys.revoked_at IS 'Set when the key is revoked; revoked keys stay listed but no longer authenticate';
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id
+  ON public.api_keys (user_id);
+
+ALTER TABLE public.api_keys ENABLE ROW LEVEL SECURITY;
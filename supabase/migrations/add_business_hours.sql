@@ -0,0 +1,14 @@
+-- Migration: Device business hours and away routing
+-- business_hours ("Mon-Fri 09:00-18:00; Sat 10:00-14:00" in the device timezone) are when the
+-- device is open; business_hours flow nodes branch on them too. Inbound messages outside them are
+-- answered by away_flow_id when closed_mode is 'away', or run the normal flow with its bot replies
+-- held in queued_sends until opening when closed_mode is 'queue'.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS business_hours text,
+ADD COLUMN IF NOT EXISTS closed_mode character varying CHECK (closed_mode IN ('away', 'queue')),
+ADD COLUMN IF NOT EXISTS away_flow_id uuid REFERENCES public.chatbot_flows(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN public.device_setting.business_hours IS 'Opening hours as "[days] HH:MM-HH:MM" windows separated by ";" (device timezone); NULL = always open';
+COMMENT ON COLUMN public.device_setting.closed_mode IS 'How inbound messages outside business_hours are handled: away (away flow answers) or queue (replies held until opening); NULL = away';
+COMMENT ON COLUMN public.device_setting.away_flow_id IS 'Flow that answers inbound messages outside business_hours in away mode';
@@ -0,0 +1,42 @@
+-- Migration: Campaign sending, CSV and segment recipients
+-- Campaigns can now be built from an uploaded CSV or a saved segment of conversations, and are
+-- sent by the backend: start, pause, resume and cancel move the status, and running campaigns
+-- send to their pending recipients at rate_per_minute. Extra CSV columns are kept per recipient
+-- in variables, for {{column}} placeholders in the message or as flow variables.
+
+CREATE TABLE IF NOT EXISTS public.contact_segments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL,
+  stage character varying,
+  execution_status character varying,
+  niche character varying,
+  created_after timestamp with time zone,
+  created_before timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_segments_user ON public.contact_segments(user_id);
+
+ALTER TABLE public.campaigns ADD COLUMN IF NOT EXISTS segment_id uuid REFERENCES public.contact_segments(id) ON DELETE SET NULL;
+ALTER TABLE public.campaigns ADD COLUMN IF NOT EXISTS rate_per_minute integer NOT NULL DEFAULT 0 CHECK (rate_per_minute BETWEEN 0 AND 60);
+ALTER TABLE public.campaigns ADD COLUMN IF NOT EXISTS started_at timestamp with time zone;
+ALTER TABLE public.campaigns ADD COLUMN IF NOT EXISTS completed_at timestamp with time zone;
+
+ALTER TABLE public.campaigns DROP CONSTRAINT IF EXISTS campaigns_source_check;
+ALTER TABLE public.campaigns ADD CONSTRAINT campaigns_source_check
+  CHECK (source IN ('manual', 'recycle', 'csv', 'segment'));
+
+ALTER TABLE public.campaign_recipients ADD COLUMN IF NOT EXISTS variables jsonb;
+
+-- A sender claims each recipient (pending -> sending) before messaging it, so two backend
+-- instances never send to the same number
+ALTER TABLE public.campaign_recipients DROP CONSTRAINT IF EXISTS campaign_recipients_status_check;
+ALTER TABLE public.campaign_recipients ADD CONSTRAINT campaign_recipients_status_check
+  CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'skipped'));
+
+-- The sender looks up running campaigns on every tick
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON public.campaigns(status) WHERE status = 'running';
+
+-- Backend writes with the service role only
+ALTER TABLE public.contact_segments ENABLE ROW LEVEL SECURITY;
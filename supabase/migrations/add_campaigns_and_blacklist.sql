@@ -0,0 +1,53 @@
+-- Migration: Campaigns (broadcasts) and contact blacklist
+-- Campaigns are created in draft; the recycle endpoint fills them from abandoned conversations.
+-- contact_blacklist holds numbers that must never be targeted (blacklist) or asked to stop (opt_out).
+
+CREATE TABLE IF NOT EXISTS public.campaigns (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  id_device character varying NOT NULL,
+  name character varying NOT NULL,
+  source character varying NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'recycle')),
+  message text,
+  flow_id uuid,
+  status character varying NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'running', 'paused', 'completed', 'cancelled')),
+  total_recipients integer NOT NULL DEFAULT 0,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_user ON public.campaigns(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS public.campaign_recipients (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  campaign_id uuid NOT NULL REFERENCES public.campaigns(id) ON DELETE CASCADE,
+  prospect_num character varying NOT NULL,
+  prospect_name character varying,
+  stage character varying,
+  status character varying NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+  error text,
+  sent_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_recipients_campaign ON public.campaign_recipients(campaign_id, status);
+CREATE INDEX IF NOT EXISTS idx_campaign_recipients_sent ON public.campaign_recipients(prospect_num, sent_at) WHERE status = 'sent';
+
+CREATE TABLE IF NOT EXISTS public.contact_blacklist (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  phone_number character varying NOT NULL,
+  reason character varying NOT NULL DEFAULT 'blacklist' CHECK (reason IN ('blacklist', 'opt_out')),
+  note text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (user_id, phone_number)
+);
+
+-- Recycling looks up abandoned conversations by device and creation date
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_device_status_created ON public.ai_whatsapp(id_device, execution_status, created_at);
+CREATE INDEX IF NOT EXISTS idx_wasapbot_device_status_created ON public.wasapbot(id_device, execution_status, created_at);
+
+-- Backend writes with the service role only
+ALTER TABLE public.campaigns ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.campaign_recipients ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.contact_blacklist ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,23 @@
+-- Migration: Flow version history
+-- Before a flow is updated, its current definition (name, niche, nodes_data and settings) is stored
+-- as the next numbered version. GET /api/flows/:id/versions lists them and
+-- POST /api/flows/:id/versions/:version/restore puts a version back (snapshotting the flow first,
+-- so a restore can be undone too).
+
+CREATE TABLE IF NOT EXISTS public.chatbot_flow_versions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  flow_id uuid NOT NULL REFERENCES public.chatbot_flows(id) ON DELETE CASCADE,
+  version integer NOT NULL,
+  name character varying,
+  node_count integer NOT NULL DEFAULT 0,
+  reason character varying NOT NULL DEFAULT 'update' CHECK (reason IN ('update', 'restore', 'model_migration')),
+  created_by uuid,
+  snapshot jsonb NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (flow_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chatbot_flow_versions_flow ON public.chatbot_flow_versions(flow_id, version DESC);
+
+-- Backend writes with the service role only
+ALTER TABLE public.chatbot_flow_versions ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,23 @@
+-- Migration: Marketing consent registry
+-- Append-only log of consent decisions captured by consent nodes. The latest record per
+-- (user_id, prospect_num) decides whether a number may receive broadcasts; no record = excluded.
+
+CREATE TABLE IF NOT EXISTS public.consent_records (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  granted boolean NOT NULL,
+  wording text NOT NULL,
+  response text NOT NULL,
+  source character varying NOT NULL DEFAULT 'flow_node',
+  flow_id uuid,
+  node_id character varying,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_consent_records_user_created ON public.consent_records(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_consent_records_device ON public.consent_records(id_device);
+
+-- Backend writes with the service role only
+ALTER TABLE public.consent_records ENABLE ROW LEVEL SECURITY;
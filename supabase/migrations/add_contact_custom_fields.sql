@@ -0,0 +1,30 @@
+-- Migration: Contact custom fields
+-- Users define their own contact fields beyond the fixed wasapbot columns (alamat, pakej, tarikh_gaji).
+-- A stage configuration whose column names a custom field captures into the conversation's
+-- custom_fields, validated by the field type; lists, conditions, templates and analytics read them there.
+
+CREATE TABLE IF NOT EXISTS public.contact_custom_fields (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL CHECK (name ~ '^[a-z][a-z0-9_]{0,62}$'),
+  label character varying NOT NULL,
+  field_type character varying NOT NULL DEFAULT 'text' CHECK (field_type IN ('text', 'number', 'date', 'boolean', 'select')),
+  options jsonb NOT NULL DEFAULT '[]'::jsonb,
+  pattern text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (user_id, name)
+);
+
+COMMENT ON COLUMN public.contact_custom_fields.name IS 'Key used in stage configurations, list columns, conditions and {{placeholders}}';
+COMMENT ON COLUMN public.contact_custom_fields.options IS 'Allowed values of a select field';
+COMMENT ON COLUMN public.contact_custom_fields.pattern IS 'Regular expression a text value must match';
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS custom_fields jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS custom_fields jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+COMMENT ON COLUMN public.ai_whatsapp.custom_fields IS 'Captured values of the owner''s contact custom fields, by field name';
+COMMENT ON COLUMN public.wasapbot.custom_fields IS 'Captured values of the owner''s contact custom fields, by field name';
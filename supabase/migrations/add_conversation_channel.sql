@@ -0,0 +1,9 @@
+-- Migration: Conversation channel
+-- channel is 'whatsapp' for provider webhooks or 'web' for the website chat widget.
+-- Web chat prospects use prospect_num 'web:<session_id>' instead of a phone number.
+
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS channel character varying(20) NOT NULL DEFAULT 'whatsapp';
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS channel character varying(20) NOT NULL DEFAULT 'whatsapp';
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_channel ON public.ai_whatsapp (id_device, channel);
+CREATE INDEX IF NOT EXISTS idx_wasapbot_channel ON public.wasapbot (id_device, channel);
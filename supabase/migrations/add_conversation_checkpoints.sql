@@ -0,0 +1,23 @@
+-- Migration: Conversation checkpoints for state restore
+-- After each inbound message is handled, the engine state of the conversation (flow, current node,
+-- execution state, stage, flow variables, progress) is stored as a checkpoint. Checkpoints are
+-- numbered as steps in created_at order; POST /api/conversations/:id/restore?to_step=N puts a
+-- conversation corrupted by a buggy flow version back to step N without starting the prospect over.
+
+CREATE TABLE IF NOT EXISTS public.conversation_checkpoints (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  conversation_table character varying NOT NULL DEFAULT 'ai_whatsapp',
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  flow_id character varying,
+  node_id character varying,
+  reason character varying NOT NULL DEFAULT 'run', -- run or restore
+  state jsonb NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_checkpoints_conversation ON public.conversation_checkpoints(conversation_table, conversation_id, created_at);
+
+-- Backend writes with the service role only
+ALTER TABLE public.conversation_checkpoints ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,26 @@
+-- Migration: Per-conversation cost ledger
+-- AI tokens priced by model, provider send fees and paid API node calls,
+-- keyed by device + prospect so it covers ai_whatsapp and wasapbot alike
+
+CREATE TABLE IF NOT EXISTS public.conversation_costs (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  flow_id uuid,
+  cost_type character varying NOT NULL CHECK (cost_type IN ('ai_tokens', 'send_fee', 'api_call')),
+  model character varying,
+  prompt_tokens integer,
+  completion_tokens integer,
+  amount numeric(12, 6) NOT NULL DEFAULT 0,
+  description text,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_costs_prospect ON public.conversation_costs(id_device, prospect_num);
+CREATE INDEX IF NOT EXISTS idx_conversation_costs_device_time ON public.conversation_costs(id_device, created_at);
+
+-- Optional per-message provider fee (USD)
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS send_fee numeric(10, 6);
+
+-- Backend writes with the service role only
+ALTER TABLE public.conversation_costs ENABLE ROW LEVEL SECURITY;
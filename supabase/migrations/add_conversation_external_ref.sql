@@ -0,0 +1,23 @@
+-- Migration: Conversation external references
+-- external_ref is a stable key for integrations alongside the serial id_prospect. Callers may supply
+-- their own on creation/start-flow; otherwise the backend sets the UUIDv5 of 'id_device:prospect_num'
+-- (namespace 7f3c2a9e-5b1d-4c8e-9a60-2d4f8b1e6c35), so CRMs can compute it without storing our IDs.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS external_ref character varying(128);
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS external_ref character varying(128);
+
+-- Backfill existing conversations with the same deterministic reference the backend generates
+UPDATE public.ai_whatsapp
+SET external_ref = uuid_generate_v5('7f3c2a9e-5b1d-4c8e-9a60-2d4f8b1e6c35'::uuid, id_device || ':' || prospect_num)::text
+WHERE external_ref IS NULL;
+
+UPDATE public.wasapbot
+SET external_ref = uuid_generate_v5('7f3c2a9e-5b1d-4c8e-9a60-2d4f8b1e6c35'::uuid, id_device || ':' || prospect_num)::text
+WHERE external_ref IS NULL;
+
+-- One Chatbot AI conversation per reference and device. WhatsApp Bot keeps one row per niche,
+-- so the same prospect may share a default reference there.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ai_whatsapp_external_ref ON public.ai_whatsapp(id_device, external_ref);
+CREATE INDEX IF NOT EXISTS idx_wasapbot_external_ref ON public.wasapbot(id_device, external_ref);
@@ -0,0 +1,16 @@
+-- Migration: Conversation flow progress
+-- flow_progress (0-100) is how far the prospect is through the current flow: a node's explicit
+-- "progress" config, otherwise its depth / the deepest node. Completion sets 100.
+-- flow_milestone is the label of the last node with a "milestone" config the conversation passed.
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS flow_progress smallint CHECK (flow_progress IS NULL OR flow_progress BETWEEN 0 AND 100),
+ADD COLUMN IF NOT EXISTS flow_milestone character varying;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS flow_progress smallint CHECK (flow_progress IS NULL OR flow_progress BETWEEN 0 AND 100),
+ADD COLUMN IF NOT EXISTS flow_milestone character varying;
+
+-- Completed conversations are all the way through
+UPDATE public.ai_whatsapp SET flow_progress = 100 WHERE execution_status = 'completed' AND flow_progress IS NULL;
+UPDATE public.wasapbot SET flow_progress = 100 WHERE execution_status = 'completed' AND flow_progress IS NULL;
@@ -0,0 +1,13 @@
+-- Migration: Inbound media messages
+-- Images, voice notes, audio, video and documents from Waha, Whacenter and Wablas webhooks are no
+-- longer dropped. The latest attachment a prospect sent is kept on the conversation, and the run it
+-- starts can branch on it with media conditions and describe it to ai_prompt nodes.
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS last_media jsonb;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS last_media jsonb;
+
+COMMENT ON COLUMN public.ai_whatsapp.last_media IS 'Latest media the prospect sent: {type, url, mime_type, filename, caption}';
+COMMENT ON COLUMN public.wasapbot.last_media IS 'Latest media the prospect sent: {type, url, mime_type, filename, caption}';
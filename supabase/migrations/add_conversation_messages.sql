@@ -0,0 +1,36 @@
+-- Migration: Structured conversation history
+-- Every message of a conversation is stored as a conversation_messages row (role, content, inbound
+-- media, the flow node that sent it or that a reply answered). conv_last on ai_whatsapp and
+-- wasapbot keeps the "User: ..." / "Bot: ..." text rendering of the same entries for existing
+-- readers, written alongside each row and trimmed to the device's history cap; the
+-- conversation_conv_last view renders the full history in that format. Earlier history is not
+-- backfilled and exists in conv_last only.
+
+CREATE TABLE IF NOT EXISTS public.conversation_messages (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  role character varying NOT NULL CHECK (role IN ('user', 'bot')),
+  content text NOT NULL,
+  media jsonb,
+  node_id character varying,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.conversation_messages.conversation_id IS 'id_prospect of the ai_whatsapp or wasapbot row named by source';
+COMMENT ON COLUMN public.conversation_messages.media IS 'Attachment of an inbound message (type, url, mime_type, filename, caption)';
+COMMENT ON COLUMN public.conversation_messages.node_id IS 'Flow node that sent the message, or that a prospect reply answered';
+
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_conversation
+ON public.conversation_messages (source, conversation_id, created_at DESC);
+
+ALTER TABLE public.conversation_messages ENABLE ROW LEVEL SECURITY;
+
+CREATE OR REPLACE VIEW public.conversation_conv_last AS
+SELECT
+  source,
+  conversation_id,
+  string_agg(CASE role WHEN 'user' THEN 'User: ' ELSE 'Bot: ' END || content, E'\n' ORDER BY created_at) AS conv_last
+FROM public.conversation_messages
+GROUP BY source, conversation_id;
@@ -0,0 +1,16 @@
+-- Migration: Conversation pinning and priority
+-- Pinned, then higher-priority conversations sort to the top of inbox lists
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS pinned boolean DEFAULT false,
+ADD COLUMN IF NOT EXISTS priority integer DEFAULT 0 CHECK (priority BETWEEN 0 AND 2);
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS pinned boolean DEFAULT false,
+ADD COLUMN IF NOT EXISTS priority integer DEFAULT 0 CHECK (priority BETWEEN 0 AND 2);
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_inbox ON public.ai_whatsapp(id_device, pinned DESC, priority DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_wasapbot_inbox ON public.wasapbot(id_device, pinned DESC, priority DESC, created_at DESC);
+
+COMMENT ON COLUMN public.ai_whatsapp.priority IS '0 normal, 1 high, 2 urgent (set manually or by escalation rules such as handoff)';
+COMMENT ON COLUMN public.wasapbot.priority IS '0 normal, 1 high, 2 urgent (set manually or by escalation rules such as handoff)';
@@ -0,0 +1,43 @@
+-- Migration: Conversation execution state machine
+-- execution_status now holds the full lifecycle state:
+--   active, waiting, scheduled, handoff, completed, abandoned
+-- waiting_for_reply is kept in sync (true only when execution_status = 'waiting')
+
+-- Normalize rows written before the state machine existed
+UPDATE public.ai_whatsapp SET execution_status = 'active' WHERE execution_status IS NULL OR execution_status = '';
+UPDATE public.wasapbot SET execution_status = 'active' WHERE execution_status IS NULL OR execution_status = '';
+
+UPDATE public.ai_whatsapp SET execution_status = 'waiting'
+WHERE execution_status = 'active' AND waiting_for_reply = true;
+UPDATE public.wasapbot SET execution_status = 'waiting'
+WHERE execution_status = 'active' AND waiting_for_reply = true;
+
+-- New rows start active
+ALTER TABLE public.ai_whatsapp ALTER COLUMN execution_status SET DEFAULT 'active';
+ALTER TABLE public.wasapbot ALTER COLUMN execution_status SET DEFAULT 'active';
+
+-- Fix contradictory rows (e.g. completed + waiting_for_reply = true)
+UPDATE public.ai_whatsapp SET waiting_for_reply = (execution_status = 'waiting');
+UPDATE public.wasapbot SET waiting_for_reply = (execution_status = 'waiting');
+
+UPDATE public.ai_whatsapp SET current_node_id = 'completed' WHERE execution_status = 'completed';
+UPDATE public.wasapbot SET current_node_id = 'completed' WHERE execution_status = 'completed';
+
+-- completed_at: set when the state machine moves a conversation to completed
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS completed_at timestamp with time zone;
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS completed_at timestamp with time zone;
+
+UPDATE public.ai_whatsapp SET completed_at = updated_at WHERE execution_status = 'completed' AND completed_at IS NULL;
+UPDATE public.wasapbot SET completed_at = updated_at WHERE execution_status = 'completed' AND completed_at IS NULL;
+
+-- Enforce valid states
+ALTER TABLE public.ai_whatsapp DROP CONSTRAINT IF EXISTS ai_whatsapp_execution_status_check;
+ALTER TABLE public.ai_whatsapp ADD CONSTRAINT ai_whatsapp_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned'));
+
+ALTER TABLE public.wasapbot DROP CONSTRAINT IF EXISTS wasapbot_execution_status_check;
+ALTER TABLE public.wasapbot ADD CONSTRAINT wasapbot_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned'));
+
+COMMENT ON COLUMN public.ai_whatsapp.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned';
+COMMENT ON COLUMN public.wasapbot.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned';
@@ -0,0 +1,10 @@
+-- Migration: Test conversation flag
+-- is_test marks traffic generated while building flows: conversations started on a device in
+-- sandbox mode, or created/flagged as tests through the API. Flagged conversations can be purged
+-- in one call with DELETE /api/conversations/cleanup?device_id=&status=test.
+
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS is_test boolean NOT NULL DEFAULT false;
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS is_test boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_is_test ON public.ai_whatsapp (id_device, created_at) WHERE is_test;
+CREATE INDEX IF NOT EXISTS idx_wasapbot_is_test ON public.wasapbot (id_device, created_at) WHERE is_test;
@@ -0,0 +1,18 @@
+-- Migration: CSAT survey node
+-- Stores the 1-5 satisfaction score collected by a csat flow node
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS csat_score integer CHECK (csat_score BETWEEN 1 AND 5),
+ADD COLUMN IF NOT EXISTS csat_attempts integer DEFAULT 0,
+ADD COLUMN IF NOT EXISTS csat_at timestamp with time zone;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS csat_score integer CHECK (csat_score BETWEEN 1 AND 5),
+ADD COLUMN IF NOT EXISTS csat_attempts integer DEFAULT 0,
+ADD COLUMN IF NOT EXISTS csat_at timestamp with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_csat ON public.ai_whatsapp(id_device, csat_at) WHERE csat_score IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wasapbot_csat ON public.wasapbot(id_device, csat_at) WHERE csat_score IS NOT NULL;
+
+COMMENT ON COLUMN public.ai_whatsapp.csat_score IS 'Customer satisfaction rating (1-5) collected by a csat node';
+COMMENT ON COLUMN public.wasapbot.csat_score IS 'Customer satisfaction rating (1-5) collected by a csat node';
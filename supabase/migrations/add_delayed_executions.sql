@@ -0,0 +1,26 @@
+-- Migration: Persistent scheduler for delay and waiting_times nodes
+-- Instead of sleeping in the request goroutine, a delay or waiting_times node stores a resume time here
+-- and pauses the conversation (delay: execution_status = 'scheduled', waiting_times: 'waiting').
+-- The flow scheduler resumes the flow after the node once resume_at has passed, so pauses survive restarts.
+
+CREATE TABLE IF NOT EXISTS public.delayed_executions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  flow_id character varying NOT NULL,
+  node_id character varying NOT NULL,
+  node_type character varying NOT NULL CHECK (node_type IN ('delay', 'waiting_times')),
+  resume_at timestamp with time zone NOT NULL,
+  status character varying NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'skipped', 'failed')),
+  last_error text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  finished_at timestamp with time zone
+);
+
+-- The dispatcher polls for due pending rows
+CREATE INDEX IF NOT EXISTS idx_delayed_executions_due ON public.delayed_executions(resume_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_delayed_executions_conversation ON public.delayed_executions(source, conversation_id) WHERE status = 'pending';
+
+-- Backend writes with the service role only
+ALTER TABLE public.delayed_executions ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,7 @@
+-- Migration: Per-device AI provider endpoint overrides
+-- ai_endpoints is keyed by provider (openrouter, openai, anthropic), each with an optional
+-- base_url and extra request headers. NULL uses the deployment endpoints (AI_ENDPOINTS_FILE /
+-- OPENROUTER_BASE_URL / ...), which fall back to the public provider APIs.
+-- TLS options are deployment-only and live in the endpoints file.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS ai_endpoints jsonb;
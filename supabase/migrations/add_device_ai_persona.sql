@@ -0,0 +1,5 @@
+-- Migration: Per-device AI persona
+-- device_setting.ai_persona is {name, tone, do[], dont[], sample_phrases[]}, injected into every
+-- ai_prompt system prompt so persona text no longer has to be copied into each AI node.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS ai_persona jsonb;
@@ -0,0 +1,6 @@
+-- Migration: Custom HTTP gateway provider for devices
+-- device_setting.custom_provider describes the API of a device whose provider is 'custom':
+-- {send_url, method, headers, content_type, body, media_body, message_id_field, status_url}.
+-- URLs, headers and bodies are templates filled per message ({{to}}, {{message}}, {{api_key}}, ...).
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS custom_provider jsonb;
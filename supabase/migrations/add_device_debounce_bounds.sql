@@ -0,0 +1,7 @@
+-- Migration: Per-device adaptive debounce bounds
+-- The external debouncer asks POST /api/debounce/window for every queued message; the window
+-- grows from debounce_min_ms to debounce_max_ms with device queue depth and sender rapid-fire.
+-- NULL uses the defaults (2000 / 15000 ms).
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS debounce_min_ms integer;
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS debounce_max_ms integer;
@@ -0,0 +1,16 @@
+-- Migration: Backup device failover
+-- A primary device can link a backup device that sends while the primary is disconnected
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS backup_device_id uuid REFERENCES public.device_setting(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS failover_notice text,
+ADD COLUMN IF NOT EXISTS connection_status character varying
+  CHECK (connection_status IS NULL OR connection_status IN ('connected', 'disconnected')),
+ADD COLUMN IF NOT EXISTS status_checked_at timestamp with time zone;
+
+ALTER TABLE public.device_setting
+ADD CONSTRAINT device_setting_backup_not_self CHECK (backup_device_id IS NULL OR backup_device_id <> id);
+
+COMMENT ON COLUMN public.device_setting.backup_device_id IS 'Device that takes over outbound sends while this device is disconnected';
+COMMENT ON COLUMN public.device_setting.failover_notice IS 'Sent once per prospect when the backup uses a different number';
+COMMENT ON COLUMN public.device_setting.connection_status IS 'Last status seen by the device health monitor';
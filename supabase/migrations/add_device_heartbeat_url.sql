@@ -0,0 +1,5 @@
+-- Migration: Device heartbeat webhook
+-- device_setting.heartbeat_url receives the device status JSON from the health monitor on every check,
+-- so external monitors (UptimeRobot, Better Stack) can alert without polling the API.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS heartbeat_url text;
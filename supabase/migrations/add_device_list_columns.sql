@@ -0,0 +1,6 @@
+-- Migration: Per-device conversation list columns
+-- list_columns holds captured field names (session_data keys such as pakej, alamat, tarikh_gaji)
+-- shown as extra columns when a device's conversation list is requested without ?columns=
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS list_columns jsonb;
+
+COMMENT ON COLUMN public.device_setting.list_columns IS 'Captured-field column names for the conversation list (max 10)';
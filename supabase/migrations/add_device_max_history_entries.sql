@@ -0,0 +1,7 @@
+-- Migration: Configurable conv_last history cap for Wasapbot flows
+-- conv_last is trimmed to the last N "User:"/"Bot:" entries (default 20 when NULL)
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS max_history_entries integer CHECK (max_history_entries IS NULL OR max_history_entries >= 1);
+
+COMMENT ON COLUMN public.device_setting.max_history_entries IS 'Max conv_last entries kept for Wasapbot conversations (NULL = default 20)';
@@ -0,0 +1,25 @@
+-- Migration: Device sandbox mode
+-- device_setting.sandbox intercepts every provider send for the device: nothing reaches WhatsApp,
+-- the full payload is logged to sandbox_messages with status 'sandbox-delivered' instead,
+-- so staging can run webhooks, flows and AI end to end without messaging real customers.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS sandbox boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS public.sandbox_messages (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  provider character varying NOT NULL,
+  message_id character varying NOT NULL,
+  recipient character varying NOT NULL,
+  message_type character varying NOT NULL,
+  body text,
+  media_url text,
+  payload jsonb NOT NULL,
+  status character varying NOT NULL DEFAULT 'sandbox-delivered',
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sandbox_messages_device_time ON public.sandbox_messages(id_device, created_at DESC);
+
+-- Backend writes with the service role only
+ALTER TABLE public.sandbox_messages ENABLE ROW LEVEL SECURITY;
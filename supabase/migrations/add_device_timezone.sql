@@ -0,0 +1,5 @@
+-- Migration: Device timezone for time-based flow conditions
+-- device_setting.timezone is an IANA zone name; NULL means Asia/Kuala_Lumpur.
+-- Conditions edges (is_business_hours, weekday, time_between, date_before/after/between) use it.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS timezone character varying(64);
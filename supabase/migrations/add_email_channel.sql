@@ -0,0 +1,29 @@
+-- Migration: Email channel
+-- send_email flow nodes send order confirmations and internal alerts from the device owner's
+-- SMTP server or SendGrid account. Devices with email_replies on route replies to
+-- <webhook_id>+<conversation id>@INBOUND_EMAIL_DOMAIN, and /api/email/inbound appends them to the
+-- conversation history.
+
+CREATE TABLE IF NOT EXISTS public.email_settings (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL UNIQUE,
+  provider character varying NOT NULL CHECK (provider IN ('smtp', 'sendgrid')),
+  from_address character varying NOT NULL,
+  from_name character varying,
+  smtp_host character varying,
+  smtp_port integer CHECK (smtp_port BETWEEN 1 AND 65535),
+  smtp_username character varying,
+  smtp_password text,
+  sendgrid_api_key text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.email_settings.smtp_port IS 'NULL = 587 (STARTTLS); 465 uses implicit TLS';
+
+ALTER TABLE public.email_settings ENABLE ROW LEVEL SECURITY;
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS email_replies boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN public.device_setting.email_replies IS 'Append email replies to send_email messages to the conversation history';
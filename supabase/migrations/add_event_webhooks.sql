@@ -0,0 +1,50 @@
+-- Migration: Outbound event webhooks
+-- Users register endpoints that receive the app's events as signed JSON POSTs: conversation.created,
+-- stage.changed, flow.completed, order.paid and the custom events of emit_event flow nodes. Every
+-- delivery is logged in event_webhook_deliveries; failed ones stay pending and are retried with a
+-- doubling delay until the webhook's max_attempts, then marked failed.
+
+CREATE TABLE IF NOT EXISTS public.event_webhooks (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  id_device character varying,
+  name character varying,
+  url text NOT NULL,
+  secret text NOT NULL,
+  events text[] NOT NULL DEFAULT '{}',
+  max_attempts integer NOT NULL DEFAULT 5 CHECK (max_attempts BETWEEN 1 AND 10),
+  retry_seconds integer NOT NULL DEFAULT 60 CHECK (retry_seconds BETWEEN 10 AND 3600),
+  is_active boolean NOT NULL DEFAULT true,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.event_webhooks.id_device IS 'Only events of this device; NULL = every device of the user';
+COMMENT ON COLUMN public.event_webhooks.secret IS 'Signs each body: X-Webhook-Signature is sha256=<hex HMAC-SHA256>';
+COMMENT ON COLUMN public.event_webhooks.events IS 'Event names received; empty or * = every event';
+
+CREATE INDEX IF NOT EXISTS idx_event_webhooks_user ON public.event_webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS public.event_webhook_deliveries (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  webhook_id uuid NOT NULL REFERENCES public.event_webhooks (id) ON DELETE CASCADE,
+  event_id character varying NOT NULL,
+  event character varying NOT NULL,
+  payload jsonb NOT NULL,
+  status character varying NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+  attempts integer NOT NULL DEFAULT 0,
+  response_status integer,
+  error text,
+  next_attempt_at timestamp with time zone,
+  delivered_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.event_webhook_deliveries.event_id IS 'The same for every webhook receiving the event';
+COMMENT ON COLUMN public.event_webhook_deliveries.next_attempt_at IS 'When a pending delivery is retried';
+
+CREATE INDEX IF NOT EXISTS idx_event_webhook_deliveries_due ON public.event_webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_event_webhook_deliveries_webhook ON public.event_webhook_deliveries (webhook_id, created_at DESC);
+
+ALTER TABLE public.event_webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.event_webhook_deliveries ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,10 @@
+-- Migration: Post-completion (re-engagement) policy per flow
+-- Decides what happens when a prospect messages again after the flow completed
+
+ALTER TABLE public.chatbot_flows
+ADD COLUMN IF NOT EXISTS completion_policy character varying DEFAULT 'ignore'
+  CHECK (completion_policy IN ('ignore', 'restart', 'after_sales', 'handoff')),
+ADD COLUMN IF NOT EXISTS after_sales_flow_id uuid REFERENCES public.chatbot_flows(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN public.chatbot_flows.completion_policy IS 'What to do when a completed prospect messages again: ignore, restart, after_sales, handoff';
+COMMENT ON COLUMN public.chatbot_flows.after_sales_flow_id IS 'Flow to route completed prospects to when completion_policy = after_sales';
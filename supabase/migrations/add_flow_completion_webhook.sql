@@ -0,0 +1,11 @@
+-- Migration: Per-flow completion webhook
+-- When a conversation completes the flow, completion_webhook_template is rendered with the conversation's
+-- variables ({{nama}}, {{alamat}}, {{pakej}}, {{disposition}}, any column or session_data key) and POSTed
+-- as JSON to completion_webhook_url, e.g. a Zapier or Make catch hook. An empty template sends all variables.
+
+ALTER TABLE public.chatbot_flows
+ADD COLUMN IF NOT EXISTS completion_webhook_url text,
+ADD COLUMN IF NOT EXISTS completion_webhook_template text;
+
+COMMENT ON COLUMN public.chatbot_flows.completion_webhook_url IS 'URL that receives the rendered completion_webhook_template when a conversation completes the flow';
+COMMENT ON COLUMN public.chatbot_flows.completion_webhook_template IS 'JSON with {{variable}} placeholders inside strings; empty sends every conversation variable';
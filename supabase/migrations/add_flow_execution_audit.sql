@@ -0,0 +1,16 @@
+-- Migration: Per-node flow execution audit trail
+-- Both flow engines now write a flow_execution_logs row for every node they run: the node, the
+-- prospect's message the run was handling, the outcome (continued, paused, completed, failed),
+-- the node taken next, how long the node took and its error. Support staff read them back through
+-- GET /api/conversations/:id/execution-log and GET /api/wasapbot/:id/execution-log.
+
+ALTER TABLE public.flow_execution_logs
+  ADD COLUMN IF NOT EXISTS node_type character varying,
+  ADD COLUMN IF NOT EXISTS input_message text,
+  ADD COLUMN IF NOT EXISTS outcome character varying,
+  ADD COLUMN IF NOT EXISTS next_node_id character varying,
+  ADD COLUMN IF NOT EXISTS duration_ms integer,
+  ADD COLUMN IF NOT EXISTS error text;
+
+COMMENT ON COLUMN public.flow_execution_logs.event IS 'node_executed for each node run, resumed when a paused node takes a reply, failed_max_steps when the loop guard stops a run';
+COMMENT ON COLUMN public.flow_execution_logs.outcome IS 'continued, paused, completed or failed';
@@ -0,0 +1,36 @@
+-- Migration: Loop protection for flow execution
+-- A flow run stops once it executes more nodes than FLOW_MAX_STEPS, or visits one node more than
+-- FLOW_MAX_NODE_VISITS times, without pausing. The conversation is parked in the new
+-- 'failed_max_steps' state (the bot stays quiet until an agent takes over or restores it)
+-- and the run is written to flow_execution_logs.
+
+ALTER TABLE public.ai_whatsapp DROP CONSTRAINT IF EXISTS ai_whatsapp_execution_status_check;
+ALTER TABLE public.ai_whatsapp ADD CONSTRAINT ai_whatsapp_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned', 'failed_max_steps'));
+
+ALTER TABLE public.wasapbot DROP CONSTRAINT IF EXISTS wasapbot_execution_status_check;
+ALTER TABLE public.wasapbot ADD CONSTRAINT wasapbot_execution_status_check
+  CHECK (execution_status IN ('active', 'waiting', 'scheduled', 'handoff', 'completed', 'abandoned', 'failed_max_steps'));
+
+COMMENT ON COLUMN public.ai_whatsapp.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned, failed_max_steps';
+COMMENT ON COLUMN public.wasapbot.execution_status IS 'Conversation state: active, waiting, scheduled, handoff, completed, abandoned, failed_max_steps';
+
+CREATE TABLE IF NOT EXISTS public.flow_execution_logs (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  flow_id character varying NOT NULL,
+  node_id character varying,
+  event character varying NOT NULL,
+  steps integer NOT NULL DEFAULT 0,
+  node_visits integer NOT NULL DEFAULT 0,
+  message text,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_flow_execution_logs_conversation ON public.flow_execution_logs(source, conversation_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_flow_execution_logs_flow ON public.flow_execution_logs(flow_id, created_at DESC);
+
+-- Backend writes with the service role only
+ALTER TABLE public.flow_execution_logs ENABLE ROW LEVEL SECURITY;
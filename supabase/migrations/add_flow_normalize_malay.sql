@@ -0,0 +1,9 @@
+-- Migration: Malay spelling normalization for flow conditions
+-- Condition matching always lowercases, strips emoji and punctuation and collapses whitespace on
+-- both the prospect's message and the condition value. With normalize_malay, stretched letters are
+-- squeezed and common Malay chat spellings (tak, x, ye, dah, nk ...) are mapped to one standard word.
+
+ALTER TABLE public.chatbot_flows
+ADD COLUMN IF NOT EXISTS normalize_malay boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN public.chatbot_flows.normalize_malay IS 'Conditions also match Malay chat spellings (tak = tidak, yaaa = ya)';
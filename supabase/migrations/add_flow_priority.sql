@@ -0,0 +1,12 @@
+-- Migration: Flow activation and priority
+-- A device can have several flows. Inbound messages try the active ones in priority order (lowest
+-- first): the first whose trigger matches starts, else the first without triggers. A deactivated
+-- flow takes no new prospects; conversations already in it continue.
+
+ALTER TABLE public.chatbot_flows ADD COLUMN IF NOT EXISTS is_active boolean NOT NULL DEFAULT true;
+ALTER TABLE public.chatbot_flows ADD COLUMN IF NOT EXISTS priority integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_chatbot_flows_device_priority ON public.chatbot_flows (id_device, priority);
+
+COMMENT ON COLUMN public.chatbot_flows.is_active IS 'Whether the flow takes new prospects';
+COMMENT ON COLUMN public.chatbot_flows.priority IS 'Order in which inbound messages try the device''s flows (lowest first)';
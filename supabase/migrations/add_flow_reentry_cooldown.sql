@@ -0,0 +1,26 @@
+-- Migration: Flow re-entry cooldown
+-- A completed prospect is only restarted by the restart completion policy once reentry_cooldown_hours
+-- have passed since they last entered the flow (flow_started_at). Manual starts can override it.
+-- flow_entries counts the flows a conversation has entered, so flows can branch on returning prospects.
+
+ALTER TABLE public.chatbot_flows
+ADD COLUMN IF NOT EXISTS reentry_cooldown_hours integer NOT NULL DEFAULT 0 CHECK (reentry_cooldown_hours >= 0);
+
+COMMENT ON COLUMN public.chatbot_flows.reentry_cooldown_hours IS 'Hours after entering the flow before a completed prospect may be restarted in it (0 = no cooldown)';
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS flow_started_at timestamp with time zone,
+ADD COLUMN IF NOT EXISTS flow_entries integer NOT NULL DEFAULT 0;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS flow_started_at timestamp with time zone,
+ADD COLUMN IF NOT EXISTS flow_entries integer NOT NULL DEFAULT 0;
+
+-- Existing conversations have entered their flow once
+UPDATE public.ai_whatsapp SET flow_entries = 1, flow_started_at = created_at WHERE flow_entries = 0 AND flow_id IS NOT NULL;
+UPDATE public.wasapbot SET flow_entries = 1, flow_started_at = created_at WHERE flow_entries = 0 AND flow_id IS NOT NULL;
+
+COMMENT ON COLUMN public.ai_whatsapp.flow_started_at IS 'When the conversation last entered a flow from its start';
+COMMENT ON COLUMN public.ai_whatsapp.flow_entries IS 'Flows entered from their start; above 1 means a returning prospect';
+COMMENT ON COLUMN public.wasapbot.flow_started_at IS 'When the conversation last entered a flow from its start';
+COMMENT ON COLUMN public.wasapbot.flow_entries IS 'Flows entered from their start; above 1 means a returning prospect';
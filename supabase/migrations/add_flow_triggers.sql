@@ -0,0 +1,8 @@
+-- Migration: Flow triggers
+-- A device can have several flows: each inbound message starts the first flow whose trigger
+-- matches it (keyword equals, contains or regex, or new_contact for a prospect's first message),
+-- and the device's flow without triggers receives the rest.
+
+ALTER TABLE public.chatbot_flows ADD COLUMN IF NOT EXISTS triggers jsonb;
+
+COMMENT ON COLUMN public.chatbot_flows.triggers IS 'Trigger rules [{type, value}]: equals, contains, regex or new_contact (NULL = catch-all flow)';
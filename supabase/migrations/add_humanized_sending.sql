@@ -0,0 +1,38 @@
+-- Migration: Humanized send delays and quiet hours
+-- Consecutive messages to the same prospect are spaced by a random delay between send_delay_min_ms
+-- and send_delay_max_ms (1-3 seconds by default). Bot messages wait as long as they take to type at
+-- typing_chars_per_second when it or typing_indicator is set. Bot messages made between
+-- quiet_hours_start and quiet_hours_end (HH:MM in the device timezone) are held in queued_sends
+-- and sent by the queued send dispatcher when the quiet hours end.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS send_delay_min_ms integer CHECK (send_delay_min_ms BETWEEN 1 AND 60000),
+ADD COLUMN IF NOT EXISTS send_delay_max_ms integer CHECK (send_delay_max_ms BETWEEN 1 AND 60000),
+ADD COLUMN IF NOT EXISTS quiet_hours_start character varying CHECK (quiet_hours_start ~ '^[0-2][0-9]:[0-5][0-9]$'),
+ADD COLUMN IF NOT EXISTS quiet_hours_end character varying CHECK (quiet_hours_end ~ '^[0-2][0-9]:[0-5][0-9]$');
+
+COMMENT ON COLUMN public.device_setting.send_delay_min_ms IS 'Shortest delay between consecutive messages to a prospect (NULL = 1000)';
+COMMENT ON COLUMN public.device_setting.send_delay_max_ms IS 'Longest delay between consecutive messages to a prospect (NULL = 3000)';
+COMMENT ON COLUMN public.device_setting.quiet_hours_start IS 'Start of the quiet hours (HH:MM, device timezone) during which bot messages are queued';
+COMMENT ON COLUMN public.device_setting.quiet_hours_end IS 'End of the quiet hours (HH:MM, device timezone); queued messages go out then';
+
+CREATE TABLE IF NOT EXISTS public.queued_sends (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  device_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  message jsonb NOT NULL,
+  flow_id character varying,
+  node_id character varying,
+  send_at timestamp with time zone NOT NULL,
+  status character varying NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'sent', 'failed')),
+  last_error text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  finished_at timestamp with time zone
+);
+
+-- The dispatcher polls for due pending rows
+CREATE INDEX IF NOT EXISTS idx_queued_sends_due ON public.queued_sends(send_at) WHERE status = 'pending';
+
+-- Backend writes with the service role only
+ALTER TABLE public.queued_sends ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,36 @@
+-- Migration: Inbound payload archive
+-- device_setting.archive_inbound keeps the raw body of every accepted inbound webhook for
+-- compliance, gzipped in the private inbound-archive bucket under <id_device>/<YYYY-MM-DD>/.
+-- inbound_archives indexes the objects for retrieval; a background purge deletes payloads and
+-- rows once expires_at (received_at + archive_retention_days) has passed.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS archive_inbound boolean NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS archive_retention_days integer CHECK (archive_retention_days BETWEEN 1 AND 3650);
+
+COMMENT ON COLUMN public.device_setting.archive_retention_days IS 'Days archived inbound payloads are kept (NULL = 365); applies to payloads archived after a change';
+
+CREATE TABLE IF NOT EXISTS public.inbound_archives (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid,
+  id_device character varying NOT NULL,
+  provider character varying,
+  object_path text NOT NULL,
+  content_type character varying,
+  size_bytes integer NOT NULL,
+  stored_bytes integer NOT NULL,
+  received_at timestamp with time zone NOT NULL,
+  expires_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_archives_device_time ON public.inbound_archives (id_device, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_inbound_archives_expires ON public.inbound_archives (expires_at);
+
+COMMENT ON COLUMN public.inbound_archives.object_path IS 'Gzipped payload path in the inbound-archive storage bucket';
+
+-- Backend writes with the service role only
+ALTER TABLE public.inbound_archives ENABLE ROW LEVEL SECURITY;
+
+INSERT INTO storage.buckets (id, name, public)
+VALUES ('inbound-archive', 'inbound-archive', false)
+ON CONFLICT (id) DO NOTHING;
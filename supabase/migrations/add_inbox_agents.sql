@@ -0,0 +1,47 @@
+-- Migration: Inbox agents and sticky assignment
+-- Agents are the members of a user's team who take over conversations in handoff. Each handoff to
+-- an agent is recorded in agent_assignments with the agent's notes, keyed by device and prospect
+-- number so the history outlives the conversation row. A prospect who messages again after the
+-- flow ended is routed back to the agent of their latest assignment while that agent is available.
+
+CREATE TABLE IF NOT EXISTS public.inbox_agents (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL,
+  phone character varying,
+  available boolean NOT NULL DEFAULT true,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.inbox_agents.phone IS 'WhatsApp number messaged when a returning prospect is routed to the agent';
+COMMENT ON COLUMN public.inbox_agents.available IS 'Returning prospects are routed to available agents only';
+
+ALTER TABLE public.inbox_agents ENABLE ROW LEVEL SECURITY;
+
+CREATE TABLE IF NOT EXISTS public.agent_assignments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  agent_id uuid NOT NULL,
+  agent_name character varying NOT NULL,
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  notes text,
+  sticky boolean NOT NULL DEFAULT false,
+  assigned_at timestamp with time zone NOT NULL DEFAULT now(),
+  released_at timestamp with time zone
+);
+
+COMMENT ON COLUMN public.agent_assignments.agent_id IS 'No foreign key: assignments of deleted agents stay in the history';
+COMMENT ON COLUMN public.agent_assignments.sticky IS 'Routed automatically because the agent handled the prospect before';
+
+CREATE INDEX IF NOT EXISTS idx_agent_assignments_prospect
+ON public.agent_assignments (id_device, prospect_num, assigned_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_agent_assignments_open
+ON public.agent_assignments (source, conversation_id)
+WHERE released_at IS NULL;
+
+ALTER TABLE public.agent_assignments ENABLE ROW LEVEL SECURITY;
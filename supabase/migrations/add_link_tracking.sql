@@ -0,0 +1,43 @@
+-- Migration: Outbound call-to-action link tracking
+-- URLs in flow send_message nodes are replaced with short links (<tracking domain>/l/<code>).
+-- tracked_links holds one row per URL sent to a prospect, attributed to its flow node;
+-- link_clicks logs every open with its timestamp for click-through analytics.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS tracking_domain text;
+
+COMMENT ON COLUMN public.device_setting.tracking_domain IS 'Base URL for tracked short links; falls back to LINK_TRACKING_BASE_URL';
+
+CREATE TABLE IF NOT EXISTS public.tracked_links (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  code character varying NOT NULL UNIQUE,
+  id_device character varying NOT NULL,
+  flow_id character varying NOT NULL,
+  node_id character varying NOT NULL,
+  conversation_id character varying,
+  prospect_num character varying NOT NULL,
+  target_url text NOT NULL,
+  click_count integer NOT NULL DEFAULT 0,
+  first_clicked_at timestamp with time zone,
+  last_clicked_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracked_links_device_created ON public.tracked_links(id_device, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tracked_links_flow_node ON public.tracked_links(flow_id, node_id);
+
+CREATE TABLE IF NOT EXISTS public.link_clicks (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  link_id uuid NOT NULL REFERENCES public.tracked_links(id) ON DELETE CASCADE,
+  id_device character varying NOT NULL,
+  flow_id character varying NOT NULL,
+  node_id character varying NOT NULL,
+  user_agent text,
+  clicked_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_clicks_link ON public.link_clicks(link_id);
+CREATE INDEX IF NOT EXISTS idx_link_clicks_device_clicked ON public.link_clicks(id_device, clicked_at DESC);
+
+-- Backend writes with the service role only
+ALTER TABLE public.tracked_links ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.link_clicks ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,22 @@
+-- Migration: Message templates
+-- Messages that were hard-coded in the engine (the DETAIL CUSTOMER / DETAIL COD order details)
+-- become templates ops can edit without a redeploy. Users save named templates with {{variable}}
+-- placeholders ({{prospect_name}}, {{alamat}}, {{pakej}}, custom fields); a send_message node
+-- sends one by setting message_template. A template named like a built-in one (detail_customer,
+-- detail_cod, detail_wages, detail_cash) replaces it for the user's flows.
+
+CREATE TABLE IF NOT EXISTS public.message_templates (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL CHECK (name ~ '^[a-z0-9][a-z0-9_-]{0,62}$'),
+  description text,
+  content text NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (user_id, name)
+);
+
+COMMENT ON COLUMN public.message_templates.name IS 'Referenced by send_message nodes (message_template)';
+COMMENT ON COLUMN public.message_templates.content IS 'Message with {{variable}} placeholders filled from the conversation';
+
+ALTER TABLE public.message_templates ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,6 @@
+-- Migration: Messenger and Instagram channels
+-- device_setting.messenger connects a Facebook page (and the Instagram account linked to it):
+-- {page_access_token, verify_token, app_secret}. Page inbox prospects use prospect_num 'fb:<PSID>'
+-- with channel 'messenger', Instagram DMs 'ig:<IGSID>' with channel 'instagram'.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS messenger jsonb;
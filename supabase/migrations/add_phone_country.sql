@@ -0,0 +1,95 @@
+-- Migration: Per-device phone number country
+-- phone_country (ISO 3166-1 alpha-2, NULL = MY) is the country a device reads phone numbers in
+-- national format in: inbound senders, numbers of manually created conversations and campaign CSVs
+-- are normalized to E.164 digits by its calling code and trunk prefix, and outbound sends are too.
+-- prospect_phone now follows the device's country instead of always Malaysia.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS phone_country character varying(2)
+  CHECK (phone_country IN ('MY', 'SG', 'BN', 'ID', 'TH', 'PH', 'VN', 'HK', 'CN', 'IN', 'PK', 'BD', 'AE', 'SA', 'AU', 'GB', 'US'));
+
+COMMENT ON COLUMN public.device_setting.phone_country IS 'Country phone numbers in national format are read in (NULL = MY)';
+
+-- Mirrors models.NormalizePhone for the formats stored before numbers were normalized by the
+-- backend: +/00 international numbers, national numbers with the trunk prefix, and E.164 digits
+CREATE OR REPLACE FUNCTION normalize_prospect_phone(prospect_num text, country text)
+RETURNS text AS $$
+DECLARE
+  phone text;
+  calling_code text;
+  trunk_prefix text;
+BEGIN
+  SELECT r.calling_code, r.trunk_prefix INTO calling_code, trunk_prefix
+  FROM (VALUES
+    ('MY', '60', '0'), ('SG', '65', NULL), ('BN', '673', NULL), ('ID', '62', '0'), ('TH', '66', '0'),
+    ('PH', '63', '0'), ('VN', '84', '0'), ('HK', '852', NULL), ('CN', '86', '0'), ('IN', '91', '0'),
+    ('PK', '92', '0'), ('BD', '880', '0'), ('AE', '971', '0'), ('SA', '966', '0'), ('AU', '61', '0'),
+    ('GB', '44', '0'), ('US', '1', NULL)
+  ) AS r(code, calling_code, trunk_prefix)
+  WHERE r.code = upper(COALESCE(country, 'MY'));
+  IF calling_code IS NULL THEN
+    calling_code := '60';
+    trunk_prefix := '0';
+  END IF;
+
+  phone := split_part(btrim(COALESCE(prospect_num, '')), '@', 1);
+  IF position(':' IN phone) > 0 THEN
+    RETURN NULL;
+  END IF;
+
+  IF phone LIKE '+%' THEN
+    phone := regexp_replace(phone, '[^0-9]', '', 'g');
+  ELSE
+    phone := regexp_replace(phone, '[^0-9]', '', 'g');
+    IF phone LIKE '00%' THEN
+      phone := substr(phone, 3);
+    ELSIF trunk_prefix IS NOT NULL AND phone LIKE trunk_prefix || '%' THEN
+      phone := calling_code || substr(phone, length(trunk_prefix) + 1);
+    END IF;
+  END IF;
+
+  IF length(phone) < 8 OR length(phone) > 15 THEN
+    RETURN NULL;
+  END IF;
+  RETURN phone;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION set_prospect_phone()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.prospect_phone := normalize_prospect_phone(
+    NEW.prospect_num,
+    (SELECT d.phone_country FROM public.device_setting d WHERE d.id_device = NEW.id_device LIMIT 1)
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS normalize_prospect_phone(text);
+
+-- Devices that change country renormalize their conversations
+CREATE OR REPLACE FUNCTION renormalize_prospect_phones()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.phone_country IS NOT DISTINCT FROM OLD.phone_country THEN
+    RETURN NEW;
+  END IF;
+
+  UPDATE public.ai_whatsapp
+  SET prospect_phone = normalize_prospect_phone(prospect_num, NEW.phone_country)
+  WHERE id_device = NEW.id_device;
+
+  UPDATE public.wasapbot
+  SET prospect_phone = normalize_prospect_phone(prospect_num, NEW.phone_country)
+  WHERE id_device = NEW.id_device;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS device_setting_phone_country ON public.device_setting;
+CREATE TRIGGER device_setting_phone_country
+  AFTER UPDATE OF phone_country ON public.device_setting
+  FOR EACH ROW
+  EXECUTE FUNCTION renormalize_prospect_phones();
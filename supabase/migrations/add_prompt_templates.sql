@@ -0,0 +1,26 @@
+-- Migration: AI prompt templates
+-- The instruction block ai_prompt nodes send as the system prompt becomes a template. Users save
+-- named templates with {{variable}} placeholders ({{prompt}}, {{persona}}, conversation fields);
+-- an ai_prompt node picks one with prompt_template, devices set a default_prompt_template, and
+-- the built-in "default" template is used when neither names one.
+
+CREATE TABLE IF NOT EXISTS public.prompt_templates (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name character varying NOT NULL CHECK (name ~ '^[a-z0-9][a-z0-9_-]{0,62}$' AND name <> 'default'),
+  description text,
+  content text NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (user_id, name)
+);
+
+COMMENT ON COLUMN public.prompt_templates.name IS 'Referenced by ai_prompt nodes (prompt_template) and device_setting.default_prompt_template';
+COMMENT ON COLUMN public.prompt_templates.content IS 'System prompt with {{prompt}}, {{persona}} and conversation variable placeholders';
+
+ALTER TABLE public.prompt_templates ENABLE ROW LEVEL SECURITY;
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS default_prompt_template character varying;
+
+COMMENT ON COLUMN public.device_setting.default_prompt_template IS 'Prompt template of ai_prompt nodes that do not name one (NULL = built-in)';
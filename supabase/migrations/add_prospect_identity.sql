@@ -0,0 +1,117 @@
+-- Migration: Prospect identity across devices
+-- Conversations carry their prospect_num normalized as prospect_phone (digits in international
+-- format, NULL for Telegram, Messenger, Instagram and web chat prospects), so the conversations of
+-- one phone number on a user's devices form one prospect that can be listed and merged.
+-- Devices with share_prospect_data copy stage and custom field changes of a prospect to its
+-- conversations on the owner's other sharing devices.
+
+-- Mirrors models.NormalizeProspectPhone
+CREATE OR REPLACE FUNCTION normalize_prospect_phone(prospect_num text)
+RETURNS text AS $$
+DECLARE
+  phone text;
+BEGIN
+  phone := split_part(btrim(COALESCE(prospect_num, '')), '@', 1);
+  IF position(':' IN phone) > 0 THEN
+    RETURN NULL;
+  END IF;
+
+  phone := regexp_replace(phone, '[^0-9]', '', 'g');
+  IF phone LIKE '00%' THEN
+    phone := substr(phone, 3);
+  ELSIF phone LIKE '0%' THEN
+    phone := '60' || substr(phone, 2);
+  END IF;
+
+  IF length(phone) < 8 OR length(phone) > 15 THEN
+    RETURN NULL;
+  END IF;
+  RETURN phone;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS prospect_phone character varying(15);
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS prospect_phone character varying(15);
+
+CREATE OR REPLACE FUNCTION set_prospect_phone()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.prospect_phone := normalize_prospect_phone(NEW.prospect_num);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ai_whatsapp_prospect_phone ON public.ai_whatsapp;
+CREATE TRIGGER ai_whatsapp_prospect_phone
+  BEFORE INSERT OR UPDATE OF prospect_num ON public.ai_whatsapp
+  FOR EACH ROW
+  EXECUTE FUNCTION set_prospect_phone();
+
+DROP TRIGGER IF EXISTS wasapbot_prospect_phone ON public.wasapbot;
+CREATE TRIGGER wasapbot_prospect_phone
+  BEFORE INSERT OR UPDATE OF prospect_num ON public.wasapbot
+  FOR EACH ROW
+  EXECUTE FUNCTION set_prospect_phone();
+
+UPDATE public.ai_whatsapp SET prospect_phone = normalize_prospect_phone(prospect_num) WHERE prospect_phone IS NULL;
+UPDATE public.wasapbot SET prospect_phone = normalize_prospect_phone(prospect_num) WHERE prospect_phone IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_prospect_phone ON public.ai_whatsapp(prospect_phone, id_device) WHERE prospect_phone IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wasapbot_prospect_phone ON public.wasapbot(prospect_phone, id_device) WHERE prospect_phone IS NOT NULL;
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS share_prospect_data boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN public.device_setting.share_prospect_data IS 'Copy stage and custom field changes of a prospect to the same phone''s conversations on the owner''s other sharing devices';
+
+-- Copy a changed stage or custom fields to the prospect's conversations on the owner's other
+-- sharing devices. The copies don't share further (pg_trigger_depth), so devices never loop.
+CREATE OR REPLACE FUNCTION share_prospect_data()
+RETURNS TRIGGER AS $$
+DECLARE
+  owner_id public.device_setting.user_id%TYPE;
+  stage_changed boolean := NEW.stage IS DISTINCT FROM OLD.stage;
+  fields_changed boolean := NEW.custom_fields IS DISTINCT FROM OLD.custom_fields;
+BEGIN
+  IF pg_trigger_depth() > 1 OR NEW.prospect_phone IS NULL OR NOT (stage_changed OR fields_changed) THEN
+    RETURN NEW;
+  END IF;
+
+  SELECT user_id INTO owner_id
+  FROM public.device_setting
+  WHERE id_device = NEW.id_device AND share_prospect_data;
+  IF owner_id IS NULL THEN
+    RETURN NEW;
+  END IF;
+
+  UPDATE public.ai_whatsapp c
+  SET stage = CASE WHEN stage_changed THEN NEW.stage ELSE c.stage END,
+      custom_fields = CASE WHEN fields_changed THEN COALESCE(c.custom_fields, '{}'::jsonb) || COALESCE(NEW.custom_fields, '{}'::jsonb) ELSE c.custom_fields END,
+      updated_at = now()
+  WHERE c.prospect_phone = NEW.prospect_phone
+    AND c.id_device <> NEW.id_device
+    AND c.id_device IN (SELECT d.id_device FROM public.device_setting d WHERE d.user_id = owner_id AND d.share_prospect_data);
+
+  UPDATE public.wasapbot c
+  SET stage = CASE WHEN stage_changed THEN NEW.stage ELSE c.stage END,
+      custom_fields = CASE WHEN fields_changed THEN COALESCE(c.custom_fields, '{}'::jsonb) || COALESCE(NEW.custom_fields, '{}'::jsonb) ELSE c.custom_fields END,
+      updated_at = now()
+  WHERE c.prospect_phone = NEW.prospect_phone
+    AND c.id_device <> NEW.id_device
+    AND c.id_device IN (SELECT d.id_device FROM public.device_setting d WHERE d.user_id = owner_id AND d.share_prospect_data);
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ai_whatsapp_share_prospect_data ON public.ai_whatsapp;
+CREATE TRIGGER ai_whatsapp_share_prospect_data
+  AFTER UPDATE OF stage, custom_fields ON public.ai_whatsapp
+  FOR EACH ROW
+  EXECUTE FUNCTION share_prospect_data();
+
+DROP TRIGGER IF EXISTS wasapbot_share_prospect_data ON public.wasapbot;
+CREATE TRIGGER wasapbot_share_prospect_data
+  AFTER UPDATE OF stage, custom_fields ON public.wasapbot
+  FOR EACH ROW
+  EXECUTE FUNCTION share_prospect_data();
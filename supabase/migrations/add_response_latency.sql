@@ -0,0 +1,22 @@
+-- Migration: Response latency tracking
+-- One row per inbound -> reply cycle; opened on the first unanswered inbound message
+-- and closed by the next outbound reply (bot or human)
+
+CREATE TABLE IF NOT EXISTS public.response_latency (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  flow_id uuid,
+  prospect_num character varying NOT NULL,
+  is_first boolean NOT NULL DEFAULT false,
+  responder character varying CHECK (responder IS NULL OR responder IN ('bot', 'human')),
+  agent character varying,
+  inbound_at timestamp with time zone NOT NULL DEFAULT now(),
+  replied_at timestamp with time zone,
+  latency_ms bigint
+);
+
+CREATE INDEX IF NOT EXISTS idx_response_latency_open ON public.response_latency(id_device, prospect_num) WHERE replied_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_response_latency_device_time ON public.response_latency(id_device, inbound_at);
+
+-- Backend writes with the service role only
+ALTER TABLE public.response_latency ENABLE ROW LEVEL SECURITY;
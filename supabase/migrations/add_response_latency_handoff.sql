@@ -0,0 +1,22 @@
+-- Migration: First human reply after each handoff
+-- first_after_handoff is set when a human closes a cycle and the prospect's previous
+-- answered cycle was the bot's (or there was none), so every bot -> human handoff
+-- contributes its first human reply to first_human_response
+
+ALTER TABLE public.response_latency
+  ADD COLUMN IF NOT EXISTS first_after_handoff boolean NOT NULL DEFAULT false;
+
+-- Backfill from the existing answered cycles
+UPDATE public.response_latency r
+SET first_after_handoff = true
+FROM (
+  SELECT id, responder,
+         LAG(responder) OVER (PARTITION BY id_device, prospect_num ORDER BY replied_at) AS previous_responder
+  FROM public.response_latency
+  WHERE replied_at IS NOT NULL
+) c
+WHERE r.id = c.id
+  AND c.responder = 'human'
+  AND (c.previous_responder IS NULL OR c.previous_responder = 'bot');
+
+COMMENT ON COLUMN public.response_latency.first_after_handoff IS 'First human reply since the bot last answered the prospect';
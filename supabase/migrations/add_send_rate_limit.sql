@@ -0,0 +1,9 @@
+-- Migration: Per-device outbound send rate
+-- Provider sends wait in a per-device queue that releases at most send_rate_per_minute messages
+-- a minute, with a random 1-3 second gap between consecutive messages to the same prospect, so
+-- flows that blast many messages do not get the number banned.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS send_rate_per_minute integer CHECK (send_rate_per_minute BETWEEN 1 AND 120);
+
+COMMENT ON COLUMN public.device_setting.send_rate_per_minute IS 'Outbound messages per minute through the send queue (NULL = 30)';
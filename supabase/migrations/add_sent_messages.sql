@@ -0,0 +1,21 @@
+-- Migration: Provider message IDs and reply threading
+-- sent_messages keeps the message ID each provider returns for a send, attributed to the flow
+-- node that sent it. When a prospect quotes one of these messages, conditions edges of type
+-- replied_to can branch on which node's message was replied to (price list vs greeting).
+
+CREATE TABLE IF NOT EXISTS public.sent_messages (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  provider_message_id character varying NOT NULL,
+  flow_id character varying,
+  node_id character varying,
+  message_type character varying NOT NULL DEFAULT 'text',
+  body text,
+  created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sent_messages_lookup ON public.sent_messages(id_device, prospect_num, provider_message_id);
+
+-- Backend writes with the service role only
+ALTER TABLE public.sent_messages ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,34 @@
+-- Migration: Google Sheets exports
+-- Sales teams keep working from their own spreadsheet. The sheet is shared with the server's Google
+-- service account (GOOGLE_SHEETS_CREDENTIALS_FILE); stage nodes then append a row with the
+-- prospect's details (name, phone, alamat, pakej, payment method) when they set an exported stage,
+-- and a scheduled job rewrites the sync tab with a whole conversation table (ai_whatsapp or wasapbot).
+
+CREATE TABLE IF NOT EXISTS public.sheet_exports (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  id_device character varying,
+  name character varying,
+  spreadsheet_id character varying NOT NULL,
+  sheet_name character varying NOT NULL DEFAULT 'Sheet1',
+  stages text[] NOT NULL DEFAULT '{}',
+  columns jsonb NOT NULL DEFAULT '[]'::jsonb,
+  sync_table character varying CHECK (sync_table IN ('ai_whatsapp', 'wasapbot')),
+  sync_sheet_name character varying NOT NULL DEFAULT 'Sync',
+  sync_interval_minutes integer NOT NULL DEFAULT 60 CHECK (sync_interval_minutes BETWEEN 15 AND 1440),
+  last_synced_at timestamp with time zone,
+  last_error text,
+  is_active boolean NOT NULL DEFAULT true,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN public.sheet_exports.id_device IS 'Only conversations of this device; NULL = every device of the user';
+COMMENT ON COLUMN public.sheet_exports.stages IS 'Stages whose stage nodes append a row; empty = every stage';
+COMMENT ON COLUMN public.sheet_exports.columns IS '[{header, value}] with value a {{variable}} template';
+COMMENT ON COLUMN public.sheet_exports.sync_table IS 'Conversation table written to sync_sheet_name on schedule; NULL = no sync';
+
+CREATE INDEX IF NOT EXISTS idx_sheet_exports_user ON public.sheet_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_sheet_exports_sync ON public.sheet_exports (last_synced_at) WHERE sync_table IS NOT NULL;
+
+ALTER TABLE public.sheet_exports ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,66 @@
+-- Migration: Conversation stage history
+-- Every stage a conversation enters is recorded with when it entered and left, whichever code
+-- path changed the stage, so the stage funnel can report reach, drop-off and time in stage.
+
+CREATE TABLE IF NOT EXISTS public.conversation_stage_history (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  conversation_id character varying NOT NULL,
+  id_device character varying NOT NULL,
+  flow_id uuid,
+  prospect_num character varying NOT NULL,
+  stage character varying NOT NULL,
+  entered_at timestamp with time zone NOT NULL DEFAULT now(),
+  left_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_stage_history_flow ON public.conversation_stage_history(flow_id, entered_at);
+CREATE INDEX IF NOT EXISTS idx_conversation_stage_history_open ON public.conversation_stage_history(source, conversation_id) WHERE left_at IS NULL;
+
+-- Close the previous stay and open the new one whenever the stage changes
+CREATE OR REPLACE FUNCTION record_stage_history()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND NEW.stage IS NOT DISTINCT FROM OLD.stage THEN
+    RETURN NEW;
+  END IF;
+
+  UPDATE public.conversation_stage_history
+  SET left_at = now()
+  WHERE source = TG_TABLE_NAME AND conversation_id = NEW.id_prospect::text AND left_at IS NULL;
+
+  IF NEW.stage IS NOT NULL AND NEW.stage <> '' THEN
+    INSERT INTO public.conversation_stage_history (source, conversation_id, id_device, flow_id, prospect_num, stage)
+    VALUES (TG_TABLE_NAME, NEW.id_prospect::text, NEW.id_device, NULLIF(NEW.flow_id::text, '')::uuid, NEW.prospect_num, NEW.stage);
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ai_whatsapp_stage_history ON public.ai_whatsapp;
+CREATE TRIGGER ai_whatsapp_stage_history
+  AFTER INSERT OR UPDATE OF stage ON public.ai_whatsapp
+  FOR EACH ROW
+  EXECUTE FUNCTION record_stage_history();
+
+DROP TRIGGER IF EXISTS wasapbot_stage_history ON public.wasapbot;
+CREATE TRIGGER wasapbot_stage_history
+  AFTER INSERT OR UPDATE OF stage ON public.wasapbot
+  FOR EACH ROW
+  EXECUTE FUNCTION record_stage_history();
+
+-- Conversations already in a stage start their stay when they entered it
+INSERT INTO public.conversation_stage_history (source, conversation_id, id_device, flow_id, prospect_num, stage, entered_at)
+SELECT 'ai_whatsapp', c.id_prospect::text, c.id_device, NULLIF(c.flow_id::text, '')::uuid, c.prospect_num, c.stage, COALESCE(c.stage_entered_at, now())
+FROM public.ai_whatsapp c
+WHERE c.stage IS NOT NULL AND c.stage <> ''
+  AND NOT EXISTS (SELECT 1 FROM public.conversation_stage_history h WHERE h.source = 'ai_whatsapp' AND h.conversation_id = c.id_prospect::text);
+
+INSERT INTO public.conversation_stage_history (source, conversation_id, id_device, flow_id, prospect_num, stage, entered_at)
+SELECT 'wasapbot', c.id_prospect::text, c.id_device, NULLIF(c.flow_id::text, '')::uuid, c.prospect_num, c.stage, COALESCE(c.stage_entered_at, now())
+FROM public.wasapbot c
+WHERE c.stage IS NOT NULL AND c.stage <> ''
+  AND NOT EXISTS (SELECT 1 FROM public.conversation_stage_history h WHERE h.source = 'wasapbot' AND h.conversation_id = c.id_prospect::text);
+
+-- Backend reads with the service role only
+ALTER TABLE public.conversation_stage_history ENABLE ROW LEVEL SECURITY;
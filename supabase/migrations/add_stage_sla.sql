@@ -0,0 +1,68 @@
+-- Migration: Per-stage SLA timers
+-- stagesetvalue.sla_minutes is the maximum dwell time in a stage. When a conversation stays longer,
+-- the stage SLA monitor runs the stage's nudge node (sla_action = 'nudge') or messages the device owner
+-- ('notify', default), flags the conversation and logs the breach for analytics.
+
+ALTER TABLE public.stagesetvalue
+ADD COLUMN IF NOT EXISTS sla_minutes integer CHECK (sla_minutes IS NULL OR sla_minutes > 0),
+ADD COLUMN IF NOT EXISTS sla_action character varying CHECK (sla_action IS NULL OR sla_action IN ('', 'nudge', 'notify')),
+ADD COLUMN IF NOT EXISTS sla_nudge_node_id character varying;
+
+ALTER TABLE public.ai_whatsapp
+ADD COLUMN IF NOT EXISTS stage_entered_at timestamp with time zone,
+ADD COLUMN IF NOT EXISTS sla_breached_at timestamp with time zone;
+
+ALTER TABLE public.wasapbot
+ADD COLUMN IF NOT EXISTS stage_entered_at timestamp with time zone,
+ADD COLUMN IF NOT EXISTS sla_breached_at timestamp with time zone;
+
+-- Restart the SLA clock whenever the stage changes, whichever code path changed it
+CREATE OR REPLACE FUNCTION reset_stage_sla_clock()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' OR NEW.stage IS DISTINCT FROM OLD.stage THEN
+    NEW.stage_entered_at = CASE WHEN NEW.stage IS NULL THEN NULL ELSE now() END;
+    NEW.sla_breached_at = NULL;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ai_whatsapp_stage_sla ON public.ai_whatsapp;
+CREATE TRIGGER ai_whatsapp_stage_sla
+  BEFORE INSERT OR UPDATE ON public.ai_whatsapp
+  FOR EACH ROW
+  EXECUTE FUNCTION reset_stage_sla_clock();
+
+DROP TRIGGER IF EXISTS wasapbot_stage_sla ON public.wasapbot;
+CREATE TRIGGER wasapbot_stage_sla
+  BEFORE INSERT OR UPDATE ON public.wasapbot
+  FOR EACH ROW
+  EXECUTE FUNCTION reset_stage_sla_clock();
+
+-- Existing conversations start their clock now
+UPDATE public.ai_whatsapp SET stage_entered_at = now() WHERE stage IS NOT NULL AND stage_entered_at IS NULL;
+UPDATE public.wasapbot SET stage_entered_at = now() WHERE stage IS NOT NULL AND stage_entered_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ai_whatsapp_stage_sla ON public.ai_whatsapp(id_device, stage, stage_entered_at) WHERE sla_breached_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_wasapbot_stage_sla ON public.wasapbot(id_device, stage, stage_entered_at) WHERE sla_breached_at IS NULL;
+
+-- One row per breach, kept after the conversation moves on
+CREATE TABLE IF NOT EXISTS public.stage_sla_breaches (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id_device character varying NOT NULL,
+  prospect_num character varying NOT NULL,
+  conversation_id character varying NOT NULL,
+  source character varying NOT NULL CHECK (source IN ('ai_whatsapp', 'wasapbot')),
+  stage character varying NOT NULL,
+  sla_minutes integer NOT NULL,
+  action character varying NOT NULL CHECK (action IN ('nudge', 'notify')),
+  handled boolean NOT NULL DEFAULT false,
+  stage_entered_at timestamp with time zone NOT NULL,
+  breached_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stage_sla_breaches_device_time ON public.stage_sla_breaches(id_device, breached_at);
+
+-- Backend writes with the service role only
+ALTER TABLE public.stage_sla_breaches ENABLE ROW LEVEL SECURITY;
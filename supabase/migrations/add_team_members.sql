@@ -0,0 +1,27 @@
+-- Migration: Team roles
+-- Every user owns a team. team_members gives other registered users a role on the owner's devices:
+-- admins do everything but delete devices and manage the team, agents view and reply to
+-- conversations and edit flow copy (not settings or admin-only nodes), viewers view conversations
+-- and flows. device_ids limits a member
+-- to some of the owner's devices (empty = all of them).
+
+CREATE TABLE IF NOT EXISTS public.team_members (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  email character varying NOT NULL,
+  role character varying NOT NULL CHECK (role IN ('admin', 'agent', 'viewer')),
+  device_ids text[] NOT NULL DEFAULT '{}',
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  UNIQUE (owner_id, user_id),
+  CHECK (owner_id <> user_id)
+);
+
+COMMENT ON COLUMN public.team_members.owner_id IS 'The user whose devices are shared';
+COMMENT ON COLUMN public.team_members.device_ids IS 'id_device of the shared devices; empty shares all of the owner''s devices';
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user_id
+  ON public.team_members (user_id);
+
+ALTER TABLE public.team_members ENABLE ROW LEVEL SECURITY;
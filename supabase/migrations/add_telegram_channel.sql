@@ -0,0 +1,6 @@
+-- Migration: Telegram channel
+-- device_setting.telegram_bot_token connects a Telegram bot to a device, so the device's flows also
+-- serve Telegram chats. Telegram prospects use prospect_num 'tg:<chat_id>' and channel 'telegram'
+-- on ai_whatsapp and wasapbot (see add_conversation_channel.sql).
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS telegram_bot_token text;
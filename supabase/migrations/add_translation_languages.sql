@@ -0,0 +1,7 @@
+-- Migration: Inline translation
+-- device_setting.working_language is the seller's language translate nodes translate into (default 'ms').
+-- ai_whatsapp/wasapbot.language is the detected prospect language; when set, replies are translated into it.
+
+ALTER TABLE public.device_setting ADD COLUMN IF NOT EXISTS working_language character varying(10);
+ALTER TABLE public.ai_whatsapp ADD COLUMN IF NOT EXISTS language character varying(10);
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS language character varying(10);
@@ -0,0 +1,11 @@
+-- Migration: Typing indicator before bot replies
+-- With typing_indicator on, each send_message node marks the chat read and shows the bot typing
+-- for as long as its message takes at typing_chars_per_second (1 to 8 seconds) before sending.
+-- Waha, Telegram and Messenger devices only.
+
+ALTER TABLE public.device_setting
+ADD COLUMN IF NOT EXISTS typing_indicator boolean NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS typing_chars_per_second integer CHECK (typing_chars_per_second BETWEEN 1 AND 100);
+
+COMMENT ON COLUMN public.device_setting.typing_indicator IS 'Mark the chat read and show typing before send_message node messages';
+COMMENT ON COLUMN public.device_setting.typing_chars_per_second IS 'Typing speed that sets how long typing shows (NULL = 15)';
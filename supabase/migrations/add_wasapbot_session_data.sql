@@ -0,0 +1,5 @@
+-- Migration: Flow variables on wasapbot
+-- session_data holds the variables set_variable nodes write (counters, totals, trimmed replies),
+-- which conditions, message templates and conversation lists read back. ai_whatsapp already has it.
+
+ALTER TABLE public.wasapbot ADD COLUMN IF NOT EXISTS session_data jsonb;